@@ -2,8 +2,12 @@ package main
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/storage"
+	"github.com/rigdev/rig/pkg/client"
 	"github.com/spf13/cobra"
 )
 
@@ -13,6 +17,24 @@ var approveCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		taskID := args[0]
+		plan, err := readPlanFile(cmd)
+		if err != nil {
+			return err
+		}
+		if rc := newRemoteClient(cmd); rc != nil {
+			if plan != "" {
+				if _, err := rc.api.EditPlan(cmd.Context(), taskID, client.EditPlanRequest{Plan: plan}); err != nil {
+					return fmt.Errorf("edit plan: %w", err)
+				}
+			}
+			message, err := rc.Decide(cmd.Context(), taskID, true)
+			if err != nil {
+				return fmt.Errorf("resume task: %w", err)
+			}
+			fmt.Println(message)
+			return nil
+		}
+
 		configPath, _ := cmd.Flags().GetString("config")
 
 		cfg, err := config.LoadConfig(configPath)
@@ -25,11 +47,33 @@ var approveCmd = &cobra.Command{
 			return err
 		}
 
+		if plan != "" {
+			if err := core.EditPlan(defaultStatePath, taskID, plan); err != nil {
+				return fmt.Errorf("edit plan: %w", err)
+			}
+			recordCLIAudit(storage.AuditPlanEdited, taskID, "")
+		}
+
 		if err := engine.Resume(cmd.Context(), taskID, true); err != nil {
 			return fmt.Errorf("resume task: %w", err)
 		}
+		recordCLIAudit(storage.AuditProposalApproved, taskID, "")
 
 		fmt.Println("Proposal approved. Task resumed and execution completed.")
 		return nil
 	},
 }
+
+// readPlanFile returns the content of --plan-file, the edited plan text of a
+// plan review to approve, or "" without the flag.
+func readPlanFile(cmd *cobra.Command) (string, error) {
+	path, _ := cmd.Flags().GetString("plan-file")
+	if path == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read plan file: %w", err)
+	}
+	return string(data), nil
+}
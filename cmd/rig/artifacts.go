@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	adapterartifact "github.com/rigdev/rig/internal/adapter/artifact"
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+)
+
+// defaultArtifactSweepInterval is how often serve applies
+// artifacts.retention when artifacts.retention.interval is unset.
+const defaultArtifactSweepInterval = time.Hour
+
+// runArtifactSweeper deletes, every interval until ctx ends, the stored
+// artifacts artifacts.retention no longer keeps.
+func runArtifactSweeper(ctx context.Context, currentCfg func() *config.Config, statePath string, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultArtifactSweepInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		cfg := currentCfg().Artifacts
+		store, err := adapterartifact.New(cfg, statePath)
+		if err != nil {
+			slog.Warn("artifact retention", "err", err)
+		} else {
+			deleted, err := core.SweepArtifacts(ctx, store, cfg.Retention.MaxAge, cfg.Retention.MaxBytes)
+			if err != nil {
+				slog.Warn("artifact retention", "err", err)
+			}
+			if deleted > 0 {
+				slog.Info("artifact retention: deleted artifacts", "count", deleted)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/user"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rigdev/rig/internal/storage"
+	"github.com/rigdev/rig/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Show the audit log of state-changing actions",
+	Long: `Show who created, approved, rejected or stopped tasks and changed settings,
+newest first. Entries come from the local database, or from the server with
+--server.
+
+  rig audit --since 168h --action proposal.approved
+  rig audit --actor github:octocat -o json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		actor, _ := cmd.Flags().GetString("actor")
+		action, _ := cmd.Flags().GetString("action")
+		target, _ := cmd.Flags().GetString("target")
+		since, _ := cmd.Flags().GetString("since")
+		limit, _ := cmd.Flags().GetInt("limit")
+
+		var entries []storage.AuditEntry
+		if rc := newRemoteClient(cmd); rc != nil {
+			var err error
+			entries, err = rc.api.ListAudit(cmd.Context(), &client.ListAuditParams{
+				Actor: actor, Action: action, Target: target, Since: since, Limit: limit,
+			})
+			if err != nil {
+				return fmt.Errorf("list audit: %w", err)
+			}
+		} else {
+			filter := storage.AuditFilter{Actor: actor, Action: action, Target: target, Limit: limit}
+			if since != "" {
+				t, err := parseAuditSince(since)
+				if err != nil {
+					return err
+				}
+				filter.Since = t
+			}
+			db, err := storage.Open(defaultDBPath())
+			if err != nil {
+				return fmt.Errorf("open database: %w", err)
+			}
+			defer db.Close()
+			if entries, err = db.ListAudit(filter); err != nil {
+				return err
+			}
+		}
+
+		if format := outputFormat(cmd); format != outputText {
+			return writeStructured(os.Stdout, format, entries)
+		}
+		if len(entries) == 0 {
+			fmt.Println("No audit entries.")
+			return nil
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "TIME\tACTOR\tSOURCE\tACTION\tTARGET\tDETAILS")
+		for _, e := range entries {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				e.Time.Local().Format("2006-01-02 15:04:05"), e.Actor, e.Source, e.Action, e.Target, e.Details)
+		}
+		return tw.Flush()
+	},
+}
+
+// parseAuditSince accepts a duration before now ("24h") or an RFC 3339 time.
+func parseAuditSince(s string) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --since %q: want a duration like 24h or an RFC 3339 time", s)
+	}
+	return t, nil
+}
+
+// recordCLIAudit appends a local CLI action to the audit log. The action
+// already happened, so a database error only warns.
+func recordCLIAudit(action, target, details string) {
+	db, err := storage.Open(defaultDBPath())
+	if err != nil {
+		slog.Warn("audit: open database", "err", err)
+		return
+	}
+	defer db.Close()
+	err = db.RecordAudit(storage.AuditEntry{
+		Actor:   cliActor(),
+		Source:  storage.AuditSourceCLI,
+		Action:  action,
+		Target:  target,
+		Details: details,
+	})
+	if err != nil {
+		slog.Warn("audit: record", "action", action, "err", err)
+	}
+}
+
+// cliActor identifies the OS user running the CLI.
+func cliActor() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return "cli:" + u.Username
+	}
+	for _, env := range []string{"USER", "USERNAME"} {
+		if name := os.Getenv(env); name != "" {
+			return "cli:" + name
+		}
+	}
+	return "cli:unknown"
+}
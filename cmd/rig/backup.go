@@ -0,0 +1,426 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rigdev/rig/internal/backup"
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/storage"
+	"github.com/rigdev/rig/internal/tenant"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultBackupKeep is how many scheduled backups rig serve keeps when
+// backup.keep is unset.
+const defaultBackupKeep = 7
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Back up and restore the state, database and config",
+	Long: `A backup is one file holding the state file (.rig/state.json or
+RIG_STATE_PATH), a consistent snapshot of the SQLite database (~/.rig/rig.db
+or RIG_DB_PATH), rig.yaml and the state file and config of each tenant,
+with a manifest of their checksums. It is encrypted when
+RIG_BACKUP_PASSPHRASE or backup.passphrase is set.
+
+rig serve also takes a backup every backup.interval and keeps the
+backup.keep newest in backup.dir.
+
+  rig backup create
+  rig backup create --file /mnt/backups/rig.tar.gz.enc --encrypt
+  rig backup list
+  rig backup restore ~/.rig/backups/rig-backup-20261016-030000.tar.gz.enc`,
+}
+
+var backupCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Write a backup of the state, database and config",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := storage.Open(defaultDBPath())
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer db.Close()
+
+		configPath, _ := cmd.Flags().GetString("config")
+		if configPath == "" {
+			configPath = "rig.yaml"
+		}
+		var b config.BackupConfig
+		if cfg, _, err := loadConfigFromSources(db, configPath); err == nil && cfg != nil {
+			b = cfg.Backup
+		}
+		// rig.yaml is backed up even when it does not load, such as with
+		// its variables unset.
+		cfgFile := ""
+		if _, err := os.Stat(configPath); err == nil {
+			cfgFile = configPath
+		}
+		passphrase := backupPassphrase(b)
+		if encrypt, _ := cmd.Flags().GetBool("encrypt"); encrypt && passphrase == "" {
+			return fmt.Errorf("--encrypt needs RIG_BACKUP_PASSPHRASE or backup.passphrase")
+		}
+		out, _ := cmd.Flags().GetString("file")
+		if out == "" {
+			out = filepath.Join(backupDir(b), backup.FileName(time.Now(), passphrase != ""))
+		}
+
+		m, err := takeBackup(db, cfgFile, out, passphrase)
+		if err != nil {
+			return err
+		}
+		names := make([]string, len(m.Files))
+		for i, f := range m.Files {
+			names[i] = f.Name
+		}
+		recordCLIAudit(storage.AuditBackupCreated, out, strings.Join(names, ", "))
+
+		if format := outputFormat(cmd); format != outputText {
+			return writeStructured(os.Stdout, format, struct {
+				Path     string           `json:"path"`
+				Manifest *backup.Manifest `json:"manifest"`
+			}{out, m})
+		}
+		var size int64
+		if info, err := os.Stat(out); err == nil {
+			size = info.Size()
+		}
+		encrypted := ""
+		if passphrase != "" {
+			encrypted = ", encrypted"
+		}
+		fmt.Printf("Wrote %s (%s%s): %s\n", out, formatSize(size), encrypted, strings.Join(names, ", "))
+		return nil
+	},
+}
+
+var backupListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the backups in backup.dir",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		backups, err := backup.List(backupDir(loadBackupConfig(cmd)))
+		if err != nil {
+			return err
+		}
+		if format := outputFormat(cmd); format != outputText {
+			return writeStructured(os.Stdout, format, backups)
+		}
+		if len(backups) == 0 {
+			fmt.Println("No backups.")
+			return nil
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "CREATED\tSIZE\tENCRYPTED\tPATH")
+		for _, b := range backups {
+			fmt.Fprintf(tw, "%s\t%s\t%v\t%s\n",
+				b.CreatedAt.Local().Format("2006-01-02 15:04:05"), formatSize(b.Size), b.Encrypted, b.Path)
+		}
+		return tw.Flush()
+	},
+}
+
+var backupRestoreCmd = &cobra.Command{
+	Use:   "restore <file>",
+	Short: "Restore the state, database and config of a backup",
+	Long: `Restores the files of a backup over the state file, the SQLite
+database, rig.yaml (or --config) and the tenants' state files and configs,
+which go where the backed up rig.yaml names them. Stop rig serve first: it
+keeps the database open. Existing files are only replaced with --force.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configPath, _ := cmd.Flags().GetString("config")
+		if configPath == "" {
+			configPath = "rig.yaml"
+		}
+		force, _ := cmd.Flags().GetBool("force")
+		targets := map[string]string{
+			backup.StateFile:  defaultStatePath,
+			backup.DBFile:     defaultDBPath(),
+			backup.ConfigFile: configPath,
+		}
+
+		f, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("open backup: %w", err)
+		}
+		defer f.Close()
+		tmp, err := os.MkdirTemp("", "rig-restore-")
+		if err != nil {
+			return fmt.Errorf("create temp dir: %w", err)
+		}
+		defer os.RemoveAll(tmp)
+		m, err := backup.Extract(f, tmp, backupPassphrase(loadBackupConfig(cmd)))
+		if errors.Is(err, backup.ErrPassphraseRequired) {
+			return fmt.Errorf("%w: set RIG_BACKUP_PASSPHRASE", err)
+		}
+		if err != nil {
+			return err
+		}
+		if err := addTenantTargets(targets, m, tmp, configPath); err != nil {
+			return err
+		}
+
+		if !force {
+			var existing []string
+			for _, file := range m.Files {
+				if _, err := os.Stat(targets[file.Name]); err == nil {
+					existing = append(existing, targets[file.Name])
+				}
+			}
+			if len(existing) > 0 {
+				return fmt.Errorf("would replace %s; stop rig serve and pass --force", strings.Join(existing, ", "))
+			}
+		}
+
+		for _, file := range m.Files {
+			src, dst := filepath.Join(tmp, filepath.FromSlash(file.Name)), targets[file.Name]
+			if err := restoreBackupFile(file.Name, src, dst); err != nil {
+				return fmt.Errorf("restore %s: %w", file.Name, err)
+			}
+			fmt.Printf("Restored %s to %s (%s)\n", file.Name, dst, formatSize(file.Size))
+		}
+		recordCLIAudit(storage.AuditBackupRestored, args[0], fmt.Sprintf("taken %s on %s", m.CreatedAt.Format(time.RFC3339), m.Host))
+		return nil
+	},
+}
+
+// loadBackupConfig returns the backup settings of rig.yaml or the --config
+// file, or none when it does not load.
+func loadBackupConfig(cmd *cobra.Command) config.BackupConfig {
+	configPath, _ := cmd.Flags().GetString("config")
+	if configPath == "" {
+		configPath = "rig.yaml"
+	}
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return config.BackupConfig{}
+	}
+	return cfg.Backup
+}
+
+// backupPassphrase is RIG_BACKUP_PASSPHRASE or else backup.passphrase.
+func backupPassphrase(b config.BackupConfig) string {
+	if p := os.Getenv("RIG_BACKUP_PASSPHRASE"); p != "" {
+		return p
+	}
+	return b.Passphrase
+}
+
+// backupDir is backup.dir or ~/.rig/backups.
+func backupDir(b config.BackupConfig) string {
+	if b.Dir != "" {
+		return b.Dir
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".rig", "backups")
+}
+
+// takeBackup writes a backup of the state file, a snapshot of db, the
+// config file cfgFile, if any, and the state files and configs of the
+// tenants to out. State files are read under their lock.
+func takeBackup(db *storage.DB, cfgFile, out, passphrase string) (*backup.Manifest, error) {
+	if err := os.MkdirAll(filepath.Dir(out), 0o700); err != nil {
+		return nil, fmt.Errorf("create backup dir: %w", err)
+	}
+	// The snapshots are as large as the originals, so they go next to the
+	// backup rather than into a possibly small temp filesystem.
+	tmp, err := os.MkdirTemp(filepath.Dir(out), ".rig-backup-")
+	if err != nil {
+		return nil, fmt.Errorf("create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	paths := map[string]string{backup.DBFile: filepath.Join(tmp, backup.DBFile)}
+	if err := db.Snapshot(paths[backup.DBFile]); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(defaultStatePath); err == nil {
+		state, err := core.LoadState(defaultStatePath)
+		if err != nil {
+			return nil, fmt.Errorf("load state: %w", err)
+		}
+		paths[backup.StateFile] = filepath.Join(tmp, backup.StateFile)
+		if err := core.SaveState(state, paths[backup.StateFile]); err != nil {
+			return nil, fmt.Errorf("copy state: %w", err)
+		}
+	}
+	// Tenants keep their state in tenants/<name>/ next to the state file.
+	tenantStates, err := filepath.Glob(tenant.StatePath(defaultStatePath, "*"))
+	if err != nil {
+		return nil, fmt.Errorf("list tenant state: %w", err)
+	}
+	for _, path := range tenantStates {
+		name := backup.TenantStateFile(filepath.Base(filepath.Dir(path)))
+		if _, _, ok := backup.Tenant(name); !ok {
+			continue
+		}
+		state, err := core.LoadState(path)
+		if err != nil {
+			return nil, fmt.Errorf("load state of %s: %w", name, err)
+		}
+		paths[name] = filepath.Join(tmp, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(paths[name]), 0o700); err != nil {
+			return nil, err
+		}
+		if err := core.SaveState(state, paths[name]); err != nil {
+			return nil, fmt.Errorf("copy state of %s: %w", name, err)
+		}
+	}
+	if cfgFile != "" {
+		paths[backup.ConfigFile] = cfgFile
+		data, err := os.ReadFile(cfgFile)
+		if err != nil {
+			return nil, fmt.Errorf("read config: %w", err)
+		}
+		configs, err := tenantConfigs(data, filepath.Dir(cfgFile))
+		if err != nil {
+			return nil, err
+		}
+		for name, path := range configs {
+			paths[backup.TenantConfigFile(name)] = path
+		}
+	}
+	return backup.WriteFile(out, paths, passphrase)
+}
+
+// tenantConfigs returns the config files of the tenants of the rig.yaml
+// data by tenant name, relative ones joined onto dir. It reads the tenants
+// alone, so a rig.yaml that does not load still names them.
+func tenantConfigs(data []byte, dir string) (map[string]string, error) {
+	var raw struct {
+		Tenants []config.TenantConfig `yaml:"tenants"`
+	}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("read tenants of config: %w", err)
+	}
+	configs := make(map[string]string, len(raw.Tenants))
+	for _, tc := range raw.Tenants {
+		if _, _, ok := backup.Tenant(backup.TenantConfigFile(tc.Name)); !ok || tc.Config == "" {
+			continue
+		}
+		path := tc.Config
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		configs[tc.Name] = path
+	}
+	return configs, nil
+}
+
+// addTenantTargets adds to targets where the tenant files of the backup m,
+// extracted to dir, are restored: state files next to the state file, and
+// configs where the backed up rig.yaml, restored to configPath, names them.
+func addTenantTargets(targets map[string]string, m *backup.Manifest, dir, configPath string) error {
+	var configs map[string]string
+	for _, file := range m.Files {
+		name, base, ok := backup.Tenant(file.Name)
+		if !ok {
+			continue
+		}
+		if base == backup.StateFile {
+			targets[file.Name] = tenant.StatePath(defaultStatePath, name)
+			continue
+		}
+		if configs == nil {
+			data, err := os.ReadFile(filepath.Join(dir, backup.ConfigFile))
+			if err != nil {
+				return fmt.Errorf("%s needs the rig.yaml of the backup: %w", file.Name, err)
+			}
+			if configs, err = tenantConfigs(data, filepath.Dir(configPath)); err != nil {
+				return err
+			}
+		}
+		path, ok := configs[name]
+		if !ok {
+			return fmt.Errorf("%s: tenant %s is not in the rig.yaml of the backup", file.Name, name)
+		}
+		targets[file.Name] = path
+	}
+	return nil
+}
+
+// restoreBackupFile replaces dst with the restored file src.
+func restoreBackupFile(name, src, dst string) error {
+	if _, base, ok := backup.Tenant(name); name == backup.StateFile || ok && base == backup.StateFile {
+		// Through the state file lock, so a running rig never reads half a file.
+		state, err := core.LoadState(src)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return err
+		}
+		return core.SaveState(state, dst)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	tmp := dst + ".restore"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if name == backup.DBFile {
+		// The write-ahead log of the replaced database would be replayed
+		// into the restored one.
+		os.Remove(dst + "-wal")
+		os.Remove(dst + "-shm")
+	}
+	return os.Rename(tmp, dst)
+}
+
+// runBackups takes a backup every backup.interval and keeps the
+// backup.keep newest until ctx is cancelled.
+func runBackups(ctx context.Context, db *storage.DB, currentCfg func() *config.Config, cfgFile string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		b := currentCfg().Backup
+		passphrase := backupPassphrase(b)
+		out := filepath.Join(backupDir(b), backup.FileName(time.Now(), passphrase != ""))
+		if _, err := takeBackup(db, cfgFile, out, passphrase); err != nil {
+			slog.Warn("backup", "err", err)
+			continue
+		}
+		keep := b.Keep
+		if keep <= 0 {
+			keep = defaultBackupKeep
+		}
+		deleted, err := backup.Prune(backupDir(b), keep)
+		if err != nil {
+			slog.Warn("backup: prune", "err", err)
+		}
+		slog.Info("backup: wrote backup", "path", out, "pruned", len(deleted))
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the rig.yaml format",
+}
+
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print a JSON Schema for rig.yaml",
+	Long: `Print a JSON Schema for rig.yaml, for editor completion and inline
+validation. With the YAML language server (VS Code, Neovim, JetBrains):
+
+  rig config schema > rig.schema.json
+
+and add this line at the top of rig.yaml:
+
+  # yaml-language-server: $schema=./rig.schema.json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out, _ := cmd.Flags().GetString("file")
+		data, err := json.MarshalIndent(config.JSONSchema(), "", "  ")
+		if err != nil {
+			return err
+		}
+		data = append(data, '\n')
+		if out == "" {
+			_, err = os.Stdout.Write(data)
+			return err
+		}
+		if err := os.WriteFile(out, data, 0o644); err != nil {
+			return fmt.Errorf("write schema: %w", err)
+		}
+		fmt.Printf("Wrote %s\n", out)
+		return nil
+	},
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+// defaultLogPruneInterval is how often serve applies log.retention when
+// log.retention.interval is unset.
+const defaultLogPruneInterval = time.Hour
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Maintain the SQLite database",
+	Long: `Maintenance of the SQLite database (~/.rig/rig.db or RIG_DB_PATH).
+
+prune applies log.retention to the stored task logs now; rig serve does
+it every log.retention.interval. Pruned rows leave free pages behind that
+new rows reuse; vacuum rebuilds the file to return them to the filesystem.
+clear-ai-cache removes the cached AI answers of ai.cache, such as a bad
+plan that retries would otherwise reuse.
+
+  rig db prune --max-age 720h
+  rig db vacuum
+  rig db clear-ai-cache`,
+}
+
+var dbPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete and compress old task logs by log.retention",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := storage.Open(defaultDBPath())
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer db.Close()
+
+		var retention config.LogRetentionConfig
+		configPath, _ := cmd.Flags().GetString("config")
+		if configPath == "" {
+			configPath = "rig.yaml"
+		}
+		if cfg, _, err := loadConfigFromSources(db, configPath); err == nil && cfg != nil {
+			retention = cfg.Log.Retention
+		}
+		flags := cmd.Flags()
+		if flags.Changed("max-rows-per-task") {
+			retention.MaxRowsPerTask, _ = flags.GetInt("max-rows-per-task")
+		}
+		if flags.Changed("max-age") {
+			retention.MaxAge, _ = flags.GetDuration("max-age")
+		}
+		if flags.Changed("max-bytes") {
+			retention.MaxBytes, _ = flags.GetInt64("max-bytes")
+		}
+		if flags.Changed("compress-after") {
+			retention.CompressAfter, _ = flags.GetDuration("compress-after")
+		}
+		r := logRetention(retention)
+		if r == (storage.LogRetention{}) {
+			return fmt.Errorf("no log retention configured; set log.retention or pass --max-age, --max-rows-per-task, --max-bytes or --compress-after")
+		}
+
+		res, err := db.PruneLogs(r)
+		if err != nil {
+			return err
+		}
+		stats, err := db.LogStats()
+		if err != nil {
+			return err
+		}
+		if format := outputFormat(cmd); format != outputText {
+			return writeStructured(os.Stdout, format, struct {
+				storage.LogPruneResult
+				Stats storage.LogStats `json:"stats"`
+			}{res, stats})
+		}
+		fmt.Printf("Deleted %d and compressed %d log lines\n", res.Deleted, res.Compressed)
+		fmt.Printf("Task logs: %d lines, %d compressed, %s\n", stats.Lines, stats.ArchivedLines, formatSize(stats.Bytes))
+		return nil
+	},
+}
+
+var dbVacuumCmd = &cobra.Command{
+	Use:   "vacuum",
+	Short: "Rebuild the database file to return free space to the filesystem",
+	Long: `Rebuilds the database file without the free pages deleted rows left
+behind. It needs free disk space of about the database size and briefly
+blocks writers, so run it when the server is idle.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := defaultDBPath()
+		db, err := storage.Open(path)
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer db.Close()
+
+		before := dbFileSize(path)
+		if err := db.Vacuum(); err != nil {
+			return err
+		}
+		after := dbFileSize(path)
+		fmt.Printf("Vacuumed %s: %s -> %s\n", path, formatSize(before), formatSize(after))
+		return nil
+	},
+}
+
+var dbClearAICacheCmd = &cobra.Command{
+	Use:   "clear-ai-cache",
+	Short: "Remove the cached AI answers so later tasks ask the AI provider again",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := storage.Open(defaultDBPath())
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer db.Close()
+
+		n, err := db.ClearAICache()
+		if err != nil {
+			return err
+		}
+		recordCLIAudit(storage.AuditAICacheCleared, "ai_cache", fmt.Sprintf("removed %d entries", n))
+		fmt.Printf("Removed %d cached AI answers\n", n)
+		return nil
+	},
+}
+
+// dbFileSize is the size of the database file and its write-ahead log.
+func dbFileSize(path string) int64 {
+	var size int64
+	for _, p := range []string{path, path + "-wal"} {
+		if info, err := os.Stat(p); err == nil {
+			size += info.Size()
+		}
+	}
+	return size
+}
+
+// logRetention converts log.retention to the storage limits.
+func logRetention(r config.LogRetentionConfig) storage.LogRetention {
+	return storage.LogRetention{
+		MaxRowsPerTask: r.MaxRowsPerTask,
+		MaxAge:         r.MaxAge,
+		MaxBytes:       r.MaxBytes,
+		CompressAfter:  r.CompressAfter,
+	}
+}
+
+// runLogPruner applies r to the task logs in db every interval until ctx
+// ends.
+func runLogPruner(ctx context.Context, db *storage.DB, r config.LogRetentionConfig) {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = defaultLogPruneInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		res, err := db.PruneLogs(logRetention(r))
+		if err != nil {
+			slog.Warn("log retention", "err", err)
+		}
+		if res.Deleted > 0 || res.Compressed > 0 {
+			slog.Info("log retention: pruned task logs", "deleted", res.Deleted, "compressed", res.Compressed)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+	"github.com/spf13/cobra"
+)
+
+var devCmd = &cobra.Command{
+	Use:   "dev",
+	Short: "Watch the working tree and re-run deploy and tests on change",
+	Long: `Watch the local working tree and, whenever files change, run the configured
+deploy commands on this machine followed by the tests selected for the changed
+files. Variables and test selection work as in the automated pipeline, but no
+task, branch, PR or AI call is involved.
+
+Deploy commands always use the local transport in dev mode.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configPath, _ := cmd.Flags().GetString("config")
+		interval, _ := cmd.Flags().GetDuration("interval")
+		once, _ := cmd.Flags().GetBool("once")
+
+		if configPath == "" {
+			configPath = "rig.yaml"
+		}
+		if interval <= 0 {
+			return fmt.Errorf("--interval must be positive")
+		}
+
+		cfg, err := config.LoadConfig(configPath)
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+		forceLocalTransport(cfg)
+
+		deployAdapter, err := newDeployAdapter(cfg)
+		if err != nil {
+			return err
+		}
+		engine := core.NewEngine(cfg, nil, nil, deployAdapter, newTestRunners(cfg, nil), nil, "")
+
+		// Stream engine logs to the terminal instead of the structured logger.
+		engine.SetLogger(slog.New(slog.DiscardHandler))
+		engine.SetLogFunc(func(_, level, msg string) {
+			prefix := ""
+			if level == "error" {
+				prefix = "✗ "
+			}
+			for _, line := range strings.Split(msg, "\n") {
+				fmt.Printf("[%s] %s%s\n", time.Now().Format("15:04:05"), prefix, line)
+			}
+		})
+
+		branch, err := gitOutput(".", "rev-parse", "--abbrev-ref", "HEAD")
+		if err != nil {
+			branch = ""
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		runCycle := func(changed []string) error {
+			res, err := engine.DevCycle(ctx, branch, changed)
+			if err != nil {
+				return err
+			}
+			switch {
+			case res.Passed:
+				fmt.Println("✓ deploy and tests passed")
+			case res.Deploy != nil && res.Deploy.Status != "success":
+				fmt.Println("✗ deploy failed")
+			default:
+				fmt.Println("✗ tests failed")
+			}
+			return nil
+		}
+
+		snap := snapshotTree(".")
+		if err := runCycle(nil); err != nil {
+			return err
+		}
+		if once {
+			return nil
+		}
+
+		fmt.Printf("Watching for changes every %s (Ctrl+C to stop)...\n", interval)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var pending map[string]bool
+		for {
+			select {
+			case <-ctx.Done():
+				fmt.Println("\nstopped.")
+				return nil
+			case <-ticker.C:
+			}
+
+			next := snapshotTree(".")
+			changed := diffSnapshots(snap, next)
+			snap = next
+			if len(changed) > 0 {
+				// Keep collecting until a poll sees no further changes, so a
+				// burst of saves triggers a single cycle.
+				if pending == nil {
+					pending = map[string]bool{}
+				}
+				for _, f := range changed {
+					pending[f] = true
+				}
+				continue
+			}
+			if len(pending) == 0 {
+				continue
+			}
+
+			files := make([]string, 0, len(pending))
+			for f := range pending {
+				files = append(files, f)
+			}
+			sort.Strings(files)
+			pending = nil
+
+			fmt.Printf("\n%d file(s) changed: %s\n", len(files), summarizeFiles(files, 5))
+			if err := runCycle(files); err != nil {
+				if ctx.Err() != nil {
+					fmt.Println("\nstopped.")
+					return nil
+				}
+				fmt.Fprintf(os.Stderr, "dev cycle failed: %v\n", err)
+			}
+			// Changes made while the cycle ran (e.g. build output) are not
+			// a reason to run again.
+			snap = snapshotTree(".")
+		}
+	},
+}
+
+// forceLocalTransport makes every custom deploy command run on this machine.
+func forceLocalTransport(cfg *config.Config) {
+	for i := range cfg.Deploy.Config.Commands {
+		c := &cfg.Deploy.Config.Commands[i]
+		if c.Transport.Type == "ssh" {
+			fmt.Fprintf(os.Stderr, "warning: deploy command %q uses ssh; running it locally in dev mode\n", c.Name)
+		}
+		c.Transport.Type = "local"
+	}
+}
+
+// fileStamp identifies a version of a file for change detection.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// snapshotTree records the files of the working tree in dir. Inside a git
+// repository it lists tracked and untracked files that are not ignored;
+// otherwise it walks the directory, skipping .git, .rig and node_modules.
+func snapshotTree(dir string) map[string]fileStamp {
+	snap := map[string]fileStamp{}
+	add := func(rel string) {
+		if info, err := os.Stat(filepath.Join(dir, rel)); err == nil && !info.IsDir() {
+			snap[filepath.ToSlash(rel)] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+
+	if out, err := gitOutput(dir, "ls-files", "-co", "--exclude-standard"); err == nil {
+		for _, rel := range strings.Split(out, "\n") {
+			if rel != "" && !strings.HasPrefix(rel, ".rig/") {
+				add(rel)
+			}
+		}
+		return snap
+	}
+
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			switch d.Name() {
+			case ".git", ".rig", "node_modules":
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if rel, err := filepath.Rel(dir, path); err == nil {
+			add(rel)
+		}
+		return nil
+	})
+	return snap
+}
+
+// diffSnapshots returns the files added, modified or removed between two
+// snapshots.
+func diffSnapshots(prev, next map[string]fileStamp) []string {
+	var changed []string
+	for f, s := range next {
+		if p, ok := prev[f]; !ok || !p.modTime.Equal(s.modTime) || p.size != s.size {
+			changed = append(changed, f)
+		}
+	}
+	for f := range prev {
+		if _, ok := next[f]; !ok {
+			changed = append(changed, f)
+		}
+	}
+	return changed
+}
+
+// summarizeFiles joins up to max file names, noting how many were left out.
+func summarizeFiles(files []string, max int) string {
+	if len(files) <= max {
+		return strings.Join(files, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(files[:max], ", "), len(files)-max)
+}
@@ -1,11 +1,16 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
+	"time"
 
+	adaptergit "github.com/rigdev/rig/internal/adapter/git"
 	"github.com/rigdev/rig/internal/config"
 	"github.com/spf13/cobra"
 )
@@ -14,52 +19,58 @@ var doctorCmd = &cobra.Command{
 	Use:   "doctor",
 	Short: "Check environment and configuration health",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		allOK := true
-
-		fmt.Println("=== Rig Doctor ===")
-		fmt.Println()
+		report := &doctorReport{OK: true}
 
 		// Check git.
 		if checkCommand("git", "--version") {
-			fmt.Println("[OK] git is installed")
+			report.add(checkOK, "git", "git is installed")
 		} else {
-			fmt.Println("[FAIL] git is not installed or not in PATH")
-			allOK = false
+			report.add(checkFail, "git", "git is not installed or not in PATH")
 		}
 
 		// Check go.
 		if checkCommand("go", "version") {
-			fmt.Println("[OK] go is installed")
+			report.add(checkOK, "go", "go is installed")
 		} else {
-			fmt.Println("[FAIL] go is not installed or not in PATH")
-			allOK = false
+			report.add(checkFail, "go", "go is not installed or not in PATH")
 		}
 
 		// Check config file.
 		configPath := "rig.yaml"
 		if _, err := os.Stat(configPath); err == nil {
-			fmt.Printf("[OK] config file found: %s\n", configPath)
+			report.add(checkOK, "config", fmt.Sprintf("config file found: %s", configPath))
 
 			// Try to validate (may fail due to env vars).
-			if _, err := config.LoadConfig(configPath); err != nil {
-				fmt.Printf("[WARN] config validation: %v\n", err)
+			if cfg, err := config.LoadConfig(configPath); err != nil {
+				report.add(checkWarn, "config", fmt.Sprintf("config validation: %v", err))
 			} else {
-				fmt.Println("[OK] config is valid")
+				report.add(checkOK, "config", "config is valid")
+				checkGitHubRateLimit(cmd.Context(), cfg, report)
+				checkTokenPermissions(cmd.Context(), cfg, report)
 			}
 		} else {
-			fmt.Printf("[WARN] config file not found: %s (run 'rig init' to create one)\n", configPath)
+			report.add(checkWarn, "config", fmt.Sprintf("config file not found: %s (run 'rig init' to create one)", configPath))
 		}
 
 		// Check state directory.
 		stateDir := filepath.Join(".", ".rig")
 		if _, err := os.Stat(stateDir); err == nil {
-			fmt.Printf("[OK] state directory exists: %s\n", stateDir)
+			report.add(checkOK, "state", fmt.Sprintf("state directory exists: %s", stateDir))
 		} else {
-			fmt.Printf("[INFO] state directory not found: %s (will be created on first execution)\n", stateDir)
+			report.add(checkInfo, "state", fmt.Sprintf("state directory not found: %s (will be created on first execution)", stateDir))
+		}
+
+		if format := outputFormat(cmd); format != outputText {
+			return writeStructured(os.Stdout, format, report)
 		}
 
+		fmt.Println("=== Rig Doctor ===")
+		fmt.Println()
+		for _, c := range report.Checks {
+			fmt.Printf("[%s] %s\n", strings.ToUpper(c.Status), c.Message)
+		}
 		fmt.Println()
-		if allOK {
+		if report.OK {
 			fmt.Println("All checks passed!")
 		} else {
 			fmt.Println("Some checks failed. Please fix the issues above.")
@@ -69,6 +80,153 @@ var doctorCmd = &cobra.Command{
 	},
 }
 
+// Doctor check statuses.
+const (
+	checkOK   = "ok"
+	checkWarn = "warn"
+	checkFail = "fail"
+	checkInfo = "info"
+)
+
+// doctorCheck is the outcome of one doctor check.
+type doctorCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"` // ok | warn | fail | info
+	Message string `json:"message"`
+}
+
+// doctorReport collects the doctor checks; OK is false once any check fails.
+type doctorReport struct {
+	OK     bool          `json:"ok"`
+	Checks []doctorCheck `json:"checks"`
+}
+
+func (r *doctorReport) add(status, name, message string) {
+	if status == checkFail {
+		r.OK = false
+	}
+	r.Checks = append(r.Checks, doctorCheck{Name: name, Status: status, Message: message})
+}
+
+// lowRateLimitHeadroom is the fraction of remaining GitHub API calls below
+// which doctor warns.
+const lowRateLimitHeadroom = 0.1
+
+// checkGitHubRateLimit reports the remaining GitHub API rate limit for the configured token.
+func checkGitHubRateLimit(ctx context.Context, cfg *config.Config, report *doctorReport) {
+	if cfg.Source.Platform != "github" || cfg.Source.Token == "" || cfg.Source.Offline {
+		return
+	}
+	owner, repo, err := splitRepo(cfg.Source.Repo)
+	if err != nil {
+		return
+	}
+	gh, err := newGitAdapter(cfg, owner, repo)
+	if err != nil {
+		report.add(checkWarn, "github_rate_limit", fmt.Sprintf("github client: %v", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	rl, err := gh.FetchRateLimit(ctx)
+	if err != nil {
+		report.add(checkWarn, "github_rate_limit", fmt.Sprintf("github rate limit: %v", err))
+		return
+	}
+
+	status := checkOK
+	if rl.Headroom() < lowRateLimitHeadroom {
+		status = checkWarn
+	}
+	report.add(status, "github_rate_limit", fmt.Sprintf("github rate limit: %d/%d remaining (resets %s)",
+		rl.Remaining, rl.Limit, rl.Reset.Local().Format("15:04:05")))
+}
+
+// githubRepos returns the source repo and every GitHub project repo, once each.
+func githubRepos(cfg *config.Config) []string {
+	repos := []string{cfg.Source.Repo}
+	seen := map[string]bool{cfg.Source.Repo: true}
+	for _, p := range cfg.Projects {
+		if (p.Platform == "" || p.Platform == "github") && p.Repo != "" && !seen[p.Repo] {
+			seen[p.Repo] = true
+			repos = append(repos, p.Repo)
+		}
+	}
+	return repos
+}
+
+// tokenPermissionReports checks the token against every configured GitHub
+// repo. Checks that could not run are returned as errors per repo.
+func tokenPermissionReports(ctx context.Context, cfg *config.Config) ([]*adaptergit.PermissionReport, map[string]error) {
+	if cfg.Source.Platform != "github" || cfg.Source.Token == "" || cfg.Source.Offline {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
+	defer cancel()
+
+	var reports []*adaptergit.PermissionReport
+	errs := map[string]error{}
+	for _, full := range githubRepos(cfg) {
+		owner, repo, err := splitRepo(full)
+		if err != nil {
+			errs[full] = err
+			continue
+		}
+		gh, err := newGitAdapter(cfg, owner, repo)
+		if err != nil {
+			errs[full] = err
+			continue
+		}
+		report, err := gh.CheckPermissions(ctx)
+		if err != nil {
+			errs[full] = err
+			continue
+		}
+		reports = append(reports, report)
+	}
+	return reports, errs
+}
+
+// checkTokenPermissions adds the token permission check of every
+// configured repo to the doctor report.
+func checkTokenPermissions(ctx context.Context, cfg *config.Config, report *doctorReport) {
+	reports, errs := tokenPermissionReports(ctx, cfg)
+	for repo, err := range errs {
+		report.add(checkWarn, "github_token_permissions", fmt.Sprintf("github token permissions for %s: %v", repo, err))
+	}
+	for _, r := range reports {
+		if r.OK() {
+			report.add(checkOK, "github_token_permissions", fmt.Sprintf("github token can read issues, push branches and open PRs on %s", r.Repo))
+			continue
+		}
+		for _, line := range strings.Split(r.String(), "\n") {
+			report.add(checkFail, "github_token_permissions", line)
+		}
+	}
+}
+
+// verifyTokenPermissions fails startup when the token is missing a
+// permission the pipeline needs, instead of letting a task fail mid-way on
+// a git push. A check that cannot run only warns, so a GitHub outage does
+// not keep rig from starting.
+func verifyTokenPermissions(ctx context.Context, cfg *config.Config) error {
+	reports, errs := tokenPermissionReports(ctx, cfg)
+	for repo, err := range errs {
+		slog.Warn("could not verify github token permissions", "repo", repo, "err", err)
+	}
+	var missing []string
+	for _, r := range reports {
+		if !r.OK() {
+			missing = append(missing, r.String())
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("github token is missing permissions:\n%s", strings.Join(missing, "\n"))
+	}
+	return nil
+}
+
 // checkCommand checks if a command is available in PATH.
 func checkCommand(name string, args ...string) bool {
 	cmd := exec.Command(name, args...)
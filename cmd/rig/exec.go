@@ -1,58 +1,112 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"log/slog"
 	"regexp"
+	"slices"
 	"strconv"
+	"sync"
+	"time"
 
 	adapterai "github.com/rigdev/rig/internal/adapter/ai"
+	adapterartifact "github.com/rigdev/rig/internal/adapter/artifact"
 	adapterdeploy "github.com/rigdev/rig/internal/adapter/deploy"
 	adaptergit "github.com/rigdev/rig/internal/adapter/git"
 	adapternotify "github.com/rigdev/rig/internal/adapter/notify"
+	adapterplugin "github.com/rigdev/rig/internal/adapter/plugin"
 	adaptertest "github.com/rigdev/rig/internal/adapter/test"
 	"github.com/rigdev/rig/internal/config"
 	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/events"
+	"github.com/rigdev/rig/internal/fixmemory"
+	"github.com/rigdev/rig/internal/format"
+	"github.com/rigdev/rig/internal/hook"
+	"github.com/rigdev/rig/internal/index"
+	"github.com/rigdev/rig/internal/storage"
 	"github.com/spf13/cobra"
 )
 
-const defaultStatePath = ".rig/state.json"
+// defaultStatePath is the task state file: $RIG_STATE_PATH, or
+// .rig/state.json in the working directory.
+var defaultStatePath = envOr("RIG_STATE_PATH", ".rig/state.json")
 
 var execCmd = &cobra.Command{
-	Use:   "exec <issue-url>",
+	Use:   "exec [issue-url]",
 	Short: "Execute the full automation cycle for an issue",
-	Args:  cobra.ExactArgs(1),
+	Long: `Execute the full automation cycle for an issue once, locally.
+
+The issue is given either as a GitHub issue URL or, with --issue, as a number
+in the configured source repository:
+
+  rig exec https://github.com/acme/api/issues/123
+  rig exec --issue 123
+
+With environments configured, the issue's deploy:<name> label or --env picks
+the one to deploy to.
+
+--step runs a single pipeline step (plan, code, commit, deploy, test or
+report) and --from/--to a range of them. With --task they run on a task of an
+earlier run, reusing its plan, workspace, branch and deploy, so one part of
+the pipeline can be iterated on:
+
+  rig exec --task task-1 --from deploy --to test
+  rig exec --task task-1 --step code
+
+Without --task a new task is started for the issue and runs from plan up to
+the step.`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		issueURL := args[0]
 		configPath, _ := cmd.Flags().GetString("config")
 		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		dryRunDir, _ := cmd.Flags().GetString("dry-run-dir")
 		step, _ := cmd.Flags().GetString("step")
+		fromFlag, _ := cmd.Flags().GetString("from")
+		toFlag, _ := cmd.Flags().GetString("to")
+		taskID, _ := cmd.Flags().GetString("task")
+		issueFlag, _ := cmd.Flags().GetInt("issue")
+		noCache, _ := cmd.Flags().GetBool("no-cache")
+		env, _ := cmd.Flags().GetString("env")
 
 		if configPath == "" {
 			configPath = "rig.yaml"
 		}
 
-		// Validate --step flag if provided.
-		validSteps := map[string]bool{"code": true, "deploy": true, "test": true}
-		if step != "" && !validSteps[step] {
-			return fmt.Errorf("invalid --step %q: must be one of code, deploy, test", step)
-		}
-
-		// Parse issue URL to extract metadata.
-		issue, err := parseIssueURL(issueURL)
+		from, to, ranged, err := parseStepFlags(step, fromFlag, toFlag, taskID != "")
 		if err != nil {
-			return fmt.Errorf("invalid issue URL: %w", err)
+			return err
+		}
+		if taskID != "" {
+			if len(args) > 0 || issueFlag > 0 {
+				return fmt.Errorf("give either an issue or --task, not both")
+			}
+		} else if (len(args) == 1) == (issueFlag > 0) {
+			return fmt.Errorf("give either an issue URL or --issue <number>")
 		}
 
-		// Load configuration and apply step filter to workflow.
 		cfg, err := config.LoadConfig(configPath)
 		if err != nil {
 			return fmt.Errorf("load config: %w", err)
 		}
+		if err := setupLogging(cmd, cfg, nil); err != nil {
+			return err
+		}
+
+		ctx := cmd.Context()
+		if noCache {
+			ctx = core.WithoutAICache(ctx)
+		}
+		if taskID != "" {
+			return execTaskSteps(ctx, cfg, taskID, from, to, dryRun)
+		}
 
-		// If --step is provided, restrict workflow to only that step.
-		if step != "" {
-			cfg.Workflow.Steps = []string{step}
-			fmt.Printf("Running only step: %s\n", step)
+		var issue core.Issue
+		if issueFlag > 0 {
+			issue = issueFromNumber(cfg, issueFlag)
+		} else if issue, err = parseIssueURL(cfg, args[0]); err != nil {
+			return fmt.Errorf("invalid issue URL: %w", err)
 		}
 
 		issueNumber, err := strconv.Atoi(issue.ID)
@@ -61,20 +115,33 @@ var execCmd = &cobra.Command{
 		}
 
 		// Fetch full issue details (title, body) from GitHub API.
+		// Offline mode has no platform to ask.
 		owner, repo, err := splitRepo(cfg.Source.Repo)
 		if err != nil {
 			return err
 		}
-		gitAdapter, err := adaptergit.NewGitHub(owner, repo, cfg.Source.Token, cfg.Server.Secret, "")
+		gitAdapter, err := newGitAdapter(cfg, owner, repo)
 		if err != nil {
 			return fmt.Errorf("create git adapter: %w", err)
 		}
-		ghIssue, err := gitAdapter.GetIssue(cmd.Context(), owner, repo, issueNumber)
-		if err != nil {
-			fmt.Printf("Warning: could not fetch issue details: %v\n", err)
-		} else {
-			issue.Title = ghIssue.Title
-			issue.Body = ghIssue.Body
+		if !cfg.Source.Offline {
+			ghIssue, err := gitAdapter.GetIssue(cmd.Context(), owner, repo, issueNumber)
+			if err != nil {
+				fmt.Printf("Warning: could not fetch issue details: %v\n", err)
+			} else {
+				issue.Title = ghIssue.Title
+				issue.Body = ghIssue.Body
+				issue.Labels = ghIssue.Labels
+			}
+		}
+		if env != "" {
+			issue.Labels = append([]string{core.EnvironmentLabelPrefix + env}, issue.Labels...)
+		}
+
+		if !dryRun {
+			if err := verifyTokenPermissions(cmd.Context(), cfg); err != nil {
+				return err
+			}
 		}
 
 		engine, err := buildEngineForIssue(cfg, defaultStatePath, issueNumber)
@@ -82,16 +149,28 @@ var execCmd = &cobra.Command{
 			return err
 		}
 		engine.SetDryRun(dryRun)
+		engine.SetDryRunDir(dryRunDir)
+		engine.AddNotifier(progressNotifier{})
 
 		if dryRun {
-			fmt.Printf("Dry-run mode: would execute issue %s (%s)\n", issue.ID, issue.Title)
+			fmt.Printf("Dry-run mode: planning and generating code for issue %s (%s)\n", issue.ID, issue.Title)
+		} else {
+			recordCLIAudit(storage.AuditTaskCreated, issue.Repo+"#"+issue.ID, "")
 		}
 
-		if err := engine.Execute(cmd.Context(), issue); err != nil {
+		if ranged {
+			task, err := engine.StartTask(ctx, issue)
+			if err != nil {
+				return fmt.Errorf("start task: %w", err)
+			}
+			return runExecSteps(ctx, engine, task.ID, from, to)
+		}
+		if err := engine.Execute(ctx, issue); err != nil {
 			return fmt.Errorf("execution failed: %w", err)
 		}
 
 		if dryRun {
+			printDryRun(issue.ID)
 			fmt.Println("Dry-run completed successfully.")
 		} else {
 			fmt.Println("Execution completed successfully.")
@@ -110,7 +189,7 @@ func buildEngineForIssue(cfg *config.Config, statePath string, issueNumber int)
 		return nil, err
 	}
 
-	gitAdapter, err := adaptergit.NewGitHub(owner, repo, cfg.Source.Token, cfg.Server.Secret, "")
+	gitAdapter, err := newGitAdapter(cfg, owner, repo)
 	if err != nil {
 		return nil, fmt.Errorf("create git adapter: %w", err)
 	}
@@ -120,24 +199,20 @@ func buildEngineForIssue(cfg *config.Config, statePath string, issueNumber int)
 		return nil, fmt.Errorf("create ai adapter: %w", err)
 	}
 
-	deployAdapter, err := adapterdeploy.NewCustom(cfg.Deploy.Config, cfg.Deploy.Rollback.Config)
+	deployAdapter, err := newDeployAdapter(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("create deploy adapter: %w", err)
-	}
-	if err := deployAdapter.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid deploy adapter config: %w", err)
-	}
-
-	testRunners := make([]core.TestRunnerIface, 0, len(cfg.Test))
-	for _, testCfg := range cfg.Test {
-		if testCfg.Type != "" && testCfg.Type != "command" {
-			continue
-		}
-		testRunners = append(testRunners, adaptertest.NewCommandRunner(testCfg))
+		return nil, err
 	}
+	testRunners := newTestRunners(cfg, gitAdapter)
 
 	notifiers := make([]core.NotifierIface, 0, len(cfg.Notify))
 	for _, notifyCfg := range cfg.Notify {
+		if notifyCfg.Type == "plugin" {
+			if p := cfg.FindPlugin(notifyCfg.Plugin); p != nil {
+				notifiers = append(notifiers, adapterplugin.NewNotifier(adapterplugin.New(*p), notifyCfg.Options))
+			}
+			continue
+		}
 		if (notifyCfg.Type == "slack" || notifyCfg.Type == "discord") && notifyCfg.Webhook != "" {
 			notifiers = append(notifiers, adapternotify.NewWebhookNotifier(notifyCfg.Type, notifyCfg.Webhook))
 			continue
@@ -152,23 +227,302 @@ func buildEngineForIssue(cfg *config.Config, statePath string, issueNumber int)
 		notifiers = append(notifiers, adapternotify.NewCommentNotifier(gitAdapter, owner, repo, issueNumber))
 	}
 
-	return core.NewEngine(cfg, gitAdapter, aiAdapter, deployAdapter, testRunners, notifiers, statePath), nil
+	engine := core.NewEngine(cfg, gitAdapter, aiAdapter, deployAdapter, testRunners, notifiers, statePath)
+	if cfg.Deploy.Strategy != "" {
+		targets, err := newDeployTargets(cfg)
+		if err != nil {
+			return nil, err
+		}
+		engine.SetDeployStrategy(cfg.Deploy.Strategy, targets)
+	}
+	if len(cfg.Environments) > 0 {
+		envs, err := newEnvironments(cfg, engine)
+		if err != nil {
+			return nil, err
+		}
+		engine.SetEnvironments(envs)
+	}
+	if cfg.AI.Index.Enabled {
+		retriever, err := newFileRetriever(cfg)
+		if err != nil {
+			slog.Warn("ai.index disabled", "err", err)
+		} else {
+			engine.SetFileRetriever(retriever)
+		}
+	}
+	if len(cfg.Workflow.Hooks) > 0 {
+		engine.SetHookRunner(hook.New(gitAdapter))
+	}
+	if cfg.Workflow.Format.Enabled {
+		engine.SetFormatter(format.New(gitAdapter, cfg.Workflow.Format))
+	}
+	if db, err := sharedDB(); err != nil {
+		slog.Warn("saved AGENTS.md disabled, using the checkout's only", "err", err)
+	} else {
+		engine.SetAgentsStore(db)
+	}
+	if fm := cfg.AI.FixMemory; fm.Enabled {
+		db, err := sharedDB()
+		if err != nil {
+			slog.Warn("ai.fix_memory disabled", "err", err)
+		} else {
+			engine.SetFixMemory(fixmemory.New(db, cfg.Source.Repo, fm.Hints, fm.MinSimilarity))
+		}
+	}
+	if cfg.Artifacts.Enabled {
+		store, err := adapterartifact.New(cfg.Artifacts, statePath)
+		if err != nil {
+			slog.Warn("artifacts disabled", "err", err)
+		} else {
+			engine.SetArtifactStore(store)
+		}
+	}
+	if d := cfg.Workflow.Dedup; d.Enabled && d.Method == "embedding" {
+		embedder, err := adapterai.NewEmbedder(cfg.AI)
+		if err != nil {
+			slog.Warn("workflow.dedup: embedding comparison disabled, comparing words", "err", err)
+		} else {
+			engine.SetIssueMatcher(index.NewIssueMatcher(embedder))
+		}
+	}
+	if len(cfg.Events) > 0 {
+		publisher, err := sharedEventPublisher(cfg.Events)
+		if err != nil {
+			slog.Warn("events disabled", "err", err)
+		} else {
+			engine.SetEventPublisher(publisher)
+		}
+	}
+	return engine, nil
+}
+
+// newDeployAdapter creates and validates the configured deploy adapter.
+func newDeployAdapter(cfg *config.Config) (core.DeployAdapterIface, error) {
+	if cfg.Deploy.Method == "plugin" {
+		p := cfg.FindPlugin(cfg.Deploy.Config.Plugin)
+		if p == nil {
+			return nil, fmt.Errorf("create deploy adapter: plugin %q is not declared", cfg.Deploy.Config.Plugin)
+		}
+		deployAdapter := adapterplugin.NewDeployAdapter(adapterplugin.New(*p), cfg.Deploy.Config.Options)
+		if err := deployAdapter.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid deploy adapter config: %w", err)
+		}
+		return deployAdapter, nil
+	}
+	deployAdapter, err := adapterdeploy.NewCustom(cfg.Deploy.Config, cfg.Deploy.Rollback.Config)
+	if err != nil {
+		return nil, fmt.Errorf("create deploy adapter: %w", err)
+	}
+	if err := deployAdapter.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid deploy adapter config: %w", err)
+	}
+	return deployAdapter, nil
+}
+
+// newDeployTargets creates and validates an adapter for every target of
+// deploy.strategy. Targets without commands of their own use the deploy
+// and rollback commands of deploy.config.
+func newDeployTargets(cfg *config.Config) ([]core.DeployTarget, error) {
+	targets := make([]core.DeployTarget, 0, len(cfg.Deploy.Targets))
+	for _, t := range cfg.Deploy.Targets {
+		commands := config.DeployMethodConfig{Commands: t.Commands}
+		if len(commands.Commands) == 0 {
+			commands = cfg.Deploy.Config
+		}
+		rollback := config.DeployMethodConfig{Commands: t.Rollback}
+		if len(rollback.Commands) == 0 {
+			rollback = cfg.Deploy.Rollback.Config
+		}
+		adapter, err := adapterdeploy.NewCustom(commands, rollback)
+		if err != nil {
+			return nil, fmt.Errorf("create deploy adapter for target %s: %w", t.Name, err)
+		}
+		if err := adapter.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid deploy target %s: %w", t.Name, err)
+		}
+		targets = append(targets, core.DeployTarget{Name: t.Name, Adapter: adapter, Vars: t.Vars})
+	}
+	return targets, nil
+}
+
+// newEnvironments creates the deploy environments. An environment with a
+// transport of its own gets deploy adapters whose commands all use it.
+func newEnvironments(cfg *config.Config, engine *core.Engine) ([]core.Environment, error) {
+	envs := make([]core.Environment, 0, len(cfg.Environments))
+	for _, env := range cfg.Environments {
+		e := core.Environment{Name: env.Name, Default: env.Default, Vars: env.Vars, Approval: env.Approval}
+		if env.Transport.Type != "" {
+			envCfg := *cfg
+			envCfg.Deploy = deployWithTransport(cfg.Deploy, env.Transport)
+			deploy, err := newDeployAdapter(&envCfg)
+			if err != nil {
+				return nil, fmt.Errorf("environment %s: %w", env.Name, err)
+			}
+			if envCfg.Deploy.Strategy != "" {
+				targets, err := newDeployTargets(&envCfg)
+				if err != nil {
+					return nil, fmt.Errorf("environment %s: %w", env.Name, err)
+				}
+				deploy = engine.StagedDeploy(envCfg.Deploy.Strategy, targets)
+			}
+			e.Deploy = deploy
+		}
+		envs = append(envs, e)
+	}
+	return envs, nil
+}
+
+// deployWithTransport returns a copy of d whose deploy and rollback
+// commands, including those of its targets, run over t.
+func deployWithTransport(d config.DeployConfig, t config.TransportConfig) config.DeployConfig {
+	withTransport := func(cmds []config.CustomCommand) []config.CustomCommand {
+		out := slices.Clone(cmds)
+		for i := range out {
+			out[i].Transport = t
+		}
+		return out
+	}
+	d.Config.Commands = withTransport(d.Config.Commands)
+	d.Rollback.Config.Commands = withTransport(d.Rollback.Config.Commands)
+	d.Targets = slices.Clone(d.Targets)
+	for i := range d.Targets {
+		d.Targets[i].Commands = withTransport(d.Targets[i].Commands)
+		d.Targets[i].Rollback = withTransport(d.Targets[i].Rollback)
+	}
+	return d
+}
+
+// newTestRunners creates a runner for every runnable test in config order.
+// Commands, coverage runs and browser suites run in the workspace of ws.
+func newTestRunners(cfg *config.Config, ws core.WorkspaceProvider) []core.TestRunnerIface {
+	testRunners := make([]core.TestRunnerIface, 0, len(cfg.Test))
+	for _, testCfg := range cfg.Test {
+		switch testCfg.Type {
+		case "", "command":
+			testRunners = append(testRunners, adaptertest.NewCommandRunner(testCfg, ws))
+		case "http":
+			testRunners = append(testRunners, adaptertest.NewHTTPRunner(testCfg))
+		case "browser":
+			testRunners = append(testRunners, adaptertest.NewBrowserRunner(testCfg, ws))
+		case "coverage":
+			testRunners = append(testRunners, adaptertest.NewCoverageRunner(testCfg, ws))
+		case "plugin":
+			if p := cfg.FindPlugin(testCfg.Plugin); p != nil {
+				testRunners = append(testRunners, adapterplugin.NewTestRunner(adapterplugin.New(*p), testCfg))
+			}
+		}
+	}
+	return testRunners
+}
+
+// defaultPatchDir is where offline mode writes patch files.
+const defaultPatchDir = ".rig/patches"
+
+// newGitAdapter creates the GitHub adapter with the source's API URL, mirror
+// and offline settings applied. Every task works in its own worktree.
+func newGitAdapter(cfg *config.Config, owner, repo string) (*adaptergit.GitHub, error) {
+	gh, err := adaptergit.NewGitHub(owner, repo, cfg.Source.Token, cfg.Server.Secret, cfg.Source.APIURL)
+	if err != nil {
+		return nil, err
+	}
+	workspaces, err := newWorkspaces(cfg)
+	if err != nil {
+		return nil, err
+	}
+	gh.SetWorkspaces(workspaces)
+	gh.SetCloneOptions(adaptergit.CloneOptions{
+		Depth:        cfg.Source.Clone.Depth,
+		SingleBranch: cfg.Source.Clone.SingleBranch,
+		Sparse:       cfg.Source.Clone.Sparse,
+		Reference:    cfg.Source.Clone.Reference,
+	})
+	gh.SetAuthor(adaptergit.Author{
+		Name:     cfg.Source.GitAuthor.Name,
+		Email:    cfg.Source.GitAuthor.Email,
+		Identity: cfg.Source.GitAuthor.Identity,
+		AppSlug:  cfg.Source.GitAuthor.AppSlug,
+	})
+	gh.SetSigning(adaptergit.Signing{Format: cfg.Source.Signing.Format, Key: cfg.Source.Signing.Key})
+	if cfg.Source.Mirror != "" {
+		gh.SetMirror(cfg.Source.Mirror)
+	}
+	if cfg.Source.Offline {
+		patchDir := cfg.Source.PatchDir
+		if patchDir == "" {
+			patchDir = defaultPatchDir
+		}
+		gh.SetOffline(patchDir)
+	}
+	return gh, nil
+}
+
+// newWorkspaces returns the checkouts source.workspaces configures.
+func newWorkspaces(cfg *config.Config) (*adaptergit.Workspaces, error) {
+	return adaptergit.NewWorkspaces(cfg.Source.Workspaces.Root, cfg.Source.Workspaces.MaxBytes)
 }
 
 // newAIAdapter creates the appropriate AI adapter based on the provider config.
+// With ai.cache enabled, its answers are cached in the rig database.
 func newAIAdapter(cfg config.AIConfig) (core.AIAdapter, error) {
-	switch cfg.Provider {
-	case "anthropic", "":
-		return adapterai.NewAnthropic(cfg)
-	case "openai":
-		return adapterai.NewOpenAI(cfg)
-	case "ollama":
-		return adapterai.NewOllama(cfg)
-	case "claude-code":
-		return adapterai.NewClaudeCode(cfg)
-	default:
-		return nil, fmt.Errorf("unsupported ai provider %q: supported providers are anthropic, openai, ollama", cfg.Provider)
+	adapter, err := adapterai.New(cfg)
+	if err != nil || !cfg.Cache.Enabled {
+		return adapter, err
+	}
+	db, err := sharedDB()
+	if err != nil {
+		slog.Warn("ai.cache disabled: open database", "err", err)
+		return adapter, nil
+	}
+	return adapterai.WithCache(adapter, cfg, db), nil
+}
+
+// newFileRetriever creates the ai.index embedding index of the source
+// repository.
+func newFileRetriever(cfg *config.Config) (*index.Index, error) {
+	embedder, err := adapterai.NewEmbedder(cfg.AI)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sharedDB()
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
 	}
+	idx := index.New(db, embedder, cfg.Source.Repo, cfg.AI.Index.TopK)
+	idx.SetIgnore(cfg.AI.ContextIgnore)
+	return idx, nil
+}
+
+// sharedDB opens the database ai.cache, ai.index, ai.fix_memory and the
+// saved AGENTS.md use once per process; it stays open for the engines built
+// later, such as the ones rig run builds per webhook.
+var sharedDB = sync.OnceValues(func() (*storage.DB, error) {
+	return storage.Open(defaultDBPath())
+})
+
+var (
+	eventPublishersMu sync.Mutex
+	eventPublishers   = map[string]*events.Publisher{}
+)
+
+// sharedEventPublisher returns one publisher per events config for the
+// process, so the engines rig serve builds per task share NATS connections.
+// A reloaded config with other sinks gets a publisher of its own.
+func sharedEventPublisher(sinks []config.EventSinkConfig) (*events.Publisher, error) {
+	key, err := json.Marshal(sinks)
+	if err != nil {
+		return nil, err
+	}
+	eventPublishersMu.Lock()
+	defer eventPublishersMu.Unlock()
+	if p, ok := eventPublishers[string(key)]; ok {
+		return p, nil
+	}
+	p, err := events.New(sinks)
+	if err != nil {
+		return nil, err
+	}
+	eventPublishers[string(key)] = p
+	return p, nil
 }
 
 func splitRepo(repo string) (string, string, error) {
@@ -180,24 +534,136 @@ func splitRepo(repo string) (string, string, error) {
 	return matches[1], matches[2], nil
 }
 
-// parseIssueURL extracts issue metadata from a GitHub issue URL.
-// Supports: https://github.com/{owner}/{repo}/issues/{number}
-func parseIssueURL(url string) (core.Issue, error) {
-	re := regexp.MustCompile(`https?://github\.com/([^/]+)/([^/]+)/issues/(\d+)`)
-	matches := re.FindStringSubmatch(url)
-	if len(matches) != 4 {
-		return core.Issue{}, fmt.Errorf("URL must match https://github.com/{owner}/{repo}/issues/{number}")
+// issueFromNumber builds the issue for --issue from the configured source
+// repository. Title and body are filled in from the platform afterwards.
+func issueFromNumber(cfg *config.Config, number int) core.Issue {
+	return core.Issue{
+		Platform: cfg.Source.Platform,
+		Repo:     cfg.Source.Repo,
+		ID:       strconv.Itoa(number),
+		Title:    fmt.Sprintf("Issue #%d", number),
+		URL:      core.NewIssueHosts(cfg).URL(cfg.Source.Platform, "", cfg.Source.Repo, strconv.Itoa(number)),
 	}
+}
 
-	owner := matches[1]
-	repo := matches[2]
-	number := matches[3]
+// parseStepFlags returns the step range of the --step, --from and --to
+// flags of rig exec, and whether one was given. A task started by the
+// command has no earlier steps to reuse, so without --task the range starts
+// at plan.
+func parseStepFlags(step, from, to string, hasTask bool) (core.StepName, core.StepName, bool, error) {
+	if step == "" && from == "" && to == "" {
+		if hasTask {
+			return "", "", false, fmt.Errorf("--task needs --step or --from/--to")
+		}
+		return "", "", false, nil
+	}
+	if step != "" && (from != "" || to != "") {
+		return "", "", false, fmt.Errorf("give either --step or --from/--to")
+	}
+	fromFlag, toFlag := "--from", "--to"
+	if step != "" {
+		from, to = step, step
+		fromFlag, toFlag = "--step", "--step"
+	}
+	first, last := core.StepPlan, core.StepReport
+	var err error
+	if from != "" {
+		if first, err = core.ParseStepName(from); err != nil {
+			return "", "", false, fmt.Errorf("invalid %s: %w", fromFlag, err)
+		}
+	}
+	if to != "" {
+		if last, err = core.ParseStepName(to); err != nil {
+			return "", "", false, fmt.Errorf("invalid %s: %w", toFlag, err)
+		}
+	}
+	if !hasTask && first != core.StepPlan {
+		if step == "" {
+			return "", "", false, fmt.Errorf("--from %s needs --task <id> of an earlier run", first)
+		}
+		fmt.Printf("No --task given: running a new task from plan to %s\n", last)
+		first = core.StepPlan
+	}
+	if _, err := core.StepRange(first, last); err != nil {
+		return "", "", false, err
+	}
+	return first, last, true, nil
+}
 
-	return core.Issue{
-		Platform: "github",
-		Repo:     owner + "/" + repo,
-		ID:       number,
-		Title:    fmt.Sprintf("Issue #%s", number),
-		URL:      url,
-	}, nil
+// execTaskSteps runs a step range of an existing task for rig exec --task.
+func execTaskSteps(ctx context.Context, cfg *config.Config, taskID string, from, to core.StepName, dryRun bool) error {
+	state, err := core.LoadState(defaultStatePath)
+	if err != nil {
+		return fmt.Errorf("load state: %w", err)
+	}
+	task := state.GetTaskByID(taskID)
+	if task == nil {
+		return fmt.Errorf("task %q not found", taskID)
+	}
+	issueNumber, _ := strconv.Atoi(task.Issue.ID)
+	engine, err := buildEngineForIssue(cfg, defaultStatePath, issueNumber)
+	if err != nil {
+		return err
+	}
+	engine.SetDryRun(dryRun)
+	engine.AddNotifier(progressNotifier{})
+	return runExecSteps(ctx, engine, taskID, from, to)
+}
+
+// runExecSteps runs the steps from..to of a task and prints how each went.
+func runExecSteps(ctx context.Context, engine *core.Engine, taskID string, from, to core.StepName) error {
+	fmt.Printf("Running steps %s..%s of task %s\n", from, to, taskID)
+	records, err := engine.RunSteps(ctx, taskID, from, to)
+	for _, rec := range records {
+		mark := "✓"
+		if rec.Status != "success" {
+			mark = "✗"
+		}
+		fmt.Printf("%s %s (%s)\n", mark, rec.Step, rec.Status)
+	}
+	if err != nil {
+		return fmt.Errorf("execution failed: %w", err)
+	}
+	if steps, _ := core.StepRange(to, core.StepReport); len(steps) > 1 {
+		fmt.Printf("Stopped after %s. Continue with: rig exec --task %s --from %s\n", to, taskID, steps[1])
+	}
+	return nil
+}
+
+// printDryRun prints the plan and diff of the latest dry run of an issue.
+func printDryRun(issueID string) {
+	state, err := core.LoadState(defaultStatePath)
+	if err != nil {
+		return
+	}
+	for i := len(state.Tasks) - 1; i >= 0; i-- {
+		task := &state.Tasks[i]
+		if task.Issue.ID != issueID || task.DryRun == nil {
+			continue
+		}
+		fmt.Printf("\n%s\n", core.FormatPlan(task.DryRun.Plan))
+		if task.DryRun.Diff == "" {
+			fmt.Println("(no changes)")
+		} else {
+			fmt.Print(task.DryRun.Diff)
+		}
+		if task.DryRun.Dir != "" {
+			fmt.Printf("\nPlan and diff written to %s (task %s)\n", task.DryRun.Dir, task.ID)
+		}
+		return
+	}
+}
+
+// progressNotifier prints phase changes to the terminal during rig exec.
+type progressNotifier struct{}
+
+func (progressNotifier) Notify(ctx context.Context, message string) error {
+	fmt.Printf("[%s] %s\n", time.Now().Format("15:04:05"), message)
+	return nil
+}
+
+// parseIssueURL extracts issue metadata from the URL of an issue on any
+// supported platform, with the self-hosted hosts cfg names.
+func parseIssueURL(cfg *config.Config, url string) (core.Issue, error) {
+	return core.NewIssueHosts(cfg).Parse(url)
 }
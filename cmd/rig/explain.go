@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/rigdev/rig/internal/config"
 	"github.com/rigdev/rig/internal/core"
@@ -16,22 +19,38 @@ var explainCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		taskID := args[0]
-		statePath := defaultStatePath
 		useAI, _ := cmd.Flags().GetBool("ai")
 		configPath, _ := cmd.Flags().GetString("config")
 		if configPath == "" {
 			configPath = "rig.yaml"
 		}
 
-		state, err := core.LoadState(statePath)
+		format := outputFormat(cmd)
+		task, err := openTaskStore(cmd).Task(cmd.Context(), taskID)
+		var notFound errTaskNotFound
+		if errors.As(err, &notFound) {
+			if format != outputText {
+				return err
+			}
+			fmt.Fprintf(os.Stdout, "%s task %q not found\n", markerWarn(), taskID)
+			return nil
+		}
 		if err != nil {
-			return fmt.Errorf("load state: %w", err)
+			return err
 		}
 
-		task := state.GetTaskByID(taskID)
-		if task == nil {
-			fmt.Fprintf(os.Stdout, "%s task %q not found\n", markerWarn(), taskID)
-			return nil
+		if format != outputText {
+			report := explainReport{Task: task}
+			if useAI {
+				if analysisLogs, currentCode := buildFailureAnalysisInput(task); strings.TrimSpace(analysisLogs) != "" {
+					suggestions, err := analyzeFailureWithAI(cmd.Context(), configPath, analysisLogs, currentCode)
+					if err != nil {
+						return err
+					}
+					report.AISuggestions = suggestions
+				}
+			}
+			return writeStructured(os.Stdout, format, report)
 		}
 
 		fmt.Fprintf(os.Stdout, "Task: %s\n", task.ID)
@@ -118,6 +137,12 @@ var explainCmd = &cobra.Command{
 			}
 		}
 
+		if ex := task.Explanation; ex != nil {
+			fmt.Fprintln(os.Stdout)
+			fmt.Fprintf(os.Stdout, "Diagnosis of attempt #%d (%s, %s):\n", ex.Attempt, nonEmpty(ex.RequestedBy), ex.GeneratedAt.Format(time.RFC3339))
+			fmt.Fprintln(os.Stdout, indentLines(ex.Diagnosis, "  "))
+		}
+
 		if useAI {
 			fmt.Fprintln(os.Stdout)
 			fmt.Fprintln(os.Stdout, "AI Failure Analysis:")
@@ -128,19 +153,9 @@ var explainCmd = &cobra.Command{
 				return nil
 			}
 
-			cfg, err := config.LoadConfig(configPath)
+			suggestions, err := analyzeFailureWithAI(cmd.Context(), configPath, analysisLogs, currentCode)
 			if err != nil {
-				return fmt.Errorf("load config: %w", err)
-			}
-
-			aiAdapter, err := newAIAdapter(cfg.AI)
-			if err != nil {
-				return fmt.Errorf("create ai adapter: %w", err)
-			}
-
-			suggestions, err := aiAdapter.AnalyzeFailure(cmd.Context(), analysisLogs, currentCode)
-			if err != nil {
-				return fmt.Errorf("analyze failure with ai: %w", err)
+				return err
 			}
 
 			if len(suggestions) == 0 {
@@ -163,6 +178,31 @@ var explainCmd = &cobra.Command{
 	},
 }
 
+// explainReport is the structured output of rig explain.
+type explainReport struct {
+	Task          *core.Task          `json:"task"`
+	AISuggestions []core.AIFileChange `json:"ai_suggestions,omitempty"`
+}
+
+// analyzeFailureWithAI asks the configured AI provider for fixes.
+func analyzeFailureWithAI(ctx context.Context, configPath, analysisLogs string, currentCode map[string]string) ([]core.AIFileChange, error) {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+
+	aiAdapter, err := newAIAdapter(cfg.AI)
+	if err != nil {
+		return nil, fmt.Errorf("create ai adapter: %w", err)
+	}
+
+	suggestions, err := aiAdapter.AnalyzeFailure(ctx, analysisLogs, currentCode)
+	if err != nil {
+		return nil, fmt.Errorf("analyze failure with ai: %w", err)
+	}
+	return suggestions, nil
+}
+
 func buildFailureAnalysisInput(task *core.Task) (string, map[string]string) {
 	var b strings.Builder
 	currentCode := make(map[string]string)
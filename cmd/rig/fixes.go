@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rigdev/rig/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var fixesCmd = &cobra.Command{
+	Use:   "fixes",
+	Short: "Inspect the memory of past fixes (ai.fix_memory)",
+	Long: `With ai.fix_memory, a retry whose tests pass is remembered as the fix of
+the failure it retried. Later retries of similar failures get those fixes
+as hints. USES counts the retries given a fix as a hint and PASSED how many
+of them passed their tests.
+
+Fixes live in the local database.`,
+}
+
+var fixesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List remembered fixes, newest first",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo, _ := cmd.Flags().GetString("repo")
+		db, err := storage.Open(defaultDBPath())
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer db.Close()
+		fixes, err := db.ListFixes(repo)
+		if err != nil {
+			return err
+		}
+
+		if format := outputFormat(cmd); format != outputText {
+			return writeStructured(os.Stdout, format, fixes)
+		}
+		if len(fixes) == 0 {
+			fmt.Println("No fixes remembered.")
+			return nil
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tREPO\tTASK\tUSES\tPASSED\tCREATED\tSIGNATURE")
+		for _, f := range fixes {
+			signature, _, _ := strings.Cut(f.Signature, "\n")
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%d\t%s\t%s\n", f.ID, f.Repo, f.TaskID, f.Uses, f.Successes,
+				f.CreatedAt.Local().Format("2006-01-02 15:04"), truncate(signature, 60))
+		}
+		return tw.Flush()
+	},
+}
+
+var fixesForgetCmd = &cobra.Command{
+	Use:   "forget <id>",
+	Short: "Forget a remembered fix that misleads failure analysis",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid fix id %q", args[0])
+		}
+		db, err := storage.Open(defaultDBPath())
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer db.Close()
+		ok, err := db.DeleteFix(id)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("fix %d not found", id)
+		}
+		fmt.Printf("Fix %d forgotten.\n", id)
+		return nil
+	},
+}
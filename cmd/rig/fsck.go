@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var fsckCmd = &cobra.Command{
+	Use:   "fsck",
+	Short: "Check state and database for inconsistent task data",
+	Long: "Validates task invariants in state.json and the SQLite database: orphaned proposals, " +
+		"attempts and pipeline steps left running, and task branches missing on the remote. " +
+		"Without --repair nothing is changed and the report shows what would be fixed.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configPath, _ := cmd.Flags().GetString("config")
+		statePath, _ := cmd.Flags().GetString("state")
+		repair, _ := cmd.Flags().GetBool("repair")
+		skipRemote, _ := cmd.Flags().GetBool("no-remote")
+
+		if configPath == "" {
+			configPath = "rig.yaml"
+		}
+		if statePath == "" {
+			statePath = defaultStatePath
+		}
+
+		state, err := core.LoadState(statePath)
+		if err != nil {
+			return fmt.Errorf("load state: %w", err)
+		}
+
+		var branches core.BranchChecker
+		if !skipRemote {
+			branches = fsckBranchChecker(configPath)
+		}
+		findings, err := core.Fsck(cmd.Context(), state, branches)
+		if err != nil {
+			return err
+		}
+
+		db, dbFindings, err := fsckDatabase(state)
+		if err != nil {
+			return err
+		}
+		if db != nil {
+			defer db.Close()
+		}
+		findings = append(findings, dbFindings...)
+
+		if len(findings) == 0 {
+			fmt.Println("No problems found.")
+			return nil
+		}
+
+		fmt.Fprintf(os.Stdout, "%-30s %-18s %-8s %s\n", "TASK ID", "KIND", "REPAIR", "DETAIL")
+		fmt.Println("------------------------------------------------------------------------------------")
+		repairable := 0
+		for _, f := range findings {
+			action := "manual"
+			if f.Repairable {
+				repairable++
+				action = "auto"
+			}
+			fmt.Fprintf(os.Stdout, "%-30s %-18s %-8s %s\n", f.TaskID, f.Kind, action, f.Detail)
+		}
+		fmt.Println()
+
+		if !repair {
+			fmt.Printf("%d problem(s), %d repairable. Dry run: run 'rig fsck --repair' to fix them.\n",
+				len(findings), repairable)
+			return nil
+		}
+
+		// Re-check under the state lock so repairs never act on stale data.
+		// Missing branches are not repairable, so the remote is not asked again.
+		repaired := 0
+		err = core.WithState(statePath, func(s *core.State) error {
+			current, err := core.Fsck(context.Background(), s, nil)
+			if err != nil {
+				return err
+			}
+			repaired = core.RepairState(s, current)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("repair state: %w", err)
+		}
+
+		if db != nil {
+			state, err := core.LoadState(statePath)
+			if err != nil {
+				return fmt.Errorf("reload state: %w", err)
+			}
+			for _, f := range dbFindings {
+				if task := state.GetTaskByID(f.TaskID); task != nil && f.Repairable {
+					if err := db.SaveTask(task); err != nil {
+						return fmt.Errorf("save task %s: %w", task.ID, err)
+					}
+					repaired++
+				}
+			}
+		}
+
+		if repaired > 0 {
+			recordCLIAudit(storage.AuditStateRepaired, statePath, fmt.Sprintf("%d task(s)", repaired))
+		}
+
+		fmt.Printf("Repaired %d task(s). %d problem(s) need manual attention.\n",
+			repaired, len(findings)-repairable)
+		return nil
+	},
+}
+
+// fsckBranchChecker returns the git adapter for remote branch checks, or nil
+// when no usable config is present or the source is offline.
+func fsckBranchChecker(configPath string) core.BranchChecker {
+	if _, err := os.Stat(configPath); err != nil {
+		return nil
+	}
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		fmt.Printf("Warning: skipping remote branch check: %v\n", err)
+		return nil
+	}
+	if cfg.Source.Offline && cfg.Source.Mirror == "" {
+		return nil
+	}
+	owner, repo, err := splitRepo(cfg.Source.Repo)
+	if err != nil {
+		return nil
+	}
+	gh, err := newGitAdapter(cfg, owner, repo)
+	if err != nil {
+		fmt.Printf("Warning: skipping remote branch check: %v\n", err)
+		return nil
+	}
+	return gh
+}
+
+// fsckDatabase compares the SQLite task mirror with state.json. Tasks whose
+// stored status differs from state are repairable by re-saving them; tasks
+// that only exist in the database are reported. The database is never
+// created here; a nil DB means there is nothing to check.
+func fsckDatabase(state *core.State) (*storage.DB, []core.FsckFinding, error) {
+	dbPath := defaultDBPath()
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, nil, nil
+	}
+	db, err := storage.Open(dbPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open database: %w", err)
+	}
+	tasks, err := db.ListTasks()
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+
+	var findings []core.FsckFinding
+	for _, stored := range tasks {
+		task := state.GetTaskByID(stored.ID)
+		switch {
+		case task == nil:
+			findings = append(findings, core.FsckFinding{
+				TaskID: stored.ID,
+				Kind:   core.FsckStaleDBTask,
+				Detail: "task exists in the database but not in state.json",
+			})
+		case task.Status != stored.Status:
+			findings = append(findings, core.FsckFinding{
+				TaskID:     stored.ID,
+				Kind:       core.FsckStaleDBTask,
+				Detail:     fmt.Sprintf("database status %s, state status %s", stored.Status, task.Status),
+				Repairable: true,
+			})
+		}
+	}
+	return db, findings, nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var indexCmd = &cobra.Command{
+	Use:   "index [path]",
+	Short: "Build or update the ai.index embedding index of a checkout",
+	Long: `Embed the files of a checkout of the source repository (default: the
+current directory) into the ai.index embedding index. Only new and changed
+files are embedded. rig updates the index by itself before each retrieval;
+running this ahead of time keeps the first task on a large repository fast.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configPath, _ := cmd.Flags().GetString("config")
+		if configPath == "" {
+			configPath = "rig.yaml"
+		}
+		workspace := "."
+		if len(args) == 1 {
+			workspace = args[0]
+		}
+
+		cfg, err := config.LoadConfig(configPath)
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+		if !cfg.AI.Index.Enabled {
+			return fmt.Errorf("ai.index is not enabled in %s", configPath)
+		}
+		idx, err := newFileRetriever(cfg)
+		if err != nil {
+			return err
+		}
+		stats, err := idx.Update(cmd.Context(), workspace)
+		if err != nil {
+			return fmt.Errorf("update index: %w", err)
+		}
+		fmt.Printf("Indexed %d files of %s: %d embedded (%d chunks), %d removed\n",
+			stats.Files, cfg.Source.Repo, stats.Embedded, stats.Chunks, stats.Removed)
+		return nil
+	},
+}
@@ -1,18 +1,31 @@
 package main
 
 import (
+	"bufio"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
 
 var initCmd = &cobra.Command{
 	Use:   "init",
-	Short: "Generate a rig.yaml configuration template",
+	Short: "Create rig.yaml for this repository",
+	Long: `Create rig.yaml and the .rig/ directory for the repository in the current
+directory.
+
+On a terminal, init detects the project language, GitHub remote, AI provider
+and deploy preset, then asks to confirm or change each value. With --yes (or
+without a terminal) the detected values are used as-is. --template writes one
+of the static templates instead (custom|docker), or a complete project
+template (go-service|node-app|k8s-app|terraform-infra) that also adds sample
+tests, deploy scripts and a GitHub webhook setup script. Existing files are
+never overwritten.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		tmpl, _ := cmd.Flags().GetString("template")
+		yes, _ := cmd.Flags().GetBool("yes")
 		outPath := filepath.Join(".", "rig.yaml")
 
 		if _, err := os.Stat(outPath); err == nil {
@@ -20,19 +33,55 @@ var initCmd = &cobra.Command{
 		}
 
 		var content string
-		switch tmpl {
-		case "docker":
+		var extra []templateFile
+		switch {
+		case cmd.Flags().Changed("template") && tmpl == "docker":
 			content = dockerTemplate()
-		default:
+		case cmd.Flags().Changed("template") && isStackTemplate(tmpl):
+			files, err := renderStackTemplate(tmpl, detectInitAnswers("."))
+			if err != nil {
+				return err
+			}
+			content, extra = files[0].Content, files[1:]
+		case cmd.Flags().Changed("template") && tmpl != "custom":
+			return fmt.Errorf("unknown template %q (custom|docker|%s)", tmpl, strings.Join(stackTemplates, "|"))
+		case cmd.Flags().Changed("template"):
 			content = customTemplate()
+		default:
+			p := &prompter{in: bufio.NewReader(os.Stdin), out: os.Stdout, auto: yes || !isTerminal(os.Stdin)}
+			answers := runInitWizard(p, detectInitAnswers("."))
+			rendered, err := renderWizardConfig(answers)
+			if err != nil {
+				return err
+			}
+			content = rendered
+			tmpl = "wizard"
 		}
 
 		if err := os.WriteFile(outPath, []byte(content), 0644); err != nil {
 			return fmt.Errorf("write rig.yaml: %w", err)
 		}
+		if err := scaffoldRigDir("."); err != nil {
+			return err
+		}
+		written, skipped, err := writeTemplateFiles(".", extra)
+		if err != nil {
+			return err
+		}
 
-		fmt.Printf("Created rig.yaml (template: %s)\n", tmpl)
-		fmt.Println("Edit the file and set your environment variables before running 'rig validate'.")
+		fmt.Printf("Created rig.yaml (%s) and .rig/\n", tmpl)
+		for _, f := range written {
+			fmt.Printf("  created %s\n", f)
+		}
+		for _, f := range skipped {
+			fmt.Printf("  kept existing %s\n", f)
+		}
+		if len(extra) > 0 {
+			fmt.Println("Register the GitHub webhook with: RIG_WEBHOOK_URL=https://<rig host> scripts/rig-setup-webhook.sh")
+		}
+		if missing := missingEnvVars(content); len(missing) > 0 {
+			fmt.Printf("Set these environment variables before running 'rig validate': %s\n", strings.Join(missing, ", "))
+		}
 		return nil
 	},
 }
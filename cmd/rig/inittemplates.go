@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// templateFS holds the project templates for rig init --template. Each
+// template is a directory whose files are rendered with text/template and
+// written without their .tmpl suffix; common/ is added to every template.
+//
+//go:embed templates
+var templateFS embed.FS
+
+// stackTemplates are the bundled project templates, in the order shown in
+// help and error messages.
+var stackTemplates = []string{"go-service", "node-app", "k8s-app", "terraform-infra"}
+
+// templateData is what the template files are rendered with.
+type templateData struct {
+	initAnswers
+	Template string
+}
+
+// templateFile is one rendered file of a project template.
+type templateFile struct {
+	Path    string
+	Content string
+	Mode    os.FileMode
+}
+
+func isStackTemplate(name string) bool {
+	for _, t := range stackTemplates {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+// renderStackTemplate renders every file of the named template with the
+// detected project values. rig.yaml comes first and is validated like the
+// wizard's output.
+func renderStackTemplate(name string, a initAnswers) ([]templateFile, error) {
+	partials, err := template.ParseFS(templateFS, "templates/partials.tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("parse template partials: %w", err)
+	}
+	data := templateData{initAnswers: a, Template: name}
+
+	var files []templateFile
+	for _, root := range []string{"templates/" + name, "templates/common"} {
+		err := fs.WalkDir(templateFS, root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			src, err := templateFS.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			t, err := template.Must(partials.Clone()).New(path.Base(p)).Parse(string(src))
+			if err != nil {
+				return fmt.Errorf("parse %s: %w", p, err)
+			}
+			var b bytes.Buffer
+			if err := t.Execute(&b, data); err != nil {
+				return fmt.Errorf("render %s: %w", p, err)
+			}
+			rel := strings.TrimSuffix(strings.TrimPrefix(p, root+"/"), ".tmpl")
+			mode := os.FileMode(0o644)
+			if strings.HasSuffix(rel, ".sh") {
+				mode = 0o755
+			}
+			files = append(files, templateFile{Path: rel, Content: b.String(), Mode: mode})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].Path == "rig.yaml" && files[j].Path != "rig.yaml"
+	})
+	if len(files) == 0 || files[0].Path != "rig.yaml" {
+		return nil, fmt.Errorf("template %s has no rig.yaml", name)
+	}
+	if err := validateRenderedConfig(files[0].Content); err != nil {
+		return nil, fmt.Errorf("template %s: %w", name, err)
+	}
+	return files, nil
+}
+
+// writeTemplateFiles writes the files of a rendered template under dir,
+// keeping files that already exist. It returns the paths written and
+// skipped.
+func writeTemplateFiles(dir string, files []templateFile) (written, skipped []string, err error) {
+	for _, f := range files {
+		target := filepath.Join(dir, filepath.FromSlash(f.Path))
+		if fileExists(target) {
+			skipped = append(skipped, f.Path)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return written, skipped, fmt.Errorf("create %s: %w", filepath.Dir(f.Path), err)
+		}
+		if err := os.WriteFile(target, []byte(f.Content), f.Mode); err != nil {
+			return written, skipped, fmt.Errorf("write %s: %w", f.Path, err)
+		}
+		written = append(written, f.Path)
+	}
+	return written, skipped, nil
+}
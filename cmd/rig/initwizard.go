@@ -0,0 +1,362 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/rigdev/rig/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// initAnswers are the values the init wizard fills into rig.yaml.
+type initAnswers struct {
+	Name         string
+	Language     string
+	Repo         string
+	BaseBranch   string
+	TokenEnv     string
+	Provider     string
+	Model        string
+	APIKeyEnv    string
+	DeployMethod string
+	ComposeFile  string
+	BuildCommand string
+	TestCommand  string
+}
+
+// providerDefaults are the model and API key variable offered per provider.
+var providerDefaults = map[string]struct{ model, keyEnv string }{
+	"anthropic":   {"claude-sonnet-4-20250514", "ANTHROPIC_API_KEY"},
+	"openai":      {"gpt-4o", "OPENAI_API_KEY"},
+	"ollama":      {"llama3.1", ""},
+	"claude-code": {"", ""},
+}
+
+// languageCommands are the build and test commands offered per language.
+var languageCommands = map[string]struct{ build, test string }{
+	"go":         {"go build ./...", "go test ./..."},
+	"typescript": {"npm run build", "npm test"},
+	"javascript": {"npm run build", "npm test"},
+	"python":     {"", "pytest"},
+	"rust":       {"cargo build", "cargo test"},
+	"java":       {"mvn -q package -DskipTests", "mvn -q test"},
+}
+
+// languageMarkers map a file in the repo root to the project language. The
+// first match wins, so more specific markers come first.
+var languageMarkers = []struct{ file, language string }{
+	{"go.mod", "go"},
+	{"tsconfig.json", "typescript"},
+	{"package.json", "javascript"},
+	{"pyproject.toml", "python"},
+	{"requirements.txt", "python"},
+	{"setup.py", "python"},
+	{"Cargo.toml", "rust"},
+	{"pom.xml", "java"},
+	{"build.gradle", "java"},
+}
+
+// composeFiles are the docker compose file names looked for in the repo root.
+var composeFiles = []string{"docker-compose.yml", "docker-compose.yaml", "compose.yml", "compose.yaml"}
+
+// githubRemote matches the owner/repo part of https and ssh GitHub remotes.
+var githubRemote = regexp.MustCompile(`github\.com[:/]([^/]+)/([^/]+?)(?:\.git)?/?$`)
+
+// detectInitAnswers fills in defaults from the repository in dir and the
+// environment.
+func detectInitAnswers(dir string) initAnswers {
+	a := initAnswers{
+		Name:         filepath.Base(dir),
+		Repo:         "owner/repo",
+		BaseBranch:   "main",
+		TokenEnv:     "GITHUB_TOKEN",
+		DeployMethod: "custom",
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		a.Name = filepath.Base(abs)
+	}
+
+	for _, m := range languageMarkers {
+		if fileExists(filepath.Join(dir, m.file)) {
+			a.Language = m.language
+			break
+		}
+	}
+	cmds := languageCommands[a.Language]
+	a.BuildCommand, a.TestCommand = cmds.build, cmds.test
+	if a.TestCommand == "" {
+		a.TestCommand = "make test"
+	}
+
+	if out, err := gitOutput(dir, "remote", "get-url", "origin"); err == nil {
+		if m := githubRemote.FindStringSubmatch(out); m != nil {
+			a.Repo = m[1] + "/" + m[2]
+		}
+	}
+	if out, err := gitOutput(dir, "symbolic-ref", "--short", "refs/remotes/origin/HEAD"); err == nil {
+		a.BaseBranch = strings.TrimPrefix(out, "origin/")
+	}
+
+	for _, f := range composeFiles {
+		if fileExists(filepath.Join(dir, f)) {
+			a.DeployMethod = "docker-compose"
+			a.ComposeFile = f
+			break
+		}
+	}
+
+	a.Provider = detectProvider()
+	a.Model = providerDefaults[a.Provider].model
+	a.APIKeyEnv = providerDefaults[a.Provider].keyEnv
+	return a
+}
+
+// detectProvider picks the AI provider whose credentials or tools are present.
+func detectProvider() string {
+	switch {
+	case os.Getenv("ANTHROPIC_API_KEY") != "":
+		return "anthropic"
+	case os.Getenv("OPENAI_API_KEY") != "":
+		return "openai"
+	}
+	if _, err := exec.LookPath("claude"); err == nil {
+		return "claude-code"
+	}
+	if _, err := exec.LookPath("ollama"); err == nil {
+		return "ollama"
+	}
+	return "anthropic"
+}
+
+// gitOutput runs git in dir and returns its trimmed stdout.
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// prompter asks questions on a terminal. With auto set it accepts every
+// default without reading input.
+type prompter struct {
+	in   *bufio.Reader
+	out  io.Writer
+	auto bool
+}
+
+// ask prompts for a free-form value; an empty answer keeps def.
+func (p *prompter) ask(label, def string) string {
+	if p.auto {
+		return def
+	}
+	if def != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", label, def)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", label)
+	}
+	line, err := p.in.ReadString('\n')
+	if err != nil {
+		// Input ended: accept this and all remaining defaults.
+		fmt.Fprintln(p.out)
+		p.auto = true
+	}
+	if line = strings.TrimSpace(line); line != "" {
+		return line
+	}
+	return def
+}
+
+// choose prompts until the answer is one of options.
+func (p *prompter) choose(label string, options []string, def string) string {
+	for {
+		answer := p.ask(fmt.Sprintf("%s (%s)", label, strings.Join(options, "/")), def)
+		for _, o := range options {
+			if answer == o {
+				return answer
+			}
+		}
+		if p.auto {
+			return def
+		}
+		fmt.Fprintf(p.out, "  please answer one of: %s\n", strings.Join(options, ", "))
+	}
+}
+
+// runInitWizard asks for the rig.yaml settings, starting from what was
+// detected in the repository.
+func runInitWizard(p *prompter, a initAnswers) initAnswers {
+	if a.Language != "" {
+		fmt.Fprintf(p.out, "Detected %s project", a.Language)
+	} else {
+		fmt.Fprint(p.out, "Could not detect the project language")
+	}
+	fmt.Fprintf(p.out, ", repository %s (base branch %s).\n\n", a.Repo, a.BaseBranch)
+
+	a.Name = p.ask("Project name", a.Name)
+	a.Language = p.ask("Language", a.Language)
+	a.Repo = p.ask("GitHub repository (owner/repo)", a.Repo)
+	a.BaseBranch = p.ask("Base branch", a.BaseBranch)
+	a.TokenEnv = p.ask("Environment variable holding the GitHub token", a.TokenEnv)
+
+	provider := p.choose("AI provider", []string{"anthropic", "openai", "ollama", "claude-code"}, a.Provider)
+	if provider != a.Provider {
+		a.Model = providerDefaults[provider].model
+		a.APIKeyEnv = providerDefaults[provider].keyEnv
+	}
+	a.Provider = provider
+	a.Model = p.ask("Model", a.Model)
+	if a.Provider == "anthropic" || a.Provider == "openai" {
+		a.APIKeyEnv = p.ask("Environment variable holding the API key", a.APIKeyEnv)
+	}
+
+	a.DeployMethod = p.choose("Deploy preset", []string{"custom", "docker-compose"}, a.DeployMethod)
+	if a.DeployMethod == "docker-compose" {
+		if a.ComposeFile == "" {
+			a.ComposeFile = "docker-compose.yml"
+		}
+		a.ComposeFile = p.ask("Compose file", a.ComposeFile)
+	} else {
+		a.BuildCommand = p.ask("Build command", a.BuildCommand)
+	}
+	a.TestCommand = p.ask("Test command", a.TestCommand)
+	return a
+}
+
+var wizardTemplate = template.Must(template.New("rig.yaml").Parse(`project:
+  name: {{.Name}}
+  language: {{.Language}}
+  description: ""
+
+source:
+  platform: github
+  repo: {{.Repo}}
+  base_branch: {{.BaseBranch}}
+  token: ${ {{- .TokenEnv -}} }
+
+ai:
+  provider: {{.Provider}}
+{{- if .Model}}
+  model: {{.Model}}
+{{- end}}
+{{- if .APIKeyEnv}}
+  api_key: ${ {{- .APIKeyEnv -}} }
+{{- end}}
+  max_retry: 3
+
+deploy:
+{{- if eq .DeployMethod "docker-compose"}}
+  method: docker-compose
+  config:
+    file: {{.ComposeFile}}
+  timeout: 300s
+  rollback:
+    enabled: true
+    method: docker-compose
+    config:
+      file: {{.ComposeFile}}
+{{- else}}
+  method: custom
+  config:
+    commands:
+      - name: build
+        run: "{{if .BuildCommand}}{{.BuildCommand}}{{else}}true{{end}}"
+        workdir: "."
+        timeout: 300s
+        transport:
+          type: local
+  timeout: 300s
+  rollback:
+    enabled: false
+{{- end}}
+
+test:
+  - type: command
+    name: test
+    run: "{{.TestCommand}}"
+    timeout: 600s
+
+workflow:
+  trigger:
+    - event: issues.opened
+      labels: ["rig"]
+
+notify:
+  - type: comment
+    on: ["all"]
+
+server:
+  port: 8080
+  secret: ${WEBHOOK_SECRET}
+`))
+
+// renderWizardConfig renders rig.yaml and validates the result, so the wizard
+// never writes a file that rig validate would reject.
+func renderWizardConfig(a initAnswers) (string, error) {
+	var b strings.Builder
+	if err := wizardTemplate.Execute(&b, a); err != nil {
+		return "", fmt.Errorf("render rig.yaml: %w", err)
+	}
+	if err := validateRenderedConfig(b.String()); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// validateRenderedConfig checks that generated rig.yaml content parses and
+// passes config validation. ${VAR} references are checked as written.
+func validateRenderedConfig(content string) error {
+	var cfg config.Config
+	if err := yaml.Unmarshal([]byte(content), &cfg); err != nil {
+		return fmt.Errorf("generated rig.yaml does not parse: %w", err)
+	}
+	return config.Validate(&cfg)
+}
+
+// scaffoldRigDir creates .rig/ and keeps its runtime files out of git.
+func scaffoldRigDir(dir string) error {
+	rigDir := filepath.Join(dir, ".rig")
+	if err := os.MkdirAll(rigDir, 0o755); err != nil {
+		return fmt.Errorf("create .rig: %w", err)
+	}
+	ignore := filepath.Join(rigDir, ".gitignore")
+	if fileExists(ignore) {
+		return nil
+	}
+	if err := os.WriteFile(ignore, []byte("# rig state, logs and patches are local to this machine\n*\n"), 0o644); err != nil {
+		return fmt.Errorf("write .rig/.gitignore: %w", err)
+	}
+	return nil
+}
+
+// missingEnvVars lists the ${VAR} references in content that are not set.
+func missingEnvVars(content string) []string {
+	var missing []string
+	seen := map[string]bool{}
+	for _, m := range regexp.MustCompile(`\$\{([^}]+)\}`).FindAllStringSubmatch(content, -1) {
+		if name := m[1]; !seen[name] {
+			seen[name] = true
+			if _, ok := os.LookupEnv(name); !ok {
+				missing = append(missing, name)
+			}
+		}
+	}
+	return missing
+}
+
+// isTerminal reports whether f is an interactive terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
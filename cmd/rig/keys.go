@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/rigdev/rig/internal/storage"
+	"github.com/rigdev/rig/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+var keysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "Manage dashboard API keys and their roles",
+	Long: `Manage API keys of the dashboard API. Roles:
+
+  viewer    read tasks, proposals, logs and metrics
+  operator  viewer + create, retry and stop tasks
+  approver  viewer + approve and reject proposals
+  admin     everything, including settings, audit and keys
+
+Keys live in the local database, or on the server with --server (which
+needs an admin key). RIG_API_KEY always works as an admin key. A key
+created with --tenant only reaches the API of that tenant.`,
+}
+
+var keysListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List API keys",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var keys []storage.APIKey
+		if rc := newRemoteClient(cmd); rc != nil {
+			var err error
+			if keys, err = rc.api.ListAPIKeys(cmd.Context()); err != nil {
+				return fmt.Errorf("list keys: %w", err)
+			}
+		} else {
+			db, err := storage.Open(defaultDBPath())
+			if err != nil {
+				return fmt.Errorf("open database: %w", err)
+			}
+			defer db.Close()
+			if keys, err = db.ListAPIKeys(); err != nil {
+				return err
+			}
+		}
+
+		if format := outputFormat(cmd); format != outputText {
+			return writeStructured(os.Stdout, format, keys)
+		}
+		if len(keys) == 0 {
+			fmt.Println("No API keys.")
+			return nil
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tROLE\tTENANT\tKEY\tCREATED")
+		for _, k := range keys {
+			tenant := k.Tenant
+			if tenant == "" {
+				tenant = "-"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s…\t%s\n", k.Name, k.Role, tenant, k.Prefix, k.CreatedAt.Local().Format("2006-01-02 15:04"))
+		}
+		return tw.Flush()
+	},
+}
+
+var keysCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create an API key and print it once",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		roleName, _ := cmd.Flags().GetString("role")
+		role, err := storage.ParseRole(roleName)
+		if err != nil {
+			return err
+		}
+		tenant, _ := cmd.Flags().GetString("tenant")
+
+		var key string
+		if rc := newRemoteClient(cmd); rc != nil {
+			resp, err := rc.api.CreateAPIKey(cmd.Context(), client.CreateAPIKeyRequest{Name: args[0], Role: string(role), Tenant: tenant})
+			if err != nil {
+				return fmt.Errorf("create key: %w", err)
+			}
+			key = resp.Key
+		} else {
+			db, err := storage.Open(defaultDBPath())
+			if err != nil {
+				return fmt.Errorf("open database: %w", err)
+			}
+			defer db.Close()
+			if key, _, err = db.CreateTenantAPIKey(args[0], role, tenant); err != nil {
+				return err
+			}
+			details := string(role)
+			if tenant != "" {
+				details += ", tenant " + tenant
+			}
+			recordCLIAudit(storage.AuditKeyCreated, args[0], details)
+		}
+
+		fmt.Println(key)
+		fmt.Fprintf(os.Stderr, "Created %s key %q. Store it now; it cannot be shown again.\n", role, args[0])
+		return nil
+	},
+}
+
+var keysDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Revoke an API key",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if rc := newRemoteClient(cmd); rc != nil {
+			if _, err := rc.api.DeleteAPIKey(cmd.Context(), args[0]); err != nil {
+				return fmt.Errorf("delete key: %w", err)
+			}
+		} else {
+			db, err := storage.Open(defaultDBPath())
+			if err != nil {
+				return fmt.Errorf("open database: %w", err)
+			}
+			defer db.Close()
+			if err := db.DeleteAPIKey(args[0]); err != nil {
+				return err
+			}
+			recordCLIAudit(storage.AuditKeyDeleted, args[0], "")
+		}
+
+		fmt.Printf("Revoked key %q.\n", args[0])
+		return nil
+	},
+}
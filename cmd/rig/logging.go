@@ -0,0 +1,66 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/logging"
+	"github.com/rigdev/rig/internal/secret"
+	"github.com/spf13/cobra"
+)
+
+// setupLogging installs the default structured logger. --log-level and
+// --log-format win over RIG_LOG_LEVEL and RIG_LOG_FORMAT, which win over the
+// log section of cfg. Records carrying a task_id also go to sink when set.
+func setupLogging(cmd *cobra.Command, cfg *config.Config, sink logging.TaskSink) error {
+	var opts logging.Options
+	if cfg != nil {
+		opts = logging.Options{Level: cfg.Log.Level, Format: cfg.Log.Format}
+	}
+	if v := os.Getenv("RIG_LOG_LEVEL"); v != "" {
+		opts.Level = v
+	}
+	if v := os.Getenv("RIG_LOG_FORMAT"); v != "" {
+		opts.Format = v
+	}
+	if v, _ := cmd.Flags().GetString("log-level"); v != "" {
+		opts.Level = v
+	}
+	if v, _ := cmd.Flags().GetString("log-format"); v != "" {
+		opts.Format = v
+	}
+	// Secrets resolved from ${secret:...} references never reach the log.
+	opts.Redact = secret.Redact
+	logger, err := logging.New(os.Stderr, opts, sink)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	return nil
+}
+
+// persistentPreRun validates the global flags, selects the config profile
+// and installs the logger before any config is loaded.
+func persistentPreRun(cmd *cobra.Command, args []string) error {
+	if err := validateOutputFormat(cmd, args); err != nil {
+		return err
+	}
+	// --profile wins over RIG_PROFILE; setting the variable hands it to
+	// every config load and to child processes.
+	if v, _ := cmd.Flags().GetString("profile"); v != "" {
+		if err := os.Setenv(config.ProfileEnv, v); err != nil {
+			return err
+		}
+	}
+	// RIG_CONFIG points every command at a config outside the working
+	// directory, such as a mounted ConfigMap; --config wins.
+	if f := cmd.Flags().Lookup("config"); f != nil && !f.Changed {
+		if v := os.Getenv("RIG_CONFIG"); v != "" {
+			if err := f.Value.Set(v); err != nil {
+				return err
+			}
+		}
+	}
+	return setupLogging(cmd, nil, nil)
+}
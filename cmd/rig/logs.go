@@ -16,17 +16,19 @@ var logsCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		taskID := args[0]
-		statePath := ".rig/state.json"
 		follow, _ := cmd.Flags().GetBool("follow")
 
-		state, err := core.LoadState(statePath)
+		store := openTaskStore(cmd)
+		task, err := store.Task(cmd.Context(), taskID)
 		if err != nil {
-			return fmt.Errorf("load state: %w", err)
+			return err
 		}
 
-		task := state.GetTaskByID(taskID)
-		if task == nil {
-			return fmt.Errorf("task %q not found", taskID)
+		if format := outputFormat(cmd); format != outputText {
+			if follow {
+				return fmt.Errorf("--follow only supports text output")
+			}
+			return writeStructured(os.Stdout, format, task)
 		}
 
 		// Print task header.
@@ -41,6 +43,19 @@ var logsCmd = &cobra.Command{
 		if task.PR != nil {
 			fmt.Fprintf(os.Stdout, "PR: %s\n", task.PR.URL)
 		}
+		if u := task.AIUsage; u != nil {
+			fmt.Fprintf(os.Stdout, "AI Usage: %d calls, %d input / %d output tokens", u.Calls, u.InputTokens, u.OutputTokens)
+			if u.Interrupted > 0 {
+				fmt.Fprintf(os.Stdout, " (%d interrupted)", u.Interrupted)
+			}
+			if u.Retries > 0 {
+				fmt.Fprintf(os.Stdout, ", %d retried", u.Retries)
+			}
+			if u.CacheHits > 0 {
+				fmt.Fprintf(os.Stdout, ", %d from cache", u.CacheHits)
+			}
+			fmt.Println()
+		}
 		fmt.Println()
 
 		// Print pipeline steps.
@@ -77,9 +92,18 @@ var logsCmd = &cobra.Command{
 				if a.Plan != "" {
 					fmt.Fprintf(os.Stdout, "  Plan: %s\n", a.Plan)
 				}
+				if a.Provider != "" {
+					fmt.Fprintf(os.Stdout, "  Provider: %s\n", a.Provider)
+				}
+				if a.Model != "" {
+					fmt.Fprintf(os.Stdout, "  Model: %s\n", a.Model)
+				}
 				if a.FailReason != "" {
 					fmt.Fprintf(os.Stdout, "  Fail Reason: %s\n", a.FailReason)
 				}
+				if a.Triage != "" {
+					fmt.Fprintf(os.Stdout, "  Triage: %s failure\n", a.Triage)
+				}
 				if len(a.FilesChanged) > 0 {
 					fmt.Fprintf(os.Stdout, "  Files Changed: %v\n", a.FilesChanged)
 				}
@@ -88,7 +112,9 @@ var logsCmd = &cobra.Command{
 				}
 				for _, t := range a.Tests {
 					status := "PASS"
-					if !t.Passed {
+					if t.Flaky {
+						status = "FLAKY"
+					} else if !t.Passed {
 						status = "FAIL"
 					}
 					fmt.Fprintf(os.Stdout, "  Test [%s] %s: %s (%s)\n", status, t.Name, t.Type, t.Duration)
@@ -111,18 +137,9 @@ var logsCmd = &cobra.Command{
 		sigCh := make(chan os.Signal, 1)
 		signal.Notify(sigCh, os.Interrupt)
 
-		lastStepCount := 0
-		lastAttemptCount := 0
-		lastStatus := ""
-
-		// Initialize counts from current state.
-		if state, err := core.LoadState(statePath); err == nil {
-			if task := state.GetTaskByID(taskID); task != nil {
-				lastStepCount = len(task.Pipeline)
-				lastAttemptCount = len(task.Attempts)
-				lastStatus = string(task.Status)
-			}
-		}
+		lastStepCount := len(task.Pipeline)
+		lastAttemptCount := len(task.Attempts)
+		lastStatus := string(task.Status)
 
 		ticker := time.NewTicker(2 * time.Second)
 		defer ticker.Stop()
@@ -133,14 +150,10 @@ var logsCmd = &cobra.Command{
 				fmt.Println("\nstopped.")
 				return nil
 			case <-ticker.C:
-				state, err := core.LoadState(statePath)
+				task, err := store.Task(cmd.Context(), taskID)
 				if err != nil {
 					continue
 				}
-				task := state.GetTaskByID(taskID)
-				if task == nil {
-					continue
-				}
 
 				// Print new pipeline steps.
 				if len(task.Pipeline) > lastStepCount {
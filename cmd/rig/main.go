@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/rigdev/rig/internal/config"
 	"github.com/spf13/cobra"
@@ -27,46 +28,98 @@ var versionCmd = &cobra.Command{
 var validateCmd = &cobra.Command{
 	Use:   "validate",
 	Short: "Validate configuration file",
+	Long: `Validate a configuration file. With --profile (or RIG_PROFILE) the
+base config merged with that profile is validated; otherwise the base
+config and every profile are.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		configPath, _ := cmd.Flags().GetString("config")
 		if configPath == "" {
 			return fmt.Errorf("--config flag is required")
 		}
 
-		_, err := config.LoadConfig(configPath)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Config validation failed: %v\n", err)
-			return err
+		profiles := []string{config.ActiveProfile()}
+		if profiles[0] == "" {
+			names, err := config.Profiles(configPath)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Config validation failed: %v\n", err)
+				return err
+			}
+			profiles = append(profiles, names...)
 		}
 
-		fmt.Printf("Config validation passed: %s\n", configPath)
+		var failed int
+		for _, profile := range profiles {
+			label := configPath
+			if profile != "" {
+				label += " (profile " + profile + ")"
+			}
+			if _, err := config.LoadProfile(configPath, profile); err != nil {
+				fmt.Fprintf(os.Stderr, "Config validation failed: %s: %v\n", label, err)
+				failed++
+				continue
+			}
+			fmt.Printf("Config validation passed: %s\n", label)
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d configs failed validation", failed, len(profiles))
+		}
 		return nil
 	},
 }
 
 func main() {
 	// Register flags.
+	rootCmd.PersistentFlags().StringP("output", "o", outputText, "Output format for status, proposals, logs, explain, doctor, audit, keys list, webhooks list, workspaces list, db prune and backup create/list (text|json|yaml)")
+	rootCmd.PersistentFlags().String("server", "", "Drive a running rig serve instance at this dashboard URL instead of local state (default: $RIG_SERVER)")
+	rootCmd.PersistentFlags().String("api-key", "", "API key for --server (default: $RIG_API_KEY)")
+	rootCmd.PersistentFlags().String("log-level", "", "Log level: debug, info, warn or error (default: log.level in rig.yaml, $RIG_LOG_LEVEL, info)")
+	rootCmd.PersistentFlags().String("profile", "", "Config profile to merge over rig.yaml, from its profiles section or rig.<profile>.yaml (default: $RIG_PROFILE)")
+	rootCmd.PersistentFlags().String("log-format", "", "Log format: text or json (default: log.format in rig.yaml, $RIG_LOG_FORMAT, text)")
+	rootCmd.PersistentPreRunE = persistentPreRun
+
 	validateCmd.Flags().StringP("config", "c", "", "Path to config file")
 	_ = validateCmd.MarkFlagRequired("config")
 
 	execCmd.Flags().StringP("config", "c", "", "Path to config file")
-	execCmd.Flags().Bool("dry-run", false, "Dry-run mode (no real execution)")
-	execCmd.Flags().String("step", "", "Execute only a specific step (code|deploy|test)")
+	execCmd.Flags().Bool("dry-run", false, "Plan and generate code only, printing the plan and diff (no git, deploy or report side effects)")
+	execCmd.Flags().String("dry-run-dir", "", "Directory for the dry-run plan.md and changes.diff (default .rig/dry-run/<task>)")
+	execCmd.Flags().String("step", "", "Execute only one step (plan|code|commit|deploy|test|report)")
+	execCmd.Flags().String("from", "", "First step of a range to execute (default plan)")
+	execCmd.Flags().String("to", "", "Last step of a range to execute (default report)")
+	execCmd.Flags().String("task", "", "Run the steps on this task of an earlier run, reusing its artifacts")
+	execCmd.Flags().Int("issue", 0, "Issue number in the configured source repo (instead of an issue URL)")
+	execCmd.Flags().Bool("no-cache", false, "Ask the AI provider again instead of reusing cached answers (ai.cache)")
+	execCmd.Flags().String("env", "", "Deploy environment (environments), overriding the issue's deploy:<name> label")
+
+	devCmd.Flags().StringP("config", "c", "", "Path to config file")
+	devCmd.Flags().Duration("interval", time.Second, "How often to check the working tree for changes")
+	devCmd.Flags().Bool("once", false, "Run a single deploy and test cycle, then exit")
 
 	runCmd.Flags().StringP("config", "c", "", "Path to config file")
 	runCmd.Flags().IntP("port", "p", 0, "Override server port")
 
 	webCmd.Flags().StringP("config", "c", "", "Path to config file")
 	webCmd.Flags().Int("port", 3000, "Dashboard server port")
+	webCmd.Flags().String("host", "", "Address to bind to (default: server.host or all interfaces)")
 
 	serveCmd.Flags().StringP("config", "c", "", "Path to config file")
 	serveCmd.Flags().Int("web-port", 3000, "Dashboard server port")
 	serveCmd.Flags().Int("webhook-port", 0, "Webhook server port (default: from config or 8080)")
+	serveCmd.Flags().String("host", "", "Address to bind both servers to (default: server.host or all interfaces)")
+	serveCmd.Flags().Bool("reload", true, "Reload rig.yaml when it changes or on SIGHUP")
+	serveCmd.Flags().Int("grpc-port", 0, "gRPC API port (default: server.grpc.port; 0 disables it)")
 
 	approveCmd.Flags().StringP("config", "c", "rig.yaml", "Path to config file")
+	approveCmd.Flags().String("plan-file", "", "Replace the plan of a plan review with this file before approving")
 	rejectCmd.Flags().StringP("config", "c", "rig.yaml", "Path to config file")
+	redeployCmd.Flags().StringP("config", "c", "rig.yaml", "Path to config file")
+	retestCmd.Flags().StringP("config", "c", "rig.yaml", "Path to config file")
 
-	initCmd.Flags().String("template", "custom", "Template type (custom|docker)")
+	initCmd.Flags().String("template", "custom", "Write a template instead of running the wizard (custom|docker|go-service|node-app|k8s-app|terraform-infra)")
+	initCmd.Flags().BoolP("yes", "y", false, "Accept the detected values without prompting")
+
+	statusCmd.Flags().BoolP("watch", "w", false, "Live view of a running rig serve instance")
+	statusCmd.Flags().String("task", "", "Follow the logs of this task instead of the latest active one (with --watch)")
 
 	logsCmd.Flags().BoolP("follow", "f", false, "Follow logs in real-time (polls every 2s)")
 	explainCmd.Flags().Bool("ai", false, "Use configured AI provider to analyze failure output")
@@ -75,22 +128,104 @@ func main() {
 	migrateCmd.Flags().StringP("config", "c", "", "Path to config file (default: rig.yaml)")
 	migrateCmd.Flags().String("state", "", "Path to state file (default: .rig/state.json)")
 
+	fsckCmd.Flags().StringP("config", "c", "", "Path to config file (default: rig.yaml)")
+	fsckCmd.Flags().String("state", "", "Path to state file (default: .rig/state.json)")
+	fsckCmd.Flags().Bool("repair", false, "Apply automatic repairs instead of only reporting")
+	fsckCmd.Flags().Bool("no-remote", false, "Skip checking task branches on the remote")
+
+	auditCmd.Flags().String("actor", "", "Only entries by this actor (e.g. api-key, github:octocat, cli:alice)")
+	auditCmd.Flags().String("action", "", "Only entries with this action (e.g. task.created, proposal.approved)")
+	auditCmd.Flags().String("target", "", "Only entries for this task ID, settings section or repository")
+	auditCmd.Flags().String("since", "", "Only entries newer than a duration (24h) or RFC 3339 time")
+	auditCmd.Flags().Int("limit", 100, "Maximum number of entries")
+
+	webhooksListCmd.Flags().String("status", "", "Only deliveries with this status (received|accepted|ignored|failed|dead)")
+	webhooksListCmd.Flags().Int("limit", 100, "Maximum number of deliveries")
+	webhooksReplayCmd.Flags().StringP("config", "c", "", "Path to config file (default: rig.yaml; without --server)")
+
+	configSchemaCmd.Flags().String("file", "", "Write the schema to this file instead of stdout")
+
+	indexCmd.Flags().StringP("config", "c", "", "Path to config file (default: rig.yaml)")
+	reportCmd.Flags().StringP("config", "c", "", "Path to config file for ai.pricing (default: rig.yaml; without --server)")
+	reportCmd.Flags().String("from", "", "Start of the period, a date or RFC 3339 time (default: 30 days before --to)")
+	reportCmd.Flags().String("to", "", "End of the period, exclusive (default: now)")
+	reportCmd.Flags().Bool("csv", false, "Write the report as CSV")
+	workspacesListCmd.Flags().StringP("config", "c", "", "Path to config file (default: rig.yaml; without --server)")
+	workspacesGCCmd.Flags().StringP("config", "c", "", "Path to config file (default: rig.yaml; without --server)")
+	dbPruneCmd.Flags().StringP("config", "c", "", "Path to config file for log.retention (default: rig.yaml)")
+	dbPruneCmd.Flags().Int("max-rows-per-task", 0, "Keep only the newest lines of each task")
+	dbPruneCmd.Flags().Duration("max-age", 0, "Delete lines older than this")
+	dbPruneCmd.Flags().Int64("max-bytes", 0, "Delete the oldest lines while all task logs take more bytes")
+	dbPruneCmd.Flags().Duration("compress-after", 0, "Compress lines older than this")
+	backupCreateCmd.Flags().StringP("config", "c", "", "Path to config file to back up and read backup settings from (default: rig.yaml)")
+	backupCreateCmd.Flags().String("file", "", "Write the backup to this file (default: a new file in backup.dir)")
+	backupCreateCmd.Flags().Bool("encrypt", false, "Fail unless the backup can be encrypted (RIG_BACKUP_PASSPHRASE or backup.passphrase)")
+	backupListCmd.Flags().StringP("config", "c", "", "Path to config file for backup.dir (default: rig.yaml)")
+	backupRestoreCmd.Flags().StringP("config", "c", "", "Path the config file is restored to (default: rig.yaml)")
+	backupRestoreCmd.Flags().Bool("force", false, "Replace existing files")
+
+	keysCreateCmd.Flags().String("role", "viewer", "Role of the key (viewer|operator|approver|admin)")
+	keysCreateCmd.Flags().String("tenant", "", "Limit the key to the API of this tenant")
+	fixesListCmd.Flags().String("repo", "", "Only fixes of this repository (owner/repo)")
+	proposalsShowCmd.Flags().Bool("diff", false, "Print the changes as a unified diff")
+	proposalsShowCmd.Flags().Int("context", 3, "Unchanged lines around each diff hunk")
+	proposalsShowCmd.Flags().String("task", "", "Task of the proposal, when proposals of several tasks share its ID")
+
+	stepStartCmd.Flags().StringP("config", "c", "", "Path to config file")
+	stepRunCmd.Flags().StringP("config", "c", "", "Path to config file")
+	stepRunCmd.Flags().String("task", "", "Task ID to run the step for")
+	stepCmd.AddCommand(stepStartCmd)
+	stepCmd.AddCommand(stepRunCmd)
+	keysCmd.AddCommand(keysListCmd)
+	keysCmd.AddCommand(keysCreateCmd)
+	keysCmd.AddCommand(keysDeleteCmd)
+	proposalsCmd.AddCommand(proposalsShowCmd)
+	fixesCmd.AddCommand(fixesListCmd)
+	fixesCmd.AddCommand(fixesForgetCmd)
+	webhooksCmd.AddCommand(webhooksListCmd)
+	webhooksCmd.AddCommand(webhooksReplayCmd)
+	configCmd.AddCommand(configSchemaCmd)
+	workspacesCmd.AddCommand(workspacesListCmd)
+	workspacesCmd.AddCommand(workspacesGCCmd)
+	dbCmd.AddCommand(dbPruneCmd)
+	dbCmd.AddCommand(dbVacuumCmd)
+	dbCmd.AddCommand(dbClearAICacheCmd)
+	backupCmd.AddCommand(backupCreateCmd)
+	backupCmd.AddCommand(backupListCmd)
+	backupCmd.AddCommand(backupRestoreCmd)
+
 	// Register all commands.
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(validateCmd)
 	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(execCmd)
+	rootCmd.AddCommand(devCmd)
 	rootCmd.AddCommand(runCmd)
 	rootCmd.AddCommand(statusCmd)
 	rootCmd.AddCommand(logsCmd)
+	rootCmd.AddCommand(openCmd)
 	rootCmd.AddCommand(explainCmd)
 	rootCmd.AddCommand(proposalsCmd)
 	rootCmd.AddCommand(approveCmd)
 	rootCmd.AddCommand(rejectCmd)
+	rootCmd.AddCommand(redeployCmd)
+	rootCmd.AddCommand(retestCmd)
 	rootCmd.AddCommand(doctorCmd)
 	rootCmd.AddCommand(webCmd)
 	rootCmd.AddCommand(serveCmd)
 	rootCmd.AddCommand(migrateCmd)
+	rootCmd.AddCommand(fsckCmd)
+	rootCmd.AddCommand(auditCmd)
+	rootCmd.AddCommand(reportCmd)
+	rootCmd.AddCommand(keysCmd)
+	rootCmd.AddCommand(webhooksCmd)
+	rootCmd.AddCommand(stepCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(indexCmd)
+	rootCmd.AddCommand(fixesCmd)
+	rootCmd.AddCommand(workspacesCmd)
+	rootCmd.AddCommand(dbCmd)
+	rootCmd.AddCommand(backupCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
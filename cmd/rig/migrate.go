@@ -3,7 +3,7 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 
 	"github.com/rigdev/rig/internal/config"
@@ -35,9 +35,10 @@ var migrateCmd = &cobra.Command{
 
 		var imported int
 
-		// Import YAML config into SQLite settings.
+		// Import YAML config into SQLite settings. ${secret:...} references
+		// are stored as written and resolved whenever the config is loaded.
 		if _, err := os.Stat(configPath); err == nil {
-			cfg, err := config.LoadConfig(configPath)
+			cfg, err := config.LoadConfigWithSecretRefs(configPath)
 			if err != nil {
 				return fmt.Errorf("load config %s: %w", configPath, err)
 			}
@@ -65,9 +66,9 @@ var migrateCmd = &cobra.Command{
 			}
 
 			imported++
-			log.Printf("Imported config from %s", configPath)
+			slog.Info("imported config", "path", configPath)
 		} else {
-			log.Printf("No config file found at %s, skipping", configPath)
+			slog.Info("no config file found, skipping", "path", configPath)
 		}
 
 		// Import state.json tasks into SQLite.
@@ -84,9 +85,9 @@ var migrateCmd = &cobra.Command{
 			}
 
 			imported++
-			log.Printf("Imported %d tasks from %s", len(state.Tasks), statePath)
+			slog.Info("imported tasks", "count", len(state.Tasks), "path", statePath)
 		} else {
-			log.Printf("No state file found at %s, skipping", statePath)
+			slog.Info("no state file found, skipping", "path", statePath)
 		}
 
 		if imported == 0 {
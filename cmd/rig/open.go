@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var openCmd = &cobra.Command{
+	Use:   "open <task-id>",
+	Short: "Check out a task's branch in the local clone",
+	Long: `Check out the branch of a task in the git repository of the current
+directory, fetching it from origin first.
+
+The task is looked up in .rig/state.json, or with --server in a running rig
+serve instance.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		task, err := openTaskStore(cmd).Task(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+		if task.Branch == "" {
+			return fmt.Errorf("task %q has no branch yet", args[0])
+		}
+		repo, branch := task.Issue.Repo, task.Branch
+
+		if remote, err := gitOutput(".", "remote", "get-url", "origin"); err != nil {
+			return fmt.Errorf("current directory is not a git clone with an origin remote")
+		} else if m := githubRemote.FindStringSubmatch(remote); m != nil && !strings.EqualFold(m[1]+"/"+m[2], repo) {
+			return fmt.Errorf("task %s belongs to %s, but this clone is %s/%s", args[0], repo, m[1], m[2])
+		}
+
+		if err := runGit("fetch", "origin", branch); err != nil {
+			return fmt.Errorf("fetch %s: %w", branch, err)
+		}
+		if _, err := gitOutput(".", "rev-parse", "--verify", "--quiet", "refs/heads/"+branch); err == nil {
+			// Existing local branch: switch and fast-forward, never discard local commits.
+			if err := runGit("checkout", branch); err != nil {
+				return err
+			}
+			if err := runGit("merge", "--ff-only", "origin/"+branch); err != nil {
+				return fmt.Errorf("local %s has diverged from origin; merge or reset it manually", branch)
+			}
+		} else if err := runGit("checkout", "-b", branch, "--track", "origin/"+branch); err != nil {
+			return err
+		}
+		fmt.Printf("Checked out %s (task %s)\n", branch, args[0])
+		return nil
+	},
+}
+
+// runGit runs git in the current directory with output on the terminal.
+func runGit(args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Formats accepted by the global --output flag.
+const (
+	outputText = "text"
+	outputJSON = "json"
+	outputYAML = "yaml"
+)
+
+// outputFormat returns the --output format of cmd.
+func outputFormat(cmd *cobra.Command) string {
+	format, _ := cmd.Flags().GetString("output")
+	if format == "" {
+		return outputText
+	}
+	return format
+}
+
+// validateOutputFormat rejects unknown --output values before any command runs.
+func validateOutputFormat(cmd *cobra.Command, _ []string) error {
+	switch f := outputFormat(cmd); f {
+	case outputText, outputJSON, outputYAML:
+		return nil
+	default:
+		return fmt.Errorf("invalid --output %q: must be one of text, json, yaml", f)
+	}
+}
+
+// writeStructured writes v as JSON or YAML. YAML is converted from the JSON
+// encoding so both formats use the json field names of the web API types.
+func writeStructured(w io.Writer, format string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode output: %w", err)
+	}
+	if format == outputYAML {
+		if data, err = jsonToYAML(data); err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// jsonToYAML re-encodes a JSON document as block-style YAML, keeping the
+// key order.
+func jsonToYAML(data []byte) ([]byte, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, fmt.Errorf("convert output to yaml: %w", err)
+	}
+	var clearStyle func(n *yaml.Node)
+	clearStyle = func(n *yaml.Node) {
+		n.Style = 0
+		for _, c := range n.Content {
+			clearStyle(c)
+		}
+	}
+	clearStyle(&node)
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return nil, fmt.Errorf("convert output to yaml: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("convert output to yaml: %w", err)
+	}
+	return out.Bytes(), nil
+}
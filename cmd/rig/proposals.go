@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/rigdev/rig/internal/core"
@@ -13,19 +14,20 @@ var proposalsCmd = &cobra.Command{
 	Short: "Show pending proposals",
 	Args:  cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		state, err := core.LoadState(defaultStatePath)
-		if err != nil {
-			return fmt.Errorf("load state: %w", err)
-		}
+		format := outputFormat(cmd)
+		store := openTaskStore(cmd)
 
 		if len(args) == 1 {
 			taskID := args[0]
-			task := state.GetTaskByID(taskID)
-			if task == nil {
-				return fmt.Errorf("task %q not found", taskID)
+			task, err := store.Task(cmd.Context(), taskID)
+			if err != nil {
+				return err
 			}
 
 			pending := pendingProposals(task)
+			if format != outputText {
+				return writeStructured(os.Stdout, format, pending)
+			}
 			if len(pending) == 0 {
 				fmt.Printf("No pending proposals for task %s.\n", taskID)
 				return nil
@@ -35,9 +37,25 @@ var proposalsCmd = &cobra.Command{
 			return nil
 		}
 
+		tasks, err := store.Tasks(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		if format != outputText {
+			items := make([]pendingProposalItem, 0)
+			for i := range tasks {
+				task := &tasks[i]
+				for _, proposal := range pendingProposals(task) {
+					items = append(items, pendingProposalItem{TaskID: task.ID, TaskTitle: task.Issue.Title, Proposal: proposal})
+				}
+			}
+			return writeStructured(os.Stdout, format, items)
+		}
+
 		found := false
-		for i := range state.Tasks {
-			task := &state.Tasks[i]
+		for i := range tasks {
+			task := &tasks[i]
 			pending := pendingProposals(task)
 			if len(pending) == 0 {
 				continue
@@ -57,6 +75,61 @@ var proposalsCmd = &cobra.Command{
 	},
 }
 
+var proposalsShowCmd = &cobra.Command{
+	Use:   "show <proposal-id>",
+	Short: "Show a proposal of any status, optionally as a unified diff",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		asDiff, _ := cmd.Flags().GetBool("diff")
+		context, _ := cmd.Flags().GetInt("context")
+		taskID, _ := cmd.Flags().GetString("task")
+		if context < 0 {
+			return fmt.Errorf("--context must not be negative")
+		}
+		store := openTaskStore(cmd)
+
+		var state core.State
+		if taskID != "" {
+			task, err := store.Task(cmd.Context(), taskID)
+			if err != nil {
+				return err
+			}
+			state.Tasks = []core.Task{*task}
+		} else {
+			tasks, err := store.Tasks(cmd.Context())
+			if err != nil {
+				return err
+			}
+			state.Tasks = tasks
+		}
+		task, proposal := state.FindProposal(args[0])
+		if proposal == nil {
+			return fmt.Errorf("proposal %q not found", args[0])
+		}
+
+		if asDiff {
+			diff := core.ProposalDiffContext(proposal, context)
+			if diff == "" {
+				fmt.Fprintln(os.Stderr, "No file changes.")
+			}
+			fmt.Print(diff)
+			return nil
+		}
+		if format := outputFormat(cmd); format != outputText {
+			return writeStructured(os.Stdout, format, proposal)
+		}
+		printTaskProposals(task, []core.Proposal{*proposal})
+		return nil
+	},
+}
+
+// pendingProposalItem matches the items of GET /api/proposals.
+type pendingProposalItem struct {
+	TaskID    string        `json:"task_id"`
+	TaskTitle string        `json:"task_title"`
+	Proposal  core.Proposal `json:"proposal"`
+}
+
 func pendingProposals(task *core.Task) []core.Proposal {
 	proposals := make([]core.Proposal, 0)
 	for _, proposal := range task.Proposals {
@@ -72,6 +145,12 @@ func printTaskProposals(task *core.Task, proposals []core.Proposal) {
 	for _, proposal := range proposals {
 		fmt.Printf("Proposal: %s (%s) [%s]\n", proposal.ID, proposal.Type, proposal.Status)
 		fmt.Printf("Summary: %q\n", proposal.Summary)
+		if proposal.Type == core.ProposalPlanReview {
+			fmt.Println("Plan:")
+			fmt.Println(indentLines(strings.TrimSpace(proposal.Plan), "  "))
+			fmt.Printf("Run: rig approve %s [--plan-file <edited plan>]  |  rig reject %s\n", task.ID, task.ID)
+			continue
+		}
 		fmt.Println("Changes:")
 		for _, change := range proposal.Changes {
 			fmt.Printf("  - %s (%s): %q\n", change.Path, change.Action, change.Reason)
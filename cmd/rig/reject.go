@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/storage"
 	"github.com/spf13/cobra"
 )
 
@@ -13,6 +14,15 @@ var rejectCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		taskID := args[0]
+		if rc := newRemoteClient(cmd); rc != nil {
+			message, err := rc.Decide(cmd.Context(), taskID, false)
+			if err != nil {
+				return fmt.Errorf("reject task: %w", err)
+			}
+			fmt.Println(message)
+			return nil
+		}
+
 		configPath, _ := cmd.Flags().GetString("config")
 
 		cfg, err := config.LoadConfig(configPath)
@@ -28,6 +38,7 @@ var rejectCmd = &cobra.Command{
 		if err := engine.Resume(cmd.Context(), taskID, false); err != nil {
 			return fmt.Errorf("reject task: %w", err)
 		}
+		recordCLIAudit(storage.AuditProposalRejected, taskID, "")
 
 		fmt.Println("Proposal rejected. Task marked as failed.")
 		return nil
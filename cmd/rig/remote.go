@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+// taskStore is where the CLI reads tasks from: the local state file, or the
+// HTTP API of a serve instance when --server or RIG_SERVER is set.
+type taskStore interface {
+	Tasks(ctx context.Context) ([]core.Task, error)
+	// Task returns errTaskNotFound when no task has the ID.
+	Task(ctx context.Context, id string) (*core.Task, error)
+}
+
+// errTaskNotFound is returned by a taskStore for an unknown task ID.
+type errTaskNotFound string
+
+func (e errTaskNotFound) Error() string { return fmt.Sprintf("task %q not found", string(e)) }
+
+// openTaskStore returns the remote store in remote mode, else the local one.
+func openTaskStore(cmd *cobra.Command) taskStore {
+	if rc := newRemoteClient(cmd); rc != nil {
+		return rc
+	}
+	return localStore{path: defaultStatePath}
+}
+
+// localStore reads tasks from a state file.
+type localStore struct {
+	path string
+}
+
+func (s localStore) Tasks(_ context.Context) ([]core.Task, error) {
+	state, err := core.LoadState(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("load state: %w", err)
+	}
+	return state.Tasks, nil
+}
+
+func (s localStore) Task(_ context.Context, id string) (*core.Task, error) {
+	state, err := core.LoadState(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("load state: %w", err)
+	}
+	task := state.GetTaskByID(id)
+	if task == nil {
+		return nil, errTaskNotFound(id)
+	}
+	return task, nil
+}
+
+// remoteClient drives the web API of a serve instance.
+type remoteClient struct {
+	api *client.Client
+}
+
+// serverURL returns the --server flag or RIG_SERVER; empty means local mode.
+func serverURL(cmd *cobra.Command) string {
+	if server, _ := cmd.Flags().GetString("server"); server != "" {
+		return strings.TrimRight(server, "/")
+	}
+	return strings.TrimRight(os.Getenv("RIG_SERVER"), "/")
+}
+
+// apiKey returns the --api-key flag or RIG_API_KEY.
+func apiKey(cmd *cobra.Command) string {
+	if key, _ := cmd.Flags().GetString("api-key"); key != "" {
+		return key
+	}
+	return os.Getenv("RIG_API_KEY")
+}
+
+// newRemoteClient returns a client for the configured server, or nil in
+// local mode.
+func newRemoteClient(cmd *cobra.Command) *remoteClient {
+	server := serverURL(cmd)
+	if server == "" {
+		return nil
+	}
+	return &remoteClient{api: client.New(server, client.WithAPIKey(apiKey(cmd)))}
+}
+
+func (c *remoteClient) Tasks(ctx context.Context) ([]core.Task, error) {
+	return c.api.ListTasks(ctx, nil)
+}
+
+func (c *remoteClient) Task(ctx context.Context, id string) (*core.Task, error) {
+	task, err := c.api.GetTask(ctx, id)
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return nil, errTaskNotFound(id)
+	}
+	return task, err
+}
+
+// Decide approves or rejects the pending proposal of a task.
+func (c *remoteClient) Decide(ctx context.Context, id string, approve bool) (string, error) {
+	decide := c.api.Reject
+	if approve {
+		decide = c.api.Approve
+	}
+	resp, err := decide(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	return resp.Message, nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/metrics"
+	"github.com/rigdev/rig/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Summarize the tasks of a period per repository",
+	Long: `Summarize the tasks created in a period per repository: tasks, success
+rate, mean time to PR, retries per task and AI tokens and cost. The period
+is [--from, --to); both take a date (2006-01-02) or an RFC 3339 time and
+default to the 30 days before now. AI cost uses ai.pricing of the config.
+
+  rig report --from 2026-09-01 --to 2026-10-01
+  rig report --from 2026-09-01 --to 2026-10-01 --csv > september.csv
+  rig report -o json --server http://ci-host:3000`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from, _ := cmd.Flags().GetString("from")
+		to, _ := cmd.Flags().GetString("to")
+		asCSV, _ := cmd.Flags().GetBool("csv")
+
+		var report metrics.TaskReport
+		if rc := newRemoteClient(cmd); rc != nil {
+			r, err := rc.api.GetTaskReport(cmd.Context(), &client.GetTaskReportParams{From: from, To: to})
+			if err != nil {
+				return fmt.Errorf("get report: %w", err)
+			}
+			report = *r
+		} else {
+			start, end, err := metrics.ReportPeriod(from, to, time.Now().UTC())
+			if err != nil {
+				return err
+			}
+			configPath, _ := cmd.Flags().GetString("config")
+			if configPath == "" {
+				configPath = "rig.yaml"
+			}
+			cfg, err := config.LoadConfig(configPath)
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			state, err := core.LoadState(defaultStatePath)
+			if err != nil {
+				return fmt.Errorf("load state: %w", err)
+			}
+			report = metrics.BuildTaskReport(state.Tasks, start, end, cfg.AI.Pricing, cfg.AI.Model)
+		}
+
+		if asCSV {
+			return report.WriteCSV(os.Stdout)
+		}
+		if format := outputFormat(cmd); format != outputText {
+			return writeStructured(os.Stdout, format, report)
+		}
+		fmt.Printf("Tasks created %s – %s\n\n", report.From.Format(time.DateOnly), report.To.Format(time.DateOnly))
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "REPO\tTASKS\tDONE\tFAILED\tSUCCESS\tTIME TO PR\tRETRIES/TASK\tTOKENS\tAI COST")
+		totals := report.Totals
+		totals.Repo = "(all)"
+		for _, row := range append(report.Repos, totals) {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%.1f%%\t%s\t%.2f\t%d\t$%.2f\n",
+				row.Repo, row.Tasks, row.Completed, row.Failed, row.SuccessRate,
+				row.MeanTimeToPR, row.RetriesPerTask, row.InputTokens+row.OutputTokens, row.AICostUSD)
+		}
+		return tw.Flush()
+	},
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var redeployCmd = newRerunCmd(core.RerunDeploy, "redeploy <task-id>",
+	"Deploy and test a finished task again without generating code",
+	storage.AuditTaskRedeployed)
+
+var retestCmd = newRerunCmd(core.RerunTest, "retest <task-id>",
+	"Run the tests of a finished task again",
+	storage.AuditTaskRetested)
+
+// newRerunCmd returns a command that runs step of a finished task again
+// with the branch and vars recorded by its last deploy, such as after a
+// change to the infrastructure.
+func newRerunCmd(step, use, short, action string) *cobra.Command {
+	return &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			taskID := args[0]
+			if rc := newRemoteClient(cmd); rc != nil {
+				rerun := rc.api.RedeployTask
+				if step == core.RerunTest {
+					rerun = rc.api.RetestTask
+				}
+				if _, err := rerun(cmd.Context(), taskID); err != nil {
+					return fmt.Errorf("rerun %s: %w", step, err)
+				}
+				fmt.Printf("Task %s: %s started on %s.\n", taskID, step, serverURL(cmd))
+				return nil
+			}
+
+			configPath, _ := cmd.Flags().GetString("config")
+			cfg, err := config.LoadConfig(configPath)
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			task, err := localStore{path: defaultStatePath}.Task(cmd.Context(), taskID)
+			if err != nil {
+				return err
+			}
+			issueNumber, _ := strconv.Atoi(task.Issue.ID)
+			engine, err := buildEngineForIssue(cfg, defaultStatePath, issueNumber)
+			if err != nil {
+				return err
+			}
+
+			err = engine.Rerun(cmd.Context(), taskID, step)
+			recordCLIAudit(action, taskID, "")
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Task %s: %s passed.\n", taskID, step)
+			return nil
+		},
+	}
+}
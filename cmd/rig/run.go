@@ -2,10 +2,13 @@ package main
 
 import (
 	"fmt"
+	"log/slog"
 	"strconv"
+	"time"
 
 	"github.com/rigdev/rig/internal/config"
 	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/storage"
 	"github.com/rigdev/rig/internal/webhook"
 	"github.com/spf13/cobra"
 )
@@ -25,10 +28,16 @@ var runCmd = &cobra.Command{
 		if err != nil {
 			return fmt.Errorf("load config: %w", err)
 		}
+		if err := setupLogging(cmd, cfg, nil); err != nil {
+			return err
+		}
 
 		if port > 0 {
 			cfg.Server.Port = port
 		}
+		if err := verifyTokenPermissions(cmd.Context(), cfg); err != nil {
+			return err
+		}
 
 		// Create webhook handler with engine execute callback.
 		handler := webhook.NewHandler(
@@ -50,6 +59,17 @@ var runCmd = &cobra.Command{
 			},
 		)
 
+		handler.SetEndpoints(webhook.Endpoints(cfg))
+
+		if db, err := storage.Open(defaultDBPath()); err != nil {
+			slog.Warn("run: audit log and webhook delivery history disabled", "err", err)
+		} else {
+			defer db.Close()
+			handler.SetAuditFunc(db.RecordAudit)
+			handler.SetDeliveryStore(db)
+			go handler.RetryFailed(cmd.Context(), time.Minute)
+		}
+
 		server := webhook.NewServer(cfg.Server, handler)
 
 		fmt.Printf("Starting rig webhook server on port %d...\n", cfg.Server.Port)
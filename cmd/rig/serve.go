@@ -2,25 +2,37 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/rigdev/rig/internal/config"
 	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/httpserver"
+	"github.com/rigdev/rig/internal/queue"
 	"github.com/rigdev/rig/internal/storage"
+	"github.com/rigdev/rig/internal/tenant"
 	"github.com/rigdev/rig/internal/web"
 	"github.com/rigdev/rig/internal/webhook"
 	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
 )
 
+// defaultDBPath is the SQLite database: $RIG_DB_PATH, or ~/.rig/rig.db.
 func defaultDBPath() string {
+	if path := os.Getenv("RIG_DB_PATH"); path != "" {
+		return path
+	}
 	home, _ := os.UserHomeDir()
 	return filepath.Join(home, ".rig", "rig.db")
 }
@@ -32,6 +44,9 @@ var serveCmd = &cobra.Command{
 		configPath, _ := cmd.Flags().GetString("config")
 		webPort, _ := cmd.Flags().GetInt("web-port")
 		webhookPort, _ := cmd.Flags().GetInt("webhook-port")
+		host, _ := cmd.Flags().GetString("host")
+		reload, _ := cmd.Flags().GetBool("reload")
+		grpcPort, _ := cmd.Flags().GetInt("grpc-port")
 
 		// Open SQLite database
 		db, err := storage.Open(defaultDBPath())
@@ -40,52 +55,144 @@ var serveCmd = &cobra.Command{
 		}
 		defer db.Close()
 
+		// Task logs are written asynchronously in batches; Close flushes them
+		// before the database is closed.
+		logWriter := db.NewLogWriter(0, 0, 0)
+		defer logWriter.Close()
+
 		// Load config: SQLite settings → rig.yaml → setup mode
-		cfg, err := loadConfigFromSources(db, configPath)
+		cfg, cfgFile, err := loadConfigFromSources(db, configPath)
 		if err != nil {
 			return fmt.Errorf("load config: %w", err)
 		}
+		// Task records reach the dashboard log view through the logger.
+		if err := setupLogging(cmd, cfg, logWriter.Write); err != nil {
+			return err
+		}
 
 		if cfg != nil && webhookPort > 0 {
 			cfg.Server.Port = webhookPort
 		}
 
+		// A config from rig.yaml is reloaded when the file changes or on
+		// SIGHUP; tasks started afterwards use the new one.
+		var reloader *config.Reloader
+		currentCfg := func() *config.Config { return cfg }
+		if cfg != nil && cfgFile != "" && reload {
+			reloader = config.NewReloader(cfgFile, cfg)
+			reloader.Override(func(c *config.Config) {
+				if webhookPort > 0 {
+					c.Server.Port = webhookPort
+				}
+			})
+			currentCfg = reloader.Current
+		}
+		if cfg != nil {
+			if err := verifyTokenPermissions(cmd.Context(), cfg); err != nil {
+				return err
+			}
+		}
+
+		// --- Listener, TLS and proxy settings ---
+		var serverCfg config.ServerConfig
+		if cfg != nil {
+			serverCfg = cfg.Server
+		}
+		if host != "" {
+			serverCfg.Host = host
+		}
+		if grpcPort > 0 {
+			serverCfg.GRPC.Port = grpcPort
+		}
+		tlsCfg, err := httpserver.TLSConfig(serverCfg.TLS)
+		if err != nil {
+			return err
+		}
+		realIP, err := httpserver.RealIP(serverCfg.TrustedProxies)
+		if err != nil {
+			return err
+		}
+		drainTimeout := serverCfg.DrainTimeout
+		if drainTimeout == 0 {
+			drainTimeout = defaultDrainTimeout
+		}
+
 		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 		defer stop()
 
-		errCh := make(chan error, 2)
+		errCh := make(chan error, 3)
 
-		// --- Shared execute callback ---
-		makeExecFn := func() func(core.Issue) error {
-			return func(issue core.Issue) error {
-				issueNumber, _ := strconv.Atoi(issue.ID)
-				engine, err := buildEngineForIssue(cfg, defaultStatePath, issueNumber)
-				if err != nil {
-					return err
-				}
-				engine.SetLogFunc(func(taskID, level, message string) {
-					_ = db.AppendLog(taskID, level, message)
-				})
-				return engine.Execute(ctx, issue)
+		// Tasks run on their own context so a shutdown can let them finish.
+		tasks := newTaskTracker()
+
+		// --- Shared execute callbacks ---
+		runner := &engineRunner{tasks: tasks, statePath: defaultStatePath, config: currentCfg, flush: logWriter.Flush}
+		// Webhooks and the queue start tasks with the AI cache in use.
+		executeIssue := func(issue core.Issue) error { return runner.execute(context.Background(), issue) }
+
+		// --- Tenants (tenants) ---
+		// Each tenant's tasks run on its own state and config, at most
+		// quota.max_concurrent_tasks at a time.
+		var tenants []*tenant.Tenant
+		var webTenants web.Tenants
+		var tenantRunners []*engineRunner
+		if cfg != nil {
+			if tenants, err = tenant.Load(cfg, filepath.Dir(cfgFile), defaultStatePath); err != nil {
+				return err
+			}
+			for _, t := range tenants {
+				tr := &engineRunner{tasks: tasks, statePath: t.StatePath, config: t.Config, flush: logWriter.Flush, acquire: t.Acquire}
+				webTenants = append(webTenants, web.Tenant{Tenant: t, Execute: tr.execute, Resume: tr.resume, Rerun: tr.rerun})
+				tenantRunners = append(tenantRunners, tr)
 			}
 		}
 
+		// Webhook deliveries are stored so failed ones are retried and can be
+		// replayed from the dashboard.
+		var whHandler *webhook.Handler
+		if cfg != nil {
+			whHandler = webhook.NewHandler(
+				cfg.Server.Secret,
+				cfg.Workflow.Trigger,
+				defaultStatePath,
+				executeIssue,
+			)
+			whHandler.SetEndpoints(webhook.Endpoints(cfg))
+			whHandler.SetAuditFunc(db.RecordAudit)
+			whHandler.SetDeliveryStore(db)
+		}
+
 		// --- Web Dashboard (always starts) ---
 		var execFn web.ExecuteFunc
+		var webResumeFn web.ResumeFunc
+		var replayFn web.ReplayFunc
+		var webRerunFn web.RerunFunc
+		var reloadStatusFn web.ReloadStatusFunc
 		if cfg != nil {
-			execFn = makeExecFn()
+			execFn = runner.execute
+			webResumeFn = runner.resume
+			webRerunFn = runner.rerun
+			replayFn = func(id int64) error {
+				_, err := whHandler.Replay(id)
+				return err
+			}
+		}
+		if reloader != nil {
+			reloadStatusFn = reloader.Status
 		}
-		webHandler := web.NewHandler(defaultStatePath, cfg, db, execFn)
+		webHandler := web.NewHandler(defaultStatePath, cfg, db, execFn, webResumeFn, replayFn, webRerunFn,
+			web.ConfigFunc(currentCfg), reloadStatusFn, web.ReadyFunc(tasks.accepting), webTenants)
 		webSrv := &http.Server{
-			Addr:         fmt.Sprintf(":%d", webPort),
-			Handler:      webHandler,
+			Addr:         httpserver.Addr(serverCfg.Host, webPort),
+			Handler:      realIP(webHandler),
 			ReadTimeout:  15 * time.Second,
 			WriteTimeout: 30 * time.Second,
 			IdleTimeout:  60 * time.Second,
 		}
+		scheme := httpserver.Scheme(tlsCfg)
 		go func() {
-			log.Printf("Dashboard running at http://localhost:%d", webPort)
-			if err := webSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			slog.Info("dashboard running", "url", fmt.Sprintf("%s://localhost:%d", scheme, webPort))
+			if err := httpserver.ListenAndServe(webSrv, tlsCfg); err != nil && err != http.ErrServerClosed {
 				errCh <- fmt.Errorf("web server: %w", err)
 			}
 		}()
@@ -93,12 +200,12 @@ var serveCmd = &cobra.Command{
 		if cfg == nil {
 			// Setup mode: no config yet, only web dashboard
 			fmt.Printf("\n  rig serve (setup mode)\n")
-			fmt.Printf("  └─ Dashboard : http://localhost:%d\n", webPort)
+			fmt.Printf("  └─ Dashboard : %s://localhost:%d\n", scheme, webPort)
 			fmt.Printf("\n  No configuration found. Visit the dashboard to set up.\n\n")
 
 			select {
 			case <-ctx.Done():
-				log.Println("Shutting down...")
+				slog.Info("shutting down")
 				shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 				defer cancel()
 				_ = webSrv.Shutdown(shutdownCtx)
@@ -109,53 +216,389 @@ var serveCmd = &cobra.Command{
 		}
 
 		// --- Webhook Server (full mode) ---
-		whHandler := webhook.NewHandler(
-			cfg.Server.Secret,
-			cfg.Workflow.Trigger,
-			defaultStatePath,
-			makeExecFn(),
-		)
-		whServer := webhook.NewServer(cfg.Server, whHandler)
+		whServer := webhook.NewServer(serverCfg, whHandler)
 		go func() {
 			if err := whServer.ListenAndServe(ctx); err != nil {
 				errCh <- fmt.Errorf("webhook server: %w", err)
 			}
 		}()
 
+		go whHandler.RetryFailed(ctx, time.Minute)
+
+		// --- gRPC API (server.grpc.port) ---
+		var grpcSrv *grpc.Server
+		if serverCfg.GRPC.Port > 0 {
+			lis, err := net.Listen("tcp", httpserver.Addr(serverCfg.Host, serverCfg.GRPC.Port))
+			if err != nil {
+				return fmt.Errorf("grpc server: %w", err)
+			}
+			grpcSrv = web.NewGRPCServer(defaultStatePath, cfg, db, tlsCfg, execFn, webResumeFn, web.ConfigFunc(currentCfg))
+			go func() {
+				if err := grpcSrv.Serve(lis); err != nil {
+					errCh <- fmt.Errorf("grpc server: %w", err)
+				}
+			}()
+		}
+
+		// --- Queue consumers (server.queues) ---
+		var consumers []*queue.Consumer
+		for _, q := range cfg.Server.Queues {
+			consumer, err := queue.New(cfg, q, defaultStatePath, executeIssue)
+			if err != nil {
+				return err
+			}
+			consumer.SetAuditFunc(db.RecordAudit)
+			go consumer.Run(ctx)
+			consumers = append(consumers, consumer)
+		}
+
+		if interval := cfg.Workflow.BranchSweep.Interval; interval > 0 {
+			go runBranchSweeper(ctx, currentCfg, interval)
+		}
+		if interval := cfg.Source.Workspaces.GCInterval; interval > 0 {
+			go runWorkspaceGC(ctx, currentCfg, interval)
+		}
+		if r := cfg.Artifacts.Retention; cfg.Artifacts.Enabled && (r.MaxAge > 0 || r.MaxBytes > 0) {
+			go runArtifactSweeper(ctx, currentCfg, defaultStatePath, r.Interval)
+		}
+		if r := cfg.Log.Retention; logRetention(r) != (storage.LogRetention{}) {
+			go runLogPruner(ctx, db, r)
+		}
+		if interval := cfg.Backup.Interval; interval > 0 {
+			go runBackups(ctx, db, currentCfg, cfgFile, interval)
+		}
+
+		if reloader != nil {
+			reloader.OnReload(func(c *config.Config, err error) {
+				entry := storage.AuditEntry{Actor: cliActor(), Source: storage.AuditSourceCLI, Action: storage.AuditConfigReloaded, Target: cfgFile}
+				if err != nil {
+					entry.Details = "failed: " + err.Error()
+				} else {
+					whHandler.SetEndpoints(webhook.Endpoints(c))
+					whHandler.SetTriggers(c.Workflow.Trigger)
+					if keys := reloader.Status().RestartRequired; len(keys) > 0 {
+						slog.Warn("config changes need a restart to take effect", "settings", strings.Join(keys, ", "))
+						entry.Details = "restart required: " + strings.Join(keys, ", ")
+					}
+				}
+				if err := db.RecordAudit(entry); err != nil {
+					slog.Warn("audit failed", "err", err)
+				}
+			})
+			go reloader.Watch(ctx, configWatchInterval)
+			go reloadOnHangup(ctx, reloader)
+		}
+
 		whPort := cfg.Server.Port
 		if whPort == 0 {
 			whPort = 8080
 		}
 
 		fmt.Printf("\n  rig serve running\n")
-		fmt.Printf("  ├─ Dashboard : http://localhost:%d\n", webPort)
-		fmt.Printf("  └─ Webhook   : http://localhost:%d/webhook\n\n", whPort)
+		fmt.Printf("  ├─ Dashboard : %s://localhost:%d\n", scheme, webPort)
+		if grpcSrv != nil {
+			fmt.Printf("  ├─ gRPC API  : localhost:%d\n", serverCfg.GRPC.Port)
+		}
+		for i, c := range consumers {
+			// SQS queues have no topic; their URL names them. NATS and
+			// Kafka URLs may hold credentials, so they are not shown.
+			q := cfg.Server.Queues[i]
+			target := q.Topic
+			if target == "" {
+				target = q.URL
+			}
+			fmt.Printf("  ├─ Queue     : %s (%s %s)\n", c.Name(), q.Broker, target)
+		}
+		if cfg.Profile != "" {
+			fmt.Printf("  ├─ Profile   : %s\n", cfg.Profile)
+		}
+		for _, t := range tenants {
+			fmt.Printf("  ├─ Tenant    : %s (%s://localhost:%d/api/t/%s/)\n", t.Name, scheme, webPort, t.Name)
+		}
+		endpoints := whHandler.Endpoints()
+		for i, ep := range endpoints {
+			branch := "├─"
+			if i == len(endpoints)-1 {
+				branch = "└─"
+			}
+			fmt.Printf("  %s Webhook   : %s://localhost:%d%s (%s)\n", branch, scheme, whPort, ep.Path, ep.Platform)
+		}
+		fmt.Println()
+
+		resumeCheckpointed(runner)
+		for _, tr := range tenantRunners {
+			resumeCheckpointed(tr)
+		}
 
 		select {
 		case <-ctx.Done():
-			log.Println("Shutting down...")
+			slog.Info("shutting down")
+			// Stop taking new triggers first: whServer and the queue
+			// consumers stop with ctx, and /readyz fails from here on so
+			// Kubernetes takes the pod out of service. The dashboard keeps
+			// answering probes until the running tasks are done.
+			if grpcSrv != nil {
+				// Streams only end when their clients go, so cut them off.
+				grpcSrv.Stop()
+			}
+			tasks.drain(drainTimeout)
 			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 			defer cancel()
 			_ = webSrv.Shutdown(shutdownCtx)
-			// whServer shuts down via ctx cancellation in its own ListenAndServe
 			return nil
 		case err := <-errCh:
+			if grpcSrv != nil {
+				grpcSrv.Stop()
+			}
+			tasks.drain(0)
 			return err
 		}
 	},
 }
 
+// defaultDrainTimeout is how long shutdown waits for running tasks unless
+// server.drain_timeout says otherwise.
+const defaultDrainTimeout = 5 * time.Minute
+
+// errShuttingDown refuses tasks triggered after shutdown began.
+var errShuttingDown = errors.New("rig serve is shutting down")
+
+// taskTracker runs engine work on a context that outlives the shutdown
+// signal, so running tasks can finish before the process exits.
+type taskTracker struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+
+	mu      sync.Mutex
+	closing bool
+	wg      sync.WaitGroup
+}
+
+func newTaskTracker() *taskTracker {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	return &taskTracker{ctx: ctx, cancel: cancel}
+}
+
+// run calls fn with the task context unless shutdown has begun.
+func (t *taskTracker) run(fn func(ctx context.Context) error) error {
+	t.mu.Lock()
+	if t.closing {
+		t.mu.Unlock()
+		return errShuttingDown
+	}
+	t.wg.Add(1)
+	t.mu.Unlock()
+	defer t.wg.Done()
+	return fn(t.ctx)
+}
+
+// accepting returns errShuttingDown once drain has begun.
+func (t *taskTracker) accepting() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closing {
+		return errShuttingDown
+	}
+	return nil
+}
+
+// drain refuses new tasks, waits up to timeout for running ones, then
+// cancels the rest with core.ErrShutdown and gives them a moment to save a
+// checkpoint, from which the next start resumes them.
+func (t *taskTracker) drain(timeout time.Duration) {
+	t.mu.Lock()
+	t.closing = true
+	t.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+	if timeout > 0 {
+		slog.Info("waiting for running tasks", "timeout", timeout)
+		select {
+		case <-done:
+			return
+		case <-time.After(timeout):
+			slog.Warn("drain timeout reached; cancelling running tasks")
+		}
+	}
+	t.cancel(core.ErrShutdown)
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		slog.Warn("tasks did not stop after cancellation")
+	}
+}
+
+// engineRunner runs the engine on the tasks of one state file, rig
+// serve's own or a tenant's, under the task tracker.
+type engineRunner struct {
+	tasks     *taskTracker
+	statePath string
+	config    func() *config.Config
+	flush     func() error
+	// acquire waits for a slot under a tenant's max_concurrent_tasks.
+	acquire func(ctx context.Context) (func(), error)
+}
+
+// run calls fn with an engine for the issue numbered issueNumber. The task
+// runs on its own context; of ctx, it only keeps whether to skip cached AI
+// answers (core.WithoutAICache).
+func (e *engineRunner) run(ctx context.Context, issueNumber int, fn func(ctx context.Context, engine *core.Engine) error) error {
+	return e.tasks.run(func(taskCtx context.Context) error {
+		if core.AICacheBypassed(ctx) {
+			taskCtx = core.WithoutAICache(taskCtx)
+		}
+		if e.acquire != nil {
+			release, err := e.acquire(taskCtx)
+			if err != nil {
+				return err
+			}
+			defer release()
+		}
+		engine, err := buildEngineForIssue(e.config(), e.statePath, issueNumber)
+		if err != nil {
+			return err
+		}
+		engine.SetLogFlusher(e.flush)
+		return fn(taskCtx, engine)
+	})
+}
+
+// issueNumber returns the issue number of the task taskID, or 0.
+func (e *engineRunner) issueNumber(taskID string) int {
+	n := 0
+	if state, err := core.LoadState(e.statePath); err == nil {
+		if task := state.GetTaskByID(taskID); task != nil {
+			n, _ = strconv.Atoi(task.Issue.ID)
+		}
+	}
+	return n
+}
+
+func (e *engineRunner) execute(ctx context.Context, issue core.Issue) error {
+	issueNumber, _ := strconv.Atoi(issue.ID)
+	return e.run(ctx, issueNumber, func(ctx context.Context, engine *core.Engine) error {
+		return engine.Execute(ctx, issue)
+	})
+}
+
+// resume resumes a task once the dashboard approved or rejected it.
+func (e *engineRunner) resume(taskID string, approved bool) error {
+	return e.run(context.Background(), e.issueNumber(taskID), func(ctx context.Context, engine *core.Engine) error {
+		return engine.Resume(ctx, taskID, approved)
+	})
+}
+
+// rerun redeploys or retests a finished task from the dashboard.
+func (e *engineRunner) rerun(ctx context.Context, taskID, step string) error {
+	return e.run(ctx, e.issueNumber(taskID), func(ctx context.Context, engine *core.Engine) error {
+		return engine.Rerun(ctx, taskID, step)
+	})
+}
+
+// resumeCheckpointed runs the tasks the last shutdown interrupted again in
+// the background, after checkpointing the tasks whose journal shows rig
+// crashed in one of their steps.
+func resumeCheckpointed(runner *engineRunner) {
+	statePath := runner.statePath
+	state, err := core.LoadState(statePath)
+	if err != nil {
+		slog.Warn("load state for checkpointed tasks", "err", err)
+		return
+	}
+	for _, task := range core.InterruptedTasks(statePath, state) {
+		issueNumber, _ := strconv.Atoi(task.Issue.ID)
+		engine, err := buildEngineForIssue(runner.config(), statePath, issueNumber)
+		if err == nil {
+			_, err = engine.RecoverTask(task.ID)
+		}
+		if err != nil {
+			slog.Warn("recover interrupted task", "task", task.ID, "err", err)
+		}
+	}
+	if state, err = core.LoadState(statePath); err != nil {
+		slog.Warn("load state for checkpointed tasks", "err", err)
+		return
+	}
+	for _, task := range state.CheckpointedTasks() {
+		slog.Info("resuming task interrupted by shutdown", "task", task.ID, "phase", task.Checkpoint.Phase)
+		go func() {
+			issueNumber, _ := strconv.Atoi(task.Issue.ID)
+			err := runner.run(context.Background(), issueNumber, func(ctx context.Context, engine *core.Engine) error {
+				return engine.ResumeCheckpoint(ctx, task.ID)
+			})
+			if err != nil && !errors.Is(err, errShuttingDown) && !errors.Is(err, core.ErrShutdown) {
+				slog.Error("resume checkpointed task failed", "task", task.ID, "err", err)
+			}
+		}()
+	}
+}
+
+// runBranchSweeper periodically deletes rig/ branches that no live task
+// needs until ctx is cancelled.
+func runBranchSweeper(ctx context.Context, currentCfg func() *config.Config, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		engine, err := buildEngine(currentCfg(), defaultStatePath)
+		if err != nil {
+			slog.Warn("branch sweep", "err", err)
+			continue
+		}
+		deleted, err := engine.SweepBranches(ctx)
+		if err != nil {
+			slog.Warn("branch sweep", "err", err)
+			continue
+		}
+		if len(deleted) > 0 {
+			slog.Info("branch sweep: deleted orphaned branches", "count", len(deleted), "branches", strings.Join(deleted, ", "))
+		}
+	}
+}
+
+// configWatchInterval is how often rig serve checks rig.yaml for changes.
+const configWatchInterval = 2 * time.Second
+
+// reloadOnHangup reloads the config on every SIGHUP until ctx is cancelled.
+func reloadOnHangup(ctx context.Context, reloader *config.Reloader) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		}
+		if err := reloader.Reload(); err != nil {
+			slog.Error("config reload failed; keeping the previous config", "err", err)
+			continue
+		}
+		slog.Info("config reloaded", "path", reloader.Status().Path)
+	}
+}
+
 // loadConfigFromSources tries: SQLite settings → YAML file → nil (setup mode).
-func loadConfigFromSources(db *storage.DB, configPath string) (*config.Config, error) {
+// It also returns the path of the YAML file the config came from, or "" for
+// SQLite settings.
+func loadConfigFromSources(db *storage.DB, configPath string) (*config.Config, string, error) {
 	// If explicit --config flag, use YAML directly
 	if configPath != "" {
 		if _, err := os.Stat(configPath); err == nil {
 			cfg, err := config.LoadConfig(configPath)
 			if err != nil {
 				// YAML exists but has errors (e.g., missing env vars) - try SQLite
-				log.Printf("YAML config error: %v, checking SQLite...", err)
+				slog.Warn("YAML config error, checking SQLite", "err", err)
 			} else {
-				return cfg, nil
+				return cfg, configPath, nil
 			}
 		}
 	}
@@ -163,19 +606,19 @@ func loadConfigFromSources(db *storage.DB, configPath string) (*config.Config, e
 	// Try SQLite settings
 	has, err := db.HasSettings()
 	if err != nil {
-		return nil, fmt.Errorf("check settings: %w", err)
+		return nil, "", fmt.Errorf("check settings: %w", err)
 	}
 	if has {
 		settings, err := db.GetAllSettings()
 		if err != nil {
-			return nil, fmt.Errorf("load settings: %w", err)
+			return nil, "", fmt.Errorf("load settings: %w", err)
 		}
 		cfg, err := config.FromSettings(settings)
 		if err != nil {
-			return nil, fmt.Errorf("parse settings: %w", err)
+			return nil, "", fmt.Errorf("parse settings: %w", err)
 		}
-		log.Println("Loaded config from SQLite database")
-		return cfg, nil
+		slog.Info("loaded config from SQLite database")
+		return cfg, "", nil
 	}
 
 	// Try default rig.yaml (no explicit flag)
@@ -185,13 +628,12 @@ func loadConfigFromSources(db *storage.DB, configPath string) (*config.Config, e
 	if _, err := os.Stat(configPath); err == nil {
 		cfg, err := config.LoadConfig(configPath)
 		if err != nil {
-			log.Printf("Config file found but has errors: %v", err)
-			log.Println("Starting in setup mode — configure via web dashboard")
-			return nil, nil
+			slog.Warn("config file has errors, starting in setup mode — configure via web dashboard", "err", err)
+			return nil, "", nil
 		}
-		return cfg, nil
+		return cfg, configPath, nil
 	}
 
 	// No config anywhere → setup mode
-	return nil, nil
+	return nil, "", nil
 }
@@ -1,25 +1,57 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/rigdev/rig/internal/core"
 	"github.com/spf13/cobra"
 )
 
+// defaultWatchServer is the dashboard --watch connects to without --server.
+const defaultWatchServer = "http://localhost:3000"
+
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show current task status from state.json",
+	Long: `Show current task status from state.json, or from a running rig serve
+instance with --server.
+
+With --watch, connect to a running rig serve instance instead and keep a live
+view of its tasks, their current phase and the log tail of the active task.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		statePath := ".rig/state.json"
+		format := outputFormat(cmd)
+		if watch, _ := cmd.Flags().GetBool("watch"); watch {
+			if format != outputText {
+				return fmt.Errorf("--watch only supports text output")
+			}
+			server := serverURL(cmd)
+			if server == "" {
+				server = defaultWatchServer
+			}
+			taskID, _ := cmd.Flags().GetString("task")
+
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+			return newStatusWatcher(server, apiKey(cmd), taskID, os.Stdout).run(ctx)
+		}
 
-		state, err := core.LoadState(statePath)
+		tasks, err := openTaskStore(cmd).Tasks(cmd.Context())
 		if err != nil {
-			return fmt.Errorf("load state: %w", err)
+			return err
+		}
+
+		if format != outputText {
+			if tasks == nil {
+				tasks = []core.Task{}
+			}
+			return writeStructured(os.Stdout, format, tasks)
 		}
 
-		if len(state.Tasks) == 0 {
+		if len(tasks) == 0 {
 			fmt.Println("No tasks found.")
 			return nil
 		}
@@ -28,7 +60,7 @@ var statusCmd = &cobra.Command{
 			"TASK ID", "STATUS", "ISSUE", "ATTEMPTS", "CREATED")
 		fmt.Println("------------------------------------------------------------------------------------")
 
-		for _, t := range state.Tasks {
+		for _, t := range tasks {
 			fmt.Fprintf(os.Stdout, "%-30s %-12s %-20s %-10d %s\n",
 				t.ID,
 				t.Status,
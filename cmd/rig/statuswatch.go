@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/storage"
+)
+
+const (
+	// watchLogTail is how many log lines the watch screen shows.
+	watchLogTail = 15
+	// watchReconnectDelay is the wait before reconnecting a dropped stream.
+	watchReconnectDelay = 2 * time.Second
+)
+
+// statusWatcher renders a live view of a serve instance from its web API:
+// the task list comes from the same /api/events stream the dashboard uses
+// and the log tail from /api/tasks/{id}/logs.
+type statusWatcher struct {
+	server string
+	apiKey string
+	taskID string // follow this task's logs; empty follows the latest active task
+	client *http.Client
+	out    io.Writer
+
+	tasks   []core.Task
+	logTask string
+	logs    []storage.LogEntry
+	lastLog int64
+	connErr string
+	logErr  string
+}
+
+func newStatusWatcher(server, apiKey, taskID string, out io.Writer) *statusWatcher {
+	return &statusWatcher{
+		server: strings.TrimRight(server, "/"),
+		apiKey: apiKey,
+		taskID: taskID,
+		client: &http.Client{},
+		out:    out,
+	}
+}
+
+// run redraws the screen on every task update and log poll until ctx is done.
+func (w *statusWatcher) run(ctx context.Context) error {
+	updates := make(chan []core.Task)
+	errs := make(chan error)
+	go w.streamTasks(ctx, updates, errs)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	w.render()
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Fprintln(w.out, "\nstopped.")
+			return nil
+		case tasks := <-updates:
+			w.tasks, w.connErr = tasks, ""
+		case err := <-errs:
+			w.connErr = err.Error()
+		case <-ticker.C:
+			w.pollLogs(ctx)
+		}
+		w.render()
+	}
+}
+
+// streamTasks reads task snapshots from the SSE stream, reconnecting when
+// the connection drops.
+func (w *statusWatcher) streamTasks(ctx context.Context, updates chan<- []core.Task, errs chan<- error) {
+	for {
+		err := w.readEvents(ctx, updates)
+		if ctx.Err() != nil {
+			return
+		}
+		select {
+		case errs <- err:
+		case <-ctx.Done():
+			return
+		}
+		select {
+		case <-time.After(watchReconnectDelay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// readEvents consumes one SSE connection and returns why it ended.
+func (w *statusWatcher) readEvents(ctx context.Context, updates chan<- []core.Task) error {
+	resp, err := w.get(ctx, "/api/events")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	event := ""
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:") && event == "tasks":
+			var tasks []core.Task
+			if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), &tasks); err != nil {
+				return fmt.Errorf("decode tasks: %w", err)
+			}
+			select {
+			case updates <- tasks:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		case line == "":
+			event = ""
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("event stream: %w", err)
+	}
+	return fmt.Errorf("event stream closed by server")
+}
+
+// pollLogs fetches log lines added since the last poll for the followed task.
+func (w *statusWatcher) pollLogs(ctx context.Context) {
+	id := w.followedTask()
+	if id == "" {
+		return
+	}
+	if id != w.logTask {
+		w.logTask, w.logs, w.lastLog, w.logErr = id, nil, 0, ""
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	resp, err := w.get(ctx, fmt.Sprintf("/api/tasks/%s/logs?after=%d", url.PathEscape(id), w.lastLog))
+	if err != nil {
+		w.logErr = err.Error()
+		return
+	}
+	defer resp.Body.Close()
+
+	var entries []storage.LogEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		w.logErr = fmt.Sprintf("decode logs: %v", err)
+		return
+	}
+	w.logErr = ""
+	for _, e := range entries {
+		if e.ID > w.lastLog {
+			w.lastLog = e.ID
+		}
+	}
+	w.logs = append(w.logs, entries...)
+	if len(w.logs) > watchLogTail {
+		w.logs = w.logs[len(w.logs)-watchLogTail:]
+	}
+}
+
+// followedTask is the task whose logs are shown: the requested one, else
+// the newest task that has not finished, else the newest task.
+func (w *statusWatcher) followedTask() string {
+	if w.taskID != "" {
+		return w.taskID
+	}
+	var newest, active *core.Task
+	for i := range w.tasks {
+		t := &w.tasks[i]
+		if newest == nil || t.CreatedAt.After(newest.CreatedAt) {
+			newest = t
+		}
+		if t.Status != core.PhaseCompleted && t.Status != core.PhaseFailed &&
+			(active == nil || t.CreatedAt.After(active.CreatedAt)) {
+			active = t
+		}
+	}
+	switch {
+	case active != nil:
+		return active.ID
+	case newest != nil:
+		return newest.ID
+	}
+	return ""
+}
+
+// get performs an authenticated GET against the server API.
+func (w *statusWatcher) get(ctx context.Context, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, w.server+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if w.apiKey != "" {
+		req.Header.Set("X-API-Key", w.apiKey)
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusUnauthorized {
+			return nil, fmt.Errorf("unauthorized (set RIG_API_KEY or --api-key)")
+		}
+		return nil, fmt.Errorf("GET %s: %s", path, resp.Status)
+	}
+	return resp, nil
+}
+
+// render clears the terminal and draws the task table and log tail.
+func (w *statusWatcher) render() {
+	var b strings.Builder
+	b.WriteString("\033[H\033[2J")
+	fmt.Fprintf(&b, "rig status — %s — %s (Ctrl+C to quit)\n\n", w.server, time.Now().Format("15:04:05"))
+	if w.connErr != "" {
+		fmt.Fprintf(&b, "[disconnected] %s — retrying\n\n", w.connErr)
+	}
+
+	if len(w.tasks) == 0 {
+		b.WriteString("No tasks.\n")
+	} else {
+		fmt.Fprintf(&b, "%-30s %-18s %-22s %-8s %s\n", "TASK ID", "STATUS", "PHASE", "ATTEMPTS", "ISSUE")
+		for _, t := range w.tasks {
+			marker := " "
+			if t.ID == w.logTask {
+				marker = ">"
+			}
+			fmt.Fprintf(&b, "%s%-29s %-18s %-22s %-8d %s\n",
+				marker,
+				truncate(t.ID, 29),
+				t.Status,
+				truncate(currentPhase(t), 22),
+				len(t.Attempts),
+				truncate(t.Issue.Title, 40),
+			)
+		}
+	}
+
+	if w.logTask != "" {
+		fmt.Fprintf(&b, "\n--- logs: %s ---\n", w.logTask)
+		if w.logErr != "" {
+			fmt.Fprintf(&b, "(logs unavailable: %s)\n", w.logErr)
+		}
+		for _, e := range w.logs {
+			fmt.Fprintf(&b, "[%s] %-5s %s\n", e.Timestamp.Local().Format("15:04:05"), e.Level, truncate(e.Message, 160))
+		}
+	}
+	fmt.Fprint(w.out, b.String())
+}
+
+// currentPhase describes the latest pipeline step of a task.
+func currentPhase(t core.Task) string {
+	if len(t.Pipeline) == 0 {
+		return "-"
+	}
+	step := t.Pipeline[len(t.Pipeline)-1]
+	return fmt.Sprintf("%s (%s)", step.Phase, step.Status)
+}
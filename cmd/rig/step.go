@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+	"github.com/spf13/cobra"
+)
+
+// stepCmd exposes individual pipeline steps so an external workflow engine
+// (Temporal activity worker, Argo workflow step) can orchestrate rig.
+// Each subcommand prints a JSON document on stdout.
+var stepCmd = &cobra.Command{
+	Use:   "step",
+	Short: "Run individual pipeline steps for external orchestrators",
+}
+
+var stepStartCmd = &cobra.Command{
+	Use:   "start <issue-url>",
+	Short: "Create a queued task for an issue and print it as JSON",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadStepConfig(cmd)
+		if err != nil {
+			return err
+		}
+		issue, err := parseIssueURL(cfg, args[0])
+		if err != nil {
+			return fmt.Errorf("invalid issue URL: %w", err)
+		}
+
+		issueNumber, _ := strconv.Atoi(issue.ID)
+		engine, err := buildEngineForIssue(cfg, defaultStatePath, issueNumber)
+		if err != nil {
+			return err
+		}
+
+		task, err := engine.StartTask(cmd.Context(), issue)
+		if err != nil {
+			return fmt.Errorf("start task: %w", err)
+		}
+		return printStepJSON(task)
+	},
+}
+
+var stepRunCmd = &cobra.Command{
+	Use:   "run <plan|code|commit|deploy|test|report>",
+	Short: "Run a single step for a task and print its record as JSON",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		step, err := core.ParseStepName(args[0])
+		if err != nil {
+			return err
+		}
+		taskID, _ := cmd.Flags().GetString("task")
+		if taskID == "" {
+			return fmt.Errorf("--task flag is required")
+		}
+
+		state, err := core.LoadState(defaultStatePath)
+		if err != nil {
+			return fmt.Errorf("load state: %w", err)
+		}
+		task := state.GetTaskByID(taskID)
+		if task == nil {
+			return fmt.Errorf("task %q not found", taskID)
+		}
+
+		engine, err := buildStepEngine(cmd, task.Issue.ID)
+		if err != nil {
+			return err
+		}
+
+		rec, runErr := engine.RunStep(cmd.Context(), taskID, step)
+		if rec != nil {
+			if err := printStepJSON(rec); err != nil {
+				return err
+			}
+		}
+		return runErr
+	},
+}
+
+func buildStepEngine(cmd *cobra.Command, issueID string) (*core.Engine, error) {
+	cfg, err := loadStepConfig(cmd)
+	if err != nil {
+		return nil, err
+	}
+	issueNumber, _ := strconv.Atoi(issueID)
+	return buildEngineForIssue(cfg, defaultStatePath, issueNumber)
+}
+
+// loadStepConfig loads the config of --config, rig.yaml by default.
+func loadStepConfig(cmd *cobra.Command) (*config.Config, error) {
+	configPath, _ := cmd.Flags().GetString("config")
+	if configPath == "" {
+		configPath = "rig.yaml"
+	}
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	return cfg, nil
+}
+
+func printStepJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
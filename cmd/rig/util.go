@@ -1,5 +1,7 @@
 package main
 
+import "os"
+
 func truncateOutput(s string, max int) string {
 	return truncateWithSuffix(s, max, "...")
 }
@@ -17,3 +19,12 @@ func truncateWithSuffix(s string, max int, suffix string) string {
 	}
 	return s[:max-len(suffix)] + suffix
 }
+
+// envOr returns the environment variable name, or def when it is unset or
+// empty.
+func envOr(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
@@ -3,12 +3,14 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/httpserver"
 	"github.com/rigdev/rig/internal/storage"
 	"github.com/rigdev/rig/internal/web"
 	"github.com/spf13/cobra"
@@ -20,6 +22,7 @@ var webCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		configPath, _ := cmd.Flags().GetString("config")
 		port, _ := cmd.Flags().GetInt("port")
+		host, _ := cmd.Flags().GetString("host")
 
 		// Open SQLite database for settings/agents APIs.
 		db, err := storage.Open(defaultDBPath())
@@ -29,16 +32,35 @@ var webCmd = &cobra.Command{
 		defer db.Close()
 
 		// Load config: SQLite settings → rig.yaml → setup mode.
-		cfg, err := loadConfigFromSources(db, configPath)
+		cfg, _, err := loadConfigFromSources(db, configPath)
 		if err != nil {
 			return fmt.Errorf("load config: %w", err)
 		}
+		if err := setupLogging(cmd, cfg, nil); err != nil {
+			return err
+		}
+
+		var serverCfg config.ServerConfig
+		if cfg != nil {
+			serverCfg = cfg.Server
+		}
+		if host != "" {
+			serverCfg.Host = host
+		}
+		tlsCfg, err := httpserver.TLSConfig(serverCfg.TLS)
+		if err != nil {
+			return err
+		}
+		realIP, err := httpserver.RealIP(serverCfg.TrustedProxies)
+		if err != nil {
+			return err
+		}
 
 		handler := web.NewHandler(defaultStatePath, cfg, db)
 
 		srv := &http.Server{
-			Addr:         fmt.Sprintf(":%d", port),
-			Handler:      handler,
+			Addr:         httpserver.Addr(serverCfg.Host, port),
+			Handler:      realIP(handler),
 			ReadTimeout:  15 * time.Second,
 			WriteTimeout: 30 * time.Second,
 			IdleTimeout:  60 * time.Second,
@@ -49,8 +71,8 @@ var webCmd = &cobra.Command{
 
 		errCh := make(chan error, 1)
 		go func() {
-			log.Printf("Dashboard running at http://localhost:%d", port)
-			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			slog.Info("dashboard running", "url", fmt.Sprintf("%s://localhost:%d", httpserver.Scheme(tlsCfg), port))
+			if err := httpserver.ListenAndServe(srv, tlsCfg); err != nil && err != http.ErrServerClosed {
 				errCh <- err
 			}
 			close(errCh)
@@ -58,7 +80,7 @@ var webCmd = &cobra.Command{
 
 		select {
 		case <-ctx.Done():
-			log.Println("Shutting down dashboard server...")
+			slog.Info("shutting down dashboard server")
 			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 			defer cancel()
 			if err := srv.Shutdown(shutdownCtx); err != nil {
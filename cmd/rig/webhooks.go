@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/storage"
+	"github.com/rigdev/rig/internal/webhook"
+	"github.com/rigdev/rig/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+var webhooksCmd = &cobra.Command{
+	Use:   "webhooks",
+	Short: "Show and replay stored webhook deliveries",
+	Long: `rig serve and rig run store every signed webhook delivery before processing
+it. Failed deliveries are retried with backoff (1m, 2m, 4m, ... up to 1h) and
+marked dead after 5 attempts; replay processes any delivery again.
+
+  rig webhooks list --status dead
+  rig webhooks replay 42 --server https://rig.example.com`,
+}
+
+var webhooksListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List webhook deliveries, newest first",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		status, _ := cmd.Flags().GetString("status")
+		limit, _ := cmd.Flags().GetInt("limit")
+
+		var list []storage.WebhookDelivery
+		if rc := newRemoteClient(cmd); rc != nil {
+			var err error
+			list, err = rc.api.ListWebhookDeliveries(cmd.Context(), &client.ListWebhookDeliveriesParams{Status: status, Limit: limit})
+			if err != nil {
+				return fmt.Errorf("list webhook deliveries: %w", err)
+			}
+		} else {
+			db, err := storage.Open(defaultDBPath())
+			if err != nil {
+				return fmt.Errorf("open database: %w", err)
+			}
+			defer db.Close()
+			if list, err = db.ListDeliveries(storage.DeliveryFilter{Status: status, Limit: limit}); err != nil {
+				return err
+			}
+		}
+
+		if format := outputFormat(cmd); format != outputText {
+			return writeStructured(os.Stdout, format, list)
+		}
+		if len(list) == 0 {
+			fmt.Println("No webhook deliveries.")
+			return nil
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tRECEIVED\tENDPOINT\tEVENT\tTARGET\tSTATUS\tATTEMPTS\tERROR")
+		for _, d := range list {
+			event := d.Event
+			if d.Action != "" {
+				event += "." + d.Action
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%d\t%s\n",
+				d.ID, d.ReceivedAt.Local().Format("2006-01-02 15:04:05"), d.Endpoint, event, d.Target, d.Status, d.Attempts, d.Error)
+		}
+		return tw.Flush()
+	},
+}
+
+var webhooksReplayCmd = &cobra.Command{
+	Use:   "replay <id>",
+	Short: "Process a stored webhook delivery again",
+	Long: `Process a stored webhook delivery again, whatever its status. With --server
+the server replays it in the background; locally the task runs in this
+process using rig.yaml.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil || id <= 0 {
+			return fmt.Errorf("invalid delivery ID %q", args[0])
+		}
+
+		if rc := newRemoteClient(cmd); rc != nil {
+			resp, err := rc.api.ReplayWebhookDelivery(cmd.Context(), args[0])
+			if err != nil {
+				return fmt.Errorf("replay delivery: %w", err)
+			}
+			fmt.Println(resp.Message)
+			return nil
+		}
+
+		configPath, _ := cmd.Flags().GetString("config")
+		if configPath == "" {
+			configPath = "rig.yaml"
+		}
+		cfg, err := config.LoadConfig(configPath)
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+		db, err := storage.Open(defaultDBPath())
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer db.Close()
+
+		handler := webhook.NewHandler(cfg.Server.Secret, cfg.Workflow.Trigger, defaultStatePath, func(issue core.Issue) error {
+			issueNumber, err := strconv.Atoi(issue.ID)
+			if err != nil {
+				return fmt.Errorf("invalid issue ID %q: %w", issue.ID, err)
+			}
+			engine, err := buildEngineForIssue(cfg, defaultStatePath, issueNumber)
+			if err != nil {
+				return err
+			}
+			return engine.Execute(cmd.Context(), issue)
+		})
+		handler.SetEndpoints(webhook.Endpoints(cfg))
+		handler.SetAuditFunc(db.RecordAudit)
+		handler.SetDeliveryStore(db)
+
+		d, err := handler.Replay(id)
+		recordCLIAudit(storage.AuditWebhookReplayed, args[0], "")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Delivery %d replayed: %s\n", d.ID, d.Status)
+		return nil
+	},
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	adaptergit "github.com/rigdev/rig/internal/adapter/git"
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var workspacesCmd = &cobra.Command{
+	Use:   "workspaces",
+	Short: "Show and clean up the local checkouts",
+	Long: `rig clones each repository once under source.workspaces.root (default
+~/.rig/workspaces) and gives every task its own git worktree of that clone,
+removed when the task ends. gc removes the worktrees no running or waiting
+task needs, such as ones left behind by a crash.
+
+  rig workspaces list
+  rig workspaces gc --server https://rig.example.com`,
+}
+
+var workspacesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List repository clones and task worktrees with their disk usage",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var list []adaptergit.Workspace
+		if rc := newRemoteClient(cmd); rc != nil {
+			var err error
+			if list, err = rc.api.ListWorkspaces(cmd.Context()); err != nil {
+				return fmt.Errorf("list workspaces: %w", err)
+			}
+		} else {
+			cfg, err := loadWorkspacesConfig(cmd)
+			if err != nil {
+				return err
+			}
+			ws, err := newWorkspaces(cfg)
+			if err != nil {
+				return err
+			}
+			if list, err = ws.List(); err != nil {
+				return err
+			}
+		}
+
+		if format := outputFormat(cmd); format != outputText {
+			return writeStructured(os.Stdout, format, list)
+		}
+		if len(list) == 0 {
+			fmt.Println("No workspaces.")
+			return nil
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "REPO\tTASK\tSIZE\tMODIFIED\tPATH")
+		var total int64
+		for _, w := range list {
+			task := w.TaskID
+			if task == "" {
+				task = "(clone)"
+			}
+			total += w.SizeBytes
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+				w.Repo, task, formatSize(w.SizeBytes), w.ModifiedAt.Local().Format("2006-01-02 15:04:05"), w.Path)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		fmt.Printf("Total: %s\n", formatSize(total))
+		return nil
+	},
+}
+
+var workspacesGCCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Remove the task worktrees no running or waiting task needs",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var removed []adaptergit.Workspace
+		if rc := newRemoteClient(cmd); rc != nil {
+			resp, err := rc.api.CollectWorkspaces(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("collect workspaces: %w", err)
+			}
+			removed = resp.Removed
+		} else {
+			cfg, err := loadWorkspacesConfig(cmd)
+			if err != nil {
+				return err
+			}
+			ws, err := newWorkspaces(cfg)
+			if err != nil {
+				return err
+			}
+			removed, err = collectWorkspaces(cmd.Context(), ws)
+			if len(removed) > 0 {
+				recordCLIAudit(storage.AuditWorkspacesCollected, ws.Root(), fmt.Sprintf("removed %d worktrees", len(removed)))
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		var freed int64
+		for _, w := range removed {
+			freed += w.SizeBytes
+			fmt.Printf("Removed %s (%s, %s)\n", w.TaskID, w.Repo, formatSize(w.SizeBytes))
+		}
+		fmt.Printf("Removed %d worktrees, freed %s\n", len(removed), formatSize(freed))
+		return nil
+	},
+}
+
+// loadWorkspacesConfig loads rig.yaml or the --config file for its
+// source.workspaces settings.
+func loadWorkspacesConfig(cmd *cobra.Command) (*config.Config, error) {
+	configPath, _ := cmd.Flags().GetString("config")
+	if configPath == "" {
+		configPath = "rig.yaml"
+	}
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	return cfg, nil
+}
+
+// collectWorkspaces removes the task worktrees in ws whose task is neither
+// running nor awaiting approval.
+func collectWorkspaces(ctx context.Context, ws *adaptergit.Workspaces) ([]adaptergit.Workspace, error) {
+	state, err := core.LoadState(defaultStatePath)
+	if err != nil {
+		return nil, fmt.Errorf("load state: %w", err)
+	}
+	return ws.GC(ctx, state.NeedsWorkspace)
+}
+
+// runWorkspaceGC periodically removes the task worktrees no task needs
+// until ctx is cancelled.
+func runWorkspaceGC(ctx context.Context, currentCfg func() *config.Config, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		ws, err := newWorkspaces(currentCfg())
+		if err != nil {
+			slog.Warn("workspace gc", "err", err)
+			continue
+		}
+		removed, err := collectWorkspaces(ctx, ws)
+		if err != nil {
+			slog.Warn("workspace gc", "err", err)
+		}
+		if len(removed) > 0 {
+			slog.Info("workspace gc: removed worktrees", "count", len(removed))
+		}
+	}
+}
+
+// formatSize renders a byte count with a binary unit.
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
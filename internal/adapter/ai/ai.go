@@ -1,3 +1,49 @@
+// Package ai provides concrete AI adapter implementations.
 package ai
 
-// Package ai provides concrete AI adapter implementations.
+import (
+	"fmt"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+)
+
+// New creates the AI adapter for the configured provider. With
+// ai.fallback set, it returns an adapter that fails over to the fallback
+// providers in order; with ai.models set, calls are routed to the model
+// configured for them.
+func New(cfg config.AIConfig) (core.AIAdapter, error) {
+	primary, err := newProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.Fallback) == 0 {
+		return withRouting(primary, cfg), nil
+	}
+	f := &failoverAdapter{providers: []provider{{name: providerName(cfg.Provider), model: cfg.Model, adapter: primary}}}
+	for i, fb := range cfg.Fallback {
+		fcfg := cfg
+		fcfg.Provider, fcfg.Model, fcfg.APIKey, fcfg.Fallback = fb.Provider, fb.Model, fb.APIKey, nil
+		adapter, err := newProvider(fcfg)
+		if err != nil {
+			return nil, fmt.Errorf("ai.fallback[%d]: %w", i, err)
+		}
+		f.providers = append(f.providers, provider{name: fb.Provider, model: fb.Model, adapter: adapter})
+	}
+	return withRouting(f, cfg), nil
+}
+
+func newProvider(cfg config.AIConfig) (core.AIAdapter, error) {
+	switch cfg.Provider {
+	case "anthropic", "":
+		return NewAnthropic(cfg)
+	case "openai":
+		return NewOpenAI(cfg)
+	case "ollama":
+		return NewOllama(cfg)
+	case "claude-code":
+		return NewClaudeCode(cfg)
+	default:
+		return nil, fmt.Errorf("unsupported ai provider %q: supported providers are anthropic, openai, ollama, claude-code", cfg.Provider)
+	}
+}
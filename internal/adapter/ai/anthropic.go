@@ -1,9 +1,11 @@
 package ai
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -27,9 +29,16 @@ type AnthropicAdapter struct {
 	model    string
 	endpoint string
 	client   *http.Client
+	language string
+	timeouts callTimeouts
+	retry    retryPolicy
 }
 
-var _ core.AIAdapter = (*AnthropicAdapter)(nil)
+var (
+	_ core.AIAdapter      = (*AnthropicAdapter)(nil)
+	_ core.TaskSummarizer = (*AnthropicAdapter)(nil)
+	_ core.ModelSwitcher  = (*AnthropicAdapter)(nil)
+)
 
 // NewAnthropic creates a new AnthropicAdapter from the AI config.
 func NewAnthropic(cfg config.AIConfig) (*AnthropicAdapter, error) {
@@ -44,33 +53,43 @@ func NewAnthropic(cfg config.AIConfig) (*AnthropicAdapter, error) {
 		apiKey:   cfg.APIKey,
 		model:    model,
 		endpoint: defaultAnthropicURL,
-		client:   &http.Client{Timeout: defaultHTTPTimeout},
+		client:   &http.Client{},
+		language: cfg.Language,
+		timeouts: newCallTimeouts(cfg.Timeouts, defaultHTTPTimeout, defaultGenerateTimeout),
+		retry:    newRetryPolicy(cfg.Retry),
 	}, nil
 }
 
+// WithModel returns a copy of the adapter that uses model.
+func (a *AnthropicAdapter) WithModel(model string) core.AIAdapter {
+	c := *a
+	c.model = model
+	return &c
+}
+
 // AnalyzeIssue sends the issue to Anthropic and parses a Plan from the response.
 func (a *AnthropicAdapter) AnalyzeIssue(ctx context.Context, issue *core.AIIssue, projectContext string) (*core.AIPlan, error) {
 	if issue == nil {
 		return nil, fmt.Errorf("anthropic: issue is nil")
 	}
 
-	systemPrompt := buildSystemPrompt(projectContext)
+	systemPrompt := withAgents(ctx, buildSystemPrompt(projectContext))
 	userPrompt := fmt.Sprintf(
 		`Analyze the following issue and create an implementation plan.
 
 Issue Title: %s
 Issue Body:
 %s
-
+%s
 Respond in the following JSON format ONLY (no markdown fences, no extra text):
 {
   "summary": "Brief summary of what needs to be done",
   "steps": ["Step 1 description", "Step 2 description"]
 }`,
-		issue.Title, issue.Body,
+		issue.Title, issue.Body, formatIssueComments(issue.Comments),
 	)
 
-	body, err := a.sendMessage(ctx, systemPrompt, userPrompt)
+	body, err := a.sendMessage(ctx, callAnalyze, systemPrompt, userPrompt, planTool)
 	if err != nil {
 		return nil, fmt.Errorf("anthropic: analyze issue: %w", err)
 	}
@@ -84,7 +103,7 @@ func (a *AnthropicAdapter) GenerateCode(ctx context.Context, plan *core.AIPlan,
 		return nil, fmt.Errorf("anthropic: plan is nil")
 	}
 
-	systemPrompt := "You are a code generation assistant. Generate file changes to implement the given plan. Output valid JSON only."
+	systemPrompt := withAgents(ctx, "You are a code generation assistant. Generate file changes to implement the given plan. Output valid JSON only.")
 
 	var filesSection strings.Builder
 	for path, content := range repoFiles {
@@ -110,7 +129,7 @@ Respond in the following JSON format ONLY (no markdown fences, no extra text):
 		filesSection.String(),
 	)
 
-	body, err := a.sendMessage(ctx, systemPrompt, userPrompt)
+	body, err := a.sendMessage(ctx, callGenerate, systemPrompt, userPrompt, fileChangesTool)
 	if err != nil {
 		return nil, fmt.Errorf("anthropic: generate code: %w", err)
 	}
@@ -144,7 +163,7 @@ Respond in the following JSON format ONLY (no markdown fences, no extra text):
 		codeSection.String(),
 	)
 
-	body, err := a.sendMessage(ctx, systemPrompt, userPrompt)
+	body, err := a.sendMessage(ctx, callGenerate, systemPrompt, userPrompt, fileChangesTool)
 	if err != nil {
 		return nil, fmt.Errorf("anthropic: analyze failure: %w", err)
 	}
@@ -182,7 +201,7 @@ Respond in the following JSON format ONLY (no markdown fences, no extra text):
 		infraSection.String(),
 	)
 
-	body, err := a.sendMessage(ctx, systemPrompt, userPrompt)
+	body, err := a.sendMessage(ctx, callGenerate, systemPrompt, userPrompt, proposedFixTool)
 	if err != nil {
 		return nil, fmt.Errorf("anthropic: analyze deploy failure: %w", err)
 	}
@@ -190,12 +209,75 @@ Respond in the following JSON format ONLY (no markdown fences, no extra text):
 	return parseProposedFix(body)
 }
 
+// SummarizeTask asks Anthropic for a short human-readable status summary of a task.
+func (a *AnthropicAdapter) SummarizeTask(ctx context.Context, report string) (string, error) {
+	body, err := a.sendMessage(ctx, callAnalyze, summarySystemPrompt, buildSummaryPrompt(report), nil)
+	if err != nil {
+		return "", fmt.Errorf("anthropic: summarize task: %w", err)
+	}
+	return strings.TrimSpace(body), nil
+}
+
+// ExplainFailure asks Anthropic for a human-readable diagnosis of a failure report.
+func (a *AnthropicAdapter) ExplainFailure(ctx context.Context, report string) (string, error) {
+	body, err := a.sendMessage(ctx, callAnalyze, explainSystemPrompt, buildExplainPrompt(report), nil)
+	if err != nil {
+		return "", fmt.Errorf("anthropic: explain failure: %w", err)
+	}
+	return strings.TrimSpace(body), nil
+}
+
+// DecomposePlan asks Anthropic to split plan into sub-tasks for workflow.decompose.
+func (a *AnthropicAdapter) DecomposePlan(ctx context.Context, plan *core.AIPlan, maxSubtasks int) ([]core.AISubtask, error) {
+	body, err := a.sendMessage(ctx, callAnalyze, decomposeSystemPrompt, buildDecomposePrompt(plan, maxSubtasks), subtasksTool)
+	if err != nil {
+		return nil, fmt.Errorf("anthropic: decompose plan: %w", err)
+	}
+	return parseSubtasks(body)
+}
+
+// SplitEpic asks Anthropic to group the checklist of an epic issue into parts
+// for workflow.epic.
+func (a *AnthropicAdapter) SplitEpic(ctx context.Context, issue *core.AIIssue, items []string, maxParts int) ([]core.AISubtask, error) {
+	body, err := a.sendMessage(ctx, callAnalyze, epicSystemPrompt, buildEpicPrompt(issue, items, maxParts), subtasksTool)
+	if err != nil {
+		return nil, fmt.Errorf("anthropic: split epic: %w", err)
+	}
+	return parseSubtasks(body)
+}
+
+// ClassifyFailure asks Anthropic for the class of failure output for workflow.triage.
+func (a *AnthropicAdapter) ClassifyFailure(ctx context.Context, logs string) (core.FailureClass, error) {
+	body, err := a.sendMessage(ctx, callAnalyze, classifySystemPrompt, buildClassifyPrompt(logs), failureClassTool)
+	if err != nil {
+		return core.FailureUnknown, fmt.Errorf("anthropic: classify failure: %w", err)
+	}
+	return parseFailureClass(body)
+}
+
 // anthropicRequest is the Anthropic Messages API request body.
 type anthropicRequest struct {
 	Model     string             `json:"model"`
 	MaxTokens int                `json:"max_tokens"`
 	System    string             `json:"system,omitempty"`
 	Messages  []anthropicMessage `json:"messages"`
+	Stream    bool               `json:"stream,omitempty"`
+
+	Tools      []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice *anthropicToolChoice `json:"tool_choice,omitempty"`
+}
+
+// anthropicTool is a tool definition the model may call.
+type anthropicTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"input_schema"`
+}
+
+// anthropicToolChoice forces the model to call the named tool.
+type anthropicToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
 }
 
 // anthropicMessage is a single message in the Anthropic conversation.
@@ -207,13 +289,22 @@ type anthropicMessage struct {
 // anthropicResponse is the Anthropic Messages API response.
 type anthropicResponse struct {
 	Content []anthropicContentBlock `json:"content"`
+	Usage   anthropicUsage          `json:"usage"`
 	Error   *anthropicError         `json:"error,omitempty"`
 }
 
-// anthropicContentBlock is a content block in the API response.
+// anthropicUsage is the token usage reported by the API.
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// anthropicContentBlock is a content block in the API response: text, or
+// the input of a tool_use call.
 type anthropicContentBlock struct {
-	Type string `json:"type"`
-	Text string `json:"text"`
+	Type  string          `json:"type"`
+	Text  string          `json:"text"`
+	Input json.RawMessage `json:"input,omitempty"`
 }
 
 // anthropicError represents an API error response.
@@ -222,15 +313,24 @@ type anthropicError struct {
 	Message string `json:"message"`
 }
 
-// sendMessage posts a single prompt to the Anthropic Messages API and returns the text response.
-func (a *AnthropicAdapter) sendMessage(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+// sendMessage posts a single prompt to the Anthropic Messages API and returns
+// the text response, or the tool input when tool is set and the model
+// called it. The response is streamed so that long generations are kept
+// alive by server-sent pings; a stream that goes silent for the idle
+// timeout is aborted. Usage is recorded even when the call is cut short.
+func (a *AnthropicAdapter) sendMessage(ctx context.Context, kind callKind, systemPrompt, userPrompt string, tool *outputTool) (string, error) {
 	reqBody := anthropicRequest{
 		Model:     a.model,
 		MaxTokens: defaultMaxTokens,
-		System:    systemPrompt,
+		System:    withLanguage(systemPrompt, a.language),
 		Messages: []anthropicMessage{
 			{Role: "user", Content: userPrompt},
 		},
+		Stream: true,
+	}
+	if tool != nil {
+		reqBody.Tools = []anthropicTool{{Name: tool.name, Description: tool.description, InputSchema: tool.schema}}
+		reqBody.ToolChoice = &anthropicToolChoice{Type: "tool", Name: tool.name}
 	}
 
 	jsonData, err := json.Marshal(reqBody)
@@ -238,23 +338,45 @@ func (a *AnthropicAdapter) sendMessage(ctx context.Context, systemPrompt, userPr
 		return "", fmt.Errorf("marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint, bytes.NewReader(jsonData))
-	if err != nil {
-		return "", fmt.Errorf("create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", a.apiKey)
-	req.Header.Set("anthropic-version", defaultAnthropicVersion)
+	callCtx, cancelTimeout := a.timeouts.withTimeout(ctx, kind)
+	defer cancelTimeout()
+	callCtx, cancel := context.WithCancelCause(callCtx)
+	defer cancel(nil)
 
-	resp, err := a.client.Do(req)
+	resp, err := a.retry.do(ctx, a.client, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(callCtx, http.MethodPost, a.endpoint, bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, fmt.Errorf("create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("x-api-key", a.apiKey)
+		req.Header.Set("anthropic-version", defaultAnthropicVersion)
+		return req, nil
+	})
 	if err != nil {
+		recordUsage(ctx, callCtx, 0, 0)
 		return "", fmt.Errorf("send request: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusOK && strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		text, usage, err := readAnthropicStream(resp.Body, a.timeouts.idle, cancel)
+		recordUsage(ctx, callCtx, usage.InputTokens, usage.OutputTokens)
+		if err != nil {
+			if errors.Is(context.Cause(callCtx), errStreamIdle) {
+				return "", fmt.Errorf("no data from stream for %s: %w", a.timeouts.idle, errStreamIdle)
+			}
+			return "", err
+		}
+		if text == "" {
+			return "", fmt.Errorf("empty response: no text content block")
+		}
+		return text, nil
+	}
+
 	respData, err := io.ReadAll(resp.Body)
 	if err != nil {
+		recordUsage(ctx, callCtx, 0, 0)
 		return "", fmt.Errorf("read response: %w", err)
 	}
 
@@ -270,6 +392,7 @@ func (a *AnthropicAdapter) sendMessage(ctx context.Context, systemPrompt, userPr
 	if err := json.Unmarshal(respData, &apiResp); err != nil {
 		return "", fmt.Errorf("unmarshal response: %w", err)
 	}
+	recordUsage(ctx, callCtx, apiResp.Usage.InputTokens, apiResp.Usage.OutputTokens)
 
 	if apiResp.Error != nil {
 		return "", fmt.Errorf("api error: %s: %s", apiResp.Error.Type, apiResp.Error.Message)
@@ -279,7 +402,14 @@ func (a *AnthropicAdapter) sendMessage(ctx context.Context, systemPrompt, userPr
 		return "", fmt.Errorf("empty response: no content blocks")
 	}
 
-	// Extract text from the first text content block.
+	// Prefer the tool input; fall back to the first text content block.
+	for _, block := range apiResp.Content {
+		if block.Type == "tool_use" {
+			if args := toolArguments(block.Input); args != "" {
+				return args, nil
+			}
+		}
+	}
 	for _, block := range apiResp.Content {
 		if block.Type == "text" {
 			return block.Text, nil
@@ -288,3 +418,74 @@ func (a *AnthropicAdapter) sendMessage(ctx context.Context, systemPrompt, userPr
 
 	return "", fmt.Errorf("empty response: no text content block")
 }
+
+// anthropicStreamEvent is one server-sent event of a streamed response.
+type anthropicStreamEvent struct {
+	Type    string `json:"type"`
+	Message struct {
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message"`
+	Delta struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+	} `json:"delta"`
+	Usage anthropicUsage  `json:"usage"`
+	Error *anthropicError `json:"error"`
+}
+
+// readAnthropicStream collects the text of a streamed response, or the tool
+// input when the model called a tool. Every line,
+// including ping keepalives, resets the idle timer; when it fires the
+// request is cancelled with errStreamIdle. The usage seen so far is returned
+// even on error.
+func readAnthropicStream(body io.Reader, idle time.Duration, cancel context.CancelCauseFunc) (string, anthropicUsage, error) {
+	timer := time.AfterFunc(idle, func() { cancel(errStreamIdle) })
+	defer timer.Stop()
+
+	var (
+		text      strings.Builder
+		toolInput strings.Builder
+		usage     anthropicUsage
+	)
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		timer.Reset(idle)
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		var ev anthropicStreamEvent
+		if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &ev); err != nil {
+			continue
+		}
+		switch ev.Type {
+		case "message_start":
+			usage.InputTokens = ev.Message.Usage.InputTokens
+			usage.OutputTokens = ev.Message.Usage.OutputTokens
+		case "content_block_delta":
+			switch ev.Delta.Type {
+			case "text_delta":
+				text.WriteString(ev.Delta.Text)
+			case "input_json_delta":
+				toolInput.WriteString(ev.Delta.PartialJSON)
+			}
+		case "message_delta":
+			usage.OutputTokens = ev.Usage.OutputTokens
+		case "error":
+			if ev.Error != nil {
+				return "", usage, fmt.Errorf("api error: %s: %s", ev.Error.Type, ev.Error.Message)
+			}
+		case "message_stop":
+			if toolInput.Len() > 0 {
+				return toolInput.String(), usage, nil
+			}
+			return text.String(), usage, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", usage, fmt.Errorf("read stream: %w", err)
+	}
+	return "", usage, fmt.Errorf("read stream: %w", io.ErrUnexpectedEOF)
+}
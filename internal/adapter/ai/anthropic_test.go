@@ -3,10 +3,13 @@ package ai
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/rigdev/rig/internal/config"
 	"github.com/rigdev/rig/internal/core"
@@ -57,6 +60,7 @@ func newTestAdapter(t *testing.T, serverURL string) *AnthropicAdapter {
 		Provider: "anthropic",
 		APIKey:   "test-key",
 		Model:    "claude-3-5-sonnet-20241022",
+		Retry:    config.AIRetryConfig{InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
 	})
 	if err != nil {
 		t.Fatalf("NewAnthropic failed: %v", err)
@@ -162,6 +166,26 @@ func TestGenerateCode(t *testing.T) {
 	}
 }
 
+func TestGenerateCodeAgentsInstructions(t *testing.T) {
+	var system string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req anthropicRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		system = req.System
+		w.Write([]byte(`{"content": [{"type": "text", "text": "[]"}]}`))
+	}))
+	defer server.Close()
+
+	adapter := newTestAdapter(t, server.URL)
+	ctx := core.WithAgentsInstructions(context.Background(), "Use tabs.")
+	if _, err := adapter.GenerateCode(ctx, &core.AIPlan{Summary: "s"}, nil); err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+	if !strings.HasPrefix(system, "Follow these instructions from the repository's AGENTS.md:\nUse tabs.\n\nYou are a code generation assistant.") {
+		t.Errorf("expected the instructions ahead of the system prompt, got %q", system)
+	}
+}
+
 func TestGenerateCodeNilPlan(t *testing.T) {
 	adapter := newTestAdapter(t, "http://unused")
 	_, err := adapter.GenerateCode(context.Background(), nil, nil)
@@ -452,6 +476,124 @@ func TestRequestHeaders(t *testing.T) {
 	}
 }
 
+func TestLanguageInstruction(t *testing.T) {
+	var system string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req anthropicRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		system = req.System
+		planJSON := `{"summary": "세션 만료 처리", "steps": ["1단계"]}`
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"content": [{"type": "text", "text": ` + jsonEscape(planJSON) + `}]}`))
+	}))
+	defer server.Close()
+
+	adapter := newTestAdapter(t, server.URL)
+	adapter.language = "ko-KR"
+	if _, err := adapter.AnalyzeIssue(context.Background(), &core.AIIssue{Title: "Test", Body: "body"}, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(system, "in Korean") {
+		t.Errorf("expected Korean language instruction in system prompt, got %q", system)
+	}
+
+	if got := withLanguage("base", ""); got != "base" {
+		t.Errorf("expected no instruction without ai.language, got %q", got)
+	}
+	if got := withLanguage("base", "Brazilian Portuguese"); !strings.Contains(got, "in Brazilian Portuguese") {
+		t.Errorf("expected free-form language to pass through, got %q", got)
+	}
+}
+
+func TestStreamingResponseRecordsUsage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req anthropicRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if !req.Stream {
+			t.Error("expected a streaming request")
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, ev := range []string{
+			`{"type": "message_start", "message": {"usage": {"input_tokens": 120, "output_tokens": 1}}}`,
+			`{"type": "ping"}`,
+			`{"type": "content_block_delta", "index": 0, "delta": {"type": "text_delta", "text": "{\"summary\": \"Fix login\", "}}`,
+			`{"type": "content_block_delta", "index": 0, "delta": {"type": "text_delta", "text": "\"steps\": [\"a\"]}"}}`,
+			`{"type": "message_delta", "delta": {"stop_reason": "end_turn"}, "usage": {"output_tokens": 42}}`,
+			`{"type": "message_stop"}`,
+		} {
+			w.Write([]byte("data: " + ev + "\n\n"))
+		}
+	}))
+	defer server.Close()
+
+	adapter := newTestAdapter(t, server.URL)
+	task := &core.Task{ID: "t1"}
+	plan, err := adapter.AnalyzeIssue(core.WithTaskUsage(context.Background(), task), &core.AIIssue{Title: "Test"}, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if plan.Summary != "Fix login" {
+		t.Errorf("expected streamed plan, got %+v", plan)
+	}
+	if u := task.AIUsage; u == nil || u.Calls != 1 || u.InputTokens != 120 || u.OutputTokens != 42 || u.Interrupted != 0 {
+		t.Errorf("unexpected usage %+v", task.AIUsage)
+	}
+}
+
+func TestStreamIdleTimeoutRecordsPartialUsage(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"type": "message_start", "message": {"usage": {"input_tokens": 80, "output_tokens": 1}}}` + "\n\n"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	adapter := newTestAdapter(t, server.URL)
+	adapter.timeouts.idle = 50 * time.Millisecond
+	task := &core.Task{ID: "t1"}
+	_, err := adapter.GenerateCode(core.WithTaskUsage(context.Background(), task), &core.AIPlan{Summary: "x"}, nil)
+	if !errors.Is(err, errStreamIdle) {
+		t.Fatalf("expected idle stream error, got %v", err)
+	}
+	if u := task.AIUsage; u == nil || u.InputTokens != 80 || u.Interrupted != 1 {
+		t.Errorf("expected partial usage for the interrupted call, got %+v", task.AIUsage)
+	}
+}
+
+func TestTimeoutPerCallKind(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"content": [{"type": "text", "text": "[]"}], "usage": {"input_tokens": 5, "output_tokens": 2}}`))
+	}))
+	defer server.Close()
+
+	adapter := newTestAdapter(t, server.URL)
+	adapter.timeouts = newCallTimeouts(config.AITimeoutsConfig{Analyze: 20 * time.Millisecond, Generate: 5 * time.Second}, 0, 0)
+
+	task := &core.Task{ID: "t1"}
+	ctx := core.WithTaskUsage(context.Background(), task)
+	if _, err := adapter.AnalyzeIssue(ctx, &core.AIIssue{Title: "Test"}, ""); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected analyze timeout, got %v", err)
+	}
+	if _, err := adapter.GenerateCode(ctx, &core.AIPlan{Summary: "x"}, nil); err != nil {
+		t.Fatalf("expected generate to fit in its timeout, got %v", err)
+	}
+	if u := task.AIUsage; u.Calls != 2 || u.Interrupted != 1 || u.OutputTokens != 2 {
+		t.Errorf("unexpected usage %+v", u)
+	}
+}
+
 func TestCleanJSON(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -525,3 +667,98 @@ func jsonEscape(s string) string {
 	b, _ := json.Marshal(s)
 	return string(b)
 }
+
+func TestFormatIssueComments(t *testing.T) {
+	if got := formatIssueComments(nil); got != "" {
+		t.Errorf("expected empty discussion, got %q", got)
+	}
+
+	comments := []core.IssueComment{
+		{Author: "alice", Body: strings.Repeat("x", maxIssueDiscussionBytes), CreatedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Author: "bob", Body: "please also cover SSO", CreatedAt: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	got := formatIssueComments(comments)
+	if !strings.Contains(got, "@bob (2025-01-02):\nplease also cover SSO") {
+		t.Errorf("expected latest comment, got %q", got)
+	}
+	if strings.Contains(got, "@alice") || !strings.Contains(got, "1 earlier comments omitted") {
+		t.Errorf("expected oldest comment to be dropped, got %q", got)
+	}
+}
+
+func TestParseSubtasks(t *testing.T) {
+	want := []core.AISubtask{{Name: "backend", Summary: "limiter", Steps: []string{"Add limiter"}}}
+	for _, raw := range []string{
+		`{"subtasks": [{"name": "backend", "summary": "limiter", "steps": ["Add limiter"]}]}`,
+		"```json\n[{\"name\": \"backend\", \"summary\": \"limiter\", \"steps\": [\"Add limiter\"]}]\n```",
+	} {
+		got, err := parseSubtasks(raw)
+		if err != nil {
+			t.Fatalf("parseSubtasks(%q): %v", raw, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("parseSubtasks(%q) = %+v", raw, got)
+		}
+	}
+	if _, err := parseSubtasks("no json here"); err == nil {
+		t.Error("expected an error for a response without sub-tasks")
+	}
+}
+
+func TestParseFailureClass(t *testing.T) {
+	for raw, want := range map[string]core.FailureClass{
+		`{"class": "infra"}`:                     core.FailureInfra,
+		"```json\n{\"class\": \"compile\"}\n```": core.FailureCompile,
+		"Timeout.":                               core.FailureTimeout,
+	} {
+		got, err := parseFailureClass(raw)
+		if err != nil || got != want {
+			t.Errorf("parseFailureClass(%q) = %s, %v; want %s", raw, got, err, want)
+		}
+	}
+	if _, err := parseFailureClass(`{"class": "cosmic rays"}`); err == nil {
+		t.Error("expected an error for an unknown class")
+	}
+}
+
+func TestToolUseStructuredOutput(t *testing.T) {
+	var tools []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req anthropicRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if len(req.Tools) != 1 || req.ToolChoice == nil || req.ToolChoice.Type != "tool" || req.ToolChoice.Name != req.Tools[0].Name {
+			t.Errorf("expected one forced tool, got %+v / %+v", req.Tools, req.ToolChoice)
+		}
+		tools = append(tools, req.ToolChoice.Name)
+
+		if req.ToolChoice.Name == fileChangesTool.name {
+			w.Header().Set("Content-Type", "text/event-stream")
+			for _, ev := range []string{
+				`{"type": "message_start", "message": {"usage": {"input_tokens": 10, "output_tokens": 1}}}`,
+				`{"type": "content_block_start", "index": 0, "content_block": {"type": "tool_use", "name": "submit_file_changes", "input": {}}}`,
+				`{"type": "content_block_delta", "index": 0, "delta": {"type": "input_json_delta", "partial_json": "{\"changes\": [{\"path\": \"a.go\", "}}`,
+				`{"type": "content_block_delta", "index": 0, "delta": {"type": "input_json_delta", "partial_json": "\"content\": \"package a\", \"action\": \"modify\"}]}"}}`,
+				`{"type": "message_stop"}`,
+			} {
+				w.Write([]byte("data: " + ev + "\n\n"))
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"content": [{"type": "tool_use", "name": "submit_plan", "input": {"summary": "Fix login", "steps": ["a", "b"]}}]}`))
+	}))
+	defer server.Close()
+
+	adapter := newTestAdapter(t, server.URL)
+	plan, err := adapter.AnalyzeIssue(context.Background(), &core.AIIssue{Title: "Test"}, "")
+	if err != nil || plan.Summary != "Fix login" || len(plan.Steps) != 2 {
+		t.Fatalf("AnalyzeIssue = %+v, %v", plan, err)
+	}
+	changes, err := adapter.GenerateCode(context.Background(), plan, nil)
+	if err != nil || len(changes) != 1 || changes[0].Path != "a.go" || changes[0].Action != "modify" {
+		t.Fatalf("GenerateCode = %+v, %v", changes, err)
+	}
+	if strings.Join(tools, ",") != "submit_plan,submit_file_changes" {
+		t.Errorf("tools = %v", tools)
+	}
+}
@@ -0,0 +1,192 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+)
+
+// defaultCacheTTL is how long cached answers are reused when ai.cache.ttl
+// is not set.
+const defaultCacheTTL = 24 * time.Hour
+
+// cacheKeyVersion is mixed into every cache key; bump it when prompts or
+// response parsing change so stale answers are not reused.
+const cacheKeyVersion = "v1"
+
+// Cache stores AI responses by key. *storage.DB implements it.
+type Cache interface {
+	GetAICache(key string) (string, bool, error)
+	PutAICache(key, value string, ttl time.Duration) error
+}
+
+// cachingAdapter answers AnalyzeIssue and GenerateCode from a cache keyed
+// on a hash of the request, so re-running the same issue does not pay for
+// the same analysis twice. Failure analysis is not cached: its input is new
+// logs every time.
+type cachingAdapter struct {
+	core.AIAdapter
+	cache    Cache
+	ttl      time.Duration
+	provider string
+	model    string
+	language string
+	models   config.AIModelsConfig
+}
+
+var (
+	_ core.TaskSummarizer = (*cachingAdapter)(nil)
+	_ core.ModelSwitcher  = (*cachingAdapter)(nil)
+)
+
+// WithCache wraps adapter so its planning and code generation answers are
+// cached for ai.cache.ttl. Cache errors are logged and never fail a call.
+func WithCache(adapter core.AIAdapter, cfg config.AIConfig, cache Cache) core.AIAdapter {
+	ttl := cfg.Cache.TTL
+	if ttl <= 0 {
+		ttl = defaultCacheTTL
+	}
+	return &cachingAdapter{
+		AIAdapter: adapter,
+		cache:     cache,
+		ttl:       ttl,
+		provider:  providerName(cfg.Provider),
+		model:     cfg.Model,
+		language:  cfg.Language,
+		models:    cfg.Models,
+	}
+}
+
+func (c *cachingAdapter) AnalyzeIssue(ctx context.Context, issue *core.AIIssue, projectContext string) (*core.AIPlan, error) {
+	return cached(ctx, c, "analyze_issue", []any{issue, projectContext, core.AgentsInstructions(ctx)}, func() (*core.AIPlan, error) {
+		return c.AIAdapter.AnalyzeIssue(ctx, issue, projectContext)
+	})
+}
+
+func (c *cachingAdapter) GenerateCode(ctx context.Context, plan *core.AIPlan, repoFiles map[string]string) ([]core.AIFileChange, error) {
+	return cached(ctx, c, "generate_code", []any{plan, repoFiles, core.AgentsInstructions(ctx)}, func() ([]core.AIFileChange, error) {
+		return c.AIAdapter.GenerateCode(ctx, plan, repoFiles)
+	})
+}
+
+// SummarizeTask passes through to the wrapped adapter.
+func (c *cachingAdapter) SummarizeTask(ctx context.Context, report string) (string, error) {
+	s, ok := c.AIAdapter.(core.TaskSummarizer)
+	if !ok {
+		return "", core.ErrSummaryUnsupported
+	}
+	return s.SummarizeTask(ctx, report)
+}
+
+// ExplainFailure passes through to the wrapped adapter.
+func (c *cachingAdapter) ExplainFailure(ctx context.Context, report string) (string, error) {
+	e, ok := c.AIAdapter.(core.FailureExplainer)
+	if !ok {
+		return "", core.ErrExplainUnsupported
+	}
+	return e.ExplainFailure(ctx, report)
+}
+
+// DecomposePlan is cached like AnalyzeIssue when the wrapped adapter can
+// split plans.
+func (c *cachingAdapter) DecomposePlan(ctx context.Context, plan *core.AIPlan, maxSubtasks int) ([]core.AISubtask, error) {
+	d, ok := c.AIAdapter.(core.PlanDecomposer)
+	if !ok {
+		return nil, core.ErrDecomposeUnsupported
+	}
+	return cached(ctx, c, "decompose_plan", []any{plan, maxSubtasks}, func() ([]core.AISubtask, error) {
+		return d.DecomposePlan(ctx, plan, maxSubtasks)
+	})
+}
+
+// SplitEpic is cached like AnalyzeIssue when the wrapped adapter can split
+// epics.
+func (c *cachingAdapter) SplitEpic(ctx context.Context, issue *core.AIIssue, items []string, maxParts int) ([]core.AISubtask, error) {
+	sp, ok := c.AIAdapter.(core.EpicSplitter)
+	if !ok {
+		return nil, core.ErrSplitEpicUnsupported
+	}
+	return cached(ctx, c, "split_epic", []any{issue, items, maxParts}, func() ([]core.AISubtask, error) {
+		return sp.SplitEpic(ctx, issue, items, maxParts)
+	})
+}
+
+// ClassifyFailure is cached like AnalyzeFailure when the wrapped adapter
+// can classify failures.
+func (c *cachingAdapter) ClassifyFailure(ctx context.Context, logs string) (core.FailureClass, error) {
+	cl, ok := c.AIAdapter.(core.FailureClassifier)
+	if !ok {
+		return core.FailureUnknown, core.ErrClassifyUnsupported
+	}
+	return cached(ctx, c, "classify_failure", []any{logs}, func() (core.FailureClass, error) {
+		return cl.ClassifyFailure(ctx, logs)
+	})
+}
+
+// WithModel switches the wrapped adapter to model; answers are cached per
+// model, so the switched adapter does not reuse the other model's answers.
+func (c *cachingAdapter) WithModel(model string) core.AIAdapter {
+	switcher, ok := c.AIAdapter.(core.ModelSwitcher)
+	if !ok {
+		return c
+	}
+	switched := *c
+	switched.AIAdapter = switcher.WithModel(model)
+	switched.model = model
+	return &switched
+}
+
+// key hashes everything that shapes an answer: the method, the provider
+// and model, the ai.models routing, the response language and the request
+// itself.
+func (c *cachingAdapter) key(method string, inputs []any) (string, error) {
+	h := sha256.New()
+	enc := json.NewEncoder(h)
+	for _, v := range append([]any{cacheKeyVersion, method, c.provider, c.model, c.models, c.language}, inputs...) {
+		if err := enc.Encode(v); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// cached returns the cached answer for method and inputs, or calls call and
+// caches its answer. With core.WithoutAICache the cached answer is skipped
+// but the fresh one still replaces it.
+func cached[T any](ctx context.Context, c *cachingAdapter, method string, inputs []any, call func() (T, error)) (T, error) {
+	key, err := c.key(method, inputs)
+	if err != nil {
+		slog.Warn("ai cache: hash request", "method", method, "err", err)
+		return call()
+	}
+	if !core.AICacheBypassed(ctx) {
+		if value, ok, err := c.cache.GetAICache(key); err != nil {
+			slog.Warn("ai cache: read", "method", method, "err", err)
+		} else if ok {
+			var v T
+			if err := json.Unmarshal([]byte(value), &v); err == nil {
+				slog.Debug("ai cache hit", "method", method)
+				core.RecordAICacheHit(ctx)
+				return v, nil
+			}
+			slog.Warn("ai cache: decode cached answer", "method", method, "err", err)
+		}
+	}
+
+	v, err := call()
+	if err != nil {
+		return v, err
+	}
+	if data, err := json.Marshal(v); err != nil {
+		slog.Warn("ai cache: encode answer", "method", method, "err", err)
+	} else if err := c.cache.PutAICache(key, string(data), c.ttl); err != nil {
+		slog.Warn("ai cache: write", "method", method, "err", err)
+	}
+	return v, nil
+}
@@ -0,0 +1,99 @@
+package ai
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+)
+
+// memCache is an in-memory Cache.
+type memCache struct {
+	entries map[string]string
+	ttls    map[string]time.Duration
+}
+
+func newMemCache() *memCache {
+	return &memCache{entries: map[string]string{}, ttls: map[string]time.Duration{}}
+}
+
+func (m *memCache) GetAICache(key string) (string, bool, error) {
+	v, ok := m.entries[key]
+	return v, ok, nil
+}
+
+func (m *memCache) PutAICache(key, value string, ttl time.Duration) error {
+	m.entries[key], m.ttls[key] = value, ttl
+	return nil
+}
+
+// switchableStub is a stubAI that can switch models.
+type switchableStub struct{ *stubAI }
+
+func (s switchableStub) WithModel(model string) core.AIAdapter { return s }
+
+func TestCacheAnalyzeIssue(t *testing.T) {
+	stub := &stubAI{plan: "plan"}
+	cache := newMemCache()
+	adapter := WithCache(switchableStub{stub}, config.AIConfig{Provider: "openai", Model: "gpt-4o"}, cache)
+	issue := &core.AIIssue{Title: "t", Body: "b"}
+
+	task := &core.Task{}
+	ctx := core.WithTaskUsage(context.Background(), task)
+	for range 2 {
+		plan, err := adapter.AnalyzeIssue(ctx, issue, "ctx")
+		if err != nil || plan.Summary != "plan" {
+			t.Fatalf("AnalyzeIssue = %+v, %v", plan, err)
+		}
+	}
+	if stub.calls != 1 {
+		t.Errorf("provider called %d times, want 1", stub.calls)
+	}
+	if task.AIUsage == nil || task.AIUsage.CacheHits != 1 {
+		t.Errorf("usage = %+v, want 1 cache hit", task.AIUsage)
+	}
+	for key, ttl := range cache.ttls {
+		if ttl != defaultCacheTTL {
+			t.Errorf("entry %s cached for %v, want %v", key, ttl, defaultCacheTTL)
+		}
+	}
+
+	// A different request misses.
+	if _, err := adapter.AnalyzeIssue(ctx, &core.AIIssue{Title: "t", Body: "changed"}, "ctx"); err != nil || stub.calls != 2 {
+		t.Errorf("a changed issue should miss (calls %d, err %v)", stub.calls, err)
+	}
+
+	// Bypassing asks again and refreshes the entry.
+	stub.plan = "fresh"
+	plan, err := adapter.AnalyzeIssue(core.WithoutAICache(ctx), issue, "ctx")
+	if err != nil || plan.Summary != "fresh" || stub.calls != 3 {
+		t.Fatalf("bypass = %+v, %v (calls %d)", plan, err, stub.calls)
+	}
+	if plan, _ := adapter.AnalyzeIssue(ctx, issue, "ctx"); plan.Summary != "fresh" || stub.calls != 3 {
+		t.Errorf("the bypassed answer should replace the cached one, got %q (calls %d)", plan.Summary, stub.calls)
+	}
+
+	// Another model has its own entries.
+	switched := adapter.(core.ModelSwitcher).WithModel("gpt-4o-mini")
+	if _, err := switched.AnalyzeIssue(ctx, issue, "ctx"); err != nil || stub.calls != 4 {
+		t.Errorf("another model should miss (calls %d, err %v)", stub.calls, err)
+	}
+}
+
+func TestCacheSkipsFailures(t *testing.T) {
+	stub := &stubAI{err: context.DeadlineExceeded}
+	cache := newMemCache()
+	adapter := WithCache(stub, config.AIConfig{Cache: config.AICacheConfig{Enabled: true, TTL: time.Hour}}, cache)
+
+	if _, err := adapter.AnalyzeIssue(context.Background(), &core.AIIssue{}, ""); err == nil {
+		t.Fatal("expected the provider error")
+	}
+	if len(cache.entries) != 0 {
+		t.Errorf("a failed call should not be cached: %v", cache.entries)
+	}
+	if _, ok := adapter.(core.ModelSwitcher).WithModel("other").(*cachingAdapter); !ok {
+		t.Error("WithModel on an adapter that cannot switch should keep the cache")
+	}
+}
@@ -0,0 +1,67 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+)
+
+// defaultGenerateTimeout bounds code generation calls, whose responses are
+// much longer than plans.
+const defaultGenerateTimeout = 5 * time.Minute
+
+// defaultIdleTimeout is how long a streamed response may stay silent.
+const defaultIdleTimeout = 60 * time.Second
+
+// errStreamIdle aborts a streamed response that stopped sending events.
+var errStreamIdle = errors.New("stream idle")
+
+// callKind selects the timeout applied to an AI call.
+type callKind int
+
+const (
+	callAnalyze  callKind = iota // issue analysis and summaries
+	callGenerate                 // code, fix and deploy-fix generation
+)
+
+// callTimeouts are the per-kind timeouts resolved from ai.timeouts.
+type callTimeouts struct {
+	analyze  time.Duration
+	generate time.Duration
+	idle     time.Duration
+}
+
+// newCallTimeouts applies ai.timeouts over the provider defaults.
+func newCallTimeouts(cfg config.AITimeoutsConfig, analyze, generate time.Duration) callTimeouts {
+	t := callTimeouts{analyze: analyze, generate: generate, idle: defaultIdleTimeout}
+	if cfg.Analyze > 0 {
+		t.analyze = cfg.Analyze
+	}
+	if cfg.Generate > 0 {
+		t.generate = cfg.Generate
+	}
+	if cfg.Idle > 0 {
+		t.idle = cfg.Idle
+	}
+	return t
+}
+
+// withTimeout bounds ctx by the timeout for kind. Cancelling the returned
+// context aborts the in-flight request.
+func (t callTimeouts) withTimeout(ctx context.Context, kind callKind) (context.Context, context.CancelFunc) {
+	d := t.analyze
+	if kind == callGenerate {
+		d = t.generate
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// recordUsage reports one call's token usage to the task in ctx. callCtx is
+// the bounded context the request ran under; a done callCtx means the call
+// was interrupted and the usage is partial.
+func recordUsage(ctx, callCtx context.Context, inputTokens, outputTokens int) {
+	core.RecordAIUsage(ctx, inputTokens, outputTokens, callCtx.Err() != nil)
+}
@@ -19,10 +19,15 @@ const defaultClaudeTimeout = 5 * time.Minute
 type ClaudeCodeAdapter struct {
 	claudePath string
 	model      string
-	timeout    time.Duration
+	language   string
+	timeouts   callTimeouts
 }
 
-var _ core.AIAdapter = (*ClaudeCodeAdapter)(nil)
+var (
+	_ core.AIAdapter      = (*ClaudeCodeAdapter)(nil)
+	_ core.TaskSummarizer = (*ClaudeCodeAdapter)(nil)
+	_ core.ModelSwitcher  = (*ClaudeCodeAdapter)(nil)
+)
 
 // NewClaudeCode creates a new ClaudeCodeAdapter.
 // The claude CLI must be available on PATH.
@@ -34,10 +39,18 @@ func NewClaudeCode(cfg config.AIConfig) (*ClaudeCodeAdapter, error) {
 	return &ClaudeCodeAdapter{
 		claudePath: claudePath,
 		model:      cfg.Model,
-		timeout:    defaultClaudeTimeout,
+		language:   cfg.Language,
+		timeouts:   newCallTimeouts(cfg.Timeouts, defaultClaudeTimeout, defaultClaudeTimeout),
 	}, nil
 }
 
+// WithModel returns a copy of the adapter that uses model.
+func (a *ClaudeCodeAdapter) WithModel(model string) core.AIAdapter {
+	c := *a
+	c.model = model
+	return &c
+}
+
 func (a *ClaudeCodeAdapter) AnalyzeIssue(ctx context.Context, issue *core.AIIssue, projectContext string) (*core.AIPlan, error) {
 	if issue == nil {
 		return nil, fmt.Errorf("claude-code: issue is nil")
@@ -49,21 +62,21 @@ func (a *ClaudeCodeAdapter) AnalyzeIssue(ctx context.Context, issue *core.AIIssu
 	}
 
 	prompt := a.buildPrompt(
-		buildSystemPrompt(projectContext),
+		withAgents(ctx, buildSystemPrompt(projectContext)),
 		fmt.Sprintf(
 			`Analyze the following issue and create an implementation plan.
 
 Issue Title: %s
 Issue Body:
 %s
-
+%s
 IMPORTANT: You MUST respond with ONLY a JSON object. No explanation, no markdown, no text before or after. Just the raw JSON:
 {"summary": "what needs to be done", "steps": ["step 1", "step 2"]}`,
-			issue.Title, body,
+			issue.Title, body, formatIssueComments(issue.Comments),
 		),
 	)
 
-	body, err := a.runClaude(ctx, prompt)
+	body, err := a.runClaude(ctx, callAnalyze, prompt)
 	if err != nil {
 		return nil, fmt.Errorf("claude-code: analyze issue: %w", err)
 	}
@@ -82,7 +95,7 @@ func (a *ClaudeCodeAdapter) GenerateCode(ctx context.Context, plan *core.AIPlan,
 	}
 
 	prompt := a.buildPrompt(
-		"You are a JSON API that returns file changes. You do NOT write files. You do NOT need permissions. You ONLY output a JSON array. No markdown. No explanation.",
+		withAgents(ctx, "You are a JSON API that returns file changes. You do NOT write files. You do NOT need permissions. You ONLY output a JSON array. No markdown. No explanation."),
 		fmt.Sprintf(
 			`Return a JSON array of file changes to implement this plan.
 
@@ -107,7 +120,7 @@ RULES:
 		),
 	)
 
-	body, err := a.runClaude(ctx, prompt)
+	body, err := a.runClaude(ctx, callGenerate, prompt)
 	if err != nil {
 		return nil, fmt.Errorf("claude-code: generate code: %w", err)
 	}
@@ -139,7 +152,7 @@ IMPORTANT: You MUST respond with ONLY a JSON array. No explanation, no markdown,
 		),
 	)
 
-	body, err := a.runClaude(ctx, prompt)
+	body, err := a.runClaude(ctx, callGenerate, prompt)
 	if err != nil {
 		return nil, fmt.Errorf("claude-code: analyze failure: %w", err)
 	}
@@ -171,7 +184,7 @@ IMPORTANT: You MUST respond with ONLY a JSON object. No explanation, no markdown
 		),
 	)
 
-	body, err := a.runClaude(ctx, prompt)
+	body, err := a.runClaude(ctx, callGenerate, prompt)
 	if err != nil {
 		return nil, fmt.Errorf("claude-code: analyze deploy failure: %w", err)
 	}
@@ -179,26 +192,73 @@ IMPORTANT: You MUST respond with ONLY a JSON object. No explanation, no markdown
 	return parseProposedFix(body)
 }
 
+// SummarizeTask asks the claude CLI for a short human-readable status summary of a task.
+func (a *ClaudeCodeAdapter) SummarizeTask(ctx context.Context, report string) (string, error) {
+	body, err := a.runClaude(ctx, callAnalyze, a.buildPrompt(summarySystemPrompt, buildSummaryPrompt(report)))
+	if err != nil {
+		return "", fmt.Errorf("claude-code: summarize task: %w", err)
+	}
+	return strings.TrimSpace(body), nil
+}
+
+// ExplainFailure asks the claude CLI for a human-readable diagnosis of a failure report.
+func (a *ClaudeCodeAdapter) ExplainFailure(ctx context.Context, report string) (string, error) {
+	body, err := a.runClaude(ctx, callAnalyze, a.buildPrompt(explainSystemPrompt, buildExplainPrompt(report)))
+	if err != nil {
+		return "", fmt.Errorf("claude-code: explain failure: %w", err)
+	}
+	return strings.TrimSpace(body), nil
+}
+
+// DecomposePlan asks the claude CLI to split plan into sub-tasks for workflow.decompose.
+func (a *ClaudeCodeAdapter) DecomposePlan(ctx context.Context, plan *core.AIPlan, maxSubtasks int) ([]core.AISubtask, error) {
+	body, err := a.runClaude(ctx, callAnalyze, a.buildPrompt(decomposeSystemPrompt, buildDecomposePrompt(plan, maxSubtasks)))
+	if err != nil {
+		return nil, fmt.Errorf("claude-code: decompose plan: %w", err)
+	}
+	return parseSubtasks(body)
+}
+
+// SplitEpic asks the claude CLI to group the checklist of an epic issue into parts
+// for workflow.epic.
+func (a *ClaudeCodeAdapter) SplitEpic(ctx context.Context, issue *core.AIIssue, items []string, maxParts int) ([]core.AISubtask, error) {
+	body, err := a.runClaude(ctx, callAnalyze, a.buildPrompt(epicSystemPrompt, buildEpicPrompt(issue, items, maxParts)))
+	if err != nil {
+		return nil, fmt.Errorf("claude-code: split epic: %w", err)
+	}
+	return parseSubtasks(body)
+}
+
+// ClassifyFailure asks the claude CLI for the class of failure output for workflow.triage.
+func (a *ClaudeCodeAdapter) ClassifyFailure(ctx context.Context, logs string) (core.FailureClass, error) {
+	body, err := a.runClaude(ctx, callAnalyze, a.buildPrompt(classifySystemPrompt, buildClassifyPrompt(logs)))
+	if err != nil {
+		return core.FailureUnknown, fmt.Errorf("claude-code: classify failure: %w", err)
+	}
+	return parseFailureClass(body)
+}
+
 // buildPrompt combines system and user prompts for the claude CLI.
 func (a *ClaudeCodeAdapter) buildPrompt(systemPrompt, userPrompt string) string {
-	return fmt.Sprintf("%s\n\n%s", systemPrompt, userPrompt)
+	return fmt.Sprintf("%s\n\n%s", withLanguage(systemPrompt, a.language), userPrompt)
 }
 
 // runClaude executes the claude CLI with the given prompt and returns the text response.
-func (a *ClaudeCodeAdapter) runClaude(ctx context.Context, prompt string) (string, error) {
-	ctx, cancel := context.WithTimeout(ctx, a.timeout)
+func (a *ClaudeCodeAdapter) runClaude(ctx context.Context, kind callKind, prompt string) (string, error) {
+	callCtx, cancel := a.timeouts.withTimeout(ctx, kind)
 	defer cancel()
 
 	args := []string{"-p", prompt, "--output-format", "json", "--max-turns", "1"}
 	if a.model != "" {
 		args = append(args, "--model", a.model)
 	}
-	cmd := exec.CommandContext(ctx, a.claudePath, args...)
+	cmd := exec.CommandContext(callCtx, a.claudePath, args...)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
+		recordUsage(ctx, callCtx, 0, 0)
 		return "", fmt.Errorf("command failed: %w (stderr: %s)", err, stderr.String())
 	}
 
@@ -207,10 +267,16 @@ func (a *ClaudeCodeAdapter) runClaude(ctx context.Context, prompt string) (strin
 	// Claude CLI with --output-format json wraps the result in a JSON envelope.
 	var envelope struct {
 		Result string `json:"result"`
+		Usage  struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
 	}
 	if err := json.Unmarshal(raw, &envelope); err == nil && envelope.Result != "" {
+		recordUsage(ctx, callCtx, envelope.Usage.InputTokens, envelope.Usage.OutputTokens)
 		return envelope.Result, nil
 	}
+	recordUsage(ctx, callCtx, 0, 0)
 
 	// Fallback: the output might be a JSON array of results.
 	var results []struct {
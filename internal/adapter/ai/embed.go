@@ -0,0 +1,149 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/rigdev/rig/internal/config"
+)
+
+const (
+	defaultOpenAIEmbeddingsURL = "https://api.openai.com/v1/embeddings"
+	defaultOllamaEmbeddingsURL = "http://localhost:11434/v1/embeddings"
+
+	defaultOpenAIEmbeddingModel = "text-embedding-3-small"
+	defaultOllamaEmbeddingModel = "nomic-embed-text"
+)
+
+// Embedder computes embeddings with the OpenAI-compatible embeddings API
+// of OpenAI or Ollama, for ai.index.
+type Embedder struct {
+	apiKey   string
+	model    string
+	endpoint string
+	client   *http.Client
+	timeouts callTimeouts
+	retry    retryPolicy
+}
+
+// NewEmbedder creates the embedder ai.index configures.
+func NewEmbedder(cfg config.AIConfig) (*Embedder, error) {
+	idx := cfg.Index
+	provider, apiKey := idx.Provider, idx.APIKey
+	if provider == "" {
+		provider = providerName(cfg.Provider)
+	}
+	if apiKey == "" && provider == providerName(cfg.Provider) {
+		apiKey = cfg.APIKey
+	}
+
+	e := &Embedder{
+		apiKey:   apiKey,
+		model:    idx.Model,
+		client:   &http.Client{},
+		timeouts: newCallTimeouts(cfg.Timeouts, defaultHTTPTimeout, defaultGenerateTimeout),
+		retry:    newRetryPolicy(cfg.Retry),
+	}
+	switch provider {
+	case "openai":
+		if apiKey == "" {
+			return nil, fmt.Errorf("openai embeddings: ai.index.api_key is required")
+		}
+		e.endpoint = defaultOpenAIEmbeddingsURL
+		if e.model == "" {
+			e.model = defaultOpenAIEmbeddingModel
+		}
+	case "ollama":
+		e.endpoint = defaultOllamaEmbeddingsURL
+		if chat := strings.TrimSpace(os.Getenv("OLLAMA_API_ENDPOINT")); chat != "" {
+			e.endpoint = strings.TrimSuffix(chat, "/chat/completions") + "/embeddings"
+		}
+		if e.model == "" {
+			e.model = defaultOllamaEmbeddingModel
+		}
+	default:
+		return nil, fmt.Errorf("ai.index: provider %q cannot compute embeddings; use openai or ollama", provider)
+	}
+	return e, nil
+}
+
+// Model returns the embedding model.
+func (e *Embedder) Model() string {
+	return e.model
+}
+
+// embeddingsRequest is the embeddings API request body.
+type embeddingsRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// embeddingsResponse is the embeddings API response.
+type embeddingsResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Error *openAIError `json:"error,omitempty"`
+}
+
+// Embed returns the embedding of each text, in order.
+func (e *Embedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	jsonData, err := json.Marshal(embeddingsRequest{Model: e.model, Input: texts})
+	if err != nil {
+		return nil, fmt.Errorf("embeddings: marshal request: %w", err)
+	}
+
+	callCtx, cancel := e.timeouts.withTimeout(ctx, callAnalyze)
+	defer cancel()
+
+	resp, err := e.retry.do(ctx, e.client, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(callCtx, http.MethodPost, e.endpoint, bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, fmt.Errorf("create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if e.apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+e.apiKey)
+		}
+		return req, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("embeddings: send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respData, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("embeddings: read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embeddings: api error (status %d): %s", resp.StatusCode, string(respData))
+	}
+
+	var apiResp embeddingsResponse
+	if err := json.Unmarshal(respData, &apiResp); err != nil {
+		return nil, fmt.Errorf("embeddings: unmarshal response: %w", err)
+	}
+	if apiResp.Error != nil {
+		return nil, fmt.Errorf("embeddings: api error: %s: %s", apiResp.Error.Type, apiResp.Error.Message)
+	}
+	if len(apiResp.Data) != len(texts) {
+		return nil, fmt.Errorf("embeddings: got %d embeddings for %d inputs", len(apiResp.Data), len(texts))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, d := range apiResp.Data {
+		if d.Index < 0 || d.Index >= len(vectors) {
+			return nil, fmt.Errorf("embeddings: index %d out of range", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
@@ -0,0 +1,59 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rigdev/rig/internal/config"
+)
+
+func TestEmbedder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer sk-openai" {
+			t.Errorf("Authorization = %q", got)
+		}
+		var req embeddingsRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "text-embedding-3-small" || len(req.Input) != 2 {
+			t.Errorf("unexpected request %+v", req)
+		}
+		// Out of order, as the API allows.
+		w.Write([]byte(`{"data": [{"index": 1, "embedding": [0, 1]}, {"index": 0, "embedding": [1, 0]}]}`))
+	}))
+	defer server.Close()
+
+	e, err := NewEmbedder(config.AIConfig{Provider: "openai", APIKey: "sk-openai", Index: config.AIIndexConfig{Enabled: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.endpoint = server.URL
+	vectors, err := e.Embed(context.Background(), []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) != 2 || vectors[0][0] != 1 || vectors[1][1] != 1 {
+		t.Errorf("vectors = %v", vectors)
+	}
+}
+
+func TestNewEmbedderProviders(t *testing.T) {
+	t.Setenv("OLLAMA_API_ENDPOINT", "http://gpu:11434/v1/chat/completions")
+	e, err := NewEmbedder(config.AIConfig{Provider: "anthropic", APIKey: "sk-ant", Index: config.AIIndexConfig{Provider: "ollama"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.endpoint != "http://gpu:11434/v1/embeddings" || e.model != "nomic-embed-text" || e.apiKey != "" {
+		t.Errorf("ollama embedder = %+v; the anthropic key should not be sent", e)
+	}
+
+	if _, err := NewEmbedder(config.AIConfig{Provider: "anthropic", APIKey: "sk-ant"}); err == nil || !strings.Contains(err.Error(), "cannot compute embeddings") {
+		t.Errorf("expected an unsupported provider error, got %v", err)
+	}
+	if _, err := NewEmbedder(config.AIConfig{Provider: "anthropic", Index: config.AIIndexConfig{Provider: "openai"}}); err == nil || !strings.Contains(err.Error(), "api_key") {
+		t.Errorf("expected a missing api_key error, got %v", err)
+	}
+}
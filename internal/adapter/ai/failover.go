@@ -0,0 +1,182 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/rigdev/rig/internal/core"
+)
+
+// provider is one entry of a failover chain.
+type provider struct {
+	name    string
+	model   string
+	adapter core.AIAdapter
+}
+
+// failoverAdapter calls its providers in order, moving on to the next one
+// when a call fails after the provider's own retries. The provider that
+// answered is recorded on the task so each attempt shows where its changes
+// came from.
+type failoverAdapter struct {
+	providers []provider
+}
+
+var (
+	_ core.AIAdapter      = (*failoverAdapter)(nil)
+	_ core.TaskSummarizer = (*failoverAdapter)(nil)
+	_ core.ModelSwitcher  = (*failoverAdapter)(nil)
+)
+
+// providerName returns the name of a configured provider; empty selects
+// anthropic.
+func providerName(name string) string {
+	if name == "" {
+		return "anthropic"
+	}
+	return name
+}
+
+func (f *failoverAdapter) AnalyzeIssue(ctx context.Context, issue *core.AIIssue, projectContext string) (*core.AIPlan, error) {
+	return failover(ctx, f.providers, func(a core.AIAdapter) (*core.AIPlan, error) {
+		return a.AnalyzeIssue(ctx, issue, projectContext)
+	})
+}
+
+func (f *failoverAdapter) GenerateCode(ctx context.Context, plan *core.AIPlan, repoFiles map[string]string) ([]core.AIFileChange, error) {
+	return failover(ctx, f.providers, func(a core.AIAdapter) ([]core.AIFileChange, error) {
+		return a.GenerateCode(ctx, plan, repoFiles)
+	})
+}
+
+func (f *failoverAdapter) AnalyzeFailure(ctx context.Context, logs string, currentCode map[string]string) ([]core.AIFileChange, error) {
+	return failover(ctx, f.providers, func(a core.AIAdapter) ([]core.AIFileChange, error) {
+		return a.AnalyzeFailure(ctx, logs, currentCode)
+	})
+}
+
+func (f *failoverAdapter) AnalyzeDeployFailure(ctx context.Context, deployLogs string, infraFiles map[string]string) (*core.AIProposedFix, error) {
+	return failover(ctx, f.providers, func(a core.AIAdapter) (*core.AIProposedFix, error) {
+		return a.AnalyzeDeployFailure(ctx, deployLogs, infraFiles)
+	})
+}
+
+// SummarizeTask fails over between the providers that can summarize.
+func (f *failoverAdapter) SummarizeTask(ctx context.Context, report string) (string, error) {
+	var summarizers []provider
+	for _, p := range f.providers {
+		if _, ok := p.adapter.(core.TaskSummarizer); ok {
+			summarizers = append(summarizers, p)
+		}
+	}
+	if len(summarizers) == 0 {
+		return "", core.ErrSummaryUnsupported
+	}
+	return failover(ctx, summarizers, func(a core.AIAdapter) (string, error) {
+		return a.(core.TaskSummarizer).SummarizeTask(ctx, report)
+	})
+}
+
+// ExplainFailure fails over between the providers that can explain failures.
+func (f *failoverAdapter) ExplainFailure(ctx context.Context, report string) (string, error) {
+	var explainers []provider
+	for _, p := range f.providers {
+		if _, ok := p.adapter.(core.FailureExplainer); ok {
+			explainers = append(explainers, p)
+		}
+	}
+	if len(explainers) == 0 {
+		return "", core.ErrExplainUnsupported
+	}
+	return failover(ctx, explainers, func(a core.AIAdapter) (string, error) {
+		return a.(core.FailureExplainer).ExplainFailure(ctx, report)
+	})
+}
+
+// DecomposePlan fails over between the providers that can split plans.
+func (f *failoverAdapter) DecomposePlan(ctx context.Context, plan *core.AIPlan, maxSubtasks int) ([]core.AISubtask, error) {
+	var decomposers []provider
+	for _, p := range f.providers {
+		if _, ok := p.adapter.(core.PlanDecomposer); ok {
+			decomposers = append(decomposers, p)
+		}
+	}
+	if len(decomposers) == 0 {
+		return nil, core.ErrDecomposeUnsupported
+	}
+	return failover(ctx, decomposers, func(a core.AIAdapter) ([]core.AISubtask, error) {
+		return a.(core.PlanDecomposer).DecomposePlan(ctx, plan, maxSubtasks)
+	})
+}
+
+// SplitEpic fails over between the providers that can split epics.
+func (f *failoverAdapter) SplitEpic(ctx context.Context, issue *core.AIIssue, items []string, maxParts int) ([]core.AISubtask, error) {
+	var splitters []provider
+	for _, p := range f.providers {
+		if _, ok := p.adapter.(core.EpicSplitter); ok {
+			splitters = append(splitters, p)
+		}
+	}
+	if len(splitters) == 0 {
+		return nil, core.ErrSplitEpicUnsupported
+	}
+	return failover(ctx, splitters, func(a core.AIAdapter) ([]core.AISubtask, error) {
+		return a.(core.EpicSplitter).SplitEpic(ctx, issue, items, maxParts)
+	})
+}
+
+// ClassifyFailure fails over between the providers that can classify
+// failures.
+func (f *failoverAdapter) ClassifyFailure(ctx context.Context, logs string) (core.FailureClass, error) {
+	var classifiers []provider
+	for _, p := range f.providers {
+		if _, ok := p.adapter.(core.FailureClassifier); ok {
+			classifiers = append(classifiers, p)
+		}
+	}
+	if len(classifiers) == 0 {
+		return core.FailureUnknown, core.ErrClassifyUnsupported
+	}
+	return failover(ctx, classifiers, func(a core.AIAdapter) (core.FailureClass, error) {
+		return a.(core.FailureClassifier).ClassifyFailure(ctx, logs)
+	})
+}
+
+// WithModel switches the primary provider to model; the fallbacks keep
+// their own models.
+func (f *failoverAdapter) WithModel(model string) core.AIAdapter {
+	switcher, ok := f.providers[0].adapter.(core.ModelSwitcher)
+	if !ok {
+		return f
+	}
+	c := &failoverAdapter{providers: append([]provider(nil), f.providers...)}
+	c.providers[0].adapter = switcher.WithModel(model)
+	c.providers[0].model = model
+	return c
+}
+
+// failover runs call against each provider until one succeeds. It stops
+// early once ctx is done, since every later provider would fail the same
+// way.
+func failover[T any](ctx context.Context, providers []provider, call func(core.AIAdapter) (T, error)) (T, error) {
+	var errs []error
+	for i, p := range providers {
+		v, err := call(p.adapter)
+		if err == nil {
+			core.RecordAIProvider(ctx, p.name, p.model)
+			return v, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", p.name, err))
+		if ctx.Err() != nil || i == len(providers)-1 {
+			break
+		}
+		slog.Warn("AI provider failed; falling back", "provider", p.name, "next", providers[i+1].name, "err", err)
+	}
+	var zero T
+	if len(errs) == 1 {
+		return zero, errs[0]
+	}
+	return zero, fmt.Errorf("all AI providers failed: %w", errors.Join(errs...))
+}
@@ -0,0 +1,103 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+)
+
+// stubAI answers AnalyzeIssue with plan or err and counts the calls.
+type stubAI struct {
+	core.AIAdapter
+	plan  string
+	err   error
+	calls int
+}
+
+func (s *stubAI) AnalyzeIssue(ctx context.Context, issue *core.AIIssue, projectContext string) (*core.AIPlan, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &core.AIPlan{Summary: s.plan}, nil
+}
+
+func TestFailoverOrder(t *testing.T) {
+	primary := &stubAI{err: errors.New("rate limited (429)")}
+	second := &stubAI{err: errors.New("api error (status 500)")}
+	third := &stubAI{plan: "from ollama"}
+	f := &failoverAdapter{providers: []provider{
+		{name: "anthropic", adapter: primary},
+		{name: "openai", adapter: second},
+		{name: "ollama", adapter: third},
+	}}
+
+	plan, err := f.AnalyzeIssue(context.Background(), &core.AIIssue{}, "")
+	if err != nil || plan.Summary != "from ollama" {
+		t.Fatalf("AnalyzeIssue = %+v, %v", plan, err)
+	}
+	if primary.calls != 1 || second.calls != 1 || third.calls != 1 {
+		t.Errorf("calls = %d, %d, %d", primary.calls, second.calls, third.calls)
+	}
+
+	third.err = errors.New("connection refused")
+	_, err = f.AnalyzeIssue(context.Background(), &core.AIIssue{}, "")
+	if err == nil || !strings.Contains(err.Error(), "all AI providers failed") ||
+		!strings.Contains(err.Error(), "openai: api error (status 500)") {
+		t.Errorf("expected every provider's error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	primary.calls, second.calls = 0, 0
+	if _, err := f.AnalyzeIssue(ctx, &core.AIIssue{}, ""); err == nil || second.calls != 0 {
+		t.Errorf("a cancelled call should not fall back (err %v, %d fallback calls)", err, second.calls)
+	}
+}
+
+func TestNewWithFallback(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer primary.Close()
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "{\"summary\": \"fallback plan\", \"steps\": [\"a\"]}"}}]}`))
+	}))
+	defer fallback.Close()
+
+	adapter, err := New(config.AIConfig{
+		Provider: "anthropic",
+		Model:    "claude-sonnet-4-20250514",
+		APIKey:   "sk-ant",
+		Retry:    config.AIRetryConfig{MaxAttempts: 1},
+		Fallback: []config.AIFallbackConfig{{Provider: "openai", Model: "gpt-4o", APIKey: "sk-openai"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := adapter.(*failoverAdapter)
+	f.providers[0].adapter.(*AnthropicAdapter).endpoint = primary.URL
+	f.providers[1].adapter.(*OpenAIAdapter).endpoint = fallback.URL
+
+	plan, err := adapter.AnalyzeIssue(context.Background(), &core.AIIssue{Title: "t"}, "")
+	if err != nil || plan.Summary != "fallback plan" {
+		t.Fatalf("AnalyzeIssue = %+v, %v", plan, err)
+	}
+
+	switched := adapter.(core.ModelSwitcher).WithModel("claude-haiku").(*failoverAdapter)
+	if switched.providers[0].model != "claude-haiku" || switched.providers[1].model != "gpt-4o" || f.providers[0].model == "claude-haiku" {
+		t.Errorf("WithModel should switch only the primary of a copy: %+v", switched.providers)
+	}
+
+	if _, err := New(config.AIConfig{Provider: "ollama", Model: "llama3", Fallback: []config.AIFallbackConfig{{Provider: "openai", Model: "gpt-4o"}}}); err == nil ||
+		!strings.Contains(err.Error(), "ai.fallback[0]") {
+		t.Errorf("expected the fallback's constructor error, got %v", err)
+	}
+}
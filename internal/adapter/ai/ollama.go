@@ -20,6 +20,8 @@ import (
 const (
 	defaultOllamaURL     = "http://localhost:11434/v1/chat/completions"
 	defaultOllamaTimeout = 120 * time.Second
+	// Local models generate slowly, so code generation gets more room.
+	defaultOllamaGenerateTimeout = 10 * time.Minute
 )
 
 // OllamaAdapter implements AIAdapter using Ollama's OpenAI-compatible chat API.
@@ -28,9 +30,16 @@ type OllamaAdapter struct {
 	model    string
 	endpoint string
 	client   *http.Client
+	language string
+	timeouts callTimeouts
+	retry    retryPolicy
 }
 
-var _ core.AIAdapter = (*OllamaAdapter)(nil)
+var (
+	_ core.AIAdapter      = (*OllamaAdapter)(nil)
+	_ core.TaskSummarizer = (*OllamaAdapter)(nil)
+	_ core.ModelSwitcher  = (*OllamaAdapter)(nil)
+)
 
 // NewOllama creates a new OllamaAdapter from the AI config.
 func NewOllama(cfg config.AIConfig) (*OllamaAdapter, error) {
@@ -47,33 +56,43 @@ func NewOllama(cfg config.AIConfig) (*OllamaAdapter, error) {
 		apiKey:   cfg.APIKey,
 		model:    cfg.Model,
 		endpoint: endpoint,
-		client:   &http.Client{Timeout: defaultOllamaTimeout},
+		client:   &http.Client{},
+		language: cfg.Language,
+		timeouts: newCallTimeouts(cfg.Timeouts, defaultOllamaTimeout, defaultOllamaGenerateTimeout),
+		retry:    newRetryPolicy(cfg.Retry),
 	}, nil
 }
 
+// WithModel returns a copy of the adapter that uses model.
+func (a *OllamaAdapter) WithModel(model string) core.AIAdapter {
+	c := *a
+	c.model = model
+	return &c
+}
+
 // AnalyzeIssue sends the issue to Ollama and parses a Plan from the response.
 func (a *OllamaAdapter) AnalyzeIssue(ctx context.Context, issue *core.AIIssue, projectContext string) (*core.AIPlan, error) {
 	if issue == nil {
 		return nil, fmt.Errorf("ollama: issue is nil")
 	}
 
-	systemPrompt := buildSystemPrompt(projectContext)
+	systemPrompt := withAgents(ctx, buildSystemPrompt(projectContext))
 	userPrompt := fmt.Sprintf(
 		`Analyze the following issue and create an implementation plan.
 
 Issue Title: %s
 Issue Body:
 %s
-
+%s
 Respond in the following JSON format ONLY (no markdown fences, no extra text):
 {
   "summary": "Brief summary of what needs to be done",
   "steps": ["Step 1 description", "Step 2 description"]
 }`,
-		issue.Title, issue.Body,
+		issue.Title, issue.Body, formatIssueComments(issue.Comments),
 	)
 
-	body, err := a.sendMessage(ctx, systemPrompt, userPrompt)
+	body, err := a.sendMessage(ctx, callAnalyze, systemPrompt, userPrompt)
 	if err != nil {
 		return nil, fmt.Errorf("ollama: analyze issue: %w", err)
 	}
@@ -87,7 +106,7 @@ func (a *OllamaAdapter) GenerateCode(ctx context.Context, plan *core.AIPlan, rep
 		return nil, fmt.Errorf("ollama: plan is nil")
 	}
 
-	systemPrompt := "You are a code generation assistant. Generate file changes to implement the given plan. Output valid JSON only."
+	systemPrompt := withAgents(ctx, "You are a code generation assistant. Generate file changes to implement the given plan. Output valid JSON only.")
 
 	var filesSection strings.Builder
 	for path, content := range repoFiles {
@@ -113,7 +132,7 @@ Respond in the following JSON format ONLY (no markdown fences, no extra text):
 		filesSection.String(),
 	)
 
-	body, err := a.sendMessage(ctx, systemPrompt, userPrompt)
+	body, err := a.sendMessage(ctx, callGenerate, systemPrompt, userPrompt)
 	if err != nil {
 		return nil, fmt.Errorf("ollama: generate code: %w", err)
 	}
@@ -147,7 +166,7 @@ Respond in the following JSON format ONLY (no markdown fences, no extra text):
 		codeSection.String(),
 	)
 
-	body, err := a.sendMessage(ctx, systemPrompt, userPrompt)
+	body, err := a.sendMessage(ctx, callGenerate, systemPrompt, userPrompt)
 	if err != nil {
 		return nil, fmt.Errorf("ollama: analyze failure: %w", err)
 	}
@@ -185,7 +204,7 @@ Respond in the following JSON format ONLY (no markdown fences, no extra text):
 		infraSection.String(),
 	)
 
-	body, err := a.sendMessage(ctx, systemPrompt, userPrompt)
+	body, err := a.sendMessage(ctx, callGenerate, systemPrompt, userPrompt)
 	if err != nil {
 		return nil, fmt.Errorf("ollama: analyze deploy failure: %w", err)
 	}
@@ -193,6 +212,52 @@ Respond in the following JSON format ONLY (no markdown fences, no extra text):
 	return parseProposedFix(body)
 }
 
+// SummarizeTask asks Ollama for a short human-readable status summary of a task.
+func (a *OllamaAdapter) SummarizeTask(ctx context.Context, report string) (string, error) {
+	body, err := a.sendMessage(ctx, callAnalyze, summarySystemPrompt, buildSummaryPrompt(report))
+	if err != nil {
+		return "", fmt.Errorf("ollama: summarize task: %w", err)
+	}
+	return strings.TrimSpace(body), nil
+}
+
+// ExplainFailure asks Ollama for a human-readable diagnosis of a failure report.
+func (a *OllamaAdapter) ExplainFailure(ctx context.Context, report string) (string, error) {
+	body, err := a.sendMessage(ctx, callAnalyze, explainSystemPrompt, buildExplainPrompt(report))
+	if err != nil {
+		return "", fmt.Errorf("ollama: explain failure: %w", err)
+	}
+	return strings.TrimSpace(body), nil
+}
+
+// DecomposePlan asks Ollama to split plan into sub-tasks for workflow.decompose.
+func (a *OllamaAdapter) DecomposePlan(ctx context.Context, plan *core.AIPlan, maxSubtasks int) ([]core.AISubtask, error) {
+	body, err := a.sendMessage(ctx, callAnalyze, decomposeSystemPrompt, buildDecomposePrompt(plan, maxSubtasks))
+	if err != nil {
+		return nil, fmt.Errorf("ollama: decompose plan: %w", err)
+	}
+	return parseSubtasks(body)
+}
+
+// SplitEpic asks Ollama to group the checklist of an epic issue into parts
+// for workflow.epic.
+func (a *OllamaAdapter) SplitEpic(ctx context.Context, issue *core.AIIssue, items []string, maxParts int) ([]core.AISubtask, error) {
+	body, err := a.sendMessage(ctx, callAnalyze, epicSystemPrompt, buildEpicPrompt(issue, items, maxParts))
+	if err != nil {
+		return nil, fmt.Errorf("ollama: split epic: %w", err)
+	}
+	return parseSubtasks(body)
+}
+
+// ClassifyFailure asks Ollama for the class of failure output for workflow.triage.
+func (a *OllamaAdapter) ClassifyFailure(ctx context.Context, logs string) (core.FailureClass, error) {
+	body, err := a.sendMessage(ctx, callAnalyze, classifySystemPrompt, buildClassifyPrompt(logs))
+	if err != nil {
+		return core.FailureUnknown, fmt.Errorf("ollama: classify failure: %w", err)
+	}
+	return parseFailureClass(body)
+}
+
 // ollamaRequest is the OpenAI-compatible chat completions request body.
 type ollamaRequest struct {
 	Model    string          `json:"model"`
@@ -215,9 +280,16 @@ type ollamaOptions struct {
 // ollamaResponse is the OpenAI-compatible response from Ollama.
 type ollamaResponse struct {
 	Choices []ollamaChoice `json:"choices"`
+	Usage   ollamaUsage    `json:"usage"`
 	Error   *ollamaError   `json:"error,omitempty"`
 }
 
+// ollamaUsage is the token usage reported with a completion.
+type ollamaUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
 // ollamaChoice is one generated completion choice.
 type ollamaChoice struct {
 	Message ollamaMessage `json:"message"`
@@ -230,11 +302,11 @@ type ollamaError struct {
 }
 
 // sendMessage posts a prompt to Ollama and returns the first message content.
-func (a *OllamaAdapter) sendMessage(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+func (a *OllamaAdapter) sendMessage(ctx context.Context, kind callKind, systemPrompt, userPrompt string) (string, error) {
 	reqBody := ollamaRequest{
 		Model: a.model,
 		Messages: []ollamaMessage{
-			{Role: "system", Content: systemPrompt},
+			{Role: "system", Content: withLanguage(systemPrompt, a.language)},
 			{Role: "user", Content: userPrompt},
 		},
 		Stream:  false,
@@ -246,18 +318,22 @@ func (a *OllamaAdapter) sendMessage(ctx context.Context, systemPrompt, userPromp
 		return "", fmt.Errorf("marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint, bytes.NewReader(jsonData))
-	if err != nil {
-		return "", fmt.Errorf("create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	if a.apiKey != "" {
-		req.Header.Set("Authorization", "Bearer "+a.apiKey)
-	}
+	callCtx, cancel := a.timeouts.withTimeout(ctx, kind)
+	defer cancel()
 
-	resp, err := a.client.Do(req)
+	resp, err := a.retry.do(ctx, a.client, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(callCtx, http.MethodPost, a.endpoint, bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, fmt.Errorf("create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if a.apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+a.apiKey)
+		}
+		return req, nil
+	})
 	if err != nil {
+		recordUsage(ctx, callCtx, 0, 0)
 		if isConnectionRefused(err) {
 			return "", fmt.Errorf("cannot connect to ollama at %s (is Ollama running?): %w", a.endpoint, err)
 		}
@@ -267,6 +343,7 @@ func (a *OllamaAdapter) sendMessage(ctx context.Context, systemPrompt, userPromp
 
 	respData, err := io.ReadAll(resp.Body)
 	if err != nil {
+		recordUsage(ctx, callCtx, 0, 0)
 		return "", fmt.Errorf("read response: %w", err)
 	}
 
@@ -278,6 +355,7 @@ func (a *OllamaAdapter) sendMessage(ctx context.Context, systemPrompt, userPromp
 	if err := json.Unmarshal(respData, &apiResp); err != nil {
 		return "", fmt.Errorf("unmarshal response: %w", err)
 	}
+	recordUsage(ctx, callCtx, apiResp.Usage.PromptTokens, apiResp.Usage.CompletionTokens)
 
 	if apiResp.Error != nil {
 		if apiResp.Error.Type != "" {
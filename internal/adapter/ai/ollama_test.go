@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/rigdev/rig/internal/config"
 	"github.com/rigdev/rig/internal/core"
@@ -63,6 +64,7 @@ func newTestOllamaAdapter(t *testing.T, serverURL string) *OllamaAdapter {
 		Provider: "ollama",
 		Model:    "llama3.1:8b",
 		APIKey:   "test-key",
+		Retry:    config.AIRetryConfig{InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
 	})
 	if err != nil {
 		t.Fatalf("NewOllama failed: %v", err)
@@ -24,9 +24,16 @@ type OpenAIAdapter struct {
 	model    string
 	endpoint string
 	client   *http.Client
+	language string
+	timeouts callTimeouts
+	retry    retryPolicy
 }
 
-var _ core.AIAdapter = (*OpenAIAdapter)(nil)
+var (
+	_ core.AIAdapter      = (*OpenAIAdapter)(nil)
+	_ core.TaskSummarizer = (*OpenAIAdapter)(nil)
+	_ core.ModelSwitcher  = (*OpenAIAdapter)(nil)
+)
 
 // NewOpenAI creates a new OpenAIAdapter from the AI config.
 func NewOpenAI(cfg config.AIConfig) (*OpenAIAdapter, error) {
@@ -43,33 +50,43 @@ func NewOpenAI(cfg config.AIConfig) (*OpenAIAdapter, error) {
 		apiKey:   cfg.APIKey,
 		model:    model,
 		endpoint: defaultOpenAIURL,
-		client:   &http.Client{Timeout: defaultHTTPTimeout},
+		client:   &http.Client{},
+		language: cfg.Language,
+		timeouts: newCallTimeouts(cfg.Timeouts, defaultHTTPTimeout, defaultGenerateTimeout),
+		retry:    newRetryPolicy(cfg.Retry),
 	}, nil
 }
 
+// WithModel returns a copy of the adapter that uses model.
+func (a *OpenAIAdapter) WithModel(model string) core.AIAdapter {
+	c := *a
+	c.model = model
+	return &c
+}
+
 // AnalyzeIssue sends the issue to OpenAI and parses a Plan from the response.
 func (a *OpenAIAdapter) AnalyzeIssue(ctx context.Context, issue *core.AIIssue, projectContext string) (*core.AIPlan, error) {
 	if issue == nil {
 		return nil, fmt.Errorf("openai: issue is nil")
 	}
 
-	systemPrompt := buildSystemPrompt(projectContext)
+	systemPrompt := withAgents(ctx, buildSystemPrompt(projectContext))
 	userPrompt := fmt.Sprintf(
 		`Analyze the following issue and create an implementation plan.
 
 Issue Title: %s
 Issue Body:
 %s
-
+%s
 Respond in the following JSON format ONLY (no markdown fences, no extra text):
 {
   "summary": "Brief summary of what needs to be done",
   "steps": ["Step 1 description", "Step 2 description"]
 }`,
-		issue.Title, issue.Body,
+		issue.Title, issue.Body, formatIssueComments(issue.Comments),
 	)
 
-	body, err := a.sendMessage(ctx, systemPrompt, userPrompt)
+	body, err := a.sendMessage(ctx, callAnalyze, systemPrompt, userPrompt, planTool)
 	if err != nil {
 		return nil, fmt.Errorf("openai: analyze issue: %w", err)
 	}
@@ -83,7 +100,7 @@ func (a *OpenAIAdapter) GenerateCode(ctx context.Context, plan *core.AIPlan, rep
 		return nil, fmt.Errorf("openai: plan is nil")
 	}
 
-	systemPrompt := "You are a code generation assistant. Generate file changes to implement the given plan. Output valid JSON only."
+	systemPrompt := withAgents(ctx, "You are a code generation assistant. Generate file changes to implement the given plan. Output valid JSON only.")
 
 	var filesSection strings.Builder
 	for path, content := range repoFiles {
@@ -109,7 +126,7 @@ Respond in the following JSON format ONLY (no markdown fences, no extra text):
 		filesSection.String(),
 	)
 
-	body, err := a.sendMessage(ctx, systemPrompt, userPrompt)
+	body, err := a.sendMessage(ctx, callGenerate, systemPrompt, userPrompt, fileChangesTool)
 	if err != nil {
 		return nil, fmt.Errorf("openai: generate code: %w", err)
 	}
@@ -143,7 +160,7 @@ Respond in the following JSON format ONLY (no markdown fences, no extra text):
 		codeSection.String(),
 	)
 
-	body, err := a.sendMessage(ctx, systemPrompt, userPrompt)
+	body, err := a.sendMessage(ctx, callGenerate, systemPrompt, userPrompt, fileChangesTool)
 	if err != nil {
 		return nil, fmt.Errorf("openai: analyze failure: %w", err)
 	}
@@ -181,7 +198,7 @@ Respond in the following JSON format ONLY (no markdown fences, no extra text):
 		infraSection.String(),
 	)
 
-	body, err := a.sendMessage(ctx, systemPrompt, userPrompt)
+	body, err := a.sendMessage(ctx, callGenerate, systemPrompt, userPrompt, proposedFixTool)
 	if err != nil {
 		return nil, fmt.Errorf("openai: analyze deploy failure: %w", err)
 	}
@@ -189,26 +206,113 @@ Respond in the following JSON format ONLY (no markdown fences, no extra text):
 	return parseProposedFix(body)
 }
 
+// SummarizeTask asks OpenAI for a short human-readable status summary of a task.
+func (a *OpenAIAdapter) SummarizeTask(ctx context.Context, report string) (string, error) {
+	body, err := a.sendMessage(ctx, callAnalyze, summarySystemPrompt, buildSummaryPrompt(report), nil)
+	if err != nil {
+		return "", fmt.Errorf("openai: summarize task: %w", err)
+	}
+	return strings.TrimSpace(body), nil
+}
+
+// ExplainFailure asks OpenAI for a human-readable diagnosis of a failure report.
+func (a *OpenAIAdapter) ExplainFailure(ctx context.Context, report string) (string, error) {
+	body, err := a.sendMessage(ctx, callAnalyze, explainSystemPrompt, buildExplainPrompt(report), nil)
+	if err != nil {
+		return "", fmt.Errorf("openai: explain failure: %w", err)
+	}
+	return strings.TrimSpace(body), nil
+}
+
+// DecomposePlan asks OpenAI to split plan into sub-tasks for workflow.decompose.
+func (a *OpenAIAdapter) DecomposePlan(ctx context.Context, plan *core.AIPlan, maxSubtasks int) ([]core.AISubtask, error) {
+	body, err := a.sendMessage(ctx, callAnalyze, decomposeSystemPrompt, buildDecomposePrompt(plan, maxSubtasks), subtasksTool)
+	if err != nil {
+		return nil, fmt.Errorf("openai: decompose plan: %w", err)
+	}
+	return parseSubtasks(body)
+}
+
+// SplitEpic asks OpenAI to group the checklist of an epic issue into parts
+// for workflow.epic.
+func (a *OpenAIAdapter) SplitEpic(ctx context.Context, issue *core.AIIssue, items []string, maxParts int) ([]core.AISubtask, error) {
+	body, err := a.sendMessage(ctx, callAnalyze, epicSystemPrompt, buildEpicPrompt(issue, items, maxParts), subtasksTool)
+	if err != nil {
+		return nil, fmt.Errorf("openai: split epic: %w", err)
+	}
+	return parseSubtasks(body)
+}
+
+// ClassifyFailure asks OpenAI for the class of failure output for workflow.triage.
+func (a *OpenAIAdapter) ClassifyFailure(ctx context.Context, logs string) (core.FailureClass, error) {
+	body, err := a.sendMessage(ctx, callAnalyze, classifySystemPrompt, buildClassifyPrompt(logs), failureClassTool)
+	if err != nil {
+		return core.FailureUnknown, fmt.Errorf("openai: classify failure: %w", err)
+	}
+	return parseFailureClass(body)
+}
+
 // openAIRequest is the OpenAI Chat Completions API request body.
 type openAIRequest struct {
 	Model       string          `json:"model"`
 	Messages    []openAIMessage `json:"messages"`
 	MaxTokens   int             `json:"max_tokens"`
 	Temperature int             `json:"temperature"`
+
+	Tools      []openAITool      `json:"tools,omitempty"`
+	ToolChoice *openAIToolChoice `json:"tool_choice,omitempty"`
 }
 
 // openAIMessage is a single message in the OpenAI conversation.
 type openAIMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []openAIToolCall `json:"tool_calls,omitempty"`
+}
+
+// openAITool is a function the model may call.
+type openAITool struct {
+	Type     string         `json:"type"`
+	Function openAIFunction `json:"function"`
+}
+
+// openAIFunction is a function definition, or the name of the function
+// tool_choice forces.
+type openAIFunction struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters,omitempty"`
+}
+
+// openAIToolChoice forces the model to call the named function.
+type openAIToolChoice struct {
+	Type     string         `json:"type"`
+	Function openAIFunction `json:"function"`
+}
+
+// openAIToolCall is a function call in a response message. Its arguments
+// are a JSON object encoded as a string.
+type openAIToolCall struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
 }
 
 // openAIResponse is the OpenAI Chat Completions API response.
 type openAIResponse struct {
 	Choices []openAIChoice `json:"choices"`
+	Usage   openAIUsage    `json:"usage"`
 	Error   *openAIError   `json:"error,omitempty"`
 }
 
+// openAIUsage is the token usage reported with a completion.
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
 // openAIChoice is a response choice.
 type openAIChoice struct {
 	Message openAIMessage `json:"message"`
@@ -220,39 +324,50 @@ type openAIError struct {
 	Message string `json:"message"`
 }
 
-// sendMessage posts prompts to OpenAI Chat Completions API and returns the assistant text.
-func (a *OpenAIAdapter) sendMessage(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+// sendMessage posts prompts to OpenAI Chat Completions API and returns the
+// assistant text, or the function arguments when tool is set and the model
+// called it.
+func (a *OpenAIAdapter) sendMessage(ctx context.Context, kind callKind, systemPrompt, userPrompt string, tool *outputTool) (string, error) {
 	reqBody := openAIRequest{
 		Model: a.model,
 		Messages: []openAIMessage{
-			{Role: "system", Content: systemPrompt},
+			{Role: "system", Content: withLanguage(systemPrompt, a.language)},
 			{Role: "user", Content: userPrompt},
 		},
 		MaxTokens:   defaultMaxTokens,
 		Temperature: 0,
 	}
+	if tool != nil {
+		reqBody.Tools = []openAITool{{Type: "function", Function: openAIFunction{Name: tool.name, Description: tool.description, Parameters: tool.schema}}}
+		reqBody.ToolChoice = &openAIToolChoice{Type: "function", Function: openAIFunction{Name: tool.name}}
+	}
 
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
 		return "", fmt.Errorf("marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint, bytes.NewReader(jsonData))
-	if err != nil {
-		return "", fmt.Errorf("create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+a.apiKey)
-
-	resp, err := a.client.Do(req)
+	callCtx, cancel := a.timeouts.withTimeout(ctx, kind)
+	defer cancel()
+
+	resp, err := a.retry.do(ctx, a.client, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(callCtx, http.MethodPost, a.endpoint, bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, fmt.Errorf("create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+a.apiKey)
+		return req, nil
+	})
 	if err != nil {
+		recordUsage(ctx, callCtx, 0, 0)
 		return "", fmt.Errorf("send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	respData, err := io.ReadAll(resp.Body)
 	if err != nil {
+		recordUsage(ctx, callCtx, 0, 0)
 		return "", fmt.Errorf("read response: %w", err)
 	}
 
@@ -268,6 +383,7 @@ func (a *OpenAIAdapter) sendMessage(ctx context.Context, systemPrompt, userPromp
 	if err := json.Unmarshal(respData, &apiResp); err != nil {
 		return "", fmt.Errorf("unmarshal response: %w", err)
 	}
+	recordUsage(ctx, callCtx, apiResp.Usage.PromptTokens, apiResp.Usage.CompletionTokens)
 
 	if apiResp.Error != nil {
 		return "", fmt.Errorf("api error: %s: %s", apiResp.Error.Type, apiResp.Error.Message)
@@ -277,6 +393,12 @@ func (a *OpenAIAdapter) sendMessage(ctx context.Context, systemPrompt, userPromp
 		return "", fmt.Errorf("empty response: no choices")
 	}
 
+	for _, call := range apiResp.Choices[0].Message.ToolCalls {
+		if args := strings.TrimSpace(call.Function.Arguments); args != "" {
+			return args, nil
+		}
+	}
+
 	content := strings.TrimSpace(apiResp.Choices[0].Message.Content)
 	if content == "" {
 		return "", fmt.Errorf("empty response: empty message content")
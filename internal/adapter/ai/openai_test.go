@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/rigdev/rig/internal/config"
 	"github.com/rigdev/rig/internal/core"
@@ -63,6 +64,7 @@ func newTestOpenAIAdapter(t *testing.T, serverURL string) *OpenAIAdapter {
 		Provider: "openai",
 		APIKey:   "test-key",
 		Model:    "gpt-4o-mini",
+		Retry:    config.AIRetryConfig{InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
 	})
 	if err != nil {
 		t.Fatalf("NewOpenAI failed: %v", err)
@@ -321,3 +323,28 @@ func TestOpenAIFileChangeMissingActionDefaultsToCreate(t *testing.T) {
 		t.Errorf("expected default action 'create', got: %q", changes[0].Action)
 	}
 }
+
+func TestOpenAIFunctionCalling(t *testing.T) {
+	fixArgs := `{"summary": "Wrong port", "reason": "app listens on 8080", "changes": [{"path": "deploy.yml", "action": "modify", "reason": "port", "content": "port: 8080"}]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openAIRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if len(req.Tools) != 1 || req.Tools[0].Function.Name != "submit_deploy_fix" || req.Tools[0].Function.Parameters == nil {
+			t.Errorf("expected the deploy fix function, got %+v", req.Tools)
+		}
+		if req.ToolChoice == nil || req.ToolChoice.Function.Name != "submit_deploy_fix" {
+			t.Errorf("expected the function to be forced, got %+v", req.ToolChoice)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": null, "tool_calls": [{"type": "function", "function": {"name": "submit_deploy_fix", "arguments": ` + jsonEscape(fixArgs) + `}}]}}]}`))
+	}))
+	defer server.Close()
+
+	fix, err := newTestOpenAIAdapter(t, server.URL).AnalyzeDeployFailure(context.Background(), "connection refused", nil)
+	if err != nil {
+		t.Fatalf("AnalyzeDeployFailure failed: %v", err)
+	}
+	if fix.Summary != "Wrong port" || len(fix.Changes) != 1 || fix.Changes[0].Content != "port: 8080" {
+		t.Errorf("unexpected fix %+v", fix)
+	}
+}
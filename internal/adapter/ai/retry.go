@@ -0,0 +1,124 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+)
+
+const (
+	defaultRetryAttempts   = 4
+	defaultInitialBackoff  = time.Second
+	defaultMaxRetryBackoff = 30 * time.Second
+)
+
+// retryPolicy resends AI requests that failed with 429, a 5xx status or a
+// network error. The call timeout bounds the retries too: a wait that would
+// run past it is not started.
+type retryPolicy struct {
+	maxAttempts int
+	initial     time.Duration
+	max         time.Duration
+}
+
+// newRetryPolicy applies ai.retry over the defaults.
+func newRetryPolicy(cfg config.AIRetryConfig) retryPolicy {
+	p := retryPolicy{maxAttempts: defaultRetryAttempts, initial: defaultInitialBackoff, max: defaultMaxRetryBackoff}
+	if cfg.MaxAttempts > 0 {
+		p.maxAttempts = cfg.MaxAttempts
+	}
+	if cfg.InitialBackoff > 0 {
+		p.initial = cfg.InitialBackoff
+	}
+	if cfg.MaxBackoff > 0 {
+		p.max = cfg.MaxBackoff
+	}
+	return p
+}
+
+// do sends the request newReq builds, building a fresh one for every
+// attempt, and returns the first response that is not retried. Each retry
+// is logged and counted in the task's AI usage.
+func (p retryPolicy) do(ctx context.Context, client *http.Client, newReq func() (*http.Request, error)) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		req, err := newReq()
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if attempt >= p.maxAttempts || !retryable(req.Context(), resp, err) {
+			return resp, err
+		}
+		wait := p.backoff(attempt)
+		if resp != nil {
+			if d, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				wait = d
+			}
+		}
+		if deadline, ok := req.Context().Deadline(); ok && time.Until(deadline) < wait {
+			return resp, err
+		}
+
+		reason := "network error"
+		if resp != nil {
+			reason = resp.Status
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		slog.Warn("retrying AI request", "reason", reason, "attempt", attempt+1, "max_attempts", p.maxAttempts, "wait", wait)
+		core.RecordAIRetry(ctx)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns the wait before retry attempt+1: exponential from the
+// initial backoff, capped at the maximum, with the upper half jittered so
+// concurrent tasks spread out.
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	d := p.initial
+	for i := 1; i < attempt && d < p.max; i++ {
+		d *= 2
+	}
+	d = min(d, p.max)
+	return d/2 + rand.N(d/2+1)
+}
+
+// retryable reports whether a request that ended with resp or err may
+// succeed if sent again.
+func retryable(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		// Cancellation and call timeouts are final; anything else from the
+		// transport is a network error.
+		return ctx.Err() == nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// retryAfter parses a Retry-After header, in seconds or as an HTTP date.
+func retryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
@@ -0,0 +1,107 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rigdev/rig/internal/core"
+)
+
+func TestRetryOnRateLimitAndServerErrors(t *testing.T) {
+	planJSON := `{"summary": "plan", "steps": ["one"]}`
+	tests := []struct {
+		name     string
+		statuses []int // responses before the successful one
+		wantHits int32
+		wantErr  bool
+	}{
+		{name: "rate limited once", statuses: []int{http.StatusTooManyRequests}, wantHits: 2},
+		{name: "overloaded twice", statuses: []int{529, http.StatusServiceUnavailable}, wantHits: 3},
+		{name: "gives up after max attempts", statuses: []int{500, 500, 500, 500, 500}, wantHits: 4, wantErr: true},
+		{name: "client error is final", statuses: []int{http.StatusBadRequest}, wantHits: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(hits.Add(1))
+				if n <= len(tt.statuses) {
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(tt.statuses[n-1])
+					w.Write([]byte(`{"error": {"type": "error", "message": "try again"}}`))
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"content": [{"type": "text", "text": "` + strings.ReplaceAll(planJSON, `"`, `\"`) + `"}]}`))
+			}))
+			defer server.Close()
+
+			task := &core.Task{}
+			ctx := core.WithTaskUsage(context.Background(), task)
+			_, err := newTestAdapter(t, server.URL).AnalyzeIssue(ctx, &core.AIIssue{Title: "t"}, "")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if hits.Load() != tt.wantHits {
+				t.Errorf("server hit %d times, want %d", hits.Load(), tt.wantHits)
+			}
+			var retries int32
+			if task.AIUsage != nil {
+				retries = int32(task.AIUsage.Retries)
+			}
+			if retries != tt.wantHits-1 {
+				t.Errorf("recorded %d retries, want %d", retries, tt.wantHits-1)
+			}
+		})
+	}
+}
+
+func TestRetryStopsAtCallDeadline(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	adapter := newTestAdapter(t, server.URL)
+	adapter.timeouts.analyze = time.Minute
+	start := time.Now()
+	if _, err := adapter.AnalyzeIssue(context.Background(), &core.AIIssue{Title: "t"}, ""); err == nil {
+		t.Fatal("expected the rate limit error")
+	}
+	if hits.Load() != 1 || time.Since(start) > 10*time.Second {
+		t.Errorf("a Retry-After past the call timeout should not be waited for (%d hits)", hits.Load())
+	}
+}
+
+func TestRetryAfterAndBackoff(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for v, want := range map[string]time.Duration{
+		"7":                             7 * time.Second,
+		"Fri, 02 Jan 2026 03:04:15 GMT": 10 * time.Second,
+		"Fri, 02 Jan 2026 03:00:00 GMT": 0,
+	} {
+		if got, ok := retryAfter(v, now); !ok || got != want {
+			t.Errorf("retryAfter(%q) = %v, %v; want %v", v, got, ok, want)
+		}
+	}
+	if _, ok := retryAfter("soon", now); ok {
+		t.Error("an unparsable Retry-After should be ignored")
+	}
+
+	p := retryPolicy{maxAttempts: 5, initial: time.Second, max: 4 * time.Second}
+	for attempt, ceiling := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 6: 4 * time.Second} {
+		for range 20 {
+			if d := p.backoff(attempt); d < ceiling/2 || d > ceiling {
+				t.Fatalf("backoff(%d) = %v, want within [%v, %v]", attempt, d, ceiling/2, ceiling)
+			}
+		}
+	}
+}
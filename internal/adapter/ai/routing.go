@@ -0,0 +1,240 @@
+package ai
+
+import (
+	"context"
+	"slices"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+)
+
+// Operations ai.models routes by.
+const (
+	opAnalyze   = "analyze"
+	opGenerate  = "generate"
+	opFix       = "fix"
+	opDeployFix = "deploy_fix"
+	opSummarize = "summarize"
+)
+
+// routingAdapter sends each call to the model ai.models picks for its
+// operation and prompt size. Calls that pick no model go to the wrapped
+// adapter as it is.
+type routingAdapter struct {
+	adapter  core.AIAdapter
+	switcher core.ModelSwitcher
+	models   config.AIModelsConfig
+	provider string
+	model    string // ai.model
+	// records is false when the wrapped adapter records the answering
+	// provider itself, as failoverAdapter does.
+	records bool
+	// pinned, set by WithModel, overrides the routing for every call.
+	pinned string
+}
+
+var (
+	_ core.AIAdapter      = (*routingAdapter)(nil)
+	_ core.TaskSummarizer = (*routingAdapter)(nil)
+	_ core.ModelSwitcher  = (*routingAdapter)(nil)
+)
+
+// withRouting wraps adapter with the ai.models routing, when it is
+// configured and the adapter can switch models.
+func withRouting(adapter core.AIAdapter, cfg config.AIConfig) core.AIAdapter {
+	m := cfg.Models
+	if m.Analyze == "" && m.Generate == "" && m.Fix == "" && m.DeployFix == "" && m.Summarize == "" && len(m.Tiers) == 0 {
+		return adapter
+	}
+	switcher, ok := adapter.(core.ModelSwitcher)
+	if !ok {
+		return adapter
+	}
+	_, failover := adapter.(*failoverAdapter)
+	return &routingAdapter{
+		adapter:  adapter,
+		switcher: switcher,
+		models:   m,
+		provider: providerName(cfg.Provider),
+		model:    cfg.Model,
+		records:  !failover,
+	}
+}
+
+// modelFor returns the model for a call of op whose prompt is about
+// inputTokens long, or "" for the configured ai.model.
+func (r *routingAdapter) modelFor(op string, inputTokens int) string {
+	if r.pinned != "" {
+		return r.pinned
+	}
+	for _, tier := range r.models.Tiers {
+		if inputTokens <= tier.MaxInputTokens && (len(tier.Operations) == 0 || slices.Contains(tier.Operations, op)) {
+			return tier.Model
+		}
+	}
+	switch op {
+	case opAnalyze:
+		return r.models.Analyze
+	case opGenerate:
+		return r.models.Generate
+	case opFix:
+		return r.models.Fix
+	case opDeployFix:
+		return r.models.DeployFix
+	case opSummarize:
+		return r.models.Summarize
+	}
+	return ""
+}
+
+// route runs call on the adapter for op and records the model it used.
+func route[T any](ctx context.Context, r *routingAdapter, op string, inputTokens int, call func(core.AIAdapter) (T, error)) (T, error) {
+	adapter, model := r.adapter, r.modelFor(op, inputTokens)
+	if model != "" {
+		adapter = r.switcher.WithModel(model)
+	} else {
+		model = r.model
+	}
+	v, err := call(adapter)
+	if err == nil && r.records {
+		core.RecordAIProvider(ctx, r.provider, model)
+	}
+	return v, err
+}
+
+func (r *routingAdapter) AnalyzeIssue(ctx context.Context, issue *core.AIIssue, projectContext string) (*core.AIPlan, error) {
+	size := len(projectContext)
+	if issue != nil {
+		size += len(issue.Title) + len(issue.Body)
+		for _, c := range issue.Comments {
+			size += len(c.Body)
+		}
+	}
+	return route(ctx, r, opAnalyze, estimateTokens(size), func(a core.AIAdapter) (*core.AIPlan, error) {
+		return a.AnalyzeIssue(ctx, issue, projectContext)
+	})
+}
+
+func (r *routingAdapter) GenerateCode(ctx context.Context, plan *core.AIPlan, repoFiles map[string]string) ([]core.AIFileChange, error) {
+	size := filesSize(repoFiles)
+	if plan != nil {
+		size += len(plan.Summary)
+		for _, s := range plan.Steps {
+			size += len(s)
+		}
+	}
+	return route(ctx, r, opGenerate, estimateTokens(size), func(a core.AIAdapter) ([]core.AIFileChange, error) {
+		return a.GenerateCode(ctx, plan, repoFiles)
+	})
+}
+
+func (r *routingAdapter) AnalyzeFailure(ctx context.Context, logs string, currentCode map[string]string) ([]core.AIFileChange, error) {
+	return route(ctx, r, opFix, estimateTokens(len(logs)+filesSize(currentCode)), func(a core.AIAdapter) ([]core.AIFileChange, error) {
+		return a.AnalyzeFailure(ctx, logs, currentCode)
+	})
+}
+
+func (r *routingAdapter) AnalyzeDeployFailure(ctx context.Context, deployLogs string, infraFiles map[string]string) (*core.AIProposedFix, error) {
+	return route(ctx, r, opDeployFix, estimateTokens(len(deployLogs)+filesSize(infraFiles)), func(a core.AIAdapter) (*core.AIProposedFix, error) {
+		return a.AnalyzeDeployFailure(ctx, deployLogs, infraFiles)
+	})
+}
+
+func (r *routingAdapter) SummarizeTask(ctx context.Context, report string) (string, error) {
+	if _, ok := r.adapter.(core.TaskSummarizer); !ok {
+		return "", core.ErrSummaryUnsupported
+	}
+	return route(ctx, r, opSummarize, estimateTokens(len(report)), func(a core.AIAdapter) (string, error) {
+		s, ok := a.(core.TaskSummarizer)
+		if !ok {
+			return "", core.ErrSummaryUnsupported
+		}
+		return s.SummarizeTask(ctx, report)
+	})
+}
+
+// ExplainFailure is routed like SummarizeTask: both write prose, not code.
+func (r *routingAdapter) ExplainFailure(ctx context.Context, report string) (string, error) {
+	if _, ok := r.adapter.(core.FailureExplainer); !ok {
+		return "", core.ErrExplainUnsupported
+	}
+	return route(ctx, r, opSummarize, estimateTokens(len(report)), func(a core.AIAdapter) (string, error) {
+		e, ok := a.(core.FailureExplainer)
+		if !ok {
+			return "", core.ErrExplainUnsupported
+		}
+		return e.ExplainFailure(ctx, report)
+	})
+}
+
+// DecomposePlan is routed like AnalyzeIssue: both plan, not code.
+func (r *routingAdapter) DecomposePlan(ctx context.Context, plan *core.AIPlan, maxSubtasks int) ([]core.AISubtask, error) {
+	if _, ok := r.adapter.(core.PlanDecomposer); !ok {
+		return nil, core.ErrDecomposeUnsupported
+	}
+	size := len(plan.Summary)
+	for _, s := range plan.Steps {
+		size += len(s)
+	}
+	return route(ctx, r, opAnalyze, estimateTokens(size), func(a core.AIAdapter) ([]core.AISubtask, error) {
+		d, ok := a.(core.PlanDecomposer)
+		if !ok {
+			return nil, core.ErrDecomposeUnsupported
+		}
+		return d.DecomposePlan(ctx, plan, maxSubtasks)
+	})
+}
+
+// SplitEpic is routed like AnalyzeIssue: both plan, not code.
+func (r *routingAdapter) SplitEpic(ctx context.Context, issue *core.AIIssue, items []string, maxParts int) ([]core.AISubtask, error) {
+	if _, ok := r.adapter.(core.EpicSplitter); !ok {
+		return nil, core.ErrSplitEpicUnsupported
+	}
+	size := len(issue.Title) + len(issue.Body)
+	for _, c := range issue.Comments {
+		size += len(c.Body)
+	}
+	return route(ctx, r, opAnalyze, estimateTokens(size), func(a core.AIAdapter) ([]core.AISubtask, error) {
+		sp, ok := a.(core.EpicSplitter)
+		if !ok {
+			return nil, core.ErrSplitEpicUnsupported
+		}
+		return sp.SplitEpic(ctx, issue, items, maxParts)
+	})
+}
+
+// ClassifyFailure is routed like SummarizeTask: a short answer, not code.
+func (r *routingAdapter) ClassifyFailure(ctx context.Context, logs string) (core.FailureClass, error) {
+	if _, ok := r.adapter.(core.FailureClassifier); !ok {
+		return core.FailureUnknown, core.ErrClassifyUnsupported
+	}
+	return route(ctx, r, opSummarize, estimateTokens(len(logs)), func(a core.AIAdapter) (core.FailureClass, error) {
+		cl, ok := a.(core.FailureClassifier)
+		if !ok {
+			return core.FailureUnknown, core.ErrClassifyUnsupported
+		}
+		return cl.ClassifyFailure(ctx, logs)
+	})
+}
+
+// WithModel returns a copy that sends every call to model, so
+// ai.retry_model overrides the routing on retries.
+func (r *routingAdapter) WithModel(model string) core.AIAdapter {
+	c := *r
+	c.pinned = model
+	return &c
+}
+
+// estimateTokens approximates the tokens of a prompt of size bytes, at
+// about four bytes per token.
+func estimateTokens(size int) int {
+	return size / 4
+}
+
+func filesSize(files map[string]string) int {
+	size := 0
+	for path, content := range files {
+		size += len(path) + len(content)
+	}
+	return size
+}
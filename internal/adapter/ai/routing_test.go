@@ -0,0 +1,68 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+)
+
+// modelStub records the model of each call it answers.
+type modelStub struct {
+	core.AIAdapter
+	model string
+	calls *[]string
+}
+
+func (m modelStub) WithModel(model string) core.AIAdapter {
+	return modelStub{model: model, calls: m.calls}
+}
+
+func (m modelStub) AnalyzeIssue(ctx context.Context, issue *core.AIIssue, projectContext string) (*core.AIPlan, error) {
+	*m.calls = append(*m.calls, m.model)
+	return &core.AIPlan{Summary: "plan"}, nil
+}
+
+func (m modelStub) GenerateCode(ctx context.Context, plan *core.AIPlan, repoFiles map[string]string) ([]core.AIFileChange, error) {
+	*m.calls = append(*m.calls, m.model)
+	return nil, nil
+}
+
+func (m modelStub) AnalyzeFailure(ctx context.Context, logs string, currentCode map[string]string) ([]core.AIFileChange, error) {
+	*m.calls = append(*m.calls, m.model)
+	return nil, nil
+}
+
+func TestRoutingByOperationAndSize(t *testing.T) {
+	var calls []string
+	adapter := withRouting(modelStub{model: "sonnet", calls: &calls}, config.AIConfig{
+		Provider: "anthropic",
+		Model:    "sonnet",
+		Models: config.AIModelsConfig{
+			Analyze:  "haiku",
+			Generate: "opus",
+			Tiers:    []config.AIModelTierConfig{{MaxInputTokens: 100, Model: "haiku", Operations: []string{"generate"}}},
+		},
+	})
+
+	ctx := context.Background()
+	plan, _ := adapter.AnalyzeIssue(ctx, &core.AIIssue{Title: "t"}, "")
+	adapter.GenerateCode(ctx, plan, map[string]string{"small.go": "package small"})
+	adapter.GenerateCode(ctx, plan, map[string]string{"big.go": strings.Repeat("x", 1000)})
+	adapter.AnalyzeFailure(ctx, "FAIL", nil)
+	adapter.(core.ModelSwitcher).WithModel("retry-model").AnalyzeIssue(ctx, &core.AIIssue{}, "")
+
+	want := "haiku,haiku,opus,sonnet,retry-model"
+	if got := strings.Join(calls, ","); got != want {
+		t.Errorf("models = %s, want %s", got, want)
+	}
+}
+
+func TestRoutingUnconfigured(t *testing.T) {
+	stub := modelStub{calls: new([]string)}
+	if got := withRouting(stub, config.AIConfig{Model: "m"}); got != core.AIAdapter(stub) {
+		t.Errorf("without ai.models the adapter should not be wrapped, got %T", got)
+	}
+}
@@ -1,6 +1,7 @@
 package ai
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -19,6 +20,149 @@ func buildSystemPrompt(projectContext string) string {
 	)
 }
 
+// withAgents puts the AGENTS.md instructions of the repository, set on ctx
+// by the engine, ahead of a system prompt.
+func withAgents(ctx context.Context, systemPrompt string) string {
+	instructions := core.AgentsInstructions(ctx)
+	if instructions == "" {
+		return systemPrompt
+	}
+	return fmt.Sprintf(
+		"Follow these instructions from the repository's %s:\n%s\n\n%s",
+		core.AgentsFile, instructions, systemPrompt,
+	)
+}
+
+// withLanguage appends the ai.language instruction to a system prompt, so
+// plans, explanations and summaries come back in the team's language.
+func withLanguage(systemPrompt, language string) string {
+	name := core.LanguageName(language)
+	if name == "" {
+		return systemPrompt
+	}
+	return systemPrompt + fmt.Sprintf(
+		"\n\nWrite all natural-language text (plan summaries, steps, explanations, reasons and summaries) in %s. Keep code, identifiers, file paths, commands and JSON keys unchanged.",
+		name,
+	)
+}
+
+// summarySystemPrompt instructs the model to write a short status summary.
+const summarySystemPrompt = "You are a release assistant reporting on an automated coding pipeline. Write a short, plain-text status summary for a busy engineer. No markdown headings, no JSON."
+
+// buildSummaryPrompt asks for a summary of the given task report.
+func buildSummaryPrompt(report string) string {
+	return fmt.Sprintf(
+		`Summarize the following task in at most five sentences: what happened, where it is stuck (if anywhere), and what is needed from the user (if anything).
+
+Task Report:
+%s`,
+		report,
+	)
+}
+
+// explainSystemPrompt instructs the model to diagnose a failure in prose.
+const explainSystemPrompt = "You are a senior engineer diagnosing a failed run of an automated coding pipeline for a teammate. Write plain text, no markdown headings, no JSON."
+
+// buildExplainPrompt asks for a diagnosis of the given failure report.
+func buildExplainPrompt(report string) string {
+	return fmt.Sprintf(
+		`Explain why the following attempt failed. Name the most likely root cause first and point to the log lines or changed files that show it, then say whether it looks like a problem in the generated code, the tests, the deployment or the environment, and what to try next. Keep it under 200 words.
+
+Failure Report:
+%s`,
+		report,
+	)
+}
+
+// decomposeSystemPrompt instructs the model to split a plan into sub-tasks.
+const decomposeSystemPrompt = "You are a tech lead splitting an implementation plan into sub-tasks that separate engineers can code independently. Respond with JSON only."
+
+// buildDecomposePrompt asks for plan split into at most maxSubtasks
+// sub-tasks.
+func buildDecomposePrompt(plan *core.AIPlan, maxSubtasks int) string {
+	return fmt.Sprintf(
+		`Split the following implementation plan into at most %d sub-tasks by area of the code, such as backend, frontend, tests and docs. Every step of the plan must belong to exactly one sub-task, and a file should be changed by only one sub-task where possible. List the sub-tasks in the order they should be done.
+
+Plan Summary: %s
+Steps:
+%s
+Respond in the following JSON format ONLY (no markdown fences, no extra text):
+{"subtasks": [{"name": "backend", "summary": "What this sub-task changes", "steps": ["Step description"]}]}`,
+		maxSubtasks, plan.Summary, formatSteps(plan.Steps),
+	)
+}
+
+// epicSystemPrompt instructs the model to group the checklist of an epic
+// issue into stacked parts.
+const epicSystemPrompt = "You are a tech lead planning an epic as a stack of pull requests, each small enough to review on its own. Respond with JSON only."
+
+// buildEpicPrompt asks for the checklist items of an epic issue grouped
+// into at most maxParts parts.
+func buildEpicPrompt(issue *core.AIIssue, items []string, maxParts int) string {
+	return fmt.Sprintf(
+		`Group the checklist items of the following epic into at most %d parts, each becoming one pull request that builds on the parts before it. Every item must belong to exactly one part; keep an item's wording as its step. List the parts in the order they should be merged, foundations first.
+
+Epic: %s
+
+%s
+%s
+Checklist:
+%s
+Respond in the following JSON format ONLY (no markdown fences, no extra text):
+{"subtasks": [{"name": "Short part title", "summary": "What this part changes", "steps": ["Checklist item"]}]}`,
+		maxParts, issue.Title, issue.Body, formatIssueComments(issue.Comments), formatSteps(items),
+	)
+}
+
+// parseSubtasks extracts the sub-tasks of a split plan from a JSON string,
+// either {"subtasks": [...]} or the bare array.
+func parseSubtasks(raw string) ([]core.AISubtask, error) {
+	cleaned := cleanJSON(raw)
+	if cleaned == "" {
+		return nil, fmt.Errorf("empty sub-tasks response")
+	}
+
+	var wrapped struct {
+		Subtasks []core.AISubtask `json:"subtasks"`
+	}
+	var subtasks []core.AISubtask
+	if strings.HasPrefix(cleaned, "{") && json.Unmarshal([]byte(cleaned), &wrapped) == nil && wrapped.Subtasks != nil {
+		subtasks = wrapped.Subtasks
+	} else if err := json.Unmarshal([]byte(cleaned), &subtasks); err != nil {
+		return nil, fmt.Errorf("parse sub-tasks: %w (raw: %.200s)", err, raw)
+	}
+	return subtasks, nil
+}
+
+// maxIssueDiscussionBytes bounds the comment thread included in planning prompts.
+const maxIssueDiscussionBytes = 16 * 1024
+
+// formatIssueComments renders the issue discussion for the planning prompt.
+// When the thread is too long, the oldest comments are dropped first.
+func formatIssueComments(comments []core.IssueComment) string {
+	if len(comments) == 0 {
+		return ""
+	}
+	var rendered []string
+	size := 0
+	for i := len(comments) - 1; i >= 0; i-- {
+		c := comments[i]
+		entry := fmt.Sprintf("- @%s (%s):\n%s\n", c.Author, c.CreatedAt.Format("2006-01-02"), strings.TrimSpace(c.Body))
+		if size+len(entry) > maxIssueDiscussionBytes {
+			rendered = append(rendered, fmt.Sprintf("- (%d earlier comments omitted)\n", i+1))
+			break
+		}
+		size += len(entry)
+		rendered = append(rendered, entry)
+	}
+	var b strings.Builder
+	b.WriteString("\nIssue Discussion (oldest first):\n")
+	for i := len(rendered) - 1; i >= 0; i-- {
+		b.WriteString(rendered[i])
+	}
+	return b.String()
+}
+
 // formatSteps formats plan steps as a numbered list.
 func formatSteps(steps []string) string {
 	var b strings.Builder
@@ -59,8 +203,14 @@ func parseFileChanges(raw string) ([]core.AIFileChange, error) {
 		return nil, fmt.Errorf("empty file changes response")
 	}
 
+	// Tool calls wrap the changes in an object; see fileChangesTool.
+	var wrapped struct {
+		Changes []core.AIFileChange `json:"changes"`
+	}
 	var changes []core.AIFileChange
-	if err := json.Unmarshal([]byte(cleaned), &changes); err != nil {
+	if strings.HasPrefix(cleaned, "{") && json.Unmarshal([]byte(cleaned), &wrapped) == nil && wrapped.Changes != nil {
+		changes = wrapped.Changes
+	} else if err := json.Unmarshal([]byte(cleaned), &changes); err != nil {
 		// Fallback 1: try repairing truncated JSON.
 		repaired := repairTruncatedJSON(cleaned)
 		if err2 := json.Unmarshal([]byte(repaired), &changes); err2 != nil {
@@ -314,3 +464,41 @@ func repairTruncatedJSON(s string) string {
 	}
 	return s + suffix
 }
+
+// classifySystemPrompt instructs the model to classify a test failure.
+const classifySystemPrompt = "You are a CI engineer triaging a failed test run of an automated coding pipeline. Respond with JSON only."
+
+// buildClassifyPrompt asks for the class of the given failure output.
+func buildClassifyPrompt(logs string) string {
+	return fmt.Sprintf(
+		`Classify the following test failure output as exactly one of:
+- compile: the code does not build or a module/import is missing
+- assertion: the tests ran and a check failed
+- infra: a service, network, disk or other environment error unrelated to the code
+- timeout: a test or the run ran out of time
+
+Failure Output:
+%s
+Respond in the following JSON format ONLY (no markdown fences, no extra text):
+{"class": "assertion"}`,
+		logs,
+	)
+}
+
+// parseFailureClass extracts the class of a failure from {"class": "..."}
+// or a bare class name.
+func parseFailureClass(raw string) (core.FailureClass, error) {
+	cleaned := cleanJSON(raw)
+	var wrapped struct {
+		Class string `json:"class"`
+	}
+	name := strings.Trim(strings.TrimSpace(raw), `"'.`)
+	if strings.HasPrefix(cleaned, "{") && json.Unmarshal([]byte(cleaned), &wrapped) == nil {
+		name = wrapped.Class
+	}
+	class := core.ParseFailureClass(name)
+	if class == core.FailureUnknown {
+		return class, fmt.Errorf("parse failure class: unknown class (raw: %.200s)", raw)
+	}
+	return class, nil
+}
@@ -0,0 +1,125 @@
+package ai
+
+import "encoding/json"
+
+// outputTool describes the structured answer of a call as a tool the model
+// is forced to call. Providers with native tool or function calling send it
+// along with the prompt and return the tool's arguments as JSON; the
+// parsers below accept those arguments as well as a plain-text answer, so a
+// model that answers in text instead still works.
+type outputTool struct {
+	name        string
+	description string
+	schema      map[string]any
+}
+
+// fileChangeSchema is the JSON schema of a core.AIFileChange.
+var fileChangeSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"path":    map[string]any{"type": "string", "description": "File path relative to the repository root"},
+		"content": map[string]any{"type": "string", "description": "Full new file content; empty for delete"},
+		"action":  map[string]any{"type": "string", "enum": []string{"create", "modify", "delete"}},
+	},
+	"required": []string{"path", "action"},
+}
+
+var (
+	planTool = &outputTool{
+		name:        "submit_plan",
+		description: "Submit the implementation plan for the issue.",
+		schema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"summary": map[string]any{"type": "string", "description": "Brief summary of what needs to be done"},
+				"steps":   map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+			},
+			"required": []string{"summary", "steps"},
+		},
+	}
+
+	// fileChangesTool wraps the changes in an object because tool arguments
+	// must be a JSON object; parseFileChanges unwraps them.
+	fileChangesTool = &outputTool{
+		name:        "submit_file_changes",
+		description: "Submit the file changes, each with the full new content of the file.",
+		schema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"changes": map[string]any{"type": "array", "items": fileChangeSchema},
+			},
+			"required": []string{"changes"},
+		},
+	}
+
+	// subtasksTool wraps the sub-tasks in an object like fileChangesTool.
+	subtasksTool = &outputTool{
+		name:        "submit_subtasks",
+		description: "Submit the sub-tasks the plan is split into, in the order they should be done.",
+		schema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"subtasks": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"name":    map[string]any{"type": "string", "description": "Short name, such as backend, tests or docs"},
+							"summary": map[string]any{"type": "string", "description": "What this sub-task changes"},
+							"steps":   map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+						},
+						"required": []string{"name", "summary", "steps"},
+					},
+				},
+			},
+			"required": []string{"subtasks"},
+		},
+	}
+
+	failureClassTool = &outputTool{
+		name:        "submit_failure_class",
+		description: "Submit the class of the test failure.",
+		schema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"class": map[string]any{"type": "string", "enum": []string{"compile", "assertion", "infra", "timeout"}},
+			},
+			"required": []string{"class"},
+		},
+	}
+
+	proposedFixTool = &outputTool{
+		name:        "submit_deploy_fix",
+		description: "Submit the diagnosis of the deployment failure and the infrastructure file changes that fix it.",
+		schema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"summary": map[string]any{"type": "string", "description": "Brief summary of the deployment issue"},
+				"reason":  map[string]any{"type": "string", "description": "Root cause analysis"},
+				"changes": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"path":    map[string]any{"type": "string"},
+							"action":  map[string]any{"type": "string", "enum": []string{"create", "modify", "delete"}},
+							"reason":  map[string]any{"type": "string", "description": "Why the file needs this change"},
+							"content": map[string]any{"type": "string"},
+						},
+						"required": []string{"path", "action", "reason"},
+					},
+				},
+			},
+			"required": []string{"summary", "reason", "changes"},
+		},
+	}
+)
+
+// toolArguments returns the arguments of a tool call as a string for the
+// parsers, or "" when the model did not call the tool.
+func toolArguments(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	return string(raw)
+}
@@ -0,0 +1,44 @@
+// Package artifact provides the stores the engine keeps the full outputs
+// and test files of attempts in: a local directory or an S3-compatible
+// bucket.
+package artifact
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+)
+
+// New creates the store artifacts configures. Local artifacts default to
+// core.ArtifactsDir of statePath.
+func New(cfg config.ArtifactsConfig, statePath string) (core.ArtifactStore, error) {
+	switch cfg.Backend {
+	case "", "local":
+		dir := cfg.Dir
+		if dir == "" {
+			dir = core.ArtifactsDir(statePath)
+		}
+		return NewLocal(dir), nil
+	case "s3":
+		s3 := cfg.S3
+		if s3.AccessKey == "" {
+			s3.AccessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+		}
+		if s3.SecretKey == "" {
+			s3.SecretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		}
+		return NewS3(s3)
+	default:
+		return nil, fmt.Errorf("unknown artifacts backend %q", cfg.Backend)
+	}
+}
+
+// validKey reports whether key is a relative slash-separated path that
+// stays below the root of a store.
+func validKey(key string) bool {
+	return key != "" && !strings.HasPrefix(key, "/") && path.Clean(key) == key && !strings.HasPrefix(key, "../") && key != ".."
+}
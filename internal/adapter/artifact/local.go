@@ -0,0 +1,118 @@
+package artifact
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rigdev/rig/internal/core"
+)
+
+// tmpPrefix marks files Put is still writing.
+const tmpPrefix = ".put-"
+
+// Local keeps artifacts as files under a directory.
+type Local struct {
+	dir string
+}
+
+var _ core.ArtifactStore = (*Local)(nil)
+
+// NewLocal creates a store under dir, which is created on the first Put.
+func NewLocal(dir string) *Local {
+	return &Local{dir: dir}
+}
+
+func (l *Local) path(key string) (string, error) {
+	if !validKey(key) {
+		return "", fmt.Errorf("invalid artifact key %q", key)
+	}
+	return filepath.Join(l.dir, filepath.FromSlash(key)), nil
+}
+
+// Put writes r to the file of key. Readers never see a partial file.
+func (l *Local) Put(_ context.Context, key string, r io.Reader, _ int64, _ string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(p), tmpPrefix+"*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), p)
+}
+
+// Get opens the file of key.
+func (l *Local) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, core.ErrArtifactNotFound
+	}
+	return f, err
+}
+
+// Delete removes the file of key and the directories it leaves empty.
+func (l *Local) Delete(_ context.Context, key string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	for dir := filepath.Dir(p); dir != l.dir && strings.HasPrefix(dir, l.dir); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// List returns every artifact under the directory.
+func (l *Local) List(_ context.Context) ([]core.ArtifactObject, error) {
+	var objects []core.ArtifactObject
+	err := filepath.WalkDir(l.dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && p == l.dir {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), tmpPrefix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(l.dir, p)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, core.ArtifactObject{Key: filepath.ToSlash(rel), Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	return objects, err
+}
@@ -0,0 +1,300 @@
+package artifact
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+)
+
+// unsignedPayload is the payload hash of requests whose body is streamed
+// without hashing it first.
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// emptyPayload is the SHA-256 of an empty body.
+const emptyPayload = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// S3 keeps artifacts as objects of an S3-compatible bucket, addressed
+// path-style and signed with AWS Signature Version 4.
+type S3 struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	prefix    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+	now       func() time.Time
+}
+
+var _ core.ArtifactStore = (*S3)(nil)
+
+// NewS3 creates a store for the bucket of cfg.
+func NewS3(cfg config.ArtifactS3Config) (*S3, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("artifacts.s3.bucket is required")
+	}
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	u, err := url.Parse(strings.TrimRight(endpoint, "/"))
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid artifacts.s3.endpoint %q", endpoint)
+	}
+	prefix := strings.Trim(cfg.Prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &S3{
+		endpoint:  u,
+		region:    region,
+		bucket:    cfg.Bucket,
+		prefix:    prefix,
+		accessKey: cfg.AccessKey,
+		secretKey: cfg.SecretKey,
+		client:    &http.Client{Timeout: 5 * time.Minute},
+		now:       time.Now,
+	}, nil
+}
+
+// Put uploads r as the object of key.
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	if !validKey(key) {
+		return fmt.Errorf("invalid artifact key %q", key)
+	}
+	req, err := s.request(ctx, http.MethodPut, s.prefix+key, nil, r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := s.do(req, unsignedPayload)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Get downloads the object of key.
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if !validKey(key) {
+		return nil, fmt.Errorf("invalid artifact key %q", key)
+	}
+	req, err := s.request(ctx, http.MethodGet, s.prefix+key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req, emptyPayload)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Delete removes the object of key.
+func (s *S3) Delete(ctx context.Context, key string) error {
+	if !validKey(key) {
+		return fmt.Errorf("invalid artifact key %q", key)
+	}
+	req, err := s.request(ctx, http.MethodDelete, s.prefix+key, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req, emptyPayload)
+	if errors.Is(err, core.ErrArtifactNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// listResult is a page of a ListObjectsV2 response.
+type listResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+}
+
+// List returns every object under the prefix, with the prefix removed
+// from their keys.
+func (s *S3) List(ctx context.Context) ([]core.ArtifactObject, error) {
+	var objects []core.ArtifactObject
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}}
+		if s.prefix != "" {
+			query.Set("prefix", s.prefix)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		req, err := s.request(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := s.do(req, emptyPayload)
+		if err != nil {
+			return nil, err
+		}
+		var page listResult
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("s3 list: %w", err)
+		}
+		for _, c := range page.Contents {
+			objects = append(objects, core.ArtifactObject{Key: strings.TrimPrefix(c.Key, s.prefix), Size: c.Size, ModTime: c.LastModified})
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// request builds a request for the object at key, or the bucket when key
+// is empty.
+func (s *S3) request(ctx context.Context, method, key string, query url.Values, body io.Reader) (*http.Request, error) {
+	u := *s.endpoint
+	p := strings.TrimRight(u.Path, "/") + "/" + s.bucket
+	if key != "" {
+		p += "/" + key
+	}
+	u.Path, u.RawPath = p, awsEscape(p, true)
+	u.RawQuery = canonicalQuery(query)
+	return http.NewRequestWithContext(ctx, method, u.String(), body)
+}
+
+// do signs and sends req and returns the response of a 2xx status. A 404
+// is core.ErrArtifactNotFound.
+func (s *S3) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	signV4(req, s.accessKey, s.secretKey, s.region, payloadHash, s.now())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("s3 %s: %w", req.Method, err)
+	}
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, core.ErrArtifactNotFound
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return nil, fmt.Errorf("s3 %s %s: %s: %s", req.Method, req.URL.Path, resp.Status, strings.TrimSpace(string(msg)))
+}
+
+// signV4 signs req with AWS Signature Version 4 for service s3, covering
+// the host and every header already set.
+func signV4(req *http.Request, accessKey, secretKey, region, payloadHash string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		if name = strings.ToLower(name); name != "authorization" {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// canonicalQuery encodes query sorted by key, as Signature Version 4
+// expects it.
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, awsEscape(k, false)+"="+awsEscape(v, false))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// awsEscape percent-encodes every byte of s but unreserved characters, and
+// slashes when path is set.
+func awsEscape(s string, path bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', path && c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"net"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -14,6 +13,7 @@ import (
 
 	"github.com/rigdev/rig/internal/config"
 	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/shell"
 	"github.com/rigdev/rig/internal/variable"
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/knownhosts"
@@ -31,7 +31,10 @@ type CustomAdapter struct {
 	rollback []config.CustomCommand
 }
 
-var _ core.DeployAdapterIface = (*CustomAdapter)(nil)
+var (
+	_ core.DeployAdapterIface = (*CustomAdapter)(nil)
+	_ core.SnapshotRollbacker = (*CustomAdapter)(nil)
+)
 
 // NewCustom creates a new CustomAdapter from deploy and rollback configs.
 func NewCustom(cfg config.DeployMethodConfig, rollbackCfg config.DeployMethodConfig) (*CustomAdapter, error) {
@@ -96,10 +99,63 @@ func (a *CustomAdapter) Rollback(ctx context.Context) error {
 	return nil
 }
 
+// RollbackTo restores the deploy recorded in snap: the rollback commands
+// run with the variables of that deploy, so ${COMMIT_SHA} is the commit
+// deployed then, plus ${ARTIFACT_<NAME>} for each artifact it reported.
+func (a *CustomAdapter) RollbackTo(ctx context.Context, snap *core.DeploySnapshot) error {
+	vars := make(map[string]string, len(snap.Vars)+len(snap.Artifacts))
+	for k, v := range snap.Vars {
+		vars[k] = v
+	}
+	for name, v := range snap.Artifacts {
+		vars[artifactVar(name)] = v
+	}
+	result, err := a.runCommands(ctx, a.rollback, vars)
+	if err != nil {
+		return err
+	}
+	if !result.Success {
+		return fmt.Errorf("rollback failed: %s", result.Output)
+	}
+	return nil
+}
+
+// artifactPrefix starts a line of command output that reports an
+// artifact: "::artifact name=value".
+const artifactPrefix = "::artifact "
+
+// parseArtifacts adds the artifacts reported in output to artifacts.
+func parseArtifacts(output string, artifacts map[string]string) {
+	for line := range strings.Lines(output) {
+		rest, ok := strings.CutPrefix(strings.TrimSpace(line), artifactPrefix)
+		if !ok {
+			continue
+		}
+		if name, value, ok := strings.Cut(rest, "="); ok && strings.TrimSpace(name) != "" {
+			artifacts[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+	}
+}
+
+// artifactVar is the variable an artifact is passed to rollback commands
+// as: image-tag becomes ARTIFACT_IMAGE_TAG.
+func artifactVar(name string) string {
+	return "ARTIFACT_" + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, name)
+}
+
 // runCommands executes a list of commands sequentially with retry logic.
 func (a *CustomAdapter) runCommands(ctx context.Context, cmds []config.CustomCommand, vars map[string]string) (*core.AdapterDeployResult, error) {
 	start := time.Now()
 	var allOutput strings.Builder
+	artifacts := make(map[string]string)
 
 	for i, cmd := range cmds {
 		resolved := variable.Resolve(cmd.Run, vars)
@@ -126,6 +182,7 @@ func (a *CustomAdapter) runCommands(ctx context.Context, cmds []config.CustomCom
 
 			if err == nil {
 				allOutput.WriteString(output)
+				parseArtifacts(output, artifacts)
 				lastErr = nil
 				break
 			}
@@ -141,16 +198,23 @@ func (a *CustomAdapter) runCommands(ctx context.Context, cmds []config.CustomCom
 		}
 	}
 
-	return &core.AdapterDeployResult{
+	result := &core.AdapterDeployResult{
 		Success:  true,
 		Output:   allOutput.String(),
 		Duration: time.Since(start),
-	}, nil
+	}
+	if len(artifacts) > 0 {
+		result.Artifacts = artifacts
+	}
+	return result, nil
 }
 
 // executeLocal runs a command on the local machine.
 func (a *CustomAdapter) executeLocal(ctx context.Context, cmd config.CustomCommand, resolved string) (string, error) {
-	c := exec.CommandContext(ctx, "sh", "-c", resolved)
+	c, err := shell.Command(ctx, cmd.Shell, resolved)
+	if err != nil {
+		return "", err
+	}
 
 	// Ensure child processes are killed when context is cancelled.
 	c.WaitDelay = 500 * time.Millisecond
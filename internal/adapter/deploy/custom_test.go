@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
 )
 
 func localCmd(name, run string) config.CustomCommand {
@@ -340,6 +341,52 @@ func TestCustomRollback(t *testing.T) {
 	}
 }
 
+func TestCustomArtifacts(t *testing.T) {
+	adapter := &CustomAdapter{
+		commands: []config.CustomCommand{
+			localCmd("build", `echo building; echo "::artifact image=registry/app:${COMMIT_SHA}"`),
+			localCmd("release", `echo "  ::artifact release-id = 42"`),
+		},
+	}
+
+	result, err := adapter.Deploy(context.Background(), map[string]string{"COMMIT_SHA": "abc123"})
+	if err != nil {
+		t.Fatalf("Deploy failed: %v", err)
+	}
+	want := map[string]string{"image": "registry/app:abc123", "release-id": "42"}
+	if len(result.Artifacts) != len(want) {
+		t.Fatalf("artifacts = %v, want %v", result.Artifacts, want)
+	}
+	for k, v := range want {
+		if result.Artifacts[k] != v {
+			t.Errorf("artifact %s = %q, want %q", k, result.Artifacts[k], v)
+		}
+	}
+}
+
+func TestCustomRollbackTo(t *testing.T) {
+	snap := &core.DeploySnapshot{
+		Vars:      map[string]string{"COMMIT_SHA": "good1"},
+		Artifacts: map[string]string{"release-id": "41"},
+	}
+	out := filepath.Join(t.TempDir(), "out")
+	adapter := &CustomAdapter{
+		commands: []config.CustomCommand{localCmd("deploy", `echo "deploy ${COMMIT_SHA}" >> "`+out+`"`)},
+		rollback: []config.CustomCommand{localCmd("restore", `echo "restore ${COMMIT_SHA} ${ARTIFACT_RELEASE_ID}" >> "`+out+`"`)},
+	}
+	if err := adapter.RollbackTo(context.Background(), snap); err != nil {
+		t.Fatalf("RollbackTo failed: %v", err)
+	}
+
+	content, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "restore good1 41\n"; string(content) != want {
+		t.Errorf("commands ran %q, want %q", content, want)
+	}
+}
+
 func TestCustomMultipleCommands(t *testing.T) {
 	adapter := &CustomAdapter{
 		commands: []config.CustomCommand{
@@ -0,0 +1,108 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Commit identities derived from the GitHub API.
+const (
+	IdentityToken = "token" // the user the token belongs to
+	IdentityApp   = "app"   // the bot user of a GitHub App
+)
+
+// Author configures the author and committer of the commits rig makes.
+// Name and Email win over Identity; without any of them the local git
+// configuration applies.
+type Author struct {
+	Name  string
+	Email string
+	// Identity derives the name and email from the GitHub API: token for
+	// the token's user, app for the bot of the GitHub App AppSlug.
+	Identity string
+	AppSlug  string
+}
+
+// Signing signs commits with a GPG key ID or an SSH key file.
+type Signing struct {
+	Format string // gpg or ssh
+	Key    string
+}
+
+// SetAuthor sets the identity of the commits the adapter makes.
+func (g *GitHubAdapter) SetAuthor(a Author) {
+	g.author = a
+	g.resolveAuthor = sync.OnceValues(func() (Author, error) {
+		return g.lookupAuthor(context.Background())
+	})
+}
+
+// SetSigning signs the commits the adapter makes.
+func (g *GitHubAdapter) SetSigning(s Signing) {
+	g.signing = s
+}
+
+// lookupAuthor fills in the name and email the identity stands for.
+// GitHub attributes commits to an account by its noreply address.
+func (g *GitHubAdapter) lookupAuthor(ctx context.Context) (Author, error) {
+	a := g.author
+	if a.Name != "" && a.Email != "" {
+		return a, nil
+	}
+	login := ""
+	switch a.Identity {
+	case "":
+		return a, nil
+	case IdentityApp:
+		login = a.AppSlug + "[bot]"
+	}
+	user, _, err := g.client.Users.Get(ctx, login)
+	if err != nil {
+		return a, fmt.Errorf("look up %s identity: %w", a.Identity, err)
+	}
+	if a.Name == "" {
+		a.Name = user.GetLogin()
+	}
+	if a.Email == "" {
+		a.Email = fmt.Sprintf("%d+%s@users.noreply.github.com", user.GetID(), user.GetLogin())
+	}
+	return a, nil
+}
+
+// commitConfig returns the git -c options that set the author, committer
+// and signing of a commit.
+func (g *GitHubAdapter) commitConfig() ([]string, error) {
+	var args []string
+	if g.resolveAuthor != nil {
+		a, err := g.resolveAuthor()
+		if err != nil {
+			return nil, err
+		}
+		if a.Name != "" {
+			args = append(args, "-c", "user.name="+a.Name)
+		}
+		if a.Email != "" {
+			args = append(args, "-c", "user.email="+a.Email)
+		}
+	}
+	if g.signing.Key != "" {
+		format := strings.ToLower(g.signing.Format)
+		if format == "" {
+			format = "gpg"
+		}
+		key := g.signing.Key
+		if format == "ssh" && strings.HasPrefix(key, "~/") {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, fmt.Errorf("get user home dir: %w", err)
+			}
+			key = filepath.Join(home, key[2:])
+		}
+		args = append(args, "-c", "gpg.format="+format, "-c", "user.signingkey="+key, "-c", "commit.gpgsign=true")
+	}
+	return args, nil
+}
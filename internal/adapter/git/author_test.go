@@ -0,0 +1,87 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rigdev/rig/internal/core"
+)
+
+func TestCommitAuthor(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /user", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"login": "octo", "id": 7}`)
+	})
+	mux.HandleFunc("GET /users/{login}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("login") != "rig-app[bot]" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"login": "rig-app[bot]", "id": 42}`)
+	})
+
+	tests := []struct {
+		name   string
+		author Author
+		want   string
+	}{
+		{"explicit", Author{Name: "Rig Bot", Email: "bot@rig.dev", Identity: IdentityApp}, "Rig Bot <bot@rig.dev>"},
+		{"token", Author{Identity: IdentityToken}, "octo <7+octo@users.noreply.github.com>"},
+		{"app", Author{Identity: IdentityApp, AppSlug: "rig-app"}, "rig-app[bot] <42+rig-app[bot]@users.noreply.github.com>"},
+		{"app name only", Author{Name: "Rig", Identity: IdentityApp, AppSlug: "rig-app"}, "Rig <42+rig-app[bot]@users.noreply.github.com>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, _ := newTestGitHub(t, mux)
+			workDir, _ := initBareRepo(t)
+			g.workspace = workDir
+			g.SetAuthor(tt.author)
+
+			changes := []core.GitFileChange{{Path: "a.go", Content: "package a\n", Action: "create"}}
+			if err := g.CommitAndPush(context.Background(), changes, "add a"); err != nil {
+				t.Fatalf("CommitAndPush: %v", err)
+			}
+			got := strings.TrimSpace(run(t, workDir, "git", "log", "-1", "--format=%an <%ae>|%cn <%ce>"))
+			if got != tt.want+"|"+tt.want {
+				t.Errorf("author|committer = %q, want %q for both", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommitAuthorLookupFails(t *testing.T) {
+	g, _ := newTestGitHub(t, http.NewServeMux())
+	workDir, _ := initBareRepo(t)
+	g.workspace = workDir
+	g.SetAuthor(Author{Identity: IdentityApp, AppSlug: "missing"})
+
+	changes := []core.GitFileChange{{Path: "a.go", Content: "package a\n", Action: "create"}}
+	if err := g.CommitAndPush(context.Background(), changes, "add a"); err == nil {
+		t.Fatal("expected an error when the identity cannot be looked up")
+	}
+}
+
+func TestCommitSigningSSH(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not available")
+	}
+	key := filepath.Join(t.TempDir(), "id_ed25519")
+	run(t, "", "ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-f", key)
+
+	workDir, _ := initBareRepo(t)
+	g := &GitHubAdapter{workspace: workDir}
+	g.SetSigning(Signing{Format: "ssh", Key: key})
+
+	changes := []core.GitFileChange{{Path: "a.go", Content: "package a\n", Action: "create"}}
+	if err := g.CommitAndPush(context.Background(), changes, "add a"); err != nil {
+		t.Fatalf("CommitAndPush: %v", err)
+	}
+	if out := run(t, workDir, "git", "cat-file", "commit", "HEAD"); !strings.Contains(out, "-----BEGIN SSH SIGNATURE-----") {
+		t.Errorf("commit is not SSH-signed:\n%s", out)
+	}
+}
@@ -0,0 +1,164 @@
+package git
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxCachedResponses bounds the number of issue/comment responses kept in memory.
+const maxCachedResponses = 512
+
+// RateLimit is the most recently observed GitHub API rate-limit window.
+type RateLimit struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Headroom returns the fraction of the rate limit still available (0..1).
+func (r RateLimit) Headroom() float64 {
+	if r.Limit <= 0 {
+		return 0
+	}
+	return float64(r.Remaining) / float64(r.Limit)
+}
+
+type cachedResponse struct {
+	etag     string
+	header   http.Header
+	body     []byte
+	storedAt time.Time
+}
+
+// issueCache is a read-through cache for GitHub issue and comment GETs.
+// Requests carry If-None-Match with the stored ETag; a 304 is answered from
+// the cache, which GitHub does not count against the rate limit. It is shared
+// by all adapters in the process because adapters are created per execution.
+type issueCache struct {
+	mu        sync.Mutex
+	entries   map[string]cachedResponse
+	rateLimit RateLimit
+}
+
+var sharedIssueCache = &issueCache{entries: make(map[string]cachedResponse)}
+
+// LastRateLimit returns the most recent rate-limit window observed by any
+// GitHub adapter in this process. ok is false until a response was seen.
+func LastRateLimit() (RateLimit, bool) {
+	sharedIssueCache.mu.Lock()
+	defer sharedIssueCache.mu.Unlock()
+	rl := sharedIssueCache.rateLimit
+	return rl, !rl.UpdatedAt.IsZero()
+}
+
+// cachingTransport wraps an http.RoundTripper with the shared issue cache.
+type cachingTransport struct {
+	base  http.RoundTripper
+	token string
+	cache *issueCache
+}
+
+func newCachingTransport(base http.RoundTripper, token string) *cachingTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	sum := sha256.Sum256([]byte(token))
+	return &cachingTransport{base: base, token: hex.EncodeToString(sum[:8]), cache: sharedIssueCache}
+}
+
+func (t *cachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	cacheable := req.Method == http.MethodGet && strings.Contains(req.URL.Path, "/issues")
+	key := t.token + " " + req.URL.String()
+
+	var cached cachedResponse
+	var hit bool
+	if cacheable {
+		t.cache.mu.Lock()
+		cached, hit = t.cache.entries[key]
+		t.cache.mu.Unlock()
+		if hit {
+			req = req.Clone(req.Context())
+			req.Header.Set("If-None-Match", cached.etag)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	t.cache.observeRateLimit(resp.Header)
+
+	if !cacheable {
+		return resp, nil
+	}
+
+	if resp.StatusCode == http.StatusNotModified && hit {
+		resp.Body.Close()
+		header := cached.header.Clone()
+		header.Set("X-From-Cache", "1")
+		return &http.Response{
+			Status:        "200 OK",
+			StatusCode:    http.StatusOK,
+			Proto:         resp.Proto,
+			ProtoMajor:    resp.ProtoMajor,
+			ProtoMinor:    resp.ProtoMinor,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(cached.body)),
+			ContentLength: int64(len(cached.body)),
+			Request:       req,
+		}, nil
+	}
+
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || etag == "" {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	t.cache.store(key, cachedResponse{etag: etag, header: resp.Header.Clone(), body: body, storedAt: time.Now()})
+	return resp, nil
+}
+
+func (c *issueCache) store(key string, entry cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= maxCachedResponses {
+		var oldestKey string
+		var oldest time.Time
+		for k, v := range c.entries {
+			if oldestKey == "" || v.storedAt.Before(oldest) {
+				oldestKey, oldest = k, v.storedAt
+			}
+		}
+		delete(c.entries, oldestKey)
+	}
+	c.entries[key] = entry
+}
+
+func (c *issueCache) observeRateLimit(h http.Header) {
+	limit, err1 := strconv.Atoi(h.Get("X-RateLimit-Limit"))
+	remaining, err2 := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	if err1 != nil || err2 != nil {
+		return
+	}
+	rl := RateLimit{Limit: limit, Remaining: remaining, UpdatedAt: time.Now().UTC()}
+	if reset, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		rl.Reset = time.Unix(reset, 0).UTC()
+	}
+	c.mu.Lock()
+	c.rateLimit = rl
+	c.mu.Unlock()
+}
@@ -0,0 +1,86 @@
+package git
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-github/v60/github"
+)
+
+func TestCachingTransport_ConditionalIssueFetch(t *testing.T) {
+	var full, notModified int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/o/r/issues/7", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "4990")
+		w.Header().Set("X-RateLimit-Reset", "1700000000")
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		atomic.AddInt32(&full, 1)
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`{"id": 1, "number": 7, "title": "Cached issue", "body": "details"}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	transport := newCachingTransport(nil, "token")
+	transport.cache = &issueCache{entries: make(map[string]cachedResponse)}
+	client := github.NewClient(&http.Client{Transport: transport})
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	adapter := &GitHubAdapter{client: client, owner: "o", repo: "r"}
+
+	for i := 0; i < 3; i++ {
+		issue, err := adapter.GetIssue(context.Background(), "o", "r", 7)
+		if err != nil {
+			t.Fatalf("GetIssue #%d: %v", i, err)
+		}
+		if issue.Title != "Cached issue" || issue.Body != "details" {
+			t.Fatalf("GetIssue #%d returned %+v", i, issue)
+		}
+	}
+
+	if full != 1 || notModified != 2 {
+		t.Errorf("expected 1 full and 2 conditional responses, got %d and %d", full, notModified)
+	}
+	rl := transport.cache.rateLimit
+	if rl.Limit != 5000 || rl.Remaining != 4990 || rl.Reset.Unix() != 1700000000 {
+		t.Errorf("unexpected rate limit: %+v", rl)
+	}
+	if h := rl.Headroom(); h < 0.99 || h > 1 {
+		t.Errorf("unexpected headroom %f", h)
+	}
+}
+
+func TestCachingTransport_SkipsNonIssueRequests(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != "" {
+			t.Errorf("unexpected conditional header on %s", r.URL.Path)
+		}
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("ETag", `"x"`)
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	transport := newCachingTransport(nil, "token")
+	transport.cache = &issueCache{entries: make(map[string]cachedResponse)}
+	httpClient := &http.Client{Transport: transport}
+	for i := 0; i < 2; i++ {
+		resp, err := httpClient.Get(server.URL + "/repos/o/r/pulls/1")
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		resp.Body.Close()
+	}
+	if len(transport.cache.entries) != 0 || calls != 2 {
+		t.Errorf("expected no caching for non-issue paths, entries=%d calls=%d", len(transport.cache.entries), calls)
+	}
+}
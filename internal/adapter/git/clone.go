@@ -0,0 +1,70 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// CloneOptions make clones of big repositories cheaper. The zero value
+// clones everything.
+type CloneOptions struct {
+	// Depth truncates the history to this many commits; 0 clones all.
+	Depth int
+	// SingleBranch fetches only the default branch.
+	SingleBranch bool
+	// Sparse checks out only these directories (cone mode) of a partial
+	// clone that downloads file contents on demand.
+	Sparse []string
+	// Reference borrows objects from a local clone or mirror of the same
+	// repository, when it exists, instead of downloading them.
+	Reference string
+}
+
+// SetCloneOptions applies opts to the clones and fetches the adapter makes.
+func (g *GitHubAdapter) SetCloneOptions(opts CloneOptions) {
+	g.clone = opts
+}
+
+// cloneArgs returns the git arguments that clone url into dir.
+func (o CloneOptions) cloneArgs(url, dir string) []string {
+	args := []string{"clone"}
+	if o.Depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(o.Depth))
+	}
+	if o.SingleBranch {
+		args = append(args, "--single-branch")
+	} else if o.Depth > 0 {
+		// --depth implies --single-branch; task branches may start from any.
+		args = append(args, "--no-single-branch")
+	}
+	if len(o.Sparse) > 0 {
+		args = append(args, "--filter=blob:none", "--sparse")
+	}
+	if o.Reference != "" {
+		args = append(args, "--reference-if-able", o.Reference)
+	}
+	return append(args, url, dir)
+}
+
+// fetchArgs returns the git arguments that update a clone from origin,
+// keeping it as shallow as it was cloned.
+func (o CloneOptions) fetchArgs() []string {
+	args := []string{"fetch", "--prune"}
+	if o.Depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(o.Depth))
+	}
+	return append(args, "origin")
+}
+
+// applySparse restricts the checkout in dir to the sparse directories.
+func (o CloneOptions) applySparse(ctx context.Context, dir string) error {
+	if len(o.Sparse) == 0 {
+		return nil
+	}
+	args := append([]string{"sparse-checkout", "set", "--cone"}, o.Sparse...)
+	if _, err := runGit(ctx, dir, args...); err != nil {
+		return fmt.Errorf("sparse checkout: %w", err)
+	}
+	return nil
+}
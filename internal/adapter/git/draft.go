@@ -0,0 +1,90 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v60/github"
+	"github.com/rigdev/rig/internal/core"
+)
+
+var _ core.DraftPRAdapter = (*GitHubAdapter)(nil)
+
+// CreateDraftPR opens a draft pull request. Offline mode has no platform to
+// hold a draft, so it is reported as unsupported.
+func (g *GitHubAdapter) CreateDraftPR(ctx context.Context, base, head, title, body string) (*core.GitPullRequest, error) {
+	if g.patchDir != "" {
+		return nil, fmt.Errorf("draft pull requests are not available in offline mode")
+	}
+
+	created, _, err := g.client.PullRequests.Create(ctx, g.owner, g.repo, &github.NewPullRequest{
+		Title: github.String(title),
+		Body:  github.String(body),
+		Head:  github.String(head),
+		Base:  github.String(base),
+		Draft: github.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create draft pull request: %w", err)
+	}
+	return &core.GitPullRequest{
+		Number: created.GetNumber(),
+		URL:    created.GetHTMLURL(),
+		Title:  created.GetTitle(),
+	}, nil
+}
+
+// UpdatePR replaces the body of pull request number.
+func (g *GitHubAdapter) UpdatePR(ctx context.Context, number int, body string) error {
+	_, _, err := g.client.PullRequests.Edit(ctx, g.owner, g.repo, number, &github.PullRequest{
+		Body: github.String(body),
+	})
+	if err != nil {
+		return fmt.Errorf("update pull request #%d: %w", number, err)
+	}
+	return nil
+}
+
+// MarkReady converts draft pull request number to ready for review. The REST
+// API cannot do this, so it goes through the GraphQL mutation.
+func (g *GitHubAdapter) MarkReady(ctx context.Context, number int) error {
+	pr, _, err := g.client.PullRequests.Get(ctx, g.owner, g.repo, number)
+	if err != nil {
+		return fmt.Errorf("get pull request #%d: %w", number, err)
+	}
+	if !pr.GetDraft() {
+		return nil
+	}
+
+	query := map[string]interface{}{
+		"query":     `mutation($id: ID!) { markPullRequestReadyForReview(input: {pullRequestId: $id}) { pullRequest { isDraft } } }`,
+		"variables": map[string]string{"id": pr.GetNodeID()},
+	}
+	req, err := g.client.NewRequest("POST", g.graphQLURL(), query)
+	if err != nil {
+		return fmt.Errorf("build ready-for-review request: %w", err)
+	}
+	var resp struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if _, err := g.client.Do(ctx, req, &resp); err != nil {
+		return fmt.Errorf("mark pull request #%d ready: %w", number, err)
+	}
+	if len(resp.Errors) > 0 {
+		return fmt.Errorf("mark pull request #%d ready: %s", number, resp.Errors[0].Message)
+	}
+	return nil
+}
+
+// graphQLURL returns the GraphQL endpoint matching the REST base URL:
+// api.github.com/graphql, or <host>/api/graphql for GitHub Enterprise.
+func (g *GitHubAdapter) graphQLURL() string {
+	base := g.client.BaseURL.String()
+	if strings.HasSuffix(base, "/api/v3/") {
+		return strings.TrimSuffix(base, "v3/") + "graphql"
+	}
+	return base + "graphql"
+}
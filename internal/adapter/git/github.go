@@ -11,6 +11,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -28,18 +29,36 @@ type GitHubAdapter struct {
 	token     string
 	secret    string // webhook secret for HMAC verification
 	workspace string // local workspace path
+	mirrorURL string // clone/push remote replacing the GitHub remote when set
+	patchDir  string // offline mode: PRs are written here as patch files
+
+	workspaces *Workspaces // per-task worktrees when set
+	taskID     string      // task whose worktree workspace is
+
+	clone CloneOptions // depth, sparse paths and reference of clones
+
+	author        Author
+	resolveAuthor func() (Author, error) // author with the identity looked up, once
+	signing       Signing
 }
 
 // GitHub is the concrete adapter used by CLI wiring.
 type GitHub = GitHubAdapter
 
 var _ core.GitAdapter = (*GitHubAdapter)(nil)
+var _ core.IssueThreadReader = (*GitHubAdapter)(nil)
+var _ core.BranchChecker = (*GitHubAdapter)(nil)
+var _ core.BranchLister = (*GitHubAdapter)(nil)
+var _ core.TaskWorkspaces = (*GitHubAdapter)(nil)
 var _ WebhookGitAdapter = (*GitHubAdapter)(nil)
 
 // NewGitHub creates a new GitHubAdapter.
 // baseURL can be empty for github.com or a custom URL for GitHub Enterprise.
 func NewGitHub(owner, repo, token, secret, baseURL string) (*GitHub, error) {
-	httpClient := &http.Client{Timeout: defaultGitHubHTTPTimeout}
+	httpClient := &http.Client{
+		Timeout:   defaultGitHubHTTPTimeout,
+		Transport: newCachingTransport(nil, token),
+	}
 	client := github.NewClient(httpClient).WithAuthToken(token)
 
 	if baseURL != "" {
@@ -66,6 +85,16 @@ func NewGitHub(owner, repo, token, secret, baseURL string) (*GitHub, error) {
 	}, nil
 }
 
+// webHost returns the host serving the web pages and git repositories of
+// the REST base URL: github.com, or the GitHub Enterprise Server host.
+func (g *GitHubAdapter) webHost() string {
+	if g.client == nil {
+		return "github.com"
+	}
+	// api.github.com serves the API of github.com.
+	return strings.TrimPrefix(g.client.BaseURL.Host, "api.")
+}
+
 // ParseWebhook validates the HMAC-SHA256 signature and parses the webhook payload as an issue event.
 func (g *GitHubAdapter) ParseWebhook(body []byte, signature string) (*Issue, error) {
 	if g.secret != "" {
@@ -165,6 +194,59 @@ func (g *GitHubAdapter) GetIssue(ctx context.Context, owner, repo string, number
 	}, nil
 }
 
+// maxIssueComments caps how many comments GetIssueThread fetches.
+const maxIssueComments = 300
+
+// GetIssueThread fetches the issue description and its comments, oldest
+// first. Reads go through the ETag cache, so re-planning an unchanged issue
+// does not cost rate limit.
+func (g *GitHubAdapter) GetIssueThread(ctx context.Context, number int) (*core.IssueThread, error) {
+	issue, err := g.GetIssue(ctx, g.owner, g.repo, number)
+	if err != nil {
+		return nil, err
+	}
+
+	thread := &core.IssueThread{Title: issue.Title, Body: issue.Body}
+	opts := &github.IssueListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for len(thread.Comments) < maxIssueComments {
+		comments, resp, err := g.client.Issues.ListComments(ctx, g.owner, g.repo, number, opts)
+		if err != nil {
+			return nil, fmt.Errorf("list comments on #%d: %w", number, err)
+		}
+		for _, c := range comments {
+			thread.Comments = append(thread.Comments, core.IssueComment{
+				Author:    c.GetUser().GetLogin(),
+				Body:      c.GetBody(),
+				CreatedAt: c.GetCreatedAt().Time,
+			})
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return thread, nil
+}
+
+// FetchRateLimit queries the core API rate-limit window. The rate_limit
+// endpoint itself does not count against the limit.
+func (g *GitHubAdapter) FetchRateLimit(ctx context.Context) (*RateLimit, error) {
+	limits, _, err := g.client.RateLimit.Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get rate limit: %w", err)
+	}
+	rate := limits.GetCore()
+	if rate == nil {
+		return nil, fmt.Errorf("get rate limit: no core rate in response")
+	}
+	return &RateLimit{
+		Limit:     rate.Limit,
+		Remaining: rate.Remaining,
+		Reset:     rate.Reset.Time.UTC(),
+		UpdatedAt: time.Now().UTC(),
+	}, nil
+}
+
 // PostComment posts a comment on an issue or pull request.
 func (g *GitHubAdapter) PostComment(ctx context.Context, owner, repo string, number int, body string) error {
 	comment := &github.IssueComment{
@@ -179,6 +261,14 @@ func (g *GitHubAdapter) PostComment(ctx context.Context, owner, repo string, num
 
 // CreateBranch creates a new git branch in the local workspace.
 func (g *GitHubAdapter) CreateBranch(ctx context.Context, branchName string) error {
+	if g.taskID != "" {
+		// Another task's worktree may still have the branch checked out; the
+		// execution lock keeps two tasks of one issue from running at once.
+		if _, err := g.gitCmd(ctx, "checkout", "--ignore-other-worktrees", "-B", branchName); err != nil {
+			return fmt.Errorf("create branch %q: %w", branchName, err)
+		}
+		return nil
+	}
 	// If the branch already exists (e.g. from a previous failed run), delete it first.
 	if _, err := g.gitCmd(ctx, "checkout", "-b", branchName); err != nil {
 		// Detect current default branch, switch to it, delete old branch, then recreate.
@@ -201,7 +291,7 @@ func (g *GitHubAdapter) CreateBranch(ctx context.Context, branchName string) err
 // CommitAndPush stages file changes, commits, and pushes to the remote.
 func (g *GitHubAdapter) CommitAndPush(ctx context.Context, changes []core.GitFileChange, message string) error {
 	for _, change := range changes {
-		absPath := filepath.Join(g.workspace, change.Path)
+		absPath := filepath.Join(g.workspace, filepath.FromSlash(change.Path))
 
 		switch change.Action {
 		case "create", "update", "modify":
@@ -224,10 +314,17 @@ func (g *GitHubAdapter) CommitAndPush(ctx context.Context, changes []core.GitFil
 		}
 	}
 
-	if _, err := g.gitCmd(ctx, "commit", "-m", message); err != nil {
+	args, err := g.commitConfig()
+	if err != nil {
+		return fmt.Errorf("git commit: %w", err)
+	}
+	if _, err := g.gitCmd(ctx, append(args, "commit", "-m", message)...); err != nil {
 		return fmt.Errorf("git commit: %w", err)
 	}
 
+	if !g.canPush() {
+		return nil
+	}
 	if _, err := g.gitCmd(ctx, "push", "origin", "HEAD"); err != nil {
 		return fmt.Errorf("git push: %w", err)
 	}
@@ -237,6 +334,10 @@ func (g *GitHubAdapter) CommitAndPush(ctx context.Context, changes []core.GitFil
 
 // CreatePR creates a pull request on the remote repository.
 func (g *GitHubAdapter) CreatePR(ctx context.Context, base, head, title, body string) (*core.GitPullRequest, error) {
+	if g.patchDir != "" {
+		return g.writePatch(ctx, base, head, title, body)
+	}
+
 	pr := &github.NewPullRequest{
 		Title: github.String(title),
 		Body:  github.String(body),
@@ -256,41 +357,157 @@ func (g *GitHubAdapter) CreatePR(ctx context.Context, base, head, title, body st
 	}, nil
 }
 
+var _ core.PRFinder = (*GitHubAdapter)(nil)
+
+// FindPR returns the open pull request from branch head, or nil if there is
+// none. Offline mode opens no pull requests, so it finds none.
+func (g *GitHubAdapter) FindPR(ctx context.Context, head string) (*core.GitPullRequest, error) {
+	if g.patchDir != "" {
+		return nil, nil
+	}
+	prs, _, err := g.client.PullRequests.List(ctx, g.owner, g.repo, &github.PullRequestListOptions{
+		State: "open",
+		Head:  g.owner + ":" + head,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list pull requests of %s: %w", head, err)
+	}
+	if len(prs) == 0 {
+		return nil, nil
+	}
+	return &core.GitPullRequest{
+		Number: prs[0].GetNumber(),
+		URL:    prs[0].GetHTMLURL(),
+		Title:  prs[0].GetTitle(),
+	}, nil
+}
+
+var _ core.PRLabeler = (*GitHubAdapter)(nil)
+
+// AddLabels adds labels to pull request number. Offline mode has no pull
+// request to label.
+func (g *GitHubAdapter) AddLabels(ctx context.Context, number int, labels []string) error {
+	if g.patchDir != "" {
+		return fmt.Errorf("labels are not available in offline mode")
+	}
+	if _, _, err := g.client.Issues.AddLabelsToIssue(ctx, g.owner, g.repo, number, labels); err != nil {
+		return fmt.Errorf("label pull request #%d: %w", number, err)
+	}
+	return nil
+}
+
+var _ core.PRReviewerRequester = (*GitHubAdapter)(nil)
+
+// RequestReviewers requests reviews of pull request number from users and,
+// for reviewers written org/team, teams of the repository's organization.
+func (g *GitHubAdapter) RequestReviewers(ctx context.Context, number int, reviewers []string) error {
+	if g.patchDir != "" {
+		return fmt.Errorf("reviewers are not available in offline mode")
+	}
+	var req github.ReviewersRequest
+	for _, r := range reviewers {
+		r = strings.TrimPrefix(r, "@")
+		if _, team, ok := strings.Cut(r, "/"); ok {
+			req.TeamReviewers = append(req.TeamReviewers, team)
+		} else {
+			req.Reviewers = append(req.Reviewers, r)
+		}
+	}
+	if _, _, err := g.client.PullRequests.RequestReviewers(ctx, g.owner, g.repo, number, req); err != nil {
+		return fmt.Errorf("request reviewers on pull request #%d: %w", number, err)
+	}
+	return nil
+}
+
+var _ core.PRAssigner = (*GitHubAdapter)(nil)
+
+// Assign assigns pull request number to the users assignees.
+func (g *GitHubAdapter) Assign(ctx context.Context, number int, assignees []string) error {
+	if g.patchDir != "" {
+		return fmt.Errorf("assignees are not available in offline mode")
+	}
+	users := make([]string, len(assignees))
+	for i, a := range assignees {
+		users[i] = strings.TrimPrefix(a, "@")
+	}
+	if _, _, err := g.client.Issues.AddAssignees(ctx, g.owner, g.repo, number, users); err != nil {
+		return fmt.Errorf("assign pull request #%d: %w", number, err)
+	}
+	return nil
+}
+
 // CloneOrPull clones a repository or pulls latest if already cloned.
 func (g *GitHubAdapter) CloneOrPull(ctx context.Context, owner, repo, token string) error {
 	if err := os.MkdirAll(filepath.Dir(g.workspace), 0o755); err != nil {
 		return fmt.Errorf("create workspace parent dir: %w", err)
 	}
 
-	cloneURL := fmt.Sprintf("https://x-access-token:%s@github.com/%s/%s.git", token, owner, repo)
+	cloneURL := fmt.Sprintf("https://x-access-token:%s@%s/%s/%s.git", token, g.webHost(), owner, repo)
+	if g.mirrorURL != "" {
+		cloneURL = g.mirrorURL
+	}
+	if g.taskID != "" {
+		_, err := g.workspaces.Checkout(ctx, owner, repo, cloneURL, g.taskID, g.clone)
+		return err
+	}
 
 	// Check if workspace already exists with a .git directory.
 	gitDir := filepath.Join(g.workspace, ".git")
 	if info, err := os.Stat(gitDir); err == nil && info.IsDir() {
 		// Already cloned — pull latest.
-		if _, err := g.gitCmd(ctx, "pull", "--ff-only"); err != nil {
+		if g.mirrorURL != "" {
+			if _, err := g.gitCmd(ctx, "remote", "set-url", "origin", g.mirrorURL); err != nil {
+				return fmt.Errorf("point origin at mirror: %w", err)
+			}
+		}
+		args := []string{"pull", "--ff-only"}
+		if g.clone.Depth > 0 {
+			args = append(args, "--depth", strconv.Itoa(g.clone.Depth))
+		}
+		if _, err := g.gitCmd(ctx, args...); err != nil {
 			return fmt.Errorf("git pull: %w", err)
 		}
 		return nil
 	}
 
 	// Clone fresh.
-	c := exec.CommandContext(ctx, "git", "clone", cloneURL, g.workspace)
-	c.WaitDelay = 500 * time.Millisecond
-	c.Cancel = func() error {
-		return c.Process.Kill()
+	if _, err := runGit(ctx, "", g.clone.cloneArgs(cloneURL, g.workspace)...); err != nil {
+		return fmt.Errorf("git clone: %w", err)
 	}
+	return g.clone.applySparse(ctx, g.workspace)
+}
 
-	output, err := c.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("git clone: %w (output: %s)", err, string(output))
+// CheckoutBase detaches the workspace at branch, fetched from the remote
+// when there is one, so the next branch created starts from it.
+func (g *GitHubAdapter) CheckoutBase(ctx context.Context, branch string) error {
+	ref := branch
+	if g.canPush() {
+		args := []string{"fetch"}
+		if g.clone.Depth > 0 {
+			args = append(args, "--depth", strconv.Itoa(g.clone.Depth))
+		}
+		if _, err := g.gitCmd(ctx, append(args, "origin", branch)...); err != nil {
+			return fmt.Errorf("fetch %s: %w", branch, err)
+		}
+		ref = "FETCH_HEAD"
+	}
+	if _, err := g.gitCmd(ctx, "checkout", "--detach", ref); err != nil {
+		return fmt.Errorf("checkout %s: %w", branch, err)
 	}
-
 	return nil
 }
 
-// Cleanup removes the local workspace directory.
+// Cleanup removes the local workspace directory. With per-task worktrees
+// only the task's worktree is removed and the shared clone is kept.
 func (g *GitHubAdapter) Cleanup() error {
+	if g.workspaces != nil {
+		if g.taskID == "" {
+			return nil
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		return g.workspaces.Remove(ctx, g.owner, g.repo, g.taskID)
+	}
 	if g.workspace == "" {
 		return nil
 	}
@@ -299,11 +516,80 @@ func (g *GitHubAdapter) Cleanup() error {
 
 // CleanupBranch deletes a remote branch (best-effort, ignores errors).
 func (g *GitHubAdapter) CleanupBranch(ctx context.Context, branchName string) {
-	if g.workspace == "" || branchName == "" {
+	if branchName == "" || !g.canPush() {
 		return
 	}
 	// Delete remote branch; ignore errors (it may not have been pushed).
-	g.gitCmd(ctx, "push", "origin", "--delete", branchName)
+	if g.workspace != "" {
+		if _, err := os.Stat(filepath.Join(g.workspace, ".git")); err == nil {
+			g.gitCmd(ctx, "push", "origin", "--delete", branchName)
+			return
+		}
+	}
+	// No clone to push from (e.g. the branch sweeper in serve mode).
+	if g.mirrorURL == "" {
+		g.client.Git.DeleteRef(ctx, g.owner, g.repo, "heads/"+branchName)
+	}
+}
+
+// ListBranches returns the remote branch names starting with prefix.
+func (g *GitHubAdapter) ListBranches(ctx context.Context, prefix string) ([]string, error) {
+	var names []string
+	if g.mirrorURL != "" {
+		out, err := exec.CommandContext(ctx, "git", "ls-remote", "--heads", g.mirrorURL).Output()
+		if err != nil {
+			return nil, fmt.Errorf("git ls-remote: %w", err)
+		}
+		for _, line := range strings.Split(string(out), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) != 2 {
+				continue
+			}
+			name := strings.TrimPrefix(fields[1], "refs/heads/")
+			if strings.HasPrefix(name, prefix) {
+				names = append(names, name)
+			}
+		}
+		return names, nil
+	}
+
+	opts := &github.BranchListOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		branches, resp, err := g.client.Repositories.ListBranches(ctx, g.owner, g.repo, opts)
+		if err != nil {
+			return nil, fmt.Errorf("list branches: %w", err)
+		}
+		for _, b := range branches {
+			if strings.HasPrefix(b.GetName(), prefix) {
+				names = append(names, b.GetName())
+			}
+		}
+		if resp.NextPage == 0 {
+			return names, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// BranchExists reports whether branch exists on the remote. With a mirror
+// configured the mirror is asked instead of the GitHub API.
+func (g *GitHubAdapter) BranchExists(ctx context.Context, branch string) (bool, error) {
+	if g.mirrorURL != "" {
+		out, err := exec.CommandContext(ctx, "git", "ls-remote", "--heads", g.mirrorURL, branch).Output()
+		if err != nil {
+			return false, fmt.Errorf("git ls-remote %s: %w", branch, err)
+		}
+		return strings.TrimSpace(string(out)) != "", nil
+	}
+
+	_, resp, err := g.client.Repositories.GetBranch(ctx, g.owner, g.repo, branch, 1)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("get branch %s: %w", branch, err)
+	}
+	return true, nil
 }
 
 // GetWorkspace returns the local workspace path.
@@ -322,8 +608,13 @@ func (g *GitHubAdapter) GetHeadSHA(ctx context.Context) (string, error) {
 
 // gitCmd runs a git command in the workspace directory.
 func (g *GitHubAdapter) gitCmd(ctx context.Context, args ...string) (string, error) {
+	return runGit(ctx, g.workspace, args...)
+}
+
+// runGit runs a git command in dir and returns its combined output.
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
 	c := exec.CommandContext(ctx, "git", args...)
-	c.Dir = g.workspace
+	c.Dir = dir
 	c.WaitDelay = 500 * time.Millisecond
 	c.Cancel = func() error {
 		return c.Process.Kill()
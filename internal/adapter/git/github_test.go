@@ -7,12 +7,14 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
@@ -416,6 +418,276 @@ func TestGitHubParseWebhook(t *testing.T) {
 	}
 }
 
+func TestGitHubGetIssueThread(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/test-owner/test-repo/issues/42", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id": 100, "number": 42, "title": "Fix the bug", "body": "Acceptance criteria: ..."}`))
+	})
+	mux.HandleFunc("/repos/test-owner/test-repo/issues/42/comments", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			w.Write([]byte(`[{"body": "second", "user": {"login": "bob"}, "created_at": "2025-01-16T10:00:00Z"}]`))
+			return
+		}
+		w.Header().Set("Link", fmt.Sprintf(`<%s?page=2>; rel="next"`, "http://"+r.Host+r.URL.Path))
+		w.Write([]byte(`[{"body": "first", "user": {"login": "alice"}, "created_at": "2025-01-15T10:00:00Z"}]`))
+	})
+	adapter, _ := newTestGitHub(t, mux)
+
+	thread, err := adapter.GetIssueThread(context.Background(), 42)
+	if err != nil {
+		t.Fatalf("GetIssueThread: %v", err)
+	}
+	if thread.Body != "Acceptance criteria: ..." {
+		t.Errorf("unexpected body %q", thread.Body)
+	}
+	if len(thread.Comments) != 2 || thread.Comments[0].Author != "alice" || thread.Comments[1].Body != "second" {
+		t.Errorf("unexpected comments %+v", thread.Comments)
+	}
+}
+
+func TestGitHubBranchExists(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/test-owner/test-repo/branches/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.TrimPrefix(r.URL.Path, "/repos/test-owner/test-repo/branches/") != "rig/issue-1" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "Branch not found"}`))
+			return
+		}
+		w.Write([]byte(`{"name": "rig/issue-1"}`))
+	})
+	adapter, _ := newTestGitHub(t, mux)
+
+	for branch, want := range map[string]bool{"rig/issue-1": true, "rig/issue-2": false} {
+		got, err := adapter.BranchExists(context.Background(), branch)
+		if err != nil {
+			t.Fatalf("BranchExists(%s): %v", branch, err)
+		}
+		if got != want {
+			t.Errorf("BranchExists(%s) = %v, want %v", branch, got, want)
+		}
+	}
+}
+
+func TestGitHubFindPR(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/test-owner/test-repo/pulls", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("state") != "open" {
+			t.Errorf("state = %q", r.URL.Query().Get("state"))
+		}
+		if r.URL.Query().Get("head") != "test-owner:rig/issue-1" {
+			w.Write([]byte(`[]`))
+			return
+		}
+		w.Write([]byte(`[{"number": 7, "html_url": "https://github.com/test-owner/test-repo/pull/7", "title": "rig: fix"}]`))
+	})
+	adapter, _ := newTestGitHub(t, mux)
+
+	pr, err := adapter.FindPR(context.Background(), "rig/issue-1")
+	if err != nil || pr == nil || pr.Number != 7 || !strings.HasSuffix(pr.URL, "/pull/7") {
+		t.Fatalf("FindPR = %+v, %v", pr, err)
+	}
+	if pr, err := adapter.FindPR(context.Background(), "rig/issue-2"); err != nil || pr != nil {
+		t.Errorf("FindPR of a branch without a PR = %+v, %v", pr, err)
+	}
+}
+
+func TestGitHubDraftPRLifecycle(t *testing.T) {
+	var created, edited map[string]interface{}
+	var graphQL string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/test-owner/test-repo/pulls", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&created)
+		w.Write([]byte(`{"number": 7, "html_url": "https://github.com/test-owner/test-repo/pull/7", "draft": true}`))
+	})
+	mux.HandleFunc("/repos/test-owner/test-repo/pulls/7", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPatch {
+			json.NewDecoder(r.Body).Decode(&edited)
+		}
+		w.Write([]byte(`{"number": 7, "node_id": "PR_kw7", "draft": true}`))
+	})
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		graphQL = string(body)
+		w.Write([]byte(`{"data": {"markPullRequestReadyForReview": {"pullRequest": {"isDraft": false}}}}`))
+	})
+	adapter, _ := newTestGitHub(t, mux)
+	ctx := context.Background()
+
+	pr, err := adapter.CreateDraftPR(ctx, "main", "rig/issue-1", "rig: fix", "body")
+	if err != nil {
+		t.Fatalf("CreateDraftPR: %v", err)
+	}
+	if pr.Number != 7 || created["draft"] != true {
+		t.Fatalf("expected draft PR #7, got %+v (request %v)", pr, created)
+	}
+	if err := adapter.UpdatePR(ctx, 7, "new body"); err != nil {
+		t.Fatalf("UpdatePR: %v", err)
+	}
+	if edited["body"] != "new body" {
+		t.Errorf("unexpected edit request %v", edited)
+	}
+	if err := adapter.MarkReady(ctx, 7); err != nil {
+		t.Fatalf("MarkReady: %v", err)
+	}
+	if !strings.Contains(graphQL, "markPullRequestReadyForReview") || !strings.Contains(graphQL, "PR_kw7") {
+		t.Errorf("unexpected GraphQL request %s", graphQL)
+	}
+}
+
+func TestGitHubAutoMerge(t *testing.T) {
+	var merged map[string]interface{}
+	var closedState string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/test-owner/test-repo/pulls/7", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"number": 7, "state": "open", "mergeable_state": "blocked", "head": {"sha": "abc"}}`))
+	})
+	mux.HandleFunc("/repos/test-owner/test-repo/pulls/8", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"number": 8, "state": "open", "mergeable_state": "clean"}`))
+	})
+	mux.HandleFunc("/repos/test-owner/test-repo/commits/abc/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"state": "pending", "statuses": []}`))
+	})
+	mux.HandleFunc("/repos/test-owner/test-repo/commits/abc/check-runs", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"total_count": 1, "check_runs": [{"name": "ci", "status": "completed", "conclusion": "failure"}]}`))
+	})
+	mux.HandleFunc("/repos/test-owner/test-repo/pulls/8/merge", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&merged)
+		w.Write([]byte(`{"merged": true, "sha": "def"}`))
+	})
+	mux.HandleFunc("/repos/test-owner/test-repo/issues/42/comments", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": 1}`))
+	})
+	mux.HandleFunc("/repos/test-owner/test-repo/issues/42", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		closedState, _ = req["state"].(string)
+		w.Write([]byte(`{"number": 42, "state": "closed"}`))
+	})
+	adapter, _ := newTestGitHub(t, mux)
+	ctx := context.Background()
+
+	status, err := adapter.PRMergeStatus(ctx, 7)
+	if err != nil {
+		t.Fatalf("PRMergeStatus: %v", err)
+	}
+	if status.Readiness != core.MergeBlocked || !strings.Contains(status.Reason, `"ci"`) {
+		t.Errorf("expected blocked by failed check run, got %+v", status)
+	}
+
+	status, err = adapter.PRMergeStatus(ctx, 8)
+	if err != nil {
+		t.Fatalf("PRMergeStatus: %v", err)
+	}
+	if status.Readiness != core.MergeReady {
+		t.Errorf("expected ready, got %+v", status)
+	}
+	if err := adapter.MergePR(ctx, 8, "squash", "rig: fix (#8)"); err != nil {
+		t.Fatalf("MergePR: %v", err)
+	}
+	if merged["merge_method"] != "squash" || merged["commit_title"] != "rig: fix (#8)" {
+		t.Errorf("unexpected merge request %v", merged)
+	}
+
+	if err := adapter.CloseIssue(ctx, 42, "Resolved"); err != nil {
+		t.Fatalf("CloseIssue: %v", err)
+	}
+	if closedState != "closed" {
+		t.Errorf("expected issue closed, got state %q", closedState)
+	}
+}
+
+func TestGitHubCheckPermissions(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(w http.ResponseWriter, r *http.Request)
+		missing []string
+	}{
+		{
+			name: "classic token with repo scope",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-OAuth-Scopes", "repo, workflow")
+				w.Write([]byte(`{"private": true, "has_issues": true, "permissions": {"pull": true, "push": true}}`))
+			},
+		},
+		{
+			name: "classic token without repo scope on private repo",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-OAuth-Scopes", "read:org")
+				w.Write([]byte(`{"private": true, "has_issues": true, "permissions": {"pull": true, "push": true}}`))
+			},
+			missing: []string{CapabilityReadIssues, CapabilityPush, CapabilityOpenPR},
+		},
+		{
+			name: "fine-grained token with read-only access",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/issues") {
+					w.Write([]byte(`[]`))
+					return
+				}
+				w.Write([]byte(`{"has_issues": true, "permissions": {"pull": true, "push": false}}`))
+			},
+			missing: []string{CapabilityPush, CapabilityOpenPR},
+		},
+		{
+			name: "fine-grained token without issues permission",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/issues") {
+					w.WriteHeader(http.StatusForbidden)
+					w.Write([]byte(`{"message": "Resource not accessible by personal access token"}`))
+					return
+				}
+				w.Write([]byte(`{"has_issues": true, "permissions": {"pull": true, "push": true}}`))
+			},
+			missing: []string{CapabilityReadIssues},
+		},
+		{
+			name: "repository not visible",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"message": "Not Found"}`))
+			},
+			missing: []string{"see the repository"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/repos/test-owner/test-repo", tt.handler)
+			mux.HandleFunc("/repos/test-owner/test-repo/issues", tt.handler)
+			adapter, _ := newTestGitHub(t, mux)
+
+			report, err := adapter.CheckPermissions(context.Background())
+			if err != nil {
+				t.Fatalf("CheckPermissions: %v", err)
+			}
+			var got []string
+			for _, m := range report.Missing {
+				got = append(got, m.Capability)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.missing, ",") {
+				t.Errorf("missing = %v, want %v\n%s", got, tt.missing, report)
+			}
+			if report.OK() != (len(tt.missing) == 0) {
+				t.Errorf("OK() = %v with missing %v", report.OK(), got)
+			}
+		})
+	}
+
+	t.Run("invalid token", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/repos/test-owner/test-repo", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"message": "Bad credentials"}`))
+		})
+		adapter, _ := newTestGitHub(t, mux)
+		if _, err := adapter.CheckPermissions(context.Background()); err == nil {
+			t.Fatal("expected error for invalid token")
+		}
+	})
+}
+
 // --- Local git operation tests ---
 
 // initBareRepo creates a bare git repo and a working clone in a temp dir.
@@ -585,6 +857,34 @@ func TestGitLocalCommitAndPushInvalidAction(t *testing.T) {
 	}
 }
 
+func TestGitLocalCheckoutBase(t *testing.T) {
+	workDir, _ := initBareRepo(t)
+	adapter := &GitHubAdapter{workspace: workDir}
+	ctx := context.Background()
+
+	if err := adapter.CreateBranch(ctx, "rig/issue-7-1"); err != nil {
+		t.Fatalf("CreateBranch failed: %v", err)
+	}
+	changes := []core.GitFileChange{{Path: "part1.go", Content: "package part\n", Action: "create"}}
+	if err := adapter.CommitAndPush(ctx, changes, "part 1"); err != nil {
+		t.Fatalf("CommitAndPush failed: %v", err)
+	}
+	run(t, workDir, "git", "checkout", "-")
+
+	if err := adapter.CheckoutBase(ctx, "rig/issue-7-1"); err != nil {
+		t.Fatalf("CheckoutBase failed: %v", err)
+	}
+	if err := adapter.CreateBranch(ctx, "rig/issue-7-2"); err != nil {
+		t.Fatalf("CreateBranch failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "part1.go")); err != nil {
+		t.Errorf("expected the next branch to start from the part before: %v", err)
+	}
+	if err := adapter.CheckoutBase(ctx, "rig/missing"); err == nil {
+		t.Error("expected an error for a missing branch")
+	}
+}
+
 func TestGitLocalCloneOrPull(t *testing.T) {
 	baseDir := t.TempDir()
 	bareDir := filepath.Join(baseDir, "origin.git")
@@ -690,6 +990,22 @@ func TestNewGitHubEnterprise(t *testing.T) {
 	}
 }
 
+func TestGitHubWebHost(t *testing.T) {
+	for baseURL, want := range map[string]string{
+		"":                                 "github.com",
+		"https://ghe.example.com/api/v3/":  "ghe.example.com",
+		"https://ghe.example.com:8443/api": "ghe.example.com:8443",
+	} {
+		adapter, err := NewGitHub("owner", "repo", "token", "", baseURL)
+		if err != nil {
+			t.Fatalf("NewGitHub(%q): %v", baseURL, err)
+		}
+		if got := adapter.webHost(); got != want {
+			t.Errorf("webHost for %q = %q, want %q", baseURL, got, want)
+		}
+	}
+}
+
 // --- Verify interface compliance ---
 
 var _ core.GitAdapter = (*GitHubAdapter)(nil)
@@ -744,3 +1060,107 @@ func TestGitCmdTimeout(t *testing.T) {
 		t.Fatal("expected timeout error, got nil")
 	}
 }
+
+func TestGitMirrorOfflinePatch(t *testing.T) {
+	_, mirrorDir := initBareRepo(t)
+	workDir := filepath.Join(t.TempDir(), "workspace")
+	patchDir := filepath.Join(t.TempDir(), "patches")
+
+	adapter := &GitHubAdapter{workspace: workDir}
+	adapter.SetMirror(mirrorDir)
+	adapter.SetOffline(patchDir)
+
+	ctx := context.Background()
+	if err := adapter.CloneOrPull(ctx, "owner", "repo", "token"); err != nil {
+		t.Fatalf("clone from mirror: %v", err)
+	}
+	run(t, workDir, "git", "config", "user.email", "test@rig.dev")
+	run(t, workDir, "git", "config", "user.name", "Rig Test")
+
+	if err := adapter.CreateBranch(ctx, "rig/issue-7"); err != nil {
+		t.Fatalf("create branch: %v", err)
+	}
+	changes := []core.GitFileChange{{Path: "fix.go", Content: "package fix\n", Action: "create"}}
+	if err := adapter.CommitAndPush(ctx, changes, "fix: issue 7"); err != nil {
+		t.Fatalf("commit and push: %v", err)
+	}
+	if out := run(t, mirrorDir, "git", "branch", "--list", "rig/issue-7"); !strings.Contains(out, "rig/issue-7") {
+		t.Errorf("expected branch pushed to mirror, got %q", out)
+	}
+
+	base := strings.TrimSpace(run(t, mirrorDir, "git", "symbolic-ref", "--short", "HEAD"))
+	pr, err := adapter.CreatePR(ctx, base, "rig/issue-7", "rig: fix", "Closes #7")
+	if err != nil {
+		t.Fatalf("create PR offline: %v", err)
+	}
+	patchPath := filepath.Join(patchDir, "rig-issue-7.patch")
+	if pr.URL != fileURL(patchPath) {
+		t.Errorf("unexpected PR URL %q", pr.URL)
+	}
+	patch, err := os.ReadFile(patchPath)
+	if err != nil {
+		t.Fatalf("read patch: %v", err)
+	}
+	if !strings.Contains(string(patch), "fix: issue 7") || !strings.Contains(string(patch), "+package fix") {
+		t.Errorf("unexpected patch content:\n%s", patch)
+	}
+	if desc, err := os.ReadFile(filepath.Join(patchDir, "rig-issue-7.md")); err != nil || !strings.Contains(string(desc), "Closes #7") {
+		t.Errorf("expected PR description next to patch, got %q (%v)", desc, err)
+	}
+}
+
+func TestFileURL(t *testing.T) {
+	cases := map[string]string{"/srv/rig/patches/a.patch": "file:///srv/rig/patches/a.patch"}
+	if runtime.GOOS == "windows" {
+		cases = map[string]string{`C:\rig\patches\a.patch`: "file:///C:/rig/patches/a.patch"}
+	}
+	for path, want := range cases {
+		if got := fileURL(path); got != want {
+			t.Errorf("fileURL(%s) = %s, want %s", path, got, want)
+		}
+	}
+}
+
+func TestGitHubRequestReviewers(t *testing.T) {
+	var got github.ReviewersRequest
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/test-owner/test-repo/pulls/7/requested_reviewers", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("method = %s, want POST", r.Method)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"number": 7}`)
+	})
+	g, _ := newTestGitHub(t, mux)
+
+	if err := g.RequestReviewers(context.Background(), 7, []string{"@alice", "@test-owner/backend", "bob"}); err != nil {
+		t.Fatalf("RequestReviewers: %v", err)
+	}
+	if strings.Join(got.Reviewers, ",") != "alice,bob" || strings.Join(got.TeamReviewers, ",") != "backend" {
+		t.Errorf("requested %+v", got)
+	}
+}
+
+func TestGitHubAssign(t *testing.T) {
+	var got struct {
+		Assignees []string `json:"assignees"`
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/test-owner/test-repo/issues/7/assignees", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("method = %s, want POST", r.Method)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"number": 7}`)
+	})
+	g, _ := newTestGitHub(t, mux)
+
+	if err := g.Assign(context.Background(), 7, []string{"@alice", "bob"}); err != nil {
+		t.Fatalf("Assign: %v", err)
+	}
+	if strings.Join(got.Assignees, ",") != "alice,bob" {
+		t.Errorf("assigned %v", got.Assignees)
+	}
+}
@@ -0,0 +1,118 @@
+package git
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-github/v60/github"
+	"github.com/rigdev/rig/internal/core"
+)
+
+var _ core.PRMerger = (*GitHubAdapter)(nil)
+var _ core.IssueCloser = (*GitHubAdapter)(nil)
+
+// failedConclusions are check run conclusions that block a merge.
+var failedConclusions = map[string]bool{
+	"failure":         true,
+	"timed_out":       true,
+	"cancelled":       true,
+	"action_required": true,
+}
+
+// PRMergeStatus reports whether pull request number can be merged. GitHub's
+// mergeable_state already accounts for branch protection (required checks
+// and reviews); check runs and statuses only explain why it is blocked.
+func (g *GitHubAdapter) PRMergeStatus(ctx context.Context, number int) (*core.PRMergeStatus, error) {
+	if g.patchDir != "" {
+		return &core.PRMergeStatus{Readiness: core.MergeBlocked, Reason: "offline mode has no pull request to merge"}, nil
+	}
+
+	pr, _, err := g.client.PullRequests.Get(ctx, g.owner, g.repo, number)
+	if err != nil {
+		return nil, fmt.Errorf("get pull request #%d: %w", number, err)
+	}
+	switch {
+	case pr.GetMerged():
+		return &core.PRMergeStatus{Merged: true}, nil
+	case pr.GetState() == "closed":
+		return &core.PRMergeStatus{Closed: true}, nil
+	case pr.GetDraft():
+		return &core.PRMergeStatus{Readiness: core.MergePending, Reason: "pull request is a draft"}, nil
+	}
+
+	switch pr.GetMergeableState() {
+	case "clean", "unstable", "has_hooks":
+		return &core.PRMergeStatus{Readiness: core.MergeReady}, nil
+	case "dirty":
+		return &core.PRMergeStatus{Readiness: core.MergeBlocked, Reason: "merge conflicts with the base branch"}, nil
+	case "blocked":
+		if reason, err := g.failedChecks(ctx, pr.GetHead().GetSHA()); err != nil {
+			return nil, err
+		} else if reason != "" {
+			return &core.PRMergeStatus{Readiness: core.MergeBlocked, Reason: reason}, nil
+		}
+		return &core.PRMergeStatus{Readiness: core.MergePending, Reason: "waiting for required checks or reviews"}, nil
+	default:
+		// unknown (still being computed) or behind.
+		return &core.PRMergeStatus{Readiness: core.MergePending, Reason: "mergeable state " + pr.GetMergeableState()}, nil
+	}
+}
+
+// failedChecks returns a description of the first failed status or check
+// run on sha, or "" if none failed.
+func (g *GitHubAdapter) failedChecks(ctx context.Context, sha string) (string, error) {
+	combined, _, err := g.client.Repositories.GetCombinedStatus(ctx, g.owner, g.repo, sha, nil)
+	if err != nil {
+		return "", fmt.Errorf("get combined status: %w", err)
+	}
+	for _, s := range combined.Statuses {
+		if s.GetState() == "failure" || s.GetState() == "error" {
+			return fmt.Sprintf("status check %q %s", s.GetContext(), s.GetState()), nil
+		}
+	}
+
+	runs, _, err := g.client.Checks.ListCheckRunsForRef(ctx, g.owner, g.repo, sha, &github.ListCheckRunsOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+	})
+	if err != nil {
+		return "", fmt.Errorf("list check runs: %w", err)
+	}
+	for _, run := range runs.CheckRuns {
+		if failedConclusions[run.GetConclusion()] {
+			return fmt.Sprintf("check run %q %s", run.GetName(), run.GetConclusion()), nil
+		}
+	}
+	return "", nil
+}
+
+// MergePR merges pull request number with method (merge, squash or rebase).
+func (g *GitHubAdapter) MergePR(ctx context.Context, number int, method, commitTitle string) error {
+	opts := &github.PullRequestOptions{MergeMethod: method}
+	if method != "rebase" {
+		opts.CommitTitle = commitTitle
+	}
+	result, _, err := g.client.PullRequests.Merge(ctx, g.owner, g.repo, number, "", opts)
+	if err != nil {
+		return fmt.Errorf("merge pull request #%d: %w", number, err)
+	}
+	if !result.GetMerged() {
+		return fmt.Errorf("merge pull request #%d: %s", number, result.GetMessage())
+	}
+	return nil
+}
+
+// CloseIssue posts comment on issue number and closes it.
+func (g *GitHubAdapter) CloseIssue(ctx context.Context, number int, comment string) error {
+	if comment != "" {
+		if err := g.PostComment(ctx, g.owner, g.repo, number, comment); err != nil {
+			return err
+		}
+	}
+	_, _, err := g.client.Issues.Edit(ctx, g.owner, g.repo, number, &github.IssueRequest{
+		State: github.String("closed"),
+	})
+	if err != nil {
+		return fmt.Errorf("close issue #%d: %w", number, err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rigdev/rig/internal/core"
+)
+
+// SetMirror makes the adapter clone from and push to mirrorURL instead of
+// github.com. Credentials for the mirror come from the URL or the local git
+// credential configuration.
+func (g *GitHubAdapter) SetMirror(mirrorURL string) {
+	g.mirrorURL = mirrorURL
+}
+
+// SetOffline replaces pull request creation with a patch file written to
+// patchDir. Without a mirror, commits stay in the local workspace.
+func (g *GitHubAdapter) SetOffline(patchDir string) {
+	g.patchDir = patchDir
+}
+
+// canPush reports whether the workspace has a reachable remote to push to.
+func (g *GitHubAdapter) canPush() bool {
+	return g.patchDir == "" || g.mirrorURL != ""
+}
+
+// writePatch exports the commits on head that are not on base as a
+// `git am`-compatible patch, with the PR description alongside it.
+func (g *GitHubAdapter) writePatch(ctx context.Context, base, head, title, body string) (*core.GitPullRequest, error) {
+	patch, err := g.gitCmd(ctx, "format-patch", "--stdout", "origin/"+base+"..HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("format patch: %w", err)
+	}
+	if strings.TrimSpace(patch) == "" {
+		return nil, fmt.Errorf("format patch: no commits between %s and %s", base, head)
+	}
+
+	dir, err := filepath.Abs(g.patchDir)
+	if err != nil {
+		return nil, fmt.Errorf("resolve patch dir: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create patch dir: %w", err)
+	}
+
+	name := strings.ReplaceAll(head, "/", "-")
+	patchPath := filepath.Join(dir, name+".patch")
+	if err := os.WriteFile(patchPath, []byte(patch), 0o644); err != nil {
+		return nil, fmt.Errorf("write patch: %w", err)
+	}
+	description := fmt.Sprintf("# %s\n\nBase: %s\nHead: %s\n\n%s\n", title, base, head, body)
+	if err := os.WriteFile(filepath.Join(dir, name+".md"), []byte(description), 0o644); err != nil {
+		return nil, fmt.Errorf("write patch description: %w", err)
+	}
+
+	return &core.GitPullRequest{
+		URL:   fileURL(patchPath),
+		Title: title,
+	}, nil
+}
+
+// fileURL returns the file URL of the absolute path p: file:///srv/x on
+// Unix and file:///C:/x on Windows.
+func fileURL(p string) string {
+	p = filepath.ToSlash(p)
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return "file://" + p
+}
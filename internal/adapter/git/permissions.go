@@ -0,0 +1,138 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v60/github"
+)
+
+// Capabilities the pipeline needs from the token on every configured repo.
+const (
+	CapabilityReadIssues = "read issues"
+	CapabilityPush       = "push branches"
+	CapabilityOpenPR     = "open pull requests"
+)
+
+// MissingPermission is a capability the token lacks and what grants it.
+type MissingPermission struct {
+	Capability string
+	Need       string
+}
+
+// PermissionReport is the result of checking the token against one repo.
+type PermissionReport struct {
+	Repo string
+	// Scopes are the OAuth scopes of a classic token; nil for fine-grained
+	// and app tokens, which do not advertise scopes.
+	Scopes  []string
+	Missing []MissingPermission
+}
+
+// OK reports whether the token has every capability the pipeline needs.
+func (r *PermissionReport) OK() bool {
+	return len(r.Missing) == 0
+}
+
+// String lists the missing capabilities, one per line.
+func (r *PermissionReport) String() string {
+	var b strings.Builder
+	for _, m := range r.Missing {
+		fmt.Fprintf(&b, "%s: cannot %s (needs %s)\n", r.Repo, m.Capability, m.Need)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// CheckPermissions verifies that the token can read issues, push branches and
+// open pull requests on the adapter's repo. Classic tokens are checked by
+// scope; fine-grained tokens do not expose their permissions, so an issue
+// listing is probed and write access is taken from the repository
+// permissions GitHub reports for the token. An error means the check itself
+// could not run (network, invalid token), not that a permission is missing.
+func (g *GitHubAdapter) CheckPermissions(ctx context.Context) (*PermissionReport, error) {
+	report := &PermissionReport{Repo: g.owner + "/" + g.repo}
+
+	repo, resp, err := g.client.Repositories.Get(ctx, g.owner, g.repo)
+	if err != nil {
+		switch responseStatus(resp, err) {
+		case http.StatusUnauthorized:
+			return nil, fmt.Errorf("github token is invalid or expired")
+		case http.StatusNotFound, http.StatusForbidden:
+			report.Missing = []MissingPermission{{
+				Capability: "see the repository",
+				Need:       "access to " + report.Repo + " (fine-grained tokens must list it under repository access)",
+			}}
+			return report, nil
+		}
+		return nil, fmt.Errorf("get repository %s: %w", report.Repo, err)
+	}
+
+	if header, classic := resp.Header[http.CanonicalHeaderKey("X-OAuth-Scopes")]; classic {
+		report.Scopes = splitScopes(strings.Join(header, ","))
+		report.Missing = append(report.Missing, missingScopes(report.Scopes, repo.GetPrivate())...)
+	} else if !repo.GetHasIssues() {
+		report.Missing = append(report.Missing, MissingPermission{CapabilityReadIssues, "issues to be enabled on the repository"})
+	} else if _, resp, err := g.client.Issues.ListByRepo(ctx, g.owner, g.repo, &github.IssueListByRepoOptions{
+		ListOptions: github.ListOptions{PerPage: 1},
+	}); err != nil {
+		if status := responseStatus(resp, err); status != http.StatusForbidden && status != http.StatusNotFound {
+			return nil, fmt.Errorf("list issues of %s: %w", report.Repo, err)
+		}
+		report.Missing = append(report.Missing, MissingPermission{CapabilityReadIssues, `fine-grained permission "Issues: Read"`})
+	}
+
+	perms := repo.GetPermissions()
+	if !perms["push"] && !perms["admin"] {
+		report.Missing = append(report.Missing,
+			MissingPermission{CapabilityPush, `write access to the repository (fine-grained permission "Contents: Read and write")`},
+			MissingPermission{CapabilityOpenPR, `write access to the repository (fine-grained permission "Pull requests: Read and write")`},
+		)
+	}
+	return report, nil
+}
+
+// missingScopes maps the scopes of a classic token to missing capabilities.
+// Public repos only need public_repo; private repos need the full repo scope.
+func missingScopes(scopes []string, private bool) []MissingPermission {
+	has := map[string]bool{}
+	for _, s := range scopes {
+		has[s] = true
+	}
+	if has["repo"] {
+		return nil
+	}
+	if private {
+		need := `classic token scope "repo"`
+		return []MissingPermission{{CapabilityReadIssues, need}, {CapabilityPush, need}, {CapabilityOpenPR, need}}
+	}
+	if has["public_repo"] {
+		return nil
+	}
+	need := `classic token scope "public_repo" or "repo"`
+	return []MissingPermission{{CapabilityPush, need}, {CapabilityOpenPR, need}}
+}
+
+func splitScopes(header string) []string {
+	scopes := []string{}
+	for _, s := range strings.Split(header, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
+
+// responseStatus returns the HTTP status of a failed API call, or 0.
+func responseStatus(resp *github.Response, err error) int {
+	var ghErr *github.ErrorResponse
+	if errors.As(err, &ghErr) && ghErr.Response != nil {
+		return ghErr.Response.StatusCode
+	}
+	if resp != nil && resp.Response != nil {
+		return resp.StatusCode
+	}
+	return 0
+}
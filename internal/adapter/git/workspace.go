@@ -0,0 +1,316 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tasksDir holds the task worktrees under the workspace root. The leading
+// dot keeps it apart from the owner directories of the clones.
+const tasksDir = ".tasks"
+
+// ErrWorkspaceQuota is returned when a new task worktree would exceed
+// source.workspaces.max_bytes.
+var ErrWorkspaceQuota = errors.New("workspace quota exceeded")
+
+// Workspaces keeps the local checkouts under a root directory: one clone
+// per repository at <root>/<owner>/<repo> and a git worktree per task at
+// <root>/.tasks/<owner>/<repo>/<task-id>. Tasks of the same repository
+// share the clone's objects but never a working tree or a checked-out
+// branch.
+type Workspaces struct {
+	root     string
+	maxBytes int64
+}
+
+// Workspace is a checkout under the workspace root.
+type Workspace struct {
+	Repo       string    `json:"repo"`
+	TaskID     string    `json:"task_id,omitempty"` // empty for the shared clone
+	Path       string    `json:"path"`
+	SizeBytes  int64     `json:"size_bytes"`
+	ModifiedAt time.Time `json:"modified_at"`
+}
+
+// NewWorkspaces returns the checkouts kept under root, by default
+// ~/.rig/workspaces. maxBytes caps their disk usage; 0 sets no quota.
+func NewWorkspaces(root string, maxBytes int64) (*Workspaces, error) {
+	if root == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("get user home dir: %w", err)
+		}
+		root = filepath.Join(home, ".rig", "workspaces")
+	}
+	return &Workspaces{root: root, maxBytes: maxBytes}, nil
+}
+
+// Root returns the directory the checkouts are kept in.
+func (w *Workspaces) Root() string {
+	return w.root
+}
+
+func (w *Workspaces) clonePath(owner, repo string) string {
+	return filepath.Join(w.root, owner, repo)
+}
+
+func (w *Workspaces) taskPath(owner, repo, taskID string) string {
+	return filepath.Join(w.root, tasksDir, owner, repo, taskID)
+}
+
+// SetWorkspaces gives every task its own worktree of a clone kept in ws,
+// instead of one checkout of the repository shared by all tasks.
+func (g *GitHubAdapter) SetWorkspaces(ws *Workspaces) {
+	g.workspaces = ws
+	g.workspace = ws.clonePath(g.owner, g.repo)
+}
+
+// UseTaskWorkspace points the adapter at the worktree of taskID. It
+// implements core.TaskWorkspaces and does nothing without SetWorkspaces.
+func (g *GitHubAdapter) UseTaskWorkspace(taskID string) {
+	if g.workspaces == nil || taskID == "" {
+		return
+	}
+	g.taskID = taskID
+	g.workspace = g.workspaces.taskPath(g.owner, g.repo, taskID)
+}
+
+// repoLocks serializes the changes to each clone (clone, fetch, adding and
+// removing worktrees) across all adapters of the process.
+var repoLocks sync.Map
+
+func lockRepo(path string) func() {
+	m, _ := repoLocks.LoadOrStore(path, &sync.Mutex{})
+	mu := m.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// Checkout returns the worktree of task taskID, first creating it from the
+// default branch of an up-to-date clone of cloneURL made with opts. A
+// worktree that already exists, such as on a retried step, is returned as
+// it is.
+func (w *Workspaces) Checkout(ctx context.Context, owner, repo, cloneURL, taskID string, opts CloneOptions) (string, error) {
+	if taskID == "" || filepath.Base(taskID) != taskID || strings.HasPrefix(taskID, ".") {
+		return "", fmt.Errorf("invalid task ID %q for a workspace", taskID)
+	}
+	path := w.taskPath(owner, repo, taskID)
+	if isCheckout(path) {
+		return path, nil
+	}
+
+	clone := w.clonePath(owner, repo)
+	defer lockRepo(clone)()
+
+	if w.maxBytes > 0 {
+		used, err := dirSize(w.root)
+		if err != nil {
+			return "", fmt.Errorf("measure workspaces: %w", err)
+		}
+		if used >= w.maxBytes {
+			return "", fmt.Errorf("%w: %d of %d bytes used in %s", ErrWorkspaceQuota, used, w.maxBytes, w.root)
+		}
+	}
+
+	if isCheckout(clone) {
+		if _, err := runGit(ctx, clone, "remote", "set-url", "origin", cloneURL); err != nil {
+			return "", fmt.Errorf("set origin: %w", err)
+		}
+		if _, err := runGit(ctx, clone, opts.fetchArgs()...); err != nil {
+			return "", fmt.Errorf("git fetch: %w", err)
+		}
+	} else {
+		if err := os.MkdirAll(filepath.Dir(clone), 0o755); err != nil {
+			return "", fmt.Errorf("create workspace parent dir: %w", err)
+		}
+		if _, err := runGit(ctx, "", opts.cloneArgs(cloneURL, clone)...); err != nil {
+			return "", fmt.Errorf("git clone: %w", err)
+		}
+	}
+
+	// A worktree left half-created or deleted by hand is registered still.
+	os.RemoveAll(path)
+	runGit(ctx, clone, "worktree", "prune")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("create worktree parent dir: %w", err)
+	}
+	start := "refs/remotes/origin/HEAD"
+	if _, err := runGit(ctx, clone, "rev-parse", "--verify", "--quiet", start); err != nil {
+		start = "HEAD"
+	}
+	if len(opts.Sparse) == 0 {
+		if _, err := runGit(ctx, clone, "worktree", "add", "--detach", path, start); err != nil {
+			return "", fmt.Errorf("git worktree add: %w", err)
+		}
+		return path, nil
+	}
+	// Set the sparse directories before the files are checked out, so the
+	// partial clone downloads only those.
+	if _, err := runGit(ctx, clone, "worktree", "add", "--no-checkout", "--detach", path, start); err != nil {
+		return "", fmt.Errorf("git worktree add: %w", err)
+	}
+	if err := opts.applySparse(ctx, path); err != nil {
+		return "", err
+	}
+	if _, err := runGit(ctx, path, "reset", "--hard", "--quiet"); err != nil {
+		return "", fmt.Errorf("check out worktree: %w", err)
+	}
+	return path, nil
+}
+
+// Remove deletes the worktree of task taskID and the local branch it had
+// checked out. Remote branches are left alone.
+func (w *Workspaces) Remove(ctx context.Context, owner, repo, taskID string) error {
+	path := w.taskPath(owner, repo, taskID)
+	clone := w.clonePath(owner, repo)
+	defer lockRepo(clone)()
+
+	// Without a clone git would find whatever repository encloses the root.
+	if !isCheckout(clone) {
+		if err := os.RemoveAll(path); err != nil {
+			return fmt.Errorf("remove worktree %s: %w", path, err)
+		}
+		return nil
+	}
+	branch := ""
+	if isCheckout(path) {
+		if out, err := runGit(ctx, path, "symbolic-ref", "--quiet", "--short", "HEAD"); err == nil {
+			branch = strings.TrimSpace(out)
+		}
+	}
+	if _, err := runGit(ctx, clone, "worktree", "remove", "--force", path); err != nil {
+		if err := os.RemoveAll(path); err != nil {
+			return fmt.Errorf("remove worktree %s: %w", path, err)
+		}
+		runGit(ctx, clone, "worktree", "prune")
+	}
+	if branch != "" {
+		runGit(ctx, clone, "branch", "-D", branch)
+	}
+	// Drop the repository's task directory once its last worktree is gone.
+	os.Remove(filepath.Dir(path))
+	return nil
+}
+
+// List returns the clones and task worktrees with their disk usage, clones
+// first.
+func (w *Workspaces) List() ([]Workspace, error) {
+	var clones, tasks []Workspace
+	for _, dir := range repoDirs(w.root) {
+		if dir.owner == tasksDir {
+			continue
+		}
+		ws, err := describe(dir.path, dir.owner+"/"+dir.repo, "")
+		if err != nil {
+			return nil, err
+		}
+		clones = append(clones, ws)
+	}
+	for _, dir := range repoDirs(filepath.Join(w.root, tasksDir)) {
+		entries, err := os.ReadDir(dir.path)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", dir.path, err)
+		}
+		for _, e := range entries {
+			if !e.IsDir() {
+				continue
+			}
+			ws, err := describe(filepath.Join(dir.path, e.Name()), dir.owner+"/"+dir.repo, e.Name())
+			if err != nil {
+				return nil, err
+			}
+			tasks = append(tasks, ws)
+		}
+	}
+	return append(clones, tasks...), nil
+}
+
+// GC removes the task worktrees for which keep returns false and returns
+// them. Clones are kept; they make the next task of the repository cheap.
+func (w *Workspaces) GC(ctx context.Context, keep func(taskID string) bool) ([]Workspace, error) {
+	list, err := w.List()
+	if err != nil {
+		return nil, err
+	}
+	var removed []Workspace
+	for _, ws := range list {
+		if ws.TaskID == "" || keep(ws.TaskID) {
+			continue
+		}
+		owner, repo, _ := strings.Cut(ws.Repo, "/")
+		if err := w.Remove(ctx, owner, repo, ws.TaskID); err != nil {
+			return removed, err
+		}
+		removed = append(removed, ws)
+	}
+	return removed, nil
+}
+
+type repoDir struct {
+	owner, repo, path string
+}
+
+// repoDirs returns the <owner>/<repo> directories under root.
+func repoDirs(root string) []repoDir {
+	var dirs []repoDir
+	owners, _ := os.ReadDir(root)
+	for _, o := range owners {
+		if !o.IsDir() {
+			continue
+		}
+		repos, _ := os.ReadDir(filepath.Join(root, o.Name()))
+		for _, r := range repos {
+			if r.IsDir() {
+				dirs = append(dirs, repoDir{owner: o.Name(), repo: r.Name(), path: filepath.Join(root, o.Name(), r.Name())})
+			}
+		}
+	}
+	return dirs
+}
+
+func describe(path, repo, taskID string) (Workspace, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Workspace{}, fmt.Errorf("stat %s: %w", path, err)
+	}
+	size, err := dirSize(path)
+	if err != nil {
+		return Workspace{}, fmt.Errorf("measure %s: %w", path, err)
+	}
+	return Workspace{Repo: repo, TaskID: taskID, Path: path, SizeBytes: size, ModifiedAt: info.ModTime().UTC()}, nil
+}
+
+// dirSize returns the total size of the files under dir; a missing dir is
+// empty.
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size, err
+}
+
+// isCheckout reports whether dir is a git checkout. In a worktree .git is
+// a file rather than a directory.
+func isCheckout(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, ".git"))
+	return err == nil
+}
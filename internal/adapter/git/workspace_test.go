@@ -0,0 +1,137 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rigdev/rig/internal/core"
+)
+
+func TestWorkspacesPerTaskWorktrees(t *testing.T) {
+	_, bareDir := initBareRepo(t)
+	ws, err := NewWorkspaces(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	newAdapter := func(taskID string) *GitHubAdapter {
+		g := &GitHubAdapter{owner: "acme", repo: "api"}
+		g.SetMirror(bareDir)
+		g.SetWorkspaces(ws)
+		g.UseTaskWorkspace(taskID)
+		return g
+	}
+	a, b := newAdapter("task-a"), newAdapter("task-b")
+	for _, g := range []*GitHubAdapter{a, b} {
+		if err := g.CloneOrPull(ctx, "acme", "api", "token"); err != nil {
+			t.Fatalf("checkout %s: %v", g.taskID, err)
+		}
+	}
+	if a.GetWorkspace() == b.GetWorkspace() {
+		t.Fatalf("tasks share workspace %s", a.GetWorkspace())
+	}
+	clone := ws.clonePath("acme", "api")
+	run(t, clone, "git", "config", "user.email", "test@rig.dev")
+	run(t, clone, "git", "config", "user.name", "Rig Test")
+
+	// Both tasks work on the same branch name without touching each other.
+	for _, g := range []*GitHubAdapter{a, b} {
+		if err := g.CreateBranch(ctx, "rig/issue-1"); err != nil {
+			t.Fatalf("create branch in %s: %v", g.taskID, err)
+		}
+	}
+	changes := []core.GitFileChange{{Path: "a.go", Content: "package a\n", Action: "create"}}
+	if err := a.CommitAndPush(ctx, changes, "add a"); err != nil {
+		t.Fatalf("commit in task-a: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(b.GetWorkspace(), "a.go")); !os.IsNotExist(err) {
+		t.Errorf("task-b sees task-a's file (err %v)", err)
+	}
+
+	list, err := ws.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 3 || list[0].TaskID != "" || list[0].Repo != "acme/api" || list[0].SizeBytes == 0 {
+		t.Fatalf("list = %+v, want the clone then two worktrees", list)
+	}
+
+	if err := a.Cleanup(); err != nil {
+		t.Fatalf("cleanup: %v", err)
+	}
+	if _, err := os.Stat(a.GetWorkspace()); !os.IsNotExist(err) {
+		t.Errorf("task-a worktree still exists (err %v)", err)
+	}
+	if _, err := os.Stat(filepath.Join(clone, ".git")); err != nil {
+		t.Errorf("cleanup removed the shared clone: %v", err)
+	}
+
+	removed, err := ws.GC(ctx, func(taskID string) bool { return false })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || removed[0].TaskID != "task-b" {
+		t.Errorf("gc removed %+v, want task-b", removed)
+	}
+	if out := run(t, clone, "git", "worktree", "list"); strings.Count(out, "\n") != 1 {
+		t.Errorf("worktrees left registered:\n%s", out)
+	}
+}
+
+func TestWorkspacesQuota(t *testing.T) {
+	_, bareDir := initBareRepo(t)
+	ws, err := NewWorkspaces(t.TempDir(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := ws.Checkout(ctx, "acme", "api", bareDir, "task-1", CloneOptions{}); err != nil {
+		t.Fatalf("first checkout under an empty root: %v", err)
+	}
+	if _, err := ws.Checkout(ctx, "acme", "api", bareDir, "task-2", CloneOptions{}); !errors.Is(err, ErrWorkspaceQuota) {
+		t.Errorf("err = %v, want ErrWorkspaceQuota", err)
+	}
+	if _, err := ws.Checkout(ctx, "acme", "api", bareDir, "../escape", CloneOptions{}); err == nil {
+		t.Error("expected an error for a task ID that is not a plain name")
+	}
+}
+
+func TestWorkspacesShallowSparseCheckout(t *testing.T) {
+	workDir, bareDir := initBareRepo(t)
+	for _, dir := range []string{"services/api", "services/web"} {
+		if err := os.MkdirAll(filepath.Join(workDir, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(workDir, dir, "main.go"), []byte("package main\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		run(t, workDir, "git", "add", ".")
+		run(t, workDir, "git", "commit", "-m", "add "+dir)
+	}
+	run(t, workDir, "git", "push", "origin", "HEAD")
+
+	ws, err := NewWorkspaces(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := CloneOptions{Depth: 1, SingleBranch: true, Sparse: []string{"services/api"}}
+	path, err := ws.Checkout(context.Background(), "acme", "mono", "file://"+bareDir, "task-1", opts)
+	if err != nil {
+		t.Fatalf("checkout: %v", err)
+	}
+
+	if count := strings.TrimSpace(run(t, path, "git", "rev-list", "--count", "HEAD")); count != "1" {
+		t.Errorf("history has %s commits, want 1", count)
+	}
+	for file, want := range map[string]bool{"README.md": true, "services/api/main.go": true, "services/web/main.go": false} {
+		_, err := os.Stat(filepath.Join(path, file))
+		if got := err == nil; got != want {
+			t.Errorf("%s checked out = %v, want %v", file, got, want)
+		}
+	}
+}
@@ -6,7 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 
@@ -65,7 +65,7 @@ func (w *WebhookNotifier) Notify(ctx context.Context, message string) error {
 		if readErr != nil {
 			return fmt.Errorf("webhook returned status %s and body read failed: %w", resp.Status, readErr)
 		}
-		log.Printf("webhook notifier received non-2xx response: status=%s body=%q", resp.Status, string(respBody))
+		slog.Warn("webhook notifier received non-2xx response", "status", resp.Status, "body", string(respBody))
 		return fmt.Errorf("webhook returned status %s", resp.Status)
 	}
 
@@ -0,0 +1,90 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/variable"
+)
+
+// DeployAdapter implements core.DeployAdapterIface with a plugin.
+type DeployAdapter struct {
+	plugin  *Plugin
+	options map[string]string
+}
+
+var (
+	_ core.DeployAdapterIface = (*DeployAdapter)(nil)
+	_ core.SnapshotRollbacker = (*DeployAdapter)(nil)
+)
+
+// NewDeployAdapter creates a deploy adapter that deploys with p, passing it
+// options.
+func NewDeployAdapter(p *Plugin, options map[string]string) *DeployAdapter {
+	return &DeployAdapter{plugin: p, options: options}
+}
+
+// Validate asks the plugin to check its options.
+func (a *DeployAdapter) Validate() error {
+	_, _, err := a.plugin.Call(context.Background(), Request{Method: MethodValidate, Options: a.options})
+	return err
+}
+
+// Deploy asks the plugin to deploy. A deploy the plugin reports as failed
+// is a failed result, not an error.
+func (a *DeployAdapter) Deploy(ctx context.Context, vars map[string]string) (*core.AdapterDeployResult, error) {
+	start := time.Now()
+	resp, stderr, err := a.plugin.Call(ctx, Request{Method: MethodDeploy, Options: resolveOptions(a.options, vars), Vars: vars})
+	if err != nil {
+		return &core.AdapterDeployResult{
+			Success:  false,
+			Output:   withStderr(err.Error(), stderr),
+			Duration: time.Since(start),
+		}, err
+	}
+	return &core.AdapterDeployResult{
+		Success:   resp.Success,
+		Output:    withStderr(resp.Output, stderr),
+		Duration:  time.Since(start),
+		Artifacts: resp.Artifacts,
+	}, nil
+}
+
+// Rollback asks the plugin to undo the last deploy.
+func (a *DeployAdapter) Rollback(ctx context.Context) error {
+	return a.rollback(ctx, Request{Method: MethodRollback, Options: resolveOptions(a.options, nil)})
+}
+
+// RollbackTo asks the plugin to restore the deploy recorded in snap, whose
+// variables and artifacts it is sent.
+func (a *DeployAdapter) RollbackTo(ctx context.Context, snap *core.DeploySnapshot) error {
+	return a.rollback(ctx, Request{
+		Method:    MethodRollback,
+		Options:   resolveOptions(a.options, snap.Vars),
+		Vars:      snap.Vars,
+		Artifacts: snap.Artifacts,
+	})
+}
+
+func (a *DeployAdapter) rollback(ctx context.Context, req Request) error {
+	resp, _, err := a.plugin.Call(ctx, req)
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return fmt.Errorf("rollback failed: %s", resp.Output)
+	}
+	return nil
+}
+
+// resolveOptions returns options with the variables of vars resolved.
+func resolveOptions(options, vars map[string]string) map[string]string {
+	resolved := maps.Clone(options)
+	for k, v := range resolved {
+		resolved[k] = variable.Resolve(v, vars)
+	}
+	return resolved
+}
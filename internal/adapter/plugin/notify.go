@@ -0,0 +1,27 @@
+package plugin
+
+import (
+	"context"
+
+	"github.com/rigdev/rig/internal/core"
+)
+
+// Notifier implements core.NotifierIface with a plugin.
+type Notifier struct {
+	plugin  *Plugin
+	options map[string]string
+}
+
+var _ core.NotifierIface = (*Notifier)(nil)
+
+// NewNotifier creates a notifier that delivers messages with p, passing it
+// options.
+func NewNotifier(p *Plugin, options map[string]string) *Notifier {
+	return &Notifier{plugin: p, options: options}
+}
+
+// Notify asks the plugin to deliver message.
+func (n *Notifier) Notify(ctx context.Context, message string) error {
+	_, _, err := n.plugin.Call(ctx, Request{Method: MethodNotify, Options: n.options, Message: message})
+	return err
+}
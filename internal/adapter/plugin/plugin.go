@@ -0,0 +1,164 @@
+// Package plugin runs deploy adapters, test runners and notifiers that live
+// outside rig as executables speaking a JSON protocol over stdio.
+//
+// rig starts the plugin's command once per call, writes one Request as JSON
+// to its stdin and closes it, then reads one Response as JSON from its
+// stdout. Anything the plugin writes to stderr is kept as output; stdout
+// must hold the response alone. A plugin reports a failure it understood
+// in Response.Error and may exit 0 or not; a plugin that exits without a
+// response fails the call. Every request carries ProtocolVersion so a
+// plugin can refuse one it does not speak, and a plugin should answer the
+// methods it does not implement with an empty response.
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+)
+
+// ProtocolVersion is the version of the protocol rig speaks.
+const ProtocolVersion = 1
+
+// defaultTimeout bounds a call of a plugin that sets no timeout.
+const defaultTimeout = 5 * time.Minute
+
+// Methods of the protocol.
+const (
+	MethodValidate = "validate" // deploy: check options before the first deploy
+	MethodDeploy   = "deploy"   // deploy: deploy with vars
+	MethodRollback = "rollback" // deploy: undo the last deploy, or the one in vars and artifacts
+	MethodRun      = "run"      // test: run the test with vars
+	MethodNotify   = "notify"   // notify: deliver message
+)
+
+// Request is what rig sends a plugin on stdin.
+type Request struct {
+	Protocol int    `json:"protocol"`
+	Method   string `json:"method"`
+	// Name is the name of the test being run.
+	Name string `json:"name,omitempty"`
+	// Options are the options of the deploy, test or notify entry that
+	// uses the plugin, with variables resolved.
+	Options   map[string]string `json:"options,omitempty"`
+	Vars      map[string]string `json:"vars,omitempty"`
+	Artifacts map[string]string `json:"artifacts,omitempty"`
+	Message   string            `json:"message,omitempty"`
+}
+
+// Response is what a plugin answers on stdout.
+type Response struct {
+	// Error fails the call with this message.
+	Error string `json:"error,omitempty"`
+	// Success reports a deploy that succeeded or a test that passed.
+	Success bool   `json:"success"`
+	Output  string `json:"output,omitempty"`
+	// Artifacts name what a deploy deployed, for rolling back to it later.
+	Artifacts map[string]string `json:"artifacts,omitempty"`
+	// Cases are the test cases a test run reports.
+	Cases []core.TestCase `json:"cases,omitempty"`
+}
+
+// Plugin is an executable rig calls through the protocol.
+type Plugin struct {
+	name    string
+	command string
+	args    []string
+	env     map[string]string
+	timeout time.Duration
+}
+
+// New creates a Plugin from its plugins entry.
+func New(cfg config.PluginConfig) *Plugin {
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	return &Plugin{
+		name:    cfg.Name,
+		command: cfg.Command,
+		args:    cfg.Args,
+		env:     cfg.Env,
+		timeout: timeout,
+	}
+}
+
+// Name returns the name of the plugin.
+func (p *Plugin) Name() string {
+	return p.name
+}
+
+// Call runs the plugin with req and returns its response and stderr. A
+// response with Error set is returned along with an error.
+func (p *Plugin) Call(ctx context.Context, req Request) (*Response, string, error) {
+	req.Protocol = ProtocolVersion
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("plugin %s: marshal request: %w", p.name, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, p.command, p.args...)
+	cmd.Cancel = func() error { return cmd.Process.Kill() }
+	cmd.WaitDelay = 3 * time.Second
+	cmd.Env = os.Environ()
+	for k, v := range p.env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	cmd.Stdin = bytes.NewReader(append(body, '\n'))
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	runErr := cmd.Run()
+	logs := stderr.String()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, logs, fmt.Errorf("plugin %s: %s timed out after %s", p.name, req.Method, p.timeout)
+	}
+
+	var resp Response
+	if err := json.NewDecoder(&stdout).Decode(&resp); err != nil {
+		if runErr != nil {
+			return nil, logs, fmt.Errorf("plugin %s: %s: %w%s", p.name, req.Method, runErr, stderrTail(logs))
+		}
+		return nil, logs, fmt.Errorf("plugin %s: %s: invalid response: %w", p.name, req.Method, err)
+	}
+	if resp.Error != "" {
+		return &resp, logs, fmt.Errorf("plugin %s: %s: %s", p.name, req.Method, resp.Error)
+	}
+	return &resp, logs, nil
+}
+
+// maxStderrTail bounds the stderr quoted in a call error.
+const maxStderrTail = 2048
+
+// stderrTail returns the end of stderr to quote in an error, or "".
+func stderrTail(stderr string) string {
+	stderr = strings.TrimSpace(stderr)
+	if stderr == "" {
+		return ""
+	}
+	if len(stderr) > maxStderrTail {
+		stderr = "..." + stderr[len(stderr)-maxStderrTail:]
+	}
+	return ": " + stderr
+}
+
+// withStderr appends the plugin's stderr to output.
+func withStderr(output, stderr string) string {
+	if stderr == "" {
+		return output
+	}
+	return output + "\n--- stderr ---\n" + stderr
+}
@@ -0,0 +1,159 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+)
+
+// TestMain makes the test binary act as a plugin when RIG_TEST_PLUGIN is
+// set, so the tests run a real executable over the protocol.
+func TestMain(m *testing.M) {
+	if mode := os.Getenv("RIG_TEST_PLUGIN"); mode != "" {
+		servePlugin(mode)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// servePlugin answers one request the way mode says.
+func servePlugin(mode string) {
+	var req Request
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		fmt.Fprintln(os.Stderr, "bad request:", err)
+		os.Exit(2)
+	}
+	var resp Response
+	switch mode {
+	case "crash":
+		fmt.Fprintln(os.Stderr, "out of cheese")
+		os.Exit(3)
+	case "hang":
+		time.Sleep(10 * time.Second)
+	case "echo":
+		fmt.Fprintln(os.Stderr, "working")
+		switch req.Method {
+		case MethodValidate:
+			if req.Options["app"] == "" {
+				resp.Error = "option app is required"
+			}
+		case MethodDeploy:
+			resp.Success = req.Vars["FAIL"] == ""
+			resp.Output = fmt.Sprintf("deployed %s at %s", req.Options["app"], req.Vars["COMMIT_SHA"])
+			resp.Artifacts = map[string]string{"release": "r-" + req.Vars["COMMIT_SHA"]}
+		case MethodRollback:
+			resp.Success = true
+			if req.Artifacts["release"] != "r-abc" {
+				resp.Success, resp.Output = false, "unknown release "+req.Artifacts["release"]
+			}
+		case MethodRun:
+			resp.Success = req.Options["target"] == "https://staging"
+			resp.Output = "ran " + req.Name
+			resp.Cases = []core.TestCase{{Name: "login", Status: "pass"}}
+		case MethodNotify:
+			if req.Protocol != ProtocolVersion || req.Message == "" {
+				resp.Error = "bad notify request"
+			}
+		}
+	}
+	json.NewEncoder(os.Stdout).Encode(resp)
+}
+
+func testPlugin(t *testing.T, mode string) *Plugin {
+	t.Helper()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(config.PluginConfig{
+		Name:    "acme",
+		Command: exe,
+		Env:     map[string]string{"RIG_TEST_PLUGIN": mode},
+		Timeout: 5 * time.Second,
+	})
+}
+
+func TestDeployAdapter(t *testing.T) {
+	a := NewDeployAdapter(testPlugin(t, "echo"), map[string]string{"app": "web"})
+	if err := a.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	result, err := a.Deploy(context.Background(), map[string]string{"COMMIT_SHA": "abc"})
+	if err != nil {
+		t.Fatalf("Deploy: %v", err)
+	}
+	if !result.Success || !strings.Contains(result.Output, "deployed web at abc") || !strings.Contains(result.Output, "working") {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if result.Artifacts["release"] != "r-abc" {
+		t.Errorf("artifacts = %v", result.Artifacts)
+	}
+
+	result, err = a.Deploy(context.Background(), map[string]string{"FAIL": "1"})
+	if err != nil || result.Success {
+		t.Errorf("expected a failed deploy without error, got %+v, %v", result, err)
+	}
+
+	if err := a.RollbackTo(context.Background(), &core.DeploySnapshot{Artifacts: map[string]string{"release": "r-abc"}}); err != nil {
+		t.Errorf("RollbackTo: %v", err)
+	}
+	if err := a.Rollback(context.Background()); err == nil || !strings.Contains(err.Error(), "unknown release") {
+		t.Errorf("expected a failed rollback, got %v", err)
+	}
+
+	invalid := NewDeployAdapter(testPlugin(t, "echo"), nil)
+	if err := invalid.Validate(); err == nil || !strings.Contains(err.Error(), "option app is required") {
+		t.Errorf("expected a validation error, got %v", err)
+	}
+}
+
+func TestTestRunner(t *testing.T) {
+	r := NewTestRunner(testPlugin(t, "echo"), config.TestConfig{
+		Type:    "plugin",
+		Name:    "smoke",
+		Options: map[string]string{"target": "${APP_URL}"},
+	})
+	result, err := r.Run(context.Background(), map[string]string{"APP_URL": "https://staging"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !result.Passed || result.Name != "smoke" || result.Type != "plugin" || !strings.Contains(result.Output, "ran smoke") {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if len(result.Cases) != 1 || result.Cases[0].Name != "login" {
+		t.Errorf("cases = %+v", result.Cases)
+	}
+}
+
+func TestNotifier(t *testing.T) {
+	n := NewNotifier(testPlugin(t, "echo"), nil)
+	if err := n.Notify(context.Background(), "tests passed"); err != nil {
+		t.Errorf("Notify: %v", err)
+	}
+}
+
+func TestCall_Failures(t *testing.T) {
+	_, _, err := testPlugin(t, "crash").Call(context.Background(), Request{Method: MethodDeploy})
+	if err == nil || !strings.Contains(err.Error(), "out of cheese") {
+		t.Errorf("expected the crash with its stderr, got %v", err)
+	}
+
+	p := testPlugin(t, "hang")
+	p.timeout = 200 * time.Millisecond
+	r := NewTestRunner(p, config.TestConfig{Type: "plugin", Name: "slow"})
+	result, err := r.Run(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if result.Passed || !strings.Contains(result.Output, "timed out") {
+		t.Errorf("expected a timed out test, got %+v", result)
+	}
+}
@@ -0,0 +1,54 @@
+package plugin
+
+import (
+	"context"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+)
+
+// TestRunner implements core.TestRunnerIface with a plugin.
+type TestRunner struct {
+	plugin *Plugin
+	cfg    config.TestConfig
+}
+
+var _ core.TestRunnerIface = (*TestRunner)(nil)
+
+// NewTestRunner creates a runner that runs the test of cfg with p, passing
+// it cfg.Options.
+func NewTestRunner(p *Plugin, cfg config.TestConfig) *TestRunner {
+	return &TestRunner{plugin: p, cfg: cfg}
+}
+
+// Run asks the plugin to run the test. A plugin that fails to answer fails
+// the test with its error as output.
+func (r *TestRunner) Run(ctx context.Context, vars map[string]string) (*core.TestResult, error) {
+	if r.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.cfg.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	resp, stderr, err := r.plugin.Call(ctx, Request{
+		Method:  MethodRun,
+		Name:    r.cfg.Name,
+		Options: resolveOptions(r.cfg.Options, vars),
+		Vars:    vars,
+	})
+	result := &core.TestResult{
+		Name:     r.cfg.Name,
+		Type:     r.cfg.Type,
+		Duration: time.Since(start),
+	}
+	if err != nil {
+		result.Output = withStderr(err.Error(), stderr)
+		return result, nil
+	}
+	result.Passed = resp.Success
+	result.Output = withStderr(resp.Output, stderr)
+	result.Cases = resp.Cases
+	return result, nil
+}
@@ -0,0 +1,165 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/shell"
+	"github.com/rigdev/rig/internal/variable"
+)
+
+const (
+	defaultBrowserCommand      = "npx playwright test --reporter=line"
+	defaultBrowserArtifactsDir = "test-results"
+	maxBrowserArtifacts        = 20
+	maxArtifactContentBytes    = 16 * 1024
+)
+
+// BrowserRunner runs a headless browser suite (Playwright by default) against
+// the deployed URL and collects screenshots and console logs on failure.
+type BrowserRunner struct {
+	cfg       config.TestConfig
+	workspace core.WorkspaceProvider
+}
+
+var _ core.TestRunnerIface = (*BrowserRunner)(nil)
+
+// NewBrowserRunner creates a BrowserRunner from a test configuration. The
+// suite runs, and a relative artifacts_dir is read, in the workspace of ws
+// as for NewCommandRunner.
+func NewBrowserRunner(cfg config.TestConfig, ws core.WorkspaceProvider) *BrowserRunner {
+	return &BrowserRunner{cfg: cfg, workspace: ws}
+}
+
+// Run executes the browser suite. The resolved target URL is exported as
+// BASE_URL and PLAYWRIGHT_BASE_URL. When the suite fails, files written to
+// the artifacts directory during the run are attached to the result.
+func (r *BrowserRunner) Run(ctx context.Context, vars map[string]string) (*core.TestResult, error) {
+	command := r.cfg.Run
+	if command == "" {
+		command = defaultBrowserCommand
+	}
+	command = variable.Resolve(command, vars)
+	baseURL := variable.Resolve(r.cfg.URL, vars)
+
+	if r.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.cfg.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+
+	cmd, err := shell.Command(ctx, r.cfg.Shell, command)
+	if err != nil {
+		return nil, fmt.Errorf("test %s: %w", r.cfg.Name, err)
+	}
+	cmd.Cancel = func() error { return cmd.Process.Kill() }
+	cmd.WaitDelay = 3 * time.Second
+	cmd.Dir = workDir(r.workspace)
+	cmd.Env = append(os.Environ(), "BASE_URL="+baseURL, "PLAYWRIGHT_BASE_URL="+baseURL, "CI=1")
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	duration := time.Since(start)
+
+	output := stdout.String()
+	if stderr.Len() > 0 {
+		output += "\n--- stderr ---\n" + stderr.String()
+	}
+
+	result := &core.TestResult{
+		Name:     r.cfg.Name,
+		Type:     "browser",
+		Duration: duration,
+		Output:   output,
+		Passed:   err == nil,
+	}
+
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			result.Output = fmt.Sprintf("browser suite timed out after %s\n%s", r.cfg.Timeout, output)
+		}
+		result.Artifacts = collectArtifacts(r.artifactsDir(), start)
+	}
+
+	return result, nil
+}
+
+// artifactsDir is where the suite writes its artifacts: artifacts_dir, or
+// test-results, in the directory the suite runs in when relative.
+func (r *BrowserRunner) artifactsDir() string {
+	dir := r.cfg.ArtifactsDir
+	if dir == "" {
+		dir = defaultBrowserArtifactsDir
+	}
+	return inWorkDir(r.workspace, dir)
+}
+
+// collectArtifacts returns files under dir modified at or after since,
+// classified by extension. Text artifacts carry truncated content.
+func collectArtifacts(dir string, since time.Time) []core.TestArtifact {
+	type found struct {
+		path    string
+		modTime time.Time
+	}
+	var files []found
+
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.ModTime().Before(since.Add(-time.Second)) {
+			return nil
+		}
+		files = append(files, found{path: path, modTime: info.ModTime()})
+		return nil
+	})
+
+	sort.Slice(files, func(i, j int) bool { return files[i].path < files[j].path })
+	if len(files) > maxBrowserArtifacts {
+		files = files[:maxBrowserArtifacts]
+	}
+
+	artifacts := make([]core.TestArtifact, 0, len(files))
+	for _, f := range files {
+		kind := artifactKind(f.path)
+		artifact := core.TestArtifact{Kind: kind, Path: filepath.ToSlash(f.path)}
+		if kind == "console" {
+			if data, err := os.ReadFile(f.path); err == nil {
+				if len(data) > maxArtifactContentBytes {
+					data = append(data[:maxArtifactContentBytes], []byte("\n...(truncated)")...)
+				}
+				artifact.Content = string(data)
+			}
+		}
+		artifacts = append(artifacts, artifact)
+	}
+	return artifacts
+}
+
+func artifactKind(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png", ".jpg", ".jpeg", ".webp":
+		return "screenshot"
+	case ".log", ".txt":
+		return "console"
+	case ".zip":
+		return "trace"
+	default:
+		return "file"
+	}
+}
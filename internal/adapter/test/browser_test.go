@@ -0,0 +1,95 @@
+package test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+)
+
+func TestBrowserRunner_PassExportsBaseURL(t *testing.T) {
+	runner := NewBrowserRunner(config.TestConfig{
+		Type:    "browser",
+		Name:    "e2e",
+		URL:     "http://staging/${BRANCH_NAME}",
+		Run:     `test "$PLAYWRIGHT_BASE_URL" = "http://staging/feature" && echo "$BASE_URL"`,
+		Timeout: 10 * time.Second,
+	}, nil)
+
+	result, err := runner.Run(context.Background(), map[string]string{"BRANCH_NAME": "feature"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Passed {
+		t.Fatalf("expected pass, got: %s", result.Output)
+	}
+	if result.Type != "browser" || len(result.Artifacts) != 0 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
+
+func TestBrowserRunner_FailureCollectsArtifacts(t *testing.T) {
+	dir := t.TempDir()
+	artifacts := filepath.Join(dir, "results")
+	runner := NewBrowserRunner(config.TestConfig{
+		Type:         "browser",
+		Name:         "e2e",
+		URL:          "http://staging",
+		ArtifactsDir: artifacts,
+		Run: "mkdir -p " + artifacts + "/login && " +
+			"printf 'png' > " + artifacts + "/login/failure.png && " +
+			"echo 'TypeError: x is undefined' > " + artifacts + "/login/console.log && exit 1",
+		Timeout: 10 * time.Second,
+	}, nil)
+
+	result, err := runner.Run(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Passed {
+		t.Fatal("expected failure")
+	}
+	if len(result.Artifacts) != 2 {
+		t.Fatalf("expected 2 artifacts, got %+v", result.Artifacts)
+	}
+
+	kinds := map[string]string{}
+	for _, a := range result.Artifacts {
+		kinds[a.Kind] = a.Content
+	}
+	if _, ok := kinds["screenshot"]; !ok {
+		t.Errorf("expected screenshot artifact, got %+v", result.Artifacts)
+	}
+	if !strings.Contains(kinds["console"], "TypeError") {
+		t.Errorf("expected console log content, got %+v", result.Artifacts)
+	}
+}
+
+// workspace is a core.WorkspaceProvider of a fixed directory.
+type workspace string
+
+func (w workspace) GetWorkspace() string { return string(w) }
+
+func TestBrowserRunner_RelativeArtifactsDirInWorkspace(t *testing.T) {
+	dir := t.TempDir()
+	runner := NewBrowserRunner(config.TestConfig{
+		Type:         "browser",
+		Name:         "e2e",
+		URL:          "http://staging",
+		ArtifactsDir: "results",
+		Run:          "mkdir -p results && printf 'png' > results/failure.png && exit 1",
+		Timeout:      10 * time.Second,
+	}, workspace(dir))
+
+	result, err := runner.Run(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := filepath.Join(dir, "results", "failure.png")
+	if len(result.Artifacts) != 1 || filepath.FromSlash(result.Artifacts[0].Path) != want {
+		t.Fatalf("expected the artifact at %s, got %+v", want, result.Artifacts)
+	}
+}
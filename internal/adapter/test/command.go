@@ -4,24 +4,29 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"os/exec"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/rigdev/rig/internal/config"
 	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/shell"
 	"github.com/rigdev/rig/internal/variable"
 )
 
 // CommandRunner executes test commands via shell and checks exit codes.
 type CommandRunner struct {
-	cfg config.TestConfig
+	cfg       config.TestConfig
+	workspace core.WorkspaceProvider
 }
 
 var _ core.TestRunnerIface = (*CommandRunner)(nil)
 
-// NewCommandRunner creates a CommandRunner from a test configuration.
-func NewCommandRunner(cfg config.TestConfig) *CommandRunner {
-	return &CommandRunner{cfg: cfg}
+// NewCommandRunner creates a CommandRunner from a test configuration. The
+// command runs in the workspace of ws, where a relative report is read
+// from, or in the current directory when ws is nil or has none.
+func NewCommandRunner(cfg config.TestConfig, ws core.WorkspaceProvider) *CommandRunner {
+	return &CommandRunner{cfg: cfg, workspace: ws}
 }
 
 // Run executes the configured test command.
@@ -40,15 +45,19 @@ func (r *CommandRunner) Run(ctx context.Context, vars map[string]string) (*core.
 
 	start := time.Now()
 
-	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd, err := shell.Command(ctx, r.cfg.Shell, command)
+	if err != nil {
+		return nil, fmt.Errorf("test %s: %w", r.cfg.Name, err)
+	}
 	cmd.Cancel = func() error { return cmd.Process.Kill() }
 	cmd.WaitDelay = 3 * time.Second
+	cmd.Dir = workDir(r.workspace)
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	err := cmd.Run()
+	err = cmd.Run()
 	duration := time.Since(start)
 
 	output := stdout.String()
@@ -63,6 +72,13 @@ func (r *CommandRunner) Run(ctx context.Context, vars map[string]string) (*core.
 		Output:   output,
 	}
 
+	cases, parseErr := r.parseCases(stdout.Bytes(), vars)
+	result.Cases = cases
+	if parseErr != nil {
+		output += "\n--- report ---\n" + parseErr.Error()
+		result.Output = output
+	}
+
 	if err != nil {
 		// Check if it was a timeout.
 		if ctx.Err() == context.DeadlineExceeded {
@@ -78,3 +94,41 @@ func (r *CommandRunner) Run(ctx context.Context, vars map[string]string) (*core.
 	result.Passed = true
 	return result, nil
 }
+
+// parseCases extracts per-test results according to the configured format.
+// JUnit reports are read from cfg.Report when set, otherwise from stdout.
+func (r *CommandRunner) parseCases(stdout []byte, vars map[string]string) ([]core.TestCase, error) {
+	switch r.cfg.Format {
+	case "go-json":
+		return parseGoTestJSON(stdout), nil
+	case "junit":
+		data := stdout
+		if r.cfg.Report != "" {
+			path := inWorkDir(r.workspace, variable.Resolve(r.cfg.Report, vars))
+			var err error
+			if data, err = os.ReadFile(path); err != nil {
+				return nil, fmt.Errorf("read junit report: %w", err)
+			}
+		}
+		return parseJUnit(data)
+	default:
+		return nil, nil
+	}
+}
+
+// workDir is the workspace of ws the tests run in, or "" for the current
+// directory when ws is nil or has none.
+func workDir(ws core.WorkspaceProvider) string {
+	if ws == nil {
+		return ""
+	}
+	return ws.GetWorkspace()
+}
+
+// inWorkDir returns path, when relative, joined onto the workspace of ws.
+func inWorkDir(ws core.WorkspaceProvider, path string) string {
+	if dir := workDir(ws); dir != "" && path != "" && !filepath.IsAbs(path) {
+		return filepath.Join(dir, path)
+	}
+	return path
+}
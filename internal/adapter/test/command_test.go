@@ -2,6 +2,9 @@ package test
 
 import (
 	"context"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -14,7 +17,7 @@ func TestCommandRunner_Success(t *testing.T) {
 		Name:    "echo-test",
 		Run:     "echo hello",
 		Timeout: 10 * time.Second,
-	})
+	}, nil)
 
 	result, err := runner.Run(context.Background(), nil)
 	if err != nil {
@@ -37,7 +40,7 @@ func TestCommandRunner_Failure(t *testing.T) {
 		Name:    "fail-test",
 		Run:     "exit 1",
 		Timeout: 10 * time.Second,
-	})
+	}, nil)
 
 	result, err := runner.Run(context.Background(), nil)
 	if err != nil {
@@ -54,7 +57,7 @@ func TestCommandRunner_Timeout(t *testing.T) {
 		Name:    "timeout-test",
 		Run:     "sleep 30",
 		Timeout: 500 * time.Millisecond,
-	})
+	}, nil)
 
 	result, err := runner.Run(context.Background(), nil)
 	if err != nil {
@@ -74,7 +77,7 @@ func TestCommandRunner_VariableResolution(t *testing.T) {
 		Name:    "var-test",
 		Run:     "echo ${BRANCH_NAME}",
 		Timeout: 10 * time.Second,
-	})
+	}, nil)
 
 	vars := map[string]string{
 		"BRANCH_NAME": "rig/issue-42",
@@ -97,7 +100,7 @@ func TestCommandRunner_ContextCancelled(t *testing.T) {
 		Type: "command",
 		Name: "cancel-test",
 		Run:  "echo hello",
-	})
+	}, nil)
 
 	result, err := runner.Run(ctx, nil)
 	if err != nil {
@@ -108,3 +111,80 @@ func TestCommandRunner_ContextCancelled(t *testing.T) {
 		t.Fatal("expected test to fail with cancelled context")
 	}
 }
+
+func TestCommandRunner_GoTestJSONCases(t *testing.T) {
+	events := `{"Action":"run","Package":"example/pkg","Test":"TestOK"}
+{"Action":"pass","Package":"example/pkg","Test":"TestOK","Elapsed":0.01}
+{"Action":"run","Package":"example/pkg","Test":"TestBroken"}
+{"Action":"output","Package":"example/pkg","Test":"TestBroken","Output":"    pkg_test.go:12: want 2, got 3\n"}
+{"Action":"fail","Package":"example/pkg","Test":"TestBroken","Elapsed":0.5}
+{"Action":"fail","Package":"example/pkg","Elapsed":0.6}`
+	runner := NewCommandRunner(config.TestConfig{
+		Type:    "command",
+		Name:    "unit",
+		Run:     "cat <<'EOF'\n" + events + "\nEOF\nexit 1",
+		Format:  "go-json",
+		Timeout: 10 * time.Second,
+	}, nil)
+
+	result, err := runner.Run(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Passed {
+		t.Fatal("expected failure")
+	}
+	if len(result.Cases) != 2 {
+		t.Fatalf("expected 2 cases, got %+v", result.Cases)
+	}
+	broken := result.Cases[1]
+	if broken.Name != "TestBroken" || broken.Status != "fail" || broken.Suite != "example/pkg" {
+		t.Errorf("unexpected failing case: %+v", broken)
+	}
+	if !strings.Contains(broken.Message, "want 2, got 3") || broken.Duration != 500*time.Millisecond {
+		t.Errorf("unexpected failure details: %+v", broken)
+	}
+}
+
+func TestCommandRunner_JUnitReportFile(t *testing.T) {
+	report := filepath.Join(t.TempDir(), "report.xml")
+	xml := `<?xml version="1.0"?>
+<testsuites>
+  <testsuite name="api">
+    <testcase name="creates user" classname="users" time="0.25"/>
+    <testcase name="deletes user" classname="users" time="1.5">
+      <failure message="expected 204">status was 500</failure>
+    </testcase>
+    <testcase name="pending" classname="users"><skipped/></testcase>
+  </testsuite>
+</testsuites>`
+	if err := os.WriteFile(report, []byte(xml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runner := NewCommandRunner(config.TestConfig{
+		Type:    "command",
+		Name:    "api",
+		Run:     "exit 1",
+		Format:  "junit",
+		Report:  report,
+		Timeout: 10 * time.Second,
+	}, nil)
+
+	result, err := runner.Run(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Cases) != 3 {
+		t.Fatalf("expected 3 cases, got %+v", result.Cases)
+	}
+	failed := result.Cases[1]
+	if failed.Status != "fail" || failed.Suite != "users" || failed.Duration != 1500*time.Millisecond {
+		t.Errorf("unexpected failing case: %+v", failed)
+	}
+	if !strings.Contains(failed.Message, "expected 204") || !strings.Contains(failed.Message, "status was 500") {
+		t.Errorf("unexpected failure message: %q", failed.Message)
+	}
+	if result.Cases[2].Status != "skip" {
+		t.Errorf("expected skipped case, got %+v", result.Cases[2])
+	}
+}
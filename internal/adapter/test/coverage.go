@@ -0,0 +1,243 @@
+package test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/variable"
+)
+
+// fileCoverage counts covered and total statements (go) or lines (lcov).
+type fileCoverage struct {
+	covered int
+	total   int
+}
+
+func (c fileCoverage) percent() float64 {
+	if c.total == 0 {
+		return 100
+	}
+	return float64(c.covered) * 100 / float64(c.total)
+}
+
+// CoverageRunner runs an optional command that writes a coverage report and
+// fails when coverage of the files changed in the attempt is below the
+// configured threshold.
+type CoverageRunner struct {
+	cfg       config.TestConfig
+	workspace core.WorkspaceProvider
+}
+
+var _ core.TestRunnerIface = (*CoverageRunner)(nil)
+
+// NewCoverageRunner creates a CoverageRunner from a test configuration. The
+// command runs, and a relative report is read, in the workspace of ws as
+// for NewCommandRunner.
+func NewCoverageRunner(cfg config.TestConfig, ws core.WorkspaceProvider) *CoverageRunner {
+	return &CoverageRunner{cfg: cfg, workspace: ws}
+}
+
+// Run executes cfg.Run (if set), parses cfg.Report as a go cover profile or
+// lcov tracefile, and compares coverage of ${CHANGED_FILES} to cfg.Threshold.
+func (r *CoverageRunner) Run(ctx context.Context, vars map[string]string) (*core.TestResult, error) {
+	start := time.Now()
+	result := &core.TestResult{Name: r.cfg.Name, Type: "coverage"}
+
+	var output strings.Builder
+	if r.cfg.Run != "" {
+		cmdCfg := r.cfg
+		cmdCfg.Type, cmdCfg.Format, cmdCfg.Report = "command", "", ""
+		cmdResult, err := NewCommandRunner(cmdCfg, r.workspace).Run(ctx, vars)
+		if err != nil {
+			return nil, err
+		}
+		if !cmdResult.Passed {
+			result.Output = cmdResult.Output
+			result.Duration = time.Since(start)
+			return result, nil
+		}
+	}
+
+	path := inWorkDir(r.workspace, variable.Resolve(r.cfg.Report, vars))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		result.Output = fmt.Sprintf("read coverage report: %v", err)
+		result.Duration = time.Since(start)
+		return result, nil
+	}
+	result.Artifacts = []core.TestArtifact{{Kind: "coverage", Path: filepath.ToSlash(path)}}
+
+	var files map[string]fileCoverage
+	switch r.format(data) {
+	case "lcov":
+		files = parseLcov(data)
+	default:
+		files, err = parseGoCoverProfile(data)
+		if err != nil {
+			result.Output = err.Error()
+			result.Duration = time.Since(start)
+			return result, nil
+		}
+	}
+
+	changed := strings.Fields(vars["CHANGED_FILES"])
+	matched := coverageForChanged(files, changed)
+	if len(matched) == 0 {
+		result.Passed = true
+		result.Output = "no changed files found in coverage report"
+		result.Duration = time.Since(start)
+		return result, nil
+	}
+
+	var total fileCoverage
+	var below []string
+	names := make([]string, 0, len(matched))
+	for name := range matched {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c := matched[name]
+		total.covered += c.covered
+		total.total += c.total
+		fmt.Fprintf(&output, "%6.1f%%  %s (%d/%d)\n", c.percent(), name, c.covered, c.total)
+		if c.percent() < r.cfg.Threshold {
+			below = append(below, name)
+		}
+	}
+
+	pct := total.percent()
+	result.Passed = pct >= r.cfg.Threshold
+	if result.Passed {
+		fmt.Fprintf(&output, "coverage of changed files %.1f%% meets threshold %.1f%%", pct, r.cfg.Threshold)
+	} else {
+		fmt.Fprintf(&output, "coverage of changed files %.1f%% is below threshold %.1f%%.\n", pct, r.cfg.Threshold)
+		fmt.Fprintf(&output, "Add or extend tests that exercise: %s", strings.Join(below, ", "))
+	}
+	result.Output = output.String()
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+func (r *CoverageRunner) format(data []byte) string {
+	if r.cfg.Format != "" {
+		return r.cfg.Format
+	}
+	if bytes.HasPrefix(data, []byte("mode:")) {
+		return "go-cover"
+	}
+	return "lcov"
+}
+
+// parseGoCoverProfile reads a `go test -coverprofile` file. Blocks reported
+// more than once (e.g. by several test binaries) are merged.
+func parseGoCoverProfile(data []byte) (map[string]fileCoverage, error) {
+	type block struct {
+		stmts int
+		hit   bool
+	}
+	blocks := map[string]map[string]block{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "mode:") {
+			continue
+		}
+		// file.go:startLine.startCol,endLine.endCol numStmts count
+		colon := strings.LastIndex(line, ":")
+		if colon < 0 {
+			return nil, fmt.Errorf("parse cover profile: malformed line %q", line)
+		}
+		fields := strings.Fields(line[colon+1:])
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("parse cover profile: malformed line %q", line)
+		}
+		stmts, err1 := strconv.Atoi(fields[1])
+		count, err2 := strconv.Atoi(fields[2])
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("parse cover profile: malformed line %q", line)
+		}
+		file := line[:colon]
+		if blocks[file] == nil {
+			blocks[file] = map[string]block{}
+		}
+		b := blocks[file][fields[0]]
+		b.stmts = stmts
+		b.hit = b.hit || count > 0
+		blocks[file][fields[0]] = b
+	}
+
+	files := make(map[string]fileCoverage, len(blocks))
+	for file, fb := range blocks {
+		var c fileCoverage
+		for _, b := range fb {
+			c.total += b.stmts
+			if b.hit {
+				c.covered += b.stmts
+			}
+		}
+		files[file] = c
+	}
+	return files, scanner.Err()
+}
+
+// parseLcov reads an lcov tracefile using its DA (line hit) records.
+func parseLcov(data []byte) map[string]fileCoverage {
+	files := map[string]fileCoverage{}
+	var current string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "SF:"):
+			current = strings.TrimPrefix(line, "SF:")
+		case strings.HasPrefix(line, "DA:") && current != "":
+			parts := strings.Split(strings.TrimPrefix(line, "DA:"), ",")
+			if len(parts) < 2 {
+				continue
+			}
+			hits, err := strconv.Atoi(parts[1])
+			if err != nil {
+				continue
+			}
+			c := files[current]
+			c.total++
+			if hits > 0 {
+				c.covered++
+			}
+			files[current] = c
+		case line == "end_of_record":
+			current = ""
+		}
+	}
+	return files
+}
+
+// coverageForChanged picks report entries for the changed repo-relative
+// paths. Report paths may be module import paths or absolute paths, so
+// entries match on a path-segment suffix.
+func coverageForChanged(files map[string]fileCoverage, changed []string) map[string]fileCoverage {
+	matched := map[string]fileCoverage{}
+	for _, name := range changed {
+		name = filepath.ToSlash(name)
+		for file, c := range files {
+			file = filepath.ToSlash(file)
+			if file == name || strings.HasSuffix(file, "/"+name) {
+				matched[name] = c
+				break
+			}
+		}
+	}
+	return matched
+}
@@ -0,0 +1,120 @@
+package test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+)
+
+func writeReport(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "cover.out")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCoverageRunner_GoProfileBelowThreshold(t *testing.T) {
+	report := writeReport(t, `mode: set
+example.com/app/api/handler.go:10.2,12.3 3 1
+example.com/app/api/handler.go:14.2,20.3 5 0
+example.com/app/api/handler.go:14.2,20.3 5 0
+example.com/app/store/db.go:5.2,8.3 4 0
+`)
+	runner := NewCoverageRunner(config.TestConfig{
+		Type:      "coverage",
+		Name:      "coverage",
+		Report:    report,
+		Threshold: 80,
+	}, nil)
+
+	result, err := runner.Run(context.Background(), map[string]string{"CHANGED_FILES": "api/handler.go README.md"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Passed {
+		t.Fatalf("expected failure, got: %s", result.Output)
+	}
+	if !strings.Contains(result.Output, "37.5%") || !strings.Contains(result.Output, "exercise: api/handler.go") {
+		t.Errorf("unexpected output: %s", result.Output)
+	}
+	if strings.Contains(result.Output, "store/db.go") {
+		t.Errorf("unchanged files must not count: %s", result.Output)
+	}
+	if len(result.Artifacts) != 1 || result.Artifacts[0].Kind != "coverage" || result.Artifacts[0].Path != filepath.ToSlash(report) {
+		t.Errorf("expected the report as a coverage artifact, got %+v", result.Artifacts)
+	}
+}
+
+func TestCoverageRunner_LcovMeetsThreshold(t *testing.T) {
+	report := writeReport(t, `TN:
+SF:/work/repo/src/app.js
+DA:1,1
+DA:2,3
+DA:3,0
+DA:4,1
+end_of_record
+`)
+	runner := NewCoverageRunner(config.TestConfig{
+		Type:      "coverage",
+		Name:      "coverage",
+		Run:       "true",
+		Report:    report,
+		Threshold: 75,
+	}, nil)
+
+	result, err := runner.Run(context.Background(), map[string]string{"CHANGED_FILES": "src/app.js"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Passed || !strings.Contains(result.Output, "75.0%") {
+		t.Fatalf("expected pass at 75%%, got: %s", result.Output)
+	}
+}
+
+func TestCoverageRunner_CommandFailure(t *testing.T) {
+	runner := NewCoverageRunner(config.TestConfig{
+		Type:      "coverage",
+		Name:      "coverage",
+		Run:       "echo 'FAIL pkg' && exit 1",
+		Report:    filepath.Join(t.TempDir(), "missing.out"),
+		Threshold: 50,
+	}, nil)
+
+	result, err := runner.Run(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Passed || !strings.Contains(result.Output, "FAIL pkg") {
+		t.Fatalf("expected command failure output, got: %+v", result)
+	}
+}
+
+func TestCoverageRunner_RunsInWorkspace(t *testing.T) {
+	dir := t.TempDir()
+	runner := NewCoverageRunner(config.TestConfig{
+		Type:      "coverage",
+		Name:      "coverage",
+		Run:       "printf 'mode: set\nexample.com/app/main.go:1.1,2.2 2 1\n' > cover.out",
+		Report:    "cover.out",
+		Threshold: 80,
+		Timeout:   10 * time.Second,
+	}, workspace(dir))
+
+	result, err := runner.Run(context.Background(), map[string]string{"CHANGED_FILES": "main.go"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Passed {
+		t.Fatalf("expected the report written in the workspace to pass, got: %s", result.Output)
+	}
+	if want := filepath.ToSlash(filepath.Join(dir, "cover.out")); len(result.Artifacts) != 1 || result.Artifacts[0].Path != want {
+		t.Errorf("expected the workspace report %s as the artifact, got %+v", want, result.Artifacts)
+	}
+}
@@ -0,0 +1,147 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/variable"
+)
+
+const (
+	defaultHTTPTestTimeout = 10 * time.Second
+	defaultHTTPTestBackoff = time.Second
+	maxHTTPTestBodyBytes   = 64 * 1024
+)
+
+// HTTPRunner performs an HTTP request and checks the response status and body.
+type HTTPRunner struct {
+	cfg    config.TestConfig
+	client *http.Client
+}
+
+var _ core.TestRunnerIface = (*HTTPRunner)(nil)
+
+// NewHTTPRunner creates an HTTPRunner from a test configuration.
+func NewHTTPRunner(cfg config.TestConfig) *HTTPRunner {
+	return &HTTPRunner{cfg: cfg, client: &http.Client{}}
+}
+
+// Run performs the configured request, retrying with exponential backoff
+// until the response matches the expectations or retries are exhausted.
+// Variables are resolved in the URL, headers and body before each run.
+func (r *HTTPRunner) Run(ctx context.Context, vars map[string]string) (*core.TestResult, error) {
+	url := variable.Resolve(r.cfg.URL, vars)
+	method := strings.ToUpper(r.cfg.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	expectStatus := r.cfg.ExpectStatus
+	if expectStatus == 0 {
+		expectStatus = http.StatusOK
+	}
+	var bodyPattern *regexp.Regexp
+	if r.cfg.ExpectBody != "" {
+		re, err := regexp.Compile(r.cfg.ExpectBody)
+		if err != nil {
+			return nil, fmt.Errorf("http test %s: invalid expect_body: %w", r.cfg.Name, err)
+		}
+		bodyPattern = re
+	}
+	timeout := r.cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultHTTPTestTimeout
+	}
+	backoff := r.cfg.Backoff
+	if backoff <= 0 {
+		backoff = defaultHTTPTestBackoff
+	}
+
+	start := time.Now()
+	var log strings.Builder
+	passed := false
+
+	for attempt := 0; attempt <= r.cfg.Retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				fmt.Fprintf(&log, "cancelled: %v\n", ctx.Err())
+				return r.result(false, log.String(), time.Since(start)), nil
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		ok, detail := r.check(ctx, method, url, vars, expectStatus, bodyPattern, timeout)
+		fmt.Fprintf(&log, "attempt %d: %s %s -> %s\n", attempt+1, method, url, detail)
+		if ok {
+			passed = true
+			break
+		}
+	}
+
+	return r.result(passed, log.String(), time.Since(start)), nil
+}
+
+// check performs a single request and reports whether it met expectations.
+func (r *HTTPRunner) check(
+	ctx context.Context,
+	method, url string,
+	vars map[string]string,
+	expectStatus int,
+	bodyPattern *regexp.Regexp,
+	timeout time.Duration,
+) (bool, string) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var reqBody io.Reader
+	if r.cfg.Body != "" {
+		reqBody = strings.NewReader(variable.Resolve(r.cfg.Body, vars))
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		return false, fmt.Sprintf("create request: %v", err)
+	}
+	for k, v := range r.cfg.Headers {
+		req.Header.Set(k, variable.Resolve(v, vars))
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return false, fmt.Sprintf("timed out after %s", timeout)
+		}
+		return false, fmt.Sprintf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPTestBodyBytes))
+	if err != nil {
+		return false, fmt.Sprintf("status %d, read body: %v", resp.StatusCode, err)
+	}
+
+	if resp.StatusCode != expectStatus {
+		return false, fmt.Sprintf("status %d, expected %d\n%s", resp.StatusCode, expectStatus, string(data))
+	}
+	if bodyPattern != nil && !bodyPattern.Match(data) {
+		return false, fmt.Sprintf("status %d, body does not match %q\n%s", resp.StatusCode, bodyPattern.String(), string(data))
+	}
+	return true, fmt.Sprintf("status %d", resp.StatusCode)
+}
+
+func (r *HTTPRunner) result(passed bool, output string, duration time.Duration) *core.TestResult {
+	return &core.TestResult{
+		Name:     r.cfg.Name,
+		Type:     "http",
+		Passed:   passed,
+		Output:   output,
+		Duration: duration,
+	}
+}
@@ -0,0 +1,136 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+)
+
+func TestHTTPRunner_Success(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health/abc123" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		if r.Header.Get("X-Commit") != "abc123" {
+			t.Errorf("expected resolved header, got %q", r.Header.Get("X-Commit"))
+		}
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer srv.Close()
+
+	runner := NewHTTPRunner(config.TestConfig{
+		Type:       "http",
+		Name:       "health",
+		URL:        srv.URL + "/health/${COMMIT_SHA}",
+		Headers:    map[string]string{"X-Commit": "${COMMIT_SHA}"},
+		ExpectBody: `"status":"ok"`,
+	})
+
+	result, err := runner.Run(context.Background(), map[string]string{"COMMIT_SHA": "abc123"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Passed {
+		t.Fatalf("expected pass, got fail: %s", result.Output)
+	}
+	if result.Type != "http" || result.Name != "health" {
+		t.Errorf("unexpected result metadata: %+v", result)
+	}
+}
+
+func TestHTTPRunner_RetriesUntilHealthy(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	runner := NewHTTPRunner(config.TestConfig{
+		Type:         "http",
+		Name:         "ready",
+		URL:          srv.URL,
+		ExpectStatus: http.StatusNoContent,
+		Retries:      3,
+		Backoff:      10 * time.Millisecond,
+	})
+
+	result, err := runner.Run(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Passed {
+		t.Fatalf("expected pass after retries, got: %s", result.Output)
+	}
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Errorf("expected 3 requests, got %d", got)
+	}
+}
+
+func TestHTTPRunner_BodyMismatchFails(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("expected POST, got %s", r.Method)
+		}
+		w.Write([]byte("degraded"))
+	}))
+	defer srv.Close()
+
+	runner := NewHTTPRunner(config.TestConfig{
+		Type:       "http",
+		Name:       "body",
+		Method:     "post",
+		URL:        srv.URL,
+		ExpectBody: "^ok$",
+		Retries:    1,
+		Backoff:    time.Millisecond,
+	})
+
+	result, err := runner.Run(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Passed {
+		t.Fatal("expected failure on body mismatch")
+	}
+	if !strings.Contains(result.Output, "attempt 2") || !strings.Contains(result.Output, "does not match") {
+		t.Errorf("unexpected output: %s", result.Output)
+	}
+}
+
+func TestHTTPRunner_Timeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	runner := NewHTTPRunner(config.TestConfig{
+		Type:    "http",
+		Name:    "slow",
+		URL:     srv.URL,
+		Timeout: 100 * time.Millisecond,
+	})
+
+	result, err := runner.Run(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Passed {
+		t.Fatal("expected timeout failure")
+	}
+	if !strings.Contains(result.Output, "timed out") {
+		t.Errorf("expected timeout in output, got: %s", result.Output)
+	}
+}
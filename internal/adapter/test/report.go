@@ -0,0 +1,165 @@
+package test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rigdev/rig/internal/core"
+)
+
+// maxCaseMessageBytes bounds the failure message kept per test case.
+const maxCaseMessageBytes = 4 * 1024
+
+// goTestEvent is one line of `go test -json` output (see `go doc test2json`).
+type goTestEvent struct {
+	Action  string  `json:"Action"`
+	Package string  `json:"Package"`
+	Test    string  `json:"Test"`
+	Elapsed float64 `json:"Elapsed"`
+	Output  string  `json:"Output"`
+}
+
+// parseGoTestJSON extracts per-test results from `go test -json` output.
+// Non-JSON lines are ignored. A package that fails without any failing test
+// (for example a build error) is reported as a single failing case named
+// after the package.
+func parseGoTestJSON(data []byte) []core.TestCase {
+	type key struct{ pkg, test string }
+	outputs := map[key]*strings.Builder{}
+	failedTests := map[string]bool{}
+	var cases []core.TestCase
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || line[0] != '{' {
+			continue
+		}
+		var ev goTestEvent
+		if err := json.Unmarshal(line, &ev); err != nil {
+			continue
+		}
+		k := key{ev.Package, ev.Test}
+
+		switch ev.Action {
+		case "output":
+			b, ok := outputs[k]
+			if !ok {
+				b = &strings.Builder{}
+				outputs[k] = b
+			}
+			if b.Len() < maxCaseMessageBytes {
+				b.WriteString(ev.Output)
+			}
+		case "pass", "fail", "skip":
+			if ev.Test == "" && (ev.Action != "fail" || failedTests[ev.Package]) {
+				continue
+			}
+			name := ev.Test
+			if name == "" {
+				name = ev.Package
+			}
+			c := core.TestCase{
+				Name:     name,
+				Suite:    ev.Package,
+				Status:   ev.Action,
+				Duration: time.Duration(ev.Elapsed * float64(time.Second)),
+			}
+			if ev.Action == "fail" {
+				failedTests[ev.Package] = true
+				if b, ok := outputs[k]; ok {
+					c.Message = truncateMessage(b.String())
+				}
+			}
+			if ev.Test == "" {
+				c.Suite = ""
+			}
+			cases = append(cases, c)
+		}
+	}
+	return cases
+}
+
+type junitSuite struct {
+	Name   string       `xml:"name,attr"`
+	Cases  []junitCase  `xml:"testcase"`
+	Suites []junitSuite `xml:"testsuite"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure"`
+	Error     *junitFailure `xml:"error"`
+	Skipped   *junitFailure `xml:"skipped"`
+	SystemOut string        `xml:"system-out"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// parseJUnit extracts per-test results from a JUnit XML report. Both a
+// <testsuites> root and a bare <testsuite> root are accepted.
+func parseJUnit(data []byte) ([]core.TestCase, error) {
+	var root junitSuite
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("parse junit: %w", err)
+	}
+	var cases []core.TestCase
+	collectJUnitCases(root, &cases)
+	return cases, nil
+}
+
+func collectJUnitCases(s junitSuite, out *[]core.TestCase) {
+	for _, tc := range s.Cases {
+		suite := tc.Classname
+		if suite == "" {
+			suite = s.Name
+		}
+		c := core.TestCase{Name: tc.Name, Suite: suite, Status: "pass"}
+		if secs, err := strconv.ParseFloat(tc.Time, 64); err == nil {
+			c.Duration = time.Duration(secs * float64(time.Second))
+		}
+		switch {
+		case tc.Failure != nil:
+			c.Status = "fail"
+			c.Message = junitMessage(tc.Failure, tc.SystemOut)
+		case tc.Error != nil:
+			c.Status = "fail"
+			c.Message = junitMessage(tc.Error, tc.SystemOut)
+		case tc.Skipped != nil:
+			c.Status = "skip"
+		}
+		*out = append(*out, c)
+	}
+	for _, child := range s.Suites {
+		collectJUnitCases(child, out)
+	}
+}
+
+func junitMessage(f *junitFailure, systemOut string) string {
+	parts := []string{}
+	for _, p := range []string{f.Message, f.Text, systemOut} {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return truncateMessage(strings.Join(parts, "\n"))
+}
+
+func truncateMessage(s string) string {
+	if len(s) > maxCaseMessageBytes {
+		return s[:maxCaseMessageBytes] + "\n...(truncated)"
+	}
+	return s
+}
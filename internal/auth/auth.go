@@ -0,0 +1,89 @@
+// Package auth implements login to the web dashboard through an OIDC
+// provider or GitHub OAuth, using only the standard library: OIDC discovery,
+// the authorization code flow, RS256 ID token verification against the
+// provider's JWKS, and HMAC-signed cookies for sessions.
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+)
+
+// Identity is a user authenticated by a Provider.
+type Identity struct {
+	// User identifies the user in allowlists, roles and the audit log: the
+	// email for oidc (or the preferred username or subject when there is
+	// none), the login for github.
+	User  string `json:"user"`
+	Email string `json:"email,omitempty"`
+	Name  string `json:"name,omitempty"`
+}
+
+// Provider runs the authorization code flow against an identity provider.
+type Provider interface {
+	// AuthCodeURL is where the browser is sent to log in.
+	AuthCodeURL(ctx context.Context, state, nonce string) (string, error)
+	// Exchange trades the code from the callback for the user's identity.
+	Exchange(ctx context.Context, code, nonce string) (*Identity, error)
+}
+
+// DefaultSessionTTL is how long a login lasts unless server.auth.session_ttl
+// says otherwise.
+const DefaultSessionTTL = 12 * time.Hour
+
+// httpTimeout bounds each call to the provider.
+const httpTimeout = 15 * time.Second
+
+// New returns the provider configured in cfg. Nothing is fetched until the
+// first login.
+func New(cfg config.AuthConfig) (Provider, error) {
+	hc := &http.Client{Timeout: httpTimeout}
+	switch cfg.Provider {
+	case "oidc":
+		return newOIDC(cfg, hc), nil
+	case "github":
+		return newGitHub(cfg, hc), nil
+	}
+	return nil, fmt.Errorf("unknown auth provider %q (want oidc or github)", cfg.Provider)
+}
+
+// Allowed reports whether id may log in under cfg.AllowedUsers and
+// cfg.AllowedDomains.
+func Allowed(cfg config.AuthConfig, id *Identity) bool {
+	if len(cfg.AllowedUsers) == 0 && len(cfg.AllowedDomains) == 0 {
+		return true
+	}
+	for _, u := range cfg.AllowedUsers {
+		if strings.EqualFold(u, id.User) || (id.Email != "" && strings.EqualFold(u, id.Email)) {
+			return true
+		}
+	}
+	if at := strings.LastIndex(id.Email, "@"); at >= 0 {
+		domain := id.Email[at+1:]
+		for _, d := range cfg.AllowedDomains {
+			if strings.EqualFold(strings.TrimPrefix(d, "@"), domain) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RoleFor returns the role of id: its entry in cfg.Roles, else
+// cfg.DefaultRole, else viewer.
+func RoleFor(cfg config.AuthConfig, id *Identity) string {
+	for user, role := range cfg.Roles {
+		if strings.EqualFold(user, id.User) || (id.Email != "" && strings.EqualFold(user, id.Email)) {
+			return role
+		}
+	}
+	if cfg.DefaultRole != "" {
+		return cfg.DefaultRole
+	}
+	return "viewer"
+}
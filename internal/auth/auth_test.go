@@ -0,0 +1,267 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+)
+
+// fakeIssuer is an OIDC provider that issues the claims set by the test.
+type fakeIssuer struct {
+	*httptest.Server
+	key    *rsa.PrivateKey
+	claims map[string]any
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeIssuer{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 f.URL,
+			"authorization_endpoint": f.URL + "/authorize",
+			"token_endpoint":         f.URL + "/token",
+			"jwks_uri":               f.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "k1",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != "good-code" || r.FormValue("client_secret") != "secret" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": f.sign(t, f.claims)})
+	})
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeIssuer) sign(t *testing.T, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "k1"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, f.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func (f *fakeIssuer) config() config.AuthConfig {
+	return config.AuthConfig{
+		Provider:     "oidc",
+		Issuer:       f.URL,
+		ClientID:     "rig",
+		ClientSecret: "secret",
+		RedirectURL:  "https://rig.example.com/auth/callback",
+	}
+}
+
+func TestOIDCLogin(t *testing.T) {
+	f := newFakeIssuer(t)
+	p, err := New(f.config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	authURL, err := p.AuthCodeURL(ctx, "st", "nc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(authURL)
+	if !strings.HasSuffix(u.Path, "/authorize") || u.Query().Get("nonce") != "nc" || u.Query().Get("scope") != "openid email profile" {
+		t.Errorf("unexpected auth URL %s", authURL)
+	}
+
+	valid := func() map[string]any {
+		return map[string]any{
+			"iss":   f.URL,
+			"sub":   "123",
+			"aud":   []string{"rig", "other"},
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"nonce": "nc",
+			"email": "alice@example.com",
+			"name":  "Alice",
+		}
+	}
+
+	f.claims = valid()
+	id, err := p.Exchange(ctx, "good-code", "nc")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if id.User != "alice@example.com" || id.Name != "Alice" {
+		t.Errorf("identity = %+v", id)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(map[string]any)
+		code   string
+	}{
+		{"bad code", func(map[string]any) {}, "bad-code"},
+		{"wrong nonce", func(c map[string]any) { c["nonce"] = "other" }, "good-code"},
+		{"wrong audience", func(c map[string]any) { c["aud"] = "someone-else" }, "good-code"},
+		{"wrong issuer", func(c map[string]any) { c["iss"] = "https://evil.example.com" }, "good-code"},
+		{"expired", func(c map[string]any) { c["exp"] = time.Now().Add(-time.Hour).Unix() }, "good-code"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f.claims = valid()
+			tt.mutate(f.claims)
+			if _, err := p.Exchange(ctx, tt.code, "nc"); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+
+	t.Run("unverified email is not the user", func(t *testing.T) {
+		f.claims = valid()
+		f.claims["email_verified"] = false
+		f.claims["preferred_username"] = "alice"
+		id, err := p.Exchange(ctx, "good-code", "nc")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id.User != "alice" || id.Email != "" {
+			t.Errorf("identity = %+v, want user alice without email", id)
+		}
+	})
+}
+
+func TestOIDCRejectsForgedSignature(t *testing.T) {
+	f := newFakeIssuer(t)
+	p := newOIDC(f.config(), http.DefaultClient)
+	d, err := p.discover(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := f.sign(t, map[string]any{"iss": f.URL})
+	parts := strings.Split(token, ".")
+	forged, _ := json.Marshal(map[string]any{"iss": f.URL, "sub": "admin"})
+	parts[1] = base64.RawURLEncoding.EncodeToString(forged)
+
+	var claims map[string]any
+	if err := p.verify(context.Background(), d, strings.Join(parts, "."), &claims); err == nil {
+		t.Error("forged token verified")
+	}
+}
+
+func TestGitHubLogin(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login/oauth/access_token":
+			if r.FormValue("code") != "good-code" {
+				json.NewEncoder(w).Encode(map[string]string{"error": "bad_verification_code", "error_description": "bad code"})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"access_token": "gho_token"})
+		case "/api/v3/user":
+			if r.Header.Get("Authorization") != "Bearer gho_token" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"login": "octocat", "email": "octo@github.example.com"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	p, err := New(config.AuthConfig{Provider: "github", Issuer: srv.URL, ClientID: "id", ClientSecret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL, _ := p.AuthCodeURL(context.Background(), "st", "")
+	if !strings.HasPrefix(authURL, srv.URL+"/login/oauth/authorize?") {
+		t.Errorf("auth URL %s does not point at the enterprise server", authURL)
+	}
+	id, err := p.Exchange(context.Background(), "good-code", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.User != "octocat" {
+		t.Errorf("user = %q, want octocat", id.User)
+	}
+	if _, err := p.Exchange(context.Background(), "bad-code", ""); err == nil || !strings.Contains(err.Error(), "bad_verification_code") {
+		t.Errorf("bad code: err = %v", err)
+	}
+}
+
+func TestAllowedAndRoleFor(t *testing.T) {
+	cfg := config.AuthConfig{
+		AllowedUsers:   []string{"octocat"},
+		AllowedDomains: []string{"example.com"},
+		DefaultRole:    "operator",
+		Roles:          map[string]string{"Alice@Example.com": "admin"},
+	}
+	alice := &Identity{User: "alice@example.com", Email: "alice@example.com"}
+	octocat := &Identity{User: "octocat"}
+	mallory := &Identity{User: "mallory@evil.com", Email: "mallory@evil.com"}
+
+	if !Allowed(cfg, alice) || !Allowed(cfg, octocat) || Allowed(cfg, mallory) {
+		t.Error("allowlist not applied")
+	}
+	if !Allowed(config.AuthConfig{}, mallory) {
+		t.Error("empty allowlists should allow everyone")
+	}
+	if got := RoleFor(cfg, alice); got != "admin" {
+		t.Errorf("RoleFor(alice) = %q, want admin", got)
+	}
+	if got := RoleFor(cfg, octocat); got != "operator" {
+		t.Errorf("RoleFor(octocat) = %q, want operator", got)
+	}
+	if got := RoleFor(config.AuthConfig{}, octocat); got != "viewer" {
+		t.Errorf("RoleFor without config = %q, want viewer", got)
+	}
+}
+
+func TestSigner(t *testing.T) {
+	s := NewSigner("k")
+	sealed, err := s.Seal(Session{User: "alice", Role: "admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Session
+	if err := s.Open(sealed, &got); err != nil || got.User != "alice" {
+		t.Fatalf("Open = %+v, %v", got, err)
+	}
+
+	forged, _ := json.Marshal(Session{User: "mallory", Role: "admin"})
+	_, sig, _ := strings.Cut(sealed, ".")
+	if err := s.Open(base64.RawURLEncoding.EncodeToString(forged)+"."+sig, &got); err == nil {
+		t.Error("tampered payload accepted")
+	}
+	if err := NewSigner("other").Open(sealed, &got); err == nil {
+		t.Error("value sealed with another secret accepted")
+	}
+}
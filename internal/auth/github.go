@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/rigdev/rig/internal/config"
+)
+
+// githubProvider logs in with a GitHub (or GitHub Enterprise Server) OAuth
+// app. GitHub is not an OIDC provider, so the identity comes from the user
+// API instead of an ID token.
+type githubProvider struct {
+	cfg     config.AuthConfig
+	hc      *http.Client
+	webURL  string
+	apiBase string
+}
+
+func newGitHub(cfg config.AuthConfig, hc *http.Client) *githubProvider {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"read:user", "user:email"}
+	}
+	p := &githubProvider{cfg: cfg, hc: hc, webURL: "https://github.com", apiBase: "https://api.github.com"}
+	if base := strings.TrimRight(cfg.Issuer, "/"); base != "" && base != p.webURL {
+		p.webURL, p.apiBase = base, base+"/api/v3"
+	}
+	return p
+}
+
+func (p *githubProvider) AuthCodeURL(_ context.Context, state, _ string) (string, error) {
+	q := url.Values{
+		"client_id":    {p.cfg.ClientID},
+		"redirect_uri": {p.cfg.RedirectURL},
+		"scope":        {strings.Join(p.cfg.Scopes, " ")},
+		"state":        {state},
+	}
+	return withQuery(p.webURL+"/login/oauth/authorize", q), nil
+}
+
+func (p *githubProvider) Exchange(ctx context.Context, code, _ string) (*Identity, error) {
+	var tok struct {
+		AccessToken      string `json:"access_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	form := url.Values{
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"client_secret": {p.cfg.ClientSecret},
+	}
+	if err := postForm(ctx, p.hc, p.webURL+"/login/oauth/access_token", form, &tok); err != nil {
+		return nil, fmt.Errorf("github token exchange: %w", err)
+	}
+	if tok.Error != "" {
+		return nil, fmt.Errorf("github token exchange: %s: %s", tok.Error, tok.ErrorDescription)
+	}
+	if tok.AccessToken == "" {
+		return nil, errors.New("github token exchange: no access token")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.apiBase+"/user", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+tok.AccessToken)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	var user struct {
+		Login string `json:"login"`
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	}
+	if err := doJSON(p.hc, req, &user); err != nil {
+		return nil, fmt.Errorf("github user: %w", err)
+	}
+	if user.Login == "" {
+		return nil, errors.New("github user: no login")
+	}
+	return &Identity{User: user.Login, Email: user.Email, Name: user.Name}, nil
+}
@@ -0,0 +1,309 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+)
+
+// clockSkew is tolerated when checking ID token expiry.
+const clockSkew = time.Minute
+
+// oidcProvider logs in through an OpenID Connect issuer such as Google or
+// Keycloak.
+type oidcProvider struct {
+	cfg config.AuthConfig
+	hc  *http.Client
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	keys      map[string]*rsa.PublicKey
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+func newOIDC(cfg config.AuthConfig, hc *http.Client) *oidcProvider {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	return &oidcProvider{cfg: cfg, hc: hc}
+}
+
+// discover fetches and caches the issuer's discovery document.
+func (p *oidcProvider) discover(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+	issuer := strings.TrimRight(p.cfg.Issuer, "/")
+	var d oidcDiscovery
+	if err := getJSON(ctx, p.hc, issuer+"/.well-known/openid-configuration", &d); err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	if strings.TrimRight(d.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("oidc discovery: issuer %q does not match configured %q", d.Issuer, p.cfg.Issuer)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, errors.New("oidc discovery: document lacks authorization, token or jwks endpoint")
+	}
+	p.discovery = &d
+	return &d, nil
+}
+
+func (p *oidcProvider) AuthCodeURL(ctx context.Context, state, nonce string) (string, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	q := url.Values{
+		"response_type": {"code"},
+		"client_id":     {p.cfg.ClientID},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"scope":         {strings.Join(p.cfg.Scopes, " ")},
+		"state":         {state},
+		"nonce":         {nonce},
+	}
+	return withQuery(d.AuthorizationEndpoint, q), nil
+}
+
+func (p *oidcProvider) Exchange(ctx context.Context, code, nonce string) (*Identity, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var tok struct {
+		IDToken string `json:"id_token"`
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"client_secret": {p.cfg.ClientSecret},
+	}
+	if err := postForm(ctx, p.hc, d.TokenEndpoint, form, &tok); err != nil {
+		return nil, fmt.Errorf("oidc token exchange: %w", err)
+	}
+	if tok.IDToken == "" {
+		return nil, errors.New("oidc token exchange: response has no id_token")
+	}
+
+	var claims struct {
+		Issuer            string          `json:"iss"`
+		Subject           string          `json:"sub"`
+		Audience          json.RawMessage `json:"aud"`
+		Expiry            int64           `json:"exp"`
+		Nonce             string          `json:"nonce"`
+		Email             string          `json:"email"`
+		EmailVerified     *bool           `json:"email_verified"`
+		Name              string          `json:"name"`
+		PreferredUsername string          `json:"preferred_username"`
+	}
+	if err := p.verify(ctx, d, tok.IDToken, &claims); err != nil {
+		return nil, err
+	}
+	if strings.TrimRight(claims.Issuer, "/") != strings.TrimRight(d.Issuer, "/") {
+		return nil, fmt.Errorf("id token: issuer %q does not match", claims.Issuer)
+	}
+	if !audienceContains(claims.Audience, p.cfg.ClientID) {
+		return nil, errors.New("id token: audience does not include the client ID")
+	}
+	if time.Unix(claims.Expiry, 0).Add(clockSkew).Before(time.Now()) {
+		return nil, errors.New("id token: expired")
+	}
+	if claims.Nonce != nonce {
+		return nil, errors.New("id token: nonce mismatch")
+	}
+
+	id := &Identity{Name: claims.Name}
+	// Unverified addresses must not match allowlists or roles.
+	if claims.EmailVerified == nil || *claims.EmailVerified {
+		id.Email = claims.Email
+	}
+	switch {
+	case id.Email != "":
+		id.User = id.Email
+	case claims.PreferredUsername != "":
+		id.User = claims.PreferredUsername
+	default:
+		id.User = claims.Subject
+	}
+	if id.User == "" {
+		return nil, errors.New("id token: no subject")
+	}
+	return id, nil
+}
+
+// verify checks the RS256 signature of a JWT and decodes its claims.
+func (p *oidcProvider) verify(ctx context.Context, d *oidcDiscovery, token string, claims any) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return errors.New("id token: malformed")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return fmt.Errorf("id token header: %w", err)
+	}
+	if header.Alg != "RS256" {
+		return fmt.Errorf("id token: unsupported algorithm %q (want RS256)", header.Alg)
+	}
+	key, err := p.key(ctx, d, header.Kid)
+	if err != nil {
+		return err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return fmt.Errorf("id token signature: %w", err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+		return errors.New("id token: invalid signature")
+	}
+	if err := decodeSegment(parts[1], claims); err != nil {
+		return fmt.Errorf("id token claims: %w", err)
+	}
+	return nil
+}
+
+// key returns the signing key kid, refetching the JWKS once when the key is
+// unknown so rotated keys are picked up.
+func (p *oidcProvider) key(ctx context.Context, d *oidcDiscovery, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if k := pickKey(p.keys, kid); k != nil {
+		return k, nil
+	}
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := getJSON(ctx, p.hc, d.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("fetch jwks: %w", err)
+	}
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	p.keys = keys
+	if k := pickKey(keys, kid); k != nil {
+		return k, nil
+	}
+	return nil, fmt.Errorf("id token: unknown signing key %q", kid)
+}
+
+// pickKey returns keys[kid], or the only key when the token names none.
+func pickKey(keys map[string]*rsa.PublicKey, kid string) *rsa.PublicKey {
+	if k, ok := keys[kid]; ok {
+		return k
+	}
+	if kid == "" && len(keys) == 1 {
+		for _, k := range keys {
+			return k
+		}
+	}
+	return nil
+}
+
+func audienceContains(raw json.RawMessage, clientID string) bool {
+	var one string
+	if json.Unmarshal(raw, &one) == nil {
+		return one == clientID
+	}
+	var many []string
+	if json.Unmarshal(raw, &many) == nil {
+		for _, a := range many {
+			if a == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func decodeSegment(seg string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// --- HTTP helpers shared by the providers ---
+
+func getJSON(ctx context.Context, hc *http.Client, u string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	return doJSON(hc, req, out)
+}
+
+func postForm(ctx context.Context, hc *http.Client, u string, form url.Values, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	return doJSON(hc, req, out)
+}
+
+func doJSON(hc *http.Client, req *http.Request, out any) error {
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s returned %d: %s", req.URL.Host, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, out)
+}
+
+func withQuery(endpoint string, q url.Values) string {
+	sep := "?"
+	if strings.Contains(endpoint, "?") {
+		sep = "&"
+	}
+	return endpoint + sep + q.Encode()
+}
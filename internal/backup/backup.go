@@ -0,0 +1,377 @@
+// Package backup writes and reads rig backups: one gzip-compressed tar of
+// the state file, the SQLite database, rig.yaml and the state and config
+// of each tenant with a manifest of their checksums, optionally encrypted
+// with a passphrase.
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Names of the files in a backup.
+const (
+	StateFile  = "state.json"
+	DBFile     = "rig.db"
+	ConfigFile = "rig.yaml"
+
+	manifestFile = "manifest.json"
+)
+
+// fileNames are the files a backup may hold besides those of tenants.
+var fileNames = []string{StateFile, DBFile, ConfigFile}
+
+// tenantFile is the form of the name of a tenant's file in a backup.
+var tenantFile = regexp.MustCompile(`^tenants/([a-z0-9][a-z0-9-]{0,62})/(` + regexp.QuoteMeta(StateFile) + `|` + regexp.QuoteMeta(ConfigFile) + `)$`)
+
+// TenantStateFile returns the name in a backup of the state file of the
+// tenant name, which is where it is relative to the state file's directory.
+func TenantStateFile(name string) string {
+	return path.Join("tenants", name, StateFile)
+}
+
+// TenantConfigFile returns the name in a backup of the config file of the
+// tenant name.
+func TenantConfigFile(name string) string {
+	return path.Join("tenants", name, ConfigFile)
+}
+
+// Tenant returns the tenant of the backup file name and its base name,
+// StateFile or ConfigFile, or false for a file of no tenant.
+func Tenant(name string) (tenant, file string, ok bool) {
+	m := tenantFile.FindStringSubmatch(name)
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
+
+// validName reports whether a backup may hold a file named name.
+func validName(name string) bool {
+	return slices.Contains(fileNames, name) || tenantFile.MatchString(name)
+}
+
+// manifestVersion is the version of the backup format Write produces.
+const manifestVersion = 1
+
+// File is a file in a backup.
+type File struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest describes a backup. It is the last entry of the tar, so a backup
+// cut short has none.
+type Manifest struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Host      string    `json:"host,omitempty"`
+	Files     []File    `json:"files"`
+}
+
+// File returns the file of the manifest named name, or nil.
+func (m *Manifest) File(name string) *File {
+	for i := range m.Files {
+		if m.Files[i].Name == name {
+			return &m.Files[i]
+		}
+	}
+	return nil
+}
+
+// Write writes a backup of the files at paths, keyed by their name in the
+// backup, to w. A non-empty passphrase encrypts it.
+func Write(w io.Writer, paths map[string]string, passphrase string) (*Manifest, error) {
+	out := w
+	var enc *encryptWriter
+	if passphrase != "" {
+		var err error
+		if enc, err = newEncryptWriter(w, passphrase); err != nil {
+			return nil, err
+		}
+		out = enc
+	}
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+
+	host, _ := os.Hostname()
+	m := &Manifest{Version: manifestVersion, CreatedAt: time.Now().UTC(), Host: host}
+	names := make([]string, 0, len(paths))
+	for name := range paths {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if !validName(name) {
+			return nil, fmt.Errorf("unknown backup file %q", name)
+		}
+		f, err := addFile(tw, name, paths[name])
+		if err != nil {
+			return nil, err
+		}
+		m.Files = append(m.Files, f)
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal manifest: %w", err)
+	}
+	hdr := &tar.Header{Name: manifestFile, Mode: 0o600, Size: int64(len(data)), ModTime: m.CreatedAt}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return nil, fmt.Errorf("write manifest: %w", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return nil, fmt.Errorf("write manifest: %w", err)
+	}
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("close tar: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("close gzip: %w", err)
+	}
+	if enc != nil {
+		if err := enc.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// addFile copies the file at path into tw as name.
+func addFile(tw *tar.Writer, name, path string) (File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return File{}, fmt.Errorf("open %s: %w", name, err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return File{}, fmt.Errorf("stat %s: %w", name, err)
+	}
+	hdr := &tar.Header{Name: name, Mode: 0o600, Size: info.Size(), ModTime: info.ModTime()}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return File{}, fmt.Errorf("write %s: %w", name, err)
+	}
+	sum := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tw, sum), f)
+	if err != nil {
+		return File{}, fmt.Errorf("write %s: %w", name, err)
+	}
+	return File{Name: name, Size: n, SHA256: hex.EncodeToString(sum.Sum(nil))}, nil
+}
+
+// WriteFile writes a backup of paths to the file path, readable by the
+// owner only. The file appears complete or not at all.
+func WriteFile(path string, paths map[string]string, passphrase string) (*Manifest, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("create backup dir: %w", err)
+	}
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("create backup: %w", err)
+	}
+	m, err := Write(f, paths, passphrase)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+	return m, nil
+}
+
+// Extract reads the backup r into dir and checks each file against the
+// manifest. An encrypted backup needs its passphrase: without one Extract
+// returns ErrPassphraseRequired, with a wrong one ErrBadPassphrase.
+func Extract(r io.Reader, dir, passphrase string) (*Manifest, error) {
+	br := bufio.NewReader(r)
+	var in io.Reader = br
+	if encrypted(br) {
+		if passphrase == "" {
+			return nil, ErrPassphraseRequired
+		}
+		dec, err := newDecryptReader(br, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		in = dec
+	}
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		if errors.Is(err, ErrBadPassphrase) || errors.Is(err, ErrTruncated) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("not a rig backup: %w", err)
+	}
+	tr := tar.NewReader(gz)
+
+	var m *Manifest
+	sums := make(map[string]File)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read backup: %w", err)
+		}
+		if hdr.Name == manifestFile {
+			m = &Manifest{}
+			if err := json.NewDecoder(tr).Decode(m); err != nil {
+				return nil, fmt.Errorf("read manifest: %w", err)
+			}
+			continue
+		}
+		if hdr.Typeflag != tar.TypeReg || !validName(hdr.Name) {
+			return nil, fmt.Errorf("unexpected entry %q in backup", hdr.Name)
+		}
+		f, err := extractFile(tr, filepath.Join(dir, hdr.Name))
+		if err != nil {
+			return nil, fmt.Errorf("extract %s: %w", hdr.Name, err)
+		}
+		f.Name = hdr.Name
+		sums[hdr.Name] = f
+	}
+
+	if m == nil {
+		return nil, fmt.Errorf("%w: backup has no manifest", ErrTruncated)
+	}
+	if m.Version > manifestVersion {
+		return nil, fmt.Errorf("backup format %d is newer than this rig reads (%d)", m.Version, manifestVersion)
+	}
+	for _, want := range m.Files {
+		got, ok := sums[want.Name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s is missing", ErrTruncated, want.Name)
+		}
+		if got != want {
+			return nil, fmt.Errorf("%s does not match its checksum in the manifest", want.Name)
+		}
+	}
+	return m, nil
+}
+
+// extractFile writes r to path and returns its size and checksum.
+func extractFile(r io.Reader, path string) (File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return File{}, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return File{}, err
+	}
+	sum := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, sum), r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return File{}, err
+	}
+	return File{Size: n, SHA256: hex.EncodeToString(sum.Sum(nil))}, nil
+}
+
+// Backup file names are rig-backup-<time>.tar.gz, with .enc appended when
+// encrypted.
+const (
+	filePrefix = "rig-backup-"
+	timeLayout = "20060102-150405"
+	plainExt   = ".tar.gz"
+	cryptExt   = ".tar.gz.enc"
+)
+
+// FileName returns the file name of a backup taken at t.
+func FileName(t time.Time, encrypted bool) string {
+	name := filePrefix + t.UTC().Format(timeLayout)
+	if encrypted {
+		return name + cryptExt
+	}
+	return name + plainExt
+}
+
+// Info is a backup file in a directory.
+type Info struct {
+	Path      string    `json:"path"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+	Encrypted bool      `json:"encrypted"`
+}
+
+// List returns the backups FileName named in dir, newest first. A missing
+// dir has none.
+func List(dir string) ([]Info, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("list backups: %w", err)
+	}
+	var backups []Info
+	for _, e := range entries {
+		name := e.Name()
+		rest, ok := strings.CutPrefix(name, filePrefix)
+		if !ok || e.IsDir() {
+			continue
+		}
+		stamp, ext, _ := strings.Cut(rest, ".")
+		at, err := time.Parse(timeLayout, stamp)
+		if err != nil || ("."+ext != plainExt && "."+ext != cryptExt) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, Info{
+			Path:      filepath.Join(dir, name),
+			Size:      info.Size(),
+			CreatedAt: at,
+			Encrypted: "."+ext == cryptExt,
+		})
+	}
+	slices.SortFunc(backups, func(a, b Info) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	return backups, nil
+}
+
+// Prune deletes the backups in dir beyond the keep newest and returns the
+// deleted paths.
+func Prune(dir string, keep int) ([]string, error) {
+	backups, err := List(dir)
+	if err != nil || len(backups) <= keep {
+		return nil, err
+	}
+	var deleted []string
+	var errs []error
+	for _, b := range backups[keep:] {
+		if err := os.Remove(b.Path); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		deleted = append(deleted, b.Path)
+	}
+	return deleted, errors.Join(errs...)
+}
@@ -0,0 +1,152 @@
+package backup
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testFiles writes a state file and a config larger than a chunk and
+// returns their paths by backup name.
+func testFiles(t *testing.T) map[string]string {
+	t.Helper()
+	dir := t.TempDir()
+	paths := map[string]string{
+		StateFile:  filepath.Join(dir, "state.json"),
+		ConfigFile: filepath.Join(dir, "rig.yaml"),
+	}
+	os.WriteFile(paths[StateFile], []byte(`{"version":"1.0","tasks":[]}`), 0o644)
+	os.WriteFile(paths[ConfigFile], []byte(strings.Repeat("project:\n  name: app\n", 10000)), 0o644)
+	return paths
+}
+
+func TestWriteExtract(t *testing.T) {
+	for _, passphrase := range []string{"", "correct horse"} {
+		paths := testFiles(t)
+		var buf bytes.Buffer
+		m, err := Write(&buf, paths, passphrase)
+		if err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		if len(m.Files) != 2 || m.File(ConfigFile) == nil {
+			t.Fatalf("unexpected manifest %+v", m)
+		}
+		if encrypted := bytes.HasPrefix(buf.Bytes(), []byte(magic)); encrypted != (passphrase != "") {
+			t.Errorf("passphrase %q: encrypted = %v", passphrase, encrypted)
+		}
+
+		dir := t.TempDir()
+		got, err := Extract(bytes.NewReader(buf.Bytes()), dir, passphrase)
+		if err != nil {
+			t.Fatalf("extract: %v", err)
+		}
+		if got.Version != manifestVersion || len(got.Files) != 2 {
+			t.Errorf("unexpected manifest %+v", got)
+		}
+		for name, path := range paths {
+			want, _ := os.ReadFile(path)
+			data, _ := os.ReadFile(filepath.Join(dir, name))
+			if !bytes.Equal(data, want) {
+				t.Errorf("passphrase %q: %s differs after restore", passphrase, name)
+			}
+		}
+	}
+}
+
+func TestWriteExtractTenant(t *testing.T) {
+	paths := testFiles(t)
+	dir := filepath.Dir(paths[StateFile])
+	paths[TenantStateFile("team-a")] = filepath.Join(dir, "team-a.json")
+	paths[TenantConfigFile("team-a")] = filepath.Join(dir, "team-a.yaml")
+	os.WriteFile(paths[TenantStateFile("team-a")], []byte(`{"version":"1.0","tasks":[{"id":"task-1"}]}`), 0o644)
+	os.WriteFile(paths[TenantConfigFile("team-a")], []byte("project:\n  name: a\n"), 0o644)
+
+	var buf bytes.Buffer
+	if _, err := Write(&buf, paths, ""); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	out := t.TempDir()
+	m, err := Extract(bytes.NewReader(buf.Bytes()), out, "")
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if m.File("tenants/team-a/state.json") == nil || m.File("tenants/team-a/rig.yaml") == nil {
+		t.Fatalf("expected the tenant's files in the manifest, got %+v", m.Files)
+	}
+	for _, name := range []string{TenantStateFile("team-a"), TenantConfigFile("team-a")} {
+		want, _ := os.ReadFile(paths[name])
+		data, _ := os.ReadFile(filepath.Join(out, name))
+		if !bytes.Equal(data, want) {
+			t.Errorf("%s differs after restore", name)
+		}
+	}
+	if tenant, file, ok := Tenant(TenantStateFile("team-a")); !ok || tenant != "team-a" || file != StateFile {
+		t.Errorf("Tenant = %q, %q, %v", tenant, file, ok)
+	}
+	if _, _, ok := Tenant(StateFile); ok {
+		t.Error("expected the top-level state file to belong to no tenant")
+	}
+}
+
+func TestExtractEncryptedErrors(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := Write(&buf, testFiles(t), "secret"); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	if _, err := Extract(bytes.NewReader(data), t.TempDir(), ""); !errors.Is(err, ErrPassphraseRequired) {
+		t.Errorf("no passphrase: %v", err)
+	}
+	if _, err := Extract(bytes.NewReader(data), t.TempDir(), "wrong"); !errors.Is(err, ErrBadPassphrase) {
+		t.Errorf("wrong passphrase: %v", err)
+	}
+	if _, err := Extract(bytes.NewReader(data[:len(data)-10]), t.TempDir(), "secret"); err == nil {
+		t.Error("expected a truncated backup to fail")
+	}
+	tampered := bytes.Clone(data)
+	tampered[len(tampered)/2] ^= 1
+	if _, err := Extract(bytes.NewReader(tampered), t.TempDir(), "secret"); !errors.Is(err, ErrBadPassphrase) {
+		t.Errorf("tampered backup: %v", err)
+	}
+}
+
+func TestWriteRejectsUnknownFile(t *testing.T) {
+	for _, name := range []string{"../etc/passwd", "tenants/../state.json", "tenants/a/b/state.json", "tenants/a/secrets.txt"} {
+		if _, err := Write(&bytes.Buffer{}, map[string]string{name: "/etc/passwd"}, ""); err == nil {
+			t.Errorf("expected the file name %q to be rejected", name)
+		}
+	}
+}
+
+func TestListPrune(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	for i := range 4 {
+		name := FileName(start.Add(time.Duration(i)*time.Hour), i%2 == 1)
+		if _, err := WriteFile(filepath.Join(dir, name), testFiles(t), ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0o644)
+
+	backups, err := List(dir)
+	if err != nil || len(backups) != 4 {
+		t.Fatalf("list: %v, %+v", err, backups)
+	}
+	if !backups[0].CreatedAt.Equal(start.Add(3*time.Hour)) || !backups[0].Encrypted {
+		t.Errorf("expected the newest, encrypted backup first, got %+v", backups[0])
+	}
+
+	deleted, err := Prune(dir, 2)
+	if err != nil || len(deleted) != 2 {
+		t.Fatalf("prune: %v, %v", err, deleted)
+	}
+	if backups, _ := List(dir); len(backups) != 2 || !backups[1].CreatedAt.Equal(start.Add(2*time.Hour)) {
+		t.Errorf("expected the 2 newest backups kept, got %+v", backups)
+	}
+}
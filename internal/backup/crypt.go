@@ -0,0 +1,200 @@
+package backup
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+var (
+	// ErrPassphraseRequired is returned when reading an encrypted backup
+	// without a passphrase.
+	ErrPassphraseRequired = errors.New("backup is encrypted; a passphrase is required")
+	// ErrBadPassphrase is returned when an encrypted backup does not open
+	// with the passphrase, or was altered.
+	ErrBadPassphrase = errors.New("wrong passphrase or corrupt backup")
+	// ErrTruncated is returned for a backup that ends early.
+	ErrTruncated = errors.New("backup is truncated")
+)
+
+// An encrypted backup is the header, magic, salt and nonce prefix,
+// followed by chunks sealed with AES-256-GCM under a key derived from the
+// passphrase with scrypt. Each chunk is a flag byte, marking the last one,
+// the length of the sealed chunk and the sealed chunk. The header and flag
+// are authenticated, so a backup cut at a chunk boundary is detected too.
+const (
+	magic      = "RIGBAK\x00\x01"
+	saltSize   = 16
+	prefixSize = 8
+	headerSize = len(magic) + saltSize + prefixSize
+	chunkSize  = 64 << 10
+
+	flagMore  = 0
+	flagFinal = 1
+)
+
+// scrypt cost parameters.
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// encrypted reports whether r starts with the header of an encrypted
+// backup.
+func encrypted(r *bufio.Reader) bool {
+	b, err := r.Peek(len(magic))
+	return err == nil && string(b) == magic
+}
+
+// newAEAD derives the key of passphrase and salt.
+func newAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, 32)
+	if err != nil {
+		return nil, fmt.Errorf("derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce is the nonce of the n-th chunk.
+func chunkNonce(prefix []byte, n uint32) []byte {
+	nonce := make([]byte, prefixSize+4)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[prefixSize:], n)
+	return nonce
+}
+
+// encryptWriter seals what is written to it in chunks. Close seals the
+// last chunk.
+type encryptWriter struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	header []byte
+	n      uint32
+	buf    []byte
+}
+
+func newEncryptWriter(w io.Writer, passphrase string) (*encryptWriter, error) {
+	header := make([]byte, headerSize)
+	copy(header, magic)
+	if _, err := rand.Read(header[len(magic):]); err != nil {
+		return nil, fmt.Errorf("generate salt: %w", err)
+	}
+	aead, err := newAEAD(passphrase, header[len(magic):len(magic)+saltSize])
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, fmt.Errorf("write backup: %w", err)
+	}
+	return &encryptWriter{w: w, aead: aead, header: header, buf: make([]byte, 0, chunkSize)}, nil
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	written := len(p)
+	for len(p) > 0 {
+		if len(e.buf) == chunkSize {
+			if err := e.seal(flagMore); err != nil {
+				return 0, err
+			}
+		}
+		n := min(len(p), chunkSize-len(e.buf))
+		e.buf = append(e.buf, p[:n]...)
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// Close seals the buffered data as the last chunk.
+func (e *encryptWriter) Close() error {
+	return e.seal(flagFinal)
+}
+
+func (e *encryptWriter) seal(flag byte) error {
+	if e.n == ^uint32(0) {
+		return errors.New("backup too large to encrypt")
+	}
+	aad := append(append([]byte{}, e.header...), flag)
+	sealed := e.aead.Seal(nil, chunkNonce(e.header[len(magic)+saltSize:], e.n), e.buf, aad)
+	e.n++
+	e.buf = e.buf[:0]
+	frame := make([]byte, 5, 5+len(sealed))
+	frame[0] = flag
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(sealed)))
+	if _, err := e.w.Write(append(frame, sealed...)); err != nil {
+		return fmt.Errorf("write backup: %w", err)
+	}
+	return nil
+}
+
+// decryptReader opens the chunks an encryptWriter sealed.
+type decryptReader struct {
+	r      io.Reader
+	aead   cipher.AEAD
+	header []byte
+	n      uint32
+	buf    []byte
+	final  bool
+}
+
+func newDecryptReader(r io.Reader, passphrase string) (*decryptReader, error) {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, ErrTruncated
+	}
+	aead, err := newAEAD(passphrase, header[len(magic):len(magic)+saltSize])
+	if err != nil {
+		return nil, err
+	}
+	return &decryptReader{r: r, aead: aead, header: header}, nil
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if d.final {
+			return 0, io.EOF
+		}
+		if err := d.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+// open reads and opens the next chunk.
+func (d *decryptReader) open() error {
+	var frame [5]byte
+	if _, err := io.ReadFull(d.r, frame[:]); err != nil {
+		return ErrTruncated
+	}
+	flag, size := frame[0], binary.BigEndian.Uint32(frame[1:])
+	if (flag != flagMore && flag != flagFinal) || size > chunkSize+uint32(d.aead.Overhead()) {
+		return ErrBadPassphrase
+	}
+	sealed := make([]byte, size)
+	if _, err := io.ReadFull(d.r, sealed); err != nil {
+		return ErrTruncated
+	}
+	aad := append(append([]byte{}, d.header...), flag)
+	plain, err := d.aead.Open(nil, chunkNonce(d.header[len(magic)+saltSize:], d.n), sealed, aad)
+	if err != nil {
+		return ErrBadPassphrase
+	}
+	d.n++
+	d.buf = plain
+	d.final = flag == flagFinal
+	return nil
+}
@@ -1,11 +1,15 @@
 package chatops
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/storage"
 )
 
 func TestParseCommand(t *testing.T) {
@@ -165,6 +169,42 @@ func TestExecuteApproveReject(t *testing.T) {
 	}
 }
 
+func TestHandlerAuditsStateChanges(t *testing.T) {
+	statePath := writeState(t, makeStateFixture())
+	h := NewHandler(statePath, nil)
+	var entries []storage.AuditEntry
+	h.SetAuditFunc(func(e storage.AuditEntry) error {
+		entries = append(entries, e)
+		return nil
+	})
+
+	for _, text := range []string{"status", "approve task-003"} {
+		form := url.Values{"command": {"/rig"}, "text": {text}, "user_name": {"alice"}}
+		req := httptest.NewRequest(http.MethodPost, "/api/chatops/slack", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		h.HandleSlack(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%q: expected 200, got %d: %s", text, rec.Code, rec.Body.String())
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/chatops/discord",
+		strings.NewReader(`{"content":"rig reject task-004","author":{"username":"bob"}}`))
+	h.HandleDiscord(httptest.NewRecorder(), req)
+
+	if len(entries) != 2 {
+		t.Fatalf("expected approve and reject to be audited, got %+v", entries)
+	}
+	if e := entries[0]; e.Actor != "slack:alice" || e.Source != storage.AuditSourceChatOps ||
+		e.Action != storage.AuditProposalApproved || e.Target != "task-003" {
+		t.Errorf("unexpected approve entry %+v", e)
+	}
+	if e := entries[1]; e.Actor != "discord:bob" || e.Action != storage.AuditProposalRejected || e.Target != "task-004" {
+		t.Errorf("unexpected reject entry %+v", e)
+	}
+}
+
 func writeState(t *testing.T, s *core.State) string {
 	t.Helper()
 	path := t.TempDir() + "/state.json"
@@ -4,21 +4,27 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
-	"regexp"
-	"strconv"
 	"strings"
 
 	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/storage"
 )
 
 // ExecuteFunc starts pipeline execution for an issue.
 type ExecuteFunc = func(issue core.Issue) error
 
+// AuditFunc records a state-changing command in the audit log.
+// storage.DB.RecordAudit matches it.
+type AuditFunc = func(e storage.AuditEntry) error
+
 // Handler receives ChatOps webhooks.
 type Handler struct {
 	statePath string
 	onExecute ExecuteFunc
+	audit     AuditFunc
+	hosts     func() core.IssueHosts
 }
 
 // NewHandler creates a ChatOps webhook handler.
@@ -26,6 +32,19 @@ func NewHandler(statePath string, onExecute ExecuteFunc) *Handler {
 	return &Handler{statePath: statePath, onExecute: onExecute}
 }
 
+// SetAuditFunc records exec, approve and reject commands with the chat user
+// as actor ("slack:alice", "discord:bob").
+func (h *Handler) SetAuditFunc(fn AuditFunc) {
+	h.audit = fn
+}
+
+// SetIssueHosts sets the issue hosts exec resolves issue URLs with, read
+// for every command so a config reload applies. Without it only the hosted
+// platforms are known.
+func (h *Handler) SetIssueHosts(hosts func() core.IssueHosts) {
+	h.hosts = hosts
+}
+
 // HandleSlack handles Slack slash command webhooks.
 func (h *Handler) HandleSlack(w http.ResponseWriter, r *http.Request) {
 	if err := r.ParseForm(); err != nil {
@@ -39,7 +58,7 @@ func (h *Handler) HandleSlack(w http.ResponseWriter, r *http.Request) {
 		raw = strings.TrimSpace(command + " " + raw)
 	}
 
-	response, status := h.handleCommand(raw)
+	response, status := h.handleCommand(raw, "slack:"+r.FormValue("user_name"), r.RemoteAddr)
 	h.writeSlack(w, response, status)
 }
 
@@ -47,6 +66,9 @@ func (h *Handler) HandleSlack(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) HandleDiscord(w http.ResponseWriter, r *http.Request) {
 	var payload struct {
 		Content string `json:"content"`
+		Author  struct {
+			Username string `json:"username"`
+		} `json:"author"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
@@ -54,11 +76,11 @@ func (h *Handler) HandleDiscord(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, status := h.handleCommand(strings.TrimSpace(payload.Content))
+	response, status := h.handleCommand(strings.TrimSpace(payload.Content), "discord:"+payload.Author.Username, r.RemoteAddr)
 	h.writeDiscord(w, response, status)
 }
 
-func (h *Handler) handleCommand(input string) (string, int) {
+func (h *Handler) handleCommand(input, actor, remote string) (string, int) {
 	cmd, err := ParseCommand(input)
 	if err != nil {
 		if errors.Is(err, errCommandNotFound) {
@@ -68,10 +90,11 @@ func (h *Handler) handleCommand(input string) (string, int) {
 	}
 
 	if cmd.Action == "exec" {
-		message, execErr := h.executeIssue(cmd)
+		message, taskID, execErr := h.executeIssue(cmd)
 		if execErr != nil {
 			return execErr.Error(), http.StatusBadRequest
 		}
+		h.record(actor, remote, storage.AuditTaskCreated, taskID)
 		return message, http.StatusOK
 	}
 
@@ -80,21 +103,48 @@ func (h *Handler) handleCommand(input string) (string, int) {
 		return execErr.Error(), http.StatusBadRequest
 	}
 
+	switch cmd.Action {
+	case "approve":
+		h.record(actor, remote, storage.AuditProposalApproved, cmd.Args[0])
+	case "reject":
+		h.record(actor, remote, storage.AuditProposalRejected, cmd.Args[0])
+	}
+
 	return message, http.StatusOK
 }
 
-func (h *Handler) executeIssue(cmd *Command) (string, error) {
+func (h *Handler) record(actor, remote, action, target string) {
+	if h.audit == nil {
+		return
+	}
+	err := h.audit(storage.AuditEntry{
+		Actor:  actor,
+		Source: storage.AuditSourceChatOps,
+		Remote: remote,
+		Action: action,
+		Target: target,
+	})
+	if err != nil {
+		slog.Warn("chatops: audit failed", "action", action, "err", err)
+	}
+}
+
+func (h *Handler) executeIssue(cmd *Command) (string, string, error) {
 	if len(cmd.Args) < 1 {
-		return "", errors.New("exec requires issue URL")
+		return "", "", errors.New("exec requires issue URL")
 	}
 
-	issue, err := parseIssueURL(cmd.Args[0])
+	hosts := core.NewIssueHosts(nil)
+	if h.hosts != nil {
+		hosts = h.hosts()
+	}
+	issue, err := hosts.Parse(cmd.Args[0])
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
 	if h.onExecute == nil {
-		return "", errors.New("execution callback not configured")
+		return "", "", errors.New("execution callback not configured")
 	}
 
 	var createdTaskID string
@@ -107,14 +157,14 @@ func (h *Handler) executeIssue(cmd *Command) (string, error) {
 		return nil
 	})
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
 	go func() {
 		_ = h.onExecute(issue)
 	}()
 
-	return fmt.Sprintf("Started task %s for issue #%s.", createdTaskID, issue.ID), nil
+	return fmt.Sprintf("Started task %s for issue #%s.", createdTaskID, issue.ID), createdTaskID, nil
 }
 
 func (h *Handler) writeSlack(w http.ResponseWriter, message string, status int) {
@@ -128,27 +178,3 @@ func (h *Handler) writeDiscord(w http.ResponseWriter, message string, status int
 	w.WriteHeader(status)
 	_, _ = w.Write([]byte(message))
 }
-
-func parseIssueURL(url string) (core.Issue, error) {
-	re := regexp.MustCompile(`https?://github\.com/([^/]+)/([^/]+)/issues/(\d+)`)
-	matches := re.FindStringSubmatch(strings.TrimSpace(url))
-	if len(matches) != 4 {
-		return core.Issue{}, errors.New("issue URL must match https://github.com/{owner}/{repo}/issues/{number}")
-	}
-
-	owner := matches[1]
-	repo := matches[2]
-	number := matches[3]
-	issueNum, err := strconv.Atoi(number)
-	if err != nil || issueNum <= 0 {
-		return core.Issue{}, errors.New("invalid issue number")
-	}
-
-	return core.Issue{
-		Platform: "github",
-		Repo:     owner + "/" + repo,
-		ID:       number,
-		Title:    fmt.Sprintf("Issue #%s", number),
-		URL:      strings.TrimSpace(url),
-	}, nil
-}
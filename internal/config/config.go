@@ -3,15 +3,34 @@ package config
 import (
 	"fmt"
 	"os"
+	"reflect"
 	"regexp"
 	"strings"
 
+	"github.com/rigdev/rig/internal/secret"
 	"gopkg.in/yaml.v3"
 )
 
 // envVarPattern matches ${VAR_NAME} patterns in config content.
 var envVarPattern = regexp.MustCompile(`\$\{([^}]+)\}`)
 
+// secretPrefix marks ${secret:REF} references, which package secret
+// resolves instead of the environment.
+const secretPrefix = "secret:"
+
+// TaskVars are the variables rig sets for each task, such as
+// ${BRANCH_NAME}, and ${ARTIFACT_<NAME>} in rollback commands. Loading
+// leaves those the environment does not set for the engine to substitute.
+var TaskVars = map[string]bool{
+	"BRANCH_NAME": true, "COMMIT_SHA": true, "ISSUE_ID": true, "ISSUE_NUMBER": true, "ISSUE_TITLE": true,
+	"REPO_OWNER": true, "REPO_NAME": true, "CHANGED_FILES": true, "ENVIRONMENT": true, "DEPLOY_TARGET": true,
+}
+
+// isTaskVar reports whether name is one of TaskVars.
+func isTaskVar(name string) bool {
+	return TaskVars[name] || strings.HasPrefix(name, "ARTIFACT_")
+}
+
 // ResolveEnvVars substitutes ${VAR_NAME} patterns with os.Getenv(VAR_NAME).
 // Unresolved variables (env var not set) are left as-is without error.
 func ResolveEnvVars(s string) string {
@@ -24,29 +43,60 @@ func ResolveEnvVars(s string) string {
 	})
 }
 
-// LoadConfig reads a YAML configuration file, substitutes environment
-// variables, parses into Config, and validates the result.
+// LoadConfig reads a YAML configuration file, applies the profile selected
+// by RIG_PROFILE, substitutes environment variables and ${secret:REF}
+// references, parses into Config, and validates the result.
 func LoadConfig(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
+	return loadConfig(path, ActiveProfile(), true)
+}
+
+// LoadProfile is LoadConfig with an explicit profile; "" loads the base
+// config alone.
+func LoadProfile(path, profile string) (*Config, error) {
+	return loadConfig(path, profile, true)
+}
+
+// LoadConfigWithSecretRefs is LoadConfig with ${secret:REF} references
+// left in place, for storing the config without the secrets it points to.
+func LoadConfigWithSecretRefs(path string) (*Config, error) {
+	return loadConfig(path, ActiveProfile(), false)
+}
+
+// configType is the type rig.yaml decodes into.
+var configType = reflect.TypeOf(Config{})
+
+func loadConfig(path, profile string, resolveSecrets bool) (*Config, error) {
+	doc, err := readYAML(path)
 	if err != nil {
-		return nil, fmt.Errorf("config: failed to read file %s: %w", path, err)
+		return nil, err
 	}
 
-	// Check for unresolved variables — any ${VAR} where the env var is not set.
-	if err := validateEnvVars(data); err != nil {
-		return nil, err
+	// The profile is merged before variables are substituted, so only the
+	// variables of the selected profile have to be set.
+	if errs := applyProfile(doc, path, profile); len(errs) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(errs, "; "))
 	}
 
-	// Substitute ${VAR_NAME} with os.Getenv(VAR_NAME)
-	resolved := envVarPattern.ReplaceAllStringFunc(string(data), func(match string) string {
-		varName := match[2 : len(match)-1] // strip ${ and }
-		return os.Getenv(varName)
-	})
+	// Substitute ${VAR_NAME} with os.Getenv(VAR_NAME). Values are
+	// substituted into parsed scalars, so quotes, colons or newlines in
+	// them cannot change the YAML.
+	if unresolved := substituteEnvNodes(doc, map[string]bool{}); len(unresolved) > 0 {
+		return nil, fmt.Errorf("config: unresolved variables found: %s",
+			strings.Join(unresolved, ", "))
+	}
+	if resolveSecrets {
+		if err := resolveSecretNodes(doc); err != nil {
+			return nil, err
+		}
+	}
 
 	var cfg Config
-	if err := yaml.Unmarshal([]byte(resolved), &cfg); err != nil {
-		return nil, fmt.Errorf("config: failed to parse YAML: %w", err)
+	if doc.Kind != 0 {
+		if err := doc.Decode(&cfg); err != nil {
+			return nil, fmt.Errorf("config: failed to parse YAML: %w", err)
+		}
 	}
+	cfg.Profile = profile
 
 	if err := Validate(&cfg); err != nil {
 		return nil, err
@@ -55,25 +105,98 @@ func LoadConfig(path string) (*Config, error) {
 	return &cfg, nil
 }
 
-// validateEnvVars checks that all ${VAR} references in raw data
-// correspond to environment variables that are actually set.
-func validateEnvVars(data []byte) error {
-	matches := envVarPattern.FindAllStringSubmatch(string(data), -1)
+// readYAML parses the file at path without substituting anything.
+func readYAML(path string) (*yaml.Node, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config: failed to read file %s: %w", path, err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("config: failed to parse YAML: %w", err)
+	}
+	return &doc, nil
+}
+
+// substituteEnvNodes replaces the ${VAR} references in the scalars under n
+// with the environment and returns the unset ones, each once.
+func substituteEnvNodes(n *yaml.Node, seen map[string]bool) []string {
+	if n.Kind == yaml.AliasNode {
+		return nil // substituted where the anchor is
+	}
 	var unresolved []string
-	seen := map[string]bool{}
-	for _, m := range matches {
-		varName := m[1]
-		if seen[varName] {
-			continue
+	if n.Kind == yaml.ScalarNode {
+		out := envVarPattern.ReplaceAllStringFunc(n.Value, func(match string) string {
+			varName := match[2 : len(match)-1] // strip ${ and }
+			if strings.HasPrefix(varName, secretPrefix) {
+				return match
+			}
+			val, ok := os.LookupEnv(varName)
+			if !ok && isTaskVar(varName) {
+				return match
+			}
+			if !ok && !seen[varName] {
+				seen[varName] = true
+				unresolved = append(unresolved, match)
+			}
+			return val
+		})
+		if out != n.Value {
+			n.Value = out
+			// A plain ${PORT} was parsed as a string; let the value decide,
+			// as if it had been written in the file.
+			if n.Style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+				n.Tag = ""
+			}
 		}
-		seen[varName] = true
-		if _, ok := os.LookupEnv(varName); !ok {
-			unresolved = append(unresolved, "${"+varName+"}")
+		return unresolved
+	}
+	for _, c := range n.Content {
+		unresolved = append(unresolved, substituteEnvNodes(c, seen)...)
+	}
+	return unresolved
+}
+
+// resolveSecretNodes replaces the ${secret:REF} references in the scalars
+// under n.
+func resolveSecretNodes(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		v, err := ResolveSecretRefs(n.Value)
+		if err != nil {
+			return err
 		}
+		n.Value = v
+		return nil
 	}
-	if len(unresolved) > 0 {
-		return fmt.Errorf("config: unresolved variables found: %s",
-			strings.Join(unresolved, ", "))
+	for _, c := range n.Content {
+		if err := resolveSecretNodes(c); err != nil {
+			return err
+		}
 	}
 	return nil
 }
+
+// ResolveSecretRefs substitutes the ${secret:REF} references in s.
+func ResolveSecretRefs(s string) (string, error) {
+	var firstErr error
+	out := envVarPattern.ReplaceAllStringFunc(s, func(match string) string {
+		ref, ok := strings.CutPrefix(match[2:len(match)-1], secretPrefix)
+		if !ok || firstErr != nil {
+			return match
+		}
+		v, err := secret.Resolve(ref)
+		if err != nil {
+			firstErr = fmt.Errorf("config: %w", err)
+			return match
+		}
+		return v
+	})
+	return out, firstErr
+}
+
+// IsReference reports whether s is exactly one ${VAR} or ${secret:REF}
+// reference, which is safe to show where values are masked.
+func IsReference(s string) bool {
+	loc := envVarPattern.FindStringIndex(s)
+	return loc != nil && loc[0] == 0 && loc[1] == len(s)
+}
@@ -1,10 +1,12 @@
 package config
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 // testdataDir returns the absolute path to the testdata directory.
@@ -211,3 +213,293 @@ func TestEnvVarSubstitution(t *testing.T) {
 		t.Errorf("server.secret = %q, want %q", cfg.Server.Secret, "my-webhook-secret")
 	}
 }
+
+func TestTaskVariablesLeftForEngine(t *testing.T) {
+	setEnvVars(t)
+	data, err := os.ReadFile(filepath.Join(testdataDir(t), "valid.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := strings.NewReplacer(
+		`run: "echo building"`, `run: "echo ${BRANCH_NAME} ${ARTIFACT_IMAGE}"`,
+		"workflow:\n", "workflow:\n  pr:\n    labels: [\"env:${ENVIRONMENT}\"]\n",
+	).Replace(string(data))
+	path := filepath.Join(t.TempDir(), "rig.yaml")
+	if err := os.WriteFile(path, []byte(raw), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := cfg.Deploy.Config.Commands[0].Run; got != "echo ${BRANCH_NAME} ${ARTIFACT_IMAGE}" {
+		t.Errorf("run = %q, want the task variables kept", got)
+	}
+	if got := cfg.Workflow.PR.Labels; len(got) != 1 || got[0] != "env:${ENVIRONMENT}" {
+		t.Errorf("workflow.pr.labels = %v, want the task variable kept", got)
+	}
+}
+
+func TestSecretReferences(t *testing.T) {
+	setEnvVars(t)
+	t.Setenv("RIG_TEST_AI_KEY", "sk-from-secret-env")
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	// Quotes, a colon and a newline would break the YAML if substituted as text.
+	if err := os.WriteFile(tokenFile, []byte("ghp_\"quoted\": value\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(testdataDir(t), "valid.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := strings.NewReplacer(
+		"${GITHUB_TOKEN}", "${secret:file:"+tokenFile+"}",
+		"${ANTHROPIC_API_KEY}", "${secret:env:RIG_TEST_AI_KEY}",
+	).Replace(string(data))
+	path := filepath.Join(dir, "rig.yaml")
+	if err := os.WriteFile(path, []byte(raw), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Source.Token != `ghp_"quoted": value` || cfg.AI.APIKey != "sk-from-secret-env" {
+		t.Errorf("secrets not resolved: token %q, api_key %q", cfg.Source.Token, cfg.AI.APIKey)
+	}
+	if cfg.Server.Secret != "test-secret" {
+		t.Errorf("env vars must still resolve, got server.secret %q", cfg.Server.Secret)
+	}
+
+	refs, err := LoadConfigWithSecretRefs(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if refs.Source.Token != "${secret:file:"+tokenFile+"}" || !IsReference(refs.AI.APIKey) {
+		t.Errorf("references should be kept, got token %q, api_key %q", refs.Source.Token, refs.AI.APIKey)
+	}
+
+	settings := map[string]string{"source": `{"repo":"a/b","token":"${secret:file:` + tokenFile + `}"}`}
+	fromSettings, err := FromSettings(settings)
+	if err != nil {
+		t.Fatalf("FromSettings: %v", err)
+	}
+	if fromSettings.Source.Token != `ghp_"quoted": value` {
+		t.Errorf("FromSettings token = %q", fromSettings.Source.Token)
+	}
+
+	if err := os.WriteFile(path, []byte(strings.ReplaceAll(raw, tokenFile, tokenFile+".missing")), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), "secret") {
+		t.Errorf("expected an unresolvable secret error, got %v", err)
+	}
+}
+
+func TestReloader(t *testing.T) {
+	setEnvVars(t)
+	data, err := os.ReadFile(filepath.Join(testdataDir(t), "valid.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "rig.yaml")
+	write := func(s string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(s), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(string(data))
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewReloader(path, cfg)
+	var seen []string
+	r.OnReload(func(c *Config, err error) {
+		if err != nil {
+			seen = append(seen, "error")
+			return
+		}
+		seen = append(seen, c.AI.Model)
+	})
+
+	write(strings.Replace(string(data), "model: claude-opus-4-6", "model: claude-sonnet-4-5", 1))
+	if err := r.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if got := r.Current().AI.Model; got != "claude-sonnet-4-5" {
+		t.Errorf("current model = %q", got)
+	}
+	if st := r.Status(); st.Reloads != 1 || st.Error != "" || len(st.RestartRequired) != 0 {
+		t.Errorf("unexpected status %+v", st)
+	}
+	if cfg.AI.Model != "claude-opus-4-6" {
+		t.Error("the previous config must not change under running tasks")
+	}
+
+	write("ai:\n  provider: nope\n")
+	if err := r.Reload(); err == nil {
+		t.Fatal("expected an invalid config to fail")
+	}
+	if got := r.Current().AI.Model; got != "claude-sonnet-4-5" {
+		t.Errorf("a failed reload replaced the config: model %q", got)
+	}
+	if st := r.Status(); st.Reloads != 1 || st.Error == "" {
+		t.Errorf("unexpected status after failure %+v", st)
+	}
+
+	r.Override(func(c *Config) { c.Server.Port = 8080 })
+	write(strings.Replace(string(data), "port: 8080", "port: 9090", 1) + "log:\n  level: debug\n")
+	if err := r.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if got := r.Status().RestartRequired; len(got) != 1 || got[0] != "log" {
+		t.Errorf("RestartRequired = %v, want [log] (server.port is overridden)", got)
+	}
+	if want := []string{"claude-sonnet-4-5", "error", "claude-opus-4-6"}; strings.Join(seen, ",") != strings.Join(want, ",") {
+		t.Errorf("callbacks saw %v, want %v", seen, want)
+	}
+}
+
+func TestUnknownKeys(t *testing.T) {
+	setEnvVars(t)
+	data, err := os.ReadFile(filepath.Join(testdataDir(t), "valid.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := strings.NewReplacer(
+		"  max_retry: 3", "  max_rety: 3",
+		"        workdir: \".\"", "        workdir: \".\"\n        interpreter: bash",
+		"    timeout: 120s", "    timeout: 120s\n    timout: 60s",
+	).Replace(string(data))
+	path := filepath.Join(t.TempDir(), "rig.yaml")
+	if err := os.WriteFile(path, []byte(raw), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = LoadConfig(path)
+	if err == nil {
+		t.Fatal("expected unknown keys to be rejected")
+	}
+	for _, want := range []string{
+		"unknown key ai.max_rety (line 16); did you mean max_retry?",
+		"unknown key deploy.config.commands[0].interpreter (line 27)",
+		"unknown key test[0].timout (line 41); did you mean timeout?",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	schema := JSONSchema()
+	if schema["additionalProperties"] != false {
+		t.Error("the top level must reject unknown keys")
+	}
+	props := schema["properties"].(map[string]any)
+	ai := props["ai"].(map[string]any)["properties"].(map[string]any)
+	if _, ok := ai["max_retry"]; !ok {
+		t.Errorf("ai.max_retry missing from %v", ai)
+	}
+	platform := props["source"].(map[string]any)["properties"].(map[string]any)["platform"].(map[string]any)
+	if enum, _ := platform["enum"].([]string); len(enum) != len(validPlatforms) {
+		t.Errorf("source.platform enum = %v", platform["enum"])
+	}
+	item := props["server"].(map[string]any)["properties"].(map[string]any)["webhooks"].(map[string]any)["items"].(map[string]any)
+	if enum, _ := item["properties"].(map[string]any)["platform"].(map[string]any)["enum"].([]string); len(enum) != 2 {
+		t.Errorf("server.webhooks[].platform enum = %v", enum)
+	}
+	if _, err := json.Marshal(schema); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	setEnvVars(t)
+	t.Setenv("RIG_TEST_PORT", "9090")
+	data, err := os.ReadFile(filepath.Join(testdataDir(t), "valid.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := strings.Replace(string(data), "  port: 8080", "  port: ${RIG_TEST_PORT}", 1) + `
+profiles:
+  staging:
+    ai:
+      model: claude-sonnet-4-5
+    test:
+      - type: command
+        name: smoke
+        run: "make smoke"
+    notify:
+      - type: slack
+        webhook: https://hooks.slack.com/staging
+        on: ["all"]
+  prod:
+    source:
+      token: ${RIG_TEST_PROD_TOKEN}
+`
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rig.yaml")
+	if err := os.WriteFile(path, []byte(base), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	overlay := "source:\n  base_branch: release\ndeploy:\n  timeout: 900s\n"
+	if err := os.WriteFile(filepath.Join(dir, "rig.prod.yaml"), []byte(overlay), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadProfile(path, "")
+	if err != nil {
+		t.Fatalf("base config: %v", err)
+	}
+	if cfg.AI.Model != "claude-opus-4-6" || cfg.Server.Port != 9090 || cfg.Profile != "" {
+		t.Errorf("unexpected base config: model %q, port %d, profile %q", cfg.AI.Model, cfg.Server.Port, cfg.Profile)
+	}
+
+	cfg, err = LoadProfile(path, "staging")
+	if err != nil {
+		t.Fatalf("staging: %v", err)
+	}
+	if cfg.AI.Model != "claude-sonnet-4-5" || cfg.AI.Provider != "anthropic" || cfg.AI.APIKey != "test-api-key" {
+		t.Errorf("ai should deep-merge, got %+v", cfg.AI)
+	}
+	if len(cfg.Test) != 1 || cfg.Test[0].Name != "smoke" || len(cfg.Notify) != 1 || cfg.Notify[0].Type != "slack" {
+		t.Errorf("lists should be replaced, got test %+v, notify %+v", cfg.Test, cfg.Notify)
+	}
+	if cfg.Profile != "staging" {
+		t.Errorf("Profile = %q", cfg.Profile)
+	}
+
+	if _, err := LoadProfile(path, "prod"); err == nil || !strings.Contains(err.Error(), "${RIG_TEST_PROD_TOKEN}") {
+		t.Errorf("prod needs its own variables, got %v", err)
+	}
+	t.Setenv("RIG_TEST_PROD_TOKEN", "ghp_prod")
+	t.Setenv(ProfileEnv, "prod")
+	cfg, err = LoadConfig(path)
+	if err != nil {
+		t.Fatalf("prod: %v", err)
+	}
+	if cfg.Source.Token != "ghp_prod" || cfg.Source.BaseBranch != "release" || cfg.Deploy.Timeout != 900*time.Second || cfg.Source.Repo == "" {
+		t.Errorf("prod should merge the section and rig.prod.yaml, got source %+v, deploy timeout %v", cfg.Source, cfg.Deploy.Timeout)
+	}
+
+	if _, err := LoadProfile(path, "qa"); err == nil || !strings.Contains(err.Error(), `profile "qa" not found`) {
+		t.Errorf("expected a missing profile error, got %v", err)
+	}
+	if names, err := Profiles(path); err != nil || strings.Join(names, ",") != "prod,staging" {
+		t.Errorf("Profiles = %v, %v", names, err)
+	}
+
+	if err := os.WriteFile(path, []byte(strings.Replace(base, "      model: claude-sonnet-4-5", "      modle: claude-sonnet-4-5", 1)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadProfile(path, ""); err == nil || !strings.Contains(err.Error(), "unknown key profiles.staging.ai.modle") {
+		t.Errorf("inactive profiles must be checked too, got %v", err)
+	}
+}
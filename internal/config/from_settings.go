@@ -3,21 +3,27 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 )
 
 // FromSettings reconstructs a Config from a settings key-value map.
 // Each key corresponds to a config section, and each value is a JSON blob.
-// Environment variables in ${VAR} format are resolved before parsing.
+// Environment variables in ${VAR} format and ${secret:REF} references are
+// resolved before parsing; the stored settings keep the references.
 func FromSettings(settings map[string]string) (*Config, error) {
 	cfg := &Config{}
 
-	// unmarshalSection resolves env vars in the JSON blob before unmarshalling.
+	// unmarshalSection resolves env vars and secrets in the JSON blob before
+	// unmarshalling.
 	unmarshalSection := func(key string, target interface{}) error {
 		v, ok := settings[key]
 		if !ok || v == "" {
 			return nil
 		}
-		resolved := ResolveEnvVars(v)
+		resolved, err := resolveJSONSecrets(ResolveEnvVars(v))
+		if err != nil {
+			return fmt.Errorf("%s settings: %w", key, err)
+		}
 		if err := json.Unmarshal([]byte(resolved), target); err != nil {
 			return fmt.Errorf("parse %s settings: %w", key, err)
 		}
@@ -54,3 +60,22 @@ func FromSettings(settings map[string]string) (*Config, error) {
 
 	return cfg, nil
 }
+
+// resolveJSONSecrets substitutes the ${secret:REF} references in a JSON
+// blob, escaped for the JSON strings they sit in.
+func resolveJSONSecrets(blob string) (string, error) {
+	var firstErr error
+	out := envVarPattern.ReplaceAllStringFunc(blob, func(match string) string {
+		if firstErr != nil || !strings.HasPrefix(match[2:], secretPrefix) {
+			return match
+		}
+		v, err := ResolveSecretRefs(match)
+		if err != nil {
+			firstErr = err
+			return match
+		}
+		quoted, _ := json.Marshal(v)
+		return string(quoted[1 : len(quoted)-1])
+	})
+	return out, firstErr
+}
@@ -0,0 +1,186 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProfileEnv selects the profile LoadConfig applies; rig --profile sets it.
+const ProfileEnv = "RIG_PROFILE"
+
+// ActiveProfile returns the profile selected by RIG_PROFILE, or "" for the
+// base config alone.
+func ActiveProfile() string {
+	return strings.TrimSpace(os.Getenv(ProfileEnv))
+}
+
+// OverlayPath returns the overlay file of profile next to the config at
+// path: rig.staging.yaml for rig.yaml.
+func OverlayPath(path, profile string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + profile + ext
+}
+
+// Profiles lists the profiles of the config at path, sorted: the keys of
+// its profiles section and the profiles of overlay files next to it.
+func Profiles(path string) ([]string, error) {
+	doc, err := readYAML(path)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	if root := rootMapping(doc); root != nil {
+		if profiles := mappingValue(root, "profiles"); profiles != nil && profiles.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(profiles.Content); i += 2 {
+				seen[profiles.Content[i].Value] = true
+			}
+		}
+	}
+	ext := filepath.Ext(path)
+	prefix := strings.TrimSuffix(filepath.Base(path), ext) + "."
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	for _, e := range entries {
+		name, ok := strings.CutPrefix(e.Name(), prefix)
+		if !ok || e.IsDir() || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		if name = strings.TrimSuffix(name, ext); name != "" && !strings.Contains(name, ".") {
+			seen[name] = true
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// applyProfile merges profile over the base config in doc: first the
+// profiles.<name> section of the file at path, then the overlay file, if
+// any. The profiles section itself is removed from doc, and every profile
+// in it is checked for unknown keys whether it is selected or not.
+func applyProfile(doc *yaml.Node, path, profile string) []string {
+	root := rootMapping(doc)
+	if root == nil {
+		root = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		*doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}
+	}
+
+	inline := map[string]*yaml.Node{}
+	var errs []string
+	if profiles := removeKey(root, "profiles"); profiles != nil {
+		profiles = resolveAlias(profiles)
+		if profiles.Kind != yaml.MappingNode {
+			return []string{fmt.Sprintf("config: profiles (line %d) must map profile names to config sections", profiles.Line)}
+		}
+		for i := 0; i+1 < len(profiles.Content); i += 2 {
+			name, section := profiles.Content[i].Value, profiles.Content[i+1]
+			inline[name] = section
+			errs = append(errs, checkKeys(section, configType, "profiles."+name)...)
+		}
+	}
+	errs = append(errs, checkKeys(doc, configType, "")...)
+	if profile == "" || len(errs) > 0 {
+		return errs
+	}
+
+	found := false
+	if section, ok := inline[profile]; ok {
+		mergeNodes(root, resolveAlias(section))
+		found = true
+	}
+	overlayPath := OverlayPath(path, profile)
+	if _, err := os.Stat(overlayPath); err == nil {
+		overlay, err := readYAML(overlayPath)
+		if err != nil {
+			return []string{err.Error()}
+		}
+		for _, e := range checkKeys(overlay, configType, "") {
+			errs = append(errs, "config: "+overlayPath+": "+strings.TrimPrefix(e, "config: "))
+		}
+		if o := rootMapping(overlay); o != nil {
+			mergeNodes(root, o)
+		}
+		found = true
+	}
+	if !found {
+		errs = append(errs, fmt.Sprintf("config: profile %q not found: no profiles.%s in %s and no %s", profile, profile, path, overlayPath))
+	}
+	return errs
+}
+
+// mergeNodes deep-merges the mapping src into dst: nested mappings merge
+// key by key, while scalars and lists in src replace those in dst.
+func mergeNodes(dst, src *yaml.Node) {
+	if src.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, val := src.Content[i], resolveAlias(src.Content[i+1])
+		cur := mappingValue(dst, key.Value)
+		switch {
+		case cur == nil:
+			dst.Content = append(dst.Content, key, val)
+		case resolveAlias(cur).Kind == yaml.MappingNode && val.Kind == yaml.MappingNode:
+			merged := *resolveAlias(cur) // copy, so an anchor shared elsewhere is left alone
+			merged.Content = append([]*yaml.Node(nil), merged.Content...)
+			mergeNodes(&merged, val)
+			setMappingValue(dst, key.Value, &merged)
+		default:
+			setMappingValue(dst, key.Value, val)
+		}
+	}
+}
+
+// rootMapping returns the top-level mapping of doc, or nil if it is empty.
+func rootMapping(doc *yaml.Node) *yaml.Node {
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		if root := resolveAlias(doc.Content[0]); root.Kind == yaml.MappingNode {
+			return root
+		}
+	}
+	return nil
+}
+
+func resolveAlias(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
+	return n
+}
+
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func setMappingValue(m *yaml.Node, key string, val *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content[i+1] = val
+			return
+		}
+	}
+}
+
+// removeKey deletes key from the mapping m and returns its value.
+func removeKey(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			val := m.Content[i+1]
+			m.Content = append(m.Content[:i:i], m.Content[i+2:]...)
+			return val
+		}
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ReloadStatus reports the outcome of the last config reload.
+type ReloadStatus struct {
+	Path string `json:"path"`
+	// LoadedAt is when the config in use was loaded.
+	LoadedAt time.Time `json:"loaded_at"`
+	// Reloads counts the successful reloads since startup.
+	Reloads int `json:"reloads"`
+	// LastAttempt is when a reload was last tried, successful or not.
+	LastAttempt time.Time `json:"last_attempt,omitzero"`
+	// Error is why the last attempt failed; the previous config stays in
+	// use until the file is fixed.
+	Error string `json:"error,omitempty"`
+	// RestartRequired lists changed settings that only take effect after a
+	// restart, such as server.port.
+	RestartRequired []string `json:"restart_required,omitempty"`
+}
+
+// Reloader keeps the config of a long-running server in step with its
+// file. Reload swaps the config atomically, so tasks that already started
+// keep the config they began with and new ones get the new one. A file
+// that fails to load or validate leaves the current config in place.
+type Reloader struct {
+	path    string
+	current atomic.Pointer[Config]
+	// initial is the config loaded at startup, which RestartRequired
+	// compares against.
+	initial *Config
+	// reloading serializes Reload so callbacks see configs in order.
+	reloading sync.Mutex
+
+	mu       sync.Mutex
+	status   ReloadStatus
+	modTime  time.Time
+	size     int64
+	onReload []func(*Config, error)
+	override func(*Config)
+}
+
+// NewReloader returns a Reloader for the file at path, starting with cfg
+// as loaded from it.
+func NewReloader(path string, cfg *Config) *Reloader {
+	r := &Reloader{path: path, status: ReloadStatus{Path: path, LoadedAt: time.Now()}}
+	r.current.Store(cfg)
+	r.initial = cfg
+	if fi, err := os.Stat(path); err == nil {
+		r.modTime, r.size = fi.ModTime(), fi.Size()
+	}
+	return r
+}
+
+// Current returns the config in use.
+func (r *Reloader) Current() *Config {
+	return r.current.Load()
+}
+
+// OnReload registers fn to be called after each reload with the new
+// config, or with nil and the error when the reload failed.
+func (r *Reloader) OnReload(fn func(*Config, error)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onReload = append(r.onReload, fn)
+}
+
+// Override registers fn to adjust each newly loaded config before it is
+// used, for command-line flags that take precedence over the file.
+func (r *Reloader) Override(fn func(*Config)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.override = fn
+}
+
+// Status returns the outcome of the last reload.
+func (r *Reloader) Status() ReloadStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status
+}
+
+// Reload loads and validates the file again and, if that succeeds, makes
+// it the current config.
+func (r *Reloader) Reload() error {
+	r.reloading.Lock()
+	defer r.reloading.Unlock()
+
+	now := time.Now()
+	fi, statErr := os.Stat(r.path)
+	cfg, err := LoadConfig(r.path)
+
+	r.mu.Lock()
+	r.status.LastAttempt = now
+	if statErr == nil {
+		r.modTime, r.size = fi.ModTime(), fi.Size()
+	}
+	callbacks := r.onReload
+	if err != nil {
+		r.status.Error = err.Error()
+		r.mu.Unlock()
+		for _, fn := range callbacks {
+			fn(nil, err)
+		}
+		return err
+	}
+	if r.override != nil {
+		r.override(cfg)
+	}
+	r.current.Store(cfg)
+	r.status.Error = ""
+	r.status.LoadedAt = now
+	r.status.Reloads++
+	r.status.RestartRequired = RestartRequired(r.initial, cfg)
+	r.mu.Unlock()
+
+	for _, fn := range callbacks {
+		fn(cfg, nil)
+	}
+	return nil
+}
+
+// Watch reloads the config whenever the file's modification time or size
+// changes, checking every interval until ctx is cancelled.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		fi, err := os.Stat(r.path)
+		if err != nil {
+			continue
+		}
+		r.mu.Lock()
+		changed := !fi.ModTime().Equal(r.modTime) || fi.Size() != r.size
+		r.mu.Unlock()
+		if !changed {
+			continue
+		}
+		if err := r.Reload(); err != nil {
+			slog.Error("config reload failed; keeping the previous config", "path", r.path, "err", err)
+			continue
+		}
+		slog.Info("config reloaded", "path", r.path)
+	}
+}
+
+// RestartRequired lists the settings that differ between prev and next but
+// are only read at startup: the listeners, login, request limits,
+// logging and tenants.
+func RestartRequired(prev, next *Config) []string {
+	if prev == nil || next == nil {
+		return nil
+	}
+	fields := []struct {
+		key        string
+		prev, next any
+	}{
+		{"server.port", prev.Server.Port, next.Server.Port},
+		{"server.host", prev.Server.Host, next.Server.Host},
+		{"server.tls", prev.Server.TLS, next.Server.TLS},
+		{"server.trusted_proxies", prev.Server.TrustedProxies, next.Server.TrustedProxies},
+		{"server.drain_timeout", prev.Server.DrainTimeout, next.Server.DrainTimeout},
+		{"server.grpc", prev.Server.GRPC, next.Server.GRPC},
+		{"server.queues", prev.Server.Queues, next.Server.Queues},
+		{"server.limits", prev.Server.Limits, next.Server.Limits},
+		{"server.auth", prev.Server.Auth, next.Server.Auth},
+		{"workflow.branch_sweep", prev.Workflow.BranchSweep, next.Workflow.BranchSweep},
+		{"artifacts.retention", prev.Artifacts.Retention, next.Artifacts.Retention},
+		{"log", prev.Log, next.Log},
+		{"tenants", prev.Tenants, next.Tenants},
+	}
+	var keys []string
+	for _, f := range fields {
+		if !reflect.DeepEqual(f.prev, f.next) {
+			keys = append(keys, f.key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+)
+
+// schemaEnums are the allowed values of keys Validate restricts, by path
+// with [] for list items.
+var schemaEnums = map[string]map[string]bool{
+	"source.platform":                validPlatforms,
+	"source.git_author.identity":     {"token": true, "app": true},
+	"source.signing.format":          {"gpg": true, "ssh": true},
+	"ai.index.provider":              validEmbeddingProviders,
+	"ai.fallback[].provider":         validAIProviders,
+	"ai.models.tiers[].operations[]": validAIOperations,
+	"projects[].platform":            validPlatforms,
+	"deploy.method":                  validDeployMethods,
+	"deploy.strategy":                validDeployStrategies,
+	"server.webhooks[].platform":     validWebhookPlatforms,
+	"workflow.hooks[].on":            validHookPoints,
+}
+
+// JSONSchema returns a JSON Schema (draft 2020-12) for rig.yaml, generated
+// from Config so it lists exactly the keys LoadConfig accepts. Editors use
+// it for completion and to flag unknown keys as they are typed.
+func JSONSchema() map[string]any {
+	s := typeSchema(configType, "")
+	// profiles.<name> holds sections merged over the base config.
+	s["properties"].(map[string]any)["profiles"] = map[string]any{
+		"type":                 "object",
+		"additionalProperties": typeSchema(configType, ""),
+	}
+	s["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	s["title"] = "rig.yaml"
+	return s
+}
+
+func typeSchema(t reflect.Type, path string) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == durationType {
+		return map[string]any{
+			"type":        "string",
+			"pattern":     `^(\d+(\.\d+)?(ns|us|µs|ms|s|m|h))+$`,
+			"description": "Duration such as 30s, 5m or 1h30m",
+		}
+	}
+	if values, ok := schemaEnums[path]; ok {
+		enum := make([]string, 0, len(values))
+		for v := range values {
+			enum = append(enum, v)
+		}
+		sort.Strings(enum)
+		return map[string]any{"type": "string", "enum": enum}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		props := map[string]any{}
+		for _, f := range yamlFields(t) {
+			props[f.name] = typeSchema(f.typ, joinPath(path, f.name))
+		}
+		return map[string]any{"type": "object", "properties": props, "additionalProperties": false}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem(), path+".*")}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem(), path+"[]")}
+	case reflect.Bool:
+		return orVariable("boolean")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return orVariable("integer")
+	case reflect.Float32, reflect.Float64:
+		return orVariable("number")
+	default:
+		return map[string]any{"type": "string"}
+	}
+}
+
+// orVariable accepts a value of typ or a ${VAR} reference, which is only
+// substituted when the file is loaded.
+func orVariable(typ string) map[string]any {
+	return map[string]any{"anyOf": []any{
+		map[string]any{"type": typ},
+		map[string]any{"type": "string", "pattern": `^\$\{[^}]+\}$`},
+	}}
+}
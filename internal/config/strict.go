@@ -0,0 +1,143 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlField is a Config field as rig.yaml spells it.
+type yamlField struct {
+	name string
+	typ  reflect.Type
+}
+
+// yamlFields lists the keys a struct type decodes, in declaration order.
+func yamlFields(t reflect.Type) []yamlField {
+	fields := make([]yamlField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields = append(fields, yamlField{name: name, typ: f.Type})
+	}
+	return fields
+}
+
+var (
+	durationType    = reflect.TypeOf(time.Duration(0))
+	unmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+)
+
+// checkKeys reports every mapping key under n that no field of t decodes,
+// such as "ai.max_rety (line 17); did you mean max_retry?". YAML decoding
+// silently drops those, so a typo would otherwise go unnoticed.
+func checkKeys(n *yaml.Node, t reflect.Type, path string) []string {
+	for n.Kind == yaml.DocumentNode || n.Kind == yaml.AliasNode {
+		if n.Kind == yaml.AliasNode {
+			n = n.Alias
+		} else if len(n.Content) > 0 {
+			n = n.Content[0]
+		} else {
+			return nil
+		}
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == durationType || reflect.PointerTo(t).Implements(unmarshalerType) {
+		return nil
+	}
+
+	var errs []string
+	switch {
+	case t.Kind() == reflect.Struct && n.Kind == yaml.MappingNode:
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, val := n.Content[i], n.Content[i+1]
+			if key.Value == "<<" { // merge key: the merged mappings are checked as if inline
+				errs = append(errs, checkKeys(val, t, path)...)
+				continue
+			}
+			f, ok := findField(fields, key.Value)
+			if !ok {
+				errs = append(errs, unknownKey(joinPath(path, key.Value), key.Line, fields))
+				continue
+			}
+			errs = append(errs, checkKeys(val, f.typ, joinPath(path, key.Value))...)
+		}
+	case t.Kind() == reflect.Map && n.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			errs = append(errs, checkKeys(n.Content[i+1], t.Elem(), joinPath(path, n.Content[i].Value))...)
+		}
+	case (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && n.Kind == yaml.SequenceNode:
+		for i, item := range n.Content {
+			errs = append(errs, checkKeys(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	}
+	return errs
+}
+
+func findField(fields []yamlField, name string) (yamlField, bool) {
+	for _, f := range fields {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return yamlField{}, false
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// unknownKey describes an unknown key, suggesting the closest known one.
+func unknownKey(path string, line int, fields []yamlField) string {
+	msg := fmt.Sprintf("config: unknown key %s (line %d)", path, line)
+	key := path[strings.LastIndex(path, ".")+1:]
+	best, bestDist := "", 3 // suggest only near misses
+	for _, f := range fields {
+		if d := editDistance(key, f.name); d < bestDist {
+			best, bestDist = f.name, d
+		}
+	}
+	if best != "" {
+		msg += "; did you mean " + best + "?"
+	}
+	return msg
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
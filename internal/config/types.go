@@ -1,6 +1,9 @@
 package config
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // Config is the top-level configuration for Rig.
 type Config struct {
@@ -14,6 +17,31 @@ type Config struct {
 	Notify   []NotifyConfig `yaml:"notify" json:"notify"`
 	Server   ServerConfig   `yaml:"server" json:"server"`
 	Projects []ProjectEntry `yaml:"projects" json:"projects"`
+	Log      LogConfig      `yaml:"log" json:"log"`
+
+	Environments []EnvironmentConfig `yaml:"environments" json:"environments,omitempty"`
+
+	// Plugins are external adapters that deploy, test and notify entries
+	// can use by name.
+	Plugins []PluginConfig `yaml:"plugins" json:"plugins,omitempty"`
+
+	// Events are sinks that receive task lifecycle events as JSON.
+	Events []EventSinkConfig `yaml:"events" json:"events,omitempty"`
+
+	// Artifacts stores full deploy and test outputs and the files test
+	// runners report outside the state file.
+	Artifacts ArtifactsConfig `yaml:"artifacts" json:"artifacts,omitempty"`
+
+	// Backup configures rig backup and the backups rig serve takes.
+	Backup BackupConfig `yaml:"backup" json:"backup,omitempty"`
+
+	// Tenants are the teams sharing one rig serve, each with its own
+	// config, state, API keys and workspaces under /api/t/<name>/.
+	Tenants []TenantConfig `yaml:"tenants" json:"tenants,omitempty"`
+
+	// Profile is the profile the config was loaded with, or "" for the
+	// base config alone.
+	Profile string `yaml:"-" json:"-"`
 }
 
 // ProjectEntry defines an additional project target for issue intake.
@@ -22,6 +50,60 @@ type ProjectEntry struct {
 	Platform   string `yaml:"platform" json:"platform"`
 	Repo       string `yaml:"repo" json:"repo"`
 	BaseBranch string `yaml:"base_branch" json:"base_branch"`
+	// Host is the web host of a self-hosted platform, e.g.
+	// github.example.com; empty is the hosted one.
+	Host string `yaml:"host" json:"host,omitempty"`
+}
+
+// FindProject returns the project called name, by name or repo: the source
+// repo (as project.name or source.repo) or a projects entry. It is nil when
+// there is none.
+func (c *Config) FindProject(name string) *ProjectEntry {
+	if name == c.Source.Repo || (name == c.Project.Name && name != "") {
+		return &ProjectEntry{Name: c.Project.Name, Platform: c.Source.Platform, Repo: c.Source.Repo, BaseBranch: c.Source.BaseBranch}
+	}
+	for i := range c.Projects {
+		if p := &c.Projects[i]; p.Name == name || p.Repo == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// FindPlugin returns the plugins entry called name, or nil.
+func (c *Config) FindPlugin(name string) *PluginConfig {
+	for i := range c.Plugins {
+		if p := &c.Plugins[i]; p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// EnvironmentConfig is a deploy environment such as staging or prod. An
+// issue label deploy:<name> picks it for a task.
+type EnvironmentConfig struct {
+	Name string `yaml:"name" json:"name"`
+	// Default is used for issues without a deploy:<name> label.
+	Default bool `yaml:"default" json:"default,omitempty"`
+	// Vars are given to deploy commands and tests, with ${ENVIRONMENT}.
+	Vars map[string]string `yaml:"vars" json:"vars,omitempty"`
+	// Transport, when set, replaces the transport of every deploy and
+	// rollback command, such as to deploy to the environment's hosts.
+	Transport TransportConfig `yaml:"transport" json:"transport,omitempty"`
+	// Approval requires human approval before deploying.
+	Approval bool `yaml:"approval" json:"approval,omitempty"`
+}
+
+// FindEnvironment returns the environment called name, ignoring case, or
+// nil.
+func (c *Config) FindEnvironment(name string) *EnvironmentConfig {
+	for i := range c.Environments {
+		if strings.EqualFold(c.Environments[i].Name, name) {
+			return &c.Environments[i]
+		}
+	}
+	return nil
 }
 
 // ProjectConfig holds project metadata.
@@ -37,26 +119,232 @@ type SourceConfig struct {
 	Repo       string `yaml:"repo" json:"repo"`
 	BaseBranch string `yaml:"base_branch" json:"base_branch"`
 	Token      string `yaml:"token" json:"token"`
+
+	// Air-gapped setups: clone/push through an internal mirror, call an
+	// internal platform API, or (offline) write PRs as patch files.
+	APIURL   string `yaml:"api_url" json:"api_url,omitempty"` // e.g. https://github.internal/api/v3/
+	Mirror   string `yaml:"mirror" json:"mirror,omitempty"`
+	Offline  bool   `yaml:"offline" json:"offline,omitempty"`
+	PatchDir string `yaml:"patch_dir" json:"patch_dir,omitempty"` // default: .rig/patches
+
+	Workspaces WorkspacesConfig `yaml:"workspaces" json:"workspaces,omitempty"`
+	Clone      CloneConfig      `yaml:"clone" json:"clone,omitempty"`
+
+	GitAuthor GitAuthorConfig `yaml:"git_author" json:"git_author,omitempty"`
+	Signing   SigningConfig   `yaml:"signing" json:"signing,omitempty"`
+}
+
+// GitAuthorConfig sets the author and committer of the commits rig makes.
+// Name and email win over identity; with none of them set the local git
+// configuration applies.
+type GitAuthorConfig struct {
+	Name  string `yaml:"name" json:"name,omitempty"`
+	Email string `yaml:"email" json:"email,omitempty"`
+	// Identity derives name and email from GitHub: token is the user the
+	// source token belongs to, app is the bot user of the GitHub App
+	// app_slug, for a token of one of its installations.
+	Identity string `yaml:"identity" json:"identity,omitempty"`
+	AppSlug  string `yaml:"app_slug" json:"app_slug,omitempty"`
+}
+
+// SigningConfig signs the commits rig makes, so GitHub shows them as
+// verified once the key is added to the committer's account.
+type SigningConfig struct {
+	// Format is gpg (default) or ssh.
+	Format string `yaml:"format" json:"format,omitempty"`
+	// Key is a GPG key ID or the path of an SSH private key; empty
+	// disables signing.
+	Key string `yaml:"key" json:"key,omitempty"`
+}
+
+// CloneConfig makes cloning big repositories cheaper. The clone is made
+// once per repository and then only fetched, so these mostly cut the first
+// task's setup and the disk space of the clone.
+type CloneConfig struct {
+	// Depth truncates the history to this many commits; 0 clones all.
+	Depth int `yaml:"depth" json:"depth,omitempty"`
+	// SingleBranch fetches only the default branch.
+	SingleBranch bool `yaml:"single_branch" json:"single_branch,omitempty"`
+	// Sparse checks out only these directories, from a partial clone that
+	// downloads the contents of other files only when needed.
+	Sparse []string `yaml:"sparse" json:"sparse,omitempty"`
+	// Reference is a local clone or mirror of the repository whose objects
+	// the clone borrows instead of downloading; ignored when missing.
+	Reference string `yaml:"reference" json:"reference,omitempty"`
+}
+
+// WorkspacesConfig places the local checkouts. Each repository is cloned
+// once and every task works in its own git worktree of that clone, removed
+// when the task ends.
+type WorkspacesConfig struct {
+	// Root holds the clones and task worktrees; default ~/.rig/workspaces.
+	Root string `yaml:"root" json:"root,omitempty"`
+	// MaxBytes caps the disk space used under Root: a task that would add a
+	// worktree past it fails. 0 sets no quota.
+	MaxBytes int64 `yaml:"max_bytes" json:"max_bytes,omitempty"`
+	// GCInterval removes, in serve mode, the worktrees no running or
+	// waiting task needs, such as ones left by a crash. 0 disables it.
+	GCInterval time.Duration `yaml:"gc_interval" json:"gc_interval,omitempty"`
 }
 
 // AIConfig holds AI provider settings.
 type AIConfig struct {
-	Provider string   `yaml:"provider" json:"provider"` // anthropic|openai|ollama|claude-code
-	Model    string   `yaml:"model" json:"model"`
-	APIKey   string   `yaml:"api_key" json:"api_key"`
-	MaxRetry int      `yaml:"max_retry" json:"max_retry"`
-	Context  []string `yaml:"context" json:"context"`
+	Provider   string   `yaml:"provider" json:"provider"` // anthropic|openai|ollama|claude-code
+	Model      string   `yaml:"model" json:"model"`
+	RetryModel string   `yaml:"retry_model" json:"retry_model"` // model for retry attempts; empty reuses model
+	Language   string   `yaml:"language" json:"language"`       // language for plans, summaries and PR text (e.g. ko)
+	APIKey     string   `yaml:"api_key" json:"api_key"`
+	MaxRetry   int      `yaml:"max_retry" json:"max_retry"`
+	Context    []string `yaml:"context" json:"context"`
+	// ContextIgnore lists patterns, in .gitignore syntax from the
+	// repository root, of files kept out of the AI's context and changes
+	// besides the ones .gitignore ignores.
+	ContextIgnore []string `yaml:"context_ignore" json:"context_ignore,omitempty"`
+
+	Timeouts AITimeoutsConfig `yaml:"timeouts" json:"timeouts,omitempty"`
+	Retry    AIRetryConfig    `yaml:"retry" json:"retry,omitempty"`
+
+	// Fallback lists providers tried in order when a call to the primary
+	// provider still fails after its retries.
+	Fallback []AIFallbackConfig `yaml:"fallback" json:"fallback,omitempty"`
+
+	Cache AICacheConfig `yaml:"cache" json:"cache,omitempty"`
+
+	Models AIModelsConfig `yaml:"models" json:"models,omitempty"`
+
+	Index AIIndexConfig `yaml:"index" json:"index,omitempty"`
+
+	FixMemory AIFixMemoryConfig `yaml:"fix_memory" json:"fix_memory,omitempty"`
+
+	// Pricing is the price of each model's tokens, keyed by model name,
+	// for the AI cost in task reports.
+	Pricing map[string]AIPrice `yaml:"pricing" json:"pricing,omitempty"`
+}
+
+// AIPrice is the price of a model in USD per million tokens.
+type AIPrice struct {
+	Input  float64 `yaml:"input" json:"input"`
+	Output float64 `yaml:"output" json:"output"`
+}
+
+// AIIndexConfig keeps an embedding index of the repository in the rig
+// database and gives code generation and failure analysis the files most
+// relevant to the plan or the failure logs, instead of the first files of
+// the repository.
+type AIIndexConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Provider computes the embeddings: openai or ollama. Empty uses
+	// ai.provider, which then must be one of them.
+	Provider string `yaml:"provider" json:"provider,omitempty"`
+	// Model is the embedding model; empty means text-embedding-3-small
+	// for openai and nomic-embed-text for ollama.
+	Model string `yaml:"model" json:"model,omitempty"`
+	// APIKey defaults to ai.api_key when the provider is ai.provider.
+	APIKey string `yaml:"api_key" json:"api_key,omitempty"`
+	// TopK is how many files are retrieved; 0 means 20.
+	TopK int `yaml:"top_k" json:"top_k,omitempty"`
+}
+
+// AIFixMemoryConfig remembers the changes of retries that made a failing
+// task pass, keyed by a normalized signature of the failure, and gives
+// failure analysis the fixes of similar past failures as hints.
+type AIFixMemoryConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Hints is how many past fixes are given at most; 0 means 3.
+	Hints int `yaml:"hints" json:"hints,omitempty"`
+	// MinSimilarity is the least share of words a past failure signature
+	// must have in common with the new one, between 0 and 1; 0 means 0.5.
+	MinSimilarity float64 `yaml:"min_similarity" json:"min_similarity,omitempty"`
+}
+
+// AIModelsConfig routes each AI call to a model of the primary provider by
+// operation and by prompt size, e.g. a cheap model for planning and a
+// strong one for code. Unset operations use ai.model.
+type AIModelsConfig struct {
+	Analyze   string `yaml:"analyze" json:"analyze,omitempty"`
+	Generate  string `yaml:"generate" json:"generate,omitempty"`
+	Fix       string `yaml:"fix" json:"fix,omitempty"`               // test and build failure analysis
+	DeployFix string `yaml:"deploy_fix" json:"deploy_fix,omitempty"` // deploy failure analysis
+	Summarize string `yaml:"summarize" json:"summarize,omitempty"`
+
+	// Tiers pick a model by the estimated size of the prompt. The first
+	// tier, in order, whose max_input_tokens the prompt fits and whose
+	// operations include the call wins over the per-operation models.
+	Tiers []AIModelTierConfig `yaml:"tiers" json:"tiers,omitempty"`
+}
+
+// AIModelTierConfig is a prompt size tier of ai.models.
+type AIModelTierConfig struct {
+	MaxInputTokens int      `yaml:"max_input_tokens" json:"max_input_tokens"`
+	Model          string   `yaml:"model" json:"model"`
+	Operations     []string `yaml:"operations" json:"operations,omitempty"` // empty means all
+}
+
+// AICacheConfig caches issue analyses and generated code in the rig
+// database, keyed on a hash of the provider, model and prompt inputs, so a
+// re-triggered or replayed issue does not pay for the same answer twice.
+type AICacheConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// TTL is how long an answer is reused; 0 means 24h.
+	TTL time.Duration `yaml:"ttl" json:"ttl,omitempty"`
+}
+
+// AIFallbackConfig is a fallback AI provider. It shares language, timeouts
+// and retries with the primary provider.
+type AIFallbackConfig struct {
+	Provider string `yaml:"provider" json:"provider"` // anthropic|openai|ollama|claude-code
+	Model    string `yaml:"model" json:"model"`
+	APIKey   string `yaml:"api_key" json:"api_key,omitempty"`
+}
+
+// AIRetryConfig retries AI requests that failed with 429, a 5xx status or
+// a network error, backing off exponentially with jitter or as long as a
+// Retry-After header asks. Zero values use the defaults.
+type AIRetryConfig struct {
+	// MaxAttempts includes the first request; 1 disables retries.
+	MaxAttempts    int           `yaml:"max_attempts" json:"max_attempts,omitempty"`
+	InitialBackoff time.Duration `yaml:"initial_backoff" json:"initial_backoff,omitempty"`
+	MaxBackoff     time.Duration `yaml:"max_backoff" json:"max_backoff,omitempty"`
+}
+
+// AITimeoutsConfig bounds AI calls by kind. Zero uses the provider default.
+type AITimeoutsConfig struct {
+	Analyze  time.Duration `yaml:"analyze" json:"analyze,omitempty"`   // planning and summaries
+	Generate time.Duration `yaml:"generate" json:"generate,omitempty"` // code, fix and deploy-fix generation
+	// Idle aborts a streamed response when nothing, not even a keepalive,
+	// arrives for this long. Only streaming providers (anthropic) use it.
+	Idle time.Duration `yaml:"idle" json:"idle,omitempty"`
 }
 
 // DeployConfig holds deployment settings.
 type DeployConfig struct {
-	Method        string               `yaml:"method" json:"method"` // custom|docker-compose|terraform|ansible|k8s
+	Method        string               `yaml:"method" json:"method"` // custom|docker-compose|terraform|ansible|k8s|plugin
 	Config        DeployMethodConfig   `yaml:"config" json:"config"`
 	Timeout       time.Duration        `yaml:"timeout" json:"timeout"`
 	Rollback      RollbackConfig       `yaml:"rollback" json:"rollback"`
 	Approval      DeployApprovalConfig `yaml:"approval" json:"approval"`
 	InfraFiles    []string             `yaml:"infra_files" json:"infra_files"`
 	InfraReadonly []string             `yaml:"infra_readonly" json:"infra_readonly"`
+
+	// Strategy deploys to Targets in stages instead of all at once:
+	// canary or blue-green deploys the first target and tests it before
+	// promoting to the rest, rolling tests after each target but the last.
+	Strategy string         `yaml:"strategy" json:"strategy,omitempty"` // canary|blue-green|rolling
+	Targets  []DeployTarget `yaml:"targets" json:"targets,omitempty"`
+}
+
+// DeployTarget is one stage of a deploy strategy, such as the canary, a
+// color or a batch of hosts.
+type DeployTarget struct {
+	Name string `yaml:"name" json:"name"`
+	// Commands deploy to the target; empty uses deploy.config.commands.
+	Commands []CustomCommand `yaml:"commands" json:"commands,omitempty"`
+	// Rollback undoes the target's deploy; empty uses
+	// deploy.rollback.config.commands.
+	Rollback []CustomCommand `yaml:"rollback" json:"rollback,omitempty"`
+	// Vars are passed to the target's commands and to the tests run
+	// against it, such as its URL.
+	Vars map[string]string `yaml:"vars" json:"vars,omitempty"`
 }
 
 // DeployApprovalConfig controls whether AI-proposed infra changes require human approval.
@@ -88,6 +376,10 @@ type DeployMethodConfig struct {
 	Manifest  string `yaml:"manifest" json:"manifest,omitempty"`
 	Namespace string `yaml:"namespace" json:"namespace,omitempty"`
 	Context   string `yaml:"context" json:"context,omitempty"`
+
+	// plugin
+	Plugin  string            `yaml:"plugin" json:"plugin,omitempty"`
+	Options map[string]string `yaml:"options" json:"options,omitempty"`
 }
 
 // CustomCommand represents a single deploy command.
@@ -99,6 +391,9 @@ type CustomCommand struct {
 	Retry     int               `yaml:"retry" json:"retry,omitempty"`
 	Env       map[string]string `yaml:"env" json:"env,omitempty"`
 	Transport TransportConfig   `yaml:"transport" json:"transport"`
+	// Shell runs a local command: sh, bash, powershell, pwsh or cmd;
+	// default sh, or powershell on Windows.
+	Shell string `yaml:"shell" json:"shell,omitempty"`
 }
 
 // TransportConfig controls how a command is executed.
@@ -126,7 +421,7 @@ type RollbackConfig struct {
 
 // TestConfig holds a single test definition.
 type TestConfig struct {
-	Type          string        `yaml:"type" json:"type"` // command|ai-verify
+	Type          string        `yaml:"type" json:"type"` // command|http|browser|coverage|ai-verify|plugin
 	Name          string        `yaml:"name" json:"name"`
 	Run           string        `yaml:"run" json:"run,omitempty"`
 	Prompt        string        `yaml:"prompt" json:"prompt,omitempty"`
@@ -134,6 +429,32 @@ type TestConfig struct {
 	Tools         []string      `yaml:"tools" json:"tools,omitempty"`
 	AffectedPaths []string      `yaml:"affected_paths" json:"affected_paths,omitempty"`
 	Timeout       time.Duration `yaml:"timeout" json:"timeout,omitempty"`
+	// Shell runs the command of command, browser and coverage tests, as
+	// in CustomCommand.
+	Shell string `yaml:"shell" json:"shell,omitempty"`
+
+	// command
+	Format string `yaml:"format" json:"format,omitempty"` // go-json|junit; empty = raw output only
+	Report string `yaml:"report" json:"report,omitempty"` // junit XML or coverage file; junit default: parse stdout
+
+	// http
+	Method       string            `yaml:"method" json:"method,omitempty"`
+	Headers      map[string]string `yaml:"headers" json:"headers,omitempty"`
+	Body         string            `yaml:"body" json:"body,omitempty"`
+	ExpectStatus int               `yaml:"expect_status" json:"expect_status,omitempty"` // default 200
+	ExpectBody   string            `yaml:"expect_body" json:"expect_body,omitempty"`     // regular expression
+	Retries      int               `yaml:"retries" json:"retries,omitempty"`
+	Backoff      time.Duration     `yaml:"backoff" json:"backoff,omitempty"` // initial delay, doubled per retry
+
+	// browser
+	ArtifactsDir string `yaml:"artifacts_dir" json:"artifacts_dir,omitempty"` // default: test-results
+
+	// coverage (also uses run, report and format: go-cover|lcov)
+	Threshold float64 `yaml:"threshold" json:"threshold,omitempty"` // minimum percent for changed files
+
+	// plugin
+	Plugin  string            `yaml:"plugin" json:"plugin,omitempty"`
+	Options map[string]string `yaml:"options" json:"options,omitempty"`
 }
 
 // PolicyConfig defines a policy-as-code rule.
@@ -149,6 +470,335 @@ type WorkflowConfig struct {
 	Trigger  []TriggerConfig `yaml:"trigger" json:"trigger"`
 	Steps    []string        `yaml:"steps" json:"steps"`
 	Approval ApprovalConfig  `yaml:"approval" json:"approval"`
+
+	// TestConcurrency is the number of test runners run in parallel.
+	// 0 or 1 runs them one at a time.
+	TestConcurrency int `yaml:"test_concurrency" json:"test_concurrency,omitempty"`
+	// TestTimeout applies to each runner whose test sets no timeout.
+	TestTimeout time.Duration `yaml:"test_timeout" json:"test_timeout,omitempty"`
+
+	Timeouts WorkflowTimeoutsConfig `yaml:"timeouts" json:"timeouts,omitempty"`
+
+	BranchSweep BranchSweepConfig `yaml:"branch_sweep" json:"branch_sweep,omitempty"`
+
+	// PRTemplate is a text/template markdown file for the PR description.
+	// Empty uses the built-in template.
+	PRTemplate string `yaml:"pr_template" json:"pr_template,omitempty"`
+	// DraftPR opens the PR as a draft at the first commit, keeps its body
+	// up to date across retries and marks it ready once tests pass.
+	DraftPR bool `yaml:"draft_pr" json:"draft_pr,omitempty"`
+
+	AutoMerge AutoMergeConfig `yaml:"auto_merge" json:"auto_merge,omitempty"`
+
+	IssueUpdates IssueUpdatesConfig `yaml:"issue_updates" json:"issue_updates,omitempty"`
+
+	PR PRConfig `yaml:"pr" json:"pr,omitempty"`
+
+	PRSize PRSizeConfig `yaml:"pr_size" json:"pr_size,omitempty"`
+
+	Decompose DecomposeConfig `yaml:"decompose" json:"decompose,omitempty"`
+
+	Epic EpicConfig `yaml:"epic" json:"epic,omitempty"`
+
+	RetryPolicy RetryPolicyConfig `yaml:"retry_policy" json:"retry_policy,omitempty"`
+
+	Triage TriageConfig `yaml:"triage" json:"triage,omitempty"`
+
+	Dedup DedupConfig `yaml:"dedup" json:"dedup,omitempty"`
+
+	// Labels change the settings of tasks whose issue carries a label, in
+	// rule order; a later rule overrides an earlier one.
+	Labels []LabelRule `yaml:"labels" json:"labels,omitempty"`
+
+	FailureBundle FailureBundleConfig `yaml:"failure_bundle" json:"failure_bundle,omitempty"`
+
+	// Hooks are scripts run at pipeline boundaries that can veto the step,
+	// rewrite the PR body or label the PR.
+	Hooks []HookConfig `yaml:"hooks" json:"hooks,omitempty"`
+
+	Format FormatConfig `yaml:"format" json:"format,omitempty"`
+}
+
+// WorkflowTimeoutsConfig bounds how long a task may run. A phase limit
+// applies to each run of that phase, retries included; Task bounds a whole
+// run of the pipeline, not counting time spent awaiting approval. Zero
+// means no limit.
+type WorkflowTimeoutsConfig struct {
+	Task       time.Duration `yaml:"task" json:"task,omitempty"`
+	Planning   time.Duration `yaml:"planning" json:"planning,omitempty"`
+	Coding     time.Duration `yaml:"coding" json:"coding,omitempty"` // clone and code generation
+	Committing time.Duration `yaml:"committing" json:"committing,omitempty"`
+	Deploying  time.Duration `yaml:"deploying" json:"deploying,omitempty"`
+	Testing    time.Duration `yaml:"testing" json:"testing,omitempty"`
+}
+
+// FailureBundleConfig writes a diagnostic bundle when a task fails and
+// links it from a comment on the source issue.
+type FailureBundleConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// URL is the dashboard address the comment links the bundle under
+	// (<url>/api/tasks/<id>/bundle). Empty names the file on the rig host.
+	URL string `yaml:"url" json:"url,omitempty"`
+}
+
+// ArtifactsConfig keeps the full deploy and test output of each attempt,
+// with the coverage reports, screenshots and logs test runners report, as
+// files the task references. The task itself keeps only the end of each
+// output.
+type ArtifactsConfig struct {
+	Enabled bool   `yaml:"enabled" json:"enabled"`
+	Backend string `yaml:"backend" json:"backend,omitempty"` // local (default)|s3
+	// Dir holds local artifacts; default artifacts/ next to the state file.
+	Dir string           `yaml:"dir" json:"dir,omitempty"`
+	S3  ArtifactS3Config `yaml:"s3" json:"s3,omitempty"`
+	// InlineBytes is how much of the end of a stored output the task keeps;
+	// default 64KiB.
+	InlineBytes int `yaml:"inline_bytes" json:"inline_bytes,omitempty"`
+	// MaxFileBytes skips reported files larger than this; default 100MiB.
+	MaxFileBytes int64                    `yaml:"max_file_bytes" json:"max_file_bytes,omitempty"`
+	Retention    ArtifactsRetentionConfig `yaml:"retention" json:"retention,omitempty"`
+}
+
+// ArtifactS3Config is an S3-compatible bucket, such as AWS S3 or MinIO.
+// Objects are addressed path-style (<endpoint>/<bucket>/<key>).
+type ArtifactS3Config struct {
+	// Endpoint defaults to https://s3.<region>.amazonaws.com.
+	Endpoint string `yaml:"endpoint" json:"endpoint,omitempty"`
+	Region   string `yaml:"region" json:"region,omitempty"` // default us-east-1
+	Bucket   string `yaml:"bucket" json:"bucket"`
+	Prefix   string `yaml:"prefix" json:"prefix,omitempty"`
+	// AccessKey and SecretKey default to AWS_ACCESS_KEY_ID and
+	// AWS_SECRET_ACCESS_KEY.
+	AccessKey string `yaml:"access_key" json:"access_key,omitempty"`
+	SecretKey string `yaml:"secret_key" json:"secret_key,omitempty"`
+}
+
+// ArtifactsRetentionConfig deletes stored artifacts in serve mode. 0
+// disables each limit.
+type ArtifactsRetentionConfig struct {
+	// MaxAge deletes artifacts stored longer ago than this.
+	MaxAge time.Duration `yaml:"max_age" json:"max_age,omitempty"`
+	// MaxBytes deletes the oldest artifacts while all of them take more.
+	MaxBytes int64 `yaml:"max_bytes" json:"max_bytes,omitempty"`
+	// Interval is how often the limits are applied; default 1h.
+	Interval time.Duration `yaml:"interval" json:"interval,omitempty"`
+}
+
+// BackupConfig configures the backups of the state file, the SQLite
+// database and rig.yaml that rig backup create writes and rig serve takes
+// every Interval.
+type BackupConfig struct {
+	// Dir holds the backups; default ~/.rig/backups.
+	Dir string `yaml:"dir" json:"dir,omitempty"`
+	// Interval is how often rig serve takes a backup; 0 disables it.
+	Interval time.Duration `yaml:"interval" json:"interval,omitempty"`
+	// Keep is how many backups rig serve keeps in Dir; default 7.
+	Keep int `yaml:"keep" json:"keep,omitempty"`
+	// Passphrase, when set, encrypts the backups rig serve takes and is the
+	// default passphrase of rig backup.
+	Passphrase string `yaml:"passphrase" json:"-"`
+}
+
+// TenantConfig is a team served by rig serve next to the top-level config.
+type TenantConfig struct {
+	// Name is the tenant's namespace in /api/t/<name>/: lowercase letters,
+	// digits and dashes.
+	Name string `yaml:"name" json:"name"`
+	// Config is the tenant's rig.yaml, relative to this file. Its projects,
+	// source, ai, deploy and workflow apply to the tenant's tasks only.
+	Config string      `yaml:"config" json:"config"`
+	Quota  TenantQuota `yaml:"quota" json:"quota,omitempty"`
+}
+
+// TenantQuota limits what one tenant can use of a shared rig serve. Zero
+// values do not limit.
+type TenantQuota struct {
+	// MaxConcurrentTasks is how many of the tenant's tasks run at once;
+	// the others wait queued.
+	MaxConcurrentTasks int `yaml:"max_concurrent_tasks" json:"max_concurrent_tasks,omitempty"`
+	// AIBudgetUSD caps the AI cost, priced with the tenant's ai.pricing,
+	// of the tasks the tenant creates in a calendar month (UTC). New tasks
+	// are refused once it is spent.
+	AIBudgetUSD float64 `yaml:"ai_budget_usd" json:"ai_budget_usd,omitempty"`
+}
+
+// PRConfig routes the task's PR to people once it is published. Entries
+// may use the task variables, such as ${ENVIRONMENT}; an entry left empty
+// or unresolved is skipped.
+type PRConfig struct {
+	Labels []string `yaml:"labels" json:"labels,omitempty"`
+	// Reviewers are users, or org/team for teams, to request reviews from.
+	Reviewers []string `yaml:"reviewers" json:"reviewers,omitempty"`
+	// Assignees are users to assign the PR to.
+	Assignees []string `yaml:"assignees" json:"assignees,omitempty"`
+}
+
+// PRSizeConfig limits the size of a single commit of generated changes.
+// Larger change sets are committed as one commit per plan step.
+type PRSizeConfig struct {
+	MaxFiles int `yaml:"max_files" json:"max_files,omitempty"` // 0 = no limit
+	MaxLines int `yaml:"max_lines" json:"max_lines,omitempty"` // added + removed, 0 = no limit
+}
+
+// RetryPolicyConfig controls what happens between a failing test run and
+// the AI retry that fixes it.
+type RetryPolicyConfig struct {
+	// FlakyReruns re-runs failing tests up to this many times, unchanged,
+	// before asking the AI for a fix. A test that passes on a re-run is
+	// marked flaky and counts as passed. 0 = no re-runs.
+	FlakyReruns int `yaml:"flaky_reruns" json:"flaky_reruns,omitempty"`
+	// Backoff is the wait before each re-run and each retry's deploy,
+	// doubling every time up to MaxBackoff. 0 = no wait.
+	Backoff    time.Duration `yaml:"backoff" json:"backoff,omitempty"`
+	MaxBackoff time.Duration `yaml:"max_backoff" json:"max_backoff,omitempty"` // 0 = no cap
+}
+
+// TriageConfig classifies a test failure before a retry fixes it and picks
+// the fix by class: compile errors are tested without a redeploy, infra
+// errors and timeouts are first retried without code changes, and infra
+// errors that persist become deploy-fix proposals.
+type TriageConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// AI asks the AI to classify failures the heuristics cannot place.
+	AI bool `yaml:"ai" json:"ai,omitempty"`
+}
+
+// DedupConfig compares each new issue with the issues of recent tasks and
+// does not start a second pipeline for one that repeats them.
+type DedupConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Method is text (default), which matches identical normalized text or
+	// a large share of common words, or embedding, which compares the
+	// issues' ai.index embeddings.
+	Method string `yaml:"method" json:"method,omitempty"`
+	// Threshold is the least similarity of a duplicate, between 0 and 1;
+	// 0 means 0.85.
+	Threshold float64 `yaml:"threshold" json:"threshold,omitempty"`
+	// Window is how far back tasks are compared; 0 means 7 days.
+	Window time.Duration `yaml:"window" json:"window,omitempty"`
+	// Action is skip (default), which comments on the new issue with a link
+	// to the existing task, or attach, which also adds the issue to that
+	// task so its PR closes both.
+	Action string `yaml:"action" json:"action,omitempty"`
+}
+
+// LabelRule changes the settings of the tasks of issues with Label.
+type LabelRule struct {
+	// Label is matched case-insensitively. One ending in "=", such as
+	// rig:model=, matches every label it starts, whose rest is the value.
+	Label string `yaml:"label" json:"label"`
+	// SkipSteps are workflow steps the task skips: deploy (and with it
+	// test) or test.
+	SkipSteps []string `yaml:"skip_steps" json:"skip_steps,omitempty"`
+	// DraftPR opens the task's PR as a draft when true and as a regular PR
+	// when false, whatever workflow.draft_pr says.
+	DraftPR *bool `yaml:"draft_pr" json:"draft_pr,omitempty"`
+	// Model is the ai.model of the task, of the same provider; a rule of a
+	// label ending in "=" without one uses the label's value.
+	Model string `yaml:"model" json:"model,omitempty"`
+}
+
+// HookConfig is a script run at a pipeline boundary. It reads the task, the
+// variables and what the boundary is about as JSON on stdin and answers
+// JSON on stdout.
+type HookConfig struct {
+	Name string `yaml:"name" json:"name"`
+	On   string `yaml:"on" json:"on"` // pre_commit|post_deploy|pre_pr
+	// Script is a Starlark file run by rig's embedded interpreter, from
+	// the task's workspace when relative, such as "hooks/policy.star".
+	Script string `yaml:"script" json:"script,omitempty"`
+	// Run is a shell command run in the task's workspace instead, such as
+	// "python3 hooks/labels.py".
+	Run string `yaml:"run" json:"run,omitempty"`
+	// Timeout bounds a run; default 1m.
+	Timeout time.Duration `yaml:"timeout" json:"timeout,omitempty"`
+	// Shell runs Run, as in CustomCommand.
+	Shell string `yaml:"shell" json:"shell,omitempty"`
+}
+
+// FormatConfig formats the generated files in the workspace before they
+// are committed, so formatting drift in AI output does not fail linters.
+type FormatConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Formatters run in order, each on the changed files it matches.
+	// Without any, Go files are formatted with goimports, or gofmt when
+	// goimports is not installed.
+	Formatters []FormatterConfig `yaml:"formatters" json:"formatters,omitempty"`
+}
+
+// FormatterConfig is a command that formats files in place, such as
+// "npx prettier --write" or "black -q". The changed files it matches are
+// appended to Run as arguments.
+type FormatterConfig struct {
+	Name string `yaml:"name" json:"name"`
+	// Files are patterns of the files it formats, such as "*.py"; a pattern
+	// with a slash matches the path from the repository root, one without
+	// the file name.
+	Files []string `yaml:"files" json:"files"`
+	Run   string   `yaml:"run" json:"run"`
+	// Timeout bounds a run; default 1m.
+	Timeout time.Duration `yaml:"timeout" json:"timeout,omitempty"`
+	// Shell runs Run, as in CustomCommand.
+	Shell string `yaml:"shell" json:"shell,omitempty"`
+}
+
+// DecomposeConfig splits the plan of a large issue into sub-tasks, such as
+// backend, tests and docs, and generates the code of each in its own AI
+// pass before merging the file changes.
+type DecomposeConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// MinSteps is the number of plan steps from which a plan is split;
+	// smaller plans are generated in one pass. 0 = 4.
+	MinSteps int `yaml:"min_steps" json:"min_steps,omitempty"`
+	// MaxSubtasks caps the number of sub-tasks. 0 = 4.
+	MaxSubtasks int `yaml:"max_subtasks" json:"max_subtasks,omitempty"`
+	// Parallel runs the passes at once on the same context instead of one
+	// after the other on the changes of the previous ones. A sub-task that
+	// changes a file another one changed is then generated again on top
+	// of the earlier changes.
+	Parallel bool `yaml:"parallel" json:"parallel,omitempty"`
+}
+
+// EpicConfig runs an issue whose body has a checklist as an epic: a child
+// task per part of the checklist, each opening a PR branched off the one
+// before, so the PRs form a stack.
+type EpicConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// MinItems is the number of open checklist items from which an issue
+	// is an epic. 0 = 2.
+	MinItems int `yaml:"min_items" json:"min_items,omitempty"`
+	// MaxParts caps the number of child tasks; the items beyond it join
+	// the last part. 0 = 10.
+	MaxParts int `yaml:"max_parts" json:"max_parts,omitempty"`
+	// AI asks the AI to group and order the items into parts; without it
+	// every item is a part, in checklist order.
+	AI bool `yaml:"ai" json:"ai,omitempty"`
+}
+
+// IssueUpdatesConfig posts progress comments on the source issue.
+type IssueUpdatesConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Events limits which updates are posted: plan, pr, failed.
+	// Empty posts all of them.
+	Events []string `yaml:"events" json:"events,omitempty"`
+}
+
+// AutoMergeConfig merges the task PR once the platform's required checks
+// and reviews pass.
+type AutoMergeConfig struct {
+	Enabled      bool          `yaml:"enabled" json:"enabled"`
+	Method       string        `yaml:"method" json:"method,omitempty"`               // merge (default) | squash | rebase
+	Timeout      time.Duration `yaml:"timeout" json:"timeout,omitempty"`             // default 1h
+	PollInterval time.Duration `yaml:"poll_interval" json:"poll_interval,omitempty"` // default 30s
+	DeleteBranch bool          `yaml:"delete_branch" json:"delete_branch,omitempty"`
+	CloseIssue   bool          `yaml:"close_issue" json:"close_issue,omitempty"` // comment a summary and close the issue
+}
+
+// BranchSweepConfig controls the periodic deletion of orphaned rig/ branches
+// in serve mode.
+type BranchSweepConfig struct {
+	Interval  time.Duration `yaml:"interval" json:"interval,omitempty"`   // 0 disables the sweeper
+	Protected []string      `yaml:"protected" json:"protected,omitempty"` // glob patterns never deleted, e.g. rig/keep-*
 }
 
 // TriggerConfig holds a single workflow trigger.
@@ -160,21 +810,255 @@ type TriggerConfig struct {
 
 // ApprovalConfig holds deployment approval settings.
 type ApprovalConfig struct {
-	BeforeDeploy bool          `yaml:"before_deploy" json:"before_deploy"`
-	Method       string        `yaml:"method" json:"method,omitempty"`
-	Approvers    []string      `yaml:"approvers" json:"approvers,omitempty"`
-	Timeout      time.Duration `yaml:"timeout" json:"timeout,omitempty"`
+	BeforeDeploy bool `yaml:"before_deploy" json:"before_deploy"`
+	// AfterPlanning publishes the AI's plan as a proposal and only generates
+	// code once it is approved, possibly edited.
+	AfterPlanning bool          `yaml:"after_planning" json:"after_planning,omitempty"`
+	Method        string        `yaml:"method" json:"method,omitempty"`
+	Approvers     []string      `yaml:"approvers" json:"approvers,omitempty"`
+	Timeout       time.Duration `yaml:"timeout" json:"timeout,omitempty"`
 }
 
 // NotifyConfig holds a single notification channel.
 type NotifyConfig struct {
-	Type    string   `yaml:"type" json:"type"` // slack|discord|comment
+	Type    string   `yaml:"type" json:"type"` // slack|discord|comment|plugin
 	Webhook string   `yaml:"webhook" json:"webhook,omitempty"`
 	On      []string `yaml:"on" json:"on"` // deploy|test_fail|test_pass|pr_created|all
+
+	// plugin
+	Plugin  string            `yaml:"plugin" json:"plugin,omitempty"`
+	Options map[string]string `yaml:"options" json:"options,omitempty"`
+}
+
+// EventSinkConfig is a destination of task lifecycle events (created,
+// phase changed, proposal pending, completed, failed).
+type EventSinkConfig struct {
+	// Type is webhook (HTTP POST), nats (core NATS publish) or kafka
+	// (through a Kafka REST proxy).
+	Type string `yaml:"type" json:"type"`
+	// URL is the webhook URL, the NATS server URL or the REST proxy URL.
+	URL string `yaml:"url" json:"url"`
+	// Topic is the NATS subject or the Kafka topic.
+	Topic string `yaml:"topic" json:"topic,omitempty"`
+	// Secret signs webhook bodies with HMAC-SHA256 in
+	// X-Rig-Signature-256, as GitHub signs its webhooks.
+	Secret string `yaml:"secret" json:"secret,omitempty"`
+	// Headers are added to every webhook request.
+	Headers map[string]string `yaml:"headers" json:"headers,omitempty"`
+	// Format is rig (default), the event as it is, or cloudevents, the
+	// event as the data of a CloudEvents 1.0 event.
+	Format string `yaml:"format" json:"format,omitempty"`
+	// Mode is the CloudEvents content mode: structured (default), one
+	// JSON envelope, or binary, the attributes as ce- headers. Kafka sinks
+	// are structured only; the REST proxy does not set record headers.
+	Mode string `yaml:"mode" json:"mode,omitempty"`
+	// Source is the CloudEvents source attribute; default "rig".
+	Source string `yaml:"source" json:"source,omitempty"`
+	// Types limits the sink to these event types, such as task.completed;
+	// empty sends every event.
+	Types []string `yaml:"types" json:"types,omitempty"`
+}
+
+// PluginConfig declares an external adapter: an executable rig runs for
+// every call, speaking a JSON protocol over stdin and stdout.
+type PluginConfig struct {
+	Name    string            `yaml:"name" json:"name"`
+	Command string            `yaml:"command" json:"command"`
+	Args    []string          `yaml:"args" json:"args,omitempty"`
+	Env     map[string]string `yaml:"env" json:"env,omitempty"`
+	// Timeout bounds each call; default 5m.
+	Timeout time.Duration `yaml:"timeout" json:"timeout,omitempty"`
 }
 
 // ServerConfig holds webhook server settings.
 type ServerConfig struct {
 	Port   int    `yaml:"port" json:"port"`
 	Secret string `yaml:"secret" json:"secret"`
+	// Host is the address the dashboard and webhook servers bind to, such as
+	// 127.0.0.1 behind a reverse proxy; empty listens on all interfaces.
+	Host string          `yaml:"host" json:"host,omitempty"`
+	TLS  ServerTLSConfig `yaml:"tls" json:"tls,omitempty"`
+	// TrustedProxies lists the IPs or CIDRs of reverse proxies whose
+	// X-Forwarded-For and X-Real-IP headers carry the client IP. Headers
+	// from anyone else are ignored.
+	TrustedProxies []string `yaml:"trusted_proxies" json:"trusted_proxies,omitempty"`
+	// DrainTimeout is how long shutdown waits for running tasks before
+	// cancelling them; default 5m.
+	DrainTimeout time.Duration      `yaml:"drain_timeout" json:"drain_timeout,omitempty"`
+	Limits       ServerLimitsConfig `yaml:"limits" json:"limits,omitempty"`
+	Auth         AuthConfig         `yaml:"auth" json:"auth"`
+	// Webhooks replaces the single GitHub endpoint at /webhook with one
+	// endpoint per entry, so one server can receive events from several
+	// repositories and platforms.
+	Webhooks []WebhookEndpointConfig `yaml:"webhooks" json:"webhooks,omitempty"`
+	// GRPC serves the gRPC API next to the dashboard.
+	GRPC ServerGRPCConfig `yaml:"grpc" json:"grpc,omitempty"`
+	// Queues are message queues rig serve takes issues from besides
+	// webhooks.
+	Queues []QueueConfig `yaml:"queues" json:"queues,omitempty"`
+}
+
+// ServerGRPCConfig configures the gRPC API of rig serve. It binds to
+// server.host and uses server.tls like the other listeners.
+type ServerGRPCConfig struct {
+	// Port is where the gRPC API listens; 0 disables it.
+	Port int `yaml:"port" json:"port,omitempty"`
+}
+
+// WebhookEndpointConfig is one webhook endpoint of the webhook server.
+type WebhookEndpointConfig struct {
+	// Path is where the endpoint is served, such as /webhook/gitlab.
+	Path string `yaml:"path" json:"path"`
+	// Platform selects the payload format and signature check: github
+	// (default, or the project's platform) or gitlab.
+	Platform string `yaml:"platform" json:"platform,omitempty"`
+	// Secret is the HMAC key for GitHub and the X-Gitlab-Token value for
+	// GitLab; empty uses server.secret.
+	Secret string `yaml:"secret" json:"secret,omitempty"`
+	// Project names a projects entry (by name or repo) or the source repo.
+	// Events from other repositories are ignored; empty accepts any.
+	Project string `yaml:"project" json:"project,omitempty"`
+}
+
+// QueueConfig is a message queue rig serve consumes issues from. Each
+// message is one issue; a message is acknowledged once its task is
+// recorded, or skipped when the issue already has a task in flight, so
+// redelivered messages start no second task.
+type QueueConfig struct {
+	// Name identifies the queue in logs and the audit log; default the
+	// broker.
+	Name string `yaml:"name" json:"name,omitempty"`
+	// Broker is nats (JetStream), kafka (through a Kafka REST proxy) or sqs.
+	Broker string `yaml:"broker" json:"broker"`
+	// URL is the NATS server URL, the REST proxy URL for Kafka or the SQS
+	// queue URL.
+	URL string `yaml:"url" json:"url"`
+	// Topic is the NATS subject or the Kafka topic; SQS takes none.
+	Topic string `yaml:"topic" json:"topic,omitempty"`
+	// Group is the JetStream durable consumer or the Kafka consumer group;
+	// default rig.
+	Group string `yaml:"group" json:"group,omitempty"`
+	// Region is the AWS region of an SQS queue; default the region in the
+	// queue URL. Credentials come from AWS_ACCESS_KEY_ID,
+	// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN.
+	Region string `yaml:"region" json:"region,omitempty"`
+	// Project names a projects entry (by name or repo) or the source repo
+	// for messages that carry no repo; default the source repo.
+	Project string `yaml:"project" json:"project,omitempty"`
+	// Mapping says where the issue fields are in a JSON message.
+	Mapping QueueMappingConfig `yaml:"mapping" json:"mapping,omitempty"`
+}
+
+// QueueMappingConfig maps a JSON message to an issue. Each field is a
+// dotted path into the message, such as issue.number; empty uses the
+// issue field's own name (platform, repo, id, title, body, url, labels).
+// Labels may be strings or objects with a name.
+type QueueMappingConfig struct {
+	Platform string `yaml:"platform" json:"platform,omitempty"`
+	Repo     string `yaml:"repo" json:"repo,omitempty"`
+	ID       string `yaml:"id" json:"id,omitempty"`
+	Title    string `yaml:"title" json:"title,omitempty"`
+	Body     string `yaml:"body" json:"body,omitempty"`
+	URL      string `yaml:"url" json:"url,omitempty"`
+	Labels   string `yaml:"labels" json:"labels,omitempty"`
+}
+
+// ServerLimitsConfig protects rig serve from clients that send too much.
+// Rates are per minute; 0 uses the default and a negative value disables
+// the limit.
+type ServerLimitsConfig struct {
+	// RequestsPerMinute caps dashboard and API requests per client IP;
+	// default 120.
+	RequestsPerMinute int `yaml:"requests_per_minute" json:"requests_per_minute,omitempty"`
+	// WebhookRequestsPerMinute caps webhook deliveries per client IP;
+	// default 600.
+	WebhookRequestsPerMinute int `yaml:"webhook_requests_per_minute" json:"webhook_requests_per_minute,omitempty"`
+	// APIMaxBodyBytes caps API request bodies; default 1 MiB.
+	APIMaxBodyBytes int64 `yaml:"api_max_body_bytes" json:"api_max_body_bytes,omitempty"`
+	// WebhookMaxBodyBytes caps webhook payloads; default 10 MiB.
+	WebhookMaxBodyBytes int64 `yaml:"webhook_max_body_bytes" json:"webhook_max_body_bytes,omitempty"`
+	// KeyRequestsPerMinute caps API requests per authenticated caller (API
+	// key or logged-in user); 0 sets no quota.
+	KeyRequestsPerMinute int `yaml:"key_requests_per_minute" json:"key_requests_per_minute,omitempty"`
+	// KeyQuotas overrides KeyRequestsPerMinute per caller, keyed by the
+	// audit actor: "key:<name>", "user:<user>" or "api-key".
+	KeyQuotas map[string]int `yaml:"key_quotas" json:"key_quotas,omitempty"`
+}
+
+// ServerTLSConfig serves the dashboard and webhooks over HTTPS, either with
+// a certificate from files or one obtained from Let's Encrypt.
+type ServerTLSConfig struct {
+	CertFile string `yaml:"cert_file" json:"cert_file,omitempty"`
+	KeyFile  string `yaml:"key_file" json:"key_file,omitempty"`
+	// AutocertDomains obtains certificates for these domains through
+	// ACME TLS-ALPN-01, which needs port 443 of each domain to reach rig.
+	AutocertDomains []string `yaml:"autocert_domains" json:"autocert_domains,omitempty"`
+	// AutocertEmail is the ACME account contact.
+	AutocertEmail string `yaml:"autocert_email" json:"autocert_email,omitempty"`
+	// AutocertCacheDir stores certificates between restarts; default
+	// ~/.rig/autocert.
+	AutocertCacheDir string `yaml:"autocert_cache_dir" json:"autocert_cache_dir,omitempty"`
+}
+
+// Enabled reports whether TLS is configured.
+func (t ServerTLSConfig) Enabled() bool {
+	return t.CertFile != "" || len(t.AutocertDomains) > 0
+}
+
+// AuthConfig enables login to the web dashboard and API through an OIDC
+// provider (Google, Keycloak, ...) or GitHub OAuth. Logged-in users get a
+// session cookie and act with the role chosen by Roles or DefaultRole.
+type AuthConfig struct {
+	// Provider is oidc or github; empty disables login.
+	Provider string `yaml:"provider" json:"provider,omitempty"`
+	// Issuer is the OIDC issuer URL. For github it optionally points at a
+	// GitHub Enterprise Server (https://github.example.com).
+	Issuer       string `yaml:"issuer" json:"issuer,omitempty"`
+	ClientID     string `yaml:"client_id" json:"client_id,omitempty"`
+	ClientSecret string `yaml:"client_secret" json:"client_secret,omitempty"`
+	// RedirectURL is the public URL of /auth/callback.
+	RedirectURL string `yaml:"redirect_url" json:"redirect_url,omitempty"`
+	// Scopes defaults to openid, email and profile for oidc and read:user
+	// and user:email for github.
+	Scopes []string `yaml:"scopes" json:"scopes,omitempty"`
+	// AllowedUsers and AllowedDomains restrict who may log in: emails (oidc)
+	// or logins (github), and email domains. Both empty allows everyone the
+	// provider authenticates.
+	AllowedUsers   []string `yaml:"allowed_users" json:"allowed_users,omitempty"`
+	AllowedDomains []string `yaml:"allowed_domains" json:"allowed_domains,omitempty"`
+	// DefaultRole is the role of users not listed in Roles; default viewer.
+	DefaultRole string `yaml:"default_role" json:"default_role,omitempty"`
+	// Roles maps a user (email or login) to viewer, operator, approver or admin.
+	Roles map[string]string `yaml:"roles" json:"roles,omitempty"`
+	// SessionTTL is how long a login lasts; default 12h.
+	SessionTTL time.Duration `yaml:"session_ttl" json:"session_ttl,omitempty"`
+	// SessionSecret signs session cookies. When empty a random secret is
+	// used and sessions end when the server restarts.
+	SessionSecret string `yaml:"session_secret" json:"session_secret,omitempty"`
+}
+
+// LogConfig selects the server log level and format. The --log-level and
+// --log-format flags and RIG_LOG_LEVEL / RIG_LOG_FORMAT override it.
+type LogConfig struct {
+	// Level is debug, info (default), warn or error.
+	Level string `yaml:"level" json:"level,omitempty"`
+	// Format is text (default) or json.
+	Format string `yaml:"format" json:"format,omitempty"`
+	// Retention bounds the task logs kept in SQLite in serve mode.
+	Retention LogRetentionConfig `yaml:"retention" json:"retention,omitempty"`
+}
+
+// LogRetentionConfig prunes and compresses the task logs kept in SQLite in
+// serve mode. 0 disables each setting.
+type LogRetentionConfig struct {
+	// MaxRowsPerTask keeps only the newest lines of each task.
+	MaxRowsPerTask int `yaml:"max_rows_per_task" json:"max_rows_per_task,omitempty"`
+	// MaxAge deletes lines logged longer ago than this.
+	MaxAge time.Duration `yaml:"max_age" json:"max_age,omitempty"`
+	// MaxBytes deletes the oldest lines while all task logs take more.
+	MaxBytes int64 `yaml:"max_bytes" json:"max_bytes,omitempty"`
+	// CompressAfter gzip-compresses lines logged longer ago than this.
+	CompressAfter time.Duration `yaml:"compress_after" json:"compress_after,omitempty"`
+	// Interval is how often the settings are applied; default 1h.
+	Interval time.Duration `yaml:"interval" json:"interval,omitempty"`
 }
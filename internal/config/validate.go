@@ -2,9 +2,37 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"path"
+	"regexp"
 	"strings"
+
+	"github.com/rigdev/rig/internal/logging"
 )
 
+// validAIProviders is the set of supported AI providers.
+var validAIProviders = map[string]bool{
+	"anthropic":   true,
+	"openai":      true,
+	"ollama":      true,
+	"claude-code": true,
+}
+
+// validAIOperations are the AI calls ai.models can route to a model.
+var validAIOperations = map[string]bool{
+	"analyze":    true,
+	"generate":   true,
+	"fix":        true,
+	"deploy_fix": true,
+	"summarize":  true,
+}
+
+// validEmbeddingProviders are the ai.index providers.
+var validEmbeddingProviders = map[string]bool{
+	"openai": true,
+	"ollama": true,
+}
+
 // validPlatforms is the set of supported source platforms.
 var validPlatforms = map[string]bool{
 	"github":    true,
@@ -20,6 +48,33 @@ var validDeployMethods = map[string]bool{
 	"terraform":      true,
 	"ansible":        true,
 	"k8s":            true,
+	"plugin":         true,
+}
+
+// validDeployStrategies is the set of supported deploy strategies.
+var validDeployStrategies = map[string]bool{
+	"canary":     true,
+	"blue-green": true,
+	"rolling":    true,
+}
+
+// validHTTPMethods is the set of methods accepted by http tests.
+var validHTTPMethods = map[string]bool{
+	"GET":     true,
+	"HEAD":    true,
+	"POST":    true,
+	"PUT":     true,
+	"PATCH":   true,
+	"DELETE":  true,
+	"OPTIONS": true,
+}
+
+// validTestFormats is the set of structured output formats a command test
+// can declare. The empty string keeps the raw output only.
+var validTestFormats = map[string]bool{
+	"":        true,
+	"go-json": true,
+	"junit":   true,
 }
 
 // Validate checks the Config for completeness and correctness.
@@ -55,22 +110,154 @@ func Validate(cfg *Config) error {
 			cfg.Source.Platform))
 	}
 
+	for i, p := range cfg.Projects {
+		if p.Platform != "" && !validPlatforms[p.Platform] {
+			errs = append(errs, fmt.Sprintf(
+				"config: projects[%d].platform '%s' is invalid; must be one of: github, gitlab, bitbucket, gitea", i, p.Platform))
+		}
+		if strings.ContainsAny(p.Host, "/ ") {
+			errs = append(errs, fmt.Sprintf("config: projects[%d].host '%s' must be a host name like github.example.com", i, p.Host))
+		}
+	}
+
+	if cfg.Source.Workspaces.MaxBytes < 0 || cfg.Source.Workspaces.GCInterval < 0 {
+		errs = append(errs, "config: source.workspaces max_bytes and gc_interval must not be negative")
+	}
+	if cfg.Source.Clone.Depth < 0 {
+		errs = append(errs, "config: source.clone.depth must not be negative")
+	}
+	switch a := cfg.Source.GitAuthor; a.Identity {
+	case "", "token":
+	case "app":
+		if a.AppSlug == "" && (a.Name == "" || a.Email == "") {
+			errs = append(errs, "config: source.git_author.app_slug is required with identity 'app'")
+		}
+	default:
+		errs = append(errs, fmt.Sprintf(
+			"config: source.git_author.identity '%s' is invalid; must be one of: token, app", a.Identity))
+	}
+	if f := cfg.Source.Signing.Format; f != "" && f != "gpg" && f != "ssh" {
+		errs = append(errs, fmt.Sprintf(
+			"config: source.signing.format '%s' is invalid; must be one of: gpg, ssh", f))
+	}
+	if cfg.Source.Signing.Format != "" && cfg.Source.Signing.Key == "" {
+		errs = append(errs, "config: source.signing.key is required to sign commits")
+	}
+	for _, dir := range cfg.Source.Clone.Sparse {
+		if dir == "" || path.IsAbs(dir) || strings.HasPrefix(path.Clean(dir), "..") {
+			errs = append(errs, fmt.Sprintf("config: source.clone.sparse path '%s' must be a directory inside the repository", dir))
+		}
+	}
+
 	// --- AI max_retry range ---
 	if cfg.AI.MaxRetry != 0 && (cfg.AI.MaxRetry < 1 || cfg.AI.MaxRetry > 10) {
 		errs = append(errs, fmt.Sprintf(
 			"config: ai.max_retry must be between 1 and 10, got %d",
 			cfg.AI.MaxRetry))
 	}
+	if t := cfg.AI.Timeouts; t.Analyze < 0 || t.Generate < 0 || t.Idle < 0 {
+		errs = append(errs, "config: ai.timeouts must not be negative")
+	}
+	if r := cfg.AI.Retry; r.MaxAttempts < 0 || r.InitialBackoff < 0 || r.MaxBackoff < 0 {
+		errs = append(errs, "config: ai.retry must not be negative")
+	}
+	if cfg.AI.Cache.TTL < 0 {
+		errs = append(errs, "config: ai.cache.ttl must not be negative")
+	}
+	for i, fb := range cfg.AI.Fallback {
+		if !validAIProviders[fb.Provider] {
+			errs = append(errs, fmt.Sprintf(
+				"config: ai.fallback[%d].provider '%s' is invalid; must be one of: anthropic, openai, ollama, claude-code",
+				i, fb.Provider))
+		}
+		if fb.Model == "" && fb.Provider != "claude-code" {
+			errs = append(errs, fmt.Sprintf("config: ai.fallback[%d].model is required", i))
+		}
+	}
+	for i, tier := range cfg.AI.Models.Tiers {
+		if tier.MaxInputTokens <= 0 {
+			errs = append(errs, fmt.Sprintf("config: ai.models.tiers[%d].max_input_tokens must be positive", i))
+		}
+		if tier.Model == "" {
+			errs = append(errs, fmt.Sprintf("config: ai.models.tiers[%d].model is required", i))
+		}
+		for _, op := range tier.Operations {
+			if !validAIOperations[op] {
+				errs = append(errs, fmt.Sprintf(
+					"config: ai.models.tiers[%d].operations: '%s' is invalid; must be one of: analyze, generate, fix, deploy_fix, summarize",
+					i, op))
+			}
+		}
+	}
+	for model, price := range cfg.AI.Pricing {
+		if price.Input < 0 || price.Output < 0 {
+			errs = append(errs, fmt.Sprintf("config: ai.pricing.%s must not be negative", model))
+		}
+	}
+	if idx := cfg.AI.Index; idx.Enabled {
+		provider := idx.Provider
+		if provider == "" {
+			provider = cfg.AI.Provider
+		}
+		if provider == "" {
+			provider = "anthropic"
+		}
+		if !validEmbeddingProviders[provider] {
+			errs = append(errs, fmt.Sprintf(
+				"config: ai.index.provider '%s' cannot compute embeddings; must be one of: openai, ollama", provider))
+		}
+		if idx.TopK < 0 {
+			errs = append(errs, "config: ai.index.top_k must not be negative")
+		}
+	}
+	if d := cfg.Workflow.Dedup; d.Enabled {
+		switch d.Method {
+		case "", "text":
+		case "embedding":
+			provider := cfg.AI.Index.Provider
+			if provider == "" {
+				provider = cfg.AI.Provider
+			}
+			if provider == "" {
+				provider = "anthropic"
+			}
+			if !validEmbeddingProviders[provider] {
+				errs = append(errs, fmt.Sprintf(
+					"config: workflow.dedup.method 'embedding' needs an ai.index.provider that computes embeddings (openai, ollama), not '%s'", provider))
+			}
+		default:
+			errs = append(errs, fmt.Sprintf("config: workflow.dedup.method '%s' is invalid; must be one of: text, embedding", d.Method))
+		}
+		if d.Action != "" && d.Action != "skip" && d.Action != "attach" {
+			errs = append(errs, fmt.Sprintf("config: workflow.dedup.action '%s' is invalid; must be one of: skip, attach", d.Action))
+		}
+		if d.Threshold < 0 || d.Threshold > 1 || d.Window < 0 {
+			errs = append(errs, "config: workflow.dedup.threshold must be between 0 and 1 and window must not be negative")
+		}
+	}
+	if fm := cfg.AI.FixMemory; fm.Hints < 0 || fm.MinSimilarity < 0 || fm.MinSimilarity > 1 {
+		errs = append(errs, "config: ai.fix_memory.hints must not be negative and min_similarity must be between 0 and 1")
+	}
+	for i, pattern := range cfg.AI.ContextIgnore {
+		for _, segment := range strings.Split(strings.Trim(strings.TrimPrefix(pattern, "!"), "/"), "/") {
+			if _, err := path.Match(segment, ""); err != nil {
+				errs = append(errs, fmt.Sprintf("config: ai.context_ignore[%d] '%s' is not a valid pattern", i, pattern))
+				break
+			}
+		}
+	}
 
 	// --- Deploy method validation ---
 	if cfg.Deploy.Method != "" && !validDeployMethods[cfg.Deploy.Method] {
 		errs = append(errs, fmt.Sprintf(
-			"config: deploy.method '%s' is invalid; must be one of: custom, docker-compose, terraform, ansible, k8s",
+			"config: deploy.method '%s' is invalid; must be one of: custom, docker-compose, terraform, ansible, k8s, plugin",
 			cfg.Deploy.Method))
 	}
 
 	// --- Deploy method-specific requirements ---
-	if cfg.Deploy.Method != "" {
+	// With a strategy, the targets may bring their own commands; they are
+	// checked with the strategy.
+	if cfg.Deploy.Method != "" && (cfg.Deploy.Strategy == "" || len(cfg.Deploy.Targets) == 0) {
 		errs = append(errs, validateDeployMethod(cfg.Deploy.Method, &cfg.Deploy.Config)...)
 	}
 
@@ -81,20 +268,388 @@ func Validate(cfg *Config) error {
 		}
 	}
 
+	// --- Deploy strategy ---
+	errs = append(errs, validateDeployStrategy(&cfg.Deploy)...)
+
+	// --- Environments ---
+	errs = append(errs, validateEnvironments(cfg.Environments)...)
+
 	// --- Rollback validation ---
 	errs = append(errs, validateRollback(&cfg.Deploy.Rollback)...)
 
+	// --- Test scheduling ---
+	if cfg.Workflow.TestConcurrency < 0 {
+		errs = append(errs, fmt.Sprintf(
+			"config: workflow.test_concurrency must not be negative, got %d",
+			cfg.Workflow.TestConcurrency))
+	}
+	if cfg.Workflow.TestTimeout < 0 {
+		errs = append(errs, "config: workflow.test_timeout must not be negative")
+	}
+	if t := cfg.Workflow.Timeouts; t.Task < 0 || t.Planning < 0 || t.Coding < 0 || t.Committing < 0 || t.Deploying < 0 || t.Testing < 0 {
+		errs = append(errs, "config: workflow.timeouts must not be negative")
+	}
+
+	// --- Branch sweeper ---
+	if cfg.Workflow.BranchSweep.Interval < 0 {
+		errs = append(errs, "config: workflow.branch_sweep.interval must not be negative")
+	}
+	for i, p := range cfg.Workflow.BranchSweep.Protected {
+		if _, err := path.Match(p, ""); err != nil {
+			errs = append(errs, fmt.Sprintf("config: workflow.branch_sweep.protected[%d] %q is not a valid pattern", i, p))
+		}
+	}
+
+	// --- Auto merge ---
+	switch cfg.Workflow.AutoMerge.Method {
+	case "", "merge", "squash", "rebase":
+	default:
+		errs = append(errs, fmt.Sprintf(
+			"config: workflow.auto_merge.method '%s' is invalid; must be one of: merge, squash, rebase",
+			cfg.Workflow.AutoMerge.Method))
+	}
+	if cfg.Workflow.AutoMerge.Timeout < 0 || cfg.Workflow.AutoMerge.PollInterval < 0 {
+		errs = append(errs, "config: workflow.auto_merge timeout and poll_interval must not be negative")
+	}
+
+	// --- Issue updates ---
+	for _, ev := range cfg.Workflow.IssueUpdates.Events {
+		switch ev {
+		case "plan", "pr", "failed":
+		default:
+			errs = append(errs, fmt.Sprintf(
+				"config: workflow.issue_updates.events '%s' is invalid; must be one of: plan, pr, failed", ev))
+		}
+	}
+
+	// --- PR routing ---
+	for i, a := range cfg.Workflow.PR.Assignees {
+		if strings.Contains(a, "/") {
+			errs = append(errs, fmt.Sprintf(
+				"config: workflow.pr.assignees[%d] '%s' is a team; only users can be assigned", i, a))
+		}
+	}
+
+	// --- PR size ---
+	if cfg.Workflow.PRSize.MaxFiles < 0 || cfg.Workflow.PRSize.MaxLines < 0 {
+		errs = append(errs, "config: workflow.pr_size max_files and max_lines must not be negative")
+	}
+
+	// --- Decompose ---
+	if cfg.Workflow.Decompose.MinSteps < 0 || cfg.Workflow.Decompose.MaxSubtasks < 0 {
+		errs = append(errs, "config: workflow.decompose min_steps and max_subtasks must not be negative")
+	}
+	if cfg.Workflow.Epic.MinItems < 0 || cfg.Workflow.Epic.MaxParts < 0 {
+		errs = append(errs, "config: workflow.epic min_items and max_parts must not be negative")
+	}
+
+	// --- Retry policy ---
+	if rp := cfg.Workflow.RetryPolicy; rp.FlakyReruns < 0 || rp.Backoff < 0 || rp.MaxBackoff < 0 {
+		errs = append(errs, "config: workflow.retry_policy flaky_reruns, backoff and max_backoff must not be negative")
+	} else if rp.MaxBackoff > 0 && rp.MaxBackoff < rp.Backoff {
+		errs = append(errs, "config: workflow.retry_policy.max_backoff must not be less than backoff")
+	}
+
+	// --- Hooks ---
+	errs = append(errs, validateHooks(cfg.Workflow.Hooks)...)
+	errs = append(errs, validateFormatters(cfg.Workflow.Format.Formatters)...)
+	errs = append(errs, validateLabelRules(cfg.Workflow.Labels)...)
+
+	// --- Failure bundle ---
+	if u := cfg.Workflow.FailureBundle.URL; u != "" && !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+		errs = append(errs, fmt.Sprintf("config: workflow.failure_bundle.url '%s' must start with http:// or https://", u))
+	}
+
+	// --- Logging ---
+	if _, err := logging.ParseLevel(cfg.Log.Level); err != nil {
+		errs = append(errs, "config: log.level: "+err.Error())
+	}
+	if f := cfg.Log.Format; f != "" && f != "text" && f != "json" {
+		errs = append(errs, fmt.Sprintf("config: log.format '%s' must be text or json", f))
+	}
+	if r := cfg.Log.Retention; r.MaxRowsPerTask < 0 || r.MaxAge < 0 || r.MaxBytes < 0 || r.CompressAfter < 0 || r.Interval < 0 {
+		errs = append(errs, "config: log.retention max_rows_per_task, max_age, max_bytes, compress_after and interval must not be negative")
+	}
+	if r := cfg.Log.Retention; r.MaxAge > 0 && r.CompressAfter >= r.MaxAge {
+		errs = append(errs, "config: log.retention.compress_after must be shorter than max_age")
+	}
+
+	// --- Server ---
+	errs = append(errs, validateServer(&cfg.Server)...)
+	errs = append(errs, validateWebhooks(cfg)...)
+	errs = append(errs, validateQueues(cfg)...)
+	errs = append(errs, validateEventSinks(cfg.Events)...)
+	errs = append(errs, validateArtifacts(&cfg.Artifacts)...)
+	if cfg.Backup.Interval < 0 || cfg.Backup.Keep < 0 {
+		errs = append(errs, "config: backup interval and keep must not be negative")
+	}
+	errs = append(errs, validateTenants(cfg.Tenants)...)
+
+	// --- Dashboard login ---
+	errs = append(errs, validateAuth(&cfg.Server.Auth)...)
+
 	// --- Test validation ---
 	for i, t := range cfg.Test {
 		errs = append(errs, validateTest(i, &t)...)
 	}
 
+	// --- Plugins ---
+	errs = append(errs, validatePlugins(cfg)...)
+
 	if len(errs) > 0 {
 		return fmt.Errorf("%s", strings.Join(errs, "; "))
 	}
 	return nil
 }
 
+// validateServer checks the listener, TLS and proxy settings.
+func validateServer(s *ServerConfig) []string {
+	var errs []string
+	if s.Port < 0 || s.Port > 65535 {
+		errs = append(errs, fmt.Sprintf("config: server.port %d is out of range", s.Port))
+	}
+	if g := s.GRPC.Port; g < 0 || g > 65535 {
+		errs = append(errs, fmt.Sprintf("config: server.grpc.port %d is out of range", g))
+	} else if g != 0 && g == s.Port {
+		errs = append(errs, fmt.Sprintf("config: server.grpc.port %d is also server.port", g))
+	}
+	if s.Host != "" && strings.Contains(s.Host, ":") && net.ParseIP(s.Host) == nil {
+		errs = append(errs, fmt.Sprintf("config: server.host '%s' must be a host name or IP without a port", s.Host))
+	}
+	t := s.TLS
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		errs = append(errs, "config: server.tls.cert_file and key_file must be set together")
+	}
+	if t.CertFile != "" && len(t.AutocertDomains) > 0 {
+		errs = append(errs, "config: server.tls: use either cert_file/key_file or autocert_domains, not both")
+	}
+	for i, p := range s.TrustedProxies {
+		if net.ParseIP(p) == nil {
+			if _, _, err := net.ParseCIDR(p); err != nil {
+				errs = append(errs, fmt.Sprintf("config: server.trusted_proxies[%d] '%s' is not an IP or CIDR", i, p))
+			}
+		}
+	}
+	if s.DrainTimeout < 0 {
+		errs = append(errs, "config: server.drain_timeout must not be negative")
+	}
+	if s.Limits.KeyRequestsPerMinute < 0 {
+		errs = append(errs, "config: server.limits.key_requests_per_minute must not be negative")
+	}
+	for actor, n := range s.Limits.KeyQuotas {
+		if n < 0 {
+			errs = append(errs, fmt.Sprintf("config: server.limits.key_quotas[%s] must not be negative", actor))
+		}
+	}
+	return errs
+}
+
+// validWebhookPlatforms are the payload formats the webhook server reads.
+var validWebhookPlatforms = map[string]bool{"github": true, "gitlab": true}
+
+// validateWebhooks checks server.webhooks: distinct paths, known platforms,
+// a secret, and projects that exist.
+func validateWebhooks(cfg *Config) []string {
+	var errs []string
+	seen := map[string]bool{}
+	for i, w := range cfg.Server.Webhooks {
+		field := fmt.Sprintf("server.webhooks[%d]", i)
+		switch {
+		case !strings.HasPrefix(w.Path, "/"):
+			errs = append(errs, fmt.Sprintf("config: %s.path '%s' must start with /", field, w.Path))
+		case seen[w.Path]:
+			errs = append(errs, fmt.Sprintf("config: %s.path '%s' is used by another webhook", field, w.Path))
+		}
+		seen[w.Path] = true
+		if w.Platform != "" && !validWebhookPlatforms[w.Platform] {
+			errs = append(errs, fmt.Sprintf("config: %s.platform '%s' is invalid; must be one of: github, gitlab", field, w.Platform))
+		}
+		if w.Secret == "" && cfg.Server.Secret == "" {
+			errs = append(errs, fmt.Sprintf("config: %s.secret is required when server.secret is empty", field))
+		}
+		if w.Project != "" && cfg.FindProject(w.Project) == nil {
+			errs = append(errs, fmt.Sprintf("config: %s.project '%s' is not the source repo or a projects entry", field, w.Project))
+		}
+	}
+	return errs
+}
+
+// validQueueBrokers are the brokers server.queues can consume.
+var validQueueBrokers = map[string]bool{"nats": true, "kafka": true, "sqs": true}
+
+// validateQueues checks server.queues: a known broker with the URL and
+// topic it needs, distinct names, and projects that exist.
+func validateQueues(cfg *Config) []string {
+	var errs []string
+	seen := map[string]bool{}
+	for i, q := range cfg.Server.Queues {
+		field := fmt.Sprintf("server.queues[%d]", i)
+		if !validQueueBrokers[q.Broker] {
+			errs = append(errs, fmt.Sprintf("config: %s.broker '%s' is invalid; must be one of: nats, kafka, sqs", field, q.Broker))
+		}
+		if q.URL == "" {
+			errs = append(errs, fmt.Sprintf("config: %s.url is required", field))
+		}
+		switch {
+		case q.Broker == "sqs" && q.Topic != "":
+			errs = append(errs, fmt.Sprintf("config: %s.topic is not used by sqs; the queue URL names the queue", field))
+		case q.Broker != "sqs" && q.Topic == "":
+			errs = append(errs, fmt.Sprintf("config: %s.topic is required for %s", field, q.Broker))
+		}
+		name := q.Name
+		if name == "" {
+			name = q.Broker
+		}
+		if seen[name] {
+			errs = append(errs, fmt.Sprintf("config: %s.name '%s' is used by another queue", field, name))
+		}
+		seen[name] = true
+		if q.Project != "" && cfg.FindProject(q.Project) == nil {
+			errs = append(errs, fmt.Sprintf("config: %s.project '%s' is not the source repo or a projects entry", field, q.Project))
+		}
+	}
+	return errs
+}
+
+// validEventSinks are the event sink types.
+var validEventSinks = map[string]bool{"webhook": true, "nats": true, "kafka": true}
+
+// validEventTypes are the task lifecycle event types.
+var validEventTypes = map[string]bool{
+	"task.created": true, "task.phase_changed": true, "task.proposal_pending": true,
+	"task.completed": true, "task.failed": true,
+}
+
+// validateEventSinks checks events: a known type with the URL and topic it
+// needs, the format and CloudEvents mode, and the event types filtered on.
+func validateEventSinks(sinks []EventSinkConfig) []string {
+	var errs []string
+	for i, s := range sinks {
+		field := fmt.Sprintf("events[%d]", i)
+		if !validEventSinks[s.Type] {
+			errs = append(errs, fmt.Sprintf("config: %s.type '%s' is invalid; must be one of: webhook, nats, kafka", field, s.Type))
+		}
+		if s.URL == "" {
+			errs = append(errs, fmt.Sprintf("config: %s.url is required", field))
+		}
+		if s.Type != "webhook" && s.Topic == "" {
+			errs = append(errs, fmt.Sprintf("config: %s.topic is required for %s", field, s.Type))
+		}
+		switch s.Format {
+		case "", "rig":
+			if s.Mode != "" || s.Source != "" {
+				errs = append(errs, fmt.Sprintf("config: %s.mode and source need format cloudevents", field))
+			}
+		case "cloudevents":
+			if s.Mode != "" && s.Mode != "structured" && s.Mode != "binary" {
+				errs = append(errs, fmt.Sprintf("config: %s.mode '%s' is invalid; must be one of: structured, binary", field, s.Mode))
+			}
+			if s.Mode == "binary" && s.Type == "kafka" {
+				errs = append(errs, fmt.Sprintf("config: %s.mode binary is not supported for kafka; use structured", field))
+			}
+		default:
+			errs = append(errs, fmt.Sprintf("config: %s.format '%s' is invalid; must be one of: rig, cloudevents", field, s.Format))
+		}
+		for _, typ := range s.Types {
+			if !validEventTypes[typ] {
+				errs = append(errs, fmt.Sprintf("config: %s.types: unknown event type '%s'", field, typ))
+			}
+		}
+	}
+	return errs
+}
+
+// validateArtifacts checks artifacts: a known backend with the bucket it
+// needs and no negative limits.
+func validateArtifacts(a *ArtifactsConfig) []string {
+	var errs []string
+	switch a.Backend {
+	case "", "local":
+	case "s3":
+		if a.Enabled && a.S3.Bucket == "" {
+			errs = append(errs, "config: artifacts.s3.bucket is required with backend 's3'")
+		}
+		if e := a.S3.Endpoint; e != "" && !strings.HasPrefix(e, "http://") && !strings.HasPrefix(e, "https://") {
+			errs = append(errs, fmt.Sprintf("config: artifacts.s3.endpoint '%s' must be an http or https URL", e))
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("config: artifacts.backend '%s' is invalid; must be one of: local, s3", a.Backend))
+	}
+	if a.InlineBytes < 0 || a.MaxFileBytes < 0 {
+		errs = append(errs, "config: artifacts inline_bytes and max_file_bytes must not be negative")
+	}
+	if r := a.Retention; r.MaxAge < 0 || r.MaxBytes < 0 || r.Interval < 0 {
+		errs = append(errs, "config: artifacts.retention max_age, max_bytes and interval must not be negative")
+	}
+	return errs
+}
+
+// tenantName is the form of a tenant name, which is a URL path segment
+// and a directory name.
+var tenantName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// validateTenants checks that tenants have unique, path-safe names, a
+// config file and no negative quotas.
+func validateTenants(tenants []TenantConfig) []string {
+	var errs []string
+	seen := make(map[string]bool, len(tenants))
+	for i, t := range tenants {
+		if !tenantName.MatchString(t.Name) {
+			errs = append(errs, fmt.Sprintf("config: tenants[%d].name '%s' must be lowercase letters, digits and dashes", i, t.Name))
+		} else if seen[t.Name] {
+			errs = append(errs, fmt.Sprintf("config: tenants[%d].name '%s' is used twice", i, t.Name))
+		}
+		seen[t.Name] = true
+		if t.Config == "" {
+			errs = append(errs, fmt.Sprintf("config: tenants[%d].config is required", i))
+		}
+		if t.Quota.MaxConcurrentTasks < 0 || t.Quota.AIBudgetUSD < 0 {
+			errs = append(errs, fmt.Sprintf("config: tenants[%d].quota must not be negative", i))
+		}
+	}
+	return errs
+}
+
+// validRoles are the API key and login roles.
+var validRoles = map[string]bool{"viewer": true, "operator": true, "approver": true, "admin": true}
+
+// validateAuth checks server.auth when a provider is set.
+func validateAuth(a *AuthConfig) []string {
+	var errs []string
+	switch a.Provider {
+	case "":
+		return nil
+	case "oidc":
+		if a.Issuer == "" {
+			errs = append(errs, "config: server.auth.issuer is required for the oidc provider")
+		}
+	case "github":
+	default:
+		return []string{fmt.Sprintf("config: server.auth.provider '%s' is invalid; must be one of: oidc, github", a.Provider)}
+	}
+	if u := a.Issuer; u != "" && !strings.HasPrefix(u, "https://") && !strings.HasPrefix(u, "http://") {
+		errs = append(errs, fmt.Sprintf("config: server.auth.issuer '%s' must start with https://", u))
+	}
+	if a.ClientID == "" || a.ClientSecret == "" {
+		errs = append(errs, "config: server.auth.client_id and client_secret are required")
+	}
+	if a.RedirectURL == "" {
+		errs = append(errs, "config: server.auth.redirect_url is required")
+	}
+	if a.DefaultRole != "" && !validRoles[a.DefaultRole] {
+		errs = append(errs, fmt.Sprintf("config: server.auth.default_role '%s' is invalid; must be one of: viewer, operator, approver, admin", a.DefaultRole))
+	}
+	for user, role := range a.Roles {
+		if !validRoles[role] {
+			errs = append(errs, fmt.Sprintf("config: server.auth.roles[%s] '%s' is invalid; must be one of: viewer, operator, approver, admin", user, role))
+		}
+	}
+	if a.SessionTTL < 0 {
+		errs = append(errs, "config: server.auth.session_ttl must not be negative")
+	}
+	return errs
+}
+
 // validateDeployMethod checks method-specific required fields.
 func validateDeployMethod(method string, dc *DeployMethodConfig) []string {
 	var errs []string
@@ -122,6 +677,10 @@ func validateDeployMethod(method string, dc *DeployMethodConfig) []string {
 		if dc.Manifest == "" {
 			errs = append(errs, "config: deploy.method 'k8s' requires 'manifest' field")
 		}
+	case "plugin":
+		if dc.Plugin == "" {
+			errs = append(errs, "config: deploy.method 'plugin' requires 'plugin' field")
+		}
 	}
 	return errs
 }
@@ -153,11 +712,20 @@ func validateCustomCommand(idx int, cmd *CustomCommand) []string {
 		if cmd.Transport.SSH.Key == "" && cmd.Transport.SSH.Password == "" {
 			errs = append(errs, sshPrefix+".key or "+sshPrefix+".password is required when transport type is 'ssh'")
 		}
+		if cmd.Shell != "" {
+			errs = append(errs, prefix+".shell applies to local commands; ssh commands run in the remote login shell")
+		}
+	}
+	if cmd.Shell != "" && !validShells[cmd.Shell] {
+		errs = append(errs, fmt.Sprintf("%s.shell '%s' is invalid; must be one of: sh, bash, powershell, pwsh, cmd", prefix, cmd.Shell))
 	}
 
 	return errs
 }
 
+// validShells are the shells a command, test or hook can run in.
+var validShells = map[string]bool{"sh": true, "bash": true, "powershell": true, "pwsh": true, "cmd": true}
+
 // validateRollback checks rollback configuration.
 func validateRollback(rb *RollbackConfig) []string {
 	var errs []string
@@ -173,16 +741,141 @@ func validateRollback(rb *RollbackConfig) []string {
 	return errs
 }
 
+// validateDeployStrategy checks deploy.strategy and deploy.targets.
+func validateDeployStrategy(d *DeployConfig) []string {
+	if d.Strategy == "" {
+		if len(d.Targets) > 0 {
+			return []string{"config: deploy.targets require deploy.strategy"}
+		}
+		return nil
+	}
+	var errs []string
+	if !validDeployStrategies[d.Strategy] {
+		errs = append(errs, fmt.Sprintf(
+			"config: deploy.strategy '%s' is invalid; must be one of: canary, blue-green, rolling", d.Strategy))
+	}
+	if d.Method != "custom" {
+		errs = append(errs, "config: deploy.strategy requires deploy.method 'custom'")
+	}
+	switch {
+	case d.Strategy == "blue-green" && len(d.Targets) != 2:
+		errs = append(errs, "config: deploy.strategy 'blue-green' requires exactly 2 deploy.targets (the idle color, then the switch)")
+	case len(d.Targets) < 2:
+		errs = append(errs, fmt.Sprintf("config: deploy.strategy '%s' requires at least 2 deploy.targets", d.Strategy))
+	}
+	seen := make(map[string]bool, len(d.Targets))
+	for i, t := range d.Targets {
+		if t.Name == "" {
+			errs = append(errs, fmt.Sprintf("config: deploy.targets[%d].name is required", i))
+		} else if seen[t.Name] {
+			errs = append(errs, fmt.Sprintf("config: deploy.targets[%d].name '%s' is duplicated", i, t.Name))
+		}
+		seen[t.Name] = true
+		if len(t.Commands) == 0 && len(d.Config.Commands) == 0 {
+			errs = append(errs, fmt.Sprintf("config: deploy.targets[%d] needs commands or deploy.config.commands", i))
+		}
+		for j, cmd := range t.Commands {
+			for _, e := range validateCustomCommand(j, &cmd) {
+				errs = append(errs, strings.Replace(e, "deploy.config.commands", fmt.Sprintf("deploy.targets[%d].commands", i), 1))
+			}
+		}
+	}
+	return errs
+}
+
+// validateEnvironments checks the environments section.
+func validateEnvironments(envs []EnvironmentConfig) []string {
+	var errs []string
+	seen := make(map[string]bool, len(envs))
+	defaults := 0
+	for i, env := range envs {
+		prefix := fmt.Sprintf("config: environments[%d]", i)
+		name := strings.ToLower(env.Name)
+		switch {
+		case env.Name == "":
+			errs = append(errs, prefix+".name is required")
+		case seen[name]:
+			errs = append(errs, fmt.Sprintf("%s.name '%s' is duplicated", prefix, env.Name))
+		case strings.ContainsAny(env.Name, " :"):
+			errs = append(errs, fmt.Sprintf("%s.name '%s' must not contain spaces or ':'", prefix, env.Name))
+		}
+		seen[name] = true
+		if env.Default {
+			defaults++
+		}
+		switch env.Transport.Type {
+		case "", "local":
+		case "ssh":
+			ssh := env.Transport.SSH
+			if ssh.Host == "" || ssh.User == "" || (ssh.Key == "" && ssh.Password == "") {
+				errs = append(errs, prefix+".transport.ssh requires host, user and key or password")
+			}
+		default:
+			errs = append(errs, fmt.Sprintf("%s.transport.type '%s' is invalid; must be one of: local, ssh", prefix, env.Transport.Type))
+		}
+	}
+	if defaults > 1 {
+		errs = append(errs, "config: only one environment may be the default")
+	}
+	return errs
+}
+
 // validateTest checks a single test configuration.
 func validateTest(idx int, t *TestConfig) []string {
 	var errs []string
 	prefix := fmt.Sprintf("config: test[%d]", idx)
 
+	if t.Shell != "" && !validShells[t.Shell] {
+		errs = append(errs, fmt.Sprintf("%s.shell '%s' is invalid; must be one of: sh, bash, powershell, pwsh, cmd", prefix, t.Shell))
+	}
+
 	switch t.Type {
 	case "command":
 		if t.Run == "" {
 			errs = append(errs, prefix+".run is required for type 'command'")
 		}
+		if !validTestFormats[t.Format] {
+			errs = append(errs, fmt.Sprintf("%s.format '%s' is invalid (go-json|junit)", prefix, t.Format))
+		}
+		if t.Report != "" && t.Format != "junit" {
+			errs = append(errs, prefix+".report requires format 'junit'")
+		}
+	case "http":
+		if t.URL == "" {
+			errs = append(errs, prefix+".url is required for type 'http'")
+		}
+		if t.Method != "" && !validHTTPMethods[strings.ToUpper(t.Method)] {
+			errs = append(errs, fmt.Sprintf("%s.method '%s' is invalid", prefix, t.Method))
+		}
+		if t.ExpectStatus != 0 && (t.ExpectStatus < 100 || t.ExpectStatus > 599) {
+			errs = append(errs, fmt.Sprintf("%s.expect_status must be a valid HTTP status, got %d", prefix, t.ExpectStatus))
+		}
+		if t.ExpectBody != "" {
+			if _, err := regexp.Compile(t.ExpectBody); err != nil {
+				errs = append(errs, fmt.Sprintf("%s.expect_body is not a valid regular expression: %v", prefix, err))
+			}
+		}
+		if t.Retries < 0 || t.Retries > 10 {
+			errs = append(errs, fmt.Sprintf("%s.retries must be between 0 and 10, got %d", prefix, t.Retries))
+		}
+	case "browser":
+		if t.URL == "" {
+			errs = append(errs, prefix+".url is required for type 'browser'")
+		}
+	case "coverage":
+		if t.Report == "" {
+			errs = append(errs, prefix+".report is required for type 'coverage'")
+		}
+		if t.Threshold <= 0 || t.Threshold > 100 {
+			errs = append(errs, fmt.Sprintf("%s.threshold must be between 0 and 100, got %g", prefix, t.Threshold))
+		}
+		if t.Format != "" && t.Format != "go-cover" && t.Format != "lcov" {
+			errs = append(errs, fmt.Sprintf("%s.format '%s' is invalid (go-cover|lcov)", prefix, t.Format))
+		}
+	case "plugin":
+		if t.Plugin == "" {
+			errs = append(errs, prefix+".plugin is required for type 'plugin'")
+		}
 	case "ai-verify":
 		if t.Name == "" {
 			errs = append(errs, prefix+".name is required for type 'ai-verify'")
@@ -196,3 +889,131 @@ func validateTest(idx int, t *TestConfig) []string {
 	}
 	return errs
 }
+
+// validHookPoints is the set of pipeline boundaries hooks run at.
+var validHookPoints = map[string]bool{
+	"pre_commit":  true,
+	"post_deploy": true,
+	"pre_pr":      true,
+}
+
+// validateHooks checks workflow.hooks.
+func validateHooks(hooks []HookConfig) []string {
+	var errs []string
+	for i, h := range hooks {
+		prefix := fmt.Sprintf("config: workflow.hooks[%d]", i)
+		if h.Name == "" {
+			errs = append(errs, prefix+".name is required")
+		}
+		if !validHookPoints[h.On] {
+			errs = append(errs, fmt.Sprintf("%s.on '%s' is invalid; must be one of: pre_commit, post_deploy, pre_pr", prefix, h.On))
+		}
+		switch {
+		case h.Run == "" && h.Script == "":
+			errs = append(errs, prefix+".script or run is required")
+		case h.Run != "" && h.Script != "":
+			errs = append(errs, prefix+" sets both script and run; use one")
+		}
+		if h.Timeout < 0 {
+			errs = append(errs, prefix+".timeout must not be negative")
+		}
+		if h.Shell != "" && !validShells[h.Shell] {
+			errs = append(errs, fmt.Sprintf("%s.shell '%s' is invalid; must be one of: sh, bash, powershell, pwsh, cmd", prefix, h.Shell))
+		}
+	}
+	return errs
+}
+
+// validateFormatters checks workflow.format.formatters.
+func validateFormatters(formatters []FormatterConfig) []string {
+	var errs []string
+	for i, f := range formatters {
+		prefix := fmt.Sprintf("config: workflow.format.formatters[%d]", i)
+		if f.Name == "" {
+			errs = append(errs, prefix+".name is required")
+		}
+		if f.Run == "" {
+			errs = append(errs, prefix+".run is required")
+		}
+		if len(f.Files) == 0 {
+			errs = append(errs, prefix+".files is required")
+		}
+		for _, pattern := range f.Files {
+			if _, err := path.Match(pattern, ""); err != nil {
+				errs = append(errs, fmt.Sprintf("%s.files '%s' is not a valid pattern", prefix, pattern))
+			}
+		}
+		if f.Timeout < 0 {
+			errs = append(errs, prefix+".timeout must not be negative")
+		}
+		if f.Shell != "" && !validShells[f.Shell] {
+			errs = append(errs, fmt.Sprintf("%s.shell '%s' is invalid; must be one of: sh, bash, powershell, pwsh, cmd", prefix, f.Shell))
+		}
+	}
+	return errs
+}
+
+// validateLabelRules checks workflow.labels.
+func validateLabelRules(rules []LabelRule) []string {
+	var errs []string
+	for i, r := range rules {
+		prefix := fmt.Sprintf("config: workflow.labels[%d]", i)
+		if strings.TrimSpace(r.Label) == "" {
+			errs = append(errs, prefix+".label is required")
+		}
+		for _, step := range r.SkipSteps {
+			if step != "deploy" && step != "test" {
+				errs = append(errs, fmt.Sprintf("%s.skip_steps '%s' is invalid; must be one of: deploy, test", prefix, step))
+			}
+		}
+		if len(r.SkipSteps) == 0 && r.DraftPR == nil && r.Model == "" && !strings.HasSuffix(r.Label, "=") {
+			errs = append(errs, prefix+" changes nothing; set skip_steps, draft_pr or model")
+		}
+	}
+	return errs
+}
+
+// validatePlugins checks the plugins section and that every deploy, test
+// and notify entry of type plugin names one of them.
+func validatePlugins(cfg *Config) []string {
+	var errs []string
+	seen := make(map[string]bool, len(cfg.Plugins))
+	for i, p := range cfg.Plugins {
+		if p.Name == "" {
+			errs = append(errs, fmt.Sprintf("config: plugins[%d].name is required", i))
+		} else if seen[p.Name] {
+			errs = append(errs, fmt.Sprintf("config: plugins[%d].name '%s' is duplicated", i, p.Name))
+		}
+		seen[p.Name] = true
+		if p.Command == "" {
+			errs = append(errs, fmt.Sprintf("config: plugins[%d].command is required", i))
+		}
+		if p.Timeout < 0 {
+			errs = append(errs, fmt.Sprintf("config: plugins[%d].timeout must not be negative", i))
+		}
+	}
+
+	undeclared := func(field, name string) {
+		if name != "" && !seen[name] {
+			errs = append(errs, fmt.Sprintf("config: %s '%s' is not declared in plugins", field, name))
+		}
+	}
+	if cfg.Deploy.Method == "plugin" {
+		undeclared("deploy.config.plugin", cfg.Deploy.Config.Plugin)
+	}
+	for i, t := range cfg.Test {
+		if t.Type == "plugin" {
+			undeclared(fmt.Sprintf("test[%d].plugin", i), t.Plugin)
+		}
+	}
+	for i, n := range cfg.Notify {
+		if n.Type != "plugin" {
+			continue
+		}
+		if n.Plugin == "" {
+			errs = append(errs, fmt.Sprintf("config: notify[%d].plugin is required for type 'plugin'", i))
+		}
+		undeclared(fmt.Sprintf("notify[%d].plugin", i), n.Plugin)
+	}
+	return errs
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestValidateRequiredFields(t *testing.T) {
@@ -138,6 +139,15 @@ func TestValidateDeployMethods(t *testing.T) {
 			}(),
 			wantErr: "custom",
 		},
+		{
+			name: "plugin missing plugin",
+			cfg: func() Config {
+				c := base()
+				c.Deploy = DeployConfig{Method: "plugin"}
+				return c
+			}(),
+			wantErr: "'plugin' requires 'plugin' field",
+		},
 	}
 
 	for _, tt := range tests {
@@ -403,6 +413,114 @@ func TestValidateTests(t *testing.T) {
 			}(),
 			wantErr: "tools",
 		},
+		{
+			name: "http missing url",
+			cfg: func() Config {
+				c := base()
+				c.Test = []TestConfig{{Type: "http", Name: "health"}}
+				return c
+			}(),
+			wantErr: "url is required",
+		},
+		{
+			name: "http invalid expect_body regex",
+			cfg: func() Config {
+				c := base()
+				c.Test = []TestConfig{{Type: "http", Name: "health", URL: "http://localhost", ExpectBody: "("}}
+				return c
+			}(),
+			wantErr: "expect_body",
+		},
+		{
+			name: "negative test concurrency",
+			cfg: func() Config {
+				c := base()
+				c.Workflow.TestConcurrency = -1
+				return c
+			}(),
+			wantErr: "test_concurrency",
+		},
+		{
+			name: "negative ai pricing",
+			cfg: func() Config {
+				c := base()
+				c.AI.Pricing = map[string]AIPrice{"claude-sonnet": {Input: -1}}
+				return c
+			}(),
+			wantErr: "ai.pricing.claude-sonnet",
+		},
+		{
+			name: "fix memory similarity above 1",
+			cfg: func() Config {
+				c := base()
+				c.AI.FixMemory = AIFixMemoryConfig{Enabled: true, MinSimilarity: 1.5}
+				return c
+			}(),
+			wantErr: "ai.fix_memory",
+		},
+		{
+			name: "bad context ignore pattern",
+			cfg: func() Config {
+				c := base()
+				c.AI.ContextIgnore = []string{"*.pb.go", "gen/[a-/"}
+				return c
+			}(),
+			wantErr: "ai.context_ignore[1]",
+		},
+		{
+			name: "negative decompose min steps",
+			cfg: func() Config {
+				c := base()
+				c.Workflow.Decompose = DecomposeConfig{Enabled: true, MinSteps: -1}
+				return c
+			}(),
+			wantErr: "workflow.decompose",
+		},
+		{
+			name: "negative flaky reruns",
+			cfg: func() Config {
+				c := base()
+				c.Workflow.RetryPolicy.FlakyReruns = -1
+				return c
+			}(),
+			wantErr: "workflow.retry_policy",
+		},
+		{
+			name: "max backoff below backoff",
+			cfg: func() Config {
+				c := base()
+				c.Workflow.RetryPolicy = RetryPolicyConfig{Backoff: time.Minute, MaxBackoff: time.Second}
+				return c
+			}(),
+			wantErr: "workflow.retry_policy.max_backoff",
+		},
+		{
+			name: "coverage missing threshold",
+			cfg: func() Config {
+				c := base()
+				c.Test = []TestConfig{{Type: "coverage", Name: "cov", Report: "cover.out"}}
+				return c
+			}(),
+			wantErr: "threshold",
+		},
+		{
+			name: "command unknown format",
+			cfg: func() Config {
+				c := base()
+				c.Test = []TestConfig{{Type: "command", Name: "unit", Run: "go test ./...", Format: "tap"}}
+				return c
+			}(),
+			wantErr: "format",
+		},
+		{
+			name: "command report without junit",
+			cfg: func() Config {
+				c := base()
+				c.Test = []TestConfig{{Type: "command", Name: "unit", Run: "go test ./...", Report: "report.xml"}}
+				return c
+			}(),
+			wantErr: "report requires",
+		},
 	}
 
 	for _, tt := range tests {
@@ -485,3 +603,633 @@ func TestValidateInvalidDeployMethod(t *testing.T) {
 		t.Errorf("error = %q, want it to contain 'deploy.method'", err.Error())
 	}
 }
+
+func TestValidateLog(t *testing.T) {
+	base := Config{
+		Project: ProjectConfig{Name: "test"},
+		Source:  SourceConfig{Platform: "github", Repo: "a/b"},
+		AI:      AIConfig{Provider: "openai", Model: "gpt-4"},
+		Deploy:  DeployConfig{Method: "custom", Config: DeployMethodConfig{Commands: []CustomCommand{{Name: "a", Run: "b"}}}},
+	}
+
+	cfg := base
+	cfg.Log = LogConfig{Level: "debug", Format: "json"}
+	if err := Validate(&cfg); err != nil {
+		t.Fatalf("expected valid log config, got: %v", err)
+	}
+
+	cfg.Log = LogConfig{Level: "verbose", Format: "xml"}
+	err := Validate(&cfg)
+	if err == nil || !strings.Contains(err.Error(), "log.level") || !strings.Contains(err.Error(), "log.format") {
+		t.Errorf("expected log.level and log.format errors, got: %v", err)
+	}
+
+	cfg.Log = LogConfig{Retention: LogRetentionConfig{MaxRowsPerTask: 10000, MaxAge: 720 * time.Hour, CompressAfter: 24 * time.Hour}}
+	if err := Validate(&cfg); err != nil {
+		t.Fatalf("expected valid log retention, got: %v", err)
+	}
+	cfg.Log = LogConfig{Retention: LogRetentionConfig{MaxBytes: -1, MaxAge: time.Hour, CompressAfter: 2 * time.Hour}}
+	err = Validate(&cfg)
+	if err == nil || !strings.Contains(err.Error(), "must not be negative") || !strings.Contains(err.Error(), "compress_after must be shorter") {
+		t.Errorf("expected log.retention errors, got: %v", err)
+	}
+}
+
+func TestValidateAuth(t *testing.T) {
+	base := Config{
+		Project: ProjectConfig{Name: "test"},
+		Source:  SourceConfig{Platform: "github", Repo: "a/b"},
+		AI:      AIConfig{Provider: "openai", Model: "gpt-4"},
+		Deploy:  DeployConfig{Method: "custom", Config: DeployMethodConfig{Commands: []CustomCommand{{Name: "a", Run: "b"}}}},
+	}
+
+	cfg := base
+	cfg.Server.Auth = AuthConfig{
+		Provider: "oidc", Issuer: "https://accounts.google.com", ClientID: "id", ClientSecret: "secret",
+		RedirectURL: "https://rig.example.com/auth/callback", DefaultRole: "operator",
+		Roles: map[string]string{"alice@example.com": "admin"},
+	}
+	if err := Validate(&cfg); err != nil {
+		t.Fatalf("expected valid auth config, got: %v", err)
+	}
+
+	cfg.Server.Auth = AuthConfig{Provider: "oidc", DefaultRole: "root", Roles: map[string]string{"bob": "owner"}}
+	err := Validate(&cfg)
+	for _, want := range []string{"server.auth.issuer", "client_id", "redirect_url", "default_role", "roles[bob]"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected a %s error, got: %v", want, err)
+		}
+	}
+
+	cfg.Server.Auth = AuthConfig{Provider: "saml"}
+	if err := Validate(&cfg); err == nil || !strings.Contains(err.Error(), "server.auth.provider") {
+		t.Errorf("expected a provider error, got: %v", err)
+	}
+}
+
+func TestValidateServer(t *testing.T) {
+	base := Config{
+		Project: ProjectConfig{Name: "test"},
+		Source:  SourceConfig{Platform: "github", Repo: "a/b"},
+		AI:      AIConfig{Provider: "openai", Model: "gpt-4"},
+		Deploy:  DeployConfig{Method: "custom", Config: DeployMethodConfig{Commands: []CustomCommand{{Name: "a", Run: "b"}}}},
+	}
+
+	cfg := base
+	cfg.Server = ServerConfig{
+		Host:           "127.0.0.1",
+		TLS:            ServerTLSConfig{AutocertDomains: []string{"rig.example.com"}},
+		TrustedProxies: []string{"10.0.0.0/8", "::1"},
+	}
+	if err := Validate(&cfg); err != nil {
+		t.Fatalf("expected valid server config, got: %v", err)
+	}
+
+	cfg.Server = ServerConfig{
+		Host:           "0.0.0.0:8080",
+		TLS:            ServerTLSConfig{CertFile: "cert.pem", AutocertDomains: []string{"rig.example.com"}},
+		TrustedProxies: []string{"proxy.local"},
+		DrainTimeout:   -1,
+		Port:           9090,
+		GRPC:           ServerGRPCConfig{Port: 9090},
+	}
+	err := Validate(&cfg)
+	for _, want := range []string{"server.host", "cert_file and key_file", "not both", "trusted_proxies[0]", "drain_timeout", "server.grpc.port 9090 is also server.port"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected a %s error, got: %v", want, err)
+		}
+	}
+}
+
+func TestValidateWebhooks(t *testing.T) {
+	base := Config{
+		Project:  ProjectConfig{Name: "test"},
+		Source:   SourceConfig{Platform: "github", Repo: "a/b"},
+		AI:       AIConfig{Provider: "openai", Model: "gpt-4"},
+		Deploy:   DeployConfig{Method: "custom", Config: DeployMethodConfig{Commands: []CustomCommand{{Name: "a", Run: "b"}}}},
+		Projects: []ProjectEntry{{Name: "infra", Platform: "gitlab", Repo: "ops/infra"}},
+	}
+
+	cfg := base
+	cfg.Server = ServerConfig{Secret: "s", Webhooks: []WebhookEndpointConfig{
+		{Path: "/webhook/app", Project: "a/b"},
+		{Path: "/webhook/gitlab", Platform: "gitlab", Secret: "t", Project: "infra"},
+	}}
+	if err := Validate(&cfg); err != nil {
+		t.Fatalf("expected valid webhooks, got: %v", err)
+	}
+
+	cfg.Server = ServerConfig{Webhooks: []WebhookEndpointConfig{
+		{Path: "webhook", Secret: "s"},
+		{Path: "/hook", Platform: "bitbucket", Project: "nope"},
+		{Path: "/hook", Secret: "s"},
+	}}
+	err := Validate(&cfg)
+	for _, want := range []string{"webhooks[0].path", "webhooks[1].platform", "webhooks[1].secret", "webhooks[1].project", "webhooks[2].path"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected a %s error, got: %v", want, err)
+		}
+	}
+}
+
+func TestValidateQueues(t *testing.T) {
+	cfg := Config{
+		Project:  ProjectConfig{Name: "test"},
+		Source:   SourceConfig{Platform: "github", Repo: "a/b"},
+		AI:       AIConfig{Provider: "openai", Model: "gpt-4"},
+		Deploy:   DeployConfig{Method: "custom", Config: DeployMethodConfig{Commands: []CustomCommand{{Name: "a", Run: "b"}}}},
+		Projects: []ProjectEntry{{Name: "infra", Platform: "gitlab", Repo: "ops/infra"}},
+	}
+	cfg.Server.Queues = []QueueConfig{
+		{Broker: "nats", URL: "nats://localhost:4222", Topic: "rig.issues"},
+		{Name: "ops", Broker: "kafka", URL: "http://rest-proxy:8082", Topic: "issues", Project: "infra"},
+		{Broker: "sqs", URL: "https://sqs.eu-west-1.amazonaws.com/123456789012/rig"},
+	}
+	if err := Validate(&cfg); err != nil {
+		t.Fatalf("expected valid queues, got: %v", err)
+	}
+
+	cfg.Server.Queues = []QueueConfig{
+		{Broker: "rabbitmq", URL: "amqp://localhost"},
+		{Broker: "sqs", Topic: "issues", Project: "nope"},
+		{Name: "sqs", Broker: "kafka", URL: "http://rest-proxy:8082"},
+	}
+	err := Validate(&cfg)
+	for _, want := range []string{"queues[0].broker", "queues[1].url", "queues[1].topic is not used", "queues[1].project", "queues[2].topic is required", "queues[2].name"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected a %s error, got: %v", want, err)
+		}
+	}
+}
+
+func TestValidateEventSinks(t *testing.T) {
+	cfg := Config{
+		Project: ProjectConfig{Name: "test"},
+		Source:  SourceConfig{Platform: "github", Repo: "a/b"},
+		AI:      AIConfig{Provider: "openai", Model: "gpt-4"},
+		Deploy:  DeployConfig{Method: "custom", Config: DeployMethodConfig{Commands: []CustomCommand{{Name: "a", Run: "b"}}}},
+		Events: []EventSinkConfig{
+			{Type: "webhook", URL: "https://tracker.example.com/rig", Secret: "s"},
+			{Type: "nats", URL: "nats://localhost:4222", Topic: "rig.events", Format: "cloudevents", Mode: "binary"},
+			{Type: "kafka", URL: "http://rest-proxy:8082", Topic: "rig", Format: "cloudevents", Types: []string{"task.completed", "task.failed"}},
+		},
+	}
+	if err := Validate(&cfg); err != nil {
+		t.Fatalf("expected valid event sinks, got: %v", err)
+	}
+
+	cfg.Events = []EventSinkConfig{{Type: "sns"}, {Type: "kafka", URL: "http://rest-proxy:8082"}}
+	err := Validate(&cfg)
+	for _, want := range []string{"events[0].type", "events[0].url", "events[1].topic"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected a %s error, got: %v", want, err)
+		}
+	}
+	cfg.Events = []EventSinkConfig{
+		{Type: "webhook", URL: "https://x", Format: "xml"},
+		{Type: "kafka", URL: "http://rest-proxy:8082", Topic: "rig", Format: "cloudevents", Mode: "binary"},
+		{Type: "webhook", URL: "https://x", Mode: "binary"},
+		{Type: "webhook", URL: "https://x", Types: []string{"task.merged"}},
+	}
+	err = Validate(&cfg)
+	for _, want := range []string{"events[0].format", "events[1].mode binary", "events[2].mode and source", "events[3].types"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected a %s error, got: %v", want, err)
+		}
+	}
+}
+
+func TestValidateArtifacts(t *testing.T) {
+	cfg := Config{
+		Project: ProjectConfig{Name: "test"},
+		Source:  SourceConfig{Platform: "github", Repo: "a/b"},
+		AI:      AIConfig{Provider: "openai", Model: "gpt-4"},
+		Deploy:  DeployConfig{Method: "custom", Config: DeployMethodConfig{Commands: []CustomCommand{{Name: "a", Run: "b"}}}},
+		Artifacts: ArtifactsConfig{
+			Enabled:   true,
+			Backend:   "s3",
+			S3:        ArtifactS3Config{Endpoint: "http://minio:9000", Bucket: "rig"},
+			Retention: ArtifactsRetentionConfig{MaxAge: 720 * time.Hour},
+		},
+	}
+	if err := Validate(&cfg); err != nil {
+		t.Fatalf("expected valid artifacts, got: %v", err)
+	}
+
+	cfg.Artifacts = ArtifactsConfig{Enabled: true, Backend: "s3", S3: ArtifactS3Config{Endpoint: "minio:9000"}, InlineBytes: -1}
+	err := Validate(&cfg)
+	for _, want := range []string{"artifacts.s3.bucket", "artifacts.s3.endpoint", "inline_bytes"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected a %s error, got: %v", want, err)
+		}
+	}
+	cfg.Artifacts = ArtifactsConfig{Backend: "gcs", Retention: ArtifactsRetentionConfig{MaxBytes: -1}}
+	err = Validate(&cfg)
+	for _, want := range []string{"artifacts.backend 'gcs'", "artifacts.retention"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected a %s error, got: %v", want, err)
+		}
+	}
+}
+
+func TestValidateAIModels(t *testing.T) {
+	cfg := Config{
+		Project: ProjectConfig{Name: "test"},
+		Source:  SourceConfig{Platform: "github", Repo: "a/b"},
+		AI:      AIConfig{Provider: "anthropic", Model: "claude-sonnet"},
+		Deploy:  DeployConfig{Method: "custom", Config: DeployMethodConfig{Commands: []CustomCommand{{Name: "a", Run: "b"}}}},
+	}
+	cfg.AI.Models = AIModelsConfig{
+		Analyze: "claude-haiku",
+		Tiers:   []AIModelTierConfig{{MaxInputTokens: 4000, Model: "claude-haiku", Operations: []string{"generate", "fix"}}},
+	}
+	if err := Validate(&cfg); err != nil {
+		t.Fatalf("expected valid ai.models, got: %v", err)
+	}
+
+	cfg.AI.Models.Tiers = []AIModelTierConfig{{Operations: []string{"plan"}}}
+	err := Validate(&cfg)
+	for _, want := range []string{"tiers[0].max_input_tokens", "tiers[0].model", "'plan' is invalid"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected a %s error, got: %v", want, err)
+		}
+	}
+}
+
+func TestValidateAIIndex(t *testing.T) {
+	cfg := Config{
+		Project: ProjectConfig{Name: "test"},
+		Source:  SourceConfig{Platform: "github", Repo: "a/b"},
+		AI:      AIConfig{Provider: "anthropic", Model: "claude-sonnet", Index: AIIndexConfig{Enabled: true, Provider: "ollama"}},
+		Deploy:  DeployConfig{Method: "custom", Config: DeployMethodConfig{Commands: []CustomCommand{{Name: "a", Run: "b"}}}},
+	}
+	if err := Validate(&cfg); err != nil {
+		t.Fatalf("expected valid ai.index, got: %v", err)
+	}
+
+	cfg.AI.Index = AIIndexConfig{Enabled: true, TopK: -1}
+	err := Validate(&cfg)
+	for _, want := range []string{"ai.index.provider 'anthropic' cannot compute embeddings", "ai.index.top_k"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected a %s error, got: %v", want, err)
+		}
+	}
+}
+
+func TestValidateWorkflowDedup(t *testing.T) {
+	cfg := Config{
+		Project:  ProjectConfig{Name: "test"},
+		Source:   SourceConfig{Platform: "github", Repo: "a/b"},
+		AI:       AIConfig{Provider: "openai", Model: "gpt-4o"},
+		Deploy:   DeployConfig{Method: "custom", Config: DeployMethodConfig{Commands: []CustomCommand{{Name: "a", Run: "b"}}}},
+		Workflow: WorkflowConfig{Dedup: DedupConfig{Enabled: true, Method: "embedding", Threshold: 0.9, Window: 72 * time.Hour, Action: "attach"}},
+	}
+	if err := Validate(&cfg); err != nil {
+		t.Fatalf("expected valid workflow.dedup, got: %v", err)
+	}
+
+	cfg.AI.Provider = "anthropic"
+	cfg.Workflow.Dedup.Action = "close"
+	cfg.Workflow.Dedup.Threshold = 1.5
+	err := Validate(&cfg)
+	for _, want := range []string{"workflow.dedup.method 'embedding' needs", "workflow.dedup.action 'close'", "workflow.dedup.threshold"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected a %s error, got: %v", want, err)
+		}
+	}
+}
+
+func TestValidateWorkflowLabels(t *testing.T) {
+	draft := true
+	cfg := Config{
+		Project: ProjectConfig{Name: "test"},
+		Source:  SourceConfig{Platform: "github", Repo: "a/b"},
+		AI:      AIConfig{Provider: "anthropic", Model: "claude-sonnet"},
+		Deploy:  DeployConfig{Method: "custom", Config: DeployMethodConfig{Commands: []CustomCommand{{Name: "a", Run: "b"}}}},
+		Workflow: WorkflowConfig{Labels: []LabelRule{
+			{Label: "rig:no-deploy", SkipSteps: []string{"deploy"}},
+			{Label: "rig:draft", DraftPR: &draft},
+			{Label: "rig:model="},
+		}},
+	}
+	if err := Validate(&cfg); err != nil {
+		t.Fatalf("expected valid workflow.labels, got: %v", err)
+	}
+
+	cfg.Workflow.Labels = []LabelRule{{SkipSteps: []string{"plan"}}, {Label: "rig:fast"}}
+	err := Validate(&cfg)
+	for _, want := range []string{"workflow.labels[0].label is required", "workflow.labels[0].skip_steps 'plan'", "workflow.labels[1] changes nothing"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected a %s error, got: %v", want, err)
+		}
+	}
+}
+
+func TestValidateWorkflowEpic(t *testing.T) {
+	cfg := Config{
+		Project:  ProjectConfig{Name: "test"},
+		Source:   SourceConfig{Platform: "github", Repo: "a/b"},
+		AI:       AIConfig{Provider: "anthropic", Model: "claude-sonnet"},
+		Deploy:   DeployConfig{Method: "custom", Config: DeployMethodConfig{Commands: []CustomCommand{{Name: "a", Run: "b"}}}},
+		Workflow: WorkflowConfig{Epic: EpicConfig{Enabled: true, MinItems: 3, MaxParts: 5, AI: true}},
+	}
+	if err := Validate(&cfg); err != nil {
+		t.Fatalf("expected valid workflow.epic, got: %v", err)
+	}
+
+	cfg.Workflow.Epic.MaxParts = -1
+	if err := Validate(&cfg); err == nil || !strings.Contains(err.Error(), "workflow.epic") {
+		t.Errorf("expected a workflow.epic error, got: %v", err)
+	}
+}
+
+func TestValidateSourceWorkspaces(t *testing.T) {
+	cfg := Config{
+		Project: ProjectConfig{Name: "test"},
+		Source:  SourceConfig{Platform: "github", Repo: "a/b", Workspaces: WorkspacesConfig{MaxBytes: 10 << 30, GCInterval: time.Hour}},
+		AI:      AIConfig{Provider: "anthropic", Model: "claude-sonnet"},
+		Deploy:  DeployConfig{Method: "custom", Config: DeployMethodConfig{Commands: []CustomCommand{{Name: "a", Run: "b"}}}},
+	}
+	if err := Validate(&cfg); err != nil {
+		t.Fatalf("expected valid source.workspaces, got: %v", err)
+	}
+
+	cfg.Source.Workspaces.MaxBytes = -1
+	if err := Validate(&cfg); err == nil || !strings.Contains(err.Error(), "source.workspaces") {
+		t.Errorf("expected a source.workspaces error, got: %v", err)
+	}
+}
+
+func TestValidateSourceClone(t *testing.T) {
+	cfg := Config{
+		Project: ProjectConfig{Name: "test"},
+		Source:  SourceConfig{Platform: "github", Repo: "a/b", Clone: CloneConfig{Depth: 1, Sparse: []string{"services/api", "libs/"}}},
+		AI:      AIConfig{Provider: "anthropic", Model: "claude-sonnet"},
+		Deploy:  DeployConfig{Method: "custom", Config: DeployMethodConfig{Commands: []CustomCommand{{Name: "a", Run: "b"}}}},
+	}
+	if err := Validate(&cfg); err != nil {
+		t.Fatalf("expected valid source.clone, got: %v", err)
+	}
+
+	cfg.Source.Clone = CloneConfig{Depth: -1, Sparse: []string{"/etc", "../other"}}
+	err := Validate(&cfg)
+	for _, want := range []string{"source.clone.depth", "'/etc'", "'../other'"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected a %s error, got: %v", want, err)
+		}
+	}
+}
+
+func TestValidateSourceGitAuthor(t *testing.T) {
+	cfg := Config{
+		Project: ProjectConfig{Name: "test"},
+		Source: SourceConfig{
+			Platform:  "github",
+			Repo:      "a/b",
+			GitAuthor: GitAuthorConfig{Identity: "app", AppSlug: "rig-app"},
+			Signing:   SigningConfig{Format: "ssh", Key: "~/.ssh/id_ed25519.pub"},
+		},
+		AI:     AIConfig{Provider: "anthropic", Model: "claude-sonnet"},
+		Deploy: DeployConfig{Method: "custom", Config: DeployMethodConfig{Commands: []CustomCommand{{Name: "a", Run: "b"}}}},
+	}
+	if err := Validate(&cfg); err != nil {
+		t.Fatalf("expected a valid git author, got: %v", err)
+	}
+
+	cfg.Source.GitAuthor = GitAuthorConfig{Identity: "app"}
+	cfg.Source.Signing = SigningConfig{Format: "x509"}
+	err := Validate(&cfg)
+	for _, want := range []string{"source.git_author.app_slug", "source.signing.format"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected a %s error, got: %v", want, err)
+		}
+	}
+
+	cfg.Source.GitAuthor = GitAuthorConfig{Identity: "bot"}
+	cfg.Source.Signing = SigningConfig{}
+	if err := Validate(&cfg); err == nil || !strings.Contains(err.Error(), "source.git_author.identity") {
+		t.Errorf("expected an identity error, got: %v", err)
+	}
+}
+
+func TestValidateDeployStrategy(t *testing.T) {
+	cfg := Config{
+		Project: ProjectConfig{Name: "test"},
+		Source:  SourceConfig{Platform: "github", Repo: "a/b"},
+		AI:      AIConfig{Provider: "anthropic", Model: "claude-sonnet"},
+		Deploy: DeployConfig{
+			Method:   "custom",
+			Strategy: "canary",
+			Targets: []DeployTarget{
+				{Name: "canary", Commands: []CustomCommand{{Name: "a", Run: "deploy canary"}}},
+				{Name: "fleet", Commands: []CustomCommand{{Name: "a", Run: "deploy fleet"}}},
+			},
+		},
+	}
+	if err := Validate(&cfg); err != nil {
+		t.Fatalf("expected a valid canary strategy, got: %v", err)
+	}
+
+	cfg.Deploy.Strategy = "blue-green"
+	cfg.Deploy.Targets = append(cfg.Deploy.Targets, DeployTarget{Name: "fleet", Commands: []CustomCommand{{Name: "a"}}})
+	err := Validate(&cfg)
+	for _, want := range []string{"exactly 2", "'fleet' is duplicated", "deploy.targets[2].commands[0].run"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected a %q error, got: %v", want, err)
+		}
+	}
+
+	cfg.Deploy.Strategy = "shadow"
+	cfg.Deploy.Targets = cfg.Deploy.Targets[:1]
+	cfg.Deploy.Targets[0].Commands = nil
+	err = Validate(&cfg)
+	for _, want := range []string{"deploy.strategy 'shadow'", "at least 2", "needs commands"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected a %q error, got: %v", want, err)
+		}
+	}
+}
+
+func TestValidateEnvironments(t *testing.T) {
+	cfg := Config{
+		Project: ProjectConfig{Name: "test"},
+		Source:  SourceConfig{Platform: "github", Repo: "a/b"},
+		AI:      AIConfig{Provider: "anthropic", Model: "claude-sonnet"},
+		Deploy:  DeployConfig{Method: "custom", Config: DeployMethodConfig{Commands: []CustomCommand{{Name: "a", Run: "b"}}}},
+		Environments: []EnvironmentConfig{
+			{Name: "staging", Default: true, Vars: map[string]string{"APP_URL": "https://staging"}},
+			{Name: "prod", Approval: true, Transport: TransportConfig{Type: "ssh", SSH: SSHConfig{Host: "prod-1", User: "deploy", Key: "~/.ssh/id"}}},
+		},
+	}
+	if err := Validate(&cfg); err != nil {
+		t.Fatalf("expected valid environments, got: %v", err)
+	}
+	if env := cfg.FindEnvironment("PROD"); env == nil || env.Name != "prod" {
+		t.Errorf("FindEnvironment(PROD) = %+v", env)
+	}
+
+	cfg.Environments = append(cfg.Environments,
+		EnvironmentConfig{Name: "Staging", Default: true},
+		EnvironmentConfig{Name: "deploy:qa", Transport: TransportConfig{Type: "ssh"}},
+	)
+	err := Validate(&cfg)
+	for _, want := range []string{"'Staging' is duplicated", "only one environment", "must not contain", "transport.ssh requires"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected a %q error, got: %v", want, err)
+		}
+	}
+}
+
+func TestValidatePlugins(t *testing.T) {
+	cfg := Config{
+		Project: ProjectConfig{Name: "test"},
+		Source:  SourceConfig{Platform: "github", Repo: "a/b"},
+		AI:      AIConfig{Provider: "anthropic", Model: "claude-sonnet"},
+		Deploy:  DeployConfig{Method: "plugin", Config: DeployMethodConfig{Plugin: "acme", Options: map[string]string{"app": "web"}}},
+		Test:    []TestConfig{{Type: "plugin", Name: "smoke", Plugin: "acme"}},
+		Notify:  []NotifyConfig{{Type: "plugin", Plugin: "acme", On: []string{"all"}}},
+		Plugins: []PluginConfig{{Name: "acme", Command: "/usr/local/bin/rig-acme"}},
+	}
+	if err := Validate(&cfg); err != nil {
+		t.Fatalf("expected valid plugins, got: %v", err)
+	}
+	if p := cfg.FindPlugin("acme"); p == nil || p.Command != "/usr/local/bin/rig-acme" {
+		t.Errorf("FindPlugin(acme) = %+v", p)
+	}
+
+	cfg.Plugins = append(cfg.Plugins, PluginConfig{Name: "acme", Timeout: -time.Second})
+	cfg.Deploy.Config.Plugin = "missing"
+	cfg.Test[0].Plugin = ""
+	cfg.Notify[0].Plugin = ""
+	err := Validate(&cfg)
+	for _, want := range []string{
+		"'acme' is duplicated", "plugins[1].command", "plugins[1].timeout",
+		"deploy.config.plugin 'missing' is not declared", "test[0].plugin is required", "notify[0].plugin is required",
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected a %q error, got: %v", want, err)
+		}
+	}
+}
+
+func TestValidateHooks(t *testing.T) {
+	cfg := Config{
+		Project: ProjectConfig{Name: "test"},
+		Source:  SourceConfig{Platform: "github", Repo: "a/b"},
+		AI:      AIConfig{Provider: "anthropic", Model: "claude-sonnet"},
+		Deploy:  DeployConfig{Method: "custom", Config: DeployMethodConfig{Commands: []CustomCommand{{Name: "a", Run: "b"}}}},
+	}
+	cfg.Workflow.Hooks = []HookConfig{
+		{Name: "no-secrets", On: "pre_commit", Script: "hooks/secrets.star"},
+		{Name: "owners", On: "pre_pr", Run: "python3 hooks/owners.py", Timeout: 30 * time.Second},
+	}
+	if err := Validate(&cfg); err != nil {
+		t.Fatalf("expected valid hooks, got: %v", err)
+	}
+
+	cfg.Workflow.Hooks = append(cfg.Workflow.Hooks,
+		HookConfig{On: "post_merge", Timeout: -time.Second},
+		HookConfig{Name: "both", On: "pre_pr", Script: "a.star", Run: "b"})
+	err := Validate(&cfg)
+	for _, want := range []string{"hooks[2].name", "hooks[2].on 'post_merge'", "hooks[2].script or run", "hooks[2].timeout", "hooks[3] sets both"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected a %q error, got: %v", want, err)
+		}
+	}
+}
+
+func TestValidateFormatters(t *testing.T) {
+	cfg := Config{
+		Project: ProjectConfig{Name: "test"},
+		Source:  SourceConfig{Platform: "github", Repo: "a/b"},
+		AI:      AIConfig{Provider: "anthropic", Model: "claude-sonnet"},
+		Deploy:  DeployConfig{Method: "custom", Config: DeployMethodConfig{Commands: []CustomCommand{{Name: "a", Run: "b"}}}},
+	}
+	cfg.Workflow.Format = FormatConfig{Enabled: true, Formatters: []FormatterConfig{
+		{Name: "prettier", Files: []string{"*.ts", "web/styles/*.css"}, Run: "npx prettier --write"},
+		{Name: "black", Files: []string{"*.py"}, Run: "black -q", Shell: "bash"},
+	}}
+	if err := Validate(&cfg); err != nil {
+		t.Fatalf("expected valid formatters, got: %v", err)
+	}
+
+	cfg.Workflow.Format.Formatters = append(cfg.Workflow.Format.Formatters, FormatterConfig{Files: []string{"[a-"}, Shell: "zsh"})
+	err := Validate(&cfg)
+	for _, want := range []string{"formatters[2].name", "formatters[2].run", "formatters[2].files '[a-'", "formatters[2].shell 'zsh'"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected a %q error, got: %v", want, err)
+		}
+	}
+}
+
+func TestValidateShells(t *testing.T) {
+	cfg := Config{
+		Project: ProjectConfig{Name: "test"},
+		Source:  SourceConfig{Platform: "github", Repo: "a/b"},
+		AI:      AIConfig{Provider: "anthropic", Model: "claude-sonnet"},
+		Deploy:  DeployConfig{Method: "custom", Config: DeployMethodConfig{Commands: []CustomCommand{{Name: "a", Run: "b", Shell: "pwsh"}}}},
+		Test:    []TestConfig{{Type: "command", Name: "unit", Run: "go test ./...", Shell: "cmd"}},
+	}
+	cfg.Workflow.Hooks = []HookConfig{{Name: "lint", On: "pre_commit", Run: "lint", Shell: "bash"}}
+	if err := Validate(&cfg); err != nil {
+		t.Fatalf("expected valid shells, got: %v", err)
+	}
+
+	cfg.Deploy.Config.Commands = []CustomCommand{
+		{Name: "a", Run: "b", Shell: "zsh"},
+		{Name: "c", Run: "d", Shell: "bash", Transport: TransportConfig{Type: "ssh", SSH: SSHConfig{Host: "h", User: "u", Key: "k"}}},
+	}
+	cfg.Test[0].Shell = "fish"
+	cfg.Workflow.Hooks[0].Shell = "csh"
+	err := Validate(&cfg)
+	for _, want := range []string{"commands[0].shell 'zsh'", "commands[1].shell applies to local", "test[0].shell 'fish'", "hooks[0].shell 'csh'"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected a %q error, got: %v", want, err)
+		}
+	}
+}
+
+func TestValidateTenants(t *testing.T) {
+	cfg := Config{
+		Project: ProjectConfig{Name: "test"},
+		Source:  SourceConfig{Platform: "github", Repo: "a/b"},
+		AI:      AIConfig{Provider: "anthropic", Model: "claude-sonnet"},
+		Deploy:  DeployConfig{Method: "custom", Config: DeployMethodConfig{Commands: []CustomCommand{{Name: "a", Run: "b"}}}},
+		Tenants: []TenantConfig{
+			{Name: "payments", Config: "tenants/payments.yaml", Quota: TenantQuota{MaxConcurrentTasks: 2, AIBudgetUSD: 100}},
+			{Name: "search-2", Config: "tenants/search.yaml"},
+		},
+	}
+	if err := Validate(&cfg); err != nil {
+		t.Fatalf("expected valid tenants, got: %v", err)
+	}
+
+	cfg.Tenants = append(cfg.Tenants,
+		TenantConfig{Name: "payments", Config: "other.yaml"},
+		TenantConfig{Name: "../etc"},
+		TenantConfig{Name: "ops", Config: "ops.yaml", Quota: TenantQuota{MaxConcurrentTasks: -1}},
+	)
+	err := Validate(&cfg)
+	for _, want := range []string{"'payments' is used twice", "'../etc' must be lowercase", "tenants[3].config is required", "tenants[4].quota must not be negative"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected a %q error, got: %v", want, err)
+		}
+	}
+}
+
+func TestValidatePRAssignees(t *testing.T) {
+	cfg := Config{
+		Project: ProjectConfig{Name: "test"},
+		Source:  SourceConfig{Platform: "github", Repo: "a/b"},
+		AI:      AIConfig{Provider: "anthropic", Model: "claude-sonnet"},
+		Deploy:  DeployConfig{Method: "custom", Config: DeployMethodConfig{Commands: []CustomCommand{{Name: "a", Run: "b"}}}},
+	}
+	cfg.Workflow.PR = PRConfig{Labels: []string{"rig"}, Reviewers: []string{"@acme/backend", "alice"}, Assignees: []string{"@bob"}}
+	if err := Validate(&cfg); err != nil {
+		t.Fatalf("expected valid workflow.pr, got: %v", err)
+	}
+	cfg.Workflow.PR.Assignees = append(cfg.Workflow.PR.Assignees, "@acme/backend")
+	if err := Validate(&cfg); err == nil || !strings.Contains(err.Error(), "workflow.pr.assignees[1] '@acme/backend' is a team") {
+		t.Errorf("expected a team assignee error, got: %v", err)
+	}
+}
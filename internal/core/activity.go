@@ -0,0 +1,492 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// StepName identifies a discrete pipeline step that can be driven by an
+// external workflow engine (e.g. a Temporal activity worker or Argo step).
+type StepName string
+
+const (
+	StepPlan   StepName = "plan"
+	StepCode   StepName = "code"
+	StepCommit StepName = "commit"
+	StepDeploy StepName = "deploy"
+	StepTest   StepName = "test"
+	StepReport StepName = "report"
+)
+
+// stepOrder lists the steps in pipeline order.
+var stepOrder = []StepName{StepPlan, StepCode, StepCommit, StepDeploy, StepTest, StepReport}
+
+// ErrStepNotReady is returned when a step's prerequisite steps have not succeeded yet.
+// External orchestrators should treat it as non-retryable.
+var ErrStepNotReady = errors.New("step prerequisites not met")
+
+// ErrStepFailed is returned when a step ran to completion but produced a failing
+// outcome (deploy status failed, tests failed). The record is persisted.
+var ErrStepFailed = errors.New("step failed")
+
+// StepRecord persists the input fingerprint and output of a single step so
+// that re-running the step with the same input returns the stored result.
+type StepRecord struct {
+	Step        StepName    `json:"step"`
+	Status      string      `json:"status"` // success|failed
+	InputHash   string      `json:"input_hash"`
+	Output      *StepOutput `json:"output,omitempty"`
+	Error       string      `json:"error,omitempty"`
+	Runs        int         `json:"runs"`
+	StartedAt   time.Time   `json:"started_at"`
+	CompletedAt *time.Time  `json:"completed_at,omitempty"`
+}
+
+// StepOutput is the union of all step outputs. Only the fields produced by
+// the recorded step are populated.
+type StepOutput struct {
+	Plan      *AIPlan        `json:"plan,omitempty"`
+	Changes   []AIFileChange `json:"changes,omitempty"`
+	CommitSHA string         `json:"commit_sha,omitempty"`
+	Deploy    *DeployResult  `json:"deploy,omitempty"`
+	Tests     []TestResult   `json:"tests,omitempty"`
+	Passed    bool           `json:"passed,omitempty"`
+	PR        *PullRequest   `json:"pr,omitempty"`
+}
+
+// ParseStepName validates a step name string.
+func ParseStepName(name string) (StepName, error) {
+	for _, s := range stepOrder {
+		if string(s) == strings.TrimSpace(strings.ToLower(name)) {
+			return s, nil
+		}
+	}
+	names := make([]string, len(stepOrder))
+	for i, s := range stepOrder {
+		names[i] = string(s)
+	}
+	return "", fmt.Errorf("unknown step %q: must be one of %s", name, strings.Join(names, ", "))
+}
+
+// GetStepRecord returns the persisted record for a step, or nil.
+func (t *Task) GetStepRecord(step StepName) *StepRecord {
+	for i := range t.Steps {
+		if t.Steps[i].Step == step {
+			return &t.Steps[i]
+		}
+	}
+	return nil
+}
+
+// putStepRecord replaces the record for rec.Step or appends it.
+func (t *Task) putStepRecord(rec StepRecord) {
+	for i := range t.Steps {
+		if t.Steps[i].Step == rec.Step {
+			t.Steps[i] = rec
+			return
+		}
+	}
+	t.Steps = append(t.Steps, rec)
+}
+
+// StartTask creates a queued task for the issue without running any step.
+// If the issue already has an in-flight task, that task is returned instead,
+// so retried activity invocations do not create duplicates.
+func (e *Engine) StartTask(ctx context.Context, issue Issue) (*Task, error) {
+	env, err := e.selectEnvironment(issue)
+	if err != nil {
+		return nil, err
+	}
+	var created Task
+	isNew := false
+	err = WithState(e.statePath, func(s *State) error {
+		for i := len(s.Tasks) - 1; i >= 0; i-- {
+			if s.Tasks[i].Issue.ID == issue.ID && !inactivePhases[s.Tasks[i].Status] {
+				created = s.Tasks[i]
+				return nil
+			}
+		}
+		isNew = true
+		task := s.CreateTask(issue)
+		task.Environment = env
+		task.Settings = e.labelSettings(issue)
+		task.AddPipelineStep(PhaseQueued, "running")
+		task.CompletePipelineStep(PhaseQueued, "success", "task queued", "")
+		created = *task
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	e.taskLog(created.ID, "info", fmt.Sprintf("Task started for issue #%s: %s", issue.ID, issue.Title))
+	if isNew {
+		e.logLabelSettings(&created)
+		e.publishPhase(ctx, &created, PhaseQueued)
+	}
+	return &created, nil
+}
+
+// RunStep executes a single pipeline step for an existing task and persists
+// its input fingerprint and output on the task. If the step has already
+// succeeded with the same input, the stored record is returned without
+// re-executing, which makes every step safe to retry.
+func (e *Engine) RunStep(ctx context.Context, taskID string, step StepName) (*StepRecord, error) {
+	lock, state, task, err := e.lockTask(taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release()
+	ctx = WithTaskUsage(ctx, task)
+	if strictlyTerminalPhases[task.Status] || task.Status == PhaseFailed {
+		return nil, fmt.Errorf("task %s is %s: %w", taskID, task.Status, ErrStepNotReady)
+	}
+
+	input, err := e.stepInput(task, step)
+	if err != nil {
+		return nil, err
+	}
+	hash := hashStepInput(input)
+
+	if prev := task.GetStepRecord(step); prev != nil && prev.Status == "success" && prev.InputHash == hash {
+		e.taskLog(task.ID, "info", fmt.Sprintf("Step %s already succeeded; returning recorded output", step))
+		rec := *prev
+		return &rec, nil
+	}
+
+	rec := StepRecord{Step: step, InputHash: hash, StartedAt: time.Now().UTC()}
+	if prev := task.GetStepRecord(step); prev != nil {
+		rec.Runs = prev.Runs
+	}
+	rec.Runs++
+
+	span := e.journalBegin(ctx, task, step, hash)
+	output, runErr := e.runStep(ctx, task, step)
+	span.end(runErr)
+	now := time.Now().UTC()
+	rec.CompletedAt = &now
+	rec.Output = output
+	rec.Status = "success"
+	if runErr != nil {
+		rec.Status = "failed"
+		rec.Error = runErr.Error()
+	}
+	task.putStepRecord(rec)
+
+	if err := mergeState(state, e.statePath); err != nil {
+		return nil, fmt.Errorf("save state: %w", err)
+	}
+	if runErr != nil {
+		return &rec, fmt.Errorf("step %s: %w", step, runErr)
+	}
+	return &rec, nil
+}
+
+// AbandonTask marks a task as failed on behalf of an external orchestrator
+// that has given up on it (e.g. retries exhausted).
+func (e *Engine) AbandonTask(ctx context.Context, taskID string, cause error) error {
+	state, err := loadTrackedState(e.statePath)
+	if err != nil {
+		return fmt.Errorf("load state: %w", err)
+	}
+	task := state.GetTaskByID(taskID)
+	if task == nil {
+		return fmt.Errorf("task not found: %s", taskID)
+	}
+	if inactivePhases[task.Status] && task.Status != PhaseAwaitingApproval {
+		return nil
+	}
+	e.bindWorkspace(task.ID)
+	return e.failTask(ctx, state, task, ReasonUnknown, cause)
+}
+
+// stepInput collects the persisted outputs of prerequisite steps that form
+// the input of the given step.
+func (e *Engine) stepInput(task *Task, step StepName) (any, error) {
+	output := func(s StepName) (*StepOutput, error) {
+		rec := task.GetStepRecord(s)
+		if rec == nil || rec.Status != "success" || rec.Output == nil {
+			return nil, fmt.Errorf("step %s requires %s to succeed first: %w", step, s, ErrStepNotReady)
+		}
+		return rec.Output, nil
+	}
+
+	switch step {
+	case StepPlan:
+		return struct {
+			Issue   Issue    `json:"issue"`
+			Context []string `json:"context"`
+		}{task.Issue, e.cfg.AI.Context}, nil
+	case StepCode:
+		return output(StepPlan)
+	case StepCommit:
+		code, err := output(StepCode)
+		if err != nil {
+			return nil, err
+		}
+		return struct {
+			Branch  string         `json:"branch"`
+			Changes []AIFileChange `json:"changes"`
+		}{task.Branch, code.Changes}, nil
+	case StepDeploy, StepTest:
+		commit, err := output(StepCommit)
+		if err != nil {
+			return nil, err
+		}
+		if step == StepTest && e.isStepEnabled(task, "deploy") {
+			if _, err := output(StepDeploy); err != nil {
+				return nil, err
+			}
+		}
+		return struct {
+			Step      StepName `json:"step"`
+			CommitSHA string   `json:"commit_sha"`
+		}{step, commit.CommitSHA}, nil
+	case StepReport:
+		commit, err := output(StepCommit)
+		if err != nil {
+			return nil, err
+		}
+		if e.isStepEnabled(task, "test") && e.isStepEnabled(task, "deploy") {
+			test, err := output(StepTest)
+			if err != nil {
+				return nil, err
+			}
+			if !test.Passed {
+				return nil, fmt.Errorf("step report requires passing tests: %w", ErrStepNotReady)
+			}
+		}
+		return struct {
+			Base      string `json:"base"`
+			Branch    string `json:"branch"`
+			CommitSHA string `json:"commit_sha"`
+		}{e.baseBranch(task), task.Branch, commit.CommitSHA}, nil
+	default:
+		return nil, fmt.Errorf("unknown step %q", step)
+	}
+}
+
+// runStep performs the side effects of a single step and returns its output.
+func (e *Engine) runStep(ctx context.Context, task *Task, step StepName) (*StepOutput, error) {
+	switch step {
+	case StepPlan:
+		if err := advancePhase(task, PhasePlanning); err != nil {
+			return nil, err
+		}
+		task.AddPipelineStep(PhasePlanning, "running")
+		e.notifyPhase(ctx, task, PhasePlanning)
+
+		aiIssue := e.loadIssueThread(ctx, task)
+		ai, _ := e.taskAI(task)
+		plan, err := stepAnalyze(e.withAgents(ctx, task), ai, aiIssue, strings.Join(e.cfg.AI.Context, "\n"))
+		if err != nil {
+			task.CompletePipelineStep(PhasePlanning, "failed", "", err.Error())
+			return nil, err
+		}
+		task.CompletePipelineStep(PhasePlanning, "success", plan.Summary, "")
+		e.postIssueUpdate(ctx, task, IssueUpdatePlan, planUpdate(plan))
+		return &StepOutput{Plan: plan}, nil
+
+	case StepCode:
+		plan := task.GetStepRecord(StepPlan).Output.Plan
+		if err := advancePhase(task, PhaseCoding); err != nil {
+			return nil, err
+		}
+		task.AddPipelineStep(PhaseCoding, "running")
+		e.notifyPhase(ctx, task, PhaseCoding)
+
+		owner, repo := parseRepo(e.cfg.Source.Repo)
+		if err := e.git.CloneOrPull(ctx, owner, repo, e.cfg.Source.Token); err != nil {
+			task.CompletePipelineStep(PhaseCoding, "failed", "", err.Error())
+			return nil, fmt.Errorf("clone: %w", err)
+		}
+		if err := e.checkoutBase(ctx, task); err != nil {
+			task.CompletePipelineStep(PhaseCoding, "failed", "", err.Error())
+			return nil, err
+		}
+		var repoFiles map[string]string
+		if wp, ok := e.git.(WorkspaceProvider); ok {
+			repoFiles = e.contextFiles(ctx, task.ID, wp.GetWorkspace(), planQuery(&task.Issue, plan))
+		}
+
+		attempt := newAttempt(len(task.Attempts) + 1)
+		attempt.Plan = plan.Summary
+		_, attempt.Model = e.taskAI(task)
+		changes, err := e.generate(e.withAgents(ctx, task), task, &attempt, plan, repoFiles)
+		if err == nil {
+			changes, err = e.screenChanges(task, changes)
+		}
+		if err == nil {
+			err = e.enforcePolicies(task, changes)
+		}
+		if err != nil {
+			task.CompletePipelineStep(PhaseCoding, "failed", "", err.Error())
+			completeAttempt(&attempt, "failed", ReasonAI)
+			task.Attempts = append(task.Attempts, attempt)
+			return nil, err
+		}
+		for _, c := range changes {
+			attempt.FilesChanged = append(attempt.FilesChanged, c.Path)
+		}
+		task.Attempts = append(task.Attempts, attempt)
+		task.CompletePipelineStep(PhaseCoding, "success", fmt.Sprintf("generated %d file changes", len(changes)), "")
+		return &StepOutput{Changes: changes}, nil
+
+	case StepCommit:
+		changes := task.GetStepRecord(StepCode).Output.Changes
+		plan := task.GetStepRecord(StepPlan).Output.Plan
+		if err := advancePhase(task, PhaseCommitting); err != nil {
+			return nil, err
+		}
+		task.AddPipelineStep(PhaseCommitting, "running")
+		e.notifyPhase(ctx, task, PhaseCommitting)
+
+		changes = e.formatChanges(ctx, task, e.addLicenseHeaders(task, changes))
+		if err := e.runHooks(ctx, task, &HookInput{Hook: HookPreCommit, Vars: e.stepVars(task), Changes: changes}); err != nil {
+			task.CompletePipelineStep(PhaseCommitting, "failed", "", err.Error())
+			e.failLastAttempt(task, ReasonConfig)
+			return nil, err
+		}
+		task.RecordBranch(task.Branch)
+		sha, err := stepCommit(ctx, e.git, task.Branch, e.commitGroups(task, plan, changes))
+		if err != nil {
+			task.CompletePipelineStep(PhaseCommitting, "failed", "", err.Error())
+			e.failLastAttempt(task, ReasonGit)
+			return nil, err
+		}
+		task.CompletePipelineStep(PhaseCommitting, "success", "changes committed", "")
+		e.openDraftPR(ctx, task, nil)
+		return &StepOutput{CommitSHA: sha}, nil
+
+	case StepDeploy:
+		if err := advancePhase(task, PhaseDeploying); err != nil {
+			return nil, err
+		}
+		task.AddPipelineStep(PhaseDeploying, "running")
+		e.notifyPhase(ctx, task, PhaseDeploying)
+
+		result, err := e.deployStep(ctx, task, e.stepVars(task))
+		if err != nil {
+			task.CompletePipelineStep(PhaseDeploying, "failed", "", err.Error())
+			e.failLastAttempt(task, ReasonDeploy)
+			return nil, err
+		}
+		if a := lastAttempt(task); a != nil {
+			a.Deploy = result
+			e.storeArtifacts(ctx, task, a)
+		}
+		if result.Status != "success" {
+			task.CompletePipelineStep(PhaseDeploying, "failed", result.Output, "deploy status failed")
+			e.failLastAttempt(task, ReasonDeploy)
+			return &StepOutput{Deploy: result}, fmt.Errorf("deploy status %s: %w", result.Status, ErrStepFailed)
+		}
+		task.CompletePipelineStep(PhaseDeploying, "success", result.Output, "")
+		return &StepOutput{Deploy: result}, nil
+
+	case StepTest:
+		if err := advancePhase(task, PhaseTesting); err != nil {
+			return nil, err
+		}
+		task.AddPipelineStep(PhaseTesting, "running")
+		e.notifyPhase(ctx, task, PhaseTesting)
+
+		var filesChanged []string
+		if a := lastAttempt(task); a != nil {
+			filesChanged = a.FilesChanged
+		}
+		results, passed := stepTest(ctx, e.testRunners, e.testConfigs, filesChanged, e.stepVars(task), e.testRunOptions())
+		e.logFlakyTests(task.ID, results)
+		if a := lastAttempt(task); a != nil {
+			a.Tests = results
+			e.storeArtifacts(ctx, task, a)
+			if passed {
+				completeAttempt(a, "passed", "")
+			} else {
+				completeAttempt(a, "failed", ReasonTest)
+			}
+		}
+		out := &StepOutput{Tests: results, Passed: passed}
+		if !passed {
+			task.CompletePipelineStep(PhaseTesting, "failed", collectTestOutput(results), "test failures detected")
+			return out, fmt.Errorf("test failures detected: %w", ErrStepFailed)
+		}
+		task.CompletePipelineStep(PhaseTesting, "success", "all tests passed", "")
+		return out, nil
+
+	case StepReport:
+		if a := lastAttempt(task); a != nil && a.Status == "running" {
+			completeAttempt(a, "passed", "")
+		}
+		if err := advancePhase(task, PhaseReporting); err != nil {
+			return nil, err
+		}
+		task.AddPipelineStep(PhaseReporting, "running")
+		e.notifyPhase(ctx, task, PhaseReporting)
+
+		pr, err := e.publishPR(ctx, task)
+		if err != nil {
+			task.CompletePipelineStep(PhaseReporting, "failed", "", err.Error())
+			return nil, err
+		}
+		task.PR = pr
+		task.CompletePipelineStep(PhaseReporting, "success", pr.URL, "")
+
+		task.AddPipelineStep(PhaseCompleted, "running")
+		if err := Transition(task, PhaseCompleted); err != nil {
+			task.CompletePipelineStep(PhaseCompleted, "failed", "", err.Error())
+			return &StepOutput{PR: pr}, err
+		}
+		e.notifyPhase(ctx, task, PhaseCompleted)
+		task.CompletePipelineStep(PhaseCompleted, "success", "task completed", "")
+		return &StepOutput{PR: pr}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown step %q", step)
+	}
+}
+
+// stepVars builds variables for deploy/test using the recorded commit SHA.
+func (e *Engine) stepVars(task *Task) map[string]string {
+	vars := e.buildVars(task)
+	if rec := task.GetStepRecord(StepCommit); rec != nil && rec.Output != nil {
+		vars["COMMIT_SHA"] = rec.Output.CommitSHA
+	}
+	return vars
+}
+
+// failLastAttempt marks the most recent running attempt as failed.
+func (e *Engine) failLastAttempt(task *Task, reason FailReason) {
+	if a := lastAttempt(task); a != nil && a.Status == "running" {
+		completeAttempt(a, "failed", reason)
+	}
+}
+
+func lastAttempt(task *Task) *Attempt {
+	if len(task.Attempts) == 0 {
+		return nil
+	}
+	return &task.Attempts[len(task.Attempts)-1]
+}
+
+// advancePhase transitions the task to phase unless it is already there,
+// so re-running a step does not trip the state machine.
+func advancePhase(task *Task, phase TaskPhase) error {
+	if task.Status == phase {
+		return nil
+	}
+	return Transition(task, phase)
+}
+
+func hashStepInput(input any) string {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
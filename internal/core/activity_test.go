@@ -0,0 +1,173 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRunStep_FullPipelineIsIdempotent(t *testing.T) {
+	cfg := testConfig()
+	gitMock := &mockGit{}
+	planCalls := 0
+	aiMock := &mockAI{
+		analyzeFunc: func(ctx context.Context, issue *AIIssue, projectCtx string) (*AIPlan, error) {
+			planCalls++
+			return &AIPlan{Summary: "plan", Steps: []string{"a"}}, nil
+		},
+	}
+	deployMock := &mockDeploy{deploySuccess: true}
+	runner := &mockTestRunner{}
+	statePath := tempStatePath(t)
+
+	engine := NewEngine(cfg, gitMock, aiMock, deployMock, []TestRunnerIface{runner}, nil, statePath)
+	ctx := context.Background()
+
+	task, err := engine.StartTask(ctx, testIssue())
+	if err != nil {
+		t.Fatalf("StartTask: %v", err)
+	}
+	again, err := engine.StartTask(ctx, testIssue())
+	if err != nil {
+		t.Fatalf("StartTask (retry): %v", err)
+	}
+	if again.ID != task.ID {
+		t.Fatalf("expected retried StartTask to return %s, got %s", task.ID, again.ID)
+	}
+
+	for _, step := range stepOrder {
+		rec, err := engine.RunStep(ctx, task.ID, step)
+		if err != nil {
+			t.Fatalf("RunStep(%s): %v", step, err)
+		}
+		if rec.Status != "success" {
+			t.Fatalf("RunStep(%s) status = %s", step, rec.Status)
+		}
+	}
+
+	rec, err := engine.RunStep(ctx, task.ID, StepPlan)
+	if err == nil {
+		t.Fatalf("expected error re-running step on completed task, got record %+v", rec)
+	}
+	if planCalls != 1 {
+		t.Errorf("expected AnalyzeIssue once, got %d", planCalls)
+	}
+	if gitMock.createPRCalls != 1 {
+		t.Errorf("expected one PR, got %d", gitMock.createPRCalls)
+	}
+
+	state, err := LoadState(statePath)
+	if err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	final := state.GetTaskByID(task.ID)
+	if final.Status != PhaseCompleted {
+		t.Fatalf("expected completed, got %s", final.Status)
+	}
+	if len(final.Steps) != len(stepOrder) {
+		t.Fatalf("expected %d step records, got %d", len(stepOrder), len(final.Steps))
+	}
+	if final.GetStepRecord(StepCode).Output.Changes[0].Path != "main.go" {
+		t.Errorf("code step output not persisted: %+v", final.GetStepRecord(StepCode).Output)
+	}
+}
+
+func TestRunStep_ReturnsRecordedOutputOnRetry(t *testing.T) {
+	cfg := testConfig()
+	planCalls := 0
+	aiMock := &mockAI{
+		analyzeFunc: func(ctx context.Context, issue *AIIssue, projectCtx string) (*AIPlan, error) {
+			planCalls++
+			return &AIPlan{Summary: "plan", Steps: []string{"a"}}, nil
+		},
+	}
+	statePath := tempStatePath(t)
+	engine := NewEngine(cfg, &mockGit{}, aiMock, &mockDeploy{deploySuccess: true}, nil, nil, statePath)
+	ctx := context.Background()
+
+	task, err := engine.StartTask(ctx, testIssue())
+	if err != nil {
+		t.Fatalf("StartTask: %v", err)
+	}
+	first, err := engine.RunStep(ctx, task.ID, StepPlan)
+	if err != nil {
+		t.Fatalf("RunStep: %v", err)
+	}
+	second, err := engine.RunStep(ctx, task.ID, StepPlan)
+	if err != nil {
+		t.Fatalf("RunStep (retry): %v", err)
+	}
+	if planCalls != 1 {
+		t.Errorf("expected AnalyzeIssue once, got %d", planCalls)
+	}
+	if second.Runs != 1 || second.InputHash != first.InputHash || second.Output.Plan.Summary != "plan" {
+		t.Errorf("unexpected retried record: %+v", second)
+	}
+}
+
+func TestRunStep_PrerequisitesAndFailures(t *testing.T) {
+	cfg := testConfig()
+	deployMock := &mockDeploy{deploySuccess: false}
+	runner := &mockTestRunner{}
+	statePath := tempStatePath(t)
+	engine := NewEngine(cfg, &mockGit{}, &mockAI{}, deployMock, []TestRunnerIface{runner}, nil, statePath)
+	ctx := context.Background()
+
+	task, err := engine.StartTask(ctx, testIssue())
+	if err != nil {
+		t.Fatalf("StartTask: %v", err)
+	}
+
+	if _, err := engine.RunStep(ctx, task.ID, StepCommit); !errors.Is(err, ErrStepNotReady) {
+		t.Fatalf("expected ErrStepNotReady, got %v", err)
+	}
+
+	for _, step := range []StepName{StepPlan, StepCode, StepCommit} {
+		if _, err := engine.RunStep(ctx, task.ID, step); err != nil {
+			t.Fatalf("RunStep(%s): %v", step, err)
+		}
+	}
+
+	rec, err := engine.RunStep(ctx, task.ID, StepDeploy)
+	if !errors.Is(err, ErrStepFailed) {
+		t.Fatalf("expected ErrStepFailed, got %v", err)
+	}
+	if rec == nil || rec.Status != "failed" || rec.Output.Deploy == nil {
+		t.Fatalf("expected failed deploy record, got %+v", rec)
+	}
+
+	deployMock.deploySuccess = true
+	rec, err = engine.RunStep(ctx, task.ID, StepDeploy)
+	if err != nil {
+		t.Fatalf("RunStep(deploy) retry: %v", err)
+	}
+	if rec.Runs != 2 || deployMock.deployCalls != 2 {
+		t.Errorf("expected deploy to re-run, runs=%d calls=%d", rec.Runs, deployMock.deployCalls)
+	}
+
+	runner.results = []*TestResult{{Name: "unit", Passed: false, Duration: time.Second}}
+	if _, err := engine.RunStep(ctx, task.ID, StepTest); !errors.Is(err, ErrStepFailed) {
+		t.Fatalf("expected ErrStepFailed from tests, got %v", err)
+	}
+	if _, err := engine.RunStep(ctx, task.ID, StepReport); !errors.Is(err, ErrStepNotReady) {
+		t.Fatalf("expected report to require passing tests, got %v", err)
+	}
+
+	if err := engine.AbandonTask(ctx, task.ID, errors.New("orchestrator gave up")); err == nil {
+		t.Fatal("expected AbandonTask to return the failure")
+	}
+	state, _ := LoadState(statePath)
+	if got := state.GetTaskByID(task.ID).Status; got != PhaseFailed {
+		t.Errorf("expected failed, got %s", got)
+	}
+}
+
+func TestParseStepName(t *testing.T) {
+	if s, err := ParseStepName("Deploy"); err != nil || s != StepDeploy {
+		t.Errorf("ParseStepName(Deploy) = %q, %v", s, err)
+	}
+	if _, err := ParseStepName("lint"); err == nil {
+		t.Error("expected error for unknown step")
+	}
+}
@@ -0,0 +1,82 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// AgentsFile is the file at the root of a repository whose instructions,
+// such as coding conventions, are given to the AI when planning and coding.
+const AgentsFile = "AGENTS.md"
+
+// maxAgentsBytes caps the instructions sent with every planning and coding
+// call.
+const maxAgentsBytes = 32 * 1024
+
+// AgentsStore returns the AGENTS.md content saved for a repository in the
+// dashboard, or "" when there is none. *storage.DB implements it.
+type AgentsStore interface {
+	GetAgents(projectRepo string) (string, error)
+}
+
+// SetAgentsStore sets where the AGENTS.md content saved for the task's
+// repository is read from; it wins over the AGENTS.md of the checkout.
+func (e *Engine) SetAgentsStore(s AgentsStore) {
+	e.agents = s
+}
+
+// agentsKey is the context key WithAgentsInstructions sets.
+type agentsKey struct{}
+
+// WithAgentsInstructions returns a context whose planning and code
+// generation calls put instructions ahead of their system prompt.
+func WithAgentsInstructions(ctx context.Context, instructions string) context.Context {
+	return context.WithValue(ctx, agentsKey{}, instructions)
+}
+
+// AgentsInstructions returns the instructions set by WithAgentsInstructions,
+// or "".
+func AgentsInstructions(ctx context.Context) string {
+	instructions, _ := ctx.Value(agentsKey{}).(string)
+	return instructions
+}
+
+// withAgents returns ctx carrying the AGENTS.md instructions of task's
+// repository: those saved for it in the dashboard, else the AGENTS.md of
+// the workspace checkout, if any.
+func (e *Engine) withAgents(ctx context.Context, task *Task) context.Context {
+	repo := task.Issue.Repo
+	if repo == "" {
+		repo = e.cfg.Source.Repo
+	}
+	content, source := "", ""
+	if e.agents != nil {
+		saved, err := e.agents.GetAgents(repo)
+		if err != nil {
+			e.taskLog(task.ID, "warn", fmt.Sprintf("Could not load the saved AGENTS.md: %v", err))
+		}
+		content, source = saved, "saved for "+repo
+	}
+	if strings.TrimSpace(content) == "" {
+		content, source = "", ""
+		if wp, ok := e.git.(WorkspaceProvider); ok && wp.GetWorkspace() != "" {
+			data, err := os.ReadFile(filepath.Join(wp.GetWorkspace(), AgentsFile))
+			if err == nil {
+				content, source = string(data), "of the checkout"
+			}
+		}
+	}
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return ctx
+	}
+	if len(content) > maxAgentsBytes {
+		content = content[:maxAgentsBytes]
+		e.taskLog(task.ID, "warn", fmt.Sprintf("AGENTS.md is over %d bytes; the rest is left out", maxAgentsBytes))
+	}
+	e.taskLog(task.ID, "info", fmt.Sprintf("Using the AGENTS.md %s", source))
+	return WithAgentsInstructions(ctx, content)
+}
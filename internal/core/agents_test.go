@@ -0,0 +1,53 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// agentsMap is an AgentsStore backed by a map.
+type agentsMap map[string]string
+
+func (m agentsMap) GetAgents(projectRepo string) (string, error) { return m[projectRepo], nil }
+
+func TestEngine_AgentsInstructions(t *testing.T) {
+	workspace := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workspace, AgentsFile), []byte("Use tabs.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(store AgentsStore) (planned, coded string) {
+		t.Helper()
+		aiMock := &mockAI{
+			analyzeFunc: func(ctx context.Context, issue *AIIssue, projectCtx string) (*AIPlan, error) {
+				planned = AgentsInstructions(ctx)
+				return &AIPlan{Summary: "plan", Steps: []string{"step"}}, nil
+			},
+			generateFunc: func(ctx context.Context, plan *AIPlan, repoFiles map[string]string) ([]AIFileChange, error) {
+				coded = AgentsInstructions(ctx)
+				return []AIFileChange{{Path: "main.go", Content: "package main", Action: "modify"}}, nil
+			},
+		}
+		engine := NewEngine(testConfig(), &workspaceGit{workspace: workspace}, aiMock, &mockDeploy{deploySuccess: true},
+			[]TestRunnerIface{&mockTestRunner{}}, nil, tempStatePath(t))
+		if store != nil {
+			engine.SetAgentsStore(store)
+		}
+		if err := engine.Execute(context.Background(), testIssue()); err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+		return planned, coded
+	}
+
+	if planned, coded := run(nil); planned != "Use tabs." || coded != "Use tabs." {
+		t.Errorf("expected the checkout's AGENTS.md, got %q and %q", planned, coded)
+	}
+	if planned, coded := run(agentsMap{"test/repo": "Use spaces."}); planned != "Use spaces." || coded != "Use spaces." {
+		t.Errorf("expected the saved AGENTS.md to win, got %q and %q", planned, coded)
+	}
+	if planned, _ := run(agentsMap{"other/repo": "Use spaces."}); planned != "Use tabs." {
+		t.Errorf("expected another repo's AGENTS.md to be ignored, got %q", planned)
+	}
+}
@@ -0,0 +1,231 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Kinds of the artifacts the engine stores besides the files test runners
+// report, whose kind is that of their TestArtifact.
+const (
+	ArtifactDeployOutput = "deploy-output"
+	ArtifactTestOutput   = "test-output"
+)
+
+// Defaults of artifacts.inline_bytes and artifacts.max_file_bytes.
+const (
+	defaultArtifactInlineBytes = 64 << 10
+	defaultArtifactMaxFile     = 100 << 20
+)
+
+// ErrArtifactNotFound is returned by an ArtifactStore for a key it does
+// not hold, such as one retention deleted.
+var ErrArtifactNotFound = errors.New("artifact not found")
+
+// ArtifactRef is a file of an attempt kept in the artifact store.
+type ArtifactRef struct {
+	// Name is unique within the attempt, e.g. deploy.log.
+	Name        string    `json:"name"`
+	Key         string    `json:"key"`
+	Kind        string    `json:"kind"`           // deploy-output|test-output|screenshot|console|trace|coverage|file
+	Test        string    `json:"test,omitempty"` // test the file came from
+	Size        int64     `json:"size"`
+	ContentType string    `json:"content_type,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ArtifactObject is an object an ArtifactStore holds.
+type ArtifactObject struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// ArtifactStore keeps artifact files by key, a slash-separated path such
+// as <task>/attempt-1/deploy.log: a local directory or an S3-compatible
+// bucket.
+type ArtifactStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	List(ctx context.Context) ([]ArtifactObject, error)
+}
+
+// ArtifactsDir is where the local artifact store keeps its files unless
+// artifacts.dir picks another directory.
+func ArtifactsDir(statePath string) string {
+	return filepath.Join(filepath.Dir(statePath), "artifacts")
+}
+
+// SetArtifactStore makes the engine keep the full deploy and test output
+// of each attempt and the files test runners report in store. The task
+// keeps references to them and only the end of each output.
+func (e *Engine) SetArtifactStore(store ArtifactStore) {
+	e.artifacts = store
+}
+
+// FindArtifact returns the artifact called name of attempt number, or nil.
+func (t *Task) FindArtifact(attempt int, name string) *ArtifactRef {
+	for i := range t.Attempts {
+		if t.Attempts[i].Number != attempt {
+			continue
+		}
+		for j := range t.Attempts[i].Artifacts {
+			if ref := &t.Attempts[i].Artifacts[j]; ref.Name == name {
+				return ref
+			}
+		}
+	}
+	return nil
+}
+
+// storeArtifacts stores the deploy and test outputs of attempt and the
+// files its tests reported that are not stored yet. An output is cut to
+// its end once stored; one that fails to store is kept whole.
+func (e *Engine) storeArtifacts(ctx context.Context, task *Task, attempt *Attempt) {
+	if e.artifacts == nil || e.dryRun || attempt == nil {
+		return
+	}
+	if d := attempt.Deploy; d != nil && d.OutputArtifact == "" && d.Output != "" {
+		ref := ArtifactRef{Name: "deploy.log", Kind: ArtifactDeployOutput, ContentType: "text/plain; charset=utf-8"}
+		if e.putArtifact(ctx, task, attempt, &ref, strings.NewReader(d.Output), int64(len(d.Output))) {
+			d.OutputArtifact = ref.Name
+			d.Output = e.inlineOutput(d.Output, ref.Name)
+		}
+	}
+	for i := range attempt.Tests {
+		t := &attempt.Tests[i]
+		if t.OutputArtifact == "" && t.Output != "" {
+			ref := ArtifactRef{Name: "test-" + bundleName(t.Name) + ".log", Kind: ArtifactTestOutput, Test: t.Name, ContentType: "text/plain; charset=utf-8"}
+			if e.putArtifact(ctx, task, attempt, &ref, strings.NewReader(t.Output), int64(len(t.Output))) {
+				t.OutputArtifact = ref.Name
+				t.Output = e.inlineOutput(t.Output, ref.Name)
+			}
+		}
+		for j := range t.Artifacts {
+			if a := &t.Artifacts[j]; a.Artifact == "" && a.Path != "" {
+				e.storeTestFile(ctx, task, attempt, t.Name, a)
+			}
+		}
+	}
+}
+
+// storeTestFile stores the file a test reported.
+func (e *Engine) storeTestFile(ctx context.Context, task *Task, attempt *Attempt, test string, a *TestArtifact) {
+	f, err := os.Open(a.Path)
+	if err != nil {
+		e.taskLog(task.ID, "warn", fmt.Sprintf("Could not store artifact %s: %v", a.Path, err))
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		return
+	}
+	maxBytes := e.cfg.Artifacts.MaxFileBytes
+	if maxBytes == 0 {
+		maxBytes = defaultArtifactMaxFile
+	}
+	if info.Size() > maxBytes {
+		e.taskLog(task.ID, "warn", fmt.Sprintf("Artifact %s is larger than %d bytes; not stored", a.Path, maxBytes))
+		return
+	}
+	contentType := mime.TypeByExtension(path.Ext(a.Path))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	ref := ArtifactRef{Name: "test-" + bundleName(test) + "-" + bundleName(path.Base(filepath.ToSlash(a.Path))), Kind: a.Kind, Test: test, ContentType: contentType}
+	if e.putArtifact(ctx, task, attempt, &ref, f, info.Size()) {
+		a.Artifact = ref.Name
+	}
+}
+
+// putArtifact stores r as ref in attempt, renaming ref when the attempt
+// already holds an artifact of its name, and reports whether it was
+// stored.
+func (e *Engine) putArtifact(ctx context.Context, task *Task, attempt *Attempt, ref *ArtifactRef, r io.Reader, size int64) bool {
+	ext := path.Ext(ref.Name)
+	base := strings.TrimSuffix(ref.Name, ext)
+	for n := 2; attemptHasArtifact(attempt, ref.Name); n++ {
+		ref.Name = base + "-" + strconv.Itoa(n) + ext
+	}
+	ref.Key = fmt.Sprintf("%s/attempt-%d/%s", task.ID, attempt.Number, ref.Name)
+	ref.Size = size
+	ref.CreatedAt = time.Now().UTC()
+	if err := e.artifacts.Put(ctx, ref.Key, r, size, ref.ContentType); err != nil {
+		e.taskLog(task.ID, "warn", fmt.Sprintf("Could not store artifact %s: %v", ref.Name, err))
+		return false
+	}
+	attempt.Artifacts = append(attempt.Artifacts, *ref)
+	return true
+}
+
+func attemptHasArtifact(attempt *Attempt, name string) bool {
+	for _, ref := range attempt.Artifacts {
+		if ref.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// inlineOutput returns the end of an output stored as the artifact name,
+// within artifacts.inline_bytes.
+func (e *Engine) inlineOutput(output, name string) string {
+	limit := e.cfg.Artifacts.InlineBytes
+	if limit == 0 {
+		limit = defaultArtifactInlineBytes
+	}
+	if len(output) <= limit {
+		return output
+	}
+	start := len(output) - limit
+	for start < len(output) && !utf8.RuneStart(output[start]) {
+		start++
+	}
+	return fmt.Sprintf("...(truncated; full output in artifact %s)\n", name) + output[start:]
+}
+
+// SweepArtifacts applies the retention limits to store: it deletes the
+// artifacts stored before maxAge ago, then the oldest ones while all that
+// are left take more than maxBytes. 0 disables a limit. It returns how many
+// artifacts it deleted.
+func SweepArtifacts(ctx context.Context, store ArtifactStore, maxAge time.Duration, maxBytes int64) (int, error) {
+	if maxAge <= 0 && maxBytes <= 0 {
+		return 0, nil
+	}
+	objects, err := store.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].ModTime.Before(objects[j].ModTime) })
+	var total int64
+	for _, o := range objects {
+		total += o.Size
+	}
+	cutoff := time.Now().Add(-maxAge)
+	deleted := 0
+	for _, o := range objects {
+		expired := maxAge > 0 && o.ModTime.Before(cutoff)
+		if !expired && (maxBytes <= 0 || total <= maxBytes) {
+			break
+		}
+		if err := store.Delete(ctx, o.Key); err != nil {
+			return deleted, fmt.Errorf("delete artifact %s: %w", o.Key, err)
+		}
+		total -= o.Size
+		deleted++
+	}
+	return deleted, nil
+}
@@ -0,0 +1,134 @@
+package core
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memArtifacts is an in-memory ArtifactStore.
+type memArtifacts struct {
+	mu      sync.Mutex
+	objects map[string]string
+	times   map[string]time.Time
+}
+
+func newMemArtifacts() *memArtifacts {
+	return &memArtifacts{objects: map[string]string{}, times: map[string]time.Time{}}
+}
+
+func (m *memArtifacts) Put(_ context.Context, key string, r io.Reader, _ int64, _ string) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[key] = string(data)
+	m.times[key] = time.Now()
+	return nil
+}
+
+func (m *memArtifacts) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.objects[key]
+	if !ok {
+		return nil, ErrArtifactNotFound
+	}
+	return io.NopCloser(strings.NewReader(data)), nil
+}
+
+func (m *memArtifacts) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.objects, key)
+	delete(m.times, key)
+	return nil
+}
+
+func (m *memArtifacts) List(context.Context) ([]ArtifactObject, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var objects []ArtifactObject
+	for k, v := range m.objects {
+		objects = append(objects, ArtifactObject{Key: k, Size: int64(len(v)), ModTime: m.times[k]})
+	}
+	return objects, nil
+}
+
+func TestExecute_StoresArtifacts(t *testing.T) {
+	cfg := testConfig()
+	cfg.Artifacts.InlineBytes = 8
+	statePath := tempStatePath(t)
+	shot := filepath.Join(t.TempDir(), "shot.png")
+	if err := os.WriteFile(shot, []byte("\x89PNG"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runner := &mockTestRunner{results: []*TestResult{{
+		Name: "e2e", Type: "browser", Passed: true, Output: "line 1\nline 2\nline 3",
+		Artifacts: []TestArtifact{{Kind: "screenshot", Path: shot}, {Kind: "file", Path: filepath.Join(t.TempDir(), "gone.txt")}},
+	}}}
+	store := newMemArtifacts()
+
+	engine := NewEngine(cfg, &mockGit{}, &mockAI{}, &mockDeploy{deploySuccess: true}, []TestRunnerIface{runner}, nil, statePath)
+	engine.SetArtifactStore(store)
+	if err := engine.Execute(context.Background(), testIssue()); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+
+	state, _ := LoadState(statePath)
+	task := state.GetTask("42")
+	attempt := task.Attempts[0]
+	var names []string
+	for _, ref := range attempt.Artifacts {
+		names = append(names, ref.Name)
+		if ref.Key != task.ID+"/attempt-1/"+ref.Name {
+			t.Errorf("unexpected key %s", ref.Key)
+		}
+	}
+	if got := strings.Join(names, ","); got != "deploy.log,test-e2e.log,test-e2e-shot.png" {
+		t.Fatalf("artifacts = %s", got)
+	}
+	if store.objects[task.ID+"/attempt-1/deploy.log"] != "deploy output" || store.objects[task.ID+"/attempt-1/test-e2e.log"] != "line 1\nline 2\nline 3" {
+		t.Errorf("stored outputs = %v", store.objects)
+	}
+
+	if attempt.Deploy.OutputArtifact != "deploy.log" || !strings.HasSuffix(attempt.Deploy.Output, "y output") || !strings.Contains(attempt.Deploy.Output, "full output in artifact deploy.log") {
+		t.Errorf("deploy = %+v", attempt.Deploy)
+	}
+	test := attempt.Tests[0]
+	if test.OutputArtifact != "test-e2e.log" || !strings.HasSuffix(test.Output, "\nline 3") {
+		t.Errorf("test output = %q (%s)", test.Output, test.OutputArtifact)
+	}
+	if test.Artifacts[0].Artifact != "test-e2e-shot.png" || test.Artifacts[1].Artifact != "" {
+		t.Errorf("test artifacts = %+v", test.Artifacts)
+	}
+	if ref := task.FindArtifact(1, "test-e2e-shot.png"); ref == nil || ref.Kind != "screenshot" || ref.Test != "e2e" || ref.ContentType != "image/png" || ref.Size != 4 {
+		t.Errorf("FindArtifact = %+v", ref)
+	}
+}
+
+func TestSweepArtifacts(t *testing.T) {
+	store := newMemArtifacts()
+	now := time.Now()
+	for key, age := range map[string]time.Duration{"old": 48 * time.Hour, "mid": 2 * time.Hour, "new": time.Minute} {
+		store.objects[key] = strings.Repeat("x", 10)
+		store.times[key] = now.Add(-age)
+	}
+
+	if n, err := SweepArtifacts(context.Background(), store, 0, 0); err != nil || n != 0 {
+		t.Fatalf("sweep without limits deleted %d: %v", n, err)
+	}
+	if n, err := SweepArtifacts(context.Background(), store, 24*time.Hour, 0); err != nil || n != 1 || store.objects["old"] != "" {
+		t.Fatalf("max_age sweep deleted %d (%v), left %v", n, err, store.objects)
+	}
+	if n, err := SweepArtifacts(context.Background(), store, 0, 15); err != nil || n != 1 || store.objects["new"] == "" {
+		t.Fatalf("max_bytes sweep deleted %d (%v), left %v", n, err, store.objects)
+	}
+}
@@ -0,0 +1,162 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultAutoMergeTimeout      = time.Hour
+	defaultAutoMergePollInterval = 30 * time.Second
+)
+
+// MergeReadiness is the platform's verdict on whether a PR can be merged.
+type MergeReadiness string
+
+const (
+	MergeReady   MergeReadiness = "ready"   // required checks and reviews passed
+	MergePending MergeReadiness = "pending" // checks running or reviews missing
+	MergeBlocked MergeReadiness = "blocked" // checks failed or conflicts; waiting will not help
+)
+
+// PRMergeStatus describes a pull request as seen by the auto-merger.
+type PRMergeStatus struct {
+	Merged    bool
+	Closed    bool
+	Readiness MergeReadiness
+	Reason    string
+}
+
+// PRMerger is implemented by GitAdapters that can report merge readiness
+// and merge pull requests. Used by workflow.auto_merge.
+type PRMerger interface {
+	PRMergeStatus(ctx context.Context, number int) (*PRMergeStatus, error)
+	MergePR(ctx context.Context, number int, method, commitTitle string) error
+}
+
+// IssueCloser is implemented by GitAdapters that can close an issue with a
+// final comment.
+type IssueCloser interface {
+	CloseIssue(ctx context.Context, number int, comment string) error
+}
+
+// autoMerge waits for the task PR's required checks and reviews, then merges
+// it. It never fails the task: the PR stays open for a human if merging is
+// not possible, and the outcome is logged and notified.
+func (e *Engine) autoMerge(ctx context.Context, task *Task) {
+	cfg := e.cfg.Workflow.AutoMerge
+	merger, ok := e.git.(PRMerger)
+	if !cfg.Enabled || !ok || task.PR == nil {
+		return
+	}
+	number, err := strconv.Atoi(task.PR.ID)
+	if err != nil {
+		return
+	}
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = defaultAutoMergeTimeout
+	}
+	interval := cfg.PollInterval
+	if interval == 0 {
+		interval = defaultAutoMergePollInterval
+	}
+	method := cfg.Method
+	if method == "" {
+		method = "merge"
+	}
+
+	e.taskLog(task.ID, "info", fmt.Sprintf("Auto-merge: waiting for required checks on PR #%d", number))
+	deadline := time.Now().Add(timeout)
+	for {
+		status, err := merger.PRMergeStatus(ctx, number)
+		if err != nil {
+			e.taskLog(task.ID, "warn", fmt.Sprintf("Auto-merge: %v", err))
+		} else {
+			switch {
+			case status.Merged:
+				e.taskLog(task.ID, "info", fmt.Sprintf("Auto-merge: PR #%d was already merged", number))
+				task.PR.Merged = true
+				return
+			case status.Closed:
+				e.taskLog(task.ID, "info", fmt.Sprintf("Auto-merge: PR #%d was closed; not merging", number))
+				return
+			case status.Readiness == MergeBlocked:
+				e.taskLog(task.ID, "warn", fmt.Sprintf("Auto-merge: PR #%d cannot be merged: %s", number, status.Reason))
+				e.notifyMessage(ctx, fmt.Sprintf("Auto-merge skipped for %s: %s", task.PR.URL, status.Reason))
+				return
+			case status.Readiness == MergeReady:
+				e.mergePR(ctx, task, merger, number, method)
+				return
+			}
+		}
+
+		if time.Now().After(deadline) {
+			e.taskLog(task.ID, "warn", fmt.Sprintf("Auto-merge: gave up on PR #%d after %s", number, timeout))
+			e.notifyMessage(ctx, fmt.Sprintf("Auto-merge timed out for %s", task.PR.URL))
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// mergePR merges a ready PR, then deletes the branch and closes the issue
+// if configured.
+func (e *Engine) mergePR(ctx context.Context, task *Task, merger PRMerger, number int, method string) {
+	cfg := e.cfg.Workflow.AutoMerge
+	title := fmt.Sprintf("rig: %s (#%d)", task.Issue.Title, number)
+	if err := merger.MergePR(ctx, number, method, title); err != nil {
+		e.taskLog(task.ID, "warn", fmt.Sprintf("Auto-merge: merge PR #%d: %v", number, err))
+		e.notifyMessage(ctx, fmt.Sprintf("Auto-merge failed for %s: %v", task.PR.URL, err))
+		return
+	}
+	now := time.Now().UTC()
+	task.PR.Merged = true
+	task.PR.MergedAt = &now
+	e.taskLog(task.ID, "info", fmt.Sprintf("Auto-merge: merged PR #%d (%s)", number, method))
+	e.notifyMessage(ctx, fmt.Sprintf("Merged %s", task.PR.URL))
+
+	if cfg.DeleteBranch {
+		e.git.CleanupBranch(ctx, task.Branch)
+	}
+	if cfg.CloseIssue {
+		closer, ok := e.git.(IssueCloser)
+		issueNumber, err := strconv.Atoi(task.Issue.ID)
+		if !ok || err != nil {
+			return
+		}
+		if err := closer.CloseIssue(ctx, issueNumber, mergeSummary(task, method)); err != nil {
+			e.taskLog(task.ID, "warn", fmt.Sprintf("Auto-merge: close issue #%d: %v", issueNumber, err))
+		}
+		// Issues workflow.dedup attached live in the source repository too.
+		for _, d := range task.Duplicates {
+			number, err := strconv.Atoi(d.Issue.ID)
+			if !d.Attached || err != nil || !strings.EqualFold(e.issueRepo(d.Issue), e.issueRepo(task.Issue)) {
+				continue
+			}
+			if err := closer.CloseIssue(ctx, number, mergeSummary(task, method)); err != nil {
+				e.taskLog(task.ID, "warn", fmt.Sprintf("Auto-merge: close issue #%d: %v", number, err))
+			}
+		}
+	}
+}
+
+// mergeSummary is the closing comment left on the issue.
+func mergeSummary(task *Task, method string) string {
+	msg := fmt.Sprintf("Resolved by %s (%s-merged by rig).", task.PR.URL, method)
+	if a := lastAttempt(task); a != nil {
+		msg += fmt.Sprintf("\n\n- Attempts: %d\n- Files changed: %d", len(task.Attempts), len(a.FilesChanged))
+		if a.Plan != "" {
+			msg += "\n- Plan: " + a.Plan
+		}
+	}
+	return msg
+}
@@ -0,0 +1,99 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mergeGit is a mockGit that reports a sequence of merge statuses.
+type mergeGit struct {
+	mockGit
+	statuses []*PRMergeStatus
+	polls    int
+	merged   []string
+	closed   []string
+}
+
+func (m *mergeGit) PRMergeStatus(ctx context.Context, number int) (*PRMergeStatus, error) {
+	s := m.statuses[min(m.polls, len(m.statuses)-1)]
+	m.polls++
+	return s, nil
+}
+
+func (m *mergeGit) MergePR(ctx context.Context, number int, method, commitTitle string) error {
+	m.merged = append(m.merged, method)
+	return nil
+}
+
+func (m *mergeGit) CloseIssue(ctx context.Context, number int, comment string) error {
+	m.closed = append(m.closed, comment)
+	return nil
+}
+
+func TestEngine_AutoMergeWaitsForChecks(t *testing.T) {
+	cfg := testConfig()
+	cfg.Workflow.AutoMerge.Enabled = true
+	cfg.Workflow.AutoMerge.Method = "squash"
+	cfg.Workflow.AutoMerge.PollInterval = time.Millisecond
+	cfg.Workflow.AutoMerge.DeleteBranch = true
+	cfg.Workflow.AutoMerge.CloseIssue = true
+	gitMock := &mergeGit{statuses: []*PRMergeStatus{
+		{Readiness: MergePending, Reason: "checks running"},
+		{Readiness: MergeReady},
+	}}
+	statePath := tempStatePath(t)
+	engine := NewEngine(cfg, gitMock, &mockAI{}, &mockDeploy{deploySuccess: true},
+		[]TestRunnerIface{&mockTestRunner{}}, nil, statePath)
+
+	if err := engine.Execute(context.Background(), testIssue()); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+
+	if gitMock.polls != 2 || len(gitMock.merged) != 1 || gitMock.merged[0] != "squash" {
+		t.Fatalf("expected one squash merge after 2 polls, got %d polls, merges %v", gitMock.polls, gitMock.merged)
+	}
+	if len(gitMock.cleanedBranches) != 1 || gitMock.cleanedBranches[0] != "rig/issue-42" {
+		t.Errorf("expected branch deletion, got %v", gitMock.cleanedBranches)
+	}
+	if len(gitMock.closed) != 1 || !strings.Contains(gitMock.closed[0], "pull/1") {
+		t.Errorf("expected closing comment with PR link, got %v", gitMock.closed)
+	}
+
+	state, err := LoadState(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr := state.Tasks[0].PR; pr == nil || !pr.Merged || pr.MergedAt == nil {
+		t.Fatalf("expected merged PR recorded, got %+v", pr)
+	}
+}
+
+func TestEngine_AutoMergeStopsWhenBlocked(t *testing.T) {
+	cfg := testConfig()
+	cfg.Workflow.AutoMerge.Enabled = true
+	cfg.Workflow.AutoMerge.PollInterval = time.Millisecond
+	gitMock := &mergeGit{statuses: []*PRMergeStatus{
+		{Readiness: MergeBlocked, Reason: `check run "ci" failure`},
+	}}
+	notifier := &mockNotifier{}
+	engine := NewEngine(cfg, gitMock, &mockAI{}, &mockDeploy{deploySuccess: true},
+		[]TestRunnerIface{&mockTestRunner{}}, []NotifierIface{notifier}, tempStatePath(t))
+
+	if err := engine.Execute(context.Background(), testIssue()); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if len(gitMock.merged) != 0 {
+		t.Fatalf("blocked PR must not be merged, got %v", gitMock.merged)
+	}
+	found := false
+	for _, m := range notifier.messages {
+		if strings.Contains(m, "Auto-merge skipped") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected a notification about the skipped merge, got %v", notifier.messages)
+	}
+}
@@ -0,0 +1,74 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// BranchPrefix is the prefix of every branch rig creates.
+const BranchPrefix = "rig/"
+
+// BranchLister is an optional GitAdapter capability used by the orphan branch
+// sweeper to enumerate remote branches.
+type BranchLister interface {
+	ListBranches(ctx context.Context, prefix string) ([]string, error)
+}
+
+// SweepOrphanBranches deletes remote rig/ branches that no live task needs.
+// A branch is kept while any task referencing it is in flight, completed, or
+// has a pull request; branches of failed tasks and branches no task knows
+// about are deleted. Branches matching a protected glob are never touched.
+// It returns the deleted branch names.
+func SweepOrphanBranches(ctx context.Context, git GitAdapter, state *State, protected []string) ([]string, error) {
+	lister, ok := git.(BranchLister)
+	if !ok {
+		return nil, nil
+	}
+	remote, err := lister.ListBranches(ctx, BranchPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("list branches: %w", err)
+	}
+
+	needed := make(map[string]bool)
+	for _, task := range state.Tasks {
+		if task.PR == nil && (task.Status == PhaseFailed || task.Status == PhaseRollback) {
+			continue
+		}
+		needed[task.Branch] = true
+		for _, b := range task.Branches {
+			needed[b] = true
+		}
+	}
+
+	var deleted []string
+	for _, branch := range remote {
+		if !strings.HasPrefix(branch, BranchPrefix) || needed[branch] || branchProtected(branch, protected) {
+			continue
+		}
+		git.CleanupBranch(ctx, branch)
+		deleted = append(deleted, branch)
+	}
+	return deleted, nil
+}
+
+// branchProtected reports whether branch matches any of the glob patterns.
+func branchProtected(branch string, patterns []string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, branch); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// SweepBranches runs SweepOrphanBranches against the engine's state with the
+// configured protection list.
+func (e *Engine) SweepBranches(ctx context.Context) ([]string, error) {
+	state, err := LoadState(e.statePath)
+	if err != nil {
+		return nil, fmt.Errorf("load state: %w", err)
+	}
+	return SweepOrphanBranches(ctx, e.git, state, e.cfg.Workflow.BranchSweep.Protected)
+}
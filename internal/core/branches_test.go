@@ -0,0 +1,51 @@
+package core
+
+import (
+	"context"
+	"testing"
+)
+
+// listingGit is a mockGit that also lists remote branches.
+type listingGit struct {
+	mockGit
+	remote []string
+}
+
+func (m *listingGit) ListBranches(ctx context.Context, prefix string) ([]string, error) {
+	return m.remote, nil
+}
+
+func TestSweepOrphanBranches(t *testing.T) {
+	gitMock := &listingGit{remote: []string{
+		"rig/issue-1", // in flight
+		"rig/issue-2", // failed, no PR
+		"rig/issue-3", // completed with PR
+		"rig/issue-4", // unknown to state
+		"rig/keep-me", // protected
+		"main",
+	}}
+	state := &State{Tasks: []Task{
+		{ID: "t1", Branch: "rig/issue-1", Status: PhaseTesting},
+		{ID: "t2", Branch: "rig/issue-2", Branches: []string{"rig/issue-2"}, Status: PhaseFailed},
+		{ID: "t3", Branch: "rig/issue-3", Status: PhaseCompleted, PR: &PullRequest{ID: "3"}},
+	}}
+
+	deleted, err := SweepOrphanBranches(context.Background(), gitMock, state, []string{"rig/keep-*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"rig/issue-2", "rig/issue-4"}
+	if len(deleted) != len(want) || deleted[0] != want[0] || deleted[1] != want[1] {
+		t.Fatalf("deleted = %v, want %v", deleted, want)
+	}
+	if len(gitMock.cleanedBranches) != 2 {
+		t.Errorf("expected 2 CleanupBranch calls, got %v", gitMock.cleanedBranches)
+	}
+}
+
+func TestSweepOrphanBranchesWithoutLister(t *testing.T) {
+	deleted, err := SweepOrphanBranches(context.Background(), &mockGit{}, &State{}, nil)
+	if err != nil || deleted != nil {
+		t.Fatalf("expected no-op, got %v, %v", deleted, err)
+	}
+}
@@ -0,0 +1,304 @@
+package core
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// maxBundleLogs is how many recent log lines per task the engine keeps
+	// for the failure bundle.
+	maxBundleLogs = 500
+	// maxBundleArtifactBytes skips test artifact files larger than this.
+	maxBundleArtifactBytes = 5 << 20
+)
+
+// BundleLog is one task log line in a diagnostic bundle.
+type BundleLog struct {
+	Time    time.Time
+	Level   string
+	Message string
+}
+
+// redactedKeys are config keys whose values never leave the rig host.
+var redactedKeys = map[string]bool{
+	"token":          true,
+	"api_key":        true,
+	"secret":         true,
+	"password":       true,
+	"key":            true,
+	"webhook":        true,
+	"client_secret":  true,
+	"session_secret": true,
+	"access_key":     true,
+	"secret_key":     true,
+}
+
+// redactedMaps are config keys whose map values are all redacted, since
+// env vars and headers routinely carry credentials.
+var redactedMaps = map[string]bool{
+	"env":     true,
+	"headers": true,
+	"vars":    true,
+}
+
+// tokenPattern matches credentials that leak into free-form values.
+var tokenPattern = regexp.MustCompile(`\b(sk-ant-|sk-|ghp_|gho_|ghs_|github_pat_)[A-Za-z0-9_\-]+`)
+
+// BundlePath is where the engine stores the failure bundle of a task, next
+// to the state file.
+func BundlePath(statePath, taskID string) string {
+	return filepath.Join(filepath.Dir(statePath), "bundles", taskID+".zip")
+}
+
+// RedactedConfig renders cfg as YAML with credentials replaced.
+func RedactedConfig(cfg *config.Config) ([]byte, error) {
+	var doc yaml.Node
+	if err := doc.Encode(cfg); err != nil {
+		return nil, err
+	}
+	redactNode(&doc, false)
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), enc.Close()
+}
+
+// redactNode replaces sensitive scalar values below n. all redacts every
+// non-empty scalar.
+func redactNode(n *yaml.Node, all bool) {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		if n.Value == "" {
+			return
+		}
+		if all {
+			n.Value, n.Tag, n.Style = "[REDACTED]", "!!str", 0
+			return
+		}
+		n.Value = tokenPattern.ReplaceAllString(n.Value, "[REDACTED]")
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i].Value
+			redactNode(n.Content[i+1], all || redactedKeys[key] || redactedMaps[key])
+		}
+	default:
+		for _, c := range n.Content {
+			redactNode(c, all)
+		}
+	}
+}
+
+// WriteBundle writes a zip diagnostic bundle for task to w:
+//
+//	config.yaml     configuration with credentials redacted
+//	task.json       the task as stored in state
+//	timeline.txt    pipeline steps with durations and errors
+//	logs.txt        the task's recent log lines
+//	failures/       failed deploy and test output per attempt, and the
+//	                screenshots and logs browser tests captured
+func WriteBundle(w io.Writer, cfg *config.Config, task *Task, logs []BundleLog) error {
+	zw := zip.NewWriter(w)
+	add := func(name string, data []byte) error {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(data)
+		return err
+	}
+
+	if cfg != nil {
+		data, err := RedactedConfig(cfg)
+		if err != nil {
+			return fmt.Errorf("render config: %w", err)
+		}
+		if err := add("config.yaml", data); err != nil {
+			return err
+		}
+	}
+	data, err := json.MarshalIndent(task, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode task: %w", err)
+	}
+	if err := add("task.json", data); err != nil {
+		return err
+	}
+	if err := add("timeline.txt", []byte(bundleTimeline(task))); err != nil {
+		return err
+	}
+	var lb strings.Builder
+	for _, l := range logs {
+		fmt.Fprintf(&lb, "%s [%s] %s\n", l.Time.UTC().Format(time.RFC3339), l.Level, l.Message)
+	}
+	if err := add("logs.txt", []byte(lb.String())); err != nil {
+		return err
+	}
+
+	for _, a := range task.Attempts {
+		dir := fmt.Sprintf("failures/attempt-%d/", a.Number)
+		if a.Deploy != nil && a.Deploy.Status == "failed" {
+			if err := add(dir+"deploy.txt", []byte(a.Deploy.Output)); err != nil {
+				return err
+			}
+		}
+		for _, t := range a.Tests {
+			if t.Passed {
+				continue
+			}
+			if err := add(dir+"test-"+bundleName(t.Name)+".txt", []byte(bundleTestOutput(t))); err != nil {
+				return err
+			}
+			for _, art := range t.Artifacts {
+				content := []byte(art.Content)
+				if info, err := os.Stat(art.Path); err == nil && info.Size() <= maxBundleArtifactBytes {
+					if data, err := os.ReadFile(art.Path); err == nil {
+						content = data
+					}
+				}
+				if len(content) == 0 {
+					continue
+				}
+				if err := add(dir+"artifacts/"+bundleName(t.Name)+"/"+path.Base(art.Path), content); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return zw.Close()
+}
+
+// bundleTimeline renders the pipeline as one line per step.
+func bundleTimeline(task *Task) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Task %s (%s)\nIssue: %s %s\n\n", task.ID, task.Status, task.Issue.Title, task.Issue.URL)
+	for _, s := range task.Pipeline {
+		duration := "-"
+		if s.EndedAt != nil {
+			duration = s.EndedAt.Sub(s.StartedAt).Round(time.Millisecond).String()
+		}
+		fmt.Fprintf(&b, "%s  %-18s %-8s %8s", s.StartedAt.UTC().Format(time.RFC3339), s.Phase, s.Status, duration)
+		if s.Output != "" {
+			fmt.Fprintf(&b, "  %s", firstLine(s.Output))
+		}
+		if s.Error != "" {
+			fmt.Fprintf(&b, "  error: %s", firstLine(s.Error))
+		}
+		b.WriteString("\n")
+	}
+	for _, a := range task.Attempts {
+		fmt.Fprintf(&b, "\nAttempt %d: %s", a.Number, a.Status)
+		if a.FailReason != "" {
+			fmt.Fprintf(&b, " (%s)", a.FailReason)
+		}
+		if len(a.FilesChanged) > 0 {
+			fmt.Fprintf(&b, "\n  files: %s", strings.Join(a.FilesChanged, ", "))
+		}
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// bundleTestOutput is the failure file of one test: its output followed by
+// the failing cases of structured runners.
+func bundleTestOutput(t TestResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s (%s) failed after %s\n\n%s\n", t.Name, t.Type, t.Duration, t.Output)
+	for _, c := range t.Cases {
+		if c.Status == "fail" {
+			fmt.Fprintf(&b, "\n--- FAIL: %s\n%s\n", c.Name, c.Message)
+		}
+	}
+	return b.String()
+}
+
+// bundleName makes a test name safe as a zip path element.
+func bundleName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' || r == ' ' {
+			return '_'
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == ".." {
+		return "test"
+	}
+	return name
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i] + " ..."
+	}
+	return s
+}
+
+// recordBundleLog keeps the last maxBundleLogs lines of a task for its
+// failure bundle.
+func (e *Engine) recordBundleLog(taskID, level, msg string) {
+	if !e.cfg.Workflow.FailureBundle.Enabled {
+		return
+	}
+	e.bundleMu.Lock()
+	defer e.bundleMu.Unlock()
+	if e.bundleLogs == nil {
+		e.bundleLogs = make(map[string][]BundleLog)
+	}
+	logs := append(e.bundleLogs[taskID], BundleLog{Time: time.Now(), Level: level, Message: msg})
+	if len(logs) > maxBundleLogs {
+		logs = logs[len(logs)-maxBundleLogs:]
+	}
+	e.bundleLogs[taskID] = logs
+}
+
+// writeFailureBundle stores the diagnostic bundle of a failed task and
+// links it from the source issue. Like issue updates it is best-effort.
+func (e *Engine) writeFailureBundle(ctx context.Context, task *Task) {
+	cfg := e.cfg.Workflow.FailureBundle
+	if !cfg.Enabled || e.dryRun {
+		return
+	}
+	e.bundleMu.Lock()
+	logs := e.bundleLogs[task.ID]
+	e.bundleMu.Unlock()
+
+	var buf bytes.Buffer
+	if err := WriteBundle(&buf, e.cfg, task, logs); err != nil {
+		e.taskLog(task.ID, "warn", fmt.Sprintf("Could not build diagnostic bundle: %v", err))
+		return
+	}
+	bundlePath := BundlePath(e.statePath, task.ID)
+	if err := os.MkdirAll(filepath.Dir(bundlePath), 0o755); err != nil {
+		e.taskLog(task.ID, "warn", fmt.Sprintf("Could not write diagnostic bundle: %v", err))
+		return
+	}
+	if err := os.WriteFile(bundlePath, buf.Bytes(), 0o600); err != nil {
+		e.taskLog(task.ID, "warn", fmt.Sprintf("Could not write diagnostic bundle: %v", err))
+		return
+	}
+	e.taskLog(task.ID, "info", "Diagnostic bundle written to "+bundlePath)
+
+	link := "`" + bundlePath + "` on the rig host"
+	if cfg.URL != "" {
+		u := strings.TrimRight(cfg.URL, "/") + "/api/tasks/" + task.ID + "/bundle"
+		link = "[" + task.ID + ".zip](" + u + ")"
+	}
+	e.postIssueComment(ctx, task, fmt.Sprintf("Task `%s` failed. Diagnostic bundle (redacted config, pipeline timeline, last logs and failed test output): %s",
+		task.ID, link))
+}
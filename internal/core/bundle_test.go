@@ -0,0 +1,167 @@
+package core
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+)
+
+// readBundle returns the files of a zip bundle by name.
+func readBundle(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("open bundle: %v", err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(content)
+	}
+	return files
+}
+
+func TestWriteBundle(t *testing.T) {
+	cfg := testConfig()
+	cfg.Source.Token = "ghp_supersecret"
+	cfg.AI.APIKey = "sk-ant-secret"
+	cfg.Server.Secret = "hook-secret"
+	cfg.Deploy.Config.Commands = []config.CustomCommand{{
+		Name: "deploy",
+		Run:  "deploy --token ghp_inline",
+		Env:  map[string]string{"DB_PASSWORD": "hunter2"},
+	}}
+
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	end := start.Add(2 * time.Second)
+	task := &Task{
+		ID:     "task-1",
+		Status: PhaseFailed,
+		Issue:  Issue{Title: "Broken", URL: "https://github.com/test/repo/issues/1"},
+		Pipeline: []PipelineStep{
+			{Phase: PhaseTesting, Status: "failed", StartedAt: start, EndedAt: &end, Error: "unit failed\nmore"},
+		},
+		Attempts: []Attempt{{
+			Number: 1,
+			Status: "failed",
+			Deploy: &DeployResult{Status: "failed", Output: "deploy exploded"},
+			Tests: []TestResult{
+				{Name: "lint", Passed: true, Output: "ok"},
+				{Name: "unit/api", Type: "command", Output: "FAIL TestX", Cases: []TestCase{{Name: "TestX", Status: "fail", Message: "want 1"}}},
+			},
+		}},
+	}
+	logs := []BundleLog{{Time: start, Level: "error", Message: "Task failed"}}
+
+	var buf bytes.Buffer
+	if err := WriteBundle(&buf, cfg, task, logs); err != nil {
+		t.Fatalf("WriteBundle: %v", err)
+	}
+	files := readBundle(t, buf.Bytes())
+
+	for _, name := range []string{"config.yaml", "task.json", "timeline.txt", "logs.txt", "failures/attempt-1/deploy.txt", "failures/attempt-1/test-unit_api.txt"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("bundle is missing %s; has %v", name, files)
+		}
+	}
+	if _, ok := files["failures/attempt-1/test-lint.txt"]; ok {
+		t.Error("passing tests must not be in the bundle")
+	}
+
+	conf := files["config.yaml"]
+	for _, secret := range []string{"ghp_supersecret", "sk-ant-secret", "hook-secret", "hunter2", "ghp_inline"} {
+		if strings.Contains(conf, secret) {
+			t.Errorf("config.yaml leaks %q:\n%s", secret, conf)
+		}
+	}
+	if !strings.Contains(conf, "repo: test/repo") {
+		t.Errorf("config.yaml should keep non-secret values:\n%s", conf)
+	}
+	if !strings.Contains(files["timeline.txt"], "testing") || !strings.Contains(files["timeline.txt"], "2s") {
+		t.Errorf("unexpected timeline:\n%s", files["timeline.txt"])
+	}
+	if !strings.Contains(files["logs.txt"], "[error] Task failed") {
+		t.Errorf("unexpected logs:\n%s", files["logs.txt"])
+	}
+	if out := files["failures/attempt-1/test-unit_api.txt"]; !strings.Contains(out, "FAIL TestX") || !strings.Contains(out, "--- FAIL: TestX") {
+		t.Errorf("unexpected test output:\n%s", out)
+	}
+}
+
+func TestEngine_FailureBundle(t *testing.T) {
+	cfg := testConfig()
+	cfg.AI.MaxRetry = 1
+	cfg.Workflow.FailureBundle.Enabled = true
+	cfg.Workflow.FailureBundle.URL = "https://rig.example.com/"
+	gitMock := &commentGit{}
+	testRunner := &mockTestRunner{results: []*TestResult{
+		{Name: "unit-test", Type: "command", Passed: false, Output: "FAIL 1"},
+		{Name: "unit-test", Type: "command", Passed: false, Output: "FAIL 2"},
+	}}
+	statePath := tempStatePath(t)
+	engine := NewEngine(cfg, gitMock, &mockAI{}, &mockDeploy{deploySuccess: true},
+		[]TestRunnerIface{testRunner}, nil, statePath)
+
+	if err := engine.Execute(context.Background(), testIssue()); err == nil {
+		t.Fatal("expected failure after max retries")
+	}
+	state, err := LoadState(statePath)
+	if err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	task := state.Tasks[0]
+
+	data, err := os.ReadFile(BundlePath(statePath, task.ID))
+	if err != nil {
+		t.Fatalf("bundle not written: %v", err)
+	}
+	files := readBundle(t, data)
+	if !strings.Contains(files["logs.txt"], "Plan: test plan") {
+		t.Errorf("bundle should include the task logs, got:\n%s", files["logs.txt"])
+	}
+	if !strings.Contains(files["failures/attempt-1/test-unit-test.txt"], "FAIL") {
+		t.Errorf("bundle should include the failed test output, got %v", files)
+	}
+
+	if len(gitMock.comments) != 1 {
+		t.Fatalf("expected one bundle comment, got %v", gitMock.comments)
+	}
+	want := "https://rig.example.com/api/tasks/" + task.ID + "/bundle"
+	if !strings.Contains(gitMock.comments[0], want) {
+		t.Errorf("comment should link %s, got %q", want, gitMock.comments[0])
+	}
+}
+
+func TestEngine_FailureBundleDisabled(t *testing.T) {
+	cfg := testConfig()
+	cfg.AI.MaxRetry = 1
+	gitMock := &commentGit{}
+	testRunner := &mockTestRunner{results: []*TestResult{
+		{Name: "unit-test", Passed: false},
+		{Name: "unit-test", Passed: false},
+	}}
+	statePath := tempStatePath(t)
+	engine := NewEngine(cfg, gitMock, &mockAI{}, &mockDeploy{deploySuccess: true},
+		[]TestRunnerIface{testRunner}, nil, statePath)
+
+	_ = engine.Execute(context.Background(), testIssue())
+	state, _ := LoadState(statePath)
+	if _, err := os.Stat(BundlePath(statePath, state.Tasks[0].ID)); !os.IsNotExist(err) {
+		t.Errorf("no bundle expected without workflow.failure_bundle, stat err = %v", err)
+	}
+	if len(gitMock.comments) != 0 {
+		t.Errorf("expected no comments, got %v", gitMock.comments)
+	}
+}
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rigdev/rig/internal/logging"
+)
+
+// ErrShutdown is the cause a server cancels the context of running tasks
+// with when it stops. A task it interrupts is checkpointed rather than
+// failed, and ResumeCheckpoint runs it again on the next start.
+var ErrShutdown = errors.New("rig is shutting down")
+
+// Checkpoint records where a shutdown interrupted a task.
+type Checkpoint struct {
+	Phase TaskPhase `json:"phase"`
+	// Attempt is the number of attempts the task had made.
+	Attempt int       `json:"attempt"`
+	At      time.Time `json:"at"`
+}
+
+// shuttingDown reports whether ctx ended because the server is stopping.
+func shuttingDown(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrShutdown)
+}
+
+// CheckpointedTasks returns the tasks a shutdown interrupted, oldest first.
+func (s *State) CheckpointedTasks() []Task {
+	var tasks []Task
+	for _, t := range s.Tasks {
+		if t.Checkpoint != nil && !inactivePhases[t.Status] {
+			tasks = append(tasks, t)
+		}
+	}
+	return tasks
+}
+
+// checkpointTask records that a shutdown interrupted task and saves it in
+// its current phase. Unlike failTask it leaves the task's branches and
+// workspace in place for ResumeCheckpoint.
+func (e *Engine) checkpointTask(state *State, task *Task, cause error) error {
+	e.taskLog(task.ID, "warn", fmt.Sprintf("Task interrupted at %s by shutdown: %v", task.Status, cause))
+	interruptTask(task, task.Status, "interrupted by shutdown")
+	if err := mergeState(state, e.statePath); err != nil {
+		e.log().Error("failed to save checkpoint", logging.TaskKey, task.ID, "err", err)
+	}
+	e.flushLogs()
+	return fmt.Errorf("task %s interrupted at %s: %w", task.ID, task.Status, ErrShutdown)
+}
+
+// interruptTask fails the running pipeline steps and attempt of task with
+// why and checkpoints it at phase.
+func interruptTask(task *Task, phase TaskPhase, why string) {
+	now := time.Now().UTC()
+	for i := range task.Pipeline {
+		if task.Pipeline[i].Status == "running" {
+			task.Pipeline[i].Status = "failed"
+			task.Pipeline[i].Error = why
+			task.Pipeline[i].EndedAt = &now
+		}
+	}
+	if a := lastAttempt(task); a != nil && a.Status == "running" {
+		completeAttempt(a, "failed", ReasonShutdown)
+	}
+	task.Checkpoint = &Checkpoint{Phase: phase, Attempt: len(task.Attempts), At: now}
+}
+
+// checkpointSteps maps the phases a task can be interrupted in to the step
+// that runs the phase again.
+var checkpointSteps = map[TaskPhase]StepName{
+	PhaseQueued:     StepPlan,
+	PhasePlanning:   StepPlan,
+	PhaseCoding:     StepCode,
+	PhaseCommitting: StepCommit,
+	PhaseDeploying:  StepDeploy,
+	PhaseTesting:    StepTest,
+	PhaseReporting:  StepReport,
+}
+
+// ResumeCheckpoint runs a checkpointed task again with RunSteps, from the
+// step of the phase it was interrupted in through report, or from plan
+// when the outputs of the steps before that one are gone. A task that
+// fails is failed as AbandonTask does, and one that another shutdown
+// interrupts is checkpointed again.
+func (e *Engine) ResumeCheckpoint(ctx context.Context, taskID string) error {
+	lock, state, task, err := e.lockTask(taskID)
+	if err != nil {
+		return err
+	}
+	cp := task.Checkpoint
+	if cp == nil {
+		lock.Release()
+		return fmt.Errorf("task %s has no checkpoint", taskID)
+	}
+	task.Checkpoint = nil
+	e.taskLog(task.ID, "info", fmt.Sprintf("Resuming task interrupted at %s by shutdown", cp.Phase))
+	err = mergeState(state, e.statePath)
+	lock.Release()
+	if err != nil {
+		return fmt.Errorf("save state: %w", err)
+	}
+
+	from, ok := checkpointSteps[cp.Phase]
+	if !ok {
+		from = StepPlan
+	}
+	records, err := e.RunSteps(ctx, taskID, from, StepReport)
+	if len(records) == 0 && errors.Is(err, ErrStepNotReady) && from != StepPlan {
+		e.taskLog(taskID, "info", fmt.Sprintf("Cannot resume at step %s (%v); starting over from plan", from, err))
+		_, err = e.RunSteps(ctx, taskID, StepPlan, StepReport)
+	}
+	if err != nil {
+		if abandonErr := e.AbandonTask(ctx, taskID, err); abandonErr != nil {
+			return abandonErr
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// shutdownDeploy stops the server in its first Deploy by cancelling the
+// task context with ErrShutdown, and deploys normally after that.
+type shutdownDeploy struct {
+	mockDeploy
+	stop context.CancelCauseFunc
+}
+
+func (m *shutdownDeploy) Deploy(ctx context.Context, vars map[string]string) (*AdapterDeployResult, error) {
+	if m.deployCalls == 0 {
+		m.deployCalls++
+		m.stop(ErrShutdown)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return m.mockDeploy.Deploy(ctx, vars)
+}
+
+func TestEngine_ShutdownCheckpointsAndResumes(t *testing.T) {
+	cfg := testConfig()
+	cfg.Deploy.Rollback.Enabled = true
+	ctx, stop := context.WithCancelCause(context.Background())
+	deployMock := &shutdownDeploy{mockDeploy: mockDeploy{deploySuccess: true}, stop: stop}
+	gitMock := &mockGit{}
+	statePath := tempStatePath(t)
+	engine := NewEngine(cfg, gitMock, &mockAI{}, deployMock, nil, nil, statePath)
+	flushes := 0
+	engine.SetLogFlusher(func() error {
+		flushes++
+		return nil
+	})
+
+	err := engine.Execute(ctx, testIssue())
+	if !errors.Is(err, ErrShutdown) {
+		t.Fatalf("expected ErrShutdown, got %v", err)
+	}
+	if flushes != 1 {
+		t.Errorf("expected one log flush for the checkpoint, got %d", flushes)
+	}
+	state, _ := LoadState(statePath)
+	task := state.Tasks[0]
+	if task.Status != PhaseDeploying || task.Checkpoint == nil || task.Checkpoint.Phase != PhaseDeploying {
+		t.Fatalf("status %s, checkpoint %+v; want a checkpoint at deploying", task.Status, task.Checkpoint)
+	}
+	if a := lastAttempt(&task); a == nil || a.FailReason != ReasonShutdown {
+		t.Errorf("last attempt = %+v, want fail reason shutdown", a)
+	}
+	for _, step := range task.Pipeline {
+		if step.Status == "running" {
+			t.Errorf("pipeline step %s left running", step.Phase)
+		}
+	}
+	if deployMock.rollbackCalls != 0 || len(gitMock.cleanedBranches) != 0 {
+		t.Errorf("checkpoint rolled back %d times and deleted branches %v", deployMock.rollbackCalls, gitMock.cleanedBranches)
+	}
+	if got := state.CheckpointedTasks(); len(got) != 1 || got[0].ID != task.ID {
+		t.Errorf("CheckpointedTasks = %v", got)
+	}
+
+	if err := engine.ResumeCheckpoint(context.Background(), task.ID); err != nil {
+		t.Fatalf("ResumeCheckpoint: %v", err)
+	}
+	state, _ = LoadState(statePath)
+	task = state.Tasks[0]
+	if task.Status != PhaseCompleted || task.Checkpoint != nil || task.PR == nil {
+		t.Errorf("status %s, checkpoint %+v, PR %v; want a completed task", task.Status, task.Checkpoint, task.PR)
+	}
+	if deployMock.deployCalls != 2 {
+		t.Errorf("deployed %d times, want 2", deployMock.deployCalls)
+	}
+	if err := engine.ResumeCheckpoint(context.Background(), task.ID); err == nil {
+		t.Error("resumed a task without a checkpoint")
+	}
+}
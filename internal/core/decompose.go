@@ -0,0 +1,262 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+)
+
+// Defaults of workflow.decompose.
+const (
+	defaultDecomposeMinSteps    = 4
+	defaultDecomposeMaxSubtasks = 4
+)
+
+// ErrDecomposeUnsupported is returned by adapter wrappers whose wrapped
+// adapter cannot split plans.
+var ErrDecomposeUnsupported = errors.New("ai adapter does not support plan decomposition")
+
+// AISubtask is one part of a plan split up by PlanDecomposer, such as the
+// backend, the tests or the docs.
+type AISubtask struct {
+	Name    string   `json:"name"`
+	Summary string   `json:"summary"`
+	Steps   []string `json:"steps"`
+}
+
+// PlanDecomposer is an optional AIAdapter capability that splits the plan
+// of a large issue into at most maxSubtasks sub-tasks whose code can be
+// generated separately. Used by workflow.decompose.
+type PlanDecomposer interface {
+	DecomposePlan(ctx context.Context, plan *AIPlan, maxSubtasks int) ([]AISubtask, error)
+}
+
+// FileConflict is a file two sub-tasks changed differently.
+type FileConflict struct {
+	Path     string
+	Subtasks [2]string
+}
+
+// generate generates the code of plan. With workflow.decompose and a plan
+// of enough steps, the plan is split into sub-tasks generated in separate
+// passes whose changes are merged; their names are recorded on attempt.
+// Without a PlanDecomposer, or when splitting fails, it generates the plan
+// in one pass.
+func (e *Engine) generate(ctx context.Context, task *Task, attempt *Attempt, plan *AIPlan, repoFiles map[string]string) ([]AIFileChange, error) {
+	ai, _ := e.taskAI(task)
+	cfg := e.cfg.Workflow.Decompose
+	minSteps := cfg.MinSteps
+	if minSteps == 0 {
+		minSteps = defaultDecomposeMinSteps
+	}
+	if !cfg.Enabled || len(plan.Steps) < minSteps {
+		return stepGenerate(ctx, ai, plan, repoFiles)
+	}
+	decomposer, ok := ai.(PlanDecomposer)
+	if !ok {
+		e.taskLog(task.ID, "warn", "AI adapter cannot split plans; generating the plan in one pass")
+		return stepGenerate(ctx, ai, plan, repoFiles)
+	}
+
+	maxSubtasks := cfg.MaxSubtasks
+	if maxSubtasks == 0 {
+		maxSubtasks = defaultDecomposeMaxSubtasks
+	}
+	subtasks, err := decomposer.DecomposePlan(ctx, plan, maxSubtasks)
+	if errors.Is(err, ErrDecomposeUnsupported) {
+		e.taskLog(task.ID, "warn", "AI adapter cannot split plans; generating the plan in one pass")
+		return stepGenerate(ctx, ai, plan, repoFiles)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("decompose plan: %w", err)
+		}
+		e.taskLog(task.ID, "warn", fmt.Sprintf("Splitting the plan failed, generating it in one pass: %v", err))
+		return stepGenerate(ctx, ai, plan, repoFiles)
+	}
+	subtasks = capSubtasks(subtasks, maxSubtasks)
+	if len(subtasks) < 2 {
+		return stepGenerate(ctx, ai, plan, repoFiles)
+	}
+
+	for _, st := range subtasks {
+		attempt.Subtasks = append(attempt.Subtasks, st.Name)
+	}
+	mode := "one after the other"
+	if cfg.Parallel {
+		mode = "in parallel"
+	}
+	e.taskLog(task.ID, "info", fmt.Sprintf("Split the plan into %d sub-tasks, generated %s: %s",
+		len(subtasks), mode, strings.Join(attempt.Subtasks, ", ")))
+
+	if cfg.Parallel {
+		return e.generateParallel(ctx, task, plan, subtasks, repoFiles)
+	}
+	return e.generateSequential(ctx, task, plan, subtasks, repoFiles)
+}
+
+// generateSequential generates the sub-tasks one after the other, each
+// seeing the repo files as the sub-tasks before it left them.
+func (e *Engine) generateSequential(ctx context.Context, task *Task, plan *AIPlan, subtasks []AISubtask, repoFiles map[string]string) ([]AIFileChange, error) {
+	ai, _ := e.taskAI(task)
+	var merged mergedChanges
+	for i, st := range subtasks {
+		changes, err := stepGenerate(ctx, ai, subtaskPlan(plan, subtasks, i), merged.apply(repoFiles))
+		if err != nil {
+			return nil, fmt.Errorf("sub-task %q: %w", st.Name, err)
+		}
+		e.taskLog(task.ID, "info", fmt.Sprintf("Sub-task %q generated %d file(s)", st.Name, len(changes)))
+		merged.add(st.Name, changes)
+	}
+	return merged.changes, nil
+}
+
+// generateParallel generates all sub-tasks at once from the same repo
+// files and merges their changes in sub-task order. A sub-task whose
+// changes conflict with those merged before it is generated again on top
+// of them, and its new changes replace the earlier ones.
+func (e *Engine) generateParallel(ctx context.Context, task *Task, plan *AIPlan, subtasks []AISubtask, repoFiles map[string]string) ([]AIFileChange, error) {
+	ai, _ := e.taskAI(task)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([][]AIFileChange, len(subtasks))
+	errs := make([]error, len(subtasks))
+	var wg sync.WaitGroup
+	for i := range subtasks {
+		wg.Go(func() {
+			results[i], errs[i] = stepGenerate(ctx, ai, subtaskPlan(plan, subtasks, i), repoFiles)
+			if errs[i] != nil {
+				cancel()
+			}
+		})
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("sub-task %q: %w", subtasks[i].Name, err)
+		}
+	}
+
+	var merged mergedChanges
+	for i, st := range subtasks {
+		changes := results[i]
+		if conflicts := merged.conflicts(st.Name, changes); len(conflicts) > 0 {
+			for _, c := range conflicts {
+				e.taskLog(task.ID, "warn", fmt.Sprintf("Sub-tasks %q and %q both changed %s", c.Subtasks[0], c.Subtasks[1], c.Path))
+			}
+			e.taskLog(task.ID, "info", fmt.Sprintf("Generating sub-task %q again on top of the earlier sub-tasks", st.Name))
+			var err error
+			changes, err = stepGenerate(ctx, ai, subtaskPlan(plan, subtasks, i), merged.apply(repoFiles))
+			if err != nil {
+				return nil, fmt.Errorf("sub-task %q: %w", st.Name, err)
+			}
+		}
+		e.taskLog(task.ID, "info", fmt.Sprintf("Sub-task %q generated %d file(s)", st.Name, len(changes)))
+		merged.add(st.Name, changes)
+	}
+	return merged.changes, nil
+}
+
+// capSubtasks drops sub-tasks without steps and folds any beyond limit into
+// the last one kept.
+func capSubtasks(subtasks []AISubtask, limit int) []AISubtask {
+	var kept []AISubtask
+	for _, st := range subtasks {
+		if len(st.Steps) == 0 {
+			continue
+		}
+		if st.Name == "" {
+			st.Name = fmt.Sprintf("part %d", len(kept)+1)
+		}
+		if len(kept) == limit {
+			kept[limit-1].Steps = append(kept[limit-1].Steps, st.Steps...)
+			continue
+		}
+		kept = append(kept, st)
+	}
+	return kept
+}
+
+// subtaskPlan is the plan of subtasks[i]: its own steps, under a summary
+// that places it in the whole plan so the pass leaves the other sub-tasks
+// to their own passes.
+func subtaskPlan(plan *AIPlan, subtasks []AISubtask, i int) *AIPlan {
+	others := make([]string, 0, len(subtasks)-1)
+	for j, st := range subtasks {
+		if j != i {
+			others = append(others, st.Name)
+		}
+	}
+	st := subtasks[i]
+	summary := fmt.Sprintf("%s\n\nThis is sub-task %d of %d, %q: %s\nOnly make the changes of this sub-task; %s are done separately.",
+		plan.Summary, i+1, len(subtasks), st.Name, st.Summary, strings.Join(others, ", "))
+	return &AIPlan{Summary: summary, Steps: st.Steps}
+}
+
+// mergedChanges accumulates the file changes of sub-tasks in order; a
+// later change to a file replaces an earlier one.
+type mergedChanges struct {
+	changes []AIFileChange
+	owner   map[string]string // path → sub-task that changed it
+}
+
+// add merges the changes of subtask.
+func (m *mergedChanges) add(subtask string, changes []AIFileChange) {
+	if m.owner == nil {
+		m.owner = make(map[string]string)
+	}
+	for _, c := range changes {
+		if _, ok := m.owner[c.Path]; ok {
+			for i := range m.changes {
+				if m.changes[i].Path == c.Path {
+					m.changes[i] = c
+				}
+			}
+		} else {
+			m.changes = append(m.changes, c)
+		}
+		m.owner[c.Path] = subtask
+	}
+}
+
+// conflicts returns the files subtask changed that another sub-task
+// already changed to something else.
+func (m *mergedChanges) conflicts(subtask string, changes []AIFileChange) []FileConflict {
+	var conflicts []FileConflict
+	for _, c := range changes {
+		owner, ok := m.owner[c.Path]
+		if !ok || owner == subtask {
+			continue
+		}
+		for _, prev := range m.changes {
+			if prev.Path == c.Path && (prev.Action != c.Action || prev.Content != c.Content) {
+				conflicts = append(conflicts, FileConflict{Path: c.Path, Subtasks: [2]string{owner, subtask}})
+			}
+		}
+	}
+	return conflicts
+}
+
+// apply returns repoFiles with the merged changes applied, the shared
+// context of the next pass.
+func (m *mergedChanges) apply(repoFiles map[string]string) map[string]string {
+	if len(m.changes) == 0 {
+		return repoFiles
+	}
+	files := maps.Clone(repoFiles)
+	if files == nil {
+		files = make(map[string]string)
+	}
+	for _, c := range m.changes {
+		if c.Action == "delete" {
+			delete(files, c.Path)
+		} else {
+			files[c.Path] = c.Content
+		}
+	}
+	return files
+}
@@ -0,0 +1,174 @@
+package core
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// decomposingAI is a mockAI that splits plans into fixed sub-tasks.
+type decomposingAI struct {
+	*mockAI
+	subtasks []AISubtask
+}
+
+func (d *decomposingAI) DecomposePlan(ctx context.Context, plan *AIPlan, maxSubtasks int) ([]AISubtask, error) {
+	return d.subtasks, nil
+}
+
+func decomposeConfigEngine(ai AIAdapter, parallel bool) *Engine {
+	cfg := testConfig()
+	cfg.Workflow.Decompose.Enabled = true
+	cfg.Workflow.Decompose.MinSteps = 2
+	cfg.Workflow.Decompose.Parallel = parallel
+	return NewEngine(cfg, &mockGit{}, ai, &mockDeploy{deploySuccess: true}, nil, nil, "")
+}
+
+var largePlan = &AIPlan{Summary: "Add rate limiting", Steps: []string{"Add limiter", "Test limiter", "Document limiter"}}
+
+func TestGenerate_SequentialSharesContext(t *testing.T) {
+	var seen []map[string]string
+	ai := &decomposingAI{
+		mockAI: &mockAI{generateFunc: func(ctx context.Context, plan *AIPlan, repoFiles map[string]string) ([]AIFileChange, error) {
+			seen = append(seen, repoFiles)
+			if strings.Contains(plan.Summary, `"backend"`) {
+				return []AIFileChange{{Path: "limit.go", Content: "package limit", Action: "create"}}, nil
+			}
+			return []AIFileChange{{Path: "limit_test.go", Content: "package limit_test", Action: "create"}}, nil
+		}},
+		subtasks: []AISubtask{
+			{Name: "backend", Summary: "limiter", Steps: []string{"Add limiter"}},
+			{Name: "tests", Summary: "tests", Steps: []string{"Test limiter", "Document limiter"}},
+		},
+	}
+	e := decomposeConfigEngine(ai, false)
+
+	var attempt Attempt
+	changes, err := e.generate(context.Background(), &Task{ID: "task-1"}, &attempt, largePlan, map[string]string{"main.go": "package main"})
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if len(changes) != 2 || changes[0].Path != "limit.go" || changes[1].Path != "limit_test.go" {
+		t.Fatalf("unexpected changes %+v", changes)
+	}
+	if !reflect.DeepEqual(attempt.Subtasks, []string{"backend", "tests"}) {
+		t.Errorf("attempt.Subtasks = %v", attempt.Subtasks)
+	}
+	if len(seen) != 2 || seen[1]["limit.go"] != "package limit" || seen[1]["main.go"] != "package main" {
+		t.Errorf("second pass should see the repo and the first pass's changes, got %v", seen)
+	}
+}
+
+func TestGenerate_ParallelRegeneratesConflicts(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	ai := &decomposingAI{
+		mockAI: &mockAI{generateFunc: func(ctx context.Context, plan *AIPlan, repoFiles map[string]string) ([]AIFileChange, error) {
+			mu.Lock()
+			calls++
+			mu.Unlock()
+			if strings.Contains(plan.Summary, `"backend"`) {
+				return []AIFileChange{
+					{Path: "server.go", Content: "backend", Action: "modify"},
+					{Path: "limit.go", Content: "package limit", Action: "create"},
+				}, nil
+			}
+			// The docs pass also touches server.go; on top of the
+			// backend's version it keeps that and adds a comment.
+			content := "docs"
+			if prev, ok := repoFiles["server.go"]; ok {
+				content = prev + "\n// documented"
+			}
+			return []AIFileChange{
+				{Path: "server.go", Content: content, Action: "modify"},
+				{Path: "README.md", Content: "# limit", Action: "modify"},
+			}, nil
+		}},
+		subtasks: []AISubtask{
+			{Name: "backend", Summary: "limiter", Steps: []string{"Add limiter", "Test limiter"}},
+			{Name: "docs", Summary: "docs", Steps: []string{"Document limiter"}},
+		},
+	}
+	e := decomposeConfigEngine(ai, true)
+
+	changes, err := e.generate(context.Background(), &Task{ID: "task-1"}, &Attempt{}, largePlan, nil)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected the conflicting sub-task to be generated again (3 calls), got %d", calls)
+	}
+	got := make(map[string]string)
+	for _, c := range changes {
+		got[c.Path] = c.Content
+	}
+	want := map[string]string{"server.go": "backend\n// documented", "limit.go": "package limit", "README.md": "# limit"}
+	if !reflect.DeepEqual(got, want) || len(changes) != 3 {
+		t.Errorf("merged changes = %+v, want %v", changes, want)
+	}
+}
+
+func TestGenerate_OnePassBelowMinSteps(t *testing.T) {
+	calls := 0
+	ai := &decomposingAI{
+		mockAI: &mockAI{generateFunc: func(ctx context.Context, plan *AIPlan, repoFiles map[string]string) ([]AIFileChange, error) {
+			calls++
+			if len(plan.Steps) != 1 {
+				t.Errorf("expected the whole plan, got %+v", plan)
+			}
+			return nil, nil
+		}},
+		subtasks: []AISubtask{{Name: "a", Steps: []string{"x"}}, {Name: "b", Steps: []string{"y"}}},
+	}
+	e := decomposeConfigEngine(ai, false)
+
+	var attempt Attempt
+	if _, err := e.generate(context.Background(), &Task{ID: "task-1"}, &attempt, &AIPlan{Summary: "small", Steps: []string{"one"}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 || attempt.Subtasks != nil {
+		t.Errorf("small plan should be generated in one pass, got %d calls and sub-tasks %v", calls, attempt.Subtasks)
+	}
+}
+
+func TestCapSubtasks(t *testing.T) {
+	got := capSubtasks([]AISubtask{
+		{Name: "backend", Steps: []string{"a"}},
+		{Name: "empty"},
+		{Steps: []string{"b"}},
+		{Name: "docs", Steps: []string{"c"}},
+	}, 2)
+	want := []AISubtask{
+		{Name: "backend", Steps: []string{"a"}},
+		{Name: "part 2", Steps: []string{"b", "c"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("capSubtasks = %+v, want %+v", got, want)
+	}
+}
+
+func TestExecute_RecordsSubtasks(t *testing.T) {
+	cfg := testConfig()
+	cfg.Workflow.Decompose.Enabled = true
+	cfg.Workflow.Decompose.MinSteps = 3
+	ai := &decomposingAI{
+		mockAI: &mockAI{analyzeFunc: func(ctx context.Context, issue *AIIssue, projectContext string) (*AIPlan, error) {
+			return largePlan, nil
+		}},
+		subtasks: []AISubtask{{Name: "backend", Steps: []string{"x"}}, {Name: "docs", Steps: []string{"y"}}},
+	}
+	statePath := tempStatePath(t)
+	runner := &mockTestRunner{results: []*TestResult{{Name: "unit-test", Type: "command", Passed: true, Duration: time.Second}}}
+	e := NewEngine(cfg, &mockGit{}, ai, &mockDeploy{deploySuccess: true}, []TestRunnerIface{runner}, nil, statePath)
+
+	if err := e.Execute(context.Background(), testIssue()); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	state, _ := LoadState(statePath)
+	if got := state.Tasks[0].Attempts[0].Subtasks; !reflect.DeepEqual(got, []string{"backend", "docs"}) {
+		t.Errorf("attempt sub-tasks = %v", got)
+	}
+}
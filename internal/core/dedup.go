@@ -0,0 +1,223 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Defaults of workflow.dedup.
+const (
+	defaultDedupThreshold = 0.85
+	defaultDedupWindow    = 7 * 24 * time.Hour
+)
+
+// Actions of workflow.dedup.action.
+const (
+	DedupSkip   = "skip"
+	DedupAttach = "attach"
+)
+
+// DuplicateIssue is an issue found to repeat a task's issue, for which no
+// task of its own was started.
+type DuplicateIssue struct {
+	Issue      Issue     `json:"issue"`
+	Similarity float64   `json:"similarity"`
+	Attached   bool      `json:"attached,omitempty"` // the task's PR closes it too
+	FoundAt    time.Time `json:"found_at"`
+}
+
+// IssueMatcher scores how alike issues are. Set with
+// Engine.SetIssueMatcher (workflow.dedup.method embedding); without one
+// the engine compares the issues' words.
+type IssueMatcher interface {
+	// Similarity returns, for each candidate in order, how alike it is to
+	// issue, between 0 and 1.
+	Similarity(ctx context.Context, issue Issue, candidates []Issue) ([]float64, error)
+}
+
+// SetIssueMatcher sets the matcher workflow.dedup compares issues with.
+func (e *Engine) SetIssueMatcher(m IssueMatcher) {
+	e.issueMatcher = m
+}
+
+// textMatcher is the default IssueMatcher: issues whose normalized text is
+// identical score 1, others the share of distinct words they have in
+// common.
+type textMatcher struct{}
+
+func (textMatcher) Similarity(_ context.Context, issue Issue, candidates []Issue) ([]float64, error) {
+	words := issueWords(issue)
+	hash := wordsHash(words)
+	scores := make([]float64, len(candidates))
+	for i, c := range candidates {
+		other := issueWords(c)
+		if wordsHash(other) == hash {
+			scores[i] = 1
+			continue
+		}
+		scores[i] = jaccard(words, other)
+	}
+	return scores, nil
+}
+
+// issueWords returns the lowercased words of the issue's title and body.
+func issueWords(issue Issue) []string {
+	return strings.FieldsFunc(strings.ToLower(issue.Title+"\n"+issue.Body), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func wordsHash(words []string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(strings.Join(words, " ")))
+}
+
+// jaccard returns the share of distinct words a and b have in common.
+func jaccard(a, b []string) float64 {
+	set := make(map[string]bool, len(a))
+	for _, w := range a {
+		set[w] = true
+	}
+	union := len(set)
+	common := 0
+	seen := make(map[string]bool, len(b))
+	for _, w := range b {
+		if seen[w] {
+			continue
+		}
+		seen[w] = true
+		if set[w] {
+			common++
+		} else {
+			union++
+		}
+	}
+	if union == 0 {
+		return 0
+	}
+	return float64(common) / float64(union)
+}
+
+// findDuplicate returns the recent task of another issue in the same
+// repository that issue repeats, with their similarity, when
+// workflow.dedup is enabled. Failed tasks and dry runs are not compared, so
+// an issue whose first task failed gets a task of its own.
+func (e *Engine) findDuplicate(ctx context.Context, state *State, issue Issue) (*Task, float64) {
+	cfg := e.cfg.Workflow.Dedup
+	if !cfg.Enabled || e.dryRun {
+		return nil, 0
+	}
+	window := cfg.Window
+	if window <= 0 {
+		window = defaultDedupWindow
+	}
+	threshold := cfg.Threshold
+	if threshold <= 0 {
+		threshold = defaultDedupThreshold
+	}
+	since := time.Now().Add(-window)
+	repo := e.issueRepo(issue)
+
+	var tasks []*Task
+	var candidates []Issue
+	seen := map[string]bool{}
+	for i := range state.Tasks {
+		t := &state.Tasks[i]
+		switch {
+		case t.CreatedAt.Before(since), t.Status == PhaseFailed, t.Status == PhaseRollback, t.Status == PhaseDryRun:
+			continue
+		case t.Issue.ID == issue.ID || !strings.EqualFold(e.issueRepo(t.Issue), repo):
+			continue
+		case seen[t.Issue.ID]:
+			continue
+		}
+		seen[t.Issue.ID] = true
+		tasks = append(tasks, t)
+		candidates = append(candidates, t.Issue)
+	}
+	if len(candidates) == 0 {
+		return nil, 0
+	}
+
+	matcher := e.issueMatcher
+	if matcher == nil {
+		matcher = textMatcher{}
+	}
+	scores, err := matcher.Similarity(ctx, issue, candidates)
+	if err != nil {
+		e.log().Warn("dedup: compare issues, starting a task", "issue", issue.ID, "err", err)
+		return nil, 0
+	}
+	var best *Task
+	bestScore := 0.0
+	for i, score := range scores {
+		if i < len(tasks) && score >= threshold && score > bestScore {
+			best, bestScore = tasks[i], score
+		}
+	}
+	return best, bestScore
+}
+
+// recordDuplicate records issue as a duplicate of task instead of starting
+// a task for it, and tells the issue where the work happens.
+func (e *Engine) recordDuplicate(ctx context.Context, task *Task, issue Issue, similarity float64) error {
+	attach := e.cfg.Workflow.Dedup.Action == DedupAttach
+	dup := DuplicateIssue{Issue: issue, Similarity: similarity, Attached: attach, FoundAt: time.Now().UTC()}
+	err := WithState(e.statePath, func(s *State) error {
+		if t := s.GetTaskByID(task.ID); t != nil {
+			t.addDuplicate(dup)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("record duplicate issue: %w", err)
+	}
+	task.addDuplicate(dup)
+
+	e.log().Info("dedup: issue repeats an existing task, not starting another", "issue", issue.ID, "duplicate_of", task.Issue.ID, "similarity", similarity)
+	outcome := "skipped it"
+	if attach {
+		outcome = "attached it"
+	}
+	e.taskLog(task.ID, "info", fmt.Sprintf("Issue #%s repeats this task's issue (%.0f%% similar); %s", issue.ID, similarity*100, outcome))
+	e.commentOnIssue(ctx, task.ID, issue, duplicateComment(task, similarity, attach))
+	return nil
+}
+
+// addDuplicate records dup unless its issue is already recorded.
+func (t *Task) addDuplicate(dup DuplicateIssue) {
+	for _, d := range t.Duplicates {
+		if d.Issue.Repo == dup.Issue.Repo && d.Issue.ID == dup.Issue.ID {
+			return
+		}
+	}
+	t.Duplicates = append(t.Duplicates, dup)
+}
+
+// duplicateComment is the comment posted on an issue found to repeat the
+// issue of task.
+func duplicateComment(task *Task, similarity float64, attached bool) string {
+	msg := fmt.Sprintf("This issue looks like a duplicate of #%s (%.0f%% similar), already handled by task `%s`", task.Issue.ID, similarity*100, task.ID)
+	if task.Issue.URL != "" {
+		msg = fmt.Sprintf("This issue looks like a duplicate of [#%s](%s) (%.0f%% similar), already handled by task `%s`", task.Issue.ID, task.Issue.URL, similarity*100, task.ID)
+	}
+	if task.PR != nil && task.PR.URL != "" {
+		msg += " in " + task.PR.URL
+	}
+	if attached {
+		return msg + ". It was attached to that task, so its PR closes this issue too."
+	}
+	return msg + ", so no new task was started."
+}
+
+// issueRepo is the repository of issue, the source repository unless it
+// names another.
+func (e *Engine) issueRepo(issue Issue) string {
+	if issue.Repo != "" {
+		return issue.Repo
+	}
+	return e.cfg.Source.Repo
+}
@@ -0,0 +1,127 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+)
+
+func TestTextMatcher(t *testing.T) {
+	issue := Issue{Title: "Login fails", Body: "Logging in with SSO returns a 500 error."}
+	scores, _ := textMatcher{}.Similarity(context.Background(), issue, []Issue{
+		{Title: "login FAILS", Body: "logging in with sso returns a 500 error"},
+		{Title: "Login fails", Body: "Logging in with SSO returns a 500 error on Safari."},
+		{Title: "Dark mode", Body: "Add a dark theme to the dashboard."},
+	})
+	if scores[0] != 1 {
+		t.Errorf("expected identical normalized text to score 1, got %v", scores[0])
+	}
+	if scores[1] < 0.8 || scores[1] >= 1 {
+		t.Errorf("expected a near duplicate to score high, got %v", scores[1])
+	}
+	if scores[2] > 0.1 {
+		t.Errorf("expected an unrelated issue to score low, got %v", scores[2])
+	}
+}
+
+func TestExecute_SkipsDuplicateIssue(t *testing.T) {
+	cfg := testConfig()
+	cfg.Workflow.Dedup.Enabled = true
+	statePath := tempStatePath(t)
+	gitMock := &commentGit{}
+	engine := NewEngine(cfg, gitMock, &mockAI{}, &mockDeploy{deploySuccess: true}, []TestRunnerIface{&mockTestRunner{}}, nil, statePath)
+
+	first := testIssue()
+	first.Body = "The login page crashes when the password is empty."
+	if err := engine.Execute(context.Background(), first); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	dup := first
+	dup.ID, dup.URL = "57", "https://github.com/test/repo/issues/57"
+	if err := engine.Execute(context.Background(), dup); err != nil {
+		t.Fatalf("execute duplicate: %v", err)
+	}
+
+	state, _ := LoadState(statePath)
+	if len(state.Tasks) != 1 {
+		t.Fatalf("expected no task for the duplicate, got %d tasks", len(state.Tasks))
+	}
+	task := state.Tasks[0]
+	if len(task.Duplicates) != 1 || task.Duplicates[0].Issue.ID != "57" || task.Duplicates[0].Attached || task.Duplicates[0].Similarity != 1 {
+		t.Fatalf("unexpected duplicates %+v", task.Duplicates)
+	}
+	last := gitMock.comments[len(gitMock.comments)-1]
+	if !strings.Contains(last, "duplicate of [#42](https://github.com/test/repo/issues/42)") || !strings.Contains(last, "https://github.com/test/repo/pull/1") || !strings.Contains(last, "no new task") {
+		t.Errorf("unexpected comment %q", last)
+	}
+
+	// A different issue gets a task of its own.
+	other := Issue{Repo: "test/repo", ID: "58", Title: "Add dark mode", Body: "The dashboard needs a dark theme."}
+	if err := engine.Execute(context.Background(), other); err != nil {
+		t.Fatalf("execute other: %v", err)
+	}
+	if state, _ = LoadState(statePath); len(state.Tasks) != 2 {
+		t.Fatalf("expected a task for a different issue, got %d tasks", len(state.Tasks))
+	}
+}
+
+func TestExecute_AttachesDuplicateIssue(t *testing.T) {
+	cfg := testConfig()
+	cfg.Workflow.Dedup = config.DedupConfig{Enabled: true, Action: DedupAttach}
+	statePath := tempStatePath(t)
+
+	state := &State{}
+	original := state.CreateTask(testIssue())
+	original.Status = PhaseAwaitingApproval
+	if err := SaveState(state, statePath); err != nil {
+		t.Fatal(err)
+	}
+	running, err := loadTrackedState(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := NewEngine(cfg, &commentGit{}, &mockAI{}, &mockDeploy{deploySuccess: true}, nil, nil, statePath)
+	dup := testIssue()
+	dup.ID = "57"
+	if err := engine.Execute(context.Background(), dup); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+
+	// The engine running the original task saves the state it loaded
+	// before the duplicate was attached.
+	running.GetTaskByID(original.ID).Status = PhaseCoding
+	if err := mergeState(running, statePath); err != nil {
+		t.Fatal(err)
+	}
+	state, _ = LoadState(statePath)
+	task := &state.Tasks[0]
+	if len(state.Tasks) != 1 || len(task.Duplicates) != 1 || !task.Duplicates[0].Attached || task.Status != PhaseCoding {
+		t.Fatalf("expected the duplicate to stay attached next to the engine's change, got %+v", state.Tasks)
+	}
+	task.Status = PhaseTesting
+	if !state.IsInFlight("57") {
+		t.Error("expected an attached issue to be in flight with its task")
+	}
+	if body := buildPRBodyData(task, "test/repo").Closes; body != "Closes #42, Closes #57" {
+		t.Errorf("Closes = %q", body)
+	}
+}
+
+func TestFindDuplicate_Window(t *testing.T) {
+	cfg := testConfig()
+	cfg.Workflow.Dedup = config.DedupConfig{Enabled: true}
+	engine := NewEngine(cfg, &mockGit{}, &mockAI{}, &mockDeploy{}, nil, nil, tempStatePath(t))
+	state := &State{}
+	old := state.CreateTask(testIssue())
+	old.CreatedAt = time.Now().Add(-8 * 24 * time.Hour)
+	failed := state.CreateTask(Issue{Repo: "test/repo", ID: "43", Title: "Fix the bug"})
+	failed.Status = PhaseFailed
+	state.CreateTask(Issue{Repo: "other/repo", ID: "44", Title: "Fix the bug"})
+
+	if task, _ := engine.findDuplicate(context.Background(), state, Issue{Repo: "test/repo", ID: "57", Title: "Fix the bug"}); task != nil {
+		t.Errorf("expected old, failed and other-repo tasks to be ignored, got %s", task.Issue.ID)
+	}
+}
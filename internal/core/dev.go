@@ -0,0 +1,52 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// devTaskID is the log ID used for rig dev cycles, which have no task.
+const devTaskID = "dev"
+
+// DevResult is the outcome of one rig dev cycle.
+type DevResult struct {
+	Deploy *DeployResult
+	Tests  []TestResult
+	Passed bool
+}
+
+// DevCycle deploys the local working tree and runs the tests selected for
+// changedFiles, the same way the deploy and test phases of the pipeline do.
+// It touches neither the state file, git nor the AI provider.
+func (e *Engine) DevCycle(ctx context.Context, branch string, changedFiles []string) (*DevResult, error) {
+	vars := e.buildVars(&Task{Branch: branch})
+
+	e.taskLog(devTaskID, "info", "Deploying...")
+	deploy, err := stepDeploy(ctx, e.deploy, vars)
+	if err != nil {
+		return nil, err
+	}
+	if deploy.Output != "" {
+		e.taskLog(devTaskID, "info", strings.TrimRight(deploy.Output, "\n"))
+	}
+	if deploy.Status != "success" {
+		e.taskLog(devTaskID, "error", fmt.Sprintf("Deploy failed after %s", deploy.Duration))
+		return &DevResult{Deploy: deploy}, nil
+	}
+	e.taskLog(devTaskID, "info", fmt.Sprintf("Deploy succeeded in %s", deploy.Duration))
+
+	e.taskLog(devTaskID, "info", "Running tests...")
+	results, passed := stepTest(ctx, e.testRunners, e.testConfigs, changedFiles, vars, e.testRunOptions())
+	for _, r := range results {
+		level, status := "info", "PASS"
+		if !r.Passed {
+			level, status = "error", "FAIL"
+		}
+		e.taskLog(devTaskID, level, fmt.Sprintf("%s %s (%s)", status, r.Name, r.Duration))
+		if !r.Passed && r.Output != "" {
+			e.taskLog(devTaskID, level, strings.TrimRight(r.Output, "\n"))
+		}
+	}
+	return &DevResult{Deploy: deploy, Tests: results, Passed: passed}, nil
+}
@@ -0,0 +1,60 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDevCycle_DeploysAndTests(t *testing.T) {
+	deploy := &mockDeploy{deploySuccess: true}
+	runner := &mockTestRunner{results: []*TestResult{
+		{Name: "unit-test", Type: "command", Passed: false, Output: "boom", Duration: time.Second},
+	}}
+	engine := NewEngine(testConfig(), nil, nil, deploy, []TestRunnerIface{runner}, nil, "")
+
+	var logged []string
+	engine.SetLogFunc(func(taskID, level, msg string) {
+		if taskID != devTaskID {
+			t.Errorf("log task ID = %q, want %q", taskID, devTaskID)
+		}
+		logged = append(logged, msg)
+	})
+
+	res, err := engine.DevCycle(context.Background(), "feature", []string{"main.go"})
+	if err != nil {
+		t.Fatalf("DevCycle: %v", err)
+	}
+	if deploy.deployCalls != 1 {
+		t.Errorf("deploy calls = %d, want 1", deploy.deployCalls)
+	}
+	if res.Passed || len(res.Tests) != 1 {
+		t.Fatalf("result = %+v, want one failing test", res)
+	}
+	found := false
+	for _, m := range logged {
+		if m == "boom" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("failing test output not logged: %v", logged)
+	}
+}
+
+func TestDevCycle_DeployFailureSkipsTests(t *testing.T) {
+	deploy := &mockDeploy{deploySuccess: false}
+	runner := &mockTestRunner{}
+	engine := NewEngine(testConfig(), nil, nil, deploy, []TestRunnerIface{runner}, nil, "")
+
+	res, err := engine.DevCycle(context.Background(), "feature", nil)
+	if err != nil {
+		t.Fatalf("DevCycle: %v", err)
+	}
+	if res.Passed || len(res.Tests) != 0 {
+		t.Errorf("result = %+v, want failed deploy without tests", res)
+	}
+	if runner.callIdx != 0 {
+		t.Errorf("tests ran after failed deploy")
+	}
+}
@@ -0,0 +1,184 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines UnifiedDiff shows around
+// each hunk.
+const diffContext = 3
+
+// maxDiffCells bounds the line-matching table; larger files are shown as a
+// whole-file replacement instead of a minimal diff.
+const maxDiffCells = 4_000_000
+
+// UnifiedDiff renders the change of one file from before to after in
+// unified diff format. Empty before means a new file, empty after a
+// deleted one.
+func UnifiedDiff(path, before, after string) string {
+	return UnifiedDiffContext(path, before, after, diffContext)
+}
+
+// UnifiedDiffContext is UnifiedDiff with context unchanged lines around
+// each hunk. A change of a binary file is shown as one line saying the
+// files differ, as git does.
+func UnifiedDiffContext(path, before, after string, context int) string {
+	if before == after {
+		return ""
+	}
+	context = max(context, 0)
+
+	oldName, newName := "a/"+path, "b/"+path
+	if before == "" {
+		oldName = "/dev/null"
+	}
+	if after == "" {
+		newName = "/dev/null"
+	}
+	if IsBinary(before) || IsBinary(after) {
+		return fmt.Sprintf("Binary files %s and %s differ\n", oldName, newName)
+	}
+
+	a, b := splitLines(before), splitLines(after)
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+	for _, h := range diffHunks(diffOps(a, b), context) {
+		out.WriteString(h)
+	}
+	return out.String()
+}
+
+// binarySniffLen is how much of a file IsBinary looks at.
+const binarySniffLen = 8000
+
+// IsBinary reports whether content looks like a binary file: it has a NUL
+// byte in its first 8000 bytes, as git decides.
+func IsBinary(content string) bool {
+	return strings.IndexByte(content[:min(len(content), binarySniffLen)], 0) >= 0
+}
+
+// ProposalDiff renders every change of a proposal as one unified diff.
+func ProposalDiff(p *Proposal) string {
+	return ProposalDiffContext(p, diffContext)
+}
+
+// ProposalDiffContext is ProposalDiff with context lines around each hunk.
+func ProposalDiffContext(p *Proposal, context int) string {
+	var out strings.Builder
+	for _, c := range p.Changes {
+		before, after := c.Before, c.After
+		if c.Action == "delete" {
+			after = ""
+		}
+		out.WriteString(UnifiedDiffContext(c.Path, before, after, context))
+	}
+	return out.String()
+}
+
+// diffOp is one line of an edit script: ' ' kept, '-' removed, '+' added.
+type diffOp struct {
+	kind     byte
+	line     string
+	old, new int // 1-based line numbers in a and b at this op
+}
+
+// diffOps computes a line edit script from a to b using a longest common
+// subsequence table.
+func diffOps(a, b []string) []diffOp {
+	if len(a)*len(b) > maxDiffCells {
+		ops := make([]diffOp, 0, len(a)+len(b))
+		for i, l := range a {
+			ops = append(ops, diffOp{'-', l, i + 1, 1})
+		}
+		for j, l := range b {
+			ops = append(ops, diffOp{'+', l, len(a) + 1, j + 1})
+		}
+		return ops
+	}
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i], i + 1, j + 1})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', a[i], i + 1, j + 1})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j], i + 1, j + 1})
+			j++
+		}
+	}
+	return ops
+}
+
+// diffHunks groups an edit script into unified diff hunks with context
+// lines around each.
+func diffHunks(ops []diffOp, context int) []string {
+	var hunks []string
+	for start := 0; start < len(ops); {
+		// Find the next change.
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		from := max(start-context, 0)
+
+		// Extend while changes are within 2*context of each other.
+		end, lastChange := start, start
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				lastChange = end
+			} else if end-lastChange > 2*context {
+				break
+			}
+			end++
+		}
+		to := min(lastChange+context+1, len(ops))
+
+		var body strings.Builder
+		oldCount, newCount := 0, 0
+		for _, op := range ops[from:to] {
+			body.WriteByte(op.kind)
+			body.WriteString(op.line)
+			body.WriteByte('\n')
+			if op.kind != '+' {
+				oldCount++
+			}
+			if op.kind != '-' {
+				newCount++
+			}
+		}
+		oldStart, newStart := ops[from].old, ops[from].new
+		if oldCount == 0 {
+			oldStart--
+		}
+		if newCount == 0 {
+			newStart--
+		}
+		hunks = append(hunks, fmt.Sprintf("@@ -%d,%d +%d,%d @@\n%s", oldStart, oldCount, newStart, newCount, body.String()))
+		start = to
+	}
+	return hunks
+}
@@ -0,0 +1,71 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	before := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	after := "a\nb\nC\nd\ne\nf\ng\nh\ni\nj\nk\n"
+	want := `--- a/f.txt
++++ b/f.txt
+@@ -1,6 +1,6 @@
+ a
+ b
+-c
++C
+ d
+ e
+ f
+@@ -8,3 +8,4 @@
+ h
+ i
+ j
++k
+`
+	if got := UnifiedDiff("f.txt", before, after); got != want {
+		t.Errorf("UnifiedDiff mismatch:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUnifiedDiffNewAndDeletedFile(t *testing.T) {
+	want := "--- /dev/null\n+++ b/new.go\n@@ -0,0 +1,2 @@\n+package x\n+\n"
+	if got := UnifiedDiff("new.go", "", "package x\n\n"); got != want {
+		t.Errorf("new file diff:\n%q\nwant:\n%q", got, want)
+	}
+	want = "--- a/old.go\n+++ /dev/null\n@@ -1,1 +0,0 @@\n-package x\n"
+	if got := UnifiedDiff("old.go", "package x\n", ""); got != want {
+		t.Errorf("deleted file diff:\n%q\nwant:\n%q", got, want)
+	}
+	if got := UnifiedDiff("same.go", "x\n", "x\n"); got != "" {
+		t.Errorf("expected no diff for identical content, got %q", got)
+	}
+}
+
+func TestUnifiedDiffContext(t *testing.T) {
+	before := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	after := "a\nb\nC\nd\ne\nf\ng\nh\ni\nj\nk\n"
+	want := "--- a/f.txt\n+++ b/f.txt\n@@ -3,1 +3,1 @@\n-c\n+C\n@@ -10,0 +11,1 @@\n+k\n"
+	if got := UnifiedDiffContext("f.txt", before, after, 0); got != want {
+		t.Errorf("zero context diff:\n%q\nwant:\n%q", got, want)
+	}
+	if got := UnifiedDiffContext("f.txt", before, after, 10); !strings.Contains(got, "@@ -1,10 +1,11 @@\n a\n") {
+		t.Errorf("wide context should make one hunk of the whole file, got:\n%s", got)
+	}
+}
+
+func TestUnifiedDiffBinary(t *testing.T) {
+	png := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+	if got, want := UnifiedDiff("logo.png", "", png), "Binary files /dev/null and b/logo.png differ\n"; got != want {
+		t.Errorf("binary diff = %q, want %q", got, want)
+	}
+	p := &Proposal{Changes: []ProposedChange{
+		{Path: "logo.png", Action: "modify", Before: png, After: png + "\x00"},
+		{Path: "main.go", Action: "delete", Before: "package main\n", After: "package main\n"},
+	}}
+	want := "Binary files a/logo.png and b/logo.png differ\n--- a/main.go\n+++ /dev/null\n@@ -1,1 +0,0 @@\n-package main\n"
+	if got := ProposalDiff(p); got != want {
+		t.Errorf("ProposalDiff:\n%q\nwant:\n%q", got, want)
+	}
+}
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// draftAdapter returns the git adapter's draft PR support when
+// workflow.draft_pr, or a label of task, asks for draft PRs.
+func (e *Engine) draftAdapter(task *Task) (DraftPRAdapter, bool) {
+	draft := e.cfg.Workflow.DraftPR
+	if task != nil && task.Settings != nil && task.Settings.DraftPR != nil {
+		draft = *task.Settings.DraftPR
+	}
+	if !draft {
+		return nil, false
+	}
+	d, ok := e.git.(DraftPRAdapter)
+	return d, ok
+}
+
+// prBodyWith renders the PR body as if current were already recorded as the
+// task's latest attempt. The engine appends attempts only once they finish.
+func (e *Engine) prBodyWith(task *Task, current *Attempt) string {
+	if current == nil {
+		return e.prBody(task)
+	}
+	snapshot := *task
+	snapshot.Attempts = append(append([]Attempt(nil), task.Attempts...), *current)
+	return e.prBody(&snapshot)
+}
+
+// openDraftPR opens a draft PR after the first commit. Failures are logged
+// and leave task.PR unset, so a regular PR is created at reporting instead.
+func (e *Engine) openDraftPR(ctx context.Context, task *Task, current *Attempt) {
+	drafts, ok := e.draftAdapter(task)
+	if !ok || task.PR != nil {
+		return
+	}
+	title := fmt.Sprintf("rig: %s", task.Issue.Title)
+	pr, err := drafts.CreateDraftPR(ctx, e.baseBranch(task), task.Branch, title, e.prBodyWith(task, current))
+	if err != nil {
+		e.taskLog(task.ID, "warn", fmt.Sprintf("Could not open draft PR: %v", err))
+		return
+	}
+	task.PR = &PullRequest{ID: strconv.Itoa(pr.Number), URL: pr.URL, Draft: true}
+	e.taskLog(task.ID, "info", fmt.Sprintf("Opened draft PR %s", pr.URL))
+	e.postIssueUpdate(ctx, task, IssueUpdatePR, "Opened draft PR "+pr.URL+"; it will be marked ready once tests pass.")
+}
+
+// syncDraftPR refreshes the draft PR body with the attempt history. Retry
+// commits land on the same branch, so the PR already shows the new code.
+func (e *Engine) syncDraftPR(ctx context.Context, task *Task, current *Attempt) {
+	drafts, ok := e.draftAdapter(task)
+	if !ok || task.PR == nil || !task.PR.Draft {
+		return
+	}
+	number, err := strconv.Atoi(task.PR.ID)
+	if err != nil {
+		return
+	}
+	if err := drafts.UpdatePR(ctx, number, e.prBodyWith(task, current)); err != nil {
+		e.taskLog(task.ID, "warn", fmt.Sprintf("Could not update draft PR: %v", err))
+	}
+}
+
+// publishPR creates the task's PR, or, when a draft is already open, updates
+// its body and marks it ready for review. The pre_pr hooks run first and
+// may rewrite the body or veto the PR; once it is published, routePR labels
+// it and requests reviewers and assignees.
+func (e *Engine) publishPR(ctx context.Context, task *Task) (*PullRequest, error) {
+	hookPR := &HookPR{Title: fmt.Sprintf("rig: %s", task.Issue.Title), Body: e.prBody(task)}
+	if err := e.runHooks(ctx, task, &HookInput{Hook: HookPrePR, Vars: e.buildVars(task), PR: hookPR}); err != nil {
+		return nil, err
+	}
+
+	drafts, ok := e.draftAdapter(task)
+	if !ok || task.PR == nil || !task.PR.Draft {
+		if pr := e.findInterruptedPR(ctx, task); pr != nil {
+			e.routePR(ctx, task, pr)
+			return pr, nil
+		}
+		pr, err := stepCreatePR(ctx, e.git, e.baseBranch(task), task.Branch, task.Issue.Title, hookPR.Body)
+		if err == nil {
+			e.routePR(ctx, task, pr)
+			e.postIssueUpdate(ctx, task, IssueUpdatePR, "Opened PR "+pr.URL)
+		}
+		return pr, err
+	}
+
+	number, err := strconv.Atoi(task.PR.ID)
+	if err != nil {
+		return nil, fmt.Errorf("draft PR id %q: %w", task.PR.ID, err)
+	}
+	if err := drafts.UpdatePR(ctx, number, hookPR.Body); err != nil {
+		return nil, fmt.Errorf("update PR: %w", err)
+	}
+	if err := drafts.MarkReady(ctx, number); err != nil {
+		return nil, fmt.Errorf("mark PR ready: %w", err)
+	}
+	e.routePR(ctx, task, task.PR)
+	e.postIssueUpdate(ctx, task, IssueUpdatePR, "Tests passed; PR "+task.PR.URL+" is ready for review.")
+	return &PullRequest{ID: task.PR.ID, URL: task.PR.URL}, nil
+}
@@ -0,0 +1,103 @@
+package core
+
+import (
+	"context"
+	"testing"
+)
+
+// draftGit is a mockGit that supports draft pull requests.
+type draftGit struct {
+	mockGit
+	drafts  int
+	updates []string
+	ready   []int
+}
+
+func (m *draftGit) CreateDraftPR(ctx context.Context, base, head, title, body string) (*GitPullRequest, error) {
+	m.drafts++
+	return &GitPullRequest{Number: 7, URL: "https://github.com/test/repo/pull/7", Title: title}, nil
+}
+
+func (m *draftGit) UpdatePR(ctx context.Context, number int, body string) error {
+	m.updates = append(m.updates, body)
+	return nil
+}
+
+func (m *draftGit) MarkReady(ctx context.Context, number int) error {
+	m.ready = append(m.ready, number)
+	return nil
+}
+
+func TestEngine_DraftPRMarkedReadyWhenTestsPass(t *testing.T) {
+	cfg := testConfig()
+	cfg.Workflow.DraftPR = true
+	gitMock := &draftGit{}
+	statePath := tempStatePath(t)
+	engine := NewEngine(cfg, gitMock, &mockAI{}, &mockDeploy{deploySuccess: true},
+		[]TestRunnerIface{&mockTestRunner{}}, nil, statePath)
+
+	if err := engine.Execute(context.Background(), testIssue()); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+
+	if gitMock.drafts != 1 || gitMock.createPRCalls != 0 {
+		t.Fatalf("expected one draft and no regular PR, got %d drafts, %d PRs", gitMock.drafts, gitMock.createPRCalls)
+	}
+	if len(gitMock.ready) != 1 || gitMock.ready[0] != 7 {
+		t.Fatalf("expected PR #7 marked ready, got %v", gitMock.ready)
+	}
+	if len(gitMock.updates) == 0 {
+		t.Fatal("expected the final PR body to be pushed")
+	}
+
+	state, err := LoadState(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr := state.Tasks[0].PR; pr == nil || pr.ID != "7" || pr.Draft {
+		t.Fatalf("expected ready PR #7 on task, got %+v", pr)
+	}
+}
+
+func TestEngine_DraftPRDisabledUsesRegularPR(t *testing.T) {
+	gitMock := &draftGit{}
+	engine := NewEngine(testConfig(), gitMock, &mockAI{}, &mockDeploy{deploySuccess: true},
+		[]TestRunnerIface{&mockTestRunner{}}, nil, tempStatePath(t))
+
+	if err := engine.Execute(context.Background(), testIssue()); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if gitMock.drafts != 0 || gitMock.createPRCalls != 1 {
+		t.Fatalf("expected a regular PR, got %d drafts, %d PRs", gitMock.drafts, gitMock.createPRCalls)
+	}
+}
+
+func TestEngine_DraftPRUpdatedOnRetryAndNeverReadyOnFailure(t *testing.T) {
+	cfg := testConfig()
+	cfg.Workflow.DraftPR = true
+	cfg.AI.MaxRetry = 2
+	gitMock := &draftGit{}
+	testRunner := &mockTestRunner{results: []*TestResult{
+		{Name: "unit-test", Type: "command", Passed: false, Output: "FAIL 1"},
+		{Name: "unit-test", Type: "command", Passed: false, Output: "FAIL 2"},
+		{Name: "unit-test", Type: "command", Passed: false, Output: "FAIL 3"},
+	}}
+	engine := NewEngine(cfg, gitMock, &mockAI{}, &mockDeploy{deploySuccess: true},
+		[]TestRunnerIface{testRunner}, nil, tempStatePath(t))
+
+	if err := engine.Execute(context.Background(), testIssue()); err == nil {
+		t.Fatal("expected failure after max retries")
+	}
+	if gitMock.drafts != 1 || gitMock.createPRCalls != 0 {
+		t.Fatalf("expected a single draft PR, got %d drafts, %d PRs", gitMock.drafts, gitMock.createPRCalls)
+	}
+	if len(gitMock.updates) == 0 {
+		t.Fatal("expected retry commits to refresh the draft PR body")
+	}
+	if len(gitMock.ready) != 0 {
+		t.Fatalf("failed task must not mark the PR ready, got %v", gitMock.ready)
+	}
+	if len(gitMock.cleanedBranches) != 0 {
+		t.Fatalf("draft PR branch must not be deleted, cleaned %v", gitMock.cleanedBranches)
+	}
+}
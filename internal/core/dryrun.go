@@ -0,0 +1,180 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DryRunOutput is what a dry run planned and would have committed.
+type DryRunOutput struct {
+	Plan *AIPlan `json:"plan"`
+	// Diff is the generated code as a unified diff against the workspace.
+	Diff string `json:"diff,omitempty"`
+	// Dir is where plan.md and changes.diff were written.
+	Dir string `json:"dir,omitempty"`
+}
+
+// Dry run artifact file names.
+const (
+	DryRunPlanFile = "plan.md"
+	DryRunDiffFile = "changes.diff"
+)
+
+// DryRunDir is where the engine writes the artifacts of a dry run of a
+// task unless SetDryRunDir picks another directory.
+func DryRunDir(statePath, taskID string) string {
+	return filepath.Join(filepath.Dir(statePath), "dry-run", taskID)
+}
+
+// SetDryRunDir makes dry runs write their plan and diff to dir instead of
+// DryRunDir.
+func (e *Engine) SetDryRunDir(dir string) {
+	e.dryRunDir = dir
+}
+
+// dryRunTask plans and generates code for task without creating branches,
+// committing, deploying or reporting. The repository is only cloned or
+// pulled for context. The plan and diff are written to the dry run
+// directory and recorded on the task, which ends in PhaseDryRun for review.
+func (e *Engine) dryRunTask(ctx context.Context, state *State, task *Task) error {
+	ctx, cancel := e.withTaskTimeout(ctx)
+	defer cancel()
+
+	if err := Transition(task, PhasePlanning); err != nil {
+		return e.failDryRun(state, task, nil, ReasonInfra, err)
+	}
+	task.AddPipelineStep(PhasePlanning, "running")
+	planCtx, cancelPlan := e.withPhaseTimeout(ctx, PhasePlanning)
+	aiIssue := e.loadIssueThread(planCtx, task)
+	e.taskLog(task.ID, "info", "Dry run: analyzing issue with AI...")
+	ai, _ := e.taskAI(task)
+	plan, err := stepAnalyze(e.withAgents(planCtx, task), ai, aiIssue, strings.Join(e.cfg.AI.Context, "\n"))
+	cancelPlan()
+	if err != nil {
+		err = timeoutCause(planCtx, err)
+		task.CompletePipelineStep(PhasePlanning, "failed", "", err.Error())
+		return e.failDryRun(state, task, nil, reasonFor(err, ReasonAI), err)
+	}
+	e.taskLog(task.ID, "info", fmt.Sprintf("Plan: %s", plan.Summary))
+	task.CompletePipelineStep(PhasePlanning, "success", plan.Summary, "")
+
+	codeCtx, cancelCode := e.withPhaseTimeout(ctx, PhaseCoding)
+	defer cancelCode()
+	// The workspace gives the AI its context and the diff its base; without
+	// it files are generated and shown as new.
+	var workspace string
+	var repoFiles map[string]string
+	owner, repo := parseRepo(e.cfg.Source.Repo)
+	if err := e.git.CloneOrPull(codeCtx, owner, repo, e.cfg.Source.Token); err != nil {
+		e.taskLog(task.ID, "warn", fmt.Sprintf("Dry run: clone failed, generating without repo context: %v", err))
+	} else if wp, ok := e.git.(WorkspaceProvider); ok {
+		workspace = wp.GetWorkspace()
+		repoFiles = e.contextFiles(codeCtx, task.ID, workspace, planQuery(&task.Issue, plan))
+	}
+
+	if err := Transition(task, PhaseCoding); err != nil {
+		return e.failDryRun(state, task, nil, ReasonInfra, err)
+	}
+	task.AddPipelineStep(PhaseCoding, "running")
+	attempt := newAttempt(1)
+	attempt.Plan = plan.Summary
+	_, attempt.Model = e.taskAI(task)
+	e.taskLog(task.ID, "info", "Dry run: generating code with AI...")
+	changes, err := e.generate(e.withAgents(codeCtx, task), task, &attempt, plan, repoFiles)
+	if err == nil {
+		changes, err = e.screenChanges(task, changes)
+	}
+	if err != nil {
+		err = timeoutCause(codeCtx, err)
+		task.CompletePipelineStep(PhaseCoding, "failed", "", err.Error())
+		return e.failDryRun(state, task, &attempt, reasonFor(err, ReasonAI), err)
+	}
+	for _, c := range changes {
+		attempt.FilesChanged = append(attempt.FilesChanged, c.Path)
+	}
+	attempt.Provider, attempt.Model = e.answeredBy(ctx, attempt.Model)
+	if err := e.enforcePolicies(task, changes); err != nil {
+		task.CompletePipelineStep(PhaseCoding, "failed", "", err.Error())
+		return e.failDryRun(state, task, &attempt, ReasonConfig, err)
+	}
+	summary := fmt.Sprintf("generated %d file(s): %s", len(changes), strings.Join(attempt.FilesChanged, ", "))
+	task.CompletePipelineStep(PhaseCoding, "success", summary, "")
+	completeAttempt(&attempt, "success", "")
+	task.Attempts = append(task.Attempts, attempt)
+
+	out := &DryRunOutput{Plan: plan, Diff: dryRunDiff(workspace, changes)}
+	dir := e.dryRunDir
+	if dir == "" {
+		dir = DryRunDir(e.statePath, task.ID)
+	}
+	if err := writeDryRun(dir, out); err != nil {
+		e.taskLog(task.ID, "warn", fmt.Sprintf("Dry run: writing artifacts failed: %v", err))
+	} else {
+		out.Dir = dir
+		e.taskLog(task.ID, "info", fmt.Sprintf("Dry run: plan and diff written to %s", dir))
+	}
+	task.DryRun = out
+
+	if err := Transition(task, PhaseDryRun); err != nil {
+		return e.failDryRun(state, task, nil, ReasonInfra, err)
+	}
+	task.AddPipelineStep(PhaseDryRun, "running")
+	task.CompletePipelineStep(PhaseDryRun, "success", summary, "")
+	if err := mergeState(state, e.statePath); err != nil {
+		return fmt.Errorf("save state: %w", err)
+	}
+	return nil
+}
+
+// failDryRun fails a dry run task. Unlike failTask it has nothing to clean
+// up, roll back or notify.
+func (e *Engine) failDryRun(state *State, task *Task, attempt *Attempt, reason FailReason, cause error) error {
+	e.taskLog(task.ID, "error", fmt.Sprintf("Dry run failed: %v (reason: %s)", cause, reason))
+	if attempt != nil {
+		completeAttempt(attempt, "failed", reason)
+		task.Attempts = append(task.Attempts, *attempt)
+	}
+	task.AddPipelineStep(PhaseFailed, "running")
+	if err := Transition(task, PhaseFailed); err != nil {
+		task.CompletePipelineStep(PhaseFailed, "failed", "", err.Error())
+	} else {
+		task.CompletePipelineStep(PhaseFailed, "success", cause.Error(), "")
+	}
+	if err := mergeState(state, e.statePath); err != nil {
+		return fmt.Errorf("save state: %w", err)
+	}
+	return fmt.Errorf("task %s failed at %s: %w", task.ID, reason, cause)
+}
+
+// dryRunDiff renders changes as one unified diff against the files of
+// workspace, if any.
+func dryRunDiff(workspace string, changes []AIFileChange) string {
+	var out strings.Builder
+	for _, c := range changes {
+		var before string
+		if workspace != "" && filepath.IsLocal(filepath.FromSlash(c.Path)) {
+			if data, err := os.ReadFile(filepath.Join(workspace, filepath.FromSlash(c.Path))); err == nil {
+				before = string(data)
+			}
+		}
+		after := c.Content
+		if c.Action == "delete" {
+			after = ""
+		}
+		out.WriteString(UnifiedDiff(c.Path, before, after))
+	}
+	return out.String()
+}
+
+func writeDryRun(dir string, out *DryRunOutput) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, DryRunPlanFile), []byte(FormatPlan(out.Plan)), 0o644); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, DryRunDiffFile), []byte(out.Diff), 0o644)
+}
@@ -0,0 +1,83 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExecute_DryRunWritesPlanAndDiff(t *testing.T) {
+	workspace := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workspace, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	git := &workspaceGit{workspace: workspace}
+	ai := &mockAI{
+		analyzeFunc: func(ctx context.Context, issue *AIIssue, projectCtx string) (*AIPlan, error) {
+			return &AIPlan{Summary: "Print a greeting", Steps: []string{"Edit main"}}, nil
+		},
+		generateFunc: func(ctx context.Context, plan *AIPlan, repoFiles map[string]string) ([]AIFileChange, error) {
+			return []AIFileChange{
+				{Path: "main.go", Content: "package main\n\nfunc main() { println(\"hi\") }\n", Action: "modify"},
+				{Path: "README.md", Content: "# app\n", Action: "create"},
+			}, nil
+		},
+	}
+	deploy := &mockDeploy{deploySuccess: true}
+	statePath := tempStatePath(t)
+	e := NewEngine(testConfig(), git, ai, deploy, nil, nil, statePath)
+	e.SetDryRun(true)
+
+	if err := e.Execute(context.Background(), testIssue()); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if git.createBranchCalls != 0 || git.commitAndPushCalls != 0 || git.createPRCalls != 0 || deploy.deployCalls != 0 {
+		t.Fatalf("dry run had side effects: git %+v, %d deploys", git.mockGit, deploy.deployCalls)
+	}
+
+	state, _ := LoadState(statePath)
+	task := state.Tasks[0]
+	if task.Status != PhaseDryRun || task.DryRun == nil {
+		t.Fatalf("task = %+v, want a dry_run task", task)
+	}
+	if len(task.Attempts) != 1 || task.Attempts[0].Status != "success" || len(task.Attempts[0].FilesChanged) != 2 {
+		t.Errorf("attempts = %+v", task.Attempts)
+	}
+	diff := task.DryRun.Diff
+	for _, want := range []string{"--- a/main.go", "-func main() {}", "+func main() { println(\"hi\") }", "--- /dev/null\n+++ b/README.md"} {
+		if !strings.Contains(diff, want) {
+			t.Errorf("diff lacks %q:\n%s", want, diff)
+		}
+	}
+
+	dir := DryRunDir(statePath, task.ID)
+	if task.DryRun.Dir != dir {
+		t.Errorf("dir = %q, want %q", task.DryRun.Dir, dir)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, DryRunDiffFile)); string(data) != diff {
+		t.Errorf("%s = %q, want the task's diff", DryRunDiffFile, data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, DryRunPlanFile)); !strings.Contains(string(data), "1. Edit main") {
+		t.Errorf("%s = %q", DryRunPlanFile, data)
+	}
+}
+
+func TestExecute_DryRunFailure(t *testing.T) {
+	ai := &mockAI{analyzeFunc: func(ctx context.Context, issue *AIIssue, projectCtx string) (*AIPlan, error) {
+		return nil, errors.New("model overloaded")
+	}}
+	statePath := tempStatePath(t)
+	e := NewEngine(testConfig(), &mockGit{}, ai, &mockDeploy{deploySuccess: true}, nil, nil, statePath)
+	e.SetDryRun(true)
+
+	if err := e.Execute(context.Background(), testIssue()); err == nil || !strings.Contains(err.Error(), "model overloaded") {
+		t.Fatalf("Execute: %v, want the AI error", err)
+	}
+	state, _ := LoadState(statePath)
+	if task := state.Tasks[0]; task.Status != PhaseFailed || task.DryRun != nil {
+		t.Errorf("task = %+v, want a failed task without dry run output", task)
+	}
+}
@@ -5,14 +5,17 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/ignore"
+	"github.com/rigdev/rig/internal/logging"
 )
 
 // WorkspaceProvider allows retrieving the git workspace path.
@@ -32,16 +35,46 @@ type LogFunc func(taskID, level, message string)
 
 // Engine orchestrates the full execution cycle: issue -> code -> deploy -> test -> PR.
 type Engine struct {
-	cfg         *config.Config
-	git         GitAdapter
-	ai          AIAdapter
-	deploy      DeployAdapterIface
-	testRunners []TestRunnerIface
-	testConfigs []config.TestConfig
-	notifiers   []NotifierIface
-	statePath   string
-	dryRun      bool
-	logFn       LogFunc
+	cfg          *config.Config
+	git          GitAdapter
+	ai           AIAdapter
+	deploy       DeployAdapterIface
+	testRunners  []TestRunnerIface
+	testConfigs  []config.TestConfig
+	notifiers    []NotifierIface
+	statePath    string
+	dryRun       bool
+	dryRunDir    string
+	logger       *slog.Logger
+	logFn        LogFunc
+	logFlushFn   func() error
+	retriever    FileRetriever
+	fixMemory    FixMemory
+	agents       AgentsStore
+	formatter    Formatter
+	hooks        HookRunner
+	events       EventPublisher
+	artifacts    ArtifactStore
+	issueMatcher IssueMatcher
+
+	environments []Environment
+
+	logCtxMu sync.Mutex
+	logCtx   map[string]taskLogContext // current phase and attempt per task
+
+	bundleMu   sync.Mutex
+	bundleLogs map[string][]BundleLog // recent lines per task for failure bundles
+}
+
+// runnableTestTypes are test types backed by a TestRunnerIface. The engine
+// keeps their configs index-aligned with the injected test runners.
+var runnableTestTypes = map[string]bool{
+	"":         true,
+	"command":  true,
+	"http":     true,
+	"browser":  true,
+	"coverage": true,
+	"plugin":   true,
 }
 
 // NewEngine creates a new Engine with all adapter dependencies injected.
@@ -54,10 +87,10 @@ func NewEngine(
 	notifiers []NotifierIface,
 	statePath string,
 ) *Engine {
-	commandTests := make([]config.TestConfig, 0, len(cfg.Test))
+	runnableTests := make([]config.TestConfig, 0, len(cfg.Test))
 	for _, testCfg := range cfg.Test {
-		if testCfg.Type == "" || testCfg.Type == "command" {
-			commandTests = append(commandTests, testCfg)
+		if runnableTestTypes[testCfg.Type] {
+			runnableTests = append(runnableTests, testCfg)
 		}
 	}
 
@@ -67,33 +100,103 @@ func NewEngine(
 		ai:          ai,
 		deploy:      deploy,
 		testRunners: testRunners,
-		testConfigs: commandTests,
+		testConfigs: runnableTests,
 		notifiers:   notifiers,
 		statePath:   statePath,
 	}
 }
 
-// SetDryRun enables dry-run mode (no state mutation, no real execution).
 // SetLogFunc sets an optional per-task log callback.
 func (e *Engine) SetLogFunc(fn LogFunc) {
 	e.logFn = fn
 }
 
-// taskLog logs a message both to stdout and to the optional log callback.
+// AddNotifier adds a notifier after construction, e.g. a terminal progress
+// printer for interactive runs.
+func (e *Engine) AddNotifier(n NotifierIface) {
+	e.notifiers = append(e.notifiers, n)
+}
+
+// testRunOptions returns the test scheduling settings from workflow config.
+func (e *Engine) testRunOptions() testRunOptions {
+	return testRunOptions{
+		concurrency: e.cfg.Workflow.TestConcurrency,
+		timeout:     e.cfg.Workflow.TestTimeout,
+		reruns:      e.cfg.Workflow.RetryPolicy.FlakyReruns,
+		backoff:     e.cfg.Workflow.RetryPolicy.Backoff,
+		maxBackoff:  e.cfg.Workflow.RetryPolicy.MaxBackoff,
+	}
+}
+
+// SetLogFlusher sets an optional callback invoked when a task stops, in a
+// terminal phase, awaiting approval or checkpointed by a shutdown, so a
+// buffered log writer persists its lines. Between phases the writer keeps
+// batching off the engine goroutine.
+func (e *Engine) SetLogFlusher(fn func() error) {
+	e.logFlushFn = fn
+}
+
+// SetLogger replaces the structured logger; by default the engine logs to
+// slog.Default().
+func (e *Engine) SetLogger(l *slog.Logger) {
+	e.logger = l
+}
+
+func (e *Engine) log() *slog.Logger {
+	if e.logger != nil {
+		return e.logger
+	}
+	return slog.Default()
+}
+
+// taskLogContext is the phase and attempt a task's log lines are tagged with.
+type taskLogContext struct {
+	phase   TaskPhase
+	attempt int
+}
+
+// setLogContext records the phase a task entered; the attempt in progress
+// is the one after the last recorded attempt.
+func (e *Engine) setLogContext(task *Task, phase TaskPhase) {
+	e.logCtxMu.Lock()
+	defer e.logCtxMu.Unlock()
+	if e.logCtx == nil {
+		e.logCtx = make(map[string]taskLogContext)
+	}
+	e.logCtx[task.ID] = taskLogContext{phase: phase, attempt: len(task.Attempts) + 1}
+}
+
+// taskLog logs a message with the task's ID, phase and attempt to the
+// structured logger, the failure bundle and the optional log callback.
 func (e *Engine) taskLog(taskID, level, msg string) {
-	log.Printf("[engine] [%s] %s", level, msg)
+	attrs := []any{logging.TaskKey, taskID}
+	e.logCtxMu.Lock()
+	lc, ok := e.logCtx[taskID]
+	e.logCtxMu.Unlock()
+	if ok {
+		attrs = append(attrs, "phase", string(lc.phase), "attempt", lc.attempt)
+	}
+	lvl, _ := logging.ParseLevel(level)
+	e.log().Log(context.Background(), lvl, msg, attrs...)
+	e.recordBundleLog(taskID, level, msg)
 	if e.logFn != nil {
 		e.logFn(taskID, level, msg)
 	}
 }
 
+// SetDryRun enables dry-run mode: Execute only plans and generates code,
+// and records the task in PhaseDryRun for review.
 func (e *Engine) SetDryRun(dryRun bool) {
 	e.dryRun = dryRun
 }
 
-// isStepEnabled checks if a workflow step is enabled in config.
-// If no steps are configured, all steps are enabled (backward compatibility).
-func (e *Engine) isStepEnabled(step string) bool {
+// isStepEnabled checks if a workflow step is enabled in config and not
+// skipped by the labels of task. If no steps are configured, all steps are
+// enabled (backward compatibility).
+func (e *Engine) isStepEnabled(task *Task, step string) bool {
+	if task.skipsStep(step) {
+		return false
+	}
 	if len(e.cfg.Workflow.Steps) == 0 {
 		return true
 	}
@@ -107,28 +210,65 @@ func (e *Engine) isStepEnabled(step string) bool {
 
 // Execute runs the execution cycle for the given issue.
 func (e *Engine) Execute(ctx context.Context, issue Issue) error {
-	log.Printf("[engine] starting execution for issue %s: %s", issue.ID, issue.Title)
+	e.log().Info("starting execution", "issue", issue.ID, "title", issue.Title)
+
+	lock, err := AcquireExecutionLock(e.statePath, issue)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
 
-	state, err := LoadState(e.statePath)
+	state, err := loadTrackedState(e.statePath)
 	if err != nil {
 		return fmt.Errorf("load state: %w", err)
 	}
 
-	task := state.CreateTask(issue)
+	if dup, similarity := e.findDuplicate(ctx, state, issue); dup != nil {
+		return e.recordDuplicate(ctx, dup, issue, similarity)
+	}
+
+	task, err := state.addTask(e.statePath, issue)
+	if err != nil {
+		return err
+	}
+	return e.startTask(ctx, state, task)
+}
+
+// startTask runs the pipeline of task, just created in state for its issue
+// or as a part of an epic.
+func (e *Engine) startTask(ctx context.Context, state *State, task *Task) error {
+	issue := task.Issue
+	ctx = WithTaskUsage(ctx, task)
+	e.bindWorkspace(task.ID)
 	e.taskLog(task.ID, "info", fmt.Sprintf("Task created for issue #%s: %s", issue.ID, issue.Title))
+	env, envErr := e.selectEnvironment(issue)
+	task.Environment = env
+	if env != "" {
+		e.taskLog(task.ID, "info", fmt.Sprintf("Deploying to environment %s", env))
+	}
+	task.Settings = e.labelSettings(issue)
+	e.logLabelSettings(task)
 	task.AddPipelineStep(PhaseQueued, "running")
 	e.notifyPhase(ctx, task, PhaseQueued)
 	task.CompletePipelineStep(PhaseQueued, "success", "task queued", "")
 
 	if e.dryRun {
-		log.Printf("[engine] dry-run mode: skipping execution for task %s", task.ID)
-		return nil
+		e.log().Info("dry-run mode: planning and generating only", logging.TaskKey, task.ID)
+		return e.dryRunTask(ctx, state, task)
 	}
 
-	if err := SaveState(state, e.statePath); err != nil {
+	if err := mergeState(state, e.statePath); err != nil {
 		return fmt.Errorf("save state: %w", err)
 	}
+	if envErr != nil {
+		return e.failTask(ctx, state, task, ReasonConfig, envErr)
+	}
+	if items := e.epicItems(task); items != nil {
+		return e.executeEpic(ctx, state, task, items)
+	}
 
+	ctx, cancel := e.withTaskTimeout(ctx)
+	defer cancel()
 	vars := e.buildVars(task)
 
 	if err := Transition(task, PhasePlanning); err != nil {
@@ -137,34 +277,55 @@ func (e *Engine) Execute(ctx context.Context, issue Issue) error {
 	task.AddPipelineStep(PhasePlanning, "running")
 	e.notifyPhase(ctx, task, PhasePlanning)
 
-	aiIssue := &AIIssue{
-		Title: issue.Title,
-		Body:  issue.Body,
-		URL:   issue.URL,
-	}
+	planCtx, cancelPlan := e.withPhaseTimeout(ctx, PhasePlanning)
+	aiIssue := e.loadIssueThread(planCtx, task)
 	projectCtx := strings.Join(e.cfg.AI.Context, "\n")
 	e.taskLog(task.ID, "info", "Analyzing issue with AI...")
-	plan, err := stepAnalyze(ctx, e.ai, aiIssue, projectCtx)
+	span := e.journalBegin(ctx, task, StepPlan, hashStepInput(aiIssue))
+	ai, _ := e.taskAI(task)
+	plan, err := stepAnalyze(e.withAgents(planCtx, task), ai, aiIssue, projectCtx)
+	span.end(err)
+	cancelPlan()
 	if err != nil {
+		err = timeoutCause(planCtx, err)
 		e.taskLog(task.ID, "error", fmt.Sprintf("Planning failed: %v", err))
 		task.CompletePipelineStep(PhasePlanning, "failed", "", err.Error())
-		return e.failTask(ctx, state, task, ReasonAI, err)
+		return e.failTask(ctx, state, task, reasonFor(err, ReasonAI), err)
 	}
 	e.taskLog(task.ID, "info", fmt.Sprintf("Plan: %s", plan.Summary))
 	task.CompletePipelineStep(PhasePlanning, "success", plan.Summary, "")
+	e.postIssueUpdate(ctx, task, IssueUpdatePlan, planUpdate(plan))
+
+	if e.cfg.Workflow.Approval.AfterPlanning {
+		return e.awaitPlanReview(ctx, state, task, plan)
+	}
+	return e.executePlan(ctx, state, task, vars, plan)
+}
 
+// executePlan codes, commits, deploys and tests plan, retrying on test
+// failures, and completes or fails task.
+func (e *Engine) executePlan(ctx context.Context, state *State, task *Task, vars map[string]string, plan *AIPlan) error {
 	// Clone or pull the repo early so we can provide files as AI context.
+	// The coding timeout covers the clone as well as code generation.
+	codeCtx, cancelCode := e.withPhaseTimeout(ctx, PhaseCoding)
+	defer cancelCode()
 	e.taskLog(task.ID, "info", "Cloning repository...")
 	owner, repo := parseRepo(e.cfg.Source.Repo)
-	if err := e.git.CloneOrPull(ctx, owner, repo, e.cfg.Source.Token); err != nil {
+	if err := e.git.CloneOrPull(codeCtx, owner, repo, e.cfg.Source.Token); err != nil {
+		err = timeoutCause(codeCtx, err)
 		e.taskLog(task.ID, "error", fmt.Sprintf("Clone failed: %v", err))
-		return e.failTask(ctx, state, task, ReasonGit, err)
+		return e.failTask(ctx, state, task, reasonFor(err, ReasonGit), err)
+	}
+	if err := e.checkoutBase(codeCtx, task); err != nil {
+		err = timeoutCause(codeCtx, err)
+		e.taskLog(task.ID, "error", err.Error())
+		return e.failTask(ctx, state, task, reasonFor(err, ReasonGit), err)
 	}
 
 	// Load repo files for AI context.
 	var repoFiles map[string]string
 	if wp, ok := e.git.(WorkspaceProvider); ok {
-		repoFiles = loadRepoFiles(wp.GetWorkspace(), 50, 100*1024)
+		repoFiles = e.contextFiles(codeCtx, task.ID, wp.GetWorkspace(), planQuery(&task.Issue, plan))
 		e.taskLog(task.ID, "info", fmt.Sprintf("Loaded %d repo files for AI context", len(repoFiles)))
 	}
 
@@ -176,21 +337,30 @@ func (e *Engine) Execute(ctx context.Context, issue Issue) error {
 
 	attempt := newAttempt(1)
 	attempt.Plan = plan.Summary
+	_, attempt.Model = e.taskAI(task)
 
 	e.taskLog(task.ID, "info", "Generating code with AI...")
-	changes, err := stepGenerate(ctx, e.ai, plan, repoFiles)
+	span := e.journalBegin(ctx, task, StepCode, hashStepInput(plan))
+	changes, err := e.generate(e.withAgents(codeCtx, task), task, &attempt, plan, repoFiles)
+	if err == nil {
+		changes, err = e.screenChanges(task, changes)
+	}
+	span.end(err)
+	cancelCode()
 	if err != nil {
+		err = timeoutCause(codeCtx, err)
 		e.taskLog(task.ID, "error", fmt.Sprintf("Code generation failed: %v", err))
 		task.CompletePipelineStep(PhaseCoding, "failed", "", err.Error())
-		completeAttempt(&attempt, "failed", ReasonAI)
+		completeAttempt(&attempt, "failed", reasonFor(err, ReasonAI))
 		task.Attempts = append(task.Attempts, attempt)
-		return e.failTask(ctx, state, task, ReasonAI, err)
+		return e.failTask(ctx, state, task, reasonFor(err, ReasonAI), err)
 	}
 	filesChanged := make([]string, len(changes))
 	for i, c := range changes {
 		filesChanged[i] = c.Path
 	}
 	attempt.FilesChanged = filesChanged
+	attempt.Provider, attempt.Model = e.answeredBy(ctx, attempt.Model)
 	if err := e.enforcePolicies(task, changes); err != nil {
 		e.taskLog(task.ID, "error", fmt.Sprintf("Policy blocked task: %v", err))
 		task.CompletePipelineStep(PhaseCoding, "failed", "", err.Error())
@@ -209,26 +379,41 @@ func (e *Engine) Execute(ctx context.Context, issue Issue) error {
 	task.AddPipelineStep(PhaseCommitting, "running")
 	e.notifyPhase(ctx, task, PhaseCommitting)
 
+	changes = e.formatChanges(ctx, task, e.addLicenseHeaders(task, changes))
+	if err := e.runHooks(ctx, task, &HookInput{Hook: HookPreCommit, Vars: vars, Changes: changes}); err != nil {
+		task.CompletePipelineStep(PhaseCommitting, "failed", "", err.Error())
+		completeAttempt(&attempt, "failed", reasonFor(err, ReasonConfig))
+		task.Attempts = append(task.Attempts, attempt)
+		return e.failTask(ctx, state, task, reasonFor(err, ReasonConfig), err)
+	}
 	e.taskLog(task.ID, "info", fmt.Sprintf("Creating branch %s and committing...", task.Branch))
-	commitSHA, err := stepCommit(ctx, e.git, task.Branch, changes, task.Issue.Title)
+	task.RecordBranch(task.Branch)
+	commitCtx, cancelCommit := e.withPhaseTimeout(ctx, PhaseCommitting)
+	span = e.journalBegin(ctx, task, StepCommit, hashStepInput(changes))
+	commitSHA, err := stepCommit(commitCtx, e.git, task.Branch, e.commitGroups(task, plan, changes))
+	span.end(err)
+	cancelCommit()
 	if err != nil {
+		err = timeoutCause(commitCtx, err)
 		e.taskLog(task.ID, "error", fmt.Sprintf("Commit failed: %v", err))
 		task.CompletePipelineStep(PhaseCommitting, "failed", "", err.Error())
-		completeAttempt(&attempt, "failed", ReasonGit)
+		completeAttempt(&attempt, "failed", reasonFor(err, ReasonGit))
 		task.Attempts = append(task.Attempts, attempt)
-		return e.failTask(ctx, state, task, ReasonGit, err)
+		return e.failTask(ctx, state, task, reasonFor(err, ReasonGit), err)
 	}
 	e.taskLog(task.ID, "info", fmt.Sprintf("Committed: %s", commitSHA))
 	task.CompletePipelineStep(PhaseCommitting, "success", "changes committed", "")
+	e.openDraftPR(ctx, task, &attempt)
 	vars["COMMIT_SHA"] = commitSHA
 
-	// Skip deploy/test if not in workflow.steps.
-	if !e.isStepEnabled("deploy") {
-		e.taskLog(task.ID, "info", "Skipping deploy step (not in workflow.steps)")
+	// Skip deploy/test if not in workflow.steps or skipped by a label.
+	if !e.isStepEnabled(task, "deploy") {
+		cause := skipCause(task, "deploy")
+		e.taskLog(task.ID, "info", "Skipping deploy step ("+cause+")")
 		task.AddPipelineStep(PhaseApproval, "running")
 		task.CompletePipelineStep(PhaseApproval, "skipped", "deploy step disabled", "")
 		task.AddPipelineStep(PhaseDeploying, "running")
-		task.CompletePipelineStep(PhaseDeploying, "skipped", "deploy step disabled in workflow config", "")
+		task.CompletePipelineStep(PhaseDeploying, "skipped", "deploy step disabled ("+cause+")", "")
 		task.AddPipelineStep(PhaseTesting, "running")
 		task.CompletePipelineStep(PhaseTesting, "skipped", "test step disabled (no deploy)", "")
 
@@ -238,7 +423,7 @@ func (e *Engine) Execute(ctx context.Context, issue Issue) error {
 	}
 
 	// Check if before_deploy approval is required.
-	if e.cfg.Workflow.Approval.BeforeDeploy {
+	if e.needsDeployApproval(task) {
 		task.AddPipelineStep(PhaseApproval, "running")
 		e.notifyPhase(ctx, task, PhaseApproval)
 
@@ -253,9 +438,10 @@ func (e *Engine) Execute(ctx context.Context, issue Issue) error {
 			task.Attempts = append(task.Attempts, attempt)
 			return e.failTask(ctx, state, task, ReasonInfra, err)
 		}
+		e.publishPhase(ctx, task, PhaseAwaitingApproval)
 		task.CompletePipelineStep(PhaseApproval, "success", "awaiting human approval before deploy", "")
 
-		if err := SaveState(state, e.statePath); err != nil {
+		if err := mergeState(state, e.statePath); err != nil {
 			return fmt.Errorf("save state: %w", err)
 		}
 		e.taskLog(task.ID, "info", "Waiting for human approval before deployment")
@@ -272,59 +458,78 @@ func (e *Engine) Execute(ctx context.Context, issue Issue) error {
 	task.AddPipelineStep(PhaseDeploying, "running")
 	e.notifyPhase(ctx, task, PhaseDeploying)
 
-	deployResult, err := stepDeploy(ctx, e.deploy, vars)
+	deployCtx, cancelDeploy := e.withPhaseTimeout(ctx, PhaseDeploying)
+	defer cancelDeploy()
+	span = e.journalBegin(ctx, task, StepDeploy, hashStepInput(vars))
+	deployResult, err := e.deployStep(deployCtx, task, vars)
+	span.end(outcome(err, deployResult != nil && deployResult.Status == "success"))
 	if err != nil {
+		err = timeoutCause(deployCtx, err)
 		task.CompletePipelineStep(PhaseDeploying, "failed", "", err.Error())
-		completeAttempt(&attempt, "failed", ReasonDeploy)
+		completeAttempt(&attempt, "failed", reasonFor(err, ReasonDeploy))
 		task.Attempts = append(task.Attempts, attempt)
-		return e.failTask(ctx, state, task, ReasonDeploy, err)
+		return e.failTask(ctx, state, task, reasonFor(err, ReasonDeploy), err)
 	}
 	attempt.Deploy = deployResult
+	e.storeArtifacts(ctx, task, &attempt)
 
 	if deployResult.Status != "success" {
 		task.CompletePipelineStep(PhaseDeploying, "failed", deployResult.Output, "deploy status failed")
+		if te := timedOut(deployCtx); te != nil {
+			completeAttempt(&attempt, "failed", ReasonTimeout)
+			task.Attempts = append(task.Attempts, attempt)
+			return e.failTask(ctx, state, task, ReasonTimeout, te)
+		}
 
-		handleErr := e.handleDeployFailure(enableDeployFailureAnalysis(ctx), task, deployResult.Output)
+		handleErr := e.handleDeployFailure(enableDeployFailureAnalysis(deployCtx), task, deployResult.Output)
 		if errors.Is(handleErr, ErrAwaitingApproval) {
 			completeAttempt(&attempt, "failed", ReasonDeploy)
 			task.Attempts = append(task.Attempts, attempt)
-			if err := SaveState(state, e.statePath); err != nil {
+			if err := mergeState(state, e.statePath); err != nil {
 				return fmt.Errorf("save state: %w", err)
 			}
 			return ErrAwaitingApproval
 		}
 		if handleErr != nil {
-			completeAttempt(&attempt, "failed", ReasonDeploy)
+			handleErr = timeoutCause(deployCtx, handleErr)
+			completeAttempt(&attempt, "failed", reasonFor(handleErr, ReasonDeploy))
 			task.Attempts = append(task.Attempts, attempt)
-			return e.failTask(ctx, state, task, ReasonDeploy, handleErr)
+			return e.failTask(ctx, state, task, reasonFor(handleErr, ReasonDeploy), handleErr)
 		}
 
 		task.AddPipelineStep(PhaseDeploying, "running")
 		e.notifyPhase(ctx, task, PhaseDeploying)
 
-		deployResult, err = stepDeploy(ctx, e.deploy, vars)
+		span = e.journalBegin(ctx, task, StepDeploy, hashStepInput(vars))
+		deployResult, err = e.deployStep(deployCtx, task, vars)
+		span.end(outcome(err, deployResult != nil && deployResult.Status == "success"))
 		if err != nil {
+			err = timeoutCause(deployCtx, err)
 			task.CompletePipelineStep(PhaseDeploying, "failed", "", err.Error())
-			completeAttempt(&attempt, "failed", ReasonDeploy)
+			completeAttempt(&attempt, "failed", reasonFor(err, ReasonDeploy))
 			task.Attempts = append(task.Attempts, attempt)
-			return e.failTask(ctx, state, task, ReasonDeploy, err)
+			return e.failTask(ctx, state, task, reasonFor(err, ReasonDeploy), err)
 		}
 		attempt.Deploy = deployResult
+		e.storeArtifacts(ctx, task, &attempt)
 
 		if deployResult.Status != "success" {
+			err := timeoutCause(deployCtx, fmt.Errorf("deploy failed after auto-apply: %s", deployResult.Output))
 			task.CompletePipelineStep(PhaseDeploying, "failed", deployResult.Output, "deploy failed after auto-apply")
-			completeAttempt(&attempt, "failed", ReasonDeploy)
+			completeAttempt(&attempt, "failed", reasonFor(err, ReasonDeploy))
 			task.Attempts = append(task.Attempts, attempt)
-			return e.failTask(ctx, state, task, ReasonDeploy, fmt.Errorf("deploy failed after auto-apply: %s", deployResult.Output))
+			return e.failTask(ctx, state, task, reasonFor(err, ReasonDeploy), err)
 		}
 	}
+	cancelDeploy()
 	task.CompletePipelineStep(PhaseDeploying, "success", deployResult.Output, "")
 
-	// Skip test if not in workflow.steps.
-	if !e.isStepEnabled("test") {
-		e.taskLog(task.ID, "info", "Skipping test step (not in workflow.steps)")
+	// Skip test if not in workflow.steps or skipped by a label.
+	if !e.isStepEnabled(task, "test") {
+		cause := skipCause(task, "test")
+		e.taskLog(task.ID, "info", "Skipping test step ("+cause+")")
 		task.AddPipelineStep(PhaseTesting, "running")
-		task.CompletePipelineStep(PhaseTesting, "skipped", "test step disabled in workflow config", "")
+		task.CompletePipelineStep(PhaseTesting, "skipped", "test step disabled ("+cause+")", "")
 
 		completeAttempt(&attempt, "passed", "")
 		task.Attempts = append(task.Attempts, attempt)
@@ -339,8 +544,20 @@ func (e *Engine) Execute(ctx context.Context, issue Issue) error {
 	task.AddPipelineStep(PhaseTesting, "running")
 	e.notifyPhase(ctx, task, PhaseTesting)
 
-	testResults, allPassed := stepTest(ctx, e.testRunners, e.testConfigs, attempt.FilesChanged, vars)
+	testCtx, cancelTest := e.withPhaseTimeout(ctx, PhaseTesting)
+	span = e.journalBegin(ctx, task, StepTest, hashStepInput([]any{vars, attempt.FilesChanged}))
+	testResults, allPassed := stepTest(testCtx, e.testRunners, e.testConfigs, attempt.FilesChanged, vars, e.testRunOptions())
+	span.end(outcome(nil, allPassed))
+	cancelTest()
+	e.logFlakyTests(task.ID, testResults)
 	attempt.Tests = testResults
+	e.storeArtifacts(ctx, task, &attempt)
+	if te := timedOut(testCtx); te != nil && !allPassed {
+		task.CompletePipelineStep(PhaseTesting, "failed", collectTestOutput(testResults), te.Error())
+		completeAttempt(&attempt, "failed", ReasonTimeout)
+		task.Attempts = append(task.Attempts, attempt)
+		return e.failTask(ctx, state, task, ReasonTimeout, te)
+	}
 
 	if allPassed {
 		task.CompletePipelineStep(PhaseTesting, "success", "all tests passed", "")
@@ -361,29 +578,38 @@ func (e *Engine) Execute(ctx context.Context, issue Issue) error {
 	err = retryLoop(ctx, e, task, vars, testResults, changes, maxRetry)
 	if err != nil {
 		if errors.Is(err, ErrAwaitingApproval) {
-			if saveErr := SaveState(state, e.statePath); saveErr != nil {
+			if saveErr := mergeState(state, e.statePath); saveErr != nil {
 				return fmt.Errorf("save state: %w", saveErr)
 			}
 			return ErrAwaitingApproval
 		}
-		log.Printf("[engine] retry loop failed: %v", err)
+		e.log().Error("retry loop failed", logging.TaskKey, task.ID, "err", err)
+		if reasonFor(err, "") == ReasonTimeout {
+			return e.failTask(ctx, state, task, ReasonTimeout, err)
+		}
 		return e.rollbackAndFail(ctx, state, task)
 	}
 
 	return e.completeTask(ctx, state, task)
 }
 
-// Resume continues a task that is currently awaiting approval.
+// Resume continues a task that is currently awaiting approval. For a part
+// of an epic it then runs the parts after it.
 func (e *Engine) Resume(ctx context.Context, taskID string, approved bool) error {
-	state, err := LoadState(e.statePath)
-	if err != nil {
-		return fmt.Errorf("load state: %w", err)
+	if err := e.resume(ctx, taskID, approved); err != nil {
+		return err
 	}
+	return e.continueEpic(ctx, taskID)
+}
 
-	task := state.GetTaskByID(taskID)
-	if task == nil {
-		return fmt.Errorf("task not found: %s", taskID)
+func (e *Engine) resume(ctx context.Context, taskID string, approved bool) error {
+	lock, state, task, err := e.lockTask(taskID)
+	if err != nil {
+		return err
 	}
+	defer lock.Release()
+	ctx = WithTaskUsage(ctx, task)
+
 	if task.Status != PhaseAwaitingApproval {
 		return fmt.Errorf("task %s is not awaiting approval", taskID)
 	}
@@ -405,7 +631,7 @@ func (e *Engine) Resume(ctx context.Context, taskID string, approved bool) error
 		e.notifyPhase(ctx, task, PhaseFailed)
 		task.CompletePipelineStep(PhaseFailed, "success", "approval rejected", "")
 
-		if err := SaveState(state, e.statePath); err != nil {
+		if err := mergeState(state, e.statePath); err != nil {
 			return fmt.Errorf("save state: %w", err)
 		}
 		return nil
@@ -418,12 +644,20 @@ func (e *Engine) Resume(ctx context.Context, taskID string, approved bool) error
 		now := time.Now().UTC()
 		proposal.Status = ProposalApproved
 		proposal.ReviewedAt = &now
-		if err := applyProposalChanges(proposal.Changes); err != nil {
-			return fmt.Errorf("apply approved proposal: %w", err)
+		if proposal.Type == ProposalPlanReview {
+			return e.resumePlan(ctx, state, task, proposal)
+		}
+		// A pre-deploy approval only lets the deploy go ahead.
+		if proposal.Type != ProposalDeployApproval {
+			if err := applyProposalChanges(proposal.Changes); err != nil {
+				return fmt.Errorf("apply approved proposal: %w", err)
+			}
+			attempt.FilesChanged = proposedChangePaths(proposal.Changes)
 		}
-		attempt.FilesChanged = proposedChangePaths(proposal.Changes)
 	}
 
+	ctx, cancel := e.withTaskTimeout(ctx)
+	defer cancel()
 	vars := e.buildVars(task)
 
 	if err := Transition(task, PhaseDeploying); err != nil {
@@ -432,20 +666,27 @@ func (e *Engine) Resume(ctx context.Context, taskID string, approved bool) error
 	task.AddPipelineStep(PhaseDeploying, "running")
 	e.notifyPhase(ctx, task, PhaseDeploying)
 
-	deployResult, err := stepDeploy(ctx, e.deploy, vars)
+	deployCtx, cancelDeploy := e.withPhaseTimeout(ctx, PhaseDeploying)
+	span := e.journalBegin(ctx, task, StepDeploy, hashStepInput(vars))
+	deployResult, err := e.deployStep(deployCtx, task, vars)
+	span.end(outcome(err, deployResult != nil && deployResult.Status == "success"))
+	cancelDeploy()
 	if err != nil {
+		err = timeoutCause(deployCtx, err)
 		task.CompletePipelineStep(PhaseDeploying, "failed", "", err.Error())
-		completeAttempt(&attempt, "failed", ReasonDeploy)
+		completeAttempt(&attempt, "failed", reasonFor(err, ReasonDeploy))
 		task.Attempts = append(task.Attempts, attempt)
-		return e.failTask(ctx, state, task, ReasonDeploy, err)
+		return e.failTask(ctx, state, task, reasonFor(err, ReasonDeploy), err)
 	}
 	attempt.Deploy = deployResult
+	e.storeArtifacts(ctx, task, &attempt)
 
 	if deployResult.Status != "success" {
+		err := timeoutCause(deployCtx, fmt.Errorf("deploy failed: %s", deployResult.Output))
 		task.CompletePipelineStep(PhaseDeploying, "failed", deployResult.Output, "deploy status failed")
-		completeAttempt(&attempt, "failed", ReasonDeploy)
+		completeAttempt(&attempt, "failed", reasonFor(err, ReasonDeploy))
 		task.Attempts = append(task.Attempts, attempt)
-		return e.failTask(ctx, state, task, ReasonDeploy, fmt.Errorf("deploy failed: %s", deployResult.Output))
+		return e.failTask(ctx, state, task, reasonFor(err, ReasonDeploy), err)
 	}
 	task.CompletePipelineStep(PhaseDeploying, "success", deployResult.Output, "")
 
@@ -457,8 +698,20 @@ func (e *Engine) Resume(ctx context.Context, taskID string, approved bool) error
 	task.AddPipelineStep(PhaseTesting, "running")
 	e.notifyPhase(ctx, task, PhaseTesting)
 
-	testResults, allPassed := stepTest(ctx, e.testRunners, e.testConfigs, attempt.FilesChanged, vars)
+	testCtx, cancelTest := e.withPhaseTimeout(ctx, PhaseTesting)
+	span = e.journalBegin(ctx, task, StepTest, hashStepInput([]any{vars, attempt.FilesChanged}))
+	testResults, allPassed := stepTest(testCtx, e.testRunners, e.testConfigs, attempt.FilesChanged, vars, e.testRunOptions())
+	span.end(outcome(nil, allPassed))
+	cancelTest()
+	e.logFlakyTests(task.ID, testResults)
 	attempt.Tests = testResults
+	e.storeArtifacts(ctx, task, &attempt)
+	if te := timedOut(testCtx); te != nil && !allPassed {
+		task.CompletePipelineStep(PhaseTesting, "failed", collectTestOutput(testResults), te.Error())
+		completeAttempt(&attempt, "failed", ReasonTimeout)
+		task.Attempts = append(task.Attempts, attempt)
+		return e.failTask(ctx, state, task, ReasonTimeout, te)
+	}
 
 	if allPassed {
 		task.CompletePipelineStep(PhaseTesting, "success", "all tests passed", "")
@@ -484,11 +737,14 @@ func (e *Engine) Resume(ctx context.Context, taskID string, approved bool) error
 	err = retryLoop(ctx, e, task, vars, testResults, retryChanges, maxRetry)
 	if err != nil {
 		if errors.Is(err, ErrAwaitingApproval) {
-			if saveErr := SaveState(state, e.statePath); saveErr != nil {
+			if saveErr := mergeState(state, e.statePath); saveErr != nil {
 				return fmt.Errorf("save state: %w", saveErr)
 			}
 			return ErrAwaitingApproval
 		}
+		if reasonFor(err, "") == ReasonTimeout {
+			return e.failTask(ctx, state, task, ReasonTimeout, err)
+		}
 		return e.rollbackAndFail(ctx, state, task)
 	}
 
@@ -501,7 +757,8 @@ func (e *Engine) handleDeployFailure(ctx context.Context, task *Task, deployLogs
 	}
 
 	infraFiles := loadInfraFiles(e.cfg.Deploy.InfraFiles)
-	proposedFix, err := e.ai.AnalyzeDeployFailure(ctx, deployLogs, infraFiles)
+	ai, _ := e.taskAI(task)
+	proposedFix, err := ai.AnalyzeDeployFailure(ctx, deployLogs, infraFiles)
 	if err != nil {
 		return fmt.Errorf("analyze deploy failure: %w", err)
 	}
@@ -514,8 +771,8 @@ func (e *Engine) handleDeployFailure(ctx context.Context, task *Task, deployLogs
 
 	// Infrastructure changes always require human approval via web dashboard.
 	// AI proposes the fix, but only proceeds after explicit human approval.
-	log.Printf("[engine] infra fix proposed for task %s: %s (reason: %s, files: %d) — awaiting human approval",
-		task.ID, proposedFix.Summary, proposedFix.Reason, len(changes))
+	e.log().Info("infra fix proposed, awaiting human approval", logging.TaskKey, task.ID,
+		"summary", proposedFix.Summary, "reason", proposedFix.Reason, "files", len(changes))
 
 	task.AddPipelineStep(PhaseAwaitingApproval, "running")
 	if err := Transition(task, PhaseAwaitingApproval); err != nil {
@@ -621,15 +878,16 @@ func (e *Engine) completeTask(ctx context.Context, state *State, task *Task) err
 	}
 	e.notifyPhase(ctx, task, PhaseReporting)
 
-	var lastAttempt *Attempt
-	if len(task.Attempts) > 0 {
-		lastAttempt = &task.Attempts[len(task.Attempts)-1]
-	}
-
-	pr, err := stepCreatePR(ctx, e.git, e.cfg.Source.BaseBranch, task.Branch, task.Issue.Title, lastAttempt)
+	span := e.journalBegin(ctx, task, StepReport, hashStepInput([]string{e.baseBranch(task), task.Branch}))
+	pr, err := e.publishPR(ctx, task)
+	span.end(err)
 	if err != nil {
 		task.CompletePipelineStep(PhaseReporting, "failed", "", err.Error())
-		return e.failTask(ctx, state, task, ReasonGit, err)
+		reason := ReasonGit
+		if veto := (*HookVetoError)(nil); errors.As(err, &veto) {
+			reason = ReasonConfig
+		}
+		return e.failTask(ctx, state, task, reason, err)
 	}
 	task.PR = pr
 	task.CompletePipelineStep(PhaseReporting, "success", pr.URL, "")
@@ -643,71 +901,126 @@ func (e *Engine) completeTask(ctx context.Context, state *State, task *Task) err
 	task.CompletePipelineStep(PhaseCompleted, "success", "task completed", "")
 
 	e.taskLog(task.ID, "info", fmt.Sprintf("Task completed with PR %s", pr.URL))
+	e.recordDeploySnapshot(state, task)
 
 	if err := e.git.Cleanup(); err != nil {
-		log.Printf("[engine] cleanup workspace: %v", err)
+		e.log().Warn("cleanup workspace", logging.TaskKey, task.ID, "err", err)
 	}
 
-	return SaveState(state, e.statePath)
+	if err := mergeState(state, e.statePath); err != nil {
+		return err
+	}
+	// A stacked part of an epic merges into the part before it, which
+	// reviewers merge first.
+	if !e.cfg.Workflow.AutoMerge.Enabled || task.BaseBranch != "" {
+		return nil
+	}
+	// Merging can wait a long time for checks; only write back the PR so
+	// state saved by other tasks meanwhile is kept.
+	e.autoMerge(ctx, task)
+	return WithState(e.statePath, func(s *State) error {
+		if t := s.GetTaskByID(task.ID); t != nil {
+			t.PR = task.PR
+		}
+		return nil
+	})
 }
 
-// rollbackAndFail rolls back deployment then marks task as failed.
+// rollbackAndFail rolls back deployment then marks task as failed, or
+// checkpoints it if a shutdown interrupted it.
 func (e *Engine) rollbackAndFail(ctx context.Context, state *State, task *Task) error {
+	if shuttingDown(ctx) {
+		return e.checkpointTask(state, task, context.Cause(ctx))
+	}
 	task.AddPipelineStep(PhaseFailed, "running")
 	if err := Transition(task, PhaseFailed); err != nil {
-		log.Printf("[engine] failed to transition to failed: %v", err)
+		e.log().Error("failed to transition to failed", logging.TaskKey, task.ID, "err", err)
 		task.CompletePipelineStep(PhaseFailed, "failed", "", err.Error())
 	} else {
 		e.notifyPhase(ctx, task, PhaseFailed)
 		task.CompletePipelineStep(PhaseFailed, "success", "max retries exceeded", "")
 	}
 
-	if e.cfg.Deploy.Rollback.Enabled {
-		task.AddPipelineStep(PhaseRollback, "running")
-		if err := Transition(task, PhaseRollback); err != nil {
-			log.Printf("[engine] failed to transition to rollback: %v", err)
-			task.CompletePipelineStep(PhaseRollback, "failed", "", err.Error())
-		} else {
-			e.notifyPhase(ctx, task, PhaseRollback)
-			if err := stepRollback(ctx, e.deploy); err != nil {
-				log.Printf("[engine] rollback failed: %v", err)
-				task.CompletePipelineStep(PhaseRollback, "failed", "", err.Error())
-			} else {
-				task.CompletePipelineStep(PhaseRollback, "success", "rollback completed", "")
-			}
-		}
-	}
+	e.rollback(ctx, state, task)
+	e.writeFailureBundle(ctx, task)
 
-	if err := SaveState(state, e.statePath); err != nil {
-		log.Printf("[engine] failed to save state after rollback: %v", err)
+	if err := mergeState(state, e.statePath); err != nil {
+		e.log().Error("failed to save state after rollback", logging.TaskKey, task.ID, "err", err)
 	}
 
 	return fmt.Errorf("task %s failed after max retries", task.ID)
 }
 
-// failTask transitions task to failed and saves state.
+// rollback reverts the deploy of a failed task when deploy.rollback is
+// enabled, restoring the project's last successful deploy when the deploy
+// adapter supports snapshots.
+func (e *Engine) rollback(ctx context.Context, state *State, task *Task) {
+	if !e.cfg.Deploy.Rollback.Enabled {
+		return
+	}
+	task.AddPipelineStep(PhaseRollback, "running")
+	if err := Transition(task, PhaseRollback); err != nil {
+		e.log().Error("failed to transition to rollback", logging.TaskKey, task.ID, "err", err)
+		task.CompletePipelineStep(PhaseRollback, "failed", "", err.Error())
+		return
+	}
+	e.notifyPhase(ctx, task, PhaseRollback)
+	deploy := e.deployer(task)
+	snap := state.DeploySnapshot(e.snapshotKey(task))
+	if err := stepRollback(ctx, deploy, snap); err != nil {
+		e.log().Error("rollback failed", logging.TaskKey, task.ID, "err", err)
+		task.CompletePipelineStep(PhaseRollback, "failed", "", err.Error())
+		return
+	}
+	msg := "rollback completed"
+	if _, ok := deploy.(SnapshotRollbacker); ok && snap != nil {
+		msg = fmt.Sprintf("restored deploy of task %s (commit %s)", snap.TaskID, shortSHA(snap.CommitSHA))
+	}
+	e.taskLog(task.ID, "info", "Rollback: "+msg)
+	task.CompletePipelineStep(PhaseRollback, "success", msg, "")
+}
+
+// failTask transitions task to failed and saves state. A task that timed
+// out after deploying is also rolled back, as the deploy may be half
+// applied. A task a shutdown interrupted is checkpointed instead.
 func (e *Engine) failTask(ctx context.Context, state *State, task *Task, reason FailReason, cause error) error {
+	if shuttingDown(ctx) || errors.Is(cause, ErrShutdown) {
+		return e.checkpointTask(state, task, cause)
+	}
 	e.taskLog(task.ID, "error", fmt.Sprintf("Task failed: %v (reason: %s)", cause, reason))
-
-	// Clean up remote branch if it was created during this run.
-	branchName := fmt.Sprintf("rig/issue-%s", task.ID)
-	e.git.CleanupBranch(ctx, branchName)
+	ctx, cancel := cleanupContext(ctx)
+	defer cancel()
+
+	// Clean up the remote branches this task created. Tasks for the same
+	// issue share a branch name, so only recorded branches are deleted, and
+	// never once a PR depends on them.
+	if task.PR == nil {
+		for _, branch := range task.Branches {
+			e.git.CleanupBranch(ctx, branch)
+		}
+	}
+	// A draft PR stays open with the failed attempt history for review.
+	e.syncDraftPR(ctx, task, nil)
 
 	if err := e.git.Cleanup(); err != nil {
-		log.Printf("[engine] cleanup workspace: %v", err)
+		e.log().Warn("cleanup workspace", logging.TaskKey, task.ID, "err", err)
 	}
 
 	task.AddPipelineStep(PhaseFailed, "running")
 	if err := Transition(task, PhaseFailed); err != nil {
-		log.Printf("[engine] failed to transition to failed: %v", err)
+		e.log().Error("failed to transition to failed", logging.TaskKey, task.ID, "err", err)
 		task.CompletePipelineStep(PhaseFailed, "failed", "", err.Error())
 	} else {
 		e.notifyPhase(ctx, task, PhaseFailed)
 		task.CompletePipelineStep(PhaseFailed, "success", cause.Error(), "")
 	}
+	if reason == ReasonTimeout && deployStarted(task) {
+		e.rollback(ctx, state, task)
+	}
+	e.writeFailureBundle(ctx, task)
 
-	if err := SaveState(state, e.statePath); err != nil {
-		log.Printf("[engine] failed to save state: %v", err)
+	if err := mergeState(state, e.statePath); err != nil {
+		e.log().Error("failed to save state", logging.TaskKey, task.ID, "err", err)
 	}
 
 	return fmt.Errorf("task %s failed at %s: %w", task.ID, reason, cause)
@@ -717,7 +1030,7 @@ func (e *Engine) failTask(ctx context.Context, state *State, task *Task, reason
 func (e *Engine) buildVars(task *Task) map[string]string {
 	owner, repo := parseRepo(e.cfg.Source.Repo)
 
-	return map[string]string{
+	vars := map[string]string{
 		"BRANCH_NAME":  task.Branch,
 		"COMMIT_SHA":   "",
 		"ISSUE_ID":     task.Issue.ID,
@@ -726,6 +1039,40 @@ func (e *Engine) buildVars(task *Task) map[string]string {
 		"REPO_OWNER":   owner,
 		"REPO_NAME":    repo,
 	}
+	e.addEnvironmentVars(task, vars)
+	return vars
+}
+
+// loadIssueThread builds the planning input for a task, fetching the current
+// issue body and comments when the git adapter supports it. Fetch failures
+// are logged and planning continues with what the trigger provided.
+func (e *Engine) loadIssueThread(ctx context.Context, task *Task) *AIIssue {
+	aiIssue := &AIIssue{Title: task.Issue.Title, Body: task.Issue.Body, URL: task.Issue.URL}
+
+	reader, ok := e.git.(IssueThreadReader)
+	if !ok {
+		return aiIssue
+	}
+	number, err := strconv.Atoi(task.Issue.ID)
+	if err != nil {
+		return aiIssue
+	}
+	thread, err := reader.GetIssueThread(ctx, number)
+	if err != nil {
+		e.taskLog(task.ID, "warn", fmt.Sprintf("Could not fetch issue thread: %v", err))
+		return aiIssue
+	}
+
+	// A part of an epic keeps its own title and body, narrowed to the part.
+	if thread.Title != "" && task.Part == nil {
+		aiIssue.Title = thread.Title
+	}
+	if thread.Body != "" && task.Part == nil {
+		aiIssue.Body = thread.Body
+	}
+	aiIssue.Comments = thread.Comments
+	e.taskLog(task.ID, "info", fmt.Sprintf("Fetched issue description and %d comments", len(thread.Comments)))
+	return aiIssue
 }
 
 // parseRepo splits "owner/repo" into owner and repo.
@@ -854,17 +1201,38 @@ func pathMatchesPolicyPattern(path string, patterns []string) bool {
 
 // notifyPhase sends a notification about a phase transition.
 func (e *Engine) notifyPhase(ctx context.Context, task *Task, phase TaskPhase) {
-	msg := fmt.Sprintf("[rig] Task %s -> %s (issue: %s)", task.ID, phase, task.Issue.Title)
+	e.setLogContext(task, phase)
+	if inactivePhases[phase] {
+		e.flushLogs()
+	}
+	e.notifyMessage(ctx, fmt.Sprintf("[rig] Task %s -> %s (issue: %s)", task.ID, phase, task.Issue.Title))
+	e.publishPhase(ctx, task, phase)
+}
+
+// flushLogs waits for the log writer of SetLogFlusher, if any, to store the
+// lines written so far.
+func (e *Engine) flushLogs() {
+	if e.logFlushFn == nil {
+		return
+	}
+	if err := e.logFlushFn(); err != nil {
+		e.log().Warn("flush logs", "err", err)
+	}
+}
+
+// notifyMessage sends a free-form message to all notifiers.
+func (e *Engine) notifyMessage(ctx context.Context, msg string) {
 	for _, n := range e.notifiers {
 		if err := n.Notify(ctx, msg); err != nil {
-			log.Printf("[engine] notification failed: %v", err)
+			e.log().Warn("notification failed", "err", err)
 		}
 	}
 }
 
 // loadRepoFiles reads source files from the git workspace to provide context
-// for AI code generation. Filters out binary files, large files, and non-code dirs.
-func loadRepoFiles(workspace string, maxFiles int, maxFileSize int64) map[string]string {
+// for AI code generation. Filters out binary files, large files, non-code
+// dirs and the files ignored matches.
+func loadRepoFiles(workspace string, maxFiles int, maxFileSize int64, ignored *ignore.Matcher) map[string]string {
 	if workspace == "" {
 		return nil
 	}
@@ -893,13 +1261,17 @@ func loadRepoFiles(workspace string, maxFiles int, maxFileSize int64) map[string
 			}
 			return err
 		}
+		relPath, err := filepath.Rel(workspace, path)
+		if err != nil {
+			return nil
+		}
 		if d.IsDir() {
-			if strings.HasPrefix(d.Name(), ".") || skipDirs[d.Name()] {
+			if path != workspace && (strings.HasPrefix(d.Name(), ".") || skipDirs[d.Name()] || ignored.Ignored(relPath, true)) {
 				return filepath.SkipDir
 			}
 			return nil
 		}
-		if !codeExts[filepath.Ext(d.Name())] {
+		if !codeExts[filepath.Ext(d.Name())] || ignored.Ignored(relPath, false) {
 			return nil
 		}
 		info, err := d.Info()
@@ -907,18 +1279,14 @@ func loadRepoFiles(workspace string, maxFiles int, maxFileSize int64) map[string
 			return nil
 		}
 		content, err := os.ReadFile(path)
-		if err != nil {
-			return nil
-		}
-		relPath, err := filepath.Rel(workspace, path)
-		if err != nil {
+		if err != nil || ignore.IsBinary(content) {
 			return nil
 		}
-		files[relPath] = string(content)
+		files[filepath.ToSlash(relPath)] = string(content)
 		count++
 		return nil
 	})
 
-	log.Printf("[engine] loaded %d repo files from %s", len(files), workspace)
+	slog.Debug("loaded repo files", "count", len(files), "workspace", workspace)
 	return files
 }
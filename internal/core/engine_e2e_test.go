@@ -688,7 +688,7 @@ func TestE2EMultipleTestRunners(t *testing.T) {
 }
 
 // ─── TestE2EDryRun ─────────────────────────────────────────────────
-// Verify dry-run mode doesn't execute or persist.
+// Verify dry-run mode plans and generates but has no side effects.
 
 func TestE2EDryRun(t *testing.T) {
 	cfg := e2eConfig()
@@ -711,14 +711,18 @@ func TestE2EDryRun(t *testing.T) {
 		t.Fatalf("dry-run should succeed, got: %v", err)
 	}
 
-	// State file should NOT be created.
-	if _, err := os.Stat(statePath); !os.IsNotExist(err) {
-		t.Fatal("state file should not exist in dry-run mode")
+	// The task is recorded with its plan and diff.
+	state, err := LoadState(statePath)
+	if err != nil || len(state.Tasks) != 1 {
+		t.Fatalf("expected one task, got %+v (%v)", state, err)
+	}
+	if task := state.Tasks[0]; task.Status != PhaseDryRun || task.DryRun == nil || task.DryRun.Diff == "" {
+		t.Errorf("expected a dry_run task with a diff, got %+v", task)
 	}
 
-	// No adapter calls should have been made.
-	if gitMock.createBranchCalls != 0 {
-		t.Errorf("expected 0 git calls in dry-run, got %d", gitMock.createBranchCalls)
+	// No git or deploy side effects.
+	if gitMock.createBranchCalls != 0 || gitMock.commitAndPushCalls != 0 {
+		t.Errorf("expected 0 git calls in dry-run, got %+v", gitMock)
 	}
 	if deployMock.deployCalls != 0 {
 		t.Errorf("expected 0 deploy calls in dry-run, got %d", deployMock.deployCalls)
@@ -1,15 +1,18 @@
 package core
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/logging"
 )
 
 // --- Mock adapters ---
@@ -62,6 +65,7 @@ type mockGit struct {
 	createBranchCalls  int
 	commitAndPushCalls int
 	createPRCalls      int
+	cleanedBranches    []string
 }
 
 func (m *mockGit) CreateBranch(ctx context.Context, branchName string) error {
@@ -90,7 +94,9 @@ func (m *mockGit) Cleanup() error {
 	return nil
 }
 
-func (m *mockGit) CleanupBranch(ctx context.Context, branchName string) {}
+func (m *mockGit) CleanupBranch(ctx context.Context, branchName string) {
+	m.cleanedBranches = append(m.cleanedBranches, branchName)
+}
 
 type mockDeploy struct {
 	deploySuccess bool
@@ -361,14 +367,15 @@ func TestEngine_DryRun(t *testing.T) {
 		t.Fatalf("expected success in dry-run, got error: %v", err)
 	}
 
-	// State file should NOT exist in dry-run (no SaveState called).
-	if _, err := os.Stat(statePath); !os.IsNotExist(err) {
-		t.Fatal("expected state file to not exist in dry-run mode")
+	// The task is recorded for review in the dry_run phase.
+	state, err := LoadState(statePath)
+	if err != nil || len(state.Tasks) != 1 || state.Tasks[0].Status != PhaseDryRun {
+		t.Fatalf("expected one dry_run task, got %+v (%v)", state, err)
 	}
 
-	// No adapter calls should have been made.
-	if gitMock.createBranchCalls != 0 {
-		t.Fatalf("expected 0 createBranch calls in dry-run, got %d", gitMock.createBranchCalls)
+	// No git or deploy side effects.
+	if gitMock.createBranchCalls != 0 || gitMock.commitAndPushCalls != 0 || gitMock.createPRCalls != 0 {
+		t.Fatalf("expected no branch, commit or PR in dry-run, got %+v", gitMock)
 	}
 	if deployMock.deployCalls != 0 {
 		t.Fatalf("expected 0 deploy calls in dry-run, got %d", deployMock.deployCalls)
@@ -486,3 +493,161 @@ func TestParseRepo(t *testing.T) {
 		}
 	}
 }
+
+func TestEngine_FlushesLogsWhenTaskStops(t *testing.T) {
+	cfg := testConfig()
+	engine := NewEngine(cfg, &mockGit{}, &mockAI{}, &mockDeploy{deploySuccess: true},
+		[]TestRunnerIface{&mockTestRunner{}}, nil, tempStatePath(t))
+
+	flushes := 0
+	engine.SetLogFlusher(func() error {
+		flushes++
+		return nil
+	})
+
+	if err := engine.Execute(context.Background(), testIssue()); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	// Only the completed phase flushes, not the phases on the way.
+	if flushes != 1 {
+		t.Errorf("expected one flush when the task completed, got %d", flushes)
+	}
+}
+
+func TestEngine_FailTaskCleansRecordedBranch(t *testing.T) {
+	gitMock := &mockGit{createPRErr: errors.New("pr failed")}
+	statePath := tempStatePath(t)
+	engine := NewEngine(testConfig(), gitMock, &mockAI{}, &mockDeploy{deploySuccess: true},
+		[]TestRunnerIface{&mockTestRunner{}}, nil, statePath)
+
+	if err := engine.Execute(context.Background(), testIssue()); err == nil {
+		t.Fatal("expected execution to fail")
+	}
+
+	state, err := LoadState(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	task := state.Tasks[0]
+	if len(task.Branches) != 1 || task.Branches[0] != "rig/issue-42" {
+		t.Fatalf("expected recorded branch rig/issue-42, got %v", task.Branches)
+	}
+	if len(gitMock.cleanedBranches) != 1 || gitMock.cleanedBranches[0] != "rig/issue-42" {
+		t.Fatalf("expected rig/issue-42 to be cleaned up, got %v", gitMock.cleanedBranches)
+	}
+}
+
+func TestEngine_FailTaskKeepsUnpushedBranch(t *testing.T) {
+	gitMock := &mockGit{}
+	aiMock := &mockAI{analyzeFunc: func(ctx context.Context, issue *AIIssue, projectCtx string) (*AIPlan, error) {
+		return nil, errors.New("ai down")
+	}}
+	engine := NewEngine(testConfig(), gitMock, aiMock, &mockDeploy{deploySuccess: true},
+		nil, nil, tempStatePath(t))
+
+	_ = engine.Execute(context.Background(), testIssue())
+	if len(gitMock.cleanedBranches) != 0 {
+		t.Fatalf("nothing was pushed, but cleaned %v", gitMock.cleanedBranches)
+	}
+}
+
+// threadGit is a mockGit that also serves the issue thread.
+type threadGit struct {
+	mockGit
+	thread *IssueThread
+	err    error
+}
+
+func (m *threadGit) GetIssueThread(ctx context.Context, number int) (*IssueThread, error) {
+	return m.thread, m.err
+}
+
+func TestEngine_PlanningSeesIssueThread(t *testing.T) {
+	gitMock := &threadGit{thread: &IssueThread{
+		Title:    "Fix login bug",
+		Body:     "Acceptance: login works with SSO",
+		Comments: []IssueComment{{Author: "alice", Body: "Also handle expired tokens"}},
+	}}
+	var seen *AIIssue
+	aiMock := &mockAI{analyzeFunc: func(ctx context.Context, issue *AIIssue, projectCtx string) (*AIPlan, error) {
+		seen = issue
+		return &AIPlan{Summary: "plan", Steps: []string{"step"}}, nil
+	}}
+
+	engine := NewEngine(testConfig(), gitMock, aiMock, &mockDeploy{deploySuccess: true},
+		[]TestRunnerIface{&mockTestRunner{}}, nil, tempStatePath(t))
+	if err := engine.Execute(context.Background(), testIssue()); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+
+	if seen == nil || seen.Body != "Acceptance: login works with SSO" {
+		t.Fatalf("expected fetched body in planning input, got %+v", seen)
+	}
+	if len(seen.Comments) != 1 || seen.Comments[0].Author != "alice" {
+		t.Errorf("expected comments in planning input, got %+v", seen.Comments)
+	}
+}
+
+func TestEngine_IssueThreadFetchFailureFallsBack(t *testing.T) {
+	gitMock := &threadGit{err: errors.New("rate limited")}
+	var seen *AIIssue
+	aiMock := &mockAI{analyzeFunc: func(ctx context.Context, issue *AIIssue, projectCtx string) (*AIPlan, error) {
+		seen = issue
+		return &AIPlan{Summary: "plan", Steps: []string{"step"}}, nil
+	}}
+
+	issue := testIssue()
+	engine := NewEngine(testConfig(), gitMock, aiMock, &mockDeploy{deploySuccess: true},
+		[]TestRunnerIface{&mockTestRunner{}}, nil, tempStatePath(t))
+	if err := engine.Execute(context.Background(), issue); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if seen == nil || seen.Title != issue.Title || seen.Body != issue.Body || len(seen.Comments) != 0 {
+		t.Errorf("expected trigger-provided issue, got %+v", seen)
+	}
+}
+
+func TestEngine_StructuredTaskLogs(t *testing.T) {
+	var out bytes.Buffer
+	var sunk []string
+	logger, err := logging.New(&out, logging.Options{Format: "json"}, func(taskID, level, message string) {
+		sunk = append(sunk, taskID+" "+level+" "+message)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testRunner := &mockTestRunner{results: []*TestResult{{Name: "unit-test", Type: "command", Passed: true}}}
+	engine := NewEngine(testConfig(), &mockGit{}, &mockAI{}, &mockDeploy{deploySuccess: true},
+		[]TestRunnerIface{testRunner}, nil, tempStatePath(t))
+	engine.SetLogger(logger)
+
+	if err := engine.Execute(context.Background(), testIssue()); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+
+	var plan map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var rec map[string]any
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("not a JSON record: %q", line)
+		}
+		if rec["msg"] == "Plan: test plan" {
+			plan = rec
+		}
+	}
+	if plan == nil {
+		t.Fatalf("plan record not logged:\n%s", out.String())
+	}
+	if plan["task_id"] == "" || plan["phase"] != string(PhasePlanning) || plan["attempt"] != float64(1) {
+		t.Errorf("plan record should carry task_id, phase and attempt, got %v", plan)
+	}
+	found := false
+	for _, line := range sunk {
+		if strings.HasSuffix(line, " info Plan: test plan") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("task sink did not receive the plan line: %v", sunk)
+	}
+}
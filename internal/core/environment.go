@@ -0,0 +1,109 @@
+package core
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+)
+
+// EnvironmentLabelPrefix starts the issue label that picks the environment
+// a task deploys to, such as deploy:staging.
+const EnvironmentLabelPrefix = "deploy:"
+
+// Environment is a deploy environment (environments in rig.yaml) with its
+// own variables, deploy adapter and approval requirement.
+type Environment struct {
+	Name string
+	// Default is used for issues without a deploy:<name> label.
+	Default bool
+	// Vars are given to the deploy commands and tests of its tasks, along
+	// with ${ENVIRONMENT}.
+	Vars map[string]string
+	// Approval requires human approval before deploying, as
+	// workflow.approval.before_deploy does for every task.
+	Approval bool
+	// Deploy deploys to the environment; nil uses the engine's adapter.
+	Deploy DeployAdapterIface
+}
+
+// SetEnvironments sets the environments tasks deploy to.
+func (e *Engine) SetEnvironments(envs []Environment) {
+	e.environments = envs
+}
+
+// selectEnvironment returns the name of the environment issue deploys to:
+// the one its deploy:<name> label names, or else the default one. It is
+// empty when no environments are configured.
+func (e *Engine) selectEnvironment(issue Issue) (string, error) {
+	if len(e.environments) == 0 {
+		return "", nil
+	}
+	for _, label := range issue.Labels {
+		name, ok := strings.CutPrefix(strings.ToLower(label), EnvironmentLabelPrefix)
+		if !ok {
+			continue
+		}
+		for _, env := range e.environments {
+			if strings.EqualFold(env.Name, name) {
+				return env.Name, nil
+			}
+		}
+		return "", fmt.Errorf("label %q names an unknown environment", label)
+	}
+	for _, env := range e.environments {
+		if env.Default {
+			return env.Name, nil
+		}
+	}
+	return "", nil
+}
+
+// environment returns the environment of task, or nil.
+func (e *Engine) environment(task *Task) *Environment {
+	if task.Environment == "" {
+		return nil
+	}
+	for i := range e.environments {
+		if e.environments[i].Name == task.Environment {
+			return &e.environments[i]
+		}
+	}
+	return nil
+}
+
+// deployer returns the deploy adapter of task's environment.
+func (e *Engine) deployer(task *Task) DeployAdapterIface {
+	if env := e.environment(task); env != nil && env.Deploy != nil {
+		return env.Deploy
+	}
+	return e.deploy
+}
+
+// needsDeployApproval reports whether task waits for approval before
+// deploying.
+func (e *Engine) needsDeployApproval(task *Task) bool {
+	if e.cfg.Workflow.Approval.BeforeDeploy {
+		return true
+	}
+	env := e.environment(task)
+	return env != nil && env.Approval
+}
+
+// addEnvironmentVars adds the variables of task's environment to vars.
+func (e *Engine) addEnvironmentVars(task *Task, vars map[string]string) {
+	env := e.environment(task)
+	if env == nil {
+		return
+	}
+	maps.Copy(vars, env.Vars)
+	vars["ENVIRONMENT"] = env.Name
+}
+
+// snapshotKey is the key of the deploy snapshot of task's project and
+// environment.
+func (e *Engine) snapshotKey(task *Task) string {
+	if task.Environment == "" {
+		return e.cfg.Source.Repo
+	}
+	return e.cfg.Source.Repo + "@" + task.Environment
+}
@@ -0,0 +1,90 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// varsDeploy records the variables of its deploys.
+type varsDeploy struct {
+	mockDeploy
+	vars []map[string]string
+}
+
+func (m *varsDeploy) Deploy(ctx context.Context, vars map[string]string) (*AdapterDeployResult, error) {
+	m.vars = append(m.vars, vars)
+	return m.mockDeploy.Deploy(ctx, vars)
+}
+
+func TestSelectEnvironment(t *testing.T) {
+	engine := NewEngine(testConfig(), &mockGit{}, &mockAI{}, &mockDeploy{}, nil, nil, tempStatePath(t))
+	if env, err := engine.selectEnvironment(testIssue()); env != "" || err != nil {
+		t.Errorf("without environments got %q, %v", env, err)
+	}
+
+	engine.SetEnvironments([]Environment{{Name: "staging", Default: true}, {Name: "prod"}})
+	tests := []struct {
+		labels  []string
+		want    string
+		wantErr bool
+	}{
+		{nil, "staging", false},
+		{[]string{"bug", "Deploy:PROD"}, "prod", false},
+		{[]string{"deploy:qa"}, "", true},
+	}
+	for _, tt := range tests {
+		issue := testIssue()
+		issue.Labels = tt.labels
+		env, err := engine.selectEnvironment(issue)
+		if env != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("labels %v: got %q, %v; want %q (error %v)", tt.labels, env, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestEngine_DeploysToEnvironment(t *testing.T) {
+	cfg := testConfig()
+	statePath := tempStatePath(t)
+	base := &varsDeploy{mockDeploy: mockDeploy{deploySuccess: true}}
+	prod := &varsDeploy{mockDeploy: mockDeploy{deploySuccess: true}}
+
+	engine := NewEngine(cfg, &mockGit{}, &mockAI{}, base, []TestRunnerIface{&mockTestRunner{}}, nil, statePath)
+	engine.SetEnvironments([]Environment{
+		{Name: "staging", Default: true, Vars: map[string]string{"APP_URL": "https://staging"}},
+		{Name: "prod", Vars: map[string]string{"APP_URL": "https://prod"}, Approval: true, Deploy: prod},
+	})
+
+	if err := engine.Execute(context.Background(), testIssue()); err != nil {
+		t.Fatalf("staging task: %v", err)
+	}
+	if len(base.vars) != 1 || base.vars[0]["ENVIRONMENT"] != "staging" || base.vars[0]["APP_URL"] != "https://staging" {
+		t.Fatalf("staging deploy vars = %v", base.vars)
+	}
+
+	// prod has its own adapter and waits for approval before deploying.
+	issue := testIssue()
+	issue.ID = "43"
+	issue.Labels = []string{"deploy:prod"}
+	if err := engine.Execute(context.Background(), issue); !errors.Is(err, ErrAwaitingApproval) {
+		t.Fatalf("prod task: err = %v, want awaiting approval", err)
+	}
+	state, err := LoadState(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	task := state.GetTask("43")
+	if task.Environment != "prod" {
+		t.Fatalf("environment = %q, want prod", task.Environment)
+	}
+	if err := engine.Resume(context.Background(), task.ID, true); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if len(prod.vars) != 1 || prod.vars[0]["APP_URL"] != "https://prod" || len(base.vars) != 1 {
+		t.Errorf("prod deploys = %v, base deploys = %d", prod.vars, len(base.vars))
+	}
+	state, _ = LoadState(statePath)
+	if state.DeploySnapshot("test/repo@prod") == nil || state.DeploySnapshot("test/repo@staging") == nil {
+		t.Errorf("snapshots = %v, want one per environment", state.Snapshots)
+	}
+}
@@ -0,0 +1,311 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Defaults of workflow.epic.
+const (
+	defaultEpicMinItems = 2
+	defaultEpicMaxParts = 10
+)
+
+// ErrSplitEpicUnsupported is returned by adapter wrappers whose wrapped
+// adapter cannot split epics.
+var ErrSplitEpicUnsupported = errors.New("ai adapter does not support epic splitting")
+
+// EpicSplitter is an optional AIAdapter capability that groups the open
+// checklist items of an epic issue into at most maxParts parts, in the
+// order they should land, each building on the parts before it. Used by
+// workflow.epic.ai.
+type EpicSplitter interface {
+	SplitEpic(ctx context.Context, issue *AIIssue, items []string, maxParts int) ([]AISubtask, error)
+}
+
+// BaseCheckout is an optional GitAdapter capability that checks out
+// another branch than the base branch to start the next branch from, so
+// the parts of an epic stack on each other.
+type BaseCheckout interface {
+	CheckoutBase(ctx context.Context, branch string) error
+}
+
+// EpicPart places a child task in the stack of its epic.
+type EpicPart struct {
+	ParentID string   `json:"parent_id"`
+	Index    int      `json:"index"` // 1-based; part 1 targets source.base_branch
+	Of       int      `json:"of"`
+	Items    []string `json:"items"` // checklist items the part implements
+}
+
+// checklistItem matches an open Markdown task list item, "- [ ] item".
+var checklistItem = regexp.MustCompile(`^\s*[-*+]\s+\[ \]\s+(.+?)\s*$`)
+
+// checklistItems returns the open task list items of an issue body.
+func checklistItems(body string) []string {
+	var items []string
+	for line := range strings.SplitSeq(body, "\n") {
+		if m := checklistItem.FindStringSubmatch(line); m != nil {
+			items = append(items, m[1])
+		}
+	}
+	return items
+}
+
+// epicItems returns the open checklist items of the issue of task when
+// workflow.epic makes it an epic, or nil. Child tasks are never epics.
+func (e *Engine) epicItems(task *Task) []string {
+	cfg := e.cfg.Workflow.Epic
+	if !cfg.Enabled || task.Part != nil {
+		return nil
+	}
+	minItems := cfg.MinItems
+	if minItems <= 0 {
+		minItems = defaultEpicMinItems
+	}
+	items := checklistItems(task.Issue.Body)
+	if len(items) < minItems {
+		return nil
+	}
+	return items
+}
+
+// splitEpic groups items into parts: by the AI with workflow.epic.ai, or
+// else one per item. Items beyond workflow.epic.max_parts join the last
+// part.
+func (e *Engine) splitEpic(ctx context.Context, task *Task, items []string) []AISubtask {
+	maxParts := e.cfg.Workflow.Epic.MaxParts
+	if maxParts <= 0 {
+		maxParts = defaultEpicMaxParts
+	}
+	if e.cfg.Workflow.Epic.AI {
+		ai, _ := e.taskAI(task)
+		splitter, ok := ai.(EpicSplitter)
+		var parts []AISubtask
+		err := ErrSplitEpicUnsupported
+		if ok {
+			parts, err = splitter.SplitEpic(ctx, e.loadIssueThread(ctx, task), items, maxParts)
+		}
+		switch {
+		case err != nil:
+			e.taskLog(task.ID, "warn", fmt.Sprintf("AI could not split the epic, one part per item: %v", err))
+		case len(parts) == 0:
+			e.taskLog(task.ID, "warn", "AI split the epic into no parts, one part per item")
+		default:
+			return capSubtasks(parts, maxParts)
+		}
+	}
+	parts := make([]AISubtask, 0, min(len(items), maxParts))
+	for i, item := range items {
+		if i >= maxParts {
+			last := &parts[maxParts-1]
+			last.Summary += "; " + item
+			last.Steps = append(last.Steps, item)
+			continue
+		}
+		parts = append(parts, AISubtask{Name: item, Summary: item, Steps: []string{item}})
+	}
+	return parts
+}
+
+// executeEpic splits the checklist of the epic task parent into parts,
+// creates a queued child task for each and runs them in order. Each child
+// branches off the branch of the one before and opens its PR against it.
+func (e *Engine) executeEpic(ctx context.Context, state *State, parent *Task, items []string) error {
+	if err := Transition(parent, PhasePlanning); err != nil {
+		return e.failTask(ctx, state, parent, ReasonInfra, err)
+	}
+	parent.AddPipelineStep(PhasePlanning, "running")
+	e.notifyPhase(ctx, parent, PhasePlanning)
+	e.taskLog(parent.ID, "info", fmt.Sprintf("Epic: splitting %d checklist items into parts", len(items)))
+	parts := e.splitEpic(ctx, parent, items)
+
+	parentID, branch, issue := parent.ID, parent.Branch, parent.Issue
+	base := ""
+	children := make([]string, len(parts))
+	for i, part := range parts {
+		child, err := state.addTask(e.statePath, childIssue(issue, part, i+1, len(parts)))
+		if err != nil {
+			return e.failTask(ctx, state, state.GetTaskByID(parentID), ReasonInfra, err)
+		}
+		child.Branch = fmt.Sprintf("%s-%d", branch, i+1)
+		child.BaseBranch = base
+		child.Part = &EpicPart{ParentID: parentID, Index: i + 1, Of: len(parts), Items: part.Steps}
+		children[i] = child.ID
+		base = child.Branch
+	}
+	parent = state.GetTaskByID(parentID)
+	parent.Children = children
+
+	summary := make([]string, len(parts))
+	for i, part := range parts {
+		summary[i] = fmt.Sprintf("%d. %s", i+1, part.Name)
+	}
+	msg := fmt.Sprintf("split into %d stacked parts", len(parts))
+	e.taskLog(parent.ID, "info", "Epic "+msg+":\n"+strings.Join(summary, "\n"))
+	parent.CompletePipelineStep(PhasePlanning, "success", msg, "")
+	e.postIssueUpdate(ctx, parent, IssueUpdatePlan, fmt.Sprintf("This issue is an epic; each part gets its own PR, stacked on the one before:\n\n%s", strings.Join(summary, "\n")))
+
+	if err := Transition(parent, PhaseEpic); err != nil {
+		return e.failTask(ctx, state, parent, ReasonInfra, err)
+	}
+	parent.AddPipelineStep(PhaseEpic, "running")
+	e.notifyPhase(ctx, parent, PhaseEpic)
+	if err := mergeState(state, e.statePath); err != nil {
+		return fmt.Errorf("save state: %w", err)
+	}
+	return e.runEpic(ctx, state, parentID)
+}
+
+// childIssue is the issue a child task of an epic works on: the epic's
+// issue narrowed to part, the index-th of n.
+func childIssue(epic Issue, part AISubtask, index, n int) Issue {
+	child := epic
+	child.Title = fmt.Sprintf("%s (%d/%d): %s", epic.Title, index, n, part.Name)
+	child.IdempotencyKey = ""
+	var b strings.Builder
+	fmt.Fprintf(&b, "Part %d of %d of the epic #%s. Implement only this part", index, n, epic.ID)
+	if index > 1 {
+		b.WriteString("; the parts before it are already on the base branch")
+	}
+	fmt.Fprintf(&b, ".\n\n%s\n", part.Summary)
+	for _, step := range part.Steps {
+		fmt.Fprintf(&b, "- %s\n", step)
+	}
+	// Quoted, so the epic's checklist does not make a retry of the part an
+	// epic again.
+	b.WriteString("\nThe epic:\n\n> ")
+	b.WriteString(strings.ReplaceAll(epic.Body, "\n", "\n> "))
+	child.Body = b.String()
+	return child
+}
+
+// runEpic runs the queued child tasks of the epic parentID in order and
+// completes the epic once all are completed. It stops, leaving the epic
+// running, when a child waits for approval; Resume continues it. A failed
+// child fails the epic and the children after it.
+func (e *Engine) runEpic(ctx context.Context, state *State, parentID string) error {
+	parent := state.GetTaskByID(parentID)
+	if parent == nil || parent.Status != PhaseEpic {
+		return nil
+	}
+	for _, id := range parent.Children {
+		child := state.GetTaskByID(id)
+		if child == nil {
+			return e.failEpic(ctx, state, parentID, fmt.Errorf("child task %s not found", id))
+		}
+		switch child.Status {
+		case PhaseCompleted:
+			continue
+		case PhaseQueued:
+			err := e.startTask(ctx, state, child)
+			if errors.Is(err, ErrAwaitingApproval) {
+				return err
+			}
+			if err != nil || child.Status != PhaseCompleted {
+				if err == nil {
+					err = fmt.Errorf("ended %s", child.Status)
+				}
+				return e.failEpic(ctx, state, parentID, fmt.Errorf("part %d/%d (%s): %w", child.Part.Index, child.Part.Of, child.ID, err))
+			}
+		case PhaseAwaitingApproval:
+			return ErrAwaitingApproval
+		default:
+			return e.failEpic(ctx, state, parentID, fmt.Errorf("part %d/%d (%s) %s", child.Part.Index, child.Part.Of, child.ID, child.Status))
+		}
+	}
+
+	parent = state.GetTaskByID(parentID)
+	var prs []string
+	for _, id := range parent.Children {
+		if c := state.GetTaskByID(id); c != nil && c.PR != nil {
+			prs = append(prs, c.PR.URL)
+		}
+	}
+	msg := fmt.Sprintf("all %d parts completed", len(parent.Children))
+	parent.CompletePipelineStep(PhaseEpic, "success", msg, "")
+	parent.AddPipelineStep(PhaseCompleted, "running")
+	if err := Transition(parent, PhaseCompleted); err != nil {
+		parent.CompletePipelineStep(PhaseCompleted, "failed", "", err.Error())
+		return fmt.Errorf("transition to completed: %w", err)
+	}
+	e.notifyPhase(ctx, parent, PhaseCompleted)
+	parent.CompletePipelineStep(PhaseCompleted, "success", "epic completed", "")
+	e.taskLog(parent.ID, "info", fmt.Sprintf("Epic completed, %s: %s", msg, strings.Join(prs, ", ")))
+	return mergeState(state, e.statePath)
+}
+
+// failEpic fails the epic parentID because of cause, and its children that
+// have not started.
+func (e *Engine) failEpic(ctx context.Context, state *State, parentID string, cause error) error {
+	parent := state.GetTaskByID(parentID)
+	for _, id := range parent.Children {
+		child := state.GetTaskByID(id)
+		if child == nil || child.Status != PhaseQueued {
+			continue
+		}
+		child.AddPipelineStep(PhaseFailed, "running")
+		if err := Transition(child, PhaseFailed); err != nil {
+			child.CompletePipelineStep(PhaseFailed, "failed", "", err.Error())
+			continue
+		}
+		child.CompletePipelineStep(PhaseFailed, "success", "an earlier part of the epic failed", "")
+		e.taskLog(child.ID, "warn", "Not started: an earlier part of the epic failed")
+	}
+	parent.CompletePipelineStep(PhaseEpic, "failed", "", cause.Error())
+	return e.failTask(ctx, state, parent, ReasonConfig, cause)
+}
+
+// continueEpic runs the rest of the epic of the child task taskID after
+// Resume finished it.
+func (e *Engine) continueEpic(ctx context.Context, taskID string) error {
+	state, err := loadTrackedState(e.statePath)
+	if err != nil {
+		return fmt.Errorf("load state: %w", err)
+	}
+	task := state.GetTaskByID(taskID)
+	if task == nil || task.Part == nil || task.Status == PhaseAwaitingApproval {
+		return nil
+	}
+	parent := state.GetTaskByID(task.Part.ParentID)
+	if parent == nil || parent.Status != PhaseEpic {
+		return nil
+	}
+	lock, err := AcquireExecutionLock(e.statePath, parent.Issue)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+	if state, err = loadTrackedState(e.statePath); err != nil {
+		return fmt.Errorf("load state: %w", err)
+	}
+	return e.runEpic(ctx, state, parent.ID)
+}
+
+// baseBranch is the branch the PR of task targets: the branch of the part
+// before it for a stacked part of an epic, or else source.base_branch.
+func (e *Engine) baseBranch(task *Task) string {
+	if task.BaseBranch != "" {
+		return task.BaseBranch
+	}
+	return e.cfg.Source.BaseBranch
+}
+
+// checkoutBase starts the branch of a stacked task from its base branch.
+func (e *Engine) checkoutBase(ctx context.Context, task *Task) error {
+	if task.BaseBranch == "" {
+		return nil
+	}
+	checkout, ok := e.git.(BaseCheckout)
+	if !ok {
+		return fmt.Errorf("git adapter cannot branch off %s for a stacked PR", task.BaseBranch)
+	}
+	if err := checkout.CheckoutBase(ctx, task.BaseBranch); err != nil {
+		return fmt.Errorf("check out %s: %w", task.BaseBranch, err)
+	}
+	e.taskLog(task.ID, "info", fmt.Sprintf("Branching off %s, the branch of the part before", task.BaseBranch))
+	return nil
+}
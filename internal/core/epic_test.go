@@ -0,0 +1,131 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rigdev/rig/internal/config"
+)
+
+// stackGit records the PRs opened and the bases checked out for stacked
+// parts of an epic.
+type stackGit struct {
+	mockGit
+	checkouts []string
+	bases     []string
+	bodies    []string
+}
+
+func (g *stackGit) CheckoutBase(ctx context.Context, branch string) error {
+	g.checkouts = append(g.checkouts, branch)
+	return nil
+}
+
+func (g *stackGit) CreatePR(ctx context.Context, base, head, title, body string) (*GitPullRequest, error) {
+	g.bases = append(g.bases, base)
+	g.bodies = append(g.bodies, body)
+	return g.mockGit.CreatePR(ctx, base, head, title, body)
+}
+
+func epicIssue() Issue {
+	issue := testIssue()
+	issue.Body = "Build the feature.\n\n- [ ] Add the model\n- [x] Write the RFC\n* [ ] Add the API\n- [ ] Add the UI\n"
+	return issue
+}
+
+func TestChecklistItems(t *testing.T) {
+	items := checklistItems(epicIssue().Body)
+	if want := []string{"Add the model", "Add the API", "Add the UI"}; !slices.Equal(items, want) {
+		t.Errorf("items = %q, want %q", items, want)
+	}
+	if items := checklistItems("- [] not a task\n> - [ ] quoted\n"); items != nil {
+		t.Errorf("expected no items, got %q", items)
+	}
+}
+
+func TestExecute_EpicStacksParts(t *testing.T) {
+	cfg := testConfig()
+	cfg.Workflow.Epic.Enabled = true
+	cfg.Workflow.Epic.MaxParts = 2
+	statePath := tempStatePath(t)
+	gitMock := &stackGit{}
+	engine := NewEngine(cfg, gitMock, &mockAI{}, &mockDeploy{deploySuccess: true}, []TestRunnerIface{&mockTestRunner{}}, nil, statePath)
+
+	if err := engine.Execute(context.Background(), epicIssue()); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	state, _ := LoadState(statePath)
+	if len(state.Tasks) != 3 {
+		t.Fatalf("expected an epic and 2 parts, got %d tasks", len(state.Tasks))
+	}
+	epic := state.Tasks[0]
+	if epic.Status != PhaseCompleted || len(epic.Children) != 2 {
+		t.Fatalf("epic %s with children %v", epic.Status, epic.Children)
+	}
+	last := state.GetTaskByID(epic.Children[1])
+	if last.Part == nil || last.Part.Index != 2 || !slices.Equal(last.Part.Items, []string{"Add the API", "Add the UI"}) {
+		t.Errorf("expected items beyond max_parts in the last part, got %+v", last.Part)
+	}
+	if last.BaseBranch != "rig/issue-42-1" || !strings.HasPrefix(last.Issue.Title, "Fix the bug (2/2): ") {
+		t.Errorf("unexpected last part on %q: %q", last.BaseBranch, last.Issue.Title)
+	}
+	if !slices.Equal(gitMock.bases, []string{"main", "rig/issue-42-1"}) || !slices.Equal(gitMock.checkouts, []string{"rig/issue-42-1"}) {
+		t.Errorf("PR bases %v, checkouts %v", gitMock.bases, gitMock.checkouts)
+	}
+	if !strings.Contains(gitMock.bodies[0], "Part 1 of 2 of #42") || !strings.Contains(gitMock.bodies[1], "Closes #42") {
+		t.Errorf("unexpected PR bodies %q", gitMock.bodies)
+	}
+	if parts, _ := (TaskQuery{Parent: epic.ID}).Apply(state.Tasks); len(parts) != 2 {
+		t.Errorf("expected the parent filter to find 2 parts, got %d", len(parts))
+	}
+}
+
+func TestExecute_EpicFailedPartFailsEpic(t *testing.T) {
+	cfg := testConfig()
+	cfg.Workflow.Epic.Enabled = true
+	statePath := tempStatePath(t)
+	// Without BaseCheckout the second part cannot stack on the first.
+	engine := NewEngine(cfg, &mockGit{}, &mockAI{}, &mockDeploy{deploySuccess: true}, []TestRunnerIface{&mockTestRunner{}}, nil, statePath)
+
+	if err := engine.Execute(context.Background(), epicIssue()); err == nil {
+		t.Fatal("expected the epic to fail")
+	}
+	state, _ := LoadState(statePath)
+	want := []TaskPhase{PhaseFailed, PhaseCompleted, PhaseFailed, PhaseFailed}
+	for i, task := range state.Tasks {
+		if task.Status != want[i] {
+			t.Errorf("task %d (%s) is %s, want %s", i, task.ID, task.Status, want[i])
+		}
+	}
+}
+
+func TestResume_ContinuesEpic(t *testing.T) {
+	cfg := testConfig()
+	cfg.Workflow.Epic = config.EpicConfig{Enabled: true}
+	cfg.Workflow.Approval.BeforeDeploy = true
+	statePath := tempStatePath(t)
+	engine := NewEngine(cfg, &stackGit{}, &mockAI{}, &mockDeploy{deploySuccess: true}, []TestRunnerIface{&mockTestRunner{}}, nil, statePath)
+
+	issue := testIssue()
+	issue.Body = "- [ ] One\n- [ ] Two\n"
+	if err := engine.Execute(context.Background(), issue); !errors.Is(err, ErrAwaitingApproval) {
+		t.Fatalf("execute: %v, want awaiting approval", err)
+	}
+	state, _ := LoadState(statePath)
+	first, second := state.Tasks[1].ID, state.Tasks[2].ID
+	if err := engine.Resume(context.Background(), first, true); !errors.Is(err, ErrAwaitingApproval) {
+		t.Fatalf("resume part 1: %v, want part 2 awaiting approval", err)
+	}
+	if err := engine.Resume(context.Background(), second, true); err != nil {
+		t.Fatalf("resume part 2: %v", err)
+	}
+	state, _ = LoadState(statePath)
+	for _, task := range state.Tasks {
+		if task.Status != PhaseCompleted {
+			t.Errorf("task %s is %s, want completed", task.ID, task.Status)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// Task lifecycle event types.
+const (
+	EventTaskCreated         = "task.created"
+	EventTaskPhaseChanged    = "task.phase_changed"
+	EventTaskProposalPending = "task.proposal_pending"
+	EventTaskCompleted       = "task.completed"
+	EventTaskFailed          = "task.failed"
+)
+
+// TaskEvent is a task lifecycle event as event sinks receive it, in JSON.
+// Fields are only ever added to it.
+type TaskEvent struct {
+	// ID is unique per event, so a receiver can drop duplicates.
+	ID   string    `json:"id"`
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	Task EventTask `json:"task"`
+	// Proposal is the proposal waiting for review, in
+	// task.proposal_pending events.
+	Proposal *EventProposal `json:"proposal,omitempty"`
+}
+
+// EventTask is the task an event is about, as it was when it happened.
+type EventTask struct {
+	ID          string       `json:"id"`
+	Status      TaskPhase    `json:"status"`
+	Issue       Issue        `json:"issue"`
+	Branch      string       `json:"branch,omitempty"`
+	Environment string       `json:"environment,omitempty"`
+	PR          *PullRequest `json:"pr,omitempty"`
+	Attempts    int          `json:"attempts"`
+	// FailReason is the failure category of the last attempt, in
+	// task.failed events.
+	FailReason FailReason `json:"fail_reason,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// EventProposal is the proposal of a task.proposal_pending event.
+type EventProposal struct {
+	ID      string       `json:"id"`
+	Type    ProposalType `json:"type"`
+	Summary string       `json:"summary"`
+	Reason  string       `json:"reason"`
+}
+
+// EventPublisher sends task lifecycle events to downstream systems. Set
+// with Engine.SetEventPublisher (events).
+type EventPublisher interface {
+	Publish(ctx context.Context, event *TaskEvent) error
+}
+
+// SetEventPublisher sets where the engine publishes task lifecycle events.
+func (e *Engine) SetEventPublisher(p EventPublisher) {
+	e.events = p
+}
+
+// publishPhase publishes the event of task entering phase: created for
+// queued, proposal_pending for awaiting_approval, completed and failed for
+// those phases, and phase_changed for the rest. Dry runs publish nothing.
+func (e *Engine) publishPhase(ctx context.Context, task *Task, phase TaskPhase) {
+	if e.events == nil || e.dryRun {
+		return
+	}
+	event := NewTaskEvent(eventType(phase), task)
+	event.Task.Status = phase
+	if phase == PhaseAwaitingApproval {
+		if p := task.GetPendingProposal(); p != nil {
+			event.Proposal = &EventProposal{ID: p.ID, Type: p.Type, Summary: p.Summary, Reason: p.Reason}
+		}
+	}
+	if err := e.events.Publish(ctx, event); err != nil {
+		e.log().Warn("publish event failed", "type", event.Type, "task", task.ID, "err", err)
+	}
+}
+
+func eventType(phase TaskPhase) string {
+	switch phase {
+	case PhaseQueued:
+		return EventTaskCreated
+	case PhaseAwaitingApproval:
+		return EventTaskProposalPending
+	case PhaseCompleted:
+		return EventTaskCompleted
+	case PhaseFailed:
+		return EventTaskFailed
+	}
+	return EventTaskPhaseChanged
+}
+
+// NewTaskEvent returns an event of type about task as it is now.
+func NewTaskEvent(typ string, task *Task) *TaskEvent {
+	id := make([]byte, 8)
+	rand.Read(id)
+	event := &TaskEvent{
+		ID:   "evt-" + hex.EncodeToString(id),
+		Type: typ,
+		Time: time.Now().UTC(),
+		Task: EventTask{
+			ID:          task.ID,
+			Status:      task.Status,
+			Issue:       task.Issue,
+			Branch:      task.Branch,
+			Environment: task.Environment,
+			PR:          task.PR,
+			Attempts:    len(task.Attempts),
+			CreatedAt:   task.CreatedAt,
+		},
+	}
+	if typ == EventTaskFailed && len(task.Attempts) > 0 {
+		event.Task.FailReason = task.Attempts[len(task.Attempts)-1].FailReason
+	}
+	return event
+}
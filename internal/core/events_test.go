@@ -0,0 +1,97 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// eventRecorder is an EventPublisher that keeps what it is sent.
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []*TaskEvent
+}
+
+func (r *eventRecorder) Publish(ctx context.Context, event *TaskEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *eventRecorder) types() string {
+	var types []string
+	for _, ev := range r.events {
+		if ev.Type == EventTaskPhaseChanged {
+			types = append(types, string(ev.Task.Status))
+		} else {
+			types = append(types, ev.Type)
+		}
+	}
+	return strings.Join(types, ",")
+}
+
+func TestExecute_PublishesLifecycleEvents(t *testing.T) {
+	runner := &mockTestRunner{results: []*TestResult{{Name: "unit-test", Type: "command", Passed: true, Duration: time.Second}}}
+	e := NewEngine(testConfig(), &mockGit{}, &mockAI{}, &mockDeploy{deploySuccess: true}, []TestRunnerIface{runner}, nil, tempStatePath(t))
+	events := &eventRecorder{}
+	e.SetEventPublisher(events)
+
+	if err := e.Execute(context.Background(), testIssue()); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	want := "task.created,planning,coding,committing,deploying,testing,reporting,task.completed"
+	if got := events.types(); got != want {
+		t.Errorf("events = %s, want %s", got, want)
+	}
+	last := events.events[len(events.events)-1]
+	if last.Task.Issue.ID != "42" || last.Task.PR == nil || last.Task.Status != PhaseCompleted || !strings.HasPrefix(last.ID, "evt-") {
+		t.Errorf("completed event = %+v", last)
+	}
+	if events.events[0].ID == last.ID {
+		t.Error("events share an id")
+	}
+}
+
+func TestExecute_PublishesPendingProposalAndFailure(t *testing.T) {
+	cfg := testConfig()
+	cfg.Workflow.Approval.BeforeDeploy = true
+	statePath := tempStatePath(t)
+	e := NewEngine(cfg, &mockGit{}, &mockAI{}, &mockDeploy{deploySuccess: true}, nil, nil, statePath)
+	events := &eventRecorder{}
+	e.SetEventPublisher(events)
+
+	if err := e.Execute(context.Background(), testIssue()); !errors.Is(err, ErrAwaitingApproval) {
+		t.Fatalf("Execute: %v, want awaiting approval", err)
+	}
+	pending := events.events[len(events.events)-1]
+	if pending.Type != EventTaskProposalPending || pending.Proposal == nil || pending.Proposal.Type != ProposalDeployApproval {
+		t.Fatalf("last event = %+v, want a pending deploy approval", pending)
+	}
+
+	state, _ := LoadState(statePath)
+	if err := e.Resume(context.Background(), state.Tasks[0].ID, false); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	failed := events.events[len(events.events)-1]
+	if failed.Type != EventTaskFailed || failed.Task.Status != PhaseFailed {
+		t.Errorf("last event = %+v, want task.failed", failed)
+	}
+}
+
+func TestExecute_DryRunPublishesNothing(t *testing.T) {
+	e := NewEngine(testConfig(), &mockGit{}, &mockAI{}, &mockDeploy{deploySuccess: true}, nil, nil, tempStatePath(t))
+	events := &eventRecorder{}
+	e.SetEventPublisher(events)
+	e.SetDryRun(true)
+
+	if err := e.Execute(context.Background(), testIssue()); err != nil {
+		t.Fatal(err)
+	}
+	if len(events.events) != 0 {
+		t.Errorf("dry run published %s", events.types())
+	}
+}
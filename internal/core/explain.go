@@ -0,0 +1,127 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrExplainUnsupported is returned when the configured AI adapter cannot
+// explain failures.
+var ErrExplainUnsupported = errors.New("ai adapter does not support failure explanations")
+
+// ErrNoFailedAttempt is returned by ExplainFailure for a task without a
+// failed attempt.
+var ErrNoFailedAttempt = errors.New("task has no failed attempt")
+
+// FailureExplainer is an optional AIAdapter capability that diagnoses a
+// failure report in prose.
+type FailureExplainer interface {
+	ExplainFailure(ctx context.Context, report string) (string, error)
+}
+
+// FailureExplanation is an AI diagnosis of a task's failed attempt. It is
+// stored with the task so everyone looking at it sees the same analysis.
+type FailureExplanation struct {
+	Attempt     int       `json:"attempt"`
+	Diagnosis   string    `json:"diagnosis"`
+	RequestedBy string    `json:"requested_by,omitempty"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// maxExplainLogLines bounds the task log lines included in a failure report.
+const maxExplainLogLines = 50
+
+// ExplainFailure asks ai to diagnose the last failed attempt of task from
+// its failure output, changes and the tail of logs, the task's log lines.
+// The caller stores the result in task.Explanation.
+func ExplainFailure(ctx context.Context, ai AIAdapter, task *Task, logs []string) (*FailureExplanation, error) {
+	attempt := lastFailedAttempt(task)
+	if attempt == nil {
+		return nil, fmt.Errorf("%w: task %s", ErrNoFailedAttempt, task.ID)
+	}
+	explainer, ok := ai.(FailureExplainer)
+	if !ok {
+		return nil, ErrExplainUnsupported
+	}
+	text, err := explainer.ExplainFailure(WithTaskUsage(ctx, task), FailureReport(task, attempt, logs))
+	if err != nil {
+		return nil, fmt.Errorf("explain failure of task %s: %w", task.ID, err)
+	}
+	return &FailureExplanation{
+		Attempt:     attempt.Number,
+		Diagnosis:   strings.TrimSpace(text),
+		GeneratedAt: time.Now().UTC(),
+	}, nil
+}
+
+func lastFailedAttempt(task *Task) *Attempt {
+	for i := len(task.Attempts) - 1; i >= 0; i-- {
+		if task.Attempts[i].Status == "failed" {
+			return &task.Attempts[i]
+		}
+	}
+	return nil
+}
+
+// FailureReport renders what an AI needs to diagnose one failed attempt:
+// the issue, the failure output, the files it changed, the diff of the
+// proposals made for it and the last of the task's log lines.
+func FailureReport(task *Task, attempt *Attempt, logs []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Issue: %s #%s %q\n", task.Issue.Repo, task.Issue.ID, task.Issue.Title)
+	if task.Branch != "" {
+		fmt.Fprintf(&b, "Branch: %s\n", task.Branch)
+	}
+	fmt.Fprintf(&b, "Attempt %d of %d: %s", attempt.Number, len(task.Attempts), attempt.Status)
+	if attempt.FailReason != "" {
+		fmt.Fprintf(&b, " (%s)", attempt.FailReason)
+	}
+	b.WriteString("\n")
+	if attempt.Rerun != "" {
+		fmt.Fprintf(&b, "Manual %s rerun without code changes\n", attempt.Rerun)
+	}
+	if attempt.Plan != "" {
+		fmt.Fprintf(&b, "Plan: %s\n", attempt.Plan)
+	}
+	if len(attempt.FilesChanged) > 0 {
+		fmt.Fprintf(&b, "Files changed: %s\n", strings.Join(attempt.FilesChanged, ", "))
+	}
+
+	if d := attempt.Deploy; d != nil {
+		fmt.Fprintf(&b, "\nDeploy %s:\n%s\n", d.Status, truncateReport(d.Output))
+	}
+	for _, r := range attempt.Tests {
+		if r.Passed {
+			continue
+		}
+		output := r.Output
+		if failed := failedCaseOutput(r); failed != "" {
+			output = failed
+		}
+		fmt.Fprintf(&b, "\nFailed test %s:\n%s\n", r.Name, truncateReport(output))
+	}
+	for _, step := range task.Pipeline {
+		if step.Error != "" && !step.StartedAt.Before(attempt.StartedAt) {
+			fmt.Fprintf(&b, "\nStep %s failed: %s\n", step.Phase, truncateReport(step.Error))
+		}
+	}
+
+	for i := range task.Proposals {
+		p := &task.Proposals[i]
+		if p.CreatedAt.Before(attempt.StartedAt) || len(p.Changes) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\nProposal %s (%s, %s): %s\n%s", p.ID, p.Type, p.Status, p.Summary, truncateReport(ProposalDiff(p)))
+	}
+
+	if len(logs) > maxExplainLogLines {
+		logs = logs[len(logs)-maxExplainLogLines:]
+	}
+	if len(logs) > 0 {
+		fmt.Fprintf(&b, "\nTask log:\n%s\n", strings.Join(logs, "\n"))
+	}
+	return b.String()
+}
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type explainingAI struct {
+	mockAI
+	report string
+}
+
+func (a *explainingAI) ExplainFailure(_ context.Context, report string) (string, error) {
+	a.report = report
+	return "  The migration test expects a users table the change never creates.  ", nil
+}
+
+func TestExplainFailure(t *testing.T) {
+	start := time.Date(2026, 9, 1, 10, 0, 0, 0, time.UTC)
+	task := &Task{
+		ID:    "task-1",
+		Issue: Issue{Repo: "acme/app", ID: "7", Title: "Add users"},
+		Attempts: []Attempt{
+			{Number: 1, Status: "failed", FailReason: ReasonAI, StartedAt: start},
+			{
+				Number: 2, Status: "failed", FailReason: ReasonTest, StartedAt: start.Add(time.Hour),
+				FilesChanged: []string{"db/users.go"},
+				Tests:        []TestResult{{Name: "unit", Output: "FAIL: no such table: users"}, {Name: "lint", Passed: true, Output: "clean"}},
+			},
+		},
+		Proposals: []Proposal{{
+			ID: "p1", Type: ProposalDeployFix, Status: ProposalPending, Summary: "Add migration", CreatedAt: start.Add(2 * time.Hour),
+			Changes: []ProposedChange{{Path: "db/001.sql", Action: "create", After: "CREATE TABLE users;"}},
+		}},
+	}
+	logs := make([]string, maxExplainLogLines+5)
+	for i := range logs {
+		logs[i] = "[info] line"
+	}
+	logs[len(logs)-1] = "[error] Task failed"
+
+	ai := &explainingAI{}
+	explanation, err := ExplainFailure(context.Background(), ai, task, logs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if explanation.Attempt != 2 || explanation.Diagnosis != "The migration test expects a users table the change never creates." {
+		t.Errorf("explanation = %+v", explanation)
+	}
+	for _, want := range []string{"Attempt 2 of 2: failed (test_error)", "Files changed: db/users.go", "no such table: users", "+CREATE TABLE users;", "[error] Task failed"} {
+		if !strings.Contains(ai.report, want) {
+			t.Errorf("report lacks %q:\n%s", want, ai.report)
+		}
+	}
+	if strings.Contains(ai.report, "clean") || strings.Count(ai.report, "[info] line") != maxExplainLogLines-1 {
+		t.Errorf("report holds passed tests or too many log lines:\n%s", ai.report)
+	}
+}
+
+func TestExplainFailureWithoutFailure(t *testing.T) {
+	task := &Task{ID: "task-1", Attempts: []Attempt{{Number: 1, Status: "passed"}}}
+	if _, err := ExplainFailure(context.Background(), &explainingAI{}, task, nil); !errors.Is(err, ErrNoFailedAttempt) {
+		t.Errorf("err = %v, want ErrNoFailedAttempt", err)
+	}
+	task.Attempts[0].Status = "failed"
+	if _, err := ExplainFailure(context.Background(), &mockAI{}, task, nil); !errors.Is(err, ErrExplainUnsupported) {
+		t.Errorf("err = %v, want ErrExplainUnsupported", err)
+	}
+}
@@ -0,0 +1,159 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// PastFix is a change that fixed an earlier failure with a similar
+// signature, offered to failure analysis as a hint.
+type PastFix struct {
+	ID         int64
+	Failure    string
+	Diff       string
+	Similarity float64 // 0..1
+}
+
+// FixMemory remembers the changes that fixed failures and finds the ones
+// that fixed failures similar to a new one. Set with Engine.SetFixMemory
+// (ai.fix_memory).
+type FixMemory interface {
+	// Similar returns the past fixes whose failure signature is most
+	// similar to signature, best first.
+	Similar(ctx context.Context, signature string) ([]PastFix, error)
+	// Remember stores diff as the fix of the failure with signature.
+	Remember(ctx context.Context, taskID, signature, failure, diff string) error
+	// RecordUse counts one retry given the fixes ids as hints and whether
+	// its tests passed.
+	RecordUse(ctx context.Context, ids []int64, passed bool) error
+}
+
+// SetFixMemory sets the memory of past fixes consulted before failure
+// analysis and taught by retries that pass.
+func (e *Engine) SetFixMemory(m FixMemory) {
+	e.fixMemory = m
+}
+
+// Limits of what a fix hint sends and stores.
+const (
+	maxSignatureLines  = 20
+	maxFixFailureBytes = 2 * 1024
+	maxFixDiffBytes    = 8 * 1024
+)
+
+var (
+	// failureMarker matches the log lines a failure signature is built
+	// from.
+	failureMarker = regexp.MustCompile(`(?i)error|fail|panic|exception|fatal|timed? ?out|refused|denied|not found|cannot|unable|undefined|expected`)
+
+	signatureNoise = []struct {
+		re   *regexp.Regexp
+		repl string
+	}{
+		{regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`), "<id>"},
+		{regexp.MustCompile(`0x[0-9a-f]+|\b[0-9a-f]{12,}\b`), "<hex>"},
+		{regexp.MustCompile(`/(tmp|var/folders)/\S+`), "<tmp>"},
+		{regexp.MustCompile(`\d+(\.\d+)*`), "<n>"},
+		{regexp.MustCompile(`\s+`), " "},
+	}
+)
+
+// FailureSignature normalizes failure logs into a signature that stays the
+// same when a failure recurs: the lines that report an error, lower-cased,
+// with numbers, durations, hex IDs, UUIDs and temporary paths replaced by
+// placeholders. Logs without such a line are signed by their first lines.
+func FailureSignature(logs string) string {
+	var lines, head []string
+	for _, line := range strings.Split(strings.ToLower(logs), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		for _, n := range signatureNoise {
+			line = n.re.ReplaceAllString(line, n.repl)
+		}
+		if len(head) < 5 {
+			head = append(head, line)
+		}
+		if failureMarker.MatchString(line) && !slices.Contains(lines, line) {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		lines = head
+	}
+	if len(lines) > maxSignatureLines {
+		lines = lines[:maxSignatureLines]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// fixHints returns the past fixes of failures similar to signature. A
+// memory error only warns: the retry goes ahead without hints.
+func (e *Engine) fixHints(ctx context.Context, taskID, signature string) []PastFix {
+	if e.fixMemory == nil || signature == "" {
+		return nil
+	}
+	fixes, err := e.fixMemory.Similar(ctx, signature)
+	if err != nil {
+		e.taskLog(taskID, "warn", fmt.Sprintf("Fix memory lookup failed: %v", err))
+		return nil
+	}
+	return fixes
+}
+
+// formatFixHints prefixes failure logs with the past fixes given as hints.
+func formatFixHints(fixes []PastFix, logs string) string {
+	if len(fixes) == 0 {
+		return logs
+	}
+	var b strings.Builder
+	b.WriteString("Similar failures in this repository were fixed before by the changes below. They are hints: apply one only if it fits this failure.\n")
+	for i, f := range fixes {
+		fmt.Fprintf(&b, "\n[past fix %d, %.0f%% similar]\nFailure:\n%s\nFix:\n%s\n",
+			i+1, f.Similarity*100, truncateBytes(f.Failure, maxFixFailureBytes), truncateBytes(f.Diff, maxFixDiffBytes))
+	}
+	b.WriteString("\nCurrent failure:\n")
+	b.WriteString(logs)
+	return b.String()
+}
+
+// rememberFix teaches the fix memory the outcome of a retry: the use of
+// the hints it was given and, when its tests passed, its changes as the
+// fix of the failure it retried.
+func (e *Engine) rememberFix(ctx context.Context, task *Task, signature, failureLogs string, hints []int64, before map[string]string, changes []AIFileChange, passed bool) {
+	if e.fixMemory == nil {
+		return
+	}
+	if err := e.fixMemory.RecordUse(ctx, hints, passed); err != nil {
+		e.taskLog(task.ID, "warn", fmt.Sprintf("Fix memory update failed: %v", err))
+	}
+	if !passed || signature == "" || len(changes) == 0 {
+		return
+	}
+	var diff strings.Builder
+	for _, c := range changes {
+		after := c.Content
+		if c.Action == "delete" {
+			after = ""
+		}
+		diff.WriteString(UnifiedDiff(c.Path, before[c.Path], after))
+	}
+	err := e.fixMemory.Remember(ctx, task.ID, signature, truncateBytes(failureLogs, maxFixFailureBytes), truncateBytes(diff.String(), maxFixDiffBytes))
+	if err != nil {
+		e.taskLog(task.ID, "warn", fmt.Sprintf("Fix memory update failed: %v", err))
+		return
+	}
+	e.taskLog(task.ID, "info", "Remembered the fix for similar failures")
+}
+
+// truncateBytes cuts s to at most n bytes.
+func truncateBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "\n... (truncated)"
+}
@@ -0,0 +1,82 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// fakeFixMemory is an in-memory FixMemory.
+type fakeFixMemory struct {
+	similar    []PastFix
+	signatures []string
+	remembered []string // diffs
+	uses       map[int64][2]int
+}
+
+func (f *fakeFixMemory) Similar(ctx context.Context, signature string) ([]PastFix, error) {
+	f.signatures = append(f.signatures, signature)
+	return f.similar, nil
+}
+
+func (f *fakeFixMemory) Remember(ctx context.Context, taskID, signature, failure, diff string) error {
+	f.remembered = append(f.remembered, diff)
+	return nil
+}
+
+func (f *fakeFixMemory) RecordUse(ctx context.Context, ids []int64, passed bool) error {
+	if f.uses == nil {
+		f.uses = make(map[int64][2]int)
+	}
+	for _, id := range ids {
+		u := f.uses[id]
+		u[0]++
+		if passed {
+			u[1]++
+		}
+		f.uses[id] = u
+	}
+	return nil
+}
+
+func TestFailureSignature(t *testing.T) {
+	a := FailureSignature("=== RUN TestDB\n2025-01-02T10:00:00Z dial tcp 10.0.0.7:5432: connect: connection refused\n--- FAIL: TestDB (0.53s)\nok\n")
+	b := FailureSignature("=== RUN TestDB\n2025-03-09T23:59:01Z dial tcp 10.0.0.9:5432: connect: connection refused\n--- FAIL: TestDB (1.20s)\n")
+	if a != b {
+		t.Fatalf("recurring failure should have the same signature:\n%s\n---\n%s", a, b)
+	}
+	if strings.Contains(a, "run testdb") || !strings.Contains(a, "connection refused") {
+		t.Errorf("signature should keep only the error lines, got %q", a)
+	}
+	if got := FailureSignature("just output\nmore output"); got != "just output\nmore output" {
+		t.Errorf("logs without errors should be signed by their first lines, got %q", got)
+	}
+}
+
+func TestRetryLoop_FixMemory(t *testing.T) {
+	var analyzed string
+	aiMock := &mockAI{failureFunc: func(ctx context.Context, logs string, currentCode map[string]string) ([]AIFileChange, error) {
+		analyzed = logs
+		return []AIFileChange{{Path: "main.go", Content: "package main\n\nfunc main() {}", Action: "modify"}}, nil
+	}}
+	testRunner := &mockTestRunner{results: []*TestResult{{Name: "unit-test", Type: "command", Passed: true, Output: "PASS"}}}
+	engine, task, _, vars, initialResults, initialChanges := newRetryTestHarness(t, 3, aiMock, &mockGit{}, &mockDeploy{deploySuccess: true}, testRunner)
+	memory := &fakeFixMemory{similar: []PastFix{{ID: 7, Failure: "FAIL before", Diff: "+retry the connection", Similarity: 0.8}}}
+	engine.SetFixMemory(memory)
+
+	if err := retryLoop(context.Background(), engine, task, vars, initialResults, initialChanges, 3); err != nil {
+		t.Fatalf("retryLoop: %v", err)
+	}
+	if !strings.Contains(analyzed, "+retry the connection") || !strings.Contains(analyzed, "80% similar") {
+		t.Errorf("failure analysis should get the past fix as a hint, got %q", analyzed)
+	}
+	if got := task.Attempts[1].FixHints; len(got) != 1 || got[0] != 7 {
+		t.Errorf("attempt fix hints = %v", got)
+	}
+	if memory.uses[7] != [2]int{1, 1} {
+		t.Errorf("hint use = %v, want one passed use", memory.uses[7])
+	}
+	if len(memory.remembered) != 1 || !strings.Contains(memory.remembered[0], "+func main() {}") {
+		t.Errorf("passing retry should be remembered as a diff, got %q", memory.remembered)
+	}
+}
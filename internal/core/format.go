@@ -0,0 +1,47 @@
+package core
+
+import (
+	"context"
+	"fmt"
+)
+
+// Formatter formats the generated files before they are committed. Set
+// with Engine.SetFormatter (workflow.format).
+type Formatter interface {
+	// Format returns changes with their files formatted. When some
+	// formatters fail, it returns the changes as the others formatted them
+	// along with the errors.
+	Format(ctx context.Context, changes []AIFileChange) ([]AIFileChange, error)
+}
+
+// SetFormatter sets the formatter run on the generated files before each
+// commit.
+func (e *Engine) SetFormatter(f Formatter) {
+	e.formatter = f
+}
+
+// formatChanges formats changes before they are committed. A failing
+// formatter only warns: the tests and the repository's CI still judge the
+// code.
+func (e *Engine) formatChanges(ctx context.Context, task *Task, changes []AIFileChange) []AIFileChange {
+	if e.formatter == nil || len(changes) == 0 {
+		return changes
+	}
+	formatted, err := e.formatter.Format(ctx, changes)
+	if err != nil {
+		e.taskLog(task.ID, "warn", fmt.Sprintf("Formatting failed: %v", err))
+	}
+	if len(formatted) != len(changes) {
+		return changes
+	}
+	n := 0
+	for i := range changes {
+		if formatted[i].Content != changes[i].Content {
+			n++
+		}
+	}
+	if n > 0 {
+		e.taskLog(task.ID, "info", fmt.Sprintf("Formatted %d file(s)", n))
+	}
+	return formatted
+}
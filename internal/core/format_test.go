@@ -0,0 +1,65 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rigdev/rig/internal/config"
+)
+
+// formatFunc is a Formatter that answers with fn.
+type formatFunc func(changes []AIFileChange) ([]AIFileChange, error)
+
+func (f formatFunc) Format(ctx context.Context, changes []AIFileChange) ([]AIFileChange, error) {
+	return f(changes)
+}
+
+// committingGit is a mockGit that records the committed changes.
+type committingGit struct {
+	*mockGit
+	committed []GitFileChange
+}
+
+func (g *committingGit) CommitAndPush(ctx context.Context, changes []GitFileChange, message string) error {
+	g.committed = append(g.committed, changes...)
+	return g.mockGit.CommitAndPush(ctx, changes, message)
+}
+
+func TestExecute_FormatsBeforeCommit(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		format formatFunc
+		want   string
+	}{
+		{"formatted", func(changes []AIFileChange) ([]AIFileChange, error) {
+			out := append([]AIFileChange(nil), changes...)
+			out[0].Content = strings.ToUpper(out[0].Content)
+			return out, nil
+		}, "PACKAGE MAIN"},
+		{"failing formatter only warns", func(changes []AIFileChange) ([]AIFileChange, error) {
+			return changes, errors.New("gofmt: syntax error")
+		}, "package main"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			git := &committingGit{mockGit: &mockGit{}}
+			var hooked string
+			hooks := []config.HookConfig{{Name: "check", On: "pre_commit", Run: "check"}}
+			e, _ := hookEngine(t, git, hooks, func(hook config.HookConfig, input *HookInput) (*HookOutput, error) {
+				hooked = input.Changes[0].Content
+				return &HookOutput{}, nil
+			})
+			e.SetFormatter(tc.format)
+			if err := e.Execute(context.Background(), testIssue()); err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+			if len(git.committed) == 0 || git.committed[0].Content != tc.want {
+				t.Errorf("committed %+v, want %q", git.committed, tc.want)
+			}
+			if hooked != tc.want {
+				t.Errorf("pre_commit hook saw %q, want %q", hooked, tc.want)
+			}
+		})
+	}
+}
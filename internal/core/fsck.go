@@ -0,0 +1,161 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// FsckKind classifies a state invariant violation found by Fsck.
+type FsckKind string
+
+const (
+	FsckOrphanedProposal FsckKind = "orphaned_proposal"
+	FsckMissingProposal  FsckKind = "missing_proposal"
+	FsckOpenAttempt      FsckKind = "open_attempt"
+	FsckRunningStep      FsckKind = "running_step"
+	FsckMissingBranch    FsckKind = "missing_branch"
+	FsckStaleDBTask      FsckKind = "stale_db_task"
+)
+
+// FsckFinding is a single invariant violation on a task.
+type FsckFinding struct {
+	TaskID     string   `json:"task_id"`
+	Kind       FsckKind `json:"kind"`
+	Detail     string   `json:"detail"`
+	Repairable bool     `json:"repairable"`
+}
+
+// BranchChecker is an optional GitAdapter capability used by Fsck to verify
+// that branches referenced by tasks still exist on the remote.
+type BranchChecker interface {
+	BranchExists(ctx context.Context, branch string) (bool, error)
+}
+
+// fsckBranchPhases are phases in which the task branch must already be pushed.
+var fsckBranchPhases = map[TaskPhase]bool{
+	PhaseApproval:         true,
+	PhaseDeploying:        true,
+	PhaseTesting:          true,
+	PhaseReporting:        true,
+	PhaseAwaitingApproval: true,
+}
+
+// Fsck checks state invariants and returns every violation found. Tasks that
+// are still in flight may legitimately have running attempts and steps, so
+// those checks only apply to inactive tasks. branches may be nil to skip the
+// remote branch check.
+func Fsck(ctx context.Context, s *State, branches BranchChecker) ([]FsckFinding, error) {
+	var findings []FsckFinding
+	for i := range s.Tasks {
+		task := &s.Tasks[i]
+		add := func(kind FsckKind, repairable bool, format string, args ...interface{}) {
+			findings = append(findings, FsckFinding{
+				TaskID:     task.ID,
+				Kind:       kind,
+				Detail:     fmt.Sprintf(format, args...),
+				Repairable: repairable,
+			})
+		}
+
+		pending := task.GetPendingProposal()
+		if task.Status == PhaseAwaitingApproval && pending == nil {
+			add(FsckMissingProposal, true, "task is awaiting approval but has no pending proposal")
+		}
+		if task.Status != PhaseAwaitingApproval {
+			for _, p := range task.Proposals {
+				if p.Status == ProposalPending {
+					add(FsckOrphanedProposal, true, "proposal %s is pending but task is %s", p.ID, task.Status)
+				}
+			}
+		}
+
+		if inactivePhases[task.Status] {
+			for _, a := range task.Attempts {
+				if a.CompletedAt == nil || a.Status == "running" {
+					add(FsckOpenAttempt, true, "attempt %d has no terminal status", a.Number)
+				}
+			}
+			for _, step := range task.Pipeline {
+				if step.Status == "running" {
+					add(FsckRunningStep, true, "pipeline step %s is still running", step.Phase)
+				}
+			}
+		}
+
+		if branches != nil && task.Branch != "" && fsckBranchPhases[task.Status] {
+			exists, err := branches.BranchExists(ctx, task.Branch)
+			if err != nil {
+				return findings, fmt.Errorf("check branch %s: %w", task.Branch, err)
+			}
+			if !exists {
+				add(FsckMissingBranch, false, "branch %s does not exist on the remote", task.Branch)
+			}
+		}
+	}
+	return findings, nil
+}
+
+// RepairState fixes the repairable findings in place and returns how many
+// tasks were changed. Orphaned proposals are rejected, open attempts and
+// running steps are closed as failed, and tasks stuck awaiting approval
+// without a proposal are failed so they can be retried.
+func RepairState(s *State, findings []FsckFinding) int {
+	now := time.Now().UTC()
+	byTask := make(map[string]map[FsckKind]bool)
+	for _, f := range findings {
+		if !f.Repairable {
+			continue
+		}
+		if byTask[f.TaskID] == nil {
+			byTask[f.TaskID] = make(map[FsckKind]bool)
+		}
+		byTask[f.TaskID][f.Kind] = true
+	}
+
+	repaired := 0
+	for i := range s.Tasks {
+		task := &s.Tasks[i]
+		kinds := byTask[task.ID]
+		if len(kinds) == 0 {
+			continue
+		}
+
+		if kinds[FsckOrphanedProposal] {
+			for j := range task.Proposals {
+				if task.Proposals[j].Status == ProposalPending {
+					task.Proposals[j].Status = ProposalRejected
+					task.Proposals[j].ReviewedAt = &now
+				}
+			}
+		}
+		if kinds[FsckMissingProposal] {
+			// awaiting_approval → failed is a valid transition.
+			_ = Transition(task, PhaseFailed)
+		}
+		if kinds[FsckOpenAttempt] || kinds[FsckMissingProposal] {
+			for j := range task.Attempts {
+				a := &task.Attempts[j]
+				if a.CompletedAt == nil || a.Status == "running" {
+					if a.Status == "running" || a.Status == "" {
+						a.Status = "failed"
+						a.FailReason = ReasonUnknown
+					}
+					a.CompletedAt = &now
+				}
+			}
+		}
+		if kinds[FsckRunningStep] || kinds[FsckMissingProposal] {
+			for j := range task.Pipeline {
+				step := &task.Pipeline[j]
+				if step.Status == "running" {
+					step.Status = "failed"
+					step.EndedAt = &now
+					step.Error = "interrupted: closed by rig fsck"
+				}
+			}
+		}
+		repaired++
+	}
+	return repaired
+}
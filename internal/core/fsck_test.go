@@ -0,0 +1,101 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type fakeBranches map[string]bool
+
+func (f fakeBranches) BranchExists(_ context.Context, branch string) (bool, error) {
+	return f[branch], nil
+}
+
+func TestFsckFindsAndRepairsInvariants(t *testing.T) {
+	now := time.Now().UTC()
+	s := &State{Version: "1.0", Tasks: []Task{
+		{
+			ID:     "task-orphan",
+			Status: PhaseFailed,
+			Proposals: []Proposal{
+				{ID: "prop-1", Status: ProposalPending},
+			},
+			Attempts: []Attempt{
+				{Number: 1, Status: "running", StartedAt: now},
+			},
+			Pipeline: []PipelineStep{
+				{Phase: PhaseTesting, Status: "running", StartedAt: now},
+			},
+			CompletedAt: &now,
+		},
+		{
+			ID:     "task-stuck",
+			Branch: "rig/issue-7",
+			Status: PhaseAwaitingApproval,
+		},
+		{
+			ID:     "task-inflight",
+			Branch: "rig/issue-8",
+			Status: PhaseTesting,
+			Attempts: []Attempt{
+				{Number: 1, Status: "running", StartedAt: now},
+			},
+		},
+	}}
+
+	findings, err := Fsck(context.Background(), s, fakeBranches{"rig/issue-8": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string][]FsckKind{}
+	for _, f := range findings {
+		got[f.TaskID] = append(got[f.TaskID], f.Kind)
+	}
+	want := map[string][]FsckKind{
+		"task-orphan": {FsckOrphanedProposal, FsckOpenAttempt, FsckRunningStep},
+		"task-stuck":  {FsckMissingProposal, FsckMissingBranch},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("findings = %+v", findings)
+	}
+	for id, kinds := range want {
+		if len(got[id]) != len(kinds) {
+			t.Fatalf("%s: kinds = %v, want %v", id, got[id], kinds)
+		}
+		for i := range kinds {
+			if got[id][i] != kinds[i] {
+				t.Errorf("%s: kinds = %v, want %v", id, got[id], kinds)
+			}
+		}
+	}
+
+	if n := RepairState(s, findings); n != 2 {
+		t.Fatalf("repaired %d tasks, want 2", n)
+	}
+	orphan := s.GetTaskByID("task-orphan")
+	if orphan.Proposals[0].Status != ProposalRejected || orphan.Proposals[0].ReviewedAt == nil {
+		t.Errorf("proposal not rejected: %+v", orphan.Proposals[0])
+	}
+	if a := orphan.Attempts[0]; a.Status != "failed" || a.CompletedAt == nil {
+		t.Errorf("attempt not closed: %+v", a)
+	}
+	if step := orphan.Pipeline[0]; step.Status != "failed" || step.EndedAt == nil {
+		t.Errorf("step not closed: %+v", step)
+	}
+	if stuck := s.GetTaskByID("task-stuck"); stuck.Status != PhaseFailed {
+		t.Errorf("stuck task status = %s, want failed", stuck.Status)
+	}
+	if inflight := s.GetTaskByID("task-inflight"); inflight.Attempts[0].Status != "running" {
+		t.Error("in-flight task must not be touched")
+	}
+
+	after, err := Fsck(context.Background(), s, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != 0 {
+		t.Errorf("expected clean state after repair, got %+v", after)
+	}
+}
@@ -0,0 +1,162 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rigdev/rig/internal/config"
+)
+
+// HookPoint is a pipeline boundary workflow.hooks scripts run at.
+type HookPoint string
+
+const (
+	// HookPreCommit runs before the generated changes are committed; a
+	// veto fails the commit.
+	HookPreCommit HookPoint = "pre_commit"
+	// HookPostDeploy runs after a successful deploy; a veto fails the
+	// deploy, which is then handled like any failed deploy.
+	HookPostDeploy HookPoint = "post_deploy"
+	// HookPrePR runs before the PR is opened or marked ready; it can
+	// rewrite the PR body, and a veto fails the report.
+	HookPrePR HookPoint = "pre_pr"
+)
+
+// HookInput is what a hook script is given: the task, the variables of the
+// pipeline, and what the boundary is about.
+type HookInput struct {
+	Hook    HookPoint         `json:"hook"`
+	Task    *Task             `json:"task"`
+	Vars    map[string]string `json:"vars,omitempty"`
+	Changes []AIFileChange    `json:"changes,omitempty"` // pre_commit
+	Deploy  *DeployResult     `json:"deploy,omitempty"`  // post_deploy
+	PR      *HookPR           `json:"pr,omitempty"`      // pre_pr
+}
+
+// HookPR is the PR a pre_pr hook sees, as the hooks before it left it.
+type HookPR struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// HookOutput is what a hook script answers. The zero value lets the step
+// go ahead unchanged.
+type HookOutput struct {
+	// Veto stops the step with this reason.
+	Veto string `json:"veto,omitempty"`
+	// PRBody replaces the PR body; only pre_pr hooks can set it.
+	PRBody string `json:"pr_body,omitempty"`
+	// Labels are added to the task's PR once it is opened.
+	Labels []string `json:"labels,omitempty"`
+}
+
+// HookRunner runs one workflow.hooks script. Set with Engine.SetHookRunner.
+type HookRunner interface {
+	RunHook(ctx context.Context, hook config.HookConfig, input *HookInput) (*HookOutput, error)
+}
+
+// PRLabeler is implemented by GitAdapters that can label pull requests.
+type PRLabeler interface {
+	AddLabels(ctx context.Context, number int, labels []string) error
+}
+
+// HookVetoError is the error of a step a hook vetoed.
+type HookVetoError struct {
+	Hook   string
+	Point  HookPoint
+	Reason string
+}
+
+func (e *HookVetoError) Error() string {
+	return fmt.Sprintf("%s hook %q vetoed: %s", e.Point, e.Hook, e.Reason)
+}
+
+// SetHookRunner sets the runner of the workflow.hooks scripts.
+func (e *Engine) SetHookRunner(r HookRunner) {
+	e.hooks = r
+}
+
+// runHooks runs the hooks of input.Hook in config order. Labels they
+// return are recorded on task for its PR, and a pre_pr hook's body
+// replaces input.PR.Body for the hooks after it. The first veto stops the
+// run with a *HookVetoError; so does a hook that fails to run, as hooks
+// enforce policies.
+func (e *Engine) runHooks(ctx context.Context, task *Task, input *HookInput) error {
+	if e.hooks == nil {
+		return nil
+	}
+	for _, hook := range e.cfg.Workflow.Hooks {
+		if HookPoint(hook.On) != input.Hook {
+			continue
+		}
+		input.Task = task
+		out, err := e.hooks.RunHook(ctx, hook, input)
+		if err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			out = &HookOutput{Veto: err.Error()}
+		}
+		if out == nil {
+			out = &HookOutput{}
+		}
+		if out.Veto != "" {
+			e.taskLog(task.ID, "error", fmt.Sprintf("Hook %s vetoed %s: %s", hook.Name, input.Hook, out.Veto))
+			return &HookVetoError{Hook: hook.Name, Point: input.Hook, Reason: out.Veto}
+		}
+		if out.PRBody != "" && input.PR != nil {
+			input.PR.Body = out.PRBody
+			e.taskLog(task.ID, "info", fmt.Sprintf("Hook %s rewrote the PR body", hook.Name))
+		}
+		for _, l := range out.Labels {
+			if l = strings.TrimSpace(l); l != "" && !slices.Contains(task.PRLabels, l) {
+				task.PRLabels = append(task.PRLabels, l)
+			}
+		}
+	}
+	return nil
+}
+
+// deployStep deploys task with stepDeploy and runs the post_deploy hooks
+// on a successful deploy. A veto turns it into a failed deploy whose
+// output ends with the reason.
+func (e *Engine) deployStep(ctx context.Context, task *Task, vars map[string]string) (*DeployResult, error) {
+	result, err := stepDeploy(ctx, e.deployer(task), vars)
+	if err != nil || result.Status != "success" {
+		return result, err
+	}
+	err = e.runHooks(ctx, task, &HookInput{Hook: HookPostDeploy, Vars: vars, Deploy: result})
+	var veto *HookVetoError
+	if errors.As(err, &veto) {
+		result.Status = "failed"
+		result.Output += "\n--- hook ---\n" + veto.Error()
+		return result, nil
+	}
+	return result, err
+}
+
+// applyPRLabels adds the labels hooks recorded on task to its PR. Failures
+// only warn: the PR is already open.
+func (e *Engine) applyPRLabels(ctx context.Context, task *Task, pr *PullRequest) {
+	if len(task.PRLabels) == 0 || pr == nil {
+		return
+	}
+	labeler, ok := e.git.(PRLabeler)
+	if !ok {
+		e.taskLog(task.ID, "warn", "Git adapter cannot label PRs; skipping hook labels")
+		return
+	}
+	number, err := strconv.Atoi(pr.ID)
+	if err != nil {
+		return
+	}
+	if err := labeler.AddLabels(ctx, number, task.PRLabels); err != nil {
+		e.taskLog(task.ID, "warn", fmt.Sprintf("Could not label PR: %v", err))
+		return
+	}
+	e.taskLog(task.ID, "info", fmt.Sprintf("Labeled PR with %s", strings.Join(task.PRLabels, ", ")))
+}
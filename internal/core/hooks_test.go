@@ -0,0 +1,120 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+)
+
+// hookFunc is a HookRunner that answers every hook with fn.
+type hookFunc func(hook config.HookConfig, input *HookInput) (*HookOutput, error)
+
+func (f hookFunc) RunHook(ctx context.Context, hook config.HookConfig, input *HookInput) (*HookOutput, error) {
+	return f(hook, input)
+}
+
+// labelingGit is a mockGit that records the PR body and labels.
+type labelingGit struct {
+	*mockGit
+	body   string
+	labels []string
+}
+
+func (g *labelingGit) CreatePR(ctx context.Context, base, head, title, body string) (*GitPullRequest, error) {
+	g.body = body
+	return g.mockGit.CreatePR(ctx, base, head, title, body)
+}
+
+func (g *labelingGit) AddLabels(ctx context.Context, number int, labels []string) error {
+	g.labels = append(g.labels, labels...)
+	return nil
+}
+
+func hookEngine(t *testing.T, git GitAdapter, hooks []config.HookConfig, fn hookFunc) (*Engine, string) {
+	cfg := testConfig()
+	cfg.Workflow.Hooks = hooks
+	statePath := tempStatePath(t)
+	runner := &mockTestRunner{results: []*TestResult{{Name: "unit-test", Type: "command", Passed: true, Duration: time.Second}}}
+	e := NewEngine(cfg, git, &mockAI{}, &mockDeploy{deploySuccess: true}, []TestRunnerIface{runner}, nil, statePath)
+	e.SetHookRunner(fn)
+	return e, statePath
+}
+
+func TestExecute_PreCommitHookVetoes(t *testing.T) {
+	git := &mockGit{}
+	e, statePath := hookEngine(t, git, []config.HookConfig{{Name: "no-secrets", On: "pre_commit", Run: "check"}},
+		func(hook config.HookConfig, input *HookInput) (*HookOutput, error) {
+			if len(input.Changes) == 0 || input.Task == nil || input.Vars["BRANCH_NAME"] == "" {
+				t.Errorf("pre_commit hook got %+v", input)
+			}
+			return &HookOutput{Veto: "secrets.env must not be committed"}, nil
+		})
+
+	err := e.Execute(context.Background(), testIssue())
+	var veto *HookVetoError
+	if !errors.As(err, &veto) || veto.Hook != "no-secrets" {
+		t.Fatalf("expected a veto by no-secrets, got %v", err)
+	}
+	if git.commitAndPushCalls != 0 {
+		t.Errorf("vetoed changes were committed")
+	}
+	state, _ := LoadState(statePath)
+	if task := state.Tasks[0]; task.Status != PhaseFailed || task.Attempts[0].FailReason != ReasonConfig {
+		t.Errorf("task = %s (%s), want failed with %s", task.Status, task.Attempts[0].FailReason, ReasonConfig)
+	}
+}
+
+func TestExecute_PrePRHookRewritesBodyAndLabels(t *testing.T) {
+	git := &labelingGit{mockGit: &mockGit{}}
+	hooks := []config.HookConfig{
+		{Name: "labels", On: "pre_commit", Run: "labels"},
+		{Name: "body", On: "pre_pr", Run: "body"},
+		{Name: "footer", On: "pre_pr", Run: "footer"},
+	}
+	e, _ := hookEngine(t, git, hooks, func(hook config.HookConfig, input *HookInput) (*HookOutput, error) {
+		switch hook.Name {
+		case "labels":
+			return &HookOutput{Labels: []string{"rig", "needs-review"}}, nil
+		case "body":
+			return &HookOutput{PRBody: "Owners: @platform\n\n" + input.PR.Body, Labels: []string{"rig"}}, nil
+		default:
+			if !strings.HasPrefix(input.PR.Body, "Owners: @platform") {
+				t.Errorf("footer hook should see the rewritten body, got %q", input.PR.Body)
+			}
+			return nil, nil
+		}
+	})
+
+	if err := e.Execute(context.Background(), testIssue()); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if !strings.HasPrefix(git.body, "Owners: @platform\n\n") {
+		t.Errorf("PR body = %q", git.body)
+	}
+	if !reflect.DeepEqual(git.labels, []string{"rig", "needs-review"}) {
+		t.Errorf("PR labels = %v", git.labels)
+	}
+}
+
+func TestDeployStep_PostDeployHookVetoFailsDeploy(t *testing.T) {
+	e, _ := hookEngine(t, &mockGit{}, []config.HookConfig{{Name: "slo", On: "post_deploy", Run: "slo"}},
+		func(hook config.HookConfig, input *HookInput) (*HookOutput, error) {
+			if input.Deploy == nil || input.Deploy.Status != "success" {
+				t.Errorf("post_deploy hook got deploy %+v", input.Deploy)
+			}
+			return nil, errors.New("exit status 1: error budget exhausted")
+		})
+
+	result, err := e.deployStep(context.Background(), &Task{ID: "task-1"}, map[string]string{"COMMIT_SHA": "abc"})
+	if err != nil {
+		t.Fatalf("deployStep: %v", err)
+	}
+	if result.Status != "failed" || !strings.Contains(result.Output, "error budget exhausted") {
+		t.Errorf("expected a deploy failed by the hook, got %+v", result)
+	}
+}
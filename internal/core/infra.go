@@ -1,7 +1,7 @@
 package core
 
 import (
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
@@ -16,20 +16,20 @@ func loadInfraFiles(patterns []string) map[string]string {
 	// Resolve the working directory as the allowed base for path validation.
 	cwd, err := os.Getwd()
 	if err != nil {
-		log.Printf("[engine] cannot determine working directory: %v", err)
+		slog.Warn("cannot determine working directory", "err", err)
 		return files
 	}
 
 	for _, pattern := range patterns {
 		// Block absolute paths and obvious traversal patterns.
 		if filepath.IsAbs(pattern) || strings.Contains(pattern, "..") {
-			log.Printf("[engine] blocked infra pattern %q: absolute or traversal path", pattern)
+			slog.Warn("blocked infra pattern: absolute or traversal path", "pattern", pattern)
 			continue
 		}
 
 		matches, err := filepath.Glob(pattern)
 		if err != nil {
-			log.Printf("[engine] invalid infra glob pattern %q: %v", pattern, err)
+			slog.Warn("invalid infra glob pattern", "pattern", pattern, "err", err)
 			continue
 		}
 
@@ -37,17 +37,17 @@ func loadInfraFiles(patterns []string) map[string]string {
 			// Validate resolved path stays within working directory.
 			absPath, err := filepath.Abs(path)
 			if err != nil {
-				log.Printf("[engine] cannot resolve %q: %v", path, err)
+				slog.Warn("cannot resolve infra file", "path", path, "err", err)
 				continue
 			}
 			if !strings.HasPrefix(absPath, cwd+string(filepath.Separator)) && absPath != cwd {
-				log.Printf("[engine] blocked infra file %q: outside working directory", path)
+				slog.Warn("blocked infra file: outside working directory", "path", path)
 				continue
 			}
 
 			content, err := os.ReadFile(path)
 			if err != nil {
-				log.Printf("[engine] failed to read infra file %q: %v", path, err)
+				slog.Warn("failed to read infra file", "path", path, "err", err)
 				continue
 			}
 			files[path] = string(content)
@@ -0,0 +1,81 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Issue update events for workflow.issue_updates.events.
+const (
+	IssueUpdatePlan   = "plan"
+	IssueUpdatePR     = "pr"
+	IssueUpdateFailed = "failed"
+)
+
+// IssueCommenter is implemented by GitAdapters that can comment on issues.
+// owner/repo come from the task's issue, which may live in another repo.
+type IssueCommenter interface {
+	PostComment(ctx context.Context, owner, repo string, number int, body string) error
+}
+
+// postIssueUpdate comments on the task's source issue if workflow.issue_updates
+// enables event. Comments are best-effort and never fail the task.
+func (e *Engine) postIssueUpdate(ctx context.Context, task *Task, event, body string) {
+	cfg := e.cfg.Workflow.IssueUpdates
+	if !cfg.Enabled || (len(cfg.Events) > 0 && !slices.Contains(cfg.Events, event)) {
+		return
+	}
+	e.postIssueComment(ctx, task, body)
+}
+
+// postIssueComment comments on the task's source issue, best-effort.
+func (e *Engine) postIssueComment(ctx context.Context, task *Task, body string) {
+	e.commentOnIssue(ctx, task.ID, task.Issue, body)
+}
+
+// commentOnIssue comments on issue, best-effort, logging a failure to the
+// task taskID.
+func (e *Engine) commentOnIssue(ctx context.Context, taskID string, issue Issue, body string) {
+	commenter, ok := e.git.(IssueCommenter)
+	if !ok {
+		return
+	}
+	number, err := strconv.Atoi(issue.ID)
+	if err != nil {
+		return
+	}
+	owner, repo := parseRepo(e.issueRepo(issue))
+	if err := commenter.PostComment(ctx, owner, repo, number, "**[rig]** "+body); err != nil {
+		e.taskLog(taskID, "warn", fmt.Sprintf("Could not post issue update: %v", err))
+	}
+}
+
+// planUpdate is the comment posted when the plan is ready.
+func planUpdate(plan *AIPlan) string {
+	var b strings.Builder
+	b.WriteString("Plan ready: ")
+	b.WriteString(plan.Summary)
+	b.WriteString("\n")
+	for i, step := range plan.Steps {
+		fmt.Fprintf(&b, "\n%d. %s", i+1, step)
+	}
+	return b.String()
+}
+
+// testFailureUpdate is the comment posted when retries are exhausted.
+func testFailureUpdate(maxRetry int, results []TestResult) string {
+	var failed []string
+	for _, r := range results {
+		if !r.Passed {
+			failed = append(failed, "`"+r.Name+"`")
+		}
+	}
+	msg := fmt.Sprintf("Tests still failing after %d retries; giving up.", maxRetry)
+	if len(failed) > 0 {
+		msg += " Failing: " + strings.Join(failed, ", ")
+	}
+	return msg
+}
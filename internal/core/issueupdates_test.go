@@ -0,0 +1,81 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// commentGit is a mockGit that records issue comments.
+type commentGit struct {
+	mockGit
+	comments []string
+	targets  []string
+}
+
+func (m *commentGit) PostComment(ctx context.Context, owner, repo string, number int, body string) error {
+	m.comments = append(m.comments, body)
+	m.targets = append(m.targets, owner+"/"+repo)
+	return nil
+}
+
+func TestEngine_IssueUpdatesPlanAndPR(t *testing.T) {
+	cfg := testConfig()
+	cfg.Workflow.IssueUpdates.Enabled = true
+	gitMock := &commentGit{}
+	engine := NewEngine(cfg, gitMock, &mockAI{}, &mockDeploy{deploySuccess: true},
+		[]TestRunnerIface{&mockTestRunner{}}, nil, tempStatePath(t))
+
+	if err := engine.Execute(context.Background(), testIssue()); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if len(gitMock.comments) != 2 {
+		t.Fatalf("expected plan and PR comments, got %v", gitMock.comments)
+	}
+	if !strings.Contains(gitMock.comments[0], "Plan ready: test plan") || !strings.Contains(gitMock.comments[0], "1. step1") {
+		t.Errorf("unexpected plan comment %q", gitMock.comments[0])
+	}
+	if !strings.Contains(gitMock.comments[1], "Opened PR https://github.com/test/repo/pull/1") {
+		t.Errorf("unexpected PR comment %q", gitMock.comments[1])
+	}
+	if gitMock.targets[0] != "test/repo" {
+		t.Errorf("expected comments on the issue repo, got %s", gitMock.targets[0])
+	}
+}
+
+func TestEngine_IssueUpdatesFailedAfterMaxRetries(t *testing.T) {
+	cfg := testConfig()
+	cfg.AI.MaxRetry = 1
+	cfg.Workflow.IssueUpdates.Enabled = true
+	cfg.Workflow.IssueUpdates.Events = []string{IssueUpdateFailed}
+	gitMock := &commentGit{}
+	testRunner := &mockTestRunner{results: []*TestResult{
+		{Name: "unit-test", Type: "command", Passed: false, Output: "FAIL 1"},
+		{Name: "unit-test", Type: "command", Passed: false, Output: "FAIL 2"},
+	}}
+	engine := NewEngine(cfg, gitMock, &mockAI{}, &mockDeploy{deploySuccess: true},
+		[]TestRunnerIface{testRunner}, nil, tempStatePath(t))
+
+	if err := engine.Execute(context.Background(), testIssue()); err == nil {
+		t.Fatal("expected failure after max retries")
+	}
+	if len(gitMock.comments) != 1 {
+		t.Fatalf("expected only the failure comment, got %v", gitMock.comments)
+	}
+	if !strings.Contains(gitMock.comments[0], "after 1 retries") || !strings.Contains(gitMock.comments[0], "`unit-test`") {
+		t.Errorf("unexpected failure comment %q", gitMock.comments[0])
+	}
+}
+
+func TestEngine_IssueUpdatesDisabled(t *testing.T) {
+	gitMock := &commentGit{}
+	engine := NewEngine(testConfig(), gitMock, &mockAI{}, &mockDeploy{deploySuccess: true},
+		[]TestRunnerIface{&mockTestRunner{}}, nil, tempStatePath(t))
+
+	if err := engine.Execute(context.Background(), testIssue()); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if len(gitMock.comments) != 0 {
+		t.Fatalf("expected no comments without workflow.issue_updates, got %v", gitMock.comments)
+	}
+}
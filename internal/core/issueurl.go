@@ -0,0 +1,155 @@
+package core
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rigdev/rig/internal/config"
+)
+
+// defaultIssueHosts are the web hosts of the hosted platforms.
+var defaultIssueHosts = map[string]string{
+	"github.com":    "github",
+	"gitlab.com":    "gitlab",
+	"bitbucket.org": "bitbucket",
+	"gitea.com":     "gitea",
+	"codeberg.org":  "gitea",
+}
+
+// hostedIssueHosts is the host of each hosted platform.
+var hostedIssueHosts = map[string]string{
+	"github":    "github.com",
+	"gitlab":    "gitlab.com",
+	"bitbucket": "bitbucket.org",
+	"gitea":     "gitea.com",
+}
+
+// IssueHosts maps the web hosts of issue trackers, lowercase and with the
+// port if not the default, to their platform (github, gitlab, bitbucket, gitea).
+type IssueHosts map[string]string
+
+// NewIssueHosts returns the hosted platforms' hosts and the self-hosted
+// ones cfg names: the host of source.api_url for source.platform and the
+// host of each projects entry. cfg may be nil.
+func NewIssueHosts(cfg *config.Config) IssueHosts {
+	hosts := IssueHosts{}
+	for host, platform := range defaultIssueHosts {
+		hosts[host] = platform
+	}
+	if cfg == nil {
+		return hosts
+	}
+	platform := cfg.Source.Platform
+	if platform == "" {
+		platform = "github"
+	}
+	if u, err := url.Parse(cfg.Source.APIURL); err == nil && u.Host != "" {
+		// api.example.com serves the API of example.com.
+		hosts[strings.TrimPrefix(strings.ToLower(u.Host), "api.")] = platform
+	}
+	for _, p := range cfg.Projects {
+		if p.Host == "" {
+			continue
+		}
+		pp := p.Platform
+		if pp == "" {
+			pp = "github"
+		}
+		hosts[strings.ToLower(p.Host)] = pp
+	}
+	return hosts
+}
+
+// Parse returns the issue an issue URL points at:
+// https://{host}/{owner}/{repo}/issues/{number} on GitHub, Gitea and
+// Bitbucket, https://{host}/{group}/.../{project}/-/issues/{number} on
+// GitLab. The host names the platform; a URL of an unknown host with
+// GitLab's "/-/" is taken for a self-hosted GitLab.
+func (h IssueHosts) Parse(rawURL string) (Issue, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return Issue{}, fmt.Errorf("issue URL must look like https://{host}/{owner}/{repo}/issues/{number}")
+	}
+	segments := strings.FieldsFunc(u.Path, func(r rune) bool { return r == '/' })
+	i := slices.Index(segments, "issues")
+	if i < 0 || i+1 >= len(segments) {
+		return Issue{}, fmt.Errorf("issue URL must look like https://{host}/{owner}/{repo}/issues/{number}")
+	}
+	number, err := strconv.Atoi(segments[i+1])
+	if err != nil || number <= 0 {
+		return Issue{}, fmt.Errorf("invalid issue number %q", segments[i+1])
+	}
+	repo := segments[:i]
+	gitlabPath := len(repo) > 0 && repo[len(repo)-1] == "-"
+	if gitlabPath {
+		repo = repo[:len(repo)-1]
+	}
+
+	host := strings.ToLower(u.Host)
+	platform, ok := h[host]
+	if !ok {
+		host = strings.ToLower(u.Hostname())
+		platform, ok = h[host]
+	}
+	switch {
+	case ok:
+	case gitlabPath:
+		platform = "gitlab"
+	default:
+		return Issue{}, fmt.Errorf("unknown issue host %s: set source.api_url or a projects entry's host for a self-hosted platform", host)
+	}
+	if len(repo) < 2 || (platform != "gitlab" && len(repo) != 2) {
+		return Issue{}, fmt.Errorf("issue URL must name the repository as {owner}/{repo}, or {group}/.../{project} on GitLab")
+	}
+	return Issue{
+		Platform: platform,
+		Repo:     strings.Join(repo, "/"),
+		ID:       strconv.Itoa(number),
+		Title:    fmt.Sprintf("Issue #%d", number),
+		URL:      rawURL,
+	}, nil
+}
+
+// URL returns the web URL of issue id of repo on platform, at host or, when
+// host is empty, the configured host of the platform.
+func (h IssueHosts) URL(platform, host, repo, id string) string {
+	if platform == "" {
+		platform = "github"
+	}
+	if host == "" {
+		host = h.Host(platform)
+	}
+	if platform == "gitlab" {
+		return fmt.Sprintf("https://%s/%s/-/issues/%s", host, repo, id)
+	}
+	return fmt.Sprintf("https://%s/%s/issues/%s", host, repo, id)
+}
+
+// Host returns the web host of platform, a self-hosted one before the
+// hosted one.
+func (h IssueHosts) Host(platform string) string {
+	if host := h.SelfHosted(platform); host != "" {
+		return host
+	}
+	if host, ok := hostedIssueHosts[platform]; ok {
+		return host
+	}
+	return hostedIssueHosts["github"]
+}
+
+// SelfHosted returns the self-hosted web host of platform, such as the
+// GitHub Enterprise Server host of source.api_url, or "" when platform is
+// only used hosted.
+func (h IssueHosts) SelfHosted(platform string) string {
+	for _, host := range slices.Sorted(maps.Keys(h)) {
+		if _, hosted := defaultIssueHosts[host]; !hosted && h[host] == platform {
+			return host
+		}
+	}
+	return ""
+}
@@ -0,0 +1,62 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/rigdev/rig/internal/config"
+)
+
+func TestIssueHostsParse(t *testing.T) {
+	cfg := &config.Config{
+		Source:   config.SourceConfig{Platform: "github", APIURL: "https://github.example.com/api/v3/"},
+		Projects: []config.ProjectEntry{{Repo: "ops/infra", Platform: "gitea", Host: "git.example.com:3000"}},
+	}
+	hosts := NewIssueHosts(cfg)
+	cases := []struct {
+		url, platform, repo, id string
+	}{
+		{"https://github.com/acme/api/issues/42", "github", "acme/api", "42"},
+		{"http://github.com/acme/api/issues/42#issuecomment-1", "github", "acme/api", "42"},
+		{"https://gitlab.com/acme/backend/api/-/issues/7", "gitlab", "acme/backend/api", "7"},
+		{"https://gitlab.internal/acme/api/-/issues/7?tab=notes", "gitlab", "acme/api", "7"},
+		{"https://bitbucket.org/acme/api/issues/3/login-fails", "bitbucket", "acme/api", "3"},
+		{"https://codeberg.org/acme/api/issues/9", "gitea", "acme/api", "9"},
+		{"https://GitHub.Example.com/acme/api/issues/12", "github", "acme/api", "12"},
+		{"https://git.example.com:3000/ops/infra/issues/5", "gitea", "ops/infra", "5"},
+	}
+	for _, c := range cases {
+		issue, err := hosts.Parse(c.url)
+		if err != nil {
+			t.Errorf("%s: %v", c.url, err)
+			continue
+		}
+		if issue.Platform != c.platform || issue.Repo != c.repo || issue.ID != c.id || issue.Title != "Issue #"+c.id {
+			t.Errorf("%s: got %+v", c.url, issue)
+		}
+	}
+
+	for _, bad := range []string{
+		"github.com/acme/api/issues/42",
+		"https://github.com/acme/api/pull/42",
+		"https://github.com/acme/api/issues/abc",
+		"https://github.com/acme/backend/api/issues/42",
+		"https://example.org/acme/api/issues/42",
+	} {
+		if _, err := hosts.Parse(bad); err == nil {
+			t.Errorf("%s: expected an error", bad)
+		}
+	}
+}
+
+func TestIssueHostsURL(t *testing.T) {
+	hosts := NewIssueHosts(&config.Config{Source: config.SourceConfig{Platform: "gitlab", APIURL: "https://gitlab.internal/api/v4"}})
+	if got := hosts.URL("gitlab", "", "acme/api", "7"); got != "https://gitlab.internal/acme/api/-/issues/7" {
+		t.Errorf("self-hosted GitLab URL = %s", got)
+	}
+	if got := hosts.URL("", "", "acme/api", "42"); got != "https://github.com/acme/api/issues/42" {
+		t.Errorf("GitHub URL = %s", got)
+	}
+	if got := hosts.URL("gitea", "git.example.com", "ops/infra", "5"); got != "https://git.example.com/ops/infra/issues/5" {
+		t.Errorf("Gitea URL = %s", got)
+	}
+}
@@ -0,0 +1,261 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/rigdev/rig/internal/logging"
+)
+
+// Journal entry events and the status of a step a crash or shutdown
+// interrupted.
+const (
+	JournalBegin       = "begin"
+	JournalEnd         = "end"
+	JournalInterrupted = "interrupted"
+)
+
+// JournalEntry is one line of a task's journal. The begin entry of a step
+// is written and synced before the step has any effect, so after a crash
+// the journal names the step that was running even when state.json was
+// last saved phases earlier.
+type JournalEntry struct {
+	Time      time.Time `json:"time"`
+	Step      StepName  `json:"step"`
+	Event     string    `json:"event"` // begin|end
+	InputHash string    `json:"input_hash,omitempty"`
+	Status    string    `json:"status,omitempty"` // success|failed|interrupted, on end
+	Error     string    `json:"error,omitempty"`
+}
+
+// PRFinder is implemented by GitAdapters that can look up the open pull
+// request of a branch. The report step uses it to adopt the PR an
+// interrupted run may have opened instead of opening a second one.
+type PRFinder interface {
+	FindPR(ctx context.Context, head string) (*GitPullRequest, error)
+}
+
+// JournalPath is where the engine appends the journal of a task, next to
+// the state file.
+func JournalPath(statePath, taskID string) string {
+	return filepath.Join(filepath.Dir(statePath), "journal", taskID+".jsonl")
+}
+
+// ReadJournal returns the journal of a task, oldest entry first. A missing
+// journal is empty, and a last line torn by a crash is skipped.
+func ReadJournal(statePath, taskID string) ([]JournalEntry, error) {
+	f, err := os.Open(JournalPath(statePath, taskID))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open journal: %w", err)
+	}
+	defer f.Close()
+
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for scanner.Scan() {
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read journal: %w", err)
+	}
+	return entries, nil
+}
+
+// OpenStep returns the begin entry of the step entries show began and
+// never ended, or nil.
+func OpenStep(entries []JournalEntry) *JournalEntry {
+	var open *JournalEntry
+	for i := range entries {
+		switch entries[i].Event {
+		case JournalBegin:
+			open = &entries[i]
+		case JournalEnd:
+			if open != nil && open.Step == entries[i].Step {
+				open = nil
+			}
+		}
+	}
+	return open
+}
+
+// interruptedBefore reports whether the run of step before the one just
+// begun never finished: it has no end, or ended interrupted.
+func interruptedBefore(entries []JournalEntry, step StepName) bool {
+	if n := len(entries); n > 0 && entries[n-1].Step == step && entries[n-1].Event == JournalBegin {
+		entries = entries[:n-1]
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Step != step {
+			continue
+		}
+		return entries[i].Event == JournalBegin || entries[i].Status == JournalInterrupted
+	}
+	return false
+}
+
+// journalSpan is a step that began in the journal and ends with end.
+type journalSpan struct {
+	ctx   context.Context
+	e     *Engine
+	task  string
+	entry JournalEntry
+}
+
+// journalBegin journals step of task beginning on input with the given
+// hash, as hashStepInput computes it.
+func (e *Engine) journalBegin(ctx context.Context, task *Task, step StepName, inputHash string) journalSpan {
+	span := journalSpan{ctx: ctx, e: e, task: task.ID, entry: JournalEntry{Step: step, InputHash: inputHash}}
+	entry := span.entry
+	entry.Event = JournalBegin
+	e.appendJournal(task.ID, entry)
+	return span
+}
+
+// end journals the step ending with err. A step a shutdown cut short
+// ends interrupted, as it may have had effects it did not report.
+func (s journalSpan) end(err error) {
+	entry := s.entry
+	entry.Event = JournalEnd
+	entry.Status = "success"
+	switch {
+	case shuttingDown(s.ctx):
+		entry.Status = JournalInterrupted
+	case err != nil:
+		entry.Status = "failed"
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	s.e.appendJournal(s.task, entry)
+}
+
+// outcome is the error a step that reports failure in its result, not as
+// an error, ends in the journal with.
+func outcome(err error, ok bool) error {
+	if err == nil && !ok {
+		return ErrStepFailed
+	}
+	return err
+}
+
+// appendJournal appends entry to the journal of a task and syncs it to
+// disk. A journal that cannot be written is logged, not fatal.
+func (e *Engine) appendJournal(taskID string, entry JournalEntry) {
+	if e.dryRun {
+		return
+	}
+	entry.Time = time.Now().UTC()
+	if err := appendJournalEntry(JournalPath(e.statePath, taskID), entry); err != nil {
+		e.log().Warn("write journal", logging.TaskKey, taskID, "err", err)
+	}
+}
+
+func appendJournalEntry(path string, entry JournalEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// RecoverTask checks the journal of a task no process is running. If it
+// shows a step that began and never ended, because rig crashed or was
+// killed in it, the step is journaled as interrupted and the task is
+// checkpointed at the step's phase for ResumeCheckpoint. It reports
+// whether the task was checkpointed; a task that is running elsewhere
+// fails to lock.
+func (e *Engine) RecoverTask(taskID string) (bool, error) {
+	lock, state, task, err := e.lockTask(taskID)
+	if err != nil {
+		return false, err
+	}
+	defer lock.Release()
+	if inactivePhases[task.Status] || task.Checkpoint != nil {
+		return false, nil
+	}
+	entries, err := ReadJournal(e.statePath, taskID)
+	if err != nil {
+		return false, err
+	}
+	open := OpenStep(entries)
+	if open == nil {
+		return false, nil
+	}
+
+	e.taskLog(task.ID, "warn", fmt.Sprintf("Step %s began at %s and never ended; rig stopped in it", open.Step, open.Time.Format(time.RFC3339)))
+	e.appendJournal(task.ID, JournalEntry{Step: open.Step, Event: JournalEnd, InputHash: open.InputHash, Status: JournalInterrupted, Error: "interrupted by a crash"})
+	interruptTask(task, stepPhases[open.Step], "interrupted by a crash")
+	if err := mergeState(state, e.statePath); err != nil {
+		return false, fmt.Errorf("save state: %w", err)
+	}
+	return true, nil
+}
+
+// InterruptedTasks returns the active tasks of s without a checkpoint whose
+// journal shows a step that never ended: the tasks RecoverTask checkpoints
+// unless another process is running them.
+func InterruptedTasks(statePath string, s *State) []Task {
+	var tasks []Task
+	for _, t := range s.Tasks {
+		if inactivePhases[t.Status] || t.Checkpoint != nil {
+			continue
+		}
+		if entries, err := ReadJournal(statePath, t.ID); err == nil && OpenStep(entries) != nil {
+			tasks = append(tasks, t)
+		}
+	}
+	return tasks
+}
+
+// findInterruptedPR returns the open PR of the task's branch when the
+// journal shows the last report step was interrupted, as it may have
+// opened the PR before it stopped, or nil.
+func (e *Engine) findInterruptedPR(ctx context.Context, task *Task) *PullRequest {
+	finder, ok := e.git.(PRFinder)
+	if !ok {
+		return nil
+	}
+	entries, err := ReadJournal(e.statePath, task.ID)
+	if err != nil || !interruptedBefore(entries, StepReport) {
+		return nil
+	}
+	pr, err := finder.FindPR(ctx, task.Branch)
+	if err != nil {
+		e.taskLog(task.ID, "warn", fmt.Sprintf("Looking up the PR of the interrupted report step failed: %v", err))
+		return nil
+	}
+	if pr == nil {
+		return nil
+	}
+	e.taskLog(task.ID, "info", fmt.Sprintf("Adopting PR %s opened by the interrupted report step", pr.URL))
+	return &PullRequest{ID: strconv.Itoa(pr.Number), URL: pr.URL}
+}
@@ -0,0 +1,142 @@
+package core
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExecute_JournalsSteps(t *testing.T) {
+	runner := &mockTestRunner{results: []*TestResult{{Name: "unit-test", Type: "command", Passed: true}}}
+	statePath := tempStatePath(t)
+	engine := NewEngine(testConfig(), &mockGit{}, &mockAI{}, &mockDeploy{deploySuccess: true}, []TestRunnerIface{runner}, nil, statePath)
+
+	if err := engine.Execute(context.Background(), testIssue()); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	state, _ := LoadState(statePath)
+	entries, err := ReadJournal(statePath, state.Tasks[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, en := range entries {
+		got = append(got, string(en.Step)+" "+en.Event+" "+en.Status)
+		if en.InputHash == "" {
+			t.Errorf("%s %s has no input hash", en.Step, en.Event)
+		}
+	}
+	want := "plan begin ,plan end success,code begin ,code end success,commit begin ,commit end success," +
+		"deploy begin ,deploy end success,test begin ,test end success,report begin ,report end success"
+	if strings.Join(got, ",") != want {
+		t.Errorf("journal = %s\nwant %s", strings.Join(got, ","), want)
+	}
+	if OpenStep(entries) != nil {
+		t.Error("a finished task has an open step")
+	}
+}
+
+func TestReadJournal_SkipsTornLine(t *testing.T) {
+	statePath := tempStatePath(t)
+	path := JournalPath(statePath, "task-1")
+	if err := appendJournalEntry(path, JournalEntry{Step: StepDeploy, Event: JournalBegin}); err != nil {
+		t.Fatal(err)
+	}
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	f.WriteString(`{"step":"deploy","ev`)
+	f.Close()
+
+	entries, err := ReadJournal(statePath, "task-1")
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("entries = %+v, %v", entries, err)
+	}
+	if open := OpenStep(entries); open == nil || open.Step != StepDeploy {
+		t.Errorf("open step = %+v, want deploy", open)
+	}
+}
+
+// crashingGit is a git adapter whose first CreatePR opens the PR and then
+// crashes, and that finds PRs by branch.
+type crashingGit struct {
+	mockGit
+	opened *GitPullRequest
+}
+
+func (m *crashingGit) CreatePR(ctx context.Context, base, head, title, body string) (*GitPullRequest, error) {
+	m.createPRCalls++
+	if m.opened == nil {
+		m.opened = &GitPullRequest{Number: 9, URL: "https://github.com/test/repo/pull/9"}
+		panic("crash")
+	}
+	return &GitPullRequest{Number: 10, URL: "https://github.com/test/repo/pull/10"}, nil
+}
+
+func (m *crashingGit) FindPR(ctx context.Context, head string) (*GitPullRequest, error) {
+	return m.opened, nil
+}
+
+func TestRecoverTask_AdoptsPROfInterruptedReport(t *testing.T) {
+	cfg := testConfig()
+	cfg.Workflow.Steps = []string{"plan", "code", "commit", "report"}
+	gitMock := &crashingGit{}
+	statePath := tempStatePath(t)
+	engine := NewEngine(cfg, gitMock, &mockAI{}, &mockDeploy{deploySuccess: true}, nil, nil, statePath)
+
+	func() {
+		defer func() { recover() }()
+		engine.Execute(context.Background(), testIssue())
+	}()
+	state, _ := LoadState(statePath)
+	tasks := InterruptedTasks(statePath, state)
+	if len(tasks) != 1 {
+		t.Fatalf("interrupted tasks = %d, want 1", len(tasks))
+	}
+
+	recovered, err := engine.RecoverTask(tasks[0].ID)
+	if err != nil || !recovered {
+		t.Fatalf("RecoverTask = %v, %v", recovered, err)
+	}
+	state, _ = LoadState(statePath)
+	if cp := state.Tasks[0].Checkpoint; cp == nil || cp.Phase != PhaseReporting {
+		t.Fatalf("checkpoint = %+v, want reporting", cp)
+	}
+	if again, _ := engine.RecoverTask(tasks[0].ID); again {
+		t.Error("recovered a checkpointed task twice")
+	}
+
+	if err := engine.ResumeCheckpoint(context.Background(), tasks[0].ID); err != nil {
+		t.Fatalf("ResumeCheckpoint: %v", err)
+	}
+	state, _ = LoadState(statePath)
+	task := state.Tasks[0]
+	if task.Status != PhaseCompleted || task.PR == nil || task.PR.ID != "9" {
+		t.Errorf("status %s, PR %+v; want completed with the adopted PR 9", task.Status, task.PR)
+	}
+	if gitMock.createPRCalls != 1 {
+		t.Errorf("CreatePR called %d times, want only the crashed call", gitMock.createPRCalls)
+	}
+}
+
+func TestInterruptedBefore(t *testing.T) {
+	begin := JournalEntry{Step: StepReport, Event: JournalBegin}
+	end := func(status string) JournalEntry {
+		return JournalEntry{Step: StepReport, Event: JournalEnd, Status: status}
+	}
+	deploy := JournalEntry{Step: StepDeploy, Event: JournalBegin}
+	cases := []struct {
+		entries []JournalEntry
+		want    bool
+	}{
+		{[]JournalEntry{begin}, false},
+		{[]JournalEntry{begin, deploy, begin}, true},
+		{[]JournalEntry{begin, end(JournalInterrupted), begin}, true},
+		{[]JournalEntry{begin, end("failed"), begin}, false},
+		{[]JournalEntry{begin, end(JournalInterrupted), begin, end("success"), begin}, false},
+	}
+	for i, c := range cases {
+		if got := interruptedBefore(c.entries, StepReport); got != c.want {
+			t.Errorf("case %d: interruptedBefore = %v, want %v", i, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// TaskSettings are the settings of a task that workflow.labels rules
+// changed for its issue's labels. They are recorded on the task so a
+// resumed task keeps them.
+type TaskSettings struct {
+	// Labels are the issue labels that matched a rule.
+	Labels []string `json:"labels"`
+	// SkipSteps are the workflow steps the task skips (deploy, test).
+	SkipSteps []string `json:"skip_steps,omitempty"`
+	// DraftPR, when set, decides whether the PR opens as a draft instead
+	// of workflow.draft_pr.
+	DraftPR *bool `json:"draft_pr,omitempty"`
+	// Model is the AI model of the task instead of ai.model.
+	Model string `json:"model,omitempty"`
+}
+
+// labelSettings returns the settings the workflow.labels rules give the
+// task of issue, or nil when none of its labels matches a rule.
+func (e *Engine) labelSettings(issue Issue) *TaskSettings {
+	var settings TaskSettings
+	for _, rule := range e.cfg.Workflow.Labels {
+		for _, label := range issue.Labels {
+			value, ok := matchLabel(rule.Label, label)
+			if !ok {
+				continue
+			}
+			if !slices.Contains(settings.Labels, label) {
+				settings.Labels = append(settings.Labels, label)
+			}
+			for _, step := range rule.SkipSteps {
+				if !slices.Contains(settings.SkipSteps, step) {
+					settings.SkipSteps = append(settings.SkipSteps, step)
+				}
+			}
+			if rule.DraftPR != nil {
+				draft := *rule.DraftPR
+				settings.DraftPR = &draft
+			}
+			switch {
+			case rule.Model != "":
+				settings.Model = rule.Model
+			case value != "":
+				settings.Model = value
+			}
+		}
+	}
+	if len(settings.Labels) == 0 {
+		return nil
+	}
+	return &settings
+}
+
+// matchLabel reports whether label matches the label of a rule, and for a
+// rule ending in "=" returns the rest of label as its value.
+func matchLabel(rule, label string) (string, bool) {
+	rule, label = strings.TrimSpace(rule), strings.TrimSpace(label)
+	if !strings.HasSuffix(rule, "=") {
+		return "", strings.EqualFold(rule, label)
+	}
+	if len(label) <= len(rule) || !strings.EqualFold(label[:len(rule)], rule) {
+		return "", false
+	}
+	return label[len(rule):], true
+}
+
+// logLabelSettings logs what the labels of task changed.
+func (e *Engine) logLabelSettings(task *Task) {
+	if task.Settings == nil {
+		return
+	}
+	var changes []string
+	if len(task.Settings.SkipSteps) > 0 {
+		changes = append(changes, "skipping "+strings.Join(task.Settings.SkipSteps, ", "))
+	}
+	if d := task.Settings.DraftPR; d != nil {
+		if *d {
+			changes = append(changes, "draft PR")
+		} else {
+			changes = append(changes, "no draft PR")
+		}
+	}
+	if task.Settings.Model != "" {
+		if _, ok := e.ai.(ModelSwitcher); ok {
+			changes = append(changes, "model "+task.Settings.Model)
+		} else {
+			changes = append(changes, fmt.Sprintf("model %s unsupported by the AI adapter, using %s", task.Settings.Model, e.cfg.AI.Model))
+		}
+	}
+	e.taskLog(task.ID, "info", fmt.Sprintf("Labels %s: %s", strings.Join(task.Settings.Labels, ", "), strings.Join(changes, "; ")))
+}
+
+// skipsStep reports whether the labels of task skip the workflow step.
+func (t *Task) skipsStep(step string) bool {
+	if t == nil || t.Settings == nil {
+		return false
+	}
+	if step == "test" && slices.Contains(t.Settings.SkipSteps, "deploy") {
+		return true
+	}
+	return slices.Contains(t.Settings.SkipSteps, step)
+}
+
+// skipCause says why step is disabled for task.
+func skipCause(task *Task, step string) string {
+	if task.skipsStep(step) {
+		return "skipped by label " + strings.Join(task.Settings.Labels, ", ")
+	}
+	return "not in workflow.steps"
+}
+
+// taskAI returns the AI adapter and model of task: the model its labels
+// chose when the adapter can switch to it, or else ai.model.
+func (e *Engine) taskAI(task *Task) (AIAdapter, string) {
+	if task == nil || task.Settings == nil || task.Settings.Model == "" || task.Settings.Model == e.cfg.AI.Model {
+		return e.ai, e.cfg.AI.Model
+	}
+	switcher, ok := e.ai.(ModelSwitcher)
+	if !ok {
+		return e.ai, e.cfg.AI.Model
+	}
+	return switcher.WithModel(task.Settings.Model), task.Settings.Model
+}
@@ -0,0 +1,104 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rigdev/rig/internal/config"
+)
+
+func labelConfig() *config.Config {
+	draft := true
+	cfg := testConfig()
+	cfg.Workflow.Labels = []config.LabelRule{
+		{Label: "rig:no-deploy", SkipSteps: []string{"deploy"}},
+		{Label: "rig:draft", DraftPR: &draft},
+		{Label: "rig:model="},
+	}
+	return cfg
+}
+
+func TestLabelSettings(t *testing.T) {
+	engine := NewEngine(labelConfig(), &mockGit{}, &mockAI{}, &mockDeploy{}, nil, nil, tempStatePath(t))
+
+	if s := engine.labelSettings(Issue{Labels: []string{"bug", "rig"}}); s != nil {
+		t.Errorf("expected no settings without matching labels, got %+v", s)
+	}
+	if s := engine.labelSettings(Issue{Labels: []string{"rig:model="}}); s != nil {
+		t.Errorf("expected a prefix rule to need a value, got %+v", s)
+	}
+	s := engine.labelSettings(Issue{Labels: []string{"RIG:No-Deploy", "rig:draft", "rig:model=gpt-4o"}})
+	if s == nil || len(s.Labels) != 3 || s.Model != "gpt-4o" || s.DraftPR == nil || !*s.DraftPR {
+		t.Fatalf("unexpected settings %+v", s)
+	}
+	task := &Task{Settings: s}
+	if !task.skipsStep("deploy") || !task.skipsStep("test") {
+		t.Error("expected skipping deploy to skip test too")
+	}
+}
+
+func TestExecute_LabelSkipsDeploy(t *testing.T) {
+	deployMock := &mockDeploy{deploySuccess: true}
+	gitMock := &mockGit{}
+	statePath := tempStatePath(t)
+	engine := NewEngine(labelConfig(), gitMock, &mockAI{}, deployMock, []TestRunnerIface{&mockTestRunner{}}, nil, statePath)
+
+	issue := testIssue()
+	issue.Labels = []string{"rig", "rig:no-deploy"}
+	if err := engine.Execute(context.Background(), issue); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if deployMock.deployCalls != 0 {
+		t.Errorf("expected no deploy, got %d", deployMock.deployCalls)
+	}
+	if gitMock.createPRCalls != 1 {
+		t.Errorf("expected a PR, got %d", gitMock.createPRCalls)
+	}
+	state, _ := LoadState(statePath)
+	task := state.Tasks[0]
+	if task.Status != PhaseCompleted || task.Settings == nil || task.Settings.SkipSteps[0] != "deploy" {
+		t.Fatalf("unexpected task %s with settings %+v", task.Status, task.Settings)
+	}
+}
+
+func TestExecute_LabelOpensDraftPR(t *testing.T) {
+	gitMock := &draftGit{}
+	engine := NewEngine(labelConfig(), gitMock, &mockAI{}, &mockDeploy{deploySuccess: true},
+		[]TestRunnerIface{&mockTestRunner{}}, nil, tempStatePath(t))
+
+	issue := testIssue()
+	issue.Labels = []string{"rig:draft"}
+	if err := engine.Execute(context.Background(), issue); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if gitMock.drafts != 1 || gitMock.createPRCalls != 0 {
+		t.Fatalf("expected a draft PR, got %d drafts, %d PRs", gitMock.drafts, gitMock.createPRCalls)
+	}
+}
+
+func TestExecute_LabelOverridesModel(t *testing.T) {
+	var calls []string
+	aiMock := &switchingAI{model: "test-model", calls: &calls}
+	testRunner := &mockTestRunner{results: []*TestResult{
+		{Name: "unit-test", Type: "command", Passed: false, Output: "FAIL"},
+		{Name: "unit-test", Type: "command", Passed: true, Output: "PASS"},
+	}}
+	statePath := tempStatePath(t)
+	engine := NewEngine(labelConfig(), &mockGit{}, aiMock, &mockDeploy{deploySuccess: true},
+		[]TestRunnerIface{testRunner}, nil, statePath)
+
+	issue := testIssue()
+	issue.Labels = []string{"rig:model=gpt-4o"}
+	if err := engine.Execute(context.Background(), issue); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if len(calls) != 1 || calls[0] != "gpt-4o" {
+		t.Errorf("expected the retry on gpt-4o, got %v", calls)
+	}
+	state, _ := LoadState(statePath)
+	for _, a := range state.Tasks[0].Attempts {
+		if a.Model != "gpt-4o" {
+			t.Errorf("attempt %d ran on %q, want gpt-4o", a.Number, a.Model)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package core
+
+import "strings"
+
+// languageNames maps ai.language codes to the names used in AI prompts.
+var languageNames = map[string]string{
+	"en": "English",
+	"ko": "Korean",
+	"ja": "Japanese",
+	"zh": "Chinese",
+	"de": "German",
+	"fr": "French",
+	"es": "Spanish",
+}
+
+// prLabels translates the headings of the default PR template. Languages
+// without an entry keep the English headings.
+var prLabels = map[string]map[string]string{
+	"ko": {
+		"Automated Fix by Rig": "Rig 자동 수정",
+		"Plan":                 "계획",
+		"Files Changed":        "변경된 파일",
+		"Deploy":               "배포",
+		"Test Results":         "테스트 결과",
+		"Test":                 "테스트",
+		"Type":                 "유형",
+		"Result":               "결과",
+		"Duration":             "소요 시간",
+		"Attempt History":      "시도 이력",
+		"Status":               "상태",
+		"Files":                "파일",
+		"Tests passed":         "통과한 테스트",
+		"attempt(s)":           "회 시도",
+	},
+}
+
+// languageCode normalizes an ai.language value ("ko", "ko-KR", "Korean")
+// to its two-letter code. Unknown languages are returned lower-cased.
+func languageCode(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, "-_"); i > 0 {
+		lang = lang[:i]
+	}
+	for code, name := range languageNames {
+		if strings.ToLower(name) == lang {
+			return code
+		}
+	}
+	return lang
+}
+
+// LanguageName returns the language name to put in AI prompts for an
+// ai.language value. Free-form values such as "Brazilian Portuguese" are
+// passed through unchanged; "" means no preference.
+func LanguageName(lang string) string {
+	if name, ok := languageNames[languageCode(lang)]; ok {
+		return name
+	}
+	return strings.TrimSpace(lang)
+}
+
+// translator returns the "t" template function for lang.
+func translator(lang string) func(string) string {
+	labels := prLabels[languageCode(lang)]
+	return func(s string) string {
+		if t, ok := labels[s]; ok {
+			return t
+		}
+		return s
+	}
+}
@@ -0,0 +1,167 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrTaskBusy is returned when another executor is already driving a task.
+var ErrTaskBusy = errors.New("task is busy")
+
+// lockWriteGrace is how long an unreadable lock file is assumed to be in
+// the middle of being written by its owner.
+const lockWriteGrace = 10 * time.Second
+
+// lockNameUnsafe matches characters not allowed in lock file names.
+var lockNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// ExecutionLock is a held per-issue execution lock.
+//
+// Tasks for the same issue share a branch, so the lock covers the issue
+// rather than a single task ID. It is a lock file next to the state file,
+// which makes it hold across processes (e.g. rig serve and rig approve).
+type ExecutionLock struct {
+	path string
+}
+
+// lockHolder is what a lock file records about its owner.
+type lockHolder struct {
+	pid  int
+	host string
+	at   time.Time
+}
+
+// executionLockPath returns the lock file path for an issue.
+func executionLockPath(statePath string, issue Issue) string {
+	key := lockNameUnsafe.ReplaceAllString(issue.Repo+"-"+issue.ID, "_")
+	return filepath.Join(filepath.Dir(statePath), "locks", key+".lock")
+}
+
+// AcquireExecutionLock takes the execution lock for issue. It returns an
+// error wrapping ErrTaskBusy if a live process holds it. Locks left behind
+// by a process that has exited on this host are taken over.
+func AcquireExecutionLock(statePath string, issue Issue) (*ExecutionLock, error) {
+	path := executionLockPath(statePath, issue)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create lock dir: %w", err)
+	}
+
+	host := lockHostname()
+	content := fmt.Sprintf("%d %s %s\n", os.Getpid(), host, time.Now().UTC().Format(time.RFC3339))
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			_, werr := f.WriteString(content)
+			cerr := f.Close()
+			if werr != nil || cerr != nil {
+				os.Remove(path)
+				return nil, fmt.Errorf("write lock file: %w", errors.Join(werr, cerr))
+			}
+			return &ExecutionLock{path: path}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("create lock file: %w", err)
+		}
+
+		holder, ok := readLockHolder(path)
+		if ok && !holder.stale(host) {
+			return nil, fmt.Errorf("%w: issue #%s is being executed by pid %d on %s since %s",
+				ErrTaskBusy, issue.ID, holder.pid, holder.host, holder.at.Format(time.RFC3339))
+		}
+		// An unreadable lock may be one that is still being written.
+		if info, err := os.Stat(path); !ok && err == nil && time.Since(info.ModTime()) < lockWriteGrace {
+			return nil, fmt.Errorf("%w: issue #%s", ErrTaskBusy, issue.ID)
+		}
+		// Stale or abandoned lock: remove it and try once more.
+		os.Remove(path)
+	}
+	return nil, fmt.Errorf("%w: issue #%s", ErrTaskBusy, issue.ID)
+}
+
+// ExecutionLocked reports whether a live executor currently holds the lock
+// for issue. It lets API handlers reject a request before starting work.
+func ExecutionLocked(statePath string, issue Issue) bool {
+	holder, ok := readLockHolder(executionLockPath(statePath, issue))
+	if !ok {
+		return false
+	}
+	return !holder.stale(lockHostname())
+}
+
+// Release removes the lock file. Releasing twice is a no-op.
+func (l *ExecutionLock) Release() {
+	if l == nil || l.path == "" {
+		return
+	}
+	os.Remove(l.path)
+	l.path = ""
+}
+
+// lockHostname identifies this host in lock files.
+func lockHostname() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return "localhost"
+	}
+	return host
+}
+
+// lockTask takes the execution lock for a task's issue and returns the state
+// as loaded after the lock was taken, so writes by the previous holder are
+// not lost. The git adapter is pointed at the task's checkout.
+func (e *Engine) lockTask(taskID string) (*ExecutionLock, *State, *Task, error) {
+	state, err := loadTrackedState(e.statePath)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("load state: %w", err)
+	}
+	task := state.GetTaskByID(taskID)
+	if task == nil {
+		return nil, nil, nil, fmt.Errorf("task not found: %s", taskID)
+	}
+
+	lock, err := AcquireExecutionLock(e.statePath, task.Issue)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	state, err = loadTrackedState(e.statePath)
+	if err != nil {
+		lock.Release()
+		return nil, nil, nil, fmt.Errorf("load state: %w", err)
+	}
+	if task = state.GetTaskByID(taskID); task == nil {
+		lock.Release()
+		return nil, nil, nil, fmt.Errorf("task not found: %s", taskID)
+	}
+	e.bindWorkspace(task.ID)
+	return lock, state, task, nil
+}
+
+// readLockHolder parses a lock file. ok is false if it is missing or corrupt.
+func readLockHolder(path string) (lockHolder, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return lockHolder{}, false
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != 3 {
+		return lockHolder{}, false
+	}
+	pid, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return lockHolder{}, false
+	}
+	at, _ := time.Parse(time.RFC3339, fields[2])
+	return lockHolder{pid: pid, host: fields[1], at: at}, true
+}
+
+// stale reports whether the holder is known to be gone. Locks held from
+// another host cannot be checked and are never considered stale.
+func (h lockHolder) stale(localHost string) bool {
+	return h.host == localHost && !processAlive(h.pid)
+}
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExecutionLockExcludesSecondExecutor(t *testing.T) {
+	statePath := tempStatePath(t)
+	issue := Issue{Repo: "test/repo", ID: "42"}
+
+	lock, err := AcquireExecutionLock(statePath, issue)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	if !ExecutionLocked(statePath, issue) {
+		t.Fatal("expected issue to be locked")
+	}
+	if _, err := AcquireExecutionLock(statePath, issue); !errors.Is(err, ErrTaskBusy) {
+		t.Fatalf("expected ErrTaskBusy, got %v", err)
+	}
+	if _, err := AcquireExecutionLock(statePath, Issue{Repo: "test/repo", ID: "43"}); err != nil {
+		t.Fatalf("other issues must not be blocked: %v", err)
+	}
+
+	lock.Release()
+	lock.Release()
+	if ExecutionLocked(statePath, issue) {
+		t.Fatal("expected lock to be released")
+	}
+	again, err := AcquireExecutionLock(statePath, issue)
+	if err != nil {
+		t.Fatalf("re-acquire: %v", err)
+	}
+	again.Release()
+}
+
+func TestExecutionLockTakesOverStaleLock(t *testing.T) {
+	statePath := tempStatePath(t)
+	issue := Issue{Repo: "test/repo", ID: "42"}
+	path := executionLockPath(statePath, issue)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// A pid far above any real pid_max belongs to no running process.
+	stale := fmt.Sprintf("%d %s %s\n", 1<<30, lockHostname(), time.Now().UTC().Format(time.RFC3339))
+	if err := os.WriteFile(path, []byte(stale), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if ExecutionLocked(statePath, issue) {
+		t.Fatal("stale lock must not count as held")
+	}
+	lock, err := AcquireExecutionLock(statePath, issue)
+	if err != nil {
+		t.Fatalf("expected stale lock to be taken over: %v", err)
+	}
+	lock.Release()
+}
+
+func TestEngine_ResumeRejectsBusyTask(t *testing.T) {
+	statePath := tempStatePath(t)
+	state := &State{Version: "1.0"}
+	task := state.CreateTask(testIssue())
+	task.Status = PhaseAwaitingApproval
+	if err := SaveState(state, statePath); err != nil {
+		t.Fatal(err)
+	}
+
+	lock, err := AcquireExecutionLock(statePath, testIssue())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Release()
+
+	engine := NewEngine(testConfig(), &mockGit{}, &mockAI{}, &mockDeploy{deploySuccess: true}, nil, nil, statePath)
+	if err := engine.Resume(context.Background(), task.ID, true); !errors.Is(err, ErrTaskBusy) {
+		t.Fatalf("expected ErrTaskBusy, got %v", err)
+	}
+	if err := engine.Execute(context.Background(), testIssue()); !errors.Is(err, ErrTaskBusy) {
+		t.Fatalf("expected ErrTaskBusy from Execute, got %v", err)
+	}
+}
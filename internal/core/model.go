@@ -0,0 +1,58 @@
+package core
+
+import "context"
+
+// ModelSwitcher is an optional AIAdapter capability that returns a copy of
+// the adapter talking to a different model of the same provider.
+type ModelSwitcher interface {
+	WithModel(model string) AIAdapter
+}
+
+// retryAI returns the adapter and model used for retry attempts of task.
+// When ai.retry_model is set and the adapter can switch models, retries run
+// on that model (cheaper or stronger); otherwise they reuse the primary
+// one. A model chosen by the task's labels is used for retries too.
+func (e *Engine) retryAI(task *Task) (AIAdapter, string) {
+	if task.Settings != nil && task.Settings.Model != "" {
+		return e.taskAI(task)
+	}
+	model := e.cfg.AI.RetryModel
+	if model == "" || model == e.cfg.AI.Model {
+		return e.ai, e.cfg.AI.Model
+	}
+	switcher, ok := e.ai.(ModelSwitcher)
+	if !ok {
+		return e.ai, e.cfg.AI.Model
+	}
+	return switcher.WithModel(model), model
+}
+
+// answeredBy returns the provider and model that produced the result of
+// the AI call just made for the task in ctx: those the adapter recorded
+// when it fails over or routes by ai.models, or else the configured
+// provider and the model that was asked.
+func (e *Engine) answeredBy(ctx context.Context, model string) (string, string) {
+	if provider, m, ok := lastAIProvider(ctx); ok {
+		return provider, m
+	}
+	provider := e.cfg.AI.Provider
+	if provider == "" {
+		provider = "anthropic"
+	}
+	return provider, model
+}
+
+// noAICacheKey is the context key WithoutAICache sets.
+type noAICacheKey struct{}
+
+// WithoutAICache returns a context whose AI calls skip cached answers and
+// ask the provider again; the fresh answers still replace the cached ones.
+func WithoutAICache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noAICacheKey{}, true)
+}
+
+// AICacheBypassed reports whether ctx came from WithoutAICache.
+func AICacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(noAICacheKey{}).(bool)
+	return bypass
+}
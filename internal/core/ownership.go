@@ -0,0 +1,270 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rigdev/rig/internal/ignore"
+)
+
+// The policies rules that change the task's work rather than check it.
+const (
+	// PolicyLicenseHeader prepends the license header template at the
+	// policy's value to the files the AI creates.
+	PolicyLicenseHeader = "license_header"
+	// PolicyCodeOwners requests reviews of the task's PR from the
+	// CODEOWNERS owners of the files it changes; the policy's value is the
+	// CODEOWNERS file, by default where GitHub looks for it.
+	PolicyCodeOwners = "codeowners"
+)
+
+// PRReviewerRequester is implemented by GitAdapters that can request
+// reviews of pull requests.
+type PRReviewerRequester interface {
+	// RequestReviewers requests reviews of pull request number from
+	// reviewers: user names, or org/team for teams, with or without "@".
+	RequestReviewers(ctx context.Context, number int, reviewers []string) error
+}
+
+// codeOwnersFiles are where GitHub looks for CODEOWNERS, in order.
+var codeOwnersFiles = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// commentStyle is how a file type writes a header comment: each line after
+// prefix, between open and close lines when the type has no line comments.
+type commentStyle struct {
+	open, prefix, close string
+}
+
+// commentStyles are the comment styles by file extension, or by name for
+// files without one. Files of other types get no header.
+var commentStyles = map[string]commentStyle{
+	".go": {prefix: "//"}, ".js": {prefix: "//"}, ".jsx": {prefix: "//"}, ".mjs": {prefix: "//"}, ".cjs": {prefix: "//"},
+	".ts": {prefix: "//"}, ".tsx": {prefix: "//"}, ".java": {prefix: "//"}, ".kt": {prefix: "//"}, ".kts": {prefix: "//"},
+	".scala": {prefix: "//"}, ".groovy": {prefix: "//"}, ".swift": {prefix: "//"}, ".dart": {prefix: "//"},
+	".c": {prefix: "//"}, ".h": {prefix: "//"}, ".cc": {prefix: "//"}, ".cpp": {prefix: "//"}, ".hpp": {prefix: "//"},
+	".cs": {prefix: "//"}, ".rs": {prefix: "//"}, ".proto": {prefix: "//"},
+	".py": {prefix: "#"}, ".rb": {prefix: "#"}, ".pl": {prefix: "#"}, ".sh": {prefix: "#"}, ".bash": {prefix: "#"},
+	".zsh": {prefix: "#"}, ".ps1": {prefix: "#"}, ".r": {prefix: "#"}, ".tf": {prefix: "#"},
+	".yaml": {prefix: "#"}, ".yml": {prefix: "#"}, ".toml": {prefix: "#"},
+	"Dockerfile": {prefix: "#"}, "Makefile": {prefix: "#"},
+	".sql": {prefix: "--"}, ".lua": {prefix: "--"}, ".hs": {prefix: "--"},
+	".css": {open: "/*", prefix: " *", close: " */"}, ".scss": {open: "/*", prefix: " *", close: " */"},
+	".less": {open: "/*", prefix: " *", close: " */"},
+}
+
+// policiesOf returns the values of the policies of rule.
+func (e *Engine) policiesOf(rule string) []string {
+	var values []string
+	for _, p := range e.cfg.Policies {
+		if strings.EqualFold(strings.TrimSpace(p.Rule), rule) {
+			values = append(values, strings.TrimSpace(p.Value))
+		}
+	}
+	return values
+}
+
+// workspaceFile returns the path of the repository file name in the task's
+// workspace, or "" without a workspace.
+func (e *Engine) workspaceFile(name string) string {
+	if wp, ok := e.git.(WorkspaceProvider); ok && wp.GetWorkspace() != "" {
+		return filepath.Join(wp.GetWorkspace(), filepath.FromSlash(name))
+	}
+	return ""
+}
+
+// readRepoFile reads name from the task's workspace, or as given when the
+// workspace has no such file.
+func (e *Engine) readRepoFile(name string) ([]byte, error) {
+	if full := e.workspaceFile(name); full != "" && !filepath.IsAbs(name) {
+		if data, err := os.ReadFile(full); err == nil {
+			return data, nil
+		}
+	}
+	return os.ReadFile(name)
+}
+
+// addLicenseHeaders prepends the license_header policy's template to the
+// files changes create that have a comment style and no header yet.
+// ${YEAR} in the template is the current year.
+func (e *Engine) addLicenseHeaders(task *Task, changes []AIFileChange) []AIFileChange {
+	values := e.policiesOf(PolicyLicenseHeader)
+	if len(values) == 0 || len(changes) == 0 {
+		return changes
+	}
+	data, err := e.readRepoFile(values[0])
+	if err != nil {
+		e.taskLog(task.ID, "warn", fmt.Sprintf("Could not read the license header template: %v", err))
+		return changes
+	}
+	template := strings.TrimSpace(strings.ReplaceAll(string(data), "${YEAR}", strconv.Itoa(time.Now().Year())))
+	if template == "" {
+		return changes
+	}
+
+	out := make([]AIFileChange, len(changes))
+	copy(out, changes)
+	var added []string
+	for i, c := range out {
+		if c.Action != "create" {
+			continue
+		}
+		content, ok := withLicenseHeader(c.Path, c.Content, template)
+		if ok {
+			out[i].Content = content
+			added = append(added, c.Path)
+		}
+	}
+	if len(added) > 0 {
+		e.taskLog(task.ID, "info", fmt.Sprintf("Added the license header to %s", strings.Join(added, ", ")))
+	}
+	return out
+}
+
+// withLicenseHeader returns content of the file name with header commented
+// out ahead of it, after any shebang line. It reports false when the file
+// type has no comment style or content has the header near its top.
+func withLicenseHeader(name, content, header string) (string, bool) {
+	style, ok := commentStyles[strings.ToLower(path.Ext(name))]
+	if !ok {
+		style, ok = commentStyles[path.Base(name)]
+	}
+	if !ok {
+		return content, false
+	}
+	first, _, _ := strings.Cut(header, "\n")
+	head := content
+	if lines := strings.SplitN(content, "\n", 20); len(lines) == 20 {
+		head = strings.Join(lines[:19], "\n")
+	}
+	if strings.Contains(head, strings.TrimSpace(first)) {
+		return content, false
+	}
+
+	var b strings.Builder
+	rest := content
+	if strings.HasPrefix(content, "#!") {
+		line, after, _ := strings.Cut(content, "\n")
+		b.WriteString(line + "\n")
+		rest = after
+	}
+	if style.open != "" {
+		b.WriteString(style.open + "\n")
+	}
+	sc := bufio.NewScanner(strings.NewReader(header))
+	for sc.Scan() {
+		if line := strings.TrimRight(sc.Text(), " \t\r"); line != "" {
+			b.WriteString(style.prefix + " " + line + "\n")
+		} else {
+			b.WriteString(style.prefix + "\n")
+		}
+	}
+	if style.close != "" {
+		b.WriteString(style.close + "\n")
+	}
+	b.WriteString("\n" + rest)
+	return b.String(), true
+}
+
+// requestCodeOwners requests reviews of pr from the CODEOWNERS owners of
+// the files task changed when a codeowners policy is set. Failures only
+// warn: the PR is already open.
+func (e *Engine) requestCodeOwners(ctx context.Context, task *Task, pr *PullRequest) {
+	values := e.policiesOf(PolicyCodeOwners)
+	if len(values) == 0 || pr == nil {
+		return
+	}
+	var files []string
+	for _, a := range task.Attempts {
+		for _, f := range a.FilesChanged {
+			if !slices.Contains(files, f) {
+				files = append(files, f)
+			}
+		}
+	}
+	if len(files) == 0 {
+		return
+	}
+
+	var data []byte
+	err := fmt.Errorf("no CODEOWNERS file in %s", strings.Join(codeOwnersFiles, ", "))
+	if values[0] != "" {
+		data, err = e.readRepoFile(values[0])
+	} else {
+		for _, name := range codeOwnersFiles {
+			if full := e.workspaceFile(name); full != "" {
+				if d, readErr := os.ReadFile(full); readErr == nil {
+					data, err = d, nil
+					break
+				}
+			}
+		}
+	}
+	if err != nil {
+		e.taskLog(task.ID, "warn", fmt.Sprintf("Could not read CODEOWNERS: %v", err))
+		return
+	}
+	reviewers := codeOwners(string(data), files)
+	if len(reviewers) == 0 {
+		return
+	}
+
+	requester, ok := e.git.(PRReviewerRequester)
+	if !ok {
+		e.taskLog(task.ID, "warn", "Git adapter cannot request PR reviewers; skipping CODEOWNERS")
+		return
+	}
+	number, err := strconv.Atoi(pr.ID)
+	if err != nil {
+		return
+	}
+	if err := requester.RequestReviewers(ctx, number, reviewers); err != nil {
+		e.taskLog(task.ID, "warn", fmt.Sprintf("Could not request PR reviewers: %v", err))
+		return
+	}
+	e.taskLog(task.ID, "info", fmt.Sprintf("Requested reviews from %s", strings.Join(reviewers, ", ")))
+}
+
+// codeOwners returns the owners the CODEOWNERS file content gives files:
+// for each, those of the last line whose pattern matches it. Owners given
+// by email cannot be requested and are left out.
+func codeOwners(content string, files []string) []string {
+	type entry struct {
+		pattern string
+		owners  []string
+	}
+	var entries []entry
+	sc := bufio.NewScanner(strings.NewReader(content))
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		entries = append(entries, entry{pattern: fields[0], owners: fields[1:]})
+	}
+
+	var owners []string
+	for _, f := range files {
+		for i := len(entries) - 1; i >= 0; i-- {
+			if !ignore.Match(entries[i].pattern, f) {
+				continue
+			}
+			for _, o := range entries[i].owners {
+				if strings.HasPrefix(o, "@") && !slices.Contains(owners, o) {
+					owners = append(owners, o)
+				}
+			}
+			break
+		}
+	}
+	slices.Sort(owners)
+	return owners
+}
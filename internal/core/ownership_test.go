@@ -0,0 +1,116 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+)
+
+// reviewingGit is a workspaceGit that records the committed changes and
+// the requested reviewers.
+type reviewingGit struct {
+	workspaceGit
+	committed []GitFileChange
+	reviewers []string
+}
+
+func (g *reviewingGit) CommitAndPush(ctx context.Context, changes []GitFileChange, message string) error {
+	g.committed = append(g.committed, changes...)
+	return g.mockGit.CommitAndPush(ctx, changes, message)
+}
+
+func (g *reviewingGit) RequestReviewers(ctx context.Context, number int, reviewers []string) error {
+	g.reviewers = append(g.reviewers, reviewers...)
+	return nil
+}
+
+func TestExecute_LicenseHeaderAndCodeOwners(t *testing.T) {
+	workspace := t.TempDir()
+	os.MkdirAll(filepath.Join(workspace, ".github"), 0o755)
+	os.WriteFile(filepath.Join(workspace, ".github", "CODEOWNERS"), []byte(
+		"# owners\n*        @acme/maintainers\n/api/   @acme/api-team @carol\ndocs/   docs@example.com\n"), 0o644)
+	os.WriteFile(filepath.Join(workspace, "HEADER.txt"), []byte("Copyright ${YEAR} Acme\n\nLicensed under MIT.\n"), 0o644)
+
+	cfg := testConfig()
+	cfg.Policies = []config.PolicyConfig{
+		{Name: "license", Rule: "license_header", Value: "HEADER.txt"},
+		{Name: "owners", Rule: "codeowners"},
+	}
+	git := &reviewingGit{workspaceGit: workspaceGit{workspace: workspace}}
+	aiMock := &mockAI{generateFunc: func(ctx context.Context, plan *AIPlan, repoFiles map[string]string) ([]AIFileChange, error) {
+		return []AIFileChange{
+			{Path: "api/handler.go", Content: "package api\n", Action: "create"},
+			{Path: "main.go", Content: "package main\n", Action: "modify"},
+			{Path: "docs/notes.md", Content: "# Notes\n", Action: "create"},
+		}, nil
+	}}
+	runner := &mockTestRunner{results: []*TestResult{{Name: "unit-test", Type: "command", Passed: true, Duration: time.Second}}}
+	e := NewEngine(cfg, git, aiMock, &mockDeploy{deploySuccess: true}, []TestRunnerIface{runner}, nil, tempStatePath(t))
+	if err := e.Execute(context.Background(), testIssue()); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+
+	year := strconv.Itoa(time.Now().Year())
+	want := map[string]string{
+		"api/handler.go": "// Copyright " + year + " Acme\n//\n// Licensed under MIT.\n\npackage api\n",
+		"main.go":        "package main\n",
+		"docs/notes.md":  "# Notes\n",
+	}
+	if len(git.committed) != len(want) {
+		t.Fatalf("committed %+v", git.committed)
+	}
+	for _, c := range git.committed {
+		if c.Content != want[c.Path] {
+			t.Errorf("committed %s as %q, want %q", c.Path, c.Content, want[c.Path])
+		}
+	}
+	if want := []string{"@acme/api-team", "@acme/maintainers", "@carol"}; !reflect.DeepEqual(git.reviewers, want) {
+		t.Errorf("requested reviews from %v, want %v", git.reviewers, want)
+	}
+}
+
+func TestWithLicenseHeader(t *testing.T) {
+	for _, tc := range []struct {
+		name, content, want string
+		ok                  bool
+	}{
+		{"run.sh", "#!/bin/sh\necho hi\n", "#!/bin/sh\n# Copyright Acme\n\necho hi\n", true},
+		{"web/app.css", "a {}\n", "/*\n * Copyright Acme\n */\n\na {}\n", true},
+		{"Dockerfile", "FROM scratch\n", "# Copyright Acme\n\nFROM scratch\n", true},
+		{"main.go", "// Copyright Acme\n\npackage main\n", "// Copyright Acme\n\npackage main\n", false},
+		{"data.json", "{}\n", "{}\n", false},
+	} {
+		got, ok := withLicenseHeader(tc.name, tc.content, "Copyright Acme")
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("withLicenseHeader(%s) = %q, %v; want %q, %v", tc.name, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestCodeOwners(t *testing.T) {
+	codeowners := strings.Join([]string{
+		"*.go        @gophers",
+		"/web/       @acme/frontend",
+		"/web/vendor/",
+		"internal/core/engine.go  @lead  # the engine",
+	}, "\n")
+	for _, tc := range []struct {
+		files []string
+		want  []string
+	}{
+		{[]string{"main.go"}, []string{"@gophers"}},
+		{[]string{"web/app.js", "web/vendor/lib.js"}, []string{"@acme/frontend"}},
+		{[]string{"internal/core/engine.go", "README.md"}, []string{"@lead"}},
+	} {
+		if got := codeOwners(codeowners, tc.files); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("codeOwners(%v) = %v, want %v", tc.files, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNoPlanReview is returned by EditPlan for a task without a plan waiting
+// for review.
+var ErrNoPlanReview = errors.New("task has no plan waiting for review")
+
+// FormatPlan renders plan as the editable text of a plan review: the
+// summary, a blank line, then one numbered line per step.
+func FormatPlan(plan *AIPlan) string {
+	var b strings.Builder
+	b.WriteString(strings.TrimSpace(plan.Summary))
+	b.WriteString("\n")
+	for i, step := range plan.Steps {
+		if i == 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%d. %s\n", i+1, strings.TrimSpace(step))
+	}
+	return b.String()
+}
+
+// ParsePlan reads a plan back from its FormatPlan text as a reviewer may
+// have edited it. Lines numbered ("1.") or bulleted ("-", "*") are steps;
+// the lines before the first step are the summary, and unmarked lines
+// after it continue the step above.
+func ParsePlan(text string) *AIPlan {
+	plan := &AIPlan{}
+	var summary []string
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if step, ok := planStep(line); ok {
+			plan.Steps = append(plan.Steps, step)
+			continue
+		}
+		if n := len(plan.Steps); n > 0 {
+			plan.Steps[n-1] += " " + line
+			continue
+		}
+		summary = append(summary, line)
+	}
+	plan.Summary = strings.Join(summary, " ")
+	return plan
+}
+
+// planStep returns the text of a numbered or bulleted line.
+func planStep(line string) (string, bool) {
+	for _, bullet := range []string{"- ", "* "} {
+		if strings.HasPrefix(line, bullet) {
+			return strings.TrimSpace(line[len(bullet):]), true
+		}
+	}
+	digits := 0
+	for digits < len(line) && line[digits] >= '0' && line[digits] <= '9' {
+		digits++
+	}
+	if digits > 0 && digits < len(line) && (line[digits] == '.' || line[digits] == ')') {
+		return strings.TrimSpace(line[digits+1:]), true
+	}
+	return "", false
+}
+
+// EditPlan replaces the text of the plan a task is waiting on review for,
+// so the approval codes what the reviewer wrote.
+func EditPlan(statePath, taskID, text string) error {
+	if strings.TrimSpace(text) == "" {
+		return errors.New("plan must not be empty")
+	}
+	return WithState(statePath, func(s *State) error {
+		task := s.GetTaskByID(taskID)
+		if task == nil {
+			return fmt.Errorf("task not found: %s", taskID)
+		}
+		p := task.GetPendingProposal()
+		if task.Status != PhaseAwaitingApproval || p == nil || p.Type != ProposalPlanReview {
+			return fmt.Errorf("%w: task %s", ErrNoPlanReview, taskID)
+		}
+		p.Plan = text
+		p.Summary = ParsePlan(text).Summary
+		return nil
+	})
+}
+
+// awaitPlanReview publishes plan as a proposal and stops the task until it
+// is approved (workflow.approval.after_planning); Resume then codes the
+// approved plan.
+func (e *Engine) awaitPlanReview(ctx context.Context, state *State, task *Task, plan *AIPlan) error {
+	p := task.AddProposal(ProposalPlanReview, plan.Summary,
+		"Workflow config requires a review of the plan before coding", nil)
+	p.Plan = FormatPlan(plan)
+
+	task.AddPipelineStep(PhaseAwaitingApproval, "running")
+	if err := Transition(task, PhaseAwaitingApproval); err != nil {
+		task.CompletePipelineStep(PhaseAwaitingApproval, "failed", "", err.Error())
+		return e.failTask(ctx, state, task, ReasonInfra, err)
+	}
+	e.notifyPhase(ctx, task, PhaseAwaitingApproval)
+	task.CompletePipelineStep(PhaseAwaitingApproval, "success", "plan waiting for review", "")
+
+	if err := mergeState(state, e.statePath); err != nil {
+		return fmt.Errorf("save state: %w", err)
+	}
+	e.taskLog(task.ID, "info", "Waiting for review of the plan before coding")
+	return ErrAwaitingApproval
+}
+
+// resumePlan codes the approved plan of a plan review.
+func (e *Engine) resumePlan(ctx context.Context, state *State, task *Task, proposal *Proposal) error {
+	plan := ParsePlan(proposal.Plan)
+	e.taskLog(task.ID, "info", fmt.Sprintf("Plan approved: %s", plan.Summary))
+
+	ctx, cancel := e.withTaskTimeout(ctx)
+	defer cancel()
+	return e.executePlan(ctx, state, task, e.buildVars(task), plan)
+}
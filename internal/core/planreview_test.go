@@ -0,0 +1,102 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFormatParsePlan(t *testing.T) {
+	plan := &AIPlan{Summary: "Add rate limiting", Steps: []string{"Add the limiter", "Wire it into server.go"}}
+	text := FormatPlan(plan)
+	if want := "Add rate limiting\n\n1. Add the limiter\n2. Wire it into server.go\n"; text != want {
+		t.Fatalf("FormatPlan = %q, want %q", text, want)
+	}
+	if got := ParsePlan(text); !reflect.DeepEqual(got, plan) {
+		t.Errorf("ParsePlan(FormatPlan) = %+v, want %+v", got, plan)
+	}
+
+	edited := ParsePlan("Add rate limiting\r\nper client\n\n- Add the limiter\n  with a token bucket\n* Document it\n3) Test it\n")
+	want := &AIPlan{
+		Summary: "Add rate limiting per client",
+		Steps:   []string{"Add the limiter with a token bucket", "Document it", "Test it"},
+	}
+	if !reflect.DeepEqual(edited, want) {
+		t.Errorf("ParsePlan(edited) = %+v, want %+v", edited, want)
+	}
+}
+
+func TestExecute_AfterPlanningWaitsForPlanReview(t *testing.T) {
+	cfg := testConfig()
+	cfg.Workflow.Approval.AfterPlanning = true
+
+	var coded *AIPlan
+	aiMock := &mockAI{
+		analyzeFunc: func(ctx context.Context, issue *AIIssue, projectContext string) (*AIPlan, error) {
+			return &AIPlan{Summary: "original plan", Steps: []string{"Change a.go"}}, nil
+		},
+		generateFunc: func(ctx context.Context, plan *AIPlan, repoFiles map[string]string) ([]AIFileChange, error) {
+			coded = plan
+			return []AIFileChange{{Path: "a.go", Content: "package a", Action: "modify"}}, nil
+		},
+	}
+	gitMock := &mockGit{}
+	runner := &mockTestRunner{results: []*TestResult{{Name: "unit-test", Type: "command", Passed: true, Duration: time.Second}}}
+	statePath := tempStatePath(t)
+	engine := NewEngine(cfg, gitMock, aiMock, &mockDeploy{deploySuccess: true}, []TestRunnerIface{runner}, nil, statePath)
+
+	if err := engine.Execute(context.Background(), testIssue()); !errors.Is(err, ErrAwaitingApproval) {
+		t.Fatalf("expected ErrAwaitingApproval, got %v", err)
+	}
+	if coded != nil {
+		t.Fatal("code was generated before the plan was approved")
+	}
+
+	state, _ := LoadState(statePath)
+	task := state.Tasks[0]
+	if task.Status != PhaseAwaitingApproval {
+		t.Fatalf("expected awaiting_approval, got %s", task.Status)
+	}
+	p := task.GetPendingProposal()
+	if p == nil || p.Type != ProposalPlanReview || p.Plan != "original plan\n\n1. Change a.go\n" {
+		t.Fatalf("unexpected proposal %+v", p)
+	}
+
+	if err := EditPlan(statePath, task.ID, "edited plan\n\n1. Change b.go\n"); err != nil {
+		t.Fatalf("EditPlan: %v", err)
+	}
+	if err := engine.Resume(context.Background(), task.ID, true); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	if coded == nil || coded.Summary != "edited plan" || !reflect.DeepEqual(coded.Steps, []string{"Change b.go"}) {
+		t.Fatalf("expected the edited plan to be coded, got %+v", coded)
+	}
+
+	state, _ = LoadState(statePath)
+	if got := state.Tasks[0].Status; got != PhaseCompleted {
+		t.Errorf("expected completed, got %s", got)
+	}
+	if gitMock.commitAndPushCalls != 1 {
+		t.Errorf("expected 1 commit, got %d", gitMock.commitAndPushCalls)
+	}
+}
+
+func TestEditPlan_RequiresPendingPlanReview(t *testing.T) {
+	statePath := tempStatePath(t)
+	state := &State{Tasks: []Task{{ID: "task-1", Status: PhaseCompleted}}}
+	if err := SaveState(state, statePath); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := EditPlan(statePath, "task-1", "plan"); !errors.Is(err, ErrNoPlanReview) {
+		t.Errorf("expected ErrNoPlanReview, got %v", err)
+	}
+	if err := EditPlan(statePath, "task-1", "  "); err == nil {
+		t.Error("expected an error for an empty plan")
+	}
+	if err := EditPlan(statePath, "missing", "plan"); err == nil || errors.Is(err, ErrNoPlanReview) {
+		t.Errorf("expected task not found, got %v", err)
+	}
+}
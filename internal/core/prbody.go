@@ -0,0 +1,139 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// PRBodyData is the data a PR body template is rendered with.
+type PRBodyData struct {
+	Task     *Task
+	Issue    Issue
+	Closes   string // "Closes #42", or "Closes owner/repo#42" for another repo; one per issue attached by workflow.dedup; "Part 1 of 3 of #42" for a part of an epic but the last
+	Plan     string
+	Files    []string
+	Deploy   *DeployResult
+	Tests    []TestResult
+	Attempts int
+}
+
+// defaultPRTemplate is used when workflow.pr_template is not set.
+const defaultPRTemplate = `## {{t "Automated Fix by Rig"}}
+
+{{if .Closes}}{{.Closes}}
+
+{{end}}{{if .Plan}}### {{t "Plan"}}
+{{.Plan}}
+
+{{end}}{{if .Files}}### {{t "Files Changed"}}
+{{range .Files}}- ` + "`{{.}}`" + `
+{{end}}
+{{end}}{{with .Deploy}}### {{t "Deploy"}}
+{{.Status}} ({{duration .Duration}})
+
+{{end}}{{if .Tests}}### {{t "Test Results"}}
+| {{t "Test"}} | {{t "Type"}} | {{t "Result"}} | {{t "Duration"}} |
+|------|------|--------|----------|
+{{range .Tests}}| {{.Name}} | {{.Type}} | {{if .Passed}}PASS{{else}}FAIL{{end}} | {{duration .Duration}} |
+{{end}}
+{{end}}{{if gt .Attempts 1}}### {{t "Attempt History"}}
+| # | {{t "Status"}} | {{t "Files"}} | {{t "Tests passed"}} |
+|---|--------|-------|--------------|
+{{range .Task.Attempts}}| {{.Number}} | {{.Status}} | {{len .FilesChanged}} | {{passed .Tests}}/{{len .Tests}} |
+{{end}}
+{{end}}_{{.Attempts}} {{t "attempt(s)"}}_
+`
+
+var prTemplateFuncs = template.FuncMap{
+	"duration": func(d time.Duration) string { return d.Round(time.Millisecond).String() },
+	"join":     strings.Join,
+	"passed": func(results []TestResult) int {
+		n := 0
+		for _, r := range results {
+			if r.Passed {
+				n++
+			}
+		}
+		return n
+	},
+}
+
+// buildPRBodyData collects the task facts shown in the PR description from
+// the last attempt.
+func buildPRBodyData(task *Task, sourceRepo string) PRBodyData {
+	data := PRBodyData{Task: task, Issue: task.Issue, Attempts: len(task.Attempts)}
+	var closes []string
+	switch {
+	case task.Issue.ID == "":
+	case task.Part != nil && task.Part.Index < task.Part.Of:
+		closes = append(closes, fmt.Sprintf("Part %d of %d of %s", task.Part.Index, task.Part.Of, issueRef(task.Issue, sourceRepo)))
+	default:
+		closes = append(closes, closesRef(task.Issue, sourceRepo))
+	}
+	for _, d := range task.Duplicates {
+		if d.Attached && d.Issue.ID != "" {
+			closes = append(closes, closesRef(d.Issue, sourceRepo))
+		}
+	}
+	data.Closes = strings.Join(closes, ", ")
+	if a := lastAttempt(task); a != nil {
+		data.Plan = a.Plan
+		data.Files = a.FilesChanged
+		data.Deploy = a.Deploy
+		data.Tests = a.Tests
+	}
+	return data
+}
+
+// closesRef is the closing keyword for issue in a PR of sourceRepo.
+func closesRef(issue Issue, sourceRepo string) string {
+	return "Closes " + issueRef(issue, sourceRepo)
+}
+
+// issueRef refers to issue from sourceRepo: "#42", or "owner/repo#42" for
+// another repo.
+func issueRef(issue Issue, sourceRepo string) string {
+	if issue.Repo == "" || issue.Repo == sourceRepo {
+		return "#" + issue.ID
+	}
+	return fmt.Sprintf("%s#%s", issue.Repo, issue.ID)
+}
+
+// renderPRBody renders the PR description. templatePath names a
+// text/template markdown file; empty uses the built-in template. lang
+// (ai.language) selects the headings produced by the "t" function.
+func renderPRBody(templatePath, lang string, data PRBodyData) (string, error) {
+	text := defaultPRTemplate
+	if templatePath != "" {
+		raw, err := os.ReadFile(templatePath)
+		if err != nil {
+			return "", fmt.Errorf("read PR template: %w", err)
+		}
+		text = string(raw)
+	}
+
+	tmpl, err := template.New("pr").Funcs(prTemplateFuncs).Funcs(template.FuncMap{"t": translator(lang)}).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("parse PR template: %w", err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("render PR template: %w", err)
+	}
+	return b.String(), nil
+}
+
+// prBody renders the PR description for task. A broken custom template must
+// not block a finished task, so it falls back to the default template.
+func (e *Engine) prBody(task *Task) string {
+	data := buildPRBodyData(task, e.cfg.Source.Repo)
+	body, err := renderPRBody(e.cfg.Workflow.PRTemplate, e.cfg.AI.Language, data)
+	if err != nil {
+		e.taskLog(task.ID, "warn", fmt.Sprintf("PR template failed, using default: %v", err))
+		body, _ = renderPRBody("", e.cfg.AI.Language, data)
+	}
+	return body
+}
@@ -0,0 +1,106 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func prTestTask() *Task {
+	return &Task{
+		ID:    "task-1",
+		Issue: Issue{Repo: "test/repo", ID: "42", Title: "Fix login"},
+		Attempts: []Attempt{
+			{Number: 1, Status: "failed"},
+			{
+				Number:       2,
+				Plan:         "Handle expired sessions",
+				FilesChanged: []string{"auth/login.go"},
+				Deploy:       &DeployResult{Status: "success", Duration: 1500 * time.Millisecond},
+				Tests: []TestResult{
+					{Name: "unit", Type: "command", Passed: true, Duration: 2 * time.Second},
+					{Name: "smoke", Type: "http", Passed: false},
+				},
+			},
+		},
+	}
+}
+
+func TestRenderPRBodyDefault(t *testing.T) {
+	body, err := renderPRBody("", "", buildPRBodyData(prTestTask(), "test/repo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Closes #42",
+		"### Plan\nHandle expired sessions",
+		"- `auth/login.go`",
+		"success (1.5s)",
+		"| unit | command | PASS | 2s |",
+		"| smoke | http | FAIL | 0s |",
+		"| 1 | failed | 0 | 0/0 |",
+		"| 2 |  | 1 | 1/2 |",
+		"_2 attempt(s)_",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestRenderPRBodyClosesOtherRepo(t *testing.T) {
+	data := buildPRBodyData(prTestTask(), "test/other")
+	if data.Closes != "Closes test/repo#42" {
+		t.Fatalf("Closes = %q", data.Closes)
+	}
+}
+
+func TestRenderPRBodyCustomTemplate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pr.md")
+	if err := os.WriteFile(path, []byte("{{.Issue.Title}}: {{join .Files \", \"}}\n{{.Closes}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	body, err := renderPRBody(path, "", buildPRBodyData(prTestTask(), "test/repo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "Fix login: auth/login.go\nCloses #42" {
+		t.Fatalf("unexpected body %q", body)
+	}
+}
+
+func TestEnginePRBodyFallsBackOnBrokenTemplate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pr.md")
+	if err := os.WriteFile(path, []byte("{{.Nope"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig()
+	cfg.Workflow.PRTemplate = path
+	engine := NewEngine(cfg, &mockGit{}, &mockAI{}, &mockDeploy{}, nil, nil, tempStatePath(t))
+
+	if body := engine.prBody(prTestTask()); !strings.Contains(body, "## Automated Fix by Rig") {
+		t.Fatalf("expected default template, got %q", body)
+	}
+}
+
+func TestRenderPRBodyLanguage(t *testing.T) {
+	body, err := renderPRBody("", "ko", buildPRBodyData(prTestTask(), "test/repo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"## Rig 자동 수정", "### 계획\nHandle expired sessions", "### 시도 이력", "_2 회 시도_"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body missing %q:\n%s", want, body)
+		}
+	}
+
+	body, err = renderPRBody("", "Klingon", buildPRBodyData(prTestTask(), "test/repo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(body, "### Plan") {
+		t.Errorf("expected English headings for a language without labels:\n%s", body)
+	}
+}
@@ -0,0 +1,97 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// PRAssigner is implemented by GitAdapters that can assign pull requests.
+type PRAssigner interface {
+	Assign(ctx context.Context, number int, assignees []string) error
+}
+
+// taskVarPattern matches the ${NAME} task variables of workflow.pr.
+var taskVarPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// routePR sends the published pr of task to people: the labels hooks asked
+// for, reviews from the CODEOWNERS owners, and the labels, reviewers and
+// assignees of workflow.pr.
+func (e *Engine) routePR(ctx context.Context, task *Task, pr *PullRequest) {
+	e.applyPRLabels(ctx, task, pr)
+	e.requestCodeOwners(ctx, task, pr)
+	e.applyPRConfig(ctx, task, pr)
+}
+
+// applyPRConfig adds the labels, reviewers and assignees of workflow.pr to
+// pr. Failures only warn: the PR is already open.
+func (e *Engine) applyPRConfig(ctx context.Context, task *Task, pr *PullRequest) {
+	cfg := e.cfg.Workflow.PR
+	if pr == nil || len(cfg.Labels)+len(cfg.Reviewers)+len(cfg.Assignees) == 0 {
+		return
+	}
+	number, err := strconv.Atoi(pr.ID)
+	if err != nil {
+		return
+	}
+	vars := e.buildVars(task)
+	labels := e.expandPREntries(task, "labels", cfg.Labels, vars)
+	reviewers := e.expandPREntries(task, "reviewers", cfg.Reviewers, vars)
+	assignees := e.expandPREntries(task, "assignees", cfg.Assignees, vars)
+
+	if len(labels) > 0 {
+		if labeler, ok := e.git.(PRLabeler); !ok {
+			e.taskLog(task.ID, "warn", "Git adapter cannot label PRs; skipping workflow.pr.labels")
+		} else if err := labeler.AddLabels(ctx, number, labels); err != nil {
+			e.taskLog(task.ID, "warn", fmt.Sprintf("Could not label PR: %v", err))
+		} else {
+			e.taskLog(task.ID, "info", fmt.Sprintf("Labeled PR with %s", strings.Join(labels, ", ")))
+		}
+	}
+	if len(reviewers) > 0 {
+		if requester, ok := e.git.(PRReviewerRequester); !ok {
+			e.taskLog(task.ID, "warn", "Git adapter cannot request PR reviewers; skipping workflow.pr.reviewers")
+		} else if err := requester.RequestReviewers(ctx, number, reviewers); err != nil {
+			e.taskLog(task.ID, "warn", fmt.Sprintf("Could not request PR reviewers: %v", err))
+		} else {
+			e.taskLog(task.ID, "info", fmt.Sprintf("Requested reviews from %s", strings.Join(reviewers, ", ")))
+		}
+	}
+	if len(assignees) > 0 {
+		if assigner, ok := e.git.(PRAssigner); !ok {
+			e.taskLog(task.ID, "warn", "Git adapter cannot assign PRs; skipping workflow.pr.assignees")
+		} else if err := assigner.Assign(ctx, number, assignees); err != nil {
+			e.taskLog(task.ID, "warn", fmt.Sprintf("Could not assign PR: %v", err))
+		} else {
+			e.taskLog(task.ID, "info", fmt.Sprintf("Assigned PR to %s", strings.Join(assignees, ", ")))
+		}
+	}
+}
+
+// expandPREntries substitutes vars into the workflow.pr entries of field
+// and returns the distinct ones left. Entries that are empty or still
+// reference a variable are skipped, the latter with a warning.
+func (e *Engine) expandPREntries(task *Task, field string, entries []string, vars map[string]string) []string {
+	var out []string
+	for _, entry := range entries {
+		unresolved := ""
+		v := taskVarPattern.ReplaceAllStringFunc(entry, func(match string) string {
+			if val, ok := vars[match[2:len(match)-1]]; ok {
+				return val
+			}
+			unresolved = match
+			return match
+		})
+		if unresolved != "" {
+			e.taskLog(task.ID, "warn", fmt.Sprintf("workflow.pr.%s entry %q has no value for %s; skipping it", field, entry, unresolved))
+			continue
+		}
+		if v = strings.TrimSpace(v); v != "" && !slices.Contains(out, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,53 @@
+package core
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/rigdev/rig/internal/config"
+)
+
+// routingGit is a mockGit that records how the PR was routed.
+type routingGit struct {
+	*mockGit
+	labels, reviewers, assignees []string
+}
+
+func (g *routingGit) AddLabels(ctx context.Context, number int, labels []string) error {
+	g.labels = append(g.labels, labels...)
+	return nil
+}
+
+func (g *routingGit) RequestReviewers(ctx context.Context, number int, reviewers []string) error {
+	g.reviewers = append(g.reviewers, reviewers...)
+	return nil
+}
+
+func (g *routingGit) Assign(ctx context.Context, number int, assignees []string) error {
+	g.assignees = append(g.assignees, assignees...)
+	return nil
+}
+
+func TestExecute_RoutesPR(t *testing.T) {
+	git := &routingGit{mockGit: &mockGit{}}
+	e, _ := hookEngine(t, git, nil, nil)
+	e.cfg.Workflow.PR = config.PRConfig{
+		Labels:    []string{"rig", "issue-${ISSUE_NUMBER}", "rig", " "},
+		Reviewers: []string{"@acme/backend", "${REPO_OWNER}"},
+		Assignees: []string{"@alice", "${ISSUE_AUTHOR}"},
+	}
+	if err := e.Execute(context.Background(), testIssue()); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+
+	if want := []string{"rig", "issue-42"}; !reflect.DeepEqual(git.labels, want) {
+		t.Errorf("labels = %v, want %v", git.labels, want)
+	}
+	if want := []string{"@acme/backend", "test"}; !reflect.DeepEqual(git.reviewers, want) {
+		t.Errorf("reviewers = %v, want %v", git.reviewers, want)
+	}
+	if want := []string{"@alice"}; !reflect.DeepEqual(git.assignees, want) {
+		t.Errorf("assignees = %v, want %v (unresolved entries skipped)", git.assignees, want)
+	}
+}
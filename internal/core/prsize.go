@@ -0,0 +1,166 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// commitGroup is one commit of a task's change set.
+type commitGroup struct {
+	Message string
+	Changes []AIFileChange
+}
+
+// commitMessage is the message of a task commit that is not split.
+func commitMessage(issueTitle string) string {
+	return fmt.Sprintf("rig: auto-fix %s", issueTitle)
+}
+
+// singleCommit puts all changes in one commit.
+func singleCommit(changes []AIFileChange, issueTitle string) []commitGroup {
+	return []commitGroup{{Message: commitMessage(issueTitle), Changes: changes}}
+}
+
+// commitGroups returns the commits for a generated change set. Change sets
+// within workflow.pr_size are committed at once; larger ones are split into
+// one commit per plan step so the PR can be reviewed commit by commit.
+func (e *Engine) commitGroups(task *Task, plan *AIPlan, changes []AIFileChange) []commitGroup {
+	limits := e.cfg.Workflow.PRSize
+	if limits.MaxFiles <= 0 && limits.MaxLines <= 0 {
+		return singleCommit(changes, task.Issue.Title)
+	}
+
+	workspace := ""
+	if wp, ok := e.git.(WorkspaceProvider); ok {
+		workspace = wp.GetWorkspace()
+	}
+	lines := changedLines(workspace, changes)
+	over := (limits.MaxFiles > 0 && len(changes) > limits.MaxFiles) ||
+		(limits.MaxLines > 0 && lines > limits.MaxLines)
+	if !over {
+		return singleCommit(changes, task.Issue.Title)
+	}
+
+	groups := splitByPlanSteps(plan, changes, task.Issue.Title)
+	if len(groups) > 1 {
+		e.taskLog(task.ID, "info", fmt.Sprintf(
+			"Change set of %d file(s), ~%d line(s) exceeds workflow.pr_size; committing in %d parts by plan step",
+			len(changes), lines, len(groups)))
+	} else {
+		e.taskLog(task.ID, "warn", fmt.Sprintf(
+			"Change set of %d file(s), ~%d line(s) exceeds workflow.pr_size but cannot be split by plan step",
+			len(changes), lines))
+	}
+	return groups
+}
+
+// splitByPlanSteps assigns each change to the first plan step that mentions
+// its path or file name and returns one commit per step in plan order.
+// Changes no step mentions go into a final commit.
+func splitByPlanSteps(plan *AIPlan, changes []AIFileChange, issueTitle string) []commitGroup {
+	if plan == nil || len(plan.Steps) < 2 {
+		return singleCommit(changes, issueTitle)
+	}
+
+	byStep := make([][]AIFileChange, len(plan.Steps)+1)
+	for _, c := range changes {
+		idx := len(plan.Steps)
+		for i, step := range plan.Steps {
+			if mentionsFile(step, c.Path) {
+				idx = i
+				break
+			}
+		}
+		byStep[idx] = append(byStep[idx], c)
+	}
+
+	type part struct {
+		title   string
+		changes []AIFileChange
+	}
+	var parts []part
+	for i, group := range byStep {
+		if len(group) == 0 {
+			continue
+		}
+		title := "remaining changes"
+		if i < len(plan.Steps) {
+			title = plan.Steps[i]
+		}
+		parts = append(parts, part{title, group})
+	}
+	if len(parts) < 2 {
+		return singleCommit(changes, issueTitle)
+	}
+
+	groups := make([]commitGroup, len(parts))
+	for i, p := range parts {
+		groups[i] = commitGroup{
+			Message: fmt.Sprintf("%s (%d/%d)\n\n%s", commitMessage(issueTitle), i+1, len(parts), p.title),
+			Changes: p.changes,
+		}
+	}
+	return groups
+}
+
+// mentionsFile reports whether step text refers to the file at p, by full
+// path or by file name.
+func mentionsFile(step, p string) bool {
+	step = strings.ToLower(step)
+	p = strings.ToLower(filepath.ToSlash(p))
+	return strings.Contains(step, p) || strings.Contains(step, path.Base(p))
+}
+
+// changedLines approximates the number of added and removed lines of a
+// change set against the files in workspace. Lines are compared as
+// multisets, which ignores moves but is cheap and good enough for a limit.
+func changedLines(workspace string, changes []AIFileChange) int {
+	total := 0
+	for _, c := range changes {
+		var old string
+		if workspace != "" {
+			if data, err := os.ReadFile(filepath.Join(workspace, c.Path)); err == nil {
+				old = string(data)
+			}
+		}
+		if c.Action == "delete" {
+			total += countLines(old)
+			continue
+		}
+		total += lineDelta(old, c.Content)
+	}
+	return total
+}
+
+// lineDelta counts the lines only in a plus the lines only in b.
+func lineDelta(a, b string) int {
+	counts := map[string]int{}
+	for _, l := range splitLines(a) {
+		counts[l]++
+	}
+	for _, l := range splitLines(b) {
+		counts[l]--
+	}
+	delta := 0
+	for _, n := range counts {
+		if n < 0 {
+			n = -n
+		}
+		delta += n
+	}
+	return delta
+}
+
+func countLines(s string) int {
+	return len(splitLines(s))
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
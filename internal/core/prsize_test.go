@@ -0,0 +1,75 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitByPlanSteps(t *testing.T) {
+	plan := &AIPlan{Steps: []string{
+		"Add the Limit type in internal/limit/limit.go",
+		"Wire the limiter into server.go",
+		"Document it",
+	}}
+	changes := []AIFileChange{
+		{Path: "server.go", Action: "modify"},
+		{Path: "internal/limit/limit.go", Action: "create"},
+		{Path: "internal/limit/limit_test.go", Action: "create"},
+	}
+
+	groups := splitByPlanSteps(plan, changes, "Rate limit")
+	if len(groups) != 3 {
+		t.Fatalf("expected 3 commits, got %d: %+v", len(groups), groups)
+	}
+	if groups[0].Changes[0].Path != "internal/limit/limit.go" || groups[1].Changes[0].Path != "server.go" {
+		t.Errorf("commits not in plan order: %+v", groups)
+	}
+	if groups[2].Changes[0].Path != "internal/limit/limit_test.go" {
+		t.Errorf("unmentioned file should be in the last commit: %+v", groups[2])
+	}
+	if !strings.HasPrefix(groups[1].Message, "rig: auto-fix Rate limit (2/3)\n\nWire the limiter") {
+		t.Errorf("unexpected message %q", groups[1].Message)
+	}
+
+	if got := splitByPlanSteps(&AIPlan{Steps: []string{"one step"}}, changes, "x"); len(got) != 1 {
+		t.Errorf("single-step plan should not split, got %d commits", len(got))
+	}
+}
+
+func TestLineDelta(t *testing.T) {
+	if n := lineDelta("a\nb\nc\n", "a\nB\nc\nd\n"); n != 3 {
+		t.Errorf("lineDelta = %d, want 3", n)
+	}
+	if n := lineDelta("", "x\ny"); n != 2 {
+		t.Errorf("lineDelta for new file = %d, want 2", n)
+	}
+}
+
+func TestExecute_SplitsOversizedChangeSet(t *testing.T) {
+	cfg := testConfig()
+	cfg.Workflow.PRSize.MaxFiles = 1
+
+	gitMock := &mockGit{}
+	aiMock := &mockAI{
+		analyzeFunc: func(ctx context.Context, issue *AIIssue, projectContext string) (*AIPlan, error) {
+			return &AIPlan{Summary: "plan", Steps: []string{"Update a.go", "Update b.go"}}, nil
+		},
+		generateFunc: func(ctx context.Context, plan *AIPlan, repoFiles map[string]string) ([]AIFileChange, error) {
+			return []AIFileChange{
+				{Path: "b.go", Content: "package b", Action: "modify"},
+				{Path: "a.go", Content: "package a", Action: "modify"},
+			}, nil
+		},
+	}
+	runner := &mockTestRunner{results: []*TestResult{{Name: "unit-test", Type: "command", Passed: true, Duration: time.Second}}}
+	engine := NewEngine(cfg, gitMock, aiMock, &mockDeploy{deploySuccess: true}, []TestRunnerIface{runner}, nil, tempStatePath(t))
+
+	if err := engine.Execute(context.Background(), testIssue()); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if gitMock.commitAndPushCalls != 2 {
+		t.Errorf("expected 2 commits, got %d", gitMock.commitAndPushCalls)
+	}
+}
@@ -0,0 +1,138 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// Steps a finished task can run again with Rerun.
+const (
+	RerunDeploy = "deploy"
+	RerunTest   = "test"
+)
+
+// ErrTaskNotFinished is returned by Rerun for a task still in progress.
+var ErrTaskNotFinished = errors.New("task has not finished")
+
+// Rerun runs one step of a finished task again without generating code:
+// RerunDeploy deploys the branch and vars recorded by the task's last
+// deploy and then tests it, RerunTest only runs the tests against what is
+// deployed. It is meant for infrastructure changes made after the task,
+// and records the run as a new attempt without changing the task status.
+// A redeploy that passes becomes the deploy snapshot; one that fails is
+// rolled back when deploy.rollback is enabled.
+func (e *Engine) Rerun(ctx context.Context, taskID, step string) error {
+	if step != RerunDeploy && step != RerunTest {
+		return fmt.Errorf("cannot rerun step %q: must be %s or %s", step, RerunDeploy, RerunTest)
+	}
+	lock, state, task, err := e.lockTask(taskID)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+	ctx = WithTaskUsage(ctx, task)
+
+	switch task.Status {
+	case PhaseCompleted, PhaseFailed, PhaseRollback:
+	default:
+		return fmt.Errorf("%w: task %s is %s", ErrTaskNotFinished, task.ID, task.Status)
+	}
+
+	vars, files := e.rerunInputs(task)
+	if vars["BRANCH_NAME"] == "" {
+		return fmt.Errorf("task %s has no branch to %s", task.ID, step)
+	}
+
+	ctx, cancel := e.withTaskTimeout(ctx)
+	defer cancel()
+
+	attempt := newAttempt(len(task.Attempts) + 1)
+	attempt.Plan = "Manual " + step + " rerun"
+	attempt.Rerun = step
+	attempt.FilesChanged = files
+	e.taskLog(task.ID, "info", fmt.Sprintf("Rerunning %s of branch %s", step, vars["BRANCH_NAME"]))
+
+	finish := func(status string, reason FailReason, cause error) error {
+		completeAttempt(&attempt, status, reason)
+		task.Attempts = append(task.Attempts, attempt)
+		if cause != nil {
+			e.taskLog(task.ID, "error", fmt.Sprintf("Rerun of %s failed: %v", step, cause))
+		} else {
+			e.taskLog(task.ID, "info", fmt.Sprintf("Rerun of %s passed", step))
+		}
+		if step == RerunDeploy && cause == nil {
+			e.recordDeploySnapshot(state, task)
+		}
+		if step == RerunDeploy && cause != nil && attempt.Deploy != nil && e.cfg.Deploy.Rollback.Enabled {
+			rctx, rcancel := cleanupContext(ctx)
+			defer rcancel()
+			if err := stepRollback(rctx, e.deployer(task), state.DeploySnapshot(e.snapshotKey(task))); err != nil {
+				e.taskLog(task.ID, "error", fmt.Sprintf("Rollback after rerun failed: %v", err))
+			} else {
+				e.taskLog(task.ID, "info", "Rollback after rerun completed")
+			}
+		}
+		if err := mergeState(state, e.statePath); err != nil {
+			return err
+		}
+		if cause != nil {
+			return fmt.Errorf("rerun %s of task %s: %w", step, task.ID, cause)
+		}
+		return nil
+	}
+
+	if step == RerunDeploy {
+		deployCtx, cancelDeploy := e.withPhaseTimeout(ctx, PhaseDeploying)
+		span := e.journalBegin(ctx, task, StepDeploy, hashStepInput(vars))
+		result, err := e.deployStep(deployCtx, task, vars)
+		span.end(outcome(err, result != nil && result.Status == "success"))
+		cancelDeploy()
+		if err != nil {
+			return finish("failed", reasonFor(err, ReasonDeploy), timeoutCause(deployCtx, err))
+		}
+		attempt.Deploy = result
+		e.storeArtifacts(ctx, task, &attempt)
+		if result.Status != "success" {
+			return finish("failed", ReasonDeploy, timeoutCause(deployCtx, fmt.Errorf("deploy failed: %s", result.Output)))
+		}
+	}
+
+	testCtx, cancelTest := e.withPhaseTimeout(ctx, PhaseTesting)
+	span := e.journalBegin(ctx, task, StepTest, hashStepInput([]any{vars, files}))
+	results, allPassed := stepTest(testCtx, e.testRunners, e.testConfigs, files, vars, e.testRunOptions())
+	span.end(outcome(nil, allPassed))
+	cancelTest()
+	e.logFlakyTests(task.ID, results)
+	attempt.Tests = results
+	e.storeArtifacts(ctx, task, &attempt)
+	if te := timedOut(testCtx); te != nil && !allPassed {
+		return finish("failed", ReasonTimeout, te)
+	}
+	if !allPassed {
+		return finish("failed", ReasonTest, errors.New("test failures detected"))
+	}
+	return finish("passed", "", nil)
+}
+
+// rerunInputs returns the vars of the last deploy of task, falling back to
+// the built-in variables, and the files changed by its last attempt that
+// changed any.
+func (e *Engine) rerunInputs(task *Task) (map[string]string, []string) {
+	var vars map[string]string
+	var files []string
+	for _, a := range slices.Backward(task.Attempts) {
+		if vars == nil && a.Deploy != nil && len(a.Deploy.Vars) > 0 {
+			vars = maps.Clone(a.Deploy.Vars)
+		}
+		if files == nil && len(a.FilesChanged) > 0 {
+			files = slices.Clone(a.FilesChanged)
+		}
+	}
+	if vars == nil {
+		vars = e.buildVars(task)
+	}
+	return vars, files
+}
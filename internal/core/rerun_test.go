@@ -0,0 +1,79 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestEngine_Rerun(t *testing.T) {
+	cfg := testConfig()
+	cfg.Deploy.Rollback.Enabled = true
+	statePath := tempStatePath(t)
+	deploy := &varsDeploy{mockDeploy: mockDeploy{deploySuccess: true}}
+	runner := &mockTestRunner{}
+
+	engine := NewEngine(cfg, &mockGit{}, &mockAI{}, deploy, []TestRunnerIface{runner}, nil, statePath)
+	if err := engine.Execute(context.Background(), testIssue()); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	state, _ := LoadState(statePath)
+	task := state.GetTask("42")
+	attempts := len(task.Attempts)
+
+	if err := engine.Rerun(context.Background(), task.ID, RerunDeploy); err != nil {
+		t.Fatalf("redeploy: %v", err)
+	}
+	if len(deploy.vars) != 2 || deploy.vars[1]["BRANCH_NAME"] != task.Branch {
+		t.Fatalf("deploys = %v, want a second one of branch %s", deploy.vars, task.Branch)
+	}
+
+	runner.results = []*TestResult{{Name: "smoke", Type: "http", Passed: false, Duration: time.Second}}
+	if err := engine.Rerun(context.Background(), task.ID, RerunTest); err == nil {
+		t.Fatal("retest with a failing test returned no error")
+	}
+	if len(deploy.vars) != 2 || deploy.rollbackCalls != 0 {
+		t.Errorf("retest deployed %d times and rolled back %d times", len(deploy.vars)-2, deploy.rollbackCalls)
+	}
+
+	state, _ = LoadState(statePath)
+	task = state.GetTask("42")
+	if task.Status != PhaseCompleted {
+		t.Errorf("status = %s, want it left completed", task.Status)
+	}
+	if len(task.Attempts) != attempts+2 {
+		t.Fatalf("got %d attempts, want %d", len(task.Attempts), attempts+2)
+	}
+	redeploy, retest := task.Attempts[attempts], task.Attempts[attempts+1]
+	if redeploy.Status != "passed" || redeploy.Deploy == nil || retest.Status != "failed" || retest.FailReason != ReasonTest || retest.Deploy != nil {
+		t.Errorf("redeploy attempt = %+v, retest attempt = %+v", redeploy, retest)
+	}
+
+	// A failed redeploy is rolled back.
+	deploy.deploySuccess = false
+	if err := engine.Rerun(context.Background(), task.ID, RerunDeploy); err == nil {
+		t.Fatal("failed redeploy returned no error")
+	}
+	if deploy.rollbackCalls != 1 {
+		t.Errorf("rollback calls = %d, want 1", deploy.rollbackCalls)
+	}
+
+	if err := engine.Rerun(context.Background(), task.ID, "code"); err == nil {
+		t.Error("rerun of the code step returned no error")
+	}
+}
+
+func TestEngine_RerunUnfinishedTask(t *testing.T) {
+	statePath := tempStatePath(t)
+	state := &State{Version: "1.0"}
+	task := state.CreateTask(testIssue())
+	task.Status = PhaseDeploying
+	if err := SaveState(state, statePath); err != nil {
+		t.Fatal(err)
+	}
+	engine := NewEngine(testConfig(), &mockGit{}, &mockAI{}, &mockDeploy{}, nil, nil, statePath)
+	if err := engine.Rerun(context.Background(), task.ID, RerunTest); !errors.Is(err, ErrTaskNotFinished) {
+		t.Errorf("err = %v, want ErrTaskNotFinished", err)
+	}
+}
@@ -0,0 +1,142 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rigdev/rig/internal/ignore"
+)
+
+// maxContextFileSize skips repository files too large to send as context.
+const maxContextFileSize = 100 * 1024
+
+// FileRetriever picks the repository files most relevant to a query, best
+// first. Set with Engine.SetFileRetriever (ai.index); without one the
+// engine sends the first files of the repository.
+type FileRetriever interface {
+	Relevant(ctx context.Context, workspace, query string) ([]string, error)
+}
+
+// SetFileRetriever sets the retriever that selects the repository files
+// given to code generation and failure analysis.
+func (e *Engine) SetFileRetriever(r FileRetriever) {
+	e.retriever = r
+}
+
+// contextFiles returns the repository files given to code generation: the
+// ones the retriever ranks most relevant to query, or else the first files
+// of the workspace.
+func (e *Engine) contextFiles(ctx context.Context, taskID, workspace, query string) map[string]string {
+	ignored := e.ignoreMatcher(workspace)
+	if e.retriever != nil && workspace != "" {
+		paths, err := e.retriever.Relevant(ctx, workspace, query)
+		if err == nil {
+			return readRepoFiles(workspace, paths, ignored)
+		}
+		e.taskLog(taskID, "warn", fmt.Sprintf("File retrieval failed, loading repo files in order: %v", err))
+	}
+	return loadRepoFiles(workspace, 50, maxContextFileSize, ignored)
+}
+
+// ignoreMatcher returns the matcher of the files of workspace kept out of
+// the AI's context and changes: the ignored ones, by .gitignore or
+// ai.context_ignore, and the vendored ones.
+func (e *Engine) ignoreMatcher(workspace string) *ignore.Matcher {
+	return ignore.New(workspace, e.cfg.AI.ContextIgnore)
+}
+
+// screenChanges drops the AI changes to ignored files, which git would not
+// commit, and refuses the ones to binary files, which the AI can only
+// garble.
+func (e *Engine) screenChanges(task *Task, changes []AIFileChange) ([]AIFileChange, error) {
+	workspace := ""
+	if wp, ok := e.git.(WorkspaceProvider); ok {
+		workspace = wp.GetWorkspace()
+	}
+	ignored := e.ignoreMatcher(workspace)
+	kept := make([]AIFileChange, 0, len(changes))
+	var binary, dropped []string
+	for _, c := range changes {
+		switch {
+		case ignore.BinaryPath(c.Path) || ignore.IsBinary([]byte(c.Content)) ||
+			workspace != "" && ignore.BinaryFile(filepath.Join(workspace, filepath.FromSlash(c.Path))):
+			binary = append(binary, c.Path)
+		case ignored.Ignored(c.Path, false):
+			dropped = append(dropped, c.Path)
+		default:
+			kept = append(kept, c)
+		}
+	}
+	if len(binary) > 0 {
+		return nil, fmt.Errorf("AI changed binary file(s) %s; only text files can be changed", strings.Join(binary, ", "))
+	}
+	if len(dropped) > 0 {
+		e.taskLog(task.ID, "warn", fmt.Sprintf("Dropped AI changes to ignored or vendored file(s): %s", strings.Join(dropped, ", ")))
+		if len(kept) == 0 {
+			return nil, fmt.Errorf("AI changed only ignored or vendored files: %s", strings.Join(dropped, ", "))
+		}
+	}
+	return kept, nil
+}
+
+// addRelevantFiles adds the repository files most relevant to the failure
+// logs to the code given to failure analysis, when a retriever is set.
+// Files already in code keep their generated content.
+func (e *Engine) addRelevantFiles(ctx context.Context, taskID, logs string, code map[string]string) {
+	wp, ok := e.git.(WorkspaceProvider)
+	if e.retriever == nil || !ok || wp.GetWorkspace() == "" {
+		return
+	}
+	paths, err := e.retriever.Relevant(ctx, wp.GetWorkspace(), logs)
+	if err != nil {
+		e.taskLog(taskID, "warn", fmt.Sprintf("File retrieval failed: %v", err))
+		return
+	}
+	for path, content := range readRepoFiles(wp.GetWorkspace(), paths, e.ignoreMatcher(wp.GetWorkspace())) {
+		if _, ok := code[path]; !ok {
+			code[path] = content
+		}
+	}
+}
+
+// readRepoFiles reads the given workspace-relative files, skipping ones
+// that are missing, too large, binary or ignored.
+func readRepoFiles(workspace string, paths []string, ignored *ignore.Matcher) map[string]string {
+	files := make(map[string]string, len(paths))
+	for _, path := range paths {
+		if ignored.Ignored(path, false) {
+			continue
+		}
+		full := filepath.Join(workspace, filepath.FromSlash(path))
+		info, err := os.Stat(full)
+		if err != nil || info.Size() > maxContextFileSize {
+			continue
+		}
+		content, err := os.ReadFile(full)
+		if err != nil || ignore.IsBinary(content) {
+			continue
+		}
+		files[path] = string(content)
+	}
+	return files
+}
+
+// planQuery is the retrieval query for code generation: the issue and the
+// plan made for it.
+func planQuery(issue *Issue, plan *AIPlan) string {
+	var b strings.Builder
+	b.WriteString(issue.Title)
+	b.WriteString("\n")
+	if plan != nil {
+		b.WriteString(plan.Summary)
+		b.WriteString("\n")
+		for _, step := range plan.Steps {
+			b.WriteString(step)
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
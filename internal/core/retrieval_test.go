@@ -0,0 +1,123 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// workspaceGit is a mockGit with a workspace on disk.
+type workspaceGit struct {
+	mockGit
+	workspace string
+}
+
+func (g *workspaceGit) GetWorkspace() string { return g.workspace }
+
+// fixedRetriever returns paths, or err, and records the queries.
+type fixedRetriever struct {
+	paths   []string
+	err     error
+	queries []string
+}
+
+func (r *fixedRetriever) Relevant(ctx context.Context, workspace, query string) ([]string, error) {
+	r.queries = append(r.queries, query)
+	return r.paths, r.err
+}
+
+func TestEngine_FileRetriever(t *testing.T) {
+	workspace := t.TempDir()
+	for path, content := range map[string]string{
+		"auth/login.go": "package auth",
+		"web/page.go":   "package web",
+		"main.go":       "package main",
+	} {
+		os.MkdirAll(filepath.Join(workspace, filepath.Dir(path)), 0o755)
+		os.WriteFile(filepath.Join(workspace, path), []byte(content), 0o644)
+	}
+
+	var got map[string]string
+	aiMock := &mockAI{
+		generateFunc: func(ctx context.Context, plan *AIPlan, repoFiles map[string]string) ([]AIFileChange, error) {
+			got = repoFiles
+			return []AIFileChange{{Path: "auth/login.go", Content: "package auth // fixed", Action: "modify"}}, nil
+		},
+	}
+	retriever := &fixedRetriever{paths: []string{"auth/login.go", "gone.go"}}
+	engine := NewEngine(testConfig(), &workspaceGit{workspace: workspace}, aiMock, &mockDeploy{deploySuccess: true}, nil, nil, tempStatePath(t))
+	engine.SetFileRetriever(retriever)
+
+	if err := engine.Execute(context.Background(), testIssue()); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if len(got) != 1 || got["auth/login.go"] != "package auth" {
+		t.Errorf("repo files = %v, want only the retrieved file", got)
+	}
+	if len(retriever.queries) != 1 || !strings.Contains(retriever.queries[0], testIssue().Title) {
+		t.Errorf("queries = %q, want the issue and plan", retriever.queries)
+	}
+
+	// Failure analysis gets the relevant files next to the generated code.
+	code := map[string]string{"auth/login.go": "package auth // fixed"}
+	retriever.paths = []string{"web/page.go", "auth/login.go"}
+	engine.addRelevantFiles(context.Background(), "t1", "FAIL web", code)
+	if len(code) != 2 || code["auth/login.go"] != "package auth // fixed" || code["web/page.go"] != "package web" {
+		t.Errorf("failure code = %v", code)
+	}
+
+	// A failing retriever falls back to loading files in order.
+	retriever.err = errors.New("embeddings down")
+	if files := engine.contextFiles(context.Background(), "t1", workspace, "q"); len(files) != 3 {
+		t.Errorf("fallback loaded %d files, want 3", len(files))
+	}
+}
+
+func TestEngine_ContextSkipsIgnoredAndBinaryFiles(t *testing.T) {
+	workspace := t.TempDir()
+	for path, content := range map[string]string{
+		".gitignore":            "gen/\n",
+		"main.go":               "package main",
+		"gen/api.go":            "package gen",
+		"vendor/lib/lib.go":     "package lib",
+		"api/api.pb.go":         "package api",
+		"assets/blob.json":      "{\x00}",
+		"internal/util/util.go": "package util",
+	} {
+		os.MkdirAll(filepath.Join(workspace, filepath.Dir(path)), 0o755)
+		os.WriteFile(filepath.Join(workspace, path), []byte(content), 0o644)
+	}
+	cfg := testConfig()
+	cfg.AI.ContextIgnore = []string{"*.pb.go"}
+	engine := NewEngine(cfg, &workspaceGit{workspace: workspace}, &mockAI{}, &mockDeploy{deploySuccess: true}, nil, nil, tempStatePath(t))
+
+	files := engine.contextFiles(context.Background(), "t1", workspace, "q")
+	if len(files) != 2 || files["main.go"] == "" || files["internal/util/util.go"] == "" {
+		t.Errorf("repo files = %v, want main.go and internal/util/util.go", files)
+	}
+
+	task := &Task{ID: "t1"}
+	kept, err := engine.screenChanges(task, []AIFileChange{
+		{Path: "main.go", Content: "package main // fixed", Action: "modify"},
+		{Path: "gen/api.go", Content: "package gen // fixed", Action: "modify"},
+		{Path: "vendor/lib/lib.go", Action: "delete"},
+	})
+	if err != nil || len(kept) != 1 || kept[0].Path != "main.go" {
+		t.Errorf("kept %v, %v; want main.go only", kept, err)
+	}
+	for _, change := range []AIFileChange{
+		{Path: "assets/blob.json", Content: "{}", Action: "modify"},
+		{Path: "logo.png", Content: "not really a png", Action: "create"},
+		{Path: "data.txt", Content: "a\x00b", Action: "create"},
+	} {
+		if _, err := engine.screenChanges(task, []AIFileChange{change}); err == nil || !strings.Contains(err.Error(), "binary") {
+			t.Errorf("expected the change to %s to be refused, got %v", change.Path, err)
+		}
+	}
+	if _, err := engine.screenChanges(task, []AIFileChange{{Path: "gen/api.go", Action: "delete"}}); err == nil {
+		t.Error("expected changes to ignored files only to fail")
+	}
+}
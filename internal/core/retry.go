@@ -4,11 +4,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+
+	"github.com/rigdev/rig/internal/logging"
 )
 
 // retryLoop implements the self-correction cycle:
-// test fail -> AI AnalyzeFailure -> GenerateCode -> redeploy -> retest.
+// test fail -> AI AnalyzeFailure -> GenerateCode -> redeploy -> retest,
+// waiting the workflow.retry_policy backoff before each redeploy. With
+// workflow.triage each failure is classified first and the cycle follows
+// its retryRoute.
 // It returns nil when tests pass, or an error when max retries are exceeded.
 func retryLoop(
 	ctx context.Context,
@@ -20,128 +24,236 @@ func retryLoop(
 	maxRetry int,
 ) error {
 	retryCount := 0
+	var lastDelta *TestDelta
+	var bareRetried FailureClass
 
 	for {
 		// Check for context cancellation between retries.
 		select {
 		case <-ctx.Done():
-			return fmt.Errorf("retry cancelled: %w", ctx.Err())
+			return timeoutCause(ctx, fmt.Errorf("retry cancelled: %w", ctx.Err()))
 		default:
 		}
 
 		retryCount++
 		if maxRetry > 0 && retryCount > maxRetry {
+			e.postIssueUpdate(ctx, task, IssueUpdateFailed, testFailureUpdate(maxRetry, testResults))
 			return fmt.Errorf("max retry count (%d) exceeded", maxRetry)
 		}
 
 		if maxRetry > 0 {
-			log.Printf("[engine] retry %d/%d for task %s", retryCount, maxRetry, task.ID)
+			e.log().Info("retrying", logging.TaskKey, task.ID, "retry", retryCount, "max_retry", maxRetry)
 		} else {
-			log.Printf("[engine] retry %d (unlimited) for task %s", retryCount, task.ID)
+			e.log().Info("retrying (unlimited)", logging.TaskKey, task.ID, "retry", retryCount)
 		}
 
-		failureLogs := collectTestOutput(testResults)
+		failureLogs := formatFailureLogs(testResults, lastDelta)
 
 		currentCode := make(map[string]string, len(changes))
 		for _, c := range changes {
 			currentCode[c.Path] = c.Content
 		}
 
-		if err := Transition(task, PhaseCoding); err != nil {
-			return fmt.Errorf("transition to coding for retry: %w", err)
-		}
-		e.notifyPhase(ctx, task, PhaseCoding)
-		task.AddPipelineStep(PhaseCoding, "running")
-
-		fixChanges, err := e.ai.AnalyzeFailure(ctx, failureLogs, currentCode)
-		if err != nil {
-			task.CompletePipelineStep(PhaseCoding, "failed", "", err.Error())
-			return fmt.Errorf("analyze failure: %w", err)
+		route := retryRoute{codegen: true, redeploy: true}
+		if e.cfg.Workflow.Triage.Enabled {
+			route = routeFailure(e.triage(ctx, task.ID, testResults), bareRetried)
 		}
-		if err := e.enforcePolicies(task, fixChanges); err != nil {
-			task.CompletePipelineStep(PhaseCoding, "failed", "", err.Error())
-			return fmt.Errorf("policy evaluation: %w", err)
+		if route.proposeDeployFix {
+			e.taskLog(task.ID, "info", "Infra failure persisted after a re-run; proposing a deploy fix")
+			err := e.handleDeployFailure(enableDeployFailureAnalysis(ctx), task, failureLogs)
+			if errors.Is(err, ErrAwaitingApproval) {
+				return ErrAwaitingApproval
+			}
+			e.taskLog(task.ID, "warn", fmt.Sprintf("Deploy fix analysis failed, fixing the code instead: %v", err))
+			route.codegen, route.redeploy = true, true
 		}
-		task.CompletePipelineStep(PhaseCoding, "success", fmt.Sprintf("generated %d retry file changes", len(fixChanges)), "")
+		bareRetried = ""
 
 		newAttemptNum := len(task.Attempts) + 1
 		retryAttempt := newAttempt(newAttemptNum)
-		retryAttempt.Plan = fmt.Sprintf("Retry #%d: fix based on test failures", retryCount)
+		retryAttempt.Triage = route.class
 
-		filesChanged := make([]string, len(fixChanges))
-		for i, c := range fixChanges {
-			filesChanged[i] = c.Path
-		}
-		retryAttempt.FilesChanged = filesChanged
-
-		if err := Transition(task, PhaseCommitting); err != nil {
-			completeAttempt(&retryAttempt, "failed", ReasonGit)
-			task.Attempts = append(task.Attempts, retryAttempt)
-			return fmt.Errorf("transition to committing for retry: %w", err)
-		}
-		e.notifyPhase(ctx, task, PhaseCommitting)
-		task.AddPipelineStep(PhaseCommitting, "running")
-
-		_, err = stepCommit(ctx, e.git, task.Branch, fixChanges, task.Issue.Title)
-		if err != nil {
-			task.CompletePipelineStep(PhaseCommitting, "failed", "", err.Error())
-			completeAttempt(&retryAttempt, "failed", ReasonGit)
-			task.Attempts = append(task.Attempts, retryAttempt)
-			return fmt.Errorf("commit retry changes: %w", err)
-		}
-		task.CompletePipelineStep(PhaseCommitting, "success", "retry changes committed", "")
+		var fixChanges []AIFileChange
+		var signature string
+		var hintIDs []int64
+		if !route.codegen {
+			// Re-run without code changes: straight back to deploy and test.
+			bareRetried = route.class
+			retryAttempt.Plan = fmt.Sprintf("Retry #%d: re-run after %s failure without code changes", retryCount, route.class)
+			for _, c := range changes {
+				retryAttempt.FilesChanged = append(retryAttempt.FilesChanged, c.Path)
+			}
+			e.taskLog(task.ID, "info", fmt.Sprintf("Retry #%d re-runs the %s failure without code changes", retryCount, route.class))
+			task.AddPipelineStep(PhaseCoding, "running")
+			task.CompletePipelineStep(PhaseCoding, "skipped", fmt.Sprintf("%s failure: retrying without code changes", route.class), "")
+		} else {
+			if err := Transition(task, PhaseCoding); err != nil {
+				return fmt.Errorf("transition to coding for retry: %w", err)
+			}
+			e.notifyPhase(ctx, task, PhaseCoding)
+			task.AddPipelineStep(PhaseCoding, "running")
 
-		task.AddPipelineStep(PhaseApproval, "running")
-		task.CompletePipelineStep(PhaseApproval, "skipped", "auto approval step skipped", "")
+			retryAI, model := e.retryAI(task)
+			if model != e.cfg.AI.Model {
+				e.taskLog(task.ID, "info", fmt.Sprintf("Retry #%d using model %s", retryCount, model))
+			}
+			codeCtx, cancelCode := e.withPhaseTimeout(ctx, PhaseCoding)
+			e.addRelevantFiles(codeCtx, task.ID, failureLogs, currentCode)
+			signature = FailureSignature(failureLogs)
+			hints := e.fixHints(codeCtx, task.ID, signature)
+			hintIDs = make([]int64, len(hints))
+			for i, h := range hints {
+				hintIDs[i] = h.ID
+			}
+			if len(hints) > 0 {
+				e.taskLog(task.ID, "info", fmt.Sprintf("Giving %d past fix(es) of similar failures as hints", len(hints)))
+			}
+			var err error
+			span := e.journalBegin(ctx, task, StepCode, hashStepInput(failureLogs))
+			fixChanges, err = retryAI.AnalyzeFailure(codeCtx, formatFixHints(hints, failureLogs), currentCode)
+			span.end(err)
+			cancelCode()
+			if err != nil {
+				err = timeoutCause(codeCtx, err)
+				task.CompletePipelineStep(PhaseCoding, "failed", "", err.Error())
+				return fmt.Errorf("analyze failure: %w", err)
+			}
+			if fixChanges, err = e.screenChanges(task, fixChanges); err != nil {
+				task.CompletePipelineStep(PhaseCoding, "failed", "", err.Error())
+				return fmt.Errorf("analyze failure: %w", err)
+			}
+			if err := e.enforcePolicies(task, fixChanges); err != nil {
+				task.CompletePipelineStep(PhaseCoding, "failed", "", err.Error())
+				return fmt.Errorf("policy evaluation: %w", err)
+			}
+			task.CompletePipelineStep(PhaseCoding, "success", fmt.Sprintf("generated %d retry file changes", len(fixChanges)), "")
 
-		if err := Transition(task, PhaseDeploying); err != nil {
-			completeAttempt(&retryAttempt, "failed", ReasonDeploy)
-			task.Attempts = append(task.Attempts, retryAttempt)
-			return fmt.Errorf("transition to deploying for retry: %w", err)
-		}
-		e.notifyPhase(ctx, task, PhaseDeploying)
-		task.AddPipelineStep(PhaseDeploying, "running")
+			retryAttempt.Plan = fmt.Sprintf("Retry #%d: fix based on test failures", retryCount)
+			retryAttempt.Provider, retryAttempt.Model = e.answeredBy(ctx, model)
 
-		deployResult, err := stepDeploy(ctx, e.deploy, vars)
-		if err != nil {
-			task.CompletePipelineStep(PhaseDeploying, "failed", "", err.Error())
-			completeAttempt(&retryAttempt, "failed", ReasonDeploy)
-			task.Attempts = append(task.Attempts, retryAttempt)
-			return fmt.Errorf("deploy retry: %w", err)
-		}
-		retryAttempt.Deploy = deployResult
+			filesChanged := make([]string, len(fixChanges))
+			for i, c := range fixChanges {
+				filesChanged[i] = c.Path
+			}
+			retryAttempt.FilesChanged = filesChanged
+			retryAttempt.FixHints = hintIDs
 
-		if deployResult.Status != "success" {
-			task.CompletePipelineStep(PhaseDeploying, "failed", deployResult.Output, "deploy failed during retry")
-			completeAttempt(&retryAttempt, "failed", ReasonDeploy)
-			task.Attempts = append(task.Attempts, retryAttempt)
+			if err := Transition(task, PhaseCommitting); err != nil {
+				completeAttempt(&retryAttempt, "failed", ReasonGit)
+				task.Attempts = append(task.Attempts, retryAttempt)
+				return fmt.Errorf("transition to committing for retry: %w", err)
+			}
+			e.notifyPhase(ctx, task, PhaseCommitting)
+			task.AddPipelineStep(PhaseCommitting, "running")
 
-			err = e.handleDeployFailure(ctx, task, deployResult.Output)
+			fixChanges = e.formatChanges(ctx, task, e.addLicenseHeaders(task, fixChanges))
+			if err := e.runHooks(ctx, task, &HookInput{Hook: HookPreCommit, Vars: vars, Changes: fixChanges}); err != nil {
+				task.CompletePipelineStep(PhaseCommitting, "failed", "", err.Error())
+				completeAttempt(&retryAttempt, "failed", reasonFor(err, ReasonConfig))
+				task.Attempts = append(task.Attempts, retryAttempt)
+				return fmt.Errorf("commit retry changes: %w", err)
+			}
+			task.RecordBranch(task.Branch)
+			commitCtx, cancelCommit := e.withPhaseTimeout(ctx, PhaseCommitting)
+			span = e.journalBegin(ctx, task, StepCommit, hashStepInput(fixChanges))
+			_, err = stepCommit(commitCtx, e.git, task.Branch, singleCommit(fixChanges, task.Issue.Title))
+			span.end(err)
+			cancelCommit()
 			if err != nil {
-				if errors.Is(err, ErrAwaitingApproval) {
-					return ErrAwaitingApproval
-				}
-				return fmt.Errorf("deploy failed during retry: %w", err)
+				err = timeoutCause(commitCtx, err)
+				task.CompletePipelineStep(PhaseCommitting, "failed", "", err.Error())
+				completeAttempt(&retryAttempt, "failed", reasonFor(err, ReasonGit))
+				task.Attempts = append(task.Attempts, retryAttempt)
+				return fmt.Errorf("commit retry changes: %w", err)
 			}
+			task.CompletePipelineStep(PhaseCommitting, "success", "retry changes committed", "")
+			e.syncDraftPR(ctx, task, &retryAttempt)
 
+			task.AddPipelineStep(PhaseApproval, "running")
+			task.CompletePipelineStep(PhaseApproval, "skipped", "auto approval step skipped", "")
+		}
+
+		if !route.redeploy {
+			task.AddPipelineStep(PhaseDeploying, "running")
+			task.CompletePipelineStep(PhaseDeploying, "skipped", "compile error fixed: testing without a redeploy", "")
+		} else {
 			if err := Transition(task, PhaseDeploying); err != nil {
-				return fmt.Errorf("transition to deploying after auto fix: %w", err)
+				completeAttempt(&retryAttempt, "failed", ReasonDeploy)
+				task.Attempts = append(task.Attempts, retryAttempt)
+				return fmt.Errorf("transition to deploying for retry: %w", err)
 			}
 			e.notifyPhase(ctx, task, PhaseDeploying)
 			task.AddPipelineStep(PhaseDeploying, "running")
 
-			deployResult, err = stepDeploy(ctx, e.deploy, vars)
+			policy := e.cfg.Workflow.RetryPolicy
+			if wait := backoffDelay(policy.Backoff, policy.MaxBackoff, retryCount); wait > 0 {
+				e.taskLog(task.ID, "info", fmt.Sprintf("Waiting %s before deploying retry #%d", wait, retryCount))
+				if err := sleepContext(ctx, wait); err != nil {
+					err = timeoutCause(ctx, fmt.Errorf("retry cancelled: %w", err))
+					task.CompletePipelineStep(PhaseDeploying, "failed", "", err.Error())
+					completeAttempt(&retryAttempt, "failed", reasonFor(err, ReasonDeploy))
+					task.Attempts = append(task.Attempts, retryAttempt)
+					return err
+				}
+			}
+
+			deployCtx, cancelDeploy := e.withPhaseTimeout(ctx, PhaseDeploying)
+			defer cancelDeploy()
+			span := e.journalBegin(ctx, task, StepDeploy, hashStepInput(vars))
+			deployResult, err := e.deployStep(deployCtx, task, vars)
+			span.end(outcome(err, deployResult != nil && deployResult.Status == "success"))
 			if err != nil {
+				err = timeoutCause(deployCtx, err)
 				task.CompletePipelineStep(PhaseDeploying, "failed", "", err.Error())
-				return fmt.Errorf("deploy retry after auto fix: %w", err)
+				completeAttempt(&retryAttempt, "failed", reasonFor(err, ReasonDeploy))
+				task.Attempts = append(task.Attempts, retryAttempt)
+				return fmt.Errorf("deploy retry: %w", err)
 			}
 			retryAttempt.Deploy = deployResult
+			e.storeArtifacts(ctx, task, &retryAttempt)
+
 			if deployResult.Status != "success" {
-				task.CompletePipelineStep(PhaseDeploying, "failed", deployResult.Output, "deploy failed after auto-apply")
-				return fmt.Errorf("deploy failed during retry after auto-apply")
+				task.CompletePipelineStep(PhaseDeploying, "failed", deployResult.Output, "deploy failed during retry")
+				if te := timedOut(deployCtx); te != nil {
+					completeAttempt(&retryAttempt, "failed", ReasonTimeout)
+					task.Attempts = append(task.Attempts, retryAttempt)
+					return fmt.Errorf("deploy retry: %w", te)
+				}
+				completeAttempt(&retryAttempt, "failed", ReasonDeploy)
+				task.Attempts = append(task.Attempts, retryAttempt)
+
+				err = e.handleDeployFailure(deployCtx, task, deployResult.Output)
+				if err != nil {
+					if errors.Is(err, ErrAwaitingApproval) {
+						return ErrAwaitingApproval
+					}
+					return fmt.Errorf("deploy failed during retry: %w", timeoutCause(deployCtx, err))
+				}
+
+				if err := Transition(task, PhaseDeploying); err != nil {
+					return fmt.Errorf("transition to deploying after auto fix: %w", err)
+				}
+				e.notifyPhase(ctx, task, PhaseDeploying)
+				task.AddPipelineStep(PhaseDeploying, "running")
+
+				span = e.journalBegin(ctx, task, StepDeploy, hashStepInput(vars))
+				deployResult, err = e.deployStep(deployCtx, task, vars)
+				span.end(outcome(err, deployResult != nil && deployResult.Status == "success"))
+				if err != nil {
+					err = timeoutCause(deployCtx, err)
+					task.CompletePipelineStep(PhaseDeploying, "failed", "", err.Error())
+					return fmt.Errorf("deploy retry after auto fix: %w", err)
+				}
+				retryAttempt.Deploy = deployResult
+				e.storeArtifacts(ctx, task, &retryAttempt)
+				if deployResult.Status != "success" {
+					task.CompletePipelineStep(PhaseDeploying, "failed", deployResult.Output, "deploy failed after auto-apply")
+					return timeoutCause(deployCtx, fmt.Errorf("deploy failed during retry after auto-apply"))
+				}
 			}
+			cancelDeploy()
+			task.CompletePipelineStep(PhaseDeploying, "success", deployResult.Output, "")
 		}
-		task.CompletePipelineStep(PhaseDeploying, "success", deployResult.Output, "")
 
 		if err := Transition(task, PhaseTesting); err != nil {
 			completeAttempt(&retryAttempt, "failed", ReasonTest)
@@ -151,14 +263,36 @@ func retryLoop(
 		e.notifyPhase(ctx, task, PhaseTesting)
 		task.AddPipelineStep(PhaseTesting, "running")
 
-		results, allPassed := stepTest(ctx, e.testRunners, e.testConfigs, retryAttempt.FilesChanged, vars)
+		testCtx, cancelTest := e.withPhaseTimeout(ctx, PhaseTesting)
+		span := e.journalBegin(ctx, task, StepTest, hashStepInput([]any{vars, retryAttempt.FilesChanged}))
+		results, allPassed := stepTest(testCtx, e.testRunners, e.testConfigs, retryAttempt.FilesChanged, vars, e.testRunOptions())
+		span.end(outcome(nil, allPassed))
+		cancelTest()
+		e.logFlakyTests(task.ID, results)
 		retryAttempt.Tests = results
+		e.storeArtifacts(ctx, task, &retryAttempt)
+		if te := timedOut(testCtx); te != nil && !allPassed {
+			task.CompletePipelineStep(PhaseTesting, "failed", collectTestOutput(results), te.Error())
+			completeAttempt(&retryAttempt, "failed", ReasonTimeout)
+			task.Attempts = append(task.Attempts, retryAttempt)
+			return fmt.Errorf("retest: %w", te)
+		}
+
+		delta := diffTestResults(testResults, results)
+		retryAttempt.TestDelta = delta
+		if !delta.IsEmpty() {
+			e.taskLog(task.ID, "info", fmt.Sprintf("Retry #%d test delta: %s", retryCount, delta))
+			e.notifyMessage(ctx, fmt.Sprintf("[rig] Task %s retry #%d: %s", task.ID, retryCount, delta))
+		}
 
+		if route.codegen {
+			e.rememberFix(ctx, task, signature, failureLogs, hintIDs, currentCode, fixChanges, allPassed)
+		}
 		if allPassed {
 			task.CompletePipelineStep(PhaseTesting, "success", "all tests passed", "")
 			completeAttempt(&retryAttempt, "passed", "")
 			task.Attempts = append(task.Attempts, retryAttempt)
-			log.Printf("[engine] retry %d succeeded for task %s", retryCount, task.ID)
+			e.log().Info("retry succeeded", logging.TaskKey, task.ID, "retry", retryCount)
 			return nil
 		}
 
@@ -166,6 +300,9 @@ func retryLoop(
 		completeAttempt(&retryAttempt, "failed", ReasonTest)
 		task.Attempts = append(task.Attempts, retryAttempt)
 		testResults = results
-		changes = fixChanges
+		if route.codegen {
+			changes = fixChanges
+		}
+		lastDelta = delta
 	}
 }
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"strings"
 	"testing"
+	"time"
 )
 
 func newRetryTestHarness(t *testing.T, maxRetry int, aiMock *mockAI, gitMock *mockGit, deployMock *mockDeploy, testRunner *mockTestRunner) (*Engine, *Task, Attempt, map[string]string, []TestResult, []AIFileChange) {
@@ -354,3 +355,140 @@ func TestRetryLoop_ContextCancellation(t *testing.T) {
 		t.Fatalf("expected attempts to remain 1, got %d", len(task.Attempts))
 	}
 }
+
+func TestRetryLoop_TestDeltaFedToAnalyzeFailure(t *testing.T) {
+	var prompts []string
+	aiMock := &mockAI{
+		failureFunc: func(ctx context.Context, logs string, currentCode map[string]string) ([]AIFileChange, error) {
+			prompts = append(prompts, logs)
+			return []AIFileChange{{Path: "main.go", Content: "package main", Action: "modify"}}, nil
+		},
+	}
+	gitMock := &mockGit{}
+	deployMock := &mockDeploy{deploySuccess: true}
+	testRunner := &mockTestRunner{results: []*TestResult{
+		{Name: "lint", Type: "command", Passed: false, Output: "FAIL"},
+		{Name: "unit-test", Type: "command", Passed: true, Output: "PASS"},
+	}}
+
+	engine, task, _, vars, initialResults, initialChanges := newRetryTestHarness(t, 2, aiMock, gitMock, deployMock, testRunner)
+	engine.testRunners = []TestRunnerIface{testRunner, testRunner}
+
+	err := retryLoop(context.Background(), engine, task, vars, initialResults, initialChanges, 2)
+	if err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+
+	if len(prompts) != 2 {
+		t.Fatalf("expected 2 AnalyzeFailure calls, got %d", len(prompts))
+	}
+	if strings.Contains(prompts[0], "Effect of previous fix") {
+		t.Errorf("first retry should have no delta, got: %s", prompts[0])
+	}
+	if !strings.Contains(prompts[1], "newly passing: unit-test") || !strings.Contains(prompts[1], "newly failing: lint") {
+		t.Errorf("second retry prompt missing delta: %s", prompts[1])
+	}
+
+	delta := task.Attempts[1].TestDelta
+	if delta == nil || len(delta.NewlyPassing) != 1 || len(delta.NewlyFailing) != 1 {
+		t.Fatalf("expected delta recorded on attempt, got %+v", delta)
+	}
+	notifier := engine.notifiers[0].(*mockNotifier)
+	found := false
+	for _, m := range notifier.messages {
+		if strings.Contains(m, "retry #1: newly passing: unit-test") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected delta notification, got %v", notifier.messages)
+	}
+}
+
+func TestDiffTestResults(t *testing.T) {
+	prev := []TestResult{
+		{Name: "a", Passed: false},
+		{Name: "b", Passed: true},
+		{Name: "c", Passed: false},
+	}
+	curr := []TestResult{
+		{Name: "a", Passed: true},
+		{Name: "b", Passed: false},
+		{Name: "c", Passed: false},
+		{Name: "d", Passed: false},
+	}
+
+	delta := diffTestResults(prev, curr)
+	if strings.Join(delta.NewlyPassing, ",") != "a" {
+		t.Errorf("newly passing = %v", delta.NewlyPassing)
+	}
+	if strings.Join(delta.NewlyFailing, ",") != "b,d" {
+		t.Errorf("newly failing = %v", delta.NewlyFailing)
+	}
+	if strings.Join(delta.StillFailing, ",") != "c" {
+		t.Errorf("still failing = %v", delta.StillFailing)
+	}
+	if got := diffTestResults(curr[:1], curr[:1]); !got.IsEmpty() {
+		t.Errorf("expected empty delta, got %+v", got)
+	}
+}
+
+func TestFormatFailureLogs_OnlyFailingCases(t *testing.T) {
+	results := []TestResult{{
+		Name:   "unit",
+		Passed: false,
+		Output: strings.Repeat("noisy log line\n", 1000),
+		Cases: []TestCase{
+			{Name: "TestOK", Suite: "pkg", Status: "pass"},
+			{Name: "TestBroken", Suite: "pkg", Status: "fail", Message: "want 2, got 3", Duration: time.Second},
+		},
+	}}
+
+	logs := formatFailureLogs(results, nil)
+	if strings.Contains(logs, "noisy log line") || strings.Contains(logs, "TestOK") {
+		t.Errorf("expected raw output and passing cases to be omitted, got %q", logs)
+	}
+	if !strings.Contains(logs, "--- FAIL: pkg/TestBroken (1s)\nwant 2, got 3") {
+		t.Errorf("expected failing case details, got %q", logs)
+	}
+}
+
+// switchingAI is a mockAI that records which model each retry ran on.
+type switchingAI struct {
+	mockAI
+	model string
+	calls *[]string
+}
+
+func (s *switchingAI) WithModel(model string) AIAdapter {
+	return &switchingAI{mockAI: s.mockAI, model: model, calls: s.calls}
+}
+
+func (s *switchingAI) AnalyzeFailure(ctx context.Context, logs string, currentCode map[string]string) ([]AIFileChange, error) {
+	*s.calls = append(*s.calls, s.model)
+	return s.mockAI.AnalyzeFailure(ctx, logs, currentCode)
+}
+
+func TestRetryLoop_UsesRetryModel(t *testing.T) {
+	var calls []string
+	aiMock := &switchingAI{model: "big-model", calls: &calls}
+	testRunner := &mockTestRunner{results: []*TestResult{{Name: "unit-test", Type: "command", Passed: true, Output: "PASS"}}}
+
+	cfg := testConfig()
+	cfg.AI.Model = "big-model"
+	cfg.AI.RetryModel = "small-model"
+	engine := NewEngine(cfg, &mockGit{}, aiMock, &mockDeploy{deploySuccess: true},
+		[]TestRunnerIface{testRunner}, nil, tempStatePath(t))
+	task := &Task{ID: "test-task", Issue: testIssue(), Branch: "rig/issue-42", Status: PhaseTesting}
+
+	failed := []TestResult{{Name: "unit-test", Type: "command", Passed: false, Output: "FAIL"}}
+	if err := retryLoop(context.Background(), engine, task, nil, failed, nil, 3); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+	if len(calls) != 1 || calls[0] != "small-model" {
+		t.Fatalf("expected retry on small-model, got %v", calls)
+	}
+	if got := task.Attempts[len(task.Attempts)-1].Model; got != "small-model" {
+		t.Errorf("expected attempt to record small-model, got %q", got)
+	}
+}
@@ -0,0 +1,86 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// backoffDelay is the wait before the nth re-run or retry: base doubled
+// n-1 times, capped at limit when limit is set.
+func backoffDelay(base, limit time.Duration, n int) time.Duration {
+	d := base
+	for i := 1; i < n && (limit <= 0 || d < limit); i++ {
+		if d > d*2 {
+			break // overflow
+		}
+		d *= 2
+	}
+	if limit > 0 {
+		d = min(d, limit)
+	}
+	return d
+}
+
+// sleepContext waits d, or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// rerunFailedTests re-runs the failing results in place, unchanged, up to
+// opts.reruns times with the backoff before each round, so a flaky test
+// does not cost an AI retry. A result that passes on a re-run replaces the
+// failure and is marked flaky; one that keeps failing keeps its last
+// failure.
+func rerunFailedTests(ctx context.Context, results []TestResult, opts testRunOptions, run func(i int) TestResult) {
+	for n := 1; n <= opts.reruns; n++ {
+		var failed []int
+		for i, r := range results {
+			if !r.Passed {
+				failed = append(failed, i)
+			}
+		}
+		if len(failed) == 0 {
+			return
+		}
+		if sleepContext(ctx, backoffDelay(opts.backoff, opts.maxBackoff, n)) != nil {
+			return
+		}
+		for _, i := range failed {
+			r := run(i)
+			r.Reruns = n
+			r.Flaky = r.Passed
+			results[i] = r
+		}
+	}
+}
+
+// flakyTests names the results that only passed on a re-run.
+func flakyTests(results []TestResult) []string {
+	var names []string
+	for _, r := range results {
+		if r.Flaky {
+			names = append(names, r.Name)
+		}
+	}
+	return names
+}
+
+// logFlakyTests warns about the tests of results that only passed on a
+// re-run.
+func (e *Engine) logFlakyTests(taskID string, results []TestResult) {
+	if names := flakyTests(results); len(names) > 0 {
+		e.taskLog(taskID, "warn", fmt.Sprintf("Flaky test(s) passed on a re-run: %s", strings.Join(names, ", ")))
+	}
+}
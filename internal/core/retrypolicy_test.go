@@ -0,0 +1,84 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		base, limit time.Duration
+		n           int
+		want        time.Duration
+	}{
+		{0, 0, 3, 0},
+		{time.Second, 0, 1, time.Second},
+		{time.Second, 0, 4, 8 * time.Second},
+		{time.Second, 5 * time.Second, 4, 5 * time.Second},
+		{10 * time.Second, 5 * time.Second, 1, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := backoffDelay(tt.base, tt.limit, tt.n); got != tt.want {
+			t.Errorf("backoffDelay(%s, %s, %d) = %s, want %s", tt.base, tt.limit, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestStepTest_RerunsFailingTests(t *testing.T) {
+	flaky := &mockTestRunner{results: []*TestResult{
+		{Name: "flaky", Passed: false, Output: "connection reset"},
+		{Name: "flaky", Passed: true},
+	}}
+	broken := &mockTestRunner{results: []*TestResult{
+		{Name: "broken", Passed: false},
+		{Name: "broken", Passed: false},
+		{Name: "broken", Passed: false},
+	}}
+	stable := &mockTestRunner{results: []*TestResult{{Name: "stable", Passed: true}}}
+
+	results, passed := stepTest(context.Background(), []TestRunnerIface{flaky, broken, stable}, nil, nil, nil, testRunOptions{reruns: 2})
+	if passed {
+		t.Fatal("expected the broken test to fail")
+	}
+	if r := results[0]; !r.Passed || !r.Flaky || r.Reruns != 1 {
+		t.Errorf("flaky result = %+v, want passed and flaky after 1 re-run", r)
+	}
+	if r := results[1]; r.Passed || r.Flaky || r.Reruns != 2 || broken.callIdx != 3 {
+		t.Errorf("broken result = %+v after %d runs, want failed after 2 re-runs", r, broken.callIdx)
+	}
+	if r := results[2]; r.Reruns != 0 || stable.callIdx != 1 {
+		t.Errorf("passing test should not be re-run, got %+v after %d runs", r, stable.callIdx)
+	}
+}
+
+func TestExecute_FlakyTestSkipsRetry(t *testing.T) {
+	cfg := testConfig()
+	cfg.Workflow.RetryPolicy.FlakyReruns = 1
+	analyzed := false
+	ai := &mockAI{failureFunc: func(ctx context.Context, logs string, currentCode map[string]string) ([]AIFileChange, error) {
+		analyzed = true
+		return nil, nil
+	}}
+	runner := &mockTestRunner{results: []*TestResult{
+		{Name: "e2e", Type: "command", Passed: false, Output: "timeout"},
+		{Name: "e2e", Type: "command", Passed: true},
+	}}
+	statePath := tempStatePath(t)
+	e := NewEngine(cfg, &mockGit{}, ai, &mockDeploy{deploySuccess: true}, []TestRunnerIface{runner}, nil, statePath)
+
+	if err := e.Execute(context.Background(), testIssue()); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if analyzed {
+		t.Error("a flaky test should not be sent to failure analysis")
+	}
+	state, _ := LoadState(statePath)
+	task := state.Tasks[0]
+	if task.Status != PhaseCompleted || len(task.Attempts) != 1 {
+		t.Fatalf("expected one completed attempt, got %s with %d attempts", task.Status, len(task.Attempts))
+	}
+	if r := task.Attempts[0].Tests[0]; !r.Flaky || r.Reruns != 1 {
+		t.Errorf("test result = %+v, want flaky", r)
+	}
+}
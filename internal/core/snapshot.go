@@ -0,0 +1,71 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DeploySnapshot records the last deploy of a project whose task
+// completed: the commit it deployed, the variables its commands ran with
+// and the artifacts the deploy adapter reported. Rolling back restores it.
+type DeploySnapshot struct {
+	Project     string            `json:"project"`
+	Environment string            `json:"environment,omitempty"`
+	TaskID      string            `json:"task_id"`
+	Branch      string            `json:"branch,omitempty"`
+	CommitSHA   string            `json:"commit_sha,omitempty"`
+	Vars        map[string]string `json:"vars,omitempty"`
+	Artifacts   map[string]string `json:"artifacts,omitempty"`
+	DeployedAt  time.Time         `json:"deployed_at"`
+}
+
+// SnapshotRollbacker is implemented by deploy adapters that can restore a
+// recorded snapshot instead of running their fixed rollback.
+type SnapshotRollbacker interface {
+	RollbackTo(ctx context.Context, snap *DeploySnapshot) error
+}
+
+// DeploySnapshot returns the last successful deploy recorded under key, a
+// project's repo or "<repo>@<environment>", or nil.
+func (s *State) DeploySnapshot(key string) *DeploySnapshot {
+	return s.Snapshots[key]
+}
+
+// recordDeploySnapshot makes the last successful deploy of task the
+// snapshot of its project. Tasks that did not deploy leave it alone.
+func (e *Engine) recordDeploySnapshot(state *State, task *Task) {
+	for i := len(task.Attempts) - 1; i >= 0; i-- {
+		d := task.Attempts[i].Deploy
+		if d == nil || d.Status != "success" {
+			continue
+		}
+		if state.Snapshots == nil {
+			state.Snapshots = make(map[string]*DeploySnapshot)
+		}
+		deployedAt := task.Attempts[i].StartedAt
+		if at := task.Attempts[i].CompletedAt; at != nil {
+			deployedAt = *at
+		}
+		state.Snapshots[e.snapshotKey(task)] = &DeploySnapshot{
+			Project:     e.cfg.Source.Repo,
+			Environment: task.Environment,
+			TaskID:      task.ID,
+			Branch:      task.Branch,
+			CommitSHA:   d.Vars["COMMIT_SHA"],
+			Vars:        d.Vars,
+			Artifacts:   d.Artifacts,
+			DeployedAt:  deployedAt,
+		}
+		e.taskLog(task.ID, "info", fmt.Sprintf("Recorded deploy snapshot of %s", e.snapshotKey(task)))
+		return
+	}
+}
+
+// shortSHA abbreviates a commit SHA for messages.
+func shortSHA(sha string) string {
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+	return sha
+}
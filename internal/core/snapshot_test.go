@@ -0,0 +1,75 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// snapshotDeploy is a deploy adapter that reports an artifact and restores
+// snapshots.
+type snapshotDeploy struct {
+	mockDeploy
+	restored *DeploySnapshot
+}
+
+func (m *snapshotDeploy) Deploy(ctx context.Context, vars map[string]string) (*AdapterDeployResult, error) {
+	result, err := m.mockDeploy.Deploy(ctx, vars)
+	if result != nil {
+		result.Artifacts = map[string]string{"image": "app:" + vars["ISSUE_ID"]}
+	}
+	return result, err
+}
+
+func (m *snapshotDeploy) RollbackTo(ctx context.Context, snap *DeploySnapshot) error {
+	m.restored = snap
+	return nil
+}
+
+func TestEngine_RollbackToDeploySnapshot(t *testing.T) {
+	cfg := testConfig()
+	cfg.AI.MaxRetry = 1
+	cfg.Deploy.Rollback.Enabled = true
+	statePath := tempStatePath(t)
+	deployMock := &snapshotDeploy{mockDeploy: mockDeploy{deploySuccess: true}}
+
+	pass := &mockTestRunner{results: []*TestResult{{Name: "unit-test", Passed: true, Duration: time.Second}}}
+	engine := NewEngine(cfg, &mockGit{}, &mockAI{}, deployMock, []TestRunnerIface{pass}, nil, statePath)
+	if err := engine.Execute(context.Background(), testIssue()); err != nil {
+		t.Fatalf("first task: %v", err)
+	}
+	state, err := LoadState(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap := state.DeploySnapshot("test/repo")
+	if snap == nil || snap.TaskID != state.Tasks[0].ID || snap.Artifacts["image"] != "app:42" || snap.Vars["ISSUE_ID"] != "42" {
+		t.Fatalf("snapshot = %+v, want the deploy of the completed task", snap)
+	}
+
+	// A failing task of another issue restores the first task's deploy and
+	// does not replace the snapshot.
+	issue := testIssue()
+	issue.ID = "43"
+	fail := &mockTestRunner{results: []*TestResult{
+		{Name: "unit-test", Passed: false, Output: "FAIL", Duration: time.Second},
+		{Name: "unit-test", Passed: false, Output: "FAIL", Duration: time.Second},
+	}}
+	engine = NewEngine(cfg, &mockGit{}, &mockAI{}, deployMock, []TestRunnerIface{fail}, nil, statePath)
+	if err := engine.Execute(context.Background(), issue); err == nil {
+		t.Fatal("expected the second task to fail")
+	}
+	if deployMock.restored == nil || deployMock.restored.Artifacts["image"] != "app:42" {
+		t.Fatalf("restored %+v, want the snapshot of issue 42", deployMock.restored)
+	}
+	if deployMock.rollbackCalls != 0 {
+		t.Errorf("fixed rollback ran %d times despite a snapshot", deployMock.rollbackCalls)
+	}
+	state, err = LoadState(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := state.DeploySnapshot("test/repo"); got == nil || got.Artifacts["image"] != "app:42" {
+		t.Errorf("snapshot after failed task = %+v, want unchanged", got)
+	}
+}
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -26,12 +27,15 @@ const (
 	PhaseFailed           TaskPhase = "failed"
 	PhaseRollback         TaskPhase = "rollback"
 	PhaseAwaitingApproval TaskPhase = "awaiting_approval"
+	PhaseDryRun           TaskPhase = "dry_run" // planned and generated by a dry run, for review
+	PhaseEpic             TaskPhase = "epic"    // split into child tasks, which are running
 )
 
 // strictlyTerminalPhases are phases from which no transition is allowed.
 var strictlyTerminalPhases = map[TaskPhase]bool{
 	PhaseCompleted: true,
 	PhaseRollback:  true,
+	PhaseDryRun:    true,
 }
 
 // inactivePhases are phases where the task is no longer "in flight" for
@@ -43,21 +47,24 @@ var inactivePhases = map[TaskPhase]bool{
 	PhaseFailed:           true,
 	PhaseRollback:         true,
 	PhaseAwaitingApproval: true,
+	PhaseDryRun:           true,
 }
 
 // validTransitions defines the allowed from→to state transitions.
 var validTransitions = map[TaskPhase]map[TaskPhase]bool{
 	PhaseQueued:           {PhasePlanning: true, PhaseFailed: true},
-	PhasePlanning:         {PhaseCoding: true, PhaseFailed: true},
-	PhaseCoding:           {PhaseCommitting: true, PhaseFailed: true},
-	PhaseCommitting:       {PhaseApproval: true, PhaseDeploying: true, PhaseReporting: true, PhaseFailed: true},
+	PhasePlanning:         {PhaseCoding: true, PhaseAwaitingApproval: true, PhaseEpic: true, PhaseFailed: true},
+	PhaseCoding:           {PhaseCommitting: true, PhaseDryRun: true, PhaseFailed: true},
+	PhaseCommitting:       {PhaseApproval: true, PhaseAwaitingApproval: true, PhaseDeploying: true, PhaseTesting: true, PhaseReporting: true, PhaseFailed: true},
 	PhaseApproval:         {PhaseDeploying: true, PhaseFailed: true},
 	PhaseDeploying:        {PhaseTesting: true, PhaseCoding: true, PhaseAwaitingApproval: true, PhaseFailed: true},
 	PhaseTesting:          {PhaseReporting: true, PhaseCoding: true, PhaseDeploying: true, PhaseAwaitingApproval: true, PhaseFailed: true},
 	PhaseReporting:        {PhaseCompleted: true, PhaseFailed: true},
 	PhaseFailed:           {PhaseRollback: true},
 	PhaseAwaitingApproval: {PhaseCoding: true, PhaseDeploying: true, PhaseFailed: true},
-	// PhaseCompleted and PhaseRollback have no outgoing transitions (terminal).
+	PhaseEpic:             {PhaseCompleted: true, PhaseFailed: true},
+	// PhaseCompleted, PhaseRollback and PhaseDryRun have no outgoing
+	// transitions (terminal).
 }
 
 // FailReason represents why a task failed.
@@ -71,6 +78,8 @@ const (
 	ReasonDeploy   FailReason = "deploy_error"
 	ReasonTest     FailReason = "test_error"
 	ReasonInfra    FailReason = "infra_error"
+	ReasonTimeout  FailReason = "timeout"
+	ReasonShutdown FailReason = "shutdown" // a shutdown or crash interrupted it
 	ReasonUnknown  FailReason = "unknown"
 )
 
@@ -78,20 +87,48 @@ const (
 type State struct {
 	Version string `json:"version"`
 	Tasks   []Task `json:"tasks"`
+	// Snapshots holds the last successful deploy of each project, keyed by
+	// source repo and, with environments, "<repo>@<environment>", for
+	// rollback.
+	Snapshots map[string]*DeploySnapshot `json:"deploy_snapshots,omitempty"`
+
+	// base is what the state file held for the tasks and snapshots when
+	// loadTrackedState loaded the state, for mergeState.
+	base *stateBase
+}
+
+// stateBase records the JSON fields of each task and the JSON of each
+// deploy snapshot of a state as loaded or last merged.
+type stateBase struct {
+	tasks     map[string]map[string]json.RawMessage
+	snapshots map[string]json.RawMessage
 }
 
 // Task represents a single issue being worked on by rig.
 type Task struct {
-	ID          string         `json:"id"`
-	Issue       Issue          `json:"issue"`
-	Branch      string         `json:"branch"`
-	Status      TaskPhase      `json:"status"`
-	PR          *PullRequest   `json:"pr,omitempty"`
-	Attempts    []Attempt      `json:"attempts"`
-	Proposals   []Proposal     `json:"proposals,omitempty"`
-	Pipeline    []PipelineStep `json:"pipeline,omitempty"`
-	CreatedAt   time.Time      `json:"created_at"`
-	CompletedAt *time.Time     `json:"completed_at,omitempty"`
+	ID          string              `json:"id"`
+	Issue       Issue               `json:"issue"`
+	Branch      string              `json:"branch"`
+	Branches    []string            `json:"branches,omitempty"` // branches rig created, for cleanup
+	Status      TaskPhase           `json:"status"`
+	Environment string              `json:"environment,omitempty"` // deploy environment
+	Settings    *TaskSettings       `json:"settings,omitempty"`    // what workflow.labels changed
+	PR          *PullRequest        `json:"pr,omitempty"`
+	PRLabels    []string            `json:"pr_labels,omitempty"`   // labels workflow.hooks asked for
+	Duplicates  []DuplicateIssue    `json:"duplicates,omitempty"`  // issues workflow.dedup found to repeat Issue
+	Children    []string            `json:"children,omitempty"`    // child tasks of an epic, in stack order
+	Part        *EpicPart           `json:"part,omitempty"`        // place of a child task in its epic
+	BaseBranch  string              `json:"base_branch,omitempty"` // branch the PR targets instead of source.base_branch
+	Attempts    []Attempt           `json:"attempts"`
+	Proposals   []Proposal          `json:"proposals,omitempty"`
+	Pipeline    []PipelineStep      `json:"pipeline,omitempty"`
+	Steps       []StepRecord        `json:"steps,omitempty"`
+	AIUsage     *AIUsage            `json:"ai_usage,omitempty"`
+	Explanation *FailureExplanation `json:"explanation,omitempty"` // latest AI diagnosis of a failure
+	Checkpoint  *Checkpoint         `json:"checkpoint,omitempty"`  // set while a shutdown has it interrupted
+	DryRun      *DryRunOutput       `json:"dry_run,omitempty"`     // plan and diff of a dry run
+	CreatedAt   time.Time           `json:"created_at"`
+	CompletedAt *time.Time          `json:"completed_at,omitempty"`
 }
 
 // Proposal represents an AI-suggested change that requires user approval.
@@ -101,6 +138,7 @@ type Proposal struct {
 	Summary    string           `json:"summary"`
 	Reason     string           `json:"reason"`
 	Changes    []ProposedChange `json:"changes"`
+	Plan       string           `json:"plan,omitempty"` // plan text of a plan_review, as edited
 	Status     ProposalStatus   `json:"status"`
 	CreatedAt  time.Time        `json:"created_at"`
 	ReviewedAt *time.Time       `json:"reviewed_at,omitempty"`
@@ -114,6 +152,7 @@ const (
 	ProposalTestFix        ProposalType = "test_fix"
 	ProposalInfraFix       ProposalType = "infra_fix"
 	ProposalDeployApproval ProposalType = "deploy_approval"
+	ProposalPlanReview     ProposalType = "plan_review"
 )
 
 // ProposalStatus tracks the lifecycle of a proposal.
@@ -152,22 +191,40 @@ type Issue struct {
 	Title    string `json:"title"`
 	Body     string `json:"body"`
 	URL      string `json:"url"`
+	// Labels are the issue's labels when it was triggered; a
+	// deploy:<name> label picks the environment.
+	Labels []string `json:"labels,omitempty"`
+	// IdempotencyKey identifies the API request or webhook delivery that
+	// triggered the task, so a retry of it returns the task instead of
+	// starting another.
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
 }
 
 // PullRequest holds PR metadata once one is created.
 type PullRequest struct {
-	ID  string `json:"id"`
-	URL string `json:"url"`
+	ID       string     `json:"id"`
+	URL      string     `json:"url"`
+	Draft    bool       `json:"draft,omitempty"`
+	Merged   bool       `json:"merged,omitempty"`
+	MergedAt *time.Time `json:"merged_at,omitempty"`
 }
 
 // Attempt records a single try at completing a task.
 type Attempt struct {
 	Number       int           `json:"number"`
 	Plan         string        `json:"plan,omitempty"`
+	Rerun        string        `json:"rerun,omitempty"`    // deploy|test for a manual rerun of a finished task
+	Provider     string        `json:"provider,omitempty"` // AI provider that produced the changes
+	Model        string        `json:"model,omitempty"`    // AI model that produced the changes
 	FilesChanged []string      `json:"files_changed,omitempty"`
+	Subtasks     []string      `json:"subtasks,omitempty"`  // sub-tasks the plan was split into (workflow.decompose)
+	FixHints     []int64       `json:"fix_hints,omitempty"` // past fixes given to failure analysis as hints (ai.fix_memory)
+	Triage       FailureClass  `json:"triage,omitempty"`    // class of the failure a retry responded to (workflow.triage)
 	Deploy       *DeployResult `json:"deploy,omitempty"`
 	Tests        []TestResult  `json:"tests"`
-	Status       string        `json:"status"` // running|passed|failed
+	TestDelta    *TestDelta    `json:"test_delta,omitempty"`
+	Artifacts    []ArtifactRef `json:"artifacts,omitempty"` // full outputs and test files kept in the artifact store
+	Status       string        `json:"status"`              // running|passed|failed
 	FailReason   FailReason    `json:"fail_reason,omitempty"`
 	StartedAt    time.Time     `json:"started_at"`
 	CompletedAt  *time.Time    `json:"completed_at,omitempty"`
@@ -178,17 +235,53 @@ type DeployResult struct {
 	Status   string        `json:"status"` // success|failed
 	Duration time.Duration `json:"duration"`
 	Output   string        `json:"output,omitempty"`
+	// OutputArtifact names the attempt's artifact holding the full output,
+	// of which Output then keeps only the end.
+	OutputArtifact string `json:"output_artifact,omitempty"`
+	// Vars are the variables the deploy ran with and Artifacts what the
+	// adapter reported deploying, such as image tags.
+	Vars      map[string]string `json:"vars,omitempty"`
+	Artifacts map[string]string `json:"artifacts,omitempty"`
 }
 
 // TestResult captures the outcome of a single test execution.
 type TestResult struct {
+	Name      string         `json:"name"`
+	Type      string         `json:"type"` // command|http|browser|ai-verify
+	Passed    bool           `json:"passed"`
+	Output    string         `json:"output,omitempty"`
+	Duration  time.Duration  `json:"duration"`
+	Artifacts []TestArtifact `json:"artifacts,omitempty"`
+	Cases     []TestCase     `json:"cases,omitempty"`
+	// Reruns counts the bare re-runs after the runner first failed; Flaky
+	// is set when one of them passed (workflow.retry_policy).
+	Reruns int  `json:"reruns,omitempty"`
+	Flaky  bool `json:"flaky,omitempty"`
+	// OutputArtifact names the attempt's artifact holding the full output,
+	// of which Output then keeps only the end.
+	OutputArtifact string `json:"output_artifact,omitempty"`
+}
+
+// TestCase is a single test parsed from structured runner output
+// (go test -json or JUnit XML).
+type TestCase struct {
 	Name     string        `json:"name"`
-	Type     string        `json:"type"` // command|ai-verify
-	Passed   bool          `json:"passed"`
-	Output   string        `json:"output,omitempty"`
+	Suite    string        `json:"suite,omitempty"`
+	Status   string        `json:"status"` // pass|fail|skip
+	Message  string        `json:"message,omitempty"`
 	Duration time.Duration `json:"duration"`
 }
 
+// TestArtifact is a file produced by a test run, such as a browser
+// screenshot or console log captured on failure.
+type TestArtifact struct {
+	Kind    string `json:"kind"` // screenshot|console|trace|coverage|file
+	Path    string `json:"path"`
+	Content string `json:"content,omitempty"` // text artifacts only, truncated
+	// Artifact names the attempt's artifact the file was stored as.
+	Artifact string `json:"artifact,omitempty"`
+}
+
 // ErrInvalidTransition is returned when a state transition is not allowed.
 var ErrInvalidTransition = errors.New("invalid state transition")
 
@@ -222,29 +315,52 @@ func Transition(task *Task, to TaskPhase) error {
 
 // LoadState reads state from the given JSON file path.
 // If the file does not exist, it returns a fresh State with version "1.0".
+// It waits for another process saving the file; see WithState.
 // NOTE: For read-modify-write cycles, use WithState instead to prevent races.
 func LoadState(path string) (*State, error) {
 	stateMu.Lock()
 	defer stateMu.Unlock()
 
+	lock, err := lockStateFile(path, false)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+
 	return loadStateUnsafe(path)
 }
 
 // SaveState writes state to the given path using atomic write (tmp + rename).
+// It waits for other processes reading or saving the file.
 func SaveState(s *State, path string) error {
 	stateMu.Lock()
 	defer stateMu.Unlock()
 
+	lock, err := lockStateFile(path, true)
+	if err != nil {
+		return err
+	}
+	defer lock.unlock()
+
 	return saveStateUnsafe(s, path)
 }
 
 // WithState executes fn while holding the state lock, ensuring atomic
 // read-modify-write operations. This prevents race conditions when
-// multiple goroutines access state.json concurrently.
+// multiple goroutines access state.json concurrently, and, through the
+// lock file next to it, when several processes do (rig serve and rig exec).
+// It returns an error wrapping ErrStateLocked if another process holds the
+// file for longer than the lock timeout.
 func WithState(path string, fn func(s *State) error) error {
 	stateMu.Lock()
 	defer stateMu.Unlock()
 
+	lock, err := lockStateFile(path, true)
+	if err != nil {
+		return err
+	}
+	defer lock.unlock()
+
 	s, err := loadStateUnsafe(path)
 	if err != nil {
 		return err
@@ -257,6 +373,149 @@ func WithState(path string, fn func(s *State) error) error {
 	return saveStateUnsafe(s, path)
 }
 
+// loadTrackedState is LoadState for a caller that saves the state with
+// mergeState: it remembers what the file held, so mergeState writes back
+// only what the caller changed.
+func loadTrackedState(path string) (*State, error) {
+	s, err := LoadState(path)
+	if err != nil {
+		return nil, err
+	}
+	s.track()
+	return s, nil
+}
+
+// mergeState saves the changes made to s since loadTrackedState loaded it
+// or mergeState last saved it. Under the state lock it reloads the file and
+// merges them in by task ID, field by field, so that the changes another
+// engine, process or the web UI saved meanwhile are kept. A state that is
+// not tracked has all its tasks merged in.
+func mergeState(s *State, path string) error {
+	return WithState(path, func(disk *State) error {
+		if err := s.mergeInto(disk); err != nil {
+			return err
+		}
+		s.track()
+		return nil
+	})
+}
+
+// track records the tasks and snapshots of s as its base.
+func (s *State) track() {
+	base := &stateBase{tasks: make(map[string]map[string]json.RawMessage), snapshots: make(map[string]json.RawMessage)}
+	for i := range s.Tasks {
+		if fields, err := taskFields(&s.Tasks[i]); err == nil {
+			base.tasks[s.Tasks[i].ID] = fields
+		}
+	}
+	for key, snap := range s.Snapshots {
+		if data, err := json.Marshal(snap); err == nil {
+			base.snapshots[key] = data
+		}
+	}
+	s.base = base
+}
+
+// trackTask records task, just created in the state file, as a task of
+// the base of s.
+func (s *State) trackTask(task *Task) {
+	if s.base == nil {
+		return
+	}
+	if fields, err := taskFields(task); err == nil {
+		s.base.tasks[task.ID] = fields
+	}
+}
+
+// mergeInto applies the changes made to the tasks and snapshots of s since
+// its base to disk. A task field changed both here and in disk takes the
+// value of s.
+func (s *State) mergeInto(disk *State) error {
+	if disk.Version == "" {
+		disk.Version = s.Version
+	}
+	for i := range s.Tasks {
+		ours, err := taskFields(&s.Tasks[i])
+		if err != nil {
+			return err
+		}
+		var base map[string]json.RawMessage
+		if s.base != nil {
+			base = s.base.tasks[s.Tasks[i].ID]
+			if sameFields(ours, base) {
+				continue
+			}
+		}
+		d := disk.GetTaskByID(s.Tasks[i].ID)
+		if d == nil {
+			disk.Tasks = append(disk.Tasks, s.Tasks[i])
+			continue
+		}
+		theirs, err := taskFields(d)
+		if err != nil {
+			return err
+		}
+		for k, v := range ours {
+			if !bytes.Equal(v, base[k]) {
+				theirs[k] = v
+			}
+		}
+		for k := range base {
+			if _, ok := ours[k]; !ok {
+				delete(theirs, k)
+			}
+		}
+		data, err := json.Marshal(theirs)
+		if err != nil {
+			return fmt.Errorf("marshal task %s: %w", d.ID, err)
+		}
+		*d = Task{}
+		if err := json.Unmarshal(data, d); err != nil {
+			return fmt.Errorf("unmarshal task %s: %w", d.ID, err)
+		}
+	}
+	for key, snap := range s.Snapshots {
+		data, err := json.Marshal(snap)
+		if err != nil {
+			return fmt.Errorf("marshal snapshot %s: %w", key, err)
+		}
+		if s.base != nil && bytes.Equal(data, s.base.snapshots[key]) {
+			continue
+		}
+		if disk.Snapshots == nil {
+			disk.Snapshots = make(map[string]*DeploySnapshot)
+		}
+		disk.Snapshots[key] = snap
+	}
+	return nil
+}
+
+// taskFields returns the JSON of each field of t, by name.
+func taskFields(t *Task) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return nil, fmt.Errorf("marshal task %s: %w", t.ID, err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("unmarshal task %s: %w", t.ID, err)
+	}
+	return fields, nil
+}
+
+// sameFields reports whether a and b hold the same fields with the same JSON.
+func sameFields(a, b map[string]json.RawMessage) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || !bytes.Equal(v, w) {
+			return false
+		}
+	}
+	return true
+}
+
 func loadStateUnsafe(path string) (*State, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -314,6 +573,33 @@ func (s *State) CreateTask(issue Issue) *Task {
 	return &s.Tasks[len(s.Tasks)-1]
 }
 
+// addTask creates a task for issue in the state file at path, under the
+// state lock so that its ID is unique among the tasks of every process, and
+// adds it to s. The unstarted task created for issue before it was handed
+// to the engine is taken instead. It returns the task in s.
+func (s *State) addTask(path string, issue Issue) (*Task, error) {
+	var task Task
+	err := WithState(path, func(disk *State) error {
+		if t := disk.UnstartedTask(issue); t != nil {
+			task = *t
+			return nil
+		}
+		task = *disk.CreateTask(issue)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create task: %w", err)
+	}
+	if t := s.GetTaskByID(task.ID); t != nil {
+		*t = task
+		s.trackTask(t)
+		return t, nil
+	}
+	s.Tasks = append(s.Tasks, task)
+	s.trackTask(&task)
+	return &s.Tasks[len(s.Tasks)-1], nil
+}
+
 // GetTask finds a task by issue ID. Returns nil if not found.
 func (s *State) GetTask(issueID string) *Task {
 	for i := range s.Tasks {
@@ -324,6 +610,17 @@ func (s *State) GetTask(issueID string) *Task {
 	return nil
 }
 
+// RecordBranch registers a branch rig created for the task so that it can be
+// deleted if the task fails. Recording the same branch twice is a no-op.
+func (t *Task) RecordBranch(name string) {
+	for _, b := range t.Branches {
+		if b == name {
+			return
+		}
+	}
+	t.Branches = append(t.Branches, name)
+}
+
 // AddPipelineStep records a new pipeline step for the task.
 func (t *Task) AddPipelineStep(phase TaskPhase, status string) *PipelineStep {
 	step := PipelineStep{
@@ -375,6 +672,28 @@ func (t *Task) GetPendingProposal() *Proposal {
 	return nil
 }
 
+// GetProposal returns the proposal of the task with id, or nil.
+func (t *Task) GetProposal(id string) *Proposal {
+	for i := range t.Proposals {
+		if t.Proposals[i].ID == id {
+			return &t.Proposals[i]
+		}
+	}
+	return nil
+}
+
+// FindProposal returns the proposal with id and its task, or nils.
+// Proposal IDs are only unique within a task, so the newest task with one
+// wins.
+func (s *State) FindProposal(id string) (*Task, *Proposal) {
+	for i := len(s.Tasks) - 1; i >= 0; i-- {
+		if p := s.Tasks[i].GetProposal(id); p != nil {
+			return &s.Tasks[i], p
+		}
+	}
+	return nil, nil
+}
+
 // GetTaskByID finds a task by its task ID. Returns nil if not found.
 func (s *State) GetTaskByID(taskID string) *Task {
 	for i := range s.Tasks {
@@ -385,13 +704,47 @@ func (s *State) GetTaskByID(taskID string) *Task {
 	return nil
 }
 
+// GetTaskByIdempotencyKey returns the last task created for key, or nil.
+// A webhook delivery whose task failed creates another one when retried.
+func (s *State) GetTaskByIdempotencyKey(key string) *Task {
+	if key == "" {
+		return nil
+	}
+	for i := len(s.Tasks) - 1; i >= 0; i-- {
+		if s.Tasks[i].Issue.IdempotencyKey == key {
+			return &s.Tasks[i]
+		}
+	}
+	return nil
+}
+
+// UnstartedTask returns the task the web API or a webhook created for
+// issue, by its idempotency key, for an engine to run, or nil once an
+// engine has started it.
+func (s *State) UnstartedTask(issue Issue) *Task {
+	t := s.GetTaskByIdempotencyKey(issue.IdempotencyKey)
+	if t == nil || t.Issue.ID != issue.ID || t.Status != PhaseQueued || len(t.Pipeline) > 0 {
+		return nil
+	}
+	return t
+}
+
 // IsInFlight reports whether an issue already has a non-terminal task.
 // Used to prevent duplicate processing from repeated webhooks.
+// An issue attached to a task as its duplicate counts as that task's.
 func (s *State) IsInFlight(issueID string) bool {
 	for _, t := range s.Tasks {
-		if t.Issue.ID == issueID && !inactivePhases[t.Status] {
+		if inactivePhases[t.Status] {
+			continue
+		}
+		if t.Issue.ID == issueID {
 			return true
 		}
+		for _, d := range t.Duplicates {
+			if d.Attached && d.Issue.ID == issueID {
+				return true
+			}
+		}
 	}
 	return false
 }
@@ -2,6 +2,8 @@ package core
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -209,6 +211,201 @@ func TestLoadState(t *testing.T) {
 	})
 }
 
+func TestStateFileLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := SaveState(&State{Version: "1.0", Tasks: []Task{}}, path); err != nil {
+		t.Fatal(err)
+	}
+	timeout := stateLockTimeout
+	t.Cleanup(func() { stateLockTimeout = timeout })
+	stateLockTimeout = 50 * time.Millisecond
+
+	// A lock taken through another descriptor stands in for another process.
+	other, err := lockStateFile(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadState(path); !errors.Is(err, ErrStateLocked) {
+		t.Errorf("load while locked: %v, want ErrStateLocked", err)
+	}
+	if err := SaveState(&State{Version: "1.0"}, path); !errors.Is(err, ErrStateLocked) {
+		t.Errorf("save while locked: %v, want ErrStateLocked", err)
+	}
+
+	stateLockTimeout = 5 * time.Second
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		other.unlock()
+	}()
+	err = WithState(path, func(s *State) error {
+		s.CreateTask(Issue{ID: "1", Title: "after the lock"})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected WithState to wait for the lock, got %v", err)
+	}
+
+	// Readers share the lock.
+	reader, err := lockStateFile(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.unlock()
+	s, err := LoadState(path)
+	if err != nil || len(s.Tasks) != 1 {
+		t.Fatalf("load next to a reader: %v, %+v", err, s)
+	}
+}
+
+func TestStateFileLock_ReadsCreateNoLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if _, err := LoadState(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(stateLockPath(path)); !os.IsNotExist(err) {
+		t.Errorf("reading a missing state file left a lock file: %v", err)
+	}
+	if lock, err := lockStateFile("", true); lock != nil || err != nil {
+		t.Errorf("expected no lock for an empty path, got %v, %v", lock, err)
+	}
+}
+
+func TestMergeState_ConcurrentWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	err := SaveState(&State{Version: "1.0", Tasks: []Task{
+		{ID: "task-1", Status: PhaseCoding, Attempts: []Attempt{}},
+		{ID: "task-2", Status: PhaseCoding, Attempts: []Attempt{}},
+	}}, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Two writers hold snapshots of the same state and each saves its own
+	// changes many times over: neither may lose the other's.
+	const rounds = 20
+	write := func(id string, change func(s *State, t *Task, i int)) error {
+		s, err := loadTrackedState(path)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < rounds; i++ {
+			change(s, s.GetTaskByID(id), i)
+			if err := mergeState(s, path); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	errs := make(chan error, 2)
+	go func() {
+		errs <- write("task-1", func(s *State, t *Task, i int) {
+			t.Branch = fmt.Sprintf("rig/branch-%d", i)
+		})
+	}()
+	go func() {
+		errs <- write("task-2", func(s *State, t *Task, i int) {
+			t.Branch = fmt.Sprintf("rig/other-%d", i)
+			// Duplicates of task-1 come from another writer, as dedup does.
+			if i == 0 {
+				s.GetTaskByID("task-1").addDuplicate(DuplicateIssue{Issue: Issue{ID: "7"}})
+			}
+		})
+	}()
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := LoadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Tasks) != 2 {
+		t.Fatalf("tasks = %+v, want 2", s.Tasks)
+	}
+	one, two := s.GetTaskByID("task-1"), s.GetTaskByID("task-2")
+	if want := fmt.Sprintf("rig/branch-%d", rounds-1); one.Branch != want {
+		t.Errorf("task-1 branch = %q, want %q", one.Branch, want)
+	}
+	if len(one.Duplicates) != 1 {
+		t.Errorf("task-1 duplicates = %+v, want the one recorded by the other writer", one.Duplicates)
+	}
+	if want := fmt.Sprintf("rig/other-%d", rounds-1); two.Branch != want {
+		t.Errorf("task-2 branch = %q, want %q", two.Branch, want)
+	}
+}
+
+func TestAddTask_UniqueAcrossStaleStates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	a, err := loadTrackedState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := loadTrackedState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ta, err := a.addTask(path, Issue{ID: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tb, err := b.addTask(path, Issue{ID: "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ta.ID == tb.ID {
+		t.Fatalf("both tasks got ID %s", ta.ID)
+	}
+	ta.Status = PhasePlanning
+	if err := mergeState(a, path); err != nil {
+		t.Fatal(err)
+	}
+	if err := mergeState(b, path); err != nil {
+		t.Fatal(err)
+	}
+	s, err := LoadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Tasks) != 2 || s.GetTaskByID(ta.ID).Status != PhasePlanning {
+		t.Errorf("tasks = %+v, want both, %s planning", s.Tasks, ta.ID)
+	}
+}
+
+func TestAddTask_TakesUnstartedTask(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	issue := Issue{ID: "5", IdempotencyKey: "github:guid-5"}
+	var created string
+	err := WithState(path, func(s *State) error {
+		created = s.CreateTask(issue).ID
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := loadTrackedState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	task, err := s.addTask(path, issue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.ID != created || len(s.Tasks) != 1 {
+		t.Fatalf("addTask gave %s of %d tasks, want the unstarted %s", task.ID, len(s.Tasks), created)
+	}
+
+	// Once started, the next delivery of the key gets a task of its own.
+	task.AddPipelineStep(PhaseQueued, "running")
+	if err := mergeState(s, path); err != nil {
+		t.Fatal(err)
+	}
+	if again, err := s.addTask(path, issue); err != nil || again.ID == created {
+		t.Errorf("addTask after the start = %+v, %v; want a new task", again, err)
+	}
+}
+
 func TestIsInFlight(t *testing.T) {
 	s := &State{
 		Version: "1.0",
@@ -245,6 +442,23 @@ func TestIsInFlight(t *testing.T) {
 	}
 }
 
+func TestGetTaskByIdempotencyKey(t *testing.T) {
+	s := &State{Version: "1.0", Tasks: []Task{
+		{ID: "task-1", Issue: Issue{ID: "1"}},
+		{ID: "task-2", Issue: Issue{ID: "2", IdempotencyKey: "k"}},
+		{ID: "task-3", Issue: Issue{ID: "2", IdempotencyKey: "k"}},
+	}}
+	if got := s.GetTaskByIdempotencyKey("k"); got == nil || got.ID != "task-3" {
+		t.Errorf("GetTaskByIdempotencyKey(k) = %+v, want task-3", got)
+	}
+	if got := s.GetTaskByIdempotencyKey(""); got != nil {
+		t.Errorf("an empty key matched %s", got.ID)
+	}
+	if got := s.GetTaskByIdempotencyKey("other"); got != nil {
+		t.Errorf("an unknown key matched %s", got.ID)
+	}
+}
+
 func TestCreateTask(t *testing.T) {
 	s := &State{Version: "1.0", Tasks: []Task{}}
 
@@ -0,0 +1,94 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrStateLocked is returned when another process held the state file lock
+// for longer than stateLockTimeout.
+var ErrStateLocked = errors.New("state file is locked by another process")
+
+// stateLockTimeout is how long LoadState, SaveState and WithState wait for
+// another process, such as rig serve next to a manual rig exec, to release
+// the state file lock.
+var stateLockTimeout = 30 * time.Second
+
+// Backoff between attempts to take a held state file lock.
+const (
+	stateLockMinWait = 5 * time.Millisecond
+	stateLockMaxWait = 200 * time.Millisecond
+)
+
+// stateFileLock is a held cross-process lock of a state file. stateMu only
+// serializes the goroutines of one process; the lock file next to the state
+// file serializes processes sharing it.
+type stateFileLock struct {
+	f *os.File
+}
+
+// stateLockPath returns the lock file of the state file at path. The state
+// file itself is replaced on every save, so it cannot carry the lock.
+func stateLockPath(path string) string {
+	return path + ".lock"
+}
+
+// lockStateFile takes the lock of the state file at path, shared to read it
+// or exclusive to write it, retrying with backoff while another process
+// holds it. It returns no lock, and creates no lock file, when there is
+// nothing to serialize: for an empty path, or a read of a state file that
+// does not exist or was never saved with a lock.
+func lockStateFile(path string, exclusive bool) (*stateFileLock, error) {
+	if path == "" {
+		return nil, nil
+	}
+	var f *os.File
+	if exclusive {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, fmt.Errorf("create state dir: %w", err)
+		}
+		var err error
+		if f, err = os.OpenFile(stateLockPath(path), os.O_CREATE|os.O_RDWR, 0o644); err != nil {
+			return nil, fmt.Errorf("open state lock: %w", err)
+		}
+	} else {
+		if _, err := os.Stat(path); err != nil {
+			return nil, nil
+		}
+		var err error
+		if f, err = os.Open(stateLockPath(path)); err != nil {
+			return nil, nil
+		}
+	}
+
+	deadline := time.Now().Add(stateLockTimeout)
+	wait := stateLockMinWait
+	for {
+		ok, err := tryLockFile(f, exclusive)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("lock state file: %w", err)
+		}
+		if ok {
+			return &stateFileLock{f: f}, nil
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("%w: %s after %s", ErrStateLocked, path, stateLockTimeout)
+		}
+		time.Sleep(wait)
+		wait = min(wait*2, stateLockMaxWait)
+	}
+}
+
+// unlock releases the lock. Unlocking a nil lock is a no-op.
+func (l *stateFileLock) unlock() {
+	if l == nil {
+		return
+	}
+	unlockFile(l.f)
+	l.f.Close()
+}
@@ -0,0 +1,180 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// stepPhases maps each step to the phase it runs in.
+var stepPhases = map[StepName]TaskPhase{
+	StepPlan:   PhasePlanning,
+	StepCode:   PhaseCoding,
+	StepCommit: PhaseCommitting,
+	StepDeploy: PhaseDeploying,
+	StepTest:   PhaseTesting,
+	StepReport: PhaseReporting,
+}
+
+// StepRange returns the steps from..to in pipeline order.
+func StepRange(from, to StepName) ([]StepName, error) {
+	i, j := slices.Index(stepOrder, from), slices.Index(stepOrder, to)
+	if i < 0 || j < 0 {
+		return nil, fmt.Errorf("unknown step range %s..%s", from, to)
+	}
+	if i > j {
+		return nil, fmt.Errorf("step %s comes after %s", from, to)
+	}
+	return stepOrder[i : j+1], nil
+}
+
+// RunSteps runs the steps from..to of an existing task in order with
+// RunStep and returns their records, stopping at the first that fails.
+// The steps of the range run again even if they succeeded before, and the
+// records of the steps after it are dropped as stale. The outputs of the
+// steps before from come from the task's step records or, for a task run
+// by Execute, from its earlier run: the reviewed plan or the attempt's
+// plan summary, the changed files as the task's workspace holds them, the
+// branch, and the last deploy and tests. A finished task is reopened at
+// from, and a range after code records a new attempt.
+func (e *Engine) RunSteps(ctx context.Context, taskID string, from, to StepName) ([]StepRecord, error) {
+	steps, err := StepRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.prepareSteps(taskID, from); err != nil {
+		return nil, err
+	}
+
+	var records []StepRecord
+	for _, step := range steps {
+		rec, err := e.RunStep(ctx, taskID, step)
+		if rec != nil {
+			records = append(records, *rec)
+		}
+		if err != nil {
+			return records, err
+		}
+	}
+	return records, nil
+}
+
+// prepareSteps readies a task to run from step from: it drops the records
+// of from and the steps after it, fills in the missing records of the steps
+// before it from the task's earlier run, and rewinds the task's phase so
+// from can start.
+func (e *Engine) prepareSteps(taskID string, from StepName) error {
+	lock, state, task, err := e.lockTask(taskID)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+	if task.Status == PhaseAwaitingApproval {
+		return fmt.Errorf("task %s is awaiting approval; approve or reject it first", taskID)
+	}
+
+	start := slices.Index(stepOrder, from)
+	task.Steps = slices.DeleteFunc(task.Steps, func(r StepRecord) bool {
+		return slices.Index(stepOrder, r.Step) >= start
+	})
+	for _, step := range stepOrder[:start] {
+		if task.GetStepRecord(step) != nil {
+			continue
+		}
+		out := e.priorStepOutput(task, step)
+		if out == nil {
+			continue
+		}
+		now := time.Now().UTC()
+		task.putStepRecord(StepRecord{Step: step, Status: "success", Output: out, StartedAt: now, CompletedAt: &now})
+		e.taskLog(task.ID, "info", fmt.Sprintf("Reusing the %s step of an earlier run", step))
+	}
+	if _, err := e.stepInput(task, from); err != nil {
+		if errors.Is(err, ErrStepNotReady) {
+			return fmt.Errorf("%w; no earlier run of task %s to reuse it from", err, taskID)
+		}
+		return err
+	}
+
+	phase := stepPhases[from]
+	if task.Status != phase && !validTransitions[task.Status][phase] {
+		before := PhaseQueued
+		if start > 0 {
+			before = stepPhases[stepOrder[start-1]]
+		}
+		e.taskLog(task.ID, "info", fmt.Sprintf("Reopening %s task at step %s", task.Status, from))
+		task.Status = before
+		task.CompletedAt = nil
+	}
+	if start > slices.Index(stepOrder, StepCode) {
+		attempt := newAttempt(len(task.Attempts) + 1)
+		attempt.Plan = fmt.Sprintf("Steps from %s", from)
+		if a := lastAttempt(task); a != nil {
+			attempt.FilesChanged = slices.Clone(a.FilesChanged)
+		}
+		task.Attempts = append(task.Attempts, attempt)
+	}
+	return mergeState(state, e.statePath)
+}
+
+// priorStepOutput rebuilds the output of step from the earlier run of a
+// task that has no record of it, or returns nil.
+func (e *Engine) priorStepOutput(task *Task, step StepName) *StepOutput {
+	switch step {
+	case StepPlan:
+		for _, p := range slices.Backward(task.Proposals) {
+			if p.Type == ProposalPlanReview && p.Plan != "" {
+				return &StepOutput{Plan: ParsePlan(p.Plan)}
+			}
+		}
+		for _, a := range task.Attempts {
+			if a.Rerun == "" && a.Plan != "" {
+				return &StepOutput{Plan: &AIPlan{Summary: a.Plan}}
+			}
+		}
+	case StepCode:
+		wp, ok := e.git.(WorkspaceProvider)
+		a := lastAttempt(task)
+		if !ok || wp.GetWorkspace() == "" || a == nil || len(a.FilesChanged) == 0 {
+			return nil
+		}
+		changes := make([]AIFileChange, 0, len(a.FilesChanged))
+		for _, path := range a.FilesChanged {
+			data, err := os.ReadFile(filepath.Join(wp.GetWorkspace(), filepath.FromSlash(path)))
+			switch {
+			case errors.Is(err, os.ErrNotExist):
+				changes = append(changes, AIFileChange{Path: path, Action: "delete"})
+			case err != nil:
+				return nil
+			default:
+				changes = append(changes, AIFileChange{Path: path, Content: string(data), Action: "modify"})
+			}
+		}
+		return &StepOutput{Changes: changes}
+	case StepCommit:
+		if task.Branch != "" && len(task.Attempts) > 0 {
+			return &StepOutput{}
+		}
+	case StepDeploy:
+		for _, a := range slices.Backward(task.Attempts) {
+			if a.Deploy != nil {
+				if a.Deploy.Status != "success" {
+					return nil
+				}
+				return &StepOutput{Deploy: a.Deploy}
+			}
+		}
+	case StepTest:
+		for _, a := range slices.Backward(task.Attempts) {
+			if len(a.Tests) > 0 {
+				passed := !slices.ContainsFunc(a.Tests, func(r TestResult) bool { return !r.Passed })
+				return &StepOutput{Tests: a.Tests, Passed: passed}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStepRange(t *testing.T) {
+	steps, err := StepRange(StepDeploy, StepReport)
+	if err != nil || len(steps) != 3 || steps[0] != StepDeploy || steps[2] != StepReport {
+		t.Errorf("StepRange(deploy, report) = %v, %v", steps, err)
+	}
+	if _, err := StepRange(StepTest, StepCode); err == nil {
+		t.Error("expected an error for a backwards range")
+	}
+}
+
+func TestRunSteps_ReusesEarlierRun(t *testing.T) {
+	cfg := testConfig()
+	deploy := &mockDeploy{deploySuccess: true}
+	runner := &mockTestRunner{results: []*TestResult{
+		{Name: "unit-test", Type: "command", Passed: true, Duration: time.Second},
+		{Name: "unit-test", Type: "command", Passed: true, Duration: time.Second},
+	}}
+	statePath := tempStatePath(t)
+	e := NewEngine(cfg, &mockGit{}, &mockAI{}, deploy, []TestRunnerIface{runner}, nil, statePath)
+	ctx := context.Background()
+
+	if err := e.Execute(ctx, testIssue()); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	state, _ := LoadState(statePath)
+	taskID := state.Tasks[0].ID
+
+	records, err := e.RunSteps(ctx, taskID, StepDeploy, StepTest)
+	if err != nil {
+		t.Fatalf("RunSteps: %v", err)
+	}
+	if len(records) != 2 || records[0].Step != StepDeploy || records[1].Step != StepTest {
+		t.Fatalf("unexpected records %+v", records)
+	}
+	if deploy.deployCalls != 2 {
+		t.Errorf("expected a second deploy, got %d", deploy.deployCalls)
+	}
+
+	state, _ = LoadState(statePath)
+	task := state.GetTaskByID(taskID)
+	if task.Status != PhaseTesting || task.CompletedAt != nil {
+		t.Errorf("expected the task reopened and stopped at testing, got %s", task.Status)
+	}
+	if rec := task.GetStepRecord(StepCommit); rec == nil || rec.Status != "success" {
+		t.Errorf("expected the commit of the earlier run to be reused, got %+v", rec)
+	}
+	last := task.Attempts[len(task.Attempts)-1]
+	if len(task.Attempts) != 2 || last.Plan != "Steps from deploy" || last.Status != "passed" || last.Deploy == nil {
+		t.Errorf("expected a new passed attempt for the range, got %+v", last)
+	}
+}
+
+func TestRunSteps_NeedsEarlierRun(t *testing.T) {
+	statePath := tempStatePath(t)
+	e := NewEngine(testConfig(), &mockGit{}, &mockAI{}, &mockDeploy{deploySuccess: true}, nil, nil, statePath)
+	task, err := e.StartTask(context.Background(), testIssue())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.RunSteps(context.Background(), task.ID, StepDeploy, StepTest); !errors.Is(err, ErrStepNotReady) {
+		t.Errorf("expected ErrStepNotReady, got %v", err)
+	}
+}
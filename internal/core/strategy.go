@@ -0,0 +1,200 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+)
+
+// Deploy strategies (deploy.strategy).
+const (
+	StrategyCanary    = "canary"
+	StrategyBlueGreen = "blue-green"
+	StrategyRolling   = "rolling"
+)
+
+// DeployTarget is one stage of a deploy strategy: the adapter deploying to
+// it and the variables given to its commands and to the tests run against
+// it.
+type DeployTarget struct {
+	Name    string
+	Adapter DeployAdapterIface
+	Vars    map[string]string
+}
+
+// SetDeployStrategy makes the engine deploy to targets in stages instead
+// of with its deploy adapter. Canary and blue-green deploy the first target
+// and run the tests against it before promoting to the rest; rolling runs
+// them after every target. When the tests or a deploy fail, the targets
+// deployed so far are rolled back and the deploy fails.
+func (e *Engine) SetDeployStrategy(strategy string, targets []DeployTarget) {
+	e.deploy = e.StagedDeploy(strategy, targets)
+}
+
+// StagedDeploy returns a deploy adapter deploying to targets by strategy,
+// as SetDeployStrategy does, for an Environment of its own.
+func (e *Engine) StagedDeploy(strategy string, targets []DeployTarget) DeployAdapterIface {
+	return &stagedDeploy{e: e, strategy: strategy, targets: targets}
+}
+
+// stagedDeploy implements DeployAdapterIface over the targets of a deploy
+// strategy.
+type stagedDeploy struct {
+	e        *Engine
+	strategy string
+	targets  []DeployTarget
+}
+
+var (
+	_ DeployAdapterIface = (*stagedDeploy)(nil)
+	_ SnapshotRollbacker = (*stagedDeploy)(nil)
+)
+
+func (s *stagedDeploy) Validate() error {
+	for _, t := range s.targets {
+		if err := t.Adapter.Validate(); err != nil {
+			return fmt.Errorf("deploy target %s: %w", t.Name, err)
+		}
+	}
+	return nil
+}
+
+// verifies reports whether the tests run after deploying target i. The
+// last target is left to the test phase that follows the deploy.
+func (s *stagedDeploy) verifies(i int) bool {
+	if i == len(s.targets)-1 {
+		return false
+	}
+	return s.strategy == StrategyRolling || i == 0
+}
+
+func (s *stagedDeploy) Deploy(ctx context.Context, vars map[string]string) (*AdapterDeployResult, error) {
+	start := time.Now()
+	var out strings.Builder
+	artifacts := make(map[string]string)
+	fail := func(msg string, err error) (*AdapterDeployResult, error) {
+		return &AdapterDeployResult{
+			Success:   false,
+			Output:    msg + "\n" + out.String(),
+			Duration:  time.Since(start),
+			Artifacts: artifacts,
+		}, err
+	}
+
+	for i, t := range s.targets {
+		targetVars := t.vars(vars)
+		s.log(ctx, "info", fmt.Sprintf("Deploying to %s (%s, target %d of %d)", t.Name, s.strategy, i+1, len(s.targets)))
+		result, err := t.Adapter.Deploy(ctx, targetVars)
+		if result != nil {
+			fmt.Fprintf(&out, "--- %s ---\n%s\n", t.Name, result.Output)
+			maps.Copy(artifacts, result.Artifacts)
+		}
+		if err != nil || !result.Success {
+			s.abort(ctx, i)
+			msg := fmt.Sprintf("deploy to %s failed; rolled back %s", t.Name, s.names(i))
+			if err != nil {
+				return fail(msg, fmt.Errorf("%s: %w", msg, err))
+			}
+			return fail(msg, nil)
+		}
+		if !s.verifies(i) {
+			continue
+		}
+
+		s.log(ctx, "info", fmt.Sprintf("Testing %s before promoting", t.Name))
+		results, passed := stepTest(ctx, s.e.testRunners, s.e.testConfigs, nil, targetVars, s.e.testRunOptions())
+		if !passed {
+			out.WriteString(collectTestOutput(results))
+			s.abort(ctx, i)
+			return fail(fmt.Sprintf("tests failed on %s; aborted the %s deploy and rolled back %s", t.Name, s.strategy, s.names(i)), nil)
+		}
+	}
+
+	result := &AdapterDeployResult{Success: true, Output: out.String(), Duration: time.Since(start)}
+	if len(artifacts) > 0 {
+		result.Artifacts = artifacts
+	}
+	return result, nil
+}
+
+// abort rolls back targets last down to the first, restoring the
+// project's last successful deploy where the target adapter can.
+func (s *stagedDeploy) abort(ctx context.Context, last int) {
+	var snap *DeploySnapshot
+	if task := taskFrom(ctx); task != nil && s.e.statePath != "" {
+		if state, err := LoadState(s.e.statePath); err == nil {
+			snap = state.DeploySnapshot(s.e.snapshotKey(task))
+		}
+	}
+	for i := last; i >= 0; i-- {
+		if err := s.targets[i].rollback(ctx, snap); err != nil {
+			s.log(ctx, "error", fmt.Sprintf("Rollback of %s failed: %v", s.targets[i].Name, err))
+		}
+	}
+}
+
+// Rollback rolls back every target, the last first.
+func (s *stagedDeploy) Rollback(ctx context.Context) error {
+	return s.rollbackAll(ctx, nil)
+}
+
+// RollbackTo restores snap on every target, the last first.
+func (s *stagedDeploy) RollbackTo(ctx context.Context, snap *DeploySnapshot) error {
+	return s.rollbackAll(ctx, snap)
+}
+
+func (s *stagedDeploy) rollbackAll(ctx context.Context, snap *DeploySnapshot) error {
+	var errs []string
+	for i := len(s.targets) - 1; i >= 0; i-- {
+		if err := s.targets[i].rollback(ctx, snap); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", s.targets[i].Name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("rollback: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// names lists the targets up to last for messages.
+func (s *stagedDeploy) names(last int) string {
+	names := make([]string, 0, last+1)
+	for _, t := range s.targets[:last+1] {
+		names = append(names, t.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+func (s *stagedDeploy) log(ctx context.Context, level, msg string) {
+	if task := taskFrom(ctx); task != nil {
+		s.e.taskLog(task.ID, level, msg)
+		return
+	}
+	if level == "error" {
+		s.e.log().Error(msg)
+		return
+	}
+	s.e.log().Info(msg)
+}
+
+// vars returns base with the target's variables and DEPLOY_TARGET added.
+func (t DeployTarget) vars(base map[string]string) map[string]string {
+	vars := make(map[string]string, len(base)+len(t.Vars)+1)
+	maps.Copy(vars, base)
+	maps.Copy(vars, t.Vars)
+	vars["DEPLOY_TARGET"] = t.Name
+	return vars
+}
+
+// rollback rolls the target back to snap when there is one and the
+// adapter can, or else with its fixed rollback.
+func (t DeployTarget) rollback(ctx context.Context, snap *DeploySnapshot) error {
+	if r, ok := t.Adapter.(SnapshotRollbacker); ok && snap != nil {
+		targetSnap := *snap
+		targetSnap.Vars = t.vars(snap.Vars)
+		return r.RollbackTo(ctx, &targetSnap)
+	}
+	return t.Adapter.Rollback(ctx)
+}
@@ -0,0 +1,102 @@
+package core
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// targetDeploy records the deploys and rollbacks of a target in a shared
+// log.
+type targetDeploy struct {
+	name string
+	log  *[]string
+}
+
+func (m *targetDeploy) Validate() error { return nil }
+
+func (m *targetDeploy) Deploy(ctx context.Context, vars map[string]string) (*AdapterDeployResult, error) {
+	*m.log = append(*m.log, "deploy "+vars["DEPLOY_TARGET"]+" "+vars["URL"])
+	return &AdapterDeployResult{Success: true, Output: "deployed " + m.name}, nil
+}
+
+func (m *targetDeploy) Rollback(ctx context.Context) error {
+	*m.log = append(*m.log, "rollback "+m.name)
+	return nil
+}
+
+// targetTestRunner passes except against the targets in failOn.
+type targetTestRunner struct {
+	failOn []string
+	log    *[]string
+}
+
+func (r *targetTestRunner) Run(ctx context.Context, vars map[string]string) (*TestResult, error) {
+	*r.log = append(*r.log, "test "+vars["DEPLOY_TARGET"])
+	passed := !slices.Contains(r.failOn, vars["DEPLOY_TARGET"])
+	return &TestResult{Name: "smoke", Type: "http", Passed: passed, Output: "smoke output", Duration: time.Millisecond}, nil
+}
+
+func TestStagedDeploy(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy string
+		targets  []string
+		failOn   []string
+		wantOK   bool
+		wantLog  []string
+	}{
+		{
+			name:     "canary promoted",
+			strategy: StrategyCanary,
+			targets:  []string{"canary", "fleet-a", "fleet-b"},
+			wantOK:   true,
+			wantLog:  []string{"deploy canary http://canary", "test canary", "deploy fleet-a http://fleet-a", "deploy fleet-b http://fleet-b"},
+		},
+		{
+			name:     "canary aborted",
+			strategy: StrategyCanary,
+			targets:  []string{"canary", "fleet"},
+			failOn:   []string{"canary"},
+			wantLog:  []string{"deploy canary http://canary", "test canary", "rollback canary"},
+		},
+		{
+			name:     "rolling aborted",
+			strategy: StrategyRolling,
+			targets:  []string{"batch-1", "batch-2", "batch-3"},
+			failOn:   []string{"batch-2"},
+			wantLog: []string{
+				"deploy batch-1 http://batch-1", "test batch-1",
+				"deploy batch-2 http://batch-2", "test batch-2",
+				"rollback batch-2", "rollback batch-1",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var log []string
+			engine := NewEngine(testConfig(), &mockGit{}, &mockAI{}, &mockDeploy{}, []TestRunnerIface{&targetTestRunner{failOn: tt.failOn, log: &log}}, nil, tempStatePath(t))
+			targets := make([]DeployTarget, len(tt.targets))
+			for i, name := range tt.targets {
+				targets[i] = DeployTarget{Name: name, Adapter: &targetDeploy{name: name, log: &log}, Vars: map[string]string{"URL": "http://" + name}}
+			}
+			engine.SetDeployStrategy(tt.strategy, targets)
+
+			result, err := engine.deploy.Deploy(context.Background(), map[string]string{"COMMIT_SHA": "abc"})
+			if err != nil {
+				t.Fatalf("Deploy: %v", err)
+			}
+			if result.Success != tt.wantOK {
+				t.Errorf("success = %v, want %v (output %q)", result.Success, tt.wantOK, result.Output)
+			}
+			if !tt.wantOK && !strings.Contains(result.Output, "smoke output") {
+				t.Errorf("output %q lacks the failed test output", result.Output)
+			}
+			if !slices.Equal(log, tt.wantLog) {
+				t.Errorf("ran\n  %q\nwant\n  %q", log, tt.wantLog)
+			}
+		})
+	}
+}
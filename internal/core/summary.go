@@ -0,0 +1,188 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrSummaryUnsupported is returned when the configured AI adapter cannot
+// produce free-form task summaries.
+var ErrSummaryUnsupported = errors.New("ai adapter does not support task summaries")
+
+// TaskSummarizer is an optional AIAdapter capability that turns a task
+// report into a short human-readable status summary.
+type TaskSummarizer interface {
+	SummarizeTask(ctx context.Context, report string) (string, error)
+}
+
+// TaskSummary is an AI-written status summary for a task. Version identifies
+// the task state it was generated from.
+type TaskSummary struct {
+	TaskID      string    `json:"task_id"`
+	Version     string    `json:"version"`
+	Summary     string    `json:"summary"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Cached      bool      `json:"cached"`
+}
+
+// maxReportOutputBytes bounds each log excerpt included in a task report.
+const maxReportOutputBytes = 2000
+
+// maxSummaryEntries bounds the tasks a SummaryCache holds summaries of.
+const maxSummaryEntries = 256
+
+// SummaryCache memoizes task summaries per task version so repeated dashboard
+// polls and notifier digests do not call the AI again until the task changes.
+// It keeps the summaries of the maxSummaryEntries tasks last asked about.
+type SummaryCache struct {
+	mu      sync.Mutex
+	entries map[string]*summaryEntry
+	max     int
+	clock   uint64 // counts the lookups, to order the entries by use
+}
+
+// summaryEntry is a cached summary and the lookup it was last asked for in.
+type summaryEntry struct {
+	summary TaskSummary
+	used    uint64
+}
+
+// NewSummaryCache creates an empty SummaryCache.
+func NewSummaryCache() *SummaryCache {
+	return &SummaryCache{entries: make(map[string]*summaryEntry), max: maxSummaryEntries}
+}
+
+// Summarize returns the cached summary for the task's current version, or
+// asks the AI adapter for a new one.
+func (c *SummaryCache) Summarize(ctx context.Context, ai AIAdapter, task *Task) (TaskSummary, error) {
+	version := TaskVersion(task)
+
+	c.mu.Lock()
+	cached, ok := c.entries[task.ID]
+	if ok && cached.summary.Version == version {
+		c.clock++
+		cached.used = c.clock
+		entry := cached.summary
+		c.mu.Unlock()
+		entry.Cached = true
+		return entry, nil
+	}
+	c.mu.Unlock()
+
+	summarizer, ok := ai.(TaskSummarizer)
+	if !ok {
+		return TaskSummary{}, ErrSummaryUnsupported
+	}
+	text, err := summarizer.SummarizeTask(ctx, TaskReport(task))
+	if err != nil {
+		return TaskSummary{}, fmt.Errorf("summarize task %s: %w", task.ID, err)
+	}
+
+	entry := TaskSummary{
+		TaskID:      task.ID,
+		Version:     version,
+		Summary:     strings.TrimSpace(text),
+		GeneratedAt: time.Now().UTC(),
+	}
+	c.mu.Lock()
+	c.put(entry)
+	c.mu.Unlock()
+	return entry, nil
+}
+
+// put caches summary, making room by dropping the summary least recently
+// asked for. The caller holds c.mu.
+func (c *SummaryCache) put(summary TaskSummary) {
+	if _, ok := c.entries[summary.TaskID]; !ok && len(c.entries) >= c.max {
+		oldest := ""
+		for id, e := range c.entries {
+			if oldest == "" || e.used < c.entries[oldest].used {
+				oldest = id
+			}
+		}
+		delete(c.entries, oldest)
+	}
+	c.clock++
+	c.entries[summary.TaskID] = &summaryEntry{summary: summary, used: c.clock}
+}
+
+// TaskVersion returns a stable fingerprint of the task's persisted state.
+// It changes whenever the task is updated.
+func TaskVersion(task *Task) string {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// TaskReport renders the facts about a task that an AI needs to explain what
+// happened, where it is stuck, and what is needed from the user.
+func TaskReport(task *Task) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Issue: %s #%s %q\n", task.Issue.Repo, task.Issue.ID, task.Issue.Title)
+	fmt.Fprintf(&b, "Status: %s\n", task.Status)
+	fmt.Fprintf(&b, "Created: %s\n", task.CreatedAt.Format(time.RFC3339))
+	if task.CompletedAt != nil {
+		fmt.Fprintf(&b, "Completed: %s\n", task.CompletedAt.Format(time.RFC3339))
+	}
+	if task.PR != nil {
+		fmt.Fprintf(&b, "Pull request: %s\n", task.PR.URL)
+	}
+
+	if len(task.Pipeline) > 0 {
+		b.WriteString("\nPipeline:\n")
+		for _, step := range task.Pipeline {
+			fmt.Fprintf(&b, "- %s: %s", step.Phase, step.Status)
+			if step.Error != "" {
+				fmt.Fprintf(&b, " (%s)", step.Error)
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	for _, a := range task.Attempts {
+		fmt.Fprintf(&b, "\nAttempt %d: %s", a.Number, a.Status)
+		if a.FailReason != "" {
+			fmt.Fprintf(&b, " (%s)", a.FailReason)
+		}
+		b.WriteString("\n")
+		if len(a.FilesChanged) > 0 {
+			fmt.Fprintf(&b, "Files changed: %s\n", strings.Join(a.FilesChanged, ", "))
+		}
+		if a.TestDelta != nil && !a.TestDelta.IsEmpty() {
+			fmt.Fprintf(&b, "Test changes: %s\n", a.TestDelta.String())
+		}
+		for _, r := range a.Tests {
+			if r.Passed {
+				continue
+			}
+			output := r.Output
+			if failed := failedCaseOutput(r); failed != "" {
+				output = failed
+			}
+			fmt.Fprintf(&b, "Failed test %s:\n%s\n", r.Name, truncateReport(output))
+		}
+	}
+
+	for _, p := range task.Proposals {
+		fmt.Fprintf(&b, "\nProposal %s (%s, %s): %s\n", p.ID, p.Type, p.Status, p.Summary)
+	}
+	return b.String()
+}
+
+func truncateReport(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) > maxReportOutputBytes {
+		return "...(truncated)\n" + s[len(s)-maxReportOutputBytes:]
+	}
+	return s
+}
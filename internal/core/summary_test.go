@@ -0,0 +1,45 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// countingSummarizer is an AI adapter that counts the summaries it writes.
+type countingSummarizer struct {
+	*mockAI
+	calls int
+}
+
+func (s *countingSummarizer) SummarizeTask(ctx context.Context, report string) (string, error) {
+	s.calls++
+	return fmt.Sprintf("summary %d", s.calls), nil
+}
+
+func TestSummaryCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	ai := &countingSummarizer{mockAI: &mockAI{}}
+	c := NewSummaryCache()
+	c.max = 2
+	tasks := []*Task{{ID: "task-1"}, {ID: "task-2"}, {ID: "task-3"}}
+	ctx := context.Background()
+
+	c.Summarize(ctx, ai, tasks[0])
+	c.Summarize(ctx, ai, tasks[1])
+	if s, _ := c.Summarize(ctx, ai, tasks[0]); !s.Cached {
+		t.Fatalf("expected task-1 cached, got %+v", s)
+	}
+	c.Summarize(ctx, ai, tasks[2])
+	if len(c.entries) != 2 {
+		t.Fatalf("cache holds %d entries, want 2", len(c.entries))
+	}
+	if s, _ := c.Summarize(ctx, ai, tasks[0]); !s.Cached {
+		t.Errorf("expected the recently used task-1 kept, got %+v", s)
+	}
+	if s, _ := c.Summarize(ctx, ai, tasks[1]); s.Cached {
+		t.Errorf("expected the least recently used task-2 evicted, got %+v", s)
+	}
+	if ai.calls != 4 {
+		t.Errorf("summarized %d times, want 4", ai.calls)
+	}
+}
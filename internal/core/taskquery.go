@@ -0,0 +1,131 @@
+package core
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Keys tasks can be sorted by with TaskQuery.Sort. A leading "-" sorts in
+// descending order.
+var taskSortKeys = map[string]func(a, b *Task) int{
+	"created_at":   func(a, b *Task) int { return a.CreatedAt.Compare(b.CreatedAt) },
+	"completed_at": func(a, b *Task) int { return compareTimes(a.CompletedAt, b.CompletedAt) },
+	"status":       func(a, b *Task) int { return cmp.Compare(a.Status, b.Status) },
+	"id":           func(a, b *Task) int { return cmp.Compare(a.ID, b.ID) },
+}
+
+// TaskQuery filters, sorts and pages a list of tasks. The zero value
+// matches every task in the order they were created.
+type TaskQuery struct {
+	Status []TaskPhase // any of these statuses; empty matches all
+	Repo   string      // issue repository, owner/name, case-insensitive
+	Since  time.Time   // created at or after
+	Until  time.Time   // created before
+	Search string      // case-insensitive text in the issue title, ID or task ID
+	Parent string      // only the parts of this epic task
+	Sort   string      // a sort key, "-" prefixed for descending; default created_at
+	Limit  int         // at most this many tasks; 0 means no limit
+	Offset int         // skip this many matching tasks
+}
+
+// Validate reports a sort key or page that Apply cannot use.
+func (q TaskQuery) Validate() error {
+	if q.Sort != "" {
+		if _, ok := taskSortKeys[strings.TrimPrefix(q.Sort, "-")]; !ok {
+			keys := make([]string, 0, len(taskSortKeys))
+			for k := range taskSortKeys {
+				keys = append(keys, k)
+			}
+			slices.Sort(keys)
+			return fmt.Errorf("unknown sort key %q: use %s, optionally prefixed with -", q.Sort, strings.Join(keys, ", "))
+		}
+	}
+	if q.Limit < 0 || q.Offset < 0 {
+		return fmt.Errorf("limit and offset must not be negative")
+	}
+	return nil
+}
+
+// Match reports whether task passes the filters of q.
+func (q TaskQuery) Match(task *Task) bool {
+	if len(q.Status) > 0 && !slices.Contains(q.Status, task.Status) {
+		return false
+	}
+	if q.Repo != "" && !strings.EqualFold(task.Issue.Repo, q.Repo) {
+		return false
+	}
+	if q.Parent != "" && (task.Part == nil || task.Part.ParentID != q.Parent) {
+		return false
+	}
+	if !q.Since.IsZero() && task.CreatedAt.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !task.CreatedAt.Before(q.Until) {
+		return false
+	}
+	if q.Search != "" {
+		search := strings.ToLower(q.Search)
+		if !strings.Contains(strings.ToLower(task.Issue.Title), search) &&
+			!strings.Contains(strings.ToLower(task.ID), search) &&
+			task.Issue.ID != strings.TrimPrefix(q.Search, "#") {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply returns the page of tasks q selects and how many tasks match in
+// all. tasks is not modified.
+func (q TaskQuery) Apply(tasks []Task) ([]Task, int) {
+	matched := make([]Task, 0, len(tasks))
+	for i := range tasks {
+		if q.Match(&tasks[i]) {
+			matched = append(matched, tasks[i])
+		}
+	}
+	if q.Sort != "" {
+		key, desc := strings.CutPrefix(q.Sort, "-")
+		if compare, ok := taskSortKeys[key]; ok {
+			slices.SortStableFunc(matched, func(a, b Task) int {
+				if desc {
+					return compare(&b, &a)
+				}
+				return compare(&a, &b)
+			})
+		}
+	}
+	total := len(matched)
+	if q.Offset >= total {
+		return []Task{}, total
+	}
+	matched = matched[q.Offset:]
+	if q.Limit > 0 && q.Limit < len(matched) {
+		matched = matched[:q.Limit]
+	}
+	return matched, total
+}
+
+// CountByStatus returns how many tasks are in each status.
+func CountByStatus(tasks []Task) map[TaskPhase]int {
+	counts := make(map[TaskPhase]int)
+	for i := range tasks {
+		counts[tasks[i].Status]++
+	}
+	return counts
+}
+
+// compareTimes orders nil times first.
+func compareTimes(a, b *time.Time) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	return a.Compare(*b)
+}
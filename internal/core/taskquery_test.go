@@ -0,0 +1,56 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTaskQuery(t *testing.T) {
+	base := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	tasks := []Task{
+		{ID: "t1", Status: PhaseCompleted, Issue: Issue{ID: "1", Repo: "acme/app", Title: "Fix login form"}, CreatedAt: base},
+		{ID: "t2", Status: PhaseFailed, Issue: Issue{ID: "2", Repo: "acme/api", Title: "Add invoice export"}, CreatedAt: base.Add(time.Hour)},
+		{ID: "t3", Status: PhaseCoding, Issue: Issue{ID: "3", Repo: "acme/app", Title: "Login rate limit"}, CreatedAt: base.Add(2 * time.Hour)},
+		{ID: "t4", Status: PhaseCompleted, Issue: Issue{ID: "4", Repo: "acme/app", Title: "Dark mode"}, CreatedAt: base.Add(3 * time.Hour)},
+	}
+	ids := func(tasks []Task) string {
+		var s string
+		for _, task := range tasks {
+			s += task.ID + " "
+		}
+		return s
+	}
+
+	tests := []struct {
+		name  string
+		query TaskQuery
+		want  string
+		total int
+	}{
+		{"all", TaskQuery{}, "t1 t2 t3 t4 ", 4},
+		{"status", TaskQuery{Status: []TaskPhase{PhaseCompleted, PhaseFailed}}, "t1 t2 t4 ", 3},
+		{"repo", TaskQuery{Repo: "ACME/app"}, "t1 t3 t4 ", 3},
+		{"range", TaskQuery{Since: base.Add(time.Hour), Until: base.Add(3 * time.Hour)}, "t2 t3 ", 2},
+		{"search title", TaskQuery{Search: "LOGIN"}, "t1 t3 ", 2},
+		{"search issue", TaskQuery{Search: "#4"}, "t4 ", 1},
+		{"newest first", TaskQuery{Sort: "-created_at"}, "t4 t3 t2 t1 ", 4},
+		{"page", TaskQuery{Sort: "-created_at", Limit: 2, Offset: 1}, "t3 t2 ", 4},
+		{"past the end", TaskQuery{Offset: 10}, "", 4},
+	}
+	for _, tt := range tests {
+		got, total := tt.query.Apply(tasks)
+		if ids(got) != tt.want || total != tt.total {
+			t.Errorf("%s: got %q of %d, want %q of %d", tt.name, ids(got), total, tt.want, tt.total)
+		}
+	}
+	if tasks[0].ID != "t1" || tasks[3].ID != "t4" {
+		t.Error("Apply reordered its input")
+	}
+
+	if err := (TaskQuery{Sort: "-title"}).Validate(); err == nil {
+		t.Error("unknown sort key accepted")
+	}
+	if err := (TaskQuery{Limit: -1}).Validate(); err == nil {
+		t.Error("negative limit accepted")
+	}
+}
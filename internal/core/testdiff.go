@@ -0,0 +1,75 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TestDelta describes how test outcomes changed between two attempts.
+type TestDelta struct {
+	NewlyPassing []string `json:"newly_passing,omitempty"`
+	NewlyFailing []string `json:"newly_failing,omitempty"`
+	StillFailing []string `json:"still_failing,omitempty"`
+}
+
+// IsEmpty reports whether the delta carries no information.
+func (d *TestDelta) IsEmpty() bool {
+	return d == nil || (len(d.NewlyPassing) == 0 && len(d.NewlyFailing) == 0 && len(d.StillFailing) == 0)
+}
+
+// String renders the delta as a short human-readable summary.
+func (d *TestDelta) String() string {
+	if d.IsEmpty() {
+		return "no test changes"
+	}
+	var parts []string
+	if len(d.NewlyPassing) > 0 {
+		parts = append(parts, "newly passing: "+strings.Join(d.NewlyPassing, ", "))
+	}
+	if len(d.NewlyFailing) > 0 {
+		parts = append(parts, "newly failing: "+strings.Join(d.NewlyFailing, ", "))
+	}
+	if len(d.StillFailing) > 0 {
+		parts = append(parts, "still failing: "+strings.Join(d.StillFailing, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// diffTestResults compares the current attempt's results against the previous
+// attempt's. Tests are matched by name; tests absent from prev count as
+// previously passing so a new failure is reported as newly failing.
+func diffTestResults(prev, curr []TestResult) *TestDelta {
+	prevPassed := make(map[string]bool, len(prev))
+	for _, r := range prev {
+		prevPassed[r.Name] = r.Passed
+	}
+
+	delta := &TestDelta{}
+	for _, r := range curr {
+		wasPassing, seen := prevPassed[r.Name]
+		switch {
+		case r.Passed && seen && !wasPassing:
+			delta.NewlyPassing = append(delta.NewlyPassing, r.Name)
+		case !r.Passed && (!seen || wasPassing):
+			delta.NewlyFailing = append(delta.NewlyFailing, r.Name)
+		case !r.Passed:
+			delta.StillFailing = append(delta.StillFailing, r.Name)
+		}
+	}
+	sort.Strings(delta.NewlyPassing)
+	sort.Strings(delta.NewlyFailing)
+	sort.Strings(delta.StillFailing)
+	return delta
+}
+
+// formatFailureLogs builds the AnalyzeFailure input. When a delta from the
+// previous fix is available it is prepended so the model can see whether
+// its last change helped.
+func formatFailureLogs(results []TestResult, delta *TestDelta) string {
+	logs := collectTestOutput(results)
+	if delta.IsEmpty() {
+		return logs
+	}
+	return fmt.Sprintf("Effect of previous fix attempt: %s\n\n%s", delta.String(), logs)
+}
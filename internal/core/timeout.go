@@ -0,0 +1,104 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// cleanupTimeout bounds the cleanup, notifications and rollback that run
+// after a task timed out, on a context of their own.
+const cleanupTimeout = 2 * time.Minute
+
+// TimeoutError reports a task or phase that ran past workflow.timeouts.
+type TimeoutError struct {
+	Scope string // "task" or the phase, such as "deploying"
+	Limit time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s timed out after %s", e.Scope, e.Limit)
+}
+
+// withTaskTimeout bounds a run of the pipeline by workflow.timeouts.task.
+func (e *Engine) withTaskTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, "task", e.cfg.Workflow.Timeouts.Task)
+}
+
+// withPhaseTimeout bounds a phase by its workflow.timeouts limit. The
+// context also ends when the task's own deadline passes.
+func (e *Engine) withPhaseTimeout(ctx context.Context, phase TaskPhase) (context.Context, context.CancelFunc) {
+	t := e.cfg.Workflow.Timeouts
+	limits := map[TaskPhase]time.Duration{
+		PhasePlanning:   t.Planning,
+		PhaseCoding:     t.Coding,
+		PhaseCommitting: t.Committing,
+		PhaseDeploying:  t.Deploying,
+		PhaseTesting:    t.Testing,
+	}
+	return withTimeout(ctx, string(phase), limits[phase])
+}
+
+func withTimeout(ctx context.Context, scope string, limit time.Duration) (context.Context, context.CancelFunc) {
+	if limit <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeoutCause(ctx, limit, &TimeoutError{Scope: scope, Limit: limit})
+}
+
+// timedOut returns the TimeoutError ctx ended with, or nil if it has not
+// ended or ended for another reason.
+func timedOut(ctx context.Context) *TimeoutError {
+	var te *TimeoutError
+	if errors.As(context.Cause(ctx), &te) {
+		return te
+	}
+	return nil
+}
+
+// timeoutCause wraps err, which a step running under ctx returned, with the
+// TimeoutError or ErrShutdown that ended ctx, if any, so the failure names
+// the timeout or shutdown rather than a bare "context deadline exceeded".
+func timeoutCause(ctx context.Context, err error) error {
+	if te := timedOut(ctx); te != nil && !errors.As(err, new(*TimeoutError)) {
+		return fmt.Errorf("%w: %w", te, err)
+	}
+	if shuttingDown(ctx) && !errors.Is(err, ErrShutdown) {
+		return fmt.Errorf("%w: %w", ErrShutdown, err)
+	}
+	return err
+}
+
+// reasonFor returns ReasonTimeout if err came from a workflow timeout,
+// ReasonShutdown if a shutdown interrupted it and reason otherwise.
+func reasonFor(err error, reason FailReason) FailReason {
+	if errors.As(err, new(*TimeoutError)) {
+		return ReasonTimeout
+	}
+	if errors.Is(err, ErrShutdown) {
+		return ReasonShutdown
+	}
+	return reason
+}
+
+// cleanupContext returns a context for the work that follows a failure:
+// ctx itself, or a fresh context bounded by cleanupTimeout once a workflow
+// timeout has ended ctx.
+func cleanupContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if timedOut(ctx) == nil {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(context.WithoutCancel(ctx), cleanupTimeout)
+}
+
+// deployStarted reports whether task has run a deploy, which a timeout may
+// have left half applied.
+func deployStarted(task *Task) bool {
+	for _, step := range task.Pipeline {
+		if step.Phase == PhaseDeploying && step.Status != "skipped" {
+			return true
+		}
+	}
+	return false
+}
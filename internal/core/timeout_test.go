@@ -0,0 +1,94 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// hangingDeploy blocks in Deploy until its context ends and records the
+// context Rollback gets.
+type hangingDeploy struct {
+	mockDeploy
+	rollbackCtxErr error
+}
+
+func (m *hangingDeploy) Deploy(ctx context.Context, vars map[string]string) (*AdapterDeployResult, error) {
+	m.deployCalls++
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (m *hangingDeploy) Rollback(ctx context.Context) error {
+	m.rollbackCalls++
+	m.rollbackCtxErr = ctx.Err()
+	return nil
+}
+
+func TestEngine_PhaseTimeout(t *testing.T) {
+	cfg := testConfig()
+	cfg.Workflow.Timeouts.Planning = 20 * time.Millisecond
+	aiMock := &mockAI{
+		analyzeFunc: func(ctx context.Context, issue *AIIssue, projectCtx string) (*AIPlan, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+	deployMock := &mockDeploy{deploySuccess: true}
+	statePath := tempStatePath(t)
+	engine := NewEngine(cfg, &mockGit{}, aiMock, deployMock, nil, nil, statePath)
+
+	err := engine.Execute(context.Background(), testIssue())
+	var te *TimeoutError
+	if !errors.As(err, &te) || te.Scope != "planning" {
+		t.Fatalf("expected a planning TimeoutError, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "failed at timeout: planning timed out after 20ms") {
+		t.Errorf("error = %q", err)
+	}
+
+	state, _ := LoadState(statePath)
+	task := state.Tasks[0]
+	if task.Status != PhaseFailed {
+		t.Fatalf("expected failed status, got %s", task.Status)
+	}
+	if deployMock.rollbackCalls != 0 {
+		t.Error("nothing was deployed, so nothing should be rolled back")
+	}
+}
+
+func TestEngine_TaskTimeoutRollsBackDeploy(t *testing.T) {
+	cfg := testConfig()
+	cfg.Workflow.Timeouts.Task = 50 * time.Millisecond
+	cfg.Deploy.Rollback.Enabled = true
+	deployMock := &hangingDeploy{}
+	notifier := &mockNotifier{}
+	statePath := tempStatePath(t)
+	engine := NewEngine(cfg, &mockGit{}, &mockAI{}, deployMock, nil, []NotifierIface{notifier}, statePath)
+
+	err := engine.Execute(context.Background(), testIssue())
+	var te *TimeoutError
+	if !errors.As(err, &te) || te.Scope != "task" {
+		t.Fatalf("expected a task TimeoutError, got %v", err)
+	}
+	if deployMock.rollbackCalls != 1 {
+		t.Fatalf("expected 1 rollback, got %d", deployMock.rollbackCalls)
+	}
+	if deployMock.rollbackCtxErr != nil {
+		t.Errorf("rollback ran on an ended context: %v", deployMock.rollbackCtxErr)
+	}
+
+	state, _ := LoadState(statePath)
+	task := state.Tasks[0]
+	if task.Status != PhaseRollback {
+		t.Fatalf("expected rollback status, got %s", task.Status)
+	}
+	if got := task.Attempts[len(task.Attempts)-1].FailReason; got != ReasonTimeout {
+		t.Errorf("attempt fail reason = %q, want %q", got, ReasonTimeout)
+	}
+	if len(notifier.messages) == 0 || !strings.Contains(strings.Join(notifier.messages, "\n"), "failed") {
+		t.Errorf("expected a failure notification, got %q", notifier.messages)
+	}
+}
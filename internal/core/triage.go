@@ -0,0 +1,148 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// FailureClass is the kind of a test failure as triage sees it; it decides
+// how a retry fixes the failure (workflow.triage).
+type FailureClass string
+
+const (
+	// FailureCompile is code that does not build: the fix is committed and
+	// tested without a redeploy.
+	FailureCompile FailureClass = "compile"
+	// FailureAssertion is a test that ran and failed: the code is fixed,
+	// redeployed and tested as without triage.
+	FailureAssertion FailureClass = "assertion"
+	// FailureInfra is a connection or environment error: it is retried
+	// once without code changes, then handed to a deploy-fix proposal.
+	FailureInfra FailureClass = "infra"
+	// FailureTimeout is a test that ran out of time: it is retried once
+	// without code changes, then fixed like an assertion.
+	FailureTimeout FailureClass = "timeout"
+	// FailureUnknown is a failure neither the heuristics nor the AI could
+	// place; it is fixed like an assertion.
+	FailureUnknown FailureClass = "unknown"
+)
+
+// ErrClassifyUnsupported is returned by adapter wrappers whose wrapped
+// adapter cannot classify failures.
+var ErrClassifyUnsupported = errors.New("ai adapter does not support failure classification")
+
+// FailureClassifier is an optional AIAdapter capability that classifies
+// failure output the heuristics could not place. Used by
+// workflow.triage.ai.
+type FailureClassifier interface {
+	ClassifyFailure(ctx context.Context, logs string) (FailureClass, error)
+}
+
+// ParseFailureClass returns the class named s, or FailureUnknown.
+func ParseFailureClass(s string) FailureClass {
+	switch c := FailureClass(strings.ToLower(strings.TrimSpace(s))); c {
+	case FailureCompile, FailureAssertion, FailureInfra, FailureTimeout:
+		return c
+	}
+	return FailureUnknown
+}
+
+// failurePatterns are the heuristics of ClassifyFailure, in precedence
+// order: code that does not build fails every test, so it wins over the
+// symptoms it causes, and an unreachable service wins over the timeouts
+// and assertions that follow from it.
+var failurePatterns = []struct {
+	class FailureClass
+	re    *regexp.Regexp
+}{
+	{FailureCompile, regexp.MustCompile(`(?i)syntax ?error|undefined: |undefined reference|cannot find (package|module|symbol)|\[build failed\]|build failed|compilation (failed|error)|compile error|failed to compile|error ts\d+|error\[e\d+\]|declared and not used|imported and not used|modulenotfounderror|importerror|cannot use .+ as .+ value|not enough arguments in call|too many arguments in call`)},
+	{FailureInfra, regexp.MustCompile(`(?i)connection refused|connection reset|econnrefused|econnreset|no such host|network is unreachable|temporary failure in name resolution|dial tcp|i/o timeout|broken pipe|tls handshake|service unavailable|bad gateway|gateway timeout|\b(status|http|code)\W{0,3}50[234]\b|no space left on device|too many open files|could not connect|failed to connect`)},
+	{FailureTimeout, regexp.MustCompile(`(?i)timed out|deadline exceeded|runner did not finish|timeout after|exceeded timeout`)},
+	{FailureAssertion, regexp.MustCompile(`(?i)--- fail|assert|expected|\bwant\b|\bgot\b|not equal|mismatch|\bfailed\b`)},
+}
+
+// ClassifyFailure places failure output by the heuristics of
+// failurePatterns, or returns FailureUnknown.
+func ClassifyFailure(logs string) FailureClass {
+	for _, p := range failurePatterns {
+		if p.re.MatchString(logs) {
+			return p.class
+		}
+	}
+	return FailureUnknown
+}
+
+// triage classifies the failure of results: by heuristics, then with
+// workflow.triage.ai by the AI for failures the heuristics could not
+// place. The class is logged on the task.
+func (e *Engine) triage(ctx context.Context, taskID string, results []TestResult) FailureClass {
+	var logs strings.Builder
+	for _, r := range results {
+		if !r.Passed {
+			fmt.Fprintf(&logs, "%s\n", r.Output)
+			for _, c := range r.Cases {
+				if c.Status == "fail" {
+					fmt.Fprintf(&logs, "%s\n", c.Message)
+				}
+			}
+		}
+	}
+	class := ClassifyFailure(logs.String())
+	by := "heuristics"
+	if class == FailureUnknown && e.cfg.Workflow.Triage.AI {
+		if c, err := e.classifyWithAI(ctx, logs.String()); err != nil {
+			e.taskLog(taskID, "warn", fmt.Sprintf("AI failure classification failed: %v", err))
+		} else {
+			class, by = c, "AI"
+		}
+	}
+	e.taskLog(taskID, "info", fmt.Sprintf("Triage: %s failure (%s)", class, by))
+	return class
+}
+
+// classifyWithAI asks the AI adapter to classify logs.
+func (e *Engine) classifyWithAI(ctx context.Context, logs string) (FailureClass, error) {
+	classifier, ok := e.ai.(FailureClassifier)
+	if !ok {
+		return FailureUnknown, ErrClassifyUnsupported
+	}
+	return classifier.ClassifyFailure(ctx, truncateBytes(logs, maxTriageLogBytes))
+}
+
+// maxTriageLogBytes bounds the failure output sent for AI classification.
+const maxTriageLogBytes = 16 * 1024
+
+// retryRoute is how a retry responds to a classified failure.
+type retryRoute struct {
+	class            FailureClass
+	codegen          bool // ask the AI for a code fix
+	redeploy         bool // deploy before testing again
+	proposeDeployFix bool // hand the failure to a deploy-fix proposal instead
+}
+
+// routeFailure decides how the next retry handles a failure of class.
+// bareRetried is the class of the failure the previous retry re-ran
+// without code changes, if it did: infra and timeout failures are re-run
+// once that way, and fixed by a deploy-fix proposal or the code when they
+// persist.
+func routeFailure(class, bareRetried FailureClass) retryRoute {
+	route := retryRoute{class: class, codegen: true, redeploy: true}
+	switch class {
+	case FailureCompile:
+		route.redeploy = false
+	case FailureInfra:
+		if bareRetried == FailureInfra {
+			route.codegen, route.redeploy, route.proposeDeployFix = false, false, true
+		} else {
+			route.codegen = false
+		}
+	case FailureTimeout:
+		if bareRetried != FailureTimeout {
+			route.codegen = false
+		}
+	}
+	return route
+}
@@ -0,0 +1,128 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestClassifyFailure(t *testing.T) {
+	tests := []struct {
+		logs string
+		want FailureClass
+	}{
+		{"# example.com/app\n./main.go:12:2: undefined: limiter\nFAIL\texample.com/app [build failed]", FailureCompile},
+		{"src/app.ts(3,1): error TS2304: Cannot find name 'x'.", FailureCompile},
+		{"Get \"http://localhost:8080/health\": dial tcp 127.0.0.1:8080: connect: connection refused", FailureInfra},
+		{"unexpected status code 503 Service Unavailable", FailureInfra},
+		{"panic: test timed out after 10m0s", FailureTimeout},
+		{"--- FAIL: TestLimit (0.00s)\n    limit_test.go:503: got 3, want 2", FailureAssertion},
+		{"exit status 1", FailureUnknown},
+	}
+	for _, tt := range tests {
+		if got := ClassifyFailure(tt.logs); got != tt.want {
+			t.Errorf("ClassifyFailure(%q) = %s, want %s", tt.logs, got, tt.want)
+		}
+	}
+}
+
+func TestRouteFailure(t *testing.T) {
+	tests := []struct {
+		class, bareRetried FailureClass
+		want               retryRoute
+	}{
+		{FailureAssertion, "", retryRoute{class: FailureAssertion, codegen: true, redeploy: true}},
+		{FailureCompile, "", retryRoute{class: FailureCompile, codegen: true}},
+		{FailureInfra, "", retryRoute{class: FailureInfra, redeploy: true}},
+		{FailureInfra, FailureInfra, retryRoute{class: FailureInfra, proposeDeployFix: true}},
+		{FailureTimeout, "", retryRoute{class: FailureTimeout, redeploy: true}},
+		{FailureTimeout, FailureTimeout, retryRoute{class: FailureTimeout, codegen: true, redeploy: true}},
+		{FailureTimeout, FailureInfra, retryRoute{class: FailureTimeout, redeploy: true}},
+	}
+	for _, tt := range tests {
+		if got := routeFailure(tt.class, tt.bareRetried); got != tt.want {
+			t.Errorf("routeFailure(%s, %q) = %+v, want %+v", tt.class, tt.bareRetried, got, tt.want)
+		}
+	}
+}
+
+func triageEngine(t *testing.T, ai AIAdapter, deploy *mockDeploy, results ...*TestResult) (*Engine, string) {
+	t.Helper()
+	cfg := testConfig()
+	cfg.Workflow.Triage.Enabled = true
+	statePath := tempStatePath(t)
+	runner := &mockTestRunner{results: results}
+	return NewEngine(cfg, &mockGit{}, ai, deploy, []TestRunnerIface{runner}, nil, statePath), statePath
+}
+
+func TestRetryLoop_TriageInfraRetriesWithoutCodegen(t *testing.T) {
+	analyzed := false
+	ai := &mockAI{failureFunc: func(ctx context.Context, logs string, currentCode map[string]string) ([]AIFileChange, error) {
+		analyzed = true
+		return nil, nil
+	}}
+	deploy := &mockDeploy{deploySuccess: true}
+	e, statePath := triageEngine(t, ai, deploy,
+		&TestResult{Name: "e2e", Passed: false, Output: "dial tcp 10.0.0.5:5432: connect: connection refused"},
+		&TestResult{Name: "e2e", Passed: true, Duration: time.Second},
+	)
+
+	if err := e.Execute(context.Background(), testIssue()); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if analyzed {
+		t.Error("an infra failure should be retried without asking for a code fix")
+	}
+	if deploy.deployCalls != 2 {
+		t.Errorf("expected a redeploy, got %d deploys", deploy.deployCalls)
+	}
+	state, _ := LoadState(statePath)
+	if a := state.Tasks[0].Attempts; len(a) != 2 || a[1].Triage != FailureInfra || a[1].Status != "passed" {
+		t.Errorf("unexpected attempts %+v", a)
+	}
+}
+
+func TestRetryLoop_TriagePersistentInfraProposesDeployFix(t *testing.T) {
+	var proposed string
+	ai := &mockAI{deployFailureFunc: func(ctx context.Context, deployLogs string, infraFiles map[string]string) (*AIProposedFix, error) {
+		proposed = deployLogs
+		return &AIProposedFix{Summary: "open the port", Reason: "database unreachable"}, nil
+	}}
+	refused := &TestResult{Name: "e2e", Passed: false, Output: "dial tcp 10.0.0.5:5432: connect: connection refused"}
+	e, statePath := triageEngine(t, ai, &mockDeploy{deploySuccess: true}, refused, refused)
+
+	if err := e.Execute(context.Background(), testIssue()); !errors.Is(err, ErrAwaitingApproval) {
+		t.Fatalf("expected ErrAwaitingApproval, got %v", err)
+	}
+	if proposed == "" {
+		t.Fatal("expected the test failure to be handed to deploy failure analysis")
+	}
+	state, _ := LoadState(statePath)
+	task := state.Tasks[0]
+	if p := task.GetPendingProposal(); task.Status != PhaseAwaitingApproval || p == nil || p.Type != ProposalDeployFix {
+		t.Errorf("expected a pending deploy fix, got %s with %+v", task.Status, p)
+	}
+}
+
+func TestRetryLoop_TriageCompileSkipsRedeploy(t *testing.T) {
+	ai := &mockAI{failureFunc: func(ctx context.Context, logs string, currentCode map[string]string) ([]AIFileChange, error) {
+		return []AIFileChange{{Path: "main.go", Content: "package main", Action: "modify"}}, nil
+	}}
+	deploy := &mockDeploy{deploySuccess: true}
+	e, statePath := triageEngine(t, ai, deploy,
+		&TestResult{Name: "unit", Passed: false, Output: "./main.go:3:2: undefined: run\nFAIL\tapp [build failed]"},
+		&TestResult{Name: "unit", Passed: true, Duration: time.Second},
+	)
+
+	if err := e.Execute(context.Background(), testIssue()); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if deploy.deployCalls != 1 {
+		t.Errorf("a compile fix should not be redeployed, got %d deploys", deploy.deployCalls)
+	}
+	state, _ := LoadState(statePath)
+	if a := state.Tasks[0].Attempts; len(a) != 2 || a[1].Triage != FailureCompile || a[1].Deploy != nil {
+		t.Errorf("unexpected attempts %+v", a)
+	}
+}
@@ -0,0 +1,111 @@
+package core
+
+import (
+	"context"
+	"sync"
+)
+
+// AIUsage counts the tokens consumed by a task's AI calls.
+type AIUsage struct {
+	Calls        int `json:"calls"`
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+	// Interrupted counts calls aborted by cancellation or a timeout. Their
+	// tokens are whatever the provider reported before the abort.
+	Interrupted int `json:"interrupted,omitempty"`
+	// Retries counts requests resent after a rate limit, server error or
+	// network error. They are not counted in Calls.
+	Retries int `json:"retries,omitempty"`
+	// CacheHits counts calls answered from ai.cache, which cost no tokens
+	// and are not counted in Calls.
+	CacheHits int `json:"cache_hits,omitempty"`
+}
+
+// usageKey is the context key for the task AI usage is accounted to.
+type usageKey struct{}
+
+// usageSink accumulates AI usage into a task.
+type usageSink struct {
+	mu   sync.Mutex
+	task *Task
+	// provider and model answered the task's last AI call, when the
+	// adapter reported them.
+	provider, model string
+}
+
+// WithTaskUsage returns a context whose AI calls are accounted to task.
+// The engine sets it for every run; RecordAIUsage reads it.
+func WithTaskUsage(ctx context.Context, task *Task) context.Context {
+	return context.WithValue(ctx, usageKey{}, &usageSink{task: task})
+}
+
+// taskFrom returns the task carried by ctx, or nil.
+func taskFrom(ctx context.Context) *Task {
+	if sink, ok := ctx.Value(usageKey{}).(*usageSink); ok {
+		return sink.task
+	}
+	return nil
+}
+
+// RecordAIUsage adds one AI call to the task carried by ctx. Adapters call
+// it after every request, including interrupted ones, so partial usage is
+// not lost. Without a task in ctx (e.g. dashboard summaries) it does nothing.
+func RecordAIUsage(ctx context.Context, inputTokens, outputTokens int, interrupted bool) {
+	updateUsage(ctx, func(u *AIUsage) {
+		u.Calls++
+		u.InputTokens += inputTokens
+		u.OutputTokens += outputTokens
+		if interrupted {
+			u.Interrupted++
+		}
+	})
+}
+
+// RecordAIRetry counts one resent AI request for the task carried by ctx.
+func RecordAIRetry(ctx context.Context) {
+	updateUsage(ctx, func(u *AIUsage) { u.Retries++ })
+}
+
+// RecordAICacheHit counts one AI call of the task in ctx that ai.cache
+// answered.
+func RecordAICacheHit(ctx context.Context) {
+	updateUsage(ctx, func(u *AIUsage) { u.CacheHits++ })
+}
+
+func updateUsage(ctx context.Context, fn func(*AIUsage)) {
+	sink, ok := ctx.Value(usageKey{}).(*usageSink)
+	if !ok {
+		return
+	}
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if sink.task.AIUsage == nil {
+		sink.task.AIUsage = &AIUsage{}
+	}
+	fn(sink.task.AIUsage)
+}
+
+// RecordAIProvider notes the provider and model that answered the last AI
+// call of the task in ctx. Adapters that fail over between providers call
+// it so each attempt records which one produced its changes.
+func RecordAIProvider(ctx context.Context, provider, model string) {
+	sink, ok := ctx.Value(usageKey{}).(*usageSink)
+	if !ok {
+		return
+	}
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	sink.provider, sink.model = provider, model
+}
+
+// lastAIProvider returns what RecordAIProvider last noted for the task in
+// ctx, if anything.
+func lastAIProvider(ctx context.Context) (provider, model string, ok bool) {
+	sink, found := ctx.Value(usageKey{}).(*usageSink)
+	if !found {
+		return "", "", false
+	}
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	return sink.provider, sink.model, sink.provider != ""
+}
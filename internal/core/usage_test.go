@@ -0,0 +1,65 @@
+package core
+
+import (
+	"context"
+	"testing"
+)
+
+func TestEngine_RecordsAIUsageOnTask(t *testing.T) {
+	aiMock := &mockAI{
+		analyzeFunc: func(ctx context.Context, issue *AIIssue, projectCtx string) (*AIPlan, error) {
+			RecordAIUsage(ctx, 100, 20, false)
+			return &AIPlan{Summary: "test plan", Steps: []string{"step1"}}, nil
+		},
+		generateFunc: func(ctx context.Context, plan *AIPlan, repoFiles map[string]string) ([]AIFileChange, error) {
+			RecordAIUsage(ctx, 300, 150, false)
+			return []AIFileChange{{Path: "main.go", Content: "package main", Action: "modify"}}, nil
+		},
+	}
+	statePath := tempStatePath(t)
+	engine := NewEngine(testConfig(), &mockGit{}, aiMock, &mockDeploy{deploySuccess: true},
+		[]TestRunnerIface{&mockTestRunner{}}, nil, statePath)
+
+	if err := engine.Execute(context.Background(), testIssue()); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	state, err := LoadState(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := state.Tasks[0].AIUsage
+	if u == nil || u.Calls != 2 || u.InputTokens != 400 || u.OutputTokens != 170 {
+		t.Fatalf("unexpected usage %+v", u)
+	}
+}
+
+func TestEngine_RecordsAnsweringProvider(t *testing.T) {
+	aiMock := &mockAI{
+		generateFunc: func(ctx context.Context, plan *AIPlan, repoFiles map[string]string) ([]AIFileChange, error) {
+			RecordAIProvider(ctx, "openai", "gpt-4o")
+			return []AIFileChange{{Path: "main.go", Content: "package main", Action: "modify"}}, nil
+		},
+	}
+	statePath := tempStatePath(t)
+	engine := NewEngine(testConfig(), &mockGit{}, aiMock, &mockDeploy{deploySuccess: true},
+		[]TestRunnerIface{&mockTestRunner{}}, nil, statePath)
+	if err := engine.Execute(context.Background(), testIssue()); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	state, _ := LoadState(statePath)
+	if a := state.Tasks[0].Attempts[0]; a.Provider != "openai" || a.Model != "gpt-4o" {
+		t.Errorf("attempt provider/model = %q/%q, want openai/gpt-4o", a.Provider, a.Model)
+	}
+
+	// Without a recorded provider the configured one is assumed.
+	statePath = tempStatePath(t)
+	engine = NewEngine(testConfig(), &mockGit{}, &mockAI{}, &mockDeploy{deploySuccess: true},
+		[]TestRunnerIface{&mockTestRunner{}}, nil, statePath)
+	if err := engine.Execute(context.Background(), testIssue()); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	state, _ = LoadState(statePath)
+	if a := state.Tasks[0].Attempts[0]; a.Provider != "anthropic" || a.Model != "test-model" {
+		t.Errorf("attempt provider/model = %q/%q, want anthropic/test-model", a.Provider, a.Model)
+	}
+}
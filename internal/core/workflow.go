@@ -3,8 +3,10 @@ package core
 import (
 	"context"
 	"fmt"
+	"maps"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/rigdev/rig/internal/config"
@@ -14,22 +16,37 @@ import (
 
 // AIIssue is a minimal issue for AI analysis.
 type AIIssue struct {
-	Title string
-	Body  string
-	URL   string
+	Title    string
+	Body     string
+	URL      string
+	Comments []IssueComment
+}
+
+// IssueComment is a single comment in an issue's discussion thread.
+type IssueComment struct {
+	Author    string
+	Body      string
+	CreatedAt time.Time
+}
+
+// IssueThread is an issue's current description and discussion.
+type IssueThread struct {
+	Title    string
+	Body     string
+	Comments []IssueComment
 }
 
 // AIPlan holds the AI-generated plan for resolving an issue.
 type AIPlan struct {
-	Summary string
-	Steps   []string
+	Summary string   `json:"summary"`
+	Steps   []string `json:"steps"`
 }
 
 // AIFileChange represents a single file modification from AI.
 type AIFileChange struct {
-	Path    string
-	Content string
-	Action  string // "create", "modify", or "delete"
+	Path    string `json:"path"`
+	Content string `json:"content"`
+	Action  string `json:"action"` // "create", "modify", or "delete"
 }
 
 // AIProposedFix is the AI's structured response for deploy/infra failures.
@@ -69,6 +86,14 @@ type GitPullRequest struct {
 	Title  string
 }
 
+// DraftPRAdapter is implemented by GitAdapters that can open a pull request
+// as a draft and later update and publish it. Used by workflow.draft_pr.
+type DraftPRAdapter interface {
+	CreateDraftPR(ctx context.Context, base, head, title, body string) (*GitPullRequest, error)
+	UpdatePR(ctx context.Context, number int, body string) error
+	MarkReady(ctx context.Context, number int) error
+}
+
 // GitAdapter defines the interface for source code management operations.
 type GitAdapter interface {
 	CreateBranch(ctx context.Context, branchName string) error
@@ -84,6 +109,9 @@ type AdapterDeployResult struct {
 	Success  bool
 	Output   string
 	Duration time.Duration
+	// Artifacts names what was deployed, such as image tags or release
+	// IDs, for rolling back to it later.
+	Artifacts map[string]string
 }
 
 // DeployAdapterIface defines the interface for deploy operations.
@@ -123,30 +151,37 @@ func stepGenerate(ctx context.Context, aiAdapter AIAdapter, plan *AIPlan, repoFi
 	return changes, nil
 }
 
+// IssueThreadReader is an optional GitAdapter capability that fetches the
+// issue description and comments so planning sees the full discussion.
+type IssueThreadReader interface {
+	GetIssueThread(ctx context.Context, number int) (*IssueThread, error)
+}
+
 // CommitSHAResolver returns the current HEAD commit SHA. Implemented by GitAdapter.
 type CommitSHAResolver interface {
 	GetHeadSHA(ctx context.Context) (string, error)
 }
 
-// stepCommit creates a branch, commits, and pushes changes.
-func stepCommit(ctx context.Context, gitAdapter GitAdapter, branch string, changes []AIFileChange, issueTitle string) (string, error) {
+// stepCommit creates a branch and commits and pushes each group of changes
+// in order.
+func stepCommit(ctx context.Context, gitAdapter GitAdapter, branch string, groups []commitGroup) (string, error) {
 	if err := gitAdapter.CreateBranch(ctx, branch); err != nil {
 		return "", fmt.Errorf("create branch: %w", err)
 	}
 
-	// Convert AI file changes to git file changes.
-	gitChanges := make([]GitFileChange, len(changes))
-	for i, c := range changes {
-		gitChanges[i] = GitFileChange{
-			Path:    c.Path,
-			Content: c.Content,
-			Action:  c.Action,
+	for _, group := range groups {
+		// Convert AI file changes to git file changes.
+		gitChanges := make([]GitFileChange, len(group.Changes))
+		for i, c := range group.Changes {
+			gitChanges[i] = GitFileChange{
+				Path:    c.Path,
+				Content: c.Content,
+				Action:  c.Action,
+			}
+		}
+		if err := gitAdapter.CommitAndPush(ctx, gitChanges, group.Message); err != nil {
+			return "", fmt.Errorf("commit and push: %w", err)
 		}
-	}
-
-	commitMsg := fmt.Sprintf("rig: auto-fix %s", issueTitle)
-	if err := gitAdapter.CommitAndPush(ctx, gitChanges, commitMsg); err != nil {
-		return "", fmt.Errorf("commit and push: %w", err)
 	}
 
 	// Retrieve actual commit SHA if the adapter supports it.
@@ -172,43 +207,156 @@ func stepDeploy(ctx context.Context, deployAdapter DeployAdapterIface, vars map[
 	}
 
 	return &DeployResult{
-		Status:   status,
-		Duration: result.Duration,
-		Output:   result.Output,
+		Status:    status,
+		Duration:  result.Duration,
+		Output:    result.Output,
+		Vars:      maps.Clone(vars),
+		Artifacts: result.Artifacts,
 	}, nil
 }
 
-// stepTest runs all test runners and returns combined results.
-func stepTest(ctx context.Context, runners []TestRunnerIface, testConfigs []config.TestConfig, changedFiles []string, vars map[string]string) ([]TestResult, bool) {
-	var results []TestResult
-	allPassed := true
+// testRunnerGracePeriod is how long stepTest waits after a runner's deadline
+// for the runner to report its own timeout before synthesizing one.
+const testRunnerGracePeriod = 5 * time.Second
+
+// testRunOptions controls how stepTest schedules runners.
+type testRunOptions struct {
+	concurrency int           // max runners in flight; <= 1 runs sequentially
+	timeout     time.Duration // per-runner default when the test sets none
+	reruns      int           // bare re-runs of failing runners (workflow.retry_policy)
+	backoff     time.Duration // wait before the first re-run, doubling
+	maxBackoff  time.Duration // cap of the wait; 0 = none
+}
 
-	for i, runner := range runners {
+// stepTest runs all selected test runners with at most opts.concurrency in
+// flight and returns their results in configuration order. Failing runners
+// are then re-run up to opts.reruns times; see rerunFailedTests.
+func stepTest(ctx context.Context, runners []TestRunnerIface, testConfigs []config.TestConfig, changedFiles []string, vars map[string]string, opts testRunOptions) ([]TestResult, bool) {
+	var selected []int
+	for i := range runners {
 		if i < len(testConfigs) && !shouldRunTestForChanges(testConfigs[i], changedFiles) {
 			continue
 		}
+		selected = append(selected, i)
+	}
 
-		result, err := runner.Run(ctx, vars)
-		if err != nil {
-			results = append(results, TestResult{
-				Name:     "unknown",
-				Type:     "command",
-				Passed:   false,
-				Output:   fmt.Sprintf("runner error: %v", err),
-				Duration: 0,
-			})
-			allPassed = false
-			continue
+	workers := opts.concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(selected) {
+		workers = len(selected)
+	}
+
+	runVars := make(map[string]string, len(vars)+1)
+	for k, v := range vars {
+		runVars[k] = v
+	}
+	runVars["CHANGED_FILES"] = strings.Join(changedFiles, " ")
+
+	results := make([]TestResult, len(selected))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				idx := selected[j]
+				var testCfg config.TestConfig
+				if idx < len(testConfigs) {
+					testCfg = testConfigs[idx]
+				}
+				results[j] = runTestRunner(ctx, runners[idx], testCfg, runVars, opts.timeout)
+			}
+		}()
+	}
+	for j := range selected {
+		jobs <- j
+	}
+	close(jobs)
+	wg.Wait()
+
+	rerunFailedTests(ctx, results, opts, func(j int) TestResult {
+		idx := selected[j]
+		var testCfg config.TestConfig
+		if idx < len(testConfigs) {
+			testCfg = testConfigs[idx]
 		}
-		results = append(results, *result)
-		if !result.Passed {
+		return runTestRunner(ctx, runners[idx], testCfg, runVars, opts.timeout)
+	})
+
+	allPassed := true
+	for _, r := range results {
+		if !r.Passed {
 			allPassed = false
 		}
 	}
-
 	return results, allPassed
 }
 
+// runTestRunner runs a single runner under its timeout (the test's own, or
+// defaultTimeout). A runner that ignores cancellation is abandoned after a
+// grace period and reported as timed out.
+func runTestRunner(ctx context.Context, runner TestRunnerIface, testCfg config.TestConfig, vars map[string]string, defaultTimeout time.Duration) TestResult {
+	timeout := testCfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	name, testType := testCfg.Name, testCfg.Type
+	if name == "" {
+		name = "unknown"
+	}
+	if testType == "" {
+		testType = "command"
+	}
+
+	type outcome struct {
+		result *TestResult
+		err    error
+	}
+	done := make(chan outcome, 1)
+	start := time.Now()
+	go func() {
+		result, err := runner.Run(ctx, vars)
+		done <- outcome{result, err}
+	}()
+
+	var out outcome
+	select {
+	case out = <-done:
+	case <-ctx.Done():
+		select {
+		case out = <-done:
+		case <-time.After(testRunnerGracePeriod):
+			return TestResult{
+				Name:     name,
+				Type:     testType,
+				Passed:   false,
+				Output:   fmt.Sprintf("runner did not finish: %v after %s", ctx.Err(), time.Since(start).Round(time.Millisecond)),
+				Duration: time.Since(start),
+			}
+		}
+	}
+
+	if out.err != nil {
+		return TestResult{
+			Name:     name,
+			Type:     testType,
+			Passed:   false,
+			Output:   fmt.Sprintf("runner error: %v", out.err),
+			Duration: 0,
+		}
+	}
+	return *out.result
+}
+
 func shouldRunTestForChanges(testCfg config.TestConfig, changedFiles []string) bool {
 	affectedPaths := testCfg.AffectedPaths
 	if len(affectedPaths) == 0 {
@@ -244,8 +392,7 @@ func shouldRunTestForChanges(testCfg config.TestConfig, changedFiles []string) b
 }
 
 // stepCreatePR creates a pull request for the task.
-func stepCreatePR(ctx context.Context, gitAdapter GitAdapter, baseBranch, branch, issueTitle string, attempt *Attempt) (*PullRequest, error) {
-	body := buildPRBody(attempt)
+func stepCreatePR(ctx context.Context, gitAdapter GitAdapter, baseBranch, branch, issueTitle, body string) (*PullRequest, error) {
 	pr, err := gitAdapter.CreatePR(ctx, baseBranch, branch, fmt.Sprintf("rig: %s", issueTitle), body)
 	if err != nil {
 		return nil, fmt.Errorf("create PR: %w", err)
@@ -256,48 +403,21 @@ func stepCreatePR(ctx context.Context, gitAdapter GitAdapter, baseBranch, branch
 	}, nil
 }
 
-// stepRollback reverses a deployment.
-func stepRollback(ctx context.Context, deployAdapter DeployAdapterIface) error {
+// stepRollback reverses a deployment, restoring snap when there is one and
+// the adapter can.
+func stepRollback(ctx context.Context, deployAdapter DeployAdapterIface, snap *DeploySnapshot) error {
+	if r, ok := deployAdapter.(SnapshotRollbacker); ok && snap != nil {
+		if err := r.RollbackTo(ctx, snap); err != nil {
+			return fmt.Errorf("rollback to snapshot: %w", err)
+		}
+		return nil
+	}
 	if err := deployAdapter.Rollback(ctx); err != nil {
 		return fmt.Errorf("rollback: %w", err)
 	}
 	return nil
 }
 
-// buildPRBody generates the PR description from attempt details.
-func buildPRBody(attempt *Attempt) string {
-	var b strings.Builder
-	b.WriteString("## Automated Fix by Rig\n\n")
-
-	if attempt == nil {
-		b.WriteString("_No attempt details available._\n")
-		return b.String()
-	}
-
-	b.WriteString(fmt.Sprintf("**Plan:** %s\n\n", attempt.Plan))
-
-	if len(attempt.FilesChanged) > 0 {
-		b.WriteString("### Files Changed\n")
-		for _, f := range attempt.FilesChanged {
-			b.WriteString(fmt.Sprintf("- `%s`\n", f))
-		}
-		b.WriteString("\n")
-	}
-
-	if len(attempt.Tests) > 0 {
-		b.WriteString("### Test Results\n")
-		for _, t := range attempt.Tests {
-			status := "PASS"
-			if !t.Passed {
-				status = "FAIL"
-			}
-			b.WriteString(fmt.Sprintf("- %s %s (%s)\n", status, t.Name, t.Duration))
-		}
-	}
-
-	return b.String()
-}
-
 // collectTestOutput gathers all test outputs into a single log string.
 func collectTestOutput(results []TestResult) string {
 	var parts []string
@@ -306,11 +426,44 @@ func collectTestOutput(results []TestResult) string {
 		if !r.Passed {
 			status = "FAIL"
 		}
-		parts = append(parts, fmt.Sprintf("[%s] %s:\n%s", status, r.Name, r.Output))
+		output := r.Output
+		if failed := failedCaseOutput(r); failed != "" {
+			output = failed
+		}
+		part := fmt.Sprintf("[%s] %s:\n%s", status, r.Name, output)
+		for _, a := range r.Artifacts {
+			if a.Content != "" {
+				part += fmt.Sprintf("\n--- %s %s ---\n%s", a.Kind, a.Path, a.Content)
+			} else {
+				part += fmt.Sprintf("\n--- %s: %s", a.Kind, a.Path)
+			}
+		}
+		parts = append(parts, part)
 	}
 	return strings.Join(parts, "\n\n")
 }
 
+// failedCaseOutput renders only the failing cases of a failed result so the
+// AI sees the relevant messages instead of the full log. It returns "" when
+// the result passed or carries no failing cases.
+func failedCaseOutput(r TestResult) string {
+	if r.Passed {
+		return ""
+	}
+	var parts []string
+	for _, c := range r.Cases {
+		if c.Status != "fail" {
+			continue
+		}
+		name := c.Name
+		if c.Suite != "" {
+			name = c.Suite + "/" + c.Name
+		}
+		parts = append(parts, fmt.Sprintf("--- FAIL: %s (%s)\n%s", name, c.Duration, strings.TrimRight(c.Message, "\n")))
+	}
+	return strings.Join(parts, "\n")
+}
+
 // newAttempt creates a new Attempt struct.
 func newAttempt(number int) Attempt {
 	return Attempt{
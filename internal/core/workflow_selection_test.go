@@ -2,6 +2,8 @@ package core
 
 import (
 	"context"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -28,7 +30,7 @@ func TestStepTest_AffectedPathsFiltersRunners(t *testing.T) {
 		{Name: "web", Type: "command", AffectedPaths: []string{"web/"}},
 	}
 
-	results, allPassed := stepTest(context.Background(), runners, testCfgs, []string{"api/handler.go"}, map[string]string{})
+	results, allPassed := stepTest(context.Background(), runners, testCfgs, []string{"api/handler.go"}, map[string]string{}, testRunOptions{})
 	if !allPassed {
 		t.Fatal("expected allPassed=true")
 	}
@@ -48,7 +50,7 @@ func TestStepTest_AffectedPathsGlobMatch(t *testing.T) {
 	runners := []TestRunnerIface{runner}
 	testCfgs := []config.TestConfig{{Name: "env-test", Type: "command", AffectedPaths: []string{"**/*.env"}}}
 
-	results, allPassed := stepTest(context.Background(), runners, testCfgs, []string{"configs/prod.env"}, map[string]string{})
+	results, allPassed := stepTest(context.Background(), runners, testCfgs, []string{"configs/prod.env"}, map[string]string{}, testRunOptions{})
 	if !allPassed {
 		t.Fatal("expected allPassed=true")
 	}
@@ -65,7 +67,7 @@ func TestStepTest_NoAffectedPathsRunsAll(t *testing.T) {
 	runners := []TestRunnerIface{runner}
 	testCfgs := []config.TestConfig{{Name: "unit", Type: "command"}}
 
-	results, allPassed := stepTest(context.Background(), runners, testCfgs, nil, map[string]string{})
+	results, allPassed := stepTest(context.Background(), runners, testCfgs, nil, map[string]string{}, testRunOptions{})
 	if !allPassed {
 		t.Fatal("expected allPassed=true")
 	}
@@ -76,3 +78,82 @@ func TestStepTest_NoAffectedPathsRunsAll(t *testing.T) {
 		t.Fatalf("expected runner to run, got %d", runner.called)
 	}
 }
+
+// barrierRunner blocks until every runner sharing the barrier has started,
+// so it only completes when runners execute concurrently.
+type barrierRunner struct {
+	name    string
+	started *sync.WaitGroup
+	delay   time.Duration
+}
+
+func (r *barrierRunner) Run(ctx context.Context, vars map[string]string) (*TestResult, error) {
+	r.started.Done()
+	r.started.Wait()
+	time.Sleep(r.delay)
+	return &TestResult{Name: r.name, Type: "command", Passed: r.name != "b"}, nil
+}
+
+func TestStepTest_ParallelKeepsConfigOrder(t *testing.T) {
+	var started sync.WaitGroup
+	started.Add(3)
+	runners := []TestRunnerIface{
+		&barrierRunner{name: "a", started: &started, delay: 30 * time.Millisecond},
+		&barrierRunner{name: "b", started: &started, delay: 10 * time.Millisecond},
+		&barrierRunner{name: "c", started: &started},
+	}
+
+	done := make(chan struct{})
+	var results []TestResult
+	var allPassed bool
+	go func() {
+		results, allPassed = stepTest(context.Background(), runners, nil, nil, nil, testRunOptions{concurrency: 3})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("runners did not run concurrently")
+	}
+
+	if allPassed {
+		t.Fatal("expected failure from runner b")
+	}
+	var names []string
+	for _, r := range results {
+		names = append(names, r.Name)
+	}
+	if strings.Join(names, ",") != "a,b,c" {
+		t.Errorf("expected results in config order, got %v", names)
+	}
+}
+
+type blockingRunner struct{}
+
+func (blockingRunner) Run(ctx context.Context, vars map[string]string) (*TestResult, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestStepTest_PerRunnerTimeout(t *testing.T) {
+	runners := []TestRunnerIface{blockingRunner{}, &selectionRunner{name: "fast"}}
+	testCfgs := []config.TestConfig{
+		{Name: "slow", Type: "command"},
+		{Name: "fast", Type: "command", Timeout: time.Minute},
+	}
+
+	start := time.Now()
+	results, allPassed := stepTest(context.Background(), runners, testCfgs, nil, nil, testRunOptions{concurrency: 2, timeout: 50 * time.Millisecond})
+	if time.Since(start) > 5*time.Second {
+		t.Fatal("default per-runner timeout was not applied")
+	}
+	if allPassed || len(results) != 2 {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	if results[0].Name != "slow" || results[0].Passed || !strings.Contains(results[0].Output, "deadline exceeded") {
+		t.Errorf("expected slow runner to time out, got %+v", results[0])
+	}
+	if !results[1].Passed {
+		t.Errorf("expected fast runner to pass, got %+v", results[1])
+	}
+}
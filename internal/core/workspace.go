@@ -0,0 +1,27 @@
+package core
+
+// TaskWorkspaces is an optional GitAdapter capability of adapters that give
+// every task its own checkout. UseTaskWorkspace points the calls that
+// follow (clone, branch, commit, cleanup) at the checkout of taskID.
+type TaskWorkspaces interface {
+	UseTaskWorkspace(taskID string)
+}
+
+// bindWorkspace points the git adapter at the checkout of taskID, when it
+// keeps one per task.
+func (e *Engine) bindWorkspace(taskID string) {
+	if tw, ok := e.git.(TaskWorkspaces); ok {
+		tw.UseTaskWorkspace(taskID)
+	}
+}
+
+// NeedsWorkspace reports whether the task taskID may still use its
+// checkout: it is running or waiting for approval. Checkouts of finished
+// and unknown tasks can be removed.
+func (s *State) NeedsWorkspace(taskID string) bool {
+	t := s.GetTaskByID(taskID)
+	if t == nil {
+		return false
+	}
+	return t.Status == PhaseAwaitingApproval || !inactivePhases[t.Status]
+}
@@ -0,0 +1,39 @@
+package core
+
+import (
+	"context"
+	"testing"
+)
+
+// taskWorkspaceGit is a mockGit that records the tasks it is pointed at.
+type taskWorkspaceGit struct {
+	mockGit
+	tasks []string
+}
+
+func (g *taskWorkspaceGit) UseTaskWorkspace(taskID string) { g.tasks = append(g.tasks, taskID) }
+
+func TestEngine_BindsTaskWorkspace(t *testing.T) {
+	git := &taskWorkspaceGit{}
+	statePath := tempStatePath(t)
+	engine := NewEngine(testConfig(), git, &mockAI{}, &mockDeploy{deploySuccess: true}, nil, nil, statePath)
+
+	if err := engine.Execute(context.Background(), testIssue()); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	state, err := LoadState(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Tasks) != 1 || len(git.tasks) != 1 || git.tasks[0] != state.Tasks[0].ID {
+		t.Fatalf("bound tasks = %v, want the executed task", git.tasks)
+	}
+	if state.NeedsWorkspace(state.Tasks[0].ID) || state.NeedsWorkspace("task-unknown") {
+		t.Error("a completed or unknown task should not need its workspace")
+	}
+
+	state.Tasks[0].Status = PhaseAwaitingApproval
+	if !state.NeedsWorkspace(state.Tasks[0].ID) {
+		t.Error("a task awaiting approval needs its workspace")
+	}
+}
@@ -0,0 +1,58 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+)
+
+// CloudEventsTypePrefix is put before a rig event type to make the
+// CloudEvents type, reverse-DNS style: dev.rig.task.completed.
+const CloudEventsTypePrefix = "dev.rig."
+
+// encode returns event, whose rig JSON is body, in the format of sink s.
+func encode(s config.EventSinkConfig, event *core.TaskEvent, body []byte) (*message, error) {
+	if s.Format != "cloudevents" {
+		return &message{body: body, contentType: "application/json"}, nil
+	}
+	attrs := cloudEventAttributes(s, event)
+	if s.Mode == "binary" {
+		// The data stays the body; its content type is the message's.
+		headers := make(map[string]string, len(attrs))
+		for name, value := range attrs {
+			headers["ce-"+name] = value
+		}
+		return &message{body: body, contentType: "application/json", headers: headers}, nil
+	}
+	envelope := make(map[string]any, len(attrs)+2)
+	for name, value := range attrs {
+		envelope[name] = value
+	}
+	envelope["datacontenttype"] = "application/json"
+	envelope["data"] = json.RawMessage(body)
+	structured, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, fmt.Errorf("marshal cloudevent: %w", err)
+	}
+	return &message{body: structured, contentType: "application/cloudevents+json"}, nil
+}
+
+// cloudEventAttributes returns the CloudEvents 1.0 context attributes of
+// event. The subject is the task, so subscribers can filter on it.
+func cloudEventAttributes(s config.EventSinkConfig, event *core.TaskEvent) map[string]string {
+	source := s.Source
+	if source == "" {
+		source = "rig"
+	}
+	return map[string]string{
+		"specversion": "1.0",
+		"id":          event.ID,
+		"source":      source,
+		"type":        CloudEventsTypePrefix + event.Type,
+		"subject":     event.Task.ID,
+		"time":        event.Time.Format(time.RFC3339Nano),
+	}
+}
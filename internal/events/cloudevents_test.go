@@ -0,0 +1,104 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+)
+
+// captureServer records the last request and body it got.
+func captureServer(t *testing.T) (*httptest.Server, *http.Header, *[]byte) {
+	var header http.Header
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, _ = io.ReadAll(r.Body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &header, &body
+}
+
+func TestCloudEventsStructured(t *testing.T) {
+	srv, header, body := captureServer(t)
+	p, _ := New([]config.EventSinkConfig{{Type: "webhook", URL: srv.URL, Format: "cloudevents", Source: "/rig/prod", Secret: "s"}})
+	event := testEvent()
+	if err := p.Publish(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+
+	if ct := header.Get("Content-Type"); ct != "application/cloudevents+json" {
+		t.Errorf("Content-Type = %s", ct)
+	}
+	if header.Get("X-Rig-Signature-256") != Sign("s", *body) {
+		t.Error("the envelope is not what was signed")
+	}
+	var ce struct {
+		SpecVersion     string         `json:"specversion"`
+		ID              string         `json:"id"`
+		Source          string         `json:"source"`
+		Type            string         `json:"type"`
+		Subject         string         `json:"subject"`
+		Time            string         `json:"time"`
+		DataContentType string         `json:"datacontenttype"`
+		Data            core.TaskEvent `json:"data"`
+	}
+	if err := json.Unmarshal(*body, &ce); err != nil {
+		t.Fatalf("body %s: %v", *body, err)
+	}
+	if ce.SpecVersion != "1.0" || ce.ID != event.ID || ce.Source != "/rig/prod" || ce.Type != "dev.rig.task.phase_changed" ||
+		ce.Subject != "task-001" || ce.Time == "" || ce.DataContentType != "application/json" {
+		t.Errorf("attributes %+v", ce)
+	}
+	if ce.Data.ID != event.ID || ce.Data.Task.Issue.ID != "42" {
+		t.Errorf("data %+v", ce.Data)
+	}
+}
+
+func TestCloudEventsBinary(t *testing.T) {
+	srv, header, body := captureServer(t)
+	p, _ := New([]config.EventSinkConfig{{Type: "webhook", URL: srv.URL, Format: "cloudevents", Mode: "binary"}})
+	event := testEvent()
+	if err := p.Publish(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{
+		"Content-Type":   "application/json",
+		"Ce-Specversion": "1.0",
+		"Ce-Id":          event.ID,
+		"Ce-Source":      "rig",
+		"Ce-Type":        "dev.rig.task.phase_changed",
+		"Ce-Subject":     "task-001",
+	} {
+		if got := header.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	var data core.TaskEvent
+	if err := json.Unmarshal(*body, &data); err != nil || data.ID != event.ID {
+		t.Errorf("body %s is not the rig event (%v)", *body, err)
+	}
+}
+
+func TestPublishFiltersByType(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { calls++ }))
+	defer srv.Close()
+
+	p, _ := New([]config.EventSinkConfig{{Type: "webhook", URL: srv.URL, Types: []string{core.EventTaskCompleted, core.EventTaskFailed}}})
+	task := &core.Task{ID: "task-001"}
+	for _, typ := range []string{core.EventTaskCreated, core.EventTaskPhaseChanged, core.EventTaskFailed} {
+		if err := p.Publish(context.Background(), core.NewTaskEvent(typ, task)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("sink got %d events, want only task.failed", calls)
+	}
+}
@@ -0,0 +1,259 @@
+// Package events publishes task lifecycle events to the sinks of the
+// events config: HTTP webhooks, NATS subjects and Kafka topics, the last
+// through a Kafka REST proxy. Each sink takes rig's own JSON or CloudEvents
+// 1.0, and may take only some event types.
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+)
+
+// sendTimeout bounds the delivery of one event to one sink.
+const sendTimeout = 10 * time.Second
+
+// sink delivers encoded events to one destination.
+type sink interface {
+	send(ctx context.Context, event *core.TaskEvent, msg *message) error
+	close()
+}
+
+// message is an event encoded for one sink.
+type message struct {
+	body        []byte
+	contentType string
+	// headers are the CloudEvents attributes in binary mode.
+	headers map[string]string
+}
+
+// target is a sink with its config.
+type target struct {
+	cfg  config.EventSinkConfig
+	sink sink
+}
+
+// Publisher sends every event to all its sinks that take its type, in
+// order, one after the other. It is safe for concurrent use.
+type Publisher struct {
+	targets []target
+}
+
+var _ core.EventPublisher = (*Publisher)(nil)
+
+// New creates a publisher for sinks. NATS connections are made on first
+// use and kept until Close.
+func New(sinks []config.EventSinkConfig) (*Publisher, error) {
+	p := &Publisher{}
+	client := &http.Client{Timeout: sendTimeout}
+	for i, s := range sinks {
+		var sk sink
+		switch s.Type {
+		case "webhook":
+			sk = &webhookSink{client: client, cfg: s}
+		case "nats":
+			sk = &natsSink{cfg: s}
+		case "kafka":
+			sk = &kafkaSink{client: client, cfg: s}
+		default:
+			return nil, fmt.Errorf("events[%d]: unknown sink type %q", i, s.Type)
+		}
+		p.targets = append(p.targets, target{cfg: s, sink: sk})
+	}
+	return p, nil
+}
+
+// Publish sends event to every sink whose types include it. A sink that
+// fails does not stop the others; the errors are joined.
+func (p *Publisher) Publish(ctx context.Context, event *core.TaskEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
+	}
+	var errs []error
+	for _, t := range p.targets {
+		if len(t.cfg.Types) > 0 && !slices.Contains(t.cfg.Types, event.Type) {
+			continue
+		}
+		msg, err := encode(t.cfg, event, body)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		sendCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), sendTimeout)
+		if err := t.sink.send(sendCtx, event, msg); err != nil {
+			errs = append(errs, err)
+		}
+		cancel()
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes the sinks' connections.
+func (p *Publisher) Close() {
+	for _, t := range p.targets {
+		t.sink.close()
+	}
+}
+
+// webhookSink POSTs events as JSON.
+type webhookSink struct {
+	client *http.Client
+	cfg    config.EventSinkConfig
+}
+
+// Sign returns the X-Rig-Signature-256 value of body signed with secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *webhookSink) send(ctx context.Context, event *core.TaskEvent, msg *message) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.URL, bytes.NewReader(msg.body))
+	if err != nil {
+		return fmt.Errorf("event webhook: %w", err)
+	}
+	for name, value := range s.cfg.Headers {
+		req.Header.Set(name, value)
+	}
+	for name, value := range msg.headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Content-Type", msg.contentType)
+	req.Header.Set("User-Agent", "rig-events")
+	req.Header.Set("X-Rig-Event", event.Type)
+	req.Header.Set("X-Rig-Delivery", event.ID)
+	if s.cfg.Secret != "" {
+		req.Header.Set("X-Rig-Signature-256", Sign(s.cfg.Secret, msg.body))
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("event webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("event webhook %s returned %s", redact(s.cfg.URL), resp.Status)
+	}
+	return nil
+}
+
+func (s *webhookSink) close() {}
+
+// natsSink publishes events to a subject. JetStream streams that store the
+// subject keep them for consumers that are offline.
+type natsSink struct {
+	cfg  config.EventSinkConfig
+	mu   sync.Mutex
+	conn *nats.Conn
+}
+
+func (s *natsSink) send(ctx context.Context, event *core.TaskEvent, msg *message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil || s.conn.IsClosed() {
+		conn, err := nats.Connect(s.cfg.URL, nats.Name("rig-events"))
+		if err != nil {
+			return fmt.Errorf("event nats: connect: %w", err)
+		}
+		s.conn = conn
+	}
+	m := &nats.Msg{Subject: s.cfg.Topic, Data: msg.body, Header: nats.Header{}}
+	for name, value := range msg.headers {
+		m.Header.Set(name, value)
+	}
+	m.Header.Set("Content-Type", msg.contentType)
+	m.Header.Set("Rig-Event", event.Type)
+	// JetStream drops a message whose id it has already stored.
+	m.Header.Set(nats.MsgIdHdr, event.ID)
+	if err := s.conn.PublishMsg(m); err != nil {
+		return fmt.Errorf("event nats: publish to %s: %w", s.cfg.Topic, err)
+	}
+	if err := s.conn.FlushWithContext(ctx); err != nil {
+		return fmt.Errorf("event nats: flush: %w", err)
+	}
+	return nil
+}
+
+func (s *natsSink) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		s.conn.Close()
+	}
+}
+
+// kafkaSink produces events to a topic through a Kafka REST proxy, keyed
+// by task so one task's events stay in order on one partition.
+type kafkaSink struct {
+	client *http.Client
+	cfg    config.EventSinkConfig
+}
+
+func (s *kafkaSink) send(ctx context.Context, event *core.TaskEvent, msg *message) error {
+	records, err := json.Marshal(map[string]any{
+		"records": []map[string]any{{"key": event.Task.ID, "value": json.RawMessage(msg.body)}},
+	})
+	if err != nil {
+		return err
+	}
+	target := strings.TrimRight(s.cfg.URL, "/") + "/topics/" + url.PathEscape(s.cfg.Topic)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(records))
+	if err != nil {
+		return fmt.Errorf("event kafka: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("event kafka: %w", err)
+	}
+	defer resp.Body.Close()
+	// The proxy reports per-record failures with a 200 status.
+	var out struct {
+		Offsets []struct {
+			ErrorCode *int   `json:"error_code"`
+			Error     string `json:"error"`
+		} `json:"offsets"`
+		Message string `json:"message"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&out)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("event kafka: REST proxy returned %s: %s", resp.Status, out.Message)
+	}
+	for _, o := range out.Offsets {
+		if o.ErrorCode != nil {
+			return fmt.Errorf("event kafka: produce to %s: %s", s.cfg.Topic, o.Error)
+		}
+	}
+	return nil
+}
+
+func (s *kafkaSink) close() {}
+
+// redact drops the user info and query of a URL for error messages.
+func redact(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "(invalid URL)"
+	}
+	u.User, u.RawQuery = nil, ""
+	return u.String()
+}
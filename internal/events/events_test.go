@@ -0,0 +1,104 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+)
+
+func testEvent() *core.TaskEvent {
+	task := &core.Task{ID: "task-001", Status: core.PhaseTesting, Issue: core.Issue{Repo: "acme/app", ID: "42", Title: "Fix login"}}
+	return core.NewTaskEvent(core.EventTaskPhaseChanged, task)
+}
+
+func TestWebhookSink(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	p, err := New([]config.EventSinkConfig{{Type: "webhook", URL: srv.URL, Secret: "s3cret", Headers: map[string]string{"Authorization": "Bearer t"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := testEvent()
+	if err := p.Publish(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded core.TaskEvent
+	if err := json.Unmarshal(body, &decoded); err != nil || decoded.ID != event.ID || decoded.Task.Issue.ID != "42" {
+		t.Errorf("body %s (%v)", body, err)
+	}
+	if got.Header.Get("X-Rig-Event") != "task.phase_changed" || got.Header.Get("X-Rig-Delivery") != event.ID || got.Header.Get("Authorization") != "Bearer t" {
+		t.Errorf("headers %v", got.Header)
+	}
+	if sig := got.Header.Get("X-Rig-Signature-256"); sig != Sign("s3cret", body) || !strings.HasPrefix(sig, "sha256=") {
+		t.Errorf("signature %q", sig)
+	}
+}
+
+func TestKafkaSink(t *testing.T) {
+	var path, contentType string
+	var records struct {
+		Records []struct {
+			Key   string         `json:"key"`
+			Value core.TaskEvent `json:"value"`
+		} `json:"records"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, contentType = r.URL.Path, r.Header.Get("Content-Type")
+		json.NewDecoder(r.Body).Decode(&records)
+		if r.URL.Path == "/topics/missing" {
+			w.Write([]byte(`{"offsets":[{"partition":null,"offset":null,"error_code":40403,"error":"Topic not found."}]}`))
+			return
+		}
+		w.Write([]byte(`{"offsets":[{"partition":0,"offset":12}]}`))
+	}))
+	defer srv.Close()
+
+	p, _ := New([]config.EventSinkConfig{{Type: "kafka", URL: srv.URL + "/", Topic: "rig-events"}})
+	if err := p.Publish(context.Background(), testEvent()); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/topics/rig-events" || contentType != "application/vnd.kafka.json.v2+json" {
+		t.Errorf("request to %s as %s", path, contentType)
+	}
+	if len(records.Records) != 1 || records.Records[0].Key != "task-001" || records.Records[0].Value.Type != core.EventTaskPhaseChanged {
+		t.Errorf("records %+v", records)
+	}
+
+	p, _ = New([]config.EventSinkConfig{{Type: "kafka", URL: srv.URL, Topic: "missing"}})
+	if err := p.Publish(context.Background(), testEvent()); err == nil || !strings.Contains(err.Error(), "Topic not found") {
+		t.Errorf("expected the record error, got %v", err)
+	}
+}
+
+func TestPublishJoinsSinkErrors(t *testing.T) {
+	calls := 0
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { calls++ }))
+	defer ok.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+
+	p, _ := New([]config.EventSinkConfig{{Type: "webhook", URL: failing.URL + "?token=secret"}, {Type: "webhook", URL: ok.URL}})
+	err := p.Publish(context.Background(), testEvent())
+	if err == nil || !strings.Contains(err.Error(), "502") || strings.Contains(err.Error(), "secret") {
+		t.Errorf("expected a redacted 502 error, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("the second sink got %d events, want 1", calls)
+	}
+}
@@ -0,0 +1,123 @@
+// Package fixmemory keeps the changes that fixed failing tasks in the rig
+// database and finds the ones that fixed failures similar to a new one, so
+// failure analysis can reuse them (ai.fix_memory).
+package fixmemory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/storage"
+)
+
+const (
+	// DefaultHints is how many past fixes Similar returns by default.
+	DefaultHints = 3
+	// DefaultMinSimilarity is the default least similarity of a hint.
+	DefaultMinSimilarity = 0.5
+)
+
+// Store is the fix memory of one repository.
+type Store struct {
+	db            *storage.DB
+	repo          string
+	hints         int
+	minSimilarity float64
+}
+
+// New returns the fix memory of repo in db. Similar returns up to hints
+// fixes (0 means DefaultHints) at least minSimilarity similar (0 means
+// DefaultMinSimilarity).
+func New(db *storage.DB, repo string, hints int, minSimilarity float64) *Store {
+	if hints <= 0 {
+		hints = DefaultHints
+	}
+	if minSimilarity <= 0 {
+		minSimilarity = DefaultMinSimilarity
+	}
+	return &Store{db: db, repo: repo, hints: hints, minSimilarity: minSimilarity}
+}
+
+// Similar returns the fixes whose signature is most similar to signature,
+// best first; among equally similar ones, those that passed more often as
+// hints and then the newest come first.
+func (s *Store) Similar(ctx context.Context, signature string) ([]core.PastFix, error) {
+	fixes, err := s.db.ListFixes(s.repo)
+	if err != nil {
+		return nil, err
+	}
+	query := tokens(signature)
+
+	type scored struct {
+		fix   storage.Fix
+		score float64
+	}
+	var matches []scored
+	for _, f := range fixes {
+		if score := similarity(query, tokens(f.Signature)); score >= s.minSimilarity {
+			matches = append(matches, scored{f, score})
+		}
+	}
+	// ListFixes is newest first and the sort is stable.
+	slices.SortStableFunc(matches, func(a, b scored) int {
+		if c := cmp.Compare(b.score, a.score); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.fix.Successes, a.fix.Successes)
+	})
+
+	var hints []core.PastFix
+	seen := make(map[string]bool)
+	for _, m := range matches {
+		if len(hints) == s.hints {
+			break
+		}
+		// The same diff may be stored for several signatures.
+		if seen[m.fix.Diff] {
+			continue
+		}
+		seen[m.fix.Diff] = true
+		hints = append(hints, core.PastFix{ID: m.fix.ID, Failure: m.fix.Failure, Diff: m.fix.Diff, Similarity: m.score})
+	}
+	return hints, nil
+}
+
+// Remember stores diff as the fix of the failure with signature.
+func (s *Store) Remember(ctx context.Context, taskID, signature, failure, diff string) error {
+	return s.db.AddFix(storage.Fix{Repo: s.repo, Signature: signature, Failure: failure, Diff: diff, TaskID: taskID})
+}
+
+// RecordUse counts one retry given the fixes ids as hints.
+func (s *Store) RecordUse(ctx context.Context, ids []int64, passed bool) error {
+	return s.db.RecordFixUse(ids, passed)
+}
+
+// tokens returns the set of words of a signature.
+func tokens(signature string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.FieldsFunc(strings.ToLower(signature), func(r rune) bool {
+		return !(r == '<' || r == '>' || r == '_' || r == '.' || r == '/' ||
+			(r >= 'a' && r <= 'z') || (r >= '0' && r <= '9'))
+	}) {
+		set[w] = true
+	}
+	return set
+}
+
+// similarity is the Jaccard similarity of two word sets: the share of
+// their words they have in common.
+func similarity(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+	common := 0
+	for w := range a {
+		if b[w] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
@@ -0,0 +1,57 @@
+package fixmemory
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/rigdev/rig/internal/storage"
+)
+
+func TestSimilar(t *testing.T) {
+	db, err := storage.Open(filepath.Join(t.TempDir(), "rig.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	store := New(db, "o/r", 2, 0)
+	for _, f := range []struct{ signature, diff string }{
+		{"error: dial tcp <n>.<n>.<n>.<n>:<n>: connection refused", "retry the connection"},
+		{"error: dial tcp <n>.<n>.<n>.<n>:<n>: connection refused\nfail: TestDB", "wait for the database"},
+		{"panic: nil map assignment", "initialize the map"},
+	} {
+		if err := store.Remember(ctx, "task", f.signature, "logs", f.diff); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := New(db, "o/other", 0, 0).Remember(ctx, "task", "error: dial tcp <n>.<n>.<n>.<n>:<n>: connection refused", "logs", "other repo"); err != nil {
+		t.Fatal(err)
+	}
+
+	hints, err := store.Similar(ctx, "error: dial tcp <n>.<n>.<n>.<n>:<n>: connection refused")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hints) != 2 || hints[0].Diff != "retry the connection" || hints[0].Similarity != 1 || hints[1].Diff != "wait for the database" {
+		t.Fatalf("unexpected hints %+v", hints)
+	}
+
+	if hints, _ := store.Similar(ctx, "build failed: undefined: Foo"); len(hints) != 0 {
+		t.Errorf("dissimilar failure should get no hints, got %+v", hints)
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	a := tokens("error: connection refused")
+	if got := similarity(a, tokens("Error connection refused!")); got != 1 {
+		t.Errorf("similarity of the same words = %v, want 1", got)
+	}
+	if got := similarity(a, tokens("error: timeout")); got != 0.25 {
+		t.Errorf("similarity = %v, want 0.25", got)
+	}
+	if got := similarity(tokens(""), tokens("")); got != 0 {
+		t.Errorf("similarity of empty signatures = %v, want 0", got)
+	}
+}
@@ -0,0 +1,179 @@
+// Package format runs the workflow.format formatters on the generated files
+// in the task's workspace before they are committed: goimports or gofmt for
+// Go by default, and any command that formats files in place, such as
+// prettier or black.
+package format
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/shell"
+)
+
+// defaultTimeout bounds a formatter that sets no timeout.
+const defaultTimeout = time.Minute
+
+// maxStderr bounds the stderr quoted in a formatter error.
+const maxStderr = 2048
+
+// Runner formats files in the task's workspace.
+type Runner struct {
+	workspace  core.WorkspaceProvider
+	formatters []config.FormatterConfig
+}
+
+var _ core.Formatter = (*Runner)(nil)
+
+// New creates a Runner of cfg's formatters, or of the default Go one when
+// cfg has none. Files are formatted in the workspace of ws.
+func New(ws core.WorkspaceProvider, cfg config.FormatConfig) *Runner {
+	formatters := cfg.Formatters
+	if len(formatters) == 0 {
+		formatters = []config.FormatterConfig{goFormatter()}
+	}
+	return &Runner{workspace: ws, formatters: formatters}
+}
+
+// goFormatter is goimports, which also fixes imports, or gofmt when
+// goimports is not installed.
+func goFormatter() config.FormatterConfig {
+	if _, err := exec.LookPath("goimports"); err == nil {
+		return config.FormatterConfig{Name: "goimports", Files: []string{"*.go"}, Run: "goimports -w"}
+	}
+	return config.FormatterConfig{Name: "gofmt", Files: []string{"*.go"}, Run: "gofmt -w"}
+}
+
+// Format writes changes to the workspace, runs each formatter on the
+// changed files it matches and returns the changes with the files as the
+// formatters left them. A formatter that fails leaves its files as they
+// were; its error is returned with the changes.
+func (r *Runner) Format(ctx context.Context, changes []core.AIFileChange) ([]core.AIFileChange, error) {
+	workspace := ""
+	if r.workspace != nil {
+		workspace = r.workspace.GetWorkspace()
+	}
+	if workspace == "" {
+		return changes, nil
+	}
+
+	formatted := make([]core.AIFileChange, len(changes))
+	copy(formatted, changes)
+	var errs []error
+	for _, f := range r.formatters {
+		var matched []int
+		for i, c := range formatted {
+			if c.Action != "delete" && matches(f.Files, c.Path) {
+				matched = append(matched, i)
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+
+		files := make([]string, 0, len(matched))
+		for _, i := range matched {
+			if err := writeFile(workspace, formatted[i]); err != nil {
+				return changes, fmt.Errorf("write %s: %w", formatted[i].Path, err)
+			}
+			files = append(files, formatted[i].Path)
+		}
+		if err := run(ctx, workspace, f, files); err != nil {
+			errs = append(errs, err)
+			// Put back what the formatter may have half written.
+			for _, i := range matched {
+				if err := writeFile(workspace, formatted[i]); err != nil {
+					return changes, fmt.Errorf("write %s: %w", formatted[i].Path, err)
+				}
+			}
+			continue
+		}
+		for _, i := range matched {
+			content, err := os.ReadFile(filepath.Join(workspace, filepath.FromSlash(formatted[i].Path)))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: read %s: %w", f.Name, formatted[i].Path, err))
+				continue
+			}
+			formatted[i].Content = string(content)
+		}
+	}
+	return formatted, errors.Join(errs...)
+}
+
+// matches reports whether the repository path name matches one of
+// patterns: by its file name, or by the whole path for patterns with a
+// slash.
+func matches(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		subject := path.Base(name)
+		if strings.Contains(pattern, "/") {
+			pattern, subject = strings.TrimPrefix(pattern, "/"), name
+		}
+		if ok, _ := path.Match(pattern, subject); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// writeFile writes the content of c to its file in workspace.
+func writeFile(workspace string, c core.AIFileChange) error {
+	full := filepath.Join(workspace, filepath.FromSlash(c.Path))
+	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(full, []byte(c.Content), 0o644)
+}
+
+// run runs the formatter f on files in workspace.
+func run(ctx context.Context, workspace string, f config.FormatterConfig, files []string) error {
+	timeout := f.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	line := f.Run
+	for _, file := range files {
+		line += " " + shell.Quote(f.Shell, filepath.FromSlash(file))
+	}
+	cmd, err := shell.Command(ctx, f.Shell, line)
+	if err != nil {
+		return fmt.Errorf("%s: %w", f.Name, err)
+	}
+	cmd.Cancel = func() error { return cmd.Process.Kill() }
+	cmd.WaitDelay = 3 * time.Second
+	cmd.Dir = workspace
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("%s timed out after %s", f.Name, timeout)
+		}
+		return fmt.Errorf("%s: %w%s", f.Name, err, tail(stderr.String()))
+	}
+	return nil
+}
+
+// tail returns the end of stderr to quote in an error, or "".
+func tail(stderr string) string {
+	stderr = strings.TrimSpace(stderr)
+	if stderr == "" {
+		return ""
+	}
+	if len(stderr) > maxStderr {
+		stderr = "..." + stderr[len(stderr)-maxStderr:]
+	}
+	return ": " + stderr
+}
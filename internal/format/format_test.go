@@ -0,0 +1,91 @@
+package format
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+)
+
+// workspace is a core.WorkspaceProvider of a fixed directory.
+type workspace string
+
+func (w workspace) GetWorkspace() string { return string(w) }
+
+func TestFormat(t *testing.T) {
+	dir := t.TempDir()
+	r := New(workspace(dir), config.FormatConfig{Enabled: true, Formatters: []config.FormatterConfig{
+		{Name: "upper", Files: []string{"*.txt"}, Run: `sh -c 'for f; do tr a-z A-Z < "$f" > "$f.tmp" && mv "$f.tmp" "$f"; done' sh`},
+		{Name: "web", Files: []string{"web/*.css"}, Run: "sed -i 's/  */ /g'"},
+	}})
+
+	changes := []core.AIFileChange{
+		{Path: "docs/notes.txt", Content: "hello\n", Action: "create"},
+		{Path: "web/app.css", Content: "a {  color:   red }\n", Action: "modify"},
+		{Path: "css/app.css", Content: "a {  color: red }\n", Action: "modify"},
+		{Path: "old.txt", Action: "delete"},
+	}
+	got, err := r.Format(context.Background(), changes)
+	if err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+	want := []string{"HELLO\n", "a { color: red }\n", "a {  color: red }\n", ""}
+	for i := range want {
+		if got[i].Content != want[i] {
+			t.Errorf("%s = %q, want %q", got[i].Path, got[i].Content, want[i])
+		}
+	}
+	if changes[0].Content != "hello\n" {
+		t.Error("expected the given changes to be left alone")
+	}
+}
+
+func TestFormat_FailingFormatterKeepsFiles(t *testing.T) {
+	dir := t.TempDir()
+	r := New(workspace(dir), config.FormatConfig{Enabled: true, Formatters: []config.FormatterConfig{
+		{Name: "broken", Files: []string{"*.txt"}, Run: "echo bad >; echo 'syntax error' >&2; false"},
+		{Name: "upper", Files: []string{"*.md"}, Run: `sh -c 'tr a-z A-Z < "$0" > "$0.tmp" && mv "$0.tmp" "$0"'`},
+	}})
+	got, err := r.Format(context.Background(), []core.AIFileChange{
+		{Path: "a.txt", Content: "keep me\n", Action: "create"},
+		{Path: "b.md", Content: "shout\n", Action: "create"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Fatalf("expected the broken formatter's error, got %v", err)
+	}
+	if got[0].Content != "keep me\n" || got[1].Content != "SHOUT\n" {
+		t.Errorf("unexpected changes %+v", got)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "a.txt")); string(data) != "keep me\n" {
+		t.Errorf("expected a.txt to be put back, got %q", data)
+	}
+}
+
+func TestFormat_GoDefault(t *testing.T) {
+	if _, err := exec.LookPath("gofmt"); err != nil {
+		t.Skip("gofmt not installed")
+	}
+	r := New(workspace(t.TempDir()), config.FormatConfig{Enabled: true})
+	got, err := r.Format(context.Background(), []core.AIFileChange{
+		{Path: "cmd/main.go", Content: "package main\nfunc main(){\nprintln( 1 )\n}\n", Action: "create"},
+	})
+	if err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+	if want := "package main\n\nfunc main() {\n\tprintln(1)\n}\n"; got[0].Content != want {
+		t.Errorf("got %q, want %q", got[0].Content, want)
+	}
+}
+
+func TestFormat_NoWorkspace(t *testing.T) {
+	changes := []core.AIFileChange{{Path: "main.go", Content: "package  main", Action: "create"}}
+	got, err := New(workspace(""), config.FormatConfig{}).Format(context.Background(), changes)
+	if err != nil || got[0].Content != "package  main" {
+		t.Errorf("expected the changes unchanged, got %+v, %v", got, err)
+	}
+}
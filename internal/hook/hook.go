@@ -0,0 +1,106 @@
+// Package hook runs the workflow.hooks scripts at pipeline boundaries. A
+// hook is either a Starlark script, run by the interpreter embedded in rig
+// with the task, vars and changes as globals, or a shell command, so it
+// can be written for any interpreter on the host (Lua, Python, a shell
+// script). A shell hook reads a core.HookInput as JSON on stdin and may
+// answer a core.HookOutput as JSON on stdout. No answer lets the step go
+// ahead unchanged.
+package hook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/shell"
+)
+
+// defaultTimeout bounds a hook that sets no timeout.
+const defaultTimeout = time.Minute
+
+// maxStderr bounds the stderr quoted in a hook error.
+const maxStderr = 2048
+
+// Runner runs hooks in the task's workspace.
+type Runner struct {
+	workspace core.WorkspaceProvider
+}
+
+var _ core.HookRunner = (*Runner)(nil)
+
+// New creates a Runner. Hooks run in the workspace of ws, or in the current
+// directory when ws is nil or has none.
+func New(ws core.WorkspaceProvider) *Runner {
+	return &Runner{workspace: ws}
+}
+
+// RunHook runs the script of hook, or its command with input on stdin. A
+// script that fails fails with its backtrace; a command that exits
+// non-zero or answers something other than a HookOutput fails with its
+// stderr.
+func (r *Runner) RunHook(ctx context.Context, hook config.HookConfig, input *core.HookInput) (*core.HookOutput, error) {
+	timeout := hook.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if hook.Script != "" {
+		return r.runScript(ctx, hook, input, timeout)
+	}
+
+	body, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("hook %s: marshal input: %w", hook.Name, err)
+	}
+
+	cmd, err := shell.Command(ctx, hook.Shell, hook.Run)
+	if err != nil {
+		return nil, fmt.Errorf("hook %s: %w", hook.Name, err)
+	}
+	cmd.Cancel = func() error { return cmd.Process.Kill() }
+	cmd.WaitDelay = 3 * time.Second
+	if r.workspace != nil {
+		cmd.Dir = r.workspace.GetWorkspace()
+	}
+	cmd.Env = append(os.Environ(), "RIG_HOOK="+string(input.Hook))
+	cmd.Stdin = bytes.NewReader(body)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("hook %s timed out after %s", hook.Name, timeout)
+		}
+		return nil, fmt.Errorf("hook %s: %w%s", hook.Name, err, tail(stderr.String()))
+	}
+
+	out := &core.HookOutput{}
+	if strings.TrimSpace(stdout.String()) == "" {
+		return out, nil
+	}
+	if err := json.Unmarshal(stdout.Bytes(), out); err != nil {
+		return nil, fmt.Errorf("hook %s: invalid output: %w", hook.Name, err)
+	}
+	return out, nil
+}
+
+// tail returns the end of stderr to quote in an error, or "".
+func tail(stderr string) string {
+	stderr = strings.TrimSpace(stderr)
+	if stderr == "" {
+		return ""
+	}
+	if len(stderr) > maxStderr {
+		stderr = "..." + stderr[len(stderr)-maxStderr:]
+	}
+	return ": " + stderr
+}
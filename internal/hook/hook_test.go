@@ -0,0 +1,139 @@
+package hook
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+)
+
+// workspace is a core.WorkspaceProvider of a fixed directory.
+type workspace string
+
+func (w workspace) GetWorkspace() string { return string(w) }
+
+func TestRunHook(t *testing.T) {
+	dir := t.TempDir()
+	r := New(workspace(dir))
+	input := &core.HookInput{
+		Hook:    core.HookPrePR,
+		Task:    &core.Task{ID: "task-1"},
+		Vars:    map[string]string{"BRANCH_NAME": "rig/issue-1"},
+		Changes: []core.AIFileChange{{Path: "main.go", Action: "modify"}},
+		PR:      &core.HookPR{Title: "rig: fix", Body: "body"},
+	}
+
+	out, err := r.RunHook(context.Background(), config.HookConfig{
+		Name: "labels",
+		On:   "pre_pr",
+		Run:  `cat > input.json; echo "{\"labels\": [\"$RIG_HOOK\"], \"pr_body\": \"new body\"}"`,
+	}, input)
+	if err != nil {
+		t.Fatalf("RunHook: %v", err)
+	}
+	if len(out.Labels) != 1 || out.Labels[0] != "pre_pr" || out.PRBody != "new body" {
+		t.Errorf("unexpected output %+v", out)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "input.json"))
+	if err != nil {
+		t.Fatalf("hook did not run in the workspace: %v", err)
+	}
+	var got core.HookInput
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Task.ID != "task-1" || got.Vars["BRANCH_NAME"] != "rig/issue-1" || got.PR.Body != "body" {
+		t.Errorf("hook read %+v", got)
+	}
+}
+
+func TestRunHook_SilentHookChangesNothing(t *testing.T) {
+	out, err := New(nil).RunHook(context.Background(), config.HookConfig{Name: "noop", Run: "cat >/dev/null"}, &core.HookInput{Hook: core.HookPreCommit})
+	if err != nil || out.Veto != "" || out.PRBody != "" || out.Labels != nil {
+		t.Errorf("expected an empty output, got %+v, %v", out, err)
+	}
+}
+
+func TestRunHook_Failures(t *testing.T) {
+	r := New(nil)
+	input := &core.HookInput{Hook: core.HookPostDeploy}
+
+	_, err := r.RunHook(context.Background(), config.HookConfig{Name: "slo", Run: "echo 'error budget exhausted' >&2; exit 1"}, input)
+	if err == nil || !strings.Contains(err.Error(), "error budget exhausted") {
+		t.Errorf("expected the failure with its stderr, got %v", err)
+	}
+
+	_, err = r.RunHook(context.Background(), config.HookConfig{Name: "chatty", Run: "echo hello"}, input)
+	if err == nil || !strings.Contains(err.Error(), "invalid output") {
+		t.Errorf("expected an invalid output error, got %v", err)
+	}
+
+	_, err = r.RunHook(context.Background(), config.HookConfig{Name: "slow", Run: "exec sleep 5", Timeout: 100 * time.Millisecond}, input)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+}
+
+func TestRunHook_Script(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "hooks"), 0o755)
+	os.WriteFile(filepath.Join(dir, "hooks", "policy.star"), []byte(`
+for c in changes:
+    if "SECRET" in c["content"]:
+        veto("%s adds a secret" % c["path"])
+if task["id"] == "task-1":
+    add_labels("rig", hook)
+set_pr_body("Branch " + vars["BRANCH_NAME"])
+`), 0o644)
+
+	r := New(workspace(dir))
+	hook := config.HookConfig{Name: "policy", On: "pre_commit", Script: "hooks/policy.star"}
+	input := &core.HookInput{
+		Hook:    core.HookPreCommit,
+		Task:    &core.Task{ID: "task-1"},
+		Vars:    map[string]string{"BRANCH_NAME": "rig/issue-1"},
+		Changes: []core.AIFileChange{{Path: "main.go", Content: "package main", Action: "modify"}},
+	}
+	out, err := r.RunHook(context.Background(), hook, input)
+	if err != nil {
+		t.Fatalf("RunHook: %v", err)
+	}
+	if out.Veto != "" || strings.Join(out.Labels, ",") != "rig,pre_commit" || out.PRBody != "Branch rig/issue-1" {
+		t.Errorf("unexpected output %+v", out)
+	}
+
+	input.Changes = append(input.Changes, core.AIFileChange{Path: "config.go", Content: `key = "SECRET"`, Action: "create"})
+	if out, err = r.RunHook(context.Background(), hook, input); err != nil || out.Veto != "config.go adds a secret" {
+		t.Errorf("expected a veto, got %+v, %v", out, err)
+	}
+}
+
+func TestRunHook_ScriptFailures(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "broken.star"), []byte("fail(\"error budget exhausted\")\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "slow.star"), []byte("while True:\n    pass\n"), 0o644)
+	r := New(workspace(dir))
+	input := &core.HookInput{Hook: core.HookPostDeploy}
+
+	_, err := r.RunHook(context.Background(), config.HookConfig{Name: "slo", Script: "broken.star"}, input)
+	if err == nil || !strings.Contains(err.Error(), "error budget exhausted") || !strings.Contains(err.Error(), "broken.star:1") {
+		t.Errorf("expected the failure with its backtrace, got %v", err)
+	}
+
+	_, err = r.RunHook(context.Background(), config.HookConfig{Name: "missing", Script: "missing.star"}, input)
+	if err == nil || !strings.Contains(err.Error(), "read script") {
+		t.Errorf("expected a read error, got %v", err)
+	}
+
+	_, err = r.RunHook(context.Background(), config.HookConfig{Name: "slow", Script: "slow.star", Timeout: 100 * time.Millisecond}, input)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+}
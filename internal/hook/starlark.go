@@ -0,0 +1,136 @@
+package hook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	starlarkjson "go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+)
+
+// scriptOptions are the Starlark dialect of hook scripts: while loops,
+// sets and top-level if/for are allowed, as in most Starlark tools.
+var scriptOptions = &syntax.FileOptions{Set: true, While: true, TopLevelControl: true, GlobalReassign: true}
+
+// runScript runs the Starlark file of hook in the embedded interpreter
+// until ctx, which times out after timeout, is done. The script reads the
+// globals hook, task, vars, changes, deploy and pr, the fields of input as
+// Starlark values, and answers through the builtins veto(reason),
+// add_labels(*labels) and set_pr_body(body). The json module is there to
+// encode and decode JSON.
+func (r *Runner) runScript(ctx context.Context, hook config.HookConfig, input *core.HookInput, timeout time.Duration) (*core.HookOutput, error) {
+	path := hook.Script
+	if !filepath.IsAbs(path) && r.workspace != nil && r.workspace.GetWorkspace() != "" {
+		path = filepath.Join(r.workspace.GetWorkspace(), path)
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("hook %s: read script: %w", hook.Name, err)
+	}
+
+	thread := &starlark.Thread{
+		Name: "hook " + hook.Name,
+		Print: func(_ *starlark.Thread, msg string) {
+			slog.Info("hook: "+msg, "hook", hook.Name)
+		},
+	}
+	globals, err := scriptGlobals(thread, input)
+	if err != nil {
+		return nil, fmt.Errorf("hook %s: %w", hook.Name, err)
+	}
+	out := &core.HookOutput{}
+	globals["veto"] = starlark.NewBuiltin("veto", func(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var reason string
+		if err := starlark.UnpackPositionalArgs(fn.Name(), args, kwargs, 1, &reason); err != nil {
+			return nil, err
+		}
+		out.Veto = reason
+		return starlark.None, nil
+	})
+	globals["add_labels"] = starlark.NewBuiltin("add_labels", func(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		if len(kwargs) > 0 {
+			return nil, fmt.Errorf("%s: unexpected keyword arguments", fn.Name())
+		}
+		for i, arg := range args {
+			label, ok := starlark.AsString(arg)
+			if !ok {
+				return nil, fmt.Errorf("%s: label %d is %s, want string", fn.Name(), i+1, arg.Type())
+			}
+			out.Labels = append(out.Labels, label)
+		}
+		return starlark.None, nil
+	})
+	globals["set_pr_body"] = starlark.NewBuiltin("set_pr_body", func(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		if err := starlark.UnpackPositionalArgs(fn.Name(), args, kwargs, 1, &out.PRBody); err != nil {
+			return nil, err
+		}
+		return starlark.None, nil
+	})
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			thread.Cancel(ctx.Err().Error())
+		case <-done:
+		}
+	}()
+
+	if _, err := starlark.ExecFileOptions(scriptOptions, thread, hook.Script, src, globals); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("hook %s timed out after %s", hook.Name, timeout)
+		}
+		var evalErr *starlark.EvalError
+		if errors.As(err, &evalErr) {
+			return nil, fmt.Errorf("hook %s: %s", hook.Name, evalErr.Backtrace())
+		}
+		return nil, fmt.Errorf("hook %s: %w", hook.Name, err)
+	}
+	return out, nil
+}
+
+// scriptGlobals returns the fields of input as the predeclared globals of a
+// hook script. Values are decoded from their JSON, so they read as in the
+// input of a shell hook; vars and changes are empty and the others None
+// when the hook point has none.
+func scriptGlobals(thread *starlark.Thread, input *core.HookInput) (starlark.StringDict, error) {
+	vars, changes := input.Vars, input.Changes
+	if vars == nil {
+		vars = map[string]string{}
+	}
+	if changes == nil {
+		changes = []core.AIFileChange{}
+	}
+	fields := map[string]any{
+		"task":    input.Task,
+		"vars":    vars,
+		"changes": changes,
+		"deploy":  input.Deploy,
+		"pr":      input.PR,
+	}
+	globals := starlark.StringDict{"hook": starlark.String(input.Hook), "json": starlarkjson.Module}
+	for name, v := range fields {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("marshal %s: %w", name, err)
+		}
+		value, err := starlark.Call(thread, starlarkjson.Module.Members["decode"], starlark.Tuple{starlark.String(data)}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("decode %s: %w", name, err)
+		}
+		value.Freeze()
+		globals[name] = value
+	}
+	return globals, nil
+}
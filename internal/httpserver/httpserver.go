@@ -0,0 +1,77 @@
+// Package httpserver holds what the dashboard and webhook servers share
+// when rig serve faces the internet directly or sits behind a reverse
+// proxy: the listen address, TLS from files or Let's Encrypt, and client
+// IPs from trusted proxy headers.
+package httpserver
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/rigdev/rig/internal/config"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Addr joins host and port into a listen address; an empty host listens on
+// all interfaces.
+func Addr(host string, port int) string {
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// TLSConfig returns the TLS configuration of cfg, or nil when TLS is off.
+// Certificate files are loaded here so a bad path fails at startup rather
+// than on the first handshake.
+func TLSConfig(cfg config.ServerTLSConfig) (*tls.Config, error) {
+	switch {
+	case cfg.CertFile != "":
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load TLS certificate: %w", err)
+		}
+		return &tls.Config{MinVersion: tls.VersionTLS12, Certificates: []tls.Certificate{cert}}, nil
+	case len(cfg.AutocertDomains) > 0:
+		dir := cfg.AutocertCacheDir
+		if dir == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, fmt.Errorf("autocert cache: %w", err)
+			}
+			dir = filepath.Join(home, ".rig", "autocert")
+		}
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.AutocertDomains...),
+			Cache:      autocert.DirCache(dir),
+			Email:      cfg.AutocertEmail,
+		}
+		tc := m.TLSConfig()
+		tc.MinVersion = tls.VersionTLS12
+		return tc, nil
+	}
+	return nil, nil
+}
+
+// ListenAndServe serves srv over TLS when tc is set and plain HTTP
+// otherwise. Like http.Server.ListenAndServe it returns
+// http.ErrServerClosed after Shutdown.
+func ListenAndServe(srv *http.Server, tc *tls.Config) error {
+	if tc == nil {
+		return srv.ListenAndServe()
+	}
+	srv.TLSConfig = tc
+	return srv.ListenAndServeTLS("", "")
+}
+
+// Scheme is "https" when tc is set and "http" otherwise, for startup
+// banners.
+func Scheme(tc *tls.Config) string {
+	if tc != nil {
+		return "https"
+	}
+	return "http"
+}
@@ -0,0 +1,164 @@
+package httpserver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+)
+
+func TestRealIP(t *testing.T) {
+	mw, err := RealIP([]string{"10.0.0.0/8", "192.168.1.5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got string
+	h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { got = r.RemoteAddr }))
+
+	tests := []struct {
+		name    string
+		remote  string
+		headers map[string]string
+		want    string
+	}{
+		{"direct client", "203.0.113.7:5555", nil, "203.0.113.7"},
+		{"untrusted peer cannot spoof", "203.0.113.7:5555", map[string]string{"X-Forwarded-For": "1.2.3.4"}, "203.0.113.7"},
+		{"trusted proxy", "10.1.2.3:443", map[string]string{"X-Forwarded-For": "198.51.100.9"}, "198.51.100.9"},
+		{"client prepends a fake hop", "10.1.2.3:443", map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.9"}, "198.51.100.9"},
+		{"chain of trusted proxies", "192.168.1.5:80", map[string]string{"X-Forwarded-For": "198.51.100.9, 10.0.0.2"}, "198.51.100.9"},
+		{"X-Real-IP", "10.1.2.3:443", map[string]string{"X-Real-IP": "198.51.100.10"}, "198.51.100.10"},
+		{"garbage header", "10.1.2.3:443", map[string]string{"X-Forwarded-For": "not-an-ip"}, "10.1.2.3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remote
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want {
+				t.Errorf("RemoteAddr = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := RealIP([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("expected an error for a bad CIDR")
+	}
+}
+
+func TestTLSConfig(t *testing.T) {
+	if tc, err := TLSConfig(config.ServerTLSConfig{}); tc != nil || err != nil {
+		t.Errorf("TLS off: got %v, %v", tc, err)
+	}
+
+	certFile, keyFile := writeSelfSigned(t)
+	tc, err := TLSConfig(config.ServerTLSConfig{CertFile: certFile, KeyFile: keyFile})
+	if err != nil || tc == nil || len(tc.Certificates) != 1 {
+		t.Fatalf("cert files: got %v, %v", tc, err)
+	}
+	if _, err := TLSConfig(config.ServerTLSConfig{CertFile: certFile, KeyFile: filepath.Join(t.TempDir(), "missing.pem")}); err == nil {
+		t.Error("expected an error for a missing key file")
+	}
+
+	tc, err = TLSConfig(config.ServerTLSConfig{AutocertDomains: []string{"rig.example.com"}, AutocertCacheDir: t.TempDir()})
+	if err != nil || tc == nil || tc.GetCertificate == nil {
+		t.Fatalf("autocert: got %v, %v", tc, err)
+	}
+}
+
+func writeSelfSigned(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	return certFile, keyFile
+}
+
+func TestRateLimit(t *testing.T) {
+	h := RateLimit(NewRateLimiter(2, time.Minute))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	do := func(remote string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remote
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// The port changes per connection; the limit is per IP.
+	do("203.0.113.7:1000")
+	do("203.0.113.7:1001")
+	rec := do("203.0.113.7:1002")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("third request: got %d, want 429", rec.Code)
+	}
+	if ra := rec.Header().Get("Retry-After"); ra == "" || ra == "0" {
+		t.Errorf("Retry-After = %q", ra)
+	}
+	if rec := do("198.51.100.1:1000"); rec.Code != http.StatusOK {
+		t.Errorf("other client: got %d, want 200", rec.Code)
+	}
+
+	if RateLimit(nil)(http.NotFoundHandler()) == nil {
+		t.Error("nil limiter should pass requests through")
+	}
+}
+
+func TestMaxBody(t *testing.T) {
+	var readErr error
+	h := MaxBody(8)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = io.ReadAll(r.Body)
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("0123456789")))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("declared length over the cap: got %d, want 413", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", io.NopCloser(strings.NewReader("0123456789")))
+	req.ContentLength = -1
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if readErr == nil {
+		t.Error("chunked body over the cap was read in full")
+	}
+}
+
+func TestLimit(t *testing.T) {
+	if Limit(0, 120) != 120 || Limit(-1, 120) != 0 || Limit(30, 120) != 30 {
+		t.Error("Limit does not pick default, none and configured values")
+	}
+}
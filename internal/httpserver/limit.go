@@ -0,0 +1,152 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimiter allows a fixed number of requests per key in each window.
+type RateLimiter struct {
+	mu       sync.Mutex
+	visitors map[string]*visitor
+	rate     int           // requests per window
+	window   time.Duration // window duration
+}
+
+type visitor struct {
+	tokens    int
+	lastReset time.Time
+}
+
+// NewRateLimiter returns a limiter of rate requests per window, or nil
+// (no limit) when rate is not positive.
+func NewRateLimiter(rate int, window time.Duration) *RateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	rl := &RateLimiter{
+		visitors: make(map[string]*visitor),
+		rate:     rate,
+		window:   window,
+	}
+	// Cleanup stale entries every 5 minutes.
+	go func() {
+		for {
+			time.Sleep(5 * time.Minute)
+			rl.cleanup()
+		}
+	}()
+	return rl
+}
+
+func (rl *RateLimiter) cleanup() {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	cutoff := time.Now().Add(-2 * rl.window)
+	for key, v := range rl.visitors {
+		if v.lastReset.Before(cutoff) {
+			delete(rl.visitors, key)
+		}
+	}
+}
+
+// Allow takes a request from key's budget. When the budget is spent it
+// returns false and how long until the window resets.
+func (rl *RateLimiter) Allow(key string) (bool, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	v, exists := rl.visitors[key]
+	now := time.Now()
+
+	if !exists {
+		rl.visitors[key] = &visitor{tokens: rl.rate - 1, lastReset: now}
+		return true, 0
+	}
+
+	if now.Sub(v.lastReset) >= rl.window {
+		v.tokens = rl.rate - 1
+		v.lastReset = now
+		return true, 0
+	}
+
+	if v.tokens <= 0 {
+		return false, rl.window - now.Sub(v.lastReset)
+	}
+	v.tokens--
+	return true, 0
+}
+
+// RateLimit returns middleware that rejects clients over rl's rate with
+// 429. Clients are told apart by r.RemoteAddr, which RealIP sets from
+// trusted proxy headers; forwarding headers are not read here because any
+// client could send them. A nil rl allows everything.
+func RateLimit(rl *RateLimiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if rl == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := r.RemoteAddr
+			if host, _, err := net.SplitHostPort(ip); err == nil {
+				ip = host
+			}
+			if ok, wait := rl.Allow(ip); !ok {
+				TooManyRequests(w, wait, "rate limit exceeded")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// TooManyRequests writes a JSON 429 with a Retry-After of wait, rounded up
+// to whole seconds.
+func TooManyRequests(w http.ResponseWriter, wait time.Duration, msg string) {
+	secs := int(math.Ceil(wait.Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(secs))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+// MaxBody returns middleware that rejects request bodies over maxBytes:
+// with 413 up front when Content-Length says so, and by failing the read
+// otherwise. A maxBytes of 0 or less allows any size.
+func MaxBody(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if maxBytes <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > maxBytes {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				json.NewEncoder(w).Encode(map[string]string{"error": "request body too large"})
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Limit picks a configured limit: the default when n is 0, none (0) when
+// n is negative.
+func Limit[T int | int64](n, def T) T {
+	switch {
+	case n == 0:
+		return def
+	case n < 0:
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,87 @@
+package httpserver
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// RealIP returns middleware that sets r.RemoteAddr to the client IP when
+// the request came through one of the trusted proxies (IPs or CIDRs). The
+// X-Forwarded-For chain is walked from the right, skipping trusted hops,
+// so a client cannot spoof its address by sending the header itself;
+// X-Real-IP is used when there is no X-Forwarded-For. Requests from
+// untrusted peers keep their RemoteAddr, without the port.
+func RealIP(trusted []string) (func(http.Handler) http.Handler, error) {
+	nets, err := parseNets(trusted)
+	if err != nil {
+		return nil, err
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.RemoteAddr = clientIP(r, nets)
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
+
+func parseNets(trusted []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, t := range trusted {
+		if ip := net.ParseIP(t); ip != nil {
+			bits := 8 * len(ip.To4())
+			if bits == 0 {
+				bits = 128
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(t)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q is not an IP or CIDR", t)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func clientIP(r *http.Request, nets []*net.IPNet) string {
+	peer := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(peer); err == nil {
+		peer = host
+	}
+	if !trustedIP(peer, nets) {
+		return peer
+	}
+	if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+		hops := strings.Split(fwd, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if net.ParseIP(hop) == nil {
+				break
+			}
+			if !trustedIP(hop, nets) || i == 0 {
+				return hop
+			}
+		}
+		return peer
+	}
+	if real := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(real) != nil {
+		return real
+	}
+	return peer
+}
+
+func trustedIP(s string, nets []*net.IPNet) bool {
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return false
+	}
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
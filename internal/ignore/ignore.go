@@ -0,0 +1,236 @@
+// Package ignore decides which files of a repository checkout rig gives the
+// AI as context and lets it change: not the ones .gitignore files or
+// ai.context_ignore ignore, nothing in vendored directories, and no binary
+// files.
+package ignore
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// VendorDirs are directories of third-party code, ignored at any depth.
+var VendorDirs = map[string]bool{
+	"vendor": true, "node_modules": true, "bower_components": true, "third_party": true,
+}
+
+// binaryExts are extensions of files that are binary whatever their
+// content, such as a PNG the AI writes as text.
+var binaryExts = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".bmp": true, ".ico": true, ".webp": true,
+	".pdf": true, ".zip": true, ".gz": true, ".tgz": true, ".tar": true, ".bz2": true, ".xz": true, ".7z": true,
+	".jar": true, ".war": true, ".class": true, ".pyc": true, ".o": true, ".a": true,
+	".exe": true, ".dll": true, ".so": true, ".dylib": true, ".bin": true, ".wasm": true,
+	".woff": true, ".woff2": true, ".ttf": true, ".otf": true, ".eot": true,
+	".mp3": true, ".mp4": true, ".mov": true, ".avi": true, ".wav": true,
+	".sqlite": true, ".db": true,
+}
+
+// sniffLen is how much of a file IsBinary looks at, as git does.
+const sniffLen = 8000
+
+// IsBinary reports whether content looks binary: it has a NUL byte near
+// the start.
+func IsBinary(content []byte) bool {
+	return bytes.IndexByte(content[:min(len(content), sniffLen)], 0) >= 0
+}
+
+// BinaryPath reports whether name has the extension of a binary format.
+func BinaryPath(name string) bool {
+	return binaryExts[strings.ToLower(path.Ext(name))]
+}
+
+// BinaryFile reports whether the file at name is binary by its extension or
+// content. Missing files are not.
+func BinaryFile(name string) bool {
+	if BinaryPath(name) {
+		return true
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	head := make([]byte, sniffLen)
+	n, _ := f.Read(head)
+	return IsBinary(head[:n])
+}
+
+// rule is one pattern of a .gitignore file or ai.context_ignore.
+type rule struct {
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool // matches the path from the rule's directory, not any name
+}
+
+// Matcher matches the paths of a checkout against its .gitignore files,
+// read as they are needed, and extra patterns of the same syntax that
+// apply from the root.
+type Matcher struct {
+	root  string
+	extra []rule
+
+	mu    sync.Mutex
+	rules map[string][]rule // by directory, "" for the root
+}
+
+// New returns the matcher of the checkout at root with the extra patterns
+// of ai.context_ignore. With no root, only those apply.
+func New(root string, patterns []string) *Matcher {
+	return &Matcher{root: root, extra: parse(patterns), rules: map[string][]rule{}}
+}
+
+// Ignored reports whether the slash-separated path rel of the checkout is
+// ignored: it, or a directory it is in, is a vendored directory or matches
+// an ignore pattern.
+func (m *Matcher) Ignored(rel string, isDir bool) bool {
+	rel = strings.Trim(path.Clean(filepath.ToSlash(rel)), "/")
+	if rel == "" || rel == "." {
+		return false
+	}
+	parts := strings.Split(rel, "/")
+	for i := range parts {
+		dir := i < len(parts)-1 || isDir
+		if dir && VendorDirs[parts[i]] {
+			return true
+		}
+		if m.match(parts[:i+1], dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// match applies the rules of the .gitignore files from the root down to
+// the directory of the path parts, then the extra ones; the last rule that
+// matches decides.
+func (m *Matcher) match(parts []string, isDir bool) bool {
+	ignored := false
+	for depth := 0; depth < len(parts); depth++ {
+		base := strings.Join(parts[:depth], "/")
+		rel := strings.Join(parts[depth:], "/")
+		for _, r := range m.dirRules(base) {
+			if r.matches(rel, isDir) {
+				ignored = !r.negate
+			}
+		}
+	}
+	for _, r := range m.extra {
+		if r.matches(strings.Join(parts, "/"), isDir) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// Match reports whether the pattern of .gitignore syntax, applying from
+// the root, matches the slash-separated path rel or a directory it is in,
+// as CODEOWNERS patterns do. Negated patterns match nothing.
+func Match(pattern, rel string) bool {
+	rules := parse([]string{pattern})
+	if len(rules) == 0 || rules[0].negate {
+		return false
+	}
+	parts := strings.Split(strings.Trim(path.Clean(filepath.ToSlash(rel)), "/"), "/")
+	for i := range parts {
+		if rules[0].matches(strings.Join(parts[:i+1], "/"), i < len(parts)-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// dirRules returns the rules of the .gitignore file in dir, if any.
+func (m *Matcher) dirRules(dir string) []rule {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rules, ok := m.rules[dir]
+	if !ok && m.root != "" {
+		if data, err := os.ReadFile(filepath.Join(m.root, filepath.FromSlash(dir), ".gitignore")); err == nil {
+			var lines []string
+			sc := bufio.NewScanner(bytes.NewReader(data))
+			for sc.Scan() {
+				lines = append(lines, sc.Text())
+			}
+			rules = parse(lines)
+		}
+		m.rules[dir] = rules
+	}
+	return rules
+}
+
+// parse reads patterns in .gitignore syntax.
+func parse(lines []string) []rule {
+	var rules []rule
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var r rule
+		if strings.HasPrefix(line, "!") {
+			r.negate = true
+			line = line[1:]
+		}
+		line = strings.TrimPrefix(line, `\`)
+		if strings.HasSuffix(line, "/") {
+			r.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		// A slash anywhere but at the end anchors the pattern to its
+		// directory.
+		if strings.Contains(line, "/") {
+			r.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		r.pattern = line
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// matches reports whether r matches rel, the path relative to the rule's
+// directory.
+func (r rule) matches(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if !r.anchored {
+		return globMatch(r.pattern, path.Base(rel))
+	}
+	return matchSegments(strings.Split(r.pattern, "/"), strings.Split(rel, "/"))
+}
+
+// matchSegments matches path segments against pattern segments, where
+// "**" matches any number of segments.
+func matchSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 || !globMatch(pattern[0], name[0]) {
+		return false
+	}
+	return matchSegments(pattern[1:], name[1:])
+}
+
+func globMatch(pattern, name string) bool {
+	ok, err := path.Match(pattern, name)
+	return err == nil && ok
+}
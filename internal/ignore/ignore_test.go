@@ -0,0 +1,79 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnored(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, ".gitignore"), []byte("# build output\n/bin/\n*.log\n!keep.log\ndocs/**/draft.md\n"), 0o644)
+	os.MkdirAll(filepath.Join(root, "web"), 0o755)
+	os.WriteFile(filepath.Join(root, "web", ".gitignore"), []byte("generated/\n!debug.log\n"), 0o644)
+	m := New(root, []string{"testdata/", "*.pb.go"})
+
+	for _, tc := range []struct {
+		path    string
+		ignored bool
+	}{
+		{"main.go", false},
+		{"bin/rig", true},
+		{"cmd/bin/tool.go", false}, // /bin/ is anchored to the root
+		{"app.log", true},
+		{"keep.log", false},
+		{"sub/dir/app.log", true},
+		{"web/debug.log", false},
+		{"web/generated/api.ts", true},
+		{"generated/api.ts", false},
+		{"docs/a/b/draft.md", true},
+		{"docs/draft.md", true},
+		{"vendor/github.com/x/y.go", true},
+		{"web/node_modules/left-pad/index.js", true},
+		{"vendor.go", false},
+		{"internal/testdata/case.json", true},
+		{"api/v1/api.pb.go", true},
+	} {
+		if got := m.Ignored(tc.path, false); got != tc.ignored {
+			t.Errorf("Ignored(%q) = %v, want %v", tc.path, got, tc.ignored)
+		}
+	}
+}
+
+func TestBinary(t *testing.T) {
+	if !IsBinary([]byte("PK\x03\x04\x00\x00")) || IsBinary([]byte("package main\n")) {
+		t.Error("expected NUL bytes to mark binary content")
+	}
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "blob"), []byte{0x7f, 'E', 'L', 'F', 0, 0}, 0o644)
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o644)
+	if !BinaryFile(filepath.Join(dir, "blob")) || BinaryFile(filepath.Join(dir, "main.go")) {
+		t.Error("expected the ELF file only to be binary")
+	}
+	if !BinaryFile(filepath.Join(dir, "logo.PNG")) || BinaryFile(filepath.Join(dir, "missing.go")) {
+		t.Error("expected binary extensions to be binary even when missing")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"*", "cmd/rig/main.go", true},
+		{"*.js", "web/static/app.js", true},
+		{"*.js", "web/static/app.ts", false},
+		{"/docs/", "docs/guide/intro.md", true},
+		{"/docs/", "internal/docs/readme.md", false},
+		{"apps/", "src/apps/main.go", true},
+		{"internal/core/", "internal/core/engine.go", true},
+		{"internal/core", "internal/core/engine.go", true},
+		{"internal/core", "pkg/internal/core/x.go", false},
+		{"docs/**/*.md", "docs/a/b/c.md", true},
+		{"!keep.go", "keep.go", false},
+	} {
+		if got := Match(tc.pattern, tc.path); got != tc.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tc.pattern, tc.path, got, tc.want)
+		}
+	}
+}
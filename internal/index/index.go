@@ -0,0 +1,316 @@
+// Package index keeps an embedding index of repository files in the rig
+// database and retrieves the files most relevant to a query, such as a
+// plan or failure logs.
+package index
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/rigdev/rig/internal/ignore"
+	"github.com/rigdev/rig/internal/storage"
+)
+
+const (
+	// DefaultTopK is how many files Relevant returns by default.
+	DefaultTopK = 20
+
+	// maxFileSize skips files too large to be useful context.
+	maxFileSize = 100 * 1024
+	// chunkSize is the target size of a chunk in bytes, about 500 tokens.
+	chunkSize = 2000
+	// batchSize is how many chunks are embedded per request.
+	batchSize = 64
+	// maxQuerySize bounds the query text embedded for retrieval.
+	maxQuerySize = 8000
+)
+
+// skipDirs are directories never indexed, besides hidden ones.
+var skipDirs = map[string]bool{
+	"node_modules": true, "vendor": true, "dist": true, "build": true,
+	"__pycache__": true, "venv": true, "target": true,
+}
+
+// indexedExts are the extensions of the files indexed.
+var indexedExts = map[string]bool{
+	".go": true, ".py": true, ".js": true, ".ts": true,
+	".jsx": true, ".tsx": true, ".java": true, ".kt": true, ".rs": true,
+	".rb": true, ".php": true, ".cs": true, ".swift": true, ".sh": true,
+	".yaml": true, ".yml": true, ".json": true, ".toml": true, ".sql": true,
+	".html": true, ".css": true, ".c": true, ".cpp": true, ".h": true,
+	".md": true, ".proto": true, ".tf": true,
+}
+
+// Embedder computes embedding vectors for texts, in order.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	// Model names the embedding model; vectors of different models are
+	// never compared.
+	Model() string
+}
+
+// Store persists embeddings. *storage.DB implements it.
+type Store interface {
+	IndexedFiles(repo, model string) (map[string]string, error)
+	ReplaceFileEmbeddings(repo, model, path, fileHash string, vectors [][]float32) error
+	DeleteFileEmbeddings(repo, path string) error
+	PruneEmbeddingModels(repo, model string) error
+	Embeddings(repo, model string) ([]storage.EmbeddedChunk, error)
+}
+
+// Index is the embedding index of one repository.
+type Index struct {
+	store    Store
+	embedder Embedder
+	repo     string
+	topK     int
+	ignore   []string
+}
+
+// New returns the index of repo (owner/name) kept in store. topK is how
+// many files Relevant returns; 0 means DefaultTopK.
+func New(store Store, embedder Embedder, repo string, topK int) *Index {
+	if topK <= 0 {
+		topK = DefaultTopK
+	}
+	return &Index{store: store, embedder: embedder, repo: repo, topK: topK}
+}
+
+// SetIgnore sets the patterns, in .gitignore syntax, of files left out of
+// the index besides the ones the workspace's .gitignore files ignore.
+func (x *Index) SetIgnore(patterns []string) {
+	x.ignore = patterns
+}
+
+// Stats describes what Update did.
+type Stats struct {
+	Files    int // files in the workspace that are indexed
+	Embedded int // files embedded because they were new or changed
+	Removed  int // files dropped because they no longer exist
+	Chunks   int // chunks embedded
+}
+
+// Update brings the index up to date with the files in workspace. Only new
+// and changed files are embedded, so after the first run it is cheap.
+func (x *Index) Update(ctx context.Context, workspace string) (Stats, error) {
+	var stats Stats
+	model := x.embedder.Model()
+	if err := x.store.PruneEmbeddingModels(x.repo, model); err != nil {
+		return stats, err
+	}
+	indexed, err := x.store.IndexedFiles(x.repo, model)
+	if err != nil {
+		return stats, err
+	}
+
+	files, err := walk(workspace, ignore.New(workspace, x.ignore))
+	if err != nil {
+		return stats, err
+	}
+	stats.Files = len(files)
+	for _, path := range files {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		content, err := os.ReadFile(filepath.Join(workspace, path))
+		if err != nil || ignore.IsBinary(content) {
+			continue
+		}
+		hash := contentHash(content)
+		if indexed[path] == hash {
+			delete(indexed, path)
+			continue
+		}
+		delete(indexed, path)
+
+		chunks := chunk(path, string(content))
+		vectors, err := x.embed(ctx, chunks)
+		if err != nil {
+			return stats, fmt.Errorf("embed %s: %w", path, err)
+		}
+		if err := x.store.ReplaceFileEmbeddings(x.repo, model, path, hash, vectors); err != nil {
+			return stats, err
+		}
+		stats.Embedded++
+		stats.Chunks += len(chunks)
+	}
+
+	// What is left was indexed but is gone from the workspace.
+	for path := range indexed {
+		if err := x.store.DeleteFileEmbeddings(x.repo, path); err != nil {
+			return stats, err
+		}
+		stats.Removed++
+	}
+	return stats, nil
+}
+
+// Relevant updates the index with workspace and returns the paths of the
+// files most relevant to query, best first. It implements
+// core.FileRetriever.
+func (x *Index) Relevant(ctx context.Context, workspace, query string) ([]string, error) {
+	stats, err := x.Update(ctx, workspace)
+	if err != nil {
+		return nil, fmt.Errorf("update index: %w", err)
+	}
+	if stats.Embedded > 0 || stats.Removed > 0 {
+		slog.Debug("updated embedding index", "repo", x.repo, "files", stats.Files, "embedded", stats.Embedded, "removed", stats.Removed)
+	}
+
+	if len(query) > maxQuerySize {
+		query = query[:maxQuerySize]
+	}
+	vectors, err := x.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("embed query: %w", err)
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("embed query: got %d vectors", len(vectors))
+	}
+	chunks, err := x.store.Embeddings(x.repo, x.embedder.Model())
+	if err != nil {
+		return nil, err
+	}
+	return rank(vectors[0], chunks, x.topK), nil
+}
+
+// embed embeds chunks in batches.
+func (x *Index) embed(ctx context.Context, chunks []string) ([][]float32, error) {
+	return embedBatches(ctx, x.embedder, chunks)
+}
+
+// embedBatches embeds texts with embedder batchSize at a time.
+func embedBatches(ctx context.Context, embedder Embedder, chunks []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(chunks))
+	for batch := range slices.Chunk(chunks, batchSize) {
+		v, err := embedder.Embed(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		if len(v) != len(batch) {
+			return nil, fmt.Errorf("got %d vectors for %d chunks", len(v), len(batch))
+		}
+		vectors = append(vectors, v...)
+	}
+	return vectors, nil
+}
+
+// rank scores each file by its chunk most similar to query and returns the
+// k best files.
+func rank(query []float32, chunks []storage.EmbeddedChunk, k int) []string {
+	best := make(map[string]float64)
+	for _, c := range chunks {
+		score := cosine(query, c.Vector)
+		if s, ok := best[c.Path]; !ok || score > s {
+			best[c.Path] = score
+		}
+	}
+	paths := make([]string, 0, len(best))
+	for path := range best {
+		paths = append(paths, path)
+	}
+	slices.SortFunc(paths, func(a, b string) int {
+		if c := cmp.Compare(best[b], best[a]); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+	if len(paths) > k {
+		paths = paths[:k]
+	}
+	return paths
+}
+
+// cosine returns the cosine similarity of a and b, or 0 when their
+// dimensions differ.
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// chunk splits a file into chunks of about chunkSize bytes at line
+// boundaries. Each chunk starts with the path, which often says as much
+// about relevance as the code.
+func chunk(path, content string) []string {
+	var (
+		chunks []string
+		b      strings.Builder
+	)
+	flush := func() {
+		if strings.TrimSpace(b.String()) != "" {
+			chunks = append(chunks, path+"\n"+b.String())
+		}
+		b.Reset()
+	}
+	for line := range strings.Lines(content) {
+		if b.Len() > 0 && b.Len()+len(line) > chunkSize {
+			flush()
+		}
+		b.WriteString(line)
+	}
+	flush()
+	if len(chunks) == 0 {
+		chunks = append(chunks, path)
+	}
+	return chunks
+}
+
+// walk returns the workspace-relative paths of the files to index, leaving
+// out the ones ignored matches.
+func walk(workspace string, ignored *ignore.Matcher) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(workspace, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(workspace, path)
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != workspace && (strings.HasPrefix(d.Name(), ".") || skipDirs[d.Name()] || ignored.Ignored(rel, true)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !indexedExts[filepath.Ext(d.Name())] || ignored.Ignored(rel, false) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() > maxFileSize {
+			return nil
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walk %s: %w", workspace, err)
+	}
+	return files, nil
+}
+
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
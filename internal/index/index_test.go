@@ -0,0 +1,131 @@
+package index
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/storage"
+)
+
+// wordEmbedder embeds a text as the counts of a fixed vocabulary.
+type wordEmbedder struct {
+	calls int
+}
+
+var vocabulary = []string{"login", "password", "invoice", "payment", "render"}
+
+func (w *wordEmbedder) Model() string { return "words" }
+
+func (w *wordEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	w.calls++
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		v := make([]float32, len(vocabulary))
+		for j, word := range vocabulary {
+			v[j] = float32(strings.Count(strings.ToLower(text), word))
+		}
+		vectors[i] = v
+	}
+	return vectors, nil
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		full := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestIndexRelevant(t *testing.T) {
+	db, err := storage.Open(filepath.Join(t.TempDir(), "rig.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	workspace := t.TempDir()
+	writeFiles(t, workspace, map[string]string{
+		"auth/login.go":          "package auth\n// check the login password\nfunc Login(password string) {}\n",
+		"billing/invoice.go":     "package billing\n// invoice payment totals\nfunc Invoice() {}\n",
+		"web/render.go":          "package web\nfunc Render() {}\n",
+		"node_modules/x/left.js": "login login login",
+		"README.txt":             "login",
+	})
+
+	embedder := &wordEmbedder{}
+	x := New(db, embedder, "acme/api", 2)
+
+	paths, err := x.Relevant(context.Background(), workspace, "Fix the login form rejecting a valid password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 2 || paths[0] != "auth/login.go" {
+		t.Fatalf("relevant = %v, want auth/login.go first of 2", paths)
+	}
+
+	// Nothing changed: only the query is embedded.
+	embedder.calls = 0
+	if paths, _ := x.Relevant(context.Background(), workspace, "invoice payment rounding"); len(paths) == 0 || paths[0] != "billing/invoice.go" {
+		t.Errorf("relevant = %v, want billing/invoice.go first", paths)
+	}
+	if embedder.calls != 1 {
+		t.Errorf("unchanged files were embedded again (%d calls)", embedder.calls)
+	}
+
+	// A changed file is embedded again and a deleted one dropped.
+	writeFiles(t, workspace, map[string]string{"web/render.go": "package web\n// render the invoice\n"})
+	os.Remove(filepath.Join(workspace, "billing/invoice.go"))
+	stats, err := x.Update(context.Background(), workspace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Files != 2 || stats.Embedded != 1 || stats.Removed != 1 {
+		t.Errorf("stats = %+v, want 2 files, 1 embedded, 1 removed", stats)
+	}
+}
+
+func TestChunk(t *testing.T) {
+	line := strings.Repeat("x", 99) + "\n"
+	chunks := chunk("a.go", strings.Repeat(line, 50))
+	if len(chunks) != 3 {
+		t.Fatalf("got %d chunks, want 3", len(chunks))
+	}
+	for _, c := range chunks {
+		if !strings.HasPrefix(c, "a.go\n") || len(c) > len("a.go\n")+chunkSize {
+			t.Errorf("bad chunk of %d bytes", len(c))
+		}
+	}
+	if got := chunk("empty.go", ""); len(got) != 1 || got[0] != "empty.go" {
+		t.Errorf("empty file chunks = %q", got)
+	}
+}
+
+func TestIssueMatcher(t *testing.T) {
+	embedder := &wordEmbedder{}
+	m := NewIssueMatcher(embedder)
+	scores, err := m.Similarity(context.Background(),
+		core.Issue{Title: "Login fails", Body: "login with a wrong password crashes"},
+		[]core.Issue{
+			{Title: "Login broken", Body: "password login crashes"},
+			{Title: "Invoice total", Body: "payment invoice is rendered wrong"},
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if embedder.calls != 1 {
+		t.Errorf("expected one embedding call, got %d", embedder.calls)
+	}
+	if len(scores) != 2 || scores[0] < 0.9 || scores[1] > 0.1 {
+		t.Errorf("unexpected scores %v", scores)
+	}
+}
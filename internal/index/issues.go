@@ -0,0 +1,48 @@
+package index
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rigdev/rig/internal/core"
+)
+
+// IssueMatcher compares issues by the cosine similarity of their
+// embeddings, for workflow.dedup.method embedding. It implements
+// core.IssueMatcher.
+type IssueMatcher struct {
+	embedder Embedder
+}
+
+// NewIssueMatcher returns a matcher that embeds issues with embedder.
+func NewIssueMatcher(embedder Embedder) *IssueMatcher {
+	return &IssueMatcher{embedder: embedder}
+}
+
+// Similarity embeds issue and candidates in one pass and returns the
+// similarity of each candidate to issue; opposite vectors score 0.
+func (m *IssueMatcher) Similarity(ctx context.Context, issue core.Issue, candidates []core.Issue) ([]float64, error) {
+	texts := make([]string, 0, len(candidates)+1)
+	texts = append(texts, issueText(issue))
+	for _, c := range candidates {
+		texts = append(texts, issueText(c))
+	}
+	vectors, err := embedBatches(ctx, m.embedder, texts)
+	if err != nil {
+		return nil, fmt.Errorf("embed issues: %w", err)
+	}
+	scores := make([]float64, len(candidates))
+	for i := range candidates {
+		scores[i] = max(cosine(vectors[0], vectors[i+1]), 0)
+	}
+	return scores, nil
+}
+
+// issueText is the text of an issue that is embedded.
+func issueText(issue core.Issue) string {
+	text := issue.Title + "\n\n" + issue.Body
+	if len(text) > maxQuerySize {
+		text = text[:maxQuerySize]
+	}
+	return text
+}
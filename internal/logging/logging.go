@@ -0,0 +1,183 @@
+// Package logging configures rig's structured logger. Records are written
+// as text or JSON and, when they carry a task_id attribute, also handed to a
+// TaskSink so the dashboard's task log view shows the same lines as the
+// server log.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// TaskKey is the attribute that correlates a record with a task.
+const TaskKey = "task_id"
+
+// TaskSink receives every record that carries a task_id, whatever the
+// configured level. storage.LogWriter.Write matches it.
+type TaskSink func(taskID, level, message string)
+
+// Options selects the level and format of the logger.
+type Options struct {
+	// Level is debug, info, warn or error; empty means info.
+	Level string
+	// Format is text or json; empty means text.
+	Format string
+	// Redact, when set, rewrites messages and string attributes before
+	// they are written anywhere, to keep resolved secrets out of logs.
+	Redact func(string) string
+}
+
+// ParseLevel parses a level name as used in rig.yaml and task logs.
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("unknown log level %q (want debug, info, warn or error)", s)
+}
+
+// LevelName is the lower-case name task logs use for a level.
+func LevelName(l slog.Level) string {
+	switch {
+	case l >= slog.LevelError:
+		return "error"
+	case l >= slog.LevelWarn:
+		return "warn"
+	case l >= slog.LevelInfo:
+		return "info"
+	}
+	return "debug"
+}
+
+// New returns a logger writing to w. sink may be nil.
+func New(w io.Writer, opts Options, sink TaskSink) (*slog.Logger, error) {
+	level, err := ParseLevel(opts.Level)
+	if err != nil {
+		return nil, err
+	}
+	hopts := &slog.HandlerOptions{Level: level}
+	var base slog.Handler
+	switch strings.ToLower(opts.Format) {
+	case "", "text":
+		base = slog.NewTextHandler(w, hopts)
+	case "json":
+		base = slog.NewJSONHandler(w, hopts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q (want text or json)", opts.Format)
+	}
+	h := base
+	if sink != nil {
+		h = &taskHandler{base: base, sink: sink}
+	}
+	if opts.Redact != nil {
+		h = &redactHandler{next: h, redact: opts.Redact}
+	}
+	return slog.New(h), nil
+}
+
+// redactHandler passes records on with their message and string values
+// rewritten by redact.
+type redactHandler struct {
+	next   slog.Handler
+	redact func(string) string
+}
+
+func (h *redactHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.next.Enabled(ctx, l)
+}
+
+func (h *redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	out := slog.NewRecord(r.Time, r.Level, h.redact(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(h.attr(a))
+		return true
+	})
+	return h.next.Handle(ctx, out)
+}
+
+func (h *redactHandler) attr(a slog.Attr) slog.Attr {
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, h.redact(v.String()))
+	case slog.KindGroup:
+		attrs := v.Group()
+		out := make([]any, len(attrs))
+		for i, ga := range attrs {
+			out[i] = h.attr(ga)
+		}
+		return slog.Group(a.Key, out...)
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			return slog.String(a.Key, h.redact(err.Error()))
+		}
+	}
+	return a
+}
+
+func (h *redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	out := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		out[i] = h.attr(a)
+	}
+	return &redactHandler{next: h.next.WithAttrs(out), redact: h.redact}
+}
+
+func (h *redactHandler) WithGroup(name string) slog.Handler {
+	return &redactHandler{next: h.next.WithGroup(name), redact: h.redact}
+}
+
+// taskHandler forwards records with a task_id to a TaskSink before passing
+// them to the base handler.
+type taskHandler struct {
+	base   slog.Handler
+	sink   TaskSink
+	taskID string // bound with Logger.With
+}
+
+func (h *taskHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	// The sink decides per record; the dashboard keeps debug task lines
+	// even when the server log is at info.
+	return true
+}
+
+func (h *taskHandler) Handle(ctx context.Context, r slog.Record) error {
+	taskID := h.taskID
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key == TaskKey {
+			taskID = a.Value.String()
+			return false
+		}
+		return true
+	})
+	if taskID != "" {
+		h.sink(taskID, LevelName(r.Level), r.Message)
+	}
+	if !h.base.Enabled(ctx, r.Level) {
+		return nil
+	}
+	return h.base.Handle(ctx, r)
+}
+
+func (h *taskHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	taskID := h.taskID
+	for _, a := range attrs {
+		if a.Key == TaskKey {
+			taskID = a.Value.String()
+		}
+	}
+	return &taskHandler{base: h.base.WithAttrs(attrs), sink: h.sink, taskID: taskID}
+}
+
+func (h *taskHandler) WithGroup(name string) slog.Handler {
+	return &taskHandler{base: h.base.WithGroup(name), sink: h.sink, taskID: h.taskID}
+}
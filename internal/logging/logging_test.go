@@ -0,0 +1,81 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+type sinkLine struct{ taskID, level, message string }
+
+func TestNewRoutesTaskRecords(t *testing.T) {
+	var out bytes.Buffer
+	var lines []sinkLine
+	logger, err := New(&out, Options{Level: "warn", Format: "json"}, func(taskID, level, message string) {
+		lines = append(lines, sinkLine{taskID, level, message})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Info("plan ready", TaskKey, "task-1", "phase", "planning", "attempt", 1)
+	logger.With(TaskKey, "task-2").Error("deploy failed")
+	logger.Warn("server warning")
+
+	want := []sinkLine{{"task-1", "info", "plan ready"}, {"task-2", "error", "deploy failed"}}
+	if len(lines) != len(want) {
+		t.Fatalf("sink got %v, want %v", lines, want)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("sink line %d = %v, want %v", i, lines[i], want[i])
+		}
+	}
+
+	// Only warn and above reach the server log.
+	records := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(records) != 2 {
+		t.Fatalf("expected 2 server records, got %q", out.String())
+	}
+	var rec map[string]any
+	if err := json.Unmarshal([]byte(records[0]), &rec); err != nil {
+		t.Fatalf("not JSON: %v", err)
+	}
+	if rec["msg"] != "deploy failed" || rec[TaskKey] != "task-2" || rec["level"] != "ERROR" {
+		t.Errorf("unexpected record %v", rec)
+	}
+}
+
+func TestNewRejectsUnknownOptions(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, Options{Level: "loud"}, nil); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+	if _, err := New(&bytes.Buffer{}, Options{Format: "xml"}, nil); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestNewRedacts(t *testing.T) {
+	var out bytes.Buffer
+	var lines []sinkLine
+	redact := func(s string) string { return strings.ReplaceAll(s, "hunter2", "[REDACTED]") }
+	logger, err := New(&out, Options{Format: "json", Redact: redact}, func(taskID, level, message string) {
+		lines = append(lines, sinkLine{taskID, level, message})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.With("token", "hunter2").Info("login with hunter2", TaskKey, "task-1", "err", errors.New("bad password hunter2"),
+		slog.Group("req", "auth", "Bearer hunter2"))
+
+	if strings.Contains(out.String(), "hunter2") {
+		t.Errorf("secret reached the log: %s", out.String())
+	}
+	if len(lines) != 1 || lines[0].message != "login with [REDACTED]" {
+		t.Errorf("sink got %v", lines)
+	}
+}
@@ -0,0 +1,193 @@
+package metrics
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/rigdev/rig/internal/core"
+)
+
+// DayCount holds the tasks created, completed and failed on one UTC day.
+type DayCount struct {
+	Date      string `json:"date"` // 2006-01-02
+	Created   int    `json:"created"`
+	Completed int    `json:"completed"`
+	Failed    int    `json:"failed"`
+}
+
+// TasksPerDay counts the tasks created, completed and failed on each day of
+// [from, to), including the days without any.
+func TasksPerDay(tasks []core.Task, from, to time.Time) []DayCount {
+	start := from.UTC().Truncate(24 * time.Hour)
+	days := []DayCount{}
+	index := make(map[string]int)
+	for d := start; d.Before(to); d = d.AddDate(0, 0, 1) {
+		index[d.Format(time.DateOnly)] = len(days)
+		days = append(days, DayCount{Date: d.Format(time.DateOnly)})
+	}
+	day := func(t time.Time) *DayCount {
+		if t.Before(from) || !t.Before(to) {
+			return nil
+		}
+		return &days[index[t.UTC().Format(time.DateOnly)]]
+	}
+
+	for i := range tasks {
+		task := &tasks[i]
+		if d := day(task.CreatedAt); d != nil {
+			d.Created++
+		}
+		if task.CompletedAt == nil {
+			continue
+		}
+		if d := day(*task.CompletedAt); d != nil {
+			switch task.Status {
+			case core.PhaseCompleted:
+				d.Completed++
+			case core.PhaseFailed, core.PhaseRollback:
+				d.Failed++
+			}
+		}
+	}
+	return days
+}
+
+// ReasonCount holds the number of failed tasks with one failure reason.
+type ReasonCount struct {
+	Reason core.FailReason `json:"reason"`
+	Tasks  int             `json:"tasks"`
+}
+
+// FailureReasons breaks the failed tasks created in [from, to) down by the
+// reason their last failed attempt recorded, most frequent first.
+func FailureReasons(tasks []core.Task, from, to time.Time) []ReasonCount {
+	counts := make(map[core.FailReason]int)
+	for i := range tasks {
+		task := &tasks[i]
+		if !inPeriod(task, from, to) || (task.Status != core.PhaseFailed && task.Status != core.PhaseRollback) {
+			continue
+		}
+		counts[failReason(task)]++
+	}
+
+	reasons := []ReasonCount{}
+	for reason, n := range counts {
+		reasons = append(reasons, ReasonCount{Reason: reason, Tasks: n})
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if reasons[i].Tasks != reasons[j].Tasks {
+			return reasons[i].Tasks > reasons[j].Tasks
+		}
+		return reasons[i].Reason < reasons[j].Reason
+	})
+	return reasons
+}
+
+// failReason returns the reason of the last failed attempt of task, not
+// counting manual reruns, or ReasonUnknown for a task that failed before
+// any attempt.
+func failReason(task *core.Task) core.FailReason {
+	for _, a := range slices.Backward(task.Attempts) {
+		if a.Rerun == "" && a.FailReason != "" {
+			return a.FailReason
+		}
+	}
+	return core.ReasonUnknown
+}
+
+// PhaseDuration summarizes how long the finished steps of one phase took.
+type PhaseDuration struct {
+	Phase  core.TaskPhase `json:"phase"`
+	Steps  int            `json:"steps"`
+	Mean   time.Duration  `json:"mean"`
+	Median time.Duration  `json:"median"`
+	Max    time.Duration  `json:"max"`
+}
+
+// durationPhases are the phases PhaseDurations reports on, in pipeline
+// order. The completed and failed steps only mark the end of a task.
+var durationPhases = []core.TaskPhase{
+	core.PhaseQueued, core.PhasePlanning, core.PhaseCoding, core.PhaseCommitting,
+	core.PhaseApproval, core.PhaseDeploying, core.PhaseTesting, core.PhaseReporting,
+	core.PhaseRollback,
+}
+
+// PhaseDurations reports the duration of the finished pipeline steps of the
+// tasks created in [from, to), per phase. Phases without a finished step
+// are left out.
+func PhaseDurations(tasks []core.Task, from, to time.Time) []PhaseDuration {
+	steps := make(map[core.TaskPhase][]time.Duration)
+	for i := range tasks {
+		task := &tasks[i]
+		if !inPeriod(task, from, to) {
+			continue
+		}
+		for _, step := range task.Pipeline {
+			if step.EndedAt != nil {
+				steps[step.Phase] = append(steps[step.Phase], step.EndedAt.Sub(step.StartedAt))
+			}
+		}
+	}
+
+	phases := []PhaseDuration{}
+	for _, phase := range durationPhases {
+		durations := steps[phase]
+		if len(durations) == 0 {
+			continue
+		}
+		slices.Sort(durations)
+		var total time.Duration
+		for _, d := range durations {
+			total += d
+		}
+		phases = append(phases, PhaseDuration{
+			Phase:  phase,
+			Steps:  len(durations),
+			Mean:   total / time.Duration(len(durations)),
+			Median: durations[len(durations)/2],
+			Max:    durations[len(durations)-1],
+		})
+	}
+	return phases
+}
+
+// RetryCount holds the number of tasks that needed a number of retries.
+type RetryCount struct {
+	Retries int `json:"retries"`
+	Tasks   int `json:"tasks"`
+}
+
+// RetryDistribution counts the tasks created in [from, to) by their number
+// of retries, from 0 up to the most any task needed.
+func RetryDistribution(tasks []core.Task, from, to time.Time) []RetryCount {
+	dist := []RetryCount{}
+	for i := range tasks {
+		task := &tasks[i]
+		if !inPeriod(task, from, to) {
+			continue
+		}
+		n := retries(task)
+		for len(dist) <= n {
+			dist = append(dist, RetryCount{Retries: len(dist)})
+		}
+		dist[n].Tasks++
+	}
+	return dist
+}
+
+// retries returns the number of attempts of task after the first. Manual
+// redeploys and retests are not retries.
+func retries(task *core.Task) int {
+	attempts := 0
+	for _, a := range task.Attempts {
+		if a.Rerun == "" {
+			attempts++
+		}
+	}
+	return max(attempts-1, 0)
+}
+
+func inPeriod(task *core.Task, from, to time.Time) bool {
+	return !task.CreatedAt.Before(from) && task.CreatedAt.Before(to)
+}
@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rigdev/rig/internal/core"
+)
+
+func analyticsTasks(from time.Time) []core.Task {
+	at := func(d time.Duration) *time.Time { t := from.Add(d); return &t }
+	return []core.Task{
+		{
+			ID: "t1", Status: core.PhaseCompleted, CreatedAt: from.Add(time.Hour), CompletedAt: at(2 * time.Hour),
+			Pipeline: []core.PipelineStep{
+				{Phase: core.PhaseCoding, StartedAt: from, EndedAt: at(10 * time.Minute)},
+				{Phase: core.PhaseTesting, StartedAt: from, EndedAt: at(time.Minute)},
+				{Phase: core.PhaseCompleted, StartedAt: from, EndedAt: at(time.Hour)},
+			},
+			Attempts: []core.Attempt{{FailReason: core.ReasonTest}, {}, {Rerun: core.RerunTest, FailReason: core.ReasonTest}},
+		},
+		{
+			ID: "t2", Status: core.PhaseFailed, CreatedAt: from.Add(25 * time.Hour), CompletedAt: at(26 * time.Hour),
+			Pipeline: []core.PipelineStep{
+				{Phase: core.PhaseCoding, StartedAt: from, EndedAt: at(20 * time.Minute)},
+				{Phase: core.PhaseCoding, StartedAt: from, EndedAt: at(30 * time.Minute)},
+				{Phase: core.PhaseDeploying, StartedAt: from},
+			},
+			Attempts: []core.Attempt{{FailReason: core.ReasonDeploy}},
+		},
+		{ID: "t3", Status: core.PhaseFailed, CreatedAt: from.Add(26 * time.Hour), CompletedAt: at(27 * time.Hour)},
+		{ID: "t4", Status: core.PhaseCoding, CreatedAt: from.Add(-time.Hour)},
+	}
+}
+
+func TestTasksPerDay(t *testing.T) {
+	from := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	days := TasksPerDay(analyticsTasks(from), from, from.AddDate(0, 0, 3))
+
+	want := []DayCount{
+		{Date: "2026-09-01", Created: 1, Completed: 1},
+		{Date: "2026-09-02", Created: 2, Failed: 2},
+		{Date: "2026-09-03"},
+	}
+	if len(days) != len(want) {
+		t.Fatalf("days = %+v", days)
+	}
+	for i := range want {
+		if days[i] != want[i] {
+			t.Errorf("day %d = %+v, want %+v", i, days[i], want[i])
+		}
+	}
+}
+
+func TestFailureReasons(t *testing.T) {
+	from := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	reasons := FailureReasons(analyticsTasks(from), from, from.AddDate(0, 0, 3))
+
+	if len(reasons) != 2 || reasons[0] != (ReasonCount{Reason: core.ReasonDeploy, Tasks: 1}) ||
+		reasons[1] != (ReasonCount{Reason: core.ReasonUnknown, Tasks: 1}) {
+		t.Errorf("reasons = %+v", reasons)
+	}
+}
+
+func TestPhaseDurations(t *testing.T) {
+	from := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	phases := PhaseDurations(analyticsTasks(from), from, from.AddDate(0, 0, 3))
+
+	if len(phases) != 2 || phases[0].Phase != core.PhaseCoding || phases[1].Phase != core.PhaseTesting {
+		t.Fatalf("phases = %+v", phases)
+	}
+	coding := phases[0]
+	if coding.Steps != 3 || coding.Mean != 20*time.Minute || coding.Median != 20*time.Minute || coding.Max != 30*time.Minute {
+		t.Errorf("coding = %+v", coding)
+	}
+}
+
+func TestRetryDistribution(t *testing.T) {
+	from := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	dist := RetryDistribution(analyticsTasks(from), from, from.AddDate(0, 0, 3))
+
+	if len(dist) != 2 || dist[0] != (RetryCount{Retries: 0, Tasks: 2}) || dist[1] != (RetryCount{Retries: 1, Tasks: 1}) {
+		t.Errorf("distribution = %+v", dist)
+	}
+}
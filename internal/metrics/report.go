@@ -0,0 +1,202 @@
+package metrics
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+)
+
+// TaskReport summarizes the tasks created in a period, per repository and
+// in total, for engineering reports.
+type TaskReport struct {
+	From   time.Time   `json:"from"`
+	To     time.Time   `json:"to"`
+	Totals ReportRow   `json:"totals"`
+	Repos  []ReportRow `json:"repos"`
+}
+
+// ReportRow holds the figures of one repository, or of all of them.
+type ReportRow struct {
+	Repo       string `json:"repo,omitempty"`
+	Tasks      int    `json:"tasks"`
+	Completed  int    `json:"completed"`
+	Failed     int    `json:"failed"`
+	InProgress int    `json:"in_progress"`
+	// SuccessRate is the percentage of finished tasks that completed.
+	SuccessRate float64 `json:"success_rate"`
+	// MeanTimeToPR is the mean time from a task's creation to its PR,
+	// over the tasks that opened one.
+	MeanTimeToPR time.Duration `json:"mean_time_to_pr"`
+	// RetriesPerTask is the mean number of attempts after the first.
+	// Manual redeploys and retests are not retries.
+	RetriesPerTask float64 `json:"retries_per_task"`
+	InputTokens    int     `json:"input_tokens"`
+	OutputTokens   int     `json:"output_tokens"`
+	// AICostUSD prices the tokens with ai.pricing; tokens of models
+	// without a price cost nothing.
+	AICostUSD float64 `json:"ai_cost_usd"`
+
+	prs     int
+	toPR    time.Duration
+	retries int
+}
+
+// BuildTaskReport reports on the tasks created in [from, to). A task's
+// tokens are priced at the model of its last attempt, or defaultModel.
+func BuildTaskReport(tasks []core.Task, from, to time.Time, pricing map[string]config.AIPrice, defaultModel string) TaskReport {
+	report := TaskReport{From: from, To: to, Repos: []ReportRow{}}
+	repos := make(map[string]*ReportRow)
+	for i := range tasks {
+		task := &tasks[i]
+		if !inPeriod(task, from, to) {
+			continue
+		}
+		row, ok := repos[task.Issue.Repo]
+		if !ok {
+			row = &ReportRow{Repo: task.Issue.Repo}
+			repos[task.Issue.Repo] = row
+		}
+		for _, r := range []*ReportRow{row, &report.Totals} {
+			r.add(task, pricing, defaultModel)
+		}
+	}
+
+	for _, row := range repos {
+		row.finish()
+		report.Repos = append(report.Repos, *row)
+	}
+	sort.Slice(report.Repos, func(i, j int) bool { return report.Repos[i].Repo < report.Repos[j].Repo })
+	report.Totals.finish()
+	return report
+}
+
+func (r *ReportRow) add(task *core.Task, pricing map[string]config.AIPrice, defaultModel string) {
+	r.Tasks++
+	switch task.Status {
+	case core.PhaseCompleted:
+		r.Completed++
+	case core.PhaseFailed, core.PhaseRollback:
+		r.Failed++
+	default:
+		r.InProgress++
+	}
+	if at, ok := prOpenedAt(task); ok {
+		r.prs++
+		r.toPR += at.Sub(task.CreatedAt)
+	}
+	r.retries += retries(task)
+	model := defaultModel
+	for _, a := range task.Attempts {
+		if a.Rerun == "" && a.Model != "" {
+			model = a.Model
+		}
+	}
+	if u := task.AIUsage; u != nil {
+		r.InputTokens += u.InputTokens
+		r.OutputTokens += u.OutputTokens
+		price := pricing[model]
+		r.AICostUSD += (float64(u.InputTokens)*price.Input + float64(u.OutputTokens)*price.Output) / 1e6
+	}
+}
+
+func (r *ReportRow) finish() {
+	if finished := r.Completed + r.Failed; finished > 0 {
+		r.SuccessRate = float64(r.Completed) / float64(finished) * 100
+	}
+	if r.prs > 0 {
+		r.MeanTimeToPR = (r.toPR / time.Duration(r.prs)).Round(time.Second)
+	}
+	if r.Tasks > 0 {
+		r.RetriesPerTask = float64(r.retries) / float64(r.Tasks)
+	}
+}
+
+// prOpenedAt returns when the task's PR was opened: the end of its
+// reporting step, or the task's completion for tasks without one.
+func prOpenedAt(task *core.Task) (time.Time, bool) {
+	if task.PR == nil {
+		return time.Time{}, false
+	}
+	for _, step := range task.Pipeline {
+		if step.Phase == core.PhaseReporting && step.Status == "success" && step.EndedAt != nil {
+			return *step.EndedAt, true
+		}
+	}
+	if task.CompletedAt != nil {
+		return *task.CompletedAt, true
+	}
+	return time.Time{}, false
+}
+
+// WriteCSV writes the report as CSV: a row per repository, then the totals
+// with the repository "(all)".
+func (r TaskReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"repo", "tasks", "completed", "failed", "in_progress", "success_rate",
+		"mean_time_to_pr_seconds", "retries_per_task", "input_tokens", "output_tokens", "ai_cost_usd"})
+	totals := r.Totals
+	totals.Repo = "(all)"
+	for _, row := range append(r.Repos, totals) {
+		cw.Write([]string{
+			row.Repo,
+			strconv.Itoa(row.Tasks),
+			strconv.Itoa(row.Completed),
+			strconv.Itoa(row.Failed),
+			strconv.Itoa(row.InProgress),
+			fmt.Sprintf("%.1f", row.SuccessRate),
+			strconv.FormatInt(int64(row.MeanTimeToPR/time.Second), 10),
+			fmt.Sprintf("%.2f", row.RetriesPerTask),
+			strconv.Itoa(row.InputTokens),
+			strconv.Itoa(row.OutputTokens),
+			fmt.Sprintf("%.4f", row.AICostUSD),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// defaultReportPeriod is the period reported on when no start is given.
+const defaultReportPeriod = 30 * 24 * time.Hour
+
+// ReportPeriod parses the bounds of a report, each a date (2006-01-02,
+// midnight UTC) or an RFC 3339 time. to defaults to now and from to 30
+// days before to.
+func ReportPeriod(from, to string, now time.Time) (time.Time, time.Time, error) {
+	end := now
+	if to != "" {
+		t, err := parseReportTime(to)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("to: %w", err)
+		}
+		end = t
+	}
+	start := end.Add(-defaultReportPeriod)
+	if from != "" {
+		t, err := parseReportTime(from)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("from: %w", err)
+		}
+		start = t
+	}
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("from must be before to")
+	}
+	return start, end, nil
+}
+
+func parseReportTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a date (2006-01-02) or an RFC 3339 time", s)
+	}
+	return t, nil
+}
@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+)
+
+func TestBuildTaskReport(t *testing.T) {
+	from := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	at := func(d time.Duration) *time.Time { t := from.Add(d); return &t }
+
+	tasks := []core.Task{
+		{
+			ID: "t1", Status: core.PhaseCompleted, Issue: core.Issue{Repo: "acme/app"},
+			CreatedAt: from, CompletedAt: at(3 * time.Hour), PR: &core.PullRequest{ID: "1"},
+			Pipeline: []core.PipelineStep{{Phase: core.PhaseReporting, Status: "success", EndedAt: at(2 * time.Hour)}},
+			Attempts: []core.Attempt{{Model: "small"}, {Model: "big"}, {Rerun: core.RerunDeploy}},
+			AIUsage:  &core.AIUsage{InputTokens: 1_000_000, OutputTokens: 100_000},
+		},
+		{
+			ID: "t2", Status: core.PhaseFailed, Issue: core.Issue{Repo: "acme/app"},
+			CreatedAt: from.Add(time.Hour), Attempts: []core.Attempt{{}},
+			AIUsage: &core.AIUsage{InputTokens: 1_000_000},
+		},
+		{
+			ID: "t3", Status: core.PhaseCompleted, Issue: core.Issue{Repo: "acme/api"},
+			CreatedAt: from.Add(24 * time.Hour), CompletedAt: at(28 * time.Hour), PR: &core.PullRequest{ID: "2"},
+		},
+		{ID: "t4", Status: core.PhaseCoding, Issue: core.Issue{Repo: "acme/api"}, CreatedAt: from.Add(48 * time.Hour)},
+		{ID: "old", Status: core.PhaseCompleted, Issue: core.Issue{Repo: "acme/app"}, CreatedAt: from.Add(-time.Hour)},
+		{ID: "next", Status: core.PhaseCompleted, Issue: core.Issue{Repo: "acme/app"}, CreatedAt: to},
+	}
+	pricing := map[string]config.AIPrice{"big": {Input: 3, Output: 15}, "default": {Input: 1}}
+
+	report := BuildTaskReport(tasks, from, to, pricing, "default")
+
+	if len(report.Repos) != 2 || report.Repos[0].Repo != "acme/api" || report.Repos[1].Repo != "acme/app" {
+		t.Fatalf("repos = %+v", report.Repos)
+	}
+	app, api, all := report.Repos[1], report.Repos[0], report.Totals
+	if app.Tasks != 2 || app.Completed != 1 || app.Failed != 1 || app.SuccessRate != 50 {
+		t.Errorf("acme/app counts = %+v", app)
+	}
+	if app.MeanTimeToPR != 2*time.Hour || app.RetriesPerTask != 0.5 {
+		t.Errorf("acme/app time to PR = %s, retries = %v", app.MeanTimeToPR, app.RetriesPerTask)
+	}
+	// t1 at big: 3 + 1.5; t2 at the default model: 1.
+	if math.Abs(app.AICostUSD-5.5) > 1e-9 || app.InputTokens != 2_000_000 {
+		t.Errorf("acme/app cost = %v, input tokens = %d", app.AICostUSD, app.InputTokens)
+	}
+	if api.InProgress != 1 || api.SuccessRate != 100 || api.MeanTimeToPR != 4*time.Hour {
+		t.Errorf("acme/api = %+v", api)
+	}
+	if all.Tasks != 4 || all.MeanTimeToPR != 3*time.Hour || all.RetriesPerTask != 0.25 {
+		t.Errorf("totals = %+v", all)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "repo,tasks,") || !strings.HasPrefix(lines[3], "(all),4,2,1,1,66.7,10800,0.25,") {
+		t.Errorf("csv =\n%s", buf.String())
+	}
+}
+
+func TestReportPeriod(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	from, to, err := ReportPeriod("", "", now)
+	if err != nil || !to.Equal(now) || !from.Equal(now.AddDate(0, 0, -30)) {
+		t.Errorf("default period = %s – %s, %v", from, to, err)
+	}
+	from, to, err = ReportPeriod("2026-09-01", "2026-10-01T00:00:00Z", now)
+	if err != nil || from.Day() != 1 || to.Month() != time.October {
+		t.Errorf("period = %s – %s, %v", from, to, err)
+	}
+	if _, _, err := ReportPeriod("2026-10-01", "2026-09-01", now); err == nil {
+		t.Error("from after to accepted")
+	}
+	if _, _, err := ReportPeriod("last month", "", now); err == nil {
+		t.Error("bad from accepted")
+	}
+}
@@ -0,0 +1,141 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+)
+
+// kafkaContentType is the Kafka REST proxy's v2 API media type.
+const kafkaContentType = "application/vnd.kafka.v2+json"
+
+// kafkaBroker consumes a topic through a Kafka REST proxy (the v2 consumer
+// API of Confluent's REST proxy and compatible ones). Offsets are committed
+// per message once it is handled; records that were not committed come
+// again when the consumer reconnects, so nack does nothing.
+type kafkaBroker struct {
+	client *http.Client
+	// base is the consumer instance's URI.
+	base string
+}
+
+func openKafka(ctx context.Context, q config.QueueConfig) (broker, error) {
+	b := &kafkaBroker{client: &http.Client{Timeout: receiveWait + 10*time.Second}}
+	var created struct {
+		BaseURI string `json:"base_uri"`
+	}
+	err := b.call(ctx, http.MethodPost, strings.TrimRight(q.URL, "/")+"/consumers/"+url.PathEscape(q.Group), map[string]string{
+		"format":             "binary",
+		"auto.offset.reset":  "earliest",
+		"auto.commit.enable": "false",
+	}, &created)
+	if err != nil {
+		return nil, fmt.Errorf("create consumer in group %s: %w", q.Group, err)
+	}
+	if created.BaseURI == "" {
+		return nil, fmt.Errorf("create consumer in group %s: the proxy returned no base_uri", q.Group)
+	}
+	b.base = created.BaseURI
+	if err := b.call(ctx, http.MethodPost, b.base+"/subscription", map[string][]string{"topics": {q.Topic}}, nil); err != nil {
+		b.close()
+		return nil, fmt.Errorf("subscribe to %s: %w", q.Topic, err)
+	}
+	return b, nil
+}
+
+// kafkaRecord is a record as the REST proxy returns it in binary format.
+type kafkaRecord struct {
+	Topic     string `json:"topic"`
+	Value     []byte `json:"value"`
+	Partition int    `json:"partition"`
+	Offset    int64  `json:"offset"`
+}
+
+func (b *kafkaBroker) receive(ctx context.Context) ([]message, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		fmt.Sprintf("%s/records?timeout=%d", b.base, receiveWait.Milliseconds()), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.kafka.binary.v2+json")
+	var records []kafkaRecord
+	if err := b.do(req, &records); err != nil {
+		return nil, fmt.Errorf("fetch records: %w", err)
+	}
+	msgs := make([]message, 0, len(records))
+	for _, r := range records {
+		msgs = append(msgs, message{
+			id:   fmt.Sprintf("%s/%d/%d", r.Topic, r.Partition, r.Offset),
+			body: r.Value,
+			ack:  func(ctx context.Context) error { return b.commit(ctx, r) },
+			nack: func(context.Context) error { return nil },
+		})
+	}
+	return msgs, nil
+}
+
+// commit commits the offset after r for its partition.
+func (b *kafkaBroker) commit(ctx context.Context, r kafkaRecord) error {
+	offsets := map[string][]map[string]any{"offsets": {{"topic": r.Topic, "partition": r.Partition, "offset": r.Offset}}}
+	return b.call(ctx, http.MethodPost, b.base+"/offsets", offsets, nil)
+}
+
+// close deletes the consumer instance so the group rebalances right away.
+func (b *kafkaBroker) close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return b.call(ctx, http.MethodDelete, b.base, nil, nil)
+}
+
+// call sends body as JSON to the proxy and decodes the response into out
+// when it is non-nil.
+func (b *kafkaBroker) call(ctx context.Context, method, target string, body, out any) error {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", kafkaContentType)
+	req.Header.Set("Accept", kafkaContentType)
+	return b.do(req, out)
+}
+
+func (b *kafkaBroker) do(req *http.Request, out any) error {
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Message != "" {
+			return fmt.Errorf("REST proxy returned %s: %s", resp.Status, apiErr.Message)
+		}
+		return fmt.Errorf("REST proxy returned %s", resp.Status)
+	}
+	if out == nil || len(body) == 0 {
+		return nil
+	}
+	return json.Unmarshal(body, out)
+}
@@ -0,0 +1,78 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rigdev/rig/internal/config"
+)
+
+func TestKafkaBroker(t *testing.T) {
+	var calls []string
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		calls = append(calls, strings.TrimSpace(r.Method+" "+r.URL.Path+" "+string(body)))
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/consumers/rig-workers":
+			json.NewEncoder(w).Encode(map[string]string{"instance_id": "c1", "base_uri": srv.URL + "/consumers/rig-workers/instances/c1"})
+		case r.URL.Path == "/consumers/rig-workers/instances/c1/records":
+			if r.Header.Get("Accept") != "application/vnd.kafka.binary.v2+json" {
+				t.Errorf("records Accept = %s", r.Header.Get("Accept"))
+			}
+			json.NewEncoder(w).Encode([]map[string]any{
+				{"topic": "issues", "partition": 2, "offset": 17, "value": []byte(`{"id":"42"}`)},
+			})
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	b, err := openKafka(context.Background(), config.QueueConfig{Broker: "kafka", URL: srv.URL + "/", Topic: "issues", Group: "rig-workers"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs, err := b.receive(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 || msgs[0].id != "issues/2/17" || string(msgs[0].body) != `{"id":"42"}` {
+		t.Fatalf("unexpected messages %+v", msgs)
+	}
+	if err := msgs[0].ack(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`POST /consumers/rig-workers {"auto.commit.enable":"false","auto.offset.reset":"earliest","format":"binary"}`,
+		`POST /consumers/rig-workers/instances/c1/subscription {"topics":["issues"]}`,
+		`GET /consumers/rig-workers/instances/c1/records`,
+		`POST /consumers/rig-workers/instances/c1/offsets {"offsets":[{"offset":17,"partition":2,"topic":"issues"}]}`,
+		`DELETE /consumers/rig-workers/instances/c1`,
+	}
+	if strings.Join(calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("calls:\n%s\nwant:\n%s", strings.Join(calls, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestKafkaBrokerProxyError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"error_code":40902,"message":"Consumer instance with the specified name already exists."}`))
+	}))
+	defer srv.Close()
+
+	_, err := openKafka(context.Background(), config.QueueConfig{Broker: "kafka", URL: srv.URL, Topic: "issues", Group: "rig"})
+	if err == nil || !strings.Contains(err.Error(), "409 Conflict: Consumer instance") {
+		t.Errorf("expected the proxy's error, got %v", err)
+	}
+}
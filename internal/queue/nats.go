@@ -0,0 +1,88 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/rigdev/rig/internal/config"
+)
+
+// natsBatch is how many messages a NATS fetch takes at most.
+const natsBatch = 10
+
+// natsBroker pulls from a durable JetStream consumer on the stream that
+// stores the subject. Core NATS subjects without a stream are not
+// supported: they would lose messages while rig is down.
+type natsBroker struct {
+	conn     *nats.Conn
+	consumer jetstream.Consumer
+}
+
+func openNATS(ctx context.Context, q config.QueueConfig) (broker, error) {
+	conn, err := nats.Connect(q.URL, nats.Name("rig"))
+	if err != nil {
+		return nil, fmt.Errorf("connect to %s: %w", q.URL, err)
+	}
+	b := &natsBroker{conn: conn}
+	if err := b.subscribe(ctx, q); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *natsBroker) subscribe(ctx context.Context, q config.QueueConfig) error {
+	js, err := jetstream.New(b.conn)
+	if err != nil {
+		return err
+	}
+	stream, err := js.StreamNameBySubject(ctx, q.Topic)
+	if err != nil {
+		return fmt.Errorf("find the JetStream stream of %s: %w", q.Topic, err)
+	}
+	b.consumer, err = js.CreateOrUpdateConsumer(ctx, stream, jetstream.ConsumerConfig{
+		Durable:       q.Group,
+		FilterSubject: q.Topic,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+	})
+	if err != nil {
+		return fmt.Errorf("create consumer %s on stream %s: %w", q.Group, stream, err)
+	}
+	return nil
+}
+
+func (b *natsBroker) receive(ctx context.Context) ([]message, error) {
+	fetchCtx, cancel := context.WithTimeout(ctx, receiveWait)
+	defer cancel()
+	batch, err := b.consumer.Fetch(natsBatch, jetstream.FetchContext(fetchCtx))
+	if err != nil {
+		return nil, err
+	}
+	var msgs []message
+	for m := range batch.Messages() {
+		id := ""
+		if meta, err := m.Metadata(); err == nil {
+			id = strconv.FormatUint(meta.Sequence.Stream, 10)
+		}
+		msgs = append(msgs, message{
+			id:   id,
+			body: m.Data(),
+			ack:  func(context.Context) error { return m.Ack() },
+			nack: func(context.Context) error { return m.Nak() },
+		})
+	}
+	if err := batch.Error(); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		// Messages taken so far come again once their ack wait passes.
+		return nil, err
+	}
+	return msgs, nil
+}
+
+func (b *natsBroker) close() error {
+	b.conn.Close()
+	return nil
+}
@@ -0,0 +1,365 @@
+// Package queue takes issues from message queues for rig serve, next to
+// the webhook server: NATS JetStream, Kafka through a Kafka REST proxy, and
+// Amazon SQS.
+//
+// Delivery is at least once. A message is acknowledged once the task it
+// starts is recorded in the state, so a restart finds the task, and a
+// message whose issue already has a task in flight is acknowledged without
+// starting another, so redeliveries are harmless. Messages that cannot be
+// read are logged and dropped rather than redelivered forever.
+package queue
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/storage"
+)
+
+// ExecuteFunc runs the task of an issue taken from a queue. It may run
+// the whole task before it returns.
+type ExecuteFunc func(issue core.Issue) error
+
+// AuditFunc records a started task in the audit log. storage.DB.RecordAudit
+// matches it.
+type AuditFunc func(e storage.AuditEntry) error
+
+// message is one message received from a broker.
+type message struct {
+	id   string
+	body []byte
+	// ack removes the message from the queue; nack hands it back for
+	// redelivery.
+	ack  func(ctx context.Context) error
+	nack func(ctx context.Context) error
+}
+
+// broker is a connection to one queue.
+type broker interface {
+	// receive waits for the next messages. It returns none when it waited
+	// a while without any.
+	receive(ctx context.Context) ([]message, error)
+	close() error
+}
+
+// Timings, variables so tests can shorten them.
+var (
+	// receiveWait is how long a broker waits for messages per receive.
+	receiveWait = 20 * time.Second
+	// recordPollInterval is how often a started task is looked for in the
+	// state.
+	recordPollInterval = 100 * time.Millisecond
+	// maxReconnectBackoff caps the wait before reconnecting.
+	maxReconnectBackoff = time.Minute
+)
+
+// Consumer takes issues from one queue and starts their tasks.
+type Consumer struct {
+	name      string
+	cfg       *config.Config
+	queue     config.QueueConfig
+	open      func(ctx context.Context) (broker, error)
+	statePath string
+	onExecute ExecuteFunc
+	audit     AuditFunc
+}
+
+// New creates a consumer of q that starts tasks with onExecute. cfg
+// resolves the projects messages name.
+func New(cfg *config.Config, q config.QueueConfig, statePath string, onExecute ExecuteFunc) (*Consumer, error) {
+	c := &Consumer{
+		name:      q.Name,
+		cfg:       cfg,
+		queue:     q,
+		statePath: statePath,
+		onExecute: onExecute,
+	}
+	if c.name == "" {
+		c.name = q.Broker
+	}
+	if q.Group == "" {
+		c.queue.Group = "rig"
+	}
+	switch q.Broker {
+	case "nats":
+		c.open = func(ctx context.Context) (broker, error) { return openNATS(ctx, c.queue) }
+	case "kafka":
+		c.open = func(ctx context.Context) (broker, error) { return openKafka(ctx, c.queue) }
+	case "sqs":
+		sqs, err := newSQS(c.queue)
+		if err != nil {
+			return nil, err
+		}
+		c.open = func(ctx context.Context) (broker, error) { return sqs, nil }
+	default:
+		return nil, fmt.Errorf("queue %s: unknown broker %q", c.name, q.Broker)
+	}
+	return c, nil
+}
+
+// Name is the queue's name, or its broker when it has none.
+func (c *Consumer) Name() string {
+	return c.name
+}
+
+// SetAuditFunc records every started task with the queue as actor.
+func (c *Consumer) SetAuditFunc(fn AuditFunc) {
+	c.audit = fn
+}
+
+// Run consumes the queue until ctx is done, reconnecting with a backoff
+// when the connection fails or a message has to be redelivered.
+func (c *Consumer) Run(ctx context.Context) {
+	failures := 0
+	for ctx.Err() == nil {
+		start := time.Now()
+		err := c.consume(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(start) > maxReconnectBackoff {
+			failures = 0
+		}
+		failures++
+		wait := reconnectBackoff(failures)
+		slog.Warn("queue: consumer stopped; reconnecting", "queue", c.name, "err", err, "in", wait)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// reconnectBackoff is the wait after the nth consecutive failure: 1s, 2s,
+// 4s, ... capped at maxReconnectBackoff.
+func reconnectBackoff(failures int) time.Duration {
+	d := time.Second << (failures - 1)
+	if d > maxReconnectBackoff || d <= 0 {
+		return maxReconnectBackoff
+	}
+	return d
+}
+
+// consume handles messages until the connection fails or one of them
+// fails. The failed message and the rest of its batch are handed back; a
+// Kafka consumer only gets them again once it reconnects, which is why
+// consume returns.
+func (c *Consumer) consume(ctx context.Context) error {
+	b, err := c.open(ctx)
+	if err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+	defer b.close()
+	slog.Info("queue: consuming", "queue", c.name, "broker", c.queue.Broker, "topic", c.queue.Topic)
+
+	for {
+		msgs, err := b.receive(ctx)
+		if err != nil {
+			return err
+		}
+		for i, m := range msgs {
+			if err := c.handle(ctx, m); err != nil {
+				for _, rest := range msgs[i:] {
+					if nerr := rest.nack(ctx); nerr != nil {
+						slog.Warn("queue: failed to hand message back", "queue", c.name, "message", rest.id, "err", nerr)
+					}
+				}
+				return fmt.Errorf("message %s: %w", m.id, err)
+			}
+			if err := m.ack(ctx); err != nil {
+				// It will come again and be skipped as in flight.
+				slog.Warn("queue: failed to acknowledge message", "queue", c.name, "message", m.id, "err", err)
+			}
+		}
+	}
+}
+
+// handle starts the task of m unless its issue already has one in flight.
+// A non-nil error means m is worth redelivering.
+func (c *Consumer) handle(ctx context.Context, m message) error {
+	issue, err := c.issue(m.body)
+	if err != nil {
+		slog.Warn("queue: dropping message", "queue", c.name, "message", m.id, "err", err)
+		return nil
+	}
+
+	state, err := core.LoadState(c.statePath)
+	if err != nil {
+		return fmt.Errorf("load state: %w", err)
+	}
+	if state.IsInFlight(issue.ID) {
+		slog.Info("queue: issue already in flight, skipping", "queue", c.name, "issue", issue.ID)
+		return nil
+	}
+
+	if err := c.start(ctx, issue); err != nil {
+		return err
+	}
+	slog.Info("queue: started task", "queue", c.name, "message", m.id, "issue", issue.Repo+"#"+issue.ID)
+
+	if c.audit != nil {
+		err := c.audit(storage.AuditEntry{
+			Actor:   "queue:" + c.name,
+			Source:  storage.AuditSourceQueue,
+			Action:  storage.AuditTaskCreated,
+			Target:  issue.Repo + "#" + issue.ID,
+			Details: c.queue.Broker + " message " + m.id,
+		})
+		if err != nil {
+			slog.Warn("queue: audit failed", "err", err)
+		}
+	}
+	return nil
+}
+
+// start runs the task of issue in the background and returns once the
+// task is recorded in the state. An error means no task was recorded.
+func (c *Consumer) start(ctx context.Context, issue core.Issue) error {
+	if c.onExecute == nil {
+		return nil
+	}
+	since := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		err := c.onExecute(issue)
+		if err != nil {
+			slog.Error("queue: execute failed", "queue", c.name, "issue", issue.ID, "err", err)
+		}
+		done <- err
+	}()
+
+	ticker := time.NewTicker(recordPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-done:
+			if err != nil && !c.recorded(issue.ID, since) {
+				return fmt.Errorf("execute: %w", err)
+			}
+			return nil
+		case <-ticker.C:
+			if c.recorded(issue.ID, since) {
+				return nil
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// recorded reports whether the state has a task for issueID created since.
+func (c *Consumer) recorded(issueID string, since time.Time) bool {
+	state, err := core.LoadState(c.statePath)
+	if err != nil {
+		return false
+	}
+	for _, t := range state.Tasks {
+		if t.Issue.ID == issueID && !t.CreatedAt.Before(since) {
+			return true
+		}
+	}
+	return false
+}
+
+// issue maps a JSON message to an issue of a configured project.
+func (c *Consumer) issue(body []byte) (core.Issue, error) {
+	var msg any
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&msg); err != nil {
+		return core.Issue{}, fmt.Errorf("parse message: %w", err)
+	}
+	m := c.queue.Mapping
+	issue := core.Issue{
+		Platform: text(lookup(msg, m.Platform, "platform")),
+		Repo:     text(lookup(msg, m.Repo, "repo")),
+		ID:       text(lookup(msg, m.ID, "id")),
+		Title:    text(lookup(msg, m.Title, "title")),
+		Body:     text(lookup(msg, m.Body, "body")),
+		URL:      text(lookup(msg, m.URL, "url")),
+		Labels:   labels(lookup(msg, m.Labels, "labels")),
+	}
+	if issue.ID == "" {
+		return core.Issue{}, fmt.Errorf("message has no issue id at %s", orDefault(m.ID, "id"))
+	}
+
+	name := issue.Repo
+	if name == "" {
+		name = orDefault(c.queue.Project, c.cfg.Source.Repo)
+	}
+	project := c.cfg.FindProject(name)
+	if project == nil {
+		return core.Issue{}, fmt.Errorf("repo %s is not the source repo or a projects entry", name)
+	}
+	issue.Repo = project.Repo
+	if issue.Platform == "" {
+		issue.Platform = orDefault(project.Platform, "github")
+	}
+	if issue.Title == "" {
+		issue.Title = "Issue #" + issue.ID
+	}
+	return issue, nil
+}
+
+// lookup returns the value at the dotted path in v, or at field when path
+// is empty; nil when there is none.
+func lookup(v any, path, field string) any {
+	for _, key := range strings.Split(orDefault(path, field), ".") {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = obj[key]
+	}
+	return v
+}
+
+// text renders a JSON scalar as a string; other values are "".
+func text(v any) string {
+	switch v := v.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
+
+// labels reads a list of label names or of objects with a name.
+func labels(v any) []string {
+	var out []string
+	switch v := v.(type) {
+	case string:
+		if v != "" {
+			out = append(out, v)
+		}
+	case []any:
+		for _, l := range v {
+			if obj, ok := l.(map[string]any); ok {
+				l = obj["name"]
+			}
+			if name := text(l); name != "" {
+				out = append(out, name)
+			}
+		}
+	}
+	return out
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
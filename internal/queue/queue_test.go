@@ -0,0 +1,223 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/storage"
+)
+
+func testConfig() *config.Config {
+	return &config.Config{
+		Source:   config.SourceConfig{Platform: "github", Repo: "acme/app"},
+		Projects: []config.ProjectEntry{{Name: "infra", Platform: "gitlab", Repo: "ops/infra"}},
+	}
+}
+
+// fakeBroker hands out its batches in order, then waits for ctx.
+type fakeBroker struct {
+	mu      sync.Mutex
+	batches [][]message
+	acked   []string
+	nacked  []string
+}
+
+func (b *fakeBroker) add(bodies ...string) {
+	var batch []message
+	for i, body := range bodies {
+		id := string(rune('a'+len(b.batches))) + string(rune('1'+i))
+		batch = append(batch, message{
+			id:   id,
+			body: []byte(body),
+			ack:  func(context.Context) error { b.record(&b.acked, id); return nil },
+			nack: func(context.Context) error { b.record(&b.nacked, id); return nil },
+		})
+	}
+	b.batches = append(b.batches, batch)
+}
+
+func (b *fakeBroker) record(list *[]string, id string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	*list = append(*list, id)
+}
+
+func (b *fakeBroker) receive(ctx context.Context) ([]message, error) {
+	b.mu.Lock()
+	if len(b.batches) > 0 {
+		batch := b.batches[0]
+		b.batches = b.batches[1:]
+		b.mu.Unlock()
+		return batch, nil
+	}
+	b.mu.Unlock()
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (b *fakeBroker) close() error { return nil }
+
+func newTestConsumer(t *testing.T, q config.QueueConfig, b *fakeBroker, onExecute ExecuteFunc) (*Consumer, string) {
+	t.Helper()
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	c, err := New(testConfig(), q, statePath, onExecute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.open = func(context.Context) (broker, error) { return b, nil }
+	return c, statePath
+}
+
+func TestConsumerIssueMapping(t *testing.T) {
+	github := config.QueueMappingConfig{
+		Repo:   "repository.full_name",
+		ID:     "issue.number",
+		Title:  "issue.title",
+		URL:    "issue.html_url",
+		Labels: "issue.labels",
+	}
+	tests := []struct {
+		name    string
+		queue   config.QueueConfig
+		body    string
+		want    core.Issue
+		wantErr string
+	}{
+		{
+			name: "issue fields",
+			body: `{"repo":"ops/infra","id":"12","title":"Rotate certs","labels":["rig","ops"]}`,
+			want: core.Issue{Platform: "gitlab", Repo: "ops/infra", ID: "12", Title: "Rotate certs", Labels: []string{"rig", "ops"}},
+		},
+		{
+			name:  "mapped GitHub payload",
+			queue: config.QueueConfig{Mapping: github},
+			body:  `{"repository":{"full_name":"acme/app"},"issue":{"number":42,"title":"Fix login","html_url":"https://github.com/acme/app/issues/42","labels":[{"name":"bug"}]}}`,
+			want:  core.Issue{Platform: "github", Repo: "acme/app", ID: "42", Title: "Fix login", URL: "https://github.com/acme/app/issues/42", Labels: []string{"bug"}},
+		},
+		{
+			name:  "queue project",
+			queue: config.QueueConfig{Project: "infra"},
+			body:  `{"id":7}`,
+			want:  core.Issue{Platform: "gitlab", Repo: "ops/infra", ID: "7", Title: "Issue #7"},
+		},
+		{
+			name: "source repo by default",
+			body: `{"id":"8","title":"Slow search"}`,
+			want: core.Issue{Platform: "github", Repo: "acme/app", ID: "8", Title: "Slow search"},
+		},
+		{name: "unknown repo", body: `{"repo":"other/repo","id":"1"}`, wantErr: "other/repo is not the source repo"},
+		{name: "no id", queue: config.QueueConfig{Mapping: github}, body: `{"issue":{}}`, wantErr: "no issue id at issue.number"},
+		{name: "not JSON", body: `issue 42`, wantErr: "parse message"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.queue.Broker = "kafka"
+			c, err := New(testConfig(), tt.queue, "", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := c.issue([]byte(tt.body))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("issue = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConsumerAcksOnceTaskIsRecorded(t *testing.T) {
+	defer func(d time.Duration) { recordPollInterval = d }(recordPollInterval)
+	recordPollInterval = 5 * time.Millisecond
+
+	b := &fakeBroker{}
+	b.add(`{"id":"5","title":"Fix login"}`, `{"id":"5","title":"Fix login"}`, `not json`)
+	var statePath string
+	started := make(chan core.Issue, 3)
+	release := make(chan struct{})
+	c, statePath := newTestConsumer(t, config.QueueConfig{Name: "issues", Broker: "kafka"}, b, func(issue core.Issue) error {
+		started <- issue
+		if err := core.WithState(statePath, func(s *core.State) error {
+			s.CreateTask(issue)
+			return nil
+		}); err != nil {
+			return err
+		}
+		// The task keeps running after its message is acknowledged.
+		<-release
+		return nil
+	})
+	defer close(release)
+	var audit []storage.AuditEntry
+	c.SetAuditFunc(func(e storage.AuditEntry) error {
+		audit = append(audit, e)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- c.consume(ctx) }()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		b.mu.Lock()
+		n := len(b.acked)
+		b.mu.Unlock()
+		if n == 3 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("consume returned %v", err)
+	}
+
+	if !reflect.DeepEqual(b.acked, []string{"a1", "a2", "a3"}) || len(b.nacked) != 0 {
+		t.Errorf("acked %v, nacked %v; want all acked", b.acked, b.nacked)
+	}
+	if len(started) != 1 || (<-started).Repo != "acme/app" {
+		t.Errorf("started %d tasks, want one for acme/app#5", len(started))
+	}
+	if len(audit) != 1 || audit[0].Actor != "queue:issues" || audit[0].Source != storage.AuditSourceQueue || audit[0].Target != "acme/app#5" {
+		t.Errorf("audit = %+v", audit)
+	}
+}
+
+func TestConsumerHandsBackFailedMessages(t *testing.T) {
+	b := &fakeBroker{}
+	b.add(`{"id":"5"}`, `{"id":"6"}`)
+	c, _ := newTestConsumer(t, config.QueueConfig{Broker: "kafka"}, b, func(issue core.Issue) error {
+		return errors.New("rig serve is shutting down")
+	})
+
+	err := c.consume(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "message a1") || !strings.Contains(err.Error(), "shutting down") {
+		t.Errorf("consume returned %v", err)
+	}
+	if len(b.acked) != 0 || !reflect.DeepEqual(b.nacked, []string{"a1", "a2"}) {
+		t.Errorf("acked %v, nacked %v; want both handed back", b.acked, b.nacked)
+	}
+}
+
+func TestReconnectBackoff(t *testing.T) {
+	for failures, want := range map[int]time.Duration{1: time.Second, 3: 4 * time.Second, 7: time.Minute, 100: time.Minute} {
+		if got := reconnectBackoff(failures); got != want {
+			t.Errorf("reconnectBackoff(%d) = %s, want %s", failures, got, want)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+)
+
+// sqsBroker long-polls an SQS queue over its JSON API, signed with
+// Signature Version 4. Deleting a message acknowledges it; a nack makes it
+// visible again right away.
+type sqsBroker struct {
+	client   *http.Client
+	queueURL string
+	// endpoint is the queue URL's scheme and host, where API calls go.
+	endpoint string
+	region   string
+	creds    awsCredentials
+	now      func() time.Time
+}
+
+// awsCredentials sign SQS requests.
+type awsCredentials struct {
+	accessKey    string
+	secretKey    string
+	sessionToken string
+}
+
+// newSQS reads the region from the queue URL unless q sets it, and the
+// credentials from the standard AWS environment variables.
+func newSQS(q config.QueueConfig) (*sqsBroker, error) {
+	u, err := url.Parse(q.URL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("sqs: invalid queue URL %q", q.URL)
+	}
+	region := q.Region
+	if region == "" {
+		region = sqsRegion(u.Hostname())
+	}
+	if region == "" {
+		return nil, fmt.Errorf("sqs: no region in %s; set region", u.Host)
+	}
+	creds := awsCredentials{
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if creds.accessKey == "" || creds.secretKey == "" {
+		return nil, errors.New("sqs: AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+	}
+	return &sqsBroker{
+		client:   &http.Client{Timeout: receiveWait + 10*time.Second},
+		queueURL: q.URL,
+		endpoint: u.Scheme + "://" + u.Host + "/",
+		region:   region,
+		creds:    creds,
+		now:      time.Now,
+	}, nil
+}
+
+// sqsRegion takes the region from an SQS host name such as
+// sqs.eu-west-1.amazonaws.com or eu-west-1.queue.amazonaws.com.
+func sqsRegion(host string) string {
+	parts := strings.Split(host, ".")
+	switch {
+	case len(parts) >= 4 && parts[0] == "sqs":
+		return parts[1]
+	case len(parts) >= 4 && parts[1] == "queue":
+		return parts[0]
+	}
+	return ""
+}
+
+func (b *sqsBroker) receive(ctx context.Context) ([]message, error) {
+	var out struct {
+		Messages []struct {
+			MessageID     string `json:"MessageId"`
+			ReceiptHandle string `json:"ReceiptHandle"`
+			Body          string `json:"Body"`
+		} `json:"Messages"`
+	}
+	err := b.call(ctx, "ReceiveMessage", map[string]any{
+		"QueueUrl":            b.queueURL,
+		"MaxNumberOfMessages": 10,
+		"WaitTimeSeconds":     int(receiveWait.Seconds()),
+	}, &out)
+	if err != nil {
+		return nil, err
+	}
+	msgs := make([]message, 0, len(out.Messages))
+	for _, m := range out.Messages {
+		handle := m.ReceiptHandle
+		msgs = append(msgs, message{
+			id:   m.MessageID,
+			body: []byte(m.Body),
+			ack: func(ctx context.Context) error {
+				return b.call(ctx, "DeleteMessage", map[string]any{"QueueUrl": b.queueURL, "ReceiptHandle": handle}, nil)
+			},
+			nack: func(ctx context.Context) error {
+				return b.call(ctx, "ChangeMessageVisibility", map[string]any{"QueueUrl": b.queueURL, "ReceiptHandle": handle, "VisibilityTimeout": 0}, nil)
+			},
+		})
+	}
+	return msgs, nil
+}
+
+func (b *sqsBroker) close() error {
+	return nil
+}
+
+// call invokes an SQS action with input as its JSON request and decodes
+// the response into out when it is non-nil.
+func (b *sqsBroker) call(ctx context.Context, action string, input, out any) error {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.0")
+	req.Header.Set("X-Amz-Target", "AmazonSQS."+action)
+	signV4(req, body, b.creds, b.region, "sqs", b.now())
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sqs %s: %w", action, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return fmt.Errorf("sqs %s: %w", action, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Type != "" {
+			return fmt.Errorf("sqs %s: %s: %s", action, apiErr.Type, apiErr.Message)
+		}
+		return fmt.Errorf("sqs %s returned %s", action, resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("sqs %s: decode response: %w", action, err)
+	}
+	return nil
+}
+
+// signV4 signs req, whose body is body, with AWS Signature Version 4. It
+// signs the host and every header already set.
+func signV4(req *http.Request, body []byte, creds awsCredentials, region, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.sessionToken)
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	payloadHash := sha256.Sum256(body)
+	canonical := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	canonicalHash := sha256.Sum256([]byte(canonical))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+creds.secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,102 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+)
+
+// The get-vanilla case of the AWS Signature Version 4 test suite.
+func TestSignV4(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	creds := awsCredentials{accessKey: "AKIDEXAMPLE", secretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	signV4(req, nil, creds, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization = %s\nwant %s", got, want)
+	}
+}
+
+func TestSQSRegion(t *testing.T) {
+	for host, want := range map[string]string{
+		"sqs.eu-west-1.amazonaws.com":     "eu-west-1",
+		"us-east-2.queue.amazonaws.com":   "us-east-2",
+		"sqs.cn-north-1.amazonaws.com.cn": "cn-north-1",
+		"localhost":                       "",
+	} {
+		if got := sqsRegion(host); got != want {
+			t.Errorf("sqsRegion(%s) = %q, want %q", host, got, want)
+		}
+	}
+}
+
+func TestSQSBroker(t *testing.T) {
+	var calls []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") || r.Header.Get("X-Amz-Security-Token") != "token" {
+			http.Error(w, `{"__type":"InvalidSignature","message":"bad signature"}`, http.StatusForbidden)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var input map[string]any
+		json.Unmarshal(body, &input)
+		action := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "AmazonSQS.")
+		calls = append(calls, action+" "+input["QueueUrl"].(string))
+		switch action {
+		case "ReceiveMessage":
+			json.NewEncoder(w).Encode(map[string]any{"Messages": []map[string]string{
+				{"MessageId": "m-1", "ReceiptHandle": "h-1", "Body": `{"id":"42"}`},
+			}})
+		case "DeleteMessage", "ChangeMessageVisibility":
+			if input["ReceiptHandle"] != "h-1" {
+				t.Errorf("%s of %v", action, input["ReceiptHandle"])
+			}
+			w.Write([]byte("{}"))
+		}
+	}))
+	defer srv.Close()
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "token")
+	queueURL := srv.URL + "/000000000000/rig"
+	b, err := newSQS(config.QueueConfig{Broker: "sqs", URL: queueURL, Region: "us-east-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs, err := b.receive(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 || msgs[0].id != "m-1" || string(msgs[0].body) != `{"id":"42"}` {
+		t.Fatalf("unexpected messages %+v", msgs)
+	}
+	if err := msgs[0].nack(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := msgs[0].ack(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"ReceiveMessage " + queueURL, "ChangeMessageVisibility " + queueURL, "DeleteMessage " + queueURL}
+	if strings.Join(calls, ",") != strings.Join(want, ",") {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+
+	t.Setenv("AWS_SESSION_TOKEN", "")
+	b, _ = newSQS(config.QueueConfig{Broker: "sqs", URL: queueURL, Region: "us-east-1"})
+	if _, err := b.receive(context.Background()); err == nil || !strings.Contains(err.Error(), "InvalidSignature: bad signature") {
+		t.Errorf("expected the API error, got %v", err)
+	}
+	if _, err := newSQS(config.QueueConfig{Broker: "sqs", URL: queueURL}); err == nil || !strings.Contains(err.Error(), "set region") {
+		t.Errorf("expected a missing region error, got %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// awsSecrets reads secrets from AWS Secrets Manager. Credentials and region
+// come from the standard AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY,
+// AWS_SESSION_TOKEN and AWS_REGION (or AWS_DEFAULT_REGION) variables;
+// AWS_ENDPOINT_URL_SECRETS_MANAGER or AWS_ENDPOINT_URL point it elsewhere,
+// such as at LocalStack. Paths are a secret name or ARN, with #field to
+// pick a key of a JSON secret.
+type awsSecrets struct {
+	client *http.Client
+	now    func() time.Time
+}
+
+func newAWS() *awsSecrets {
+	return &awsSecrets{client: &http.Client{Timeout: resolveTimeout}, now: time.Now}
+}
+
+// awsCredentials signs requests.
+type awsCredentials struct {
+	AccessKeyID, SecretAccessKey, SessionToken string
+}
+
+func (a *awsSecrets) Resolve(ctx context.Context, ref string) (string, error) {
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		return "", errors.New("AWS_REGION is not set")
+	}
+	creds := awsCredentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return "", errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are not set")
+	}
+	endpoint := os.Getenv("AWS_ENDPOINT_URL_SECRETS_MANAGER")
+	if endpoint == "" {
+		endpoint = os.Getenv("AWS_ENDPOINT_URL")
+	}
+	if endpoint == "" {
+		endpoint = "https://secretsmanager." + region + ".amazonaws.com"
+	}
+
+	id, key, _ := strings.Cut(ref, "#")
+	body, _ := json.Marshal(map[string]string{"SecretId": id})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(endpoint, "/")+"/", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	signV4(req, body, creds, region, "secretsmanager", a.now())
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("aws: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("aws: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Type string `json:"__type"`
+		}
+		_ = json.Unmarshal(data, &apiErr)
+		return "", fmt.Errorf("aws: %s %s", resp.Status, apiErr.Type)
+	}
+
+	var out struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return "", fmt.Errorf("aws: decode response: %w", err)
+	}
+	if key == "" {
+		return out.SecretString, nil
+	}
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(out.SecretString), &fields); err != nil {
+		return "", errors.New("aws: secret is not a JSON object; drop the #field")
+	}
+	return field(fields, key)
+}
+
+// signV4 adds AWS Signature Version 4 headers to req.
+func signV4(req *http.Request, body []byte, creds awsCredentials, region, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, vals := range req.Header {
+		headers[strings.ToLower(name)] = strings.Join(vals, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonHeaders strings.Builder
+	for _, name := range names {
+		canonHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	query := strings.ReplaceAll(req.URL.Query().Encode(), "+", "%20")
+	canonRequest := strings.Join([]string{
+		req.Method, path, query, canonHeaders.String(), signedHeaders, sha256Hex(body),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonRequest))
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	for _, part := range []string{region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
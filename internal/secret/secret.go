@@ -0,0 +1,165 @@
+// Package secret resolves the ${secret:...} references of rig.yaml and the
+// dashboard settings, so tokens and API keys can live in a secret store
+// instead of the config file or the settings database.
+//
+// A reference names a provider and a path: env:NAME, file:/run/secrets/x,
+// vault:secret/data/rig#token or aws:prod/rig#token. Without a provider
+// prefix the path is a Vault path. Values are resolved when the config is
+// loaded and remembered only so Redact can keep them out of logs.
+package secret
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Resolver looks up a secret by the path after its provider prefix.
+type Resolver interface {
+	Resolve(ctx context.Context, path string) (string, error)
+}
+
+// ResolverFunc adapts a function to Resolver.
+type ResolverFunc func(ctx context.Context, path string) (string, error)
+
+// Resolve calls f.
+func (f ResolverFunc) Resolve(ctx context.Context, path string) (string, error) {
+	return f(ctx, path)
+}
+
+// DefaultProvider resolves references without a provider prefix.
+const DefaultProvider = "vault"
+
+// resolveTimeout bounds a single lookup against a remote store.
+const resolveTimeout = 30 * time.Second
+
+// minRedactLen keeps short values, which would match all over the logs,
+// out of Redact.
+const minRedactLen = 4
+
+var (
+	mu        sync.RWMutex
+	resolvers = map[string]Resolver{
+		"env":   ResolverFunc(resolveEnv),
+		"file":  ResolverFunc(resolveFile),
+		"vault": newVault(),
+		"aws":   newAWS(),
+	}
+	// values are the secrets handed out so far, for Redact.
+	values = map[string]bool{}
+)
+
+// Register adds a provider or replaces a built-in one.
+func Register(provider string, r Resolver) {
+	mu.Lock()
+	defer mu.Unlock()
+	resolvers[provider] = r
+}
+
+// Providers returns the registered provider names, sorted.
+func Providers() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(resolvers))
+	for name := range resolvers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolve looks up ref, such as vault:secret/data/rig#token. Errors name
+// the reference but never contain a secret value.
+func Resolve(ref string) (string, error) {
+	provider, path, ok := strings.Cut(ref, ":")
+	mu.RLock()
+	r, known := resolvers[provider]
+	if !ok || !known {
+		provider, path = DefaultProvider, ref
+		r = resolvers[provider]
+	}
+	mu.RUnlock()
+	if path == "" {
+		return "", fmt.Errorf("secret %q: empty path", ref)
+	}
+	if r == nil {
+		return "", fmt.Errorf("secret %q: no %s provider registered", ref, provider)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+	v, err := r.Resolve(ctx, path)
+	if err != nil {
+		return "", fmt.Errorf("secret %q: %w", ref, err)
+	}
+	if len(v) >= minRedactLen {
+		mu.Lock()
+		values[v] = true
+		mu.Unlock()
+	}
+	return v, nil
+}
+
+// Redact replaces every secret value resolved so far with [REDACTED].
+func Redact(s string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	for v := range values {
+		if strings.Contains(s, v) {
+			s = strings.ReplaceAll(s, v, "[REDACTED]")
+		}
+	}
+	return s
+}
+
+// errNotSet is returned for an env reference to an unset variable.
+var errNotSet = errors.New("environment variable is not set")
+
+func resolveEnv(_ context.Context, name string) (string, error) {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return "", errNotSet
+	}
+	return v, nil
+}
+
+// resolveFile reads a mounted secret, such as a Docker or Kubernetes
+// secret file, without its trailing newline.
+func resolveFile(_ context.Context, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		// The path is already in the reference; keep the error short.
+		if errors.Is(err, os.ErrNotExist) {
+			return "", errors.New("file does not exist")
+		}
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// field picks key from the fields of a structured secret. Without a key a
+// secret with a single field yields that field.
+func field(fields map[string]any, key string) (string, error) {
+	if key == "" {
+		if len(fields) != 1 {
+			return "", fmt.Errorf("secret has %d fields; name one with #field", len(fields))
+		}
+		for k := range fields {
+			key = k
+		}
+	}
+	v, ok := fields[key]
+	if !ok {
+		return "", fmt.Errorf("secret has no field %q", key)
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("secret field %q is not a string", key)
+	}
+	return s, nil
+}
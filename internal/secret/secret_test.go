@@ -0,0 +1,136 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolveEnvAndFile(t *testing.T) {
+	t.Setenv("RIG_TEST_SECRET", "from-env")
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for ref, want := range map[string]string{"env:RIG_TEST_SECRET": "from-env", "file:" + path: "from-file"} {
+		got, err := Resolve(ref)
+		if err != nil || got != want {
+			t.Errorf("Resolve(%q) = %q, %v; want %q", ref, got, err, want)
+		}
+	}
+	if _, err := Resolve("env:RIG_TEST_UNSET"); err == nil || !strings.Contains(err.Error(), "env:RIG_TEST_UNSET") {
+		t.Errorf("expected an error naming the reference, got %v", err)
+	}
+	if _, err := Resolve("file:" + path + ".missing"); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestResolveVault(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/rig":
+			w.Write([]byte(`{"data":{"data":{"token":"ghp_vault","api_key":"sk-vault"},"metadata":{"version":3}}}`))
+		case "/v1/kv/rig":
+			w.Write([]byte(`{"data":{"value":"v1-secret"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	t.Setenv("VAULT_ADDR", srv.URL)
+	t.Setenv("VAULT_TOKEN", "root")
+
+	tests := map[string]string{
+		"vault:secret/data/rig#token": "ghp_vault",
+		"secret/data/rig#api_key":     "sk-vault", // vault is the default provider
+		"vault:kv/rig":                "v1-secret",
+	}
+	for ref, want := range tests {
+		got, err := Resolve(ref)
+		if err != nil || got != want {
+			t.Errorf("Resolve(%q) = %q, %v; want %q", ref, got, err, want)
+		}
+	}
+	for _, ref := range []string{"vault:secret/data/rig", "vault:secret/data/rig#nope", "vault:secret/data/other#x"} {
+		if _, err := Resolve(ref); err == nil {
+			t.Errorf("Resolve(%q): expected an error", ref)
+		}
+	}
+}
+
+func TestResolveAWS(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" ||
+			!strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var req struct{ SecretId string }
+		json.NewDecoder(r.Body).Decode(&req)
+		switch req.SecretId {
+		case "prod/rig":
+			w.Write([]byte(`{"SecretString":"{\"token\":\"ghp_aws\"}"}`))
+		case "plain":
+			w.Write([]byte(`{"SecretString":"plain-aws"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"ResourceNotFoundException"}`))
+		}
+	}))
+	defer srv.Close()
+	t.Setenv("AWS_REGION", "eu-west-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_ENDPOINT_URL_SECRETS_MANAGER", srv.URL)
+
+	for ref, want := range map[string]string{"aws:prod/rig#token": "ghp_aws", "aws:plain": "plain-aws"} {
+		got, err := Resolve(ref)
+		if err != nil || got != want {
+			t.Errorf("Resolve(%q) = %q, %v; want %q", ref, got, err, want)
+		}
+	}
+	if _, err := Resolve("aws:missing"); err == nil || !strings.Contains(err.Error(), "ResourceNotFoundException") {
+		t.Errorf("expected the AWS error type, got %v", err)
+	}
+}
+
+// TestSignV4 checks the signer against the GET example of the AWS
+// Signature Version 4 documentation.
+func TestSignV4(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	signV4(req, nil, awsCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}, "us-east-1", "iam", now)
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, " +
+		"SignedHeaders=content-type;host;x-amz-date, " +
+		"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRegisterAndRedact(t *testing.T) {
+	Register("test", ResolverFunc(func(_ context.Context, path string) (string, error) {
+		return "resolved-" + path, nil
+	}))
+	got, err := Resolve("test:abc")
+	if err != nil || got != "resolved-abc" {
+		t.Fatalf("Resolve = %q, %v", got, err)
+	}
+	if s := Redact("token=resolved-abc in use"); s != "token=[REDACTED] in use" {
+		t.Errorf("Redact = %q", s)
+	}
+}
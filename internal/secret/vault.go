@@ -0,0 +1,83 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// vault reads secrets from HashiCorp Vault over its HTTP API. The address,
+// token and namespace come from the standard VAULT_ADDR, VAULT_TOKEN (or
+// ~/.vault-token) and VAULT_NAMESPACE variables. Paths are API paths:
+// secret/data/rig#token for KV v2, secret/rig#token for KV v1.
+type vault struct {
+	client *http.Client
+}
+
+func newVault() *vault {
+	return &vault{client: &http.Client{Timeout: resolveTimeout}}
+}
+
+func (v *vault) Resolve(ctx context.Context, ref string) (string, error) {
+	addr := strings.TrimRight(os.Getenv("VAULT_ADDR"), "/")
+	if addr == "" {
+		return "", errors.New("VAULT_ADDR is not set")
+	}
+	token, err := vaultToken()
+	if err != nil {
+		return "", err
+	}
+	path, key, _ := strings.Cut(ref, "#")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, addr+"/v1/"+strings.TrimLeft(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if ns := os.Getenv("VAULT_NAMESPACE"); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vault: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault: %s", resp.Status)
+	}
+
+	var body struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("vault: decode response: %w", err)
+	}
+	fields := body.Data
+	// KV v2 nests the secret under data.data next to data.metadata.
+	if inner, ok := fields["data"].(map[string]any); ok {
+		if _, ok := fields["metadata"]; ok {
+			fields = inner
+		}
+	}
+	return field(fields, key)
+}
+
+func vaultToken() (string, error) {
+	if t := os.Getenv("VAULT_TOKEN"); t != "" {
+		return t, nil
+	}
+	home, err := os.UserHomeDir()
+	if err == nil {
+		if data, err := os.ReadFile(filepath.Join(home, ".vault-token")); err == nil {
+			if t := strings.TrimSpace(string(data)); t != "" {
+				return t, nil
+			}
+		}
+	}
+	return "", errors.New("VAULT_TOKEN is not set and ~/.vault-token is missing")
+}
@@ -0,0 +1,62 @@
+// Package shell runs the command lines of deploy commands, tests and hooks
+// with the shell their config names: sh, bash, powershell, pwsh or cmd.
+// Commands that name none use sh, or powershell on Windows.
+package shell
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Names are the shells a command can name.
+var Names = []string{"sh", "bash", "powershell", "pwsh", "cmd"}
+
+// Default returns the shell of commands that name none.
+func Default() string {
+	if runtime.GOOS == "windows" {
+		return "powershell"
+	}
+	return "sh"
+}
+
+// Command returns the command that runs line with shell, or with Default
+// when shell is empty.
+func Command(ctx context.Context, shell, line string) (*exec.Cmd, error) {
+	if shell == "" {
+		shell = Default()
+	}
+	var cmd *exec.Cmd
+	switch shell {
+	case "sh", "bash":
+		cmd = exec.CommandContext(ctx, shell, "-c", line)
+	case "powershell", "pwsh":
+		cmd = exec.CommandContext(ctx, shell, "-NoProfile", "-NonInteractive", "-Command", line)
+	case "cmd":
+		cmd = exec.CommandContext(ctx, "cmd", "/d", "/s", "/c", line)
+		// cmd parses its own command line, not argv.
+		setRawCmdLine(cmd, `cmd /d /s /c "`+line+`"`)
+	default:
+		return nil, fmt.Errorf("unknown shell %q; must be one of: sh, bash, powershell, pwsh, cmd", shell)
+	}
+	return cmd, nil
+}
+
+// Quote returns arg quoted as one argument of a command line run with
+// shell, or with Default when shell is empty. cmd has no escape for a
+// double quote, so such an argument is not quoted right for it.
+func Quote(shell, arg string) string {
+	if shell == "" {
+		shell = Default()
+	}
+	switch shell {
+	case "powershell", "pwsh":
+		return "'" + strings.ReplaceAll(arg, "'", "''") + "'"
+	case "cmd":
+		return `"` + arg + `"`
+	default:
+		return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+}
@@ -0,0 +1,71 @@
+package shell
+
+import (
+	"context"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCommandArgs(t *testing.T) {
+	for shell, want := range map[string][]string{
+		"sh":         {"sh", "-c", "echo hi"},
+		"bash":       {"bash", "-c", "echo hi"},
+		"powershell": {"powershell", "-NoProfile", "-NonInteractive", "-Command", "echo hi"},
+		"pwsh":       {"pwsh", "-NoProfile", "-NonInteractive", "-Command", "echo hi"},
+		"cmd":        {"cmd", "/d", "/s", "/c", "echo hi"},
+	} {
+		cmd, err := Command(context.Background(), shell, "echo hi")
+		if err != nil {
+			t.Fatalf("%s: %v", shell, err)
+		}
+		if !slices.Equal(cmd.Args, want) {
+			t.Errorf("%s: args = %q, want %q", shell, cmd.Args, want)
+		}
+	}
+	if _, err := Command(context.Background(), "fish", "echo hi"); err == nil || !strings.Contains(err.Error(), "unknown shell") {
+		t.Errorf("expected an unknown shell error, got %v", err)
+	}
+}
+
+func TestCommandRunsWithDefault(t *testing.T) {
+	cmd, err := Command(context.Background(), "", "echo hi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[bool]string{true: "powershell", false: "sh"}[runtime.GOOS == "windows"]; cmd.Args[0] != want {
+		t.Errorf("default shell = %s, want %s", cmd.Args[0], want)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		t.Skipf("%s is not available: %v", cmd.Args[0], err)
+	}
+	if strings.TrimSpace(string(out)) != "hi" {
+		t.Errorf("output = %q", out)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	for shell, want := range map[string]string{
+		"sh":         `'it'\''s a.go'`,
+		"bash":       `'it'\''s a.go'`,
+		"powershell": `'it''s a.go'`,
+		"pwsh":       `'it''s a.go'`,
+		"cmd":        `"it's a.go"`,
+	} {
+		if got := Quote(shell, "it's a.go"); got != want {
+			t.Errorf("%s: Quote = %s, want %s", shell, got, want)
+		}
+	}
+	if runtime.GOOS != "windows" {
+		out, err := exec.Command("sh", "-c", "printf %s "+Quote("sh", "it's $HOME")).Output()
+		if err != nil {
+			t.Skipf("sh is not available: %v", err)
+		}
+		if string(out) != "it's $HOME" {
+			t.Errorf("sh read %q", out)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package shell
+
+import "os/exec"
+
+// setRawCmdLine does nothing: only Windows processes see a command line.
+func setRawCmdLine(cmd *exec.Cmd, line string) {}
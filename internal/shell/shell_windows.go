@@ -0,0 +1,14 @@
+//go:build windows
+
+package shell
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setRawCmdLine passes line to the process as is, without the argv quoting
+// that cmd does not understand.
+func setRawCmdLine(cmd *exec.Cmd, line string) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CmdLine: line}
+}
@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// GetAICache returns the cached AI response stored under key, unless it
+// has expired.
+func (d *DB) GetAICache(key string) (string, bool, error) {
+	var value string
+	err := d.db.QueryRow(
+		"SELECT value FROM ai_cache WHERE key = ? AND expires_at > ?", key, time.Now().UTC(),
+	).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("get ai cache: %w", err)
+	}
+	return value, true, nil
+}
+
+// PutAICache stores value under key for ttl, replacing any earlier entry,
+// and drops the entries that have expired.
+func (d *DB) PutAICache(key, value string, ttl time.Duration) error {
+	now := time.Now().UTC()
+	if _, err := d.db.Exec("DELETE FROM ai_cache WHERE expires_at <= ?", now); err != nil {
+		return fmt.Errorf("prune ai cache: %w", err)
+	}
+	_, err := d.db.Exec(
+		`INSERT INTO ai_cache (key, value, created_at, expires_at) VALUES (?, ?, ?, ?)
+		 ON CONFLICT(key) DO UPDATE SET value = excluded.value, created_at = excluded.created_at, expires_at = excluded.expires_at`,
+		key, value, now, now.Add(ttl),
+	)
+	if err != nil {
+		return fmt.Errorf("put ai cache: %w", err)
+	}
+	return nil
+}
+
+// ClearAICache removes every cached AI response and returns how many there
+// were.
+func (d *DB) ClearAICache() (int64, error) {
+	res, err := d.db.Exec("DELETE FROM ai_cache")
+	if err != nil {
+		return 0, fmt.Errorf("clear ai cache: %w", err)
+	}
+	return res.RowsAffected()
+}
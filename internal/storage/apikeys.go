@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Role is the access level of an API key.
+type Role string
+
+// API key roles. Viewers only read; operators also create, retry and stop
+// tasks; approvers also approve and reject proposals; admins can do
+// everything, including settings, audit and key management.
+const (
+	RoleViewer   Role = "viewer"
+	RoleOperator Role = "operator"
+	RoleApprover Role = "approver"
+	RoleAdmin    Role = "admin"
+)
+
+// Roles lists every role, least privileged first.
+var Roles = []Role{RoleViewer, RoleOperator, RoleApprover, RoleAdmin}
+
+// ParseRole validates a role name.
+func ParseRole(s string) (Role, error) {
+	for _, r := range Roles {
+		if string(r) == s {
+			return r, nil
+		}
+	}
+	return "", fmt.Errorf("unknown role %q (want viewer, operator, approver or admin)", s)
+}
+
+// ErrAPIKeyExists is returned by CreateAPIKey for a name already in use.
+var ErrAPIKeyExists = errors.New("api key name already exists")
+
+// ErrAPIKeyNotFound is returned by DeleteAPIKey for an unknown name.
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// apiKeyPrefix marks rig API keys so they are recognizable in configs and
+// secret scanners.
+const apiKeyPrefix = "rig_"
+
+// APIKey is a stored API key. Only a hash of the key itself is kept.
+type APIKey struct {
+	Name string `json:"name"`
+	Role Role   `json:"role"`
+	// Prefix is the start of the key, enough to tell keys apart.
+	Prefix string `json:"prefix"`
+	// Tenant limits the key to the API of that tenant; empty keys are not
+	// limited.
+	Tenant    string    `json:"tenant,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateAPIKey generates a key for name with role and returns it. The
+// plaintext key is not stored and cannot be shown again.
+func (d *DB) CreateAPIKey(name string, role Role) (string, *APIKey, error) {
+	return d.CreateTenantAPIKey(name, role, "")
+}
+
+// CreateTenantAPIKey is CreateAPIKey for a key limited to tenant.
+func (d *DB) CreateTenantAPIKey(name string, role Role, tenant string) (string, *APIKey, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, errors.New("api key name is required")
+	}
+	if _, err := ParseRole(string(role)); err != nil {
+		return "", nil, err
+	}
+
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, fmt.Errorf("generate api key: %w", err)
+	}
+	key := apiKeyPrefix + hex.EncodeToString(buf)
+	k := &APIKey{Name: name, Role: role, Prefix: key[:len(apiKeyPrefix)+6], Tenant: tenant, CreatedAt: time.Now().UTC()}
+
+	res, err := d.db.Exec(
+		`INSERT INTO api_keys (name, key_hash, role, prefix, tenant, created_at) VALUES (?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(name) DO NOTHING`,
+		k.Name, hashAPIKey(key), string(k.Role), k.Prefix, k.Tenant, k.CreatedAt,
+	)
+	if err != nil {
+		return "", nil, fmt.Errorf("create api key %q: %w", name, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return "", nil, fmt.Errorf("%w: %s", ErrAPIKeyExists, name)
+	}
+	return key, k, nil
+}
+
+// LookupAPIKey returns the stored key matching key, or nil if none does.
+func (d *DB) LookupAPIKey(key string) (*APIKey, error) {
+	var k APIKey
+	var role string
+	err := d.db.QueryRow(
+		"SELECT name, role, prefix, tenant, created_at FROM api_keys WHERE key_hash = ?", hashAPIKey(key),
+	).Scan(&k.Name, &role, &k.Prefix, &k.Tenant, &k.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lookup api key: %w", err)
+	}
+	k.Role = Role(role)
+	return &k, nil
+}
+
+// ListAPIKeys returns all stored keys by name.
+func (d *DB) ListAPIKeys() ([]APIKey, error) {
+	rows, err := d.db.Query("SELECT name, role, prefix, tenant, created_at FROM api_keys ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		var k APIKey
+		var role string
+		if err := rows.Scan(&k.Name, &role, &k.Prefix, &k.Tenant, &k.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan api key: %w", err)
+		}
+		k.Role = Role(role)
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// HasAPIKeys reports whether any key is stored.
+func (d *DB) HasAPIKeys() (bool, error) {
+	var count int
+	if err := d.db.QueryRow("SELECT COUNT(*) FROM api_keys").Scan(&count); err != nil {
+		return false, fmt.Errorf("count api keys: %w", err)
+	}
+	return count > 0, nil
+}
+
+// DeleteAPIKey revokes the key named name.
+func (d *DB) DeleteAPIKey(name string) error {
+	res, err := d.db.Exec("DELETE FROM api_keys WHERE name = ?", name)
+	if err != nil {
+		return fmt.Errorf("delete api key %q: %w", name, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: %s", ErrAPIKeyNotFound, name)
+	}
+	return nil
+}
+
+// hashAPIKey hashes a key for storage. Keys are long random strings, so an
+// unsalted SHA-256 is enough to keep them out of the database.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
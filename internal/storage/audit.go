@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Audit actions recorded for state-changing requests.
+const (
+	AuditTaskCreated         = "task.created"
+	AuditTaskRetried         = "task.retried"
+	AuditTaskStopped         = "task.stopped"
+	AuditTaskRedeployed      = "task.redeployed"
+	AuditTaskRetested        = "task.retested"
+	AuditTaskExplained       = "task.explained"
+	AuditProposalApproved    = "proposal.approved"
+	AuditProposalRejected    = "proposal.rejected"
+	AuditPlanEdited          = "plan.edited"
+	AuditSettingsChanged     = "settings.changed"
+	AuditAgentsChanged       = "agents.changed"
+	AuditStateRepaired       = "state.repaired"
+	AuditKeyCreated          = "key.created"
+	AuditKeyDeleted          = "key.deleted"
+	AuditUserLogin           = "user.login"
+	AuditWebhookReplayed     = "webhook.replayed"
+	AuditConfigReloaded      = "config.reloaded"
+	AuditWorkspacesCollected = "workspaces.collected"
+	AuditBackupCreated       = "backup.created"
+	AuditBackupRestored      = "backup.restored"
+	AuditAICacheCleared      = "ai_cache.cleared"
+)
+
+// Audit sources: where an action came in.
+const (
+	AuditSourceWeb     = "web"
+	AuditSourceWebhook = "webhook"
+	AuditSourceChatOps = "chatops"
+	AuditSourceCLI     = "cli"
+	AuditSourceGRPC    = "grpc"
+	AuditSourceQueue   = "queue"
+)
+
+// defaultAuditLimit caps ListAudit when the filter sets no limit.
+const defaultAuditLimit = 100
+
+// AuditEntry records who did what to which task or setting, and when.
+type AuditEntry struct {
+	ID   int64     `json:"id"`
+	Time time.Time `json:"time"`
+	// Actor identifies who acted: "api-key", "github:octocat",
+	// "slack:alice", "cli:alice", "user:alice@example.com" or "anonymous".
+	Actor  string `json:"actor"`
+	Source string `json:"source"`
+	// Remote is the client address of HTTP requests.
+	Remote string `json:"remote,omitempty"`
+	Action string `json:"action"`
+	// Target is the task ID, settings section, repository or issue acted on.
+	Target  string `json:"target"`
+	Details string `json:"details,omitempty"`
+}
+
+// AuditFilter selects audit entries. Zero fields match everything.
+type AuditFilter struct {
+	Actor  string
+	Action string
+	Target string
+	Since  time.Time
+	// Limit caps the number of entries; 0 means 100.
+	Limit int
+}
+
+// RecordAudit appends an audit entry. A zero Time means now.
+func (d *DB) RecordAudit(e AuditEntry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	_, err := d.db.Exec(
+		`INSERT INTO audit_log (time, actor, source, remote, action, target, details) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		e.Time.UTC(), e.Actor, e.Source, e.Remote, e.Action, e.Target, e.Details,
+	)
+	if err != nil {
+		return fmt.Errorf("record audit %s: %w", e.Action, err)
+	}
+	return nil
+}
+
+// ListAudit returns matching audit entries, newest first.
+func (d *DB) ListAudit(f AuditFilter) ([]AuditEntry, error) {
+	var where []string
+	var args []any
+	if f.Actor != "" {
+		where = append(where, "actor = ?")
+		args = append(args, f.Actor)
+	}
+	if f.Action != "" {
+		where = append(where, "action = ?")
+		args = append(args, f.Action)
+	}
+	if f.Target != "" {
+		where = append(where, "target = ?")
+		args = append(args, f.Target)
+	}
+	if !f.Since.IsZero() {
+		where = append(where, "time >= ?")
+		args = append(args, f.Since.UTC())
+	}
+	limit := f.Limit
+	if limit <= 0 {
+		limit = defaultAuditLimit
+	}
+
+	query := `SELECT id, time, actor, source, remote, action, target, details FROM audit_log`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list audit: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var e AuditEntry
+		if err := rows.Scan(&e.ID, &e.Time, &e.Actor, &e.Source, &e.Remote, &e.Action, &e.Target, &e.Details); err != nil {
+			return nil, fmt.Errorf("scan audit: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// EmbeddedChunk is the embedding of one chunk of an indexed repository file.
+type EmbeddedChunk struct {
+	Path   string
+	Chunk  int
+	Vector []float32
+}
+
+// IndexedFiles returns the content hash of every file of repo embedded
+// with model, by path.
+func (d *DB) IndexedFiles(repo, model string) (map[string]string, error) {
+	rows, err := d.db.Query(
+		"SELECT DISTINCT path, file_hash FROM embeddings WHERE repo = ? AND model = ?", repo, model,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list indexed files: %w", err)
+	}
+	defer rows.Close()
+
+	files := make(map[string]string)
+	for rows.Next() {
+		var path, hash string
+		if err := rows.Scan(&path, &hash); err != nil {
+			return nil, fmt.Errorf("scan indexed file: %w", err)
+		}
+		files[path] = hash
+	}
+	return files, rows.Err()
+}
+
+// ReplaceFileEmbeddings stores the chunk embeddings of a file, replacing
+// the ones stored for an earlier version of it.
+func (d *DB) ReplaceFileEmbeddings(repo, model, path, fileHash string, vectors [][]float32) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM embeddings WHERE repo = ? AND path = ?", repo, path); err != nil {
+		return fmt.Errorf("delete embeddings of %s: %w", path, err)
+	}
+	for i, v := range vectors {
+		_, err := tx.Exec(
+			"INSERT INTO embeddings (repo, path, chunk, file_hash, model, vector) VALUES (?, ?, ?, ?, ?, ?)",
+			repo, path, i, fileHash, model, encodeVector(v),
+		)
+		if err != nil {
+			return fmt.Errorf("insert embedding of %s: %w", path, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteFileEmbeddings removes the embeddings of a file that no longer
+// exists.
+func (d *DB) DeleteFileEmbeddings(repo, path string) error {
+	if _, err := d.db.Exec("DELETE FROM embeddings WHERE repo = ? AND path = ?", repo, path); err != nil {
+		return fmt.Errorf("delete embeddings of %s: %w", path, err)
+	}
+	return nil
+}
+
+// PruneEmbeddingModels removes the embeddings of repo computed with any
+// model other than model; vectors of different models cannot be compared.
+func (d *DB) PruneEmbeddingModels(repo, model string) error {
+	if _, err := d.db.Exec("DELETE FROM embeddings WHERE repo = ? AND model != ?", repo, model); err != nil {
+		return fmt.Errorf("prune embeddings: %w", err)
+	}
+	return nil
+}
+
+// Embeddings returns every chunk embedding of repo computed with model.
+func (d *DB) Embeddings(repo, model string) ([]EmbeddedChunk, error) {
+	rows, err := d.db.Query(
+		"SELECT path, chunk, vector FROM embeddings WHERE repo = ? AND model = ?", repo, model,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	var chunks []EmbeddedChunk
+	for rows.Next() {
+		var (
+			c    EmbeddedChunk
+			blob []byte
+		)
+		if err := rows.Scan(&c.Path, &c.Chunk, &blob); err != nil {
+			return nil, fmt.Errorf("scan embedding: %w", err)
+		}
+		c.Vector = decodeVector(blob)
+		chunks = append(chunks, c)
+	}
+	return chunks, rows.Err()
+}
+
+// encodeVector stores a vector as little-endian float32s.
+func encodeVector(v []float32) []byte {
+	b := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(f))
+	}
+	return b
+}
+
+func decodeVector(b []byte) []float32 {
+	v := make([]float32, len(b)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+	}
+	return v
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Fix is a change that made a failing task pass, remembered with the
+// normalized signature of the failure it fixed (ai.fix_memory).
+type Fix struct {
+	ID        int64     `json:"id"`
+	Repo      string    `json:"repo"`
+	Signature string    `json:"signature"`
+	Failure   string    `json:"failure"` // excerpt of the failure logs
+	Diff      string    `json:"diff"`
+	TaskID    string    `json:"task_id"`
+	Uses      int       `json:"uses"`      // retries given the fix as a hint
+	Successes int       `json:"successes"` // of those, retries whose tests passed
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AddFix remembers a fix. The same diff for the same signature is stored
+// once; adding it again makes it the latest.
+func (d *DB) AddFix(f Fix) error {
+	sum := sha256.Sum256([]byte(f.Diff))
+	_, err := d.db.Exec(
+		`INSERT INTO fixes (repo, signature, failure, diff, diff_hash, task_id, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(repo, signature, diff_hash) DO UPDATE SET failure = excluded.failure, task_id = excluded.task_id, created_at = excluded.created_at`,
+		f.Repo, f.Signature, f.Failure, f.Diff, hex.EncodeToString(sum[:]), f.TaskID, time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("add fix: %w", err)
+	}
+	return nil
+}
+
+// ListFixes returns the fixes of repo, or of every repository for "",
+// newest first.
+func (d *DB) ListFixes(repo string) ([]Fix, error) {
+	query := "SELECT id, repo, signature, failure, diff, task_id, uses, successes, created_at FROM fixes"
+	var args []any
+	if repo != "" {
+		query += " WHERE repo = ?"
+		args = append(args, repo)
+	}
+	rows, err := d.db.Query(query+" ORDER BY created_at DESC, id DESC", args...)
+	if err != nil {
+		return nil, fmt.Errorf("list fixes: %w", err)
+	}
+	defer rows.Close()
+
+	fixes := []Fix{}
+	for rows.Next() {
+		var f Fix
+		if err := rows.Scan(&f.ID, &f.Repo, &f.Signature, &f.Failure, &f.Diff, &f.TaskID, &f.Uses, &f.Successes, &f.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan fix: %w", err)
+		}
+		fixes = append(fixes, f)
+	}
+	return fixes, rows.Err()
+}
+
+// RecordFixUse counts one retry given the fixes ids as hints, and whether
+// its tests passed.
+func (d *DB) RecordFixUse(ids []int64, passed bool) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	success := 0
+	if passed {
+		success = 1
+	}
+	args := []any{success}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	_, err := d.db.Exec("UPDATE fixes SET uses = uses + 1, successes = successes + ? WHERE id IN ("+placeholders+")", args...)
+	if err != nil {
+		return fmt.Errorf("record fix use: %w", err)
+	}
+	return nil
+}
+
+// DeleteFix forgets a fix.
+func (d *DB) DeleteFix(id int64) (bool, error) {
+	res, err := d.db.Exec("DELETE FROM fixes WHERE id = ?", id)
+	if err != nil {
+		return false, fmt.Errorf("delete fix: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
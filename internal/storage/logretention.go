@@ -0,0 +1,505 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+const (
+	// logArchiveLines is the most lines PruneLogs compresses into one archive.
+	logArchiveLines = 5000
+	// logPruneBatch is how many of the oldest lines PruneLogs looks at a time
+	// to get under LogRetention.MaxBytes.
+	logPruneBatch = 500
+)
+
+// LogRetention bounds the task log history kept in the database. Zero
+// fields are unlimited.
+type LogRetention struct {
+	// MaxRowsPerTask keeps only the newest lines of each task.
+	MaxRowsPerTask int
+	// MaxAge deletes the lines older than it.
+	MaxAge time.Duration
+	// MaxBytes caps the size of all task logs together, counting compressed
+	// lines at their compressed size. The oldest lines go first.
+	MaxBytes int64
+	// CompressAfter moves the lines older than it into gzip-compressed
+	// archives. GetLogs and GetLogsSince still return them.
+	CompressAfter time.Duration
+}
+
+// LogPruneResult counts the lines PruneLogs deleted and compressed.
+type LogPruneResult struct {
+	Deleted    int64 `json:"deleted"`
+	Compressed int64 `json:"compressed"`
+}
+
+// LogStats describes the stored task logs.
+type LogStats struct {
+	// Lines are uncompressed lines; ArchivedLines are compressed ones.
+	Lines         int64 `json:"lines"`
+	ArchivedLines int64 `json:"archived_lines"`
+	// Bytes is the size of the messages plus the compressed archives.
+	Bytes int64 `json:"bytes"`
+}
+
+// archivedLine is a task log line inside a compressed archive.
+type archivedLine struct {
+	ID        int64     `json:"id"`
+	Timestamp time.Time `json:"ts"`
+	Level     string    `json:"level"`
+	Message   string    `json:"msg"`
+}
+
+// logTime formats t the way task_logs stores timestamps, so they compare
+// as strings.
+func logTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
+// PruneLogs applies r to the task logs: it deletes lines past MaxAge,
+// compresses lines past CompressAfter, then trims each task to
+// MaxRowsPerTask and all tasks to MaxBytes. The space deleted rows held is
+// reused by new rows; Vacuum returns it to the filesystem.
+func (d *DB) PruneLogs(r LogRetention) (LogPruneResult, error) {
+	var res LogPruneResult
+	now := time.Now()
+	if r.MaxAge > 0 {
+		n, err := d.deleteLogsBefore(logTime(now.Add(-r.MaxAge)))
+		res.Deleted += n
+		if err != nil {
+			return res, fmt.Errorf("prune task logs by age: %w", err)
+		}
+	}
+	if r.CompressAfter > 0 {
+		n, err := d.compressLogsBefore(logTime(now.Add(-r.CompressAfter)))
+		res.Compressed += n
+		if err != nil {
+			return res, fmt.Errorf("compress task logs: %w", err)
+		}
+	}
+	if r.MaxRowsPerTask > 0 {
+		n, err := d.capTaskLogs(r.MaxRowsPerTask)
+		res.Deleted += n
+		if err != nil {
+			return res, fmt.Errorf("prune task logs by rows: %w", err)
+		}
+	}
+	if r.MaxBytes > 0 {
+		n, err := d.capLogBytes(r.MaxBytes)
+		res.Deleted += n
+		if err != nil {
+			return res, fmt.Errorf("prune task logs by size: %w", err)
+		}
+	}
+	return res, nil
+}
+
+// LogStats counts the stored task log lines and their size.
+func (d *DB) LogStats() (LogStats, error) {
+	var s LogStats
+	var liveBytes, archiveBytes int64
+	err := d.db.QueryRow(`SELECT COUNT(*), COALESCE(SUM(length(CAST(message AS BLOB))), 0) FROM task_logs`).Scan(&s.Lines, &liveBytes)
+	if err != nil {
+		return s, err
+	}
+	err = d.db.QueryRow(`SELECT COALESCE(SUM(lines), 0), COALESCE(SUM(length(data)), 0) FROM task_log_archives`).Scan(&s.ArchivedLines, &archiveBytes)
+	s.Bytes = liveBytes + archiveBytes
+	return s, err
+}
+
+// Vacuum rebuilds the database file, returning the space of deleted rows to
+// the filesystem, and truncates the write-ahead log.
+func (d *DB) Vacuum() error {
+	if _, err := d.db.Exec("VACUUM"); err != nil {
+		return fmt.Errorf("vacuum: %w", err)
+	}
+	if _, err := d.db.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	return nil
+}
+
+// deleteLogsBefore deletes the lines logged before cutoff, and the archives
+// whose newest line is.
+func (d *DB) deleteLogsBefore(cutoff string) (int64, error) {
+	res, err := d.db.Exec(`DELETE FROM task_logs WHERE timestamp < ?`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	deleted, _ := res.RowsAffected()
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return deleted, err
+	}
+	defer tx.Rollback()
+	var lines int64
+	if err := tx.QueryRow(`SELECT COALESCE(SUM(lines), 0) FROM task_log_archives WHERE newest < ?`, cutoff).Scan(&lines); err != nil {
+		return deleted, err
+	}
+	if _, err := tx.Exec(`DELETE FROM task_log_archives WHERE newest < ?`, cutoff); err != nil {
+		return deleted, err
+	}
+	if err := tx.Commit(); err != nil {
+		return deleted, err
+	}
+	return deleted + lines, nil
+}
+
+// compressLogsBefore moves the lines logged before cutoff into archives of
+// at most logArchiveLines lines of one task.
+func (d *DB) compressLogsBefore(cutoff string) (int64, error) {
+	tasks, err := d.logTaskIDs(`SELECT DISTINCT task_id FROM task_logs WHERE timestamp < ?`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	var compressed int64
+	for _, taskID := range tasks {
+		for {
+			n, err := d.archiveLogs(taskID, cutoff)
+			compressed += int64(n)
+			if err != nil {
+				return compressed, err
+			}
+			if n < logArchiveLines {
+				break
+			}
+		}
+	}
+	return compressed, nil
+}
+
+// archiveLogs compresses the oldest logArchiveLines lines of taskID logged
+// before cutoff into one archive.
+func (d *DB) archiveLogs(taskID, cutoff string) (int, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(
+		`SELECT id, timestamp, level, message FROM task_logs WHERE task_id = ? AND timestamp < ? ORDER BY id LIMIT ?`,
+		taskID, cutoff, logArchiveLines,
+	)
+	if err != nil {
+		return 0, err
+	}
+	var lines []archivedLine
+	var newest time.Time
+	for rows.Next() {
+		var l archivedLine
+		if err := rows.Scan(&l.ID, &l.Timestamp, &l.Level, &l.Message); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if l.Timestamp.After(newest) {
+			newest = l.Timestamp
+		}
+		lines = append(lines, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(lines) == 0 {
+		return 0, err
+	}
+
+	data, err := encodeArchive(lines)
+	if err != nil {
+		return 0, err
+	}
+	last := lines[len(lines)-1].ID
+	if _, err := tx.Exec(
+		`INSERT INTO task_log_archives (task_id, first_id, last_id, lines, newest, data) VALUES (?, ?, ?, ?, ?, ?)`,
+		taskID, lines[0].ID, last, len(lines), logTime(newest), data,
+	); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`DELETE FROM task_logs WHERE task_id = ? AND timestamp < ? AND id <= ?`, taskID, cutoff, last); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(lines), nil
+}
+
+// capTaskLogs trims every task with more than limit lines to its newest
+// limit.
+func (d *DB) capTaskLogs(limit int) (int64, error) {
+	tasks, err := d.logTaskIDs(`
+		SELECT task_id FROM (
+			SELECT task_id, COUNT(*) AS n FROM task_logs GROUP BY task_id
+			UNION ALL
+			SELECT task_id, SUM(lines) AS n FROM task_log_archives GROUP BY task_id
+		) GROUP BY task_id HAVING SUM(n) > ?`, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	for _, taskID := range tasks {
+		n, err := d.capTaskLog(taskID, limit)
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
+// capTaskLog deletes every line of taskID but the newest keep, rewriting
+// the archive the cut falls into.
+func (d *DB) capTaskLog(taskID string, keep int) (int64, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var ids []int64
+	rows, err := tx.Query(`SELECT id FROM task_logs WHERE task_id = ?`, taskID)
+	if err != nil {
+		return 0, err
+	}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	archives, err := taskArchives(tx, taskID)
+	if err != nil {
+		return 0, err
+	}
+	for _, a := range archives {
+		for _, l := range a.lines {
+			ids = append(ids, l.ID)
+		}
+	}
+	if len(ids) <= keep {
+		return 0, nil
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] > ids[j] })
+	boundary := ids[keep-1]
+
+	res, err := tx.Exec(`DELETE FROM task_logs WHERE task_id = ? AND id < ?`, taskID, boundary)
+	if err != nil {
+		return 0, err
+	}
+	deleted, _ := res.RowsAffected()
+	for _, a := range archives {
+		switch {
+		case a.lastID < boundary:
+			if _, err := tx.Exec(`DELETE FROM task_log_archives WHERE id = ?`, a.id); err != nil {
+				return 0, err
+			}
+			deleted += int64(len(a.lines))
+		case a.firstID < boundary:
+			var kept []archivedLine
+			for _, l := range a.lines {
+				if l.ID >= boundary {
+					kept = append(kept, l)
+				}
+			}
+			data, err := encodeArchive(kept)
+			if err != nil {
+				return 0, err
+			}
+			if _, err := tx.Exec(
+				`UPDATE task_log_archives SET first_id = ?, lines = ?, data = ? WHERE id = ?`,
+				kept[0].ID, len(kept), data, a.id,
+			); err != nil {
+				return 0, err
+			}
+			deleted += int64(len(a.lines) - len(kept))
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
+
+// capLogBytes deletes the oldest lines and archives of all tasks until
+// their size is at most limit.
+func (d *DB) capLogBytes(limit int64) (int64, error) {
+	stats, err := d.LogStats()
+	if err != nil {
+		return 0, err
+	}
+	total := stats.Bytes
+	var deleted int64
+	for total > limit {
+		var archiveID, archiveFirst, archiveLines, archiveSize int64
+		err := d.db.QueryRow(`SELECT id, first_id, lines, length(data) FROM task_log_archives ORDER BY first_id LIMIT 1`).
+			Scan(&archiveID, &archiveFirst, &archiveLines, &archiveSize)
+		hasArchive := err == nil
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return deleted, err
+		}
+
+		type liveLine struct{ id, size int64 }
+		var live []liveLine
+		rows, err := d.db.Query(`SELECT id, length(CAST(message AS BLOB)) FROM task_logs ORDER BY id LIMIT ?`, logPruneBatch)
+		if err != nil {
+			return deleted, err
+		}
+		for rows.Next() {
+			var l liveLine
+			if err := rows.Scan(&l.id, &l.size); err != nil {
+				rows.Close()
+				return deleted, err
+			}
+			live = append(live, l)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return deleted, err
+		}
+
+		if hasArchive && (len(live) == 0 || archiveFirst < live[0].id) {
+			if _, err := d.db.Exec(`DELETE FROM task_log_archives WHERE id = ?`, archiveID); err != nil {
+				return deleted, err
+			}
+			total -= archiveSize
+			deleted += archiveLines
+			continue
+		}
+		if len(live) == 0 {
+			break
+		}
+		cut := live[0].id
+		for _, l := range live {
+			if hasArchive && l.id > archiveFirst {
+				break
+			}
+			cut = l.id
+			total -= l.size
+			if total <= limit {
+				break
+			}
+		}
+		res, err := d.db.Exec(`DELETE FROM task_logs WHERE id <= ?`, cut)
+		if err != nil {
+			return deleted, err
+		}
+		n, _ := res.RowsAffected()
+		deleted += n
+	}
+	return deleted, nil
+}
+
+// logArchive is a decoded row of task_log_archives.
+type logArchive struct {
+	id, firstID, lastID int64
+	lines               []archivedLine
+}
+
+// taskArchives decodes every archive of taskID.
+func taskArchives(tx *sql.Tx, taskID string) ([]logArchive, error) {
+	rows, err := tx.Query(`SELECT id, first_id, last_id, data FROM task_log_archives WHERE task_id = ? ORDER BY first_id`, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var archives []logArchive
+	for rows.Next() {
+		var a logArchive
+		var data []byte
+		if err := rows.Scan(&a.id, &a.firstID, &a.lastID, &data); err != nil {
+			return nil, err
+		}
+		if a.lines, err = decodeArchive(data); err != nil {
+			return nil, fmt.Errorf("task log archive %d: %w", a.id, err)
+		}
+		archives = append(archives, a)
+	}
+	return archives, rows.Err()
+}
+
+// archivedLogs returns the compressed lines of taskID with an id above
+// afterID.
+func (d *DB) archivedLogs(taskID string, afterID int64) ([]LogEntry, error) {
+	rows, err := d.db.Query(`SELECT id, data FROM task_log_archives WHERE task_id = ? AND last_id > ? ORDER BY first_id`, taskID, afterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var logs []LogEntry
+	for rows.Next() {
+		var id int64
+		var data []byte
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, err
+		}
+		lines, err := decodeArchive(data)
+		if err != nil {
+			return nil, fmt.Errorf("task log archive %d: %w", id, err)
+		}
+		for _, l := range lines {
+			if l.ID > afterID {
+				logs = append(logs, LogEntry{ID: l.ID, TaskID: taskID, Timestamp: l.Timestamp, Level: l.Level, Message: l.Message})
+			}
+		}
+	}
+	return logs, rows.Err()
+}
+
+// logTaskIDs returns the task ids query selects.
+func (d *DB) logTaskIDs(query string, args ...any) ([]string, error) {
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func encodeArchive(lines []archivedLine) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(lines); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeArchive(data []byte) ([]archivedLine, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+	var lines []archivedLine
+	err = json.Unmarshal(raw, &lines)
+	return lines, err
+}
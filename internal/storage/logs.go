@@ -1,6 +1,9 @@
 package storage
 
-import "time"
+import (
+	"sort"
+	"time"
+)
 
 // LogEntry represents a single log line for a task.
 type LogEntry struct {
@@ -20,30 +23,26 @@ func (d *DB) AppendLog(taskID, level, message string) error {
 	return err
 }
 
-// GetLogs returns all log entries for a task, ordered by id.
+// GetLogs returns all log entries for a task, ordered by id. Lines
+// PruneLogs compressed are included.
 func (d *DB) GetLogs(taskID string) ([]LogEntry, error) {
-	rows, err := d.db.Query(
-		`SELECT id, task_id, timestamp, level, message FROM task_logs WHERE task_id = ? ORDER BY id`,
-		taskID,
-	)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var logs []LogEntry
-	for rows.Next() {
-		var l LogEntry
-		if err := rows.Scan(&l.ID, &l.TaskID, &l.Timestamp, &l.Level, &l.Message); err != nil {
-			return nil, err
-		}
-		logs = append(logs, l)
-	}
-	return logs, rows.Err()
+	return d.logsAfter(taskID, 0)
 }
 
 // GetLogsSince returns log entries after a given id (for polling).
 func (d *DB) GetLogsSince(taskID string, afterID int64) ([]LogEntry, error) {
+	return d.logsAfter(taskID, afterID)
+}
+
+// logsAfter returns the archived and live lines of a task with an id
+// above afterID, ordered by id.
+func (d *DB) logsAfter(taskID string, afterID int64) ([]LogEntry, error) {
+	logs, err := d.archivedLogs(taskID, afterID)
+	if err != nil {
+		return nil, err
+	}
+	archived := len(logs)
+
 	rows, err := d.db.Query(
 		`SELECT id, task_id, timestamp, level, message FROM task_logs WHERE task_id = ? AND id > ? ORDER BY id`,
 		taskID, afterID,
@@ -53,7 +52,6 @@ func (d *DB) GetLogsSince(taskID string, afterID int64) ([]LogEntry, error) {
 	}
 	defer rows.Close()
 
-	var logs []LogEntry
 	for rows.Next() {
 		var l LogEntry
 		if err := rows.Scan(&l.ID, &l.TaskID, &l.Timestamp, &l.Level, &l.Message); err != nil {
@@ -61,5 +59,11 @@ func (d *DB) GetLogsSince(taskID string, afterID int64) ([]LogEntry, error) {
 		}
 		logs = append(logs, l)
 	}
-	return logs, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if archived > 0 {
+		sort.SliceStable(logs, func(i, j int) bool { return logs[i].ID < logs[j].ID })
+	}
+	return logs, nil
 }
@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	defaultLogQueueSize     = 4096
+	defaultLogBatchSize     = 200
+	defaultLogFlushInterval = 500 * time.Millisecond
+)
+
+// LogWriter buffers task log lines and inserts them in batches from a single
+// background goroutine, so callers on the engine's hot path never wait on
+// SQLite. When the bounded queue is full, Write falls back to a synchronous
+// insert instead of dropping the line.
+type LogWriter struct {
+	db       *DB
+	queue    chan LogEntry
+	flushReq chan chan error
+	done     chan struct{}
+	batch    int
+	interval time.Duration
+
+	// mu guards closed: Write checks it and enqueues under the read lock,
+	// and Close sets it under the write lock, so no line is enqueued after
+	// the final drain.
+	mu        sync.RWMutex
+	closed    bool
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewLogWriter starts an async batched writer for task logs.
+// Zero values select defaults (queue 4096, batch 200, interval 500ms).
+func (d *DB) NewLogWriter(queueSize, batchSize int, interval time.Duration) *LogWriter {
+	if queueSize <= 0 {
+		queueSize = defaultLogQueueSize
+	}
+	if batchSize <= 0 {
+		batchSize = defaultLogBatchSize
+	}
+	if interval <= 0 {
+		interval = defaultLogFlushInterval
+	}
+
+	w := &LogWriter{
+		db:       d,
+		queue:    make(chan LogEntry, queueSize),
+		flushReq: make(chan chan error),
+		done:     make(chan struct{}),
+		batch:    batchSize,
+		interval: interval,
+	}
+	go w.run()
+	return w
+}
+
+// Write enqueues a log line. It matches core.LogFunc.
+func (w *LogWriter) Write(taskID, level, message string) {
+	entry := LogEntry{TaskID: taskID, Timestamp: time.Now().UTC(), Level: level, Message: message}
+	if w.enqueue(entry) {
+		return
+	}
+	// Queue full or writer closed: insert synchronously rather than drop the line.
+	if err := w.db.insertLogs([]LogEntry{entry}); err != nil {
+		slog.Error("storage: write task log", "task", taskID, "err", err)
+	}
+}
+
+// enqueue queues entry unless the writer is closed or the queue is full.
+func (w *LogWriter) enqueue(entry LogEntry) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return false
+	}
+	select {
+	case w.queue <- entry:
+		return true
+	default:
+		return false
+	}
+}
+
+// Flush blocks until every line enqueued before the call is persisted.
+func (w *LogWriter) Flush() error {
+	reply := make(chan error, 1)
+	select {
+	case w.flushReq <- reply:
+		return <-reply
+	case <-w.done:
+		return nil
+	}
+}
+
+// Close flushes pending lines and stops the background goroutine.
+// It must be called before the DB is closed.
+func (w *LogWriter) Close() error {
+	w.closeOnce.Do(func() {
+		w.mu.Lock()
+		w.closed = true
+		w.mu.Unlock()
+		w.closeErr = w.Flush()
+		close(w.done)
+	})
+	return w.closeErr
+}
+
+func (w *LogWriter) run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	pending := make([]LogEntry, 0, w.batch)
+	write := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		err := w.db.insertLogs(pending)
+		if err != nil {
+			slog.Error("storage: batch write task logs", "lines", len(pending), "err", err)
+		}
+		pending = pending[:0]
+		return err
+	}
+	drain := func() {
+		for {
+			select {
+			case e := <-w.queue:
+				pending = append(pending, e)
+				if len(pending) >= w.batch {
+					_ = write()
+				}
+			default:
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case e := <-w.queue:
+			pending = append(pending, e)
+			if len(pending) >= w.batch {
+				_ = write()
+			}
+		case <-ticker.C:
+			_ = write()
+		case reply := <-w.flushReq:
+			drain()
+			reply <- write()
+		case <-w.done:
+			drain()
+			_ = write()
+			return
+		}
+	}
+}
+
+// insertLogs writes entries in a single transaction.
+func (d *DB) insertLogs(entries []LogEntry) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	stmt, err := tx.Prepare(`INSERT INTO task_logs (task_id, timestamp, level, message) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, e := range entries {
+		if _, err := stmt.Exec(e.TaskID, logTime(e.Timestamp), e.Level, e.Message); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("insert: %w", err)
+		}
+	}
+	return tx.Commit()
+}
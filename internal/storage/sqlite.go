@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"os"
@@ -20,7 +21,8 @@ func Open(dbPath string) (*DB, error) {
 		return nil, fmt.Errorf("create db directory: %w", err)
 	}
 
-	db, err := sql.Open("sqlite", dbPath+"?_journal_mode=WAL&_busy_timeout=5000")
+	// WAL keeps committed log batches durable across a crash without blocking readers.
+	db, err := sql.Open("sqlite", dbPath+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=synchronous(NORMAL)")
 	if err != nil {
 		return nil, fmt.Errorf("open sqlite: %w", err)
 	}
@@ -39,6 +41,20 @@ func Open(dbPath string) (*DB, error) {
 	return d, nil
 }
 
+// Ping checks that the database can still be reached.
+func (d *DB) Ping(ctx context.Context) error {
+	return d.db.PingContext(ctx)
+}
+
+// Snapshot writes a consistent copy of the database to path, which must not
+// exist, while other connections keep reading and writing.
+func (d *DB) Snapshot(path string) error {
+	if _, err := d.db.Exec("VACUUM INTO ?", path); err != nil {
+		return fmt.Errorf("snapshot database: %w", err)
+	}
+	return nil
+}
+
 // Close closes the database connection.
 func (d *DB) Close() error {
 	return d.db.Close()
@@ -79,8 +95,114 @@ func (d *DB) migrate() error {
 		message   TEXT NOT NULL
 	);
 	CREATE INDEX IF NOT EXISTS idx_task_logs_task ON task_logs(task_id, id);
+
+	-- Older task_logs lines, gzip-compressed in batches by PruneLogs.
+	CREATE TABLE IF NOT EXISTS task_log_archives (
+		id       INTEGER PRIMARY KEY AUTOINCREMENT,
+		task_id  TEXT NOT NULL,
+		first_id INTEGER NOT NULL,
+		last_id  INTEGER NOT NULL,
+		lines    INTEGER NOT NULL,
+		newest   DATETIME NOT NULL,
+		data     BLOB NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_task_log_archives_task ON task_log_archives(task_id, last_id);
+
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id      INTEGER PRIMARY KEY AUTOINCREMENT,
+		time    DATETIME NOT NULL,
+		actor   TEXT NOT NULL,
+		source  TEXT NOT NULL,
+		remote  TEXT NOT NULL DEFAULT '',
+		action  TEXT NOT NULL,
+		target  TEXT NOT NULL DEFAULT '',
+		details TEXT NOT NULL DEFAULT ''
+	);
+	CREATE INDEX IF NOT EXISTS idx_audit_log_time ON audit_log(time);
+
+	CREATE TABLE IF NOT EXISTS api_keys (
+		name       TEXT PRIMARY KEY,
+		key_hash   TEXT NOT NULL UNIQUE,
+		role       TEXT NOT NULL,
+		prefix     TEXT NOT NULL,
+		created_at DATETIME NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id           INTEGER PRIMARY KEY AUTOINCREMENT,
+		delivery_id  TEXT NOT NULL DEFAULT '',
+		endpoint     TEXT NOT NULL DEFAULT '/webhook',
+		event        TEXT NOT NULL,
+		action       TEXT NOT NULL DEFAULT '',
+		target       TEXT NOT NULL DEFAULT '',
+		sender       TEXT NOT NULL DEFAULT '',
+		remote       TEXT NOT NULL DEFAULT '',
+		headers      TEXT NOT NULL DEFAULT '{}',
+		payload      BLOB NOT NULL,
+		status       TEXT NOT NULL,
+		error        TEXT NOT NULL DEFAULT '',
+		attempts     INTEGER NOT NULL DEFAULT 0,
+		next_attempt DATETIME,
+		received_at  DATETIME NOT NULL,
+		updated_at   DATETIME NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status ON webhook_deliveries(status, next_attempt);
+
+	CREATE TABLE IF NOT EXISTS ai_cache (
+		key        TEXT PRIMARY KEY,
+		value      TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		expires_at DATETIME NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_ai_cache_expires ON ai_cache(expires_at);
+
+	CREATE TABLE IF NOT EXISTS embeddings (
+		repo      TEXT NOT NULL,
+		path      TEXT NOT NULL,
+		chunk     INTEGER NOT NULL,
+		file_hash TEXT NOT NULL,
+		model     TEXT NOT NULL,
+		vector    BLOB NOT NULL,
+		PRIMARY KEY (repo, path, chunk)
+	);
+
+	CREATE TABLE IF NOT EXISTS fixes (
+		id         INTEGER PRIMARY KEY AUTOINCREMENT,
+		repo       TEXT NOT NULL,
+		signature  TEXT NOT NULL,
+		failure    TEXT NOT NULL,
+		diff       TEXT NOT NULL,
+		diff_hash  TEXT NOT NULL,
+		task_id    TEXT NOT NULL,
+		uses       INTEGER NOT NULL DEFAULT 0,
+		successes  INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME NOT NULL,
+		UNIQUE (repo, signature, diff_hash)
+	);
 	`
 
-	_, err := d.db.Exec(schema)
+	if _, err := d.db.Exec(schema); err != nil {
+		return err
+	}
+	// Columns added after their table was first released.
+	if err := d.addColumn("webhook_deliveries", "endpoint", "TEXT NOT NULL DEFAULT '/webhook'"); err != nil {
+		return err
+	}
+	if err := d.addColumn("api_keys", "tenant", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	// The dashboard saved AGENTS.md under the escaped repo, owner%2Fname.
+	_, err := d.db.Exec(`UPDATE OR IGNORE project_agents SET project_repo = replace(project_repo, '%2F', '/') WHERE project_repo LIKE '%\%2F%' ESCAPE '\'`)
+	return err
+}
+
+// addColumn adds column to table unless it is already there.
+func (d *DB) addColumn(table, column, def string) error {
+	var n int
+	err := d.db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&n)
+	if err != nil || n > 0 {
+		return err
+	}
+	_, err = d.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, def))
 	return err
 }
@@ -1,8 +1,11 @@
 package storage
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -369,6 +372,255 @@ func TestAgents_List(t *testing.T) {
 	}
 }
 
+func TestAgents_UnescapesRepo(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetAgents("owner%2Frepo", "escaped")
+	db.Close()
+
+	db, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if content, _ := db.GetAgents("owner/repo"); content != "escaped" {
+		t.Errorf("expected the escaped repo to be migrated, got %q", content)
+	}
+}
+
+// --- Audit ---
+
+func TestAudit_RecordAndList(t *testing.T) {
+	db := testDB(t)
+
+	old := time.Now().Add(-2 * time.Hour)
+	entries := []AuditEntry{
+		{Time: old, Actor: "github:octocat", Source: AuditSourceWebhook, Action: AuditTaskCreated, Target: "acme/app#7"},
+		{Actor: "api-key", Source: AuditSourceWeb, Remote: "10.0.0.1:5000", Action: AuditProposalApproved, Target: "task-001"},
+		{Actor: "api-key", Source: AuditSourceWeb, Action: AuditSettingsChanged, Target: "settings", Details: "ai"},
+	}
+	for _, e := range entries {
+		if err := db.RecordAudit(e); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+
+	all, err := db.ListAudit(AuditFilter{})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(all))
+	}
+	if all[0].Action != AuditSettingsChanged || all[2].Action != AuditTaskCreated {
+		t.Errorf("expected newest first, got %+v", all)
+	}
+	if all[1].Remote != "10.0.0.1:5000" || all[1].Time.IsZero() {
+		t.Errorf("unexpected entry %+v", all[1])
+	}
+
+	byActor, _ := db.ListAudit(AuditFilter{Actor: "api-key"})
+	if len(byActor) != 2 {
+		t.Errorf("actor filter: expected 2, got %d", len(byActor))
+	}
+	byTarget, _ := db.ListAudit(AuditFilter{Action: AuditProposalApproved, Target: "task-001"})
+	if len(byTarget) != 1 {
+		t.Errorf("action/target filter: expected 1, got %d", len(byTarget))
+	}
+	recent, _ := db.ListAudit(AuditFilter{Since: time.Now().Add(-time.Hour)})
+	if len(recent) != 2 {
+		t.Errorf("since filter: expected 2, got %d", len(recent))
+	}
+	limited, _ := db.ListAudit(AuditFilter{Limit: 1})
+	if len(limited) != 1 || limited[0].Action != AuditSettingsChanged {
+		t.Errorf("limit: unexpected %+v", limited)
+	}
+}
+
+// --- API keys ---
+
+func TestWebhookDeliveries(t *testing.T) {
+	db := testDB(t)
+
+	failed := &WebhookDelivery{Event: "issues", Headers: map[string]string{"X-GitHub-Event": "issues"}, Payload: []byte(`{"action":"opened"}`)}
+	if err := db.RecordDelivery(failed); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	if failed.ID == 0 || failed.Status != DeliveryReceived {
+		t.Fatalf("expected an ID and received status, got %+v", failed)
+	}
+	failed.Action, failed.Target = "opened", "org/repo#1"
+	failed.Status, failed.Error, failed.Attempts = DeliveryFailed, "boom", 1
+	failed.NextAttempt = time.Now().Add(time.Minute)
+	if err := db.UpdateDelivery(failed); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if err := db.RecordDelivery(&WebhookDelivery{Event: "ping", Status: DeliveryIgnored}); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+
+	got, err := db.GetDelivery(failed.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if string(got.Payload) != `{"action":"opened"}` || got.Headers["X-GitHub-Event"] != "issues" ||
+		got.Status != DeliveryFailed || got.Attempts != 1 || got.Target != "org/repo#1" || got.NextAttempt.IsZero() {
+		t.Errorf("unexpected delivery %+v", got)
+	}
+	if _, err := db.GetDelivery(99); !errors.Is(err, ErrDeliveryNotFound) {
+		t.Errorf("expected ErrDeliveryNotFound, got %v", err)
+	}
+
+	all, _ := db.ListDeliveries(DeliveryFilter{})
+	if len(all) != 2 || all[0].Event != "ping" || all[1].Payload != nil {
+		t.Errorf("expected newest first without payloads, got %+v", all)
+	}
+	byStatus, _ := db.ListDeliveries(DeliveryFilter{Status: DeliveryFailed})
+	if len(byStatus) != 1 {
+		t.Errorf("status filter: expected 1, got %d", len(byStatus))
+	}
+
+	due, _ := db.DueDeliveries(time.Now(), time.Now())
+	if len(due) != 0 {
+		t.Errorf("expected nothing due before the backoff, got %+v", due)
+	}
+	due, _ = db.DueDeliveries(time.Now().Add(2*time.Minute), time.Now())
+	if len(due) != 1 || due[0].ID != failed.ID || due[0].Payload == nil {
+		t.Errorf("expected the failed delivery to be due, got %+v", due)
+	}
+}
+
+func TestAPIKeys_CreateLookupDelete(t *testing.T) {
+	db := testDB(t)
+
+	if has, _ := db.HasAPIKeys(); has {
+		t.Fatal("expected no keys in a new database")
+	}
+	key, created, err := db.CreateAPIKey("ci", RoleOperator)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if !strings.HasPrefix(key, created.Prefix) || created.Role != RoleOperator {
+		t.Errorf("unexpected key %q / %+v", key, created)
+	}
+	if _, _, err := db.CreateAPIKey("ci", RoleAdmin); !errors.Is(err, ErrAPIKeyExists) {
+		t.Errorf("expected ErrAPIKeyExists, got %v", err)
+	}
+	if _, _, err := db.CreateAPIKey("x", Role("root")); err == nil {
+		t.Error("expected an error for an unknown role")
+	}
+
+	found, err := db.LookupAPIKey(key)
+	if err != nil || found == nil || found.Name != "ci" || found.Role != RoleOperator {
+		t.Fatalf("lookup: %+v %v", found, err)
+	}
+	if found, _ := db.LookupAPIKey(key + "x"); found != nil {
+		t.Errorf("lookup of a wrong key returned %+v", found)
+	}
+	keys, _ := db.ListAPIKeys()
+	if len(keys) != 1 || keys[0].Name != "ci" {
+		t.Errorf("list: %+v", keys)
+	}
+
+	if err := db.DeleteAPIKey("ci"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := db.DeleteAPIKey("ci"); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("expected ErrAPIKeyNotFound, got %v", err)
+	}
+	if found, _ := db.LookupAPIKey(key); found != nil {
+		t.Error("deleted key still authenticates")
+	}
+}
+
+func TestAPIKeys_Tenant(t *testing.T) {
+	db := testDB(t)
+
+	key, created, err := db.CreateTenantAPIKey("payments-ci", RoleOperator, "payments")
+	if err != nil || created.Tenant != "payments" {
+		t.Fatalf("create: %+v %v", created, err)
+	}
+	if found, _ := db.LookupAPIKey(key); found == nil || found.Tenant != "payments" {
+		t.Errorf("lookup: %+v", found)
+	}
+	db.CreateAPIKey("admin", RoleAdmin)
+	keys, _ := db.ListAPIKeys()
+	if len(keys) != 2 || keys[0].Tenant != "" || keys[1].Tenant != "payments" {
+		t.Errorf("list: %+v", keys)
+	}
+}
+
+func TestAICache(t *testing.T) {
+	db := testDB(t)
+
+	if _, ok, err := db.GetAICache("k"); ok || err != nil {
+		t.Fatalf("expected a miss, got ok=%v err=%v", ok, err)
+	}
+	if err := db.PutAICache("k", "v1", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.PutAICache("k", "v2", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok, _ := db.GetAICache("k"); !ok || v != "v2" {
+		t.Errorf("get = %q, %v; want the replaced value", v, ok)
+	}
+	if err := db.PutAICache("old", "v", -time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := db.GetAICache("old"); ok {
+		t.Error("an expired entry was returned")
+	}
+	if n, err := db.ClearAICache(); err != nil || n != 2 {
+		t.Errorf("clear = %d, %v; want 2", n, err)
+	}
+}
+
+func TestEmbeddings(t *testing.T) {
+	db := testDB(t)
+
+	if err := db.ReplaceFileEmbeddings("a/b", "m1", "main.go", "h1", [][]float32{{1, 0}, {0, -1.5}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.ReplaceFileEmbeddings("a/b", "m1", "main.go", "h2", [][]float32{{0.5, 0.25}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.ReplaceFileEmbeddings("a/b", "m0", "old.go", "h", [][]float32{{1}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.ReplaceFileEmbeddings("other/repo", "m1", "x.go", "h", [][]float32{{1, 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := db.IndexedFiles("a/b", "m1")
+	if err != nil || len(files) != 1 || files["main.go"] != "h2" {
+		t.Fatalf("indexed files = %v, %v", files, err)
+	}
+	chunks, err := db.Embeddings("a/b", "m1")
+	if err != nil || len(chunks) != 1 || chunks[0].Vector[0] != 0.5 || chunks[0].Vector[1] != 0.25 {
+		t.Fatalf("embeddings = %+v, %v; want the replaced vector", chunks, err)
+	}
+
+	if err := db.PruneEmbeddingModels("a/b", "m1"); err != nil {
+		t.Fatal(err)
+	}
+	if files, _ := db.IndexedFiles("a/b", "m0"); len(files) != 0 {
+		t.Errorf("other model's embeddings were kept: %v", files)
+	}
+	if err := db.DeleteFileEmbeddings("a/b", "main.go"); err != nil {
+		t.Fatal(err)
+	}
+	if chunks, _ := db.Embeddings("a/b", "m1"); len(chunks) != 0 {
+		t.Errorf("deleted file still has embeddings: %+v", chunks)
+	}
+	if chunks, _ := db.Embeddings("other/repo", "m1"); len(chunks) != 1 {
+		t.Errorf("another repo's embeddings were touched: %+v", chunks)
+	}
+}
+
 // --- DB Lifecycle ---
 
 func TestOpen_CreatesDir(t *testing.T) {
@@ -393,3 +645,313 @@ func TestOpen_CloseIdempotent(t *testing.T) {
 	db.Close()
 	// t.Cleanup will call Close() again — should not panic
 }
+
+func TestOpen_UsesWAL(t *testing.T) {
+	db := testDB(t)
+
+	var mode string
+	if err := db.db.QueryRow(`PRAGMA journal_mode`).Scan(&mode); err != nil {
+		t.Fatalf("query journal_mode: %v", err)
+	}
+	if mode != "wal" {
+		t.Errorf("journal_mode = %q, want wal", mode)
+	}
+}
+
+// --- LogWriter ---
+
+func TestLogWriter_BatchesAndFlushes(t *testing.T) {
+	db := testDB(t)
+	w := db.NewLogWriter(0, 3, time.Hour)
+	defer w.Close()
+
+	for i := 0; i < 5; i++ {
+		w.Write("task-1", "info", "line")
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+
+	logs, err := db.GetLogs("task-1")
+	if err != nil {
+		t.Fatalf("get logs: %v", err)
+	}
+	if len(logs) != 5 {
+		t.Fatalf("expected 5 logs after flush, got %d", len(logs))
+	}
+	if logs[0].Timestamp.IsZero() {
+		t.Error("expected timestamp to be set")
+	}
+}
+
+func TestLogWriter_FullQueueWritesSynchronously(t *testing.T) {
+	db := testDB(t)
+	w := db.NewLogWriter(1, 100, time.Hour)
+	defer w.Close()
+
+	for i := 0; i < 50; i++ {
+		w.Write("task-1", "info", "line")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	w.Write("task-1", "info", "after close")
+
+	logs, err := db.GetLogs("task-1")
+	if err != nil {
+		t.Fatalf("get logs: %v", err)
+	}
+	if len(logs) != 51 {
+		t.Fatalf("expected no lost lines, got %d", len(logs))
+	}
+}
+
+func TestLogWriter_CloseWhileWriting(t *testing.T) {
+	db := testDB(t)
+	w := db.NewLogWriter(0, 10, time.Hour)
+
+	// Lines written while Close runs are either drained by it or written
+	// synchronously after it; none is left in the queue.
+	const writers, lines = 4, 50
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < lines; j++ {
+				w.Write("task-1", "info", "line")
+			}
+		}()
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	wg.Wait()
+
+	logs, err := db.GetLogs("task-1")
+	if err != nil {
+		t.Fatalf("get logs: %v", err)
+	}
+	if len(logs) != writers*lines {
+		t.Fatalf("expected %d lines, got %d", writers*lines, len(logs))
+	}
+}
+
+// --- Log retention ---
+
+// insertAged writes n lines of taskID logged age ago.
+func insertAged(t *testing.T, db *DB, taskID string, n int, age time.Duration, msg string) {
+	t.Helper()
+	entries := make([]LogEntry, n)
+	for i := range entries {
+		entries[i] = LogEntry{TaskID: taskID, Timestamp: time.Now().Add(-age), Level: "info", Message: msg}
+	}
+	if err := db.insertLogs(entries); err != nil {
+		t.Fatalf("insert logs: %v", err)
+	}
+}
+
+func TestPruneLogs_CompressKeepsLinesReadable(t *testing.T) {
+	db := testDB(t)
+	insertAged(t, db, "task-1", 3, 48*time.Hour, "old")
+	insertAged(t, db, "task-1", 2, time.Minute, "new")
+	all, _ := db.GetLogs("task-1")
+
+	res, err := db.PruneLogs(LogRetention{CompressAfter: 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	if res.Compressed != 3 || res.Deleted != 0 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	stats, _ := db.LogStats()
+	if stats.Lines != 2 || stats.ArchivedLines != 3 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	logs, err := db.GetLogs("task-1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if len(logs) != 5 {
+		t.Fatalf("got %d logs, want 5", len(logs))
+	}
+	for i, l := range logs {
+		if l.ID != all[i].ID || l.Message != all[i].Message || l.TaskID != "task-1" || !l.Timestamp.Equal(all[i].Timestamp) {
+			t.Errorf("line %d = %+v, want %+v", i, l, all[i])
+		}
+	}
+	since, _ := db.GetLogsSince("task-1", all[1].ID)
+	if len(since) != 3 || since[0].ID != all[2].ID {
+		t.Errorf("unexpected lines since %d: %+v", all[1].ID, since)
+	}
+}
+
+func TestPruneLogs_MaxAge(t *testing.T) {
+	db := testDB(t)
+	insertAged(t, db, "task-1", 2, 72*time.Hour, "expired")
+	insertAged(t, db, "task-1", 1, 36*time.Hour, "archived")
+	insertAged(t, db, "task-1", 1, time.Minute, "new")
+	if _, err := db.PruneLogs(LogRetention{CompressAfter: 24 * time.Hour}); err != nil {
+		t.Fatalf("compress: %v", err)
+	}
+
+	res, err := db.PruneLogs(LogRetention{MaxAge: 48 * time.Hour})
+	if err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	// The archive holding the expired lines also holds a newer one, so it
+	// is kept until that expires too.
+	if res.Deleted != 0 {
+		t.Errorf("deleted %d lines of a partly expired archive", res.Deleted)
+	}
+	if res, _ = db.PruneLogs(LogRetention{MaxAge: 30 * time.Hour}); res.Deleted != 3 {
+		t.Errorf("deleted %d lines, want 3", res.Deleted)
+	}
+	logs, _ := db.GetLogs("task-1")
+	if len(logs) != 1 || logs[0].Message != "new" {
+		t.Errorf("unexpected logs %+v", logs)
+	}
+}
+
+func TestPruneLogs_MaxRowsPerTask(t *testing.T) {
+	db := testDB(t)
+	insertAged(t, db, "task-1", 4, 48*time.Hour, "old")
+	insertAged(t, db, "task-1", 3, time.Minute, "new")
+	insertAged(t, db, "task-2", 2, time.Minute, "other")
+	if _, err := db.PruneLogs(LogRetention{CompressAfter: 24 * time.Hour}); err != nil {
+		t.Fatalf("compress: %v", err)
+	}
+	all, _ := db.GetLogs("task-1")
+
+	res, err := db.PruneLogs(LogRetention{MaxRowsPerTask: 5})
+	if err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	if res.Deleted != 2 {
+		t.Errorf("deleted %d lines, want 2", res.Deleted)
+	}
+	logs, _ := db.GetLogs("task-1")
+	if len(logs) != 5 || logs[0].ID != all[2].ID {
+		t.Errorf("expected the newest 5 lines, got %+v", logs)
+	}
+	if stats, _ := db.LogStats(); stats.ArchivedLines != 2 {
+		t.Errorf("expected the archive to be trimmed to 2 lines, got %+v", stats)
+	}
+	if other, _ := db.GetLogs("task-2"); len(other) != 2 {
+		t.Errorf("task-2 lost lines: %+v", other)
+	}
+
+	if _, err := db.PruneLogs(LogRetention{MaxRowsPerTask: 2}); err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	logs, _ = db.GetLogs("task-1")
+	if len(logs) != 2 || logs[0].Message != "new" {
+		t.Errorf("unexpected logs %+v", logs)
+	}
+	if stats, _ := db.LogStats(); stats.ArchivedLines != 0 {
+		t.Errorf("expected the archive to be deleted, got %+v", stats)
+	}
+}
+
+func TestPruneLogs_MaxBytes(t *testing.T) {
+	db := testDB(t)
+	insertAged(t, db, "task-1", 10, time.Hour, strings.Repeat("a", 10))
+	insertAged(t, db, "task-2", 10, time.Minute, strings.Repeat("b", 10))
+
+	res, err := db.PruneLogs(LogRetention{MaxBytes: 150})
+	if err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	if res.Deleted != 5 {
+		t.Errorf("deleted %d lines, want 5", res.Deleted)
+	}
+	if stats, _ := db.LogStats(); stats.Bytes != 150 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if logs, _ := db.GetLogs("task-2"); len(logs) != 10 {
+		t.Errorf("expected the newest task to keep its lines, got %d", len(logs))
+	}
+}
+
+func TestVacuum(t *testing.T) {
+	db := testDB(t)
+	insertAged(t, db, "task-1", 100, 48*time.Hour, strings.Repeat("x", 1000))
+	if _, err := db.PruneLogs(LogRetention{MaxAge: time.Hour}); err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	if err := db.Vacuum(); err != nil {
+		t.Fatalf("vacuum: %v", err)
+	}
+	if stats, _ := db.LogStats(); stats.Lines != 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	db := testDB(t)
+	if err := db.SetSetting("project", `{"name":"app"}`); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "snapshot.db")
+	if err := db.Snapshot(path); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	snap, err := Open(path)
+	if err != nil {
+		t.Fatalf("open snapshot: %v", err)
+	}
+	defer snap.Close()
+	if v, err := snap.GetSetting("project"); err != nil || v != `{"name":"app"}` {
+		t.Errorf("snapshot setting = %q, %v", v, err)
+	}
+	if err := db.Snapshot(path); err == nil {
+		t.Error("expected snapshotting over an existing file to fail")
+	}
+}
+
+// --- Fixes ---
+
+func TestFixes(t *testing.T) {
+	db := testDB(t)
+
+	for _, f := range []Fix{
+		{Repo: "o/r", Signature: "error: connection refused", Failure: "dial tcp: connection refused", Diff: "diff-a", TaskID: "t1"},
+		{Repo: "o/r", Signature: "error: connection refused", Failure: "again", Diff: "diff-a", TaskID: "t2"},
+		{Repo: "o/other", Signature: "panic", Diff: "diff-b", TaskID: "t3"},
+	} {
+		if err := db.AddFix(f); err != nil {
+			t.Fatalf("AddFix: %v", err)
+		}
+	}
+
+	fixes, err := db.ListFixes("o/r")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixes) != 1 || fixes[0].TaskID != "t2" || fixes[0].Failure != "again" {
+		t.Fatalf("the same diff for the same signature should be stored once, got %+v", fixes)
+	}
+	if all, _ := db.ListFixes(""); len(all) != 2 {
+		t.Fatalf("expected 2 fixes in all repos, got %d", len(all))
+	}
+
+	id := fixes[0].ID
+	if err := db.RecordFixUse([]int64{id}, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.RecordFixUse([]int64{id}, false); err != nil {
+		t.Fatal(err)
+	}
+	fixes, _ = db.ListFixes("o/r")
+	if fixes[0].Uses != 2 || fixes[0].Successes != 1 {
+		t.Errorf("uses/successes = %d/%d, want 2/1", fixes[0].Uses, fixes[0].Successes)
+	}
+
+	if ok, err := db.DeleteFix(id); err != nil || !ok {
+		t.Fatalf("DeleteFix = %v, %v", ok, err)
+	}
+	if ok, _ := db.DeleteFix(id); ok {
+		t.Error("deleting a missing fix should report false")
+	}
+}
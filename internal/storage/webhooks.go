@@ -0,0 +1,216 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Webhook delivery statuses.
+const (
+	// DeliveryReceived is stored before a delivery is processed; one still
+	// received after a restart was cut off and is processed again.
+	DeliveryReceived = "received"
+	// DeliveryAccepted started a task.
+	DeliveryAccepted = "accepted"
+	// DeliveryIgnored was not for rig: an untracked event, no matching
+	// trigger, or an issue already in flight.
+	DeliveryIgnored = "ignored"
+	// DeliveryFailed could not start its task and waits for NextAttempt.
+	DeliveryFailed = "failed"
+	// DeliveryDead failed every attempt; only a manual replay retries it.
+	DeliveryDead = "dead"
+)
+
+// defaultDeliveryLimit caps ListDeliveries when the filter sets no limit.
+const defaultDeliveryLimit = 100
+
+// ErrDeliveryNotFound is returned by GetDelivery for an unknown ID.
+var ErrDeliveryNotFound = errors.New("webhook delivery not found")
+
+// WebhookDelivery is one webhook request as received, with what became of
+// it.
+type WebhookDelivery struct {
+	ID int64 `json:"id"`
+	// DeliveryID is the platform's delivery GUID (X-GitHub-Delivery or
+	// X-Gitlab-Event-UUID).
+	DeliveryID string `json:"delivery_id,omitempty"`
+	// Endpoint is the path of the webhook endpoint that received it.
+	Endpoint string `json:"endpoint"`
+	Event    string `json:"event"`
+	// Action is the event action, such as opened or labeled.
+	Action string `json:"action,omitempty"`
+	// Target is owner/repo#number of the issue the event is about.
+	Target  string            `json:"target,omitempty"`
+	Sender  string            `json:"sender,omitempty"`
+	Remote  string            `json:"remote,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Payload is the raw body; list results leave it out.
+	Payload     []byte    `json:"payload,omitempty"`
+	Status      string    `json:"status"`
+	Error       string    `json:"error,omitempty"`
+	Attempts    int       `json:"attempts"`
+	NextAttempt time.Time `json:"next_attempt,omitzero"`
+	ReceivedAt  time.Time `json:"received_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// DeliveryFilter selects webhook deliveries. Zero fields match everything.
+type DeliveryFilter struct {
+	Status string
+	// Limit caps the number of deliveries; 0 means 100.
+	Limit int
+}
+
+// RecordDelivery stores a new delivery and sets its ID. Zero times mean
+// now and an empty status means received.
+func (d *DB) RecordDelivery(w *WebhookDelivery) error {
+	now := time.Now()
+	if w.ReceivedAt.IsZero() {
+		w.ReceivedAt = now
+	}
+	w.UpdatedAt = now
+	if w.Status == "" {
+		w.Status = DeliveryReceived
+	}
+	headers, err := json.Marshal(w.Headers)
+	if err != nil {
+		return fmt.Errorf("record delivery: %w", err)
+	}
+	payload := w.Payload
+	if payload == nil {
+		payload = []byte{}
+	}
+	res, err := d.db.Exec(
+		`INSERT INTO webhook_deliveries (delivery_id, endpoint, event, action, target, sender, remote, headers, payload, status, error, attempts, next_attempt, received_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		w.DeliveryID, w.Endpoint, w.Event, w.Action, w.Target, w.Sender, w.Remote, string(headers), payload,
+		w.Status, w.Error, w.Attempts, nullTime(w.NextAttempt), w.ReceivedAt.UTC(), w.UpdatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("record delivery: %w", err)
+	}
+	w.ID, err = res.LastInsertId()
+	return err
+}
+
+// UpdateDelivery saves the outcome fields of w: action, target, sender,
+// status, error, attempts and next attempt.
+func (d *DB) UpdateDelivery(w *WebhookDelivery) error {
+	w.UpdatedAt = time.Now()
+	res, err := d.db.Exec(
+		`UPDATE webhook_deliveries SET action = ?, target = ?, sender = ?, status = ?, error = ?, attempts = ?, next_attempt = ?, updated_at = ? WHERE id = ?`,
+		w.Action, w.Target, w.Sender, w.Status, w.Error, w.Attempts, nullTime(w.NextAttempt), w.UpdatedAt.UTC(), w.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("update delivery %d: %w", w.ID, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrDeliveryNotFound
+	}
+	return nil
+}
+
+// GetDelivery returns a delivery with its payload.
+func (d *DB) GetDelivery(id int64) (*WebhookDelivery, error) {
+	row := d.db.QueryRow(`SELECT `+deliveryColumns+`, payload FROM webhook_deliveries WHERE id = ?`, id)
+	var w WebhookDelivery
+	if err := scanDelivery(row, &w, &w.Payload); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrDeliveryNotFound
+		}
+		return nil, fmt.Errorf("get delivery %d: %w", id, err)
+	}
+	return &w, nil
+}
+
+// ListDeliveries returns matching deliveries without payloads, newest first.
+func (d *DB) ListDeliveries(f DeliveryFilter) ([]WebhookDelivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries`
+	var args []any
+	if f.Status != "" {
+		query += ` WHERE status = ?`
+		args = append(args, f.Status)
+	}
+	limit := f.Limit
+	if limit <= 0 {
+		limit = defaultDeliveryLimit
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+	return d.queryDeliveries(query, args...)
+}
+
+// DueDeliveries returns, oldest first and with payloads, the failed
+// deliveries whose next attempt is due and the received ones last touched
+// before cutoff: those were cut off by a restart.
+func (d *DB) DueDeliveries(now, cutoff time.Time) ([]WebhookDelivery, error) {
+	rows, err := d.db.Query(
+		`SELECT `+deliveryColumns+`, payload FROM webhook_deliveries
+		 WHERE (status = ? AND next_attempt <= ?) OR (status = ? AND updated_at < ?)
+		 ORDER BY id`,
+		DeliveryFailed, now.UTC(), DeliveryReceived, cutoff.UTC(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("due deliveries: %w", err)
+	}
+	defer rows.Close()
+	due := []WebhookDelivery{}
+	for rows.Next() {
+		var w WebhookDelivery
+		if err := scanDelivery(rows, &w, &w.Payload); err != nil {
+			return nil, fmt.Errorf("scan delivery: %w", err)
+		}
+		due = append(due, w)
+	}
+	return due, rows.Err()
+}
+
+const deliveryColumns = `id, delivery_id, endpoint, event, action, target, sender, remote, headers, status, error, attempts, next_attempt, received_at, updated_at`
+
+func (d *DB) queryDeliveries(query string, args ...any) ([]WebhookDelivery, error) {
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list deliveries: %w", err)
+	}
+	defer rows.Close()
+	list := []WebhookDelivery{}
+	for rows.Next() {
+		var w WebhookDelivery
+		if err := scanDelivery(rows, &w); err != nil {
+			return nil, fmt.Errorf("scan delivery: %w", err)
+		}
+		list = append(list, w)
+	}
+	return list, rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanDelivery scans deliveryColumns, then extra.
+func scanDelivery(s scanner, w *WebhookDelivery, extra ...any) error {
+	var headers string
+	var next sql.NullTime
+	dest := append([]any{
+		&w.ID, &w.DeliveryID, &w.Endpoint, &w.Event, &w.Action, &w.Target, &w.Sender, &w.Remote, &headers,
+		&w.Status, &w.Error, &w.Attempts, &next, &w.ReceivedAt, &w.UpdatedAt,
+	}, extra...)
+	if err := s.Scan(dest...); err != nil {
+		return err
+	}
+	if next.Valid {
+		w.NextAttempt = next.Time
+	}
+	return json.Unmarshal([]byte(headers), &w.Headers)
+}
+
+func nullTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC()
+}
@@ -0,0 +1,143 @@
+// Package tenant runs the teams of a shared rig serve side by side: each
+// tenant has its own config, state file, workspaces and API keys, and a
+// quota of concurrent tasks and monthly AI spend (tenants in rig.yaml).
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/metrics"
+)
+
+// ErrBudgetExceeded is returned by CheckBudget once a tenant has spent its
+// AI budget for the month.
+var ErrBudgetExceeded = errors.New("AI budget exceeded")
+
+// Tenant is a team served under /api/t/<name>/.
+type Tenant struct {
+	Name string
+	// StatePath is the tenant's state file, apart from rig serve's own.
+	StatePath string
+	Quota     config.TenantQuota
+
+	cfg     *config.Config
+	slots   chan struct{}
+	running atomic.Int32
+}
+
+// Load loads the tenants of cfg. Relative config paths are relative to dir,
+// the directory of cfg's file. Each tenant keeps its state in
+// tenants/<name>/ next to statePath, and its workspaces there too unless
+// its config sets source.workspaces.root.
+func Load(cfg *config.Config, dir, statePath string) ([]*Tenant, error) {
+	tenants := make([]*Tenant, 0, len(cfg.Tenants))
+	for _, tc := range cfg.Tenants {
+		path := tc.Config
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		tcfg, err := config.LoadConfig(path)
+		if err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", tc.Name, err)
+		}
+		if len(tcfg.Tenants) > 0 {
+			return nil, fmt.Errorf("tenant %s: %s must not define tenants", tc.Name, path)
+		}
+		if tc.Quota.AIBudgetUSD > 0 && len(tcfg.AI.Pricing) == 0 {
+			return nil, fmt.Errorf("tenant %s: quota.ai_budget_usd needs ai.pricing in %s", tc.Name, path)
+		}
+		t := &Tenant{Name: tc.Name, StatePath: StatePath(statePath, tc.Name), Quota: tc.Quota, cfg: tcfg}
+		if tcfg.Source.Workspaces.Root == "" {
+			tcfg.Source.Workspaces.Root = filepath.Join(filepath.Dir(t.StatePath), "workspaces")
+		}
+		if n := tc.Quota.MaxConcurrentTasks; n > 0 {
+			t.slots = make(chan struct{}, n)
+		}
+		tenants = append(tenants, t)
+	}
+	return tenants, nil
+}
+
+// StatePath returns the state file of the tenant name of a rig serve whose
+// own state file is statePath.
+func StatePath(statePath, name string) string {
+	return filepath.Join(filepath.Dir(statePath), "tenants", name, "state.json")
+}
+
+// Config returns the tenant's config.
+func (t *Tenant) Config() *config.Config {
+	return t.cfg
+}
+
+// Acquire waits for one of the tenant's quota.max_concurrent_tasks slots
+// and returns the function that frees it. It fails when ctx ends first.
+func (t *Tenant) Acquire(ctx context.Context) (func(), error) {
+	if t.slots != nil {
+		select {
+		case t.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, context.Cause(ctx)
+		}
+	}
+	t.running.Add(1)
+	return func() {
+		t.running.Add(-1)
+		if t.slots != nil {
+			<-t.slots
+		}
+	}, nil
+}
+
+// AICost returns the AI cost in USD of the tenant's tasks created in the
+// calendar month of now, in UTC.
+func (t *Tenant) AICost(now time.Time) (float64, error) {
+	state, err := core.LoadState(t.StatePath)
+	if err != nil {
+		return 0, err
+	}
+	now = now.UTC()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	report := metrics.BuildTaskReport(state.Tasks, from, from.AddDate(0, 1, 0), t.cfg.AI.Pricing, t.cfg.AI.Model)
+	return report.Totals.AICostUSD, nil
+}
+
+// CheckBudget returns ErrBudgetExceeded when the tenant has spent its
+// quota.ai_budget_usd in the month of now.
+func (t *Tenant) CheckBudget(now time.Time) error {
+	if t.Quota.AIBudgetUSD <= 0 {
+		return nil
+	}
+	cost, err := t.AICost(now)
+	if err != nil {
+		return err
+	}
+	if cost >= t.Quota.AIBudgetUSD {
+		return fmt.Errorf("%w: tenant %s spent $%.2f of $%.2f this month", ErrBudgetExceeded, t.Name, cost, t.Quota.AIBudgetUSD)
+	}
+	return nil
+}
+
+// Usage is what a tenant uses of its quota.
+type Usage struct {
+	Name         string             `json:"name"`
+	Quota        config.TenantQuota `json:"quota"`
+	RunningTasks int                `json:"running_tasks"`
+	// AICostUSD is the AI cost of the tasks created this month.
+	AICostUSD float64 `json:"ai_cost_usd"`
+}
+
+// Usage reports the tenant's use of its quota at now.
+func (t *Tenant) Usage(now time.Time) (Usage, error) {
+	cost, err := t.AICost(now)
+	if err != nil {
+		return Usage{}, err
+	}
+	return Usage{Name: t.Name, Quota: t.Quota, RunningTasks: int(t.running.Load()), AICostUSD: cost}, nil
+}
@@ -0,0 +1,114 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+)
+
+const tenantYAML = `project:
+  name: payments
+source:
+  platform: github
+  repo: acme/payments
+ai:
+  provider: anthropic
+  model: claude-sonnet
+  pricing:
+    claude-sonnet: { input: 3, output: 15 }
+deploy:
+  method: custom
+  config:
+    commands:
+      - name: deploy
+        run: "true"
+`
+
+func loadTenants(t *testing.T, quota config.TenantQuota) []*Tenant {
+	t.Helper()
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "tenants"), 0o755)
+	if err := os.WriteFile(filepath.Join(dir, "tenants", "payments.yaml"), []byte(tenantYAML), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{Tenants: []config.TenantConfig{{Name: "payments", Config: "tenants/payments.yaml", Quota: quota}}}
+	tenants, err := Load(cfg, dir, filepath.Join(dir, ".rig", "state.json"))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	return tenants
+}
+
+func TestLoad(t *testing.T) {
+	tenants := loadTenants(t, config.TenantQuota{})
+	if len(tenants) != 1 {
+		t.Fatalf("expected 1 tenant, got %d", len(tenants))
+	}
+	tn := tenants[0]
+	if filepath.Base(filepath.Dir(tn.StatePath)) != "payments" || tn.Config().Source.Repo != "acme/payments" {
+		t.Errorf("unexpected tenant %+v", tn)
+	}
+	if root := tn.Config().Source.Workspaces.Root; filepath.Dir(root) != filepath.Dir(tn.StatePath) {
+		t.Errorf("expected workspaces next to the tenant state, got %s", root)
+	}
+
+	cfg := &config.Config{Tenants: []config.TenantConfig{{Name: "ops", Config: "missing.yaml"}}}
+	if _, err := Load(cfg, t.TempDir(), "state.json"); err == nil {
+		t.Error("expected a missing tenant config to fail")
+	}
+}
+
+func TestAcquire(t *testing.T) {
+	tn := loadTenants(t, config.TenantQuota{MaxConcurrentTasks: 1})[0]
+	release, err := tn.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := tn.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the second task to wait, got %v", err)
+	}
+	if u, _ := tn.Usage(time.Now()); u.RunningTasks != 1 {
+		t.Errorf("expected 1 running task, got %d", u.RunningTasks)
+	}
+	release()
+	release, err = tn.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("expected a free slot after release: %v", err)
+	}
+	release()
+}
+
+func TestCheckBudget(t *testing.T) {
+	tn := loadTenants(t, config.TenantQuota{AIBudgetUSD: 10})[0]
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	state := &core.State{Tasks: []core.Task{
+		// $9 this month, $30 last month.
+		{ID: "task-1", CreatedAt: now.Add(-time.Hour), AIUsage: &core.AIUsage{InputTokens: 3_000_000}},
+		{ID: "task-2", CreatedAt: now.AddDate(0, -1, 0), AIUsage: &core.AIUsage{InputTokens: 10_000_000}},
+	}}
+	if err := core.SaveState(state, tn.StatePath); err != nil {
+		t.Fatal(err)
+	}
+	if err := tn.CheckBudget(now); err != nil {
+		t.Errorf("expected budget left, got %v", err)
+	}
+
+	state.Tasks = append(state.Tasks, core.Task{ID: "task-3", CreatedAt: now, AIUsage: &core.AIUsage{OutputTokens: 100_000}})
+	if err := core.SaveState(state, tn.StatePath); err != nil {
+		t.Fatal(err)
+	}
+	if err := tn.CheckBudget(now); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("expected ErrBudgetExceeded, got %v", err)
+	}
+	if u, _ := tn.Usage(now); u.AICostUSD != 10.5 {
+		t.Errorf("expected $10.50 this month, got %v", u.AICostUSD)
+	}
+}
@@ -2,6 +2,7 @@ package variable
 
 import (
 	"os"
+	"reflect"
 	"slices"
 	"testing"
 
@@ -418,7 +419,12 @@ func configEqual(a, b *config.Config) bool {
 	}
 
 	// Compare Source
-	if a.Source != b.Source {
+	as, bs := a.Source, b.Source
+	if !slices.Equal(as.Clone.Sparse, bs.Clone.Sparse) {
+		return false
+	}
+	as.Clone.Sparse, bs.Clone.Sparse = nil, nil
+	if !reflect.DeepEqual(as, bs) {
 		return false
 	}
 
@@ -0,0 +1,27 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/rigdev/rig/internal/storage"
+)
+
+// clearAICacheResponse is how many cached AI answers a clear removed.
+type clearAICacheResponse struct {
+	Deleted int64 `json:"deleted"`
+}
+
+// handleClearAICache removes every cached AI answer (ai.cache), so the
+// tasks started next ask the AI provider again.
+func handleClearAICache(db *storage.DB, audit *auditor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		n, err := db.ClearAICache()
+		if err != nil {
+			writeErrorJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+		audit.record(r, storage.AuditAICacheCleared, "ai_cache", fmt.Sprintf("removed %d entries", n))
+		writeJSON(w, http.StatusOK, clearAICacheResponse{Deleted: n})
+	}
+}
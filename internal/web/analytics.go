@@ -0,0 +1,38 @@
+package web
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/metrics"
+)
+
+// analyticsRoutes registers the aggregates the dashboard charts, each over
+// the period of the from and to query parameters (default the last 30 days).
+func analyticsRoutes(r chi.Router, statePath string) {
+	r.Get("/tasks-per-day", handleAnalytics(statePath, metrics.TasksPerDay))
+	r.Get("/failure-reasons", handleAnalytics(statePath, metrics.FailureReasons))
+	r.Get("/phase-durations", handleAnalytics(statePath, metrics.PhaseDurations))
+	r.Get("/retries", handleAnalytics(statePath, metrics.RetryDistribution))
+}
+
+// handleAnalytics serves the aggregate compute returns for the tasks in
+// state and the requested period.
+func handleAnalytics[T any](statePath string, compute func([]core.Task, time.Time, time.Time) T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		from, to, err := metrics.ReportPeriod(q.Get("from"), q.Get("to"), time.Now().UTC())
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		state, err := core.LoadState(statePath)
+		if err != nil {
+			writeErrorJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, compute(state.Tasks, from, to))
+	}
+}
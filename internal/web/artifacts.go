@@ -0,0 +1,122 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	adapterartifact "github.com/rigdev/rig/internal/adapter/artifact"
+	"github.com/rigdev/rig/internal/core"
+)
+
+// taskArtifact is an artifact of a task attempt with its download URL.
+type taskArtifact struct {
+	Attempt     int       `json:"attempt"`
+	Name        string    `json:"name"`
+	Kind        string    `json:"kind"`
+	Test        string    `json:"test,omitempty"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"content_type,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	URL         string    `json:"url"`
+}
+
+// handleListTaskArtifacts lists the artifacts the attempts of a task
+// reference, oldest attempt first. An artifact retention deleted is still
+// listed; downloading it answers 404.
+func handleListTaskArtifacts(statePath string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		state, err := core.LoadState(statePath)
+		if err != nil {
+			writeErrorJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+		task := state.GetTaskByID(chi.URLParam(r, "id"))
+		if task == nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "task not found"})
+			return
+		}
+		list := []taskArtifact{}
+		for _, a := range task.Attempts {
+			for _, ref := range a.Artifacts {
+				list = append(list, taskArtifact{
+					Attempt:     a.Number,
+					Name:        ref.Name,
+					Kind:        ref.Kind,
+					Test:        ref.Test,
+					Size:        ref.Size,
+					ContentType: ref.ContentType,
+					CreatedAt:   ref.CreatedAt,
+					URL:         fmt.Sprintf("/api/tasks/%s/artifacts/%d/%s", url.PathEscape(task.ID), a.Number, url.PathEscape(ref.Name)),
+				})
+			}
+		}
+		writeJSON(w, http.StatusOK, list)
+	}
+}
+
+// handleGetTaskArtifact downloads an artifact of a task attempt from the
+// configured artifact store. Only images and plain text are shown inline,
+// so a stored HTML report cannot run in the dashboard's origin.
+func handleGetTaskArtifact(statePath string, current ConfigFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		attempt, err := strconv.Atoi(chi.URLParam(r, "attempt"))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "attempt must be a number"})
+			return
+		}
+		state, err := core.LoadState(statePath)
+		if err != nil {
+			writeErrorJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+		task := state.GetTaskByID(chi.URLParam(r, "id"))
+		if task == nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "task not found"})
+			return
+		}
+		ref := task.FindArtifact(attempt, chi.URLParam(r, "name"))
+		if ref == nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "artifact not found"})
+			return
+		}
+		store, err := adapterartifact.New(current().Artifacts, statePath)
+		if err != nil {
+			writeErrorJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+		rc, err := store.Get(r.Context(), ref.Key)
+		if errors.Is(err, core.ErrArtifactNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "artifact is no longer stored"})
+			return
+		}
+		if err != nil {
+			writeErrorJSON(w, http.StatusBadGateway, err)
+			return
+		}
+		defer rc.Close()
+
+		contentType := ref.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		disposition := "attachment"
+		if strings.HasPrefix(contentType, "image/") || strings.HasPrefix(contentType, "text/plain") {
+			disposition = "inline"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("%s; filename=%q", disposition, ref.Name))
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.Copy(w, rc); err != nil {
+			slog.Warn("web: artifact download", "key", ref.Key, "err", err)
+		}
+	}
+}
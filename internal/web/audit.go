@@ -0,0 +1,119 @@
+package web
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rigdev/rig/internal/storage"
+)
+
+type callerKey struct{}
+
+type caller struct {
+	actor string
+	role  storage.Role
+	// tenant is the tenant a tenant key is limited to.
+	tenant string
+}
+
+// withActor stores the identity and role apiKeyAuth authenticated.
+func withActor(r *http.Request, actor string, role storage.Role) *http.Request {
+	return withCaller(r, caller{actor: actor, role: role})
+}
+
+func withCaller(r *http.Request, c caller) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), callerKey{}, c))
+}
+
+// requestTenant returns the tenant r's caller is limited to, or "".
+func requestTenant(r *http.Request) string {
+	c, _ := r.Context().Value(callerKey{}).(caller)
+	return c.tenant
+}
+
+// requestActor returns the authenticated identity of r, or "anonymous" when
+// the API is open.
+func requestActor(r *http.Request) string {
+	if c, ok := r.Context().Value(callerKey{}).(caller); ok && c.actor != "" {
+		return c.actor
+	}
+	return "anonymous"
+}
+
+// requestRole returns the role of r's caller; an open API grants admin.
+func requestRole(r *http.Request) storage.Role {
+	if c, ok := r.Context().Value(callerKey{}).(caller); ok && c.role != "" {
+		return c.role
+	}
+	return storage.RoleAdmin
+}
+
+// auditor records state-changing API calls. A nil auditor, or one without a
+// database, records nothing.
+type auditor struct {
+	db *storage.DB
+}
+
+func (a *auditor) record(r *http.Request, action, target, details string) {
+	if a == nil || a.db == nil {
+		return
+	}
+	err := a.db.RecordAudit(storage.AuditEntry{
+		Actor:   requestActor(r),
+		Source:  storage.AuditSourceWeb,
+		Remote:  r.RemoteAddr,
+		Action:  action,
+		Target:  target,
+		Details: details,
+	})
+	if err != nil {
+		slog.Warn("web: audit failed", "action", action, "err", err)
+	}
+}
+
+// handleGetAudit lists audit entries, newest first. ?since accepts a
+// duration ("24h") or an RFC 3339 time.
+func handleGetAudit(db *storage.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		filter := storage.AuditFilter{
+			Actor:  q.Get("actor"),
+			Action: q.Get("action"),
+			Target: q.Get("target"),
+		}
+		if s := q.Get("since"); s != "" {
+			since, err := parseSince(s, time.Now())
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "since must be a duration or an RFC 3339 time"})
+				return
+			}
+			filter.Since = since
+		}
+		if s := q.Get("limit"); s != "" {
+			limit, err := strconv.Atoi(s)
+			if err != nil || limit < 0 {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "limit must be a non-negative integer"})
+				return
+			}
+			filter.Limit = limit
+		}
+
+		entries, err := db.ListAudit(filter)
+		if err != nil {
+			writeErrorJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, entries)
+	}
+}
+
+// parseSince accepts a duration before now or an RFC 3339 time.
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
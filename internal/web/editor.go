@@ -0,0 +1,185 @@
+package web
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rigdev/rig/internal/core"
+)
+
+// editorTask is the compact task shape served to editor extensions.
+type editorTask struct {
+	ID              string          `json:"id"`
+	Status          core.TaskPhase  `json:"status"`
+	Phase           string          `json:"phase,omitempty"`
+	Repo            string          `json:"repo"`
+	Issue           editorIssue     `json:"issue"`
+	Branch          string          `json:"branch,omitempty"`
+	PRURL           string          `json:"pr_url,omitempty"`
+	Attempts        int             `json:"attempts"`
+	PendingProposal *editorProposal `json:"pending_proposal,omitempty"`
+}
+
+type editorIssue struct {
+	Number string `json:"number"`
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+}
+
+type editorProposal struct {
+	ID      string            `json:"id"`
+	Type    core.ProposalType `json:"type"`
+	Summary string            `json:"summary"`
+	Files   []string          `json:"files"`
+}
+
+// editorCheckout tells an editor (or rig open) how to get a task branch.
+type editorCheckout struct {
+	TaskID   string   `json:"task_id"`
+	Repo     string   `json:"repo"`
+	Branch   string   `json:"branch"`
+	CloneURL string   `json:"clone_url"`
+	Commands []string `json:"commands"`
+}
+
+// editorRoutes mounts the editor extension API: a small surface over the
+// task state for listing tasks, reviewing a proposal's diff, approving or
+// rejecting it and checking out the task branch.
+func editorRoutes(r chi.Router, statePath string, resume *resumer, audit *auditor) {
+	r.Get("/tasks", handleEditorTasks(statePath))
+	r.Get("/tasks/{taskId}", handleEditorTask(statePath))
+	r.Get("/tasks/{taskId}/diff", handleEditorDiff(statePath))
+	r.Get("/tasks/{taskId}/checkout", handleEditorCheckout(statePath))
+	r.Post("/tasks/{taskId}/approve", handleReview(statePath, resume, audit, true))
+	r.Post("/tasks/{taskId}/reject", handleReview(statePath, resume, audit, false))
+}
+
+// handleEditorTasks lists tasks, newest first. ?repo=owner/name limits the
+// list to the repository open in the editor and ?active=true hides
+// finished tasks.
+func handleEditorTasks(statePath string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		state, err := core.LoadState(statePath)
+		if err != nil {
+			writeErrorJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+		repo := r.URL.Query().Get("repo")
+		activeOnly := r.URL.Query().Get("active") == "true"
+
+		tasks := []editorTask{}
+		for i := len(state.Tasks) - 1; i >= 0; i-- {
+			t := &state.Tasks[i]
+			if repo != "" && !strings.EqualFold(t.Issue.Repo, repo) {
+				continue
+			}
+			if activeOnly && (t.Status == core.PhaseCompleted || t.Status == core.PhaseFailed) {
+				continue
+			}
+			tasks = append(tasks, toEditorTask(t))
+		}
+		writeJSON(w, http.StatusOK, tasks)
+	}
+}
+
+func handleEditorTask(statePath string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		task, ok := loadEditorTask(w, statePath, chi.URLParam(r, "taskId"))
+		if !ok {
+			return
+		}
+		writeJSON(w, http.StatusOK, toEditorTask(task))
+	}
+}
+
+// handleEditorDiff returns the pending proposal of a task as a unified diff.
+func handleEditorDiff(statePath string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		task, ok := loadEditorTask(w, statePath, chi.URLParam(r, "taskId"))
+		if !ok {
+			return
+		}
+		proposal := task.GetPendingProposal()
+		if proposal == nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "no pending proposal"})
+			return
+		}
+		w.Header().Set("Content-Type", "text/x-diff; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(core.ProposalDiff(proposal)))
+	}
+}
+
+func handleEditorCheckout(statePath string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		task, ok := loadEditorTask(w, statePath, chi.URLParam(r, "taskId"))
+		if !ok {
+			return
+		}
+		if task.Branch == "" {
+			writeJSON(w, http.StatusConflict, map[string]string{"error": "task has no branch yet"})
+			return
+		}
+		writeJSON(w, http.StatusOK, editorCheckout{
+			TaskID:   task.ID,
+			Repo:     task.Issue.Repo,
+			Branch:   task.Branch,
+			CloneURL: cloneURL(task.Issue),
+			Commands: []string{
+				"git fetch origin " + task.Branch,
+				"git checkout " + task.Branch,
+			},
+		})
+	}
+}
+
+func loadEditorTask(w http.ResponseWriter, statePath, id string) (*core.Task, bool) {
+	state, err := core.LoadState(statePath)
+	if err != nil {
+		writeErrorJSON(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	task := state.GetTaskByID(id)
+	if task == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "task not found"})
+		return nil, false
+	}
+	return task, true
+}
+
+func toEditorTask(t *core.Task) editorTask {
+	et := editorTask{
+		ID:       t.ID,
+		Status:   t.Status,
+		Repo:     t.Issue.Repo,
+		Issue:    editorIssue{Number: t.Issue.ID, Title: t.Issue.Title, URL: t.Issue.URL},
+		Branch:   t.Branch,
+		Attempts: len(t.Attempts),
+	}
+	if n := len(t.Pipeline); n > 0 {
+		et.Phase = string(t.Pipeline[n-1].Phase)
+	}
+	if t.PR != nil {
+		et.PRURL = t.PR.URL
+	}
+	if p := t.GetPendingProposal(); p != nil {
+		files := make([]string, len(p.Changes))
+		for i, c := range p.Changes {
+			files[i] = c.Path
+		}
+		et.PendingProposal = &editorProposal{ID: p.ID, Type: p.Type, Summary: p.Summary, Files: files}
+	}
+	return et
+}
+
+// cloneURL derives the https clone URL of the issue's repository from the
+// issue URL, so GitHub Enterprise hosts are kept.
+func cloneURL(issue core.Issue) string {
+	host := "https://github.com"
+	if u, err := url.Parse(issue.URL); err == nil && u.Host != "" {
+		host = u.Scheme + "://" + u.Host
+	}
+	return host + "/" + issue.Repo + ".git"
+}
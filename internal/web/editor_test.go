@@ -0,0 +1,72 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rigdev/rig/internal/core"
+)
+
+func TestEditorTasksFilter(t *testing.T) {
+	state := testState()
+	state.Tasks[1].Issue.Repo = "acme/web"
+	handler := NewHandler(writeStateFile(t, state), testConfig(), nil)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/editor/tasks?repo=acme/app", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var tasks []editorTask
+	if err := json.NewDecoder(rec.Body).Decode(&tasks); err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || tasks[0].ID != "task-001" || tasks[0].PRURL == "" {
+		t.Fatalf("unexpected tasks %+v", tasks)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/editor/tasks?active=true", nil))
+	tasks = nil
+	_ = json.NewDecoder(rec.Body).Decode(&tasks)
+	if len(tasks) != 1 || tasks[0].ID != "task-002" {
+		t.Fatalf("expected only the active task, got %+v", tasks)
+	}
+}
+
+func TestEditorDiffAndCheckout(t *testing.T) {
+	state := testState()
+	state.Tasks[1].Status = core.PhaseAwaitingApproval
+	state.Tasks[1].AddProposal(core.ProposalTestFix, "Fix test", "nil check", []core.ProposedChange{
+		{Path: "auth.go", Action: "modify", Before: "a\nb\n", After: "a\nc\n"},
+	})
+	handler := NewHandler(writeStateFile(t, state), testConfig(), nil)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/editor/tasks/task-002/diff", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "--- a/auth.go") || !strings.Contains(body, "-b\n+c\n") {
+		t.Errorf("unexpected diff:\n%s", body)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/editor/tasks/task-001/diff", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without pending proposal, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/editor/tasks/task-002/checkout", nil))
+	var co editorCheckout
+	if err := json.NewDecoder(rec.Body).Decode(&co); err != nil {
+		t.Fatal(err)
+	}
+	if co.Branch != "rig/issue-43" || co.CloneURL != "https://github.com/acme/app.git" {
+		t.Errorf("unexpected checkout %+v", co)
+	}
+}
@@ -0,0 +1,126 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/storage"
+)
+
+// explainer runs at most one AI failure explanation per task and lets a
+// teammate cancel it.
+type explainer struct {
+	newAI    func() (core.AIAdapter, error)
+	db       *storage.DB
+	inFlight sync.Map // task ID → *explainRun
+}
+
+// explainRun is a running explanation.
+type explainRun struct {
+	cancel context.CancelCauseFunc
+}
+
+// errExplainCancelled is the cause of an explanation cancelled by DELETE.
+var errExplainCancelled = errors.New("explanation cancelled")
+
+// handleExplainTask asks the AI to diagnose the last failed attempt of a
+// task and stores the diagnosis with the task. The call runs with the
+// request, so it ends when the client goes away or DELETE cancels it.
+func (x *explainer) handleExplainTask(statePath string, audit *auditor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		state, err := core.LoadState(statePath)
+		if err != nil {
+			writeErrorJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+		task := state.GetTaskByID(id)
+		if task == nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "task not found"})
+			return
+		}
+		ai, err := x.newAI()
+		if err != nil {
+			writeErrorJSON(w, http.StatusServiceUnavailable, err)
+			return
+		}
+
+		ctx, cancel := context.WithCancelCause(r.Context())
+		defer cancel(nil)
+		run := &explainRun{cancel: cancel}
+		if _, busy := x.inFlight.LoadOrStore(task.ID, run); busy {
+			writeJSON(w, http.StatusConflict, map[string]string{"error": "an explanation of this task is already running"})
+			return
+		}
+		defer x.inFlight.CompareAndDelete(task.ID, run)
+
+		explanation, err := core.ExplainFailure(ctx, ai, task, x.taskLogs(task.ID))
+		switch {
+		case errors.Is(err, core.ErrNoFailedAttempt):
+			writeErrorJSON(w, http.StatusConflict, err)
+			return
+		case errors.Is(err, core.ErrExplainUnsupported):
+			writeErrorJSON(w, http.StatusNotImplemented, err)
+			return
+		case errors.Is(context.Cause(ctx), errExplainCancelled):
+			writeErrorJSON(w, http.StatusConflict, errExplainCancelled)
+			return
+		case err != nil:
+			writeErrorJSON(w, http.StatusBadGateway, err)
+			return
+		}
+		explanation.RequestedBy = requestActor(r)
+
+		err = core.WithState(statePath, func(s *core.State) error {
+			t := s.GetTaskByID(task.ID)
+			if t == nil {
+				return fmt.Errorf("task %s was removed", task.ID)
+			}
+			t.Explanation = explanation
+			t.AIUsage = task.AIUsage
+			return nil
+		})
+		if err != nil {
+			writeErrorJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+		audit.record(r, storage.AuditTaskExplained, task.ID, fmt.Sprintf("attempt %d", explanation.Attempt))
+
+		writeJSON(w, http.StatusOK, explanation)
+	}
+}
+
+// handleCancelExplain cancels the running explanation of a task.
+func (x *explainer) handleCancelExplain(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	run, ok := x.inFlight.LoadAndDelete(id)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "no explanation of this task is running"})
+		return
+	}
+	run.(*explainRun).cancel(errExplainCancelled)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "cancelled", "task_id": id})
+}
+
+// taskLogs returns the stored log lines of a task, or none without a
+// database.
+func (x *explainer) taskLogs(taskID string) []string {
+	if x.db == nil {
+		return nil
+	}
+	entries, err := x.db.GetLogs(taskID)
+	if err != nil {
+		return nil
+	}
+	lines := make([]string, 0, len(entries))
+	for _, e := range entries {
+		lines = append(lines, fmt.Sprintf("[%s] %s", e.Level, e.Message))
+	}
+	return lines
+}
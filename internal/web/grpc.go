@@ -0,0 +1,468 @@
+package web
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/storage"
+	"github.com/rigdev/rig/pkg/rigpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// grpcPollInterval is how often WatchTasks and GetLogs with follow look for
+// changes, as the event stream does.
+var grpcPollInterval = 2 * time.Second
+
+// grpcPermissions are the permissions of the Rig methods that change
+// something; the others, and server reflection, need PermRead.
+var grpcPermissions = map[string]Permission{
+	rigpb.Rig_CreateTask_FullMethodName: PermOperate,
+	rigpb.Rig_Approve_FullMethodName:    PermApprove,
+	rigpb.Rig_Reject_FullMethodName:     PermApprove,
+}
+
+// NewGRPCServer returns the gRPC API server: the Rig service of pkg/rigpb
+// and server reflection. It authenticates and authorizes calls as the REST
+// API does, with the key in the authorization ("Bearer <key>") or
+// x-api-key metadata; dashboard sessions do not apply. tlsCfg, if set,
+// serves TLS. Of the options it uses ExecuteFunc, ResumeFunc and ConfigFunc.
+func NewGRPCServer(statePath string, cfg *config.Config, db *storage.DB, tlsCfg *tls.Config, opts ...HandlerOption) *grpc.Server {
+	var callbacks handlerCallbacks
+	for _, opt := range opts {
+		if opt != nil {
+			opt.applyTo(&callbacks)
+		}
+	}
+	svc := &grpcService{statePath: statePath, db: db, execute: callbacks.execute, current: callbacks.config}
+	if svc.current == nil {
+		svc.current = func() *config.Config { return cfg }
+	}
+	if callbacks.resume != nil {
+		svc.resume = &resumer{fn: callbacks.resume}
+	}
+	if db != nil {
+		svc.audit = &auditor{db: db}
+	}
+
+	auth := &grpcAuth{db: db, envKey: os.Getenv("RIG_API_KEY"), login: cfg.Server.Auth.Provider != ""}
+	serverOpts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(auth.unary),
+		grpc.ChainStreamInterceptor(auth.stream),
+	}
+	if tlsCfg != nil {
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(tlsCfg)))
+	}
+	srv := grpc.NewServer(serverOpts...)
+	rigpb.RegisterRigServer(srv, svc)
+	reflection.Register(srv)
+	return srv
+}
+
+// --- Authentication ---
+
+type grpcAuth struct {
+	db     *storage.DB
+	envKey string
+	login  bool
+}
+
+func (a *grpcAuth) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := a.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *grpcAuth) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authorize(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &callerStream{ServerStream: ss, ctx: ctx})
+}
+
+// authorize returns ctx with the caller of the call, or an Unauthenticated
+// or PermissionDenied status.
+func (a *grpcAuth) authorize(ctx context.Context, method string) (context.Context, error) {
+	open, err := apiOpen(a.db, a.envKey, a.login)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if open {
+		return ctx, nil
+	}
+	var c caller
+	if key := grpcAPIKey(ctx); key != "" {
+		if c, err = keyCaller(a.db, a.envKey, key); err != nil {
+			return nil, grpcError(err)
+		}
+	}
+	if c.actor == "" {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	// The gRPC API serves the top-level config only.
+	if c.tenant != "" {
+		return nil, status.Errorf(codes.PermissionDenied, "forbidden: the key is limited to tenant %s", c.tenant)
+	}
+	perm := grpcPermissions[method]
+	if perm == "" {
+		perm = PermRead
+	}
+	if !roleAllows(c.role, perm) {
+		return nil, status.Errorf(codes.PermissionDenied, "forbidden: role %s lacks the %s permission", c.role, perm)
+	}
+	return context.WithValue(ctx, callerKey{}, c), nil
+}
+
+// grpcAPIKey returns the key sent in the call's metadata, if any.
+func grpcAPIKey(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		if key, ok := strings.CutPrefix(v, "Bearer "); ok {
+			return key
+		}
+	}
+	if v := md.Get("x-api-key"); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// callerStream is a server stream whose context carries the caller.
+type callerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *callerStream) Context() context.Context { return s.ctx }
+
+// grpcError converts an error of the shared API code to a status: an
+// *apiError by its HTTP status, anything else as a sanitized Internal.
+func grpcError(err error) error {
+	var ae *apiError
+	if !errors.As(err, &ae) {
+		return status.Error(codes.Internal, sanitizeError(err.Error()))
+	}
+	code := codes.Internal
+	switch ae.status {
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusConflict:
+		code = codes.FailedPrecondition
+	case http.StatusServiceUnavailable:
+		code = codes.Unavailable
+	}
+	return status.Error(code, ae.msg)
+}
+
+// grpcIdempotencyKey returns the idempotency-key sent in the call's
+// metadata, if any.
+func grpcIdempotencyKey(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get("idempotency-key"); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// --- Service ---
+
+type grpcService struct {
+	rigpb.UnimplementedRigServer
+
+	statePath string
+	db        *storage.DB
+	current   ConfigFunc
+	execute   ExecuteFunc
+	resume    *resumer
+	audit     *auditor
+}
+
+func (s *grpcService) CreateTask(ctx context.Context, req *rigpb.CreateTaskRequest) (*rigpb.Task, error) {
+	task, replayed, err := createTask(context.Background(), s.statePath, s.current(), s.execute, createTaskRequest{
+		Project:        req.GetProject(),
+		IssueNum:       req.GetIssueNumber(),
+		IssueURL:       req.GetIssueUrl(),
+		IssueID:        req.GetIssueId(),
+		Title:          req.GetTitle(),
+		Environment:    req.GetEnvironment(),
+		IdempotencyKey: grpcIdempotencyKey(ctx),
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if replayed {
+		grpc.SetHeader(ctx, metadata.Pairs("idempotent-replayed", "true"))
+		return taskProto(task), nil
+	}
+	s.record(ctx, storage.AuditTaskCreated, task.ID, task.Issue.Repo+"#"+task.Issue.ID)
+	return taskProto(task), nil
+}
+
+func (s *grpcService) GetTask(ctx context.Context, req *rigpb.GetTaskRequest) (*rigpb.Task, error) {
+	state, err := core.LoadState(s.statePath)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	task := state.GetTaskByID(req.GetId())
+	if task == nil {
+		return nil, status.Error(codes.NotFound, "task not found")
+	}
+	return taskProto(task), nil
+}
+
+func (s *grpcService) ListTasks(ctx context.Context, req *rigpb.ListTasksRequest) (*rigpb.ListTasksResponse, error) {
+	query, err := filterQuery(req.GetFilter())
+	if err != nil {
+		return nil, err
+	}
+	state, err := core.LoadState(s.statePath)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	tasks, total := query.Apply(state.Tasks)
+	resp := &rigpb.ListTasksResponse{Tasks: make([]*rigpb.Task, len(tasks)), Total: int32(total)}
+	for i := range tasks {
+		resp.Tasks[i] = taskProto(&tasks[i])
+	}
+	return resp, nil
+}
+
+// WatchTasks sends every matching task, then each one that changes, with
+// the status counts of the moment.
+func (s *grpcService) WatchTasks(req *rigpb.WatchTasksRequest, stream rigpb.Rig_WatchTasksServer) error {
+	query, err := filterQuery(req.GetFilter())
+	if err != nil {
+		return err
+	}
+	sent := make(map[string]string)
+	ticker := time.NewTicker(grpcPollInterval)
+	defer ticker.Stop()
+	for {
+		state, err := core.LoadState(s.statePath)
+		if err != nil {
+			slog.Warn("web: gRPC watch", "err", err)
+		} else {
+			tasks, _ := query.Apply(state.Tasks)
+			counts := make(map[string]int32)
+			for phase, n := range core.CountByStatus(state.Tasks) {
+				counts[string(phase)] = int32(n)
+			}
+			for i := range tasks {
+				cur := marshalTasks(tasks[i])
+				if sent[tasks[i].ID] == cur {
+					continue
+				}
+				if err := stream.Send(&rigpb.TaskEvent{Task: taskProto(&tasks[i]), Counts: counts}); err != nil {
+					return err
+				}
+				sent[tasks[i].ID] = cur
+			}
+		}
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *grpcService) Approve(ctx context.Context, req *rigpb.ReviewRequest) (*rigpb.ReviewResponse, error) {
+	return s.review(ctx, req.GetTaskId(), true)
+}
+
+func (s *grpcService) Reject(ctx context.Context, req *rigpb.ReviewRequest) (*rigpb.ReviewResponse, error) {
+	return s.review(ctx, req.GetTaskId(), false)
+}
+
+func (s *grpcService) review(ctx context.Context, taskID string, approved bool) (*rigpb.ReviewResponse, error) {
+	result, err := reviewProposal(s.statePath, s.resume, taskID, approved)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	action := storage.AuditProposalApproved
+	if !approved {
+		action = storage.AuditProposalRejected
+	}
+	s.record(ctx, action, result.taskID, result.proposalID)
+	return &rigpb.ReviewResponse{Status: result.Status, Message: result.Message}, nil
+}
+
+// GetLogs sends the stored log lines of a task. With follow it polls for
+// new ones until the task has finished and its last lines are sent.
+func (s *grpcService) GetLogs(req *rigpb.GetLogsRequest, stream rigpb.Rig_GetLogsServer) error {
+	if s.db == nil {
+		return status.Error(codes.Unavailable, "logs not available")
+	}
+	after := req.GetAfterId()
+	ticker := time.NewTicker(grpcPollInterval)
+	defer ticker.Stop()
+	for {
+		// Read the status first so lines written as the task finishes
+		// are still sent.
+		finished := true
+		if req.GetFollow() {
+			finished = s.taskFinished(req.GetTaskId())
+		}
+		logs, err := s.db.GetLogsSince(req.GetTaskId(), after)
+		if err != nil {
+			return grpcError(err)
+		}
+		for _, l := range logs {
+			if err := stream.Send(&rigpb.LogEntry{
+				Id:        l.ID,
+				TaskId:    l.TaskID,
+				Timestamp: timestamppb.New(l.Timestamp),
+				Level:     l.Level,
+				Message:   l.Message,
+			}); err != nil {
+				return err
+			}
+			after = l.ID
+		}
+		if finished {
+			return nil
+		}
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// taskFinished reports whether the task is done running: completed, failed,
+// rolled back, or gone.
+func (s *grpcService) taskFinished(taskID string) bool {
+	state, err := core.LoadState(s.statePath)
+	if err != nil {
+		return false
+	}
+	task := state.GetTaskByID(taskID)
+	if task == nil {
+		return true
+	}
+	switch task.Status {
+	case core.PhaseCompleted, core.PhaseFailed, core.PhaseRollback, core.PhaseDryRun:
+		return true
+	}
+	return false
+}
+
+// record adds a state-changing call to the audit log.
+func (s *grpcService) record(ctx context.Context, action, target, details string) {
+	if s.audit == nil {
+		return
+	}
+	entry := storage.AuditEntry{Actor: "anonymous", Source: storage.AuditSourceGRPC, Action: action, Target: target, Details: details}
+	if c, ok := ctx.Value(callerKey{}).(caller); ok && c.actor != "" {
+		entry.Actor = c.actor
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		entry.Remote = p.Addr.String()
+	}
+	if err := s.audit.db.RecordAudit(entry); err != nil {
+		slog.Warn("web: audit failed", "action", action, "err", err)
+	}
+}
+
+// filterQuery reads a TaskFilter as GET /api/tasks reads its query
+// parameters.
+func filterQuery(f *rigpb.TaskFilter) (core.TaskQuery, error) {
+	q := url.Values{}
+	for name, v := range map[string]string{
+		"repo":  f.GetRepo(),
+		"since": f.GetSince(),
+		"until": f.GetUntil(),
+		"q":     f.GetQ(),
+		"sort":  f.GetSort(),
+	} {
+		if v != "" {
+			q.Set(name, v)
+		}
+	}
+	q["status"] = f.GetStatus()
+	if f.GetLimit() != 0 {
+		q.Set("limit", strconv.Itoa(int(f.GetLimit())))
+	}
+	if f.GetOffset() != 0 {
+		q.Set("offset", strconv.Itoa(int(f.GetOffset())))
+	}
+	query, err := parseTaskQuery(q, time.Now())
+	if err != nil {
+		return query, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return query, nil
+}
+
+// --- Conversion ---
+
+func taskProto(t *core.Task) *rigpb.Task {
+	p := &rigpb.Task{
+		Id: t.ID,
+		Issue: &rigpb.Issue{
+			Platform: t.Issue.Platform,
+			Repo:     t.Issue.Repo,
+			Id:       t.Issue.ID,
+			Title:    t.Issue.Title,
+			Url:      t.Issue.URL,
+			Labels:   t.Issue.Labels,
+		},
+		Branch:      t.Branch,
+		Status:      string(t.Status),
+		Environment: t.Environment,
+		CreatedAt:   timestamppb.New(t.CreatedAt),
+		CompletedAt: timeProto(t.CompletedAt),
+	}
+	if t.PR != nil {
+		p.Pr = &rigpb.PullRequest{Id: t.PR.ID, Url: t.PR.URL, Draft: t.PR.Draft, Merged: t.PR.Merged}
+	}
+	for _, a := range t.Attempts {
+		p.Attempts = append(p.Attempts, &rigpb.Attempt{
+			Number:       int32(a.Number),
+			Status:       a.Status,
+			FailReason:   string(a.FailReason),
+			FilesChanged: a.FilesChanged,
+			StartedAt:    timestamppb.New(a.StartedAt),
+			CompletedAt:  timeProto(a.CompletedAt),
+		})
+	}
+	for _, pr := range t.Proposals {
+		p.Proposals = append(p.Proposals, &rigpb.Proposal{
+			Id:        pr.ID,
+			Type:      string(pr.Type),
+			Summary:   pr.Summary,
+			Reason:    pr.Reason,
+			Status:    string(pr.Status),
+			CreatedAt: timestamppb.New(pr.CreatedAt),
+		})
+	}
+	return p
+}
+
+func timeProto(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
@@ -0,0 +1,261 @@
+package web
+
+import (
+	"context"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/storage"
+	"github.com/rigdev/rig/pkg/rigpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dialGRPC serves srv in memory and returns a connection to it.
+func dialGRPC(t *testing.T, srv *grpc.Server) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func withKey(key string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+key)
+}
+
+func TestGRPCTasksAndRoles(t *testing.T) {
+	t.Setenv("RIG_API_KEY", "")
+	statePath := writeStateFile(t, testState())
+	db, err := storage.Open(filepath.Join(t.TempDir(), "rig.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	viewer, _, err := db.CreateAPIKey("dash", storage.RoleViewer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	operator, _, err := db.CreateAPIKey("platform", storage.RoleOperator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan core.Issue, 1)
+	execute := ExecuteFunc(func(_ context.Context, issue core.Issue) error {
+		started <- issue
+		return nil
+	})
+	client := rigpb.NewRigClient(dialGRPC(t, NewGRPCServer(statePath, testConfig(), db, nil, execute)))
+
+	if _, err := client.ListTasks(context.Background(), &rigpb.ListTasksRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("call without a key: %v, want Unauthenticated", err)
+	}
+	create := &rigpb.CreateTaskRequest{Project: "acme/app", IssueNumber: "77", Title: "Slow search"}
+	if _, err := client.CreateTask(withKey(viewer), create); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("viewer CreateTask: %v, want PermissionDenied", err)
+	}
+	if _, err := client.CreateTask(withKey(operator), &rigpb.CreateTaskRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("CreateTask without an issue: %v, want InvalidArgument", err)
+	}
+
+	task, err := client.CreateTask(withKey(operator), create)
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	if task.GetStatus() != string(core.PhaseQueued) || task.GetIssue().GetRepo() != "acme/app" || task.GetIssue().GetId() != "77" {
+		t.Errorf("unexpected task %v", task)
+	}
+	select {
+	case issue := <-started:
+		if issue.ID != "77" {
+			t.Errorf("started issue %+v", issue)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("task was not started")
+	}
+
+	keyed := metadata.AppendToOutgoingContext(withKey(operator), "idempotency-key", "retry-1")
+	first, err := client.CreateTask(keyed, &rigpb.CreateTaskRequest{Project: "acme/app", IssueNumber: "78"})
+	if err != nil {
+		t.Fatalf("CreateTask with a key: %v", err)
+	}
+	<-started
+	var header metadata.MD
+	again, err := client.CreateTask(keyed, &rigpb.CreateTaskRequest{Project: "acme/app", IssueNumber: "78"}, grpc.Header(&header))
+	if err != nil || again.GetId() != first.GetId() || len(header.Get("idempotent-replayed")) == 0 {
+		t.Errorf("retried CreateTask = %v, %v (header %v), want task %s replayed", again, err, header, first.GetId())
+	}
+	select {
+	case issue := <-started:
+		t.Errorf("a retried CreateTask started issue %s", issue.ID)
+	default:
+	}
+
+	got, err := client.GetTask(withKey(viewer), &rigpb.GetTaskRequest{Id: "task-001"})
+	if err != nil {
+		t.Fatalf("GetTask: %v", err)
+	}
+	if got.GetPr().GetUrl() != "https://github.com/acme/app/pull/99" || len(got.GetAttempts()) != 1 || got.GetCompletedAt() == nil {
+		t.Errorf("unexpected task %v", got)
+	}
+	if _, err := client.GetTask(withKey(viewer), &rigpb.GetTaskRequest{Id: "nope"}); status.Code(err) != codes.NotFound {
+		t.Errorf("GetTask of a missing task: %v, want NotFound", err)
+	}
+
+	list, err := client.ListTasks(withKey(viewer), &rigpb.ListTasksRequest{Filter: &rigpb.TaskFilter{Status: []string{"coding", "queued"}, Sort: "id"}})
+	if err != nil {
+		t.Fatalf("ListTasks: %v", err)
+	}
+	if list.GetTotal() != 3 || list.GetTasks()[0].GetId() != "task-002" {
+		t.Errorf("unexpected list %v", list)
+	}
+	if _, err := client.ListTasks(withKey(viewer), &rigpb.ListTasksRequest{Filter: &rigpb.TaskFilter{Sort: "color"}}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("ListTasks with a bad sort: %v, want InvalidArgument", err)
+	}
+
+	entries, err := db.ListAudit(storage.AuditFilter{Action: storage.AuditTaskCreated})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Actor != "key:platform" || entries[0].Source != storage.AuditSourceGRPC {
+		t.Errorf("audit entries = %+v", entries)
+	}
+}
+
+func TestGRPCApproveAndReflection(t *testing.T) {
+	t.Setenv("RIG_API_KEY", "")
+	state := testState()
+	state.Tasks[1].Status = core.PhaseAwaitingApproval
+	state.Tasks[1].AddProposal(core.ProposalDeployApproval, "Deploy?", "approval required", nil)
+	statePath := writeStateFile(t, state)
+	resumed := make(chan bool, 1)
+	resume := ResumeFunc(func(taskID string, approved bool) error {
+		resumed <- approved
+		return nil
+	})
+	conn := dialGRPC(t, NewGRPCServer(statePath, testConfig(), nil, nil, resume))
+	client := rigpb.NewRigClient(conn)
+
+	if _, err := client.Approve(context.Background(), &rigpb.ReviewRequest{TaskId: "task-001"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Approve without a proposal: %v, want InvalidArgument", err)
+	}
+	resp, err := client.Approve(context.Background(), &rigpb.ReviewRequest{TaskId: "task-002"})
+	if err != nil {
+		t.Fatalf("Approve: %v", err)
+	}
+	if resp.GetStatus() != "approved" {
+		t.Errorf("unexpected response %v", resp)
+	}
+	select {
+	case approved := <-resumed:
+		if !approved {
+			t.Error("task was resumed as rejected")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("task was not resumed")
+	}
+
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.Send(&reflectionpb.ServerReflectionRequest{MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{}}); err != nil {
+		t.Fatal(err)
+	}
+	reply, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, svc := range reply.GetListServicesResponse().GetService() {
+		found = found || svc.GetName() == "rig.v1.Rig"
+	}
+	if !found {
+		t.Errorf("reflection does not list rig.v1.Rig: %v", reply)
+	}
+}
+
+func TestGRPCStreams(t *testing.T) {
+	t.Setenv("RIG_API_KEY", "")
+	defer func(d time.Duration) { grpcPollInterval = d }(grpcPollInterval)
+	grpcPollInterval = 10 * time.Millisecond
+
+	statePath := writeStateFile(t, testState())
+	db, err := storage.Open(filepath.Join(t.TempDir(), "rig.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := db.AppendLog("task-002", "info", "Planning"); err != nil {
+		t.Fatal(err)
+	}
+	client := rigpb.NewRigClient(dialGRPC(t, NewGRPCServer(statePath, testConfig(), db, nil)))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	setStatus := func(phase core.TaskPhase) {
+		t.Helper()
+		if err := core.WithState(statePath, func(s *core.State) error {
+			s.GetTaskByID("task-002").Status = phase
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	watch, err := client.WatchTasks(ctx, &rigpb.WatchTasksRequest{Filter: &rigpb.TaskFilter{Sort: "id"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"task-001", "task-002"} {
+		ev, err := watch.Recv()
+		if err != nil {
+			t.Fatalf("WatchTasks: %v", err)
+		}
+		if ev.GetTask().GetId() != want || ev.GetCounts()["completed"] != 1 {
+			t.Errorf("event %v, want task %s", ev, want)
+		}
+	}
+	setStatus(core.PhaseTesting)
+	ev, err := watch.Recv()
+	if err != nil {
+		t.Fatalf("WatchTasks: %v", err)
+	}
+	if ev.GetTask().GetId() != "task-002" || ev.GetTask().GetStatus() != "testing" {
+		t.Errorf("change event %v", ev)
+	}
+
+	logs, err := client.GetLogs(ctx, &rigpb.GetLogsRequest{TaskId: "task-002", Follow: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	line, err := logs.Recv()
+	if err != nil || line.GetMessage() != "Planning" {
+		t.Fatalf("first line %v, %v", line, err)
+	}
+	if err := db.AppendLog("task-002", "info", "Tests passed"); err != nil {
+		t.Fatal(err)
+	}
+	setStatus(core.PhaseCompleted)
+	if line, err = logs.Recv(); err != nil || line.GetMessage() != "Tests passed" {
+		t.Fatalf("followed line %v, %v", line, err)
+	}
+	if _, err := logs.Recv(); err != io.EOF {
+		t.Errorf("expected the stream to end with the task, got %v", err)
+	}
+}
@@ -1,24 +1,34 @@
 package web
 
 import (
+	"bytes"
+	"context"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	adapterai "github.com/rigdev/rig/internal/adapter/ai"
+	adaptergit "github.com/rigdev/rig/internal/adapter/git"
 	"github.com/rigdev/rig/internal/chatops"
 	"github.com/rigdev/rig/internal/config"
 	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/httpserver"
+	"github.com/rigdev/rig/internal/logging"
 	"github.com/rigdev/rig/internal/metrics"
 	"github.com/rigdev/rig/internal/storage"
+	"github.com/rigdev/rig/internal/tenant"
 )
 
 //go:embed static/*
@@ -31,6 +41,8 @@ type configResponse struct {
 	AI       aiInfo       `json:"ai"`
 	Deploy   deployInfo   `json:"deploy"`
 	Workflow workflowInfo `json:"workflow"`
+	// Profile is the config profile in use (--profile / RIG_PROFILE).
+	Profile string `json:"profile,omitempty"`
 }
 
 type projectInfo struct {
@@ -61,12 +73,74 @@ type workflowInfo struct {
 }
 
 // ExecuteFunc is a callback that executes the automation pipeline for an issue.
-type ExecuteFunc func(issue core.Issue) error
+// ctx does not end with the request; it carries values such as
+// core.WithoutAICache for the task.
+type ExecuteFunc func(ctx context.Context, issue core.Issue) error
+
+// ResumeFunc is a callback that resumes a task awaiting approval once its
+// pending proposal has been approved or rejected.
+type ResumeFunc func(taskID string, approved bool) error
+
+// ReplayFunc processes a stored webhook delivery again.
+type ReplayFunc func(id int64) error
+
+// RerunFunc runs the deploy or test step of a finished task again, as
+// core.Engine.Rerun does. ctx is as for ExecuteFunc.
+type RerunFunc func(ctx context.Context, taskID, step string) error
+
+// ConfigFunc returns the config in use, for servers that reload it while
+// running. Without one the handler keeps the config it was built with.
+type ConfigFunc func() *config.Config
+
+// ReloadStatusFunc reports the outcome of the last config reload, shown by
+// GET /api/status.
+type ReloadStatusFunc func() config.ReloadStatus
+
+// HandlerOption wires an optional engine callback into NewHandler.
+// ExecuteFunc, ResumeFunc, ReplayFunc, RerunFunc, ConfigFunc,
+// ReloadStatusFunc, ReadyFunc and Tenants implement it.
+type HandlerOption interface {
+	applyTo(cb *handlerCallbacks)
+}
+
+type handlerCallbacks struct {
+	execute      ExecuteFunc
+	resume       ResumeFunc
+	replay       ReplayFunc
+	rerun        RerunFunc
+	config       ConfigFunc
+	reloadStatus ReloadStatusFunc
+	ready        ReadyFunc
+	tenants      Tenants
+}
+
+func (f ExecuteFunc) applyTo(cb *handlerCallbacks)      { cb.execute = f }
+func (f ResumeFunc) applyTo(cb *handlerCallbacks)       { cb.resume = f }
+func (f ReplayFunc) applyTo(cb *handlerCallbacks)       { cb.replay = f }
+func (f RerunFunc) applyTo(cb *handlerCallbacks)        { cb.rerun = f }
+func (f ConfigFunc) applyTo(cb *handlerCallbacks)       { cb.config = f }
+func (f ReloadStatusFunc) applyTo(cb *handlerCallbacks) { cb.reloadStatus = f }
+
+// resumer runs a ResumeFunc in the background, at most once per task at a time.
+type resumer struct {
+	fn       ResumeFunc
+	inFlight sync.Map
+}
+
+// start launches the resume and reports false if one is already running.
+func (r *resumer) start(taskID string, approved bool) bool {
+	if _, busy := r.inFlight.LoadOrStore(taskID, struct{}{}); busy {
+		return false
+	}
+	go func() {
+		defer r.inFlight.Delete(taskID)
+		if err := r.fn(taskID, approved); err != nil {
+			slog.Error("web: resume task failed", logging.TaskKey, taskID, "err", sanitizeError(err.Error()))
+		}
+	}()
+	return true
+}
 
-// NewHandler creates an http.Handler that serves the web dashboard API and SPA.
-// If db is provided, settings/agents APIs are enabled.
-// If cfg is nil, the server runs in setup mode (settings only).
-// If execFn is provided, new tasks trigger the automation pipeline.
 // securityHeadersMiddleware adds standard security headers to all responses.
 func securityHeadersMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -116,125 +190,6 @@ func corsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// rateLimiter implements a simple per-IP token bucket rate limiter.
-type rateLimiter struct {
-	mu       sync.Mutex
-	visitors map[string]*visitor
-	rate     int           // requests per window
-	window   time.Duration // window duration
-}
-
-type visitor struct {
-	tokens    int
-	lastReset time.Time
-}
-
-func newRateLimiter(rate int, window time.Duration) *rateLimiter {
-	rl := &rateLimiter{
-		visitors: make(map[string]*visitor),
-		rate:     rate,
-		window:   window,
-	}
-	// Cleanup stale entries every 5 minutes.
-	go func() {
-		for {
-			time.Sleep(5 * time.Minute)
-			rl.cleanup()
-		}
-	}()
-	return rl
-}
-
-func (rl *rateLimiter) cleanup() {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-	cutoff := time.Now().Add(-2 * rl.window)
-	for ip, v := range rl.visitors {
-		if v.lastReset.Before(cutoff) {
-			delete(rl.visitors, ip)
-		}
-	}
-}
-
-func (rl *rateLimiter) allow(ip string) bool {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-
-	v, exists := rl.visitors[ip]
-	now := time.Now()
-
-	if !exists {
-		rl.visitors[ip] = &visitor{tokens: rl.rate - 1, lastReset: now}
-		return true
-	}
-
-	if now.Sub(v.lastReset) >= rl.window {
-		v.tokens = rl.rate - 1
-		v.lastReset = now
-		return true
-	}
-
-	if v.tokens <= 0 {
-		return false
-	}
-	v.tokens--
-	return true
-}
-
-// rateLimitMiddleware rejects requests that exceed the rate limit.
-func rateLimitMiddleware(rl *rateLimiter) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ip := r.RemoteAddr
-			if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
-				ip = strings.SplitN(fwd, ",", 2)[0]
-			}
-			ip = strings.TrimSpace(ip)
-
-			if !rl.allow(ip) {
-				w.Header().Set("Retry-After", "60")
-				writeJSON(w, http.StatusTooManyRequests, map[string]string{"error": "rate limit exceeded"})
-				return
-			}
-			next.ServeHTTP(w, r)
-		})
-	}
-}
-
-// apiKeyAuthMiddleware checks for a valid API key if RIG_API_KEY env var is set.
-// If RIG_API_KEY is not set, authentication is skipped (open access).
-// API key can be passed via Authorization header (Bearer <key>) or X-API-Key header.
-func apiKeyAuthMiddleware(next http.Handler) http.Handler {
-	apiKey := os.Getenv("RIG_API_KEY")
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if apiKey == "" {
-			next.ServeHTTP(w, r)
-			return
-		}
-
-		// Check Authorization: Bearer <key>
-		auth := r.Header.Get("Authorization")
-		if strings.HasPrefix(auth, "Bearer ") && strings.TrimPrefix(auth, "Bearer ") == apiKey {
-			next.ServeHTTP(w, r)
-			return
-		}
-
-		// Check X-API-Key header
-		if r.Header.Get("X-API-Key") == apiKey {
-			next.ServeHTTP(w, r)
-			return
-		}
-
-		// Check query param for SSE/browser convenience
-		if r.URL.Query().Get("api_key") == apiKey {
-			next.ServeHTTP(w, r)
-			return
-		}
-
-		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
-	})
-}
-
 // sanitizeError strips sensitive information from error messages before returning to clients.
 // It removes paths containing tokens, API keys, passwords, and other credentials.
 func sanitizeError(errMsg string) string {
@@ -258,59 +213,121 @@ func sanitizeError(errMsg string) string {
 	return result
 }
 
-func NewHandler(statePath string, cfg *config.Config, db *storage.DB, execFn ...ExecuteFunc) http.Handler {
+// NewHandler creates an http.Handler that serves the web dashboard API and SPA.
+// If db is provided, settings/agents APIs are enabled.
+// If cfg is nil, the server runs in setup mode (settings only).
+// If an ExecuteFunc is provided, new tasks trigger the automation pipeline.
+// If a ResumeFunc is provided, approving or rejecting a proposal resumes the
+// waiting task immediately instead of on the next engine cycle.
+// If a ReplayFunc is provided, stored webhook deliveries can be replayed.
+// If a RerunFunc is provided, finished tasks can be redeployed and retested.
+// If a ConfigFunc is provided, new tasks and the config endpoints use the
+// config it returns rather than cfg.
+// If a ReadyFunc is provided, GET /readyz fails while it returns an error.
+// Tenants are served under /api/t/{tenant}/.
+func NewHandler(statePath string, cfg *config.Config, db *storage.DB, opts ...HandlerOption) http.Handler {
 	r := chi.NewRouter()
 
 	// Security headers on all responses
 	r.Use(securityHeadersMiddleware)
 	// CORS (controlled by RIG_CORS_ORIGINS env var, default: same-origin only)
 	r.Use(corsMiddleware)
-	// Rate limiting: 120 requests per minute per IP
-	r.Use(rateLimitMiddleware(newRateLimiter(120, time.Minute)))
-
 	configured := cfg != nil
+	var limits config.ServerLimitsConfig
+	if configured {
+		limits = cfg.Server.Limits
+	}
+	// Rate limiting per client IP (server.limits.requests_per_minute)
+	r.Use(httpserver.RateLimit(httpserver.NewRateLimiter(httpserver.Limit(limits.RequestsPerMinute, defaultRequestsPerMinute), time.Minute)))
 
-	var executeFn ExecuteFunc
-	if len(execFn) > 0 && execFn[0] != nil {
-		executeFn = execFn[0]
+	var callbacks handlerCallbacks
+	for _, opt := range opts {
+		if opt != nil {
+			opt.applyTo(&callbacks)
+		}
+	}
+
+	// Kubernetes probes; like the webhooks, they need no credentials.
+	r.Get("/healthz", handleHealthz)
+	r.Get("/readyz", handleReadyz(statePath, db, callbacks.ready))
+	executeFn := callbacks.execute
+	current := callbacks.config
+	if current == nil {
+		current = func() *config.Config { return cfg }
+	}
+	var resume *resumer
+	if callbacks.resume != nil {
+		resume = &resumer{fn: callbacks.resume}
+	}
+	var audit *auditor
+	if db != nil {
+		audit = &auditor{db: db}
+	}
+
+	// --- Dashboard login (server.auth) ---
+	var lg *login
+	if configured {
+		var err error
+		if lg, err = newLogin(cfg.Server.Auth, audit); err != nil {
+			// Validate rejects this; fail closed rather than serve unauthenticated.
+			slog.Error("web: dashboard login unavailable", "err", err)
+			r.Handle("/*", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "internal server error: login misconfigured", http.StatusInternalServerError)
+			}))
+			return r
+		}
+	}
+	if lg != nil {
+		r.Get("/auth/login", lg.handleLogin)
+		r.Get("/auth/callback", lg.handleCallback)
+		r.Post("/auth/logout", lg.handleLogout)
 	}
 
 	// --- API routes ---
+	root := r
 	r.Route("/api", func(r chi.Router) {
-		// API key auth on all API routes (if RIG_API_KEY is set)
-		r.Use(apiKeyAuthMiddleware)
-		chatopsHandler := chatops.NewHandler(statePath, executeFn)
+		// Auth and role checks on all API routes (if any key or login exists)
+		r.Use(apiKeyAuth(db, root, lg))
+		r.Use(callerQuota(limits))
+		r.Use(httpserver.MaxBody(httpserver.Limit(limits.APIMaxBodyBytes, defaultAPIMaxBodyBytes)))
+		r.Get("/openapi.json", handleOpenAPI(root))
+		r.Get("/auth/me", handleGetMe(lg))
+		var chatopsExecute chatops.ExecuteFunc
+		if executeFn != nil {
+			chatopsExecute = func(issue core.Issue) error { return executeFn(context.Background(), issue) }
+		}
+		chatopsHandler := chatops.NewHandler(statePath, chatopsExecute)
+		chatopsHandler.SetIssueHosts(func() core.IssueHosts { return core.NewIssueHosts(current()) })
+		if db != nil {
+			chatopsHandler.SetAuditFunc(db.RecordAudit)
+		}
 		r.Post("/chatops/slack", chatopsHandler.HandleSlack)
 		r.Post("/chatops/discord", chatopsHandler.HandleDiscord)
 
 		// Always available: settings, agents, status
 		if db != nil {
 			r.Get("/settings", handleGetSettings(db))
-			r.Post("/settings", handleSaveSettings(db))
+			r.Post("/settings", handleSaveSettings(db, audit))
 			r.Get("/agents/{repo}", handleGetAgents(db))
-			r.Post("/agents/{repo}", handleSaveAgents(db))
+			r.Post("/agents/{repo}", handleSaveAgents(db, audit))
 			r.Get("/agents", handleListAgents(db))
+			r.Get("/audit", handleGetAudit(db))
+			r.Get("/keys", handleListAPIKeys(db, ""))
+			r.Post("/keys", handleCreateAPIKey(db, audit, "", callbacks.tenants.names()))
+			r.Delete("/keys/{name}", handleDeleteAPIKey(db, audit, ""))
+			r.Get("/webhooks", handleListDeliveries(db))
+			r.Get("/webhooks/{id}", handleGetDelivery(db))
+			r.Post("/webhooks/{id}/replay", handleReplayDelivery(db, callbacks.replay, audit))
+			r.Delete("/ai-cache", handleClearAICache(db, audit))
 		}
-		r.Get("/status", handleGetStatus(configured))
+		r.Get("/status", handleGetStatus(configured, callbacks.reloadStatus))
 
 		// Task/proposal routes require config (full mode)
 		if configured {
-			r.Get("/tasks", handleGetTasks(statePath))
-			r.Get("/metrics/dora", handleGetDORAMetrics(statePath))
-			r.Post("/tasks", handleCreateTask(statePath, cfg, executeFn))
-			r.Post("/tasks/{id}/retry", handleRetryTask(statePath, executeFn))
-			r.Post("/tasks/{id}/stop", handleStopTask(statePath))
-			if db != nil {
-				r.Get("/tasks/{id}/logs", handleGetTaskLogs(db))
-			}
-			r.Get("/tasks/{id}", handleGetTask(statePath))
-			r.Get("/proposals", handleGetProposals(statePath))
-			r.Get("/proposals/{taskId}", handleGetTaskProposals(statePath))
-			r.Post("/approve/{taskId}", handleApprove(statePath, cfg))
-			r.Post("/reject/{taskId}", handleReject(statePath))
-			r.Get("/config", handleGetConfig(cfg))
-			r.Get("/projects", handleGetProjects(cfg))
-			r.Get("/events", handleSSE(statePath))
+			api := taskAPI{statePath: statePath, current: current, execute: executeFn, resume: resume, rerun: callbacks.rerun}
+			api.routes(r, db, audit)
+			r.Get("/tenants", handleListTenants(callbacks.tenants))
+			r.Mount("/t/{tenant}", newTenantRouter(api, callbacks.tenants, db, audit))
 		} else {
 			// Setup mode: return 503 for task routes
 			setupHandler := func(w http.ResponseWriter, r *http.Request) {
@@ -332,18 +349,83 @@ func NewHandler(statePath string, cfg *config.Config, db *storage.DB, execFn ...
 	// --- Static SPA files ---
 	staticSub, err := fs.Sub(staticFS, "static")
 	if err != nil {
-		log.Printf("web: CRITICAL: failed to create sub-filesystem: %v", err)
+		slog.Error("web: failed to create static sub-filesystem", "err", err)
 		r.Handle("/*", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "internal server error: static files unavailable", http.StatusInternalServerError)
 		}))
 		return r
 	}
-	fileServer := http.FileServer(http.FS(staticSub))
+	var fileServer http.Handler = http.FileServer(http.FS(staticSub))
+	if lg != nil {
+		fileServer = lg.requireSession(fileServer)
+	}
 	r.Handle("/*", fileServer)
 
 	return r
 }
 
+// taskAPI is what the task routes work on: the state and config of rig
+// serve, or of one of its tenants.
+type taskAPI struct {
+	statePath string
+	current   ConfigFunc
+	execute   ExecuteFunc
+	resume    *resumer
+	rerun     RerunFunc
+	// tenant is set for the routes of a tenant under /api/t/{tenant}/.
+	tenant *tenant.Tenant
+}
+
+// routes registers the task, proposal, report and workspace routes of a.
+func (a taskAPI) routes(r chi.Router, db *storage.DB, audit *auditor) {
+	statePath, current := a.statePath, a.current
+	admit := func(next http.Handler) http.Handler { return next }
+	logs := func(next http.Handler) http.Handler { return next }
+	if a.tenant != nil {
+		admit = a.admit
+		// Task logs share one table; only the tenant's own tasks are shown.
+		logs = requireTask(statePath)
+	}
+	r.Get("/tasks", handleGetTasks(statePath))
+	r.Get("/metrics/dora", handleGetDORAMetrics(statePath))
+	r.Get("/reports/tasks", handleGetTaskReport(statePath, current))
+	r.Route("/analytics", func(r chi.Router) {
+		analyticsRoutes(r, statePath)
+	})
+	r.With(admit).Post("/tasks", handleCreateTask(statePath, current, a.execute, audit))
+	r.With(admit).Post("/tasks/{id}/retry", handleRetryTask(statePath, a.execute, audit))
+	r.Post("/tasks/{id}/stop", handleStopTask(statePath, audit))
+	r.Post("/tasks/{id}/redeploy", handleRerunTask(statePath, core.RerunDeploy, a.rerun, audit))
+	r.Post("/tasks/{id}/retest", handleRerunTask(statePath, core.RerunTest, a.rerun, audit))
+	if db != nil {
+		r.With(logs).Get("/tasks/{id}/logs", handleGetTaskLogs(db))
+	}
+	r.Get("/tasks/{id}/summary", handleGetTaskSummary(statePath, core.NewSummaryCache(), func() (core.AIAdapter, error) {
+		return adapterai.New(current().AI)
+	}))
+	explain := &explainer{newAI: func() (core.AIAdapter, error) { return adapterai.New(current().AI) }, db: db}
+	r.Post("/tasks/{id}/explain", explain.handleExplainTask(statePath, audit))
+	r.Delete("/tasks/{id}/explain", explain.handleCancelExplain)
+	r.Get("/tasks/{id}/bundle", handleGetTaskBundle(statePath, current, db))
+	r.Get("/tasks/{id}/artifacts", handleListTaskArtifacts(statePath))
+	r.Get("/tasks/{id}/artifacts/{attempt}/{name}", handleGetTaskArtifact(statePath, current))
+	r.Get("/tasks/{id}", handleGetTask(statePath))
+	r.Get("/proposals", handleGetProposals(statePath))
+	r.Get("/proposals/{taskId}", handleGetTaskProposals(statePath))
+	r.Put("/proposals/{taskId}/plan", handleEditPlan(statePath, audit))
+	r.Get("/proposals/{id}/diff", handleProposalDiff(statePath))
+	r.Post("/approve/{taskId}", handleReview(statePath, a.resume, audit, true))
+	r.Post("/reject/{taskId}", handleReview(statePath, a.resume, audit, false))
+	r.Get("/config", handleGetConfig(current))
+	r.Get("/projects", handleGetProjects(current))
+	r.Get("/events", handleSSE(statePath))
+	r.Get("/workspaces", handleListWorkspaces(current))
+	r.Post("/workspaces/gc", handleCollectWorkspaces(statePath, current, audit))
+	r.Route("/editor", func(r chi.Router) {
+		editorRoutes(r, statePath, a.resume, audit)
+	})
+}
+
 func handleGetDORAMetrics(statePath string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		state, err := core.LoadState(statePath)
@@ -356,17 +438,67 @@ func handleGetDORAMetrics(statePath string) http.HandlerFunc {
 	}
 }
 
+// handleGetTasks lists the tasks the query parameters select, with the
+// number of all matching tasks in X-Total-Count.
 func handleGetTasks(statePath string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		query, err := parseTaskQuery(r.URL.Query(), time.Now())
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
 		state, err := core.LoadState(statePath)
 		if err != nil {
 			writeErrorJSON(w, http.StatusInternalServerError, err)
 			return
 		}
-		writeJSON(w, http.StatusOK, state.Tasks)
+		tasks, total := query.Apply(state.Tasks)
+		w.Header().Set("X-Total-Count", strconv.Itoa(total))
+		writeJSON(w, http.StatusOK, tasks)
 	}
 }
 
+// parseTaskQuery reads the filters of GET /api/tasks and /api/events.
+func parseTaskQuery(q url.Values, now time.Time) (core.TaskQuery, error) {
+	query := core.TaskQuery{
+		Repo:   strings.TrimSpace(q.Get("repo")),
+		Search: strings.TrimSpace(q.Get("q")),
+		Parent: strings.TrimSpace(q.Get("parent")),
+		Sort:   q.Get("sort"),
+	}
+	for _, v := range q["status"] {
+		for status := range strings.SplitSeq(v, ",") {
+			if status = strings.TrimSpace(status); status != "" {
+				query.Status = append(query.Status, core.TaskPhase(status))
+			}
+		}
+	}
+	if s := q.Get("since"); s != "" {
+		since, err := parseSince(s, now)
+		if err != nil {
+			return query, fmt.Errorf("since must be a duration or an RFC 3339 time")
+		}
+		query.Since = since
+	}
+	if s := q.Get("until"); s != "" {
+		until, err := parseSince(s, now)
+		if err != nil {
+			return query, fmt.Errorf("until must be a duration or an RFC 3339 time")
+		}
+		query.Until = until
+	}
+	for name, dst := range map[string]*int{"limit": &query.Limit, "offset": &query.Offset} {
+		if s := q.Get(name); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 0 {
+				return query, fmt.Errorf("%s must be a non-negative integer", name)
+			}
+			*dst = n
+		}
+	}
+	return query, query.Validate()
+}
+
 func handleGetTask(statePath string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id := chi.URLParam(r, "id")
@@ -388,6 +520,92 @@ func handleGetTask(statePath string) http.HandlerFunc {
 	}
 }
 
+// handleGetTaskBundle downloads the diagnostic bundle of a task: the one
+// the engine stored when the task failed, or else one built from the
+// current state and stored logs.
+func handleGetTaskBundle(statePath string, current ConfigFunc, db *storage.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		state, err := core.LoadState(statePath)
+		if err != nil {
+			writeErrorJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+		task := state.GetTaskByID(id)
+		if task == nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "task not found"})
+			return
+		}
+
+		if data, err := os.ReadFile(core.BundlePath(statePath, task.ID)); err == nil {
+			writeBundle(w, task.ID, data)
+			return
+		}
+
+		var logs []core.BundleLog
+		if db != nil {
+			entries, err := db.GetLogs(task.ID)
+			if err != nil {
+				writeErrorJSON(w, http.StatusInternalServerError, err)
+				return
+			}
+			for _, e := range entries {
+				logs = append(logs, core.BundleLog{Time: e.Timestamp, Level: e.Level, Message: e.Message})
+			}
+		}
+		var buf bytes.Buffer
+		if err := core.WriteBundle(&buf, current(), task, logs); err != nil {
+			writeErrorJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeBundle(w, task.ID, buf.Bytes())
+	}
+}
+
+func writeBundle(w http.ResponseWriter, taskID string, data []byte) {
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", taskID+".zip"))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(data)
+}
+
+// handleGetTaskSummary asks the configured AI for a short status summary of a
+// task. Summaries are cached per task version, so the AI is only called again
+// after the task changes.
+func handleGetTaskSummary(statePath string, cache *core.SummaryCache, newAI func() (core.AIAdapter, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		state, err := core.LoadState(statePath)
+		if err != nil {
+			writeErrorJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+		task := state.GetTaskByID(id)
+		if task == nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "task not found"})
+			return
+		}
+
+		ai, err := newAI()
+		if err != nil {
+			writeErrorJSON(w, http.StatusServiceUnavailable, err)
+			return
+		}
+		summary, err := cache.Summarize(r.Context(), ai, task)
+		if errors.Is(err, core.ErrSummaryUnsupported) {
+			writeErrorJSON(w, http.StatusNotImplemented, err)
+			return
+		}
+		if err != nil {
+			writeErrorJSON(w, http.StatusBadGateway, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, summary)
+	}
+}
+
 type createTaskRequest struct {
 	Project  string `json:"project"`
 	IssueNum string `json:"issue_num"`
@@ -395,12 +613,22 @@ type createTaskRequest struct {
 	IssueID  string `json:"issue_id"`
 	Title    string `json:"title"`
 	Body     string `json:"body"`
+	// Environment is the deploy environment, as a deploy:<name> issue
+	// label would pick it.
+	Environment string `json:"environment,omitempty"`
+	// IdempotencyKey makes retries of the request return the task the
+	// first one created. The Idempotency-Key header sets it too.
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
 }
 
+// maxIdempotencyKey bounds the length of an idempotency key.
+const maxIdempotencyKey = 255
+
 func mergedProjects(cfg *config.Config) []config.ProjectEntry {
 	projects := make([]config.ProjectEntry, 0, 1+len(cfg.Projects))
 	seen := make(map[string]struct{}, 1+len(cfg.Projects))
 
+	hosts := core.NewIssueHosts(cfg)
 	appendUnique := func(p config.ProjectEntry) {
 		repo := strings.TrimSpace(p.Repo)
 		if repo == "" {
@@ -412,6 +640,9 @@ func mergedProjects(cfg *config.Config) []config.ProjectEntry {
 		if p.Platform == "" {
 			p.Platform = "github"
 		}
+		if p.Host == "" {
+			p.Host = hosts.Host(p.Platform)
+		}
 		projects = append(projects, p)
 		seen[repo] = struct{}{}
 	}
@@ -430,171 +661,189 @@ func mergedProjects(cfg *config.Config) []config.ProjectEntry {
 	return projects
 }
 
-type issueURLParts struct {
-	owner  string
-	repo   string
-	number string
-}
-
-func parseIssueURL(url string) *issueURLParts {
-	// Parse: https://github.com/{owner}/{repo}/issues/{number}
-	// Also handle: http://github.com/{owner}/{repo}/issues/{number}
-	parts := issueURLParts{}
+func handleCreateTask(statePath string, current ConfigFunc, executeFn ExecuteFunc, audit *auditor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req createTaskRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+			return
+		}
+		if key := r.Header.Get("Idempotency-Key"); key != "" {
+			if req.IdempotencyKey != "" && req.IdempotencyKey != key {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Idempotency-Key header and idempotency_key differ"})
+				return
+			}
+			req.IdempotencyKey = key
+		}
+		task, replayed, err := createTask(taskContext(r), statePath, current(), executeFn, req)
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		if replayed {
+			w.Header().Set("Idempotent-Replayed", "true")
+			writeJSON(w, http.StatusOK, task)
+			return
+		}
+		audit.record(r, storage.AuditTaskCreated, task.ID, task.Issue.Repo+"#"+task.Issue.ID)
 
-	// Simple regex-free parsing
-	// Expected format: https://github.com/owner/repo/issues/123
-	if len(url) < 30 {
-		return nil
+		writeJSON(w, http.StatusCreated, task)
 	}
+}
 
-	// Find "github.com/"
-	idx := 0
-	for i := 0; i < len(url)-10; i++ {
-		if url[i:i+11] == "github.com/" {
-			idx = i + 11
-			break
-		}
-	}
-	if idx == 0 {
-		return nil
+// createTask records a task for the issue req names and starts it with
+// executeFn, if any, in the background with ctx. The REST and gRPC APIs
+// share it.
+// When a task was already created with req's idempotency key it returns
+// that task instead and reports it replayed.
+func createTask(ctx context.Context, statePath string, cfg *config.Config, executeFn ExecuteFunc, req createTaskRequest) (*core.Task, bool, error) {
+	req.Project = strings.TrimSpace(req.Project)
+	req.IssueNum = strings.TrimSpace(req.IssueNum)
+	req.IssueURL = strings.TrimSpace(req.IssueURL)
+	req.IssueID = strings.TrimSpace(req.IssueID)
+	req.Title = strings.TrimSpace(req.Title)
+	req.IdempotencyKey = strings.TrimSpace(req.IdempotencyKey)
+	if len(req.IdempotencyKey) > maxIdempotencyKey {
+		return nil, false, badRequest(fmt.Sprintf("idempotency key longer than %d bytes", maxIdempotencyKey))
 	}
 
-	// Extract owner/repo/issues/number
-	remaining := url[idx:]
-	segments := make([]string, 0)
-	current := ""
-	for i := 0; i < len(remaining); i++ {
-		if remaining[i] == '/' {
-			if current != "" {
-				segments = append(segments, current)
-				current = ""
-			}
-		} else if remaining[i] == '#' || remaining[i] == '?' {
-			if current != "" {
-				segments = append(segments, current)
+	// Parse issue_url if provided
+	var issue core.Issue
+	if req.Project != "" && req.IssueNum != "" {
+		projects := mergedProjects(cfg)
+		var project *config.ProjectEntry
+		for i := range projects {
+			if projects[i].Repo == req.Project {
+				project = &projects[i]
+				break
 			}
-			break
-		} else {
-			current += string(remaining[i])
 		}
+		if project == nil {
+			return nil, false, badRequest("project not found")
+		}
+		platform := project.Platform
+		if platform == "" {
+			platform = "github"
+		}
+		issue = core.Issue{
+			Platform: platform,
+			Repo:     project.Repo,
+			ID:       req.IssueNum,
+			Title:    req.Title,
+			URL:      core.NewIssueHosts(cfg).URL(platform, project.Host, project.Repo, req.IssueNum),
+		}
+		if issue.Title == "" {
+			issue.Title = "Issue #" + req.IssueNum
+		}
+	} else if req.IssueURL != "" {
+		parsed, err := core.NewIssueHosts(cfg).Parse(req.IssueURL)
+		if err != nil {
+			return nil, false, badRequest("invalid issue URL: " + err.Error())
+		}
+		issue = parsed
+		if req.Title != "" {
+			issue.Title = req.Title
+		}
+	} else if req.IssueID != "" {
+		issue = core.Issue{
+			Platform: "github",
+			ID:       req.IssueID,
+			Title:    req.Title,
+		}
+	} else {
+		return nil, false, badRequest("project+issue_num or issue_url or issue_id required")
 	}
-	if current != "" {
-		segments = append(segments, current)
-	}
+	issue.IdempotencyKey = req.IdempotencyKey
 
-	// Need at least: owner, repo, "issues", number
-	if len(segments) < 4 {
-		return nil
+	var env *config.EnvironmentConfig
+	if name := strings.TrimSpace(req.Environment); name != "" {
+		if env = cfg.FindEnvironment(name); env == nil {
+			return nil, false, badRequest("unknown environment " + name)
+		}
+		issue.Labels = []string{core.EnvironmentLabelPrefix + env.Name}
 	}
 
-	if segments[2] != "issues" {
+	// The lookup and the new task are one state update, so concurrent
+	// retries cannot both create a task.
+	var task core.Task
+	replayed := false
+	err := core.WithState(statePath, func(state *core.State) error {
+		if prior := state.GetTaskByIdempotencyKey(issue.IdempotencyKey); prior != nil {
+			if prior.Issue.Repo != issue.Repo || prior.Issue.ID != issue.ID {
+				return &apiError{status: http.StatusConflict, msg: "idempotency key was used for task " + prior.ID + " of another issue"}
+			}
+			task, replayed = *prior, true
+			return nil
+		}
+		created := state.CreateTask(issue)
+		if env != nil {
+			created.Environment = env.Name
+		}
+		task = *created
 		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if replayed {
+		return &task, true, nil
 	}
 
-	parts.owner = segments[0]
-	parts.repo = segments[1]
-	parts.number = segments[3]
-
-	return &parts
+	// Execute task in background with a detached context (outlives the request).
+	if executeFn != nil {
+		go func(taskID string, iss core.Issue) {
+			if err := executeFn(ctx, iss); err != nil {
+				slog.Error("web: execute task failed", logging.TaskKey, taskID, "err", sanitizeError(err.Error()))
+			}
+		}(task.ID, issue)
+	}
+	return &task, false, nil
 }
 
-func handleCreateTask(statePath string, cfg *config.Config, executeFn ExecuteFunc) http.HandlerFunc {
+func handleRetryTask(statePath string, executeFn ExecuteFunc, audit *auditor) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var req createTaskRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		id := chi.URLParam(r, "id")
+
+		state, err := core.LoadState(statePath)
+		if err != nil {
+			writeErrorJSON(w, http.StatusInternalServerError, err)
 			return
 		}
 
-		req.Project = strings.TrimSpace(req.Project)
-		req.IssueNum = strings.TrimSpace(req.IssueNum)
-		req.IssueURL = strings.TrimSpace(req.IssueURL)
-		req.IssueID = strings.TrimSpace(req.IssueID)
-		req.Title = strings.TrimSpace(req.Title)
-
-		// Parse issue_url if provided
-		var issue core.Issue
-		if req.Project != "" && req.IssueNum != "" {
-			projects := mergedProjects(cfg)
-			var project *config.ProjectEntry
-			for i := range projects {
-				if projects[i].Repo == req.Project {
-					project = &projects[i]
-					break
-				}
-			}
-			if project == nil {
-				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "project not found"})
-				return
-			}
-			platform := project.Platform
-			if platform == "" {
-				platform = "github"
-			}
-			issueURL := "https://github.com/" + project.Repo + "/issues/" + req.IssueNum
-			issue = core.Issue{
-				Platform: platform,
-				Repo:     project.Repo,
-				ID:       req.IssueNum,
-				Title:    req.Title,
-				URL:      issueURL,
-			}
-			if issue.Title == "" {
-				issue.Title = "Issue #" + req.IssueNum
-			}
-		} else if req.IssueURL != "" {
-			parts := parseIssueURL(req.IssueURL)
-			if parts == nil {
-				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid issue URL format"})
-				return
-			}
-			issue = core.Issue{
-				Platform: "github",
-				Repo:     parts.owner + "/" + parts.repo,
-				ID:       parts.number,
-				Title:    req.Title,
-				URL:      req.IssueURL,
-			}
-			if issue.Title == "" {
-				issue.Title = "Issue #" + parts.number
-			}
-		} else if req.IssueID != "" {
-			issue = core.Issue{
-				Platform: "github",
-				ID:       req.IssueID,
-				Title:    req.Title,
-			}
-		} else {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "project+issue_num or issue_url or issue_id required"})
+		task := state.GetTaskByID(id)
+		if task == nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "task not found"})
 			return
 		}
 
-		state, err := core.LoadState(statePath)
-		if err != nil {
-			writeErrorJSON(w, http.StatusInternalServerError, err)
+		if executeFn == nil {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "execution not available"})
 			return
 		}
 
-		task := state.CreateTask(issue)
-		if err := core.SaveState(state, statePath); err != nil {
-			writeErrorJSON(w, http.StatusInternalServerError, err)
+		if busy := taskBusy(statePath, state, task); busy != "" {
+			writeJSON(w, http.StatusConflict, map[string]string{"error": busy})
 			return
 		}
 
-		// Execute task in background with a detached context (outlives HTTP request).
-		if executeFn != nil {
-			go func(taskID string, iss core.Issue) {
-				if err := executeFn(iss); err != nil {
-					log.Printf("web: execute task %s failed: %v", taskID, sanitizeError(err.Error()))
-				}
-			}(task.ID, issue)
-		}
+		go func(ctx context.Context, taskID string, iss core.Issue) {
+			if err := executeFn(ctx, iss); err != nil {
+				slog.Error("web: retry task failed", logging.TaskKey, taskID, "err", sanitizeError(err.Error()))
+			}
+		}(taskContext(r), task.ID, task.Issue)
+		audit.record(r, storage.AuditTaskRetried, task.ID, "")
 
-		writeJSON(w, http.StatusCreated, task)
+		writeJSON(w, http.StatusOK, map[string]string{"status": "started", "task_id": task.ID})
 	}
 }
 
-func handleRetryTask(statePath string, executeFn ExecuteFunc) http.HandlerFunc {
+// handleRerunTask starts running step of a finished task again in the
+// background.
+func handleRerunTask(statePath, step string, rerunFn RerunFunc, audit *auditor) http.HandlerFunc {
+	action := storage.AuditTaskRedeployed
+	if step == core.RerunTest {
+		action = storage.AuditTaskRetested
+	}
 	return func(w http.ResponseWriter, r *http.Request) {
 		id := chi.URLParam(r, "id")
 
@@ -610,22 +859,39 @@ func handleRetryTask(statePath string, executeFn ExecuteFunc) http.HandlerFunc {
 			return
 		}
 
-		if executeFn == nil {
+		if rerunFn == nil {
 			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "execution not available"})
 			return
 		}
 
-		go func(taskID string, iss core.Issue) {
-			if err := executeFn(iss); err != nil {
-				log.Printf("web: retry task %s failed: %v", taskID, sanitizeError(err.Error()))
+		if busy := taskBusy(statePath, state, task); busy != "" {
+			writeJSON(w, http.StatusConflict, map[string]string{"error": busy})
+			return
+		}
+
+		go func(ctx context.Context, taskID string) {
+			if err := rerunFn(ctx, taskID, step); err != nil {
+				slog.Error("web: rerun task failed", logging.TaskKey, taskID, "step", step, "err", sanitizeError(err.Error()))
 			}
-		}(task.ID, task.Issue)
+		}(taskContext(r), task.ID)
+		audit.record(r, action, task.ID, "")
 
 		writeJSON(w, http.StatusOK, map[string]string{"status": "started", "task_id": task.ID})
 	}
 }
 
-func handleStopTask(statePath string) http.HandlerFunc {
+// taskContext returns the context of a task r starts: it does not end with
+// r, and skips cached AI answers (ai.cache) when r sends Cache-Control:
+// no-cache.
+func taskContext(r *http.Request) context.Context {
+	ctx := context.Background()
+	if strings.Contains(strings.ToLower(r.Header.Get("Cache-Control")), "no-cache") {
+		ctx = core.WithoutAICache(ctx)
+	}
+	return ctx
+}
+
+func handleStopTask(statePath string, audit *auditor) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id := chi.URLParam(r, "id")
 
@@ -651,6 +917,8 @@ func handleStopTask(statePath string) http.HandlerFunc {
 			return
 		}
 
+		audit.record(r, storage.AuditTaskStopped, task.ID, "")
+
 		writeJSON(w, http.StatusOK, map[string]string{"status": "stopped", "task_id": task.ID})
 	}
 }
@@ -679,16 +947,20 @@ func handleGetTaskLogs(db *storage.DB) http.HandlerFunc {
 	}
 }
 
-func handleGetProjects(cfg *config.Config) http.HandlerFunc {
-	projects := mergedProjects(cfg)
+func handleGetProjects(current ConfigFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, mergedProjects(current()))
+	}
+}
 
+func handleGetConfig(current ConfigFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		writeJSON(w, http.StatusOK, projects)
+		writeJSON(w, http.StatusOK, newConfigResponse(current()))
 	}
 }
 
-func handleGetConfig(cfg *config.Config) http.HandlerFunc {
-	resp := configResponse{
+func newConfigResponse(cfg *config.Config) configResponse {
+	return configResponse{
 		Project: projectInfo{
 			Name:        cfg.Project.Name,
 			Language:    cfg.Project.Language,
@@ -711,10 +983,7 @@ func handleGetConfig(cfg *config.Config) http.HandlerFunc {
 			Steps:    cfg.Workflow.Steps,
 			Triggers: cfg.Workflow.Trigger,
 		},
-	}
-
-	return func(w http.ResponseWriter, r *http.Request) {
-		writeJSON(w, http.StatusOK, resp)
+		Profile: cfg.Profile,
 	}
 }
 
@@ -777,87 +1046,188 @@ func handleGetTaskProposals(statePath string) http.HandlerFunc {
 	}
 }
 
-func handleApprove(statePath string, _ *config.Config) http.HandlerFunc {
+// maxDiffContext bounds the context lines a proposal diff may ask for.
+const maxDiffContext = 1000
+
+// handleProposalDiff returns a proposal, whatever its status, as a unified
+// diff with ?context= unchanged lines around each hunk (default 3).
+// ?task= picks the task when proposals of several tasks share the ID.
+func handleProposalDiff(statePath string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		taskID := chi.URLParam(r, "taskId")
+		id := chi.URLParam(r, "id")
+		context := 3
+		if v := r.URL.Query().Get("context"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 || n > maxDiffContext {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("context must be a number from 0 to %d", maxDiffContext)})
+				return
+			}
+			context = n
+		}
 
 		state, err := core.LoadState(statePath)
 		if err != nil {
 			writeErrorJSON(w, http.StatusInternalServerError, err)
 			return
 		}
-
-		task := state.GetTaskByID(taskID)
-		if task == nil {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "task not found"})
-			return
+		var proposal *core.Proposal
+		if taskID := r.URL.Query().Get("task"); taskID != "" {
+			if task := state.GetTaskByID(taskID); task != nil {
+				proposal = task.GetProposal(id)
+			}
+		} else {
+			_, proposal = state.FindProposal(id)
 		}
-
-		proposal := task.GetPendingProposal()
 		if proposal == nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "no pending proposal"})
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "proposal not found"})
 			return
 		}
+		w.Header().Set("Content-Type", "text/x-diff; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(core.ProposalDiffContext(proposal, context)))
+	}
+}
 
-		now := time.Now().UTC()
-		proposal.Status = core.ProposalApproved
-		proposal.ReviewedAt = &now
+// handleEditPlan replaces the plan text of a pending plan review
+// (workflow.approval.after_planning); approving it then codes the edit.
+func handleEditPlan(statePath string, audit *auditor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		taskID := chi.URLParam(r, "taskId")
+		var req editPlanRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+			return
+		}
+		if strings.TrimSpace(req.Plan) == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "plan is required"})
+			return
+		}
 
-		if err := core.SaveState(state, statePath); err != nil {
+		err := core.EditPlan(statePath, taskID, req.Plan)
+		switch {
+		case errors.Is(err, core.ErrNoPlanReview):
+			writeErrorJSON(w, http.StatusConflict, err)
+			return
+		case err != nil && strings.HasPrefix(err.Error(), "task not found"):
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "task not found"})
+			return
+		case err != nil:
 			writeErrorJSON(w, http.StatusInternalServerError, err)
 			return
 		}
+		audit.record(r, storage.AuditPlanEdited, taskID, "")
 
-		writeJSON(w, http.StatusOK, map[string]string{
-			"status":  "approved",
-			"message": "Proposal approved. Task will resume on next engine cycle.",
-		})
+		writeJSON(w, http.StatusOK, map[string]string{"status": "edited", "task_id": taskID, "message": "Plan updated. Approve it to start coding."})
 	}
 }
 
-func handleReject(statePath string) http.HandlerFunc {
+// handleReview approves or rejects the pending proposal of a task.
+func handleReview(statePath string, resume *resumer, audit *auditor, approved bool) http.HandlerFunc {
+	action := storage.AuditProposalApproved
+	if !approved {
+		action = storage.AuditProposalRejected
+	}
 	return func(w http.ResponseWriter, r *http.Request) {
-		taskID := chi.URLParam(r, "taskId")
-
-		state, err := core.LoadState(statePath)
+		result, err := reviewProposal(statePath, resume, chi.URLParam(r, "taskId"), approved)
 		if err != nil {
-			writeErrorJSON(w, http.StatusInternalServerError, err)
+			writeAPIError(w, err)
 			return
 		}
+		audit.record(r, action, result.taskID, result.proposalID)
 
-		task := state.GetTaskByID(taskID)
-		if task == nil {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "task not found"})
-			return
-		}
+		writeJSON(w, result.httpStatus, map[string]string{"status": result.Status, "message": result.Message})
+	}
+}
 
-		proposal := task.GetPendingProposal()
-		if proposal == nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "no pending proposal"})
-			return
+// reviewResult is the outcome of approving or rejecting a proposal.
+type reviewResult struct {
+	Status  string
+	Message string
+
+	httpStatus int
+	taskID     string
+	proposalID string
+}
+
+// reviewProposal approves or rejects the pending proposal of a task. A task
+// awaiting approval is handed to resume, which marks the proposal and goes
+// on; otherwise the proposal is marked here, a rejection fails the task, and
+// the engine picks it up on its next cycle.
+func reviewProposal(statePath string, resume *resumer, taskID string, approved bool) (*reviewResult, error) {
+	state, err := core.LoadState(statePath)
+	if err != nil {
+		return nil, err
+	}
+
+	task := state.GetTaskByID(taskID)
+	if task == nil {
+		return nil, &apiError{status: http.StatusNotFound, msg: "task not found"}
+	}
+
+	proposal := task.GetPendingProposal()
+	if proposal == nil {
+		return nil, badRequest("no pending proposal")
+	}
+	result := &reviewResult{httpStatus: http.StatusOK, taskID: task.ID, proposalID: proposal.ID}
+
+	// The engine marks the proposal and applies its changes itself.
+	if resume != nil && task.Status == core.PhaseAwaitingApproval {
+		if core.ExecutionLocked(statePath, task.Issue) {
+			return nil, &apiError{status: http.StatusConflict, msg: core.ErrTaskBusy.Error() + ": task is being executed"}
+		}
+		if !resume.start(task.ID, approved) {
+			return nil, &apiError{status: http.StatusConflict, msg: "task is already resuming"}
+		}
+		result.httpStatus = http.StatusAccepted
+		result.Status, result.Message = "approved", "Proposal approved. Task is resuming."
+		if !approved {
+			result.Status, result.Message = "rejected", "Proposal rejected. Task is being marked as failed."
 		}
+		return result, nil
+	}
 
-		now := time.Now().UTC()
+	now := time.Now().UTC()
+	proposal.ReviewedAt = &now
+	if approved {
+		proposal.Status = core.ProposalApproved
+		result.Status, result.Message = "approved", "Proposal approved. Task will resume on next engine cycle."
+	} else {
 		proposal.Status = core.ProposalRejected
-		proposal.ReviewedAt = &now
-
 		if err := core.Transition(task, core.PhaseFailed); err != nil {
-			writeErrorJSON(w, http.StatusBadRequest, err)
-			return
+			return nil, badRequest(sanitizeError(err.Error()))
 		}
+		result.Status, result.Message = "rejected", "Proposal rejected. Task marked as failed."
+	}
 
-		if err := core.SaveState(state, statePath); err != nil {
-			writeErrorJSON(w, http.StatusInternalServerError, err)
-			return
-		}
+	if err := core.SaveState(state, statePath); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
 
-		writeJSON(w, http.StatusOK, map[string]string{
-			"status":  "rejected",
-			"message": "Proposal rejected. Task marked as failed.",
-		})
+// taskBusy explains why a new run of task must not start now, or returns ""
+// if it may. A task is busy while an executor holds its issue's lock or
+// another task for the same issue is still in flight or awaiting approval.
+func taskBusy(statePath string, state *core.State, task *core.Task) string {
+	if core.ExecutionLocked(statePath, task.Issue) {
+		return fmt.Sprintf("%v: issue #%s is being executed", core.ErrTaskBusy, task.Issue.ID)
+	}
+	for _, t := range state.Tasks {
+		if t.Issue.ID != task.Issue.ID || t.Issue.Repo != task.Issue.Repo {
+			continue
+		}
+		switch t.Status {
+		case core.PhaseCompleted, core.PhaseFailed, core.PhaseRollback, core.PhaseDryRun:
+		default:
+			return fmt.Sprintf("%v: task %s is %s", core.ErrTaskBusy, t.ID, t.Status)
+		}
 	}
+	return ""
 }
 
+// handleSSE streams the tasks GET /api/tasks would return for the same
+// query parameters as "tasks" events, and the number of tasks in each
+// status as "counts" events, whenever they change.
 func handleSSE(statePath string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		flusher, ok := w.(http.Flusher)
@@ -865,6 +1235,11 @@ func handleSSE(statePath string) http.HandlerFunc {
 			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
 			return
 		}
+		query, err := parseTaskQuery(r.URL.Query(), time.Now())
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
 
 		w.Header().Set("Content-Type", "text/event-stream")
 		w.Header().Set("Cache-Control", "no-cache")
@@ -874,15 +1249,18 @@ func handleSSE(statePath string) http.HandlerFunc {
 		// Send initial state immediately.
 		state, err := core.LoadState(statePath)
 		if err != nil {
-			log.Printf("web: SSE initial load error: %v", err)
+			slog.Warn("web: SSE initial load", "err", err)
 			return
 		}
-		sendSSEEvent(w, flusher, "tasks", state.Tasks)
+		tasks, _ := query.Apply(state.Tasks)
+		sendSSEEvent(w, flusher, "tasks", tasks)
+		sendSSEEvent(w, flusher, "counts", core.CountByStatus(state.Tasks))
 
 		ticker := time.NewTicker(2 * time.Second)
 		defer ticker.Stop()
 
-		prevJSON := marshalTasks(state.Tasks)
+		prevJSON := marshalTasks(tasks)
+		prevCounts := marshalTasks(core.CountByStatus(state.Tasks))
 
 		for {
 			select {
@@ -891,23 +1269,28 @@ func handleSSE(statePath string) http.HandlerFunc {
 			case <-ticker.C:
 				state, err := core.LoadState(statePath)
 				if err != nil {
-					log.Printf("web: SSE poll error: %v", err)
+					slog.Warn("web: SSE poll", "err", err)
 					continue
 				}
-				curJSON := marshalTasks(state.Tasks)
-				if curJSON != prevJSON {
-					sendSSEEvent(w, flusher, "tasks", state.Tasks)
+				tasks, _ := query.Apply(state.Tasks)
+				if curJSON := marshalTasks(tasks); curJSON != prevJSON {
+					sendSSEEvent(w, flusher, "tasks", tasks)
 					prevJSON = curJSON
 				}
+				counts := core.CountByStatus(state.Tasks)
+				if curCounts := marshalTasks(counts); curCounts != prevCounts {
+					sendSSEEvent(w, flusher, "counts", counts)
+					prevCounts = curCounts
+				}
 			}
 		}
 	}
 }
 
-func sendSSEEvent(w http.ResponseWriter, flusher http.Flusher, event string, data []core.Task) {
+func sendSSEEvent(w http.ResponseWriter, flusher http.Flusher, event string, data any) {
 	payload, err := json.Marshal(data)
 	if err != nil {
-		log.Printf("web: SSE marshal error: %v", err)
+		slog.Error("web: SSE marshal", "err", err)
 		return
 	}
 	// SSE clients may disconnect between frames; write errors are expected and safely ignored.
@@ -917,10 +1300,12 @@ func sendSSEEvent(w http.ResponseWriter, flusher http.Flusher, event string, dat
 	flusher.Flush()
 }
 
-func marshalTasks(tasks []core.Task) string {
-	data, err := json.Marshal(tasks)
+// marshalTasks encodes an SSE payload for comparing it with the last one
+// sent.
+func marshalTasks(v any) string {
+	data, err := json.Marshal(v)
 	if err != nil {
-		log.Printf("web: tasks marshal error: %v", err)
+		slog.Error("web: tasks marshal", "err", err)
 		return ""
 	}
 	return string(data)
@@ -930,7 +1315,7 @@ func writeJSON(w http.ResponseWriter, status int, v interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	if err := json.NewEncoder(w).Encode(v); err != nil {
-		log.Printf("web: JSON encode error: %v", err)
+		slog.Error("web: JSON encode", "err", err)
 	}
 }
 
@@ -939,24 +1324,62 @@ func writeErrorJSON(w http.ResponseWriter, status int, err error) {
 	writeJSON(w, status, map[string]string{"error": sanitizeError(err.Error())})
 }
 
+// apiError is a request the API refuses, with the HTTP status to answer.
+type apiError struct {
+	status int
+	msg    string
+}
+
+func (e *apiError) Error() string { return e.msg }
+
+func badRequest(msg string) *apiError {
+	return &apiError{status: http.StatusBadRequest, msg: msg}
+}
+
+// writeAPIError answers an *apiError with its status and anything else as
+// a sanitized 500.
+func writeAPIError(w http.ResponseWriter, err error) {
+	var ae *apiError
+	if errors.As(err, &ae) {
+		writeJSON(w, ae.status, map[string]string{"error": ae.msg})
+		return
+	}
+	writeErrorJSON(w, http.StatusInternalServerError, err)
+}
+
 // --- Settings API ---
 
+// sensitiveFields are the masked fields of each settings section: a key,
+// or list[].key for a key of every item in a list.
 var sensitiveFields = map[string][]string{
 	"source": {"token"},
-	"ai":     {"api_key"},
+	"ai":     {"api_key", "fallback[].api_key", "index.api_key"},
 	"server": {"secret"},
 }
 
-func handleGetStatus(configured bool) http.HandlerFunc {
+func handleGetStatus(configured bool, reloadStatus ReloadStatusFunc) http.HandlerFunc {
 	mode := "full"
 	if !configured {
 		mode = "setup"
 	}
 	return func(w http.ResponseWriter, r *http.Request) {
-		writeJSON(w, http.StatusOK, map[string]interface{}{
+		resp := map[string]interface{}{
 			"configured": configured,
 			"mode":       mode,
-		})
+		}
+		if rl, ok := adaptergit.LastRateLimit(); ok {
+			resp["github_rate_limit"] = map[string]interface{}{
+				"limit":      rl.Limit,
+				"remaining":  rl.Remaining,
+				"reset":      rl.Reset,
+				"headroom":   rl.Headroom(),
+				"updated_at": rl.UpdatedAt,
+			}
+		}
+		if reloadStatus != nil {
+			resp["config_reload"] = reloadStatus()
+		}
+		writeJSON(w, http.StatusOK, resp)
 	}
 }
 
@@ -991,7 +1414,8 @@ type saveSettingsRequest struct {
 	Data    json.RawMessage `json:"data"`
 }
 
-func handleSaveSettings(db *storage.DB) http.HandlerFunc {
+// handleSaveSettings audits the saved section names, never their values.
+func handleSaveSettings(db *storage.DB, audit *auditor) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		body, err := io.ReadAll(r.Body)
 		if err != nil {
@@ -1016,6 +1440,7 @@ func handleSaveSettings(db *storage.DB) http.HandlerFunc {
 				writeErrorJSON(w, http.StatusInternalServerError, err)
 				return
 			}
+			audit.record(r, storage.AuditSettingsChanged, req.Section, "")
 		} else {
 			// Bulk save: data is a map of sections.
 			var sections map[string]json.RawMessage
@@ -1033,6 +1458,7 @@ func handleSaveSettings(db *storage.DB) http.HandlerFunc {
 					writeErrorJSON(w, http.StatusInternalServerError, err)
 					return
 				}
+				audit.record(r, storage.AuditSettingsChanged, section, "")
 			}
 		}
 
@@ -1054,10 +1480,10 @@ func mergeWithExisting(db *storage.DB, section string, incoming json.RawMessage)
 
 	needsMerge := false
 	for _, field := range fields {
-		if v, ok := incomingMap[field]; ok {
-			if s, ok := v.(string); ok && s == "***" {
+		holders, key := fieldHolders(incomingMap, field)
+		for _, h := range holders {
+			if s, ok := h[key].(string); ok && s == "***" {
 				needsMerge = true
-				break
 			}
 		}
 	}
@@ -1077,10 +1503,12 @@ func mergeWithExisting(db *storage.DB, section string, incoming json.RawMessage)
 	}
 
 	for _, field := range fields {
-		if v, ok := incomingMap[field]; ok {
-			if s, ok := v.(string); ok && s == "***" {
-				if orig, ok := existingMap[field]; ok {
-					incomingMap[field] = orig
+		holders, key := fieldHolders(incomingMap, field)
+		existingHolders, _ := fieldHolders(existingMap, field)
+		for i, h := range holders {
+			if s, ok := h[key].(string); ok && s == "***" && i < len(existingHolders) {
+				if orig, ok := existingHolders[i][key]; ok {
+					h[key] = orig
 				}
 			}
 		}
@@ -1089,15 +1517,39 @@ func mergeWithExisting(db *storage.DB, section string, incoming json.RawMessage)
 	return json.Marshal(incomingMap)
 }
 
+// fieldHolders returns the maps that hold field in m, with the key to look
+// up in them: m itself, the object for object.key, or the items of the
+// list for list[].key. Items that are not maps are returned as nil maps to
+// keep list positions.
+func fieldHolders(m map[string]interface{}, field string) ([]map[string]interface{}, string) {
+	list, key, ok := strings.Cut(field, "[].")
+	if !ok {
+		if obj, key, ok := strings.Cut(field, "."); ok {
+			sub, _ := m[obj].(map[string]interface{})
+			return []map[string]interface{}{sub}, key
+		}
+		return []map[string]interface{}{m}, field
+	}
+	items, _ := m[list].([]interface{})
+	holders := make([]map[string]interface{}, len(items))
+	for i, item := range items {
+		holders[i], _ = item.(map[string]interface{})
+	}
+	return holders, key
+}
+
 func maskFields(section string, m map[string]interface{}) {
 	fields, ok := sensitiveFields[section]
 	if !ok {
 		return
 	}
 	for _, field := range fields {
-		if v, ok := m[field]; ok {
-			if s, ok := v.(string); ok && s != "" {
-				m[field] = "***"
+		holders, key := fieldHolders(m, field)
+		for _, h := range holders {
+			// References such as ${secret:vault:...} are not secrets
+			// themselves and show where the value comes from.
+			if s, ok := h[key].(string); ok && s != "" && !config.IsReference(s) {
+				h[key] = "***"
 			}
 		}
 	}
@@ -1105,9 +1557,19 @@ func maskFields(section string, m map[string]interface{}) {
 
 // --- Agents API ---
 
+// agentsRepo returns the {repo} of an agents route: owner/name, which the
+// dashboard sends escaped as one path segment.
+func agentsRepo(r *http.Request) string {
+	repo := chi.URLParam(r, "repo")
+	if unescaped, err := url.PathUnescape(repo); err == nil {
+		return unescaped
+	}
+	return repo
+}
+
 func handleGetAgents(db *storage.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		repo := chi.URLParam(r, "repo")
+		repo := agentsRepo(r)
 		content, err := db.GetAgents(repo)
 		if err != nil {
 			writeErrorJSON(w, http.StatusInternalServerError, err)
@@ -1117,9 +1579,9 @@ func handleGetAgents(db *storage.DB) http.HandlerFunc {
 	}
 }
 
-func handleSaveAgents(db *storage.DB) http.HandlerFunc {
+func handleSaveAgents(db *storage.DB, audit *auditor) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		repo := chi.URLParam(r, "repo")
+		repo := agentsRepo(r)
 
 		var req struct {
 			Content string `json:"content"`
@@ -1133,6 +1595,7 @@ func handleSaveAgents(db *storage.DB) http.HandlerFunc {
 			writeErrorJSON(w, http.StatusInternalServerError, err)
 			return
 		}
+		audit.record(r, storage.AuditAgentsChanged, repo, "")
 
 		writeJSON(w, http.StatusOK, map[string]string{"status": "saved"})
 	}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -53,3 +54,81 @@ func TestGetDORAMetrics(t *testing.T) {
 		t.Fatal("expected change_failure_rate field")
 	}
 }
+
+func TestGetTaskReport(t *testing.T) {
+	now := time.Now().UTC()
+	state := &core.State{
+		Version: "1.0",
+		Tasks: []core.Task{
+			{ID: "task-1", Status: core.PhaseCompleted, Issue: core.Issue{Repo: "acme/app"}, CreatedAt: now.Add(-time.Hour)},
+			{ID: "task-2", Status: core.PhaseFailed, Issue: core.Issue{Repo: "acme/app"}, CreatedAt: now.Add(-2 * time.Hour)},
+		},
+	}
+	handler := NewHandler(writeStateFile(t, state), testConfig(), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/reports/tasks", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var payload struct {
+		Totals struct {
+			Tasks       int     `json:"tasks"`
+			SuccessRate float64 `json:"success_rate"`
+		} `json:"totals"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&payload); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if payload.Totals.Tasks != 2 || payload.Totals.SuccessRate != 50 {
+		t.Fatalf("totals = %+v", payload.Totals)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/reports/tasks?format=csv", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/csv" {
+		t.Fatalf("csv: got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/reports/tasks?from=yesterday", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("bad from: expected 400, got %d", rec.Code)
+	}
+}
+
+func TestGetAnalytics(t *testing.T) {
+	now := time.Now().UTC()
+	state := &core.State{
+		Version: "1.0",
+		Tasks: []core.Task{{
+			ID: "task-1", Status: core.PhaseFailed, CreatedAt: now.Add(-time.Hour),
+			Attempts: []core.Attempt{{FailReason: core.ReasonTest}},
+		}},
+	}
+	handler := NewHandler(writeStateFile(t, state), testConfig(), nil)
+
+	for path, want := range map[string]string{
+		"/api/analytics/tasks-per-day":   `"created":1`,
+		"/api/analytics/failure-reasons": `"reason":"test_error"`,
+		"/api/analytics/phase-durations": `[]`,
+		"/api/analytics/retries":         `{"retries":0,"tasks":1}`,
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("%s: got %d %s, want %s", path, rec.Code, rec.Body.String(), want)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/analytics/retries?to=soon", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("bad to: expected 400, got %d", rec.Code)
+	}
+}
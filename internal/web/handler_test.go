@@ -1,7 +1,11 @@
 package web
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -10,8 +14,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/go-chi/chi/v5"
+	adapterartifact "github.com/rigdev/rig/internal/adapter/artifact"
+	adaptergit "github.com/rigdev/rig/internal/adapter/git"
 	"github.com/rigdev/rig/internal/config"
 	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/storage"
 )
 
 // testState returns a State with two tasks for testing.
@@ -144,6 +152,37 @@ func TestGetTasks(t *testing.T) {
 	}
 }
 
+func TestGetTasksQuery(t *testing.T) {
+	statePath := writeStateFile(t, testState())
+	handler := NewHandler(statePath, testConfig(), nil)
+
+	get := func(query string) (*httptest.ResponseRecorder, []core.Task) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/tasks?"+query, nil))
+		var tasks []core.Task
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&tasks); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+		}
+		return rec, tasks
+	}
+
+	rec, tasks := get("status=coding,failed&q=dark")
+	if len(tasks) != 1 || tasks[0].ID != "task-002" || rec.Header().Get("X-Total-Count") != "1" {
+		t.Fatalf("filtered tasks = %v, total %q", tasks, rec.Header().Get("X-Total-Count"))
+	}
+	rec, tasks = get("sort=-id&limit=1")
+	if len(tasks) != 1 || tasks[0].ID != "task-002" || rec.Header().Get("X-Total-Count") != "2" {
+		t.Fatalf("first page = %v, total %q", tasks, rec.Header().Get("X-Total-Count"))
+	}
+	for _, bad := range []string{"sort=title", "limit=-1", "since=yesterday"} {
+		if rec, _ := get(bad); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", bad, rec.Code)
+		}
+	}
+}
+
 func TestGetTaskByID(t *testing.T) {
 	statePath := writeStateFile(t, testState())
 	handler := NewHandler(statePath, testConfig(), nil)
@@ -184,6 +223,58 @@ func TestGetTaskNotFound(t *testing.T) {
 	}
 }
 
+func TestGetTaskBundle(t *testing.T) {
+	statePath := writeStateFile(t, testState())
+	cfg := testConfig()
+	cfg.Source.Token = "ghp_secret"
+	handler := NewHandler(statePath, cfg, nil)
+
+	// Without a stored bundle one is built from the current state.
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/tasks/task-001/bundle", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/zip" {
+		t.Errorf("expected application/zip, got %q", ct)
+	}
+	body := rec.Body.Bytes()
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("response is not a zip: %v", err)
+	}
+	names := map[string]bool{}
+	for _, f := range zr.File {
+		names[f.Name] = true
+	}
+	if !names["config.yaml"] || !names["task.json"] || !names["timeline.txt"] {
+		t.Errorf("unexpected bundle files %v", names)
+	}
+	if bytes.Contains(body, []byte("ghp_secret")) {
+		t.Error("bundle leaks the GitHub token")
+	}
+
+	// A bundle stored by the engine is served as-is.
+	stored := core.BundlePath(statePath, "task-001")
+	if err := os.MkdirAll(filepath.Dir(stored), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(stored, []byte("stored"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/tasks/task-001/bundle", nil))
+	if rec.Body.String() != "stored" {
+		t.Errorf("expected the stored bundle, got %q", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/tasks/nonexistent/bundle", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+}
+
 func TestGetConfig(t *testing.T) {
 	statePath := writeStateFile(t, testState())
 	handler := NewHandler(statePath, testConfig(), nil)
@@ -212,6 +303,48 @@ func TestGetConfig(t *testing.T) {
 	}
 }
 
+func TestConfigFuncAndReloadStatus(t *testing.T) {
+	statePath := writeStateFile(t, testState())
+	current := testConfig()
+	reload := config.ReloadStatus{Path: "rig.yaml", Reloads: 1, Error: "config: ai.provider is invalid"}
+	handler := NewHandler(statePath, testConfig(), nil,
+		ConfigFunc(func() *config.Config { return current }),
+		ReloadStatusFunc(func() config.ReloadStatus { return reload }))
+
+	get := func(path string, v any) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", path, rec.Code)
+		}
+		if err := json.NewDecoder(rec.Body).Decode(v); err != nil {
+			t.Fatalf("%s: decode response: %v", path, err)
+		}
+	}
+
+	reloaded := *current
+	reloaded.Project.Name = "renamed-app"
+	reloaded.Projects = []config.ProjectEntry{{Name: "added", Platform: "github", Repo: "acme/added"}}
+	current = &reloaded
+
+	var cfgResp configResponse
+	get("/api/config", &cfgResp)
+	if cfgResp.Project.Name != "renamed-app" {
+		t.Errorf("/api/config should serve the reloaded config, got %q", cfgResp.Project.Name)
+	}
+	var projects []config.ProjectEntry
+	get("/api/projects", &projects)
+	if len(projects) != 2 || projects[1].Repo != "acme/added" {
+		t.Errorf("/api/projects should serve the reloaded projects, got %+v", projects)
+	}
+	var status statusResponse
+	get("/api/status", &status)
+	if status.ConfigReload == nil || status.ConfigReload.Error != reload.Error || status.ConfigReload.Reloads != 1 {
+		t.Errorf("unexpected config_reload %+v", status.ConfigReload)
+	}
+}
+
 func TestGetProjectsMergedAndDeduped(t *testing.T) {
 	statePath := writeStateFile(t, testState())
 	cfg := testConfig()
@@ -279,6 +412,128 @@ func TestCreateTaskWithProjectAndIssueNum(t *testing.T) {
 	}
 }
 
+func TestCreateTaskWithIssueURL(t *testing.T) {
+	statePath := writeStateFile(t, &core.State{Version: "1.0", Tasks: []core.Task{}})
+	cfg := testConfig()
+	cfg.Projects = []config.ProjectEntry{{Platform: "gitea", Repo: "ops/infra", Host: "git.example.com"}}
+	handler := NewHandler(statePath, cfg, nil)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/tasks", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := post(`{"issue_url":"https://gitlab.com/acme/backend/api/-/issues/7"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	var task core.Task
+	if err := json.NewDecoder(rec.Body).Decode(&task); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if task.Issue.Platform != "gitlab" || task.Issue.Repo != "acme/backend/api" || task.Issue.ID != "7" {
+		t.Fatalf("unexpected issue %+v", task.Issue)
+	}
+
+	rec = post(`{"project":"ops/infra","issue_num":"5"}`)
+	if err := json.NewDecoder(rec.Body).Decode(&task); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if task.Issue.Platform != "gitea" || task.Issue.URL != "https://git.example.com/ops/infra/issues/5" {
+		t.Fatalf("unexpected issue %+v", task.Issue)
+	}
+
+	if rec := post(`{"issue_url":"https://example.org/acme/api/issues/1"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown host, got %d", rec.Code)
+	}
+}
+
+func TestCreateTaskWithEnvironment(t *testing.T) {
+	statePath := writeStateFile(t, &core.State{Version: "1.0", Tasks: []core.Task{}})
+	cfg := testConfig()
+	cfg.Environments = []config.EnvironmentConfig{{Name: "staging", Default: true}, {Name: "prod"}}
+	handler := NewHandler(statePath, cfg, nil)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/tasks", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := post(`{"project":"acme/app","issue_num":"7","environment":"Prod"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var task core.Task
+	if err := json.NewDecoder(rec.Body).Decode(&task); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if task.Environment != "prod" || len(task.Issue.Labels) != 1 || task.Issue.Labels[0] != "deploy:prod" {
+		t.Fatalf("environment = %q, labels = %v; want prod and deploy:prod", task.Environment, task.Issue.Labels)
+	}
+
+	if rec := post(`{"project":"acme/app","issue_num":"8","environment":"qa"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown environment, got %d", rec.Code)
+	}
+}
+
+func TestCreateTaskIdempotencyKey(t *testing.T) {
+	statePath := writeStateFile(t, &core.State{Version: "1.0", Tasks: []core.Task{}})
+	handler := NewHandler(statePath, testConfig(), nil)
+
+	post := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/tasks", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := post("req-1", `{"project":"acme/app","issue_num":"7"}`)
+	if rec.Code != http.StatusCreated || rec.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("expected a new task, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var first core.Task
+	json.NewDecoder(rec.Body).Decode(&first)
+	if first.Issue.IdempotencyKey != "req-1" {
+		t.Fatalf("idempotency key = %q, want req-1", first.Issue.IdempotencyKey)
+	}
+
+	// The key may come in the body as well.
+	rec = post("", `{"project":"acme/app","issue_num":"7","idempotency_key":"req-1"}`)
+	if rec.Code != http.StatusOK || rec.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("expected a replay, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var replay core.Task
+	json.NewDecoder(rec.Body).Decode(&replay)
+	if replay.ID != first.ID {
+		t.Errorf("replay returned %s, want %s", replay.ID, first.ID)
+	}
+
+	if rec := post("req-1", `{"project":"acme/app","issue_num":"8"}`); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 for a key reused for another issue, got %d", rec.Code)
+	}
+	if rec := post("req-1", `{"project":"acme/app","issue_num":"7","idempotency_key":"req-2"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for differing keys, got %d", rec.Code)
+	}
+	if rec := post("req-2", `{"project":"acme/app","issue_num":"7"}`); rec.Code != http.StatusCreated {
+		t.Errorf("expected 201 for a new key, got %d", rec.Code)
+	}
+
+	state, _ := core.LoadState(statePath)
+	if len(state.Tasks) != 2 {
+		t.Errorf("state has %d tasks, want 2", len(state.Tasks))
+	}
+}
+
 func TestStaticFileServing(t *testing.T) {
 	// Use a non-existent state file — LoadState handles that gracefully.
 	statePath := filepath.Join(t.TempDir(), "nonexistent.json")
@@ -321,6 +576,542 @@ func TestGetTasksMissingStateFile(t *testing.T) {
 	}
 }
 
+// fakeSummaryAI implements core.AIAdapter and core.TaskSummarizer.
+type fakeSummaryAI struct {
+	core.AIAdapter
+	calls   int
+	reports []string
+}
+
+func (f *fakeSummaryAI) SummarizeTask(_ context.Context, report string) (string, error) {
+	f.calls++
+	f.reports = append(f.reports, report)
+	return "  Waiting on tests for #43.  ", nil
+}
+
+func TestGetTaskSummaryCachedPerVersion(t *testing.T) {
+	state := testState()
+	statePath := writeStateFile(t, state)
+	ai := &fakeSummaryAI{}
+	handler := handleGetTaskSummary(statePath, core.NewSummaryCache(), func() (core.AIAdapter, error) { return ai, nil })
+	r := chi.NewRouter()
+	r.Get("/api/tasks/{id}/summary", handler)
+
+	get := func() core.TaskSummary {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/tasks/task-002/summary", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var summary core.TaskSummary
+		if err := json.NewDecoder(rec.Body).Decode(&summary); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return summary
+	}
+
+	first := get()
+	if first.Summary != "Waiting on tests for #43." || first.Cached {
+		t.Fatalf("unexpected first summary: %+v", first)
+	}
+	if !strings.Contains(ai.reports[0], "Add dark mode") || !strings.Contains(ai.reports[0], "Status: coding") {
+		t.Errorf("report missing task facts: %q", ai.reports[0])
+	}
+	if second := get(); !second.Cached || ai.calls != 1 {
+		t.Fatalf("expected cached summary without another AI call, got %+v after %d calls", second, ai.calls)
+	}
+
+	state.Tasks[1].Status = core.PhaseTesting
+	if err := core.SaveState(state, statePath); err != nil {
+		t.Fatal(err)
+	}
+	if third := get(); third.Cached || third.Version == first.Version || ai.calls != 2 {
+		t.Fatalf("expected a fresh summary after the task changed, got %+v after %d calls", third, ai.calls)
+	}
+}
+
+func TestGetTaskSummaryUnsupportedAdapter(t *testing.T) {
+	statePath := writeStateFile(t, testState())
+	handler := handleGetTaskSummary(statePath, core.NewSummaryCache(), func() (core.AIAdapter, error) {
+		return struct{ core.AIAdapter }{}, nil
+	})
+	r := chi.NewRouter()
+	r.Get("/api/tasks/{id}/summary", handler)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/tasks/task-001/summary", nil))
+	if rec.Code != http.StatusNotImplemented {
+		t.Fatalf("expected 501, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/tasks/missing/summary", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+}
+
+// fakeExplainAI implements core.FailureExplainer; with block set it waits
+// until the call is cancelled.
+type fakeExplainAI struct {
+	core.AIAdapter
+	block   bool
+	started chan struct{}
+}
+
+func (f *fakeExplainAI) ExplainFailure(ctx context.Context, _ string) (string, error) {
+	if f.block {
+		close(f.started)
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	return "The unit tests hit a nil session.", nil
+}
+
+func TestExplainTaskStoresDiagnosis(t *testing.T) {
+	state := testState()
+	state.Tasks[0].Status = core.PhaseFailed
+	state.Tasks[0].Attempts[0].Status = "failed"
+	statePath := writeStateFile(t, state)
+	x := &explainer{newAI: func() (core.AIAdapter, error) { return &fakeExplainAI{}, nil }}
+	r := chi.NewRouter()
+	r.Post("/api/tasks/{id}/explain", x.handleExplainTask(statePath, nil))
+	r.Delete("/api/tasks/{id}/explain", x.handleCancelExplain)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/tasks/task-001/explain", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	saved, err := core.LoadState(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := saved.GetTaskByID("task-001").Explanation
+	if got == nil || got.Attempt != 1 || got.Diagnosis != "The unit tests hit a nil session." || got.RequestedBy != "anonymous" {
+		t.Fatalf("stored explanation = %+v", got)
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/tasks/task-002/explain", nil))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("task without failure: expected 409, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/tasks/task-001/explain", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("cancel without a running explanation: expected 404, got %d", rec.Code)
+	}
+}
+
+func TestExplainTaskCancel(t *testing.T) {
+	state := testState()
+	state.Tasks[0].Attempts[0].Status = "failed"
+	statePath := writeStateFile(t, state)
+	ai := &fakeExplainAI{block: true, started: make(chan struct{})}
+	x := &explainer{newAI: func() (core.AIAdapter, error) { return ai, nil }}
+	r := chi.NewRouter()
+	r.Post("/api/tasks/{id}/explain", x.handleExplainTask(statePath, nil))
+	r.Delete("/api/tasks/{id}/explain", x.handleCancelExplain)
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/tasks/task-001/explain", nil))
+		done <- rec
+	}()
+	<-ai.started
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/tasks/task-001/explain", nil))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("second explanation: expected 409, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/tasks/task-001/explain", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("cancel: expected 200, got %d", rec.Code)
+	}
+	if rec := <-done; rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "cancelled") {
+		t.Fatalf("cancelled explanation: got %d %s", rec.Code, rec.Body.String())
+	}
+	saved, _ := core.LoadState(statePath)
+	if saved.GetTaskByID("task-001").Explanation != nil {
+		t.Fatal("cancelled explanation was stored")
+	}
+}
+
+func TestApproveResumesAwaitingTask(t *testing.T) {
+	state := testState()
+	state.Tasks[1].Status = core.PhaseAwaitingApproval
+	state.Tasks[1].AddProposal(core.ProposalDeployApproval, "Deploy?", "approval required", nil)
+	statePath := writeStateFile(t, state)
+
+	type call struct {
+		taskID   string
+		approved bool
+	}
+	calls := make(chan call, 1)
+	resume := ResumeFunc(func(taskID string, approved bool) error {
+		calls <- call{taskID, approved}
+		return nil
+	})
+	handler := NewHandler(statePath, testConfig(), nil, ExecuteFunc(nil), resume)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/approve/task-002", nil))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	select {
+	case got := <-calls:
+		if got.taskID != "task-002" || !got.approved {
+			t.Fatalf("unexpected resume call: %+v", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("resume was not called")
+	}
+
+	// The engine owns the proposal once resumed, so the handler leaves it pending.
+	reloaded, err := core.LoadState(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := reloaded.GetTaskByID("task-002").GetPendingProposal(); p == nil {
+		t.Fatal("expected proposal to stay pending for the engine")
+	}
+}
+
+func TestEditPlan(t *testing.T) {
+	state := testState()
+	state.Tasks[1].Status = core.PhaseAwaitingApproval
+	p := state.Tasks[1].AddProposal(core.ProposalPlanReview, "old plan", "review", nil)
+	p.Plan = "old plan\n\n1. Step\n"
+	statePath := writeStateFile(t, state)
+	handler := NewHandler(statePath, testConfig(), nil)
+
+	put := func(taskID, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/api/proposals/"+taskID+"/plan", strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := put("task-002", `{"plan":"new plan\n\n1. Other step\n"}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	saved, _ := core.LoadState(statePath)
+	if got := saved.GetTaskByID("task-002").GetPendingProposal(); got.Plan != "new plan\n\n1. Other step\n" || got.Summary != "new plan" {
+		t.Fatalf("plan not saved: %+v", got)
+	}
+
+	if rec := put("task-001", `{"plan":"x"}`); rec.Code != http.StatusConflict {
+		t.Errorf("task without plan review: expected 409, got %d", rec.Code)
+	}
+	if rec := put("nope", `{"plan":"x"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("missing task: expected 404, got %d", rec.Code)
+	}
+	if rec := put("task-002", `{"plan":" "}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("empty plan: expected 400, got %d", rec.Code)
+	}
+}
+
+func TestProposalDiff(t *testing.T) {
+	state := testState()
+	p := state.Tasks[1].AddProposal(core.ProposalDeployFix, "fix port", "deploy failed", []core.ProposedChange{
+		{Path: "deploy.yaml", Action: "modify", Before: "a\nb\nc\nd\nport: 80\n", After: "a\nb\nc\nd\nport: 8080\n"},
+		{Path: "logo.png", Action: "create", After: "\x89PNG\x00"},
+	})
+	p.Status = core.ProposalRejected
+	statePath := writeStateFile(t, state)
+	handler := NewHandler(statePath, testConfig(), nil)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/api/proposals/" + p.ID + "/diff")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/x-diff") {
+		t.Fatalf("expected a diff, got %d %s: %s", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+	want := "--- a/deploy.yaml\n+++ b/deploy.yaml\n@@ -2,4 +2,4 @@\n b\n c\n d\n-port: 80\n+port: 8080\nBinary files /dev/null and b/logo.png differ\n"
+	if rec.Body.String() != want {
+		t.Errorf("diff:\n%q\nwant:\n%q", rec.Body.String(), want)
+	}
+
+	rec = get("/api/proposals/" + p.ID + "/diff?context=0&task=task-002")
+	if !strings.Contains(rec.Body.String(), "@@ -5,1 +5,1 @@\n-port: 80\n") {
+		t.Errorf("zero context diff:\n%s", rec.Body.String())
+	}
+	if rec := get("/api/proposals/" + p.ID + "/diff?task=task-001"); rec.Code != http.StatusNotFound {
+		t.Errorf("proposal of another task: expected 404, got %d", rec.Code)
+	}
+	if rec := get("/api/proposals/nope/diff"); rec.Code != http.StatusNotFound {
+		t.Errorf("missing proposal: expected 404, got %d", rec.Code)
+	}
+	if rec := get("/api/proposals/" + p.ID + "/diff?context=-1"); rec.Code != http.StatusBadRequest {
+		t.Errorf("negative context: expected 400, got %d", rec.Code)
+	}
+}
+
+func TestTaskArtifacts(t *testing.T) {
+	state := testState()
+	state.Tasks[0].Attempts = []core.Attempt{{Number: 1, Status: "passed", Artifacts: []core.ArtifactRef{
+		{Name: "deploy.log", Key: "task-001/attempt-1/deploy.log", Kind: core.ArtifactDeployOutput, Size: 11, ContentType: "text/plain; charset=utf-8"},
+		{Name: "test-e2e-report.html", Key: "task-001/attempt-1/test-e2e-report.html", Kind: "file", Test: "e2e", ContentType: "text/html"},
+		{Name: "test-e2e.log", Key: "task-001/attempt-1/test-e2e.log", Kind: core.ArtifactTestOutput, Test: "e2e"},
+	}}}
+	statePath := writeStateFile(t, state)
+	store := adapterartifact.NewLocal(core.ArtifactsDir(statePath))
+	for key, body := range map[string]string{"task-001/attempt-1/deploy.log": "full output", "task-001/attempt-1/test-e2e-report.html": "<script></script>"} {
+		if err := store.Put(context.Background(), key, strings.NewReader(body), int64(len(body)), ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	handler := NewHandler(statePath, testConfig(), nil)
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/api/tasks/task-001/artifacts")
+	var list []taskArtifact
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("list: %d %v", rec.Code, err)
+	}
+	if len(list) != 3 || list[0].Attempt != 1 || list[0].URL != "/api/tasks/task-001/artifacts/1/deploy.log" || list[1].Test != "e2e" {
+		t.Errorf("unexpected list %+v", list)
+	}
+
+	rec = get(list[0].URL)
+	if rec.Code != http.StatusOK || rec.Body.String() != "full output" || !strings.HasPrefix(rec.Header().Get("Content-Disposition"), "inline") {
+		t.Errorf("download: %d %q %s", rec.Code, rec.Body.String(), rec.Header().Get("Content-Disposition"))
+	}
+	if rec = get(list[1].URL); !strings.HasPrefix(rec.Header().Get("Content-Disposition"), "attachment") || rec.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Errorf("html artifact must download as an attachment, got %v", rec.Header())
+	}
+	if rec = get(list[2].URL); rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "no longer stored") {
+		t.Errorf("deleted artifact: expected 404, got %d %s", rec.Code, rec.Body.String())
+	}
+	if rec = get("/api/tasks/task-001/artifacts/2/deploy.log"); rec.Code != http.StatusNotFound {
+		t.Errorf("artifact of another attempt: expected 404, got %d", rec.Code)
+	}
+	if rec = get("/api/tasks/task-001/artifacts/x/deploy.log"); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad attempt: expected 400, got %d", rec.Code)
+	}
+	if rec = get("/api/tasks/nope/artifacts"); rec.Code != http.StatusNotFound {
+		t.Errorf("missing task: expected 404, got %d", rec.Code)
+	}
+}
+
+func TestAuditRecordsStateChanges(t *testing.T) {
+	t.Setenv("RIG_API_KEY", "k3y")
+	statePath := writeStateFile(t, testState())
+	db, err := storage.Open(filepath.Join(t.TempDir(), "rig.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	handler := NewHandler(statePath, testConfig(), db)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-API-Key", "k3y")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodPost, "/api/tasks/task-002/stop", ""); rec.Code != http.StatusOK {
+		t.Fatalf("stop: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodPost, "/api/tasks/nope/stop", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("stop missing task: %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/api/settings", `{"section":"ai","data":{"api_key":"secret"}}`); rec.Code != http.StatusOK {
+		t.Fatalf("settings: %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec := do(http.MethodGet, "/api/audit", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("audit: %d %s", rec.Code, rec.Body.String())
+	}
+	var entries []storage.AuditEntry
+	if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected only the successful changes to be audited, got %+v", entries)
+	}
+	if e := entries[0]; e.Action != storage.AuditSettingsChanged || e.Target != "ai" || e.Actor != "api-key" {
+		t.Errorf("unexpected settings entry %+v", e)
+	}
+	if strings.Contains(rec.Body.String(), "secret") {
+		t.Error("audit log must not contain setting values")
+	}
+	if e := entries[1]; e.Action != storage.AuditTaskStopped || e.Target != "task-002" || e.Source != storage.AuditSourceWeb {
+		t.Errorf("unexpected stop entry %+v", e)
+	}
+
+	rec = do(http.MethodGet, "/api/audit?action=task.stopped&since=1h", "")
+	if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil || len(entries) != 1 {
+		t.Errorf("filtered audit: %v %s", err, rec.Body.String())
+	}
+	if rec := do(http.MethodGet, "/api/audit?since=yesterday", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a bad since, got %d", rec.Code)
+	}
+}
+
+func TestSettingsMaskFallbackAPIKeys(t *testing.T) {
+	t.Setenv("RIG_API_KEY", "k3y")
+	db, err := storage.Open(filepath.Join(t.TempDir(), "rig.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	handler := NewHandler(writeStateFile(t, testState()), testConfig(), db)
+	do := func(method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/settings", strings.NewReader(body))
+		req.Header.Set("X-API-Key", "k3y")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	do(http.MethodPost, `{"section":"ai","data":{"api_key":"sk-primary","fallback":[{"provider":"openai","api_key":"sk-openai"},{"provider":"ollama"}],"index":{"provider":"openai","api_key":"sk-index"}}}`)
+	rec := do(http.MethodGet, "")
+	if body := rec.Body.String(); strings.Contains(body, "sk-") || !strings.Contains(body, `"api_key":"***"`) {
+		t.Fatalf("expected masked API keys, got %s", body)
+	}
+
+	// Saving the masked values back keeps the stored keys.
+	do(http.MethodPost, `{"section":"ai","data":{"api_key":"***","fallback":[{"provider":"openai","api_key":"***"},{"provider":"ollama"}],"index":{"provider":"openai","api_key":"***"}}}`)
+	stored, _ := db.GetSetting("ai")
+	if !strings.Contains(stored, `"sk-primary"`) || !strings.Contains(stored, `"sk-openai"`) || !strings.Contains(stored, `"sk-index"`) {
+		t.Errorf("masked values overwrote the stored keys: %s", stored)
+	}
+}
+
+func TestRetryRejectsBusyTask(t *testing.T) {
+	statePath := writeStateFile(t, testState())
+	started := make(chan core.Issue, 1)
+	handler := NewHandler(statePath, testConfig(), nil, ExecuteFunc(func(_ context.Context, issue core.Issue) error {
+		started <- issue
+		return nil
+	}))
+	retry := func(id string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/tasks/"+id+"/retry", nil))
+		return rec
+	}
+
+	// task-002 is still coding.
+	if rec := retry("task-002"); rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for in-flight task, got %d", rec.Code)
+	}
+
+	// task-001 is completed but another executor holds its issue.
+	lock, err := core.AcquireExecutionLock(statePath, testState().Tasks[0].Issue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := retry("task-001")
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "task is busy") {
+		t.Fatalf("expected 409 busy, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	lock.Release()
+	if rec := retry("task-001"); rec.Code != http.StatusOK {
+		t.Fatalf("expected retry to start, got %d: %s", rec.Code, rec.Body.String())
+	}
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("retry did not start execution")
+	}
+}
+
+func TestRerunTask(t *testing.T) {
+	statePath := writeStateFile(t, testState())
+	type call struct {
+		taskID, step string
+		noCache      bool
+	}
+	started := make(chan call, 1)
+	handler := NewHandler(statePath, testConfig(), nil, RerunFunc(func(ctx context.Context, taskID, step string) error {
+		started <- call{taskID, step, core.AICacheBypassed(ctx)}
+		return nil
+	}))
+	rerun := func(id, step string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/tasks/"+id+"/"+step, nil))
+		return rec
+	}
+
+	if rec := rerun("task-002", "redeploy"); rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for in-flight task, got %d", rec.Code)
+	}
+	if rec := rerun("nope", "retest"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown task, got %d", rec.Code)
+	}
+	for _, tt := range []struct{ path, step string }{{"redeploy", core.RerunDeploy}, {"retest", core.RerunTest}} {
+		if rec := rerun("task-001", tt.path); rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", tt.path, rec.Code, rec.Body.String())
+		}
+		select {
+		case c := <-started:
+			if c != (call{"task-001", tt.step, false}) {
+				t.Errorf("%s: rerun called with %+v", tt.path, c)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%s did not start", tt.path)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/tasks/task-001/retest", nil)
+	req.Header.Set("Cache-Control", "no-cache")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	select {
+	case c := <-started:
+		if !c.noCache {
+			t.Error("expected Cache-Control: no-cache to skip cached AI answers")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("retest did not start")
+	}
+}
+
+func TestClearAICache(t *testing.T) {
+	db, err := storage.Open(filepath.Join(t.TempDir(), "rig.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, key := range []string{"plan", "code"} {
+		if err := db.PutAICache(key, "{}", time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+	handler := NewHandler(writeStateFile(t, testState()), testConfig(), db)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/ai-cache", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"deleted":2`) {
+		t.Fatalf("expected 2 entries deleted, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if _, ok, _ := db.GetAICache("plan"); ok {
+		t.Error("expected the cache to be empty")
+	}
+}
+
 func containsString(haystack, needle string) bool {
 	return len(haystack) >= len(needle) && searchString(haystack, needle)
 }
@@ -338,3 +1129,71 @@ func searchString(s, sub string) bool {
 func TestMain(m *testing.M) {
 	os.Exit(m.Run())
 }
+
+func TestWorkspacesListAndGC(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"acme/api", ".tasks/acme/api/task-001", ".tasks/acme/api/task-002"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := testConfig()
+	cfg.Source.Workspaces.Root = root
+	handler := NewHandler(writeStateFile(t, testState()), cfg, nil)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/workspaces", nil))
+	var list []adaptergit.Workspace
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("list: %d %v", rec.Code, err)
+	}
+	if len(list) != 3 {
+		t.Fatalf("listed %d workspaces, want 3", len(list))
+	}
+
+	// task-001 is completed; task-002 is still coding.
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/workspaces/gc", nil))
+	var gc workspaceGCResponse
+	if err := json.NewDecoder(rec.Body).Decode(&gc); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("gc: %d %v", rec.Code, err)
+	}
+	if len(gc.Removed) != 1 || gc.Removed[0].TaskID != "task-001" {
+		t.Errorf("gc removed %+v, want task-001", gc.Removed)
+	}
+	if _, err := os.Stat(filepath.Join(root, ".tasks/acme/api/task-002")); err != nil {
+		t.Errorf("gc removed the running task's worktree: %v", err)
+	}
+}
+
+func TestHealthAndReadiness(t *testing.T) {
+	t.Setenv("RIG_API_KEY", "secret")
+	statePath := writeStateFile(t, testState())
+	var notReady error
+	handler := NewHandler(statePath, testConfig(), nil, ReadyFunc(func() error { return notReady }))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+	for _, path := range []string{"/healthz", "/readyz"} {
+		if rec := get(path); rec.Code != http.StatusOK || rec.Body.String() != "ok\n" {
+			t.Errorf("%s = %d %q without credentials, want 200 ok", path, rec.Code, rec.Body.String())
+		}
+	}
+
+	notReady = errors.New("rig serve is shutting down")
+	if rec := get("/readyz"); rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "shutting down") {
+		t.Errorf("/readyz while draining = %d %q", rec.Code, rec.Body.String())
+	}
+	if rec := get("/healthz"); rec.Code != http.StatusOK {
+		t.Errorf("/healthz while draining = %d, want 200", rec.Code)
+	}
+
+	notReady = nil
+	handler = NewHandler(filepath.Join(t.TempDir(), "unmounted", "state.json"), testConfig(), nil)
+	if rec := get("/readyz"); rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "state directory") {
+		t.Errorf("/readyz without the state volume = %d %q", rec.Code, rec.Body.String())
+	}
+}
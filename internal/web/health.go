@@ -0,0 +1,60 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rigdev/rig/internal/storage"
+)
+
+// ReadyFunc reports whether the server takes new tasks; GET /readyz fails
+// with its error, as rig serve does while it drains for shutdown.
+type ReadyFunc func() error
+
+func (f ReadyFunc) applyTo(cb *handlerCallbacks) { cb.ready = f }
+
+// readyTimeout bounds the database check of GET /readyz.
+const readyTimeout = 2 * time.Second
+
+// handleHealthz answers liveness probes: the process serves HTTP.
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("ok\n"))
+}
+
+// handleReadyz answers readiness probes: the server takes tasks, the
+// database answers and the state directory exists, so a volume that is not
+// mounted yet keeps the pod out of service.
+func handleReadyz(statePath string, db *storage.DB, ready ReadyFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := func() error {
+			if ready != nil {
+				if err := ready(); err != nil {
+					return err
+				}
+			}
+			if db != nil {
+				ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+				defer cancel()
+				if err := db.Ping(ctx); err != nil {
+					return fmt.Errorf("database: %w", err)
+				}
+			}
+			if info, err := os.Stat(filepath.Dir(statePath)); err != nil || !info.IsDir() {
+				return fmt.Errorf("state directory %s is not available", filepath.Dir(statePath))
+			}
+			return nil
+		}()
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintf(w, "not ready: %s\n", sanitizeError(err.Error()))
+			return
+		}
+		w.Write([]byte("ok\n"))
+	}
+}
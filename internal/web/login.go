@@ -0,0 +1,214 @@
+package web
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rigdev/rig/internal/auth"
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/storage"
+)
+
+const (
+	sessionCookie = "rig_session"
+	// loginCookie carries the state and nonce of a login in progress.
+	loginCookie = "rig_login"
+	loginTTL    = 10 * time.Minute
+)
+
+// login implements dashboard login through server.auth. A nil login
+// disables it.
+type login struct {
+	cfg      config.AuthConfig
+	provider auth.Provider
+	signer   *auth.Signer
+	ttl      time.Duration
+	secure   bool
+	audit    *auditor
+}
+
+// pendingLogin is sealed into loginCookie between /auth/login and
+// /auth/callback.
+type pendingLogin struct {
+	State   string    `json:"state"`
+	Nonce   string    `json:"nonce"`
+	Return  string    `json:"return"`
+	Expires time.Time `json:"expires"`
+}
+
+// newLogin returns the login configured in cfg, or nil when no provider is
+// set.
+func newLogin(cfg config.AuthConfig, audit *auditor) (*login, error) {
+	if cfg.Provider == "" {
+		return nil, nil
+	}
+	provider, err := auth.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	ttl := cfg.SessionTTL
+	if ttl <= 0 {
+		ttl = auth.DefaultSessionTTL
+	}
+	return &login{
+		cfg:      cfg,
+		provider: provider,
+		signer:   auth.NewSigner(cfg.SessionSecret),
+		ttl:      ttl,
+		secure:   strings.HasPrefix(cfg.RedirectURL, "https://"),
+		audit:    audit,
+	}, nil
+}
+
+// session returns the valid session of r, or nil.
+func (l *login) session(r *http.Request) *auth.Session {
+	if l == nil {
+		return nil
+	}
+	c, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return nil
+	}
+	var s auth.Session
+	if err := l.signer.Open(c.Value, &s); err != nil || time.Now().After(s.Expires) {
+		return nil
+	}
+	return &s
+}
+
+func (l *login) setCookie(w http.ResponseWriter, name, value string, expires time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   l.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (l *login) clearCookie(w http.ResponseWriter, name string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   l.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// handleLogin sends the browser to the provider. ?return is the dashboard
+// path to come back to.
+func (l *login) handleLogin(w http.ResponseWriter, r *http.Request) {
+	p := pendingLogin{
+		State:   auth.RandomString(24),
+		Nonce:   auth.RandomString(24),
+		Return:  safeReturnPath(r.URL.Query().Get("return")),
+		Expires: time.Now().Add(loginTTL),
+	}
+	target, err := l.provider.AuthCodeURL(r.Context(), p.State, p.Nonce)
+	if err != nil {
+		slog.Error("web: login failed", "err", err)
+		http.Error(w, "login provider unavailable", http.StatusBadGateway)
+		return
+	}
+	sealed, err := l.signer.Seal(p)
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	l.setCookie(w, loginCookie, sealed, p.Expires)
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// handleCallback completes the login started by handleLogin.
+func (l *login) handleCallback(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		http.Error(w, "login failed: "+e, http.StatusUnauthorized)
+		return
+	}
+	var p pendingLogin
+	c, err := r.Cookie(loginCookie)
+	if err != nil || l.signer.Open(c.Value, &p) != nil || time.Now().After(p.Expires) {
+		http.Error(w, "login expired; start again", http.StatusBadRequest)
+		return
+	}
+	if q.Get("state") == "" || q.Get("state") != p.State {
+		http.Error(w, "login state mismatch", http.StatusBadRequest)
+		return
+	}
+	l.clearCookie(w, loginCookie)
+
+	id, err := l.provider.Exchange(r.Context(), q.Get("code"), p.Nonce)
+	if err != nil {
+		slog.Warn("web: login failed", "err", err)
+		http.Error(w, "login failed", http.StatusUnauthorized)
+		return
+	}
+	if !auth.Allowed(l.cfg, id) {
+		slog.Warn("web: login denied", "user", id.User)
+		http.Error(w, "user "+id.User+" is not allowed", http.StatusForbidden)
+		return
+	}
+
+	s := auth.Session{User: id.User, Role: auth.RoleFor(l.cfg, id), Expires: time.Now().Add(l.ttl)}
+	sealed, err := l.signer.Seal(s)
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	l.setCookie(w, sessionCookie, sealed, s.Expires)
+	l.audit.record(withActor(r, "user:"+s.User, storage.Role(s.Role)), storage.AuditUserLogin, s.User, s.Role)
+
+	http.Redirect(w, r, p.Return, http.StatusFound)
+}
+
+func (l *login) handleLogout(w http.ResponseWriter, r *http.Request) {
+	l.clearCookie(w, sessionCookie)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// requireSession redirects browsers without a session to the login page.
+func (l *login) requireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if l.session(r) == nil {
+			http.Redirect(w, r, "/auth/login?return="+url.QueryEscape(r.URL.RequestURI()), http.StatusFound)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// safeReturnPath keeps post-login redirects on this server.
+func safeReturnPath(p string) string {
+	if !strings.HasPrefix(p, "/") || strings.HasPrefix(p, "//") || strings.HasPrefix(p, "/\\") {
+		return "/"
+	}
+	return p
+}
+
+// meResponse describes the caller of GET /api/auth/me.
+type meResponse struct {
+	User string `json:"user"`
+	Role string `json:"role"`
+	// Login reports whether dashboard login is enabled, so the UI knows
+	// whether to offer logout.
+	Login bool `json:"login"`
+}
+
+func handleGetMe(l *login) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, meResponse{
+			User:  requestActor(r),
+			Role:  string(requestRole(r)),
+			Login: l != nil,
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/storage"
+)
+
+func TestDashboardLogin(t *testing.T) {
+	t.Setenv("RIG_API_KEY", "")
+	gh := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login/oauth/access_token":
+			json.NewEncoder(w).Encode(map[string]string{"access_token": "gho_" + r.FormValue("code")})
+		case "/api/v3/user":
+			json.NewEncoder(w).Encode(map[string]string{"login": strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer gho_")})
+		}
+	}))
+	defer gh.Close()
+
+	cfg := testConfig()
+	cfg.Server.Auth = config.AuthConfig{
+		Provider:      "github",
+		Issuer:        gh.URL,
+		ClientID:      "id",
+		ClientSecret:  "secret",
+		RedirectURL:   "http://rig.test/auth/callback",
+		AllowedUsers:  []string{"octocat", "hubot"},
+		Roles:         map[string]string{"octocat": "approver"},
+		SessionSecret: "test-secret",
+	}
+	db, err := storage.Open(filepath.Join(t.TempDir(), "rig.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	handler := NewHandler(writeStateFile(t, testState()), cfg, db)
+
+	do := func(method, path string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	cookie := func(rec *httptest.ResponseRecorder, name string) *http.Cookie {
+		for _, c := range rec.Result().Cookies() {
+			if c.Name == name {
+				return c
+			}
+		}
+		t.Fatalf("no %s cookie", name)
+		return nil
+	}
+	// loginAs runs the login flow and returns the callback response.
+	loginAs := func(user string) *httptest.ResponseRecorder {
+		rec := do(http.MethodGet, "/auth/login?return=/tasks")
+		if rec.Code != http.StatusFound {
+			t.Fatalf("login: %d %s", rec.Code, rec.Body.String())
+		}
+		u, _ := url.Parse(rec.Header().Get("Location"))
+		if !strings.HasPrefix(u.String(), gh.URL+"/login/oauth/authorize") {
+			t.Fatalf("login redirects to %s", u)
+		}
+		state := u.Query().Get("state")
+		return do(http.MethodGet, "/auth/callback?code="+user+"&state="+state, cookie(rec, loginCookie))
+	}
+
+	t.Run("unauthenticated requests", func(t *testing.T) {
+		if rec := do(http.MethodGet, "/api/tasks"); rec.Code != http.StatusUnauthorized {
+			t.Errorf("API without session: got %d, want 401", rec.Code)
+		}
+		rec := do(http.MethodGet, "/index.html")
+		if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/auth/login?return=%2Findex.html" {
+			t.Errorf("dashboard without session: got %d to %q", rec.Code, rec.Header().Get("Location"))
+		}
+	})
+
+	t.Run("login sets a session with the configured role", func(t *testing.T) {
+		rec := loginAs("octocat")
+		if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/tasks" {
+			t.Fatalf("callback: %d to %q: %s", rec.Code, rec.Header().Get("Location"), rec.Body.String())
+		}
+		session := cookie(rec, sessionCookie)
+		if !session.HttpOnly {
+			t.Error("session cookie is not HttpOnly")
+		}
+
+		rec = do(http.MethodGet, "/api/auth/me", session)
+		var me meResponse
+		json.Unmarshal(rec.Body.Bytes(), &me)
+		if me.User != "user:octocat" || me.Role != "approver" || !me.Login {
+			t.Errorf("me = %+v", me)
+		}
+		if rec := do(http.MethodPost, "/api/tasks/task-001/stop", session); rec.Code != http.StatusForbidden {
+			t.Errorf("approver stopping a task: got %d, want 403", rec.Code)
+		}
+
+		entries, _ := db.ListAudit(storage.AuditFilter{Action: storage.AuditUserLogin})
+		if len(entries) != 1 || entries[0].Actor != "user:octocat" {
+			t.Errorf("login audit = %+v", entries)
+		}
+	})
+
+	t.Run("users outside the allowlist are refused", func(t *testing.T) {
+		if rec := loginAs("mallory"); rec.Code != http.StatusForbidden {
+			t.Errorf("callback for mallory: got %d, want 403", rec.Code)
+		}
+	})
+
+	t.Run("callback checks the state", func(t *testing.T) {
+		rec := do(http.MethodGet, "/auth/login")
+		if rec := do(http.MethodGet, "/auth/callback?code=octocat&state=forged", cookie(rec, loginCookie)); rec.Code != http.StatusBadRequest {
+			t.Errorf("forged state: got %d, want 400", rec.Code)
+		}
+	})
+}
+
+func TestSafeReturnPath(t *testing.T) {
+	for in, want := range map[string]string{
+		"/tasks?id=1":          "/tasks?id=1",
+		"":                     "/",
+		"https://evil.example": "/",
+		"//evil.example":       "/",
+		`/\evil.example`:       "/",
+	} {
+		if got := safeReturnPath(in); got != want {
+			t.Errorf("safeReturnPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
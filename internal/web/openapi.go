@@ -0,0 +1,526 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/go-chi/chi/v5"
+	adaptergit "github.com/rigdev/rig/internal/adapter/git"
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/metrics"
+	"github.com/rigdev/rig/internal/storage"
+	"github.com/rigdev/rig/internal/tenant"
+)
+
+// Operation describes one route of the web API. The OpenAPI document served
+// at /api/openapi.json and the generated client in pkg/client are both built
+// from these.
+type Operation struct {
+	Method  string
+	Path    string
+	ID      string
+	Summary string
+	Tag     string
+	// Request is the JSON request body type, nil if the route takes none.
+	Request reflect.Type
+	// Response is the JSON success response type, nil for non-JSON routes.
+	Response reflect.Type
+	// Status is the success status code; 0 means 200.
+	Status int
+	// ContentType is the success content type of routes that do not answer
+	// with JSON.
+	ContentType string
+	Query       []QueryParam
+	// Permission is what the caller's role needs; empty means PermRead for
+	// GET and PermAdmin otherwise.
+	Permission Permission
+	// NoClient leaves the route out of the generated client: chat webhooks
+	// are called by Slack and Discord, and the event stream is not a
+	// request/response call.
+	NoClient bool
+}
+
+// QueryParam is a query string parameter of an Operation.
+type QueryParam struct {
+	Name        string
+	Description string
+	// Kind is reflect.String, reflect.Int, reflect.Int64 or reflect.Bool.
+	Kind reflect.Kind
+}
+
+// actionResponse is the body of routes that start, stop or save something.
+type actionResponse struct {
+	Status  string `json:"status"`
+	TaskID  string `json:"task_id,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// errorResponse is the body of every non-2xx JSON response.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type statusResponse struct {
+	Configured      bool           `json:"configured"`
+	Mode            string         `json:"mode"`
+	GitHubRateLimit *rateLimitInfo `json:"github_rate_limit,omitempty"`
+	// ConfigReload is set when rig serve reloads rig.yaml on change.
+	ConfigReload *config.ReloadStatus `json:"config_reload,omitempty"`
+}
+
+type rateLimitInfo struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+	Headroom  float64   `json:"headroom"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type agentsResponse struct {
+	Repo    string `json:"repo"`
+	Content string `json:"content"`
+}
+
+type saveAgentsRequest struct {
+	Content string `json:"content"`
+}
+
+type editPlanRequest struct {
+	Plan string `json:"plan"`
+}
+
+func typeOf[T any]() reflect.Type { return reflect.TypeOf((*T)(nil)).Elem() }
+
+// taskQueryParams are the task filters of GET /api/tasks and /api/events.
+var taskQueryParams = []QueryParam{
+	{Name: "status", Description: "Only tasks in these statuses, comma-separated", Kind: reflect.String},
+	{Name: "repo", Description: "Only tasks of this repository, owner/name", Kind: reflect.String},
+	{Name: "since", Description: "Only tasks created after a duration ago (24h) or an RFC 3339 time", Kind: reflect.String},
+	{Name: "until", Description: "Only tasks created before a duration ago (24h) or an RFC 3339 time", Kind: reflect.String},
+	{Name: "q", Description: "Only tasks with this text in the issue title, or this task ID or issue number", Kind: reflect.String},
+	{Name: "parent", Description: "Only the parts of this epic task", Kind: reflect.String},
+	{Name: "sort", Description: "Sort by created_at (default), completed_at, status or id; prefix - for descending", Kind: reflect.String},
+	{Name: "limit", Description: "Maximum number of tasks (default all)", Kind: reflect.Int},
+	{Name: "offset", Description: "Skip this many matching tasks", Kind: reflect.Int},
+}
+
+// periodParams are the reporting period of GET /api/reports/tasks and the
+// analytics routes.
+var periodParams = []QueryParam{
+	{Name: "from", Description: "Start of the period, a date (2006-01-02) or RFC 3339 time; default 30 days before to", Kind: reflect.String},
+	{Name: "to", Description: "End of the period, exclusive; default now", Kind: reflect.String},
+}
+
+// operations lists every API route. TestOpenAPICoversRoutes keeps it in sync
+// with the router.
+var operations = withTenantOperations([]Operation{
+	{Method: http.MethodPost, Path: "/api/chatops/slack", ID: "SlackCommand", Tag: "chatops", Summary: "Slack slash command webhook", ContentType: "application/x-www-form-urlencoded", NoClient: true},
+	{Method: http.MethodPost, Path: "/api/chatops/discord", ID: "DiscordInteraction", Tag: "chatops", Summary: "Discord interaction webhook", NoClient: true},
+
+	{Method: http.MethodGet, Path: "/api/settings", ID: "GetSettings", Tag: "settings", Summary: "Get all settings sections, secrets masked", Response: typeOf[map[string]any](), Permission: PermAdmin},
+	{Method: http.MethodPost, Path: "/api/settings", ID: "SaveSettings", Tag: "settings", Summary: "Save one settings section, or all when section is empty", Request: typeOf[saveSettingsRequest](), Response: typeOf[actionResponse]()},
+	{Method: http.MethodGet, Path: "/api/agents", ID: "ListAgents", Tag: "agents", Summary: "List AGENTS.md content per repository", Response: typeOf[map[string]string]()},
+	{Method: http.MethodGet, Path: "/api/agents/{repo}", ID: "GetAgents", Tag: "agents", Summary: "Get the AGENTS.md content of a repository", Response: typeOf[agentsResponse]()},
+	{Method: http.MethodPost, Path: "/api/agents/{repo}", ID: "SaveAgents", Tag: "agents", Summary: "Save the AGENTS.md content of a repository", Request: typeOf[saveAgentsRequest](), Response: typeOf[actionResponse]()},
+	{Method: http.MethodGet, Path: "/api/audit", ID: "ListAudit", Tag: "audit", Summary: "Audit log of state-changing actions, newest first", Response: typeOf[[]storage.AuditEntry](), Permission: PermAdmin,
+		Query: []QueryParam{
+			{Name: "actor", Description: "Only entries by this actor, e.g. api-key or github:octocat", Kind: reflect.String},
+			{Name: "action", Description: "Only entries with this action, e.g. proposal.approved", Kind: reflect.String},
+			{Name: "target", Description: "Only entries for this task ID, settings section or repository", Kind: reflect.String},
+			{Name: "since", Description: "Only entries newer than a duration (24h) or RFC 3339 time", Kind: reflect.String},
+			{Name: "limit", Description: "Maximum number of entries (default 100)", Kind: reflect.Int},
+		}},
+	{Method: http.MethodGet, Path: "/api/keys", ID: "ListAPIKeys", Tag: "keys", Summary: "List API keys and their roles", Response: typeOf[[]storage.APIKey](), Permission: PermAdmin},
+	{Method: http.MethodPost, Path: "/api/keys", ID: "CreateAPIKey", Tag: "keys", Summary: "Create an API key; the response holds the only copy of the key", Request: typeOf[createAPIKeyRequest](), Response: typeOf[createAPIKeyResponse](), Status: http.StatusCreated},
+	{Method: http.MethodDelete, Path: "/api/keys/{name}", ID: "DeleteAPIKey", Tag: "keys", Summary: "Revoke an API key", Response: typeOf[actionResponse]()},
+
+	{Method: http.MethodGet, Path: "/api/webhooks", ID: "ListWebhookDeliveries", Tag: "webhooks", Summary: "Stored webhook deliveries, newest first, without payloads", Response: typeOf[[]storage.WebhookDelivery](),
+		Query: []QueryParam{
+			{Name: "status", Description: "Only deliveries with this status: received, accepted, ignored, failed or dead", Kind: reflect.String},
+			{Name: "limit", Description: "Maximum number of deliveries (default 100)", Kind: reflect.Int},
+		}},
+	{Method: http.MethodGet, Path: "/api/webhooks/{id}", ID: "GetWebhookDelivery", Tag: "webhooks", Summary: "Get a stored webhook delivery with its headers and payload", Response: typeOf[storage.WebhookDelivery](), Permission: PermAdmin},
+	{Method: http.MethodPost, Path: "/api/webhooks/{id}/replay", ID: "ReplayWebhookDelivery", Tag: "webhooks", Summary: "Process a stored webhook delivery again", Response: typeOf[actionResponse](), Status: http.StatusAccepted, Permission: PermOperate},
+
+	{Method: http.MethodDelete, Path: "/api/ai-cache", ID: "ClearAICache", Tag: "system", Summary: "Remove every cached AI answer (ai.cache), so later tasks ask the AI provider again", Response: typeOf[clearAICacheResponse](), Permission: PermAdmin},
+
+	{Method: http.MethodGet, Path: "/api/workspaces", ID: "ListWorkspaces", Tag: "workspaces", Summary: "Repository clones and task worktrees with their disk usage", Response: typeOf[[]adaptergit.Workspace](), Permission: PermAdmin},
+	{Method: http.MethodPost, Path: "/api/workspaces/gc", ID: "CollectWorkspaces", Tag: "workspaces", Summary: "Remove the task worktrees no running or waiting task needs", Response: typeOf[workspaceGCResponse](), Permission: PermAdmin},
+
+	{Method: http.MethodGet, Path: "/api/auth/me", ID: "GetMe", Tag: "auth", Summary: "The authenticated caller and its role", Response: typeOf[meResponse]()},
+
+	{Method: http.MethodGet, Path: "/api/status", ID: "GetStatus", Tag: "system", Summary: "Server mode, GitHub rate limit and config reload status", Response: typeOf[statusResponse]()},
+	{Method: http.MethodGet, Path: "/api/config", ID: "GetConfig", Tag: "system", Summary: "Non-secret configuration summary", Response: typeOf[configResponse]()},
+	{Method: http.MethodGet, Path: "/api/projects", ID: "ListProjects", Tag: "system", Summary: "Configured projects", Response: typeOf[[]config.ProjectEntry]()},
+	{Method: http.MethodGet, Path: "/api/metrics/dora", ID: "GetDORAMetrics", Tag: "system", Summary: "DORA metrics over the last 30 days", Response: typeOf[metrics.DORAMetrics]()},
+	{Method: http.MethodGet, Path: "/api/reports/tasks", ID: "GetTaskReport", Tag: "system", Summary: "Per-repository summary of the tasks created in a period", Response: typeOf[metrics.TaskReport](),
+		Query: append(periodParams[:len(periodParams):len(periodParams)],
+			QueryParam{Name: "format", Description: "json (default) or csv for a text/csv download", Kind: reflect.String})},
+	{Method: http.MethodGet, Path: "/api/analytics/tasks-per-day", ID: "GetTasksPerDay", Tag: "system", Summary: "Tasks created, completed and failed per UTC day of a period", Response: typeOf[[]metrics.DayCount](),
+		Query: periodParams},
+	{Method: http.MethodGet, Path: "/api/analytics/failure-reasons", ID: "GetFailureReasons", Tag: "system", Summary: "Failed tasks of a period per failure reason", Response: typeOf[[]metrics.ReasonCount](),
+		Query: periodParams},
+	{Method: http.MethodGet, Path: "/api/analytics/phase-durations", ID: "GetPhaseDurations", Tag: "system", Summary: "Mean, median and maximum duration of each pipeline phase over a period", Response: typeOf[[]metrics.PhaseDuration](),
+		Query: periodParams},
+	{Method: http.MethodGet, Path: "/api/analytics/retries", ID: "GetRetryDistribution", Tag: "system", Summary: "Tasks of a period per number of retries", Response: typeOf[[]metrics.RetryCount](),
+		Query: periodParams},
+	{Method: http.MethodGet, Path: "/api/openapi.json", ID: "GetOpenAPI", Tag: "system", Summary: "This OpenAPI document", ContentType: "application/json", NoClient: true},
+
+	{Method: http.MethodGet, Path: "/api/tasks", ID: "ListTasks", Tag: "tasks", Summary: "List tasks; X-Total-Count holds the number of all matching tasks", Response: typeOf[[]core.Task](),
+		Query: taskQueryParams},
+	{Method: http.MethodPost, Path: "/api/tasks", ID: "CreateTask", Tag: "tasks", Summary: "Create a task from an issue and start it; a retry with the same Idempotency-Key returns the first task, and Cache-Control: no-cache skips cached AI answers", Request: typeOf[createTaskRequest](), Response: typeOf[core.Task](), Status: http.StatusCreated, Permission: PermOperate},
+	{Method: http.MethodGet, Path: "/api/tasks/{id}", ID: "GetTask", Tag: "tasks", Summary: "Get a task", Response: typeOf[core.Task]()},
+	{Method: http.MethodPost, Path: "/api/tasks/{id}/retry", ID: "RetryTask", Tag: "tasks", Summary: "Run a task again; Cache-Control: no-cache skips cached AI answers", Response: typeOf[actionResponse](), Permission: PermOperate},
+	{Method: http.MethodPost, Path: "/api/tasks/{id}/redeploy", ID: "RedeployTask", Tag: "tasks", Summary: "Deploy and test a finished task again without generating code; Cache-Control: no-cache skips cached AI answers", Response: typeOf[actionResponse](), Permission: PermOperate},
+	{Method: http.MethodPost, Path: "/api/tasks/{id}/retest", ID: "RetestTask", Tag: "tasks", Summary: "Run the tests of a finished task again; Cache-Control: no-cache skips cached AI answers", Response: typeOf[actionResponse](), Permission: PermOperate},
+	{Method: http.MethodPost, Path: "/api/tasks/{id}/stop", ID: "StopTask", Tag: "tasks", Summary: "Mark a task as failed", Response: typeOf[actionResponse](), Permission: PermOperate},
+	{Method: http.MethodGet, Path: "/api/tasks/{id}/logs", ID: "GetTaskLogs", Tag: "tasks", Summary: "Task log lines", Response: typeOf[[]storage.LogEntry](),
+		Query: []QueryParam{{Name: "after", Description: "Only return lines with a larger ID", Kind: reflect.Int64}}},
+	{Method: http.MethodPost, Path: "/api/tasks/{id}/explain", ID: "ExplainTask", Tag: "tasks", Summary: "AI diagnosis of the last failed attempt of a task, stored with the task; 409 while one runs", Response: typeOf[core.FailureExplanation](), Permission: PermOperate},
+	{Method: http.MethodDelete, Path: "/api/tasks/{id}/explain", ID: "CancelExplainTask", Tag: "tasks", Summary: "Cancel the running AI diagnosis of a task", Response: typeOf[actionResponse](), Permission: PermOperate},
+	{Method: http.MethodGet, Path: "/api/tasks/{id}/summary", ID: "GetTaskSummary", Tag: "tasks", Summary: "AI-written status summary of a task; a cache miss makes an AI call", Response: typeOf[core.TaskSummary](), Permission: PermOperate},
+	{Method: http.MethodGet, Path: "/api/tasks/{id}/bundle", ID: "GetTaskBundle", Tag: "tasks", Summary: "Diagnostic bundle of a task as a zip", ContentType: "application/zip"},
+	{Method: http.MethodGet, Path: "/api/tasks/{id}/artifacts", ID: "ListTaskArtifacts", Tag: "tasks", Summary: "Full deploy and test outputs and test files the attempts of a task stored", Response: typeOf[[]taskArtifact]()},
+	{Method: http.MethodGet, Path: "/api/tasks/{id}/artifacts/{attempt}/{name}", ID: "GetTaskArtifact", Tag: "tasks", Summary: "Download an artifact of a task attempt; 404 once retention deleted it", ContentType: "application/octet-stream"},
+
+	{Method: http.MethodGet, Path: "/api/proposals", ID: "ListProposals", Tag: "proposals", Summary: "Pending proposals of all tasks", Response: typeOf[[]pendingProposalItem]()},
+	{Method: http.MethodGet, Path: "/api/proposals/{taskId}", ID: "ListTaskProposals", Tag: "proposals", Summary: "Pending proposals of a task", Response: typeOf[[]core.Proposal]()},
+	{Method: http.MethodGet, Path: "/api/proposals/{id}/diff", ID: "GetProposalDiff", Tag: "proposals", Summary: "A proposal of any status as a unified diff; binary files are only named", ContentType: "text/x-diff",
+		Query: []QueryParam{
+			{Name: "context", Description: "Unchanged lines around each hunk (default 3)", Kind: reflect.Int},
+			{Name: "task", Description: "Task of the proposal, when proposals of several tasks share its ID", Kind: reflect.String},
+		}},
+	{Method: http.MethodPut, Path: "/api/proposals/{taskId}/plan", ID: "EditPlan", Tag: "proposals", Summary: "Replace the plan text of a plan review before approving it", Request: typeOf[editPlanRequest](), Response: typeOf[actionResponse](), Permission: PermApprove},
+	{Method: http.MethodPost, Path: "/api/approve/{taskId}", ID: "Approve", Tag: "proposals", Summary: "Approve the pending proposal of a task", Response: typeOf[actionResponse](), Permission: PermApprove},
+	{Method: http.MethodPost, Path: "/api/reject/{taskId}", ID: "Reject", Tag: "proposals", Summary: "Reject the pending proposal of a task", Response: typeOf[actionResponse](), Permission: PermApprove},
+
+	{Method: http.MethodGet, Path: "/api/events", ID: "StreamEvents", Tag: "events", Summary: "Server-sent events with the task list and status counts whenever they change", ContentType: "text/event-stream", NoClient: true,
+		Query: taskQueryParams},
+
+	{Method: http.MethodGet, Path: "/api/editor/tasks", ID: "ListEditorTasks", Tag: "editor", Summary: "Compact task list for editor extensions, newest first", Response: typeOf[[]editorTask](),
+		Query: []QueryParam{
+			{Name: "repo", Description: "Only tasks of this owner/name repository", Kind: reflect.String},
+			{Name: "active", Description: "Hide completed and failed tasks", Kind: reflect.Bool},
+		}},
+	{Method: http.MethodGet, Path: "/api/editor/tasks/{taskId}", ID: "GetEditorTask", Tag: "editor", Summary: "Compact task for editor extensions", Response: typeOf[editorTask]()},
+	{Method: http.MethodGet, Path: "/api/editor/tasks/{taskId}/diff", ID: "GetEditorDiff", Tag: "editor", Summary: "Pending proposal as a unified diff", ContentType: "text/x-diff"},
+	{Method: http.MethodGet, Path: "/api/editor/tasks/{taskId}/checkout", ID: "GetEditorCheckout", Tag: "editor", Summary: "How to check out the task branch", Response: typeOf[editorCheckout]()},
+	{Method: http.MethodPost, Path: "/api/editor/tasks/{taskId}/approve", ID: "EditorApprove", Tag: "editor", Summary: "Approve the pending proposal of a task", Response: typeOf[actionResponse](), Permission: PermApprove},
+	{Method: http.MethodPost, Path: "/api/editor/tasks/{taskId}/reject", ID: "EditorReject", Tag: "editor", Summary: "Reject the pending proposal of a task", Response: typeOf[actionResponse](), Permission: PermApprove},
+
+	{Method: http.MethodGet, Path: "/api/tenants", ID: "ListTenants", Tag: "tenants", Summary: "Tenants with their quota and what they use of it; a tenant key sees its own", Response: typeOf[[]tenant.Usage]()},
+})
+
+// Operations returns every API route, sorted by path and method.
+func Operations() []Operation {
+	ops := append([]Operation(nil), operations...)
+	sort.SliceStable(ops, func(i, j int) bool {
+		if ops[i].Path != ops[j].Path {
+			return ops[i].Path < ops[j].Path
+		}
+		return ops[i].Method < ops[j].Method
+	})
+	return ops
+}
+
+// SchemaName is the component schema name of a named type, which is also
+// its name in the generated client.
+func SchemaName(t reflect.Type) string {
+	r := []rune(t.Name())
+	if len(r) == 0 {
+		return ""
+	}
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+var pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+// handleOpenAPI serves the OpenAPI document of the routes registered on
+// router. It is built on first request, once every route is mounted.
+func handleOpenAPI(router chi.Routes) http.HandlerFunc {
+	var (
+		once sync.Once
+		doc  []byte
+		err  error
+	)
+	return func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() {
+			var spec map[string]any
+			if spec, err = OpenAPISpec(router); err == nil {
+				doc, err = json.MarshalIndent(spec, "", "  ")
+			}
+		})
+		if err != nil {
+			writeErrorJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(doc)
+	}
+}
+
+// OpenAPISpec builds an OpenAPI 3 document for the API routes of router.
+// Routes without an Operation are listed without schemas.
+func OpenAPISpec(router chi.Routes) (map[string]any, error) {
+	known := make(map[string]Operation, len(operations))
+	for _, op := range operations {
+		known[op.Method+" "+op.Path] = op
+	}
+
+	var routes []Operation
+	err := chi.Walk(router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		route = strings.TrimSuffix(route, "/")
+		if !strings.HasPrefix(route, "/api/") {
+			return nil
+		}
+		op, ok := known[method+" "+route]
+		if !ok {
+			op = Operation{Method: method, Path: route, Tag: "other"}
+		}
+		routes = append(routes, op)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walk routes: %w", err)
+	}
+
+	schemas := newSchemaBuilder()
+	paths := map[string]map[string]any{}
+	for _, op := range routes {
+		item := paths[op.Path]
+		if item == nil {
+			item = map[string]any{}
+			paths[op.Path] = item
+		}
+		item[strings.ToLower(op.Method)] = openAPIOperation(op, schemas)
+	}
+	if schemas.err != nil {
+		return nil, schemas.err
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "rig API",
+			"description": "Dashboard API of rig serve/web. Send an API key as X-API-Key or a bearer token when keys are configured; x-rig-permission is the permission each operation needs.",
+			"version":     "1",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas.schemas,
+			"securitySchemes": map[string]any{
+				"apiKey": map[string]any{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+				"bearer": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		},
+		"security": []any{
+			map[string]any{"apiKey": []string{}},
+			map[string]any{"bearer": []string{}},
+		},
+	}, nil
+}
+
+func openAPIOperation(op Operation, schemas *schemaBuilder) map[string]any {
+	out := map[string]any{}
+	if op.ID != "" {
+		out["operationId"] = op.ID
+	}
+	if op.Summary != "" {
+		out["summary"] = op.Summary
+	}
+	out["tags"] = []string{op.Tag}
+	out["x-rig-permission"] = routePermission(op.Method, op.Path)
+
+	var params []any
+	for _, m := range pathParam.FindAllStringSubmatch(op.Path, -1) {
+		params = append(params, map[string]any{
+			"name": m[1], "in": "path", "required": true, "schema": map[string]any{"type": "string"},
+		})
+	}
+	for _, q := range op.Query {
+		params = append(params, map[string]any{
+			"name": q.Name, "in": "query", "description": q.Description, "schema": kindSchema(q.Kind),
+		})
+	}
+	if len(params) > 0 {
+		out["parameters"] = params
+	}
+
+	if op.Request != nil {
+		out["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{"application/json": map[string]any{"schema": schemas.schema(op.Request)}},
+		}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := map[string]any{"description": http.StatusText(status)}
+	switch {
+	case op.Response != nil:
+		success["content"] = map[string]any{"application/json": map[string]any{"schema": schemas.schema(op.Response)}}
+	case op.ContentType == "application/zip":
+		success["content"] = map[string]any{op.ContentType: map[string]any{"schema": map[string]any{"type": "string", "format": "binary"}}}
+	case op.ContentType != "" && op.ContentType != "application/x-www-form-urlencoded":
+		success["content"] = map[string]any{op.ContentType: map[string]any{"schema": map[string]any{"type": "string"}}}
+	}
+	errResp := map[string]any{
+		"description": "Error",
+		"content":     map[string]any{"application/json": map[string]any{"schema": schemas.schema(typeOf[errorResponse]())}},
+	}
+	out["responses"] = map[string]any{
+		fmt.Sprint(status): success,
+		"default":          errResp,
+	}
+	return out
+}
+
+func kindSchema(k reflect.Kind) map[string]any {
+	switch k {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int:
+		return map[string]any{"type": "integer"}
+	case reflect.Int64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	default:
+		return map[string]any{"type": "string"}
+	}
+}
+
+// schemaBuilder derives JSON schemas from Go types, collecting named structs
+// as components.
+type schemaBuilder struct {
+	schemas map[string]any
+	types   map[string]reflect.Type
+	err     error
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{schemas: map[string]any{}, types: map[string]reflect.Type{}}
+}
+
+var (
+	timeType = typeOf[time.Time]()
+	rawType  = typeOf[json.RawMessage]()
+)
+
+func (b *schemaBuilder) schema(t reflect.Type) map[string]any {
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case rawType:
+		return map[string]any{}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := b.schema(t.Elem())
+		if _, ref := s["$ref"]; ref {
+			return map[string]any{"allOf": []any{s}, "nullable": true}
+		}
+		s["nullable"] = true
+		return s
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint64:
+		// time.Duration is encoded as nanoseconds.
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.object(t)
+		}
+		name := SchemaName(t)
+		if prev, ok := b.types[name]; ok {
+			if prev != t && b.err == nil {
+				b.err = fmt.Errorf("schema name %s is used by both %v and %v", name, prev, t)
+			}
+		} else {
+			b.types[name] = t
+			b.schemas[name] = nil // placeholder for recursive types
+			b.schemas[name] = b.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	default:
+		return map[string]any{}
+	}
+}
+
+// object is the schema of a struct's JSON fields. Embedded structs without
+// a JSON name are flattened like encoding/json does.
+func (b *schemaBuilder) object(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	var required []string
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, omitempty, skip := JSONField(f)
+			if skip {
+				continue
+			}
+			if f.Anonymous && name == "" {
+				ft := f.Type
+				if ft.Kind() == reflect.Pointer {
+					ft = ft.Elem()
+				}
+				if ft.Kind() == reflect.Struct {
+					walk(ft)
+					continue
+				}
+			}
+			if name == "" {
+				name = f.Name
+			}
+			props[name] = b.schema(f.Type)
+			if !omitempty && f.Type.Kind() != reflect.Pointer {
+				required = append(required, name)
+			}
+		}
+	}
+	walk(t)
+	s := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+// JSONField returns the JSON name of a struct field from its tag, whether it
+// is omitempty, and whether encoding/json skips it.
+func JSONField(f reflect.StructField) (name string, omitempty, skip bool) {
+	if !f.IsExported() && !f.Anonymous {
+		return "", false, true
+	}
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+	name, opts, _ := strings.Cut(tag, ",")
+	return name, strings.Contains(","+opts+",", ",omitempty,") || strings.Contains(","+opts+",", ",omitzero,"), false
+}
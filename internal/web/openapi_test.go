@@ -0,0 +1,91 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rigdev/rig/internal/storage"
+)
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	db, err := storage.Open(filepath.Join(t.TempDir(), "rig.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+	handler := NewHandler(writeStateFile(t, testState()), testConfig(), db)
+
+	known := map[string]bool{}
+	for _, op := range Operations() {
+		known[op.Method+" "+op.Path] = true
+	}
+	routed := map[string]bool{}
+	err = chi.Walk(handler.(chi.Routes), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if strings.HasPrefix(route, "/api/") {
+			routed[method+" "+route] = true
+			if !known[method+" "+route] {
+				t.Errorf("route %s %s has no Operation", method, route)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for route := range known {
+		if !routed[route] {
+			t.Errorf("Operation %s is not routed", route)
+		}
+	}
+}
+
+func TestServeOpenAPI(t *testing.T) {
+	handler := NewHandler(writeStateFile(t, testState()), testConfig(), nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var spec struct {
+		OpenAPI string `json:"openapi"`
+		Paths   map[string]map[string]struct {
+			OperationID string `json:"operationId"`
+			Responses   map[string]struct {
+				Content map[string]struct {
+					Schema map[string]any `json:"schema"`
+				} `json:"content"`
+			} `json:"responses"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]any `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Errorf("expected OpenAPI 3, got %q", spec.OpenAPI)
+	}
+
+	getTask := spec.Paths["/api/tasks/{id}"]["get"]
+	if getTask.OperationID != "GetTask" {
+		t.Errorf("expected GetTask, got %+v", getTask)
+	}
+	if ref := getTask.Responses["200"].Content["application/json"].Schema["$ref"]; ref != "#/components/schemas/Task" {
+		t.Errorf("GetTask should return a Task, got %v", ref)
+	}
+	if _, ok := spec.Components.Schemas["Task"].Properties["status"]; !ok {
+		t.Errorf("Task schema is missing status: %+v", spec.Components.Schemas["Task"])
+	}
+	if _, ok := spec.Paths["/api/settings"]; ok {
+		t.Error("settings routes are not mounted without a database and must not be listed")
+	}
+}
@@ -0,0 +1,57 @@
+package web
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/httpserver"
+)
+
+// Defaults for server.limits.
+const (
+	defaultRequestsPerMinute = 120
+	defaultAPIMaxBodyBytes   = 1 << 20
+)
+
+// callerQuota returns middleware that caps API requests per authenticated
+// caller under server.limits.key_requests_per_minute and key_quotas, so one
+// misbehaving integration cannot starve the others. Anonymous requests are
+// left to the per-IP limit.
+func callerQuota(limits config.ServerLimitsConfig) func(http.Handler) http.Handler {
+	if limits.KeyRequestsPerMinute <= 0 && len(limits.KeyQuotas) == 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	var mu sync.Mutex
+	limiters := make(map[string]*httpserver.RateLimiter)
+	limiterFor := func(actor string) *httpserver.RateLimiter {
+		mu.Lock()
+		defer mu.Unlock()
+		if rl, ok := limiters[actor]; ok {
+			return rl
+		}
+		rate := limits.KeyRequestsPerMinute
+		if n, ok := limits.KeyQuotas[actor]; ok {
+			rate = n
+		}
+		rl := httpserver.NewRateLimiter(rate, time.Minute)
+		limiters[actor] = rl
+		return rl
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			actor := requestActor(r)
+			if actor != "anonymous" {
+				if rl := limiterFor(actor); rl != nil {
+					if ok, wait := rl.Allow(actor); !ok {
+						httpserver.TooManyRequests(w, wait, "quota exceeded for "+actor)
+						return
+					}
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/rigdev/rig/internal/storage"
+)
+
+func TestCallerQuota(t *testing.T) {
+	t.Setenv("RIG_API_KEY", "")
+	db, err := storage.Open(filepath.Join(t.TempDir(), "rig.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ciKey, _, err := db.CreateAPIKey("ci", storage.RoleViewer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminKey, _, err := db.CreateAPIKey("root", storage.RoleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := testConfig()
+	cfg.Server.Limits.KeyRequestsPerMinute = 2
+	cfg.Server.Limits.KeyQuotas = map[string]int{"key:root": 0}
+	handler := NewHandler(writeStateFile(t, testState()), cfg, db)
+
+	do := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/tasks", nil)
+		req.Header.Set("X-API-Key", key)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 2; i++ {
+		if rec := do(ciKey); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: got %d", i+1, rec.Code)
+		}
+	}
+	rec := do(ciKey)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("over quota: got %d with Retry-After %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	// key_quotas 0 exempts a caller.
+	for i := 0; i < 3; i++ {
+		if rec := do(adminKey); rec.Code != http.StatusOK {
+			t.Errorf("exempt key request %d: got %d", i+1, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rigdev/rig/internal/storage"
+)
+
+// Permission is what a route requires of the caller's role.
+type Permission string
+
+// Route permissions. Operations without one need PermRead for GET and
+// PermAdmin for everything else.
+const (
+	PermRead    Permission = "read"
+	PermOperate Permission = "operate"
+	PermApprove Permission = "approve"
+	PermAdmin   Permission = "admin"
+)
+
+// roleAllows reports whether role grants p.
+func roleAllows(role storage.Role, p Permission) bool {
+	switch role {
+	case storage.RoleAdmin:
+		return true
+	case storage.RoleApprover:
+		return p == PermRead || p == PermApprove
+	case storage.RoleOperator:
+		return p == PermRead || p == PermOperate
+	case storage.RoleViewer:
+		return p == PermRead
+	}
+	return false
+}
+
+// routePermission returns the permission of the route pattern.
+func routePermission(method, pattern string) Permission {
+	if op, ok := operationIndex[method+" "+pattern]; ok && op.Permission != "" {
+		return op.Permission
+	}
+	if method == http.MethodGet {
+		return PermRead
+	}
+	return PermAdmin
+}
+
+var operationIndex = func() map[string]Operation {
+	index := make(map[string]Operation, len(operations))
+	for _, op := range operations {
+		index[op.Method+" "+op.Path] = op
+	}
+	return index
+}()
+
+// apiKeyAuth authenticates API requests and enforces route permissions.
+//
+// Keys come from the Authorization (Bearer), X-API-Key or api_key query
+// parameter. RIG_API_KEY is an admin key; keys created with rig keys or
+// POST /api/keys have the role they were created with. With dashboard login
+// enabled, a session cookie authenticates as the logged-in user. When there
+// are no keys and no login, the API is open. Authenticated requests carry
+// the actor "api-key", "key:<name>" or "user:<user>" for the audit log.
+func apiKeyAuth(db *storage.DB, routes chi.Routes, l *login) func(http.Handler) http.Handler {
+	envKey := os.Getenv("RIG_API_KEY")
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			open, err := apiOpen(db, envKey, l != nil)
+			if err != nil {
+				writeErrorJSON(w, http.StatusInternalServerError, err)
+				return
+			}
+			if open {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := requestAPIKey(r)
+			var c caller
+			if key == "" {
+				if s := l.session(r); s != nil {
+					c = caller{actor: "user:" + s.User, role: storage.Role(s.Role)}
+				}
+			} else if c, err = keyCaller(db, envKey, key); err != nil {
+				writeErrorJSON(w, http.StatusInternalServerError, err)
+				return
+			}
+			if c.actor == "" {
+				http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+				return
+			}
+
+			rctx := chi.NewRouteContext()
+			if pattern := routes.Find(rctx, r.Method, r.URL.Path); pattern != "" {
+				if c.tenant != "" && !tenantAllows(c.tenant, pattern, rctx.URLParam("tenant")) {
+					writeJSON(w, http.StatusForbidden, map[string]string{
+						"error": "forbidden: the key is limited to tenant " + c.tenant,
+					})
+					return
+				}
+				if perm := routePermission(r.Method, pattern); !roleAllows(c.role, perm) {
+					writeJSON(w, http.StatusForbidden, map[string]string{
+						"error": "forbidden: role " + string(c.role) + " lacks the " + string(perm) + " permission",
+					})
+					return
+				}
+			}
+			next.ServeHTTP(w, withCaller(r, c))
+		})
+	}
+}
+
+// apiOpen reports whether the API takes requests without credentials:
+// when there is no RIG_API_KEY, no stored key and no dashboard login.
+func apiOpen(db *storage.DB, envKey string, login bool) (bool, error) {
+	if envKey != "" || login {
+		return false, nil
+	}
+	if db == nil {
+		return true, nil
+	}
+	hasKeys, err := db.HasAPIKeys()
+	return !hasKeys, err
+}
+
+// keyCaller returns the caller of an API key, or one without an actor for
+// a key that is not valid.
+func keyCaller(db *storage.DB, envKey, key string) (caller, error) {
+	if envKey != "" && key == envKey {
+		return caller{actor: "api-key", role: storage.RoleAdmin}, nil
+	}
+	if db == nil {
+		return caller{}, nil
+	}
+	k, err := db.LookupAPIKey(key)
+	if err != nil || k == nil {
+		return caller{}, err
+	}
+	return caller{actor: "key:" + k.Name, role: k.Role, tenant: k.Tenant}, nil
+}
+
+// requestAPIKey returns the key sent with r, if any.
+func requestAPIKey(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	// Query param for SSE/browser convenience.
+	return r.URL.Query().Get("api_key")
+}
+
+// --- Key management API ---
+
+type createAPIKeyRequest struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+	// Tenant limits the key to the API of that tenant. Under
+	// /api/t/{tenant}/ keys are always limited to the tenant.
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// createAPIKeyResponse carries the only copy of the new key.
+type createAPIKeyResponse struct {
+	Name   string `json:"name"`
+	Role   string `json:"role"`
+	Prefix string `json:"prefix"`
+	Tenant string `json:"tenant,omitempty"`
+	Key    string `json:"key"`
+}
+
+// handleListAPIKeys lists all keys, or those of the tenant scope when it
+// is set.
+func handleListAPIKeys(db *storage.DB, scope string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		keys, err := scopedAPIKeys(db, scope)
+		if err != nil {
+			writeErrorJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, keys)
+	}
+}
+
+// scopedAPIKeys returns all keys, or those of the tenant scope when it is
+// set.
+func scopedAPIKeys(db *storage.DB, scope string) ([]storage.APIKey, error) {
+	keys, err := db.ListAPIKeys()
+	if err != nil || scope == "" {
+		return keys, err
+	}
+	return slices.DeleteFunc(keys, func(k storage.APIKey) bool { return k.Tenant != scope }), nil
+}
+
+// handleCreateAPIKey creates a key, limited to the tenant scope when it is
+// set, or else to the one of tenants the request names, if any.
+func handleCreateAPIKey(db *storage.DB, audit *auditor, scope string, tenants []string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req createAPIKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+			return
+		}
+		if strings.TrimSpace(req.Name) == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "name is required"})
+			return
+		}
+		role, err := storage.ParseRole(req.Role)
+		if err != nil {
+			writeErrorJSON(w, http.StatusBadRequest, err)
+			return
+		}
+		switch {
+		case scope != "" && req.Tenant != "" && req.Tenant != scope:
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "keys created here belong to tenant " + scope})
+			return
+		case scope != "":
+			req.Tenant = scope
+		case req.Tenant != "" && !slices.Contains(tenants, req.Tenant):
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unknown tenant " + req.Tenant})
+			return
+		}
+		key, k, err := db.CreateTenantAPIKey(req.Name, role, req.Tenant)
+		if errors.Is(err, storage.ErrAPIKeyExists) {
+			writeErrorJSON(w, http.StatusConflict, err)
+			return
+		}
+		if err != nil {
+			writeErrorJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+		audit.record(r, storage.AuditKeyCreated, k.Name, keyDetails(k))
+
+		writeJSON(w, http.StatusCreated, createAPIKeyResponse{Name: k.Name, Role: string(k.Role), Prefix: k.Prefix, Tenant: k.Tenant, Key: key})
+	}
+}
+
+// handleDeleteAPIKey revokes a key; with a tenant scope, only one of the
+// tenant.
+func handleDeleteAPIKey(db *storage.DB, audit *auditor, scope string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "name")
+		if scope != "" {
+			keys, err := scopedAPIKeys(db, scope)
+			if err != nil {
+				writeErrorJSON(w, http.StatusInternalServerError, err)
+				return
+			}
+			if !slices.ContainsFunc(keys, func(k storage.APIKey) bool { return k.Name == name }) {
+				writeErrorJSON(w, http.StatusNotFound, fmt.Errorf("%w: %s", storage.ErrAPIKeyNotFound, name))
+				return
+			}
+		}
+		if err := db.DeleteAPIKey(name); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, storage.ErrAPIKeyNotFound) {
+				status = http.StatusNotFound
+			}
+			writeErrorJSON(w, status, err)
+			return
+		}
+		audit.record(r, storage.AuditKeyDeleted, name, "")
+
+		writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+	}
+}
+
+// keyDetails is the audit detail of a created key: its role, and tenant.
+func keyDetails(k *storage.APIKey) string {
+	if k.Tenant != "" {
+		return string(k.Role) + ", tenant " + k.Tenant
+	}
+	return string(k.Role)
+}
@@ -0,0 +1,137 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/storage"
+)
+
+func TestAPIKeyRoles(t *testing.T) {
+	t.Setenv("RIG_API_KEY", "")
+	state := testState()
+	state.Tasks[1].Status = core.PhaseAwaitingApproval
+	state.Tasks[1].AddProposal(core.ProposalDeployApproval, "Deploy?", "approval required", nil)
+	statePath := writeStateFile(t, state)
+	db, err := storage.Open(filepath.Join(t.TempDir(), "rig.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	handler := NewHandler(statePath, testConfig(), db)
+
+	do := func(method, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Without keys the API is open, so the first admin key can be created.
+	rec := do(http.MethodPost, "/api/keys", "", `{"name":"root","role":"admin"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create first key: %d %s", rec.Code, rec.Body.String())
+	}
+	var created createAPIKeyResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	admin := created.Key
+
+	keys := map[storage.Role]string{storage.RoleAdmin: admin}
+	for _, role := range []storage.Role{storage.RoleViewer, storage.RoleOperator, storage.RoleApprover} {
+		rec := do(http.MethodPost, "/api/keys", admin, `{"name":"`+string(role)+`","role":"`+string(role)+`"}`)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create %s key: %d %s", role, rec.Code, rec.Body.String())
+		}
+		json.Unmarshal(rec.Body.Bytes(), &created)
+		keys[role] = created.Key
+	}
+
+	tests := []struct {
+		name   string
+		role   storage.Role
+		method string
+		path   string
+		want   int
+	}{
+		{"viewer reads tasks", storage.RoleViewer, http.MethodGet, "/api/tasks", http.StatusOK},
+		{"viewer cannot stop", storage.RoleViewer, http.MethodPost, "/api/tasks/task-001/stop", http.StatusForbidden},
+		{"viewer cannot read settings", storage.RoleViewer, http.MethodGet, "/api/settings", http.StatusForbidden},
+		{"operator cannot approve", storage.RoleOperator, http.MethodPost, "/api/approve/task-002", http.StatusForbidden},
+		{"approver cannot stop", storage.RoleApprover, http.MethodPost, "/api/tasks/task-002/stop", http.StatusForbidden},
+		{"viewer cannot approve via the editor API", storage.RoleViewer, http.MethodPost, "/api/editor/tasks/task-002/approve", http.StatusForbidden},
+		{"approver approves", storage.RoleApprover, http.MethodPost, "/api/approve/task-002", http.StatusOK},
+		{"operator passes the stop check", storage.RoleOperator, http.MethodPost, "/api/tasks/task-001/stop", http.StatusBadRequest},
+		{"operator cannot manage keys", storage.RoleOperator, http.MethodGet, "/api/keys", http.StatusForbidden},
+		{"admin reads audit", storage.RoleAdmin, http.MethodGet, "/api/audit", http.StatusOK},
+		{"viewer cannot read a webhook payload", storage.RoleViewer, http.MethodGet, "/api/webhooks/1", http.StatusForbidden},
+		{"operator cannot read a webhook payload", storage.RoleOperator, http.MethodGet, "/api/webhooks/1", http.StatusForbidden},
+		{"admin reads a webhook delivery", storage.RoleAdmin, http.MethodGet, "/api/webhooks/1", http.StatusNotFound},
+		{"viewer cannot summarize a task", storage.RoleViewer, http.MethodGet, "/api/tasks/task-001/summary", http.StatusForbidden},
+		{"viewer cannot clear the AI cache", storage.RoleViewer, http.MethodDelete, "/api/ai-cache", http.StatusForbidden},
+		{"operator cannot clear the AI cache", storage.RoleOperator, http.MethodDelete, "/api/ai-cache", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := do(tt.method, tt.path, keys[tt.role], ""); rec.Code != tt.want {
+				t.Errorf("%s %s as %s: got %d, want %d: %s", tt.method, tt.path, tt.role, rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+
+	if rec := do(http.MethodGet, "/api/tasks", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a key once keys exist, got %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/api/tasks", "rig_wrong", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for an unknown key, got %d", rec.Code)
+	}
+
+	entries, err := db.ListAudit(storage.AuditFilter{Action: storage.AuditProposalApproved})
+	if err != nil || len(entries) != 1 || entries[0].Actor != "key:approver" {
+		t.Errorf("expected the approval audited as key:approver, got %+v %v", entries, err)
+	}
+
+	if rec := do(http.MethodDelete, "/api/keys/viewer", admin, ""); rec.Code != http.StatusOK {
+		t.Fatalf("delete key: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodGet, "/api/tasks", keys[storage.RoleViewer], ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("revoked key still works: %d", rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/api/keys/viewer", admin, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 deleting a missing key, got %d", rec.Code)
+	}
+}
+
+func TestEnvAPIKeyIsAdmin(t *testing.T) {
+	t.Setenv("RIG_API_KEY", "k3y")
+	db, err := storage.Open(filepath.Join(t.TempDir(), "rig.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, _, err := db.CreateAPIKey("viewer", storage.RoleViewer); err != nil {
+		t.Fatal(err)
+	}
+	handler := NewHandler(writeStateFile(t, testState()), testConfig(), db)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/keys", nil)
+	req.Header.Set("Authorization", "Bearer k3y")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected RIG_API_KEY to manage keys, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var keys []storage.APIKey
+	if err := json.Unmarshal(rec.Body.Bytes(), &keys); err != nil || len(keys) != 1 || keys[0].Role != storage.RoleViewer {
+		t.Errorf("unexpected keys %s", rec.Body.String())
+	}
+}
@@ -0,0 +1,47 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/metrics"
+)
+
+// handleGetTaskReport summarizes the tasks created between the from and to
+// query parameters, as JSON or, with format=csv, as a CSV download.
+func handleGetTaskReport(statePath string, current ConfigFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		from, to, err := metrics.ReportPeriod(q.Get("from"), q.Get("to"), time.Now().UTC())
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		format := q.Get("format")
+		if format != "" && format != "json" && format != "csv" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "format must be json or csv"})
+			return
+		}
+
+		state, err := core.LoadState(statePath)
+		if err != nil {
+			writeErrorJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+		cfg := current()
+		report := metrics.BuildTaskReport(state.Tasks, from, to, cfg.AI.Pricing, cfg.AI.Model)
+
+		if format != "csv" {
+			writeJSON(w, http.StatusOK, report)
+			return
+		}
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="rig-tasks-%s-%s.csv"`,
+			from.Format(time.DateOnly), to.Format(time.DateOnly)))
+		if err := report.WriteCSV(w); err != nil {
+			writeErrorJSON(w, http.StatusInternalServerError, err)
+		}
+	}
+}
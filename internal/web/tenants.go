@@ -0,0 +1,191 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/storage"
+	"github.com/rigdev/rig/internal/tenant"
+)
+
+// Tenant is a tenant of rig serve with the callbacks that run its tasks.
+type Tenant struct {
+	*tenant.Tenant
+	Execute ExecuteFunc
+	Resume  ResumeFunc
+	Rerun   RerunFunc
+}
+
+// Tenants are served under /api/t/{tenant}/, each with the task routes of
+// /api/ on its own state and config, and its own API keys.
+type Tenants []Tenant
+
+func (t Tenants) applyTo(cb *handlerCallbacks) { cb.tenants = t }
+
+func (t Tenants) names() []string {
+	names := make([]string, len(t))
+	for i := range t {
+		names[i] = t[i].Name
+	}
+	return names
+}
+
+// tenantPrefix is the route prefix of the tenant APIs.
+const tenantPrefix = "/api/t/{tenant}"
+
+// tenantScopes are the first path segments under /api/ whose routes every
+// tenant has under /api/t/{tenant}/ too.
+var tenantScopes = []string{
+	"analytics", "approve", "config", "editor", "events", "keys", "metrics", "projects",
+	"proposals", "reject", "reports", "tasks", "workspaces",
+}
+
+// tenantScoped reports whether path, under /api/, has a tenant route.
+func tenantScoped(path string) bool {
+	rest, ok := strings.CutPrefix(path, "/api/")
+	if !ok {
+		return false
+	}
+	first, _, _ := strings.Cut(rest, "/")
+	return slices.Contains(tenantScopes, first)
+}
+
+// withTenantOperations appends the operations of the tenant routes to ops:
+// a copy of each tenant scoped one, left out of the generated client, which
+// reaches them with client.WithTenant.
+func withTenantOperations(ops []Operation) []Operation {
+	all := slices.Clone(ops)
+	for _, op := range ops {
+		if !tenantScoped(op.Path) {
+			continue
+		}
+		op.Path = tenantPrefix + strings.TrimPrefix(op.Path, "/api")
+		op.ID = "Tenant" + op.ID
+		op.Tag = "tenants"
+		op.NoClient = true
+		all = append(all, op)
+	}
+	return all
+}
+
+// tenantAllows reports whether a key limited to keyTenant may call the
+// route pattern of a request for the tenant named in its path, if any.
+func tenantAllows(keyTenant, pattern, pathTenant string) bool {
+	if strings.HasPrefix(pattern, tenantPrefix+"/") {
+		return pathTenant == keyTenant
+	}
+	switch pattern {
+	case "/api/auth/me", "/api/openapi.json", "/api/tenants":
+		return true
+	}
+	return false
+}
+
+// tenantRouter dispatches /api/t/{tenant}/ to the routes of each tenant.
+// All tenants have the same routes, so a stand-in router answers route
+// lookups, for permissions and the OpenAPI document.
+type tenantRouter struct {
+	chi.Router
+	tenants map[string]http.Handler
+}
+
+// newTenantRouter builds the routes of each tenant after those of api.
+func newTenantRouter(api taskAPI, tenants Tenants, db *storage.DB, audit *auditor) *tenantRouter {
+	build := func(api taskAPI) chi.Router {
+		r := chi.NewRouter()
+		api.routes(r, db, audit)
+		if db != nil {
+			r.Get("/keys", handleListAPIKeys(db, api.tenant.Name))
+			r.Post("/keys", handleCreateAPIKey(db, audit, api.tenant.Name, nil))
+			r.Delete("/keys/{name}", handleDeleteAPIKey(db, audit, api.tenant.Name))
+		}
+		return r
+	}
+
+	stand := api
+	stand.tenant = &tenant.Tenant{}
+	tr := &tenantRouter{Router: build(stand), tenants: make(map[string]http.Handler, len(tenants))}
+	for _, t := range tenants {
+		var resume *resumer
+		if t.Resume != nil {
+			resume = &resumer{fn: t.Resume}
+		}
+		tr.tenants[t.Name] = build(taskAPI{
+			statePath: t.StatePath,
+			current:   t.Config,
+			execute:   t.Execute,
+			resume:    resume,
+			rerun:     t.Rerun,
+			tenant:    t.Tenant,
+		})
+	}
+	return tr
+}
+
+func (tr *tenantRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h, ok := tr.tenants[chi.URLParam(r, "tenant")]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "tenant not found"})
+		return
+	}
+	h.ServeHTTP(w, r)
+}
+
+// admit refuses new tasks of a tenant that has spent its AI budget.
+func (a taskAPI) admit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := a.tenant.CheckBudget(time.Now()); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, tenant.ErrBudgetExceeded) {
+				status = http.StatusTooManyRequests
+			}
+			writeErrorJSON(w, status, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireTask answers 404 for tasks that are not in the state at statePath.
+func requireTask(statePath string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			state, err := core.LoadState(statePath)
+			if err != nil {
+				writeErrorJSON(w, http.StatusInternalServerError, err)
+				return
+			}
+			if state.GetTaskByID(chi.URLParam(r, "id")) == nil {
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": "task not found"})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// handleListTenants lists the tenants with their quota use; a key limited
+// to a tenant sees that one only.
+func handleListTenants(tenants Tenants) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		only := requestTenant(r)
+		usage := []tenant.Usage{}
+		for _, t := range tenants {
+			if only != "" && t.Name != only {
+				continue
+			}
+			u, err := t.Usage(time.Now())
+			if err != nil {
+				writeErrorJSON(w, http.StatusInternalServerError, err)
+				return
+			}
+			usage = append(usage, u)
+		}
+		writeJSON(w, http.StatusOK, usage)
+	}
+}
@@ -0,0 +1,125 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/storage"
+	"github.com/rigdev/rig/internal/tenant"
+)
+
+// testTenants loads the tenants payments, with a $1 AI budget, and search
+// of a rig serve whose state is at statePath.
+func testTenants(t *testing.T, statePath string) Tenants {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := &config.Config{}
+	for _, name := range []string{"payments", "search"} {
+		yaml := `project: { name: ` + name + ` }
+source: { platform: github, repo: acme/` + name + `, base_branch: main }
+ai:
+  provider: anthropic
+  model: claude-sonnet
+  pricing: { claude-sonnet: { input: 3, output: 15 } }
+deploy: { method: custom, config: { commands: [{ name: deploy, run: "true" }] } }
+`
+		if err := os.WriteFile(filepath.Join(dir, name+".yaml"), []byte(yaml), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		cfg.Tenants = append(cfg.Tenants, config.TenantConfig{Name: name, Config: name + ".yaml"})
+	}
+	cfg.Tenants[0].Quota.AIBudgetUSD = 1
+	loaded, err := tenant.Load(cfg, dir, statePath)
+	if err != nil {
+		t.Fatalf("load tenants: %v", err)
+	}
+	var tenants Tenants
+	for _, tn := range loaded {
+		tenants = append(tenants, Tenant{Tenant: tn})
+	}
+	return tenants
+}
+
+func TestTenantRoutes(t *testing.T) {
+	t.Setenv("RIG_API_KEY", "root")
+	statePath := writeStateFile(t, testState())
+	tenants := testTenants(t, statePath)
+	db, err := storage.Open(filepath.Join(t.TempDir(), "rig.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	handler := NewHandler(statePath, testConfig(), db, tenants)
+
+	do := func(method, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-API-Key", key)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/api/t/payments/tasks", "root", `{"project":"acme/payments","issue_num":"3"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create tenant task: %d %s", rec.Code, rec.Body.String())
+	}
+	state, err := core.LoadState(tenants[0].StatePath)
+	if err != nil || len(state.Tasks) != 1 || state.Tasks[0].Issue.Repo != "acme/payments" {
+		t.Fatalf("expected the task in the tenant state, got %+v %v", state, err)
+	}
+	if rec := do(http.MethodPost, "/api/t/search/tasks", "root", `{"project":"acme/payments","issue_num":"3"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected another tenant's project to be unknown, got %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/api/t/nope/tasks", "root", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown tenant, got %d", rec.Code)
+	}
+	var tasks []core.Task
+	json.Unmarshal(do(http.MethodGet, "/api/tasks", "root", "").Body.Bytes(), &tasks)
+	if len(tasks) != len(testState().Tasks) {
+		t.Errorf("expected the top-level tasks only, got %d", len(tasks))
+	}
+
+	// A tenant key reaches its tenant only.
+	rec = do(http.MethodPost, "/api/keys", "root", `{"name":"pay-ci","role":"operator","tenant":"payments"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create tenant key: %d %s", rec.Code, rec.Body.String())
+	}
+	var created createAPIKeyResponse
+	json.Unmarshal(rec.Body.Bytes(), &created)
+	for path, want := range map[string]int{
+		"/api/t/payments/tasks": http.StatusOK,
+		"/api/t/search/tasks":   http.StatusForbidden,
+		"/api/tasks":            http.StatusForbidden,
+		"/api/tenants":          http.StatusOK,
+	} {
+		if rec := do(http.MethodGet, path, created.Key, ""); rec.Code != want {
+			t.Errorf("GET %s with a tenant key: expected %d, got %d", path, want, rec.Code)
+		}
+	}
+	var usage []tenant.Usage
+	json.Unmarshal(do(http.MethodGet, "/api/tenants", created.Key, "").Body.Bytes(), &usage)
+	if len(usage) != 1 || usage[0].Name != "payments" {
+		t.Errorf("expected the key's tenant only, got %+v", usage)
+	}
+	if rec := do(http.MethodPost, "/api/keys", "root", `{"name":"x","role":"viewer","tenant":"nope"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected an unknown tenant to be refused, got %d", rec.Code)
+	}
+
+	// New tasks are refused once the AI budget is spent.
+	state.Tasks[0].AIUsage = &core.AIUsage{InputTokens: 400_000}
+	state.Tasks[0].CreatedAt = time.Now()
+	if err := core.SaveState(state, tenants[0].StatePath); err != nil {
+		t.Fatal(err)
+	}
+	if rec := do(http.MethodPost, "/api/t/payments/tasks", created.Key, `{"project":"acme/payments","issue_num":"4"}`); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected 429 over budget, got %d %s", rec.Code, rec.Body.String())
+	}
+}
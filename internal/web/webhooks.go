@@ -0,0 +1,94 @@
+package web
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rigdev/rig/internal/storage"
+)
+
+// handleListDeliveries lists stored webhook deliveries, newest first,
+// without payloads.
+func handleListDeliveries(db *storage.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		filter := storage.DeliveryFilter{Status: q.Get("status")}
+		if s := q.Get("limit"); s != "" {
+			limit, err := strconv.Atoi(s)
+			if err != nil || limit < 0 {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "limit must be a non-negative integer"})
+				return
+			}
+			filter.Limit = limit
+		}
+		list, err := db.ListDeliveries(filter)
+		if err != nil {
+			writeErrorJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, list)
+	}
+}
+
+func handleGetDelivery(db *storage.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := deliveryID(w, r)
+		if !ok {
+			return
+		}
+		d, err := db.GetDelivery(id)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, storage.ErrDeliveryNotFound) {
+				status = http.StatusNotFound
+			}
+			writeErrorJSON(w, status, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, d)
+	}
+}
+
+// handleReplayDelivery processes a stored delivery again in the background;
+// its history entry shows the outcome. Without a ReplayFunc (rig web) it
+// answers 503.
+func handleReplayDelivery(db *storage.DB, replay ReplayFunc, audit *auditor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if replay == nil {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "webhook replay needs rig serve"})
+			return
+		}
+		id, ok := deliveryID(w, r)
+		if !ok {
+			return
+		}
+		if _, err := db.GetDelivery(id); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, storage.ErrDeliveryNotFound) {
+				status = http.StatusNotFound
+			}
+			writeErrorJSON(w, status, err)
+			return
+		}
+		go func() {
+			if err := replay(id); err != nil {
+				slog.Warn("web: webhook replay failed", "delivery", id, "err", err)
+			}
+		}()
+		audit.record(r, storage.AuditWebhookReplayed, strconv.FormatInt(id, 10), "")
+
+		writeJSON(w, http.StatusAccepted, actionResponse{Status: "replaying", Message: "delivery " + strconv.FormatInt(id, 10) + " is being processed again"})
+	}
+}
+
+func deliveryID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid delivery ID"})
+		return 0, false
+	}
+	return id, true
+}
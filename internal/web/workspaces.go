@@ -0,0 +1,75 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+
+	adaptergit "github.com/rigdev/rig/internal/adapter/git"
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/storage"
+)
+
+// workspaceGCResponse lists the task worktrees a garbage collection removed.
+type workspaceGCResponse struct {
+	Removed    []adaptergit.Workspace `json:"removed"`
+	FreedBytes int64                  `json:"freed_bytes"`
+}
+
+// handleListWorkspaces lists the repository clones and task worktrees
+// under source.workspaces.root with their disk usage.
+func handleListWorkspaces(current ConfigFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ws, err := workspacesOf(current())
+		if err != nil {
+			writeErrorJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+		list, err := ws.List()
+		if err != nil {
+			writeErrorJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+		if list == nil {
+			list = []adaptergit.Workspace{}
+		}
+		writeJSON(w, http.StatusOK, list)
+	}
+}
+
+// handleCollectWorkspaces removes the task worktrees no running or waiting
+// task needs.
+func handleCollectWorkspaces(statePath string, current ConfigFunc, audit *auditor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ws, err := workspacesOf(current())
+		if err != nil {
+			writeErrorJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+		state, err := core.LoadState(statePath)
+		if err != nil {
+			writeErrorJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+		removed, err := ws.GC(r.Context(), state.NeedsWorkspace)
+		resp := workspaceGCResponse{Removed: removed}
+		if resp.Removed == nil {
+			resp.Removed = []adaptergit.Workspace{}
+		}
+		for _, rm := range removed {
+			resp.FreedBytes += rm.SizeBytes
+		}
+		if len(removed) > 0 {
+			audit.record(r, storage.AuditWorkspacesCollected, ws.Root(), fmt.Sprintf("removed %d worktrees", len(removed)))
+		}
+		if err != nil {
+			writeErrorJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+func workspacesOf(cfg *config.Config) (*adaptergit.Workspaces, error) {
+	return adaptergit.NewWorkspaces(cfg.Source.Workspaces.Root, cfg.Source.Workspaces.MaxBytes)
+}
@@ -0,0 +1,134 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+)
+
+// Webhook platforms.
+const (
+	PlatformGitHub = "github"
+	PlatformGitLab = "gitlab"
+)
+
+// defaultPath is the endpoint served when server.webhooks is empty.
+const defaultPath = "/webhook"
+
+// Endpoint is one webhook receiver: where it is served, how its payloads
+// are read and verified, and which repository it accepts events for.
+type Endpoint struct {
+	Path     string
+	Platform string
+	Secret   string
+	// Repo, when set, is the only owner/repo whose events are accepted.
+	Repo string
+	// Host is the web host of the self-hosted instance the endpoint takes
+	// events from, such as a GitHub Enterprise Server; empty for the hosted
+	// platform. GitHub Enterprise deliveries from another host are ignored.
+	Host string
+}
+
+// Endpoints resolves server.webhooks against the projects of cfg. Without
+// server.webhooks it is the single GitHub endpoint at /webhook with
+// server.secret.
+func Endpoints(cfg *config.Config) []Endpoint {
+	hosts := core.NewIssueHosts(cfg)
+	if len(cfg.Server.Webhooks) == 0 {
+		return []Endpoint{{Path: defaultPath, Platform: PlatformGitHub, Secret: cfg.Server.Secret, Host: hosts.SelfHosted(PlatformGitHub)}}
+	}
+	eps := make([]Endpoint, 0, len(cfg.Server.Webhooks))
+	for _, w := range cfg.Server.Webhooks {
+		ep := Endpoint{Path: w.Path, Platform: w.Platform, Secret: w.Secret}
+		if ep.Secret == "" {
+			ep.Secret = cfg.Server.Secret
+		}
+		if w.Project != "" {
+			if p := cfg.FindProject(w.Project); p != nil {
+				ep.Repo = p.Repo
+				ep.Host = strings.ToLower(p.Host)
+				if ep.Platform == "" {
+					ep.Platform = p.Platform
+				}
+			}
+		}
+		if ep.Platform == "" {
+			ep.Platform = PlatformGitHub
+		}
+		if ep.Host == "" {
+			ep.Host = hosts.SelfHosted(ep.Platform)
+		}
+		eps = append(eps, ep)
+	}
+	return eps
+}
+
+// enterpriseHost is the header GitHub Enterprise Server names itself in.
+const enterpriseHost = "X-GitHub-Enterprise-Host"
+
+// acceptsHost reports whether a delivery sent by the GitHub Enterprise
+// Server host (empty for github.com) is for the endpoint.
+func (ep Endpoint) acceptsHost(host string) bool {
+	if ep.Host == "" || host == "" {
+		return true
+	}
+	name, _, _ := strings.Cut(ep.Host, ":")
+	return strings.EqualFold(host, ep.Host) || strings.EqualFold(host, name)
+}
+
+// eventType returns the event header of the endpoint's platform.
+func (ep Endpoint) eventType(r *http.Request) string {
+	if ep.Platform == PlatformGitLab {
+		return r.Header.Get("X-Gitlab-Event")
+	}
+	return r.Header.Get("X-GitHub-Event")
+}
+
+// deliveryID returns the platform's delivery GUID.
+func (ep Endpoint) deliveryID(r *http.Request) string {
+	if ep.Platform == PlatformGitLab {
+		return r.Header.Get("X-Gitlab-Event-UUID")
+	}
+	return r.Header.Get("X-GitHub-Delivery")
+}
+
+// verify checks the request against the endpoint secret: the HMAC-SHA256
+// signature for GitHub, the shared token for GitLab.
+func (ep Endpoint) verify(r *http.Request, body []byte) bool {
+	if ep.Secret == "" {
+		slog.Warn("webhook: no webhook secret configured, rejecting request for safety", "path", ep.Path)
+		return false // Reject if no secret configured — require explicit opt-in.
+	}
+	if ep.Platform == PlatformGitLab {
+		token := r.Header.Get("X-Gitlab-Token")
+		return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(ep.Secret)) == 1
+	}
+	return verifySignature(ep.Secret, body, r.Header.Get("X-Hub-Signature-256"))
+}
+
+// verifySignature checks the HMAC-SHA256 signature from GitHub.
+func verifySignature(secret string, body []byte, signature string) bool {
+	if signature == "" {
+		return false
+	}
+
+	// GitHub sends "sha256=<hex>".
+	prefix := "sha256="
+	if !strings.HasPrefix(signature, prefix) {
+		return false
+	}
+	sigHex := signature[len(prefix):]
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+
+	return hmac.Equal([]byte(sigHex), []byte(expected))
+}
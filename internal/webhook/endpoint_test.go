@@ -0,0 +1,228 @@
+package webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+)
+
+const gitlabIssuePayload = `{
+  "object_kind": "issue",
+  "user": {"username": "alice"},
+  "project": {"path_with_namespace": "ops/infra"},
+  "object_attributes": {"iid": 12, "title": "Fix DNS", "description": "it is always DNS",
+    "url": "https://gitlab.example.com/ops/infra/-/issues/12", "action": "update"},
+  "labels": [{"title": "bug"}, {"title": "rig"}],
+  "changes": {"labels": {"previous": [{"title": "bug"}], "current": [{"title": "bug"}, {"title": "rig"}]}}
+}`
+
+const gitlabNotePayload = `{
+  "object_kind": "note",
+  "user": {"username": "bob"},
+  "project": {"path_with_namespace": "ops/infra"},
+  "object_attributes": {"note": "/rig please", "noteable_type": "Issue",
+    "url": "https://gitlab.example.com/ops/infra/-/issues/12#note_99"},
+  "issue": {"iid": 12, "title": "Fix DNS", "description": "", "labels": []}
+}`
+
+func TestEndpoints(t *testing.T) {
+	cfg := &config.Config{
+		Source:   config.SourceConfig{Platform: "github", Repo: "org/app"},
+		Server:   config.ServerConfig{Secret: "shared"},
+		Projects: []config.ProjectEntry{{Name: "infra", Platform: "gitlab", Repo: "ops/infra"}},
+	}
+	if eps := Endpoints(cfg); len(eps) != 1 || eps[0] != (Endpoint{Path: "/webhook", Platform: "github", Secret: "shared"}) {
+		t.Errorf("expected the default endpoint, got %+v", eps)
+	}
+
+	cfg.Server.Webhooks = []config.WebhookEndpointConfig{
+		{Path: "/webhook/app", Project: "org/app", Secret: "own"},
+		{Path: "/webhook/infra", Project: "infra"},
+	}
+	want := []Endpoint{
+		{Path: "/webhook/app", Platform: "github", Secret: "own", Repo: "org/app"},
+		{Path: "/webhook/infra", Platform: "gitlab", Secret: "shared", Repo: "ops/infra"},
+	}
+	eps := Endpoints(cfg)
+	if len(eps) != len(want) {
+		t.Fatalf("expected %d endpoints, got %+v", len(want), eps)
+	}
+	for i := range want {
+		if eps[i] != want[i] {
+			t.Errorf("endpoint %d: got %+v, want %+v", i, eps[i], want[i])
+		}
+	}
+}
+
+func TestParseGitLabEvent(t *testing.T) {
+	event, err := parseGitLabEvent([]byte(gitlabIssuePayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Kind != "issues" || event.Action != "labeled" || event.IssueNumber != 12 ||
+		event.RepoFullName != "ops/infra" || event.Sender != "alice" || len(event.IssueLabels) != 2 {
+		t.Errorf("unexpected issue event %+v", event)
+	}
+
+	event, err = parseGitLabEvent([]byte(gitlabNotePayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Kind != "issue_comment" || event.Action != "created" || event.CommentBody != "/rig please" ||
+		event.IssueURL != "https://gitlab.example.com/ops/infra/-/issues/12" {
+		t.Errorf("unexpected note event %+v", event)
+	}
+}
+
+func TestServerMultipleEndpoints(t *testing.T) {
+	var got []core.Issue
+	handler := NewHandler("", []config.TriggerConfig{{Event: "issues.labeled", Labels: []string{"rig"}}}, filepath.Join(t.TempDir(), "state.json"), func(issue core.Issue) error {
+		got = append(got, issue)
+		return nil
+	})
+	handler.SetEndpoints([]Endpoint{
+		{Path: "/webhook/app", Platform: "github", Secret: "gh-secret", Repo: "org/app"},
+		{Path: "/webhook/gitlab", Platform: "gitlab", Secret: "gl-token"},
+	})
+	ts := httptest.NewServer(NewServer(config.ServerConfig{}, handler).Router())
+	defer ts.Close()
+
+	post := func(path string, body []byte, headers map[string]string) int {
+		t.Helper()
+		req, _ := http.NewRequest("POST", ts.URL+path, strings.NewReader(string(body)))
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	github := func(secret string, body []byte) map[string]string {
+		return map[string]string{"X-GitHub-Event": "issues", "X-Hub-Signature-256": signPayload(secret, body)}
+	}
+
+	appPayload := makeIssuePayload("labeled", 7, "Add feature", []string{"rig"}, "org/app")
+	otherPayload := makeIssuePayload("labeled", 8, "Other", []string{"rig"}, "org/other")
+	gitlab := map[string]string{"X-Gitlab-Event": "Issue Hook", "X-Gitlab-Token": "gl-token"}
+
+	tests := []struct {
+		name    string
+		path    string
+		body    []byte
+		headers map[string]string
+		want    int
+	}{
+		{"github endpoint", "/webhook/app", appPayload, github("gh-secret", appPayload), http.StatusAccepted},
+		{"other endpoint's secret", "/webhook/app", appPayload, github("gl-token", appPayload), http.StatusUnauthorized},
+		{"repo outside the project", "/webhook/app", otherPayload, github("gh-secret", otherPayload), http.StatusOK},
+		{"gitlab endpoint", "/webhook/gitlab", []byte(gitlabIssuePayload), gitlab, http.StatusAccepted},
+		{"wrong gitlab token", "/webhook/gitlab", []byte(gitlabIssuePayload), map[string]string{"X-Gitlab-Event": "Issue Hook", "X-Gitlab-Token": "nope"}, http.StatusUnauthorized},
+		{"default path not served", "/webhook", appPayload, github("gh-secret", appPayload), http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if status := post(tt.path, tt.body, tt.headers); status != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, status)
+		}
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("expected 2 accepted issues, got %+v", got)
+	}
+	if got[0].Platform != "github" || got[0].Repo != "org/app" || got[0].ID != "7" {
+		t.Errorf("unexpected github issue %+v", got[0])
+	}
+	if got[1].Platform != "gitlab" || got[1].Repo != "ops/infra" || got[1].ID != "12" ||
+		got[1].URL != "https://gitlab.example.com/ops/infra/-/issues/12" {
+		t.Errorf("unexpected gitlab issue %+v", got[1])
+	}
+}
+
+func TestServerServesReplacedEndpoints(t *testing.T) {
+	handler := NewHandler("old-secret", nil, filepath.Join(t.TempDir(), "state.json"), nil)
+	ts := httptest.NewServer(NewServer(config.ServerConfig{}, handler).Router())
+	defer ts.Close()
+
+	payload := makeIssuePayload("opened", 3, "Reload", nil, "org/app")
+	post := func(path, secret string) int {
+		t.Helper()
+		req, _ := http.NewRequest("POST", ts.URL+path, strings.NewReader(string(payload)))
+		req.Header.Set("X-GitHub-Event", "issues")
+		req.Header.Set("X-Hub-Signature-256", signPayload(secret, payload))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if status := post("/webhook", "old-secret"); status != http.StatusAccepted {
+		t.Fatalf("expected 202 before the reload, got %d", status)
+	}
+	handler.SetEndpoints([]Endpoint{{Path: "/webhook/app", Platform: PlatformGitHub, Secret: "new-secret"}})
+	handler.SetTriggers([]config.TriggerConfig{{Event: "issues.labeled"}})
+
+	if status := post("/webhook", "old-secret"); status != http.StatusNotFound {
+		t.Errorf("removed endpoint: expected 404, got %d", status)
+	}
+	if status := post("/webhook/app", "old-secret"); status != http.StatusUnauthorized {
+		t.Errorf("old secret: expected 401, got %d", status)
+	}
+	if status := post("/webhook/app", "new-secret"); status != http.StatusOK {
+		t.Errorf("new endpoint with triggers for labels only: expected 200 (ignored), got %d", status)
+	}
+}
+
+func TestServerEnterpriseHost(t *testing.T) {
+	cfg := &config.Config{
+		Source: config.SourceConfig{Platform: "github", APIURL: "https://ghe.example.com/api/v3/"},
+		Server: config.ServerConfig{Secret: "ghe-secret"},
+	}
+	eps := Endpoints(cfg)
+	if len(eps) != 1 || eps[0].Host != "ghe.example.com" {
+		t.Fatalf("expected the enterprise host on the default endpoint, got %+v", eps)
+	}
+
+	var got []core.Issue
+	handler := NewHandler("", nil, filepath.Join(t.TempDir(), "state.json"), func(issue core.Issue) error {
+		got = append(got, issue)
+		return nil
+	})
+	handler.SetEndpoints(eps)
+	ts := httptest.NewServer(NewServer(config.ServerConfig{}, handler).Router())
+	defer ts.Close()
+
+	payload := []byte(`{"action": "opened", "issue": {"number": 5, "title": "Fix login"},
+  "repository": {"full_name": "corp/app"}, "sender": {"login": "octocat"}}`)
+	post := func(host string) int {
+		t.Helper()
+		req, _ := http.NewRequest("POST", ts.URL+"/webhook", strings.NewReader(string(payload)))
+		req.Header.Set("X-GitHub-Event", "issues")
+		req.Header.Set("X-GitHub-Enterprise-Host", host)
+		req.Header.Set("X-Hub-Signature-256", signPayload("ghe-secret", payload))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if status := post("other.example.com"); status != http.StatusOK {
+		t.Errorf("expected another enterprise host to be ignored, got %d", status)
+	}
+	if status := post("ghe.example.com"); status != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", status)
+	}
+	if len(got) != 1 || got[0].URL != "https://ghe.example.com/corp/app/issues/5" {
+		t.Errorf("expected the issue URL on the enterprise host, got %+v", got)
+	}
+}
@@ -0,0 +1,118 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type gitlabLabel struct {
+	Title string `json:"title"`
+}
+
+// parseGitLabEvent reads a GitLab issue or note (comment) payload into the
+// GitHub names triggers use: an opened issue is issues.opened, an update
+// that adds labels is issues.labeled, and a comment on an issue is
+// issue_comment.created.
+func parseGitLabEvent(body []byte) (*webhookEvent, error) {
+	var raw struct {
+		ObjectKind string `json:"object_kind"`
+		User       struct {
+			Username string `json:"username"`
+		} `json:"user"`
+		Project struct {
+			PathWithNamespace string `json:"path_with_namespace"`
+		} `json:"project"`
+		ObjectAttributes struct {
+			IID          int    `json:"iid"`
+			Title        string `json:"title"`
+			Description  string `json:"description"`
+			URL          string `json:"url"`
+			Action       string `json:"action"`
+			Note         string `json:"note"`
+			NoteableType string `json:"noteable_type"`
+		} `json:"object_attributes"`
+		Labels  []gitlabLabel `json:"labels"`
+		Changes struct {
+			Labels struct {
+				Previous []gitlabLabel `json:"previous"`
+				Current  []gitlabLabel `json:"current"`
+			} `json:"labels"`
+		} `json:"changes"`
+		Issue struct {
+			IID         int           `json:"iid"`
+			Title       string        `json:"title"`
+			Description string        `json:"description"`
+			Labels      []gitlabLabel `json:"labels"`
+		} `json:"issue"`
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("unmarshal gitlab webhook: %w", err)
+	}
+
+	attrs := raw.ObjectAttributes
+	event := &webhookEvent{
+		RepoFullName: raw.Project.PathWithNamespace,
+		Sender:       raw.User.Username,
+	}
+	switch raw.ObjectKind {
+	case "issue":
+		event.Kind = "issues"
+		event.Action = gitlabIssueAction(attrs.Action, raw.Changes.Labels.Previous, raw.Changes.Labels.Current)
+		event.IssueNumber = attrs.IID
+		event.IssueTitle = attrs.Title
+		event.IssueBody = attrs.Description
+		event.IssueURL = attrs.URL
+		event.IssueLabels = labelTitles(raw.Labels)
+	case "note":
+		event.Kind = "note"
+		event.Action = strings.ToLower(attrs.NoteableType)
+		if attrs.NoteableType == "Issue" {
+			event.Kind, event.Action = "issue_comment", "created"
+		}
+		event.IssueNumber = raw.Issue.IID
+		event.IssueTitle = raw.Issue.Title
+		event.IssueBody = raw.Issue.Description
+		// The note URL is the issue URL plus a #note_<id> anchor.
+		event.IssueURL, _, _ = strings.Cut(attrs.URL, "#")
+		event.IssueLabels = labelTitles(raw.Issue.Labels)
+		event.CommentBody = attrs.Note
+	default:
+		event.Kind = raw.ObjectKind
+		event.Action = attrs.Action
+	}
+	return event, nil
+}
+
+// gitlabIssueAction maps GitLab issue actions to GitHub's: open is opened,
+// and an update that adds a label is labeled.
+func gitlabIssueAction(action string, previous, current []gitlabLabel) string {
+	switch action {
+	case "open":
+		return "opened"
+	case "reopen":
+		return "reopened"
+	case "close":
+		return "closed"
+	case "update":
+		had := make(map[string]bool, len(previous))
+		for _, l := range previous {
+			had[l.Title] = true
+		}
+		for _, l := range current {
+			if !had[l.Title] {
+				return "labeled"
+			}
+		}
+		return "edited"
+	}
+	return action
+}
+
+func labelTitles(labels []gitlabLabel) []string {
+	titles := make([]string, 0, len(labels))
+	for _, l := range labels {
+		titles = append(titles, l.Title)
+	}
+	return titles
+}
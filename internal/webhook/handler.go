@@ -1,153 +1,365 @@
 package webhook
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/rigdev/rig/internal/config"
 	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/logging"
+	"github.com/rigdev/rig/internal/storage"
 )
 
 // ExecuteFunc is a callback invoked when a valid webhook event is accepted.
 // It receives the parsed issue and the raw event action string.
 type ExecuteFunc func(issue core.Issue) error
 
-// Handler processes incoming GitHub webhook events.
+// AuditFunc records an accepted event in the audit log. storage.DB.RecordAudit
+// matches it.
+type AuditFunc func(e storage.AuditEntry) error
+
+// DeliveryStore persists webhook deliveries for retries, replay and the
+// delivery history. storage.DB implements it.
+type DeliveryStore interface {
+	RecordDelivery(w *storage.WebhookDelivery) error
+	UpdateDelivery(w *storage.WebhookDelivery) error
+	GetDelivery(id int64) (*storage.WebhookDelivery, error)
+	DueDeliveries(now, cutoff time.Time) ([]storage.WebhookDelivery, error)
+}
+
+// Handler processes incoming webhook events.
 type Handler struct {
-	secret    string
+	// mu guards endpoints and triggers, which a config reload replaces.
+	mu        sync.RWMutex
+	endpoints []Endpoint
 	triggers  []config.TriggerConfig
 	statePath string
 	onExecute ExecuteFunc
+	audit     AuditFunc
+	store     DeliveryStore
+	// started is when the handler was built; received deliveries older than
+	// that were cut off by a restart.
+	started time.Time
 }
 
-// NewHandler creates a new webhook Handler.
+// NewHandler creates a new webhook Handler serving GitHub events at
+// /webhook, verified with secret, until SetEndpoints says otherwise.
 func NewHandler(secret string, triggers []config.TriggerConfig, statePath string, onExecute ExecuteFunc) *Handler {
 	return &Handler{
-		secret:    secret,
+		endpoints: []Endpoint{{Path: defaultPath, Platform: PlatformGitHub, Secret: secret}},
 		triggers:  triggers,
 		statePath: statePath,
 		onExecute: onExecute,
+		started:   time.Now(),
 	}
 }
 
-// HandleWebhook is the HTTP handler for POST /webhook.
-func (h *Handler) HandleWebhook(w http.ResponseWriter, r *http.Request) {
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		http.Error(w, "failed to read body", http.StatusBadRequest)
-		return
+// SetAuditFunc records every accepted event with the sender as actor.
+func (h *Handler) SetAuditFunc(fn AuditFunc) {
+	h.audit = fn
+}
+
+// SetDeliveryStore persists every signed delivery before it is processed,
+// so failed ones can be retried by RetryFailed and replayed by Replay.
+func (h *Handler) SetDeliveryStore(store DeliveryStore) {
+	h.store = store
+}
+
+// SetEndpoints replaces the endpoints the handler serves, as resolved by
+// Endpoints. An empty list keeps the current ones.
+func (h *Handler) SetEndpoints(eps []Endpoint) {
+	if len(eps) > 0 {
+		h.mu.Lock()
+		h.endpoints = eps
+		h.mu.Unlock()
 	}
-	defer r.Body.Close()
+}
 
-	// Verify HMAC-SHA256 signature.
-	signature := r.Header.Get("X-Hub-Signature-256")
-	if !h.verifySignature(body, signature) {
-		http.Error(w, "invalid signature", http.StatusUnauthorized)
-		return
+// SetTriggers replaces the trigger filters events are matched against.
+func (h *Handler) SetTriggers(triggers []config.TriggerConfig) {
+	h.mu.Lock()
+	h.triggers = triggers
+	h.mu.Unlock()
+}
+
+// Endpoints returns the endpoints the handler serves.
+func (h *Handler) Endpoints() []Endpoint {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.endpoints
+}
+
+func (h *Handler) endpoint(path string) (Endpoint, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, ep := range h.endpoints {
+		if ep.Path == path {
+			return ep, true
+		}
 	}
+	return Endpoint{}, false
+}
+
+// deliveryHeaders are the request headers kept with a delivery.
+var deliveryHeaders = []string{
+	"X-GitHub-Event", "X-GitHub-Delivery", "X-GitHub-Hook-ID",
+	enterpriseHost, "X-GitHub-Enterprise-Version",
+	"X-Gitlab-Event", "X-Gitlab-Event-UUID", "X-Gitlab-Instance",
+	"User-Agent", "Content-Type",
+}
 
-	// Determine event type.
-	eventType := r.Header.Get("X-GitHub-Event")
-	if eventType == "" {
-		http.Error(w, "missing event type", http.StatusBadRequest)
+// ServeHTTP serves POST requests to whichever endpoint is configured at
+// the request path, so endpoints set after the server started are served
+// too.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ep, ok := h.endpoint(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
 		return
 	}
+	h.HandleEndpoint(ep)(w, r)
+}
+
+// HandleEndpoint returns the HTTP handler for POST requests to ep.Path.
+func (h *Handler) HandleEndpoint(ep Endpoint) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "failed to read body", http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+
+		if !ep.verify(r, body) {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		// Determine event type.
+		eventType := ep.eventType(r)
+		if eventType == "" {
+			http.Error(w, "missing event type", http.StatusBadRequest)
+			return
+		}
+
+		d := &storage.WebhookDelivery{
+			DeliveryID: ep.deliveryID(r),
+			Endpoint:   ep.Path,
+			Event:      eventType,
+			Remote:     r.RemoteAddr,
+			Headers:    make(map[string]string),
+			Payload:    body,
+		}
+		for _, name := range deliveryHeaders {
+			if v := r.Header.Get(name); v != "" {
+				d.Headers[name] = v
+			}
+		}
+		if h.store != nil {
+			if err := h.store.RecordDelivery(d); err != nil {
+				slog.Warn("webhook: failed to store delivery", "err", err)
+			}
+		}
+
+		status, msg := h.process(d, false)
+		w.WriteHeader(status)
+		fmt.Fprint(w, msg)
+	}
+}
+
+// process runs a delivery through the trigger filters and starts its task,
+// then saves the outcome; see dispatch for replay. It returns the HTTP
+// status and message for the sender.
+func (h *Handler) process(d *storage.WebhookDelivery, replay bool) (int, string) {
+	status, msg, err := h.dispatch(d, replay)
+	switch {
+	case err != nil:
+		d.Attempts++
+		d.Error = err.Error()
+		if h.store != nil && d.ID != 0 && d.Attempts < maxDeliveryAttempts {
+			d.Status = storage.DeliveryFailed
+			d.NextAttempt = time.Now().Add(retryBackoff(d.Attempts))
+			msg += "; queued for retry"
+		} else {
+			d.Status = storage.DeliveryDead
+			d.NextAttempt = time.Time{}
+		}
+	case status == http.StatusAccepted:
+		d.Status, d.Error, d.NextAttempt = storage.DeliveryAccepted, "", time.Time{}
+	default:
+		d.Status, d.Error, d.NextAttempt = storage.DeliveryIgnored, msg, time.Time{}
+	}
+	if h.store != nil && d.ID != 0 {
+		if err := h.store.UpdateDelivery(d); err != nil {
+			slog.Warn("webhook: failed to update delivery", "id", d.ID, "err", err)
+		}
+	}
+	return status, msg
+}
+
+// dispatch starts the task of d if it matches a trigger. A non-nil error
+// means the delivery is worth retrying. A replay starts a task even when d
+// already created one that has not failed.
+func (h *Handler) dispatch(d *storage.WebhookDelivery, replay bool) (int, string, error) {
+	ep, ok := h.endpoint(d.Endpoint)
+	if !ok {
+		return http.StatusNotFound, fmt.Sprintf("endpoint %s is not configured", d.Endpoint), nil
+	}
 
 	// Parse the payload.
-	event, err := h.parseEvent(eventType, body)
+	var event *webhookEvent
+	var err error
+	if ep.Platform == PlatformGitLab {
+		event, err = parseGitLabEvent(d.Payload)
+	} else {
+		event, err = h.parseEvent(d.Event, d.Payload)
+	}
 	if err != nil {
-		log.Printf("failed to parse event: %v", err)
-		http.Error(w, "failed to parse event", http.StatusBadRequest)
-		return
+		slog.Warn("webhook: failed to parse event", "err", err)
+		return http.StatusBadRequest, "failed to parse event", nil
+	}
+	d.Action = event.Action
+	d.Sender = event.Sender
+	if event.IssueNumber > 0 {
+		d.Target = fmt.Sprintf("%s#%d", event.RepoFullName, event.IssueNumber)
 	}
 
 	// Check if the event action is one we care about.
-	action := fmt.Sprintf("%s.%s", eventType, event.Action)
+	action := fmt.Sprintf("%s.%s", event.Kind, event.Action)
 	if !h.isTrackedAction(action) {
-		w.WriteHeader(http.StatusOK)
-		fmt.Fprintf(w, "event %s ignored", action)
-		return
+		return http.StatusOK, fmt.Sprintf("event %s ignored", action), nil
+	}
+
+	// Endpoints of a GitHub Enterprise Server only take its events.
+	host := d.Headers[enterpriseHost]
+	if !ep.acceptsHost(host) {
+		return http.StatusOK, fmt.Sprintf("event from %s ignored by %s", host, ep.Path), nil
+	}
+
+	// Endpoints mapped to a project only take that project's events.
+	if ep.Repo != "" && !strings.EqualFold(ep.Repo, event.RepoFullName) {
+		return http.StatusOK, fmt.Sprintf("event for %s ignored by %s", event.RepoFullName, ep.Path), nil
 	}
 
 	// Check trigger filters (labels/keywords).
 	if !h.matchesTrigger(action, event) {
-		w.WriteHeader(http.StatusOK)
-		fmt.Fprintf(w, "event %s did not match trigger filters", action)
-		return
+		return http.StatusOK, fmt.Sprintf("event %s did not match trigger filters", action), nil
 	}
 
 	// Build core.Issue from the webhook event.
 	issue := core.Issue{
-		Platform: "github",
+		Platform: ep.Platform,
 		Repo:     event.RepoFullName,
 		ID:       fmt.Sprintf("%d", event.IssueNumber),
 		Title:    event.IssueTitle,
 		Body:     event.IssueBody,
 		URL:      event.IssueURL,
+		Labels:   event.IssueLabels,
+	}
+	if issue.URL == "" && event.IssueNumber > 0 {
+		if host == "" {
+			host = ep.Host
+		}
+		issue.URL = core.IssueHosts{}.URL(ep.Platform, host, issue.Repo, issue.ID)
+	}
+	if d.DeliveryID != "" {
+		issue.IdempotencyKey = ep.Platform + ":" + d.DeliveryID
 	}
 
-	// Check for in-flight duplicates via state.json.
-	state, err := core.LoadState(h.statePath)
+	// The lookup and the new task are one state update, so concurrent
+	// deliveries cannot both start a task.
+	var task core.Task
+	skip := ""
+	err = core.WithState(h.statePath, func(state *core.State) error {
+		// A redelivery keeps its delivery ID. Unless replayed, it starts no
+		// task while the one its first delivery created is in flight or
+		// succeeded; a failed one is retried.
+		if prior := state.GetTaskByIdempotencyKey(issue.IdempotencyKey); prior != nil && !replay && !retryablePhases[prior.Status] {
+			skip = fmt.Sprintf("delivery %s already created task %s", d.DeliveryID, prior.ID)
+			slog.Info("webhook: delivery already created a task, skipping", "delivery", d.DeliveryID, logging.TaskKey, prior.ID)
+			return nil
+		}
+		if state.IsInFlight(issue.ID) {
+			skip = fmt.Sprintf("issue %s already in-flight", issue.ID)
+			slog.Info("webhook: issue already in flight, skipping", "issue", issue.ID)
+			return nil
+		}
+		if h.onExecute != nil && issue.IdempotencyKey != "" {
+			task = *state.CreateTask(issue)
+		}
+		return nil
+	})
 	if err != nil {
-		log.Printf("failed to load state: %v", err)
-		http.Error(w, "internal error", http.StatusInternalServerError)
-		return
+		slog.Error("webhook: failed to update state", "err", err)
+		return http.StatusInternalServerError, "internal error", fmt.Errorf("update state: %w", err)
 	}
-
-	if state.IsInFlight(issue.ID) {
-		log.Printf("issue %s already in-flight, skipping", issue.ID)
-		w.WriteHeader(http.StatusOK)
-		fmt.Fprintf(w, "issue %s already in-flight", issue.ID)
-		return
+	if skip != "" {
+		return http.StatusOK, skip, nil
 	}
 
-	// Invoke engine.Execute placeholder.
+	// The engine runs the task just created for a delivery with an ID, or
+	// creates one.
 	if h.onExecute != nil {
 		if err := h.onExecute(issue); err != nil {
-			log.Printf("execute failed for issue %s: %v", issue.ID, err)
-			http.Error(w, "execution failed", http.StatusInternalServerError)
-			return
+			slog.Error("webhook: execute failed", "issue", issue.ID, "err", err)
+			if task.ID != "" {
+				h.dropUnstarted(task.ID, issue)
+			}
+			return http.StatusInternalServerError, "execution failed", err
 		}
 	}
 
-	w.WriteHeader(http.StatusAccepted)
-	fmt.Fprintf(w, "accepted issue %s", issue.ID)
-}
-
-// verifySignature checks the HMAC-SHA256 signature from GitHub.
-func (h *Handler) verifySignature(body []byte, signature string) bool {
-	if h.secret == "" {
-		log.Println("[webhook] WARNING: no webhook secret configured — rejecting request for safety")
-		return false // Reject if no secret configured — require explicit opt-in.
-	}
-
-	if signature == "" {
-		return false
+	if h.audit != nil {
+		err := h.audit(storage.AuditEntry{
+			Actor:   ep.Platform + ":" + event.Sender,
+			Source:  storage.AuditSourceWebhook,
+			Remote:  d.Remote,
+			Action:  storage.AuditTaskCreated,
+			Target:  issue.Repo + "#" + issue.ID,
+			Details: action,
+		})
+		if err != nil {
+			slog.Warn("webhook: audit failed", "err", err)
+		}
 	}
 
-	// GitHub sends "sha256=<hex>".
-	prefix := "sha256="
-	if !strings.HasPrefix(signature, prefix) {
-		return false
-	}
-	sigHex := signature[len(prefix):]
+	return http.StatusAccepted, fmt.Sprintf("accepted issue %s", issue.ID), nil
+}
 
-	mac := hmac.New(sha256.New, []byte(h.secret))
-	mac.Write(body)
-	expected := hex.EncodeToString(mac.Sum(nil))
+// retryablePhases are the phases of a delivery's task after which a
+// redelivery starts another task.
+var retryablePhases = map[core.TaskPhase]bool{
+	core.PhaseFailed:   true,
+	core.PhaseRollback: true,
+}
 
-	return hmac.Equal([]byte(sigHex), []byte(expected))
+// dropUnstarted removes the task taskID created for issue if the engine
+// failed before starting it, so a retry of the delivery is not taken for
+// a redelivery of a task in flight.
+func (h *Handler) dropUnstarted(taskID string, issue core.Issue) {
+	err := core.WithState(h.statePath, func(state *core.State) error {
+		if t := state.UnstartedTask(issue); t != nil && t.ID == taskID {
+			state.Tasks = slices.DeleteFunc(state.Tasks, func(t core.Task) bool { return t.ID == taskID })
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Warn("webhook: failed to drop unstarted task", logging.TaskKey, taskID, "err", err)
+	}
 }
 
-// webhookEvent is an intermediate representation of a GitHub webhook payload.
+// webhookEvent is an intermediate representation of a webhook payload.
 type webhookEvent struct {
+	// Kind and Action use GitHub's names whatever the platform, such as
+	// issues and opened, so triggers match either.
+	Kind         string
 	Action       string
 	IssueNumber  int
 	IssueTitle   string
@@ -156,6 +368,7 @@ type webhookEvent struct {
 	IssueLabels  []string
 	RepoFullName string
 	CommentBody  string
+	Sender       string
 }
 
 // parseEvent extracts relevant fields from a GitHub webhook payload.
@@ -177,6 +390,9 @@ func (h *Handler) parseEvent(eventType string, body []byte) (*webhookEvent, erro
 		Comment struct {
 			Body string `json:"body"`
 		} `json:"comment"`
+		Sender struct {
+			Login string `json:"login"`
+		} `json:"sender"`
 	}
 
 	if err := json.Unmarshal(body, &raw); err != nil {
@@ -189,6 +405,7 @@ func (h *Handler) parseEvent(eventType string, body []byte) (*webhookEvent, erro
 	}
 
 	return &webhookEvent{
+		Kind:         eventType,
 		Action:       raw.Action,
 		IssueNumber:  raw.Issue.Number,
 		IssueTitle:   raw.Issue.Title,
@@ -197,6 +414,7 @@ func (h *Handler) parseEvent(eventType string, body []byte) (*webhookEvent, erro
 		IssueLabels:  labels,
 		RepoFullName: raw.Repository.FullName,
 		CommentBody:  raw.Comment.Body,
+		Sender:       raw.Sender.Login,
 	}, nil
 }
 
@@ -212,11 +430,14 @@ func (h *Handler) isTrackedAction(action string) bool {
 
 // matchesTrigger checks if the event matches any configured trigger filter.
 func (h *Handler) matchesTrigger(action string, event *webhookEvent) bool {
-	if len(h.triggers) == 0 {
+	h.mu.RLock()
+	triggers := h.triggers
+	h.mu.RUnlock()
+	if len(triggers) == 0 {
 		return true // No triggers configured, accept all tracked events.
 	}
 
-	for _, trigger := range h.triggers {
+	for _, trigger := range triggers {
 		// Match event type if specified.
 		if trigger.Event != "" && trigger.Event != action {
 			continue
@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -15,6 +16,7 @@ import (
 
 	"github.com/rigdev/rig/internal/config"
 	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/storage"
 )
 
 const testSecret = "test-webhook-secret"
@@ -129,7 +131,7 @@ func TestHandlerLabelFiltering(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			var called bool
-			handler := NewHandler(testSecret, tt.triggers, "", func(issue core.Issue) error {
+			handler := NewHandler(testSecret, tt.triggers, filepath.Join(t.TempDir(), "state.json"), func(issue core.Issue) error {
 				called = true
 				return nil
 			})
@@ -186,7 +188,7 @@ func TestHandlerKeywordFiltering(t *testing.T) {
 			var called bool
 			handler := NewHandler(testSecret, []config.TriggerConfig{
 				{Event: "issues.opened", Keyword: tt.keyword},
-			}, "", func(issue core.Issue) error {
+			}, filepath.Join(t.TempDir(), "state.json"), func(issue core.Issue) error {
 				called = true
 				return nil
 			})
@@ -215,7 +217,7 @@ func TestHandlerIssueCommentKeyword(t *testing.T) {
 	var called bool
 	handler := NewHandler(testSecret, []config.TriggerConfig{
 		{Event: "issue_comment.created", Keyword: "/rig"},
-	}, "", func(issue core.Issue) error {
+	}, filepath.Join(t.TempDir(), "state.json"), func(issue core.Issue) error {
 		called = true
 		return nil
 	})
@@ -260,7 +262,7 @@ func TestHandlerIssueCommentKeyword(t *testing.T) {
 func TestHandlerExecuteError(t *testing.T) {
 	handler := NewHandler(testSecret, []config.TriggerConfig{
 		{Event: "issues.opened"},
-	}, "", func(issue core.Issue) error {
+	}, filepath.Join(t.TempDir(), "state.json"), func(issue core.Issue) error {
 		return fmt.Errorf("engine error: something broke")
 	})
 
@@ -286,7 +288,7 @@ func TestHandlerIssueLabeledEvent(t *testing.T) {
 	var called bool
 	handler := NewHandler(testSecret, []config.TriggerConfig{
 		{Event: "issues.labeled", Labels: []string{"rig"}},
-	}, "", func(issue core.Issue) error {
+	}, filepath.Join(t.TempDir(), "state.json"), func(issue core.Issue) error {
 		called = true
 		return nil
 	})
@@ -312,6 +314,39 @@ func TestHandlerIssueLabeledEvent(t *testing.T) {
 	}
 }
 
+func TestHandlerAuditsAcceptedEvents(t *testing.T) {
+	handler := NewHandler(testSecret, []config.TriggerConfig{
+		{Event: "issues.labeled", Labels: []string{"rig"}},
+	}, filepath.Join(t.TempDir(), "state.json"), func(issue core.Issue) error { return nil })
+	var entries []storage.AuditEntry
+	handler.SetAuditFunc(func(e storage.AuditEntry) error {
+		entries = append(entries, e)
+		return nil
+	})
+
+	srv := NewServer(config.ServerConfig{}, handler)
+	ts := httptest.NewServer(srv.Router())
+	defer ts.Close()
+
+	for _, labels := range [][]string{{"rig"}, {"other"}} {
+		payload := makeIssuePayload("labeled", 7, "Add feature", labels, "org/repo")
+		resp, err := http.DefaultClient.Do(newSignedRequest(ts.URL, payload, "issues"))
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("expected only the accepted event to be audited, got %+v", entries)
+	}
+	e := entries[0]
+	if e.Actor != "github:octocat" || e.Source != storage.AuditSourceWebhook ||
+		e.Action != storage.AuditTaskCreated || e.Target != "org/repo#7" || e.Details != "issues.labeled" {
+		t.Errorf("unexpected audit entry %+v", e)
+	}
+}
+
 func TestHandlerSignatureVerification(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -350,7 +385,7 @@ func TestHandlerSignatureVerification(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			handler := NewHandler(tt.secret, []config.TriggerConfig{
 				{Event: "issues.opened"},
-			}, "", func(issue core.Issue) error {
+			}, filepath.Join(t.TempDir(), "state.json"), func(issue core.Issue) error {
 				return nil
 			})
 
@@ -435,3 +470,94 @@ func TestHandlerCompletedTaskNotInFlight(t *testing.T) {
 		t.Error("Expected execute to be called for completed task re-triggered")
 	}
 }
+
+func TestHandlerRedeliveryReturnsTask(t *testing.T) {
+	// A redelivered event keeps its delivery ID even after its task finished.
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	var issues []core.Issue
+	handler := NewHandler(testSecret, []config.TriggerConfig{
+		{Event: "issues.opened"},
+	}, statePath, func(issue core.Issue) error {
+		issues = append(issues, issue)
+		// The engine runs the task the handler created for the delivery.
+		return core.WithState(statePath, func(s *core.State) error {
+			task := s.UnstartedTask(issue)
+			if task == nil {
+				return errors.New("no unstarted task for the delivery")
+			}
+			task.Status = core.PhaseCompleted
+			return nil
+		})
+	})
+
+	srv := NewServer(config.ServerConfig{}, handler)
+	ts := httptest.NewServer(srv.Router())
+	defer ts.Close()
+
+	payload := makeIssuePayload("opened", 51, "Fix it", nil, "org/repo")
+	send := func(delivery string) *http.Response {
+		req := newSignedRequest(ts.URL, payload, "issues")
+		req.Header.Set("X-GitHub-Delivery", delivery)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	if resp := send("guid-1"); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d", resp.StatusCode)
+	}
+	if len(issues) != 1 || issues[0].IdempotencyKey != "github:guid-1" {
+		t.Fatalf("issues = %+v, want one keyed github:guid-1", issues)
+	}
+	if resp := send("guid-1"); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 for a redelivery, got %d", resp.StatusCode)
+	}
+	if len(issues) != 1 {
+		t.Errorf("redelivery executed again: %d executions", len(issues))
+	}
+	if resp := send("guid-2"); resp.StatusCode != http.StatusAccepted || len(issues) != 2 {
+		t.Errorf("a new delivery got %d after %d executions, want 202 and 2", resp.StatusCode, len(issues))
+	}
+}
+
+func TestHandlerRedeliveryRetriesFailedTask(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	executions := 0
+	handler := NewHandler(testSecret, []config.TriggerConfig{
+		{Event: "issues.opened"},
+	}, statePath, func(issue core.Issue) error {
+		executions++
+		return core.WithState(statePath, func(s *core.State) error {
+			s.UnstartedTask(issue).Status = core.PhaseFailed
+			return nil
+		})
+	})
+
+	ts := httptest.NewServer(NewServer(config.ServerConfig{}, handler).Router())
+	defer ts.Close()
+
+	payload := makeIssuePayload("opened", 52, "Fix it", nil, "org/repo")
+	for i := 1; i <= 2; i++ {
+		req := newSignedRequest(ts.URL, payload, "issues")
+		req.Header.Set("X-GitHub-Delivery", "guid-1")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusAccepted || executions != i {
+			t.Fatalf("delivery %d got %d after %d executions, want 202 and %d", i, resp.StatusCode, executions, i)
+		}
+	}
+
+	state, err := core.LoadState(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Tasks) != 2 {
+		t.Errorf("tasks = %d, want one per delivery of the failed task", len(state.Tasks))
+	}
+}
@@ -0,0 +1,81 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/rigdev/rig/internal/storage"
+)
+
+// maxDeliveryAttempts is how often a failing delivery is tried before it
+// is marked dead.
+const maxDeliveryAttempts = 5
+
+// retryBackoff is the wait after the nth failed attempt: 1m, 2m, 4m, ...
+// capped at an hour.
+func retryBackoff(attempt int) time.Duration {
+	d := time.Minute << (attempt - 1)
+	if d > time.Hour || d <= 0 {
+		return time.Hour
+	}
+	return d
+}
+
+// ErrNoDeliveryStore is returned by Replay when deliveries are not stored.
+var ErrNoDeliveryStore = errors.New("webhook deliveries are not stored")
+
+// Replay processes a stored delivery again, whatever its status, and
+// returns its outcome. It starts a task even if the delivery already
+// created one, unless the issue has a task in flight. A failed replay is
+// retried like a new delivery.
+func (h *Handler) Replay(id int64) (*storage.WebhookDelivery, error) {
+	if h.store == nil {
+		return nil, ErrNoDeliveryStore
+	}
+	d, err := h.store.GetDelivery(id)
+	if err != nil {
+		return nil, err
+	}
+	d.Attempts = 0
+	status, msg := h.process(d, true)
+	if status >= http.StatusInternalServerError {
+		return d, fmt.Errorf("replay delivery %d: %s", id, msg)
+	}
+	return d, nil
+}
+
+// RetryFailed processes due deliveries every interval until ctx is done:
+// failed ones whose backoff has passed, and ones a restart cut off before
+// they were processed.
+func (h *Handler) RetryFailed(ctx context.Context, interval time.Duration) {
+	if h.store == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		h.retryDue()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *Handler) retryDue() {
+	due, err := h.store.DueDeliveries(time.Now(), h.started)
+	if err != nil {
+		slog.Warn("webhook: failed to load due deliveries", "err", err)
+		return
+	}
+	for i := range due {
+		d := &due[i]
+		slog.Info("webhook: retrying delivery", "id", d.ID, "event", d.Event, "target", d.Target, "attempt", d.Attempts+1)
+		h.process(d, false)
+	}
+}
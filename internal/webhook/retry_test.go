@@ -0,0 +1,150 @@
+package webhook
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/storage"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	for attempt, want := range map[int]time.Duration{1: time.Minute, 2: 2 * time.Minute, 4: 8 * time.Minute, 7: time.Hour, 100: time.Hour} {
+		if got := retryBackoff(attempt); got != want {
+			t.Errorf("retryBackoff(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
+
+func TestHandlerStoresFailedDeliveryAndReplays(t *testing.T) {
+	db, err := storage.Open(filepath.Join(t.TempDir(), "rig.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	fail := true
+	calls := 0
+	handler := NewHandler(testSecret, []config.TriggerConfig{{Event: "issues.opened"}}, filepath.Join(t.TempDir(), "state.json"), func(issue core.Issue) error {
+		calls++
+		if fail {
+			return errors.New("engine error")
+		}
+		return nil
+	})
+	handler.SetDeliveryStore(db)
+
+	ts := httptest.NewServer(NewServer(config.ServerConfig{}, handler).Router())
+	defer ts.Close()
+
+	req := newSignedRequest(ts.URL, makeIssuePayload("opened", 3, "Broken", nil, "org/repo"), "issues")
+	req.Header.Set("X-GitHub-Delivery", "abc-123")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("Expected 500, got %d", resp.StatusCode)
+	}
+
+	list, _ := db.ListDeliveries(storage.DeliveryFilter{})
+	if len(list) != 1 {
+		t.Fatalf("expected 1 stored delivery, got %d", len(list))
+	}
+	d := list[0]
+	if d.Status != storage.DeliveryFailed || d.Attempts != 1 || d.DeliveryID != "abc-123" ||
+		d.Target != "org/repo#3" || d.Error != "engine error" || d.NextAttempt.IsZero() {
+		t.Errorf("unexpected failed delivery %+v", d)
+	}
+
+	// Not due yet: the retry loop leaves it alone.
+	handler.retryDue()
+	if calls != 1 {
+		t.Errorf("expected no retry before the backoff, got %d calls", calls)
+	}
+
+	fail = false
+	got, err := handler.Replay(d.ID)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if got.Status != storage.DeliveryAccepted || calls != 2 {
+		t.Errorf("expected accepted after replay, got %+v (%d calls)", got, calls)
+	}
+	stored, _ := db.GetDelivery(d.ID)
+	if stored.Status != storage.DeliveryAccepted || stored.Error != "" || !stored.NextAttempt.IsZero() {
+		t.Errorf("replay outcome not saved: %+v", stored)
+	}
+}
+
+func TestReplayStartsFinishedDeliveryAgain(t *testing.T) {
+	db, err := storage.Open(filepath.Join(t.TempDir(), "rig.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	calls := 0
+	handler := NewHandler(testSecret, []config.TriggerConfig{{Event: "issues.opened"}}, statePath, func(issue core.Issue) error {
+		calls++
+		return core.WithState(statePath, func(s *core.State) error {
+			s.UnstartedTask(issue).Status = core.PhaseCompleted
+			return nil
+		})
+	})
+	handler.SetDeliveryStore(db)
+
+	d := &storage.WebhookDelivery{DeliveryID: "abc-456", Endpoint: "/webhook", Event: "issues", Payload: makeIssuePayload("opened", 4, "Done", nil, "org/repo")}
+	if err := db.RecordDelivery(d); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	if status, _ := handler.process(d, false); status != http.StatusAccepted {
+		t.Fatalf("first delivery got %d, want 202", status)
+	}
+	if status, _ := handler.process(d, false); status != http.StatusOK || calls != 1 {
+		t.Fatalf("redelivery got %d after %d calls, want 200 and 1", status, calls)
+	}
+
+	got, err := handler.Replay(d.ID)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if got.Status != storage.DeliveryAccepted || calls != 2 {
+		t.Errorf("expected the replay to start the task again, got %+v (%d calls)", got, calls)
+	}
+}
+
+func TestHandlerMarksDeliveryDeadAfterMaxAttempts(t *testing.T) {
+	db, err := storage.Open(filepath.Join(t.TempDir(), "rig.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	handler := NewHandler(testSecret, nil, filepath.Join(t.TempDir(), "state.json"), func(issue core.Issue) error {
+		return errors.New("engine error")
+	})
+	handler.SetDeliveryStore(db)
+
+	d := &storage.WebhookDelivery{Endpoint: "/webhook", Event: "issues", Payload: makeIssuePayload("opened", 3, "Broken", nil, "org/repo")}
+	if err := db.RecordDelivery(d); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	for i := 0; i < maxDeliveryAttempts; i++ {
+		handler.process(d, false)
+	}
+	stored, _ := db.GetDelivery(d.ID)
+	if stored.Status != storage.DeliveryDead || stored.Attempts != maxDeliveryAttempts {
+		t.Errorf("expected dead after %d attempts, got %+v", maxDeliveryAttempts, stored)
+	}
+	if _, err := NewHandler(testSecret, nil, filepath.Join(t.TempDir(), "state.json"), nil).Replay(d.ID); !errors.Is(err, ErrNoDeliveryStore) {
+		t.Errorf("expected ErrNoDeliveryStore, got %v", err)
+	}
+}
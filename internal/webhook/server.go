@@ -3,7 +3,7 @@ package webhook
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os/signal"
 	"syscall"
@@ -11,6 +11,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/httpserver"
 )
 
 // Server is the webhook HTTP server.
@@ -28,24 +29,28 @@ func NewServer(cfg config.ServerConfig, handler *Handler) *Server {
 	}
 }
 
-// ListenAndServe starts the webhook server with graceful shutdown.
-// It blocks until the context is cancelled or a termination signal is received.
+// ListenAndServe starts the webhook server with graceful shutdown, over
+// TLS when server.tls is set. It blocks until the context is cancelled or a
+// termination signal is received.
 func (s *Server) ListenAndServe(ctx context.Context) error {
-	r := chi.NewRouter()
-
-	// Body size limit: 10MB
-	r.Use(bodySizeLimitMiddleware(10 << 20))
-
-	r.Post("/webhook", s.handler.HandleWebhook)
+	tlsCfg, err := httpserver.TLSConfig(s.cfg.TLS)
+	if err != nil {
+		return err
+	}
+	realIP, err := httpserver.RealIP(s.cfg.TrustedProxies)
+	if err != nil {
+		return err
+	}
 
 	port := s.cfg.Port
 	if port == 0 {
 		port = 8080
 	}
 
+	// realIP runs first so limits and the audit log see client IPs.
 	s.srv = &http.Server{
-		Addr:         fmt.Sprintf(":%d", port),
-		Handler:      r,
+		Addr:         httpserver.Addr(s.cfg.Host, port),
+		Handler:      realIP(s.Router()),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 30 * time.Second,
 		IdleTimeout:  60 * time.Second,
@@ -57,8 +62,8 @@ func (s *Server) ListenAndServe(ctx context.Context) error {
 
 	errCh := make(chan error, 1)
 	go func() {
-		log.Printf("webhook server listening on :%d", port)
-		if err := s.srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		slog.Info("webhook server listening", "port", port)
+		if err := httpserver.ListenAndServe(s.srv, tlsCfg); err != nil && err != http.ErrServerClosed {
 			errCh <- err
 		}
 		close(errCh)
@@ -66,7 +71,7 @@ func (s *Server) ListenAndServe(ctx context.Context) error {
 
 	select {
 	case <-ctx.Done():
-		log.Println("shutting down webhook server...")
+		slog.Info("shutting down webhook server")
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 		if err := s.srv.Shutdown(shutdownCtx); err != nil {
@@ -78,20 +83,21 @@ func (s *Server) ListenAndServe(ctx context.Context) error {
 	}
 }
 
-// Router returns the chi router for testing purposes.
+// Defaults for server.limits.
+const (
+	defaultWebhookRequestsPerMinute = 600
+	defaultWebhookMaxBodyBytes      = 10 << 20
+)
+
+// Router routes POST requests to the handler's endpoints, looked up per
+// request so a config reload can change them, with rate and payload size
+// limits from server.limits.
 func (s *Server) Router() http.Handler {
+	limits := s.cfg.Limits
 	r := chi.NewRouter()
-	r.Use(bodySizeLimitMiddleware(10 << 20))
-	r.Post("/webhook", s.handler.HandleWebhook)
+	r.Use(httpserver.RateLimit(httpserver.NewRateLimiter(
+		httpserver.Limit(limits.WebhookRequestsPerMinute, defaultWebhookRequestsPerMinute), time.Minute)))
+	r.Use(httpserver.MaxBody(httpserver.Limit(limits.WebhookMaxBodyBytes, defaultWebhookMaxBodyBytes)))
+	r.Post("/*", s.handler.ServeHTTP)
 	return r
 }
-
-// bodySizeLimitMiddleware limits the request body size.
-func bodySizeLimitMiddleware(maxBytes int64) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
-			next.ServeHTTP(w, r)
-		})
-	}
-}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -42,6 +43,9 @@ func makeIssuePayload(action string, number int, title string, labels []string,
 		"repository": map[string]interface{}{
 			"full_name": repo,
 		},
+		"sender": map[string]interface{}{
+			"login": "octocat",
+		},
 	}
 	data, _ := json.Marshal(payload)
 	return data
@@ -53,7 +57,7 @@ func TestServerValidWebhook(t *testing.T) {
 
 	handler := NewHandler(secret, []config.TriggerConfig{
 		{Event: "issues.opened", Labels: []string{"rig"}},
-	}, "", func(issue core.Issue) error {
+	}, filepath.Join(t.TempDir(), "state.json"), func(issue core.Issue) error {
 		executedIssue = &issue
 		return nil
 	})
@@ -94,7 +98,7 @@ func TestServerValidWebhook(t *testing.T) {
 func TestServerInvalidSignature(t *testing.T) {
 	secret := "correct-secret"
 
-	handler := NewHandler(secret, nil, "", nil)
+	handler := NewHandler(secret, nil, filepath.Join(t.TempDir(), "state.json"), nil)
 	srv := NewServer(config.ServerConfig{Port: 0, Secret: secret}, handler)
 	ts := httptest.NewServer(srv.Router())
 	defer ts.Close()
@@ -120,7 +124,7 @@ func TestServerInvalidSignature(t *testing.T) {
 func TestServerMissingSignature(t *testing.T) {
 	secret := "my-secret"
 
-	handler := NewHandler(secret, nil, "", nil)
+	handler := NewHandler(secret, nil, filepath.Join(t.TempDir(), "state.json"), nil)
 	srv := NewServer(config.ServerConfig{Port: 0, Secret: secret}, handler)
 	ts := httptest.NewServer(srv.Router())
 	defer ts.Close()
@@ -146,7 +150,7 @@ func TestServerNoSecretRejectsRequest(t *testing.T) {
 
 	handler := NewHandler("", []config.TriggerConfig{
 		{Event: "issues.opened"},
-	}, "", func(issue core.Issue) error {
+	}, filepath.Join(t.TempDir(), "state.json"), func(issue core.Issue) error {
 		called = true
 		return nil
 	})
@@ -179,7 +183,7 @@ func TestServerUntrackedEventIgnored(t *testing.T) {
 	secret := "test-secret-untracked"
 	var called bool
 
-	handler := NewHandler(secret, nil, "", func(issue core.Issue) error {
+	handler := NewHandler(secret, nil, filepath.Join(t.TempDir(), "state.json"), func(issue core.Issue) error {
 		called = true
 		return nil
 	})
@@ -211,7 +215,7 @@ func TestServerUntrackedEventIgnored(t *testing.T) {
 }
 
 func TestServerMethodNotAllowed(t *testing.T) {
-	handler := NewHandler("", nil, "", nil)
+	handler := NewHandler("", nil, filepath.Join(t.TempDir(), "state.json"), nil)
 	srv := NewServer(config.ServerConfig{}, handler)
 	ts := httptest.NewServer(srv.Router())
 	defer ts.Close()
@@ -226,3 +230,31 @@ func TestServerMethodNotAllowed(t *testing.T) {
 		t.Errorf("Expected 405, got %d", resp.StatusCode)
 	}
 }
+
+func TestServerLimits(t *testing.T) {
+	handler := NewHandler("secret", nil, filepath.Join(t.TempDir(), "state.json"), nil)
+	srv := NewServer(config.ServerConfig{Limits: config.ServerLimitsConfig{
+		WebhookMaxBodyBytes:      64,
+		WebhookRequestsPerMinute: 1,
+	}}, handler)
+	ts := httptest.NewServer(srv.Router())
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/webhook", "application/json", strings.NewReader(strings.Repeat("x", 100)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized payload: got %d, want 413", resp.StatusCode)
+	}
+
+	resp, err = http.Post(ts.URL+"/webhook", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") == "" {
+		t.Errorf("second delivery in a minute: got %d, want 429 with Retry-After", resp.StatusCode)
+	}
+}
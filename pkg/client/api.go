@@ -0,0 +1,898 @@
+// Code generated by gen.go from the web API operations; DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/rigdev/rig/internal/adapter/git"
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/metrics"
+	"github.com/rigdev/rig/internal/storage"
+	"github.com/rigdev/rig/internal/tenant"
+)
+
+// Types shared with the rig server.
+type (
+	APIKey             = storage.APIKey
+	AuditEntry         = storage.AuditEntry
+	DORAMetrics        = metrics.DORAMetrics
+	DayCount           = metrics.DayCount
+	FailureExplanation = core.FailureExplanation
+	LogEntry           = storage.LogEntry
+	PhaseDuration      = metrics.PhaseDuration
+	ProjectEntry       = config.ProjectEntry
+	Proposal           = core.Proposal
+	ProposalType       = core.ProposalType
+	ReasonCount        = metrics.ReasonCount
+	ReloadStatus       = config.ReloadStatus
+	RetryCount         = metrics.RetryCount
+	Task               = core.Task
+	TaskPhase          = core.TaskPhase
+	TaskReport         = metrics.TaskReport
+	TaskSummary        = core.TaskSummary
+	TriggerConfig      = config.TriggerConfig
+	Usage              = tenant.Usage
+	WebhookDelivery    = storage.WebhookDelivery
+	Workspace          = git.Workspace
+)
+
+type ActionResponse struct {
+	Status  string `json:"status"`
+	TaskID  string `json:"task_id,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type AgentsResponse struct {
+	Repo    string `json:"repo"`
+	Content string `json:"content"`
+}
+
+type ClearAICacheResponse struct {
+	Deleted int64 `json:"deleted"`
+}
+
+type ConfigResponse struct {
+	Project  ProjectInfo  `json:"project"`
+	Source   SourceInfo   `json:"source"`
+	AI       AiInfo       `json:"ai"`
+	Deploy   DeployInfo   `json:"deploy"`
+	Workflow WorkflowInfo `json:"workflow"`
+	Profile  string       `json:"profile,omitempty"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name   string `json:"name"`
+	Role   string `json:"role"`
+	Tenant string `json:"tenant,omitempty"`
+}
+
+type CreateAPIKeyResponse struct {
+	Name   string `json:"name"`
+	Role   string `json:"role"`
+	Prefix string `json:"prefix"`
+	Tenant string `json:"tenant,omitempty"`
+	Key    string `json:"key"`
+}
+
+type CreateTaskRequest struct {
+	Project        string `json:"project"`
+	IssueNum       string `json:"issue_num"`
+	IssueURL       string `json:"issue_url"`
+	IssueID        string `json:"issue_id"`
+	Title          string `json:"title"`
+	Body           string `json:"body"`
+	Environment    string `json:"environment,omitempty"`
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+}
+
+type EditPlanRequest struct {
+	Plan string `json:"plan"`
+}
+
+type EditorCheckout struct {
+	TaskID   string   `json:"task_id"`
+	Repo     string   `json:"repo"`
+	Branch   string   `json:"branch"`
+	CloneURL string   `json:"clone_url"`
+	Commands []string `json:"commands"`
+}
+
+type EditorTask struct {
+	ID              string          `json:"id"`
+	Status          TaskPhase       `json:"status"`
+	Phase           string          `json:"phase,omitempty"`
+	Repo            string          `json:"repo"`
+	Issue           EditorIssue     `json:"issue"`
+	Branch          string          `json:"branch,omitempty"`
+	PRURL           string          `json:"pr_url,omitempty"`
+	Attempts        int             `json:"attempts"`
+	PendingProposal *EditorProposal `json:"pending_proposal,omitempty"`
+}
+
+type MeResponse struct {
+	User  string `json:"user"`
+	Role  string `json:"role"`
+	Login bool   `json:"login"`
+}
+
+type PendingProposalItem struct {
+	TaskID    string   `json:"task_id"`
+	TaskTitle string   `json:"task_title"`
+	Proposal  Proposal `json:"proposal"`
+}
+
+type SaveAgentsRequest struct {
+	Content string `json:"content"`
+}
+
+type SaveSettingsRequest struct {
+	Section string          `json:"section"`
+	Data    json.RawMessage `json:"data"`
+}
+
+type StatusResponse struct {
+	Configured      bool           `json:"configured"`
+	Mode            string         `json:"mode"`
+	GitHubRateLimit *RateLimitInfo `json:"github_rate_limit,omitempty"`
+	ConfigReload    *ReloadStatus  `json:"config_reload,omitempty"`
+}
+
+type TaskArtifact struct {
+	Attempt     int       `json:"attempt"`
+	Name        string    `json:"name"`
+	Kind        string    `json:"kind"`
+	Test        string    `json:"test,omitempty"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"content_type,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	URL         string    `json:"url"`
+}
+
+type WorkspaceGCResponse struct {
+	Removed    []Workspace `json:"removed"`
+	FreedBytes int64       `json:"freed_bytes"`
+}
+
+type AiInfo struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	MaxRetry int    `json:"max_retry"`
+}
+
+type DeployInfo struct {
+	Method string `json:"method"`
+}
+
+type EditorIssue struct {
+	Number string `json:"number"`
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+}
+
+type EditorProposal struct {
+	ID      string       `json:"id"`
+	Type    ProposalType `json:"type"`
+	Summary string       `json:"summary"`
+	Files   []string     `json:"files"`
+}
+
+type ProjectInfo struct {
+	Name        string `json:"name"`
+	Language    string `json:"language"`
+	Description string `json:"description"`
+}
+
+type RateLimitInfo struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+	Headroom  float64   `json:"headroom"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type SourceInfo struct {
+	Platform   string `json:"platform"`
+	Repo       string `json:"repo"`
+	BaseBranch string `json:"base_branch"`
+}
+
+type WorkflowInfo struct {
+	Steps    []string        `json:"steps"`
+	Triggers []TriggerConfig `json:"triggers"`
+}
+
+// ListAgents calls GET /api/agents: list AGENTS.md content per repository.
+func (c *Client) ListAgents(ctx context.Context) (map[string]string, error) {
+	var out map[string]string
+	if err := c.do(ctx, http.MethodGet, "/api/agents", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetAgents calls GET /api/agents/{repo}: get the AGENTS.md content of a repository.
+func (c *Client) GetAgents(ctx context.Context, repo string) (*AgentsResponse, error) {
+	var out AgentsResponse
+	if err := c.do(ctx, http.MethodGet, "/api/agents/"+url.PathEscape(repo), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SaveAgents calls POST /api/agents/{repo}: save the AGENTS.md content of a repository.
+func (c *Client) SaveAgents(ctx context.Context, repo string, req SaveAgentsRequest) (*ActionResponse, error) {
+	var out ActionResponse
+	if err := c.do(ctx, http.MethodPost, "/api/agents/"+url.PathEscape(repo), nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ClearAICache calls DELETE /api/ai-cache: remove every cached AI answer (ai.cache), so later tasks ask the AI provider again.
+func (c *Client) ClearAICache(ctx context.Context) (*ClearAICacheResponse, error) {
+	var out ClearAICacheResponse
+	if err := c.do(ctx, http.MethodDelete, "/api/ai-cache", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetFailureReasonsParams are the optional query parameters of GetFailureReasons.
+type GetFailureReasonsParams struct {
+	// Start of the period, a date (2006-01-02) or RFC 3339 time; default 30 days before to.
+	From string
+	// End of the period, exclusive; default now.
+	To string
+}
+
+// GetFailureReasons calls GET /api/analytics/failure-reasons: failed tasks of a period per failure reason.
+func (c *Client) GetFailureReasons(ctx context.Context, params *GetFailureReasonsParams) ([]ReasonCount, error) {
+	q := url.Values{}
+	if params != nil {
+		if params.From != "" {
+			q.Set("from", params.From)
+		}
+		if params.To != "" {
+			q.Set("to", params.To)
+		}
+	}
+	var out []ReasonCount
+	if err := c.do(ctx, http.MethodGet, "/api/analytics/failure-reasons", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetPhaseDurationsParams are the optional query parameters of GetPhaseDurations.
+type GetPhaseDurationsParams struct {
+	// Start of the period, a date (2006-01-02) or RFC 3339 time; default 30 days before to.
+	From string
+	// End of the period, exclusive; default now.
+	To string
+}
+
+// GetPhaseDurations calls GET /api/analytics/phase-durations: mean, median and maximum duration of each pipeline phase over a period.
+func (c *Client) GetPhaseDurations(ctx context.Context, params *GetPhaseDurationsParams) ([]PhaseDuration, error) {
+	q := url.Values{}
+	if params != nil {
+		if params.From != "" {
+			q.Set("from", params.From)
+		}
+		if params.To != "" {
+			q.Set("to", params.To)
+		}
+	}
+	var out []PhaseDuration
+	if err := c.do(ctx, http.MethodGet, "/api/analytics/phase-durations", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetRetryDistributionParams are the optional query parameters of GetRetryDistribution.
+type GetRetryDistributionParams struct {
+	// Start of the period, a date (2006-01-02) or RFC 3339 time; default 30 days before to.
+	From string
+	// End of the period, exclusive; default now.
+	To string
+}
+
+// GetRetryDistribution calls GET /api/analytics/retries: tasks of a period per number of retries.
+func (c *Client) GetRetryDistribution(ctx context.Context, params *GetRetryDistributionParams) ([]RetryCount, error) {
+	q := url.Values{}
+	if params != nil {
+		if params.From != "" {
+			q.Set("from", params.From)
+		}
+		if params.To != "" {
+			q.Set("to", params.To)
+		}
+	}
+	var out []RetryCount
+	if err := c.do(ctx, http.MethodGet, "/api/analytics/retries", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetTasksPerDayParams are the optional query parameters of GetTasksPerDay.
+type GetTasksPerDayParams struct {
+	// Start of the period, a date (2006-01-02) or RFC 3339 time; default 30 days before to.
+	From string
+	// End of the period, exclusive; default now.
+	To string
+}
+
+// GetTasksPerDay calls GET /api/analytics/tasks-per-day: tasks created, completed and failed per UTC day of a period.
+func (c *Client) GetTasksPerDay(ctx context.Context, params *GetTasksPerDayParams) ([]DayCount, error) {
+	q := url.Values{}
+	if params != nil {
+		if params.From != "" {
+			q.Set("from", params.From)
+		}
+		if params.To != "" {
+			q.Set("to", params.To)
+		}
+	}
+	var out []DayCount
+	if err := c.do(ctx, http.MethodGet, "/api/analytics/tasks-per-day", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Approve calls POST /api/approve/{taskId}: approve the pending proposal of a task.
+func (c *Client) Approve(ctx context.Context, taskID string) (*ActionResponse, error) {
+	var out ActionResponse
+	if err := c.do(ctx, http.MethodPost, "/api/approve/"+url.PathEscape(taskID), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListAuditParams are the optional query parameters of ListAudit.
+type ListAuditParams struct {
+	// Only entries by this actor, e.g. api-key or github:octocat.
+	Actor string
+	// Only entries with this action, e.g. proposal.approved.
+	Action string
+	// Only entries for this task ID, settings section or repository.
+	Target string
+	// Only entries newer than a duration (24h) or RFC 3339 time.
+	Since string
+	// Maximum number of entries (default 100).
+	Limit int
+}
+
+// ListAudit calls GET /api/audit: audit log of state-changing actions, newest first.
+func (c *Client) ListAudit(ctx context.Context, params *ListAuditParams) ([]AuditEntry, error) {
+	q := url.Values{}
+	if params != nil {
+		if params.Actor != "" {
+			q.Set("actor", params.Actor)
+		}
+		if params.Action != "" {
+			q.Set("action", params.Action)
+		}
+		if params.Target != "" {
+			q.Set("target", params.Target)
+		}
+		if params.Since != "" {
+			q.Set("since", params.Since)
+		}
+		if params.Limit != 0 {
+			q.Set("limit", strconv.Itoa(params.Limit))
+		}
+	}
+	var out []AuditEntry
+	if err := c.do(ctx, http.MethodGet, "/api/audit", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetMe calls GET /api/auth/me: the authenticated caller and its role.
+func (c *Client) GetMe(ctx context.Context) (*MeResponse, error) {
+	var out MeResponse
+	if err := c.do(ctx, http.MethodGet, "/api/auth/me", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetConfig calls GET /api/config: non-secret configuration summary.
+func (c *Client) GetConfig(ctx context.Context) (*ConfigResponse, error) {
+	var out ConfigResponse
+	if err := c.do(ctx, http.MethodGet, "/api/config", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListEditorTasksParams are the optional query parameters of ListEditorTasks.
+type ListEditorTasksParams struct {
+	// Only tasks of this owner/name repository.
+	Repo string
+	// Hide completed and failed tasks.
+	Active bool
+}
+
+// ListEditorTasks calls GET /api/editor/tasks: compact task list for editor extensions, newest first.
+func (c *Client) ListEditorTasks(ctx context.Context, params *ListEditorTasksParams) ([]EditorTask, error) {
+	q := url.Values{}
+	if params != nil {
+		if params.Repo != "" {
+			q.Set("repo", params.Repo)
+		}
+		if params.Active {
+			q.Set("active", "true")
+		}
+	}
+	var out []EditorTask
+	if err := c.do(ctx, http.MethodGet, "/api/editor/tasks", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetEditorTask calls GET /api/editor/tasks/{taskId}: compact task for editor extensions.
+func (c *Client) GetEditorTask(ctx context.Context, taskID string) (*EditorTask, error) {
+	var out EditorTask
+	if err := c.do(ctx, http.MethodGet, "/api/editor/tasks/"+url.PathEscape(taskID), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// EditorApprove calls POST /api/editor/tasks/{taskId}/approve: approve the pending proposal of a task.
+func (c *Client) EditorApprove(ctx context.Context, taskID string) (*ActionResponse, error) {
+	var out ActionResponse
+	if err := c.do(ctx, http.MethodPost, "/api/editor/tasks/"+url.PathEscape(taskID)+"/approve", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetEditorCheckout calls GET /api/editor/tasks/{taskId}/checkout: how to check out the task branch.
+func (c *Client) GetEditorCheckout(ctx context.Context, taskID string) (*EditorCheckout, error) {
+	var out EditorCheckout
+	if err := c.do(ctx, http.MethodGet, "/api/editor/tasks/"+url.PathEscape(taskID)+"/checkout", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetEditorDiff calls GET /api/editor/tasks/{taskId}/diff: pending proposal as a unified diff.
+func (c *Client) GetEditorDiff(ctx context.Context, taskID string) ([]byte, error) {
+	return c.doRaw(ctx, http.MethodGet, "/api/editor/tasks/"+url.PathEscape(taskID)+"/diff", nil)
+}
+
+// EditorReject calls POST /api/editor/tasks/{taskId}/reject: reject the pending proposal of a task.
+func (c *Client) EditorReject(ctx context.Context, taskID string) (*ActionResponse, error) {
+	var out ActionResponse
+	if err := c.do(ctx, http.MethodPost, "/api/editor/tasks/"+url.PathEscape(taskID)+"/reject", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListAPIKeys calls GET /api/keys: list API keys and their roles.
+func (c *Client) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	var out []APIKey
+	if err := c.do(ctx, http.MethodGet, "/api/keys", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateAPIKey calls POST /api/keys: create an API key; the response holds the only copy of the key.
+func (c *Client) CreateAPIKey(ctx context.Context, req CreateAPIKeyRequest) (*CreateAPIKeyResponse, error) {
+	var out CreateAPIKeyResponse
+	if err := c.do(ctx, http.MethodPost, "/api/keys", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteAPIKey calls DELETE /api/keys/{name}: revoke an API key.
+func (c *Client) DeleteAPIKey(ctx context.Context, name string) (*ActionResponse, error) {
+	var out ActionResponse
+	if err := c.do(ctx, http.MethodDelete, "/api/keys/"+url.PathEscape(name), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetDORAMetrics calls GET /api/metrics/dora: DORA metrics over the last 30 days.
+func (c *Client) GetDORAMetrics(ctx context.Context) (*DORAMetrics, error) {
+	var out DORAMetrics
+	if err := c.do(ctx, http.MethodGet, "/api/metrics/dora", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListProjects calls GET /api/projects: configured projects.
+func (c *Client) ListProjects(ctx context.Context) ([]ProjectEntry, error) {
+	var out []ProjectEntry
+	if err := c.do(ctx, http.MethodGet, "/api/projects", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListProposals calls GET /api/proposals: pending proposals of all tasks.
+func (c *Client) ListProposals(ctx context.Context) ([]PendingProposalItem, error) {
+	var out []PendingProposalItem
+	if err := c.do(ctx, http.MethodGet, "/api/proposals", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetProposalDiffParams are the optional query parameters of GetProposalDiff.
+type GetProposalDiffParams struct {
+	// Unchanged lines around each hunk (default 3).
+	Context int
+	// Task of the proposal, when proposals of several tasks share its ID.
+	Task string
+}
+
+// GetProposalDiff calls GET /api/proposals/{id}/diff: A proposal of any status as a unified diff; binary files are only named.
+func (c *Client) GetProposalDiff(ctx context.Context, id string, params *GetProposalDiffParams) ([]byte, error) {
+	q := url.Values{}
+	if params != nil {
+		if params.Context != 0 {
+			q.Set("context", strconv.Itoa(params.Context))
+		}
+		if params.Task != "" {
+			q.Set("task", params.Task)
+		}
+	}
+	return c.doRaw(ctx, http.MethodGet, "/api/proposals/"+url.PathEscape(id)+"/diff", q)
+}
+
+// ListTaskProposals calls GET /api/proposals/{taskId}: pending proposals of a task.
+func (c *Client) ListTaskProposals(ctx context.Context, taskID string) ([]Proposal, error) {
+	var out []Proposal
+	if err := c.do(ctx, http.MethodGet, "/api/proposals/"+url.PathEscape(taskID), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// EditPlan calls PUT /api/proposals/{taskId}/plan: replace the plan text of a plan review before approving it.
+func (c *Client) EditPlan(ctx context.Context, taskID string, req EditPlanRequest) (*ActionResponse, error) {
+	var out ActionResponse
+	if err := c.do(ctx, http.MethodPut, "/api/proposals/"+url.PathEscape(taskID)+"/plan", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Reject calls POST /api/reject/{taskId}: reject the pending proposal of a task.
+func (c *Client) Reject(ctx context.Context, taskID string) (*ActionResponse, error) {
+	var out ActionResponse
+	if err := c.do(ctx, http.MethodPost, "/api/reject/"+url.PathEscape(taskID), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetTaskReportParams are the optional query parameters of GetTaskReport.
+type GetTaskReportParams struct {
+	// Start of the period, a date (2006-01-02) or RFC 3339 time; default 30 days before to.
+	From string
+	// End of the period, exclusive; default now.
+	To string
+	// json (default) or csv for a text/csv download.
+	Format string
+}
+
+// GetTaskReport calls GET /api/reports/tasks: per-repository summary of the tasks created in a period.
+func (c *Client) GetTaskReport(ctx context.Context, params *GetTaskReportParams) (*TaskReport, error) {
+	q := url.Values{}
+	if params != nil {
+		if params.From != "" {
+			q.Set("from", params.From)
+		}
+		if params.To != "" {
+			q.Set("to", params.To)
+		}
+		if params.Format != "" {
+			q.Set("format", params.Format)
+		}
+	}
+	var out TaskReport
+	if err := c.do(ctx, http.MethodGet, "/api/reports/tasks", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetSettings calls GET /api/settings: get all settings sections, secrets masked.
+func (c *Client) GetSettings(ctx context.Context) (map[string]any, error) {
+	var out map[string]any
+	if err := c.do(ctx, http.MethodGet, "/api/settings", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SaveSettings calls POST /api/settings: save one settings section, or all when section is empty.
+func (c *Client) SaveSettings(ctx context.Context, req SaveSettingsRequest) (*ActionResponse, error) {
+	var out ActionResponse
+	if err := c.do(ctx, http.MethodPost, "/api/settings", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetStatus calls GET /api/status: server mode, GitHub rate limit and config reload status.
+func (c *Client) GetStatus(ctx context.Context) (*StatusResponse, error) {
+	var out StatusResponse
+	if err := c.do(ctx, http.MethodGet, "/api/status", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListTasksParams are the optional query parameters of ListTasks.
+type ListTasksParams struct {
+	// Only tasks in these statuses, comma-separated.
+	Status string
+	// Only tasks of this repository, owner/name.
+	Repo string
+	// Only tasks created after a duration ago (24h) or an RFC 3339 time.
+	Since string
+	// Only tasks created before a duration ago (24h) or an RFC 3339 time.
+	Until string
+	// Only tasks with this text in the issue title, or this task ID or issue number.
+	Q string
+	// Only the parts of this epic task.
+	Parent string
+	// Sort by created_at (default), completed_at, status or id; prefix - for descending.
+	Sort string
+	// Maximum number of tasks (default all).
+	Limit int
+	// Skip this many matching tasks.
+	Offset int
+}
+
+// ListTasks calls GET /api/tasks: list tasks; X-Total-Count holds the number of all matching tasks.
+func (c *Client) ListTasks(ctx context.Context, params *ListTasksParams) ([]Task, error) {
+	q := url.Values{}
+	if params != nil {
+		if params.Status != "" {
+			q.Set("status", params.Status)
+		}
+		if params.Repo != "" {
+			q.Set("repo", params.Repo)
+		}
+		if params.Since != "" {
+			q.Set("since", params.Since)
+		}
+		if params.Until != "" {
+			q.Set("until", params.Until)
+		}
+		if params.Q != "" {
+			q.Set("q", params.Q)
+		}
+		if params.Parent != "" {
+			q.Set("parent", params.Parent)
+		}
+		if params.Sort != "" {
+			q.Set("sort", params.Sort)
+		}
+		if params.Limit != 0 {
+			q.Set("limit", strconv.Itoa(params.Limit))
+		}
+		if params.Offset != 0 {
+			q.Set("offset", strconv.Itoa(params.Offset))
+		}
+	}
+	var out []Task
+	if err := c.do(ctx, http.MethodGet, "/api/tasks", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateTask calls POST /api/tasks: create a task from an issue and start it; a retry with the same Idempotency-Key returns the first task, and Cache-Control: no-cache skips cached AI answers.
+func (c *Client) CreateTask(ctx context.Context, req CreateTaskRequest) (*Task, error) {
+	var out Task
+	if err := c.do(ctx, http.MethodPost, "/api/tasks", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetTask calls GET /api/tasks/{id}: get a task.
+func (c *Client) GetTask(ctx context.Context, id string) (*Task, error) {
+	var out Task
+	if err := c.do(ctx, http.MethodGet, "/api/tasks/"+url.PathEscape(id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListTaskArtifacts calls GET /api/tasks/{id}/artifacts: full deploy and test outputs and test files the attempts of a task stored.
+func (c *Client) ListTaskArtifacts(ctx context.Context, id string) ([]TaskArtifact, error) {
+	var out []TaskArtifact
+	if err := c.do(ctx, http.MethodGet, "/api/tasks/"+url.PathEscape(id)+"/artifacts", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetTaskArtifact calls GET /api/tasks/{id}/artifacts/{attempt}/{name}: download an artifact of a task attempt; 404 once retention deleted it.
+func (c *Client) GetTaskArtifact(ctx context.Context, id string, attempt string, name string) ([]byte, error) {
+	return c.doRaw(ctx, http.MethodGet, "/api/tasks/"+url.PathEscape(id)+"/artifacts/"+url.PathEscape(attempt)+"/"+url.PathEscape(name), nil)
+}
+
+// GetTaskBundle calls GET /api/tasks/{id}/bundle: diagnostic bundle of a task as a zip.
+func (c *Client) GetTaskBundle(ctx context.Context, id string) ([]byte, error) {
+	return c.doRaw(ctx, http.MethodGet, "/api/tasks/"+url.PathEscape(id)+"/bundle", nil)
+}
+
+// CancelExplainTask calls DELETE /api/tasks/{id}/explain: cancel the running AI diagnosis of a task.
+func (c *Client) CancelExplainTask(ctx context.Context, id string) (*ActionResponse, error) {
+	var out ActionResponse
+	if err := c.do(ctx, http.MethodDelete, "/api/tasks/"+url.PathEscape(id)+"/explain", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ExplainTask calls POST /api/tasks/{id}/explain: AI diagnosis of the last failed attempt of a task, stored with the task; 409 while one runs.
+func (c *Client) ExplainTask(ctx context.Context, id string) (*FailureExplanation, error) {
+	var out FailureExplanation
+	if err := c.do(ctx, http.MethodPost, "/api/tasks/"+url.PathEscape(id)+"/explain", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetTaskLogsParams are the optional query parameters of GetTaskLogs.
+type GetTaskLogsParams struct {
+	// Only return lines with a larger ID.
+	After int64
+}
+
+// GetTaskLogs calls GET /api/tasks/{id}/logs: task log lines.
+func (c *Client) GetTaskLogs(ctx context.Context, id string, params *GetTaskLogsParams) ([]LogEntry, error) {
+	q := url.Values{}
+	if params != nil {
+		if params.After != 0 {
+			q.Set("after", strconv.FormatInt(params.After, 10))
+		}
+	}
+	var out []LogEntry
+	if err := c.do(ctx, http.MethodGet, "/api/tasks/"+url.PathEscape(id)+"/logs", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RedeployTask calls POST /api/tasks/{id}/redeploy: deploy and test a finished task again without generating code; Cache-Control: no-cache skips cached AI answers.
+func (c *Client) RedeployTask(ctx context.Context, id string) (*ActionResponse, error) {
+	var out ActionResponse
+	if err := c.do(ctx, http.MethodPost, "/api/tasks/"+url.PathEscape(id)+"/redeploy", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RetestTask calls POST /api/tasks/{id}/retest: run the tests of a finished task again; Cache-Control: no-cache skips cached AI answers.
+func (c *Client) RetestTask(ctx context.Context, id string) (*ActionResponse, error) {
+	var out ActionResponse
+	if err := c.do(ctx, http.MethodPost, "/api/tasks/"+url.PathEscape(id)+"/retest", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RetryTask calls POST /api/tasks/{id}/retry: run a task again; Cache-Control: no-cache skips cached AI answers.
+func (c *Client) RetryTask(ctx context.Context, id string) (*ActionResponse, error) {
+	var out ActionResponse
+	if err := c.do(ctx, http.MethodPost, "/api/tasks/"+url.PathEscape(id)+"/retry", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// StopTask calls POST /api/tasks/{id}/stop: mark a task as failed.
+func (c *Client) StopTask(ctx context.Context, id string) (*ActionResponse, error) {
+	var out ActionResponse
+	if err := c.do(ctx, http.MethodPost, "/api/tasks/"+url.PathEscape(id)+"/stop", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetTaskSummary calls GET /api/tasks/{id}/summary: AI-written status summary of a task; a cache miss makes an AI call.
+func (c *Client) GetTaskSummary(ctx context.Context, id string) (*TaskSummary, error) {
+	var out TaskSummary
+	if err := c.do(ctx, http.MethodGet, "/api/tasks/"+url.PathEscape(id)+"/summary", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListTenants calls GET /api/tenants: tenants with their quota and what they use of it; a tenant key sees its own.
+func (c *Client) ListTenants(ctx context.Context) ([]Usage, error) {
+	var out []Usage
+	if err := c.do(ctx, http.MethodGet, "/api/tenants", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListWebhookDeliveriesParams are the optional query parameters of ListWebhookDeliveries.
+type ListWebhookDeliveriesParams struct {
+	// Only deliveries with this status: received, accepted, ignored, failed or dead.
+	Status string
+	// Maximum number of deliveries (default 100).
+	Limit int
+}
+
+// ListWebhookDeliveries calls GET /api/webhooks: stored webhook deliveries, newest first, without payloads.
+func (c *Client) ListWebhookDeliveries(ctx context.Context, params *ListWebhookDeliveriesParams) ([]WebhookDelivery, error) {
+	q := url.Values{}
+	if params != nil {
+		if params.Status != "" {
+			q.Set("status", params.Status)
+		}
+		if params.Limit != 0 {
+			q.Set("limit", strconv.Itoa(params.Limit))
+		}
+	}
+	var out []WebhookDelivery
+	if err := c.do(ctx, http.MethodGet, "/api/webhooks", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetWebhookDelivery calls GET /api/webhooks/{id}: get a stored webhook delivery with its headers and payload.
+func (c *Client) GetWebhookDelivery(ctx context.Context, id string) (*WebhookDelivery, error) {
+	var out WebhookDelivery
+	if err := c.do(ctx, http.MethodGet, "/api/webhooks/"+url.PathEscape(id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ReplayWebhookDelivery calls POST /api/webhooks/{id}/replay: process a stored webhook delivery again.
+func (c *Client) ReplayWebhookDelivery(ctx context.Context, id string) (*ActionResponse, error) {
+	var out ActionResponse
+	if err := c.do(ctx, http.MethodPost, "/api/webhooks/"+url.PathEscape(id)+"/replay", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListWorkspaces calls GET /api/workspaces: repository clones and task worktrees with their disk usage.
+func (c *Client) ListWorkspaces(ctx context.Context) ([]Workspace, error) {
+	var out []Workspace
+	if err := c.do(ctx, http.MethodGet, "/api/workspaces", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CollectWorkspaces calls POST /api/workspaces/gc: remove the task worktrees no running or waiting task needs.
+func (c *Client) CollectWorkspaces(ctx context.Context) (*WorkspaceGCResponse, error) {
+	var out WorkspaceGCResponse
+	if err := c.do(ctx, http.MethodPost, "/api/workspaces/gc", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,153 @@
+// Package client is a Go client for the web API of rig serve and rig web.
+//
+// The typed methods in api.go are generated from the same route table as
+// the server's OpenAPI document (GET /api/openapi.json); run go generate in
+// this directory after changing a route.
+//
+//	c := client.New("http://localhost:3000", client.WithAPIKey(os.Getenv("RIG_API_KEY")))
+//	tasks, err := c.ListTasks(ctx, nil)
+package client
+
+//go:generate go run gen.go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout bounds a single API call unless WithHTTPClient is used.
+const DefaultTimeout = 30 * time.Second
+
+// Client calls the rig web API.
+type Client struct {
+	baseURL    string
+	apiKey     string
+	tenant     string
+	httpClient *http.Client
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithAPIKey sends key as X-API-Key, matching the server's RIG_API_KEY.
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// WithTenant sends the calls that every tenant has, such as those of
+// tasks, proposals, reports and keys, to the API of tenant name under
+// /api/t/<name>/.
+func WithTenant(name string) Option {
+	return func(c *Client) { c.tenant = name }
+}
+
+// tenantScopes are the first path segments under /api/ of the calls
+// WithTenant sends to a tenant.
+var tenantScopes = []string{
+	"analytics", "approve", "config", "editor", "events", "keys", "metrics", "projects",
+	"proposals", "reject", "reports", "tasks", "workspaces",
+}
+
+// WithHTTPClient replaces the default HTTP client.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// New returns a client for the server at baseURL, e.g. http://localhost:3000.
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: DefaultTimeout},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// APIError is a non-2xx response from the server.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("server returned %d: %s", e.StatusCode, e.Message)
+}
+
+// do sends a JSON request and decodes the JSON response into out.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encode request: %w", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	data, err := c.send(ctx, method, path, query, reqBody)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("decode response from %s: %w", path, err)
+	}
+	return nil
+}
+
+// doRaw sends a request and returns the response body as-is.
+func (c *Client) doRaw(ctx context.Context, method, path string, query url.Values) ([]byte, error) {
+	return c.send(ctx, method, path, query, nil)
+}
+
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, body io.Reader) ([]byte, error) {
+	if rest, ok := strings.CutPrefix(path, "/api/"); ok && c.tenant != "" {
+		first, _, _ := strings.Cut(rest, "/")
+		if slices.Contains(tenantScopes, first) {
+			path = "/api/t/" + url.PathEscape(c.tenant) + "/" + rest
+		}
+	}
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		msg := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+			msg = apiErr.Error
+		}
+		return nil, &APIError{StatusCode: resp.StatusCode, Message: msg}
+	}
+	return data, nil
+}
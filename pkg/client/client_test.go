@@ -0,0 +1,176 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+	"github.com/rigdev/rig/internal/web"
+)
+
+func testServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	state := &core.State{Version: "1.0", Tasks: []core.Task{{
+		ID:     "task-001",
+		Status: core.PhaseAwaitingApproval,
+		Issue:  core.Issue{Platform: "github", Repo: "acme/app", ID: "7", Title: "Fix login"},
+		Branch: "rig/issue-7",
+		Proposals: []core.Proposal{{
+			ID: "prop-1", Type: core.ProposalDeployApproval, Status: core.ProposalPending, Summary: "Fix it",
+		}},
+	}}}
+	if err := core.SaveState(state, statePath); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		Project: config.ProjectConfig{Name: "acme-app"},
+		Source:  config.SourceConfig{Platform: "github", Repo: "acme/app", BaseBranch: "main"},
+	}
+	srv := httptest.NewServer(web.NewHandler(statePath, cfg, nil))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClient(t *testing.T) {
+	t.Setenv("RIG_API_KEY", "k3y")
+	srv := testServer(t)
+	ctx := context.Background()
+	c := New(srv.URL+"/", WithAPIKey("k3y"))
+
+	tasks, err := c.ListTasks(ctx, nil)
+	if err != nil {
+		t.Fatalf("ListTasks: %v", err)
+	}
+	if len(tasks) != 1 || tasks[0].ID != "task-001" {
+		t.Fatalf("unexpected tasks %+v", tasks)
+	}
+
+	task, err := c.GetTask(ctx, "task-001")
+	if err != nil {
+		t.Fatalf("GetTask: %v", err)
+	}
+	if task.Issue.Title != "Fix login" {
+		t.Errorf("unexpected task %+v", task)
+	}
+
+	_, err = c.GetTask(ctx, "nope")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Message != "task not found" {
+		t.Errorf("expected a 404 APIError, got %v", err)
+	}
+
+	editorTasks, err := c.ListEditorTasks(ctx, &ListEditorTasksParams{Repo: "other/repo"})
+	if err != nil {
+		t.Fatalf("ListEditorTasks: %v", err)
+	}
+	if len(editorTasks) != 0 {
+		t.Errorf("repo filter not applied: %+v", editorTasks)
+	}
+
+	diff, err := c.GetEditorDiff(ctx, "task-001")
+	if err != nil {
+		t.Fatalf("GetEditorDiff: %v", err)
+	}
+	if diff == nil {
+		t.Error("expected a diff body")
+	}
+
+	created, err := c.CreateTask(ctx, CreateTaskRequest{Project: "acme/app", IssueNum: "8"})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	if created.Issue.ID != "8" {
+		t.Errorf("unexpected created task %+v", created)
+	}
+
+	resp, err := c.Reject(ctx, "task-001")
+	if err != nil {
+		t.Fatalf("Reject: %v", err)
+	}
+	if resp.Status != "rejected" {
+		t.Errorf("unexpected reject response %+v", resp)
+	}
+
+	_, err = New(srv.URL).ListTasks(ctx, nil)
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 without the API key, got %v", err)
+	}
+}
+
+func TestClientCoversOperations(t *testing.T) {
+	ct := reflect.TypeOf(&Client{})
+	for _, op := range web.Operations() {
+		if op.NoClient {
+			continue
+		}
+		if _, ok := ct.MethodByName(op.ID); !ok {
+			t.Errorf("client has no method for %s %s (%s); run go generate", op.Method, op.Path, op.ID)
+		}
+	}
+}
+
+func TestGeneratedClientUpToDate(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the generator")
+	}
+	out := filepath.Join(t.TempDir(), "api.go")
+	cmd := exec.Command("go", "run", "gen.go", "-o", out)
+	if msg, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("gen.go: %v\n%s", err, msg)
+	}
+	want, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile("api.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("api.go is out of date; run go generate ./pkg/client")
+	}
+}
+
+func TestWithTenant(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Write([]byte("[]"))
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	c := New(srv.URL, WithTenant("payments"))
+	c.ListTasks(ctx, nil)
+	c.ListAPIKeys(ctx)
+	c.ListTenants(ctx)
+
+	want := []string{"/api/t/payments/tasks", "/api/t/payments/keys", "/api/tenants"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("expected %v, got %v", want, paths)
+	}
+}
+
+func TestTenantScopesMatchOperations(t *testing.T) {
+	for _, op := range web.Operations() {
+		rest, ok := strings.CutPrefix(op.Path, "/api/t/{tenant}/")
+		if !ok {
+			continue
+		}
+		first, _, _ := strings.Cut(rest, "/")
+		if !slices.Contains(tenantScopes, first) {
+			t.Errorf("WithTenant does not send %s calls to the tenant", first)
+		}
+	}
+}
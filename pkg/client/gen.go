@@ -0,0 +1,318 @@
+//go:build ignore
+
+// gen.go writes api.go, the typed methods of the client, from the operations
+// of the web API. Run it with go generate after changing a route.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/rigdev/rig/internal/web"
+)
+
+var pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+func main() {
+	out := flag.String("o", "api.go", "output file")
+	flag.Parse()
+
+	src, err := generate(web.Operations())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// generator renders Go type expressions, collecting the named types the
+// client has to declare: aliases for exported types, and copies of the web
+// package's unexported request and response shapes.
+type generator struct {
+	webPkg  string
+	imports map[string]bool
+	aliases map[string]reflect.Type
+	structs map[string]reflect.Type
+}
+
+func generate(ops []web.Operation) ([]byte, error) {
+	g := &generator{
+		webPkg:  reflect.TypeOf(web.Operation{}).PkgPath(),
+		imports: map[string]bool{"context": true, "net/http": true},
+		aliases: map[string]reflect.Type{},
+		structs: map[string]reflect.Type{},
+	}
+
+	var methods bytes.Buffer
+	for _, op := range ops {
+		if op.NoClient {
+			continue
+		}
+		if err := g.method(&methods, op); err != nil {
+			return nil, fmt.Errorf("%s %s: %w", op.Method, op.Path, err)
+		}
+	}
+
+	// Rendering struct fields can add more types; loop until stable.
+	var types bytes.Buffer
+	done := map[string]bool{}
+	for {
+		names := sortedNames(g.structs)
+		pending := false
+		for _, name := range names {
+			if done[name] {
+				continue
+			}
+			pending = true
+			done[name] = true
+			if err := g.structDecl(&types, name, g.structs[name]); err != nil {
+				return nil, err
+			}
+		}
+		if !pending {
+			break
+		}
+	}
+	var aliases bytes.Buffer
+	for _, name := range sortedNames(g.aliases) {
+		t := g.aliases[name]
+		fmt.Fprintf(&aliases, "\t%s = %s.%s\n", name, path.Base(t.PkgPath()), t.Name())
+	}
+
+	var b bytes.Buffer
+	b.WriteString("// Code generated by gen.go from the web API operations; DO NOT EDIT.\n\npackage client\n\nimport (\n")
+	// Standard library first, then the module's packages.
+	var std, module []string
+	for imp := range g.imports {
+		if strings.Contains(imp, ".") {
+			module = append(module, imp)
+		} else {
+			std = append(std, imp)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(module)
+	for _, imp := range std {
+		fmt.Fprintf(&b, "\t%q\n", imp)
+	}
+	if len(module) > 0 {
+		b.WriteString("\n")
+	}
+	for _, imp := range module {
+		fmt.Fprintf(&b, "\t%q\n", imp)
+	}
+	b.WriteString(")\n\n")
+	if aliases.Len() > 0 {
+		b.WriteString("// Types shared with the rig server.\ntype (\n")
+		b.Write(aliases.Bytes())
+		b.WriteString(")\n\n")
+	}
+	b.Write(types.Bytes())
+	b.Write(methods.Bytes())
+	return format.Source(b.Bytes())
+}
+
+func (g *generator) method(b *bytes.Buffer, op web.Operation) error {
+	params := []string{"ctx context.Context"}
+	pathExpr := `"` + pathParam.ReplaceAllStringFunc(op.Path, func(m string) string {
+		name := strings.Replace(strings.Trim(m, "{}"), "Id", "ID", 1)
+		params = append(params, name+" string")
+		g.imports["net/url"] = true
+		return `"+url.PathEscape(` + name + `)+"`
+	}) + `"`
+	pathExpr = strings.TrimSuffix(pathExpr, `+""`)
+
+	body := "nil"
+	if op.Request != nil {
+		typ, err := g.typeExpr(op.Request)
+		if err != nil {
+			return err
+		}
+		params = append(params, "req "+typ)
+		body = "req"
+	}
+
+	query := "nil"
+	var queryCode bytes.Buffer
+	if len(op.Query) > 0 {
+		g.imports["net/url"] = true
+		paramsType := op.ID + "Params"
+		params = append(params, "params *"+paramsType)
+		fmt.Fprintf(b, "// %s are the optional query parameters of %s.\ntype %s struct {\n", paramsType, op.ID, paramsType)
+		queryCode.WriteString("\tq := url.Values{}\n\tif params != nil {\n")
+		for _, q := range op.Query {
+			field := exported(q.Name)
+			fmt.Fprintf(b, "\t// %s.\n", q.Description)
+			switch q.Kind {
+			case reflect.Bool:
+				fmt.Fprintf(b, "\t%s bool\n", field)
+				fmt.Fprintf(&queryCode, "\t\tif params.%s {\n\t\t\tq.Set(%q, \"true\")\n\t\t}\n", field, q.Name)
+			case reflect.Int:
+				g.imports["strconv"] = true
+				fmt.Fprintf(b, "\t%s int\n", field)
+				fmt.Fprintf(&queryCode, "\t\tif params.%s != 0 {\n\t\t\tq.Set(%q, strconv.Itoa(params.%s))\n\t\t}\n", field, q.Name, field)
+			case reflect.Int64:
+				g.imports["strconv"] = true
+				fmt.Fprintf(b, "\t%s int64\n", field)
+				fmt.Fprintf(&queryCode, "\t\tif params.%s != 0 {\n\t\t\tq.Set(%q, strconv.FormatInt(params.%s, 10))\n\t\t}\n", field, q.Name, field)
+			default:
+				fmt.Fprintf(b, "\t%s string\n", field)
+				fmt.Fprintf(&queryCode, "\t\tif params.%s != \"\" {\n\t\t\tq.Set(%q, params.%s)\n\t\t}\n", field, q.Name, field)
+			}
+		}
+		b.WriteString("}\n\n")
+		queryCode.WriteString("\t}\n")
+		query = "q"
+	}
+
+	fmt.Fprintf(b, "// %s calls %s %s: %s.\n", op.ID, op.Method, op.Path, lowerFirst(op.Summary))
+	method := methodConst(op.Method)
+	switch {
+	case op.Response == nil:
+		fmt.Fprintf(b, "func (c *Client) %s(%s) ([]byte, error) {\n", op.ID, strings.Join(params, ", "))
+		b.Write(queryCode.Bytes())
+		fmt.Fprintf(b, "\treturn c.doRaw(ctx, %s, %s, %s)\n}\n\n", method, pathExpr, query)
+	case op.Response.Kind() == reflect.Struct:
+		typ, err := g.typeExpr(op.Response)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(b, "func (c *Client) %s(%s) (*%s, error) {\n", op.ID, strings.Join(params, ", "), typ)
+		b.Write(queryCode.Bytes())
+		fmt.Fprintf(b, "\tvar out %s\n\tif err := c.do(ctx, %s, %s, %s, %s, &out); err != nil {\n\t\treturn nil, err\n\t}\n\treturn &out, nil\n}\n\n",
+			typ, method, pathExpr, query, body)
+	default:
+		typ, err := g.typeExpr(op.Response)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(b, "func (c *Client) %s(%s) (%s, error) {\n", op.ID, strings.Join(params, ", "), typ)
+		b.Write(queryCode.Bytes())
+		fmt.Fprintf(b, "\tvar out %s\n\tif err := c.do(ctx, %s, %s, %s, %s, &out); err != nil {\n\t\treturn nil, err\n\t}\n\treturn out, nil\n}\n\n",
+			typ, method, pathExpr, query, body)
+	}
+	return nil
+}
+
+func (g *generator) structDecl(b *bytes.Buffer, name string, t reflect.Type) error {
+	fmt.Fprintf(b, "type %s struct {\n", name)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if _, _, skip := web.JSONField(f); skip {
+			continue
+		}
+		typ, err := g.typeExpr(f.Type)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", name, f.Name, err)
+		}
+		fmt.Fprintf(b, "\t%s %s `%s`\n", f.Name, typ, f.Tag)
+	}
+	b.WriteString("}\n\n")
+	return nil
+}
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	rawType  = reflect.TypeOf(json.RawMessage{})
+)
+
+// typeExpr is the Go expression for t in the client package.
+func (g *generator) typeExpr(t reflect.Type) (string, error) {
+	switch t {
+	case timeType:
+		g.imports["time"] = true
+		return "time.Time", nil
+	case rawType:
+		g.imports["encoding/json"] = true
+		return "json.RawMessage", nil
+	}
+	if t.Name() != "" && t.PkgPath() != "" {
+		name := web.SchemaName(t)
+		switch {
+		case t.PkgPath() == g.webPkg && t.Kind() == reflect.Struct:
+			g.structs[name] = t
+		case t.PkgPath() == g.webPkg:
+			return "", fmt.Errorf("unsupported web type %v", t)
+		default:
+			if prev, ok := g.aliases[name]; ok && prev != t {
+				return "", fmt.Errorf("type name %s is used by both %v and %v", name, prev, t)
+			}
+			g.aliases[name] = t
+			g.imports[t.PkgPath()] = true
+		}
+		return name, nil
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		elem, err := g.typeExpr(t.Elem())
+		return "*" + elem, err
+	case reflect.Slice:
+		elem, err := g.typeExpr(t.Elem())
+		return "[]" + elem, err
+	case reflect.Map:
+		key, err := g.typeExpr(t.Key())
+		if err != nil {
+			return "", err
+		}
+		elem, err := g.typeExpr(t.Elem())
+		return "map[" + key + "]" + elem, err
+	case reflect.Interface:
+		if t.NumMethod() == 0 {
+			return "any", nil
+		}
+	case reflect.Bool, reflect.String, reflect.Int, reflect.Int64, reflect.Float64:
+		return t.Name(), nil
+	}
+	return "", fmt.Errorf("unsupported type %v", t)
+}
+
+func methodConst(method string) string {
+	switch method {
+	case http.MethodGet:
+		return "http.MethodGet"
+	case http.MethodPost:
+		return "http.MethodPost"
+	case http.MethodPut:
+		return "http.MethodPut"
+	case http.MethodDelete:
+		return "http.MethodDelete"
+	}
+	return fmt.Sprintf("%q", method)
+}
+
+func exported(s string) string {
+	r := []rune(s)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+func lowerFirst(s string) string {
+	r := []rune(s)
+	if len(r) > 1 && unicode.IsLower(r[1]) {
+		r[0] = unicode.ToLower(r[0])
+	}
+	return string(r)
+}
+
+func sortedNames[T any](m map[string]T) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
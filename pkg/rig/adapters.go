@@ -0,0 +1,179 @@
+package rig
+
+import (
+	"fmt"
+
+	adapterai "github.com/rigdev/rig/internal/adapter/ai"
+	adapterartifact "github.com/rigdev/rig/internal/adapter/artifact"
+	adapterdeploy "github.com/rigdev/rig/internal/adapter/deploy"
+	adaptergit "github.com/rigdev/rig/internal/adapter/git"
+	adapternotify "github.com/rigdev/rig/internal/adapter/notify"
+	adapterplugin "github.com/rigdev/rig/internal/adapter/plugin"
+	adaptertest "github.com/rigdev/rig/internal/adapter/test"
+	"github.com/rigdev/rig/internal/events"
+	"github.com/rigdev/rig/internal/format"
+	"github.com/rigdev/rig/internal/hook"
+	"github.com/rigdev/rig/internal/index"
+)
+
+// The built-in adapters, configured from a Config the way the rig command
+// configures them. Mix them freely with adapters of your own.
+
+// NewAIAdapter returns the adapter of cfg.Provider.
+func NewAIAdapter(cfg AIConfig) (AIAdapter, error) {
+	return adapterai.New(cfg)
+}
+
+// NewGitHub returns the GitHub adapter for owner/repo with the token, API
+// URL, workspaces, clone, author, signing, mirror and offline settings of
+// cfg.Source. It is also a WorkspaceProvider, DraftPRAdapter, PRLabeler,
+// PRReviewerRequester and PRAssigner.
+func NewGitHub(cfg *Config, owner, repo string) (GitAdapter, error) {
+	gh, err := adaptergit.NewGitHub(owner, repo, cfg.Source.Token, cfg.Server.Secret, cfg.Source.APIURL)
+	if err != nil {
+		return nil, err
+	}
+	workspaces, err := adaptergit.NewWorkspaces(cfg.Source.Workspaces.Root, cfg.Source.Workspaces.MaxBytes)
+	if err != nil {
+		return nil, err
+	}
+	gh.SetWorkspaces(workspaces)
+	gh.SetCloneOptions(adaptergit.CloneOptions{
+		Depth:        cfg.Source.Clone.Depth,
+		SingleBranch: cfg.Source.Clone.SingleBranch,
+		Sparse:       cfg.Source.Clone.Sparse,
+		Reference:    cfg.Source.Clone.Reference,
+	})
+	gh.SetAuthor(adaptergit.Author{
+		Name:     cfg.Source.GitAuthor.Name,
+		Email:    cfg.Source.GitAuthor.Email,
+		Identity: cfg.Source.GitAuthor.Identity,
+		AppSlug:  cfg.Source.GitAuthor.AppSlug,
+	})
+	gh.SetSigning(adaptergit.Signing{Format: cfg.Source.Signing.Format, Key: cfg.Source.Signing.Key})
+	if cfg.Source.Mirror != "" {
+		gh.SetMirror(cfg.Source.Mirror)
+	}
+	if cfg.Source.Offline {
+		patchDir := cfg.Source.PatchDir
+		if patchDir == "" {
+			patchDir = ".rig/patches"
+		}
+		gh.SetOffline(patchDir)
+	}
+	return gh, nil
+}
+
+// NewDeployAdapter returns the validated adapter of cfg.Deploy: the custom
+// commands, or the plugin deploy.config.plugin names.
+func NewDeployAdapter(cfg *Config) (DeployAdapter, error) {
+	var adapter DeployAdapter
+	if cfg.Deploy.Method == "plugin" {
+		p := cfg.FindPlugin(cfg.Deploy.Config.Plugin)
+		if p == nil {
+			return nil, fmt.Errorf("create deploy adapter: plugin %q is not declared", cfg.Deploy.Config.Plugin)
+		}
+		adapter = adapterplugin.NewDeployAdapter(adapterplugin.New(*p), cfg.Deploy.Config.Options)
+	} else {
+		custom, err := adapterdeploy.NewCustom(cfg.Deploy.Config, cfg.Deploy.Rollback.Config)
+		if err != nil {
+			return nil, fmt.Errorf("create deploy adapter: %w", err)
+		}
+		adapter = custom
+	}
+	if err := adapter.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid deploy adapter config: %w", err)
+	}
+	return adapter, nil
+}
+
+// NewTestRunners returns a runner for every runnable entry of cfg.Test, in
+// order, as NewEngine expects them. They run in the current directory.
+func NewTestRunners(cfg *Config) []TestRunner {
+	runners := make([]TestRunner, 0, len(cfg.Test))
+	for _, testCfg := range cfg.Test {
+		switch testCfg.Type {
+		case "", "command":
+			runners = append(runners, adaptertest.NewCommandRunner(testCfg, nil))
+		case "http":
+			runners = append(runners, adaptertest.NewHTTPRunner(testCfg))
+		case "browser":
+			runners = append(runners, adaptertest.NewBrowserRunner(testCfg, nil))
+		case "coverage":
+			runners = append(runners, adaptertest.NewCoverageRunner(testCfg, nil))
+		case "plugin":
+			if p := cfg.FindPlugin(testCfg.Plugin); p != nil {
+				runners = append(runners, adapterplugin.NewTestRunner(adapterplugin.New(*p), testCfg))
+			}
+		}
+	}
+	return runners
+}
+
+// NewNotifiers returns the Slack, Discord and plugin notifiers of
+// cfg.Notify. Comment notifiers post on one issue; create them with
+// NewCommentNotifier.
+func NewNotifiers(cfg *Config) []Notifier {
+	notifiers := make([]Notifier, 0, len(cfg.Notify))
+	for _, n := range cfg.Notify {
+		switch n.Type {
+		case "slack", "discord":
+			if n.Webhook != "" {
+				notifiers = append(notifiers, adapternotify.NewWebhookNotifier(n.Type, n.Webhook))
+			}
+		case "plugin":
+			if p := cfg.FindPlugin(n.Plugin); p != nil {
+				notifiers = append(notifiers, adapterplugin.NewNotifier(adapterplugin.New(*p), n.Options))
+			}
+		}
+	}
+	return notifiers
+}
+
+// NewCommentNotifier returns a notifier that comments on issue number of
+// owner/repo through git, which must be the adapter NewGitHub returned.
+func NewCommentNotifier(git GitAdapter, owner, repo string, number int) (Notifier, error) {
+	poster, ok := git.(adapternotify.CommentPoster)
+	if !ok {
+		return nil, fmt.Errorf("git adapter %T cannot post comments", git)
+	}
+	return adapternotify.NewCommentNotifier(poster, owner, repo, number), nil
+}
+
+// NewArtifactStore returns the local or S3-compatible store of
+// cfg.Artifacts; local artifacts default to artifacts/ next to statePath.
+// Set it with Engine.SetArtifactStore.
+func NewArtifactStore(cfg *Config, statePath string) (ArtifactStore, error) {
+	return adapterartifact.New(cfg.Artifacts, statePath)
+}
+
+// NewHookRunner returns the runner of workflow.hooks scripts: Starlark
+// scripts in its embedded interpreter and commands in the task's workspace
+// from ws. Set it with Engine.SetHookRunner.
+func NewHookRunner(ws WorkspaceProvider) HookRunner {
+	return hook.New(ws)
+}
+
+// NewFormatter returns the runner of the cfg.Workflow.Format formatters,
+// which format files in the task's workspace from ws. Set it with
+// Engine.SetFormatter.
+func NewFormatter(cfg *Config, ws WorkspaceProvider) Formatter {
+	return format.New(ws, cfg.Workflow.Format)
+}
+
+// NewEventPublisher returns the publisher of the cfg.Events sinks. Set it
+// with Engine.SetEventPublisher.
+func NewEventPublisher(cfg *Config) (EventPublisher, error) {
+	return events.New(cfg.Events)
+}
+
+// NewIssueMatcher returns the matcher of workflow.dedup method embedding,
+// which compares issues by the embeddings of cfg's provider. Set it with
+// Engine.SetIssueMatcher.
+func NewIssueMatcher(cfg AIConfig) (IssueMatcher, error) {
+	embedder, err := adapterai.NewEmbedder(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return index.NewIssueMatcher(embedder), nil
+}
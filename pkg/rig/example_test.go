@@ -0,0 +1,107 @@
+package rig_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rigdev/rig/pkg/rig"
+)
+
+// scriptedAI is an AIAdapter that changes one file whatever the issue.
+type scriptedAI struct{}
+
+func (scriptedAI) AnalyzeIssue(ctx context.Context, issue *rig.AIIssue, projectContext string) (*rig.AIPlan, error) {
+	return &rig.AIPlan{Summary: "Fix " + issue.Title, Steps: []string{"edit main.go"}}, nil
+}
+
+func (scriptedAI) GenerateCode(ctx context.Context, plan *rig.AIPlan, repoFiles map[string]string) ([]rig.AIFileChange, error) {
+	return []rig.AIFileChange{{Path: "main.go", Content: "package main\n", Action: "modify"}}, nil
+}
+
+func (scriptedAI) AnalyzeFailure(ctx context.Context, logs string, currentCode map[string]string) ([]rig.AIFileChange, error) {
+	return nil, nil
+}
+
+func (scriptedAI) AnalyzeDeployFailure(ctx context.Context, deployLogs string, infraFiles map[string]string) (*rig.AIProposedFix, error) {
+	return &rig.AIProposedFix{Summary: "none"}, nil
+}
+
+// localGit is a GitAdapter that keeps everything in memory.
+type localGit struct{ commits int }
+
+func (g *localGit) CloneOrPull(ctx context.Context, owner, repo, token string) error { return nil }
+func (g *localGit) CreateBranch(ctx context.Context, branchName string) error        { return nil }
+func (g *localGit) Cleanup() error                                                   { return nil }
+func (g *localGit) CleanupBranch(ctx context.Context, branchName string)             {}
+
+func (g *localGit) CommitAndPush(ctx context.Context, changes []rig.GitFileChange, message string) error {
+	g.commits++
+	return nil
+}
+
+func (g *localGit) CreatePR(ctx context.Context, base, head, title, body string) (*rig.GitPullRequest, error) {
+	return &rig.GitPullRequest{Number: 7, URL: "https://example.com/pr/7", Title: title}, nil
+}
+
+// canaryDeploy is a DeployAdapter for a platform rig has no adapter for.
+type canaryDeploy struct{}
+
+func (canaryDeploy) Validate() error { return nil }
+
+func (canaryDeploy) Deploy(ctx context.Context, vars map[string]string) (*rig.AdapterDeployResult, error) {
+	return &rig.AdapterDeployResult{Success: true, Output: "canary up at " + vars["BRANCH_NAME"]}, nil
+}
+
+func (canaryDeploy) Rollback(ctx context.Context) error { return nil }
+
+// smokeTest is a TestRunner that always passes.
+type smokeTest struct{}
+
+func (smokeTest) Run(ctx context.Context, vars map[string]string) (*rig.TestResult, error) {
+	return &rig.TestResult{Name: "smoke", Type: "command", Passed: true, Duration: time.Millisecond}, nil
+}
+
+// inbox is a Notifier that keeps the messages.
+type inbox struct{ messages []string }
+
+func (n *inbox) Notify(ctx context.Context, message string) error {
+	n.messages = append(n.messages, message)
+	return nil
+}
+
+// Wiring custom adapters into the engine without a rig.yaml.
+func Example_customAdapters() {
+	dir, err := os.MkdirTemp("", "rig-example")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := &rig.Config{
+		Project: rig.ProjectConfig{Name: "shop", Language: "go"},
+		Source:  rig.SourceConfig{Platform: "github", Repo: "acme/shop", BaseBranch: "main"},
+		AI:      rig.AIConfig{Provider: "anthropic", Model: "any", MaxRetry: 1},
+		Deploy:  rig.DeployConfig{Method: "custom"},
+		Test:    []rig.TestConfig{{Type: "command", Name: "smoke", Run: "true"}},
+	}
+	git := &localGit{}
+	notes := &inbox{}
+	statePath := filepath.Join(dir, "state.json")
+	engine := rig.NewEngine(cfg, git, scriptedAI{}, canaryDeploy{}, []rig.TestRunner{smokeTest{}}, []rig.Notifier{notes}, statePath)
+
+	issue := rig.Issue{Platform: "github", Repo: "acme/shop", ID: "42", Title: "Checkout button is broken"}
+	if err := engine.Execute(context.Background(), issue); err != nil {
+		panic(err)
+	}
+
+	state, err := rig.LoadState(statePath)
+	if err != nil {
+		panic(err)
+	}
+	task := state.Tasks[0]
+	fmt.Println(task.Status, git.commits, task.PR.URL, len(notes.messages) > 0)
+	// Output: completed 1 https://example.com/pr/7 true
+}
@@ -0,0 +1,248 @@
+// Package rig embeds the rig pipeline in another Go program: load or build
+// a config, wire the engine with the built-in adapters or your own, and run
+// issues through plan, code, commit, deploy, test and report.
+//
+// This package is rig's public Go API; everything under internal/ may
+// change between releases. It follows semantic versioning as APIVersion
+// reports: the names below keep their meaning within a major version, and
+// the types they alias only gain fields and methods. Adapters are plain
+// interfaces, so a custom one is any type with their methods:
+//
+//	cfg, err := rig.LoadConfig("rig.yaml")
+//	ai, err := rig.NewAIAdapter(cfg.AI)
+//	git, err := rig.NewGitHub(cfg, "acme", "app")
+//	engine := rig.NewEngine(cfg, git, ai, myDeploy{}, rig.NewTestRunners(cfg), nil, ".rig/state.json")
+//	err = engine.Execute(ctx, rig.Issue{Platform: "github", Repo: "acme/app", ID: "42", Title: "..."})
+package rig
+
+import (
+	"context"
+	"time"
+
+	"github.com/rigdev/rig/internal/config"
+	"github.com/rigdev/rig/internal/core"
+)
+
+// APIVersion is the semantic version of this package's API.
+const APIVersion = "1.16.0"
+
+// Configuration.
+type (
+	Config             = config.Config
+	ProjectConfig      = config.ProjectConfig
+	SourceConfig       = config.SourceConfig
+	AIConfig           = config.AIConfig
+	DeployConfig       = config.DeployConfig
+	DeployMethodConfig = config.DeployMethodConfig
+	RollbackConfig     = config.RollbackConfig
+	TestConfig         = config.TestConfig
+	NotifyConfig       = config.NotifyConfig
+	WorkflowConfig     = config.WorkflowConfig
+	PRConfig           = config.PRConfig
+	HookConfig         = config.HookConfig
+	FormatConfig       = config.FormatConfig
+	FormatterConfig    = config.FormatterConfig
+	PluginConfig       = config.PluginConfig
+	EventSinkConfig    = config.EventSinkConfig
+	ArtifactsConfig    = config.ArtifactsConfig
+	DedupConfig        = config.DedupConfig
+	LabelRule          = config.LabelRule
+	EpicConfig         = config.EpicConfig
+)
+
+// LoadConfig reads rig.yaml at path with the RIG_PROFILE profile applied
+// and environment variables and ${secret:...} references resolved, and
+// validates it.
+func LoadConfig(path string) (*Config, error) {
+	return config.LoadConfig(path)
+}
+
+// LoadProfile is LoadConfig with an explicit profile; "" loads the base
+// config alone.
+func LoadProfile(path, profile string) (*Config, error) {
+	return config.LoadProfile(path, profile)
+}
+
+// ValidateConfig checks a config built in code as LoadConfig checks a file.
+func ValidateConfig(cfg *Config) error {
+	return config.Validate(cfg)
+}
+
+// NewIssueHosts returns the issue hosts of the hosted platforms and the
+// self-hosted ones cfg names, to read issue URLs with.
+func NewIssueHosts(cfg *Config) IssueHosts {
+	return core.NewIssueHosts(cfg)
+}
+
+// AgentsInstructions returns the AGENTS.md instructions of the repository
+// that the engine sets on the context of planning and code generation
+// calls, for an AIAdapter to put ahead of its system prompt.
+func AgentsInstructions(ctx context.Context) string {
+	return core.AgentsInstructions(ctx)
+}
+
+// The engine and what it runs.
+type (
+	Engine      = core.Engine
+	Issue       = core.Issue
+	IssueHosts  = core.IssueHosts
+	StepName    = core.StepName
+	StepRecord  = core.StepRecord
+	StepOutput  = core.StepOutput
+	Environment = core.Environment
+	LogFunc     = core.LogFunc
+)
+
+// Steps of the pipeline, in order, for Engine.RunStep and Engine.RunSteps.
+const (
+	StepPlan   = core.StepPlan
+	StepCode   = core.StepCode
+	StepCommit = core.StepCommit
+	StepDeploy = core.StepDeploy
+	StepTest   = core.StepTest
+	StepReport = core.StepReport
+)
+
+// ErrAwaitingApproval is returned by Engine.Execute and Engine.Resume when
+// the task stopped for a human to approve a proposal.
+var ErrAwaitingApproval = core.ErrAwaitingApproval
+
+// ErrShutdown is the cause to cancel a task's context with to stop it for a
+// shutdown: the task is checkpointed instead of failed, and
+// Engine.ResumeCheckpoint runs it again.
+var ErrShutdown = core.ErrShutdown
+
+// ErrArtifactNotFound is returned by an ArtifactStore for a key it does
+// not hold.
+var ErrArtifactNotFound = core.ErrArtifactNotFound
+
+// NewEngine creates an engine that runs issues with the given adapters and
+// keeps task state in the JSON file at statePath. tests holds one runner per
+// runnable cfg.Test entry (command, http, browser, coverage and plugin), in
+// config order; NewTestRunners builds exactly that.
+func NewEngine(cfg *Config, git GitAdapter, ai AIAdapter, deploy DeployAdapter, tests []TestRunner, notifiers []Notifier, statePath string) *Engine {
+	return core.NewEngine(cfg, git, ai, deploy, tests, notifiers, statePath)
+}
+
+// Adapter interfaces. Optional capabilities are separate interfaces the
+// engine finds by type assertion.
+type (
+	AIAdapter     = core.AIAdapter
+	GitAdapter    = core.GitAdapter
+	DeployAdapter = core.DeployAdapterIface
+	TestRunner    = core.TestRunnerIface
+	Notifier      = core.NotifierIface
+
+	WorkspaceProvider   = core.WorkspaceProvider
+	DraftPRAdapter      = core.DraftPRAdapter
+	PRLabeler           = core.PRLabeler
+	PRReviewerRequester = core.PRReviewerRequester
+	PRAssigner          = core.PRAssigner
+	SnapshotRollbacker  = core.SnapshotRollbacker
+	HookRunner          = core.HookRunner
+	FixMemory           = core.FixMemory
+	FileRetriever       = core.FileRetriever
+	EventPublisher      = core.EventPublisher
+	PRFinder            = core.PRFinder
+	ArtifactStore       = core.ArtifactStore
+	IssueMatcher        = core.IssueMatcher
+	AgentsStore         = core.AgentsStore
+	Formatter           = core.Formatter
+)
+
+// What adapters take and return.
+type (
+	AIIssue             = core.AIIssue
+	AIPlan              = core.AIPlan
+	AIFileChange        = core.AIFileChange
+	AIProposedFix       = core.AIProposedFix
+	AIProposedFile      = core.AIProposedFile
+	GitFileChange       = core.GitFileChange
+	GitPullRequest      = core.GitPullRequest
+	AdapterDeployResult = core.AdapterDeployResult
+	DeploySnapshot      = core.DeploySnapshot
+	HookInput           = core.HookInput
+	HookOutput          = core.HookOutput
+	ArtifactObject      = core.ArtifactObject
+)
+
+// Task state.
+type (
+	State          = core.State
+	Task           = core.Task
+	TaskPhase      = core.TaskPhase
+	Attempt        = core.Attempt
+	FailReason     = core.FailReason
+	Checkpoint     = core.Checkpoint
+	JournalEntry   = core.JournalEntry
+	DryRunOutput   = core.DryRunOutput
+	ArtifactRef    = core.ArtifactRef
+	PullRequest    = core.PullRequest
+	Proposal       = core.Proposal
+	ProposalStatus = core.ProposalStatus
+	DeployResult   = core.DeployResult
+	TestResult     = core.TestResult
+	TestCase       = core.TestCase
+	DuplicateIssue = core.DuplicateIssue
+	TaskSettings   = core.TaskSettings
+	EpicPart       = core.EpicPart
+)
+
+// Phases of a task.
+const (
+	PhaseQueued           = core.PhaseQueued
+	PhasePlanning         = core.PhasePlanning
+	PhaseCoding           = core.PhaseCoding
+	PhaseCommitting       = core.PhaseCommitting
+	PhaseApproval         = core.PhaseApproval
+	PhaseDeploying        = core.PhaseDeploying
+	PhaseTesting          = core.PhaseTesting
+	PhaseReporting        = core.PhaseReporting
+	PhaseCompleted        = core.PhaseCompleted
+	PhaseFailed           = core.PhaseFailed
+	PhaseRollback         = core.PhaseRollback
+	PhaseAwaitingApproval = core.PhaseAwaitingApproval
+	PhaseDryRun           = core.PhaseDryRun
+	PhaseEpic             = core.PhaseEpic
+)
+
+// Actions of workflow.dedup.action.
+const (
+	DedupSkip   = core.DedupSkip
+	DedupAttach = core.DedupAttach
+)
+
+// Task lifecycle events, as an EventPublisher receives them.
+type (
+	TaskEvent     = core.TaskEvent
+	EventTask     = core.EventTask
+	EventProposal = core.EventProposal
+)
+
+// Event types.
+const (
+	EventTaskCreated         = core.EventTaskCreated
+	EventTaskPhaseChanged    = core.EventTaskPhaseChanged
+	EventTaskProposalPending = core.EventTaskProposalPending
+	EventTaskCompleted       = core.EventTaskCompleted
+	EventTaskFailed          = core.EventTaskFailed
+)
+
+// LoadState reads the task state at path; a missing file is an empty
+// state.
+func LoadState(path string) (*State, error) {
+	return core.LoadState(path)
+}
+
+// SweepArtifacts deletes the artifacts of store stored before maxAge ago,
+// then the oldest while the rest take more than maxBytes, as serve does
+// for artifacts.retention. 0 disables a limit.
+func SweepArtifacts(ctx context.Context, store ArtifactStore, maxAge time.Duration, maxBytes int64) (int, error) {
+	return core.SweepArtifacts(ctx, store, maxAge, maxBytes)
+}
+
+// ReadJournal reads the step journal of a task kept next to the state file
+// at statePath, oldest entry first.
+func ReadJournal(statePath, taskID string) ([]JournalEntry, error) {
+	return core.ReadJournal(statePath, taskID)
+}
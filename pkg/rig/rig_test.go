@@ -0,0 +1,30 @@
+package rig
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestAPIVersionIsSemver(t *testing.T) {
+	if !regexp.MustCompile(`^\d+\.\d+\.\d+$`).MatchString(APIVersion) {
+		t.Errorf("APIVersion %q is not MAJOR.MINOR.PATCH", APIVersion)
+	}
+}
+
+func TestNewTestRunners_SkipsNonRunnableTypes(t *testing.T) {
+	cfg := &Config{Test: []TestConfig{
+		{Type: "command", Name: "unit", Run: "true"},
+		{Type: "ai-verify", Name: "review"},
+		{Type: "http", Name: "health", URL: "http://localhost"},
+	}}
+	if got := NewTestRunners(cfg); len(got) != 2 {
+		t.Errorf("got %d runners, want 2", len(got))
+	}
+}
+
+func TestNewDeployAdapter_UndeclaredPlugin(t *testing.T) {
+	cfg := &Config{Deploy: DeployConfig{Method: "plugin", Config: DeployMethodConfig{Plugin: "acme"}}}
+	if _, err := NewDeployAdapter(cfg); err == nil {
+		t.Error("expected an error for an undeclared plugin")
+	}
+}
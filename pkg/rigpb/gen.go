@@ -0,0 +1,5 @@
+// Package rigpb holds the protobuf messages and gRPC stubs of the rig gRPC
+// API, generated from rig.proto.
+package rigpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative rig.proto